@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+
+	commonconfig "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/runneragent/core/agent"
+	"github.com/koderover/zadig/pkg/setting"
+	zadiglog "github.com/koderover/zadig/pkg/tool/log"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	zadiglog.Init(&zadiglog.Config{
+		Level:       commonconfig.LogLevel(),
+		Development: commonconfig.Mode() != setting.ReleaseMode,
+	})
+
+	a, err := agent.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := a.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
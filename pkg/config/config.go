@@ -17,8 +17,10 @@ limitations under the License.
 package config
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/viper"
 
@@ -85,6 +87,13 @@ func SecretKey() string {
 	return viper.GetString(setting.ENVSecretKey)
 }
 
+// ServiceTokenKey signs the tokens the services call each other with. It is derived from the secret key,
+// so that the login tokens of the users, which are signed by the secret key itself, can not be used as them.
+func ServiceTokenKey() []byte {
+	hash := sha256.Sum256([]byte("service-token:" + SecretKey()))
+	return hash[:]
+}
+
 func AslanServiceAddress() string {
 	s := AslanServiceInfo()
 	return GetServiceAddress(s.Name, s.Port)
@@ -150,6 +159,20 @@ func WarpDriveServiceName() string {
 	return WarpDriveServiceInfo().Name
 }
 
+func WarpDriveServiceAddress() string {
+	s := WarpDriveServiceInfo()
+	return GetServiceAddress(s.Name, s.Port)
+}
+
+func CronServiceInfo() *setting.ServiceInfo {
+	return GetServiceByCode(setting.Cron)
+}
+
+func CronServiceAddress() string {
+	s := CronServiceInfo()
+	return GetServiceAddress(s.Name, s.Port)
+}
+
 func OPAServiceInfo() *setting.ServiceInfo {
 	return GetServiceByCode(setting.OPA)
 }
@@ -251,6 +274,58 @@ func MysqlHost() string {
 	return viper.GetString(setting.ENVMysqlHost)
 }
 
+// RedisAddress is the host:port of the redis used as the cache, the cache is disabled if it is empty.
+func RedisAddress() string {
+	return viper.GetString(setting.ENVRedisAddress)
+}
+
+func RedisPassword() string {
+	return viper.GetString(setting.ENVRedisPassword)
+}
+
+func RedisDB() int {
+	return viper.GetInt(setting.ENVRedisDB)
+}
+
+// DefaultLanguage is the language of the messages for the users who haven't chosen one, zh-CN by default.
+func DefaultLanguage() string {
+	return viper.GetString(setting.ENVDefaultLanguage)
+}
+
+// ClientTimeout is the default timeout of the requests between the services, unit is second.
+func ClientTimeout() time.Duration {
+	if timeout := viper.GetInt(setting.ENVClientTimeout); timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// ClientRetryCount is the number of retries of the idempotent requests between the services
+// which failed because the target service is unavailable.
+func ClientRetryCount() int {
+	if !viper.IsSet(setting.ENVClientRetryCount) {
+		return 2
+	}
+	return viper.GetInt(setting.ENVClientRetryCount)
+}
+
+// ClientBreakerThreshold is the number of consecutive failures which open the circuit breaker
+// of a service.
+func ClientBreakerThreshold() int {
+	if threshold := viper.GetInt(setting.ENVClientBreakerThreshold); threshold > 0 {
+		return threshold
+	}
+	return 5
+}
+
+// ClientBreakerCoolDown is how long an open circuit breaker rejects the requests, unit is second.
+func ClientBreakerCoolDown() time.Duration {
+	if coolDown := viper.GetInt(setting.ENVClientBreakerCoolDown); coolDown > 0 {
+		return time.Duration(coolDown) * time.Second
+	}
+	return 30 * time.Second
+}
+
 func AdminEmail() string {
 	return viper.GetString(setting.ENVAdminEmail)
 }
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const externalURLCacheTTL = time.Minute
+
+// ExternalURLSetting is the addresses which zadig is accessed by from outside, for example through a
+// reverse proxy or by more than one domain.
+type ExternalURLSetting struct {
+	// BaseURLs are the base urls with the protocol and the path prefix, the first one is used for
+	// the links generated outside of a request.
+	BaseURLs []string
+	// TrustForwardedHeaders tells whether the X-Forwarded-* headers of the requests are used to find
+	// the base url the request is sent to.
+	TrustForwardedHeaders bool
+}
+
+var externalURL = struct {
+	sync.RWMutex
+	loader   func() (*ExternalURLSetting, error)
+	setting  *ExternalURLSetting
+	expireAt time.Time
+}{}
+
+// RegisterExternalURLLoader registers the function loading the external url setting, the setting is
+// cached for a minute. SystemAddress is used until a loader is registered.
+func RegisterExternalURLLoader(loader func() (*ExternalURLSetting, error)) {
+	externalURL.Lock()
+	defer externalURL.Unlock()
+
+	externalURL.loader = loader
+	externalURL.setting = nil
+	externalURL.expireAt = time.Time{}
+}
+
+// RefreshExternalURL drops the cached setting, it is called after the setting is changed.
+func RefreshExternalURL() {
+	externalURL.Lock()
+	defer externalURL.Unlock()
+
+	externalURL.expireAt = time.Time{}
+}
+
+func getExternalURLSetting() *ExternalURLSetting {
+	externalURL.RLock()
+	if time.Now().Before(externalURL.expireAt) || externalURL.loader == nil {
+		defer externalURL.RUnlock()
+		return externalURL.setting
+	}
+	externalURL.RUnlock()
+
+	externalURL.Lock()
+	defer externalURL.Unlock()
+	if time.Now().Before(externalURL.expireAt) {
+		return externalURL.setting
+	}
+	// the previous setting is kept if it can not be loaded.
+	if setting, err := externalURL.loader(); err == nil {
+		externalURL.setting = setting
+	}
+	externalURL.expireAt = time.Now().Add(externalURLCacheTTL)
+	return externalURL.setting
+}
+
+// ExternalBaseURL is the base url of the links which are not generated for a request, such as the links
+// in the notifications and the webhooks registered to the codehosts.
+func ExternalBaseURL() string {
+	if setting := getExternalURLSetting(); setting != nil && len(setting.BaseURLs) > 0 {
+		return strings.TrimSuffix(setting.BaseURLs[0], "/")
+	}
+	return strings.TrimSuffix(SystemAddress(), "/")
+}
+
+// ExternalBaseURLFromRequest is the base url the request is sent to, so that the links returned to
+// the user stay on the domain the user is visiting. Only the configured base urls are returned if
+// there are any, ExternalBaseURL is returned if the request does not match any of them.
+func ExternalBaseURLFromRequest(r *http.Request) string {
+	setting := getExternalURLSetting()
+	if setting == nil || r == nil {
+		return ExternalBaseURL()
+	}
+
+	scheme, host, prefix := "http", r.Host, ""
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if setting.TrustForwardedHeaders {
+		if proto := firstHeaderValue(r, "X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		if forwardedHost := firstHeaderValue(r, "X-Forwarded-Host"); forwardedHost != "" {
+			host = forwardedHost
+		}
+		prefix = strings.TrimSuffix(firstHeaderValue(r, "X-Forwarded-Prefix"), "/")
+	}
+	requested := scheme + "://" + host + prefix
+
+	for _, baseURL := range setting.BaseURLs {
+		if strings.EqualFold(strings.TrimSuffix(baseURL, "/"), requested) {
+			return strings.TrimSuffix(baseURL, "/")
+		}
+	}
+	// the forwarded address is trusted as is only if no base url is configured to match against.
+	if len(setting.BaseURLs) == 0 && setting.TrustForwardedHeaders && host != "" {
+		return requested
+	}
+	return ExternalBaseURL()
+}
+
+// firstHeaderValue returns the value set by the outermost proxy if the header is set by a chain of proxies.
+func firstHeaderValue(r *http.Request, key string) string {
+	value := strings.Split(r.Header.Get(key), ",")[0]
+	return strings.TrimSpace(value)
+}
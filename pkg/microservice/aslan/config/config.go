@@ -62,6 +62,69 @@ func DefaultRecycleDay() int {
 	return int(defaultRecycleDayValue)
 }
 
+// WebhookWorkers is the number of workers processing the queued webhooks, default is 8.
+func WebhookWorkers() int {
+	workers := viper.GetInt(setting.ENVWebhookWorkers)
+	if workers <= 0 {
+		return 8
+	}
+	return workers
+}
+
+// GitMirrorPath is the directory of the bare mirrors of the repos, it should be a volume shared by
+// all the replicas of aslan.
+func GitMirrorPath() string {
+	path := viper.GetString(setting.ENVGitMirrorPath)
+	if path == "" {
+		return configbase.DataPath() + "/git-mirrors"
+	}
+	return path
+}
+
+// BootstrapConfigFile is the declarative system configuration reconciled when aslan starts.
+func BootstrapConfigFile() string {
+	file := viper.GetString(setting.ENVBootstrapConfigFile)
+	if file == "" {
+		return "/etc/zadig/bootstrap/system-config.yaml"
+	}
+	return file
+}
+
+// DevSeedSnapshot is the snapshot seeded in the development mode, it is a directory or "builtin" for the
+// sample snapshot shipped with aslan. Nothing is seeded if it is empty.
+func DevSeedSnapshot() string {
+	return viper.GetString(setting.ENVDevSeedSnapshot)
+}
+
+// DevMockProviderAddr is the listening address of the mock code provider started with the seeding.
+func DevMockProviderAddr() string {
+	addr := viper.GetString(setting.ENVDevMockProviderAddr)
+	if addr == "" {
+		return "127.0.0.1:26080"
+	}
+	return addr
+}
+
+func EncryptionKeyProvider() string {
+	return viper.GetString(setting.ENVEncryptionKeyProvider)
+}
+
+func EncryptionKeyID() string {
+	return viper.GetString(setting.ENVEncryptionKeyID)
+}
+
+func EncryptionKMSRegion() string {
+	return viper.GetString(setting.ENVEncryptionKMSRegion)
+}
+
+func VaultAddress() string {
+	return viper.GetString(setting.ENVVaultAddress)
+}
+
+func VaultToken() string {
+	return viper.GetString(setting.ENVVaultToken)
+}
+
 func PodName() string {
 	return viper.GetString(setting.ENVPodName)
 }
@@ -214,6 +277,11 @@ func OldEnvSupported() bool {
 	return viper.GetString(setting.ENVOldEnvSupported) == "true"
 }
 
+// TenantIsolationEnabled returns whether the projects and the resources are isolated by tenants.
+func TenantIsolationEnabled() bool {
+	return viper.GetString(setting.ENVTenantIsolation) == "true"
+}
+
 func ProxySocks5Addr() string {
 	return viper.GetString(setting.ProxySocks5Addr)
 }
@@ -223,7 +291,10 @@ func JenkinsImage() string {
 }
 
 func WebHookURL() string {
-	return fmt.Sprintf("%s/api/aslan/webhook", configbase.SystemAddress())
+	if relayURL := viper.GetString(setting.ENVWebhookRelayURL); relayURL != "" {
+		return relayURL
+	}
+	return fmt.Sprintf("%s/api/aslan/webhook", configbase.ExternalBaseURL())
 }
 
 func ObjectStorageServicePath(project, service string) string {
@@ -41,8 +41,11 @@ const (
 	EnvRecyclePolicyNever      = "never"
 
 	// 定时器的所属job类型
-	WorkflowCronjob = "workflow"
-	TestingCronjob  = "test"
+	WorkflowCronjob       = "workflow"
+	TestingCronjob        = "test"
+	EnvDataRefreshCronjob = "env_data_refresh"
+	EnvSmokeTestCronjob   = "env_smoke_test"
+	ReleaseTrainCronjob   = "release_train"
 )
 
 var (
@@ -166,6 +169,9 @@ const (
 	StepArchiveDistribute StepType = "archive_distribute"
 	StepJunitReport       StepType = "junit_report"
 	StepHtmlReport        StepType = "html_report"
+	StepAndroidPublish    StepType = "android_publish"
+	StepDownload          StepType = "download"
+	StepSummary           StepType = "summary"
 )
 
 type JobType string
@@ -179,6 +185,21 @@ const (
 	JobZadigHelmDeploy JobType = "zadig-helm-deploy"
 	JobFreestyle       JobType = "freestyle"
 	JobPlugin          JobType = "plugin"
+	JobJenkins         JobType = "jenkins"
+	JobZadigRollout    JobType = "zadig-rollout"
+	JobLicenseScan     JobType = "license-scan"
+	JobAndroidBuild    JobType = "android-build"
+	JobSandboxCluster  JobType = "sandbox-cluster"
+)
+
+// SandboxClusterProvider provisions the ephemeral cluster of the sandbox cluster jobs.
+type SandboxClusterProvider string
+
+const (
+	// SandboxProviderVCluster installs a vcluster in a namespace of the host cluster.
+	SandboxProviderVCluster SandboxClusterProvider = "vcluster"
+	// SandboxProviderClusterAPI creates a cluster from a ClusterClass in the Cluster API management cluster.
+	SandboxProviderClusterAPI SandboxClusterProvider = "cluster-api"
 )
 
 type ApproveOrReject string
@@ -188,6 +209,74 @@ const (
 	Reject  ApproveOrReject = "reject"
 )
 
+type ApprovalType string
+
+const (
+	NativeApproval  ApprovalType = "native"
+	WebhookApproval ApprovalType = "webhook"
+)
+
+// WebhookApprovalDecision is answered by the external approval service of a webhook approval.
+type WebhookApprovalDecision string
+
+const (
+	WebhookApprovalApprove WebhookApprovalDecision = "approve"
+	WebhookApprovalDeny    WebhookApprovalDecision = "deny"
+	WebhookApprovalPending WebhookApprovalDecision = "pending"
+)
+
+// ExecutionHookEvent is the point in the lifecycle of a task where the execution hook is called.
+type ExecutionHookEvent string
+
+const (
+	ExecutionHookPreStart   ExecutionHookEvent = "pre_start"
+	ExecutionHookPostFinish ExecutionHookEvent = "post_finish"
+)
+
+// ExecutionHookDecision is answered by the external system to a pre-start hook.
+type ExecutionHookDecision string
+
+const (
+	ExecutionHookProceed ExecutionHookDecision = "proceed"
+	ExecutionHookVeto    ExecutionHookDecision = "veto"
+	ExecutionHookDelay   ExecutionHookDecision = "delay"
+)
+
+type ReleaseTrainItemStatus string
+
+const (
+	ReleaseTrainItemPending ReleaseTrainItemStatus = "pending"
+	ReleaseTrainItemShipped ReleaseTrainItemStatus = "shipped"
+)
+
+type EnvAutoUpdateStatus string
+
+const (
+	EnvAutoUpdatePending   EnvAutoUpdateStatus = "pending"
+	EnvAutoUpdateDeploying EnvAutoUpdateStatus = "deploying"
+	EnvAutoUpdateDeployed  EnvAutoUpdateStatus = "deployed"
+	EnvAutoUpdateFailed    EnvAutoUpdateStatus = "failed"
+	EnvAutoUpdateSkipped   EnvAutoUpdateStatus = "skipped"
+)
+
+type DependencyKind string
+
+const (
+	DependencyKindImage DependencyKind = "image"
+	DependencyKindChart DependencyKind = "chart"
+)
+
+type DependencyUpdateStatus string
+
+const (
+	// DependencyUpdateDetected means the pull request of the update is not opened yet.
+	DependencyUpdateDetected DependencyUpdateStatus = "detected"
+	DependencyUpdatePROpened DependencyUpdateStatus = "pr_opened"
+	DependencyUpdateFailed   DependencyUpdateStatus = "failed"
+	// DependencyUpdateResolved means the service uses the version, or a newer one.
+	DependencyUpdateResolved DependencyUpdateStatus = "resolved"
+)
+
 type DeploySourceType string
 
 const (
@@ -210,6 +299,10 @@ const (
 	Chart DistributeType = "chart"
 )
 
+// DeliveryArtifactSourceExternal is the source of the artifacts built by the external CI systems and registered
+// by the open API.
+const DeliveryArtifactSourceExternal = "external"
+
 type NotifyType int
 
 var (
@@ -308,3 +401,159 @@ const (
 	RegistryProviderECR       = "ecr"
 	RegistryProviderNative    = "native"
 )
+
+// status of the webhook events in the processing queue
+const (
+	WebhookEventPending    = "pending"
+	WebhookEventProcessing = "processing"
+	WebhookEventSucceeded  = "succeeded"
+	// WebhookEventDead is the status of the events which still fail after all the retries.
+	WebhookEventDead = "dead"
+)
+
+// conditions the dependents of a service wait for before they are deployed
+const (
+	ServiceReadyConditionReady = "ready"
+	ServiceReadyConditionNone  = "none"
+)
+
+// the gerrit changes which are built together with the change triggering the workflow
+const (
+	GerritSeriesRelationChain = "relation_chain"
+	GerritSeriesTopic         = "topic"
+)
+
+// kinds of the credentials checked by the credential health check
+const (
+	CredentialKindCodehost = "codehost"
+	CredentialKindRegistry = "registry"
+	CredentialKindCluster  = "cluster"
+	// CredentialKindSecret is the credential variables of the variable groups, their usages are audited
+	// but they are not checked.
+	CredentialKindSecret = "secret"
+
+	// CredentialExpiryNotifyDays is how many days before a credential expires its owners are notified.
+	CredentialExpiryNotifyDays = 7
+)
+
+// DedupStrategy decides what happens when a webhook triggers a workflow for a commit which already has
+// a running task of the workflow, e.g. both the push and the pull request sync events fire for it.
+type DedupStrategy string
+
+const (
+	// DedupStrategyForceNew always creates a new task, it is the default.
+	DedupStrategyForceNew DedupStrategy = "force_new"
+	// DedupStrategyReuse skips the new trigger and reuses the running task.
+	DedupStrategyReuse DedupStrategy = "reuse"
+	// DedupStrategyAttach skips the new trigger and reports the status of the running task to it.
+	DedupStrategyAttach DedupStrategy = "attach"
+)
+
+// HelmPostRendererType is the way the manifests rendered by helm are mutated before they are applied.
+type HelmPostRendererType string
+
+const (
+	// HelmPostRendererKustomize applies the kustomization to the manifests with kustomize.
+	HelmPostRendererKustomize HelmPostRendererType = "kustomize"
+	// HelmPostRendererCommand pipes the manifests to a command, e.g. an OPA based mutation.
+	HelmPostRendererCommand HelmPostRendererType = "command"
+)
+
+type HealthProbeType string
+
+const (
+	HealthProbeHTTP HealthProbeType = "http"
+	HealthProbeGRPC HealthProbeType = "grpc"
+)
+
+// ComparisonSignalType is the kind of the signal the deploy comparison compares with the previous deployment.
+type ComparisonSignalType string
+
+const (
+	ComparisonSignalErrorRate ComparisonSignalType = "error_rate"
+	ComparisonSignalLatency   ComparisonSignalType = "latency"
+	// ComparisonSignalTestPassRate is the pass rate in percent of the smoke tests of the env during the bake period.
+	ComparisonSignalTestPassRate ComparisonSignalType = "test_pass_rate"
+)
+
+type ClusterSharedServiceStatus string
+
+const (
+	ClusterSharedServiceStatusDeploying ClusterSharedServiceStatus = "deploying"
+	ClusterSharedServiceStatusRunning   ClusterSharedServiceStatus = "running"
+	ClusterSharedServiceStatusFailed    ClusterSharedServiceStatus = "failed"
+)
+
+// RunnerType is where the agents of a runner come from.
+type RunnerType string
+
+const (
+	// RunnerTypeStatic runs the jobs on the agents started by the users, e.g. on bare-metal machines.
+	RunnerTypeStatic RunnerType = "static"
+	// RunnerTypeEC2 launches an ephemeral ec2 instance running the agent for each job.
+	RunnerTypeEC2 RunnerType = "ec2"
+	// RunnerTypeECS runs an ephemeral ecs task running the agent for each job.
+	RunnerTypeECS RunnerType = "ecs"
+	// RunnerTypeMacOS runs the jobs on the self-hosted mac machines, the signing identity of the runner is
+	// installed for each job, e.g. for the iOS builds.
+	RunnerTypeMacOS RunnerType = "macos"
+)
+
+type VariableGroupChangeAction string
+
+const (
+	VariableGroupChangeAdded   VariableGroupChangeAction = "added"
+	VariableGroupChangeUpdated VariableGroupChangeAction = "updated"
+	VariableGroupChangeRemoved VariableGroupChangeAction = "removed"
+)
+
+// NotificationSeverity is how serious an event routed to the notification channels is, the routes, the quiet
+// hours and the preferences of the users are evaluated against it.
+type NotificationSeverity string
+
+const (
+	NotificationSeverityInfo    NotificationSeverity = "info"
+	NotificationSeverityWarning NotificationSeverity = "warning"
+	NotificationSeverityError   NotificationSeverity = "error"
+	// NotificationSeverityCritical is sent during the quiet hours.
+	NotificationSeverityCritical NotificationSeverity = "critical"
+)
+
+const (
+	NotificationEventWorkflow = "workflow"
+	NotificationEventPipeline = "pipeline"
+	NotificationEventTesting  = "testing"
+	// NotificationEventDeployRegression is sent when the deploy comparison finds a regression.
+	NotificationEventDeployRegression = "deploy_regression"
+	// NotificationEventProjectDigest is the weekly health digest of a project.
+	NotificationEventProjectDigest = "project_digest"
+)
+
+// HealthComponent is a signal the health score of a project is computed from.
+type HealthComponent string
+
+const (
+	HealthComponentBuildSuccess     HealthComponent = "build_success"
+	HealthComponentDeployFrequency  HealthComponent = "deploy_frequency"
+	HealthComponentTestCoverage     HealthComponent = "test_coverage"
+	HealthComponentSecurityFindings HealthComponent = "security_findings"
+	HealthComponentEnvDrift         HealthComponent = "env_drift"
+)
+
+// CustomFieldScope is the kind of the resources a custom field is attached to.
+type CustomFieldScope string
+
+const (
+	CustomFieldScopeProject  CustomFieldScope = "project"
+	CustomFieldScopeWorkflow CustomFieldScope = "workflow"
+)
+
+// CustomFieldType is how the values of a custom field are validated, the values are saved as strings.
+type CustomFieldType string
+
+const (
+	CustomFieldTypeString CustomFieldType = "string"
+	CustomFieldTypeNumber CustomFieldType = "number"
+	CustomFieldTypeBool   CustomFieldType = "bool"
+	CustomFieldTypeEnum   CustomFieldType = "enum"
+)
@@ -216,6 +216,9 @@ func CreateBuild(username string, build *commonmodels.Build, log *zap.SugaredLog
 	if err := commonutil.CheckDefineResourceParam(build.PreBuild.ResReq, build.PreBuild.ResReqSpec); err != nil {
 		return e.ErrCreateBuildModule.AddDesc(err.Error())
 	}
+	if err := commonutil.CheckArtifactDownloads(build.PreBuild.Downloads); err != nil {
+		return e.ErrCreateBuildModule.AddDesc(err.Error())
+	}
 
 	build.UpdateBy = username
 	err := correctFields(build)
@@ -238,6 +241,9 @@ func UpdateBuild(username string, build *commonmodels.Build, log *zap.SugaredLog
 	if err := commonutil.CheckDefineResourceParam(build.PreBuild.ResReq, build.PreBuild.ResReqSpec); err != nil {
 		return e.ErrUpdateBuildModule.AddDesc(err.Error())
 	}
+	if err := commonutil.CheckArtifactDownloads(build.PreBuild.Downloads); err != nil {
+		return e.ErrUpdateBuildModule.AddDesc(err.Error())
+	}
 
 	existed, err := commonrepo.NewBuildColl().Find(&commonrepo.BuildFindOption{Name: build.Name, ProductName: build.ProductName})
 	if err == nil && existed.PreBuild != nil && build.PreBuild != nil {
@@ -476,6 +482,26 @@ func UpdateBuildTargets(name, productName string, targets []*commonmodels.Servic
 	return nil
 }
 
+// pinDockerfileTemplateRevision keeps the build on the revision of the dockerfile template it is
+// saved with, a revision which does not belong to the template is replaced by the latest one.
+func pinDockerfileTemplateRevision(dockerBuild *commonmodels.DockerBuild) error {
+	if dockerBuild.Source != setting.DockerfileSourceTemplate || dockerBuild.TemplateID == "" {
+		dockerBuild.TemplateRevision = 0
+		return nil
+	}
+	if dockerBuild.TemplateRevision > 0 {
+		if _, err := commonrepo.NewDockerfileTemplateVersionColl().Find(dockerBuild.TemplateID, dockerBuild.TemplateRevision); err == nil {
+			return nil
+		}
+	}
+	dockerfileTemplate, err := commonrepo.NewDockerfileTemplateColl().GetById(dockerBuild.TemplateID)
+	if err != nil {
+		return fmt.Errorf("failed to find dockerfile template with id: %s, err: %s", dockerBuild.TemplateID, err)
+	}
+	dockerBuild.TemplateRevision = dockerfileTemplate.Revision
+	return nil
+}
+
 func correctFields(build *commonmodels.Build) error {
 	err := fillBuildTargetData(build)
 	if err != nil {
@@ -495,6 +521,9 @@ func correctFields(build *commonmodels.Build) error {
 	if build.PostBuild != nil && build.PostBuild.DockerBuild != nil {
 		build.PostBuild.DockerBuild.DockerFile = strings.Trim(build.PostBuild.DockerBuild.DockerFile, " ")
 		build.PostBuild.DockerBuild.WorkDir = strings.Trim(build.PostBuild.DockerBuild.WorkDir, " ")
+		if err := pinDockerfileTemplateRevision(build.PostBuild.DockerBuild); err != nil {
+			return err
+		}
 	}
 	if build.TemplateID == "" {
 		for _, repo := range build.Repos {
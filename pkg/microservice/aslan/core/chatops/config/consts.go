@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "time"
+
+type AppType string
+
+const (
+	AppTypeSlack AppType = "slack"
+	AppTypeLark  AppType = "lark"
+)
+
+type Action string
+
+const (
+	ActionRun      Action = "run"
+	ActionApprove  Action = "approve"
+	ActionReject   Action = "reject"
+	ActionRollback Action = "rollback"
+	ActionConfirm  Action = "confirm"
+	ActionHelp     Action = "help"
+)
+
+const (
+	// ConfirmationTimeout is how long a command waiting for confirmation stays valid.
+	ConfirmationTimeout = 5 * time.Minute
+	// FollowTimeout is how long the result of a triggered task is followed in the thread.
+	FollowTimeout = 24 * time.Hour
+)
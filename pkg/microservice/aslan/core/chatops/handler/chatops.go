@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListChatOpsApps(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListChatOpsApps(ctx.Logger)
+}
+
+func CreateChatOpsApp(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.ChatOpsApp)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统设置-ChatOps", args.Name, "", ctx.Logger)
+	args.UpdateBy = ctx.UserName
+	ctx.Err = service.CreateChatOpsApp(args, ctx.Logger)
+}
+
+func UpdateChatOpsApp(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.ChatOpsApp)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统设置-ChatOps", c.Param("id"), "", ctx.Logger)
+	args.UpdateBy = ctx.UserName
+	ctx.Err = service.UpdateChatOpsApp(c.Param("id"), args, ctx.Logger)
+}
+
+func DeleteChatOpsApp(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统设置-ChatOps", c.Param("id"), "", ctx.Logger)
+	ctx.Err = service.DeleteChatOpsApp(c.Param("id"), ctx.Logger)
+}
+
+func ListChatOpsBindings(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListChatOpsBindings(c.Query("app_id"), ctx.Logger)
+}
+
+func CreateChatOpsBinding(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.ChatOpsBinding)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统设置-ChatOps用户绑定", args.ChatUserID, "", ctx.Logger)
+	args.CreateBy = ctx.UserName
+	ctx.Err = service.CreateChatOpsBinding(args, ctx.Logger)
+}
+
+func DeleteChatOpsBinding(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统设置-ChatOps用户绑定", c.Param("id"), "", ctx.Logger)
+	ctx.Err = service.DeleteChatOpsBinding(c.Param("id"), ctx.Logger)
+}
+
+func HandleSlackCommand(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	body, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.HandleSlackCommand(c.Param("id"), c.Request.Header, body, ctx.Logger)
+}
+
+func HandleLarkEvent(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	body, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	resp, err := service.HandleLarkEvent(c.Param("id"), body, ctx.Logger)
+	if resp != nil {
+		ctx.Resp = resp
+	}
+	ctx.Err = err
+}
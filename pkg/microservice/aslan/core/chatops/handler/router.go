@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type Router struct{}
+
+func (*Router) Inject(router *gin.RouterGroup) {
+	apps := router.Group("apps")
+	{
+		apps.GET("", ListChatOpsApps)
+		apps.POST("", CreateChatOpsApp)
+		apps.PUT("/:id", UpdateChatOpsApp)
+		apps.DELETE("/:id", DeleteChatOpsApp)
+	}
+
+	bindings := router.Group("bindings")
+	{
+		bindings.GET("", ListChatOpsBindings)
+		bindings.POST("", CreateChatOpsBinding)
+		bindings.DELETE("/:id", DeleteChatOpsBinding)
+	}
+
+	// callbacks of the IM platforms, the requests are verified with the app credentials.
+	router.POST("/slack/:id/command", HandleSlackCommand)
+	router.POST("/lark/:id/event", HandleLarkEvent)
+}
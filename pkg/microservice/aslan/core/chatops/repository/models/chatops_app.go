@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/config"
+)
+
+// ChatOpsApp is a bot installed in an IM workspace which receives commands from users.
+type ChatOpsApp struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"         json:"id,omitempty"`
+	Name    string             `bson:"name"                  json:"name"`
+	Type    config.AppType     `bson:"type"                  json:"type"`
+	Enabled bool               `bson:"enabled"               json:"enabled"`
+	// slack app credentials
+	SigningSecret string `bson:"signing_secret,omitempty"   json:"signing_secret,omitempty"`
+	BotToken      string `bson:"bot_token,omitempty"        json:"bot_token,omitempty"`
+	// lark app credentials
+	AppID             string `bson:"app_id,omitempty"             json:"app_id,omitempty"`
+	AppSecret         string `bson:"app_secret,omitempty"         json:"app_secret,omitempty"`
+	VerificationToken string `bson:"verification_token,omitempty" json:"verification_token,omitempty"`
+	UpdateBy          string `bson:"update_by"                    json:"update_by"`
+	UpdateTime        int64  `bson:"update_time"                  json:"update_time"`
+}
+
+func (ChatOpsApp) TableName() string {
+	return "chatops_app"
+}
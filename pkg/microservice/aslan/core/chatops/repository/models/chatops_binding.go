@@ -0,0 +1,37 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatOpsBinding maps a user of the IM workspace to a zadig user, commands are
+// executed with the permissions of the bound zadig user.
+type ChatOpsBinding struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	AppID      string             `bson:"app_id"          json:"app_id"`
+	ChatUserID string             `bson:"chat_user_id"    json:"chat_user_id"`
+	UserID     string             `bson:"user_id"         json:"user_id"`
+	UserName   string             `bson:"user_name"       json:"user_name"`
+	CreateBy   string             `bson:"create_by"       json:"create_by"`
+	CreateTime int64              `bson:"create_time"     json:"create_time"`
+}
+
+func (ChatOpsBinding) TableName() string {
+	return "chatops_binding"
+}
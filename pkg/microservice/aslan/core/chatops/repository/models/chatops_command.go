@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/config"
+)
+
+// ChatOpsCommand is a command waiting for the confirmation of the user who sent it.
+type ChatOpsCommand struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	Code       string             `bson:"code"            json:"code"`
+	AppID      string             `bson:"app_id"          json:"app_id"`
+	ChatUserID string             `bson:"chat_user_id"    json:"chat_user_id"`
+	Action     config.Action      `bson:"action"          json:"action"`
+	Args       []string           `bson:"args"            json:"args"`
+	// Thread is where the results are replied, its format depends on the app type.
+	Thread     string `bson:"thread"          json:"thread"`
+	ExpireTime int64  `bson:"expire_time"     json:"expire_time"`
+}
+
+func (ChatOpsCommand) TableName() string {
+	return "chatops_command"
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ChatOpsAppColl struct {
+	*mongo.Collection
+	coll string
+}
+
+func NewChatOpsAppColl() *ChatOpsAppColl {
+	name := models.ChatOpsApp{}.TableName()
+	return &ChatOpsAppColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ChatOpsAppColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ChatOpsAppColl) EnsureIndex(_ context.Context) error {
+	return nil
+}
+
+func (c *ChatOpsAppColl) Create(app *models.ChatOpsApp) error {
+	app.UpdateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), app)
+	return err
+}
+
+func (c *ChatOpsAppColl) Update(id string, app *models.ChatOpsApp) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	app.ID = oid
+	app.UpdateTime = time.Now().Unix()
+	_, err = c.ReplaceOne(context.TODO(), bson.M{"_id": oid}, app)
+	return err
+}
+
+func (c *ChatOpsAppColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
+
+func (c *ChatOpsAppColl) Get(id string) (*models.ChatOpsApp, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.ChatOpsApp{}
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(res)
+	return res, err
+}
+
+func (c *ChatOpsAppColl) List() ([]*models.ChatOpsApp, error) {
+	res := make([]*models.ChatOpsApp, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, err
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ChatOpsBindingColl struct {
+	*mongo.Collection
+	coll string
+}
+
+func NewChatOpsBindingColl() *ChatOpsBindingColl {
+	name := models.ChatOpsBinding{}.TableName()
+	return &ChatOpsBindingColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ChatOpsBindingColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ChatOpsBindingColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "app_id", Value: 1},
+			bson.E{Key: "chat_user_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ChatOpsBindingColl) Create(binding *models.ChatOpsBinding) error {
+	binding.CreateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), binding)
+	return err
+}
+
+func (c *ChatOpsBindingColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
+
+func (c *ChatOpsBindingColl) Find(appID, chatUserID string) (*models.ChatOpsBinding, error) {
+	res := &models.ChatOpsBinding{}
+	err := c.FindOne(context.TODO(), bson.M{"app_id": appID, "chat_user_id": chatUserID}).Decode(res)
+	return res, err
+}
+
+func (c *ChatOpsBindingColl) List(appID string) ([]*models.ChatOpsBinding, error) {
+	res := make([]*models.ChatOpsBinding, 0)
+	query := bson.M{}
+	if appID != "" {
+		query["app_id"] = appID
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, err
+}
+
+func (c *ChatOpsBindingColl) DeleteByApp(appID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"app_id": appID})
+	return err
+}
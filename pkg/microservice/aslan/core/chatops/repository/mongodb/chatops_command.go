@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ChatOpsCommandColl struct {
+	*mongo.Collection
+	coll string
+}
+
+func NewChatOpsCommandColl() *ChatOpsCommandColl {
+	name := models.ChatOpsCommand{}.TableName()
+	return &ChatOpsCommandColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ChatOpsCommandColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ChatOpsCommandColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "app_id", Value: 1},
+			bson.E{Key: "code", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ChatOpsCommandColl) Create(command *models.ChatOpsCommand) error {
+	_, err := c.InsertOne(context.TODO(), command)
+	return err
+}
+
+// Consume removes the command and returns it, so that a command can only be confirmed once.
+func (c *ChatOpsCommandColl) Consume(appID, code, chatUserID string) (*models.ChatOpsCommand, error) {
+	query := bson.M{
+		"app_id":       appID,
+		"code":         code,
+		"chat_user_id": chatUserID,
+		"expire_time":  bson.M{"$gt": time.Now().Unix()},
+	}
+	res := &models.ChatOpsCommand{}
+	err := c.FindOneAndDelete(context.TODO(), query).Decode(res)
+	return res, err
+}
+
+func (c *ChatOpsCommandColl) DeleteExpired() error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"expire_time": bson.M{"$lte": time.Now().Unix()}})
+	return err
+}
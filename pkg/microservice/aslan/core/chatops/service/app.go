@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func validateApp(app *models.ChatOpsApp) error {
+	switch app.Type {
+	case config.AppTypeSlack:
+		if app.SigningSecret == "" || app.BotToken == "" {
+			return fmt.Errorf("signing secret and bot token are required for slack app")
+		}
+	case config.AppTypeLark:
+		if app.AppID == "" || app.AppSecret == "" || app.VerificationToken == "" {
+			return fmt.Errorf("app id, app secret and verification token are required for lark app")
+		}
+	default:
+		return fmt.Errorf("unsupported app type: %s", app.Type)
+	}
+	return nil
+}
+
+func CreateChatOpsApp(app *models.ChatOpsApp, logger *zap.SugaredLogger) error {
+	if err := validateApp(app); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := mongodb.NewChatOpsAppColl().Create(app); err != nil {
+		logger.Errorf("failed to create chatops app %s, err: %s", app.Name, err)
+		return e.ErrCreateChatOpsApp.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateChatOpsApp keeps the stored secrets if they are not given, since they are never returned by the list API.
+func UpdateChatOpsApp(id string, app *models.ChatOpsApp, logger *zap.SugaredLogger) error {
+	origin, err := mongodb.NewChatOpsAppColl().Get(id)
+	if err != nil {
+		return e.ErrUpdateChatOpsApp.AddErr(err)
+	}
+	if app.SigningSecret == "" {
+		app.SigningSecret = origin.SigningSecret
+	}
+	if app.BotToken == "" {
+		app.BotToken = origin.BotToken
+	}
+	if app.AppSecret == "" {
+		app.AppSecret = origin.AppSecret
+	}
+	if app.VerificationToken == "" {
+		app.VerificationToken = origin.VerificationToken
+	}
+	if err := validateApp(app); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := mongodb.NewChatOpsAppColl().Update(id, app); err != nil {
+		logger.Errorf("failed to update chatops app %s, err: %s", id, err)
+		return e.ErrUpdateChatOpsApp.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteChatOpsApp(id string, logger *zap.SugaredLogger) error {
+	if err := mongodb.NewChatOpsAppColl().Delete(id); err != nil {
+		logger.Errorf("failed to delete chatops app %s, err: %s", id, err)
+		return e.ErrDeleteChatOpsApp.AddErr(err)
+	}
+	if err := mongodb.NewChatOpsBindingColl().DeleteByApp(id); err != nil {
+		logger.Errorf("failed to delete bindings of chatops app %s, err: %s", id, err)
+		return e.ErrDeleteChatOpsApp.AddErr(err)
+	}
+	return nil
+}
+
+func ListChatOpsApps(logger *zap.SugaredLogger) ([]*models.ChatOpsApp, error) {
+	apps, err := mongodb.NewChatOpsAppColl().List()
+	if err != nil {
+		logger.Errorf("failed to list chatops apps, err: %s", err)
+		return nil, e.ErrListChatOpsApps.AddErr(err)
+	}
+	for _, app := range apps {
+		app.SigningSecret = ""
+		app.BotToken = ""
+		app.AppSecret = ""
+		app.VerificationToken = ""
+	}
+	return apps, nil
+}
+
+func CreateChatOpsBinding(binding *models.ChatOpsBinding, logger *zap.SugaredLogger) error {
+	if binding.AppID == "" || binding.ChatUserID == "" || binding.UserID == "" {
+		return e.ErrInvalidParam.AddDesc("app id, chat user id and user id are required")
+	}
+	if _, err := mongodb.NewChatOpsAppColl().Get(binding.AppID); err != nil {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("chatops app %s not found", binding.AppID))
+	}
+	if err := mongodb.NewChatOpsBindingColl().Create(binding); err != nil {
+		logger.Errorf("failed to create chatops binding for %s, err: %s", binding.ChatUserID, err)
+		return e.ErrCreateChatOpsBinding.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteChatOpsBinding(id string, logger *zap.SugaredLogger) error {
+	if err := mongodb.NewChatOpsBindingColl().Delete(id); err != nil {
+		logger.Errorf("failed to delete chatops binding %s, err: %s", id, err)
+		return e.ErrDeleteChatOpsBinding.AddErr(err)
+	}
+	return nil
+}
+
+func ListChatOpsBindings(appID string, logger *zap.SugaredLogger) ([]*models.ChatOpsBinding, error) {
+	bindings, err := mongodb.NewChatOpsBindingColl().List(appID)
+	if err != nil {
+		logger.Errorf("failed to list chatops bindings, err: %s", err)
+		return nil, e.ErrListChatOpsBindings.AddErr(err)
+	}
+	return bindings, nil
+}
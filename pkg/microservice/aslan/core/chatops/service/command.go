@@ -0,0 +1,332 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	aslanconfig "github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/mongodb"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	evaluationservice "github.com/koderover/zadig/pkg/microservice/picket/core/evaluation/service"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+const helpMessage = `Usage:
+  run <project> <workflow>                          trigger a workflow with its default args
+  rollback <workflow> <task-id>                     run a previous task again, e.g. redeploy the images of a deploy workflow
+  approve <workflow> <task-id> <stage> [comment]    approve a stage waiting for approval
+  reject <workflow> <task-id> <stage> [comment]     reject a stage waiting for approval
+  confirm <code>                                    confirm a pending run or rollback`
+
+const followInterval = 10 * time.Second
+
+// replier sends messages to the thread where a command is received.
+type replier interface {
+	Reply(text string) error
+	// Thread identifies the thread so that the replier can be restored when the command is confirmed.
+	Thread() string
+}
+
+type command struct {
+	action config.Action
+	args   []string
+}
+
+func parseCommand(text string) (*command, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return &command{action: config.ActionHelp}, nil
+	}
+	cmd := &command{action: config.Action(strings.ToLower(fields[0])), args: fields[1:]}
+	var minArgs int
+	switch cmd.action {
+	case config.ActionHelp:
+	case config.ActionConfirm:
+		minArgs = 1
+	case config.ActionRun, config.ActionRollback:
+		minArgs = 2
+	case config.ActionApprove, config.ActionReject:
+		minArgs = 3
+	default:
+		return nil, fmt.Errorf("unknown command %s", fields[0])
+	}
+	if len(cmd.args) < minArgs {
+		return nil, fmt.Errorf("command %s requires at least %d arguments", cmd.action, minArgs)
+	}
+	return cmd, nil
+}
+
+// handleCommand is run asynchronously since the IM platforms require the callback to return in seconds.
+func handleCommand(app *models.ChatOpsApp, chatUserID, text string, r replier, logger *zap.SugaredLogger) {
+	reply := func(format string, args ...interface{}) {
+		if err := r.Reply(fmt.Sprintf(format, args...)); err != nil {
+			logger.Errorf("failed to reply to chatops command, err: %s", err)
+		}
+	}
+
+	cmd, err := parseCommand(text)
+	if err != nil {
+		reply("%s\n\n%s", err, helpMessage)
+		return
+	}
+	if cmd.action == config.ActionHelp {
+		reply(helpMessage)
+		return
+	}
+
+	appID := app.ID.Hex()
+	binding, err := mongodb.NewChatOpsBindingColl().Find(appID, chatUserID)
+	if err != nil {
+		reply("your account %s is not bound to a zadig user, please contact the administrator", chatUserID)
+		return
+	}
+
+	if cmd.action == config.ActionConfirm {
+		pending, err := mongodb.NewChatOpsCommandColl().Consume(appID, cmd.args[0], chatUserID)
+		if err != nil {
+			reply("command %s not found or expired", cmd.args[0])
+			return
+		}
+		cmd = &command{action: pending.Action, args: pending.Args}
+		if restored, err := newReplier(app, pending.Thread); err == nil {
+			r = restored
+		}
+		if err := authorize(binding, cmd, logger); err != nil {
+			reply("%s", err)
+			return
+		}
+		executeCommand(binding, cmd, r, logger)
+		return
+	}
+
+	if err := authorize(binding, cmd, logger); err != nil {
+		reply("%s", err)
+		return
+	}
+	switch cmd.action {
+	case config.ActionApprove, config.ActionReject:
+		// approving is a confirmation itself
+		executeCommand(binding, cmd, r, logger)
+	default:
+		code, err := randomCode()
+		if err != nil {
+			reply("failed to create confirmation: %s", err)
+			return
+		}
+		// reply first so that the thread is started before it is saved
+		reply("you are going to %s %s as %s, reply `confirm %s` in %s to continue", cmd.action, strings.Join(cmd.args, " "), binding.UserName, code, config.ConfirmationTimeout)
+		if err := mongodb.NewChatOpsCommandColl().Create(&models.ChatOpsCommand{
+			Code:       code,
+			AppID:      appID,
+			ChatUserID: chatUserID,
+			Action:     cmd.action,
+			Args:       cmd.args,
+			Thread:     r.Thread(),
+			ExpireTime: time.Now().Add(config.ConfirmationTimeout).Unix(),
+		}); err != nil {
+			reply("failed to create confirmation: %s", err)
+		}
+	}
+}
+
+func executeCommand(binding *models.ChatOpsBinding, cmd *command, r replier, logger *zap.SugaredLogger) {
+	reply := func(format string, args ...interface{}) {
+		if err := r.Reply(fmt.Sprintf(format, args...)); err != nil {
+			logger.Errorf("failed to reply to chatops command, err: %s", err)
+		}
+	}
+
+	switch cmd.action {
+	case config.ActionRun, config.ActionRollback:
+		var args *commonmodels.WorkflowV4
+		var err error
+		if cmd.action == config.ActionRun {
+			args, err = workflowRunArgs(cmd.args[0], cmd.args[1])
+		} else {
+			args, err = workflowRollbackArgs(cmd.args[0], cmd.args[1], logger)
+		}
+		if err != nil {
+			reply("failed to %s: %s", cmd.action, err)
+			return
+		}
+		resp, err := workflowservice.CreateWorkflowTaskV4(binding.UserName, args, logger)
+		if err != nil {
+			reply("failed to %s: %s", cmd.action, err)
+			return
+		}
+		reply("task %s #%d is created: %s", resp.WorkflowName, resp.TaskID, taskURL(resp.ProjectName, resp.WorkflowName, resp.TaskID))
+		go followTask(resp.ProjectName, resp.WorkflowName, resp.TaskID, r, logger)
+	case config.ActionApprove, config.ActionReject:
+		taskID, err := strconv.ParseInt(cmd.args[1], 10, 64)
+		if err != nil {
+			reply("invalid task id %s", cmd.args[1])
+			return
+		}
+		approve := cmd.action == config.ActionApprove
+		comment := strings.Join(cmd.args[3:], " ")
+		if err := workflowservice.ApproveStage(cmd.args[0], cmd.args[2], binding.UserName, binding.UserID, comment, taskID, approve, logger); err != nil {
+			reply("failed to %s stage %s: %s", cmd.action, cmd.args[2], err)
+			return
+		}
+		reply("stage %s of task %s #%d is %sd by %s", cmd.args[2], cmd.args[0], taskID, cmd.action, binding.UserName)
+	}
+}
+
+func workflowRunArgs(project, workflowName string) (*commonmodels.WorkflowV4, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		return nil, fmt.Errorf("workflow %s not found", workflowName)
+	}
+	if workflow.Project != project {
+		return nil, fmt.Errorf("workflow %s not found in project %s", workflowName, project)
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if err := jobctl.SetPreset(job, workflow); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return workflow, nil
+}
+
+func workflowRollbackArgs(workflowName, taskIDStr string, logger *zap.SugaredLogger) (*commonmodels.WorkflowV4, error) {
+	taskID, err := strconv.ParseInt(taskIDStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task id %s", taskIDStr)
+	}
+	return workflowservice.CloneWorkflowTaskV4(workflowName, taskID, logger)
+}
+
+// authorize evaluates the permission of the bound user with the same policies as the REST API.
+func authorize(binding *models.ChatOpsBinding, cmd *command, logger *zap.SugaredLogger) error {
+	var project, workflowName, endpoint string
+	switch cmd.action {
+	case config.ActionRun:
+		project, workflowName = cmd.args[0], cmd.args[1]
+		endpoint = "/api/aslan/workflow/v4/workflowtask"
+	case config.ActionRollback:
+		workflowName = cmd.args[0]
+		endpoint = "/api/aslan/workflow/v4/workflowtask"
+	case config.ActionApprove, config.ActionReject:
+		workflowName = cmd.args[0]
+		endpoint = "/api/aslan/workflow/v4/workflowtask/approve"
+	default:
+		return nil
+	}
+	if project == "" {
+		workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+		if err != nil {
+			return fmt.Errorf("workflow %s not found", workflowName)
+		}
+		project = workflow.Project
+	}
+
+	token, err := userToken(binding)
+	if err != nil {
+		return fmt.Errorf("failed to authorize: %s", err)
+	}
+	header := http.Header{}
+	header.Set(setting.AuthorizationHeader, "Bearer "+token)
+	grants, err := evaluationservice.Evaluate(header, project, []evaluationservice.GrantReq{{EndPoint: endpoint, Method: http.MethodPost}}, logger)
+	if err != nil {
+		return fmt.Errorf("failed to authorize: %s", err)
+	}
+	if len(grants) == 0 || !grants[0].Allow {
+		return fmt.Errorf("permission denied, %s is not allowed to %s workflow %s", binding.UserName, cmd.action, workflowName)
+	}
+	return nil
+}
+
+// userToken issues a short-lived token of the bound user for the policy evaluation.
+func userToken(binding *models.ChatOpsBinding) (string, error) {
+	claims := jwt.MapClaims{
+		"name":               binding.UserName,
+		"uid":                binding.UserID,
+		"preferred_username": binding.UserName,
+		"exp":                time.Now().Add(time.Minute).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(configbase.SecretKey()))
+}
+
+// followTask replies the result of the task to the thread after it is completed.
+func followTask(project, workflowName string, taskID int64, r replier, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	timeout := time.After(config.FollowTimeout)
+	for {
+		select {
+		case <-timeout:
+			return
+		case <-ticker.C:
+		}
+		task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+		if err != nil {
+			logger.Errorf("failed to find task %s #%d, err: %s", workflowName, taskID, err)
+			return
+		}
+		switch task.Status {
+		case aslanconfig.StatusPassed, aslanconfig.StatusFailed, aslanconfig.StatusTimeout, aslanconfig.StatusCancelled, aslanconfig.StatusReject:
+			if err := r.Reply(fmt.Sprintf("task %s #%d is %s: %s", workflowName, taskID, task.Status, taskURL(project, workflowName, taskID))); err != nil {
+				logger.Errorf("failed to reply task result, err: %s", err)
+			}
+			return
+		}
+	}
+}
+
+func taskURL(project, workflowName string, taskID int64) string {
+	return fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.ExternalBaseURL(), project, workflowName, taskID)
+}
+
+func randomCode() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newReplier(app *models.ChatOpsApp, thread string) (replier, error) {
+	switch app.Type {
+	case config.AppTypeSlack:
+		parts := strings.SplitN(thread, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid slack thread %s", thread)
+		}
+		return &slackReplier{app: app, channel: parts[0], threadTS: parts[1]}, nil
+	case config.AppTypeLark:
+		return &larkReplier{app: app, messageID: thread}, nil
+	}
+	return nil, fmt.Errorf("unsupported app type: %s", app.Type)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	larkTenantTokenURL = "https://open.feishu.cn/open-apis/auth/v3/tenant_access_token/internal"
+	larkReplyURL       = "https://open.feishu.cn/open-apis/im/v1/messages/%s/reply"
+)
+
+var larkMentionRegex = regexp.MustCompile(`@_user_\d+`)
+
+type larkEvent struct {
+	// url verification request
+	Type      string `json:"type"`
+	Token     string `json:"token"`
+	Challenge string `json:"challenge"`
+	Encrypt   string `json:"encrypt"`
+
+	Header *struct {
+		Token     string `json:"token"`
+		EventType string `json:"event_type"`
+	} `json:"header"`
+	Event *struct {
+		Sender struct {
+			SenderID struct {
+				OpenID string `json:"open_id"`
+			} `json:"sender_id"`
+		} `json:"sender"`
+		Message struct {
+			MessageID   string `json:"message_id"`
+			MessageType string `json:"message_type"`
+			Content     string `json:"content"`
+		} `json:"message"`
+	} `json:"event"`
+}
+
+type LarkChallengeResp struct {
+	Challenge string `json:"challenge"`
+}
+
+// HandleLarkEvent handles the message events of a lark bot, only the 2.0 schema without encryption is supported.
+func HandleLarkEvent(appID string, body []byte, logger *zap.SugaredLogger) (*LarkChallengeResp, error) {
+	app, err := mongodb.NewChatOpsAppColl().Get(appID)
+	if err != nil || app.Type != config.AppTypeLark || !app.Enabled {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("lark app %s not found", appID))
+	}
+	event := &larkEvent{}
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	if event.Encrypt != "" {
+		return nil, e.ErrInvalidParam.AddDesc("encrypted events are not supported, please disable the encrypt key of the lark app")
+	}
+
+	if event.Type == "url_verification" {
+		if event.Token != app.VerificationToken {
+			return nil, e.ErrUnauthorized.AddDesc("verification token mismatch")
+		}
+		return &LarkChallengeResp{Challenge: event.Challenge}, nil
+	}
+	if event.Header == nil || event.Header.Token != app.VerificationToken {
+		return nil, e.ErrUnauthorized.AddDesc("verification token mismatch")
+	}
+	if event.Header.EventType != "im.message.receive_v1" || event.Event == nil || event.Event.Message.MessageType != "text" {
+		return nil, nil
+	}
+
+	content := &struct {
+		Text string `json:"text"`
+	}{}
+	if err := json.Unmarshal([]byte(event.Event.Message.Content), content); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	text := strings.TrimSpace(larkMentionRegex.ReplaceAllString(content.Text, ""))
+	r := &larkReplier{app: app, messageID: event.Event.Message.MessageID}
+	go handleCommand(app, event.Event.Sender.SenderID.OpenID, text, r, logger)
+	return nil, nil
+}
+
+type larkReplier struct {
+	app       *models.ChatOpsApp
+	messageID string
+}
+
+type larkResp struct {
+	Code              int    `json:"code"`
+	Msg               string `json:"msg"`
+	TenantAccessToken string `json:"tenant_access_token"`
+}
+
+func (r *larkReplier) Reply(text string) error {
+	tokenResp := &larkResp{}
+	if _, err := httpclient.Post(larkTenantTokenURL,
+		httpclient.SetBody(map[string]string{"app_id": r.app.AppID, "app_secret": r.app.AppSecret}),
+		httpclient.SetResult(tokenResp),
+	); err != nil {
+		return err
+	}
+	if tokenResp.Code != 0 {
+		return fmt.Errorf("failed to get lark tenant access token: %s", tokenResp.Msg)
+	}
+
+	content, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp := &larkResp{}
+	if _, err := httpclient.Post(fmt.Sprintf(larkReplyURL, r.messageID),
+		httpclient.SetHeader("Authorization", "Bearer "+tokenResp.TenantAccessToken),
+		httpclient.SetBody(map[string]string{"msg_type": "text", "content": string(content)}),
+		httpclient.SetResult(resp),
+	); err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("failed to reply lark message: %s", resp.Msg)
+	}
+	return nil
+}
+
+func (r *larkReplier) Thread() string {
+	return r.messageID
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// HandleSlackCommand handles a slash command, the reply is posted as a message so that
+// the following results can be replied in its thread.
+func HandleSlackCommand(appID string, header http.Header, body []byte, logger *zap.SugaredLogger) (*SlackCommandResp, error) {
+	app, err := mongodb.NewChatOpsAppColl().Get(appID)
+	if err != nil || app.Type != config.AppTypeSlack || !app.Enabled {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("slack app %s not found", appID))
+	}
+	if err := verifySlackRequest(app.SigningSecret, header, body); err != nil {
+		logger.Warnf("failed to verify slack request, err: %s", err)
+		return nil, e.ErrUnauthorized.AddErr(err)
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	r := &slackReplier{app: app, channel: values.Get("channel_id")}
+	go handleCommand(app, values.Get("user_id"), values.Get("text"), r, logger)
+	return &SlackCommandResp{ResponseType: "ephemeral", Text: "processing: " + values.Get("text")}, nil
+}
+
+type SlackCommandResp struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// see https://api.slack.com/authentication/verifying-requests-from-slack
+func verifySlackRequest(secret string, header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s", timestamp)
+	}
+	if math.Abs(float64(time.Now().Unix()-ts)) > 5*60 {
+		return fmt.Errorf("request is expired")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+type slackReplier struct {
+	app      *models.ChatOpsApp
+	channel  string
+	threadTS string
+}
+
+type slackPostMessageResp struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	TS    string `json:"ts"`
+}
+
+func (r *slackReplier) Reply(text string) error {
+	body := map[string]string{
+		"channel": r.channel,
+		"text":    text,
+	}
+	if r.threadTS != "" {
+		body["thread_ts"] = r.threadTS
+	}
+	resp := &slackPostMessageResp{}
+	if _, err := httpclient.Post(slackPostMessageURL,
+		httpclient.SetHeader("Authorization", "Bearer "+r.app.BotToken),
+		httpclient.SetBody(body),
+		httpclient.SetResult(resp),
+	); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("failed to post slack message: %s", resp.Error)
+	}
+	// the first message starts the thread
+	if r.threadTS == "" {
+		r.threadTS = resp.TS
+	}
+	return nil
+}
+
+func (r *slackReplier) Thread() string {
+	return r.channel + ":" + r.threadTS
+}
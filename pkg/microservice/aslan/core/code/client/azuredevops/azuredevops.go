@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type Config struct {
+	Address      string `json:"address"`
+	AccessToken  string `json:"access_token"`
+	Organization string `json:"azure_organization"`
+	Project      string `json:"azure_project"`
+	EnableProxy  bool   `json:"enable_proxy"`
+	ProxyURL     string `json:"proxy_url"`
+}
+
+type Client struct {
+	Client  *azuredevops.Client
+	project string
+}
+
+func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
+	organizationURL := strings.TrimSuffix(c.Address, "/") + "/" + c.Organization
+	return &Client{
+		Client:  azuredevops.NewClient(organizationURL, c.AccessToken, c.ProxyURL, c.EnableProxy),
+		project: c.Project,
+	}, nil
+}
+
+func (c *Client) ListBranches(opt client.ListOpt) ([]*client.Branch, error) {
+	branches, err := c.Client.ListBranches(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Branch
+	for _, b := range branches {
+		res = append(res, &client.Branch{
+			Name: b.Name,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListTags(opt client.ListOpt) ([]*client.Tag, error) {
+	tags, err := c.Client.ListTags(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Tag
+	for _, t := range tags {
+		res = append(res, &client.Tag{
+			Name: t.Name,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListPrs(opt client.ListOpt) ([]*client.PullRequest, error) {
+	prs, err := c.Client.ListPullRequests(opt.Namespace, opt.ProjectName, opt.TargeBr)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.PullRequest
+	for _, pr := range prs {
+		item := &client.PullRequest{
+			ID:           pr.PullRequestID,
+			Number:       pr.PullRequestID,
+			Title:        pr.Title,
+			State:        pr.Status,
+			TargetBranch: pr.TargetBranch(),
+			SourceBranch: pr.SourceBranch(),
+			CreatedAt:    pr.CreationDate.Unix(),
+		}
+		if pr.CreatedBy != nil {
+			item.AuthorUsername = pr.CreatedBy.UniqueName
+			item.User = pr.CreatedBy.DisplayName
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// ListNamespaces returns the azure devops projects, which hold the repositories. Only the project of the
+// codehost is returned if it is set.
+func (c *Client) ListNamespaces(keyword string) ([]*client.Namespace, error) {
+	if c.project != "" {
+		return []*client.Namespace{{Name: c.project, Path: c.project, Kind: client.GroupKind}}, nil
+	}
+	projects, err := c.Client.ListProjects(keyword)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Namespace
+	for _, p := range projects {
+		res = append(res, &client.Namespace{
+			Name:        p.Name,
+			Path:        p.Name,
+			Kind:        client.GroupKind,
+			ProjectUUID: p.ID,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
+	repos, err := c.Client.ListRepositories(opt.Namespace, opt.Key)
+	if err != nil {
+		return nil, e.ErrCodehostListProjects.AddDesc(err.Error())
+	}
+	var res []*client.Project
+	for _, repo := range repos {
+		project := &client.Project{
+			Name:          repo.Name,
+			DefaultBranch: strings.TrimPrefix(repo.DefaultBranch, "refs/heads/"),
+			RepoID:        repo.ID,
+		}
+		if repo.Project != nil {
+			project.Namespace = repo.Project.Name
+		}
+		res = append(res, project)
+	}
+	return res, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucketserver
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type Config struct {
+	Address     string `json:"address"`
+	AccessToken string `json:"access_token"`
+	EnableProxy bool   `json:"enable_proxy"`
+	ProxyURL    string `json:"proxy_url"`
+}
+
+type Client struct {
+	Client *bitbucketserver.Client
+}
+
+func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
+	return &Client{Client: bitbucketserver.NewClient(c.Address, c.AccessToken, c.ProxyURL, c.EnableProxy)}, nil
+}
+
+func (c *Client) ListBranches(opt client.ListOpt) ([]*client.Branch, error) {
+	branches, err := c.Client.ListBranches(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Branch
+	for _, b := range branches {
+		res = append(res, &client.Branch{
+			Name: b.DisplayID,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListTags(opt client.ListOpt) ([]*client.Tag, error) {
+	tags, err := c.Client.ListTags(opt.Namespace, opt.ProjectName, opt.Key)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Tag
+	for _, t := range tags {
+		res = append(res, &client.Tag{
+			Name: t.DisplayID,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListPrs(opt client.ListOpt) ([]*client.PullRequest, error) {
+	prs, err := c.Client.ListPullRequests(opt.Namespace, opt.ProjectName, opt.TargeBr)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.PullRequest
+	for _, pr := range prs {
+		item := &client.PullRequest{
+			ID:           pr.ID,
+			Number:       pr.ID,
+			Title:        pr.Title,
+			State:        pr.State,
+			TargetBranch: pr.ToRef.DisplayID,
+			SourceBranch: pr.FromRef.DisplayID,
+			CreatedAt:    pr.CreatedDate / 1000,
+			UpdatedAt:    pr.UpdatedDate / 1000,
+		}
+		if pr.Author != nil && pr.Author.User != nil {
+			item.AuthorUsername = pr.Author.User.Name
+			item.User = pr.Author.User.Name
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// ListNamespaces returns the bitbucket projects, which hold the repositories.
+func (c *Client) ListNamespaces(keyword string) ([]*client.Namespace, error) {
+	projects, err := c.Client.ListProjects(keyword, 0)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Namespace
+	for _, p := range projects {
+		res = append(res, &client.Namespace{
+			Name: p.Key,
+			Path: p.Key,
+			Kind: client.GroupKind,
+		})
+	}
+	return res, nil
+}
+
+func (c *Client) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
+	repos, err := c.Client.ListRepositories(opt.Namespace, opt.Key, 0)
+	if err != nil {
+		return nil, e.ErrCodehostListProjects.AddDesc(err.Error())
+	}
+	var res []*client.Project
+	for _, repo := range repos {
+		project := &client.Project{
+			ID:   repo.ID,
+			Name: repo.Slug,
+		}
+		if repo.Project != nil {
+			project.Namespace = repo.Project.Key
+		}
+		res = append(res, project)
+	}
+	return res, nil
+}
@@ -19,7 +19,6 @@ package codehub
 import (
 	"go.uber.org/zap"
 
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
 	"github.com/koderover/zadig/pkg/tool/codehub"
 	e "github.com/koderover/zadig/pkg/tool/errors"
@@ -30,6 +29,7 @@ type Config struct {
 	AccessKey   string `json:"application_id"`
 	SecretKey   string `json:"client_secret"`
 	EnableProxy bool   `json:"enable_proxy"`
+	ProxyURL    string `json:"proxy_url"`
 }
 
 type Client struct {
@@ -37,7 +37,7 @@ type Client struct {
 }
 
 func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
-	codehubClient := codehub.NewCodeHubClient(c.AccessKey, c.SecretKey, c.Region, config.ProxyHTTPSAddr(), c.EnableProxy)
+	codehubClient := codehub.NewCodeHubClient(c.AccessKey, c.SecretKey, c.Region, c.ProxyURL, c.EnableProxy)
 	return &Client{Client: codehubClient}, nil
 }
 
@@ -17,7 +17,6 @@ limitations under the License.
 package gerrit
 
 import (
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
@@ -31,6 +30,7 @@ type Config struct {
 	AccessToken string `json:"access_token"`
 	AccessKey   string `json:"application_id"`
 	EnableProxy bool   `json:"enable_proxy"`
+	ProxyURL    string `json:"proxy_url"`
 }
 
 type Client struct {
@@ -38,7 +38,7 @@ type Client struct {
 }
 
 func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
-	gerritClient := gerrit.NewClient(c.Address, c.AccessToken, config.ProxyHTTPSAddr(), c.EnableProxy)
+	gerritClient := gerrit.NewClient(c.Address, c.AccessToken, c.ProxyURL, c.EnableProxy)
 	return &Client{Client: gerritClient}, nil
 }
 
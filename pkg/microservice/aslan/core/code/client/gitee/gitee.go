@@ -23,7 +23,6 @@ import (
 	"github.com/antihax/optional"
 	"go.uber.org/zap"
 
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
 	"github.com/koderover/zadig/pkg/tool/gitee"
 )
@@ -31,6 +30,7 @@ import (
 type Config struct {
 	AccessToken string `json:"access_token"`
 	EnableProxy bool   `json:"enable_proxy"`
+	ProxyURL    string `json:"proxy_url"`
 }
 
 type Client struct {
@@ -39,7 +39,7 @@ type Client struct {
 }
 
 func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
-	client := gitee.NewClient(id, c.AccessToken, config.ProxyHTTPSAddr(), c.EnableProxy)
+	client := gitee.NewClient(id, c.AccessToken, c.ProxyURL, c.EnableProxy)
 	return &Client{
 		Client:      client,
 		AccessToken: c.AccessToken,
@@ -22,7 +22,6 @@ import (
 	github2 "github.com/google/go-github/v35/github"
 	"go.uber.org/zap"
 
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
 	"github.com/koderover/zadig/pkg/tool/git/github"
 )
@@ -30,6 +29,7 @@ import (
 type Config struct {
 	AccessToken string `json:"access_token"`
 	EnableProxy bool   `json:"enable_proxy"`
+	ProxyURL    string `json:"proxy_url"`
 }
 
 type Client struct {
@@ -42,7 +42,7 @@ func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient,
 		AccessToken: c.AccessToken,
 	}
 	if c.EnableProxy {
-		cfg.Proxy = config.ProxyHTTPSAddr()
+		cfg.Proxy = c.ProxyURL
 	}
 	return &Client{
 		Client: github.NewClient(cfg),
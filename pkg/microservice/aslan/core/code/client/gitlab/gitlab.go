@@ -21,7 +21,6 @@ import (
 
 	gogitlab "github.com/xanzy/go-gitlab"
 
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/git/gitlab"
@@ -31,7 +30,8 @@ type Config struct {
 	Address     string `json:"address"`
 	AccessToken string `json:"access_token"`
 	// the field determine whether the proxy is enabled
-	EnableProxy bool `json:"enable_proxy"`
+	EnableProxy bool   `json:"enable_proxy"`
+	ProxyURL    string `json:"proxy_url"`
 }
 
 type Client struct {
@@ -40,7 +40,7 @@ type Client struct {
 
 func (c *Config) Open(id int, logger *zap.SugaredLogger) (client.CodeHostClient, error) {
 
-	client, err := gitlab.NewClient(id, c.Address, c.AccessToken, config.ProxyHTTPSAddr(), c.EnableProxy)
+	client, err := gitlab.NewClient(id, c.Address, c.AccessToken, c.ProxyURL, c.EnableProxy)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package open
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/cache"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	// browseCacheTTL is short so that the new repos and branches show up soon.
+	browseCacheTTL      = time.Minute
+	rateLimitRetries    = 3
+	rateLimitBackoff    = time.Second
+	maxRateLimitBackoff = 10 * time.Second
+)
+
+// cachedClient caches the namespaces, repos, branches and tags listed from the provider for a short time,
+// and retries the requests rate limited by the provider with backoff. The keys include the update time of
+// the codehost, so the lists are not reused after the codehost is changed.
+type cachedClient struct {
+	client.CodeHostClient
+	prefix string
+}
+
+func newCachedClient(ch *systemconfig.CodeHost, cli client.CodeHostClient) client.CodeHostClient {
+	return &cachedClient{CodeHostClient: cli, prefix: fmt.Sprintf("codehost-browse:%d:%d", ch.ID, ch.UpdatedAt)}
+}
+
+func (c *cachedClient) key(kind string, args ...interface{}) string {
+	return fmt.Sprintf("%s:%s:%s", c.prefix, kind, cache.Hash(args...))
+}
+
+func (c *cachedClient) ListBranches(opt client.ListOpt) ([]*client.Branch, error) {
+	return cache.Load(c.key("branches", opt), browseCacheTTL, func() ([]*client.Branch, error) {
+		return withBackoff(func() ([]*client.Branch, error) { return c.CodeHostClient.ListBranches(opt) })
+	})
+}
+
+func (c *cachedClient) ListTags(opt client.ListOpt) ([]*client.Tag, error) {
+	return cache.Load(c.key("tags", opt), browseCacheTTL, func() ([]*client.Tag, error) {
+		return withBackoff(func() ([]*client.Tag, error) { return c.CodeHostClient.ListTags(opt) })
+	})
+}
+
+// ListPrs is not cached, the pull requests are expected to be up to date.
+func (c *cachedClient) ListPrs(opt client.ListOpt) ([]*client.PullRequest, error) {
+	return withBackoff(func() ([]*client.PullRequest, error) { return c.CodeHostClient.ListPrs(opt) })
+}
+
+func (c *cachedClient) ListNamespaces(keyword string) ([]*client.Namespace, error) {
+	return cache.Load(c.key("namespaces", keyword), browseCacheTTL, func() ([]*client.Namespace, error) {
+		return withBackoff(func() ([]*client.Namespace, error) { return c.CodeHostClient.ListNamespaces(keyword) })
+	})
+}
+
+func (c *cachedClient) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
+	return cache.Load(c.key("projects", opt), browseCacheTTL, func() ([]*client.Project, error) {
+		return withBackoff(func() ([]*client.Project, error) { return c.CodeHostClient.ListProjects(opt) })
+	})
+}
+
+// withBackoff retries the rate limited requests. The wait given by the provider is respected, and the
+// error is returned at once if it is longer than maxRateLimitBackoff, so a request never hangs until
+// a far reset.
+func withBackoff[T any](list func() (T, error)) (T, error) {
+	backoff := rateLimitBackoff
+	for retry := 0; ; retry++ {
+		resp, err := list()
+		wait, limited := rateLimitWait(err)
+		if !limited || retry == rateLimitRetries {
+			return resp, err
+		}
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		if wait > maxRateLimitBackoff {
+			return resp, err
+		}
+		log.Warnf("rate limited by the codehost, retry in %s, err: %s", wait, err)
+		time.Sleep(wait)
+	}
+}
+
+// rateLimitWait reports whether the error is caused by the rate limit of the provider, and how long to wait
+// before the next request if the provider tells.
+func rateLimitWait(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return time.Until(rateLimitErr.Rate.Reset.Time), true
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return *abuseErr.RetryAfter, true
+		}
+		return 0, true
+	}
+	var gitlabErr *gitlab.ErrorResponse
+	if errors.As(err, &gitlabErr) && gitlabErr.Response != nil {
+		if gitlabErr.Response.StatusCode != http.StatusTooManyRequests {
+			return 0, false
+		}
+		seconds, _ := strconv.Atoi(gitlabErr.Response.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, true
+	}
+	// the other clients return the errors of the provider as messages.
+	msg := strings.ToLower(err.Error())
+	return 0, strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+}
@@ -22,7 +22,10 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client/azuredevops"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client/bitbucketserver"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client/codehub"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client/gerrit"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client/gitee"
@@ -37,13 +40,17 @@ type ClientConfig interface {
 }
 
 var ClientsConfig = map[string]func() ClientConfig{
-	setting.SourceFromGitlab:  func() ClientConfig { return new(gitlab.Config) },
-	setting.SourceFromGithub:  func() ClientConfig { return new(github.Config) },
-	setting.SourceFromGerrit:  func() ClientConfig { return new(gerrit.Config) },
-	setting.SourceFromCodeHub: func() ClientConfig { return new(codehub.Config) },
-	setting.SourceFromGitee:   func() ClientConfig { return new(gitee.Config) },
+	setting.SourceFromGitlab:          func() ClientConfig { return new(gitlab.Config) },
+	setting.SourceFromGithub:          func() ClientConfig { return new(github.Config) },
+	setting.SourceFromGerrit:          func() ClientConfig { return new(gerrit.Config) },
+	setting.SourceFromCodeHub:         func() ClientConfig { return new(codehub.Config) },
+	setting.SourceFromGitee:           func() ClientConfig { return new(gitee.Config) },
+	setting.SourceFromBitbucketServer: func() ClientConfig { return new(bitbucketserver.Config) },
+	setting.SourceFromAzureDevOps:     func() ClientConfig { return new(azuredevops.Config) },
 }
 
+// OpenClient opens the client of the codehost, the proxy of the codehost and the system proxy are resolved into
+// EnableProxy and ProxyURL of the client config, so the clients use ProxyURL as is.
 func OpenClient(ch *systemconfig.CodeHost, log *zap.SugaredLogger) (client.CodeHostClient, error) {
 	var c client.CodeHostClient
 	f, ok := ClientsConfig[ch.Type]
@@ -52,7 +59,10 @@ func OpenClient(ch *systemconfig.CodeHost, log *zap.SugaredLogger) (client.CodeH
 		return c, fmt.Errorf("unknow codehost type")
 	}
 	clientConfig := f()
-	bs, err := json.Marshal(ch)
+	resolved := *ch
+	resolved.EnableProxy = ch.UseProxy()
+	resolved.ProxyURL = ch.ProxyAddr(config.ProxyHTTPSAddr())
+	bs, err := json.Marshal(&resolved)
 	if err != nil {
 		return nil, err
 	}
@@ -60,5 +70,9 @@ func OpenClient(ch *systemconfig.CodeHost, log *zap.SugaredLogger) (client.CodeH
 		log.Errorf("marsh err:%s", err)
 		return nil, err
 	}
-	return clientConfig.Open(ch.ID, log)
+	cli, err := clientConfig.Open(ch.ID, log)
+	if err != nil {
+		return nil, err
+	}
+	return newCachedClient(ch, cli), nil
 }
@@ -17,12 +17,14 @@ limitations under the License.
 package handler
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/service"
+	tenantservice "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
@@ -36,17 +38,70 @@ func GetCodeHostList(c *gin.Context) {
 	codeHostSlice := make([]*systemconfig.CodeHost, 0)
 	codeHosts, err := systemconfig.New().ListCodeHostsInternal()
 	ctx.Err = err
+	scope, err := tenantservice.GetScope(ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = e.ErrListTenant.AddErr(err)
+		return
+	}
+	codeHostScope, err := service.GetCodeHostScope(ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = e.ErrListCodehost.AddErr(err)
+		return
+	}
 	for _, codeHost := range codeHosts {
-		codeHost.AccessToken = setting.MaskValue
-		codeHost.AccessKey = setting.MaskValue
-		codeHost.SecretKey = setting.MaskValue
-		codeHost.Password = setting.MaskValue
+		if !scope.CodeHostVisible(codeHost.ID) {
+			continue
+		}
+		if !codeHostScope.Visible(codeHost, c.Query("projectName")) {
+			continue
+		}
+		maskCodeHost(codeHost)
 
 		codeHostSlice = append(codeHostSlice, codeHost)
 	}
 	ctx.Resp = codeHostSlice
 }
 
+func GetCodeHost(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	codehostID, err := strconv.Atoi(c.Param("codehostId"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	codeHost, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		ctx.Err = e.ErrGetCodehost.AddErr(err)
+		return
+	}
+	scope, err := tenantservice.GetScope(ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = e.ErrListTenant.AddErr(err)
+		return
+	}
+	codeHostScope, err := service.GetCodeHostScope(ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = e.ErrGetCodehost.AddErr(err)
+		return
+	}
+	if !scope.CodeHostVisible(codeHost.ID) || !codeHostScope.Visible(codeHost, c.Query("projectName")) {
+		ctx.Err = e.ErrForbidden.AddDesc(fmt.Sprintf("codehost %d is not visible to the user", codehostID))
+		return
+	}
+	maskCodeHost(codeHost)
+	ctx.Resp = codeHost
+}
+
+// maskCodeHost hides the credentials of the codehost returned to the users.
+func maskCodeHost(codeHost *systemconfig.CodeHost) {
+	codeHost.AccessToken = setting.MaskValue
+	codeHost.AccessKey = setting.MaskValue
+	codeHost.SecretKey = setting.MaskValue
+	codeHost.Password = setting.MaskValue
+}
+
 func CodeHostGetNamespaceList(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -59,6 +114,9 @@ func CodeHostGetNamespaceList(c *gin.Context) {
 		return
 	}
 	chID, _ := strconv.Atoi(codehostID)
+	if ctx.Err = service.CheckCodehostAvailable(chID, c.Query("projectName"), ctx.Logger); ctx.Err != nil {
+		return
+	}
 	ctx.Resp, ctx.Err = service.CodeHostListNamespaces(chID, keyword, ctx.Logger)
 }
 
@@ -100,6 +158,9 @@ func CodeHostGetProjectsList(c *gin.Context) {
 	}
 
 	chID, _ := strconv.Atoi(codehostID)
+	if ctx.Err = service.CheckCodehostAvailable(chID, c.Query("projectName"), ctx.Logger); ctx.Err != nil {
+		return
+	}
 	projects, err := service.CodeHostListProjects(
 		chID,
 		strings.Replace(repoOwner, "%2F", "/", -1),
@@ -154,6 +215,9 @@ func CodeHostGetBranchList(c *gin.Context) {
 	}
 
 	chID, _ := strconv.Atoi(codehostID)
+	if ctx.Err = service.CheckCodehostAvailable(chID, c.Query("projectName"), ctx.Logger); ctx.Err != nil {
+		return
+	}
 	ctx.Resp, ctx.Err = service.CodeHostListBranches(
 		chID,
 		repoName,
@@ -190,6 +254,9 @@ func CodeHostGetTagList(c *gin.Context) {
 	}
 
 	chID, _ := strconv.Atoi(codehostID)
+	if ctx.Err = service.CheckCodehostAvailable(chID, c.Query("projectName"), ctx.Logger); ctx.Err != nil {
+		return
+	}
 	ctx.Resp, ctx.Err = service.CodeHostListTags(chID, repoName, strings.Replace(repoOwner, "%2F", "/", -1), args.Key, args.Page, args.PerPage, ctx.Logger)
 }
 
@@ -223,6 +290,9 @@ func CodeHostGetPRList(c *gin.Context) {
 	targetBr := c.Query("targetBranch")
 
 	chID, _ := strconv.Atoi(codehostID)
+	if ctx.Err = service.CheckCodehostAvailable(chID, c.Query("projectName"), ctx.Logger); ctx.Err != nil {
+		return
+	}
 	ctx.Resp, ctx.Err = service.CodeHostListPRs(chID, repoName, strings.Replace(repoOwner, "%2F", "/", -1), targetBr, args.Key, args.Page, args.PerPage, ctx.Logger)
 }
 
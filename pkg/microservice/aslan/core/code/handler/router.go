@@ -26,6 +26,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	codehost := router.Group("codehost")
 	{
 		codehost.GET("", GetCodeHostList)
+		codehost.GET("/:codehostId", GetCodeHost)
 		codehost.GET("/:codehostId/namespaces", CodeHostGetNamespaceList)
 		codehost.GET("/:codehostId/projects", CodeHostGetProjectsList)
 		codehost.GET("/:codehostId/branches", CodeHostGetBranchList)
@@ -35,6 +36,19 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		codehost.POST("/branches/regular/check", MatchBranchesList)
 	}
 
+	// bare mirrors of the repos kept by zadig, the builds fetch from them if the codehosts are unreliable
+	mirror := router.Group("mirror")
+	{
+		mirror.GET("/setting", GetGitMirrorSetting)
+		mirror.PUT("/setting", UpdateGitMirrorSetting)
+		mirror.GET("", ListGitMirrors)
+		mirror.POST("", CreateGitMirror)
+		mirror.DELETE("/:id", DeleteGitMirror)
+		mirror.POST("/:id/sync", SyncGitMirror)
+		mirror.GET("/git/*path", ServeGitMirror)
+		mirror.POST("/git/*path", ServeGitMirror)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// Pipeline workspace 管理接口
 	// ---------------------------------------------------------------------------------------
@@ -34,6 +34,10 @@ func CodeHostListBranches(codeHostID int, projectName, namespace, key string, pa
 	if ch.Type == setting.SourceFromOther {
 		return []*client.Branch{}, nil
 	}
+	if err := checkRepoAllowed(ch, namespace, projectName); err != nil {
+		log.Warn(err)
+		return nil, err
+	}
 	cli, err := open.OpenClient(ch, log)
 	if err != nil {
 		log.Errorf("open client err:%s", err)
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	policyservice "github.com/koderover/zadig/pkg/microservice/policy/core/service"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+)
+
+// CodeHostScope holds the projects of a user, only the codehosts shared or bound to one of them are
+// visible to the user. A nil scope sees all the codehosts, which is the case for the system admins
+// and the internal requests without a user.
+type CodeHostScope struct {
+	projects sets.String
+}
+
+func GetCodeHostScope(uid string, log *zap.SugaredLogger) (*CodeHostScope, error) {
+	if uid == "" {
+		return nil, nil
+	}
+	projects, isAdmin, err := policyservice.ListUserProjects(uid)
+	if err != nil {
+		log.Errorf("failed to list the projects of user %s, err: %s", uid, err)
+		return nil, err
+	}
+	if isAdmin {
+		return nil, nil
+	}
+	return &CodeHostScope{projects: sets.NewString(projects...)}, nil
+}
+
+// Visible reports whether the codehost is visible to the user, it must also be available to the
+// project if the project is specified.
+func (s *CodeHostScope) Visible(ch *systemconfig.CodeHost, projectName string) bool {
+	if projectName != "" {
+		return (s == nil || s.projects.Has(projectName)) && ch.AvailableToProject(projectName)
+	}
+	return s == nil || ch.AvailableToAnyProject(s.projects.List())
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitmirror"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetGitMirrorSetting(log *zap.SugaredLogger) (*commonmodels.GitMirrorSetting, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get system settings, err: %s", err)
+		return nil, e.ErrGetGitMirrorSetting.AddErr(err)
+	}
+	if sysSetting.GitMirror == nil {
+		return &commonmodels.GitMirrorSetting{}, nil
+	}
+	return sysSetting.GitMirror, nil
+}
+
+func UpdateGitMirrorSetting(args *commonmodels.GitMirrorSetting, log *zap.SugaredLogger) error {
+	if args.BaseURL != "" {
+		u, err := url.Parse(args.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return e.ErrUpdateGitMirrorSetting.AddDesc(fmt.Sprintf("base url %s must be an absolute http or https url", args.BaseURL))
+		}
+	}
+	if args.SyncInterval < 0 {
+		return e.ErrUpdateGitMirrorSetting.AddDesc("sync interval must not be negative")
+	}
+
+	if err := commonrepo.NewSystemSettingColl().UpdateGitMirrorSetting(args); err != nil {
+		log.Errorf("failed to update the git mirror setting, err: %s", err)
+		return e.ErrUpdateGitMirrorSetting.AddErr(err)
+	}
+	return nil
+}
+
+func ListGitMirrors(log *zap.SugaredLogger) ([]*commonmodels.GitMirror, error) {
+	resp, err := commonrepo.NewGitMirrorColl().List("", "")
+	if err != nil {
+		log.Errorf("failed to list git mirrors, err: %s", err)
+		return nil, e.ErrListGitMirrors.AddErr(err)
+	}
+	return resp, nil
+}
+
+// CreateGitMirror adds the repo to the mirrored ones, the first sync runs in the background and the
+// builds keep fetching from the codehost until it succeeds.
+func CreateGitMirror(username string, args *commonmodels.GitMirror, log *zap.SugaredLogger) (*commonmodels.GitMirror, error) {
+	if args.CodehostID == 0 || args.RepoName == "" || args.GetRepoNamespace() == "" {
+		return nil, e.ErrCreateGitMirror.AddDesc("codehost, namespace and name of the repo are required")
+	}
+	if _, err := systemconfig.New().GetCodeHost(args.CodehostID); err != nil {
+		return nil, e.ErrCreateGitMirror.AddErr(err)
+	}
+
+	mirror := &commonmodels.GitMirror{
+		CodehostID:    args.CodehostID,
+		RepoOwner:     args.RepoOwner,
+		RepoNamespace: args.GetRepoNamespace(),
+		RepoName:      args.RepoName,
+		Status:        gitmirror.StatusSyncing,
+		CreatedBy:     username,
+		CreateTime:    time.Now().Unix(),
+	}
+	if err := commonrepo.NewGitMirrorColl().Create(mirror); err != nil {
+		log.Errorf("failed to create the mirror of %s/%s, err: %s", mirror.RepoNamespace, mirror.RepoName, err)
+		return nil, e.ErrCreateGitMirror.AddErr(err)
+	}
+	gitmirror.SyncAsync(mirror)
+	return mirror, nil
+}
+
+func DeleteGitMirror(id string, log *zap.SugaredLogger) error {
+	mirror, err := commonrepo.NewGitMirrorColl().Get(id)
+	if err != nil {
+		return e.ErrDeleteGitMirror.AddErr(err)
+	}
+	if err := commonrepo.NewGitMirrorColl().Delete(id); err != nil {
+		log.Errorf("failed to delete the mirror %s, err: %s", id, err)
+		return e.ErrDeleteGitMirror.AddErr(err)
+	}
+	if err := gitmirror.Remove(mirror); err != nil {
+		log.Warnf("failed to remove the mirrored repo %s, err: %s", id, err)
+	}
+	return nil
+}
+
+// SyncGitMirror syncs the mirror right away, the error of the sync is returned.
+func SyncGitMirror(id string, log *zap.SugaredLogger) error {
+	mirror, err := commonrepo.NewGitMirrorColl().Get(id)
+	if err != nil {
+		return e.ErrSyncGitMirror.AddErr(err)
+	}
+	if err := gitmirror.Sync(mirror, log); err != nil {
+		return e.ErrSyncGitMirror.AddErr(err)
+	}
+	return nil
+}
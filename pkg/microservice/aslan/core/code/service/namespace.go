@@ -17,6 +17,8 @@ limitations under the License.
 package service
 
 import (
+	"fmt"
+
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
@@ -54,5 +56,47 @@ func CodeHostListNamespaces(codeHostID int, keyword string, log *zap.SugaredLogg
 		log.Errorf("list namespace err:%s", err)
 		return nil, err
 	}
-	return ns, nil
+	if ch.RepoPolicy == nil {
+		return ns, nil
+	}
+
+	allowed := make([]*client.Namespace, 0, len(ns))
+	for _, n := range ns {
+		if ch.RepoPolicy.NamespaceAllowed(namespacePath(n)) {
+			allowed = append(allowed, n)
+		}
+	}
+	return allowed, nil
+}
+
+func namespacePath(n *client.Namespace) string {
+	if n.Path != "" {
+		return n.Path
+	}
+	return n.Name
+}
+
+// CheckCodehostAvailable rejects the requests of a project to a codehost bound to other projects,
+// nothing is checked if the project is not specified.
+func CheckCodehostAvailable(codeHostID int, projectName string, log *zap.SugaredLogger) error {
+	if projectName == "" {
+		return nil
+	}
+	ch, err := systemconfig.New().GetCodeHost(codeHostID)
+	if err != nil {
+		log.Errorf("get code host info err:%s", err)
+		return err
+	}
+	if !ch.AvailableToProject(projectName) {
+		return fmt.Errorf("codehost %d is not available to project %s", codeHostID, projectName)
+	}
+	return nil
+}
+
+// checkRepoAllowed rejects the repos excluded by the repo policy of the codehost.
+func checkRepoAllowed(ch *systemconfig.CodeHost, namespace, repo string) error {
+	if !ch.RepoPolicy.RepoAllowed(namespace, repo) {
+		return fmt.Errorf("repo %s/%s is not allowed by the policy of codehost %d", namespace, repo, ch.ID)
+	}
+	return nil
 }
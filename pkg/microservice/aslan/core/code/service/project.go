@@ -50,5 +50,15 @@ func CodeHostListProjects(codeHostID int, namespace, namespaceType string, page,
 		log.Errorf("list projects err:%s", err)
 		return nil, err
 	}
-	return projects, nil
+	if ch.RepoPolicy == nil {
+		return projects, nil
+	}
+
+	allowed := make([]*client.Project, 0, len(projects))
+	for _, project := range projects {
+		if ch.RepoPolicy.RepoAllowed(namespace, project.Name) {
+			allowed = append(allowed, project)
+		}
+	}
+	return allowed, nil
 }
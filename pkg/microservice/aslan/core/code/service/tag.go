@@ -34,6 +34,10 @@ func CodeHostListTags(codeHostID int, projectName string, namespace string, key
 	if ch.Type == setting.SourceFromOther {
 		return []*client.Tag{}, nil
 	}
+	if err := checkRepoAllowed(ch, namespace, projectName); err != nil {
+		log.Warn(err)
+		return nil, err
+	}
 	cli, err := open.OpenClient(ch, log)
 	if err != nil {
 		log.Errorf("open client err:%s", err)
@@ -44,7 +48,7 @@ func CodeHostListTags(codeHostID int, projectName string, namespace string, key
 		ProjectName: projectName,
 		Key:         key,
 		Page:        page,
-		PerPage:     page,
+		PerPage:     perPage,
 	})
 	if err != nil {
 		log.Errorf("list tags err:%s", err)
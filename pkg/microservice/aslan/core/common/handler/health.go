@@ -16,7 +16,11 @@ limitations under the License.
 
 package handler
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
 
 func Health(c *gin.Context) {
 	resp := gin.H{"message": "success"}
@@ -32,3 +36,9 @@ func Health(c *gin.Context) {
 
 	c.JSON(200, resp)
 }
+
+// ClientHealth returns the circuit breakers of the services called by aslan, an open breaker
+// means the service has been unavailable recently.
+func ClientHealth(c *gin.Context) {
+	c.JSON(200, httpclient.ListBreakerStatus())
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AttachedTrigger is a webhook trigger deduplicated to a running workflow task, the status of the
+// task is also reported to the pull request of the trigger.
+type AttachedTrigger struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	WorkflowName   string             `bson:"workflow_name"   json:"workflow_name"`
+	TaskID         int64              `bson:"task_id"         json:"task_id"`
+	HookPayload    *HookPayload       `bson:"hook_payload"    json:"hook_payload"`
+	NotificationID string             `bson:"notification_id" json:"notification_id"`
+	CreateTime     int64              `bson:"create_time"     json:"create_time"`
+}
+
+func (AttachedTrigger) TableName() string {
+	return "attached_trigger"
+}
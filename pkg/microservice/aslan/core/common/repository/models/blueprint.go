@@ -0,0 +1,46 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Blueprint is the golden path of the new services maintained by the platform team, a repository is
+// generated from the template repository and onboarded to a project together with a dev environment.
+type Blueprint struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"        json:"id,omitempty"`
+	Name        string             `bson:"name"                 json:"name"`
+	Description string             `bson:"description"          json:"description"`
+	// the template repository is a template repository of github or a project of gitlab.
+	CodehostID        int    `bson:"codehost_id"          json:"codehost_id"`
+	TemplateNamespace string `bson:"template_namespace"   json:"template_namespace"`
+	TemplateRepo      string `bson:"template_repo"        json:"template_repo"`
+	Branch            string `bson:"branch"               json:"branch"`
+	// no environment is provisioned if EnvName is empty.
+	EnvName    string `bson:"env_name"             json:"env_name"`
+	ClusterID  string `bson:"cluster_id"           json:"cluster_id"`
+	RegistryID string `bson:"registry_id"          json:"registry_id"`
+	CreatedBy  string `bson:"created_by"           json:"created_by"`
+	CreateTime int64  `bson:"create_time"          json:"create_time"`
+	UpdatedBy  string `bson:"updated_by"           json:"updated_by"`
+	UpdateTime int64  `bson:"update_time"          json:"update_time"`
+}
+
+func (Blueprint) TableName() string {
+	return "blueprint"
+}
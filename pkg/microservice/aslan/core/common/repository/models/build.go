@@ -21,6 +21,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
 )
 
 type Build struct {
@@ -56,6 +57,9 @@ type Build struct {
 	CacheUserDir string             `bson:"cache_user_dir" json:"cache_user_dir"`
 	// New since V1.10.0. Only to tell the webpage should the advanced settings be displayed
 	AdvancedSettingsModified bool `bson:"advanced_setting_modified" json:"advanced_setting_modified"`
+
+	// TemplateOverride overrides the sections inherited from the build template, it is only used if TemplateID is set.
+	TemplateOverride *BuildTemplateOverride `bson:"template_override,omitempty" json:"template_override,omitempty"`
 }
 
 // PreBuild prepares an environment for a job
@@ -83,6 +87,14 @@ type PreBuild struct {
 
 	// TODO: Deprecated.
 	Namespace string `bson:"namespace"                       json:"namespace"`
+	// Runner runs the build outside kubernetes, ClusterID is ignored if it is set.
+	Runner string `bson:"runner,omitempty" json:"runner,omitempty"`
+	// RunnerLabels runs the build on any runner which has all the labels if Runner is empty.
+	RunnerLabels []string `bson:"runner_labels,omitempty" json:"runner_labels,omitempty"`
+	// Downloads are the external artifacts downloaded into the workspace before the build scripts run.
+	Downloads []*step.ArtifactDownload `bson:"downloads,omitempty" json:"downloads,omitempty"`
+	// VariableGroups are the names of the variable groups in the project which are injected before the Envs.
+	VariableGroups []string `bson:"variable_groups,omitempty" json:"variable_groups,omitempty"`
 }
 
 type BuildObj struct {
@@ -126,6 +138,8 @@ type DockerBuild struct {
 	TemplateID string `bson:"template_id"            json:"template_id"`
 	// TemplateName is the name of the template dockerfile
 	TemplateName string `bson:"template_name"        json:"template_name"`
+	// TemplateRevision is the revision of the template dockerfile used by the build, 0 means the latest
+	TemplateRevision int64 `bson:"template_revision,omitempty" json:"template_revision,omitempty"`
 }
 
 type JenkinsBuild struct {
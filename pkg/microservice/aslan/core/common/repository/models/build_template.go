@@ -45,3 +45,20 @@ type BuildTemplate struct {
 func (BuildTemplate) TableName() string {
 	return "build_template"
 }
+
+// BuildTemplateOverride are the override points of the builds inheriting a build template, the empty
+// fields inherit the template.
+type BuildTemplateOverride struct {
+	// base image
+	BuildOS   string `bson:"build_os,omitempty"   json:"build_os,omitempty"`
+	ImageFrom string `bson:"image_from,omitempty" json:"image_from,omitempty"`
+	ImageID   string `bson:"image_id,omitempty"   json:"image_id,omitempty"`
+	Timeout   int    `bson:"timeout,omitempty"    json:"timeout,omitempty"`
+	// cache settings
+	CacheEnable  *bool              `bson:"cache_enable,omitempty"   json:"cache_enable,omitempty"`
+	CacheDirType types.CacheDirType `bson:"cache_dir_type,omitempty" json:"cache_dir_type,omitempty"`
+	CacheUserDir string             `bson:"cache_user_dir,omitempty" json:"cache_user_dir,omitempty"`
+	// script sections run before and after the scripts of the template
+	PreScripts  string `bson:"pre_scripts,omitempty"  json:"pre_scripts,omitempty"`
+	PostScripts string `bson:"post_scripts,omitempty" json:"post_scripts,omitempty"`
+}
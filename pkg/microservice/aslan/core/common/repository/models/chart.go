@@ -31,6 +31,9 @@ type Chart struct {
 	Revision       int64              `bson:"revision"      json:"revision"`
 	ChartVariables []*ChartVariable   `bson:"variables"     json:"variables"`
 	Sha1           string             `bson:"sha1"          json:"sha1"`
+	Language       string             `bson:"language"      json:"language"`
+	Framework      string             `bson:"framework"     json:"framework"`
+	Description    string             `bson:"description"   json:"description"`
 }
 
 type ChartVariable struct {
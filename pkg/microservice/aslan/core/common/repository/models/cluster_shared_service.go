@@ -0,0 +1,46 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// ClusterSharedService is an infrastructure chart such as an ingress controller which is installed once in
+// a cluster and referenced by the envs in the cluster, every upgrade saves a new revision.
+type ClusterSharedService struct {
+	ID            primitive.ObjectID                `bson:"_id,omitempty"    json:"id,omitempty"`
+	ClusterID     string                            `bson:"cluster_id"       json:"cluster_id"`
+	Name          string                            `bson:"name"             json:"name"`
+	Namespace     string                            `bson:"namespace"        json:"namespace"`
+	ChartRepoName string                            `bson:"chart_repo_name"  json:"chart_repo_name"`
+	ChartName     string                            `bson:"chart_name"       json:"chart_name"`
+	ChartVersion  string                            `bson:"chart_version"    json:"chart_version"`
+	ValuesYaml    string                            `bson:"values_yaml"      json:"values_yaml"`
+	Revision      int64                             `bson:"revision"         json:"revision"`
+	Status        config.ClusterSharedServiceStatus `bson:"status"           json:"status"`
+	Error         string                            `bson:"error"            json:"error"`
+	UpdateBy      string                            `bson:"update_by"        json:"update_by"`
+	CreateTime    int64                             `bson:"create_time"      json:"create_time"`
+	UpdateTime    int64                             `bson:"update_time"      json:"update_time"`
+}
+
+func (ClusterSharedService) TableName() string {
+	return "cluster_shared_service"
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkflowV4Version is the state of a workflow saved every time it is created, updated or deleted, it is used to
+// look up the workflow as of a time.
+type WorkflowV4Version struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"      json:"id,omitempty"`
+	ProjectName  string             `bson:"project_name"       json:"project_name"`
+	WorkflowName string             `bson:"workflow_name"      json:"workflow_name"`
+	// Workflow is empty if the workflow is deleted by this version.
+	Workflow   *WorkflowV4 `bson:"workflow,omitempty" json:"workflow,omitempty"`
+	Deleted    bool        `bson:"deleted"            json:"deleted"`
+	CreateBy   string      `bson:"create_by"          json:"create_by"`
+	CreateTime int64       `bson:"create_time"        json:"create_time"`
+}
+
+func (WorkflowV4Version) TableName() string {
+	return "workflow_v4_version"
+}
+
+// EnvVersion is the state of an environment saved every time its services or render set are changed.
+type EnvVersion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"      json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"       json:"project_name"`
+	EnvName     string             `bson:"env_name"           json:"env_name"`
+	// Env is empty if the environment is deleted by this version.
+	Env        *Product `bson:"env,omitempty"      json:"env,omitempty"`
+	Deleted    bool     `bson:"deleted"            json:"deleted"`
+	CreateTime int64    `bson:"create_time"        json:"create_time"`
+}
+
+func (EnvVersion) TableName() string {
+	return "env_version"
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CredentialHealth is the result of the last check of a codehost, registry or cluster credential.
+// ExpiresAt is 0 if the expiry can not be estimated, NotifiedAt is the last time the owners were
+// notified so that they are not notified on every check.
+type CredentialHealth struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"       json:"id,omitempty"`
+	Kind       string             `bson:"kind"                json:"kind"`
+	ResourceID string             `bson:"resource_id"         json:"resource_id"`
+	Name       string             `bson:"name"                json:"name"`
+	Owner      string             `bson:"owner"               json:"owner"`
+	Healthy    bool               `bson:"healthy"             json:"healthy"`
+	Message    string             `bson:"message"             json:"message"`
+	ExpiresAt  int64              `bson:"expires_at"          json:"expires_at"`
+	CheckedAt  int64              `bson:"checked_at"          json:"checked_at"`
+	NotifiedAt int64              `bson:"notified_at"         json:"notified_at"`
+}
+
+func (CredentialHealth) TableName() string {
+	return "credential_health"
+}
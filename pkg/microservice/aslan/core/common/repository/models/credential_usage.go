@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CredentialUsage records that a codehost token, registry credential or project secret was handed to a job,
+// so that the tasks which used a credential can be found when it may have leaked. CredentialID is the id of
+// the codehost or registry, or the id of the variable group of the secret whose Keys were used.
+type CredentialUsage struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"       json:"id,omitempty"`
+	Kind           string             `bson:"kind"                json:"kind"`
+	CredentialID   string             `bson:"credential_id"       json:"credential_id"`
+	CredentialName string             `bson:"credential_name"     json:"credential_name"`
+	Keys           []string           `bson:"keys,omitempty"      json:"keys,omitempty"`
+	ProjectName    string             `bson:"project_name"        json:"project_name"`
+	WorkflowName   string             `bson:"workflow_name"       json:"workflow_name"`
+	TaskID         int64              `bson:"task_id"             json:"task_id"`
+	TaskCreator    string             `bson:"task_creator"        json:"task_creator"`
+	JobName        string             `bson:"job_name"            json:"job_name"`
+	JobType        string             `bson:"job_type"            json:"job_type"`
+	ClusterID      string             `bson:"cluster_id"          json:"cluster_id"`
+	Runner         string             `bson:"runner,omitempty"    json:"runner,omitempty"`
+	UsedAt         int64              `bson:"used_at"             json:"used_at"`
+}
+
+func (CredentialUsage) TableName() string {
+	return "credential_usage"
+}
@@ -21,20 +21,27 @@ import (
 )
 
 type Cronjob struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"`
-	Name         string             `bson:"name"`
-	Type         string             `bson:"type"`
-	Number       uint64             `bson:"number"`
-	Frequency    string             `bson:"frequency"`
-	Time         string             `bson:"time"`
-	Cron         string             `bson:"cron"`
-	ProductName  string             `bson:"product_name,omitempty"`
-	MaxFailure   int                `bson:"max_failures,omitempty"`
-	TaskArgs     *TaskArgs          `bson:"task_args,omitempty"`
-	WorkflowArgs *WorkflowTaskArgs  `bson:"workflow_args,omitempty"`
-	TestArgs     *TestTaskArgs      `bson:"test_args,omitempty"`
-	JobType      string             `bson:"job_type"`
-	Enabled      bool               `bson:"enabled"`
+	ID                 primitive.ObjectID  `bson:"_id,omitempty"`
+	Name               string              `bson:"name"`
+	Type               string              `bson:"type"`
+	Number             uint64              `bson:"number"`
+	Frequency          string              `bson:"frequency"`
+	Time               string              `bson:"time"`
+	Cron               string              `bson:"cron"`
+	ProductName        string              `bson:"product_name,omitempty"`
+	MaxFailure         int                 `bson:"max_failures,omitempty"`
+	TaskArgs           *TaskArgs           `bson:"task_args,omitempty"`
+	WorkflowArgs       *WorkflowTaskArgs   `bson:"workflow_args,omitempty"`
+	TestArgs           *TestTaskArgs       `bson:"test_args,omitempty"`
+	EnvDataRefreshArgs *EnvDataRefreshArgs `bson:"env_data_refresh_args,omitempty"`
+	EnvSmokeTestArgs   *EnvSmokeTestArgs   `bson:"env_smoke_test_args,omitempty"`
+	ReleaseTrainArgs   *ReleaseTrainArgs   `bson:"release_train_args,omitempty"`
+	JobType            string              `bson:"job_type"`
+	Enabled            bool                `bson:"enabled"`
+	CatchUpPolicy      string              `bson:"catch_up_policy"`
+	// LastScheduledTime is the latest scheduled time which is triggered or skipped, the runs
+	// missed after it are caught up when the cron service starts.
+	LastScheduledTime int64 `bson:"last_scheduled_time,omitempty"`
 }
 
 func (Cronjob) TableName() string {
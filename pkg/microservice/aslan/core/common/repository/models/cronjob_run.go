@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CronjobRun records a scheduled time of a cronjob, the idempotency key makes sure that a
+// scheduled time is triggered only once even if it is caught up after the cron service restarts.
+type CronjobRun struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"       json:"id,omitempty"`
+	CronjobID      string             `bson:"cronjob_id"          json:"cronjob_id"`
+	ParentName     string             `bson:"parent_name"         json:"parent_name"`
+	ParentType     string             `bson:"parent_type"         json:"parent_type"`
+	ProductName    string             `bson:"product_name"        json:"product_name"`
+	IdempotencyKey string             `bson:"idempotency_key"     json:"idempotency_key"`
+	ScheduledTime  int64              `bson:"scheduled_time"      json:"scheduled_time"`
+	Missed         bool               `bson:"missed"              json:"missed"`
+	CatchUpPolicy  string             `bson:"catch_up_policy"     json:"catch_up_policy,omitempty"`
+	Status         string             `bson:"status"              json:"status"`
+	CreateTime     int64              `bson:"create_time"         json:"create_time"`
+}
+
+func (CronjobRun) TableName() string {
+	return "cronjob_run"
+}
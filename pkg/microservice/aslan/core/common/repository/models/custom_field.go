@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// CustomField is an organization-specific metadata field defined by the admins, e.g. the cost center of the
+// projects or the compliance owner of the workflows. The key is unique across the scopes so that the fields of a
+// workflow and its project can be put together in the events.
+type CustomField struct {
+	ID          primitive.ObjectID      `bson:"_id,omitempty" json:"id,omitempty"`
+	Key         string                  `bson:"key"           json:"key"`
+	Name        string                  `bson:"name"          json:"name"`
+	Description string                  `bson:"description"   json:"description"`
+	Scope       config.CustomFieldScope `bson:"scope"         json:"scope"`
+	Type        config.CustomFieldType  `bson:"type"          json:"type"`
+	// Required fields are given when the projects or the workflows are created.
+	Required bool `bson:"required" json:"required"`
+	// Regex is matched against the whole value of the string fields.
+	Regex string `bson:"regex,omitempty" json:"regex,omitempty"`
+	// Options are the values of the enum fields.
+	Options    []string `bson:"options,omitempty" json:"options,omitempty"`
+	CreatedBy  string   `bson:"created_by"        json:"created_by"`
+	CreateTime int64    `bson:"create_time"       json:"create_time"`
+	UpdateBy   string   `bson:"update_by"         json:"update_by"`
+	UpdateTime int64    `bson:"update_time"       json:"update_time"`
+}
+
+func (CustomField) TableName() string {
+	return "custom_field"
+}
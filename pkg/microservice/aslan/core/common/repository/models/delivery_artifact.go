@@ -38,6 +38,9 @@ type DeliveryArtifact struct {
 	PackageStorageURI   string             `bson:"package_storage_uri,omitempty"   json:"package_storage_uri,omitempty"`
 	CreatedBy           string             `bson:"created_by"                      json:"created_by"`
 	CreatedTime         int64              `bson:"created_time"                    json:"created_time"`
+	// ProjectName and Metadata are only set for the artifacts registered by the external CI systems.
+	ProjectName string            `bson:"project_name,omitempty"          json:"project_name,omitempty"`
+	Metadata    map[string]string `bson:"metadata,omitempty"              json:"metadata,omitempty"`
 }
 
 type Descriptor struct {
@@ -61,6 +61,13 @@ type DeliveryImage struct {
 	Os            string `bson:"os"              json:"os"`
 	CreationTime  string `bson:"creation_time"   json:"creationTime"`
 	UpdateTime    string `bson:"update_time"     json:"updateTime"`
+	// Layers are only resolved for the registries serving the schema2 manifests, they are not stored.
+	Layers []*DeliveryImageLayer `bson:"-" json:"layers,omitempty"`
+}
+
+type DeliveryImageLayer struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
 }
 
 type DeliveryPackage struct {
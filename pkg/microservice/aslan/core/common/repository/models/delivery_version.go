@@ -44,6 +44,8 @@ type DeliveryVersion struct {
 	CreatedBy      string                   `bson:"created_by"              json:"createdBy"`
 	CreatedAt      int64                    `bson:"created_at"              json:"created_at"`
 	DeletedAt      int64                    `bson:"deleted_at"              json:"deleted_at"`
+	// ReleaseTrain is the content shipped by the release train which created the version.
+	ReleaseTrain *ReleaseTrainContent `bson:"release_train,omitempty" json:"release_train,omitempty"`
 }
 
 func (DeliveryVersion) TableName() string {
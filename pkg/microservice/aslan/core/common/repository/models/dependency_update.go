@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// DependencyUpdatePolicy enables the periodical check of the images and the chart dependencies used by the
+// services of the project, the bumps are opened as pull requests in the repos the services are loaded from.
+type DependencyUpdatePolicy struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	ProjectName  string             `bson:"project_name"   json:"project_name"`
+	Enabled      bool               `bson:"enabled"        json:"enabled"`
+	AutoCreatePR bool               `bson:"auto_create_pr" json:"auto_create_pr"`
+	// Ignored are the names of the images or the charts which are not checked.
+	Ignored    []string `bson:"ignored"        json:"ignored"`
+	UpdatedBy  string   `bson:"updated_by"     json:"updated_by"`
+	UpdateTime int64    `bson:"update_time"    json:"update_time"`
+	// LastCheckTime is updated by the checks only.
+	LastCheckTime int64 `bson:"last_check_time" json:"last_check_time"`
+}
+
+func (DependencyUpdatePolicy) TableName() string {
+	return "dependency_update_policy"
+}
+
+// DependencyUpdate is a newer version found for an image or a chart dependency of a service.
+type DependencyUpdate struct {
+	ID             primitive.ObjectID            `bson:"_id,omitempty"         json:"id,omitempty"`
+	ProjectName    string                        `bson:"project_name"          json:"project_name"`
+	ServiceName    string                        `bson:"service_name"          json:"service_name"`
+	Kind           config.DependencyKind         `bson:"kind"                  json:"kind"`
+	Name           string                        `bson:"name"                  json:"name"`
+	CurrentVersion string                        `bson:"current_version"       json:"current_version"`
+	LatestVersion  string                        `bson:"latest_version"        json:"latest_version"`
+	Files          []string                      `bson:"files"                 json:"files"`
+	CodehostID     int                           `bson:"codehost_id"           json:"codehost_id"`
+	RepoOwner      string                        `bson:"repo_owner"            json:"repo_owner"`
+	RepoName       string                        `bson:"repo_name"             json:"repo_name"`
+	BaseBranch     string                        `bson:"base_branch"           json:"base_branch"`
+	Branch         string                        `bson:"branch,omitempty"      json:"branch,omitempty"`
+	PRNumber       int                           `bson:"pr_number,omitempty"   json:"pr_number,omitempty"`
+	PRURL          string                        `bson:"pr_url,omitempty"      json:"pr_url,omitempty"`
+	Status         config.DependencyUpdateStatus `bson:"status"                json:"status"`
+	Error          string                        `bson:"error,omitempty"       json:"error,omitempty"`
+	WorkflowTasks  []*DependencyUpdateTask       `bson:"workflow_tasks"        json:"workflow_tasks"`
+	CreateTime     int64                         `bson:"create_time"           json:"create_time"`
+	UpdateTime     int64                         `bson:"update_time"           json:"update_time"`
+}
+
+func (DependencyUpdate) TableName() string {
+	return "dependency_update"
+}
+
+// DependencyUpdateTask is a workflow task triggered by the pull request of the update.
+type DependencyUpdateTask struct {
+	WorkflowName string        `bson:"workflow_name"  json:"workflow_name"`
+	TaskID       int64         `bson:"task_id"        json:"task_id"`
+	Status       config.Status `bson:"status"         json:"status"`
+	EndTime      int64         `bson:"end_time"       json:"end_time"`
+}
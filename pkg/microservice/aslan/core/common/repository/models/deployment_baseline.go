@@ -0,0 +1,37 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// DeploymentBaseline is the signals measured by the deploy comparison for the last successful deployment of a
+// service in an env, the next deployment of the service is compared with it.
+type DeploymentBaseline struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	ProjectName  string             `bson:"project_name"    json:"project_name"`
+	EnvName      string             `bson:"env_name"        json:"env_name"`
+	ServiceName  string             `bson:"service_name"    json:"service_name"`
+	WorkflowName string             `bson:"workflow_name"   json:"workflow_name"`
+	TaskID       int64              `bson:"task_id"         json:"task_id"`
+	// Signals are the values by the names of the signals.
+	Signals    map[string]float64 `bson:"signals"         json:"signals"`
+	UpdateTime int64              `bson:"update_time"     json:"update_time"`
+}
+
+func (DeploymentBaseline) TableName() string {
+	return "deployment_baseline"
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// DNSProvider manages the records of the ingress hosts of the environments under Domain, in a zone of
+// route53, cloud dns or alidns.
+type DNSProvider struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"     json:"id"`
+	Name     string             `bson:"name"              json:"name"`
+	Provider string             `bson:"provider"          json:"provider"`
+	Enabled  bool               `bson:"enabled"           json:"enabled"`
+	// Domain is the suffix of the ingress hosts whose records are managed, e.g. preview.example.com.
+	Domain string `bson:"domain"            json:"domain"`
+	// Zone is the hosted zone id of route53, the managed zone name of cloud dns, or the domain name of alidns.
+	Zone        string `bson:"zone"              json:"zone"`
+	Region      string `bson:"region"            json:"region"`
+	Project     string `bson:"project"           json:"project"`
+	AccessKeyID string `bson:"access_key_id"     json:"access_key_id"`
+	// Secret is the access key secret of route53 and alidns, or the service account key of cloud dns.
+	Secret          string `bson:"-"                 json:"secret"`
+	EncryptedSecret string `bson:"encrypted_secret"  json:"-"`
+	// Target is the value of the records, the address of the load balancer of the ingress is used if it is empty.
+	Target string `bson:"target"            json:"target"`
+	TTL    int64  `bson:"ttl"               json:"ttl"`
+	// OwnerID tells the records of the installations sharing the zone apart.
+	OwnerID    string `bson:"owner_id"          json:"owner_id"`
+	UpdateBy   string `bson:"update_by"         json:"update_by"`
+	UpdateTime int64  `bson:"update_time"       json:"update_time"`
+}
+
+func (DNSProvider) TableName() string {
+	return "dns_provider"
+}
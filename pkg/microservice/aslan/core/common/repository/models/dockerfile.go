@@ -19,11 +19,29 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type DockerfileTemplate struct {
-	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Name    string             `bson:"name"          json:"name"`
-	Content string             `bson:"content"       json:"content"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string             `bson:"name"          json:"name"`
+	Content     string             `bson:"content"       json:"content"`
+	Language    string             `bson:"language"      json:"language"`
+	Framework   string             `bson:"framework"     json:"framework"`
+	Description string             `bson:"description"   json:"description"`
+	Revision    int64              `bson:"revision"      json:"revision"`
 }
 
 func (DockerfileTemplate) TableName() string {
 	return "dockerfile_template"
 }
+
+// DockerfileTemplateVersion is the content of a dockerfile template at a revision, builds keep
+// using the revision they were created with until they are re-rendered.
+type DockerfileTemplateVersion struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	TemplateID string             `bson:"template_id"   json:"template_id"`
+	Revision   int64              `bson:"revision"      json:"revision"`
+	Content    string             `bson:"content"       json:"content"`
+	CreateTime int64              `bson:"create_time"   json:"create_time"`
+}
+
+func (DockerfileTemplateVersion) TableName() string {
+	return "dockerfile_template_version"
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// EnvAutoUpdatePolicy keeps the environment tracking the latest successful builds of a branch, the images
+// built from the branch are deployed by the deploy job of the deploy workflow automatically.
+type EnvAutoUpdatePolicy struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"        json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"         json:"project_name"`
+	EnvName     string             `bson:"env_name"             json:"env_name"`
+	Branch      string             `bson:"branch"               json:"branch"`
+	// SourceWorkflow builds the images, the builds of all the workflows of the project are tracked if it is empty.
+	SourceWorkflow string `bson:"source_workflow"      json:"source_workflow"`
+	// Services are tracked only, all the services are tracked if it is empty.
+	Services       []string `bson:"services"             json:"services"`
+	DeployWorkflow string   `bson:"deploy_workflow"      json:"deploy_workflow"`
+	DeployJobName  string   `bson:"deploy_job_name"      json:"deploy_job_name"`
+	CreatedBy      string   `bson:"created_by"           json:"created_by"`
+	CreateTime     int64    `bson:"create_time"          json:"create_time"`
+	UpdatedBy      string   `bson:"updated_by"           json:"updated_by"`
+	UpdateTime     int64    `bson:"update_time"          json:"update_time"`
+
+	// the fields below are updated by the pause controls only.
+	Paused      bool   `bson:"paused"               json:"paused"`
+	PausedBy    string `bson:"paused_by"            json:"paused_by"`
+	PauseReason string `bson:"pause_reason"         json:"pause_reason"`
+	PauseTime   int64  `bson:"pause_time"           json:"pause_time"`
+
+	LastDeployTime int64 `bson:"last_deploy_time"     json:"last_deploy_time"`
+}
+
+func (EnvAutoUpdatePolicy) TableName() string {
+	return "env_auto_update_policy"
+}
+
+// EnvAutoUpdateEvent is an entry of the change feed of the environment, it records the images a passed task
+// built from the tracked branch and the task deploying them.
+type EnvAutoUpdateEvent struct {
+	ID             primitive.ObjectID         `bson:"_id,omitempty"          json:"id,omitempty"`
+	PolicyID       string                     `bson:"policy_id"              json:"policy_id"`
+	ProjectName    string                     `bson:"project_name"           json:"project_name"`
+	EnvName        string                     `bson:"env_name"               json:"env_name"`
+	Branch         string                     `bson:"branch"                 json:"branch"`
+	SourceWorkflow string                     `bson:"source_workflow"        json:"source_workflow"`
+	SourceTaskID   int64                      `bson:"source_task_id"         json:"source_task_id"`
+	Commits        []*ReleaseTrainCommit      `bson:"commits"                json:"commits"`
+	Images         []*ServiceAndImage         `bson:"images"                 json:"images"`
+	Status         config.EnvAutoUpdateStatus `bson:"status"                 json:"status"`
+	Message        string                     `bson:"message,omitempty"      json:"message,omitempty"`
+	Batch          string                     `bson:"batch,omitempty"        json:"-"`
+	DeployWorkflow string                     `bson:"deploy_workflow"        json:"deploy_workflow"`
+	DeployTaskID   int64                      `bson:"deploy_task_id"         json:"deploy_task_id"`
+	CreateTime     int64                      `bson:"create_time"            json:"create_time"`
+	UpdateTime     int64                      `bson:"update_time"            json:"update_time"`
+}
+
+func (EnvAutoUpdateEvent) TableName() string {
+	return "env_auto_update_event"
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// EnvDataRefresh refreshes the data of an environment, e.g. loads a sanitized production snapshot
+// into the databases of a staging environment. The script runs in a pod in the namespace of the
+// environment with the credentials injected as env vars, the mask hooks run after it to scrub the
+// data which must not leave production.
+type EnvDataRefresh struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"        json:"id,omitempty"`
+	Name            string             `bson:"name"                 json:"name"`
+	ProjectName     string             `bson:"project_name"         json:"project_name"`
+	EnvName         string             `bson:"env_name"             json:"env_name"`
+	Description     string             `bson:"description"          json:"description"`
+	Source          string             `bson:"source"               json:"source"`
+	Image           string             `bson:"image"                json:"image"`
+	Script          string             `bson:"script"               json:"script"`
+	MaskHooks       []*DataMaskHook    `bson:"mask_hooks"           json:"mask_hooks"`
+	Credentials     []*KeyVal          `bson:"credentials"          json:"credentials"`
+	Timeout         int64              `bson:"timeout"              json:"timeout"`
+	Schedules       *ScheduleCtrl      `bson:"-"                    json:"schedules,omitempty"`
+	ScheduleEnabled bool               `bson:"schedule_enabled"     json:"schedule_enabled"`
+	CreatedBy       string             `bson:"created_by"           json:"created_by"`
+	CreateTime      int64              `bson:"create_time"          json:"create_time"`
+	UpdatedBy       string             `bson:"updated_by"           json:"updated_by"`
+	UpdateTime      int64              `bson:"update_time"          json:"update_time"`
+}
+
+type DataMaskHook struct {
+	Name   string `bson:"name"     json:"name"`
+	Script string `bson:"script"   json:"script"`
+}
+
+func (EnvDataRefresh) TableName() string {
+	return "env_data_refresh"
+}
+
+// EnvDataRefreshRecord is a run of the data refresh.
+type EnvDataRefreshRecord struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"    json:"id,omitempty"`
+	RefreshID   string             `bson:"refresh_id"       json:"refresh_id"`
+	ProjectName string             `bson:"project_name"     json:"project_name"`
+	EnvName     string             `bson:"env_name"         json:"env_name"`
+	Status      config.Status      `bson:"status"           json:"status"`
+	TriggeredBy string             `bson:"triggered_by"     json:"triggered_by"`
+	Error       string             `bson:"error,omitempty"  json:"error,omitempty"`
+	StartTime   int64              `bson:"start_time"       json:"start_time"`
+	EndTime     int64              `bson:"end_time"         json:"end_time,omitempty"`
+}
+
+func (EnvDataRefreshRecord) TableName() string {
+	return "env_data_refresh_record"
+}
+
+// EnvDataRefreshArgs tells the cron service which data refresh a schedule runs.
+type EnvDataRefreshArgs struct {
+	ProjectName string `bson:"project_name"   json:"project_name"`
+	EnvName     string `bson:"env_name"       json:"env_name"`
+	RefreshID   string `bson:"refresh_id"     json:"refresh_id"`
+	TriggeredBy string `bson:"-"              json:"triggered_by,omitempty"`
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+const (
+	EnvDNSRecordSynced   = "synced"
+	EnvDNSRecordPending  = "pending"
+	EnvDNSRecordConflict = "conflict"
+	EnvDNSRecordFailed   = "failed"
+)
+
+// EnvDNSRecord is a record managed for an ingress host of an environment, it is kept so that the record is
+// removed when the host or the environment is.
+type EnvDNSRecord struct {
+	ProjectName string   `bson:"project_name"      json:"project_name"`
+	EnvName     string   `bson:"env_name"          json:"env_name"`
+	Host        string   `bson:"host"              json:"host"`
+	ProviderID  string   `bson:"provider_id"       json:"provider_id"`
+	Type        string   `bson:"type"              json:"type"`
+	Values      []string `bson:"values"            json:"values"`
+	// Created is true once the record is created by zadig, only such records are deleted.
+	Created    bool   `bson:"created"           json:"created"`
+	Status     string `bson:"status"            json:"status"`
+	Message    string `bson:"message"           json:"message"`
+	UpdateTime int64  `bson:"update_time"       json:"update_time"`
+}
+
+func (EnvDNSRecord) TableName() string {
+	return "env_dns_record"
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "fmt"
+
+const (
+	EnvLockKindWorkflow = "workflow"
+	EnvLockKindManual   = "manual"
+)
+
+// EnvLock makes sure that an environment is changed by only one deploy at a time, the lock is taken
+// by the deploy jobs of a workflow task or by a user manually.
+type EnvLock struct {
+	ID           string `bson:"_id"                     json:"-"`
+	ProjectName  string `bson:"project_name"            json:"project_name"`
+	EnvName      string `bson:"env_name"                json:"env_name"`
+	Owner        string `bson:"owner"                   json:"owner"`
+	Kind         string `bson:"kind"                    json:"kind"`
+	WorkflowName string `bson:"workflow_name,omitempty" json:"workflow_name,omitempty"`
+	TaskID       int64  `bson:"task_id,omitempty"       json:"task_id,omitempty"`
+	LockedBy     string `bson:"locked_by"               json:"locked_by"`
+	Reason       string `bson:"reason,omitempty"        json:"reason,omitempty"`
+	LockTime     int64  `bson:"lock_time"               json:"lock_time"`
+	ExpireAt     int64  `bson:"expire_at"               json:"expire_at"`
+}
+
+func (EnvLock) TableName() string {
+	return "env_lock"
+}
+
+func EnvLockID(projectName, envName string) string {
+	return fmt.Sprintf("%s/%s", projectName, envName)
+}
+
+// WorkflowEnvLockOwner is the owner of the locks taken by the deploy jobs of a workflow task.
+func WorkflowEnvLockOwner(workflowName string, taskID int64) string {
+	return fmt.Sprintf("task:%s:%d", workflowName, taskID)
+}
+
+// ManualEnvLockOwner is the owner of the locks taken by a user.
+func ManualEnvLockOwner(userName string) string {
+	return fmt.Sprintf("user:%s", userName)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// EnvSmokeTest binds a testing module to an environment, the test runs on its own schedule
+// regardless of the deployments. The latest result is part of the health of the environment.
+type EnvSmokeTest struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"        json:"id,omitempty"`
+	Name            string             `bson:"name"                 json:"name"`
+	ProjectName     string             `bson:"project_name"         json:"project_name"`
+	EnvName         string             `bson:"env_name"             json:"env_name"`
+	TestName        string             `bson:"test_name"            json:"test_name"`
+	Description     string             `bson:"description"          json:"description"`
+	Timeout         int64              `bson:"timeout"              json:"timeout"`
+	AlertRule       *SmokeTestAlert    `bson:"alert_rule"           json:"alert_rule"`
+	Schedules       *ScheduleCtrl      `bson:"-"                    json:"schedules,omitempty"`
+	ScheduleEnabled bool               `bson:"schedule_enabled"     json:"schedule_enabled"`
+	CreatedBy       string             `bson:"created_by"           json:"created_by"`
+	CreateTime      int64              `bson:"create_time"          json:"create_time"`
+	UpdatedBy       string             `bson:"updated_by"           json:"updated_by"`
+	UpdateTime      int64              `bson:"update_time"          json:"update_time"`
+
+	// the fields below are updated by the runs only.
+	LastStatus          config.Status `bson:"last_status"          json:"last_status"`
+	LastRunTime         int64         `bson:"last_run_time"        json:"last_run_time"`
+	ConsecutiveFailures int           `bson:"consecutive_failures" json:"consecutive_failures"`
+	Alerting            bool          `bson:"alerting"             json:"alerting"`
+}
+
+// SmokeTestAlert notifies the receivers once the smoke test fails the given times in a row,
+// and optionally when it passes again.
+type SmokeTestAlert struct {
+	Enabled          bool     `bson:"enabled"            json:"enabled"`
+	FailureThreshold int      `bson:"failure_threshold"  json:"failure_threshold"`
+	Receivers        []string `bson:"receivers"          json:"receivers"`
+	NotifyRecovery   bool     `bson:"notify_recovery"    json:"notify_recovery"`
+}
+
+func (EnvSmokeTest) TableName() string {
+	return "env_smoke_test"
+}
+
+// EnvSmokeTestRecord is a run of the smoke test, it refers to the test task which does the work.
+type EnvSmokeTestRecord struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"    json:"id,omitempty"`
+	SmokeTestID  string             `bson:"smoke_test_id"    json:"smoke_test_id"`
+	ProjectName  string             `bson:"project_name"     json:"project_name"`
+	EnvName      string             `bson:"env_name"         json:"env_name"`
+	TestName     string             `bson:"test_name"        json:"test_name"`
+	PipelineName string             `bson:"pipeline_name"    json:"pipeline_name"`
+	TaskID       int64              `bson:"task_id"          json:"task_id"`
+	Status       config.Status      `bson:"status"           json:"status"`
+	TriggeredBy  string             `bson:"triggered_by"     json:"triggered_by"`
+	Error        string             `bson:"error,omitempty"  json:"error,omitempty"`
+	StartTime    int64              `bson:"start_time"       json:"start_time"`
+	EndTime      int64              `bson:"end_time"         json:"end_time,omitempty"`
+}
+
+func (EnvSmokeTestRecord) TableName() string {
+	return "env_smoke_test_record"
+}
+
+// EnvSmokeTestArgs tells the cron service which smoke test a schedule runs.
+type EnvSmokeTestArgs struct {
+	ProjectName string `bson:"project_name"   json:"project_name"`
+	EnvName     string `bson:"env_name"       json:"env_name"`
+	SmokeTestID string `bson:"smoke_test_id"  json:"smoke_test_id"`
+	TriggeredBy string `bson:"-"              json:"triggered_by,omitempty"`
+}
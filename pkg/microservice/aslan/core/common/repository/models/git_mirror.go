@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GitMirror is a bare mirror of a repo kept by zadig, the builds fetch from it instead of the
+// codehost if it is ready.
+type GitMirror struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	CodehostID    int                `bson:"codehost_id"    json:"codehost_id"`
+	RepoOwner     string             `bson:"repo_owner"     json:"repo_owner"`
+	RepoNamespace string             `bson:"repo_namespace" json:"repo_namespace"`
+	RepoName      string             `bson:"repo_name"      json:"repo_name"`
+	Status        string             `bson:"status"         json:"status"`
+	Error         string             `bson:"error"          json:"error"`
+	// SyncedAt is the last time the mirror was updated successfully.
+	SyncedAt   int64  `bson:"synced_at"   json:"synced_at"`
+	Size       int64  `bson:"size"        json:"size"`
+	CreatedBy  string `bson:"created_by"  json:"created_by"`
+	CreateTime int64  `bson:"create_time" json:"create_time"`
+}
+
+func (m *GitMirror) GetRepoNamespace() string {
+	if m.RepoNamespace != "" {
+		return m.RepoNamespace
+	}
+	return m.RepoOwner
+}
+
+func (GitMirror) TableName() string {
+	return "git_mirror"
+}
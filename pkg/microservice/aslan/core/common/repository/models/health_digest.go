@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// HealthDigestPolicy sends the health report of the project to the notification routes once a week at the
+// configured time, it is sent to NotifyCtl if no route matches.
+type HealthDigestPolicy struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"  json:"project_name"`
+	Enabled     bool               `bson:"enabled"       json:"enabled"`
+	// Weekday is the day the digest is sent on, 0 is Sunday.
+	Weekday int `bson:"weekday"       json:"weekday"`
+	// Hour is the hour of the day in the local time of aslan.
+	Hour       int        `bson:"hour"                 json:"hour"`
+	NotifyCtl  *NotifyCtl `bson:"notify_ctl,omitempty" json:"notify_ctl,omitempty"`
+	UpdatedBy  string     `bson:"updated_by"           json:"updated_by"`
+	UpdateTime int64      `bson:"update_time"          json:"update_time"`
+}
+
+func (HealthDigestPolicy) TableName() string {
+	return "health_digest_policy"
+}
+
+// ProjectHealth is the health score of a project in a period, the score is the weighted average of the
+// scores of the components having data in the period.
+type ProjectHealth struct {
+	ProjectName string             `bson:"project_name" json:"project_name"`
+	Score       int                `bson:"score"        json:"score"`
+	StartTime   int64              `bson:"start_time"   json:"start_time"`
+	EndTime     int64              `bson:"end_time"     json:"end_time"`
+	Components  []*HealthComponent `bson:"components"   json:"components"`
+}
+
+type HealthComponent struct {
+	Name   config.HealthComponent `bson:"name"   json:"name"`
+	Weight int                    `bson:"weight" json:"weight"`
+	// Available is false if there is no data of the component in the period, it is not scored then.
+	Available bool    `bson:"available" json:"available"`
+	Score     int     `bson:"score"     json:"score"`
+	Value     float64 `bson:"value"     json:"value"`
+	// Detail explains the value, e.g. "18/20 tasks passed".
+	Detail string `bson:"detail" json:"detail"`
+	// URL links to the data the component is computed from.
+	URL string `bson:"url" json:"url"`
+}
+
+// HealthDigest is a weekly digest sent of a project, Week is the ISO week, e.g. 2026-W42, which makes sure
+// the digest is sent only once a week by the replicas of aslan.
+type HealthDigest struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"  json:"project_name"`
+	Week        string             `bson:"week"          json:"week"`
+	Health      *ProjectHealth     `bson:"health"        json:"health"`
+	CreateTime  int64              `bson:"create_time"   json:"create_time"`
+}
+
+func (HealthDigest) TableName() string {
+	return "health_digest"
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// HelmPostRenderRecord keeps the manifests produced by the post renderer of an env for audit.
+type HelmPostRenderRecord struct {
+	ID          primitive.ObjectID          `bson:"_id,omitempty"    json:"id,omitempty"`
+	ProjectName string                      `bson:"project_name"     json:"project_name"`
+	EnvName     string                      `bson:"env_name"         json:"env_name"`
+	ReleaseName string                      `bson:"release_name"     json:"release_name"`
+	Type        config.HelmPostRendererType `bson:"type"             json:"type"`
+	Image       string                      `bson:"image"            json:"image"`
+	Manifest    string                      `bson:"manifest"         json:"manifest,omitempty"`
+	Error       string                      `bson:"error,omitempty"  json:"error,omitempty"`
+	CreateTime  int64                       `bson:"create_time"      json:"create_time"`
+}
+
+func (HelmPostRenderRecord) TableName() string {
+	return "helm_post_render_record"
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// JobWarmPool keeps Size idle job pods of a cluster and image profile running, a job with the same
+// profile takes one of them instead of waiting for the image pulling and the pod scheduling.
+type JobWarmPool struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty"          json:"id,omitempty"`
+	Name            string              `bson:"name"                   json:"name"`
+	ClusterID       string              `bson:"cluster_id"             json:"cluster_id"`
+	BuildOS         string              `bson:"build_os"               json:"build_os"`
+	ImageFrom       string              `bson:"image_from"             json:"image_from"`
+	ResourceRequest setting.Request     `bson:"res_req"                json:"res_req"`
+	ResReqSpec      setting.RequestSpec `bson:"res_req_spec"           json:"res_req_spec"`
+	Size            int                 `bson:"size"                   json:"size"`
+	Enabled         bool                `bson:"enabled"                json:"enabled"`
+	CreateTime      int64               `bson:"create_time"            json:"create_time"`
+	UpdateTime      int64               `bson:"update_time"            json:"update_time"`
+	UpdateBy        string              `bson:"update_by"              json:"update_by"`
+}
+
+func (JobWarmPool) TableName() string {
+	return "job_warm_pool"
+}
@@ -45,6 +45,7 @@ type K8SCluster struct {
 	LastConnectionTime     int64                    `json:"last_connection_time"      bson:"last_connection_time"`
 	UpdateHubagentErrorMsg string                   `json:"update_hubagent_error_msg" bson:"update_hubagent_error_msg"`
 	DindCfg                *DindCfg                 `json:"dind_cfg"                  bson:"dind_cfg"`
+	JobIsolation           *JobIsolation            `json:"job_isolation,omitempty"   bson:"job_isolation,omitempty"`
 
 	// new field in 1.14, intended to enable kubeconfig for cluster management
 	Type       string `json:"type"           bson:"type"` // either agent or kubeconfig supported
@@ -107,6 +108,33 @@ type DindStorage struct {
 	StorageSizeInGiB int64           `json:"storage_size_in_gib" bson:"storage_size_in_gib"`
 }
 
+// JobIsolation runs the job pods of the cluster in dedicated namespaces, the pods use a service account
+// without any permission and can only reach the dns, the zadig services and the addresses out of the
+// cluster.
+type JobIsolation struct {
+	Enabled bool `json:"enabled"            bson:"enabled"`
+	// Namespace is used by the projects which have no namespace of their own.
+	Namespace         string                 `json:"namespace"          bson:"namespace"`
+	ProjectNamespaces []*ProjectJobNamespace `json:"project_namespaces" bson:"project_namespaces"`
+	// DeniedCIDRs are the pod and service cidrs of the cluster.
+	DeniedCIDRs []string `json:"denied_cidrs"       bson:"denied_cidrs"`
+}
+
+type ProjectJobNamespace struct {
+	Project   string `json:"project"   bson:"project"`
+	Namespace string `json:"namespace" bson:"namespace"`
+}
+
+// JobNamespace returns the namespace the jobs of the project run in.
+func (j *JobIsolation) JobNamespace(project string) string {
+	for _, ns := range j.ProjectNamespaces {
+		if ns.Project == project {
+			return ns.Namespace
+		}
+	}
+	return j.Namespace
+}
+
 func (K8SCluster) TableName() string {
 	return "k8s_cluster"
 }
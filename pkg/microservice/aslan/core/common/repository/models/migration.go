@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// Migration is a versioned data migration applied at startup.
+type Migration struct {
+	Version    int    `bson:"version"     json:"version"`
+	Name       string `bson:"name"        json:"name"`
+	Status     string `bson:"status"      json:"status"`
+	Error      string `bson:"error"       json:"error"`
+	ExecutedBy string `bson:"executed_by" json:"executed_by"`
+	StartTime  int64  `bson:"start_time"  json:"start_time"`
+	EndTime    int64  `bson:"end_time"    json:"end_time"`
+}
+
+func (Migration) TableName() string {
+	return "migration"
+}
+
+// MigrationLock makes sure that the migrations are run by only one aslan instance.
+type MigrationLock struct {
+	ID       string `bson:"_id"`
+	Owner    string `bson:"owner"`
+	ExpireAt int64  `bson:"expire_at"`
+}
+
+func (MigrationLock) TableName() string {
+	return "migration_lock"
+}
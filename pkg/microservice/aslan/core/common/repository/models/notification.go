@@ -24,27 +24,35 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/tool/i18n"
+	"github.com/koderover/zadig/pkg/tool/logannotation"
 )
 
 type Notification struct {
-	ID           primitive.ObjectID  `bson:"_id,omitempty"                json:"id,omitempty"`
-	CodehostID   int                 `bson:"codehost_id"                  json:"codehost_id"`
-	Tasks        []*NotificationTask `bson:"tasks,omitempty"              json:"tasks,omitempty"`
-	PrID         int                 `bson:"pr_id"                        json:"pr_id"`
-	CommentID    string              `bson:"comment_id"                   json:"comment_id"`
-	ProjectID    string              `bson:"project_id"                   json:"project_id"`
-	Created      int64               `bson:"create"                       json:"create"`
-	BaseURI      string              `bson:"base_uri"                     json:"base_uri"`
-	IsPipeline   bool                `bson:"is_pipeline"                  json:"is_pipeline"`
-	IsTest       bool                `bson:"is_test"                      json:"is_test"`
-	IsScanning   bool                `bson:"is_scanning"                  json:"is_scanning"`
-	IsWorkflowV4 bool                `bson:"is_workflowv4"                json:"is_workflowv4"`
-	ErrInfo      string              `bson:"err_info"                     json:"err_info"`
-	PrTask       *PrTaskInfo         `bson:"pr_task_info,omitempty"       json:"pr_task_info,omitempty"`
-	Label        string              `bson:"label"                        json:"label"  `
-	Revision     string              `bson:"revision"                     json:"revision"`
-	RepoOwner    string              `bson:"repo_owner"                   json:"repo_owner"`
-	RepoName     string              `bson:"repo_name"                    json:"repo_name"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty"                json:"id,omitempty"`
+	CodehostID    int                 `bson:"codehost_id"                  json:"codehost_id"`
+	Tasks         []*NotificationTask `bson:"tasks,omitempty"              json:"tasks,omitempty"`
+	PrID          int                 `bson:"pr_id"                        json:"pr_id"`
+	CommentID     string              `bson:"comment_id"                   json:"comment_id"`
+	ProjectID     string              `bson:"project_id"                   json:"project_id"`
+	Created       int64               `bson:"create"                       json:"create"`
+	BaseURI       string              `bson:"base_uri"                     json:"base_uri"`
+	IsPipeline    bool                `bson:"is_pipeline"                  json:"is_pipeline"`
+	IsTest        bool                `bson:"is_test"                      json:"is_test"`
+	IsScanning    bool                `bson:"is_scanning"                  json:"is_scanning"`
+	IsWorkflowV4  bool                `bson:"is_workflowv4"                json:"is_workflowv4"`
+	ErrInfo       string              `bson:"err_info"                     json:"err_info"`
+	PrTask        *PrTaskInfo         `bson:"pr_task_info,omitempty"       json:"pr_task_info,omitempty"`
+	Label         string              `bson:"label"                        json:"label"  `
+	Revision      string              `bson:"revision"                     json:"revision"`
+	RepoOwner     string              `bson:"repo_owner"                   json:"repo_owner"`
+	RepoName      string              `bson:"repo_name"                    json:"repo_name"`
+	SeriesChanges []*GerritChange     `bson:"series_changes,omitempty"     json:"series_changes,omitempty"`
+}
+
+type GerritChange struct {
+	Project string `bson:"project" json:"project"`
+	Number  int    `bson:"number"  json:"number"`
 }
 
 type PrTaskInfo struct {
@@ -64,11 +72,20 @@ type NotificationTask struct {
 	ID           int64             `bson:"id"              json:"id"`
 	Status       config.TaskStatus `bson:"status"          json:"status"`
 	TestReports  []*TestSuite      `bson:"test_reports,omitempty" json:"test_reports,omitempty"`
+	// Summaries are reported by the jobs of the custom workflow task.
+	Summaries []*JobSummary `bson:"summaries,omitempty" json:"summaries,omitempty"`
+	// Annotations link the errors in the logs of the failed jobs to the source.
+	Annotations []*logannotation.Annotation `bson:"annotations,omitempty" json:"annotations,omitempty"`
 
 	FirstCommented bool `json:"first_commented,omitempty" bson:"first_commented,omitempty"`
 }
 
+// StatusVerbose returns the status in the default language, the comments are read by all the members of the repository.
 func (t NotificationTask) StatusVerbose() string {
+	return i18n.T(i18n.DefaultLanguage(), t.statusVerbose())
+}
+
+func (t NotificationTask) statusVerbose() string {
 	switch t.Status {
 	case config.TaskStatusReady:
 		return "准备中"
@@ -140,7 +157,8 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 			tmplSource = "触发的工作流：等待任务启动中"
 		} else {
 			tmplSource =
-				"|触发的工作流|状态| \n |---|---| \n {{range .Tasks}}|[{{.WorkflowName}}#{{.ID}}]({{$.BaseURI}}/v1/projects/detail/{{.ProductName}}/pipelines/custom/{{.WorkflowName}}/{{.ID}}) | {{if eq .StatusVerbose $.Success}} {+ {{.StatusVerbose}} +}{{else}}{- {{.StatusVerbose}} -}{{end}} | \n {{end}}"
+				"|触发的工作流|状态| \n |---|---| \n {{range .Tasks}}|[{{.WorkflowName}}#{{.ID}}]({{$.BaseURI}}/v1/projects/detail/{{.ProductName}}/pipelines/custom/{{.WorkflowName}}/{{.ID}}) | {{if eq .StatusVerbose $.Success}} {+ {{.StatusVerbose}} +}{{else}}{- {{.StatusVerbose}} -}{{end}} | \n {{end}}{{range .Tasks}}{{range .Summaries}}\n\n{{.Markdown}}{{end}}{{end}}" +
+					"{{range .Tasks}}{{if .Annotations}}\n\n#### {{.WorkflowName}}#{{.ID}} 错误定位\n{{range .Annotations}}\n- {{.Markdown}}{{end}}{{end}}{{end}}"
 		}
 	} else {
 		if len(n.Tasks) == 0 {
@@ -167,7 +185,8 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 		}
 	}
 
-	tmpl := template.Must(template.New("comment").Parse(tmplSource))
+	lang := i18n.DefaultLanguage()
+	tmpl := template.Must(template.New("comment").Parse(i18n.Localize(lang, tmplSource)))
 	buffer := bytes.NewBufferString("")
 
 	if err = tmpl.Execute(buffer, struct {
@@ -179,7 +198,7 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 		n.Tasks,
 		n.BaseURI,
 		len(n.Tasks) == 0,
-		"成功",
+		i18n.T(lang, "成功"),
 	}); err != nil {
 		return
 	}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// NotificationRoute sends the events matching it to its channels, the routes replace the notifications configured
+// on each workflow. The routes are evaluated by priority and the evaluation stops at the first matched one unless
+// it continues.
+type NotificationRoute struct {
+	ID          primitive.ObjectID      `bson:"_id,omitempty"          json:"id,omitempty"`
+	Name        string                  `bson:"name"                   json:"name"`
+	Description string                  `bson:"description"            json:"description"`
+	Enabled     bool                    `bson:"enabled"                json:"enabled"`
+	Priority    int                     `bson:"priority"               json:"priority"`
+	Match       *NotificationRouteMatch `bson:"match"                  json:"match"`
+	Channels    []*NotifyCtl            `bson:"channels"               json:"channels"`
+	// MentionUsers are the names of the users mentioned in the messages, their mobiles and whether they want to be
+	// mentioned are in their notification preferences.
+	MentionUsers []string                `bson:"mention_users"          json:"mention_users"`
+	MentionActor bool                    `bson:"mention_actor"          json:"mention_actor"`
+	QuietHours   *QuietHours             `bson:"quiet_hours,omitempty"  json:"quiet_hours,omitempty"`
+	Escalation   *NotificationEscalation `bson:"escalation,omitempty"   json:"escalation,omitempty"`
+	Continue     bool                    `bson:"continue"               json:"continue"`
+	CreatedBy    string                  `bson:"created_by"             json:"created_by"`
+	CreateTime   int64                   `bson:"create_time"            json:"create_time"`
+	UpdateBy     string                  `bson:"update_by"              json:"update_by"`
+	UpdateTime   int64                   `bson:"update_time"            json:"update_time"`
+}
+
+// NotificationRouteMatch is the attributes of the events the route matches, an empty attribute matches all.
+type NotificationRouteMatch struct {
+	Projects   []string                      `bson:"projects"        json:"projects"`
+	Envs       []string                      `bson:"envs"            json:"envs"`
+	EventTypes []string                      `bson:"event_types"     json:"event_types"`
+	Severities []config.NotificationSeverity `bson:"severities"      json:"severities"`
+	Actors     []string                      `bson:"actors"          json:"actors"`
+	// LabelSelector matches the tags of the resource of the event, e.g. "team=payments,tier=backend".
+	LabelSelector string `bson:"label_selector" json:"label_selector"`
+}
+
+// QuietHours is a daily window in which only the critical and the escalated events are sent, the window spans
+// midnight if the start is after the end.
+type QuietHours struct {
+	// Start and End are in the form of HH:MM.
+	Start    string `bson:"start"    json:"start"`
+	End      string `bson:"end"      json:"end"`
+	Timezone string `bson:"timezone" json:"timezone"`
+}
+
+// NotificationEscalation also sends the events to its channels once the subject of the events failed for the
+// given times in a row.
+type NotificationEscalation struct {
+	AfterFailures int          `bson:"after_failures" json:"after_failures"`
+	Channels      []*NotifyCtl `bson:"channels"       json:"channels"`
+	MentionUsers  []string     `bson:"mention_users"  json:"mention_users"`
+}
+
+func (NotificationRoute) TableName() string {
+	return "notification_route"
+}
+
+// NotificationStreak is the failures in a row of a subject on a route, it is removed when the subject passes.
+type NotificationStreak struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RouteID    string             `bson:"route_id"      json:"route_id"`
+	Subject    string             `bson:"subject"       json:"subject"`
+	Failures   int                `bson:"failures"      json:"failures"`
+	UpdateTime int64              `bson:"update_time"   json:"update_time"`
+}
+
+func (NotificationStreak) TableName() string {
+	return "notification_streak"
+}
@@ -62,10 +62,55 @@ type Product struct {
 	// New Since v1.11.0.
 	ShareEnv ProductShareEnv `bson:"share_env" json:"share_env"`
 
+	// RequireDiffApproval pauses the deploy jobs of custom workflows until the changes are confirmed.
+	RequireDiffApproval bool `bson:"require_diff_approval" json:"require_diff_approval"`
+	// AutoRollback rolls back the deployments of custom workflows which fail the post-deploy verification.
+	AutoRollback bool `bson:"auto_rollback" json:"auto_rollback"`
+
+	// HelmPostRenderer mutates the manifests rendered by helm before the releases are installed or upgraded.
+	HelmPostRenderer *HelmPostRenderer `bson:"helm_post_renderer,omitempty" json:"helm_post_renderer,omitempty"`
+
+	// NamespaceMeta is applied to the namespace of the env when the env is created or updated.
+	NamespaceMeta *NamespaceMeta `bson:"namespace_meta,omitempty" json:"namespace_meta,omitempty"`
+
+	// ClusterSharedServices are the names of the shared services in the cluster which the env depends on.
+	ClusterSharedServices []string `bson:"cluster_shared_services,omitempty" json:"cluster_shared_services,omitempty"`
+
 	// New Since v1.13.0.
 	EnvConfigs []*CreateUpdateCommonEnvCfgArgs `bson:"-"   json:"env_configs,omitempty"`
 }
 
+// HelmPostRenderer runs in a job in the zadig namespace, the manifests are mounted in /zadig/input and
+// the job prints the mutated manifests to stdout.
+type HelmPostRenderer struct {
+	Enabled bool                        `bson:"enabled"         json:"enabled"`
+	Type    config.HelmPostRendererType `bson:"type"            json:"type"`
+	Image   string                      `bson:"image"           json:"image"`
+	// Kustomization is the content of kustomization.yaml, the manifests are added to its resources.
+	Kustomization string `bson:"kustomization"   json:"kustomization"`
+	// Command reads the manifests from /zadig/input/manifests.yaml.
+	Command string `bson:"command"         json:"command"`
+	// Timeout is in seconds.
+	Timeout int64 `bson:"timeout"         json:"timeout"`
+}
+
+// NamespaceMeta are the labels and annotations declared for the namespace of the env.
+type NamespaceMeta struct {
+	Labels      map[string]string `bson:"labels"                 json:"labels"`
+	Annotations map[string]string `bson:"annotations"            json:"annotations"`
+	PodSecurity *PodSecurity      `bson:"pod_security,omitempty" json:"pod_security,omitempty"`
+}
+
+// PodSecurity is the pod security admission levels of the namespace, the level is one of
+// privileged, baseline and restricted, and is not set if empty.
+type PodSecurity struct {
+	Enforce string `bson:"enforce" json:"enforce"`
+	Audit   string `bson:"audit"   json:"audit"`
+	Warn    string `bson:"warn"    json:"warn"`
+	// Version pins the version of the standards such as v1.25, the latest is used if empty.
+	Version string `bson:"version" json:"version"`
+}
+
 type CreateUpdateCommonEnvCfgArgs struct {
 	EnvName              string                        `json:"env_name"`
 	ProductName          string                        `json:"product_name"`
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProjectQuota limits the resources consumed by a project for chargeback, a zero limit means unlimited. The
+// operations beyond a soft limit are allowed with a warning, the ones beyond a hard limit are rejected unless
+// the quota is overridden by a system admin.
+type ProjectQuota struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"  json:"project_name"`
+	// BuildMinutes limits the running minutes of the workflow tasks in a calendar month.
+	BuildMinutes *QuotaLimit `bson:"build_minutes" json:"build_minutes"`
+	// StorageMB limits the size of the artifacts of the workflow tasks kept in the default object storage.
+	StorageMB *QuotaLimit `bson:"storage_mb" json:"storage_mb"`
+	// Environments limits the number of the environments existing at the same time.
+	Environments *QuotaLimit    `bson:"environments"       json:"environments"`
+	Override     *QuotaOverride `bson:"override,omitempty" json:"override,omitempty"`
+	// StorageBytes is the size of the artifacts measured at StorageMeasuredAt, it is measured periodically
+	// since the objects of all the workflows are listed.
+	StorageBytes      int64  `bson:"storage_bytes"       json:"storage_bytes"`
+	StorageMeasuredAt int64  `bson:"storage_measured_at" json:"storage_measured_at"`
+	UpdateTime        int64  `bson:"update_time"         json:"update_time"`
+	UpdateBy          string `bson:"update_by"           json:"update_by"`
+}
+
+type QuotaLimit struct {
+	Soft int64 `bson:"soft" json:"soft"`
+	Hard int64 `bson:"hard" json:"hard"`
+}
+
+// QuotaOverride lifts the hard limits of the project until it expires.
+type QuotaOverride struct {
+	Until     int64  `bson:"until"      json:"until"`
+	Reason    string `bson:"reason"     json:"reason"`
+	CreatedBy string `bson:"created_by" json:"created_by"`
+	CreatedAt int64  `bson:"created_at" json:"created_at"`
+}
+
+func (ProjectQuota) TableName() string {
+	return "project_quota"
+}
+
+// Overridden returns whether the hard limits are lifted now.
+func (q *ProjectQuota) Overridden() bool {
+	return q.Override != nil && q.Override.Until > time.Now().Unix()
+}
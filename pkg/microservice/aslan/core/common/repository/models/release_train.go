@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// ReleaseTrain batches the changes merged into the source workflows, the images built by their webhook
+// triggered tasks wait on the train and are shipped together by the deploy job of the deploy workflow,
+// either on schedule or when the train is dispatched manually.
+type ReleaseTrain struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"        json:"id,omitempty"`
+	Name            string             `bson:"name"                 json:"name"`
+	ProjectName     string             `bson:"project_name"         json:"project_name"`
+	Description     string             `bson:"description"          json:"description"`
+	SourceWorkflows []string           `bson:"source_workflows"     json:"source_workflows"`
+	DeployWorkflow  string             `bson:"deploy_workflow"      json:"deploy_workflow"`
+	DeployJobName   string             `bson:"deploy_job_name"      json:"deploy_job_name"`
+	Schedules       *ScheduleCtrl      `bson:"-"                    json:"schedules,omitempty"`
+	ScheduleEnabled bool               `bson:"schedule_enabled"     json:"schedule_enabled"`
+	CreatedBy       string             `bson:"created_by"           json:"created_by"`
+	CreateTime      int64              `bson:"create_time"          json:"create_time"`
+	UpdatedBy       string             `bson:"updated_by"           json:"updated_by"`
+	UpdateTime      int64              `bson:"update_time"          json:"update_time"`
+
+	// the fields below are updated by the dispatches only.
+	LastVersion      string `bson:"last_version"         json:"last_version"`
+	LastDispatchTime int64  `bson:"last_dispatch_time"   json:"last_dispatch_time"`
+}
+
+func (ReleaseTrain) TableName() string {
+	return "release_train"
+}
+
+// ReleaseTrainItem is a passed task of a source workflow waiting on the train, it is shipped in the
+// delivery version created by the dispatch.
+type ReleaseTrainItem struct {
+	ID             primitive.ObjectID            `bson:"_id,omitempty"              json:"id,omitempty"`
+	TrainID        string                        `bson:"train_id"                   json:"train_id"`
+	ProjectName    string                        `bson:"project_name"               json:"project_name"`
+	WorkflowName   string                        `bson:"workflow_name"              json:"workflow_name"`
+	TaskID         int64                         `bson:"task_id"                    json:"task_id"`
+	MergeRequestID string                        `bson:"merge_request_id,omitempty" json:"merge_request_id,omitempty"`
+	CommitMessage  string                        `bson:"commit_message,omitempty"   json:"commit_message,omitempty"`
+	CommitAuthor   string                        `bson:"commit_author,omitempty"    json:"commit_author,omitempty"`
+	Commits        []*ReleaseTrainCommit         `bson:"commits"                    json:"commits"`
+	Artifacts      []*ServiceAndImage            `bson:"artifacts"                  json:"artifacts"`
+	Status         config.ReleaseTrainItemStatus `bson:"status"                     json:"status"`
+	Version        string                        `bson:"version,omitempty"          json:"version,omitempty"`
+	CreateTime     int64                         `bson:"create_time"                json:"create_time"`
+}
+
+func (ReleaseTrainItem) TableName() string {
+	return "release_train_item"
+}
+
+type ReleaseTrainCommit struct {
+	CodehostID    int    `bson:"codehost_id"              json:"codehost_id"`
+	RepoOwner     string `bson:"repo_owner"               json:"repo_owner"`
+	RepoName      string `bson:"repo_name"                json:"repo_name"`
+	Branch        string `bson:"branch"                   json:"branch"`
+	PR            int    `bson:"pr,omitempty"             json:"pr,omitempty"`
+	CommitID      string `bson:"commit_id"                json:"commit_id"`
+	CommitMessage string `bson:"commit_message,omitempty" json:"commit_message,omitempty"`
+	AuthorName    string `bson:"author_name,omitempty"    json:"author_name,omitempty"`
+}
+
+// ReleaseTrainContent records what a dispatch of the train shipped on the delivery version, the
+// artifacts are the latest image of every service module among the items.
+type ReleaseTrainContent struct {
+	TrainID       string                `bson:"train_id"        json:"train_id"`
+	TrainName     string                `bson:"train_name"      json:"train_name"`
+	Tasks         []*ReleaseTrainTask   `bson:"tasks"           json:"tasks"`
+	MergeRequests []string              `bson:"merge_requests"  json:"merge_requests"`
+	Commits       []*ReleaseTrainCommit `bson:"commits"         json:"commits"`
+	Artifacts     []*ServiceAndImage    `bson:"artifacts"       json:"artifacts"`
+}
+
+type ReleaseTrainTask struct {
+	WorkflowName string `bson:"workflow_name"   json:"workflow_name"`
+	TaskID       int64  `bson:"task_id"         json:"task_id"`
+}
+
+// ReleaseTrainArgs tells the cron service which release train a schedule dispatches.
+type ReleaseTrainArgs struct {
+	ProjectName string `bson:"project_name"   json:"project_name"`
+	TrainID     string `bson:"train_id"       json:"train_id"`
+	TriggeredBy string `bson:"-"              json:"triggered_by,omitempty"`
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+	"github.com/koderover/zadig/pkg/types/job"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+// Runner runs the build jobs outside kubernetes, e.g. for docker-in-docker, nested virtualization or the
+// macOS and Windows builds. The jobs are leased by the agents of the runner.
+type Runner struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"       json:"id,omitempty"`
+	Name        string             `bson:"name"                json:"name"`
+	Type        config.RunnerType  `bson:"type"                json:"type"`
+	Description string             `bson:"description"         json:"description"`
+	// Platform is the os/arch of the agents, e.g. darwin/arm64, it is informative only.
+	Platform string `bson:"platform" json:"platform"`
+	// Token authenticates the agents, it is not returned by the list api.
+	Token string           `bson:"token"         json:"token,omitempty"`
+	EC2   *RunnerEC2Config `bson:"ec2,omitempty" json:"ec2,omitempty"`
+	ECS   *RunnerECSConfig `bson:"ecs,omitempty" json:"ecs,omitempty"`
+	// LastSeenTime is when an agent of the runner asked for a job lastly.
+	LastSeenTime int64  `bson:"last_seen_time" json:"last_seen_time"`
+	UpdatedBy    string `bson:"updated_by"     json:"updated_by"`
+	CreateTime   int64  `bson:"create_time"    json:"create_time"`
+	UpdateTime   int64  `bson:"update_time"    json:"update_time"`
+	// Labels are matched by the jobs targeting the runners by labels instead of the name.
+	Labels []string           `bson:"labels,omitempty" json:"labels,omitempty"`
+	MacOS  *RunnerMacOSConfig `bson:"macos,omitempty"  json:"macos,omitempty"`
+}
+
+func (Runner) TableName() string {
+	return "runner"
+}
+
+type RunnerAWSCredential struct {
+	Region          string `bson:"region"            json:"region"`
+	AccessKeyID     string `bson:"access_key_id"     json:"access_key_id"`
+	SecretAccessKey string `bson:"secret_access_key" json:"secret_access_key"`
+}
+
+// RunnerEC2Config launches the instances from an image with the agent and the job executor installed.
+type RunnerEC2Config struct {
+	RunnerAWSCredential `bson:",inline"`
+	ImageID             string   `bson:"image_id"             json:"image_id"`
+	InstanceType        string   `bson:"instance_type"        json:"instance_type"`
+	SubnetID            string   `bson:"subnet_id"            json:"subnet_id"`
+	SecurityGroupIDs    []string `bson:"security_group_ids"   json:"security_group_ids"`
+	KeyName             string   `bson:"key_name"             json:"key_name"`
+	IAMInstanceProfile  string   `bson:"iam_instance_profile" json:"iam_instance_profile"`
+	// AgentCommand starts the agent in the user data of the instance, it is configured by the
+	// environment variables exported before it.
+	AgentCommand string `bson:"agent_command" json:"agent_command"`
+}
+
+// RunnerECSConfig runs the tasks of a task definition whose container runs the agent.
+type RunnerECSConfig struct {
+	RunnerAWSCredential `bson:",inline"`
+	Cluster             string `bson:"cluster"         json:"cluster"`
+	TaskDefinition      string `bson:"task_definition" json:"task_definition"`
+	// ContainerName is the container the agent runs in, the environment variables are set on it.
+	ContainerName  string   `bson:"container_name"   json:"container_name"`
+	LaunchType     string   `bson:"launch_type"      json:"launch_type"`
+	Subnets        []string `bson:"subnets"          json:"subnets"`
+	SecurityGroups []string `bson:"security_groups"  json:"security_groups"`
+	AssignPublicIP bool     `bson:"assign_public_ip" json:"assign_public_ip"`
+}
+
+// RunnerMacOSConfig is the signing identity the iOS apps are signed with on the runner, the secrets are
+// not returned by the apis.
+type RunnerMacOSConfig struct {
+	Signing *runner.IOSSigning `bson:"signing,omitempty" json:"signing,omitempty"`
+}
+
+// RunnerJob is a job dispatched to a runner, it is leased by an agent which runs it with the job executor
+// and reports the result.
+type RunnerJob struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RunnerName   string             `bson:"runner_name"   json:"runner_name"`
+	ProjectName  string             `bson:"project_name"  json:"project_name"`
+	WorkflowName string             `bson:"workflow_name" json:"workflow_name"`
+	TaskID       int64              `bson:"task_id"       json:"task_id"`
+	JobName      string             `bson:"job_name"      json:"job_name"`
+	// Context is the yaml config of the job executor.
+	Context string `bson:"context" json:"-"`
+	// Timeout is in minutes.
+	Timeout int64         `bson:"timeout"         json:"timeout"`
+	Status  config.Status `bson:"status"          json:"status"`
+	Error   string        `bson:"error,omitempty" json:"error,omitempty"`
+	// Agent is the agent the job is leased by.
+	Agent string `bson:"agent,omitempty" json:"agent,omitempty"`
+	// InstanceID is the ec2 instance or the ecs task launched for the job.
+	InstanceID      string           `bson:"instance_id,omitempty" json:"instance_id,omitempty"`
+	Outputs         []*job.JobOutput `bson:"outputs,omitempty"     json:"outputs,omitempty"`
+	LeaseExpireTime int64            `bson:"lease_expire_time"     json:"lease_expire_time"`
+	CreateTime      int64            `bson:"create_time"           json:"create_time"`
+	StartTime       int64            `bson:"start_time"            json:"start_time"`
+	EndTime         int64            `bson:"end_time"              json:"end_time"`
+	// SecretFindings are found when the log pushed by the agent is redacted.
+	SecretFindings []*secretscan.Finding `bson:"secret_findings,omitempty" json:"secret_findings,omitempty"`
+	// Labels are set if the job targets the runners by labels, RunnerName is empty until it is leased.
+	Labels []string `bson:"labels,omitempty" json:"labels,omitempty"`
+}
+
+func (RunnerJob) TableName() string {
+	return "runner_job"
+}
@@ -72,6 +72,23 @@ type Service struct {
 	EnvName          string           `bson:"env_name,omitempty"             json:"env_name,omitempty"`
 	TemplateID       string           `bson:"template_id,omitempty"          json:"template_id,omitempty"`
 	AutoSync         bool             `bson:"auto_sync"                      json:"auto_sync"`
+	// ResourceStatusMappings defines when the custom resources in the service are ready.
+	ResourceStatusMappings []*ResourceStatusMapping `bson:"resource_status_mappings,omitempty" json:"resource_status_mappings,omitempty"`
+}
+
+// ResourceStatusMapping maps the status of a custom resource to ready, the resource is ready
+// when all the conditions are matched.
+type ResourceStatusMapping struct {
+	APIVersion      string             `bson:"api_version"      json:"api_version"`
+	Kind            string             `bson:"kind"             json:"kind"`
+	ReadyConditions []*StatusCondition `bson:"ready_conditions" json:"ready_conditions"`
+}
+
+// StatusCondition is matched when the value found by JSONPath equals Value,
+// e.g. {.status.conditions[?(@.type=="Ready")].status} equals True.
+type StatusCondition struct {
+	JSONPath string `bson:"json_path" json:"json_path"`
+	Value    string `bson:"value"     json:"value"`
 }
 
 type CreateFromRepo struct {
@@ -79,6 +96,14 @@ type CreateFromRepo struct {
 	LoadPath      string                        `bson:"load_path,omitempty"            json:"load_path,omitempty"`
 }
 
+// CreateFromUmbrellaChart is a subchart of the umbrella chart under LoadPath, the subchart is named SubChart
+// in the umbrella chart and the values of the umbrella chart under its name are its default values.
+type CreateFromUmbrellaChart struct {
+	GitRepoConfig *templatemodels.GitRepoConfig `bson:"git_repo_config,omitempty" json:"git_repo_config,omitempty"`
+	LoadPath      string                        `bson:"load_path"                 json:"load_path"`
+	SubChart      string                        `bson:"sub_chart"                 json:"sub_chart"`
+}
+
 type CreateFromPublicRepo struct {
 	RepoLink string `bson:"repo_link" json:"repo_link"`
 	LoadPath string `bson:"load_path,omitempty"        json:"load_path,omitempty"`
@@ -89,6 +114,8 @@ type CreateFromChartTemplate struct {
 	TemplateName string                     `bson:"template_name" json:"template_name"`
 	ServiceName  string                     `bson:"service_name" json:"service_name"`
 	Variables    []*Variable                `bson:"variables" json:"variables"`
+	// TemplateRevision is the revision of the template the service is rendered from.
+	TemplateRevision int64 `bson:"template_revision,omitempty" json:"template_revision,omitempty"`
 }
 
 type CreateFromChartRepo struct {
@@ -16,14 +16,67 @@ limitations under the License.
 
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/types"
+)
 
 type SystemSetting struct {
-	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	WorkflowConcurrency int64              `bson:"workflow_concurrency" json:"workflow_concurrency"`
-	BuildConcurrency    int64              `bson:"build_concurrency" json:"build_concurrency"`
-	DefaultLogin        string             `bson:"default_login" json:"default_login"`
-	UpdateTime          int64              `bson:"update_time" json:"update_time"`
+	ID                  primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	WorkflowConcurrency int64               `bson:"workflow_concurrency" json:"workflow_concurrency"`
+	BuildConcurrency    int64               `bson:"build_concurrency" json:"build_concurrency"`
+	DefaultLogin        string              `bson:"default_login" json:"default_login"`
+	UpdateTime          int64               `bson:"update_time" json:"update_time"`
+	Maintenance         *MaintenanceSetting `bson:"maintenance,omitempty" json:"maintenance,omitempty"`
+	JobSecurity         *JobSecuritySetting `bson:"job_security,omitempty" json:"job_security,omitempty"`
+	// CodeHostNotify is the IM channel the admins are alerted through when the token of a codehost is about
+	// to expire or can not be refreshed.
+	CodeHostNotify *NotifyCtl `bson:"codehost_notify,omitempty" json:"codehost_notify,omitempty"`
+	// ExternalURL is used to generate the links when zadig is accessed through a reverse proxy or by
+	// more than one domain.
+	ExternalURL *ExternalURLSetting `bson:"external_url,omitempty" json:"external_url,omitempty"`
+	// GitMirror makes the builds fetch the mirrored repos from zadig instead of the codehosts.
+	GitMirror *GitMirrorSetting `bson:"git_mirror,omitempty" json:"git_mirror,omitempty"`
+}
+
+type GitMirrorSetting struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// BaseURL is the address of aslan reachable from the build pods, e.g. https://zadig.example.com/api/aslan,
+	// the address of the aslan service is used if it is empty, which is only reachable from the local cluster.
+	BaseURL string `bson:"base_url" json:"base_url"`
+	// SyncInterval is the minutes between the periodic updates of the mirrors, they are updated on the
+	// webhooks of the repos as well.
+	SyncInterval int64 `bson:"sync_interval" json:"sync_interval"`
+}
+
+type ExternalURLSetting struct {
+	// BaseURLs are the base urls with the protocol and the path prefix, e.g. https://zadig.example.com/ci,
+	// the first one is used for the links in the notifications and the webhooks.
+	BaseURLs              []string `bson:"base_urls"               json:"base_urls"`
+	TrustForwardedHeaders bool     `bson:"trust_forwarded_headers" json:"trust_forwarded_headers"`
+}
+
+// MaintenanceSetting stops the platform from accepting new tasks so that it can be upgraded without
+// killing the running ones.
+type MaintenanceSetting struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Checkpoint is true if the running custom workflow tasks are resumed after the upgrade instead of
+	// being drained.
+	Checkpoint bool   `bson:"checkpoint" json:"checkpoint"`
+	Message    string `bson:"message" json:"message"`
+	// RetryAfter is the seconds the clients are suggested to wait before retrying.
+	RetryAfter int64  `bson:"retry_after" json:"retry_after"`
+	StartedBy  string `bson:"started_by" json:"started_by"`
+	StartTime  int64  `bson:"start_time" json:"start_time"`
+}
+
+// JobSecuritySetting is enforced on the jobs of all the projects, the projects can only choose a profile
+// at least as strict as the minimum one.
+type JobSecuritySetting struct {
+	MinimumProfile types.JobSecurityProfile `bson:"minimum_profile" json:"minimum_profile"`
+	UpdatedBy      string                   `bson:"updated_by"      json:"updated_by"`
+	UpdateTime     int64                    `bson:"update_time"     json:"update_time"`
 }
 
 func (SystemSetting) TableName() string {
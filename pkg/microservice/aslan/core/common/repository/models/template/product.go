@@ -20,6 +20,8 @@ import (
 	"strings"
 
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+	"github.com/koderover/zadig/pkg/types"
 )
 
 // Vars不做保存，只做input参数
@@ -43,6 +45,10 @@ type Product struct {
 	Description         string                `bson:"description,omitempty"     json:"desc,omitempty"`
 	ProductFeature      *ProductFeature       `bson:"product_feature,omitempty" json:"product_feature,omitempty"`
 	ImageSearchingRules []*ImageSearchingRule `bson:"image_searching_rules,omitempty" json:"image_searching_rules,omitempty"`
+	ServiceDependencies []*ServiceDependency  `bson:"service_dependencies,omitempty" json:"service_dependencies,omitempty"`
+	CommitPolicy        *CommitPolicy         `bson:"commit_policy,omitempty"         json:"commit_policy,omitempty"`
+	ManifestPolicy      *ManifestPolicy       `bson:"manifest_policy,omitempty"       json:"manifest_policy,omitempty"`
+	ManifestLint        *ManifestLintPolicy   `bson:"manifest_lint,omitempty"         json:"manifest_lint,omitempty"`
 	// onboarding状态，0表示onboarding完成，1、2、3、4代表当前onboarding所在的步骤
 	OnboardingStatus int `bson:"onboarding_status"         json:"onboarding_status"`
 	// CI场景的onboarding流程创建的ci工作流id，用于前端跳转
@@ -71,6 +77,139 @@ type Product struct {
 	CustomTarRule              *CustomRule          `bson:"custom_tar_rule,omitempty"           json:"custom_tar_rule,omitempty"`
 	DeliveryVersionHook        *DeliveryVersionHook `bson:"delivery_version_hook"               json:"delivery_version_hook"`
 	Public                     bool                 `bson:"public,omitempty"                    json:"public"`
+	LicensePolicy              *LicensePolicy       `bson:"license_policy,omitempty"            json:"license_policy,omitempty"`
+	SecretScanPolicy           *SecretScanPolicy    `bson:"secret_scan_policy,omitempty"        json:"secret_scan_policy,omitempty"`
+	JobSecurityPolicy          *JobSecurityPolicy   `bson:"job_security_policy,omitempty"       json:"job_security_policy,omitempty"`
+	GerritVotingPolicy         *GerritVotingPolicy  `bson:"gerrit_voting_policy,omitempty"      json:"gerrit_voting_policy,omitempty"`
+	// CustomFields are the values of the custom fields of the projects defined by the admins, keyed by the field keys.
+	CustomFields map[string]string `bson:"custom_fields,omitempty" json:"custom_fields,omitempty"`
+}
+
+// ServiceDependency declares the services a service depends on, the service is deployed after
+// all the services it depends on are ready.
+type ServiceDependency struct {
+	ServiceName string   `bson:"service_name" json:"service_name"`
+	DependsOn   []string `bson:"depends_on"   json:"depends_on"`
+	// WaitFor is the condition the dependents of the service wait for, nil means the workloads
+	// of the service are ready.
+	WaitFor *ServiceReadyCondition `bson:"wait_for,omitempty" json:"wait_for,omitempty"`
+}
+
+// CommitPolicy is checked against the commits of the build repos when a workflow task deploys to
+// a protected environment.
+type CommitPolicy struct {
+	Enabled bool `bson:"enabled"                json:"enabled"`
+	// RequireSigned requires the commits to be GPG or SSH signed and verified by the codehost.
+	RequireSigned bool `bson:"require_signed"         json:"require_signed"`
+	// AllowedAuthorDomains are the email domains the commit authors must belong to, e.g. koderover.com,
+	// any author is allowed if it is empty.
+	AllowedAuthorDomains []string `bson:"allowed_author_domains" json:"allowed_author_domains"`
+	// ProtectedEnvs are the environments the policy is applied to, all environments are protected if it is empty.
+	ProtectedEnvs []string `bson:"protected_envs"         json:"protected_envs"`
+}
+
+// ManifestPolicy is evaluated against the rendered manifests by the deploy jobs of workflows, the
+// deployment is blocked if any violation is found.
+type ManifestPolicy struct {
+	Enabled bool `bson:"enabled"           json:"enabled"`
+	// DisabledRules are the bundled rules not applied, e.g. missing-limits.
+	DisabledRules []string `bson:"disabled_rules"    json:"disabled_rules"`
+	// BannedRegistries are the image prefixes not allowed by the banned-registries rule, e.g. docker.io/.
+	BannedRegistries []string `bson:"banned_registries" json:"banned_registries"`
+	// Policies are provided by the project, they are evaluated in the same way as the bundled rules.
+	Policies []*RegoPolicy `bson:"policies"          json:"policies"`
+	// ProtectedEnvs are the environments the policy is applied to, all environments are protected if it is empty.
+	ProtectedEnvs []string `bson:"protected_envs"    json:"protected_envs"`
+}
+
+// ManifestLintPolicy runs the best-practice checks on the rendered manifests before the deploy jobs of
+// workflows apply them, the deployment is blocked if any finding is at least as severe as FailOn.
+type ManifestLintPolicy struct {
+	Enabled bool `bson:"enabled"        json:"enabled"`
+	// DisabledRules are the checks not run, e.g. missing-probes.
+	DisabledRules []string `bson:"disabled_rules" json:"disabled_rules"`
+	// FailOn is the lowest severity blocking the deployment, error or warning, it is error by default.
+	FailOn string `bson:"fail_on"        json:"fail_on"`
+	// ProtectedEnvs are the environments the gate is applied to, all environments are protected if it is empty.
+	ProtectedEnvs []string `bson:"protected_envs" json:"protected_envs"`
+}
+
+// RegoPolicy reports violations in the same way as gatekeeper, i.e. by the rule violation[{"msg": msg}]
+// with the resource in input.review.object and the parameters of the project in input.parameters. The
+// package of the policy is replaced when it is uploaded.
+type RegoPolicy struct {
+	Name string `bson:"name" json:"name"`
+	Rego string `bson:"rego" json:"rego"`
+}
+
+// LicensePolicy is enforced by the license scan jobs of workflows against the licenses of the packages
+// found in the scanned images, the job fails if any violation is not waived.
+type LicensePolicy struct {
+	// Allowed are the licenses, e.g. MIT, the packages are allowed to use, any license not denied is
+	// allowed if it is empty.
+	Allowed []string `bson:"allowed"         json:"allowed"`
+	Denied  []string `bson:"denied"          json:"denied"`
+	// FailOnUnknown reports the packages whose licenses can not be detected as violations.
+	FailOnUnknown bool             `bson:"fail_on_unknown" json:"fail_on_unknown"`
+	Waivers       []*LicenseWaiver `bson:"waivers"         json:"waivers"`
+}
+
+// SecretScanPolicy detects the secrets leaked in the source diffs checked out and the logs of the jobs of
+// the workflows, the secrets found in the logs are redacted before they are saved.
+type SecretScanPolicy struct {
+	Enabled bool `bson:"enabled"         json:"enabled"`
+	// ScanSource scans the changes of the pull requests merged by the git steps.
+	ScanSource bool `bson:"scan_source"     json:"scan_source"`
+	ScanLogs   bool `bson:"scan_logs"       json:"scan_logs"`
+	// FailOnFinding fails the job if any secret is found.
+	FailOnFinding bool               `bson:"fail_on_finding" json:"fail_on_finding"`
+	Rules         *secretscan.Config `bson:"rules"           json:"rules"`
+}
+
+// JobSecurityPolicy is the security profile of the job pods of the project, the minimum profile of the
+// system is used if it is stricter.
+type JobSecurityPolicy struct {
+	Profile types.JobSecurityProfile `bson:"profile" json:"profile"`
+}
+
+// GerritVotingPolicy maps the results of the workflow tasks triggered by the gerrit changes to the votes on the
+// labels of the changes, e.g. Verified +1 when the build passes. The label of the webhook is voted +1 or -1 if
+// it is disabled.
+type GerritVotingPolicy struct {
+	Enabled bool               `bson:"enabled" json:"enabled"`
+	Votes   []*GerritLabelVote `bson:"votes"   json:"votes"`
+}
+
+// GerritLabelVote is the values voted on the label for the results of the tasks, such as +1 or -2, nothing is
+// voted for a result without a value. The vote is reset to 0 when a task is created if Reset is true.
+type GerritLabelVote struct {
+	Label     string `bson:"label"     json:"label"`
+	Passed    string `bson:"passed"    json:"passed"`
+	Failed    string `bson:"failed"    json:"failed"`
+	Timeout   string `bson:"timeout"   json:"timeout"`
+	Cancelled string `bson:"cancelled" json:"cancelled"`
+	Reset     bool   `bson:"reset"     json:"reset"`
+}
+
+// LicenseWaiver accepts the violations of a package or a license, at least one of them is set.
+type LicenseWaiver struct {
+	ID string `bson:"id"          json:"id"`
+	// Package is the name of the package, any package is waived if it is empty.
+	Package string `bson:"package"     json:"package"`
+	// License is waived for the package, any license is waived if it is empty.
+	License    string `bson:"license"     json:"license"`
+	Reason     string `bson:"reason"      json:"reason"`
+	CreatedBy  string `bson:"created_by"  json:"created_by"`
+	CreateTime int64  `bson:"create_time" json:"create_time"`
+	// ExpireTime is the unix time the waiver expires at, it never expires if it is 0.
+	ExpireTime int64 `bson:"expire_time" json:"expire_time"`
+}
+
+type ServiceReadyCondition struct {
+	// Type is ready or none, the dependents do not wait for the service if it is none.
+	Type string `bson:"type"    json:"type"`
+	// Timeout is the seconds to wait for the service to be ready.
+	Timeout int `bson:"timeout" json:"timeout"`
 }
 
 type ServiceInfo struct {
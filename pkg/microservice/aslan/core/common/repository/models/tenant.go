@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tenant groups projects and the resources they use, when the tenant isolation is enabled the
+// resources bound to a tenant are only visible to its members. Resources not bound to any tenant
+// are shared by all users.
+type Tenant struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"          json:"id,omitempty"`
+	Key         string             `bson:"key"                    json:"key"`
+	Name        string             `bson:"name"                   json:"name"`
+	Description string             `bson:"description"            json:"description"`
+	// Admins and Members are user ids, admins can manage the members of the tenant.
+	Admins     []string     `bson:"admins"                 json:"admins"`
+	Members    []string     `bson:"members"                json:"members"`
+	Projects   []string     `bson:"projects"               json:"projects"`
+	Clusters   []string     `bson:"clusters"               json:"clusters"`
+	Registries []string     `bson:"registries"             json:"registries"`
+	CodeHosts  []int        `bson:"code_hosts"             json:"code_hosts"`
+	Quota      *TenantQuota `bson:"quota"                  json:"quota"`
+	CreateTime int64        `bson:"create_time"            json:"create_time"`
+	UpdateTime int64        `bson:"update_time"            json:"update_time"`
+	UpdateBy   string       `bson:"update_by"              json:"update_by"`
+}
+
+// TenantQuota limits the resources used by a tenant, 0 means unlimited.
+type TenantQuota struct {
+	MaxProjects            int `bson:"max_projects"             json:"max_projects"`
+	MaxWorkflowConcurrency int `bson:"max_workflow_concurrency" json:"max_workflow_concurrency"`
+}
+
+func (Tenant) TableName() string {
+	return "tenant"
+}
+
+// HasUser returns whether the user is a member or an admin of the tenant.
+func (t *Tenant) HasUser(uid string) bool {
+	return t.IsAdmin(uid) || containsString(t.Members, uid)
+}
+
+func (t *Tenant) IsAdmin(uid string) bool {
+	return containsString(t.Admins, uid)
+}
+
+func containsString(list []string, item string) bool {
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
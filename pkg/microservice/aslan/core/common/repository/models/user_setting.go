@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// UserSetting is the personal settings of a user, the language is used for the API error messages
+// and the notifications of the tasks created by the user.
+type UserSetting struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	UserID     string             `bson:"user_id"        json:"user_id"`
+	UserName   string             `bson:"user_name"      json:"user_name"`
+	Language   string             `bson:"language"       json:"language"`
+	UpdateTime int64              `bson:"update_time"    json:"update_time"`
+	// Notification is how the user is mentioned in the messages of the notification routes.
+	Notification *NotificationPreference `bson:"notification,omitempty" json:"notification,omitempty"`
+}
+
+// NotificationPreference is whether and how a user wants to be mentioned by the notification routes, the user
+// is still mentioned in the escalated messages regardless of it.
+type NotificationPreference struct {
+	Mobile        string                      `bson:"mobile"                json:"mobile"`
+	Muted         bool                        `bson:"muted"                 json:"muted"`
+	MutedProjects []string                    `bson:"muted_projects"        json:"muted_projects"`
+	MinSeverity   config.NotificationSeverity `bson:"min_severity"          json:"min_severity"`
+	QuietHours    *QuietHours                 `bson:"quiet_hours,omitempty" json:"quiet_hours,omitempty"`
+}
+
+func (UserSetting) TableName() string {
+	return "user_setting"
+}
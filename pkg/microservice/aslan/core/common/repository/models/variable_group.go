@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// VariableGroup is a named set of variables in a project which is referenced by the builds and the freestyle
+// jobs, so that a credential is rotated in one place. Every update increases the revision.
+type VariableGroup struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"    json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"     json:"project_name"`
+	Name        string             `bson:"name"             json:"name"`
+	Description string             `bson:"description"      json:"description"`
+	Variables   []*KeyVal          `bson:"variables"        json:"variables"`
+	Revision    int64              `bson:"revision"         json:"revision"`
+	CreateBy    string             `bson:"create_by"        json:"create_by"`
+	UpdateBy    string             `bson:"update_by"        json:"update_by"`
+	CreateTime  int64              `bson:"create_time"      json:"create_time"`
+	UpdateTime  int64              `bson:"update_time"      json:"update_time"`
+}
+
+func (VariableGroup) TableName() string {
+	return "variable_group"
+}
+
+// VariableGroupHistory records the keys changed by a revision of a variable group, the values are never saved.
+type VariableGroupHistory struct {
+	ID          primitive.ObjectID     `bson:"_id,omitempty"    json:"id,omitempty"`
+	ProjectName string                 `bson:"project_name"     json:"project_name"`
+	GroupName   string                 `bson:"group_name"       json:"group_name"`
+	Revision    int64                  `bson:"revision"         json:"revision"`
+	Changes     []*VariableGroupChange `bson:"changes"          json:"changes"`
+	UpdateBy    string                 `bson:"update_by"        json:"update_by"`
+	CreateTime  int64                  `bson:"create_time"      json:"create_time"`
+}
+
+type VariableGroupChange struct {
+	Key    string                           `bson:"key"     json:"key"`
+	Action config.VariableGroupChangeAction `bson:"action"  json:"action"`
+}
+
+func (VariableGroupHistory) TableName() string {
+	return "variable_group_history"
+}
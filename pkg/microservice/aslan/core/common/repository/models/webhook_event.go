@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookEvent is a webhook received from a code host, it is persisted before being processed
+// so that it can be retried if the processing fails.
+type WebhookEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RequestID string             `bson:"request_id"    json:"request_id"`
+	Source    string             `bson:"source"        json:"source"`
+	Event     string             `bson:"event"         json:"event"`
+	// RequestURI is kept since the gerrit and gitee hooks read the parameters from it.
+	RequestURI string              `bson:"request_uri" json:"request_uri"`
+	Header     map[string][]string `bson:"header"      json:"header"`
+	Payload    string              `bson:"payload"     json:"payload"`
+	Status     string              `bson:"status"      json:"status"`
+	Attempts   int                 `bson:"attempts"    json:"attempts"`
+	LastError  string              `bson:"last_error"  json:"last_error"`
+	// Processed are the processors finished in the previous attempts, they are skipped in
+	// the retries so that a workflow is not triggered twice by the same event.
+	Processed []string `bson:"processed" json:"processed"`
+	// NextAttemptTime is the time after which a pending event can be processed.
+	NextAttemptTime int64 `bson:"next_attempt_time" json:"next_attempt_time"`
+	// LockedUntil is the time until which a processing event is held by a worker, the event is
+	// picked up again if the worker dies before finishing it.
+	LockedUntil int64 `bson:"locked_until" json:"locked_until"`
+	CreateTime  int64 `bson:"create_time"  json:"create_time"`
+	UpdateTime  int64 `bson:"update_time"  json:"update_time"`
+	// ExpireAt is used by the ttl index to clean up the processed events.
+	ExpireAt *time.Time `bson:"expire_at,omitempty" json:"-"`
+}
+
+func (WebhookEvent) TableName() string {
+	return "webhook_event"
+}
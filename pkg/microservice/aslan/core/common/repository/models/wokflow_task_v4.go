@@ -17,9 +17,16 @@ limitations under the License.
 package models
 
 import (
+	"fmt"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/tool/diagnosis"
+	"github.com/koderover/zadig/pkg/tool/logannotation"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+	"github.com/koderover/zadig/pkg/tool/summary"
 )
 
 type WorkflowTask struct {
@@ -44,12 +51,50 @@ type WorkflowTask struct {
 	Error              string             `bson:"error,omitempty"           json:"error,omitempty"`
 	IsRestart          bool               `bson:"is_restart"                json:"is_restart"`
 	MultiRun           bool               `bson:"multi_run"                 json:"multi_run"`
+	// Labels are given by the caller when the task is created, the tasks can be searched by them.
+	Labels map[string]string `bson:"labels,omitempty" json:"labels,omitempty"`
+	// Hotfix tasks are run before the other waiting tasks regardless of the concurrency limits.
+	Hotfix       bool   `bson:"hotfix,omitempty"        json:"hotfix,omitempty"`
+	HotfixReason string `bson:"hotfix_reason,omitempty" json:"hotfix_reason,omitempty"`
+	// ExecutionHookResults are the answers of the execution hooks of the workflow called by the task.
+	ExecutionHookResults []*ExecutionHookResult `bson:"execution_hook_results,omitempty" json:"execution_hook_results,omitempty"`
+}
+
+type ExecutionHookResult struct {
+	Name     string                       `bson:"name"               json:"name"`
+	Event    config.ExecutionHookEvent    `bson:"event"              json:"event"`
+	Decision config.ExecutionHookDecision `bson:"decision,omitempty" json:"decision,omitempty"`
+	Message  string                       `bson:"message,omitempty"  json:"message,omitempty"`
+	Error    string                       `bson:"error,omitempty"    json:"error,omitempty"`
+	CalledAt int64                        `bson:"called_at"          json:"called_at"`
 }
 
 func (WorkflowTask) TableName() string {
 	return "workflow_task"
 }
 
+// JobSummaries returns the summaries reported by all the jobs of the task.
+func (t *WorkflowTask) JobSummaries() []*JobSummary {
+	var resp []*JobSummary
+	for _, stage := range t.Stages {
+		for _, job := range stage.Jobs {
+			resp = append(resp, job.Summaries...)
+		}
+	}
+	return resp
+}
+
+// LogAnnotations returns the annotations of the logs of all the failed jobs of the task.
+func (t *WorkflowTask) LogAnnotations() []*logannotation.Annotation {
+	var resp []*logannotation.Annotation
+	for _, stage := range t.Stages {
+		for _, job := range stage.Jobs {
+			resp = append(resp, job.Annotations...)
+		}
+	}
+	return resp
+}
+
 type StageTask struct {
 	Name      string        `bson:"name"          json:"name"`
 	Status    config.Status `bson:"status"        json:"status"`
@@ -72,6 +117,55 @@ type JobTask struct {
 	Retry     int64         `bson:"retry"               json:"retry"`
 	Spec      interface{}   `bson:"spec"                json:"spec"`
 	Outputs   []*Output     `bson:"outputs"             json:"outputs"`
+	// K8sJobName is the kubernetes job running the job, it is used to reattach the job after aslan restarts.
+	K8sJobName string `bson:"k8s_job_name,omitempty" json:"k8s_job_name,omitempty"`
+	// DependsOn are the names of the jobs in the same stage which must pass before the job runs.
+	DependsOn []string `bson:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// SecretFindings are the secrets found in the source checked out and the log of the job.
+	SecretFindings []*secretscan.Finding `bson:"secret_findings,omitempty" json:"secret_findings,omitempty"`
+	// Diagnoses are the probable causes and the remediations of the failure of the job.
+	Diagnoses []*diagnosis.Diagnosis `bson:"diagnoses,omitempty" json:"diagnoses,omitempty"`
+	// Annotations are the errors in the log of the failed job which refer to the source.
+	Annotations []*logannotation.Annotation `bson:"annotations,omitempty" json:"annotations,omitempty"`
+	// RunnerJobID is the job dispatched to the runner if the job runs outside kubernetes.
+	RunnerJobID string `bson:"runner_job_id,omitempty" json:"runner_job_id,omitempty"`
+	// Debug is the pod kept alive for debugging after the job fails.
+	Debug *JobDebugSession `bson:"debug,omitempty" json:"debug,omitempty"`
+	// EnvLock is the lock held by someone else which the deploy job is waiting for.
+	EnvLock *EnvLock `bson:"env_lock,omitempty" json:"env_lock,omitempty"`
+	// Summaries are reported by the summary steps of the job.
+	Summaries []*JobSummary `bson:"summaries,omitempty" json:"summaries,omitempty"`
+}
+
+// JobSummary is the summary file reported by a step, Error is set if the file can not be parsed.
+type JobSummary struct {
+	JobName         string `bson:"job_name"        json:"job_name"`
+	StepName        string `bson:"step_name"       json:"step_name"`
+	Error           string `bson:"error,omitempty" json:"error,omitempty"`
+	summary.Summary `bson:",inline"`
+}
+
+// Markdown renders the summary in markdown, the step name is the title if the summary does not have one.
+func (s *JobSummary) Markdown() string {
+	if s.Error != "" {
+		return fmt.Sprintf("#### %s\n\n%s", s.StepName, s.Error)
+	}
+	return s.Summary.Markdown(s.StepName)
+}
+
+type JobDebugSession struct {
+	ClusterID     string `bson:"cluster_id"         json:"cluster_id"`
+	Namespace     string `bson:"namespace"          json:"namespace"`
+	PodName       string `bson:"pod_name"           json:"pod_name"`
+	ContainerName string `bson:"container_name"     json:"container_name"`
+	ExpireTime    int64  `bson:"expire_time"        json:"expire_time"`
+	EndTime       int64  `bson:"end_time,omitempty" json:"end_time,omitempty"`
+	EndedBy       string `bson:"ended_by,omitempty" json:"ended_by,omitempty"`
+}
+
+// Active tells whether the pod of the debug session can still be accessed.
+func (s *JobDebugSession) Active() bool {
+	return s != nil && s.EndTime == 0 && time.Now().Unix() < s.ExpireTime
 }
 
 type JobTaskCustomDeploySpec struct {
@@ -87,15 +181,23 @@ type JobTaskCustomDeploySpec struct {
 }
 
 type JobTaskDeploySpec struct {
-	Env                string     `bson:"env"                              json:"env"                                 yaml:"env"`
-	ServiceName        string     `bson:"service_name"                     json:"service_name"                        yaml:"service_name"`
-	ServiceType        string     `bson:"service_type"                     json:"service_type"                        yaml:"service_type"`
-	ServiceModule      string     `bson:"service_module"                   json:"service_module"                      yaml:"service_module"`
-	SkipCheckRunStatus bool       `bson:"skip_check_run_status"            json:"skip_check_run_status"               yaml:"skip_check_run_status"`
-	Image              string     `bson:"image"                            json:"image"                               yaml:"image"`
-	ClusterID          string     `bson:"cluster_id"                       json:"cluster_id"                          yaml:"cluster_id"`
-	Timeout            int        `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
-	ReplaceResources   []Resource `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
+	Env                 string                     `bson:"env"                              json:"env"                                 yaml:"env"`
+	ServiceName         string                     `bson:"service_name"                     json:"service_name"                        yaml:"service_name"`
+	ServiceType         string                     `bson:"service_type"                     json:"service_type"                        yaml:"service_type"`
+	ServiceModule       string                     `bson:"service_module"                   json:"service_module"                      yaml:"service_module"`
+	SkipCheckRunStatus  bool                       `bson:"skip_check_run_status"            json:"skip_check_run_status"               yaml:"skip_check_run_status"`
+	Image               string                     `bson:"image"                            json:"image"                               yaml:"image"`
+	ClusterID           string                     `bson:"cluster_id"                       json:"cluster_id"                          yaml:"cluster_id"`
+	Timeout             int                        `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
+	ReplaceResources    []Resource                 `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
+	DiffApproval        *DiffApproval              `bson:"diff_approval,omitempty"          json:"diff_approval,omitempty"             yaml:"diff_approval,omitempty"`
+	PolicyViolations    []*ManifestPolicyViolation `bson:"policy_violations,omitempty"      json:"policy_violations,omitempty"         yaml:"policy_violations,omitempty"`
+	LintFindings        []*ManifestLintFinding     `bson:"lint_findings,omitempty"          json:"lint_findings,omitempty"             yaml:"lint_findings,omitempty"`
+	PinImageDigest      bool                       `bson:"pin_image_digest"                 json:"pin_image_digest"                    yaml:"pin_image_digest"`
+	Verification        *DeployVerification        `bson:"verification,omitempty"           json:"verification,omitempty"              yaml:"verification,omitempty"`
+	VerificationResults []*VerificationResult      `bson:"verification_results,omitempty"   json:"verification_results,omitempty"      yaml:"verification_results,omitempty"`
+	RolledBack          bool                       `bson:"rolled_back"                      json:"rolled_back"                         yaml:"rolled_back"`
+	ImageDigest         string                     `bson:"image_digest,omitempty"           json:"image_digest,omitempty"              yaml:"image_digest,omitempty"`
 }
 
 type Resource struct {
@@ -106,20 +208,90 @@ type Resource struct {
 }
 
 type JobTaskHelmDeploySpec struct {
-	Env                string                   `bson:"env"                              json:"env"                                 yaml:"env"`
-	ServiceName        string                   `bson:"service_name"                     json:"service_name"                        yaml:"service_name"`
-	ServiceType        string                   `bson:"service_type"                     json:"service_type"                        yaml:"service_type"`
-	SkipCheckRunStatus bool                     `bson:"skip_check_run_status"            json:"skip_check_run_status"               yaml:"skip_check_run_status"`
-	ImageAndModules    []*ImageAndServiceModule `bson:"image_and_service_modules"        json:"image_and_service_modules"           yaml:"image_and_service_modules"`
-	ClusterID          string                   `bson:"cluster_id"                       json:"cluster_id"                          yaml:"cluster_id"`
-	ReleaseName        string                   `bson:"release_name"                     json:"release_name"                        yaml:"release_name"`
-	Timeout            int                      `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
-	ReplaceResources   []Resource               `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
+	Env                 string                     `bson:"env"                              json:"env"                                 yaml:"env"`
+	ServiceName         string                     `bson:"service_name"                     json:"service_name"                        yaml:"service_name"`
+	ServiceType         string                     `bson:"service_type"                     json:"service_type"                        yaml:"service_type"`
+	SkipCheckRunStatus  bool                       `bson:"skip_check_run_status"            json:"skip_check_run_status"               yaml:"skip_check_run_status"`
+	ImageAndModules     []*ImageAndServiceModule   `bson:"image_and_service_modules"        json:"image_and_service_modules"           yaml:"image_and_service_modules"`
+	ClusterID           string                     `bson:"cluster_id"                       json:"cluster_id"                          yaml:"cluster_id"`
+	ReleaseName         string                     `bson:"release_name"                     json:"release_name"                        yaml:"release_name"`
+	Timeout             int                        `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
+	ReplaceResources    []Resource                 `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
+	DiffApproval        *DiffApproval              `bson:"diff_approval,omitempty"          json:"diff_approval,omitempty"             yaml:"diff_approval,omitempty"`
+	PolicyViolations    []*ManifestPolicyViolation `bson:"policy_violations,omitempty"      json:"policy_violations,omitempty"         yaml:"policy_violations,omitempty"`
+	LintFindings        []*ManifestLintFinding     `bson:"lint_findings,omitempty"          json:"lint_findings,omitempty"             yaml:"lint_findings,omitempty"`
+	PinImageDigest      bool                       `bson:"pin_image_digest"                 json:"pin_image_digest"                    yaml:"pin_image_digest"`
+	Verification        *DeployVerification        `bson:"verification,omitempty"           json:"verification,omitempty"              yaml:"verification,omitempty"`
+	VerificationResults []*VerificationResult      `bson:"verification_results,omitempty"   json:"verification_results,omitempty"      yaml:"verification_results,omitempty"`
+	RolledBack          bool                       `bson:"rolled_back"                      json:"rolled_back"                         yaml:"rolled_back"`
+}
+
+// DiffApproval holds the changes a deploy job is going to apply, the job waits for the confirmation
+// before applying them if the environment requires it.
+type DiffApproval struct {
+	Required        bool                   `bson:"required"                         json:"required"                            yaml:"required"`
+	Diffs           []*ResourceDiff        `bson:"diffs"                            json:"diffs"                               yaml:"diffs"`
+	RejectOrApprove config.ApproveOrReject `bson:"reject_or_approve"                json:"reject_or_approve"                   yaml:"reject_or_approve"`
+	ConfirmedBy     string                 `bson:"confirmed_by"                     json:"confirmed_by"                        yaml:"confirmed_by"`
+	ConfirmTime     int64                  `bson:"confirm_time"                     json:"confirm_time"                        yaml:"confirm_time"`
+	// Deprecations warns about the resources which break when the cluster is upgraded to the next version.
+	Deprecations []*APIDeprecation `bson:"deprecations,omitempty"           json:"deprecations,omitempty"              yaml:"deprecations,omitempty"`
+}
+
+// ResourceDiff is the yaml diff of a single resource, Current is empty if the resource does not exist yet.
+type ResourceDiff struct {
+	Kind    string `bson:"kind"                                 json:"kind"                                    yaml:"kind"`
+	Name    string `bson:"name"                                 json:"name"                                    yaml:"name"`
+	Current string `bson:"current"                              json:"current"                                 yaml:"current"`
+	Latest  string `bson:"latest"                               json:"latest"                                  yaml:"latest"`
+	Diff    string `bson:"diff"                                 json:"diff"                                    yaml:"diff"`
+}
+
+// VerificationResult is the result of a check of the post-deploy verification.
+type VerificationResult struct {
+	Type    string `bson:"type"                                 json:"type"                                    yaml:"type"`
+	Target  string `bson:"target"                               json:"target"                                  yaml:"target"`
+	Passed  bool   `bson:"passed"                               json:"passed"                                  yaml:"passed"`
+	Message string `bson:"message"                              json:"message"                                 yaml:"message"`
+}
+
+// ManifestPolicyViolation is a violation of the manifest policy of the project found in a rendered resource.
+type ManifestPolicyViolation struct {
+	Rule    string `bson:"rule"                                 json:"rule"                                    yaml:"rule"`
+	Kind    string `bson:"kind"                                 json:"kind"                                    yaml:"kind"`
+	Name    string `bson:"name"                                 json:"name"                                    yaml:"name"`
+	Message string `bson:"message"                              json:"message"                                 yaml:"message"`
+}
+
+// ManifestLintFinding is a best-practice issue found in a rendered resource, Container is empty if the
+// finding is about the resource itself.
+type ManifestLintFinding struct {
+	Rule      string `bson:"rule"                                 json:"rule"                                    yaml:"rule"`
+	Severity  string `bson:"severity"                             json:"severity"                                yaml:"severity"`
+	Kind      string `bson:"kind"                                 json:"kind"                                    yaml:"kind"`
+	Name      string `bson:"name"                                 json:"name"                                    yaml:"name"`
+	Container string `bson:"container,omitempty"                  json:"container,omitempty"                     yaml:"container,omitempty"`
+	Message   string `bson:"message"                              json:"message"                                 yaml:"message"`
+}
+
+// APIDeprecation is a resource using an api version deprecated or removed in a kubernetes version up to
+// the target version, Severity is error if the api version is already removed in the cluster.
+type APIDeprecation struct {
+	APIVersion   string `bson:"api_version"                          json:"api_version"                             yaml:"api_version"`
+	Kind         string `bson:"kind"                                 json:"kind"                                    yaml:"kind"`
+	Name         string `bson:"name"                                 json:"name"                                    yaml:"name"`
+	Namespace    string `bson:"namespace,omitempty"                  json:"namespace,omitempty"                     yaml:"namespace,omitempty"`
+	DeprecatedIn string `bson:"deprecated_in,omitempty"              json:"deprecated_in,omitempty"                 yaml:"deprecated_in,omitempty"`
+	RemovedIn    string `bson:"removed_in"                           json:"removed_in"                              yaml:"removed_in"`
+	Replacement  string `bson:"replacement,omitempty"                json:"replacement,omitempty"                   yaml:"replacement,omitempty"`
+	Severity     string `bson:"severity"                             json:"severity"                                yaml:"severity"`
+	Message      string `bson:"message"                              json:"message"                                 yaml:"message"`
 }
 
 type ImageAndServiceModule struct {
 	ServiceModule string `bson:"service_module"                     json:"service_module"                        yaml:"service_module"`
 	Image         string `bson:"image"                              json:"image"                                 yaml:"image"`
+	ImageDigest   string `bson:"image_digest,omitempty"             json:"image_digest,omitempty"                yaml:"image_digest,omitempty"`
 }
 
 type JobTaskBuildSpec struct {
@@ -132,6 +304,129 @@ type JobTaskPluginSpec struct {
 	Plugin     *PluginTemplate `bson:"plugin"              json:"plugin"            yaml:"plugin"`
 }
 
+type JobTaskJenkinsSpec struct {
+	ID      string          `bson:"id"                  json:"id"                yaml:"id"`
+	Timeout int64           `bson:"timeout"             json:"timeout"           yaml:"timeout"`
+	Job     *JenkinsJobInfo `bson:"job"                 json:"job"               yaml:"job"`
+	// results of the triggered jenkins build, filled in by the job controller.
+	BuildNumber int64    `bson:"build_number"        json:"build_number"      yaml:"build_number"`
+	BuildURL    string   `bson:"build_url"           json:"build_url"         yaml:"build_url"`
+	Result      string   `bson:"result"              json:"result"            yaml:"result"`
+	Artifacts   []string `bson:"artifacts"           json:"artifacts"         yaml:"artifacts"`
+}
+
+type JobTaskZadigRolloutSpec struct {
+	Version        string             `bson:"version"             json:"version"           yaml:"version"`
+	Images         []*ServiceAndImage `bson:"images"              json:"images"            yaml:"images"`
+	PauseAfterWave bool               `bson:"pause_after_wave"    json:"pause_after_wave"  yaml:"pause_after_wave"`
+	Timeout        int                `bson:"timeout"             json:"timeout"           yaml:"timeout"`
+	Waves          []*RolloutWaveTask `bson:"waves"               json:"waves"             yaml:"waves"`
+	// Paused is set when the rollout waits to be resumed before the next wave.
+	Paused bool `bson:"paused"              json:"paused"            yaml:"paused"`
+	// ControlledBy is the user who paused, resumed or aborted the rollout last.
+	ControlledBy string `bson:"controlled_by"       json:"controlled_by"     yaml:"controlled_by"`
+}
+
+type RolloutWaveTask struct {
+	Name                string                `bson:"name"                           json:"name"                           yaml:"name"`
+	Envs                []string              `bson:"envs"                           json:"envs"                           yaml:"envs"`
+	Verification        *DeployVerification   `bson:"verification,omitempty"         json:"verification,omitempty"         yaml:"verification,omitempty"`
+	Status              config.Status         `bson:"status"                         json:"status"                         yaml:"status"`
+	Error               string                `bson:"error"                          json:"error"                          yaml:"error"`
+	StartTime           int64                 `bson:"start_time"                     json:"start_time"                     yaml:"start_time"`
+	EndTime             int64                 `bson:"end_time"                       json:"end_time"                       yaml:"end_time"`
+	Deploys             []*RolloutDeployTask  `bson:"deploys"                        json:"deploys"                        yaml:"deploys"`
+	VerificationResults []*VerificationResult `bson:"verification_results,omitempty" json:"verification_results,omitempty" yaml:"verification_results,omitempty"`
+}
+
+// RolloutDeployTask is the deployment of an image to an env in a wave.
+type RolloutDeployTask struct {
+	Env           string        `bson:"env"                 json:"env"               yaml:"env"`
+	ServiceName   string        `bson:"service_name"        json:"service_name"      yaml:"service_name"`
+	ServiceModule string        `bson:"service_module"      json:"service_module"    yaml:"service_module"`
+	Image         string        `bson:"image"               json:"image"             yaml:"image"`
+	Status        config.Status `bson:"status"              json:"status"            yaml:"status"`
+	Error         string        `bson:"error"               json:"error"             yaml:"error"`
+	RolledBack    bool          `bson:"rolled_back"         json:"rolled_back"       yaml:"rolled_back"`
+}
+
+type JobTaskLicenseScanSpec struct {
+	Images       []*ServiceAndImage   `bson:"images"              json:"images"            yaml:"images"`
+	ScannerImage string               `bson:"scanner_image"       json:"scanner_image"     yaml:"scanner_image"`
+	ClusterID    string               `bson:"cluster_id"          json:"cluster_id"        yaml:"cluster_id"`
+	Timeout      int64                `bson:"timeout"             json:"timeout"           yaml:"timeout"`
+	Registries   []*RegistryNamespace `bson:"registries"          json:"registries"        yaml:"registries"`
+	Results      []*LicenseScanResult `bson:"results"             json:"results"           yaml:"results"`
+}
+
+type JobTaskSandboxClusterSpec struct {
+	Provider          config.SandboxClusterProvider `bson:"provider"            json:"provider"            yaml:"provider"`
+	HostClusterID     string                        `bson:"host_cluster_id"     json:"host_cluster_id"     yaml:"host_cluster_id"`
+	HostNamespace     string                        `bson:"host_namespace"      json:"host_namespace"      yaml:"host_namespace"`
+	VClusterVersion   string                        `bson:"vcluster_version"    json:"vcluster_version"    yaml:"vcluster_version"`
+	VClusterValues    string                        `bson:"vcluster_values"     json:"vcluster_values"     yaml:"vcluster_values"`
+	ClusterClass      string                        `bson:"cluster_class"       json:"cluster_class"       yaml:"cluster_class"`
+	KubernetesVersion string                        `bson:"kubernetes_version"  json:"kubernetes_version"  yaml:"kubernetes_version"`
+	WorkerReplicas    int32                         `bson:"worker_replicas"     json:"worker_replicas"     yaml:"worker_replicas"`
+	Env               string                        `bson:"env"                 json:"env"                 yaml:"env"`
+	// Namespace is the namespace of the env, the services are deployed to the same namespace in the sandbox.
+	Namespace  string               `bson:"namespace"           json:"namespace"           yaml:"namespace"`
+	Manifests  []*SandboxManifest   `bson:"manifests"           json:"manifests"           yaml:"manifests"`
+	Suites     []*SandboxTestSuite  `bson:"suites"              json:"suites"              yaml:"suites"`
+	Timeout    int64                `bson:"timeout"             json:"timeout"             yaml:"timeout"`
+	Registries []*RegistryNamespace `bson:"registries"          json:"registries"          yaml:"registries"`
+	// SandboxName and SandboxClusterID are set once the cluster is provisioned and registered, the cluster
+	// is removed from zadig when the job finishes.
+	SandboxName      string                `bson:"sandbox_name"        json:"sandbox_name"        yaml:"sandbox_name"`
+	SandboxClusterID string                `bson:"sandbox_cluster_id"  json:"sandbox_cluster_id"  yaml:"sandbox_cluster_id"`
+	Phase            string                `bson:"phase"               json:"phase"               yaml:"phase"`
+	SuiteResults     []*SandboxSuiteResult `bson:"suite_results"       json:"suite_results"       yaml:"suite_results"`
+}
+
+// SandboxManifest is the rendered yaml of a service of the env with the images under test.
+type SandboxManifest struct {
+	ServiceName string `bson:"service_name"        json:"service_name"        yaml:"service_name"`
+	Yaml        string `bson:"yaml"                json:"yaml"                yaml:"yaml"`
+}
+
+// SandboxSuiteResult is the result of a test suite, Logs is the tail of the logs of the suite.
+type SandboxSuiteResult struct {
+	Name      string        `bson:"name"                json:"name"                yaml:"name"`
+	Status    config.Status `bson:"status"              json:"status"              yaml:"status"`
+	Error     string        `bson:"error"               json:"error"               yaml:"error"`
+	Logs      string        `bson:"logs"                json:"logs"                yaml:"logs"`
+	StartTime int64         `bson:"start_time"          json:"start_time"          yaml:"start_time"`
+	EndTime   int64         `bson:"end_time"            json:"end_time"            yaml:"end_time"`
+}
+
+// LicenseScanResult aggregates the licenses of the packages found in an image.
+type LicenseScanResult struct {
+	ServiceName   string          `bson:"service_name"        json:"service_name"      yaml:"service_name"`
+	ServiceModule string          `bson:"service_module"      json:"service_module"    yaml:"service_module"`
+	Image         string          `bson:"image"               json:"image"             yaml:"image"`
+	Packages      int             `bson:"packages"            json:"packages"          yaml:"packages"`
+	Licenses      []*LicenseCount `bson:"licenses"            json:"licenses"          yaml:"licenses"`
+	// Violations fail the job, the Waived ones are accepted by the waivers of the license policy.
+	Violations []*LicenseViolation `bson:"violations"          json:"violations"        yaml:"violations"`
+	Waived     []*LicenseViolation `bson:"waived"              json:"waived"            yaml:"waived"`
+	Error      string              `bson:"error"               json:"error"             yaml:"error"`
+}
+
+type LicenseCount struct {
+	License string `bson:"license"             json:"license"           yaml:"license"`
+	Count   int    `bson:"count"               json:"count"             yaml:"count"`
+}
+
+type LicenseViolation struct {
+	Package string `bson:"package"             json:"package"           yaml:"package"`
+	Version string `bson:"version"             json:"version"           yaml:"version"`
+	Type    string `bson:"type"                json:"type"              yaml:"type"`
+	License string `bson:"license"             json:"license"           yaml:"license"`
+	Reason  string `bson:"reason"              json:"reason"            yaml:"reason"`
+	// WaiverID is the waiver accepting the violation.
+	WaiverID string `bson:"waiver_id,omitempty" json:"waiver_id,omitempty" yaml:"waiver_id,omitempty"`
+}
+
 type StepTask struct {
 	Name     string          `bson:"name"           json:"name"      yaml:"name"`
 	JobName  string          `bson:"job_name"       json:"job_name"  yaml:"job_name"`
@@ -147,6 +442,7 @@ type WorkflowTaskCtx struct {
 	WorkflowName      string
 	ProjectName       string
 	TaskID            int64
+	TaskCreator       string
 	DockerHost        string
 	Workspace         string
 	DistDir           string
@@ -156,4 +452,8 @@ type WorkflowTaskCtx struct {
 	GlobalContextGet  func(key string) (string, bool)
 	GlobalContextSet  func(key, value string)
 	GlobalContextEach func(f func(k, v string) bool)
+	DebugOnFailure    *DebugOnFailure
+	Labels            map[string]string
+	// CustomFields are the values of the custom fields of the workflow and its project, they are sent in the events.
+	CustomFields map[string]string
 }
@@ -92,6 +92,20 @@ type MainHookRepo struct {
 	Label         string                 `bson:"label"                     json:"label"`
 	Revision      string                 `bson:"revision"                  json:"revision"`
 	IsRegular     bool                   `bson:"is_regular"                json:"is_regular"`
+	GerritSeries  string                 `bson:"gerrit_series,omitempty"   json:"gerrit_series,omitempty"`
+	TagFilter     *types.TagFilter       `bson:"tag_filter,omitempty"      json:"tag_filter,omitempty"`
+	// GerritApproval is the vote triggering the workflow on the comment-added events of gerrit, Code-Review +2 is
+	// used if it is not set.
+	GerritApproval *GerritApproval `bson:"gerrit_approval,omitempty" json:"gerrit_approval,omitempty"`
+	// SeriesChanges is set when the workflow is triggered, the result is also reported to these changes.
+	SeriesChanges []*GerritChange `bson:"-"                         json:"-"`
+	// ChangedFiles is set when the event matches, they are the files changed by the event.
+	ChangedFiles []string `bson:"-"                         json:"-"`
+}
+
+type GerritApproval struct {
+	Label string `bson:"label" json:"label"`
+	Value int    `bson:"value" json:"value"`
 }
 
 func (m *MainHookRepo) GetRepoNamespace() string {
@@ -114,18 +128,23 @@ type ScheduleCtrl struct {
 	Items   []*Schedule `bson:"items"      json:"items"`
 }
 
+
 type Schedule struct {
-	ID           primitive.ObjectID  `bson:"_id,omitempty"                 json:"id,omitempty"`
-	Number       uint64              `bson:"number"                        json:"number"`
-	Frequency    string              `bson:"frequency"                     json:"frequency"`
-	Time         string              `bson:"time"                          json:"time"`
-	MaxFailures  int                 `bson:"max_failures,omitempty"        json:"max_failures,omitempty"`
-	TaskArgs     *TaskArgs           `bson:"task_args,omitempty"           json:"task_args,omitempty"`
-	WorkflowArgs *WorkflowTaskArgs   `bson:"workflow_args,omitempty"       json:"workflow_args,omitempty"`
-	TestArgs     *TestTaskArgs       `bson:"test_args,omitempty"           json:"test_args,omitempty"`
-	Type         config.ScheduleType `bson:"type"                          json:"type"`
-	Cron         string              `bson:"cron"                          json:"cron"`
-	IsModified   bool                `bson:"-"                             json:"-"`
+	ID                 primitive.ObjectID  `bson:"_id,omitempty"                 json:"id,omitempty"`
+	Number             uint64              `bson:"number"                        json:"number"`
+	Frequency          string              `bson:"frequency"                     json:"frequency"`
+	Time               string              `bson:"time"                          json:"time"`
+	MaxFailures        int                 `bson:"max_failures,omitempty"        json:"max_failures,omitempty"`
+	TaskArgs           *TaskArgs           `bson:"task_args,omitempty"           json:"task_args,omitempty"`
+	WorkflowArgs       *WorkflowTaskArgs   `bson:"workflow_args,omitempty"       json:"workflow_args,omitempty"`
+	TestArgs           *TestTaskArgs       `bson:"test_args,omitempty"           json:"test_args,omitempty"`
+	EnvDataRefreshArgs *EnvDataRefreshArgs `bson:"env_data_refresh_args,omitempty" json:"env_data_refresh_args,omitempty"`
+	EnvSmokeTestArgs   *EnvSmokeTestArgs   `bson:"env_smoke_test_args,omitempty"   json:"env_smoke_test_args,omitempty"`
+	ReleaseTrainArgs   *ReleaseTrainArgs   `bson:"release_train_args,omitempty"    json:"release_train_args,omitempty"`
+	Type               config.ScheduleType `bson:"type"                          json:"type"`
+	Cron               string              `bson:"cron"                          json:"cron"`
+	CatchUpPolicy      string              `bson:"catch_up_policy,omitempty"     json:"catch_up_policy,omitempty"`
+	IsModified         bool                `bson:"-"                             json:"-"`
 	// 自由编排工作流的开关是放在schedule里面的
 	Enabled bool `bson:"enabled"                       json:"enabled"`
 }
@@ -226,6 +245,8 @@ type TestTaskArgs struct {
 	RepoOwner      string `bson:"repo_owner"       json:"repo_owner"`
 	RepoNamespace  string `bson:"repo_namespace"   json:"repo_namespace"`
 	RepoName       string `bson:"repo_name"        json:"repo_name"`
+	// the environment the test runs against, it is set by the smoke tests of the environment.
+	EnvName string `bson:"env_name,omitempty" json:"env_name,omitempty"`
 }
 
 type Slack struct {
@@ -323,6 +344,9 @@ type NotifyCtl struct {
 	AtMobiles       []string `bson:"at_mobiles,omitempty"             json:"at_mobiles,omitempty"`
 	IsAtAll         bool     `bson:"is_at_all,omitempty"              json:"is_at_all,omitempty"`
 	NotifyTypes     []string `bson:"notify_type"                      json:"notify_type"`
+	// LabelSelector routes the notification by workflow tags, e.g. "team=payments", it is only
+	// sent when the workflow carries all of the labels.
+	LabelSelector string `bson:"label_selector,omitempty"         json:"label_selector,omitempty"`
 }
 
 type TaskInfo struct {
@@ -364,6 +388,13 @@ type HookPayload struct {
 	CommitID       string `bson:"commit_id"        json:"commit_id,omitempty"`
 	DeliveryID     string `bson:"delivery_id"      json:"delivery_id,omitempty"`
 	CodehostID     int    `bson:"codehost_id"      json:"codehost_id"`
+	MergeGate      bool   `bson:"merge_gate"       json:"merge_gate,omitempty"`
+	Tag            string `bson:"tag,omitempty"    json:"tag,omitempty"`
+	// the metadata of the trigger, they are exposed as the variables of the task
+	CommitAuthor  string   `bson:"commit_author,omitempty"  json:"commit_author,omitempty"`
+	CommitMessage string   `bson:"commit_message,omitempty" json:"commit_message,omitempty"`
+	Labels        []string `bson:"labels,omitempty"         json:"labels,omitempty"`
+	ChangedFiles  []string `bson:"changed_files,omitempty"  json:"changed_files,omitempty"`
 }
 
 type TargetArgs struct {
@@ -448,6 +479,13 @@ func (d *DistributeStage) IsDistributeS3Enabled() bool {
 
 //Validate validate schedule setting
 func (schedule *Schedule) Validate() error {
+	if schedule.CatchUpPolicy != "" &&
+		schedule.CatchUpPolicy != setting.CatchUpSkip &&
+		schedule.CatchUpPolicy != setting.CatchUpRunOnce &&
+		schedule.CatchUpPolicy != setting.CatchUpRunAllMissed {
+		return fmt.Errorf("%s 错过任务的补偿策略错误", e.InvalidFormatErrMsg)
+	}
+
 	switch schedule.Type {
 	case config.TimingSchedule:
 		// 默认间隔循环间隔是1
@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// WorkflowBadge holds the token which grants access to the public status of a workflow,
+// the token is a part of the badge url so that it can be embedded without logging in.
+type WorkflowBadge struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	WorkflowName string             `bson:"workflow_name"  json:"workflow_name"`
+	Token        string             `bson:"token"          json:"token"`
+	UpdatedBy    string             `bson:"updated_by"     json:"updated_by"`
+	UpdateTime   int64              `bson:"update_time"    json:"update_time"`
+	// BadgeURL and StatusURL are generated with the base url the user is visiting.
+	BadgeURL  string `bson:"-" json:"badge_url,omitempty"`
+	StatusURL string `bson:"-" json:"status_url,omitempty"`
+}
+
+func (WorkflowBadge) TableName() string {
+	return "workflow_badge"
+}
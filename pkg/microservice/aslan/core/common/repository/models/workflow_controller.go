@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// WorkflowControllerInstance is a running instance of the workflow controller, the instances
+// which stop sending heartbeats are considered dead and their tasks are taken over.
+type WorkflowControllerInstance struct {
+	ID            string `bson:"_id"            json:"id"`
+	StartTime     int64  `bson:"start_time"     json:"start_time"`
+	HeartbeatTime int64  `bson:"heartbeat_time" json:"heartbeat_time"`
+}
+
+func (WorkflowControllerInstance) TableName() string {
+	return "workflow_controller_instance"
+}
+
+// WorkflowControllerLease is held by the leader of the workflow controller instances.
+type WorkflowControllerLease struct {
+	ID       string `bson:"_id"`
+	Owner    string `bson:"owner"`
+	ExpireAt int64  `bson:"expire_at"`
+}
+
+func (WorkflowControllerLease) TableName() string {
+	return "workflow_controller_lease"
+}
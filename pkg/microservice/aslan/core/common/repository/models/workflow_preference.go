@@ -0,0 +1,33 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// WorkflowPreference is the per user settings of a workflow, the parameters are the values the user
+// ran the workflow with last time.
+type WorkflowPreference struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	UserID       string             `bson:"user_id"        json:"user_id"`
+	WorkflowName string             `bson:"workflow_name"  json:"workflow_name"`
+	Params       []*Param           `bson:"params"         json:"params"`
+	UpdateTime   int64              `bson:"update_time"    json:"update_time"`
+}
+
+func (WorkflowPreference) TableName() string {
+	return "workflow_preference"
+}
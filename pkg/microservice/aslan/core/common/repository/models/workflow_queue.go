@@ -33,6 +33,8 @@ type WorkflowQueue struct {
 	TaskRevoker  string             `bson:"task_revoker,omitempty"                     json:"task_revoker,omitempty"`
 	CreateTime   int64              `bson:"create_time"                                json:"create_time,omitempty"`
 	MultiRun     bool               `bson:"multi_run"                                  json:"multi_run"`
+	Owner        string             `bson:"owner,omitempty"                            json:"owner,omitempty"`
+	Hotfix       bool               `bson:"hotfix,omitempty"                           json:"hotfix,omitempty"`
 }
 
 func (WorkflowQueue) TableName() string {
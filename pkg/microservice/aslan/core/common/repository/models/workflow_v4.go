@@ -25,6 +25,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
 )
 
 type WorkflowV4 struct {
@@ -44,6 +45,59 @@ type WorkflowV4 struct {
 	NotificationID string             `bson:"notification_id"     yaml:"-"            json:"notification_id"`
 	HookPayload    *HookPayload       `bson:"hook_payload"        yaml:"-"            json:"hook_payload,omitempty"`
 	BaseName       string             `bson:"base_name"           yaml:"-"            json:"base_name"`
+	// DedupStrategy applies when a webhook triggers the workflow for a commit having a running task
+	DedupStrategy config.DedupStrategy `bson:"dedup_strategy,omitempty" yaml:"dedup_strategy,omitempty" json:"dedup_strategy,omitempty"`
+	// APIParamPolicy limits the parameters which can be overridden when the workflow is triggered by API
+	APIParamPolicy *APIParamPolicy `bson:"api_param_policy,omitempty" yaml:"api_param_policy,omitempty" json:"api_param_policy,omitempty"`
+	// DebugOnFailure is set per run, it keeps the pods of the failed jobs alive so that they can be inspected
+	DebugOnFailure *DebugOnFailure `bson:"debug_on_failure,omitempty" yaml:"-" json:"debug_on_failure,omitempty"`
+	// the archived workflows are kept but can not be run any more, e.g. they are not run for months.
+	Archived   bool   `bson:"archived,omitempty"    yaml:"-" json:"archived,omitempty"`
+	ArchivedBy string `bson:"archived_by,omitempty" yaml:"-" json:"archived_by,omitempty"`
+	ArchivedAt int64  `bson:"archived_at,omitempty" yaml:"-" json:"archived_at,omitempty"`
+	// RunLabels are set per run, e.g. the ticket id or the release name, they are saved in the labels of the task.
+	RunLabels map[string]string `bson:"run_labels,omitempty" yaml:"-" json:"run_labels,omitempty"`
+	// HotfixPolicy designates the workflow for the hotfixes and tells who are notified of the hotfix runs.
+	HotfixPolicy *HotfixPolicy `bson:"hotfix_policy,omitempty" yaml:"hotfix_policy,omitempty" json:"hotfix_policy,omitempty"`
+	// Hotfix is set per run to run a workflow not designated for the hotfixes as a hotfix, it is saved in the task.
+	Hotfix *HotfixRun `bson:"-" yaml:"-" json:"hotfix,omitempty"`
+	// SummaryNotifyCtl receives the summaries reported by the jobs when a task ends.
+	SummaryNotifyCtl *NotifyCtl `bson:"summary_notify_ctl,omitempty" yaml:"summary_notify_ctl,omitempty" json:"summary_notify_ctl,omitempty"`
+	// CustomFields are the values of the custom fields of the workflows defined by the admins, keyed by the field keys.
+	CustomFields map[string]string `bson:"custom_fields,omitempty" yaml:"custom_fields,omitempty" json:"custom_fields,omitempty"`
+	// ExecutionHooks call the external systems before a task starts and after it finishes.
+	ExecutionHooks []*ExecutionHook `bson:"execution_hooks,omitempty" yaml:"execution_hooks,omitempty" json:"execution_hooks,omitempty"`
+}
+
+// HotfixPolicy makes all the runs of the workflow hotfix runs if it is enabled. The hotfix runs bypass the queue
+// ordering and are not blocked by the running tasks of the same workflow.
+type HotfixPolicy struct {
+	Enabled bool `bson:"enabled" yaml:"enabled" json:"enabled"`
+	// Receivers are the users notified in the site when a hotfix run is created.
+	Receivers []string   `bson:"receivers" yaml:"receivers" json:"receivers"`
+	NotifyCtl *NotifyCtl `bson:"notify_ctl,omitempty" yaml:"notify_ctl,omitempty" json:"notify_ctl,omitempty"`
+}
+
+type HotfixRun struct {
+	Reason string `bson:"reason" yaml:"reason" json:"reason"`
+}
+
+type DebugOnFailure struct {
+	Enabled bool `bson:"enabled" yaml:"enabled" json:"enabled"`
+	// Timeout is the minutes the pod of a failed job is kept alive
+	Timeout int64 `bson:"timeout" yaml:"timeout" json:"timeout"`
+}
+
+type APIParamPolicy struct {
+	Enabled bool            `bson:"enabled" yaml:"enabled" json:"enabled"`
+	Rules   []*APIParamRule `bson:"rules"   yaml:"rules"   json:"rules"`
+}
+
+// APIParamRule allows a parameter to be overridden, the name is the name of a workflow param or
+// job_name.KEY for the variables of a build job. The value must fully match the pattern if it is set.
+type APIParamRule struct {
+	Name    string `bson:"name"    yaml:"name"    json:"name"`
+	Pattern string `bson:"pattern" yaml:"pattern" json:"pattern"`
 }
 
 type WorkflowStage struct {
@@ -60,6 +114,40 @@ type Approval struct {
 	NeededApprovers int                    `bson:"needed_approvers"            yaml:"needed_approvers"           json:"needed_approvers"`
 	Description     string                 `bson:"description"                 yaml:"description"                json:"description"`
 	RejectOrApprove config.ApproveOrReject `bson:"reject_or_approve"           yaml:"-"                          json:"reject_or_approve"`
+	// Type is NativeApproval if it is empty, the webhook approvals are decided by Webhook instead of the approve users.
+	Type    config.ApprovalType `bson:"type,omitempty"              yaml:"type,omitempty"             json:"type,omitempty"`
+	Webhook *WebhookApproval    `bson:"webhook,omitempty"           yaml:"webhook,omitempty"          json:"webhook,omitempty"`
+}
+
+// WebhookApproval delegates the decision of the approval to an external service, e.g. an in-house change
+// approval system. The service is called with the context of the task, and it answers approve, deny or pending,
+// the pending approvals are requested again after the poll interval.
+type WebhookApproval struct {
+	URL string `bson:"url"                         yaml:"url"                        json:"url"`
+	// Secret signs the request body by HMAC-SHA256, the signature is sent in the X-Zadig-Signature-256 header.
+	Secret string `bson:"secret"                      yaml:"secret"                     json:"secret"`
+	// PollInterval is in seconds, the interval returned by the service takes precedence.
+	PollInterval int                            `bson:"poll_interval"               yaml:"poll_interval"              json:"poll_interval"`
+	Decision     config.WebhookApprovalDecision `bson:"decision,omitempty"          yaml:"-"                          json:"decision,omitempty"`
+	Message      string                         `bson:"message,omitempty"           yaml:"-"                          json:"message,omitempty"`
+}
+
+// ExecutionHook is an external http endpoint called in the lifecycle of the tasks, e.g. by a capacity manager
+// or a compliance recorder. A pre-start hook answers whether the task proceeds, is vetoed or is delayed, the
+// answer of a post-finish hook is ignored.
+type ExecutionHook struct {
+	Name  string                    `bson:"name"  yaml:"name"  json:"name"`
+	Event config.ExecutionHookEvent `bson:"event" yaml:"event" json:"event"`
+	URL   string                    `bson:"url"   yaml:"url"   json:"url"`
+	// Secret is the HMAC-SHA256 key the hook verifies the calls of the tasks with. It is returned to the users as
+	// the mask, which keeps the saved secret if it is sent back.
+	Secret string `bson:"secret" yaml:"secret" json:"secret"`
+	// Timeout of a request in seconds, 10 seconds by default.
+	Timeout int `bson:"timeout" yaml:"timeout" json:"timeout"`
+	// FailOpen lets the task proceed if the pre-start hook can not be reached, otherwise the task fails.
+	FailOpen bool `bson:"fail_open" yaml:"fail_open" json:"fail_open"`
+	// MaxDelay is the longest time in minutes a pre-start hook may delay the task, the task fails after it.
+	MaxDelay int `bson:"max_delay" yaml:"max_delay" json:"max_delay"`
 }
 
 type User struct {
@@ -131,6 +219,73 @@ type ZadigDeployJobSpec struct {
 	// 当 source 为 fromjob 时需要，指定部署镜像来源是上游哪一个构建任务
 	JobName          string             `bson:"job_name"             yaml:"job_name"             json:"job_name"`
 	ServiceAndImages []*ServiceAndImage `bson:"service_and_images"   yaml:"service_and_images"   json:"service_and_images"`
+	// PinImageDigest resolves the tags of the images to digests when deploying, so that the images can not be
+	// changed by pushing the same tags.
+	PinImageDigest bool                `bson:"pin_image_digest"       yaml:"pin_image_digest"       json:"pin_image_digest"`
+	Verification   *DeployVerification `bson:"verification,omitempty" yaml:"verification,omitempty" json:"verification,omitempty"`
+}
+
+// DeployVerification is run after the rollout of the deploy job, the job fails if any of the checks fails and
+// the deployment is rolled back if the environment enables auto rollback.
+type DeployVerification struct {
+	Enabled bool `bson:"enabled"                     yaml:"enabled"                     json:"enabled"`
+	// Timeout is the seconds the probes are retried until they pass, the default is 300.
+	Timeout int64 `bson:"timeout"                     yaml:"timeout"                     json:"timeout"`
+	// ObserveDuration is the seconds the prometheus checks are evaluated periodically after the probes
+	// pass, they are evaluated once if it is 0.
+	ObserveDuration  int64              `bson:"observe_duration"            yaml:"observe_duration"            json:"observe_duration"`
+	Probes           []*HealthProbe     `bson:"probes"                      yaml:"probes"                      json:"probes"`
+	PrometheusChecks []*PrometheusCheck `bson:"prometheus_checks"           yaml:"prometheus_checks"           json:"prometheus_checks"`
+	// Comparison is run by the deploy jobs after the checks above pass.
+	Comparison *DeployComparison `bson:"comparison,omitempty"        yaml:"comparison,omitempty"        json:"comparison,omitempty"`
+}
+
+// DeployComparison compares the signals of the new version with the ones recorded for the previous successful
+// deployment of the service in the env. The deployment fails, is rolled back if the environment enables it and
+// an alert is sent if any signal regresses more than its tolerance. The signals of a passed deployment become
+// the baseline of the next one.
+type DeployComparison struct {
+	Enabled bool `bson:"enabled"                     yaml:"enabled"                     json:"enabled"`
+	// BakeDuration is the seconds the new version runs before the signals are measured.
+	BakeDuration int64               `bson:"bake_duration"               yaml:"bake_duration"               json:"bake_duration"`
+	Signals      []*ComparisonSignal `bson:"signals"                     yaml:"signals"                     json:"signals"`
+	// NotifyCtl receives the alert if the regression does not match any notification route.
+	NotifyCtl *NotifyCtl `bson:"notify_ctl,omitempty"        yaml:"notify_ctl,omitempty"        json:"notify_ctl,omitempty"`
+}
+
+type ComparisonSignal struct {
+	// Name is the key of the signal in the baseline, renaming a signal drops its baseline.
+	Name string                      `bson:"name"                        yaml:"name"                        json:"name"`
+	Type config.ComparisonSignalType `bson:"type"                        yaml:"type"                        json:"type"`
+	// Address and Query are the prometheus instant query of the error rate and latency signals, the query
+	// must return a single value, e.g. the error rate of the service in the bake period.
+	Address string `bson:"address"                     yaml:"address"                     json:"address"`
+	Query   string `bson:"query"                       yaml:"query"                       json:"query"`
+	// Tolerance is the increase in percent of the baseline accepted for the error rate and latency signals, and
+	// the decrease in percentage points of the pass rate accepted for the test pass rate signals.
+	Tolerance float64 `bson:"tolerance"                   yaml:"tolerance"                   json:"tolerance"`
+	// MinDelta is the absolute increase of the error rate and latency signals ignored regardless of the tolerance,
+	// so that the noise around a baseline of zero is not a regression.
+	MinDelta float64 `bson:"min_delta"                   yaml:"min_delta"                   json:"min_delta"`
+}
+
+type HealthProbe struct {
+	Type config.HealthProbeType `bson:"type"                        yaml:"type"                        json:"type"`
+	// Address is the url of http probes or the host:port of grpc probes, it must be reachable from zadig.
+	Address string `bson:"address"                     yaml:"address"                     json:"address"`
+	// Service is the service name of the grpc health check request, the whole server is checked if it is empty.
+	Service string `bson:"service"                     yaml:"service"                     json:"service"`
+	// ExpectedStatus is the status code of http probes, any 2xx code is accepted if it is 0.
+	ExpectedStatus int `bson:"expected_status"             yaml:"expected_status"             json:"expected_status"`
+}
+
+type PrometheusCheck struct {
+	Name    string `bson:"name"                        yaml:"name"                        json:"name"`
+	Address string `bson:"address"                     yaml:"address"                     json:"address"`
+	// Query is an instant query, e.g. the error rate of the service in the last minutes. The check fails if
+	// any value of the result is greater than the threshold.
+	Query     string  `bson:"query"                       yaml:"query"                       json:"query"`
+	Threshold float64 `bson:"threshold"                   yaml:"threshold"                   json:"threshold"`
 }
 
 type ServiceAndImage struct {
@@ -139,6 +294,124 @@ type ServiceAndImage struct {
 	Image         string `bson:"image"               yaml:"image"           json:"image"`
 }
 
+type JenkinsJobSpec struct {
+	// ID is the id of the jenkins integration used to trigger jobs.
+	ID string `bson:"id"                     json:"id"                    yaml:"id"`
+	// unit is minute.
+	Timeout int64             `bson:"timeout"                json:"timeout"               yaml:"timeout"`
+	Jobs    []*JenkinsJobInfo `bson:"jobs"                   json:"jobs"                  yaml:"jobs"`
+}
+
+// ZadigRolloutJobSpec rolls the images of a delivery version out to the envs in many clusters or regions
+// wave by wave, a wave starts only if the former one is deployed and verified.
+type ZadigRolloutJobSpec struct {
+	// Version is the name of the delivery version of the project, it can be overridden when the task is created.
+	Version string         `bson:"version"                json:"version"               yaml:"version"`
+	Waves   []*RolloutWave `bson:"waves"                  json:"waves"                 yaml:"waves"`
+	// PauseAfterWave pauses the rollout after every wave but the last one until it is resumed.
+	PauseAfterWave bool `bson:"pause_after_wave"       json:"pause_after_wave"      yaml:"pause_after_wave"`
+	// Timeout of deploying a service in an env, unit is minute.
+	Timeout int `bson:"timeout"                json:"timeout"               yaml:"timeout"`
+}
+
+type RolloutWave struct {
+	Name string `bson:"name"                   json:"name"                  yaml:"name"`
+	// Envs are deployed in parallel, they are usually the envs of the project in the clusters of a region.
+	Envs []string `bson:"envs"                   json:"envs"                  yaml:"envs"`
+	// Verification checks the health of the wave after all its envs are deployed.
+	Verification *DeployVerification `bson:"verification,omitempty" json:"verification,omitempty" yaml:"verification,omitempty"`
+}
+
+// LicenseScanJobSpec scans the packages in the images for their licenses, the licenses are checked against
+// the license policy of the project.
+type LicenseScanJobSpec struct {
+	// fromjob/runtime, the images are built by the build job of JobName if it is fromjob.
+	Source           config.DeploySourceType `bson:"source"             json:"source"             yaml:"source"`
+	JobName          string                  `bson:"job_name"           json:"job_name"           yaml:"job_name"`
+	ServiceAndImages []*ServiceAndImage      `bson:"service_and_images" json:"service_and_images" yaml:"service_and_images"`
+	// ScannerImage is the image of syft which generates the sbom of the images, a pinned release is used if
+	// it is empty.
+	ScannerImage string `bson:"scanner_image"      json:"scanner_image"      yaml:"scanner_image"`
+	ClusterID    string `bson:"cluster_id"         json:"cluster_id"         yaml:"cluster_id"`
+	// Timeout of scanning an image, unit is minute.
+	Timeout int64 `bson:"timeout"            json:"timeout"            yaml:"timeout"`
+}
+
+// SandboxClusterJobSpec provisions an ephemeral cluster, registers it in zadig during the job, deploys the
+// services of Env with the images under test into it, runs the test suites and tears the cluster down.
+type SandboxClusterJobSpec struct {
+	Provider config.SandboxClusterProvider `bson:"provider"           json:"provider"           yaml:"provider"`
+	// HostClusterID is the cluster the vcluster is installed in, or the Cluster API management cluster.
+	HostClusterID string `bson:"host_cluster_id"    json:"host_cluster_id"    yaml:"host_cluster_id"`
+	// HostNamespace is where the vcluster or the Cluster API cluster is created, the vcluster gets its own
+	// namespace if it is empty.
+	HostNamespace string `bson:"host_namespace"     json:"host_namespace"     yaml:"host_namespace"`
+	// VClusterVersion and VClusterValues are the chart version and the values of the vcluster release.
+	VClusterVersion string `bson:"vcluster_version"   json:"vcluster_version"   yaml:"vcluster_version"`
+	VClusterValues  string `bson:"vcluster_values"    json:"vcluster_values"    yaml:"vcluster_values"`
+	// ClusterClass, KubernetesVersion and WorkerReplicas are the topology of the Cluster API cluster.
+	ClusterClass      string `bson:"cluster_class"      json:"cluster_class"      yaml:"cluster_class"`
+	KubernetesVersion string `bson:"kubernetes_version" json:"kubernetes_version" yaml:"kubernetes_version"`
+	WorkerReplicas    int32  `bson:"worker_replicas"    json:"worker_replicas"    yaml:"worker_replicas"`
+	// Env is the environment whose services are deployed, the images of the services are replaced by the
+	// images built by the build job of JobName if Source is fromjob.
+	Env              string                  `bson:"env"                json:"env"                yaml:"env"`
+	Source           config.DeploySourceType `bson:"source"             json:"source"             yaml:"source"`
+	JobName          string                  `bson:"job_name"           json:"job_name"           yaml:"job_name"`
+	ServiceAndImages []*ServiceAndImage      `bson:"service_and_images" json:"service_and_images" yaml:"service_and_images"`
+	Suites           []*SandboxTestSuite     `bson:"suites"             json:"suites"             yaml:"suites"`
+	// Timeout of the whole job including the provisioning, unit is minute.
+	Timeout int64 `bson:"timeout"            json:"timeout"            yaml:"timeout"`
+}
+
+// SandboxTestSuite runs the script in the image as a kubernetes job in the namespace of the env in the
+// sandbox cluster, the suite passes if the script exits with 0.
+type SandboxTestSuite struct {
+	Name   string `bson:"name"               json:"name"               yaml:"name"`
+	Image  string `bson:"image"              json:"image"              yaml:"image"`
+	Script string `bson:"script"             json:"script"             yaml:"script"`
+}
+
+// AndroidBuildJobSpec builds the android app with gradle, signs it with the keystore and publishes the apk
+// and aab, which are saved as the artifacts of the task.
+type AndroidBuildJobSpec struct {
+	Properties *JobProperties      `bson:"properties"         json:"properties"         yaml:"properties"`
+	Repos      []*types.Repository `bson:"repos"              json:"repos"              yaml:"repos"`
+	// ProjectDir is the dir of the gradle wrapper, relative to the workspace.
+	ProjectDir  string   `bson:"project_dir"        json:"project_dir"        yaml:"project_dir"`
+	GradleTasks []string `bson:"gradle_tasks"       json:"gradle_tasks"       yaml:"gradle_tasks"`
+	// VersionCode is the id of the task if it is empty, the version name of the project is kept if VersionName is empty.
+	VersionName string `bson:"version_name"       json:"version_name"       yaml:"version_name"`
+	VersionCode string `bson:"version_code"       json:"version_code"       yaml:"version_code"`
+	// Outputs are the globs of the apk and aab files relative to ProjectDir.
+	Outputs    []string              `bson:"outputs"            json:"outputs"            yaml:"outputs"`
+	Signing    *AndroidSigning       `bson:"signing,omitempty"  json:"signing,omitempty"  yaml:"signing,omitempty"`
+	Firebase   *step.FirebasePublish `bson:"firebase,omitempty" json:"firebase,omitempty" yaml:"firebase,omitempty"`
+	GooglePlay *step.PlayPublish     `bson:"google_play,omitempty" json:"google_play,omitempty" yaml:"google_play,omitempty"`
+}
+
+// AndroidSigning refers to the credential variables of the job, the keystore is injected into the workspace
+// and passed to gradle as the injected signing config.
+type AndroidSigning struct {
+	// KeystoreEnv is the variable of the base64 encoded keystore file.
+	KeystoreEnv         string `bson:"keystore_env"          json:"keystore_env"          yaml:"keystore_env"`
+	KeystorePasswordEnv string `bson:"keystore_password_env" json:"keystore_password_env" yaml:"keystore_password_env"`
+	KeyAlias            string `bson:"key_alias"             json:"key_alias"             yaml:"key_alias"`
+	KeyPasswordEnv      string `bson:"key_password_env"      json:"key_password_env"      yaml:"key_password_env"`
+}
+
+type JenkinsJobInfo struct {
+	JobName    string                 `bson:"job_name"           json:"job_name"          yaml:"job_name"`
+	Parameters []*JenkinsJobParameter `bson:"parameters"         json:"parameters"        yaml:"parameters"`
+}
+
+type JenkinsJobParameter struct {
+	Name    string   `bson:"name"                   json:"name"                  yaml:"name"`
+	Value   string   `bson:"value"                  json:"value"                 yaml:"value"`
+	Type    string   `bson:"type"                   json:"type"                  yaml:"type"`
+	Choices []string `bson:"choices,omitempty"      json:"choices,omitempty"     yaml:"choices,omitempty"`
+}
+
 type JobProperties struct {
 	Timeout         int64               `bson:"timeout"                json:"timeout"               yaml:"timeout"`
 	Retry           int64               `bson:"retry"                  json:"retry"                 yaml:"retry"`
@@ -161,6 +434,12 @@ type JobProperties struct {
 	CacheEnable  bool                 `bson:"cache_enable"           json:"cache_enable"          yaml:"cache_enable"`
 	CacheDirType types.CacheDirType   `bson:"cache_dir_type"         json:"cache_dir_type"        yaml:"cache_dir_type"`
 	CacheUserDir string               `bson:"cache_user_dir"         json:"cache_user_dir"        yaml:"cache_user_dir"`
+	// Runner is the runner the job is dispatched to instead of kubernetes, e.g. for the macOS builds.
+	Runner string `bson:"runner,omitempty" json:"runner,omitempty" yaml:"runner,omitempty"`
+	// RunnerLabels targets the runners which have all the labels if Runner is empty.
+	RunnerLabels []string `bson:"runner_labels,omitempty" json:"runner_labels,omitempty" yaml:"runner_labels,omitempty"`
+	// VariableGroups are the names of the variable groups in the project which are injected before the Envs.
+	VariableGroups []string `bson:"variable_groups,omitempty" json:"variable_groups,omitempty" yaml:"variable_groups,omitempty"`
 }
 
 type Step struct {
@@ -178,8 +457,10 @@ type Output struct {
 type WorkflowV4Hook struct {
 	Name                string              `bson:"name"                      json:"name"`
 	AutoCancel          bool                `bson:"auto_cancel"               json:"auto_cancel"`
+	CancelInProgress    bool                `bson:"cancel_in_progress"        json:"cancel_in_progress"`
 	CheckPatchSetChange bool                `bson:"check_patch_set_change"    json:"check_patch_set_change"`
 	Enabled             bool                `bson:"enabled"                   json:"enabled"`
+	MergeGate           bool                `bson:"merge_gate"                json:"merge_gate"`
 	MainRepo            *MainHookRepo       `bson:"main_repo"                 json:"main_repo"`
 	Description         string              `bson:"description,omitempty"     json:"description,omitempty"`
 	Repos               []*types.Repository `bson:"-"                         json:"repos,omitempty"`
@@ -195,6 +476,41 @@ type Param struct {
 	ChoiceOption []string `bson:"choice_option,omitempty"   json:"choice_option,omitempty"     yaml:"choice_option,omitempty"`
 	Default      string   `bson:"default"                   json:"default"                     yaml:"default"`
 	IsCredential bool     `bson:"is_credential"             json:"is_credential"               yaml:"is_credential"`
+	// Source lists the options of a choice param when the workflow is run, ChoiceOption is ignored if it is set.
+	Source *ParamSource `bson:"source,omitempty"          json:"source,omitempty"            yaml:"source,omitempty"`
+}
+
+type ParamSourceType string
+
+const (
+	ParamSourceBranches     ParamSourceType = "branches"
+	ParamSourceEnvironments ParamSourceType = "environments"
+	ParamSourceClusters     ParamSourceType = "clusters"
+)
+
+// ParamSource is where the options of a param come from. An input of the source is either fixed or the value
+// of another param the user selects first, e.g. the branches of the repo selected in the param RepoParam.
+type ParamSource struct {
+	Type ParamSourceType `bson:"type"                     json:"type"                     yaml:"type"`
+	// the repo of the branches, RepoParam names a param whose value is like namespace/name
+	CodehostID    int    `bson:"codehost_id,omitempty"    json:"codehost_id,omitempty"    yaml:"codehost_id,omitempty"`
+	RepoNamespace string `bson:"repo_namespace,omitempty" json:"repo_namespace,omitempty" yaml:"repo_namespace,omitempty"`
+	RepoName      string `bson:"repo_name,omitempty"      json:"repo_name,omitempty"      yaml:"repo_name,omitempty"`
+	RepoParam     string `bson:"repo_param,omitempty"     json:"repo_param,omitempty"     yaml:"repo_param,omitempty"`
+	// the cluster of the environments, ClusterParam names a param whose value is the id of a cluster
+	ClusterID    string `bson:"cluster_id,omitempty"     json:"cluster_id,omitempty"     yaml:"cluster_id,omitempty"`
+	ClusterParam string `bson:"cluster_param,omitempty"  json:"cluster_param,omitempty"  yaml:"cluster_param,omitempty"`
+}
+
+// DependsOn returns the name of the param the options depend on, it is empty if the inputs are fixed.
+func (s *ParamSource) DependsOn() string {
+	switch s.Type {
+	case ParamSourceBranches:
+		return s.RepoParam
+	case ParamSourceEnvironments:
+		return s.ClusterParam
+	}
+	return ""
 }
 
 func IToiYaml(before interface{}, after interface{}) error {
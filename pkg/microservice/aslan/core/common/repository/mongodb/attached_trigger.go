@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type AttachedTriggerColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewAttachedTriggerColl() *AttachedTriggerColl {
+	name := models.AttachedTrigger{}.TableName()
+	return &AttachedTriggerColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *AttachedTriggerColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *AttachedTriggerColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "workflow_name", Value: 1},
+			bson.E{Key: "task_id", Value: 1},
+		},
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *AttachedTriggerColl) Create(args *models.AttachedTrigger) error {
+	args.CreateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *AttachedTriggerColl) List(workflowName string, taskID int64) ([]*models.AttachedTrigger, error) {
+	resp := make([]*models.AttachedTrigger, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{"workflow_name": workflowName, "task_id": taskID})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *AttachedTriggerColl) Delete(workflowName string, taskID int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"workflow_name": workflowName, "task_id": taskID})
+	return err
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type BlueprintColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewBlueprintColl() *BlueprintColl {
+	name := models.Blueprint{}.TableName()
+	return &BlueprintColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *BlueprintColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *BlueprintColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *BlueprintColl) Create(args *models.Blueprint) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *BlueprintColl) Update(args *models.Blueprint) error {
+	args.UpdateTime = time.Now().Unix()
+	change := bson.M{"$set": bson.M{
+		"description":        args.Description,
+		"codehost_id":        args.CodehostID,
+		"template_namespace": args.TemplateNamespace,
+		"template_repo":      args.TemplateRepo,
+		"branch":             args.Branch,
+		"env_name":           args.EnvName,
+		"cluster_id":         args.ClusterID,
+		"registry_id":        args.RegistryID,
+		"updated_by":         args.UpdatedBy,
+		"update_time":        args.UpdateTime,
+	}}
+	res, err := c.UpdateOne(context.TODO(), bson.M{"name": args.Name}, change)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (c *BlueprintColl) Find(name string) (*models.Blueprint, error) {
+	resp := new(models.Blueprint)
+	err := c.FindOne(context.TODO(), bson.M{"name": name}).Decode(resp)
+	return resp, err
+}
+
+func (c *BlueprintColl) List() ([]*models.Blueprint, error) {
+	resp := make([]*models.Blueprint, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *BlueprintColl) Delete(name string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"name": name})
+	return err
+}
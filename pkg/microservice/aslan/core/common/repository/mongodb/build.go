@@ -42,6 +42,8 @@ type BuildListOption struct {
 	BasicImageID string
 	PrivateKeyID string
 	TemplateID   string
+	// VariableGroup lists the builds which reference the variable group.
+	VariableGroup string
 }
 
 // FindOption ...
@@ -146,6 +148,9 @@ func (c *BuildColl) List(opt *BuildListOption) ([]*models.Build, error) {
 	if len(opt.TemplateID) != 0 {
 		query["template_id"] = opt.TemplateID
 	}
+	if len(opt.VariableGroup) != 0 {
+		query["pre_build.variable_groups"] = opt.VariableGroup
+	}
 
 	var resp []*models.Build
 	ctx := context.Background()
@@ -244,6 +249,19 @@ func (c *BuildColl) UpdateBuildParam(name, productName string, params []*models.
 	return err
 }
 
+func (c *BuildColl) UpdateDockerfileTemplateRevision(name, productName string, revision int64) error {
+	query := bson.M{"name": name}
+	if productName != "" {
+		query["product_name"] = productName
+	}
+
+	change := bson.M{"$set": bson.M{
+		"post_build.docker_build.template_revision": revision,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 // DistinctTargets finds modules distinct service templates
 func (c *BuildColl) DistinctTargets(excludeModule []string, productName string) (map[string]bool, error) {
 	query := bson.M{}
@@ -285,3 +303,19 @@ func (c *BuildColl) GetDockerfileTemplateReference(templateID string) ([]*models
 	}
 	return ret, nil
 }
+
+// GetCodehostReference returns the builds which clone the repos of the codehost.
+func (c *BuildColl) GetCodehostReference(codehostID int) ([]*models.Build, error) {
+	ret := make([]*models.Build, 0)
+	query := bson.M{"repos.codehost_id": codehostID}
+
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -133,6 +133,19 @@ func (c *ChartColl) Update(obj *models.Chart) error {
 	return err
 }
 
+// UpdateMeta updates the classification of the template without creating a new revision.
+func (c *ChartColl) UpdateMeta(name, language, framework, description string) error {
+	query := bson.M{"name": name}
+	change := bson.M{"$set": bson.M{
+		"language":    language,
+		"framework":   framework,
+		"description": description,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
 func (c *ChartColl) Delete(name string) error {
 	query := bson.M{"name": name}
 	_, err := c.DeleteOne(context.TODO(), query)
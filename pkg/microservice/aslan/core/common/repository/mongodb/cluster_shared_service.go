@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ClusterSharedServiceColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewClusterSharedServiceColl() *ClusterSharedServiceColl {
+	name := models.ClusterSharedService{}.TableName()
+	return &ClusterSharedServiceColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ClusterSharedServiceColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ClusterSharedServiceColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "cluster_id", Value: 1},
+			bson.E{Key: "name", Value: 1},
+			bson.E{Key: "revision", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ClusterSharedServiceColl) Create(args *models.ClusterSharedService) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = time.Now().Unix()
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// Find returns the latest revision of the shared service if revision is 0.
+func (c *ClusterSharedServiceColl) Find(clusterID, name string, revision int64) (*models.ClusterSharedService, error) {
+	query := bson.M{"cluster_id": clusterID, "name": name}
+	if revision > 0 {
+		query["revision"] = revision
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "revision", Value: -1}})
+
+	resp := new(models.ClusterSharedService)
+	err := c.FindOne(context.TODO(), query, opts).Decode(resp)
+	return resp, err
+}
+
+// ListRevisions returns all revisions of the shared service, the latest one comes first.
+func (c *ClusterSharedServiceColl) ListRevisions(clusterID, name string) ([]*models.ClusterSharedService, error) {
+	return c.list(bson.M{"cluster_id": clusterID, "name": name})
+}
+
+// ListLatest returns the latest revision of each shared service in the cluster.
+func (c *ClusterSharedServiceColl) ListLatest(clusterID string) ([]*models.ClusterSharedService, error) {
+	revisions, err := c.list(bson.M{"cluster_id": clusterID})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*models.ClusterSharedService, 0)
+	seen := make(map[string]bool)
+	for _, svc := range revisions {
+		if seen[svc.Name] {
+			continue
+		}
+		seen[svc.Name] = true
+		resp = append(resp, svc)
+	}
+	return resp, nil
+}
+
+func (c *ClusterSharedServiceColl) list(query bson.M) ([]*models.ClusterSharedService, error) {
+	resp := make([]*models.ClusterSharedService, 0)
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "revision", Value: -1}})
+
+	cursor, err := c.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &resp)
+	return resp, err
+}
+
+func (c *ClusterSharedServiceColl) UpdateStatus(args *models.ClusterSharedService) error {
+	query := bson.M{"_id": args.ID}
+	change := bson.M{"$set": bson.M{
+		"status":      args.Status,
+		"error":       args.Error,
+		"update_time": time.Now().Unix(),
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+// Delete removes all revisions of the shared service.
+func (c *ClusterSharedServiceColl) Delete(clusterID, name string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"cluster_id": clusterID, "name": name})
+	return err
+}
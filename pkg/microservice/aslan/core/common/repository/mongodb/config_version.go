@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WorkflowV4VersionColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowV4VersionColl() *WorkflowV4VersionColl {
+	name := models.WorkflowV4Version{}.TableName()
+	return &WorkflowV4VersionColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WorkflowV4VersionColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowV4VersionColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "create_time", Value: 1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *WorkflowV4VersionColl) Create(args *models.WorkflowV4Version) error {
+	args.CreateTime = time.Now().Unix()
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// ListAsOf returns the latest version of every workflow of the project saved before the time, the deleted ones
+// are included.
+func (c *WorkflowV4VersionColl) ListAsOf(projectName string, timestamp int64) ([]*models.WorkflowV4Version, error) {
+	resp := make([]*models.WorkflowV4Version, 0)
+	cursor, err := c.Aggregate(context.TODO(), latestVersionsPipeline(projectName, "workflow_name", timestamp))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *WorkflowV4VersionColl) DeleteByProject(projectName string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"project_name": projectName})
+	return err
+}
+
+type EnvVersionColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvVersionColl() *EnvVersionColl {
+	name := models.EnvVersion{}.TableName()
+	return &EnvVersionColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvVersionColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvVersionColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "create_time", Value: 1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvVersionColl) Create(args *models.EnvVersion) error {
+	args.CreateTime = time.Now().Unix()
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// ListAsOf returns the latest version of every environment of the project saved before the time, the deleted ones
+// are included.
+func (c *EnvVersionColl) ListAsOf(projectName string, timestamp int64) ([]*models.EnvVersion, error) {
+	resp := make([]*models.EnvVersion, 0)
+	cursor, err := c.Aggregate(context.TODO(), latestVersionsPipeline(projectName, "env_name", timestamp))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvVersionColl) DeleteByProject(projectName string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"project_name": projectName})
+	return err
+}
+
+// latestVersionsPipeline groups the versions of the project saved before the time by the key and keeps the last one.
+func latestVersionsPipeline(projectName, key string, timestamp int64) []bson.M {
+	return []bson.M{
+		{"$match": bson.M{"project_name": projectName, "create_time": bson.M{"$lte": timestamp}}},
+		{"$sort": bson.D{{"create_time", 1}, {"_id", 1}}},
+		{"$group": bson.M{"_id": "$" + key, "version": bson.M{"$last": "$$ROOT"}}},
+		{"$replaceRoot": bson.M{"newRoot": "$version"}},
+	}
+}
@@ -70,6 +70,51 @@ func (c *CounterColl) GetNextSeq(counterName string) (int64, error) {
 	return ct.Seq, nil
 }
 
+// GetNextSeqFrom increases the counter like GetNextSeq, a missing counter is seeded by the sequence
+// returned by seed first, so that the sequence continues from the existing data.
+func (c *CounterColl) GetNextSeqFrom(counterName string, seed func() (int64, error)) (int64, error) {
+	return c.GetNextSeqsFrom(counterName, 1, seed)
+}
+
+// GetNextSeqsFrom reserves n sequences of the counter in one atomic increase and returns the first one,
+// the reserved sequences are [first, first+n). A missing counter is seeded like GetNextSeqFrom.
+func (c *CounterColl) GetNextSeqsFrom(counterName string, n int64, seed func() (int64, error)) (int64, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid number of sequences %d", n)
+	}
+	ct := &models.Counter{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := c.FindOneAndUpdate(context.TODO(), bson.M{"_id": counterName}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(ct)
+	if err == nil {
+		return ct.Seq - n + 1, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+
+	seq, err := seed()
+	if err != nil {
+		return 0, err
+	}
+	if err := c.Seed(counterName, seq); err != nil {
+		return 0, err
+	}
+	if err := c.FindOneAndUpdate(context.TODO(), bson.M{"_id": counterName}, bson.M{"$inc": bson.M{"seq": n}}, opts).Decode(ct); err != nil {
+		return 0, err
+	}
+	return ct.Seq - n + 1, nil
+}
+
+// Seed makes sure the sequence of the counter is not less than seq, the counter is created if it does not exist.
+func (c *CounterColl) Seed(counterName string, seq int64) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": counterName}, bson.M{"$max": bson.M{"seq": seq}}, options.Update().SetUpsert(true))
+	// the counter is created by another upsert at the same time.
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
 func (c *CounterColl) Delete(counterName string) error {
 	query := bson.M{"_id": counterName}
 	_, err := c.DeleteOne(context.TODO(), query)
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type CredentialHealthColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewCredentialHealthColl() *CredentialHealthColl {
+	name := models.CredentialHealth{}.TableName()
+	return &CredentialHealthColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *CredentialHealthColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *CredentialHealthColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "kind", Value: 1},
+			bson.E{Key: "resource_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *CredentialHealthColl) Upsert(args *models.CredentialHealth) error {
+	query := bson.M{"kind": args.Kind, "resource_id": args.ResourceID}
+	change := bson.M{"$set": bson.M{
+		"name":        args.Name,
+		"owner":       args.Owner,
+		"healthy":     args.Healthy,
+		"message":     args.Message,
+		"expires_at":  args.ExpiresAt,
+		"checked_at":  args.CheckedAt,
+		"notified_at": args.NotifiedAt,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *CredentialHealthColl) List(kind string) ([]*models.CredentialHealth, error) {
+	resp := make([]*models.CredentialHealth, 0)
+	query := bson.M{}
+	if kind != "" {
+		query["kind"] = kind
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{Key: "kind", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// DeleteStale removes the records of the credentials which were deleted, they are not checked any more.
+func (c *CredentialHealthColl) DeleteStale(checkedBefore int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"checked_at": bson.M{"$lt": checkedBefore}})
+	return err
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type CredentialUsageColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+type ListCredentialUsageOption struct {
+	Kind         string
+	CredentialID string
+	ProjectName  string
+	// StartTime and EndTime bound the time of the usages if they are set.
+	StartTime int64
+	EndTime   int64
+	Page      int64
+	PageSize  int64
+}
+
+func NewCredentialUsageColl() *CredentialUsageColl {
+	name := models.CredentialUsage{}.TableName()
+	return &CredentialUsageColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *CredentialUsageColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *CredentialUsageColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "kind", Value: 1},
+				bson.E{Key: "credential_id", Value: 1},
+				bson.E{Key: "used_at", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "used_at", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *CredentialUsageColl) BulkCreate(args []*models.CredentialUsage) error {
+	if len(args) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		docs = append(docs, arg)
+	}
+	_, err := c.InsertMany(context.TODO(), docs)
+	return err
+}
+
+// List returns the usages from the latest and the total number of the usages matching the option.
+func (c *CredentialUsageColl) List(opt *ListCredentialUsageOption) ([]*models.CredentialUsage, int64, error) {
+	query := bson.M{}
+	if opt.Kind != "" {
+		query["kind"] = opt.Kind
+	}
+	if opt.CredentialID != "" {
+		query["credential_id"] = opt.CredentialID
+	}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	usedAt := bson.M{}
+	if opt.StartTime > 0 {
+		usedAt["$gte"] = opt.StartTime
+	}
+	if opt.EndTime > 0 {
+		usedAt["$lte"] = opt.EndTime
+	}
+	if len(usedAt) > 0 {
+		query["used_at"] = usedAt
+	}
+
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOption := options.Find().SetSort(bson.D{{Key: "used_at", Value: -1}})
+	if opt.Page > 0 && opt.PageSize > 0 {
+		findOption.SetSkip((opt.Page - 1) * opt.PageSize).SetLimit(opt.PageSize)
+	}
+	resp := make([]*models.CredentialUsage, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, findOption)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, count, err
+}
@@ -137,6 +137,22 @@ func (c *CronjobColl) Create(args *models.Cronjob) error {
 	return nil
 }
 
+func (c *CronjobColl) GetByID(id string) (*models.Cronjob, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.Cronjob)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// UpdateLastScheduledTime moves the last scheduled time forward, an earlier time is ignored.
+func (c *CronjobColl) UpdateLastScheduledTime(id primitive.ObjectID, scheduledTime int64) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$max": bson.M{"last_scheduled_time": scheduledTime}})
+	return err
+}
+
 func (c *CronjobColl) Update(job *models.Cronjob) error {
 	if job == nil {
 		return errors.New("nil cron job args")
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ListCronjobRunOption struct {
+	CronjobID  string
+	MissedOnly bool
+	Limit      int64
+}
+
+type CronjobRunColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewCronjobRunColl() *CronjobRunColl {
+	name := models.CronjobRun{}.TableName()
+	return &CronjobRunColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *CronjobRunColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *CronjobRunColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"idempotency_key": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "cronjob_id", Value: 1},
+				bson.E{Key: "scheduled_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+// Create inserts the run, false is returned if the run of the idempotency key already exists.
+func (c *CronjobRunColl) Create(args *models.CronjobRun) (bool, error) {
+	args.CreateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), args)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+func (c *CronjobRunColl) List(opt *ListCronjobRunOption) ([]*models.CronjobRun, error) {
+	resp := make([]*models.CronjobRun, 0)
+	query := bson.M{"cronjob_id": opt.CronjobID}
+	if opt.MissedOnly {
+		query["missed"] = true
+	}
+	opts := options.Find().SetSort(bson.M{"scheduled_time": -1})
+	if opt.Limit > 0 {
+		opts.SetLimit(opt.Limit)
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *CronjobRunColl) DeleteByCronjobIDs(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := c.DeleteMany(context.TODO(), bson.M{"cronjob_id": bson.M{"$in": ids}})
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type CustomFieldColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewCustomFieldColl() *CustomFieldColl {
+	name := models.CustomField{}.TableName()
+	return &CustomFieldColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *CustomFieldColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *CustomFieldColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"key": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *CustomFieldColl) Find(id string) (*models.CustomField, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	field := new(models.CustomField)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(field)
+	return field, err
+}
+
+func (c *CustomFieldColl) FindByKey(key string) (*models.CustomField, error) {
+	field := new(models.CustomField)
+	err := c.FindOne(context.TODO(), bson.M{"key": key}).Decode(field)
+	return field, err
+}
+
+// List lists the custom fields of the scope, the fields of all scopes are listed if it is empty.
+func (c *CustomFieldColl) List(scope config.CustomFieldScope) ([]*models.CustomField, error) {
+	query := bson.M{}
+	if scope != "" {
+		query["scope"] = scope
+	}
+	opts := options.Find().SetSort(bson.M{"create_time": 1})
+	resp := make([]*models.CustomField, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *CustomFieldColl) Create(args *models.CustomField) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *CustomFieldColl) Update(id string, args *models.CustomField) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	args.ID = oid
+	args.UpdateTime = time.Now().Unix()
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, bson.M{"$set": args})
+	return err
+}
+
+func (c *CustomFieldColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
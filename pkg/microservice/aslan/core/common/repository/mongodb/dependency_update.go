@@ -0,0 +1,214 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type DependencyUpdatePolicyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewDependencyUpdatePolicyColl() *DependencyUpdatePolicyColl {
+	name := models.DependencyUpdatePolicy{}.TableName()
+	return &DependencyUpdatePolicyColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *DependencyUpdatePolicyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *DependencyUpdatePolicyColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"project_name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *DependencyUpdatePolicyColl) Find(projectName string) (*models.DependencyUpdatePolicy, error) {
+	resp := new(models.DependencyUpdatePolicy)
+	err := c.FindOne(context.TODO(), bson.M{"project_name": projectName}).Decode(resp)
+	return resp, err
+}
+
+func (c *DependencyUpdatePolicyColl) Upsert(args *models.DependencyUpdatePolicy) error {
+	args.UpdateTime = time.Now().Unix()
+	change := bson.M{"$set": bson.M{
+		"enabled":        args.Enabled,
+		"auto_create_pr": args.AutoCreatePR,
+		"ignored":        args.Ignored,
+		"updated_by":     args.UpdatedBy,
+		"update_time":    args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"project_name": args.ProjectName}, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *DependencyUpdatePolicyColl) UpdateLastCheckTime(projectName string, checkTime int64) error {
+	change := bson.M{"$set": bson.M{"last_check_time": checkTime}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"project_name": projectName}, change)
+	return err
+}
+
+func (c *DependencyUpdatePolicyColl) ListEnabled() ([]*models.DependencyUpdatePolicy, error) {
+	resp := make([]*models.DependencyUpdatePolicy, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+type DependencyUpdateColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+type ListDependencyUpdateOption struct {
+	ProjectName string
+	ServiceName string
+	Status      []config.DependencyUpdateStatus
+}
+
+func NewDependencyUpdateColl() *DependencyUpdateColl {
+	name := models.DependencyUpdate{}.TableName()
+	return &DependencyUpdateColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *DependencyUpdateColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *DependencyUpdateColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "service_name", Value: 1},
+				bson.E{Key: "kind", Value: 1},
+				bson.E{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "codehost_id", Value: 1},
+				bson.E{Key: "repo_owner", Value: 1},
+				bson.E{Key: "repo_name", Value: 1},
+				bson.E{Key: "pr_number", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *DependencyUpdateColl) Create(args *models.DependencyUpdate) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	if args.WorkflowTasks == nil {
+		args.WorkflowTasks = make([]*models.DependencyUpdateTask, 0)
+	}
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *DependencyUpdateColl) Update(args *models.DependencyUpdate) error {
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.ReplaceOne(context.TODO(), bson.M{"_id": args.ID}, args)
+	return err
+}
+
+func (c *DependencyUpdateColl) FindByID(id string) (*models.DependencyUpdate, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.DependencyUpdate)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *DependencyUpdateColl) List(opt *ListDependencyUpdateOption) ([]*models.DependencyUpdate, error) {
+	resp := make([]*models.DependencyUpdate, 0)
+	query := bson.M{"project_name": opt.ProjectName}
+	if opt.ServiceName != "" {
+		query["service_name"] = opt.ServiceName
+	}
+	if len(opt.Status) > 0 {
+		query["status"] = bson.M{"$in": opt.Status}
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.M{"create_time": -1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// FindByPR returns the update the pull request is opened for.
+func (c *DependencyUpdateColl) FindByPR(codehostID int, owner, repo string, prNumber int) (*models.DependencyUpdate, error) {
+	resp := new(models.DependencyUpdate)
+	query := bson.M{
+		"codehost_id": codehostID,
+		"repo_owner":  owner,
+		"repo_name":   repo,
+		"pr_number":   prNumber,
+	}
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+// UpsertTask adds the workflow task to the update, or refreshes its status if it is already linked.
+func (c *DependencyUpdateColl) UpsertTask(id primitive.ObjectID, task *models.DependencyUpdateTask) error {
+	query := bson.M{"_id": id, "workflow_tasks": bson.M{"$elemMatch": bson.M{"workflow_name": task.WorkflowName, "task_id": task.TaskID}}}
+	res, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": bson.M{"workflow_tasks.$": task}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$push": bson.M{"workflow_tasks": task}})
+	return err
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type DeploymentBaselineColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewDeploymentBaselineColl() *DeploymentBaselineColl {
+	name := models.DeploymentBaseline{}.TableName()
+	return &DeploymentBaselineColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *DeploymentBaselineColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *DeploymentBaselineColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.D{{"project_name", 1}, {"env_name", 1}, {"service_name", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *DeploymentBaselineColl) Find(projectName, envName, serviceName string) (*models.DeploymentBaseline, error) {
+	resp := new(models.DeploymentBaseline)
+	query := bson.M{"project_name": projectName, "env_name": envName, "service_name": serviceName}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+// Upsert replaces the baseline of the service in the env.
+func (c *DeploymentBaselineColl) Upsert(args *models.DeploymentBaseline) error {
+	query := bson.M{"project_name": args.ProjectName, "env_name": args.EnvName, "service_name": args.ServiceName}
+	change := bson.M{"$set": bson.M{
+		"workflow_name": args.WorkflowName,
+		"task_id":       args.TaskID,
+		"signals":       args.Signals,
+		"update_time":   time.Now().Unix(),
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type DNSProviderColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewDNSProviderColl() *DNSProviderColl {
+	name := models.DNSProvider{}.TableName()
+	return &DNSProviderColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *DNSProviderColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *DNSProviderColl) EnsureIndex(ctx context.Context) error {
+	return nil
+}
+
+func (c *DNSProviderColl) Find(id string) (*models.DNSProvider, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	provider := new(models.DNSProvider)
+	if err := c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(provider); err != nil {
+		return nil, err
+	}
+	if provider.Secret, err = crypto.DecryptSecret(provider.EncryptedSecret); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// List lists the providers with the secrets decrypted, only the enabled ones are listed if enabledOnly is true.
+func (c *DNSProviderColl) List(enabledOnly bool) ([]*models.DNSProvider, error) {
+	query := bson.M{}
+	if enabledOnly {
+		query["enabled"] = true
+	}
+	resp := make([]*models.DNSProvider, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, err
+	}
+	for _, provider := range resp {
+		if provider.Secret, err = crypto.DecryptSecret(provider.EncryptedSecret); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (c *DNSProviderColl) Create(args *models.DNSProvider) error {
+	encrypted, err := crypto.EncryptSecret(args.Secret)
+	if err != nil {
+		return err
+	}
+	args.EncryptedSecret = encrypted
+	args.UpdateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *DNSProviderColl) Update(id string, args *models.DNSProvider) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	encrypted, err := crypto.EncryptSecret(args.Secret)
+	if err != nil {
+		return err
+	}
+	args.ID = oid
+	args.EncryptedSecret = encrypted
+	args.UpdateTime = time.Now().Unix()
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, bson.M{"$set": args})
+	return err
+}
+
+func (c *DNSProviderColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
+
+// ListEncrypted lists the providers without decrypting the secrets, it is used to re-encrypt the secrets.
+func (c *DNSProviderColl) ListEncrypted() ([]*models.DNSProvider, error) {
+	var providers []*models.DNSProvider
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &providers)
+	return providers, err
+}
+
+// UpdateEncryptedSecret replaces the encrypted secret only if it is not changed since it is read.
+func (c *DNSProviderColl) UpdateEncryptedSecret(id primitive.ObjectID, oldEncryptedSecret, encryptedSecret string) error {
+	_, err := c.UpdateOne(context.TODO(),
+		bson.M{"_id": id, "encrypted_secret": oldEncryptedSecret},
+		bson.M{"$set": bson.M{"encrypted_secret": encryptedSecret}},
+	)
+	return err
+}
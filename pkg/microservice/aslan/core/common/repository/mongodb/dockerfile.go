@@ -30,6 +30,13 @@ import (
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
+const dockerfileTemplateCounterName = "template&dockerfile:%s"
+
+type DockerfileTemplateListOption struct {
+	Language  string
+	Framework string
+}
+
 type DockerfileTemplateColl struct {
 	*mongo.Collection
 
@@ -64,8 +71,14 @@ func (c *DockerfileTemplateColl) Create(obj *models.DockerfileTemplate) error {
 		return fmt.Errorf("nil object")
 	}
 
-	_, err := c.InsertOne(context.TODO(), obj)
-	return err
+	res, err := c.InsertOne(context.TODO(), obj)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		obj.ID = id
+	}
+	return nil
 }
 
 func (c *DockerfileTemplateColl) Update(idString string, obj *models.DockerfileTemplate) error {
@@ -83,18 +96,24 @@ func (c *DockerfileTemplateColl) Update(idString string, obj *models.DockerfileT
 	return err
 }
 
-func (c *DockerfileTemplateColl) List(pageNum, pageSize int) ([]*models.DockerfileTemplate, int, error) {
+func (c *DockerfileTemplateColl) List(pageNum, pageSize int, opt *DockerfileTemplateListOption) ([]*models.DockerfileTemplate, int, error) {
 	resp := make([]*models.DockerfileTemplate, 0)
 	query := bson.M{}
+	if opt != nil && opt.Language != "" {
+		query["language"] = opt.Language
+	}
+	if opt != nil && opt.Framework != "" {
+		query["framework"] = opt.Framework
+	}
 	count, err := c.CountDocuments(context.TODO(), query)
 	if err != nil {
 		return nil, 0, err
 	}
-	opt := options.Find().
+	findOpt := options.Find().
 		SetSkip(int64((pageNum - 1) * pageSize)).
 		SetLimit(int64(pageSize))
 
-	cursor, err := c.Collection.Find(context.TODO(), query, opt)
+	cursor, err := c.Collection.Find(context.TODO(), query, findOpt)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -120,6 +139,10 @@ func (c *DockerfileTemplateColl) GetById(idstring string) (*models.DockerfileTem
 	return resp, nil
 }
 
+func (c *DockerfileTemplateColl) GetNextRevision(idString string) (int64, error) {
+	return NewCounterColl().GetNextSeq(fmt.Sprintf(dockerfileTemplateCounterName, idString))
+}
+
 func (c *DockerfileTemplateColl) DeleteByID(idstring string) error {
 	id, err := primitive.ObjectIDFromHex(idstring)
 	if err != nil {
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type DockerfileTemplateVersionColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewDockerfileTemplateVersionColl() *DockerfileTemplateVersionColl {
+	name := models.DockerfileTemplateVersion{}.TableName()
+	return &DockerfileTemplateVersionColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *DockerfileTemplateVersionColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *DockerfileTemplateVersionColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "template_id", Value: 1},
+			bson.E{Key: "revision", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+
+	return err
+}
+
+func (c *DockerfileTemplateVersionColl) Create(obj *models.DockerfileTemplateVersion) error {
+	if obj == nil {
+		return fmt.Errorf("nil object")
+	}
+
+	_, err := c.InsertOne(context.TODO(), obj)
+	return err
+}
+
+func (c *DockerfileTemplateVersionColl) Find(templateID string, revision int64) (*models.DockerfileTemplateVersion, error) {
+	resp := new(models.DockerfileTemplateVersion)
+	query := bson.M{"template_id": templateID, "revision": revision}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *DockerfileTemplateVersionColl) DeleteByTemplateID(templateID string) error {
+	query := bson.M{"template_id": templateID}
+
+	_, err := c.DeleteMany(context.TODO(), query)
+	return err
+}
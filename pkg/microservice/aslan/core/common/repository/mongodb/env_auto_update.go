@@ -0,0 +1,290 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvAutoUpdatePolicyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvAutoUpdatePolicyColl() *EnvAutoUpdatePolicyColl {
+	name := models.EnvAutoUpdatePolicy{}.TableName()
+	return &EnvAutoUpdatePolicyColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvAutoUpdatePolicyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvAutoUpdatePolicyColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvAutoUpdatePolicyColl) Create(args *models.EnvAutoUpdatePolicy) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Update saves the settings of the policy, the pause controls and the last deploy are kept.
+func (c *EnvAutoUpdatePolicyColl) Update(args *models.EnvAutoUpdatePolicy) error {
+	args.UpdateTime = time.Now().Unix()
+	change := bson.M{"$set": bson.M{
+		"branch":          args.Branch,
+		"source_workflow": args.SourceWorkflow,
+		"services":        args.Services,
+		"deploy_workflow": args.DeployWorkflow,
+		"deploy_job_name": args.DeployJobName,
+		"updated_by":      args.UpdatedBy,
+		"update_time":     args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": args.ID}, change)
+	return err
+}
+
+func (c *EnvAutoUpdatePolicyColl) UpdatePause(id primitive.ObjectID, paused bool, pausedBy, reason string) error {
+	pauseTime := int64(0)
+	if paused {
+		pauseTime = time.Now().Unix()
+	}
+	change := bson.M{"$set": bson.M{
+		"paused":       paused,
+		"paused_by":    pausedBy,
+		"pause_reason": reason,
+		"pause_time":   pauseTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, change)
+	return err
+}
+
+func (c *EnvAutoUpdatePolicyColl) UpdateLastDeploy(id primitive.ObjectID, deployTime int64) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_deploy_time": deployTime}})
+	return err
+}
+
+func (c *EnvAutoUpdatePolicyColl) FindByID(id string) (*models.EnvAutoUpdatePolicy, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.EnvAutoUpdatePolicy)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// List returns the policies of the project, or of the environment if envName is not empty.
+func (c *EnvAutoUpdatePolicyColl) List(projectName, envName string) ([]*models.EnvAutoUpdatePolicy, error) {
+	resp := make([]*models.EnvAutoUpdatePolicy, 0)
+	query := bson.M{"project_name": projectName}
+	if envName != "" {
+		query["env_name"] = envName
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.M{"create_time": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvAutoUpdatePolicyColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+type EnvAutoUpdateEventColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+type ListEnvAutoUpdateEventOption struct {
+	ProjectName string
+	EnvName     string
+	PolicyID    string
+	Batch       string
+	Limit       int64
+}
+
+func NewEnvAutoUpdateEventColl() *EnvAutoUpdateEventColl {
+	name := models.EnvAutoUpdateEvent{}.TableName()
+	return &EnvAutoUpdateEventColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvAutoUpdateEventColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvAutoUpdateEventColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "env_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "policy_id", Value: 1},
+				bson.E{Key: "status", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "deploy_workflow", Value: 1},
+				bson.E{Key: "deploy_task_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *EnvAutoUpdateEventColl) Create(args *models.EnvAutoUpdateEvent) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// List returns the latest events first, the events of a batch are returned in the order they are recorded.
+func (c *EnvAutoUpdateEventColl) List(opt *ListEnvAutoUpdateEventOption) ([]*models.EnvAutoUpdateEvent, error) {
+	resp := make([]*models.EnvAutoUpdateEvent, 0)
+	query := bson.M{}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	if opt.EnvName != "" {
+		query["env_name"] = opt.EnvName
+	}
+	if opt.PolicyID != "" {
+		query["policy_id"] = opt.PolicyID
+	}
+	opts := options.Find().SetSort(bson.M{"create_time": -1})
+	if opt.Batch != "" {
+		query["batch"] = opt.Batch
+		opts.SetSort(bson.M{"create_time": 1})
+	}
+	if opt.Limit > 0 {
+		opts.SetLimit(opt.Limit)
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// ListPendingPolicies returns the ids of the policies which have events waiting to be deployed.
+func (c *EnvAutoUpdateEventColl) ListPendingPolicies() ([]string, error) {
+	values, err := c.Distinct(context.TODO(), "policy_id", bson.M{"status": config.EnvAutoUpdatePending})
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			resp = append(resp, id)
+		}
+	}
+	return resp, nil
+}
+
+// Claim marks the pending events of the policy as deploying in the batch, the events claimed by another
+// instance in the meantime are left as they are.
+func (c *EnvAutoUpdateEventColl) Claim(policyID, batch string) error {
+	query := bson.M{"policy_id": policyID, "status": config.EnvAutoUpdatePending}
+	change := bson.M{"$set": bson.M{"status": config.EnvAutoUpdateDeploying, "batch": batch, "update_time": time.Now().Unix()}}
+	_, err := c.UpdateMany(context.TODO(), query, change)
+	return err
+}
+
+// SetDeployTask records the task deploying the events of the batch.
+func (c *EnvAutoUpdateEventColl) SetDeployTask(batch, workflowName string, taskID int64) error {
+	change := bson.M{"$set": bson.M{"deploy_workflow": workflowName, "deploy_task_id": taskID, "update_time": time.Now().Unix()}}
+	_, err := c.UpdateMany(context.TODO(), bson.M{"batch": batch}, change)
+	return err
+}
+
+// FinishBatch sets the final status of the events of the batch.
+func (c *EnvAutoUpdateEventColl) FinishBatch(batch string, status config.EnvAutoUpdateStatus, message string) error {
+	change := bson.M{"$set": bson.M{"status": status, "message": message, "update_time": time.Now().Unix()}}
+	_, err := c.UpdateMany(context.TODO(), bson.M{"batch": batch}, change)
+	return err
+}
+
+// FinishDeployTask sets the final status of the events deployed by the task.
+func (c *EnvAutoUpdateEventColl) FinishDeployTask(workflowName string, taskID int64, status config.EnvAutoUpdateStatus, message string) error {
+	query := bson.M{"deploy_workflow": workflowName, "deploy_task_id": taskID, "status": config.EnvAutoUpdateDeploying}
+	change := bson.M{"$set": bson.M{"status": status, "message": message, "update_time": time.Now().Unix()}}
+	_, err := c.UpdateMany(context.TODO(), query, change)
+	return err
+}
+
+// SkipPending skips the events of the policy waiting to be deployed.
+func (c *EnvAutoUpdateEventColl) SkipPending(policyID, message string) error {
+	query := bson.M{"policy_id": policyID, "status": config.EnvAutoUpdatePending}
+	change := bson.M{"$set": bson.M{"status": config.EnvAutoUpdateSkipped, "message": message, "update_time": time.Now().Unix()}}
+	_, err := c.UpdateMany(context.TODO(), query, change)
+	return err
+}
+
+func (c *EnvAutoUpdateEventColl) DeleteByPolicyID(policyID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"policy_id": policyID})
+	return err
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvDataRefreshColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvDataRefreshColl() *EnvDataRefreshColl {
+	name := models.EnvDataRefresh{}.TableName()
+	return &EnvDataRefreshColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvDataRefreshColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvDataRefreshColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvDataRefreshColl) Create(args *models.EnvDataRefresh) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *EnvDataRefreshColl) Update(args *models.EnvDataRefresh) error {
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.ReplaceOne(context.TODO(), bson.M{"_id": args.ID}, args)
+	return err
+}
+
+func (c *EnvDataRefreshColl) Find(projectName, envName, name string) (*models.EnvDataRefresh, error) {
+	resp := new(models.EnvDataRefresh)
+	query := bson.M{"project_name": projectName, "env_name": envName, "name": name}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvDataRefreshColl) FindByID(id string) (*models.EnvDataRefresh, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.EnvDataRefresh)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvDataRefreshColl) List(projectName, envName string) ([]*models.EnvDataRefresh, error) {
+	resp := make([]*models.EnvDataRefresh, 0)
+	query := bson.M{"project_name": projectName, "env_name": envName}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvDataRefreshColl) ListWithScheduleEnabled() ([]*models.EnvDataRefresh, error) {
+	resp := make([]*models.EnvDataRefresh, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"schedule_enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvDataRefreshColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+type EnvDataRefreshRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvDataRefreshRecordColl() *EnvDataRefreshRecordColl {
+	name := models.EnvDataRefreshRecord{}.TableName()
+	return &EnvDataRefreshRecordColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvDataRefreshRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvDataRefreshRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "refresh_id", Value: 1},
+			bson.E{Key: "start_time", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvDataRefreshRecordColl) Create(args *models.EnvDataRefreshRecord) error {
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *EnvDataRefreshRecordColl) Update(args *models.EnvDataRefreshRecord) error {
+	_, err := c.ReplaceOne(context.TODO(), bson.M{"_id": args.ID}, args)
+	return err
+}
+
+// List returns the latest records of the data refresh, all records are returned if limit is 0.
+func (c *EnvDataRefreshRecordColl) List(refreshID string, limit int64) ([]*models.EnvDataRefreshRecord, error) {
+	resp := make([]*models.EnvDataRefreshRecord, 0)
+	opts := options.Find().SetSort(bson.M{"start_time": -1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"refresh_id": refreshID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvDataRefreshRecordColl) FindRunning(refreshID string) (*models.EnvDataRefreshRecord, error) {
+	resp := new(models.EnvDataRefreshRecord)
+	query := bson.M{"refresh_id": refreshID, "status": config.StatusRunning}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvDataRefreshRecordColl) DeleteByRefreshID(refreshID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"refresh_id": refreshID})
+	return err
+}
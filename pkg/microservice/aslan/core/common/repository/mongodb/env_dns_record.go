@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvDNSRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvDNSRecordColl() *EnvDNSRecordColl {
+	name := models.EnvDNSRecord{}.TableName()
+	return &EnvDNSRecordColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvDNSRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvDNSRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+			bson.E{Key: "host", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// List lists the records of the environment, the records of all the environments are listed if both the
+// project and the environment are empty.
+func (c *EnvDNSRecordColl) List(projectName, envName string) ([]*models.EnvDNSRecord, error) {
+	query := bson.M{}
+	if projectName != "" {
+		query["project_name"] = projectName
+	}
+	if envName != "" {
+		query["env_name"] = envName
+	}
+	resp := make([]*models.EnvDNSRecord, 0)
+	opts := options.Find().SetSort(bson.D{{"host", 1}})
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvDNSRecordColl) Upsert(record *models.EnvDNSRecord) error {
+	record.UpdateTime = time.Now().Unix()
+	query := bson.M{"project_name": record.ProjectName, "env_name": record.EnvName, "host": record.Host}
+	_, err := c.ReplaceOne(context.TODO(), query, record, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (c *EnvDNSRecordColl) Delete(projectName, envName, host string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"project_name": projectName, "env_name": envName, "host": host})
+	return err
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvLockColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvLockColl() *EnvLockColl {
+	name := models.EnvLock{}.TableName()
+	return &EnvLockColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvLockColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvLockColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "project_name", Value: 1}},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "owner", Value: 1}},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+// Acquire takes the lock of the environment if it is free, expired or already owned by the owner of
+// the lock, the lock is extended by ttl in all the cases. False is returned if the lock is held by
+// someone else.
+func (c *EnvLockColl) Acquire(lock *models.EnvLock, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	lock.ID = models.EnvLockID(lock.ProjectName, lock.EnvName)
+	lock.ExpireAt = now.Add(ttl).Unix()
+
+	// the lock time is kept when the owner extends the lock.
+	res, err := c.UpdateOne(context.TODO(),
+		bson.M{"_id": lock.ID, "owner": lock.Owner},
+		bson.M{"$set": bson.M{"expire_at": lock.ExpireAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+
+	lock.LockTime = now.Unix()
+	query := bson.M{"_id": lock.ID, "expire_at": bson.M{"$lt": now.Unix()}}
+	// the upsert fails with a duplicate key error if the lock exists and is held by someone else.
+	_, err = c.ReplaceOne(context.TODO(), query, lock, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Find returns the lock of the environment, nil is returned if the environment is not locked.
+func (c *EnvLockColl) Find(projectName, envName string) (*models.EnvLock, error) {
+	query := bson.M{
+		"_id":       models.EnvLockID(projectName, envName),
+		"expire_at": bson.M{"$gte": time.Now().Unix()},
+	}
+	resp := new(models.EnvLock)
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *EnvLockColl) List(projectName string) ([]*models.EnvLock, error) {
+	query := bson.M{
+		"project_name": projectName,
+		"expire_at":    bson.M{"$gte": time.Now().Unix()},
+	}
+	resp := make([]*models.EnvLock, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Release releases the lock of the environment if it is held by the owner.
+func (c *EnvLockColl) Release(projectName, envName, owner string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": models.EnvLockID(projectName, envName), "owner": owner})
+	return err
+}
+
+// ReleaseByOwner releases all the locks held by the owner.
+func (c *EnvLockColl) ReleaseByOwner(owner string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"owner": owner})
+	return err
+}
+
+// ForceRelease releases the lock of the environment whoever holds it.
+func (c *EnvLockColl) ForceRelease(projectName, envName string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": models.EnvLockID(projectName, envName)})
+	return err
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvSmokeTestColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvSmokeTestColl() *EnvSmokeTestColl {
+	name := models.EnvSmokeTest{}.TableName()
+	return &EnvSmokeTestColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvSmokeTestColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvSmokeTestColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvSmokeTestColl) Create(args *models.EnvSmokeTest) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Update saves the settings of the smoke test, the result of the runs is kept.
+func (c *EnvSmokeTestColl) Update(args *models.EnvSmokeTest) error {
+	args.UpdateTime = time.Now().Unix()
+	change := bson.M{"$set": bson.M{
+		"test_name":        args.TestName,
+		"description":      args.Description,
+		"timeout":          args.Timeout,
+		"alert_rule":       args.AlertRule,
+		"schedule_enabled": args.ScheduleEnabled,
+		"updated_by":       args.UpdatedBy,
+		"update_time":      args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": args.ID}, change)
+	return err
+}
+
+func (c *EnvSmokeTestColl) UpdateResult(args *models.EnvSmokeTest) error {
+	change := bson.M{"$set": bson.M{
+		"last_status":          args.LastStatus,
+		"last_run_time":        args.LastRunTime,
+		"consecutive_failures": args.ConsecutiveFailures,
+		"alerting":             args.Alerting,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": args.ID}, change)
+	return err
+}
+
+func (c *EnvSmokeTestColl) Find(projectName, envName, name string) (*models.EnvSmokeTest, error) {
+	resp := new(models.EnvSmokeTest)
+	query := bson.M{"project_name": projectName, "env_name": envName, "name": name}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvSmokeTestColl) FindByID(id string) (*models.EnvSmokeTest, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.EnvSmokeTest)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvSmokeTestColl) List(projectName, envName string) ([]*models.EnvSmokeTest, error) {
+	resp := make([]*models.EnvSmokeTest, 0)
+	query := bson.M{"project_name": projectName, "env_name": envName}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvSmokeTestColl) ListWithScheduleEnabled() ([]*models.EnvSmokeTest, error) {
+	resp := make([]*models.EnvSmokeTest, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"schedule_enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvSmokeTestColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+type EnvSmokeTestRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvSmokeTestRecordColl() *EnvSmokeTestRecordColl {
+	name := models.EnvSmokeTestRecord{}.TableName()
+	return &EnvSmokeTestRecordColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *EnvSmokeTestRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvSmokeTestRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "smoke_test_id", Value: 1},
+			bson.E{Key: "start_time", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvSmokeTestRecordColl) Create(args *models.EnvSmokeTestRecord) error {
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *EnvSmokeTestRecordColl) Update(args *models.EnvSmokeTestRecord) error {
+	_, err := c.ReplaceOne(context.TODO(), bson.M{"_id": args.ID}, args)
+	return err
+}
+
+// List returns the latest records of the smoke test, all records are returned if limit is 0.
+func (c *EnvSmokeTestRecordColl) List(smokeTestID string, limit int64) ([]*models.EnvSmokeTestRecord, error) {
+	resp := make([]*models.EnvSmokeTestRecord, 0)
+	opts := options.Find().SetSort(bson.M{"start_time": -1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"smoke_test_id": smokeTestID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// ListEndedSince returns the records of the smoke tests of the env which are started after the given time and
+// have ended.
+func (c *EnvSmokeTestRecordColl) ListEndedSince(projectName, envName string, since int64) ([]*models.EnvSmokeTestRecord, error) {
+	resp := make([]*models.EnvSmokeTestRecord, 0)
+	query := bson.M{
+		"project_name": projectName,
+		"env_name":     envName,
+		"start_time":   bson.M{"$gte": since},
+		"end_time":     bson.M{"$gt": 0},
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *EnvSmokeTestRecordColl) FindRunning(smokeTestID string) (*models.EnvSmokeTestRecord, error) {
+	resp := new(models.EnvSmokeTestRecord)
+	query := bson.M{"smoke_test_id": smokeTestID, "status": config.StatusRunning}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvSmokeTestRecordColl) DeleteBySmokeTestID(smokeTestID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"smoke_test_id": smokeTestID})
+	return err
+}
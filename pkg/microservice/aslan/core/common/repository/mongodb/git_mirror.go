@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type GitMirrorColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewGitMirrorColl() *GitMirrorColl {
+	name := models.GitMirror{}.TableName()
+	return &GitMirrorColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *GitMirrorColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *GitMirrorColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "codehost_id", Value: 1},
+			bson.E{Key: "repo_namespace", Value: 1},
+			bson.E{Key: "repo_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *GitMirrorColl) Create(args *models.GitMirror) error {
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *GitMirrorColl) Get(id string) (*models.GitMirror, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.GitMirror{}
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// Find returns the mirror of the repo, the namespace is the owner if the repo has no namespace.
+func (c *GitMirrorColl) Find(codehostID int, namespace, name string) (*models.GitMirror, error) {
+	resp := &models.GitMirror{}
+	query := bson.M{"codehost_id": codehostID, "repo_namespace": namespace, "repo_name": name}
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+// List returns all the mirrors if namespace and name are empty, the webhooks only know the path of
+// the repo so the codehost is not filtered.
+func (c *GitMirrorColl) List(namespace, name string) ([]*models.GitMirror, error) {
+	resp := make([]*models.GitMirror, 0)
+	query := bson.M{}
+	if namespace != "" {
+		query["repo_namespace"] = namespace
+	}
+	if name != "" {
+		query["repo_name"] = name
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{Key: "repo_namespace", Value: 1}, {Key: "repo_name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *GitMirrorColl) UpdateStatus(args *models.GitMirror) error {
+	change := bson.M{"$set": bson.M{
+		"status":    args.Status,
+		"error":     args.Error,
+		"synced_at": args.SyncedAt,
+		"size":      args.Size,
+	}}
+	_, err := c.UpdateByID(context.TODO(), args.ID, change)
+	return err
+}
+
+func (c *GitMirrorColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
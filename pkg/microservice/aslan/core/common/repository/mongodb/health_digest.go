@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type HealthDigestPolicyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewHealthDigestPolicyColl() *HealthDigestPolicyColl {
+	name := models.HealthDigestPolicy{}.TableName()
+	return &HealthDigestPolicyColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *HealthDigestPolicyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *HealthDigestPolicyColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"project_name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *HealthDigestPolicyColl) Find(projectName string) (*models.HealthDigestPolicy, error) {
+	resp := new(models.HealthDigestPolicy)
+	err := c.FindOne(context.TODO(), bson.M{"project_name": projectName}).Decode(resp)
+	return resp, err
+}
+
+func (c *HealthDigestPolicyColl) Upsert(args *models.HealthDigestPolicy) error {
+	args.UpdateTime = time.Now().Unix()
+	change := bson.M{"$set": bson.M{
+		"enabled":     args.Enabled,
+		"weekday":     args.Weekday,
+		"hour":        args.Hour,
+		"notify_ctl":  args.NotifyCtl,
+		"updated_by":  args.UpdatedBy,
+		"update_time": args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"project_name": args.ProjectName}, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *HealthDigestPolicyColl) ListEnabled() ([]*models.HealthDigestPolicy, error) {
+	resp := make([]*models.HealthDigestPolicy, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+type HealthDigestColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewHealthDigestColl() *HealthDigestColl {
+	name := models.HealthDigest{}.TableName()
+	return &HealthDigestColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *HealthDigestColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *HealthDigestColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "week", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Create inserts the digest, false is returned if the digest of the week is already sent.
+func (c *HealthDigestColl) Create(args *models.HealthDigest) (bool, error) {
+	args.CreateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), args)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+// List returns the digests of the project, the latest ones are returned first.
+func (c *HealthDigestColl) List(projectName string, limit int64) ([]*models.HealthDigest, error) {
+	resp := make([]*models.HealthDigest, 0)
+	opts := options.Find().SetSort(bson.M{"create_time": -1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"project_name": projectName}, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// Delete removes the digest so that it is sent again, it is used if the digest fails to be sent.
+func (c *HealthDigestColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
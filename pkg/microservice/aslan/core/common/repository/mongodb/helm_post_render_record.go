@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type HelmPostRenderRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewHelmPostRenderRecordColl() *HelmPostRenderRecordColl {
+	name := models.HelmPostRenderRecord{}.TableName()
+	return &HelmPostRenderRecordColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *HelmPostRenderRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *HelmPostRenderRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+			bson.E{Key: "release_name", Value: 1},
+			bson.E{Key: "create_time", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *HelmPostRenderRecordColl) Create(args *models.HelmPostRenderRecord) error {
+	args.CreateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// List returns the latest records of the env, the records of all releases are returned if releaseName is empty.
+func (c *HelmPostRenderRecordColl) List(projectName, envName, releaseName string, limit int64) ([]*models.HelmPostRenderRecord, error) {
+	resp := make([]*models.HelmPostRenderRecord, 0)
+	query := bson.M{"project_name": projectName, "env_name": envName}
+	if releaseName != "" {
+		query["release_name"] = releaseName
+	}
+	opts := options.Find().SetSort(bson.M{"create_time": -1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
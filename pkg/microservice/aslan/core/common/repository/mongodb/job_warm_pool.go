@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type JobWarmPoolColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewJobWarmPoolColl() *JobWarmPoolColl {
+	name := models.JobWarmPool{}.TableName()
+	return &JobWarmPoolColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *JobWarmPoolColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *JobWarmPoolColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *JobWarmPoolColl) List() ([]*models.JobWarmPool, error) {
+	return c.list(bson.M{})
+}
+
+func (c *JobWarmPoolColl) ListEnabled() ([]*models.JobWarmPool, error) {
+	return c.list(bson.M{"enabled": true, "size": bson.M{"$gt": 0}})
+}
+
+func (c *JobWarmPoolColl) list(query bson.M) ([]*models.JobWarmPool, error) {
+	resp := make([]*models.JobWarmPool, 0)
+	ctx := context.Background()
+
+	cursor, err := c.Collection.Find(ctx, query, options.Find().SetSort(bson.D{bson.E{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &resp)
+	return resp, err
+}
+
+func (c *JobWarmPoolColl) Find(id string) (*models.JobWarmPool, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.JobWarmPool{}
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *JobWarmPoolColl) Create(args *models.JobWarmPool) error {
+	if args == nil {
+		return errors.New("nil job warm pool info")
+	}
+
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = time.Now().Unix()
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *JobWarmPoolColl) Update(id string, args *models.JobWarmPool) error {
+	if args == nil {
+		return errors.New("nil job warm pool info")
+	}
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"_id": oid}
+	change := bson.M{"$set": bson.M{
+		"name":         args.Name,
+		"cluster_id":   args.ClusterID,
+		"build_os":     args.BuildOS,
+		"image_from":   args.ImageFrom,
+		"res_req":      args.ResourceRequest,
+		"res_req_spec": args.ResReqSpec,
+		"size":         args.Size,
+		"enabled":      args.Enabled,
+		"update_by":    args.UpdateBy,
+		"update_time":  time.Now().Unix(),
+	}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err == nil && res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
+
+func (c *JobWarmPoolColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
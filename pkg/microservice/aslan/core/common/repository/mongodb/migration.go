@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+const migrationLockID = "migration"
+
+type MigrationColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewMigrationColl() *MigrationColl {
+	name := models.Migration{}.TableName()
+	return &MigrationColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *MigrationColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *MigrationColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"version": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *MigrationColl) List() ([]*models.Migration, error) {
+	resp := make([]*models.Migration, 0)
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "version", Value: 1}})
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *MigrationColl) Upsert(args *models.Migration) error {
+	query := bson.M{"version": args.Version}
+	_, err := c.ReplaceOne(context.TODO(), query, args, options.Replace().SetUpsert(true))
+	return err
+}
+
+type MigrationLockColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewMigrationLockColl() *MigrationLockColl {
+	name := models.MigrationLock{}.TableName()
+	return &MigrationLockColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *MigrationLockColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *MigrationLockColl) EnsureIndex(_ context.Context) error {
+	return nil
+}
+
+// Acquire takes the lock if it is free, expired or already owned by the owner, the lock is
+// extended by ttl in all the cases. False is returned if the lock is held by someone else.
+func (c *MigrationLockColl) Acquire(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	query := bson.M{
+		"_id": migrationLockID,
+		"$or": []bson.M{
+			{"owner": owner},
+			{"expire_at": bson.M{"$lt": now.Unix()}},
+		},
+	}
+	change := bson.M{"$set": bson.M{"owner": owner, "expire_at": now.Add(ttl).Unix()}}
+
+	// the upsert fails with a duplicate key error if the lock exists and is held by someone else.
+	_, err := c.UpdateOne(ctx, query, change, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *MigrationLockColl) Release(ctx context.Context, owner string) error {
+	_, err := c.DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": owner})
+	return err
+}
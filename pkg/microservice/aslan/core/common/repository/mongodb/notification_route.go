@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type NotificationRouteColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewNotificationRouteColl() *NotificationRouteColl {
+	name := models.NotificationRoute{}.TableName()
+	return &NotificationRouteColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *NotificationRouteColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *NotificationRouteColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *NotificationRouteColl) Find(id string) (*models.NotificationRoute, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	route := new(models.NotificationRoute)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(route)
+	return route, err
+}
+
+// List lists the routes in the order they are evaluated, only the enabled ones are listed if enabledOnly is true.
+func (c *NotificationRouteColl) List(enabledOnly bool) ([]*models.NotificationRoute, error) {
+	query := bson.M{}
+	if enabledOnly {
+		query["enabled"] = true
+	}
+	opts := options.Find().SetSort(bson.D{{"priority", 1}, {"create_time", 1}})
+	resp := make([]*models.NotificationRoute, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *NotificationRouteColl) Create(args *models.NotificationRoute) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *NotificationRouteColl) Update(id string, args *models.NotificationRoute) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	args.ID = oid
+	args.UpdateTime = time.Now().Unix()
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, bson.M{"$set": args})
+	return err
+}
+
+func (c *NotificationRouteColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type NotificationStreakColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewNotificationStreakColl() *NotificationStreakColl {
+	name := models.NotificationStreak{}.TableName()
+	return &NotificationStreakColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *NotificationStreakColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *NotificationStreakColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.D{{"route_id", 1}, {"subject", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// IncFailures adds a failure to the streak of the subject on the route and returns the failures in a row.
+func (c *NotificationStreakColl) IncFailures(routeID, subject string) (int, error) {
+	query := bson.M{"route_id": routeID, "subject": subject}
+	change := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{"update_time": time.Now().Unix()},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	streak := new(models.NotificationStreak)
+	if err := c.FindOneAndUpdate(context.TODO(), query, change, opts).Decode(streak); err != nil {
+		return 0, err
+	}
+	return streak.Failures, nil
+}
+
+// Reset ends the streak of the subject on the route.
+func (c *NotificationStreakColl) Reset(routeID, subject string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"route_id": routeID, "subject": subject})
+	return err
+}
+
+func (c *NotificationStreakColl) DeleteByRoute(routeID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"route_id": routeID})
+	return err
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -351,6 +352,9 @@ func (c *ProductColl) UpdateRender(envName, productName string, render *models.R
 		"render": render,
 	}}
 	_, err := c.UpdateOne(context.TODO(), query, change)
+	if err == nil {
+		c.saveVersion(envName, productName)
+	}
 
 	return err
 }
@@ -358,6 +362,9 @@ func (c *ProductColl) UpdateRender(envName, productName string, render *models.R
 func (c *ProductColl) Delete(owner, productName string) error {
 	query := bson.M{"env_name": owner, "product_name": productName}
 	_, err := c.DeleteOne(context.TODO(), query)
+	if err == nil {
+		c.saveVersion(owner, productName)
+	}
 
 	return err
 }
@@ -376,6 +383,9 @@ func (c *ProductColl) Update(args *models.Product) error {
 	}}
 
 	_, err := c.UpdateOne(context.TODO(), query, change)
+	if err == nil {
+		c.saveVersion(args.EnvName, args.ProductName)
+	}
 
 	return err
 }
@@ -390,6 +400,9 @@ func (c *ProductColl) Create(args *models.Product) error {
 	args.CreateTime = now
 	args.UpdateTime = now
 	_, err := c.InsertOne(context.TODO(), args)
+	if err == nil {
+		c.saveVersion(args.EnvName, args.ProductName)
+	}
 
 	return err
 }
@@ -406,10 +419,31 @@ func (c *ProductColl) UpdateGroup(envName, productName string, groupIndex int, g
 	}
 
 	_, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": change})
+	if err == nil {
+		c.saveVersion(envName, productName)
+	}
 
 	return err
 }
 
+// saveVersion saves the current state of the env so that it can be looked up as of a time, the env is saved as
+// deleted if it does not exist any more. The failure is only logged since the change has been made.
+func (c *ProductColl) saveVersion(envName, productName string) {
+	version := &models.EnvVersion{ProjectName: productName, EnvName: envName}
+	env, err := c.Find(&ProductFindOptions{Name: productName, EnvName: envName})
+	if err == mongo.ErrNoDocuments {
+		version.Deleted = true
+	} else if err != nil {
+		log.Warnf("failed to find env %s/%s to save its version, err: %s", productName, envName, err)
+		return
+	} else {
+		version.Env = env
+	}
+	if err := NewEnvVersionColl().Create(version); err != nil {
+		log.Warnf("failed to save the version of env %s/%s, err: %s", productName, envName, err)
+	}
+}
+
 func (c *ProductColl) UpdateProductRecycleDay(envName, productName string, recycleDay int) error {
 	query := bson.M{"env_name": envName, "product_name": productName}
 
@@ -421,6 +455,61 @@ func (c *ProductColl) UpdateProductRecycleDay(envName, productName string, recyc
 	return err
 }
 
+func (c *ProductColl) UpdateRequireDiffApproval(envName, productName string, required bool) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"update_time":           time.Now().Unix(),
+		"require_diff_approval": required,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
+func (c *ProductColl) UpdateAutoRollback(envName, productName string, enabled bool) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"update_time":   time.Now().Unix(),
+		"auto_rollback": enabled,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
+func (c *ProductColl) UpdateNamespaceMeta(envName, productName string, meta *models.NamespaceMeta) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"update_time":    time.Now().Unix(),
+		"namespace_meta": meta,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
+func (c *ProductColl) UpdateClusterSharedServices(envName, productName string, names []string) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"update_time":             time.Now().Unix(),
+		"cluster_shared_services": names,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
+func (c *ProductColl) UpdateHelmPostRenderer(envName, productName string, postRenderer *models.HelmPostRenderer) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"update_time":        time.Now().Unix(),
+		"helm_post_renderer": postRenderer,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
 func (c *ProductColl) UpdateIsPublic(envName, productName string, isPublic bool) error {
 	query := bson.M{"env_name": envName, "product_name": productName}
 	change := bson.M{"$set": bson.M{
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ProjectQuotaColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewProjectQuotaColl() *ProjectQuotaColl {
+	name := models.ProjectQuota{}.TableName()
+	return &ProjectQuotaColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ProjectQuotaColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ProjectQuotaColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"project_name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ProjectQuotaColl) Find(projectName string) (*models.ProjectQuota, error) {
+	resp := new(models.ProjectQuota)
+	err := c.FindOne(context.TODO(), bson.M{"project_name": projectName}).Decode(resp)
+	return resp, err
+}
+
+func (c *ProjectQuotaColl) List() ([]*models.ProjectQuota, error) {
+	resp := make([]*models.ProjectQuota, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"project_name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// Upsert saves the limits of the project, the override and the measured storage are kept.
+func (c *ProjectQuotaColl) Upsert(args *models.ProjectQuota) error {
+	query := bson.M{"project_name": args.ProjectName}
+	change := bson.M{"$set": bson.M{
+		"build_minutes": args.BuildMinutes,
+		"storage_mb":    args.StorageMB,
+		"environments":  args.Environments,
+		"update_time":   time.Now().Unix(),
+		"update_by":     args.UpdateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// UpdateOverride sets the override of the project, a nil override revokes it.
+func (c *ProjectQuotaColl) UpdateOverride(projectName string, override *models.QuotaOverride) error {
+	change := bson.M{"$unset": bson.M{"override": ""}}
+	if override != nil {
+		change = bson.M{"$set": bson.M{"override": override}}
+	}
+
+	res, err := c.UpdateOne(context.TODO(), bson.M{"project_name": projectName}, change)
+	if err == nil && res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
+
+func (c *ProjectQuotaColl) UpdateStorage(projectName string, storageBytes int64) error {
+	change := bson.M{"$set": bson.M{
+		"storage_bytes":       storageBytes,
+		"storage_measured_at": time.Now().Unix(),
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), bson.M{"project_name": projectName}, change)
+	return err
+}
+
+func (c *ProjectQuotaColl) Delete(projectName string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"project_name": projectName})
+	return err
+}
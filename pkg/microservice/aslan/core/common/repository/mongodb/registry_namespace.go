@@ -28,9 +28,16 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/cache"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
+// registries are queried with various options, the cached queries are invalidated together on writes.
+const (
+	registryCacheNamespace = "registry"
+	registryCacheTTL       = 5 * time.Minute
+)
+
 type FindRegOps struct {
 	ID          string `json:"id"`
 	RegAddr     string `json:"reg_addr"`
@@ -79,6 +86,7 @@ func (r *RegistryNamespaceColl) Create(args *models.RegistryNamespace) error {
 	args.UpdateTime = time.Now().Unix()
 
 	_, err := r.InsertOne(context.TODO(), args)
+	cache.Invalidate(registryCacheNamespace)
 	return err
 }
 
@@ -115,15 +123,25 @@ func (opt FindRegOps) getQuery() bson.M {
 }
 
 func (r *RegistryNamespaceColl) Find(opt *FindRegOps) (*models.RegistryNamespace, error) {
-	query := opt.getQuery()
+	key := cache.NamespaceKey(registryCacheNamespace, "find:"+cache.Hash(*opt))
+	return cache.Load(key, registryCacheTTL, func() (*models.RegistryNamespace, error) {
+		query := opt.getQuery()
 
-	res := &models.RegistryNamespace{}
-	err := r.FindOne(context.TODO(), query).Decode(res)
+		res := &models.RegistryNamespace{}
+		err := r.FindOne(context.TODO(), query).Decode(res)
 
-	return res, err
+		return res, err
+	})
 }
 
 func (r *RegistryNamespaceColl) FindAll(opt *FindRegOps) ([]*models.RegistryNamespace, error) {
+	key := cache.NamespaceKey(registryCacheNamespace, "find_all:"+cache.Hash(*opt))
+	return cache.Load(key, registryCacheTTL, func() ([]*models.RegistryNamespace, error) {
+		return r.findAll(opt)
+	})
+}
+
+func (r *RegistryNamespaceColl) findAll(opt *FindRegOps) ([]*models.RegistryNamespace, error) {
 	query := opt.getQuery()
 
 	ctx := context.Background()
@@ -159,6 +177,7 @@ func (r *RegistryNamespaceColl) Update(id string, args *models.RegistryNamespace
 
 	change := bson.M{"$set": args}
 	_, err = r.UpdateOne(context.TODO(), query, change)
+	cache.Invalidate(registryCacheNamespace)
 	return err
 }
 
@@ -170,6 +189,7 @@ func (r *RegistryNamespaceColl) Delete(id string) error {
 
 	query := bson.M{"_id": oid}
 	_, err = r.DeleteOne(context.TODO(), query)
+	cache.Invalidate(registryCacheNamespace)
 
 	return err
 }
@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ReleaseTrainColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewReleaseTrainColl() *ReleaseTrainColl {
+	name := models.ReleaseTrain{}.TableName()
+	return &ReleaseTrainColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ReleaseTrainColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ReleaseTrainColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ReleaseTrainColl) Create(args *models.ReleaseTrain) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Update saves the settings of the train, the result of the dispatches is kept.
+func (c *ReleaseTrainColl) Update(args *models.ReleaseTrain) error {
+	args.UpdateTime = time.Now().Unix()
+	change := bson.M{"$set": bson.M{
+		"description":      args.Description,
+		"source_workflows": args.SourceWorkflows,
+		"deploy_workflow":  args.DeployWorkflow,
+		"deploy_job_name":  args.DeployJobName,
+		"schedule_enabled": args.ScheduleEnabled,
+		"updated_by":       args.UpdatedBy,
+		"update_time":      args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": args.ID}, change)
+	return err
+}
+
+func (c *ReleaseTrainColl) UpdateDispatch(id primitive.ObjectID, version string, dispatchTime int64) error {
+	change := bson.M{"$set": bson.M{
+		"last_version":       version,
+		"last_dispatch_time": dispatchTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, change)
+	return err
+}
+
+func (c *ReleaseTrainColl) Find(projectName, name string) (*models.ReleaseTrain, error) {
+	resp := new(models.ReleaseTrain)
+	query := bson.M{"project_name": projectName, "name": name}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *ReleaseTrainColl) FindByID(id string) (*models.ReleaseTrain, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.ReleaseTrain)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *ReleaseTrainColl) List(projectName string) ([]*models.ReleaseTrain, error) {
+	resp := make([]*models.ReleaseTrain, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"project_name": projectName}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// ListBySourceWorkflow returns the trains boarded by the passed tasks of the workflow.
+func (c *ReleaseTrainColl) ListBySourceWorkflow(projectName, workflowName string) ([]*models.ReleaseTrain, error) {
+	resp := make([]*models.ReleaseTrain, 0)
+	query := bson.M{"project_name": projectName, "source_workflows": workflowName}
+
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *ReleaseTrainColl) ListWithScheduleEnabled() ([]*models.ReleaseTrain, error) {
+	resp := make([]*models.ReleaseTrain, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"schedule_enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *ReleaseTrainColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+type ReleaseTrainItemColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+type ListReleaseTrainItemOption struct {
+	TrainID string
+	Status  config.ReleaseTrainItemStatus
+	Version string
+	Limit   int64
+}
+
+func NewReleaseTrainItemColl() *ReleaseTrainItemColl {
+	name := models.ReleaseTrainItem{}.TableName()
+	return &ReleaseTrainItemColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ReleaseTrainItemColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ReleaseTrainItemColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "train_id", Value: 1},
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "train_id", Value: 1},
+				bson.E{Key: "status", Value: 1},
+				bson.E{Key: "create_time", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *ReleaseTrainItemColl) Create(args *models.ReleaseTrainItem) error {
+	args.CreateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// List returns the items in the order they boarded the train, all items are returned if the limit is 0.
+func (c *ReleaseTrainItemColl) List(opt *ListReleaseTrainItemOption) ([]*models.ReleaseTrainItem, error) {
+	resp := make([]*models.ReleaseTrainItem, 0)
+	query := bson.M{"train_id": opt.TrainID}
+	if opt.Status != "" {
+		query["status"] = opt.Status
+	}
+	if opt.Version != "" {
+		query["version"] = opt.Version
+	}
+	opts := options.Find().SetSort(bson.M{"create_time": 1})
+	if opt.Limit > 0 {
+		opts.SetLimit(opt.Limit)
+	}
+
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// Ship marks the pending items as shipped in the version, the items which have been shipped by another
+// dispatch in the meantime are left as they are.
+func (c *ReleaseTrainItemColl) Ship(ids []primitive.ObjectID, version string) error {
+	query := bson.M{"_id": bson.M{"$in": ids}, "status": config.ReleaseTrainItemPending}
+	change := bson.M{"$set": bson.M{"status": config.ReleaseTrainItemShipped, "version": version}}
+	_, err := c.UpdateMany(context.TODO(), query, change)
+	return err
+}
+
+// Unship puts the items shipped in the version back on the train.
+func (c *ReleaseTrainItemColl) Unship(trainID, version string) error {
+	query := bson.M{"train_id": trainID, "version": version}
+	change := bson.M{"$set": bson.M{"status": config.ReleaseTrainItemPending, "version": ""}}
+	_, err := c.UpdateMany(context.TODO(), query, change)
+	return err
+}
+
+func (c *ReleaseTrainItemColl) DeleteByTrainID(trainID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"train_id": trainID})
+	return err
+}
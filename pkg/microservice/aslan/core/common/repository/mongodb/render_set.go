@@ -236,6 +236,16 @@ func (c *RenderSetColl) Find(opt *RenderSetFindOption) (*models.RenderSet, error
 	return rs, err
 }
 
+// FindAsOf finds the latest revision of the render set updated before the time.
+func (c *RenderSetColl) FindAsOf(name string, timestamp int64) (*models.RenderSet, error) {
+	query := bson.M{"name": name, "update_time": bson.M{"$lte": timestamp}}
+	opts := options.FindOne().SetSort(bson.D{{"revision", -1}})
+
+	rs := &models.RenderSet{}
+	err := c.FindOne(context.TODO(), query, opts).Decode(rs)
+	return rs, err
+}
+
 func (c *RenderSetColl) Create(args *models.RenderSet) error {
 	if args == nil {
 		return errors.New("RenderSet cannot be nil")
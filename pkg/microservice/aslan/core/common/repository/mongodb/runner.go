@@ -0,0 +1,263 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+type RunnerColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewRunnerColl() *RunnerColl {
+	name := models.Runner{}.TableName()
+	return &RunnerColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *RunnerColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *RunnerColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *RunnerColl) Create(args *models.Runner) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Update updates the settings of the runner, the token is not changed.
+func (c *RunnerColl) Update(name string, args *models.Runner) error {
+	args.UpdateTime = time.Now().Unix()
+	change := bson.M{"$set": bson.M{
+		"type":        args.Type,
+		"description": args.Description,
+		"platform":    args.Platform,
+		"ec2":         args.EC2,
+		"ecs":         args.ECS,
+		"updated_by":  args.UpdatedBy,
+		"update_time": args.UpdateTime,
+		"labels":      args.Labels,
+		"macos":       args.MacOS,
+	}}
+	res, err := c.UpdateOne(context.TODO(), bson.M{"name": name}, change)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (c *RunnerColl) UpdateLastSeenTime(name string, seenTime int64) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"name": name}, bson.M{"$set": bson.M{"last_seen_time": seenTime}})
+	return err
+}
+
+func (c *RunnerColl) Delete(name string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"name": name})
+	return err
+}
+
+func (c *RunnerColl) Find(name string) (*models.Runner, error) {
+	resp := new(models.Runner)
+	err := c.FindOne(context.TODO(), bson.M{"name": name}).Decode(resp)
+	return resp, err
+}
+
+func (c *RunnerColl) List() ([]*models.Runner, error) {
+	resp := make([]*models.Runner, 0)
+
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// CountByLabels counts the runners which have all the labels.
+func (c *RunnerColl) CountByLabels(labels []string) (int64, error) {
+	return c.CountDocuments(context.TODO(), bson.M{"labels": bson.M{"$all": labels}})
+}
+
+type RunnerJobColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewRunnerJobColl() *RunnerJobColl {
+	name := models.RunnerJob{}.TableName()
+	return &RunnerJobColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *RunnerJobColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *RunnerJobColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "runner_name", Value: 1},
+			bson.E{Key: "status", Value: 1},
+			bson.E{Key: "create_time", Value: 1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *RunnerJobColl) Create(args *models.RunnerJob) error {
+	args.CreateTime = time.Now().Unix()
+	args.Status = config.StatusQueued
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *RunnerJobColl) FindByID(id string) (*models.RunnerJob, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.RunnerJob)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *RunnerJobColl) UpdateInstanceID(id primitive.ObjectID, instanceID string) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"instance_id": instanceID}})
+	return err
+}
+
+// Lease assigns the oldest queued job of the runner to the agent, or the given job if jobID is not empty.
+// mongo.ErrNoDocuments is returned if there is no job to run.
+func (c *RunnerJobColl) Lease(r *models.Runner, jobID, agent string, leaseExpireTime int64) (*models.RunnerJob, error) {
+	labels := r.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	query := bson.M{
+		"status": config.StatusQueued,
+		// the jobs targeting the runners by labels are leased if the runner has all the labels.
+		"$or": bson.A{
+			bson.M{"runner_name": r.Name},
+			bson.M{"runner_name": "", "labels": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$nin": labels}}}},
+		},
+	}
+	if jobID != "" {
+		oid, err := primitive.ObjectIDFromHex(jobID)
+		if err != nil {
+			return nil, err
+		}
+		query["_id"] = oid
+	}
+	change := bson.M{"$set": bson.M{
+		"runner_name":       r.Name,
+		"status":            config.StatusRunning,
+		"agent":             agent,
+		"lease_expire_time": leaseExpireTime,
+		"start_time":        time.Now().Unix(),
+	}}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"create_time": 1}).SetReturnDocument(options.After)
+	resp := new(models.RunnerJob)
+	err := c.FindOneAndUpdate(context.TODO(), query, change, opts).Decode(resp)
+	return resp, err
+}
+
+// Renew extends the lease of the running job held by the agent, the job is returned so that the agent
+// knows whether it is cancelled.
+func (c *RunnerJobColl) Renew(id primitive.ObjectID, agent string, leaseExpireTime int64) (*models.RunnerJob, error) {
+	query := bson.M{"_id": id, "agent": agent, "status": config.StatusRunning}
+	change := bson.M{"$set": bson.M{"lease_expire_time": leaseExpireTime}}
+	resp := new(models.RunnerJob)
+	err := c.FindOneAndUpdate(context.TODO(), query, change, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(resp)
+	return resp, err
+}
+
+// Complete saves the result reported by the agent, mongo.ErrNoDocuments is returned if the job is not
+// running on the agent, e.g. it has been cancelled.
+func (c *RunnerJobColl) Complete(id primitive.ObjectID, agent string, status config.Status, errMsg string, outputs []*job.JobOutput) error {
+	query := bson.M{"_id": id, "agent": agent, "status": config.StatusRunning}
+	change := bson.M{"$set": bson.M{
+		"status":   status,
+		"error":    errMsg,
+		"outputs":  outputs,
+		"end_time": time.Now().Unix(),
+	}}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (c *RunnerJobColl) AddSecretFindings(id primitive.ObjectID, findings []*secretscan.Finding) error {
+	change := bson.M{"$push": bson.M{"secret_findings": bson.M{"$each": findings}}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, change)
+	return err
+}
+
+// Stop ends the job which is not done yet with the status, e.g. when it is cancelled or times out.
+func (c *RunnerJobColl) Stop(id primitive.ObjectID, status config.Status, errMsg string) error {
+	query := bson.M{"_id": id, "status": bson.M{"$in": []config.Status{config.StatusQueued, config.StatusRunning}}}
+	change := bson.M{"$set": bson.M{
+		"status":   status,
+		"error":    errMsg,
+		"end_time": time.Now().Unix(),
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
@@ -63,7 +63,7 @@ func (c *S3StorageColl) FindDefault() (*models.S3Storage, error) {
 		return nil, err
 	}
 
-	decryptedKey, err := crypto.AesDecrypt(storage.EncryptedSk)
+	decryptedKey, err := crypto.DecryptSecret(storage.EncryptedSk)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +84,7 @@ func (c *S3StorageColl) Find(id string) (*models.S3Storage, error) {
 		return nil, err
 	}
 
-	decryptedKey, err := crypto.AesDecrypt(storage.EncryptedSk)
+	decryptedKey, err := crypto.DecryptSecret(storage.EncryptedSk)
 	if err != nil {
 		return nil, err
 	}
@@ -105,7 +105,7 @@ func (c *S3StorageColl) GetS3Storage() (*models.S3Storage, error) {
 		return nil, err
 	}
 
-	decryptedKey, err := crypto.AesDecrypt(storage.EncryptedSk)
+	decryptedKey, err := crypto.DecryptSecret(storage.EncryptedSk)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +134,7 @@ func (c *S3StorageColl) Update(id string, args *models.S3Storage) error {
 	query := bson.M{"_id": args.ID}
 	args.UpdateTime = time.Now().Unix()
 
-	encryptedKey, err := crypto.AesEncrypt(args.Sk)
+	encryptedKey, err := crypto.EncryptSecret(args.Sk)
 	if err != nil {
 		return err
 	}
@@ -165,7 +165,7 @@ func (c *S3StorageColl) Delete(id string) error {
 // Create if the crated storage is default, all other default storage will be set as not default
 func (c *S3StorageColl) Create(args *models.S3Storage) error {
 	args.UpdateTime = time.Now().Unix()
-	encryptedKey, err := crypto.AesEncrypt(args.Sk)
+	encryptedKey, err := crypto.EncryptSecret(args.Sk)
 	if err != nil {
 		return err
 	}
@@ -195,7 +195,7 @@ func (c *S3StorageColl) FindAll() ([]*models.S3Storage, error) {
 	}
 
 	for _, s := range storages {
-		decryptedKey, err := crypto.AesDecrypt(s.EncryptedSk)
+		decryptedKey, err := crypto.DecryptSecret(s.EncryptedSk)
 		if err != nil {
 			return nil, err
 		}
@@ -205,6 +205,25 @@ func (c *S3StorageColl) FindAll() ([]*models.S3Storage, error) {
 	return storages, nil
 }
 
+// ListEncrypted lists the storages without decrypting the secret keys, it is used to re-encrypt the keys.
+func (c *S3StorageColl) ListEncrypted() ([]*models.S3Storage, error) {
+	var storages []*models.S3Storage
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &storages)
+	return storages, err
+}
+
+// UpdateEncryptedSk replaces the encrypted secret key only if it is not changed since it is read.
+func (c *S3StorageColl) UpdateEncryptedSk(id primitive.ObjectID, oldEncryptedSk, encryptedSk string) error {
+	query := bson.M{"_id": id, "encryptedSk": oldEncryptedSk}
+	change := bson.M{"$set": bson.M{"encryptedSk": encryptedSk}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 func (c *S3StorageColl) InitData() error {
 	minioEndpoint := config.S3StorageEndpoint()
 	endpointInfo := strings.Split(minioEndpoint, ":")
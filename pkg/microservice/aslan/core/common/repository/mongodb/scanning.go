@@ -158,3 +158,19 @@ func (c *ScanningColl) DeleteByID(idstring string) error {
 	_, err = c.DeleteOne(context.TODO(), query)
 	return err
 }
+
+// GetCodehostReference returns the scannings which clone the repos of the codehost.
+func (c *ScanningColl) GetCodehostReference(codehostID int) ([]*models.Scanning, error) {
+	ret := make([]*models.Scanning, 0)
+	query := bson.M{"repos.codehost_id": codehostID}
+
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -184,6 +184,17 @@ func (c *ServiceColl) ListMaxRevisionsByProduct(productName string) ([]*models.S
 	return c.listMaxRevisions(m, nil)
 }
 
+// ListMaxRevisionsAsOf lists the latest revisions of the services of the project created before the time, the
+// services deleted later are included.
+func (c *ServiceColl) ListMaxRevisionsAsOf(productName string, timestamp int64) ([]*models.Service, error) {
+	m := bson.M{
+		"product_name": productName,
+		"create_time":  bson.M{"$lte": timestamp},
+	}
+
+	return c.listMaxRevisions(m, nil)
+}
+
 func (c *ServiceColl) ListMaxRevisionServicesByYamlTemplate(templateId string) ([]*models.Service, error) {
 	m := bson.M{
 		"template_id": templateId,
@@ -342,6 +353,13 @@ func (c *ServiceColl) Update(args *models.Service) error {
 	return err
 }
 
+func (c *ServiceColl) UpdateResourceStatusMappings(serviceName, productName string, revision int64, mappings []*models.ResourceStatusMapping) error {
+	query := bson.M{"product_name": productName, "service_name": serviceName, "revision": revision}
+	change := bson.M{"$set": bson.M{"resource_status_mappings": mappings}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 // ListExternalServicesBy list service only for external services  ,other service type not use  before refactor
 func (c *ServiceColl) ListExternalWorkloadsBy(productName, envName string, serviceNames ...string) ([]*models.Service, error) {
 	services := make([]*models.Service, 0)
@@ -666,6 +684,15 @@ func (c *ServiceColl) GetTemplateReference(templateID string) ([]*models.Service
 	return c.listMaxRevisions(query, nil)
 }
 
+// GetCodehostReference returns the latest revisions of the services which are loaded from the repos of the codehost.
+func (c *ServiceColl) GetCodehostReference(codehostID int) ([]*models.Service, error) {
+	preMatch := bson.M{"status": bson.M{"$ne": setting.ProductStatusDeleting}}
+	// only the latest revision counts, the codehost of the service may be changed by it.
+	postMatch := bson.M{"codehost_id": codehostID}
+
+	return c.listMaxRevisions(preMatch, postMatch)
+}
+
 func (c *ServiceColl) listMaxRevisions(preMatch, postMatch bson.M) ([]*models.Service, error) {
 	var pipeResp []*grouped
 	pipeline := []bson.M{
@@ -681,9 +708,10 @@ func (c *ServiceColl) listMaxRevisions(preMatch, postMatch bson.M) ([]*models.Se
 					{"product_name", "$product_name"},
 					{"service_name", "$service_name"},
 				},
-				"service_id": bson.M{"$last": "$_id"},
-				"visibility": bson.M{"$last": "$visibility"},
-				"build_name": bson.M{"$last": "$build_name"},
+				"service_id":  bson.M{"$last": "$_id"},
+				"visibility":  bson.M{"$last": "$visibility"},
+				"build_name":  bson.M{"$last": "$build_name"},
+				"codehost_id": bson.M{"$last": "$codehost_id"},
 			},
 		},
 	}
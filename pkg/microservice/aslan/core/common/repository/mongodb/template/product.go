@@ -28,9 +28,17 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	"github.com/koderover/zadig/pkg/tool/cache"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
+// projects are read by almost every request, e.g. to check the deploy type, so they are cached.
+const projectCacheTTL = 5 * time.Minute
+
+func projectCacheKey(productName string) string {
+	return "project:" + productName
+}
+
 type ProjectInfo struct {
 	Name          string `bson:"product_name"`
 	Alias         string `bson:"project_name"`
@@ -71,10 +79,12 @@ func (c *ProductColl) EnsureIndex(ctx context.Context) error {
 }
 
 func (c *ProductColl) Find(productName string) (*template.Product, error) {
-	res := &template.Product{}
-	query := bson.M{"product_name": productName}
-	err := c.FindOne(context.TODO(), query).Decode(res)
-	return res, err
+	return cache.Load(projectCacheKey(productName), projectCacheTTL, func() (*template.Product, error) {
+		res := &template.Product{}
+		query := bson.M{"product_name": productName}
+		err := c.FindOne(context.TODO(), query).Decode(res)
+		return res, err
+	})
 }
 
 func (c *ProductColl) FindProjectName(project string) (*template.Product, error) {
@@ -239,6 +249,7 @@ func (c *ProductColl) Create(args *template.Product) error {
 	}
 
 	_, err = c.InsertOne(context.TODO(), args)
+	cache.Delete(projectCacheKey(args.ProductName))
 	return err
 }
 
@@ -252,6 +263,146 @@ func (c *ProductColl) UpdateServiceOrchestration(productName string, services []
 	}}
 
 	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+// UpdateServiceDependencies saves the service dependencies together with the orchestration derived from them.
+func (c *ProductColl) UpdateServiceDependencies(productName string, dependencies []*template.ServiceDependency, services [][]string, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"service_dependencies": dependencies,
+		"services":             services,
+		"update_time":          time.Now().Unix(),
+		"update_by":            updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+func (c *ProductColl) UpdateCommitPolicy(productName string, policy *template.CommitPolicy, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"commit_policy": policy,
+		"update_time":   time.Now().Unix(),
+		"update_by":     updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+func (c *ProductColl) UpdateGerritVotingPolicy(productName string, policy *template.GerritVotingPolicy, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"gerrit_voting_policy": policy,
+		"update_time":          time.Now().Unix(),
+		"update_by":            updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+func (c *ProductColl) UpdateCustomFields(productName string, fields map[string]string, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"custom_fields": fields,
+		"update_time":   time.Now().Unix(),
+		"update_by":     updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+// UnsetCustomField removes the value of the custom field from all projects, e.g. when the field is deleted.
+func (c *ProductColl) UnsetCustomField(key string) error {
+	field := "custom_fields." + key
+	query := bson.M{field: bson.M{"$exists": true}}
+	var projects []*template.Product
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetProjection(bson.M{"product_name": 1}))
+	if err != nil {
+		return err
+	}
+	if err := cursor.All(context.TODO(), &projects); err != nil {
+		return err
+	}
+
+	_, err = c.UpdateMany(context.TODO(), query, bson.M{"$unset": bson.M{field: ""}})
+	for _, project := range projects {
+		cache.Delete(projectCacheKey(project.ProductName))
+	}
+	return err
+}
+
+func (c *ProductColl) UpdateLicensePolicy(productName string, policy *template.LicensePolicy, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"license_policy": policy,
+		"update_time":    time.Now().Unix(),
+		"update_by":      updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+func (c *ProductColl) UpdateSecretScanPolicy(productName string, policy *template.SecretScanPolicy, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"secret_scan_policy": policy,
+		"update_time":        time.Now().Unix(),
+		"update_by":          updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+func (c *ProductColl) UpdateJobSecurityPolicy(productName string, policy *template.JobSecurityPolicy, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"job_security_policy": policy,
+		"update_time":         time.Now().Unix(),
+		"update_by":           updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+func (c *ProductColl) UpdateManifestPolicy(productName string, policy *template.ManifestPolicy, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"manifest_policy": policy,
+		"update_time":     time.Now().Unix(),
+		"update_by":       updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
+	return err
+}
+
+func (c *ProductColl) UpdateManifestLint(productName string, policy *template.ManifestLintPolicy, updateBy string) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"manifest_lint": policy,
+		"update_time":   time.Now().Unix(),
+		"update_by":     updateBy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
 	return err
 }
 
@@ -284,6 +435,7 @@ func (c *ProductColl) Update(productName string, args *template.Product) error {
 	}}
 
 	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
 	return err
 }
 
@@ -309,6 +461,7 @@ func (c *ProductColl) AddService(productName, serviceName string) error {
 		"services.1": serviceName,
 	}}
 	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
 	return err
 }
 
@@ -322,7 +475,9 @@ func (c *ProductColl) UpdateAll(projects []*template.Product) error {
 	}
 
 	var ms []mongo.WriteModel
+	keys := make([]string, 0, len(projects))
 	for _, p := range projects {
+		keys = append(keys, projectCacheKey(p.ProductName))
 		ms = append(ms,
 			mongo.NewUpdateOneModel().
 				SetFilter(bson.D{{"product_name", p.ProductName}}).
@@ -330,6 +485,7 @@ func (c *ProductColl) UpdateAll(projects []*template.Product) error {
 		)
 	}
 	_, err := c.BulkWrite(context.TODO(), ms)
+	cache.Delete(keys...)
 
 	return err
 }
@@ -341,6 +497,7 @@ func (c *ProductColl) UpdateOnboardingStatus(productName string, status int) err
 	}}
 
 	_, err := c.UpdateOne(context.TODO(), query, change)
+	cache.Delete(projectCacheKey(productName))
 	return err
 }
 
@@ -348,6 +505,7 @@ func (c *ProductColl) Delete(productName string) error {
 	query := bson.M{"product_name": productName}
 
 	_, err := c.DeleteOne(context.TODO(), query)
+	cache.Delete(projectCacheKey(productName))
 
 	return err
 }
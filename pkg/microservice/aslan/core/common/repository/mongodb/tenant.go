@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TenantColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTenantColl() *TenantColl {
+	name := models.Tenant{}.TableName()
+	return &TenantColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *TenantColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TenantColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "projects", Value: 1}},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *TenantColl) List() ([]*models.Tenant, error) {
+	return c.list(bson.M{})
+}
+
+// ListByUser returns the tenants the user is a member or an admin of.
+func (c *TenantColl) ListByUser(uid string) ([]*models.Tenant, error) {
+	return c.list(bson.M{"$or": bson.A{bson.M{"members": uid}, bson.M{"admins": uid}}})
+}
+
+func (c *TenantColl) list(query bson.M) ([]*models.Tenant, error) {
+	resp := make([]*models.Tenant, 0)
+	ctx := context.Background()
+
+	cursor, err := c.Collection.Find(ctx, query, options.Find().SetSort(bson.D{bson.E{Key: "key", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &resp)
+	return resp, err
+}
+
+func (c *TenantColl) Find(key string) (*models.Tenant, error) {
+	resp := &models.Tenant{}
+	err := c.FindOne(context.TODO(), bson.M{"key": key}).Decode(resp)
+	return resp, err
+}
+
+// FindByProject returns the tenant the project is bound to, mongo.ErrNoDocuments is returned
+// if the project is shared.
+func (c *TenantColl) FindByProject(projectName string) (*models.Tenant, error) {
+	resp := &models.Tenant{}
+	err := c.FindOne(context.TODO(), bson.M{"projects": projectName}).Decode(resp)
+	return resp, err
+}
+
+func (c *TenantColl) Create(args *models.Tenant) error {
+	if args == nil {
+		return errors.New("nil tenant info")
+	}
+
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = time.Now().Unix()
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *TenantColl) Update(key string, args *models.Tenant) error {
+	if args == nil {
+		return errors.New("nil tenant info")
+	}
+
+	query := bson.M{"key": key}
+	change := bson.M{"$set": bson.M{
+		"name":        args.Name,
+		"description": args.Description,
+		"admins":      args.Admins,
+		"members":     args.Members,
+		"projects":    args.Projects,
+		"clusters":    args.Clusters,
+		"registries":  args.Registries,
+		"code_hosts":  args.CodeHosts,
+		"quota":       args.Quota,
+		"update_by":   args.UpdateBy,
+		"update_time": time.Now().Unix(),
+	}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err == nil && res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
+
+func (c *TenantColl) UpdateMembers(key string, admins, members []string, updateBy string) error {
+	query := bson.M{"key": key}
+	change := bson.M{"$set": bson.M{
+		"admins":      admins,
+		"members":     members,
+		"update_by":   updateBy,
+		"update_time": time.Now().Unix(),
+	}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err == nil && res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return err
+}
+
+func (c *TenantColl) AddProject(key, projectName string) error {
+	query := bson.M{"key": key}
+	change := bson.M{"$addToSet": bson.M{"projects": projectName}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+// RemoveProject unbinds a deleted project from its tenant.
+func (c *TenantColl) RemoveProject(projectName string) error {
+	query := bson.M{"projects": projectName}
+	change := bson.M{"$pull": bson.M{"projects": projectName}}
+
+	_, err := c.UpdateMany(context.TODO(), query, change)
+	return err
+}
+
+func (c *TenantColl) Delete(key string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"key": key})
+	return err
+}
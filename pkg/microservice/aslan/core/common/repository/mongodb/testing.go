@@ -177,3 +177,19 @@ func (c *TestingColl) Update(testing *models.Testing) error {
 	_, err := c.UpdateOne(context.TODO(), query, change)
 	return err
 }
+
+// GetCodehostReference returns the testings which clone the repos of the codehost.
+func (c *TestingColl) GetCodehostReference(codehostID int) ([]*models.Testing, error) {
+	ret := make([]*models.Testing, 0)
+	query := bson.M{"repos.codehost_id": codehostID}
+
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
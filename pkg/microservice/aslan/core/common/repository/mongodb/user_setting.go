@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type UserSettingColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewUserSettingColl() *UserSettingColl {
+	name := models.UserSetting{}.TableName()
+	return &UserSettingColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *UserSettingColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *UserSettingColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"user_id": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"user_name": 1},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *UserSettingColl) Find(userID string) (*models.UserSetting, error) {
+	resp := new(models.UserSetting)
+	err := c.FindOne(context.TODO(), bson.M{"user_id": userID}).Decode(resp)
+	return resp, err
+}
+
+// FindByUserName finds the settings of the user, the tasks and the notifications only record the name of the user.
+func (c *UserSettingColl) FindByUserName(userName string) (*models.UserSetting, error) {
+	resp := new(models.UserSetting)
+	opts := options.FindOne().SetSort(bson.M{"update_time": -1})
+	err := c.FindOne(context.TODO(), bson.M{"user_name": userName}, opts).Decode(resp)
+	return resp, err
+}
+
+func (c *UserSettingColl) Upsert(args *models.UserSetting) error {
+	query := bson.M{"user_id": args.UserID}
+	change := bson.M{"$set": bson.M{
+		"user_name":   args.UserName,
+		"language":    args.Language,
+		"update_time": time.Now().Unix(),
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// UpdateNotification updates the notification preference of the user, the other settings are kept.
+func (c *UserSettingColl) UpdateNotification(userID, userName string, pref *models.NotificationPreference) error {
+	query := bson.M{"user_id": userID}
+	change := bson.M{"$set": bson.M{
+		"user_name":    userName,
+		"notification": pref,
+		"update_time":  time.Now().Unix(),
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
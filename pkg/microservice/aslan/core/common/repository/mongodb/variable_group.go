@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type VariableGroupColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVariableGroupColl() *VariableGroupColl {
+	name := models.VariableGroup{}.TableName()
+	return &VariableGroupColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *VariableGroupColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VariableGroupColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *VariableGroupColl) Create(args *models.VariableGroup) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = time.Now().Unix()
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *VariableGroupColl) Find(projectName, name string) (*models.VariableGroup, error) {
+	query := bson.M{"project_name": projectName, "name": name}
+
+	resp := new(models.VariableGroup)
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *VariableGroupColl) List(projectName string) ([]*models.VariableGroup, error) {
+	return c.list(bson.M{"project_name": projectName})
+}
+
+// ListByNames returns the groups in the order of the names, the names which are not found are skipped.
+func (c *VariableGroupColl) ListByNames(projectName string, names []string) ([]*models.VariableGroup, error) {
+	groups, err := c.list(bson.M{"project_name": projectName, "name": bson.M{"$in": names}})
+	if err != nil {
+		return nil, err
+	}
+
+	groupMap := make(map[string]*models.VariableGroup, len(groups))
+	for _, group := range groups {
+		groupMap[group.Name] = group
+	}
+	resp := make([]*models.VariableGroup, 0, len(groups))
+	for _, name := range names {
+		if group, ok := groupMap[name]; ok {
+			resp = append(resp, group)
+		}
+	}
+	return resp, nil
+}
+
+func (c *VariableGroupColl) list(query bson.M) ([]*models.VariableGroup, error) {
+	resp := make([]*models.VariableGroup, 0)
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := c.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &resp)
+	return resp, err
+}
+
+// Update saves the group only if its revision is not changed since it was read, the revision is increased.
+func (c *VariableGroupColl) Update(args *models.VariableGroup) error {
+	query := bson.M{"project_name": args.ProjectName, "name": args.Name, "revision": args.Revision}
+	change := bson.M{"$set": bson.M{
+		"description": args.Description,
+		"variables":   args.Variables,
+		"revision":    args.Revision + 1,
+		"update_by":   args.UpdateBy,
+		"update_time": time.Now().Unix(),
+	}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	args.Revision++
+	return nil
+}
+
+func (c *VariableGroupColl) Delete(projectName, name string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"project_name": projectName, "name": name})
+	return err
+}
+
+func (c *VariableGroupColl) DeleteByProject(projectName string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"project_name": projectName})
+	return err
+}
+
+type VariableGroupHistoryColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVariableGroupHistoryColl() *VariableGroupHistoryColl {
+	name := models.VariableGroupHistory{}.TableName()
+	return &VariableGroupHistoryColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *VariableGroupHistoryColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VariableGroupHistoryColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "group_name", Value: 1},
+			bson.E{Key: "revision", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *VariableGroupHistoryColl) Create(args *models.VariableGroupHistory) error {
+	args.CreateTime = time.Now().Unix()
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// List returns the history of the group, the latest revision comes first.
+func (c *VariableGroupHistoryColl) List(projectName, groupName string) ([]*models.VariableGroupHistory, error) {
+	resp := make([]*models.VariableGroupHistory, 0)
+	ctx := context.Background()
+	query := bson.M{"project_name": projectName, "group_name": groupName}
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: -1}})
+
+	cursor, err := c.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &resp)
+	return resp, err
+}
+
+func (c *VariableGroupHistoryColl) Delete(projectName, groupName string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"project_name": projectName, "group_name": groupName})
+	return err
+}
+
+func (c *VariableGroupHistoryColl) DeleteByProject(projectName string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"project_name": projectName})
+	return err
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WebhookEventColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWebhookEventColl() *WebhookEventColl {
+	name := models.WebhookEvent{}.TableName()
+	return &WebhookEventColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WebhookEventColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WebhookEventColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "status", Value: 1},
+				bson.E{Key: "next_attempt_time", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "status", Value: 1},
+				bson.E{Key: "locked_until", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.M{"create_time": -1},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.M{"expire_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *WebhookEventColl) Create(event *models.WebhookEvent) error {
+	now := time.Now().Unix()
+	event.Status = config.WebhookEventPending
+	event.NextAttemptTime = now
+	event.CreateTime = now
+	event.UpdateTime = now
+	res, err := c.InsertOne(context.TODO(), event)
+	if err != nil {
+		return err
+	}
+	if id, ok := res.InsertedID.(primitive.ObjectID); ok {
+		event.ID = id
+	}
+	return nil
+}
+
+// Claim takes a due pending event or a processing event whose worker has gone, the event is
+// locked for the given duration and its attempts are increased. Nil is returned if there is
+// no event to process.
+func (c *WebhookEventColl) Claim(lock time.Duration) (*models.WebhookEvent, error) {
+	now := time.Now().Unix()
+	query := bson.M{"$or": bson.A{
+		bson.M{"status": config.WebhookEventPending, "next_attempt_time": bson.M{"$lte": now}},
+		bson.M{"status": config.WebhookEventProcessing, "locked_until": bson.M{"$lt": now}},
+	}}
+	change := bson.M{
+		"$set": bson.M{
+			"status":       config.WebhookEventProcessing,
+			"locked_until": time.Now().Add(lock).Unix(),
+			"update_time":  now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_time", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	event := &models.WebhookEvent{}
+	err := c.FindOneAndUpdate(context.TODO(), query, change, opts).Decode(event)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// MarkProcessed records a processor finished for the event.
+func (c *WebhookEventColl) MarkProcessed(id primitive.ObjectID, processor string) error {
+	change := bson.M{"$addToSet": bson.M{"processed": processor}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, change)
+	return err
+}
+
+// MarkSucceeded marks the event as processed, it is removed by the ttl index after the retention.
+func (c *WebhookEventColl) MarkSucceeded(id primitive.ObjectID, retention time.Duration) error {
+	expireAt := time.Now().Add(retention)
+	change := bson.M{"$set": bson.M{
+		"status":      config.WebhookEventSucceeded,
+		"last_error":  "",
+		"update_time": time.Now().Unix(),
+		"expire_at":   expireAt,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, change)
+	return err
+}
+
+// MarkFailed puts the event back to the queue to be retried at the given time, or marks it as
+// dead if dead is true.
+func (c *WebhookEventColl) MarkFailed(id primitive.ObjectID, errMsg string, nextAttemptTime int64, dead bool) error {
+	status := config.WebhookEventPending
+	if dead {
+		status = config.WebhookEventDead
+	}
+	change := bson.M{"$set": bson.M{
+		"status":            status,
+		"last_error":        errMsg,
+		"next_attempt_time": nextAttemptTime,
+		"locked_until":      int64(0),
+		"update_time":       time.Now().Unix(),
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, change)
+	return err
+}
+
+type ListWebhookEventOption struct {
+	Status   string
+	Source   string
+	PageNum  int64
+	PageSize int64
+}
+
+func (c *WebhookEventColl) List(opt *ListWebhookEventOption) ([]*models.WebhookEvent, int64, error) {
+	query := bson.M{}
+	if opt.Status != "" {
+		query["status"] = opt.Status
+	}
+	if opt.Source != "" {
+		query["source"] = opt.Source
+	}
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+	findOption := options.Find().SetSort(bson.D{{Key: "create_time", Value: -1}})
+	if opt.PageNum > 0 && opt.PageSize > 0 {
+		findOption.SetSkip((opt.PageNum - 1) * opt.PageSize).SetLimit(opt.PageSize)
+	}
+	res := make([]*models.WebhookEvent, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, findOption)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, count, err
+}
+
+func (c *WebhookEventColl) Find(id string) (*models.WebhookEvent, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	event := &models.WebhookEvent{}
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(event)
+	return event, err
+}
+
+// Retry puts a dead event back to the queue with its attempts reset.
+func (c *WebhookEventColl) Retry(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	query := bson.M{"_id": oid, "status": config.WebhookEventDead}
+	change := bson.M{
+		"$set": bson.M{
+			"status":            config.WebhookEventPending,
+			"attempts":          0,
+			"next_attempt_time": now,
+			"update_time":       now,
+		},
+	}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (c *WebhookEventColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
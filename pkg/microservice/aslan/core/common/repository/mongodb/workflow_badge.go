@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WorkflowBadgeColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowBadgeColl() *WorkflowBadgeColl {
+	name := models.WorkflowBadge{}.TableName()
+	return &WorkflowBadgeColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WorkflowBadgeColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowBadgeColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"workflow_name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *WorkflowBadgeColl) Find(workflowName string) (*models.WorkflowBadge, error) {
+	resp := new(models.WorkflowBadge)
+	query := bson.M{"workflow_name": workflowName}
+
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *WorkflowBadgeColl) Upsert(args *models.WorkflowBadge) error {
+	query := bson.M{"workflow_name": args.WorkflowName}
+	change := bson.M{"$set": bson.M{
+		"token":       args.Token,
+		"updated_by":  args.UpdatedBy,
+		"update_time": time.Now().Unix(),
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *WorkflowBadgeColl) Delete(workflowName string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"workflow_name": workflowName})
+	return err
+}
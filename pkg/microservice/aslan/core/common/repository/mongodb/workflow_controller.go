@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+const workflowControllerLeaseID = "leader"
+
+type WorkflowControllerInstanceColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowControllerInstanceColl() *WorkflowControllerInstanceColl {
+	name := models.WorkflowControllerInstance{}.TableName()
+	return &WorkflowControllerInstanceColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WorkflowControllerInstanceColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowControllerInstanceColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"heartbeat_time": 1},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *WorkflowControllerInstanceColl) Heartbeat(id string, startTime int64) error {
+	query := bson.M{"_id": id}
+	change := bson.M{"$set": bson.M{"start_time": startTime, "heartbeat_time": time.Now().Unix()}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// ListAlive lists the instances which sent heartbeats after the given time.
+func (c *WorkflowControllerInstanceColl) ListAlive(after int64) ([]*models.WorkflowControllerInstance, error) {
+	resp := make([]*models.WorkflowControllerInstance, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"heartbeat_time": bson.M{"$gte": after}})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *WorkflowControllerInstanceColl) DeleteDead(before int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"heartbeat_time": bson.M{"$lt": before}})
+	return err
+}
+
+func (c *WorkflowControllerInstanceColl) Delete(id string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+type WorkflowControllerLeaseColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowControllerLeaseColl() *WorkflowControllerLeaseColl {
+	name := models.WorkflowControllerLease{}.TableName()
+	return &WorkflowControllerLeaseColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WorkflowControllerLeaseColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowControllerLeaseColl) EnsureIndex(_ context.Context) error {
+	return nil
+}
+
+// Acquire takes or renews the leader lease, false is returned if the lease is held by another instance.
+func (c *WorkflowControllerLeaseColl) Acquire(owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	query := bson.M{
+		"_id": workflowControllerLeaseID,
+		"$or": []bson.M{
+			{"owner": owner},
+			{"expire_at": bson.M{"$lt": now.Unix()}},
+		},
+	}
+	change := bson.M{"$set": bson.M{"owner": owner, "expire_at": now.Add(ttl).Unix()}}
+
+	// the upsert fails with a duplicate key error if the lease is held by another instance.
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *WorkflowControllerLeaseColl) Release(owner string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": workflowControllerLeaseID, "owner": owner})
+	return err
+}
@@ -32,6 +32,7 @@ import (
 type ListWorfklowQueueOption struct {
 	WorkflowName string
 	Status       config.Status
+	Owner        string
 }
 
 type WorkflowQueueColl struct {
@@ -73,6 +74,9 @@ func (c *WorkflowQueueColl) List(opt *ListWorfklowQueueOption) ([]*models.Workfl
 		if opt.Status != "" {
 			query["status"] = opt.Status
 		}
+		if opt.Owner != "" {
+			query["owner"] = opt.Owner
+		}
 	}
 
 	var resp []*models.WorkflowQueue
@@ -124,3 +128,46 @@ func (c *WorkflowQueueColl) Update(args *models.WorkflowQueue) error {
 	_, err := c.UpdateOne(context.TODO(), query, change)
 	return err
 }
+
+// Claim sets the status and the owner of the queue item if its status is not changed since it is
+// listed, false is returned if the item is claimed by another instance.
+func (c *WorkflowQueueColl) Claim(args *models.WorkflowQueue, status config.Status, owner string) (bool, error) {
+	if args == nil {
+		return false, errors.New("nil workflow queue")
+	}
+
+	query := bson.M{"task_id": args.TaskID, "workflow_name": args.WorkflowName, "create_time": args.CreateTime, "status": args.Status}
+	change := bson.M{"$set": bson.M{
+		"status": status,
+		"owner":  owner,
+	}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// Adopt changes the owner of the queue item if it is still owned by args.Owner, false is returned if
+// the item is adopted by another instance.
+func (c *WorkflowQueueColl) Adopt(args *models.WorkflowQueue, owner string) (bool, error) {
+	if args == nil {
+		return false, errors.New("nil workflow queue")
+	}
+
+	query := bson.M{"task_id": args.TaskID, "workflow_name": args.WorkflowName, "create_time": args.CreateTime, "owner": args.Owner}
+	if args.Owner == "" {
+		// the owner is omitted if it is empty.
+		query["owner"] = bson.M{"$in": bson.A{"", nil}}
+	}
+	change := bson.M{"$set": bson.M{
+		"owner": owner,
+	}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
@@ -20,11 +20,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+	"github.com/koderover/zadig/pkg/tool/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -34,10 +36,29 @@ import (
 type ListWorkflowTaskV4Option struct {
 	WorkflowName    string
 	WorkflowNames   []string
+	TaskCreator     string
+	ProjectName     string
 	CreateTime      int64
 	BeforeCreatTime bool
-	Limit           int
-	Skip            int
+	// Labels matches the tasks having all the labels.
+	Labels map[string]string
+	Limit  int
+	Skip   int
+}
+
+// SearchWorkflowTaskV4Option searches the tasks across the projects, the given conditions are combined with AND.
+type SearchWorkflowTaskV4Option struct {
+	// Projects limits the search to the tasks of the projects, all the projects are searched if it is empty.
+	Projects []string
+	// CommitID matches the tasks checking out a commit starting with it.
+	CommitID string
+	// Image matches the tasks building or deploying the image.
+	Image       string
+	ParamName   string
+	ParamValue  string
+	TaskCreator string
+	Limit       int
+	Skip        int
 }
 
 type WorkflowTaskv4Coll struct {
@@ -78,6 +99,42 @@ func (c *WorkflowTaskv4Coll) EnsureIndex(ctx context.Context) error {
 			},
 			Options: options.Index().SetUnique(false),
 		},
+		// for the recent tasks of a user
+		{
+			Keys: bson.D{
+				bson.E{Key: "task_creator", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		// for the cursor pagination
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "is_archived", Value: 1},
+				bson.E{Key: "is_deleted", Value: 1},
+				bson.E{Key: "task_id", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "is_archived", Value: 1},
+				bson.E{Key: "is_deleted", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+				bson.E{Key: "task_id", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		// for the search across the projects
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
 	}
 
 	_, err := c.Indexes().CreateMany(ctx, mod)
@@ -101,6 +158,42 @@ func (c *WorkflowTaskv4Coll) Create(obj *models.WorkflowTask) (string, error) {
 	return ID.Hex(), err
 }
 
+var workflowTaskV4Schema = &pagination.Schema{
+	Key:         "task_id",
+	Sortable:    []string{"create_time"},
+	Filterable:  []string{"status", "task_creator"},
+	Searchable:  []string{"task_creator"},
+	DefaultSort: "-task_id",
+}
+
+// ListByQuery lists the tasks of a workflow with the cursor pagination.
+func (c *WorkflowTaskv4Coll) ListByQuery(workflowName string, labels map[string]string, q *pagination.Query) ([]*models.WorkflowTask, *pagination.Page, error) {
+	query := bson.M{"is_archived": false, "is_deleted": false}
+	if workflowName != "" {
+		query["workflow_name"] = workflowName
+	}
+	for key, value := range labels {
+		query["labels."+key] = value
+	}
+	mq, err := pagination.Mongo(query, q, workflowTaskV4Schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	count, err := c.CountDocuments(context.TODO(), mq.Count)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := make([]*models.WorkflowTask, 0)
+	cursor, err := c.Collection.Find(context.TODO(), mq.Filter, mq.Options)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, nil, err
+	}
+	return pagination.MongoPage(resp, mq, count)
+}
+
 func (c *WorkflowTaskv4Coll) List(opt *ListWorkflowTaskV4Option) ([]*models.WorkflowTask, int64, error) {
 	resp := make([]*models.WorkflowTask, 0)
 	query := bson.M{}
@@ -110,6 +203,15 @@ func (c *WorkflowTaskv4Coll) List(opt *ListWorkflowTaskV4Option) ([]*models.Work
 	if opt.WorkflowNames != nil {
 		query["workflow_name"] = bson.M{"$in": opt.WorkflowNames}
 	}
+	if opt.TaskCreator != "" {
+		query["task_creator"] = opt.TaskCreator
+	}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	for key, value := range opt.Labels {
+		query["labels."+key] = value
+	}
 	query["is_archived"] = false
 	query["is_deleted"] = false
 	if opt.CreateTime > 0 {
@@ -142,6 +244,54 @@ func (c *WorkflowTaskv4Coll) List(opt *ListWorkflowTaskV4Option) ([]*models.Work
 	return resp, count, nil
 }
 
+// Search finds the tasks of all the workflows matching the option, the latest tasks are returned first.
+func (c *WorkflowTaskv4Coll) Search(opt *SearchWorkflowTaskV4Option) ([]*models.WorkflowTask, int64, error) {
+	query := bson.M{"is_archived": false, "is_deleted": false}
+	if len(opt.Projects) > 0 {
+		query["project_name"] = bson.M{"$in": opt.Projects}
+	}
+	if opt.TaskCreator != "" {
+		query["task_creator"] = opt.TaskCreator
+	}
+	if opt.CommitID != "" {
+		query["stages.jobs.spec.steps.spec.repos.commit_id"] = bson.M{"$regex": "^" + regexp.QuoteMeta(opt.CommitID)}
+	}
+	if opt.Image != "" {
+		query["$or"] = bson.A{
+			bson.M{"stages.jobs.spec.image": opt.Image},
+			bson.M{"stages.jobs.spec.image_and_service_modules.image": opt.Image},
+			bson.M{"stages.jobs.spec.properties.envs": bson.M{"$elemMatch": bson.M{"key": "IMAGE", "value": opt.Image}}},
+		}
+	}
+	if opt.ParamValue != "" {
+		param := bson.M{"value": opt.ParamValue}
+		if opt.ParamName != "" {
+			param["name"] = opt.ParamName
+		}
+		query["params"] = bson.M{"$elemMatch": param}
+	}
+
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOption := options.Find().SetSort(bson.D{{"create_time", -1}})
+	if opt.Limit > 0 {
+		findOption.SetSkip(int64(opt.Skip))
+		findOption.SetLimit(int64(opt.Limit))
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query, findOption)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := make([]*models.WorkflowTask, 0)
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp, count, nil
+}
+
 func (c *WorkflowTaskv4Coll) FindTodoTasksByWorkflowName(workflowName string) ([]*models.WorkflowTask, error) {
 	ret := make([]*models.WorkflowTask, 0)
 	query := bson.M{"status": bson.M{"$in": []string{"waiting", "queued", "created", "running", "blocked"}}}
@@ -254,3 +404,134 @@ func (c *WorkflowTaskv4Coll) ArchiveHistoryWorkflowTask(workflowName string, rem
 
 	return err
 }
+
+// WorkflowTaskUsage is the number and the total running seconds of the finished workflow tasks.
+type WorkflowTaskUsage struct {
+	ProjectName string `bson:"_id"      json:"project_name"`
+	TaskCount   int64  `bson:"count"    json:"task_count"`
+	Duration    int64  `bson:"duration" json:"duration"`
+}
+
+// StatUsageByProjects sums up the finished tasks of the projects created in [startTime, endTime), a
+// zero endTime means now.
+func (c *WorkflowTaskv4Coll) StatUsageByProjects(projects []string, startTime, endTime int64) ([]*WorkflowTaskUsage, error) {
+	res := make([]*WorkflowTaskUsage, 0)
+	if len(projects) == 0 {
+		return res, nil
+	}
+
+	createTime := bson.M{"$gte": startTime}
+	if endTime > 0 {
+		createTime["$lt"] = endTime
+	}
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{
+				"project_name": bson.M{"$in": projects},
+				"create_time":  createTime,
+				"start_time":   bson.M{"$gt": 0},
+				"end_time":     bson.M{"$gt": 0},
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":      "$project_name",
+				"count":    bson.M{"$sum": 1},
+				"duration": bson.M{"$sum": bson.M{"$subtract": bson.A{"$end_time", "$start_time"}}},
+			},
+		},
+	}
+
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// WorkflowTaskStat is the number, the passed number and the total running seconds of the finished
+// tasks of a workflow.
+type WorkflowTaskStat struct {
+	WorkflowName string `bson:"_id"      json:"workflow_name"`
+	TaskCount    int64  `bson:"count"    json:"task_count"`
+	SuccessCount int64  `bson:"success"  json:"success_count"`
+	Duration     int64  `bson:"duration" json:"duration"`
+}
+
+// StatByWorkflows sums up the finished tasks of the workflows created in [startTime, endTime), a
+// zero endTime means now.
+func (c *WorkflowTaskv4Coll) StatByWorkflows(projectName string, workflows []string, startTime, endTime int64) ([]*WorkflowTaskStat, error) {
+	res := make([]*WorkflowTaskStat, 0)
+	if len(workflows) == 0 {
+		return res, nil
+	}
+
+	createTime := bson.M{"$gte": startTime}
+	if endTime > 0 {
+		createTime["$lt"] = endTime
+	}
+	match := bson.M{
+		"workflow_name": bson.M{"$in": workflows},
+		"create_time":   createTime,
+		"start_time":    bson.M{"$gt": 0},
+		"end_time":      bson.M{"$gt": 0},
+	}
+	if projectName != "" {
+		match["project_name"] = projectName
+	}
+	pipeline := []bson.M{
+		{
+			"$match": match,
+		},
+		{
+			"$group": bson.M{
+				"_id":   "$workflow_name",
+				"count": bson.M{"$sum": 1},
+				"success": bson.M{"$sum": bson.M{
+					"$cond": bson.A{bson.M{"$eq": bson.A{"$status", config.StatusPassed}}, 1, 0},
+				}},
+				"duration": bson.M{"$sum": bson.M{"$subtract": bson.A{"$end_time", "$start_time"}}},
+			},
+		},
+	}
+
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// WorkflowLastRun is the create time of the latest task of a workflow.
+type WorkflowLastRun struct {
+	WorkflowName string `bson:"_id"         json:"workflow_name"`
+	CreateTime   int64  `bson:"create_time" json:"create_time"`
+}
+
+// ListLastRuns returns the latest run of every workflow which has tasks.
+func (c *WorkflowTaskv4Coll) ListLastRuns() ([]*WorkflowLastRun, error) {
+	res := make([]*WorkflowLastRun, 0)
+	pipeline := []bson.M{
+		{
+			"$group": bson.M{
+				"_id":         "$workflow_name",
+				"create_time": bson.M{"$max": "$create_time"},
+			},
+		},
+	}
+
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
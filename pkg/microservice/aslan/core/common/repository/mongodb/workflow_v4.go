@@ -210,6 +210,29 @@ func (c *WorkflowV4Coll) Update(idString string, obj *models.WorkflowV4) error {
 	return err
 }
 
+// UnsetCustomField removes the value of the custom field from all workflows, e.g. when the field is deleted.
+func (c *WorkflowV4Coll) UnsetCustomField(key string) error {
+	field := "custom_fields." + key
+	_, err := c.UpdateMany(context.TODO(), bson.M{field: bson.M{"$exists": true}}, bson.M{"$unset": bson.M{field: ""}})
+	return err
+}
+
+// SetArchived archives or restores the workflow, ErrNoDocuments is returned if it does not exist.
+func (c *WorkflowV4Coll) SetArchived(name string, archived bool, user string) error {
+	update := bson.M{"$set": bson.M{"archived": archived, "archived_by": user, "archived_at": time.Now().Unix()}}
+	if !archived {
+		update = bson.M{"$unset": bson.M{"archived": "", "archived_by": "", "archived_at": ""}}
+	}
+	res, err := c.UpdateOne(context.TODO(), bson.M{"name": name}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (c *WorkflowV4Coll) DeleteByID(idString string) error {
 	id, err := primitive.ObjectIDFromHex(idString)
 	if err != nil {
@@ -220,3 +243,24 @@ func (c *WorkflowV4Coll) DeleteByID(idString string) error {
 	_, err = c.DeleteOne(context.TODO(), query)
 	return err
 }
+
+// GetCodehostReference returns the workflows whose build, freestyle or android build jobs clone the repos
+// of the codehost.
+func (c *WorkflowV4Coll) GetCodehostReference(codehostID int) ([]*models.WorkflowV4, error) {
+	resp := make([]*models.WorkflowV4, 0)
+	query := bson.M{"$or": bson.A{
+		bson.M{"stages.jobs.spec.service_and_builds.repos.codehost_id": codehostID},
+		bson.M{"stages.jobs.spec.steps.spec.repos.codehost_id": codehostID},
+		bson.M{"stages.jobs.spec.repos.codehost_id": codehostID},
+	}}
+
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -207,3 +207,64 @@ func MergeBuildEnvs(templateEnvs []*commonmodels.KeyVal, customEnvs []*commonmod
 	}
 	return retEnvs
 }
+
+// FillBuildDetail fills the build created from the build template with the sections of the template,
+// the repos and envs of the service module and the overrides of the build are applied on top of it.
+func FillBuildDetail(moduleBuild *commonmodels.Build, buildTemplate *commonmodels.BuildTemplate, serviceName, serviceModule string) {
+	moduleBuild.Timeout = buildTemplate.Timeout
+	moduleBuild.PreBuild = nil
+	if buildTemplate.PreBuild != nil {
+		// the template may be shared by a number of builds, it must not be changed.
+		preBuild := *buildTemplate.PreBuild
+		moduleBuild.PreBuild = &preBuild
+	}
+	moduleBuild.JenkinsBuild = buildTemplate.JenkinsBuild
+	moduleBuild.Scripts = buildTemplate.Scripts
+	moduleBuild.PostBuild = buildTemplate.PostBuild
+	moduleBuild.SSHs = buildTemplate.SSHs
+	moduleBuild.PMDeployScripts = buildTemplate.PMDeployScripts
+	moduleBuild.CacheEnable = buildTemplate.CacheEnable
+	moduleBuild.CacheDirType = buildTemplate.CacheDirType
+	moduleBuild.CacheUserDir = buildTemplate.CacheUserDir
+	moduleBuild.AdvancedSettingsModified = buildTemplate.AdvancedSettingsModified
+
+	// repos are configured by service modules
+	for _, serviceConfig := range moduleBuild.Targets {
+		if serviceConfig.ServiceName == serviceName && serviceConfig.ServiceModule == serviceModule {
+			moduleBuild.Repos = serviceConfig.Repos
+			if moduleBuild.PreBuild == nil {
+				moduleBuild.PreBuild = &commonmodels.PreBuild{}
+			}
+			moduleBuild.PreBuild.Envs = MergeBuildEnvs(moduleBuild.PreBuild.Envs, serviceConfig.Envs)
+			break
+		}
+	}
+
+	override := moduleBuild.TemplateOverride
+	if override == nil {
+		return
+	}
+	if override.Timeout > 0 {
+		moduleBuild.Timeout = override.Timeout
+	}
+	if override.BuildOS != "" || override.ImageID != "" {
+		if moduleBuild.PreBuild == nil {
+			moduleBuild.PreBuild = &commonmodels.PreBuild{}
+		}
+		moduleBuild.PreBuild.BuildOS = override.BuildOS
+		moduleBuild.PreBuild.ImageFrom = override.ImageFrom
+		moduleBuild.PreBuild.ImageID = override.ImageID
+	}
+	if override.CacheEnable != nil {
+		moduleBuild.CacheEnable = *override.CacheEnable
+		moduleBuild.CacheDirType = override.CacheDirType
+		moduleBuild.CacheUserDir = override.CacheUserDir
+	}
+	var scripts []string
+	for _, section := range []string{override.PreScripts, moduleBuild.Scripts, override.PostScripts} {
+		if section != "" {
+			scripts = append(scripts, section)
+		}
+	}
+	moduleBuild.Scripts = strings.Join(scripts, "\n")
+}
@@ -99,15 +99,18 @@ func RunGitCmds(codehostDetail *systemconfig.CodeHost, repoOwner, repoNamespace,
 	tokens = append(tokens, repo.OauthToken)
 	cmds = append(cmds, buildGitCommands(repo)...)
 
-	if codehostDetail.EnableProxy {
-		httpsProxy := config.ProxyHTTPSAddr()
-		httpProxy := config.ProxyHTTPAddr()
+	if codehostDetail.UseProxy() {
+		httpsProxy := codehostDetail.ProxyAddr(config.ProxyHTTPSAddr())
+		httpProxy := codehostDetail.ProxyAddr(config.ProxyHTTPAddr())
 		if httpsProxy != "" {
 			envs = append(envs, fmt.Sprintf("https_proxy=%s", httpsProxy))
 		}
 		if httpProxy != "" {
 			envs = append(envs, fmt.Sprintf("http_proxy=%s", httpProxy))
 		}
+		if len(codehostDetail.NoProxy) > 0 {
+			envs = append(envs, fmt.Sprintf("no_proxy=%s", strings.Join(codehostDetail.NoProxy, ",")))
+		}
 	}
 
 	for _, c := range cmds {
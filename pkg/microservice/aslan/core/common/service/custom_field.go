@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// ValidateCustomFields checks the values of the custom fields of the scope, the values of the unknown fields are
+// rejected. The required fields are only checked if required is true, e.g. when the resource is created, so that
+// the resources created before a field is required can still be updated.
+func ValidateCustomFields(scope config.CustomFieldScope, values map[string]string, required bool) error {
+	fields, err := commonrepo.NewCustomFieldColl().List(scope)
+	if err != nil {
+		return fmt.Errorf("failed to list custom fields: %s", err)
+	}
+
+	known := sets.NewString()
+	for _, field := range fields {
+		known.Insert(field.Key)
+		value, ok := values[field.Key]
+		if !ok || value == "" {
+			if required && field.Required {
+				return fmt.Errorf("custom field %s is required", field.Name)
+			}
+			continue
+		}
+		if err := ValidateCustomFieldValue(field, value); err != nil {
+			return err
+		}
+	}
+
+	unknown := make([]string, 0)
+	for key := range values {
+		if !known.Has(key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown custom fields %v of %s", unknown, scope)
+	}
+	return nil
+}
+
+// ValidateCustomFieldValue checks the value against the type and the regex of the field.
+func ValidateCustomFieldValue(field *commonmodels.CustomField, value string) error {
+	switch field.Type {
+	case config.CustomFieldTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("value %s of custom field %s is not a number", value, field.Name)
+		}
+	case config.CustomFieldTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("value %s of custom field %s is not a bool", value, field.Name)
+		}
+	case config.CustomFieldTypeEnum:
+		if !sets.NewString(field.Options...).Has(value) {
+			return fmt.Errorf("value %s of custom field %s is not one of %v", value, field.Name, field.Options)
+		}
+	}
+	if field.Regex != "" {
+		re, err := regexp.Compile("^(?:" + field.Regex + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regex %s of custom field %s: %s", field.Regex, field.Name, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("value %s of custom field %s does not match %s", value, field.Name, field.Regex)
+		}
+	}
+	return nil
+}
@@ -222,7 +222,7 @@ func GetSubTaskContent(deliveryVersion *commonmodels.DeliveryVersion, pipelineTa
 				container.Image = deployInfo.Image
 				containers = append(containers, container)
 
-				yamlContent, err := getServiceRenderYAML(productEnvInfo, containers, deployInfo.ServiceName, setting.K8SDeployType, log)
+				yamlContent, err := GetServiceRenderYAML(productEnvInfo, containers, deployInfo.ServiceName, setting.K8SDeployType, log)
 				if err != nil {
 					log.Errorf("get deployInfo ExportYamls failed ! err:%v", err)
 					continue
@@ -450,7 +450,8 @@ func updateServiceImage(serviceName, image, containerName string, product *commo
 	}
 }
 
-func getServiceRenderYAML(productInfo *commonmodels.Product, containers []*commonmodels.Container, serviceName, deployType string, log *zap.SugaredLogger) (string, error) {
+// GetServiceRenderYAML renders the yaml of the service in the env with the images of the containers.
+func GetServiceRenderYAML(productInfo *commonmodels.Product, containers []*commonmodels.Container, serviceName, deployType string, log *zap.SugaredLogger) (string, error) {
 	if deployType == setting.K8SDeployType {
 		opt := &commonrepo.RenderSetFindOption{
 			Name:        productInfo.Render.Name,
@@ -0,0 +1,419 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devseed
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockRepo is a repository served by the mock provider, the branches and the tags have the same files.
+type MockRepo struct {
+	Namespace     string              `json:"namespace"`
+	Name          string              `json:"name"`
+	DefaultBranch string              `json:"default_branch"`
+	Branches      []string            `json:"branches"`
+	Tags          []string            `json:"tags"`
+	MergeRequests []*MockMergeRequest `json:"merge_requests"`
+	// Files are the contents by the paths relative to the root of the repository.
+	Files map[string]string `json:"files"`
+}
+
+type MockMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	Author       string `json:"author"`
+}
+
+// MockProvider serves the repositories by the subset of the gitlab v4 api used by zadig, so that the codehosts
+// of the type gitlab work without a real gitlab. The hooks and the commit statuses are accepted and dropped,
+// the files updated by the api are kept in memory.
+type MockProvider struct {
+	mu    sync.RWMutex
+	repos []*MockRepo
+	hooks int
+}
+
+func NewMockProvider(repos []*MockRepo) *MockProvider {
+	return &MockProvider{repos: repos}
+}
+
+const mockUser = "zadig-dev"
+
+var mockTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (p *MockProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the project ids are the escaped full paths, the path is split before it is unescaped.
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4"), "/"), "/")
+	for i, segment := range segments {
+		segments[i], _ = url.PathUnescape(segment)
+	}
+	baseURL := "http://" + r.Host
+
+	switch {
+	case match(segments, "user"):
+		writeJSON(w, map[string]interface{}{"id": 1, "username": mockUser, "name": mockUser, "state": "active"})
+	case match(segments, "namespaces"):
+		writeJSON(w, p.namespaces(r.URL.Query().Get("search")))
+	case match(segments, "groups", "*", "projects"), match(segments, "users", "*", "projects"):
+		projects := make([]map[string]interface{}, 0)
+		for i, repo := range p.repos {
+			if repo.Namespace == segments[1] && strings.Contains(repo.Name, r.URL.Query().Get("search")) {
+				projects = append(projects, project(i, repo, baseURL))
+			}
+		}
+		writeJSON(w, projects)
+	case len(segments) >= 2 && segments[0] == "projects":
+		i, repo := p.findRepo(segments[1])
+		if repo == nil {
+			writeNotFound(w)
+			return
+		}
+		p.serveProject(w, r, i, repo, segments[2:], baseURL)
+	default:
+		writeNotFound(w)
+	}
+}
+
+func (p *MockProvider) serveProject(w http.ResponseWriter, r *http.Request, i int, repo *MockRepo, segments []string, baseURL string) {
+	query := r.URL.Query()
+	switch {
+	case len(segments) == 0:
+		writeJSON(w, project(i, repo, baseURL))
+	case match(segments, "repository", "branches"):
+		if r.Method == http.MethodPost {
+			p.mu.Lock()
+			repo.Branches = append(repo.Branches, query.Get("branch"))
+			p.mu.Unlock()
+			writeJSON(w, branch(repo, query.Get("branch")))
+			return
+		}
+		branches := make([]map[string]interface{}, 0)
+		for _, name := range repo.Branches {
+			if strings.Contains(name, query.Get("search")) {
+				branches = append(branches, branch(repo, name))
+			}
+		}
+		writeJSON(w, branches)
+	case match(segments, "repository", "branches", "*"):
+		if !contains(repo.Branches, segments[2]) {
+			writeNotFound(w)
+			return
+		}
+		writeJSON(w, branch(repo, segments[2]))
+	case match(segments, "repository", "tags"):
+		tags := make([]map[string]interface{}, 0)
+		for _, name := range repo.Tags {
+			if strings.Contains(name, query.Get("search")) {
+				tags = append(tags, map[string]interface{}{"name": name, "commit": commit(repo, name)})
+			}
+		}
+		writeJSON(w, tags)
+	case match(segments, "repository", "commits"):
+		ref := query.Get("ref_name")
+		if ref == "" {
+			ref = repo.DefaultBranch
+		}
+		writeJSON(w, []map[string]interface{}{commit(repo, ref)})
+	case match(segments, "repository", "commits", "*"):
+		writeJSON(w, commit(repo, segments[2]))
+	case match(segments, "repository", "compare"):
+		writeJSON(w, map[string]interface{}{"commits": []interface{}{}, "diffs": []interface{}{}})
+	case match(segments, "repository", "tree"):
+		writeJSON(w, p.tree(repo, query.Get("path"), query.Get("recursive") == "true"))
+	case match(segments, "repository", "files", "*"), match(segments, "repository", "files", "*", "raw"):
+		p.serveFile(w, r, repo, segments[2], len(segments) == 4)
+	case match(segments, "repository", "blobs", "*", "raw"):
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		for path, content := range repo.Files {
+			if blobID(path, content) == segments[2] {
+				_, _ = io.WriteString(w, content)
+				return
+			}
+		}
+		writeNotFound(w)
+	case match(segments, "merge_requests"):
+		p.serveMergeRequests(w, r, repo, baseURL)
+	case match(segments, "hooks"):
+		if r.Method == http.MethodPost {
+			p.mu.Lock()
+			p.hooks++
+			id := p.hooks
+			p.mu.Unlock()
+			writeJSON(w, map[string]interface{}{"id": id, "url": query.Get("url")})
+			return
+		}
+		writeJSON(w, []interface{}{})
+	case match(segments, "hooks", "*"):
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		id, _ := strconv.Atoi(segments[1])
+		writeJSON(w, map[string]interface{}{"id": id, "url": query.Get("url")})
+	case match(segments, "statuses", "*"):
+		writeJSON(w, map[string]interface{}{"sha": segments[1], "status": query.Get("state"), "name": query.Get("name")})
+	default:
+		writeNotFound(w)
+	}
+}
+
+func (p *MockProvider) serveFile(w http.ResponseWriter, r *http.Request, repo *MockRepo, path string, raw bool) {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		body := struct {
+			Content string `json:"content"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.mu.Lock()
+		repo.Files[path] = body.Content
+		p.mu.Unlock()
+		writeJSON(w, map[string]interface{}{"file_path": path, "branch": r.URL.Query().Get("branch")})
+		return
+	}
+
+	p.mu.RLock()
+	content, ok := repo.Files[path]
+	p.mu.RUnlock()
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if raw {
+		_, _ = io.WriteString(w, content)
+		return
+	}
+	ref := r.URL.Query().Get("ref")
+	writeJSON(w, map[string]interface{}{
+		"file_name":      path[strings.LastIndex(path, "/")+1:],
+		"file_path":      path,
+		"size":           len(content),
+		"encoding":       "base64",
+		"content":        base64.StdEncoding.EncodeToString([]byte(content)),
+		"ref":            ref,
+		"blob_id":        blobID(path, content),
+		"commit_id":      sha(repo, ref),
+		"last_commit_id": sha(repo, ref),
+	})
+}
+
+func (p *MockProvider) serveMergeRequests(w http.ResponseWriter, r *http.Request, repo *MockRepo, baseURL string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r.Method == http.MethodPost {
+		body := struct {
+			Title        string `json:"title"`
+			SourceBranch string `json:"source_branch"`
+			TargetBranch string `json:"target_branch"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mr := &MockMergeRequest{IID: len(repo.MergeRequests) + 1, Title: body.Title, SourceBranch: body.SourceBranch, TargetBranch: body.TargetBranch, Author: mockUser}
+		repo.MergeRequests = append(repo.MergeRequests, mr)
+		writeJSON(w, mergeRequest(repo, mr, baseURL))
+		return
+	}
+
+	targetBranch := r.URL.Query().Get("target_branch")
+	mrs := make([]map[string]interface{}, 0)
+	for _, mr := range repo.MergeRequests {
+		if targetBranch == "" || mr.TargetBranch == targetBranch {
+			mrs = append(mrs, mergeRequest(repo, mr, baseURL))
+		}
+	}
+	writeJSON(w, mrs)
+}
+
+func (p *MockProvider) namespaces(search string) []map[string]interface{} {
+	resp := make([]map[string]interface{}, 0)
+	seen := map[string]bool{}
+	for _, repo := range p.repos {
+		if seen[repo.Namespace] || !strings.Contains(repo.Namespace, search) {
+			continue
+		}
+		seen[repo.Namespace] = true
+		resp = append(resp, namespace(len(resp)+1, repo.Namespace))
+	}
+	return resp
+}
+
+// findRepo finds the repository by the numeric id or the full path.
+func (p *MockProvider) findRepo(id string) (int, *MockRepo) {
+	for i, repo := range p.repos {
+		if id == strconv.Itoa(i+1) || id == repo.Namespace+"/"+repo.Name {
+			return i, repo
+		}
+	}
+	return 0, nil
+}
+
+// tree returns the direct children of the path, or all the descendants if it is recursive.
+func (p *MockProvider) tree(repo *MockRepo, path string, recursive bool) []map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	prefix := ""
+	if path != "" {
+		prefix = strings.TrimSuffix(path, "/") + "/"
+	}
+	nodes := map[string]map[string]interface{}{}
+	for file, content := range repo.Files {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(file, prefix), "/")
+		for depth := range parts {
+			if depth > 0 && !recursive {
+				break
+			}
+			nodePath := prefix + strings.Join(parts[:depth+1], "/")
+			node := map[string]interface{}{"name": parts[depth], "path": nodePath, "type": "tree", "mode": "040000", "id": blobID(nodePath, "")}
+			if depth == len(parts)-1 {
+				node["type"], node["mode"], node["id"] = "blob", "100644", blobID(file, content)
+			}
+			nodes[nodePath] = node
+		}
+	}
+
+	resp := make([]map[string]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		resp = append(resp, node)
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i]["path"].(string) < resp[j]["path"].(string) })
+	return resp
+}
+
+func namespace(id int, name string) map[string]interface{} {
+	return map[string]interface{}{"id": id, "name": name, "path": name, "kind": "group", "full_path": name}
+}
+
+func project(i int, repo *MockRepo, baseURL string) map[string]interface{} {
+	fullPath := repo.Namespace + "/" + repo.Name
+	return map[string]interface{}{
+		"id":                  i + 1,
+		"name":                repo.Name,
+		"path":                repo.Name,
+		"name_with_namespace": fullPath,
+		"path_with_namespace": fullPath,
+		"default_branch":      repo.DefaultBranch,
+		"namespace":           namespace(0, repo.Namespace),
+		"web_url":             baseURL + "/" + fullPath,
+		"http_url_to_repo":    baseURL + "/" + fullPath + ".git",
+		"ssh_url_to_repo":     fmt.Sprintf("git@%s:%s.git", strings.TrimPrefix(baseURL, "http://"), fullPath),
+	}
+}
+
+func branch(repo *MockRepo, name string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "default": name == repo.DefaultBranch, "commit": commit(repo, name)}
+}
+
+func commit(repo *MockRepo, ref string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              sha(repo, ref),
+		"short_id":        sha(repo, ref)[:8],
+		"title":           "sample commit of " + ref,
+		"message":         "sample commit of " + ref,
+		"author_name":     mockUser,
+		"author_email":    mockUser + "@example.com",
+		"committer_name":  mockUser,
+		"committer_email": mockUser + "@example.com",
+		"created_at":      mockTime,
+		"committed_date":  mockTime,
+	}
+}
+
+func mergeRequest(repo *MockRepo, mr *MockMergeRequest, baseURL string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            mr.IID,
+		"iid":           mr.IID,
+		"title":         mr.Title,
+		"state":         "opened",
+		"source_branch": mr.SourceBranch,
+		"target_branch": mr.TargetBranch,
+		"sha":           sha(repo, mr.SourceBranch),
+		"author":        map[string]interface{}{"username": mr.Author, "name": mr.Author},
+		"web_url":       fmt.Sprintf("%s/%s/%s/-/merge_requests/%d", baseURL, repo.Namespace, repo.Name, mr.IID),
+		"created_at":    mockTime,
+	}
+}
+
+// sha is the fake commit sha of the ref, a commit sha is its own ref.
+func sha(repo *MockRepo, ref string) string {
+	if len(ref) == 40 {
+		return ref
+	}
+	sum := sha1.Sum([]byte(repo.Namespace + "/" + repo.Name + "@" + ref))
+	return hex.EncodeToString(sum[:])
+}
+
+func blobID(path, content string) string {
+	sum := sha1.Sum([]byte(path + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// match reports whether the segments match the pattern, "*" matches any segment.
+func match(segments []string, pattern ...string) bool {
+	if len(segments) != len(pattern) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = io.WriteString(w, `{"message":"404 Not Found"}`)
+}
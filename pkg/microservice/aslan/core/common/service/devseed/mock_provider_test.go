@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devseed
+
+import (
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+
+	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
+)
+
+var _ = Describe("the mock code provider", func() {
+	var (
+		server *httptest.Server
+		client *gitlabtool.Client
+	)
+
+	BeforeEach(func() {
+		snapshotFS, err := OpenSnapshot(BuiltinSnapshot)
+		Expect(err).NotTo(HaveOccurred())
+		repos, err := LoadRepos(snapshotFS)
+		Expect(err).NotTo(HaveOccurred())
+
+		server = httptest.NewServer(NewMockProvider(repos))
+		cli, err := gitlab.NewOAuthClient("mock-access-token", gitlab.WithBaseURL(server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		client = &gitlabtool.Client{Client: cli}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("seeds the builtin snapshot pointing to the mock provider", func() {
+		snapshotFS, err := OpenSnapshot(BuiltinSnapshot)
+		Expect(err).NotTo(HaveOccurred())
+		collections, err := LoadDocuments(snapshotFS, server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(collections).To(HaveKey("code_host"))
+		Expect(collections).To(HaveKey("template_product"))
+		Expect(collections).To(HaveKey("workflow_v4"))
+		Expect(collections["code_host"][0].Map()["address"]).To(Equal(server.URL))
+	})
+
+	It("lists the namespaces, the projects and the branches", func() {
+		namespaces, err := client.ListNamespaces("", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(namespaces).To(HaveLen(1))
+		Expect(namespaces[0].Path).To(Equal("zadig-demo"))
+
+		projects, err := client.ListGroupProjects("zadig-demo", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(projects).To(HaveLen(1))
+		Expect(projects[0].PathWithNamespace).To(Equal("zadig-demo/voting-app"))
+
+		branches, err := client.ListBranches("zadig-demo", "voting-app", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branches).To(HaveLen(2))
+
+		mrs, err := client.ListOpenedProjectMergeRequests("zadig-demo", "voting-app", "main", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mrs).To(HaveLen(1))
+	})
+
+	It("serves and updates the files", func() {
+		content, err := client.GetFileContent("zadig-demo", "voting-app", "deploy/voting-app.yaml", "main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("kind: Deployment"))
+
+		raw, err := client.GetRawFile("zadig-demo", "voting-app", "main", "Dockerfile")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(raw)).To(HavePrefix("FROM nginx"))
+
+		tree, err := client.ListTree("zadig-demo", "voting-app", "deploy", "main", false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tree).To(HaveLen(1))
+		Expect(tree[0].Path).To(Equal("deploy/voting-app.yaml"))
+
+		Expect(client.UpdateFile("zadig-demo", "voting-app", "README.md", "main", "update", []byte("updated"))).To(Succeed())
+		content, err = client.GetFileContent("zadig-demo", "voting-app", "README.md", "main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("updated"))
+	})
+
+	It("returns not found for the unknown repositories", func() {
+		_, err := client.GetFileContent("zadig-demo", "unknown", "README.md", "main")
+		Expect(err).To(HaveOccurred())
+	})
+})
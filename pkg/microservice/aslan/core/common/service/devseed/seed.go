@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devseed
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/setting"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+// BuiltinSnapshot is the name of the sample snapshot shipped with aslan.
+const BuiltinSnapshot = "builtin"
+
+// mockProviderURLPlaceholder is replaced by the address of the mock provider in the seeded documents.
+const mockProviderURLPlaceholder = "${MOCK_PROVIDER_URL}"
+
+//go:embed snapshot
+var builtinSnapshot embed.FS
+
+// Init seeds the snapshot into mongo and starts the mock code provider for the local development, so that
+// zadig works without the credentials of a real code host. A snapshot has the following layout:
+//
+//	repos.yaml                 the repositories served by the mock provider
+//	mongo/<collection>.json    the documents of the collection in the mongo extended json
+//
+// A collection is seeded only if it is empty, so the data changed locally is kept across the restarts.
+func Init(ctx context.Context, logger *zap.SugaredLogger) {
+	snapshot := config.DevSeedSnapshot()
+	if snapshot == "" {
+		return
+	}
+	if configbase.Mode() == setting.ReleaseMode {
+		logger.Warnf("the dev seed snapshot %s is ignored in the release mode", snapshot)
+		return
+	}
+
+	snapshotFS, err := OpenSnapshot(snapshot)
+	if err != nil {
+		logger.Errorf("failed to open the dev seed snapshot %s: %s", snapshot, err)
+		return
+	}
+	repos, err := LoadRepos(snapshotFS)
+	if err != nil {
+		logger.Errorf("failed to load the repositories of the dev seed snapshot %s: %s", snapshot, err)
+		return
+	}
+
+	addr := config.DevMockProviderAddr()
+	server := &http.Server{Addr: addr, Handler: NewMockProvider(repos)}
+	go func() {
+		logger.Infof("the mock code provider is serving %d repositories on %s", len(repos), addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("the mock code provider exited: %s", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	if err := seed(ctx, snapshotFS, "http://"+addr, logger); err != nil {
+		logger.Errorf("failed to seed the dev seed snapshot %s: %s", snapshot, err)
+	}
+}
+
+// OpenSnapshot opens the snapshot directory, or the sample snapshot if it is "builtin".
+func OpenSnapshot(snapshot string) (fs.FS, error) {
+	if snapshot == BuiltinSnapshot {
+		return fs.Sub(builtinSnapshot, "snapshot")
+	}
+	if _, err := os.Stat(snapshot); err != nil {
+		return nil, err
+	}
+	return os.DirFS(snapshot), nil
+}
+
+func LoadRepos(snapshotFS fs.FS) ([]*MockRepo, error) {
+	data, err := fs.ReadFile(snapshotFS, "repos.yaml")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	repos := make([]*MockRepo, 0)
+	if err := yaml.Unmarshal(data, &repos); err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		if repo.Namespace == "" || repo.Name == "" {
+			return nil, fmt.Errorf("namespace and name are required for the repositories")
+		}
+		if repo.DefaultBranch == "" {
+			repo.DefaultBranch = "main"
+		}
+		if !contains(repo.Branches, repo.DefaultBranch) {
+			repo.Branches = append([]string{repo.DefaultBranch}, repo.Branches...)
+		}
+		if repo.Files == nil {
+			repo.Files = map[string]string{}
+		}
+	}
+	return repos, nil
+}
+
+// LoadDocuments loads the documents of the collections in the snapshot, the documents are keyed by the
+// collection names.
+func LoadDocuments(snapshotFS fs.FS, mockProviderURL string) (map[string][]bson.D, error) {
+	entries, err := fs.ReadDir(snapshotFS, "mongo")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	resp := make(map[string][]bson.D)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(snapshotFS, path.Join("mongo", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		content := strings.ReplaceAll(string(data), mockProviderURLPlaceholder, mockProviderURL)
+
+		docs := struct {
+			Documents []bson.D `bson:"documents"`
+		}{}
+		if err := bson.UnmarshalExtJSON([]byte(`{"documents":`+content+`}`), false, &docs); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", entry.Name(), err)
+		}
+		resp[strings.TrimSuffix(entry.Name(), ".json")] = docs.Documents
+	}
+	return resp, nil
+}
+
+func seed(ctx context.Context, snapshotFS fs.FS, mockProviderURL string, logger *zap.SugaredLogger) error {
+	collections, err := LoadDocuments(snapshotFS, mockProviderURL)
+	if err != nil {
+		return err
+	}
+
+	db := mongotool.Database(config.MongoDatabase())
+	for name, docs := range collections {
+		if len(docs) == 0 {
+			continue
+		}
+		coll := db.Collection(name)
+		count, err := coll.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			return fmt.Errorf("failed to count the documents of %s: %s", name, err)
+		}
+		if count > 0 {
+			logger.Infof("collection %s is not empty, skip seeding it", name)
+			continue
+		}
+
+		data := make([]interface{}, 0, len(docs))
+		for _, doc := range docs {
+			data = append(data, doc)
+		}
+		if _, err := coll.InsertMany(ctx, data); err != nil {
+			return fmt.Errorf("failed to seed %s: %s", name, err)
+		}
+		logger.Infof("seeded %d documents into collection %s", len(docs), name)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	commontemplate "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
+)
+
+// DuplicateNameArgs are the variables which can be used in the name templates when a resource is
+// duplicated, e.g. {{.Name}}-copy or {{.Project}}-env-{{.Env}}. The functions of FuncMap in the common template
+// package can be used to keep the names valid, e.g. {{k8sName .Env}}.
+type DuplicateNameArgs struct {
+	// Name is the name of the resource being duplicated.
+	Name string
+	// Project is the key of the project the duplicate belongs to.
+	Project string
+	// SourceProject is the key of the project the resource is duplicated from.
+	SourceProject string
+	// Env is the name of the duplicated environment.
+	Env string
+}
+
+// RenderDuplicateName renders the name template, the default template is used if it is empty.
+func RenderDuplicateName(nameTemplate, defaultTemplate string, args *DuplicateNameArgs) (string, error) {
+	if nameTemplate == "" {
+		nameTemplate = defaultTemplate
+	}
+	tmpl, err := template.New("name").Funcs(commontemplate.FuncMap()).Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid name template %s: %s", nameTemplate, err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, args); err != nil {
+		return "", fmt.Errorf("failed to render name template %s: %s", nameTemplate, err)
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("name template %s renders an empty name", nameTemplate)
+	}
+	return name, nil
+}
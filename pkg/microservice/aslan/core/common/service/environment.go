@@ -24,6 +24,7 @@ import (
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -417,6 +418,61 @@ func ListWorkloads(envName, clusterID, namespace, productName string, perPage, p
 			Annotation: v.Annotations,
 		})
 	}
+	daemonSets, err := getter.ListDaemonSetsWithCache(nil, informer)
+	if err != nil {
+		log.Errorf("[%s][%s] list daemonSets error: %v", envName, namespace, err)
+		return 0, resp, e.ErrListGroups.AddDesc(err.Error())
+	}
+	for _, v := range daemonSets {
+		workLoads = append(workLoads, &Workload{
+			Name:       v.Name,
+			Spec:       v.Spec.Template,
+			Type:       setting.DaemonSet,
+			Images:     wrapper.DaemonSet(v).ImageInfos(),
+			Ready:      wrapper.DaemonSet(v).Ready(),
+			Annotation: v.Annotations,
+		})
+	}
+	jobs, err := getter.ListJobsWithCache(nil, informer)
+	if err != nil {
+		log.Errorf("[%s][%s] list jobs error: %v", envName, namespace, err)
+		return 0, resp, e.ErrListGroups.AddDesc(err.Error())
+	}
+	for _, v := range jobs {
+		// the jobs created by cronJobs are shown with their cronJobs
+		if owner := metav1.GetControllerOf(v); owner != nil && owner.Kind == setting.CronJob {
+			continue
+		}
+		workLoads = append(workLoads, &Workload{
+			Name:       v.Name,
+			Spec:       v.Spec.Template,
+			Type:       setting.Job,
+			Images:     wrapper.Job(v).ImageInfos(),
+			Ready:      wrapper.Job(v).Complete(),
+			Annotation: v.Annotations,
+		})
+	}
+	// cronJobs are not watched by the informer since their api version differs among the k8s versions
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), clusterID)
+	if err != nil {
+		log.Errorf("[%s][%s] error: %v", envName, namespace, err)
+		return 0, resp, e.ErrListGroups.AddDesc(err.Error())
+	}
+	cronJobs, err := getter.ListCronJobs(namespace, nil, kubeClient)
+	if err != nil {
+		// err of listing cronJobs should not block the return of other workloads
+		log.Warnf("[%s][%s] list cronJobs error: %v", envName, namespace, err)
+	}
+	for _, v := range cronJobs {
+		workLoads = append(workLoads, &Workload{
+			Name:       v.Name,
+			Spec:       v.Spec.JobTemplate.Spec.Template,
+			Type:       setting.CronJob,
+			Images:     wrapper.CronJob(v).ImageInfos(),
+			Ready:      wrapper.CronJob(v).Ready(),
+			Annotation: v.Annotations,
+		})
+	}
 
 	err = fillServiceName(envName, productName, workLoads)
 	// err of getting service name should not block the return of workloads
@@ -494,10 +550,15 @@ func ListWorkloads(envName, clusterID, namespace, productName string, perPage, p
 			Status:       setting.PodRunning,
 		}
 
-		selector := labels.SelectorFromSet(labels.Set(workload.Spec.Labels))
-		// Note: In some scenarios, such as environment sharing, there may be more containers in Pod than workload.
-		// We call GetSelectedPodsInfo to get the status and readiness to keep same logic with k8s projects
-		productRespInfo.Status, productRespInfo.Ready, productRespInfo.Images = kube.GetSelectedPodsInfo(selector, informer, log)
+		if workload.Type == setting.CronJob && len(workload.Spec.Labels) == 0 {
+			// the pods of a cronJob can not be selected without labels in its job template
+			productRespInfo.Status, productRespInfo.Ready = setting.PodNonStarted, setting.PodNotReady
+		} else {
+			selector := labels.SelectorFromSet(labels.Set(workload.Spec.Labels))
+			// Note: In some scenarios, such as environment sharing, there may be more containers in Pod than workload.
+			// We call GetSelectedPodsInfo to get the status and readiness to keep same logic with k8s projects
+			productRespInfo.Status, productRespInfo.Ready, productRespInfo.Images = kube.GetSelectedPodsInfo(selector, informer, log)
+		}
 
 		productRespInfo.Ingress = &IngressInfo{
 			HostInfo: findServiceFromIngress(hostInfos, workload, allServices),
@@ -607,7 +668,7 @@ func GetServiceNameToReleaseNameMap(prod *models.Product) (map[string]string, er
 }
 
 // GetHelmServiceName get service name from annotations of resources deployed by helm
-// resType currently only support Deployment and StatefulSet
+// resType currently only support Deployment, StatefulSet, DaemonSet, Job and CronJob
 // this function needs to be optimized
 func GetHelmServiceName(prod *models.Product, resType, resName string, kubeClient client.Client) (string, error) {
 	res := &unstructured.Unstructured{}
@@ -618,11 +679,18 @@ func GetHelmServiceName(prod *models.Product, resType, resName string, kubeClien
 		return "", err
 	}
 
-	res.SetGroupVersionKind(schema.GroupVersionKind{
+	gvk := schema.GroupVersionKind{
 		Group:   "apps",
 		Version: "v1",
 		Kind:    resType,
-	})
+	}
+	switch resType {
+	case setting.Job:
+		gvk.Group = "batch"
+	case setting.CronJob:
+		gvk.Group, gvk.Version = "batch", "v1beta1"
+	}
+	res.SetGroupVersionKind(gvk)
 	found, err := getter.GetResourceInCache(namespace, resName, res, kubeClient)
 	if err != nil {
 		return "", fmt.Errorf("failed to find resource %s, type %s, err %s", resName, resType, err.Error())
@@ -84,6 +84,11 @@ func GetTaskLink(baseURI, productName, pipelineName string, pipelineType config.
 	)
 }
 
+// CheckRunName is the name of the check-run created for the pipeline by the GitHub App.
+func CheckRunName(pipeName string) string {
+	return fmt.Sprintf("Aslan - %s", pipeName)
+}
+
 func UIType(pipelineType config.PipelineType) string {
 	if pipelineType == config.SingleType {
 		return "single"
@@ -98,7 +103,7 @@ func UIType(pipelineType config.PipelineType) string {
 func (c *Client) StartGitCheck(check *GitCheck) (int64, error) {
 
 	opt := github.CreateCheckRunOptions{
-		Name:       fmt.Sprintf("Aslan - %s", check.PipeName),
+		Name:       CheckRunName(check.PipeName),
 		HeadSHA:    check.Ref,
 		DetailsURL: github.String(check.DetailsURL()),
 		ExternalID: github.String(fmt.Sprintf("%s/%d", check.PipeName, check.TaskID)),
@@ -119,7 +124,7 @@ func (c *Client) StartGitCheck(check *GitCheck) (int64, error) {
 // https://developer.github.com/v3/checks/runs/#update-a-check-run
 func (c *Client) UpdateGitCheck(gitCheckID int64, check *GitCheck) error {
 	opt := github.UpdateCheckRunOptions{
-		Name:       fmt.Sprintf("Aslan - %s", check.PipeName),
+		Name:       CheckRunName(check.PipeName),
 		DetailsURL: github.String(check.DetailsURL()),
 		ExternalID: github.String(fmt.Sprintf("%s/%d", check.PipeName, check.TaskID)),
 		Status:     github.String(StatusInProgress),
@@ -150,7 +155,7 @@ func (c *Client) CompleteGitCheck(gitCheckID int64, status CIStatus, check *GitC
 	}
 
 	opt := github.UpdateCheckRunOptions{
-		Name:        fmt.Sprintf("Aslan - %s", check.PipeName),
+		Name:        CheckRunName(check.PipeName),
 		DetailsURL:  github.String(check.DetailsURL()),
 		ExternalID:  github.String(fmt.Sprintf("%s/%d", check.PipeName, check.TaskID)),
 		Status:      github.String(StatusCompleted),
@@ -47,7 +47,7 @@ type StatusOptions struct {
 }
 
 func (c *Client) UpdateCheckStatus(opt *StatusOptions) error {
-	sc := setting.ProductName + "/" + opt.PipeName
+	sc := StatusContext(opt.PipeName)
 	_, err := c.CreateStatus(
 		context.TODO(), opt.Owner, opt.Repo, opt.Ref,
 		&github.RepoStatus{
@@ -64,3 +64,8 @@ func (c *Client) UpdateCheckStatus(opt *StatusOptions) error {
 		})
 	return err
 }
+
+// StatusContext is the context of the commit status published for the pipeline.
+func StatusContext(pipeName string) string {
+	return setting.ProductName + "/" + pipeName
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitmirror
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	StatusSyncing = "syncing"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+
+	defaultSyncInterval = 30 * time.Minute
+	syncTimeout         = 30 * time.Minute
+	cloneTokenTTL       = 2 * time.Hour
+)
+
+// the state of the syncs of a mirror in this replica, a webhook received during the sync makes the
+// mirror synced again instead of starting another fetch.
+type syncState struct {
+	mu      sync.Mutex
+	running bool
+	pending bool
+}
+
+var syncStates sync.Map
+
+// Setting returns the mirror setting, mirroring is disabled if it is not set.
+func Setting() *commonmodels.GitMirrorSetting {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil || sysSetting.GitMirror == nil {
+		return &commonmodels.GitMirrorSetting{}
+	}
+	return sysSetting.GitMirror
+}
+
+func repoDir(mirror *commonmodels.GitMirror) string {
+	return filepath.Join(config.GitMirrorPath(), mirror.ID.Hex()+".git")
+}
+
+// Remove deletes the mirrored repo from the disk.
+func Remove(mirror *commonmodels.GitMirror) error {
+	return os.RemoveAll(repoDir(mirror))
+}
+
+// Sync fetches all the refs of the repo from the codehost into the mirror and records the result,
+// the mirror is still used if it was synced before even if the latest sync fails.
+func Sync(mirror *commonmodels.GitMirror, logger *zap.SugaredLogger) error {
+	err := fetch(mirror)
+	if err != nil {
+		logger.Warnf("failed to sync the mirror of %s/%s, err: %s", mirror.GetRepoNamespace(), mirror.RepoName, err)
+		mirror.Status, mirror.Error = StatusFailed, err.Error()
+	} else {
+		mirror.Status, mirror.Error, mirror.SyncedAt = StatusReady, "", time.Now().Unix()
+		mirror.Size = dirSize(repoDir(mirror))
+	}
+	if updateErr := commonrepo.NewGitMirrorColl().UpdateStatus(mirror); updateErr != nil {
+		logger.Errorf("failed to update the status of the mirror %s, err: %s", mirror.ID.Hex(), updateErr)
+	}
+	return err
+}
+
+// SyncAsync syncs the mirror in the background, it returns immediately if the mirror is being synced.
+func SyncAsync(mirror *commonmodels.GitMirror) {
+	v, _ := syncStates.LoadOrStore(mirror.ID.Hex(), &syncState{})
+	state := v.(*syncState)
+	state.mu.Lock()
+	if state.running {
+		state.pending = true
+		state.mu.Unlock()
+		return
+	}
+	state.running = true
+	state.mu.Unlock()
+
+	go func() {
+		for {
+			_ = Sync(mirror, log.SugaredLogger())
+			state.mu.Lock()
+			if !state.pending {
+				state.running = false
+				state.mu.Unlock()
+				return
+			}
+			state.pending = false
+			state.mu.Unlock()
+		}
+	}()
+}
+
+// NotifyPush syncs the mirrors of the repo when its webhook is received, fullName is the path of the
+// repo with the namespace, e.g. koderover/zadig.
+func NotifyPush(fullName string) {
+	idx := strings.LastIndex(fullName, "/")
+	if idx <= 0 || !Setting().Enabled {
+		return
+	}
+	mirrors, err := commonrepo.NewGitMirrorColl().List(fullName[:idx], fullName[idx+1:])
+	if err != nil {
+		log.Warnf("failed to list the mirrors of %s, err: %s", fullName, err)
+		return
+	}
+	for _, mirror := range mirrors {
+		SyncAsync(mirror)
+	}
+}
+
+// StartSync syncs all the mirrors periodically in case the webhooks are missed.
+func StartSync(ctx context.Context, logger *zap.SugaredLogger) {
+	for {
+		interval := defaultSyncInterval
+		setting := Setting()
+		if setting.SyncInterval > 0 {
+			interval = time.Duration(setting.SyncInterval) * time.Minute
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if !setting.Enabled {
+			continue
+		}
+		mirrors, err := commonrepo.NewGitMirrorColl().List("", "")
+		if err != nil {
+			logger.Warnf("failed to list the mirrors, err: %s", err)
+			continue
+		}
+		for _, mirror := range mirrors {
+			SyncAsync(mirror)
+		}
+	}
+}
+
+// CloneURL returns the url the builds fetch the repo from, it is empty if the repo is not mirrored or
+// the mirror does not have the commit to build yet.
+func CloneURL(repo *types.Repository) string {
+	setting := Setting()
+	if !setting.Enabled {
+		return ""
+	}
+	mirror, err := commonrepo.NewGitMirrorColl().Find(repo.CodehostID, repo.GetRepoNamespace(), repo.RepoName)
+	if err != nil || mirror.SyncedAt == 0 {
+		return ""
+	}
+	// the mirror is used only if it is known to be up to date, i.e. the ref points to the commit to build
+	if repo.CommitID == "" || repo.Ref() == "" {
+		return ""
+	}
+	if !refAtCommit(mirror, repo.Ref(), repo.CommitID) {
+		SyncAsync(mirror)
+		return ""
+	}
+
+	token, err := signCloneToken(mirror.ID.Hex(), cloneTokenTTL)
+	if err != nil {
+		log.Warnf("failed to sign the token of the mirror %s, err: %s", mirror.ID.Hex(), err)
+		return ""
+	}
+	baseURL := strings.TrimSuffix(setting.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = configbase.AslanServiceAddress() + "/api"
+	}
+	u, err := url.Parse(fmt.Sprintf("%s/code/mirror/git/%s.git", baseURL, mirror.ID.Hex()))
+	if err != nil {
+		return ""
+	}
+	u.User = url.UserPassword(cloneTokenUser, token)
+	return u.String()
+}
+
+func refAtCommit(mirror *commonmodels.GitMirror, ref, commitID string) bool {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = repoDir(mirror)
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == commitID
+}
+
+func fetch(mirror *commonmodels.GitMirror) error {
+	ch, err := systemconfig.New().GetCodeHost(mirror.CodehostID)
+	if err != nil {
+		return err
+	}
+	remote, err := upstreamURL(ch, mirror)
+	if err != nil {
+		return err
+	}
+
+	dir := repoDir(mirror)
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if out, err := exec.Command("git", "init", "--bare", dir).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to init the mirror: %s, %s", err, out)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	// the url is passed on the command line so that the credential is not persisted in the mirror
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--prune", "--force", remote, "+refs/*:refs/*")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if ch.UseProxy() {
+		if httpsProxy := ch.ProxyAddr(config.ProxyHTTPSAddr()); httpsProxy != "" {
+			cmd.Env = append(cmd.Env, "https_proxy="+httpsProxy)
+		}
+		if httpProxy := ch.ProxyAddr(config.ProxyHTTPAddr()); httpProxy != "" {
+			cmd.Env = append(cmd.Env, "http_proxy="+httpProxy)
+		}
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to fetch the repo: %s, %s", err, maskURL(string(out), remote))
+	}
+	return nil
+}
+
+// upstreamURL returns the http clone url of the repo with the credential of the codehost, the codehosts
+// accessed by ssh are not supported.
+func upstreamURL(ch *systemconfig.CodeHost, mirror *commonmodels.GitMirror) (string, error) {
+	owner, name := mirror.GetRepoNamespace(), mirror.RepoName
+	u, err := url.Parse(strings.TrimSuffix(ch.Address, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid address of codehost %d: %s", ch.ID, err)
+	}
+
+	switch ch.Type {
+	case systemconfig.GitHubProvider:
+		return fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", ch.AccessToken, owner, name), nil
+	case systemconfig.GitLabProvider, systemconfig.GiteeProvider:
+		u.User = url.UserPassword("oauth2", ch.AccessToken)
+		u.Path = fmt.Sprintf("%s/%s/%s.git", u.Path, owner, name)
+	case systemconfig.GerritProvider:
+		if ch.AuthType == types.SSHAuthType {
+			return "", fmt.Errorf("the repos of gerrit accessed by ssh can not be mirrored")
+		}
+		userpass, _ := base64.StdEncoding.DecodeString(ch.AccessToken)
+		pair := strings.SplitN(string(userpass), ":", 2)
+		if len(pair) == 2 {
+			u.User = url.UserPassword(pair[0], pair[1])
+		} else {
+			u.User = url.User(pair[0])
+		}
+		u.Path = fmt.Sprintf("/a/%s", name)
+	case systemconfig.BitbucketServerProvider:
+		user := ch.Username
+		if user == "" {
+			user = "x-token-auth"
+		}
+		u.User = url.UserPassword(user, ch.AccessToken)
+		u.Path = fmt.Sprintf("%s/scm/%s/%s.git", u.Path, owner, name)
+	case systemconfig.OtherProvider:
+		if ch.AuthType != types.PrivateAccessTokenAuthType {
+			return "", fmt.Errorf("only the repos accessed by private access token can be mirrored")
+		}
+		u.User = url.UserPassword("oauth2", ch.PrivateAccessToken)
+		u.Path = fmt.Sprintf("%s/%s/%s.git", u.Path, owner, name)
+	default:
+		return "", fmt.Errorf("the repos of %s can not be mirrored", ch.Type)
+	}
+	return u.String(), nil
+}
+
+func maskURL(message, remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil {
+		return message
+	}
+	if password, ok := u.User.Password(); ok && password != "" {
+		message = strings.ReplaceAll(message, password, "********")
+	}
+	return message
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitmirror
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cgi"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const cloneTokenUser = "zadig"
+
+// the mirrors are read by the build pods with the basic auth, the password is a token of the mirror.
+func signCloneToken(mirrorID string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"mirror": mirrorID,
+		"exp":    time.Now().Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(configbase.SecretKey()))
+}
+
+func verifyCloneToken(token, mirrorID string) error {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(configbase.SecretKey()), nil
+	})
+	if err != nil {
+		return err
+	}
+	if claims["mirror"] != mirrorID {
+		return fmt.Errorf("the token is not issued for mirror %s", mirrorID)
+	}
+	return nil
+}
+
+// ServeHTTP serves the mirrors by the smart http protocol of git, path is like /<id>.git/info/refs.
+// The mirrors are read-only, pushing to them is rejected.
+func ServeHTTP(w http.ResponseWriter, r *http.Request, path string) {
+	path = "/" + strings.TrimPrefix(path, "/")
+	idx := strings.Index(path, ".git/")
+	if idx <= 1 {
+		http.NotFound(w, r)
+		return
+	}
+	mirrorID := path[1:idx]
+
+	user, token, ok := r.BasicAuth()
+	if !ok || user != cloneTokenUser || verifyCloneToken(token, mirrorID) != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="zadig"`)
+		http.Error(w, "invalid token of the mirror", http.StatusUnauthorized)
+		return
+	}
+	if strings.HasSuffix(path, "/git-receive-pack") || r.URL.Query().Get("service") == "git-receive-pack" {
+		http.Error(w, "the mirror is read-only", http.StatusForbidden)
+		return
+	}
+
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		log.Errorf("failed to find git, err: %s", err)
+		http.Error(w, "git is not installed", http.StatusInternalServerError)
+		return
+	}
+	handler := &cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env: []string{
+			"GIT_PROJECT_ROOT=" + config.GitMirrorPath(),
+			"GIT_HTTP_EXPORT_ALL=1",
+		},
+	}
+	req := r.Clone(r.Context())
+	req.URL.Path = path
+	handler.ServeHTTP(w, req)
+}
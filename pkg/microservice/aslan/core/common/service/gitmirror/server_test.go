@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitmirror
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("serving the mirrors", func() {
+	const mirrorID = "6352c5d3a4e4b2a1c8f0e001"
+
+	BeforeEach(func() {
+		viper.Set(setting.ENVSecretKey, "test-secret")
+	})
+
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/code/mirror/git"+path, nil)
+		if token != "" {
+			req.SetBasicAuth(cloneTokenUser, token)
+		}
+		w := httptest.NewRecorder()
+		ServeHTTP(w, req, path)
+		return w
+	}
+
+	It("accepts the tokens of the mirror only", func() {
+		token, err := signCloneToken(mirrorID, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifyCloneToken(token, mirrorID)).To(Succeed())
+		Expect(verifyCloneToken(token, "6352c5d3a4e4b2a1c8f0e002")).NotTo(Succeed())
+
+		expired, err := signCloneToken(mirrorID, -time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifyCloneToken(expired, mirrorID)).NotTo(Succeed())
+	})
+
+	It("rejects the requests without a valid token", func() {
+		Expect(serve("/"+mirrorID+".git/info/refs", "").Code).To(Equal(http.StatusUnauthorized))
+
+		other, err := signCloneToken("6352c5d3a4e4b2a1c8f0e002", time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(serve("/"+mirrorID+".git/info/refs", other).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects pushing to the mirrors", func() {
+		token, err := signCloneToken(mirrorID, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(serve("/"+mirrorID+".git/git-receive-pack", token).Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("returns not found for the paths out of the mirrors", func() {
+		Expect(serve("/info/refs", "").Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -17,6 +17,7 @@ limitations under the License.
 package service
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
 	"path"
@@ -82,6 +83,9 @@ func PreLoadServiceManifests(base string, svc *commonmodels.Service) error {
 		return preLoadServiceManifestsFromGerrit(svc)
 	case setting.SourceFromGitee:
 		return preLoadServiceManifestsFromGitee(svc)
+	case setting.SourceFromUmbrellaChart:
+		// the load path is the umbrella chart, the subchart can only be reloaded by resyncing the umbrella chart
+		return fmt.Errorf("chart of service %s is not found, resync it from the umbrella chart", svc.ServiceName)
 	default:
 		return preLoadServiceManifestsFromSource(svc)
 	}
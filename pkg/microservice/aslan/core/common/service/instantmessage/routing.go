@@ -0,0 +1,294 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	labeldb "github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+	labelservice "github.com/koderover/zadig/pkg/microservice/aslan/core/label/service"
+)
+
+// NotificationEvent is an event routed to the channels by the notification routes.
+type NotificationEvent struct {
+	Type        string
+	ProjectName string
+	Envs        []string
+	Severity    config.NotificationSeverity
+	Actor       string
+	// Resource is what the event is about, the label selectors of the routes are matched against its tags.
+	Resource *labeldb.Resource
+	// Subject identifies the series of events the failures in a row are counted on for the escalations, e.g.
+	// a workflow. The failures are not counted if it is empty.
+	Subject string
+	Title   string
+	Content string
+}
+
+var severityLevels = map[config.NotificationSeverity]int{
+	config.NotificationSeverityInfo:     1,
+	config.NotificationSeverityWarning:  2,
+	config.NotificationSeverityError:    3,
+	config.NotificationSeverityCritical: 4,
+}
+
+// ValidSeverity reports whether the severity is known, the empty one is not.
+func ValidSeverity(severity config.NotificationSeverity) bool {
+	return severityLevels[severity] > 0
+}
+
+// TaskSeverity returns the severity of a task ended with the status, it is empty if the task is not ended.
+func TaskSeverity(status config.Status) config.NotificationSeverity {
+	switch status {
+	case config.StatusPassed:
+		return config.NotificationSeverityInfo
+	case config.StatusCancelled, config.StatusReject:
+		return config.NotificationSeverityWarning
+	case config.StatusFailed, config.StatusTimeout:
+		return config.NotificationSeverityError
+	}
+	return ""
+}
+
+func (e *NotificationEvent) failed() bool {
+	return severityLevels[e.Severity] >= severityLevels[config.NotificationSeverityError]
+}
+
+// RouteNotification sends the event to the channels of the routes it matches. It returns false if there is no
+// matched route so that the caller falls back to the notifications configured on the resource.
+func (w *Service) RouteNotification(event *NotificationEvent, logger *zap.SugaredLogger) (bool, error) {
+	routes, err := w.notificationRouteColl.List(true)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	routed := false
+	for _, route := range routes {
+		if !matchRoute(route.Match, event, logger) {
+			continue
+		}
+		routed = true
+
+		escalated := w.escalate(route, event, logger)
+		quiet := event.Severity != config.NotificationSeverityCritical && !escalated && InQuietHours(route.QuietHours, now)
+		if !quiet {
+			mentions := route.MentionUsers
+			if route.MentionActor && event.Actor != "" {
+				mentions = append([]string{event.Actor}, mentions...)
+			}
+			w.sendRouteMessages(route.Channels, w.resolveMentions(mentions, event, escalated, now, logger), event, logger)
+		}
+		if escalated {
+			title := fmt.Sprintf("[升级] %s", event.Title)
+			w.sendRouteMessages(route.Escalation.Channels, w.resolveMentions(route.Escalation.MentionUsers, event, true, now, logger), &NotificationEvent{Title: title, Content: event.Content}, logger)
+		}
+
+		if !route.Continue {
+			break
+		}
+	}
+	return routed, nil
+}
+
+func matchRoute(match *models.NotificationRouteMatch, event *NotificationEvent, logger *zap.SugaredLogger) bool {
+	if match == nil {
+		return true
+	}
+	if len(match.Projects) > 0 && !sets.NewString(match.Projects...).Has(event.ProjectName) {
+		return false
+	}
+	if len(match.Envs) > 0 && !sets.NewString(match.Envs...).HasAny(event.Envs...) {
+		return false
+	}
+	if len(match.EventTypes) > 0 && !sets.NewString(match.EventTypes...).Has(event.Type) {
+		return false
+	}
+	if len(match.Severities) > 0 {
+		found := false
+		for _, severity := range match.Severities {
+			if severity == event.Severity {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(match.Actors) > 0 && !sets.NewString(match.Actors...).Has(event.Actor) {
+		return false
+	}
+	if match.LabelSelector != "" {
+		if event.Resource == nil {
+			return false
+		}
+		matched, err := labelservice.MatchSelector(*event.Resource, match.LabelSelector)
+		if err != nil {
+			logger.Errorf("failed to match label selector %s of %s, err: %s", match.LabelSelector, event.Resource.Name, err)
+			return false
+		}
+		return matched
+	}
+	return true
+}
+
+// escalate counts the failures in a row of the subject on the route, the event is escalated once the failures
+// reach the threshold of the route.
+func (w *Service) escalate(route *models.NotificationRoute, event *NotificationEvent, logger *zap.SugaredLogger) bool {
+	if route.Escalation == nil || route.Escalation.AfterFailures <= 0 || event.Subject == "" {
+		return false
+	}
+	routeID := route.ID.Hex()
+	if !event.failed() {
+		if err := w.notificationStreakColl.Reset(routeID, event.Subject); err != nil {
+			logger.Warnf("failed to reset the failures of %s on route %s, err: %s", event.Subject, route.Name, err)
+		}
+		return false
+	}
+	failures, err := w.notificationStreakColl.IncFailures(routeID, event.Subject)
+	if err != nil {
+		logger.Warnf("failed to count the failures of %s on route %s, err: %s", event.Subject, route.Name, err)
+		return false
+	}
+	return failures >= route.Escalation.AfterFailures
+}
+
+// resolveMentions returns the mobiles of the users who want to be mentioned by the event according to their
+// notification preferences, the users without a mobile are skipped. The escalated events mention all the users.
+func (w *Service) resolveMentions(userNames []string, event *NotificationEvent, escalated bool, now time.Time, logger *zap.SugaredLogger) []string {
+	mobiles := make([]string, 0)
+	seen := sets.NewString()
+	for _, userName := range userNames {
+		if seen.Has(userName) {
+			continue
+		}
+		seen.Insert(userName)
+
+		setting, err := w.userSettingColl.FindByUserName(userName)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				logger.Warnf("failed to find the notification preference of user %s, err: %s", userName, err)
+			}
+			continue
+		}
+		pref := setting.Notification
+		if pref == nil || pref.Mobile == "" {
+			continue
+		}
+		if !escalated && !wantsMention(pref, event, now) {
+			continue
+		}
+		mobiles = append(mobiles, pref.Mobile)
+	}
+	return mobiles
+}
+
+func wantsMention(pref *models.NotificationPreference, event *NotificationEvent, now time.Time) bool {
+	if pref.Muted || sets.NewString(pref.MutedProjects...).Has(event.ProjectName) {
+		return false
+	}
+	if pref.MinSeverity != "" && severityLevels[event.Severity] < severityLevels[pref.MinSeverity] {
+		return false
+	}
+	return event.Severity == config.NotificationSeverityCritical || !InQuietHours(pref.QuietHours, now)
+}
+
+func (w *Service) sendRouteMessages(channels []*models.NotifyCtl, mobiles []string, event *NotificationEvent, logger *zap.SugaredLogger) {
+	for _, channel := range channels {
+		if channel == nil || !channel.Enabled {
+			continue
+		}
+		notifyCtl := *channel
+		content := event.Content
+		if len(mobiles) > 0 && !notifyCtl.IsAtAll {
+			notifyCtl.AtMobiles = sets.NewString(append(notifyCtl.AtMobiles, mobiles...)...).List()
+			// dingding only highlights the mobiles mentioned in the content
+			if notifyCtl.WebHookType == dingDingType {
+				content = fmt.Sprintf("%s\n\n**相关人员**：@%s", content, strings.Join(notifyCtl.AtMobiles, "@"))
+			}
+		}
+		if err := w.SendSystemMessage(&notifyCtl, event.Title, content); err != nil {
+			logger.Errorf("failed to send %s message of %s, err: %s", notifyCtl.WebHookType, event.Title, err)
+		}
+	}
+}
+
+// ValidateQuietHours checks the window and the timezone of the quiet hours.
+func ValidateQuietHours(quietHours *models.QuietHours) error {
+	if quietHours == nil {
+		return nil
+	}
+	if _, err := parseClock(quietHours.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(quietHours.End); err != nil {
+		return err
+	}
+	if quietHours.Timezone != "" {
+		if _, err := time.LoadLocation(quietHours.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %s: %s", quietHours.Timezone, err)
+		}
+	}
+	return nil
+}
+
+// InQuietHours reports whether the time is in the quiet hours, the local timezone is used if the timezone of the
+// quiet hours is empty.
+func InQuietHours(quietHours *models.QuietHours, now time.Time) bool {
+	if quietHours == nil {
+		return false
+	}
+	start, err := parseClock(quietHours.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(quietHours.End)
+	if err != nil || start == end {
+		return false
+	}
+	if quietHours.Timezone != "" {
+		loc, err := time.LoadLocation(quietHours.Timezone)
+		if err != nil {
+			return false
+		}
+		now = now.In(loc)
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minutes >= start && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+// parseClock returns the minutes since midnight of a clock in the form of HH:MM.
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock %q, expected HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
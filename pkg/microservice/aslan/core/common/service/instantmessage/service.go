@@ -32,8 +32,12 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
+	labelconfig "github.com/koderover/zadig/pkg/microservice/aslan/core/label/config"
+	labeldb "github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+	labelservice "github.com/koderover/zadig/pkg/microservice/aslan/core/label/service"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/i18n"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -57,6 +61,10 @@ type Service struct {
 	pipelineColl     *mongodb.PipelineColl
 	testingColl      *mongodb.TestingColl
 	testTaskStatColl *mongodb.TestTaskStatColl
+
+	notificationRouteColl  *mongodb.NotificationRouteColl
+	notificationStreakColl *mongodb.NotificationStreakColl
+	userSettingColl        *mongodb.UserSettingColl
 }
 
 func NewWeChatClient() *Service {
@@ -66,6 +74,10 @@ func NewWeChatClient() *Service {
 		pipelineColl:     mongodb.NewPipelineColl(),
 		testingColl:      mongodb.NewTestingColl(),
 		testTaskStatColl: mongodb.NewTestTaskStatColl(),
+
+		notificationRouteColl:  mongodb.NewNotificationRouteColl(),
+		notificationStreakColl: mongodb.NewNotificationStreakColl(),
+		userSettingColl:        mongodb.NewUserSettingColl(),
 	}
 }
 
@@ -77,6 +89,8 @@ type wechatNotification struct {
 	TotalTime   int64      `json:"total_time"`
 	AtMobiles   []string   `json:"atMobiles"`
 	IsAtAll     bool       `json:"is_at_all"`
+	// Language is the language of the message, it is the one chosen by the creator of the task.
+	Language string `json:"language"`
 }
 
 func (w *Service) SendMessageRequest(uri string, message interface{}) ([]byte, error) {
@@ -131,7 +145,20 @@ func (w *Service) SendInstantMessage(task *task.Task, testTaskStatusChanged bool
 		return nil
 	}
 
+	if event := taskNotificationEvent(task); event != nil {
+		routed, err := w.RouteNotification(event, log.SugaredLogger())
+		if err != nil {
+			log.Errorf("failed to route the notification of task %s#%d, err: %s", task.PipelineName, task.TaskID, err)
+		}
+		if routed {
+			return nil
+		}
+	}
+
 	for _, notifyCtl := range notifyCtls {
+		if !matchLabelSelector(task, notifyCtl) {
+			continue
+		}
 		if err := w.sendMessage(task, notifyCtl, testTaskStatusChanged, desc); err != nil {
 			log.Errorf("send %s message err: %s", notifyCtl.WebHookType, err)
 			continue
@@ -140,6 +167,63 @@ func (w *Service) SendInstantMessage(task *task.Task, testTaskStatusChanged bool
 	return nil
 }
 
+// taskNotificationEvent returns the event of an ended task for the notification routes, the notifications of
+// the tasks which are not ended are not routed.
+func taskNotificationEvent(task *task.Task) *NotificationEvent {
+	severity := TaskSeverity(task.Status)
+	if severity == "" {
+		return nil
+	}
+	event := &NotificationEvent{
+		ProjectName: task.ProductName,
+		Severity:    severity,
+		Actor:       task.TaskCreator,
+		Subject:     fmt.Sprintf("%s/%s/%s", task.Type, task.ProductName, task.PipelineName),
+		Title:       fmt.Sprintf("%s #%d %s", task.PipelineName, task.TaskID, task.Status),
+	}
+	url := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/single/%s/%d", configbase.ExternalBaseURL(), task.ProductName, task.PipelineName, task.TaskID)
+	switch task.Type {
+	case config.SingleType:
+		event.Type = config.NotificationEventPipeline
+	case config.WorkflowType:
+		event.Type = config.NotificationEventWorkflow
+		event.Resource = &labeldb.Resource{
+			Name:        task.PipelineName,
+			ProjectName: task.ProductName,
+			Type:        string(labelconfig.ResourceTypeWorkflow),
+		}
+		if task.WorkflowArgs != nil && task.WorkflowArgs.Namespace != "" {
+			event.Envs = strings.Split(task.WorkflowArgs.Namespace, ",")
+		}
+		url = fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/multi/%s/%d", configbase.ExternalBaseURL(), task.ProductName, task.PipelineName, task.TaskID)
+	case config.TestType:
+		event.Type = config.NotificationEventTesting
+		url = fmt.Sprintf("%s/v1/projects/detail/%s/test/detail/function/%s/%d", configbase.ExternalBaseURL(), task.ProductName, task.PipelineName, task.TaskID)
+	default:
+		return nil
+	}
+	event.Content = fmt.Sprintf("### %s\n\n项目：%s, 执行用户：%s, 状态：%s\n\n[点击查看更多信息](%s)", event.Title, task.ProductName, task.TaskCreator, task.Status, url)
+	return event
+}
+
+// matchLabelSelector checks the label selector of the notification against the tags of the workflow,
+// notifications of the other task types do not support it.
+func matchLabelSelector(task *task.Task, notifyCtl *models.NotifyCtl) bool {
+	if notifyCtl == nil || notifyCtl.LabelSelector == "" || task.Type != config.WorkflowType {
+		return true
+	}
+	matched, err := labelservice.MatchSelector(labeldb.Resource{
+		Name:        task.PipelineName,
+		ProjectName: task.ProductName,
+		Type:        string(labelconfig.ResourceTypeWorkflow),
+	}, notifyCtl.LabelSelector)
+	if err != nil {
+		log.Errorf("failed to match label selector %s of workflow %s, err: %s", notifyCtl.LabelSelector, task.PipelineName, err)
+		return false
+	}
+	return matched
+}
+
 func (w *Service) sendMessage(task *task.Task, notifyCtl *models.NotifyCtl, testTaskStatusChanged bool, desc string) error {
 	if notifyCtl == nil {
 		return nil
@@ -168,12 +252,13 @@ func (w *Service) sendMessage(task *task.Task, notifyCtl *models.NotifyCtl, test
 			}
 			content, err = w.createNotifyBody(&wechatNotification{
 				Task:        task,
-				BaseURI:     configbase.SystemAddress(),
+				BaseURI:     configbase.ExternalBaseURL(),
 				IsSingle:    true,
 				WebHookType: webHookType,
 				TotalTime:   time.Now().Unix() - task.StartTime,
 				AtMobiles:   atMobiles,
 				IsAtAll:     isAtAll,
+				Language:    i18n.UserLanguageOrDefault(task.TaskCreator),
 			})
 			if err != nil {
 				log.Errorf("pipeline createNotifyBody err :%s", err)
@@ -194,12 +279,13 @@ func (w *Service) sendMessage(task *task.Task, notifyCtl *models.NotifyCtl, test
 			}
 			title, content, larkCard, err = w.createNotifyBodyOfWorkflowIM(&wechatNotification{
 				Task:        task,
-				BaseURI:     configbase.SystemAddress(),
+				BaseURI:     configbase.ExternalBaseURL(),
 				IsSingle:    false,
 				WebHookType: webHookType,
 				TotalTime:   time.Now().Unix() - task.StartTime,
 				AtMobiles:   atMobiles,
 				IsAtAll:     isAtAll,
+				Language:    i18n.UserLanguageOrDefault(task.TaskCreator),
 			})
 			if err != nil {
 				log.Errorf("workflow createNotifyBodyOfWorkflowIM err :%s", err)
@@ -221,12 +307,13 @@ func (w *Service) sendMessage(task *task.Task, notifyCtl *models.NotifyCtl, test
 			}
 			title, content, larkCard, err = w.createNotifyBodyOfTestIM(desc, &wechatNotification{
 				Task:        task,
-				BaseURI:     configbase.SystemAddress(),
+				BaseURI:     configbase.ExternalBaseURL(),
 				IsSingle:    false,
 				WebHookType: webHookType,
 				TotalTime:   time.Now().Unix() - task.StartTime,
 				AtMobiles:   atMobiles,
 				IsAtAll:     isAtAll,
+				Language:    i18n.UserLanguageOrDefault(task.TaskCreator),
 			})
 			if err != nil {
 				log.Errorf("testing createNotifyBodyOfTestIM err :%s", err)
@@ -413,7 +500,7 @@ func (w *Service) createNotifyBodyOfWorkflowIM(weChatNotification *wechatNotific
 		lc.AddI18NElementsZhcnFeild(test, true)
 	}
 	workflowDetailURL, _ = getTplExec(workflowDetailURL, weChatNotification)
-	lc.AddI18NElementsZhcnAction(buttonContent, workflowDetailURL)
+	lc.AddI18NElementsZhcnAction(i18n.T(weChatNotification.Language, buttonContent), workflowDetailURL)
 	return "", "", lc, nil
 }
 
@@ -466,7 +553,7 @@ func (w *Service) createNotifyBodyOfTestIM(desc string, weChatNotification *wech
 		lc.AddI18NElementsZhcnFeild(tplTestCaseInfo, true)
 	}
 	workflowDetailURL, _ = getTplExec(workflowDetailURL, weChatNotification)
-	lc.AddI18NElementsZhcnAction(buttonContent, workflowDetailURL)
+	lc.AddI18NElementsZhcnAction(i18n.T(weChatNotification.Language, buttonContent), workflowDetailURL)
 
 	return "", "", lc, nil
 }
@@ -499,6 +586,8 @@ func getHTMLTestReport(task *task.Task) []string {
 }
 
 func getTplExec(tplcontent string, weChatNotification *wechatNotification) (string, error) {
+	// the templates are written in Chinese, the phrases are translated before the template is executed.
+	tplcontent = i18n.Localize(weChatNotification.Language, tplcontent)
 	tmpl := template.Must(template.New("notify").Funcs(template.FuncMap{
 		"getColor": func(status config.Status) string {
 			if status == config.StatusPassed {
@@ -518,13 +607,13 @@ func getTplExec(tplcontent string, weChatNotification *wechatNotification) (stri
 		},
 		"taskStatus": func(status config.Status) string {
 			if status == config.StatusPassed {
-				return "执行成功"
+				return i18n.T(weChatNotification.Language, "执行成功")
 			} else if status == config.StatusCancelled {
-				return "执行取消"
+				return i18n.T(weChatNotification.Language, "执行取消")
 			} else if status == config.StatusTimeout {
-				return "执行超时"
+				return i18n.T(weChatNotification.Language, "执行超时")
 			}
-			return "执行失败"
+			return i18n.T(weChatNotification.Language, "执行失败")
 		},
 		"getIcon": func(status config.Status) string {
 			if status == config.StatusPassed {
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// WebHookURI returns the webhook the notification is sent to, it is empty if the webhook type is not supported.
+func WebHookURI(notifyCtl *models.NotifyCtl) string {
+	switch notifyCtl.WebHookType {
+	case dingDingType:
+		return notifyCtl.DingDingWebHook
+	case feiShuType:
+		return notifyCtl.FeiShuWebHook
+	case weChatWorkType:
+		return notifyCtl.WeChatWebHook
+	}
+	return ""
+}
+
+// SendSystemMessage sends a markdown message which is not about a task, e.g. the alerts of the integrations
+// to the admins.
+func (w *Service) SendSystemMessage(notifyCtl *models.NotifyCtl, title, content string) error {
+	if notifyCtl == nil || !notifyCtl.Enabled {
+		return nil
+	}
+	uri := WebHookURI(notifyCtl)
+	if uri == "" {
+		return fmt.Errorf("no webhook of type %s is configured", notifyCtl.WebHookType)
+	}
+
+	switch notifyCtl.WebHookType {
+	case dingDingType:
+		var atMobiles []string
+		if !notifyCtl.IsAtAll {
+			atMobiles = notifyCtl.AtMobiles
+		}
+		return w.sendDingDingMessage(uri, title, content, atMobiles)
+	case feiShuType:
+		return w.sendFeishuMessageOfSingleType(title, uri, content)
+	default:
+		return w.SendWeChatWorkMessage(weChatTextTypeMarkdown, uri, content)
+	}
+}
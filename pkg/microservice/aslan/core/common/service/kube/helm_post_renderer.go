@@ -0,0 +1,295 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/containerlog"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/util/rand"
+)
+
+const (
+	defaultHelmPostRenderTimeout = 300
+	defaultKustomizeImage        = "registry.k8s.io/kustomize/kustomize:v4.5.7"
+	helmPostRenderLabel          = "zadig-helm-post-render"
+	helmPostRenderContainer      = "post-render"
+	helmPostRenderManifests      = "manifests.yaml"
+)
+
+// NewHelmPostRenderOptions returns the helm options applying the post renderer of the env, nil is returned
+// if the env has no post renderer. The manifests of the dry runs are not recorded.
+func NewHelmPostRenderOptions(env *models.Product, releaseName string, dryRun bool, log *zap.SugaredLogger) *helmclient.GenericHelmOptions {
+	if env == nil || env.HelmPostRenderer == nil || !env.HelmPostRenderer.Enabled {
+		return nil
+	}
+	return &helmclient.GenericHelmOptions{
+		PostRenderer: &helmPostRenderer{
+			spec:        env.HelmPostRenderer,
+			projectName: env.ProductName,
+			envName:     env.EnvName,
+			releaseName: releaseName,
+			dryRun:      dryRun,
+			log:         log,
+		},
+	}
+}
+
+func ValidateHelmPostRenderer(spec *models.HelmPostRenderer) error {
+	if spec == nil || !spec.Enabled {
+		return nil
+	}
+	switch spec.Type {
+	case config.HelmPostRendererKustomize:
+		if _, err := buildHelmPostRenderKustomization(spec.Kustomization); err != nil {
+			return fmt.Errorf("invalid kustomization: %s", err)
+		}
+	case config.HelmPostRendererCommand:
+		if spec.Image == "" {
+			return fmt.Errorf("image is empty")
+		}
+		if strings.TrimSpace(spec.Command) == "" {
+			return fmt.Errorf("command is empty")
+		}
+	default:
+		return fmt.Errorf("unknown post renderer type %s", spec.Type)
+	}
+	if spec.Timeout < 0 {
+		return fmt.Errorf("timeout can not be negative")
+	}
+	return nil
+}
+
+type helmPostRenderer struct {
+	spec        *models.HelmPostRenderer
+	projectName string
+	envName     string
+	releaseName string
+	dryRun      bool
+	log         *zap.SugaredLogger
+}
+
+// Run mutates the manifests in a job, the result is recorded whether the job succeeds or not.
+func (r *helmPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	manifest, err := runHelmPostRenderJob(r.spec, renderedManifests.Bytes())
+	if !r.dryRun {
+		record := &models.HelmPostRenderRecord{
+			ProjectName: r.projectName,
+			EnvName:     r.envName,
+			ReleaseName: r.releaseName,
+			Type:        r.spec.Type,
+			Image:       helmPostRenderImage(r.spec),
+			Manifest:    manifest,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if errRecord := mongodb.NewHelmPostRenderRecordColl().Create(record); errRecord != nil {
+			r.log.Errorf("failed to record the post rendered manifests of release %s, err: %s", r.releaseName, errRecord)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to post render release %s: %s", r.releaseName, err)
+	}
+	return bytes.NewBufferString(manifest), nil
+}
+
+func helmPostRenderImage(spec *models.HelmPostRenderer) string {
+	if spec.Image == "" && spec.Type == config.HelmPostRendererKustomize {
+		return defaultKustomizeImage
+	}
+	return spec.Image
+}
+
+// buildHelmPostRenderKustomization adds the manifests rendered by helm to the resources of the kustomization.
+func buildHelmPostRenderKustomization(content string) ([]byte, error) {
+	kustomization := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(content), &kustomization); err != nil {
+		return nil, err
+	}
+	if kustomization == nil {
+		kustomization = map[string]interface{}{}
+	}
+	resources, _ := kustomization["resources"].([]interface{})
+	kustomization["resources"] = append(resources, helmPostRenderManifests)
+	return yaml.Marshal(kustomization)
+}
+
+// runHelmPostRenderJob runs the post renderer in a job of the local cluster. The job runs without the token
+// of any service account and with a read only root filesystem, the input is mounted from a secret since the
+// manifests may contain secrets. The mutated manifests are read from stdout, the errors from stderr are
+// reported by the termination message.
+func runHelmPostRenderJob(spec *models.HelmPostRenderer, manifests []byte) (string, error) {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), setting.LocalClusterID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kube client: %s", err)
+	}
+	clientset, err := kubeclient.GetClientset(config.HubServerAddress(), setting.LocalClusterID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kube clientset: %s", err)
+	}
+
+	namespace := config.Namespace()
+	name := rand.GenerateName("helm-post-render-")
+	jobLabels := map[string]string{helmPostRenderLabel: name}
+
+	script := spec.Command
+	input := map[string][]byte{helmPostRenderManifests: manifests}
+	if spec.Type == config.HelmPostRendererKustomize {
+		kustomization, err := buildHelmPostRenderKustomization(spec.Kustomization)
+		if err != nil {
+			return "", fmt.Errorf("invalid kustomization: %s", err)
+		}
+		input["kustomization.yaml"] = kustomization
+		script = "kustomize build ."
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: jobLabels},
+		Data:       input,
+	}
+	if err := updater.UpdateOrCreateSecret(secret, kubeClient); err != nil {
+		return "", fmt.Errorf("failed to create input secret: %s", err)
+	}
+	defer func() {
+		_ = updater.DeleteJob(namespace, name, kubeClient)
+		_ = updater.DeleteSecretWithName(namespace, name, kubeClient)
+	}()
+
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = defaultHelmPostRenderTimeout
+	}
+	backoffLimit := int32(0)
+	disabled := false
+	enabled := true
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: jobLabels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &timeout,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: jobLabels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: &disabled,
+					EnableServiceLinks:           &disabled,
+					Containers: []corev1.Container{{
+						Name:       helmPostRenderContainer,
+						Image:      helmPostRenderImage(spec),
+						Command:    []string{"sh", "-c", buildHelmPostRenderScript(script)},
+						WorkingDir: "/tmp/workspace",
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: &disabled,
+							ReadOnlyRootFilesystem:   &enabled,
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+						},
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("500m"),
+								corev1.ResourceMemory: resource.MustParse("512Mi"),
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "input", MountPath: "/zadig/input", ReadOnly: true},
+							{Name: "workspace", MountPath: "/tmp/workspace"},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{Name: "input", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: name}}},
+						{Name: "workspace", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					},
+				},
+			},
+		},
+	}
+	if err := updater.CreateJob(job, kubeClient); err != nil {
+		return "", fmt.Errorf("failed to create job: %s", err)
+	}
+
+	succeeded := false
+	err = wait.PollImmediate(2*time.Second, time.Duration(timeout)*time.Second+time.Minute, func() (bool, error) {
+		job, found, err := getter.GetJob(namespace, name, kubeClient)
+		if err != nil || !found {
+			return false, nil
+		}
+		succeeded = job.Status.Succeeded > 0
+		return succeeded || job.Status.Failed > 0, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("job did not finish in %d seconds", timeout)
+	}
+
+	pods, err := getter.ListPods(namespace, labels.SelectorFromSet(jobLabels), kubeClient)
+	if err != nil || len(pods) == 0 {
+		return "", fmt.Errorf("failed to find the pod of job %s: %v", name, err)
+	}
+	pod := pods[0]
+	if !succeeded {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil && status.State.Terminated.Message != "" {
+				return "", fmt.Errorf("job failed: %s", status.State.Terminated.Message)
+			}
+		}
+		return "", fmt.Errorf("job %s failed", name)
+	}
+
+	stream, err := containerlog.GetContainerLogStream(context.TODO(), namespace, pod.Name, helmPostRenderContainer, false, 0, clientset)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the output of job %s: %s", name, err)
+	}
+	defer stream.Close()
+	output, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the output of job %s: %s", name, err)
+	}
+	return string(output), nil
+}
+
+// buildHelmPostRenderScript keeps only the mutated manifests in stdout, stderr goes to the termination
+// message of the container.
+func buildHelmPostRenderScript(script string) string {
+	return strings.Join([]string{
+		"set -e",
+		"cp -L /zadig/input/* /tmp/workspace/",
+		"{",
+		script,
+		"} 2>/dev/termination-log",
+	}, "\n")
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	zadigtypes "github.com/koderover/zadig/pkg/types"
+)
+
+const podSecurityLabelPrefix = "pod-security.kubernetes.io/"
+
+var (
+	podSecurityLevels         = sets.NewString("privileged", "baseline", "restricted")
+	podSecurityVersionRegexp  = regexp.MustCompile(`^(latest|v1\.\d+)$`)
+	reservedNamespaceMetaKeys = sets.NewString(setting.ProductLabel, setting.EnvCreatedBy, zadigtypes.IstioLabelKeyInjection, setting.ManagedNamespaceMetaAnnotation)
+)
+
+// managedNamespaceMeta are the keys applied by zadig, so that the keys removed from the env can be
+// removed from the namespace as well.
+type managedNamespaceMeta struct {
+	Labels      []string `json:"labels,omitempty"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+func ValidateNamespaceMeta(meta *models.NamespaceMeta) error {
+	if meta == nil {
+		return nil
+	}
+	for key, value := range meta.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %s: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid value of label %s: %s", key, strings.Join(errs, ", "))
+		}
+		if reservedNamespaceMetaKeys.Has(key) || strings.HasPrefix(key, podSecurityLabelPrefix) {
+			return fmt.Errorf("label %s is managed by zadig", key)
+		}
+	}
+	for key := range meta.Annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation key %s: %s", key, strings.Join(errs, ", "))
+		}
+		if reservedNamespaceMetaKeys.Has(key) {
+			return fmt.Errorf("annotation %s is managed by zadig", key)
+		}
+	}
+	if ps := meta.PodSecurity; ps != nil {
+		for mode, level := range map[string]string{"enforce": ps.Enforce, "audit": ps.Audit, "warn": ps.Warn} {
+			if level != "" && !podSecurityLevels.Has(level) {
+				return fmt.Errorf("invalid pod security %s level %s, must be one of %s", mode, level, strings.Join(podSecurityLevels.List(), ", "))
+			}
+		}
+		if ps.Version != "" && !podSecurityVersionRegexp.MatchString(ps.Version) {
+			return fmt.Errorf("invalid pod security version %s", ps.Version)
+		}
+	}
+	return nil
+}
+
+// NamespaceMetaLabels returns the labels declared by the namespace meta, including the pod security admission labels.
+func NamespaceMetaLabels(meta *models.NamespaceMeta) map[string]string {
+	ls := map[string]string{}
+	if meta == nil {
+		return ls
+	}
+	for key, value := range meta.Labels {
+		ls[key] = value
+	}
+	if ps := meta.PodSecurity; ps != nil {
+		for mode, level := range map[string]string{"enforce": ps.Enforce, "audit": ps.Audit, "warn": ps.Warn} {
+			if level == "" {
+				continue
+			}
+			ls[podSecurityLabelPrefix+mode] = level
+			if ps.Version != "" {
+				ls[podSecurityLabelPrefix+mode+"-version"] = ps.Version
+			}
+		}
+	}
+	return ls
+}
+
+// EnsureNamespaceMeta applies the namespace meta to the namespace, the labels and annotations applied before
+// but no longer declared are removed, others set out of zadig are kept.
+func EnsureNamespaceMeta(namespace string, meta *models.NamespaceMeta, kubeClient client.Client) error {
+	nsObj := &corev1.Namespace{}
+	if err := kubeClient.Get(context.TODO(), client.ObjectKey{Name: namespace}, nsObj); err != nil {
+		return err
+	}
+
+	previous := &managedNamespaceMeta{}
+	previousData, ok := nsObj.Annotations[setting.ManagedNamespaceMetaAnnotation]
+	if ok {
+		if err := json.Unmarshal([]byte(previousData), previous); err != nil {
+			return fmt.Errorf("failed to parse annotation %s of namespace %s: %s", setting.ManagedNamespaceMetaAnnotation, namespace, err)
+		}
+	}
+	declaredLabels := NamespaceMetaLabels(meta)
+	declaredAnnotations := map[string]string{}
+	if meta != nil {
+		for key, value := range meta.Annotations {
+			declaredAnnotations[key] = value
+		}
+	}
+	if len(previous.Labels) == 0 && len(previous.Annotations) == 0 && len(declaredLabels) == 0 && len(declaredAnnotations) == 0 {
+		return nil
+	}
+
+	if nsObj.Labels == nil {
+		nsObj.Labels = map[string]string{}
+	}
+	if nsObj.Annotations == nil {
+		nsObj.Annotations = map[string]string{}
+	}
+	changed := applyManagedMeta(nsObj.Labels, previous.Labels, declaredLabels)
+	if applyManagedMeta(nsObj.Annotations, previous.Annotations, declaredAnnotations) {
+		changed = true
+	}
+
+	current := &managedNamespaceMeta{Labels: sortedKeys(declaredLabels), Annotations: sortedKeys(declaredAnnotations)}
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if len(current.Labels) == 0 && len(current.Annotations) == 0 {
+		delete(nsObj.Annotations, setting.ManagedNamespaceMetaAnnotation)
+	} else {
+		nsObj.Annotations[setting.ManagedNamespaceMetaAnnotation] = string(data)
+	}
+	if !changed && nsObj.Annotations[setting.ManagedNamespaceMetaAnnotation] == previousData {
+		return nil
+	}
+	return updater.UpdateNamespace(nsObj, kubeClient)
+}
+
+// applyManagedMeta removes the previously managed keys which are not declared any more and sets the declared ones,
+// it reports whether the target is changed.
+func applyManagedMeta(target map[string]string, previous []string, declared map[string]string) bool {
+	changed := false
+	for _, key := range previous {
+		if _, ok := declared[key]; ok {
+			continue
+		}
+		if _, ok := target[key]; ok {
+			delete(target, key)
+			changed = true
+		}
+	}
+	for key, value := range declared {
+		if current, ok := target[key]; !ok || current != value {
+			target[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
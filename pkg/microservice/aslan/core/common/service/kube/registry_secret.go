@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultServiceAccount is created by kubernetes in every namespace, it is used by the pods not
+// specifying a service account.
+const DefaultServiceAccount = "default"
+
+// AttachImagePullSecrets adds the secrets to the image pull secrets of the service account, so that
+// the pods run by it can pull the images even if the secrets are not declared in their specs. Nothing
+// is done if the service account is not created yet.
+func AttachImagePullSecrets(namespace, serviceAccount string, secretNames []string, kubeClient client.Client) error {
+	sa := &corev1.ServiceAccount{}
+	err := kubeClient.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: serviceAccount}, sa)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	attached := make(map[string]bool, len(sa.ImagePullSecrets))
+	for _, secret := range sa.ImagePullSecrets {
+		attached[secret.Name] = true
+	}
+	changed := false
+	for _, name := range secretNames {
+		if attached[name] {
+			continue
+		}
+		sa.ImagePullSecrets = append(sa.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+		attached[name] = true
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return kubeClient.Update(context.TODO(), sa)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+func ValidateResourceStatusMappings(mappings []*models.ResourceStatusMapping) error {
+	for _, mapping := range mappings {
+		if mapping.APIVersion == "" || mapping.Kind == "" {
+			return fmt.Errorf("api_version and kind are required in resource status mapping")
+		}
+		if _, err := schema.ParseGroupVersion(mapping.APIVersion); err != nil {
+			return fmt.Errorf("invalid api_version %s: %s", mapping.APIVersion, err)
+		}
+		if len(mapping.ReadyConditions) == 0 {
+			return fmt.Errorf("no ready conditions for %s", mapping.Kind)
+		}
+		for _, condition := range mapping.ReadyConditions {
+			if err := jsonpath.New(mapping.Kind).Parse(wrapJSONPath(condition.JSONPath)); err != nil {
+				return fmt.Errorf("invalid json path %s for %s: %s", condition.JSONPath, mapping.Kind, err)
+			}
+		}
+	}
+	return nil
+}
+
+// FindResourceStatusMapping returns the mapping of the custom resource, or nil if the kind is not mapped.
+func FindResourceStatusMapping(mappings []*models.ResourceStatusMapping, u *unstructured.Unstructured) *models.ResourceStatusMapping {
+	for _, mapping := range mappings {
+		if mapping.APIVersion == u.GetAPIVersion() && mapping.Kind == u.GetKind() {
+			return mapping
+		}
+	}
+	return nil
+}
+
+// IsCustomResourceReady checks the custom resource against the ready conditions of the mapping,
+// a condition is not matched if its path is missing in the resource.
+func IsCustomResourceReady(u *unstructured.Unstructured, mapping *models.ResourceStatusMapping) (bool, error) {
+	for _, condition := range mapping.ReadyConditions {
+		j := jsonpath.New(mapping.Kind).AllowMissingKeys(true)
+		if err := j.Parse(wrapJSONPath(condition.JSONPath)); err != nil {
+			return false, err
+		}
+		buf := &bytes.Buffer{}
+		if err := j.Execute(buf, u.Object); err != nil {
+			return false, err
+		}
+		if strings.TrimSpace(buf.String()) != condition.Value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// GetCustomResourcesStatus returns the status of the mapped custom resources of the service in the namespace.
+func GetCustomResourcesStatus(namespace, productName, serviceName string, mappings []*models.ResourceStatusMapping, cl client.Reader) (string, string) {
+	selector := labels.Set{setting.ProductLabel: productName, setting.ServiceLabel: serviceName}.AsSelector()
+	for _, mapping := range mappings {
+		gvk := schema.FromAPIVersionAndKind(mapping.APIVersion, mapping.Kind)
+		resources, err := getter.ListUnstructuredResourceInCache(namespace, selector, nil, gvk, cl)
+		if err != nil {
+			return setting.PodError, setting.PodNotReady
+		}
+		for _, u := range resources {
+			ready, err := IsCustomResourceReady(u, mapping)
+			if err != nil {
+				return setting.PodError, setting.PodNotReady
+			}
+			if !ready {
+				return setting.PodUnstable, setting.PodNotReady
+			}
+		}
+	}
+	return setting.PodRunning, setting.PodReady
+}
+
+func wrapJSONPath(path string) string {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "{") {
+		return path
+	}
+	return fmt.Sprintf("{%s}", path)
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package licensepolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+)
+
+// UnknownLicense is reported for the packages whose licenses are not detected.
+const UnknownLicense = "UNKNOWN"
+
+// Package is a package found in an image by syft.
+type Package struct {
+	Name     string
+	Version  string
+	Type     string
+	Licenses []string
+}
+
+type syftDocument struct {
+	Artifacts []struct {
+		Name     string            `json:"name"`
+		Version  string            `json:"version"`
+		Type     string            `json:"type"`
+		Licenses []json.RawMessage `json:"licenses"`
+	} `json:"artifacts"`
+}
+
+// ParseSyftJSON returns the packages in the sbom generated by syft in its json format. The licenses
+// are strings before syft 0.85 and objects with the value and the spdx expression since then.
+func ParseSyftJSON(data []byte) ([]*Package, error) {
+	doc := &syftDocument{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the sbom: %v", err)
+	}
+
+	packages := make([]*Package, 0, len(doc.Artifacts))
+	for _, artifact := range doc.Artifacts {
+		pkg := &Package{Name: artifact.Name, Version: artifact.Version, Type: artifact.Type}
+		for _, raw := range artifact.Licenses {
+			var license string
+			if err := json.Unmarshal(raw, &license); err != nil {
+				obj := struct {
+					Value          string `json:"value"`
+					SPDXExpression string `json:"spdxExpression"`
+				}{}
+				if err := json.Unmarshal(raw, &obj); err != nil {
+					return nil, fmt.Errorf("failed to parse the licenses of package %s: %v", artifact.Name, err)
+				}
+				license = obj.SPDXExpression
+				if license == "" {
+					license = obj.Value
+				}
+			}
+			if license != "" {
+				pkg.Licenses = append(pkg.Licenses, license)
+			}
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// Validate checks the license policy before it is saved.
+func Validate(policy *template.LicensePolicy) error {
+	allowed := sets.NewString(normalize(policy.Allowed)...)
+	for _, license := range normalize(policy.Denied) {
+		if allowed.Has(license) {
+			return fmt.Errorf("license %s is both allowed and denied", license)
+		}
+	}
+	for _, waiver := range policy.Waivers {
+		if waiver.Package == "" && waiver.License == "" {
+			return fmt.Errorf("the package or the license of the waiver should be set")
+		}
+		if waiver.Reason == "" {
+			return fmt.Errorf("the reason of the waiver for %s%s should be set", waiver.Package, waiver.License)
+		}
+	}
+	return nil
+}
+
+// Summarize counts the packages by their licenses.
+func Summarize(packages []*Package) []*commonmodels.LicenseCount {
+	counts := make(map[string]int)
+	for _, pkg := range packages {
+		if len(pkg.Licenses) == 0 {
+			counts[UnknownLicense]++
+			continue
+		}
+		for _, license := range pkg.Licenses {
+			counts[license]++
+		}
+	}
+
+	resp := make([]*commonmodels.LicenseCount, 0, len(counts))
+	for license, count := range counts {
+		resp = append(resp, &commonmodels.LicenseCount{License: license, Count: count})
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		if resp[i].Count != resp[j].Count {
+			return resp[i].Count > resp[j].Count
+		}
+		return resp[i].License < resp[j].License
+	})
+	return resp
+}
+
+// Evaluate returns the violations of the packages against the policy, the violations accepted by the
+// waivers not expired at now are returned separately.
+func Evaluate(policy *template.LicensePolicy, packages []*Package, now int64) (violations, waived []*commonmodels.LicenseViolation) {
+	violations = make([]*commonmodels.LicenseViolation, 0)
+	waived = make([]*commonmodels.LicenseViolation, 0)
+	if policy == nil {
+		return
+	}
+	allowed := sets.NewString(normalize(policy.Allowed)...)
+	denied := sets.NewString(normalize(policy.Denied)...)
+
+	for _, pkg := range packages {
+		licenses := pkg.Licenses
+		if len(licenses) == 0 {
+			if !policy.FailOnUnknown {
+				continue
+			}
+			licenses = []string{UnknownLicense}
+		}
+		for _, license := range licenses {
+			reason := check(license, allowed, denied)
+			if reason == "" {
+				continue
+			}
+			violation := &commonmodels.LicenseViolation{
+				Package: pkg.Name,
+				Version: pkg.Version,
+				Type:    pkg.Type,
+				License: license,
+				Reason:  reason,
+			}
+			if waiver := findWaiver(policy.Waivers, pkg.Name, license, now); waiver != nil {
+				violation.WaiverID = waiver.ID
+				waived = append(waived, violation)
+				continue
+			}
+			violations = append(violations, violation)
+		}
+	}
+	return
+}
+
+// check returns why the license expression violates the policy, an expression with OR is allowed if any of
+// its licenses is allowed, otherwise all of them should be allowed.
+func check(expression string, allowed, denied sets.String) string {
+	if expression == UnknownLicense {
+		return "the license is unknown"
+	}
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+	if alternatives := splitExpression(expression, " OR "); len(alternatives) > 1 {
+		var reason string
+		for _, alternative := range alternatives {
+			if reason = check(alternative, allowed, denied); reason == "" {
+				return ""
+			}
+		}
+		return reason
+	}
+	for _, license := range splitExpression(expression, " AND ") {
+		key := strings.ToLower(license)
+		if denied.Has(key) {
+			return fmt.Sprintf("license %s is denied", license)
+		}
+		if allowed.Len() > 0 && !allowed.Has(key) {
+			return fmt.Sprintf("license %s is not allowed", license)
+		}
+	}
+	return ""
+}
+
+func splitExpression(expression, operator string) []string {
+	resp := make([]string, 0)
+	for _, part := range strings.Split(expression, operator) {
+		if part = strings.TrimSpace(part); part != "" {
+			resp = append(resp, part)
+		}
+	}
+	return resp
+}
+
+func findWaiver(waivers []*template.LicenseWaiver, pkg, license string, now int64) *template.LicenseWaiver {
+	for _, waiver := range waivers {
+		if waiver.ExpireTime > 0 && waiver.ExpireTime < now {
+			continue
+		}
+		if waiver.Package != "" && waiver.Package != pkg {
+			continue
+		}
+		if waiver.License != "" && !strings.EqualFold(waiver.License, license) {
+			continue
+		}
+		return waiver
+	}
+	return nil
+}
+
+func normalize(licenses []string) []string {
+	resp := make([]string, 0, len(licenses))
+	for _, license := range licenses {
+		resp = append(resp, strings.ToLower(strings.TrimSpace(license)))
+	}
+	return resp
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package licensepolicy
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+)
+
+func TestLicensePolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "license policy Suite")
+}
+
+const testSBOM = `{
+  "artifacts": [
+    {"name": "gin", "version": "v1.8.1", "type": "go-module", "licenses": ["MIT"]},
+    {"name": "readline", "version": "8.1", "type": "deb", "licenses": [{"value": "GPL-3.0-only", "spdxExpression": "GPL-3.0-only"}]},
+    {"name": "dual", "version": "1.0.0", "type": "npm", "licenses": ["(GPL-2.0-only OR Apache-2.0)"]},
+    {"name": "mystery", "version": "0.1.0", "type": "npm", "licenses": []}
+  ]
+}`
+
+var _ = Describe("Testing license policy", func() {
+
+	It("parses the licenses of both syft formats", func() {
+		packages, err := ParseSyftJSON([]byte(testSBOM))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(packages).To(HaveLen(4))
+		Expect(packages[0].Licenses).To(Equal([]string{"MIT"}))
+		Expect(packages[1].Licenses).To(Equal([]string{"GPL-3.0-only"}))
+		Expect(packages[3].Licenses).To(BeEmpty())
+
+		summary := Summarize(packages)
+		Expect(summary).To(HaveLen(4))
+		Expect(summary[0].Count).To(Equal(1))
+	})
+
+	It("reports the denied and the unknown licenses unless they are waived", func() {
+		packages, err := ParseSyftJSON([]byte(testSBOM))
+		Expect(err).NotTo(HaveOccurred())
+
+		policy := &template.LicensePolicy{Denied: []string{"gpl-3.0-only", "GPL-2.0-only"}, FailOnUnknown: true}
+		violations, waived := Evaluate(policy, packages, 100)
+		Expect(waived).To(BeEmpty())
+		Expect(violations).To(HaveLen(2))
+		Expect(violations[0].Package).To(Equal("readline"))
+		Expect(violations[1].License).To(Equal(UnknownLicense))
+
+		policy.Waivers = []*template.LicenseWaiver{
+			{ID: "expired", Package: "readline", ExpireTime: 50},
+			{ID: "readline", Package: "readline", License: "GPL-3.0-ONLY"},
+		}
+		violations, waived = Evaluate(policy, packages, 100)
+		Expect(violations).To(HaveLen(1))
+		Expect(waived).To(HaveLen(1))
+		Expect(waived[0].WaiverID).To(Equal("readline"))
+	})
+
+	It("only allows the listed licenses if any", func() {
+		packages, err := ParseSyftJSON([]byte(testSBOM))
+		Expect(err).NotTo(HaveOccurred())
+
+		violations, _ := Evaluate(&template.LicensePolicy{Allowed: []string{"MIT", "Apache-2.0"}}, packages, 0)
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].Reason).To(Equal("license GPL-3.0-only is not allowed"))
+
+		Expect(Validate(&template.LicensePolicy{Allowed: []string{"MIT"}, Denied: []string{"mit"}})).To(HaveOccurred())
+		Expect(Validate(&template.LicensePolicy{Waivers: []*template.LicenseWaiver{{Package: "gin"}}})).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestlint
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// DeprecatedAPI is an api version of some kinds deprecated or removed by kubernetes, DeprecatedIn is
+// empty if the api version is removed without being deprecated for a release.
+type DeprecatedAPI struct {
+	APIVersion   string
+	Kinds        []string
+	DeprecatedIn string
+	RemovedIn    string
+	Replacement  string
+}
+
+var deprecatedAPIs = []*DeprecatedAPI{
+	{APIVersion: "extensions/v1beta1", Kinds: []string{"Deployment", "DaemonSet", "ReplicaSet"}, DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{APIVersion: "extensions/v1beta1", Kinds: []string{"NetworkPolicy"}, DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "networking.k8s.io/v1"},
+	{APIVersion: "extensions/v1beta1", Kinds: []string{"PodSecurityPolicy"}, DeprecatedIn: "1.10", RemovedIn: "1.16", Replacement: "policy/v1beta1"},
+	{APIVersion: "extensions/v1beta1", Kinds: []string{"Ingress"}, DeprecatedIn: "1.14", RemovedIn: "1.22", Replacement: "networking.k8s.io/v1"},
+	{APIVersion: "apps/v1beta1", Kinds: []string{"Deployment", "StatefulSet", "ReplicaSet"}, DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{APIVersion: "apps/v1beta2", Kinds: []string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet"}, DeprecatedIn: "1.9", RemovedIn: "1.16", Replacement: "apps/v1"},
+	{APIVersion: "networking.k8s.io/v1beta1", Kinds: []string{"Ingress", "IngressClass"}, DeprecatedIn: "1.19", RemovedIn: "1.22", Replacement: "networking.k8s.io/v1"},
+	{APIVersion: "rbac.authorization.k8s.io/v1beta1", Kinds: []string{"Role", "ClusterRole", "RoleBinding", "ClusterRoleBinding"}, DeprecatedIn: "1.17", RemovedIn: "1.22", Replacement: "rbac.authorization.k8s.io/v1"},
+	{APIVersion: "apiextensions.k8s.io/v1beta1", Kinds: []string{"CustomResourceDefinition"}, DeprecatedIn: "1.16", RemovedIn: "1.22", Replacement: "apiextensions.k8s.io/v1"},
+	{APIVersion: "admissionregistration.k8s.io/v1beta1", Kinds: []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"}, DeprecatedIn: "1.16", RemovedIn: "1.22", Replacement: "admissionregistration.k8s.io/v1"},
+	{APIVersion: "scheduling.k8s.io/v1beta1", Kinds: []string{"PriorityClass"}, DeprecatedIn: "1.14", RemovedIn: "1.22", Replacement: "scheduling.k8s.io/v1"},
+	{APIVersion: "storage.k8s.io/v1beta1", Kinds: []string{"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"}, DeprecatedIn: "1.19", RemovedIn: "1.22", Replacement: "storage.k8s.io/v1"},
+	{APIVersion: "storage.k8s.io/v1beta1", Kinds: []string{"CSIStorageCapacity"}, DeprecatedIn: "1.24", RemovedIn: "1.27", Replacement: "storage.k8s.io/v1"},
+	{APIVersion: "certificates.k8s.io/v1beta1", Kinds: []string{"CertificateSigningRequest"}, DeprecatedIn: "1.19", RemovedIn: "1.22", Replacement: "certificates.k8s.io/v1"},
+	{APIVersion: "coordination.k8s.io/v1beta1", Kinds: []string{"Lease"}, DeprecatedIn: "1.14", RemovedIn: "1.22", Replacement: "coordination.k8s.io/v1"},
+	{APIVersion: "batch/v1beta1", Kinds: []string{"CronJob"}, DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "batch/v1"},
+	{APIVersion: "policy/v1beta1", Kinds: []string{"PodDisruptionBudget"}, DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "policy/v1"},
+	{APIVersion: "policy/v1beta1", Kinds: []string{"PodSecurityPolicy"}, DeprecatedIn: "1.21", RemovedIn: "1.25"},
+	{APIVersion: "discovery.k8s.io/v1beta1", Kinds: []string{"EndpointSlice"}, DeprecatedIn: "1.21", RemovedIn: "1.25", Replacement: "discovery.k8s.io/v1"},
+	{APIVersion: "events.k8s.io/v1beta1", Kinds: []string{"Event"}, DeprecatedIn: "1.19", RemovedIn: "1.25", Replacement: "events.k8s.io/v1"},
+	{APIVersion: "autoscaling/v2beta1", Kinds: []string{"HorizontalPodAutoscaler"}, DeprecatedIn: "1.22", RemovedIn: "1.25", Replacement: "autoscaling/v2"},
+	{APIVersion: "autoscaling/v2beta2", Kinds: []string{"HorizontalPodAutoscaler"}, DeprecatedIn: "1.23", RemovedIn: "1.26", Replacement: "autoscaling/v2"},
+	{APIVersion: "node.k8s.io/v1beta1", Kinds: []string{"RuntimeClass"}, DeprecatedIn: "1.20", RemovedIn: "1.25", Replacement: "node.k8s.io/v1"},
+}
+
+// the jobs exit by design, they are not probed.
+var batchKinds = sets.NewString("Job", "CronJob")
+
+type resource struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Object     map[string]interface{}
+}
+
+type container struct {
+	Name   string
+	Object map[string]interface{}
+}
+
+func newResource(object map[string]interface{}) *resource {
+	kind, _ := object["kind"].(string)
+	if kind == "" {
+		return nil
+	}
+	res := &resource{Kind: kind, Object: object}
+	res.APIVersion, _ = object["apiVersion"].(string)
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		res.Name, _ = metadata["name"].(string)
+	}
+	return res
+}
+
+// containers returns the containers of the pod template of the workloads, the init containers are not
+// returned since they are neither long-running nor probed.
+func (r *resource) containers() []*container {
+	var path []string
+	switch r.Kind {
+	case "Pod":
+		path = []string{"spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		path = []string{"spec", "template", "spec"}
+	case "CronJob":
+		path = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+
+	podSpec := r.Object
+	for _, key := range path {
+		next, ok := podSpec[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		podSpec = next
+	}
+	items, _ := podSpec["containers"].([]interface{})
+	resp := make([]*container, 0, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := object["name"].(string)
+		resp = append(resp, &container{Name: name, Object: object})
+	}
+	return resp
+}
+
+func (r *resource) longRunning() bool {
+	return !batchKinds.Has(r.Kind)
+}
+
+func (r *resource) finding(rule, severity string, c *container, format string, args ...interface{}) *commonmodels.ManifestLintFinding {
+	f := &commonmodels.ManifestLintFinding{
+		Rule:     rule,
+		Severity: severity,
+		Kind:     r.Kind,
+		Name:     r.Name,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if c != nil {
+		f.Container = c.Name
+	}
+	return f
+}
+
+// checkAPIVersion reports the removed api versions as errors and the deprecated ones as warnings, all
+// of them are warnings if the version of the cluster is unknown.
+func checkAPIVersion(r *resource, kubeVersion *version.Version) []*commonmodels.ManifestLintFinding {
+	api := FindDeprecatedAPI(r.APIVersion, r.Kind)
+	if api == nil {
+		return nil
+	}
+	switch {
+	case kubeVersion == nil:
+		return []*commonmodels.ManifestLintFinding{r.finding(RuleDeprecatedAPIVersion, SeverityWarning, nil,
+			"%s %s is removed in kubernetes %s, %s", r.APIVersion, r.Kind, api.RemovedIn, api.suggestion())}
+	case api.removedBy(kubeVersion):
+		return []*commonmodels.ManifestLintFinding{r.finding(RuleDeprecatedAPIVersion, SeverityError, nil,
+			"%s %s is removed in kubernetes %s, the cluster is %s, %s", r.APIVersion, r.Kind, api.RemovedIn, kubeVersion, api.suggestion())}
+	case api.deprecatedBy(kubeVersion):
+		return []*commonmodels.ManifestLintFinding{r.finding(RuleDeprecatedAPIVersion, SeverityWarning, nil,
+			"%s %s is deprecated since kubernetes %s and removed in %s, %s", r.APIVersion, r.Kind, api.DeprecatedIn, api.RemovedIn, api.suggestion())}
+	}
+	return nil
+}
+
+func checkImageTag(r *resource, c *container) []*commonmodels.ManifestLintFinding {
+	image, _ := c.Object["image"].(string)
+	// the images which are not rendered yet are not checked.
+	if image == "" || strings.Contains(image, "{{") || strings.Contains(image, "$") {
+		return nil
+	}
+	if strings.Contains(image, "@") {
+		return nil
+	}
+	tag := ""
+	// the port of the registry is not a tag, e.g. registry:5000/app.
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		tag = image[i+1:]
+	}
+	if tag == "" {
+		return []*commonmodels.ManifestLintFinding{r.finding(RuleLatestTag, SeverityWarning, c, "image %s has no tag, latest is pulled", image)}
+	}
+	if tag == "latest" {
+		return []*commonmodels.ManifestLintFinding{r.finding(RuleLatestTag, SeverityWarning, c, "image %s uses the latest tag", image)}
+	}
+	return nil
+}
+
+func checkLimits(r *resource, c *container) []*commonmodels.ManifestLintFinding {
+	limits := map[string]interface{}{}
+	if resources, ok := c.Object["resources"].(map[string]interface{}); ok {
+		limits, _ = resources["limits"].(map[string]interface{})
+	}
+	missing := []string{}
+	for _, name := range []string{"cpu", "memory"} {
+		if _, ok := limits[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return []*commonmodels.ManifestLintFinding{r.finding(RuleMissingLimits, SeverityWarning, c, "no %s limit", strings.Join(missing, " or "))}
+}
+
+// checkProbes reports the missing readiness probe as a warning, the missing liveness probe only as an
+// info since restarting on a failed probe is not always wanted.
+func checkProbes(r *resource, c *container) []*commonmodels.ManifestLintFinding {
+	resp := make([]*commonmodels.ManifestLintFinding, 0)
+	if _, ok := c.Object["readinessProbe"]; !ok {
+		resp = append(resp, r.finding(RuleMissingProbes, SeverityWarning, c, "no readiness probe, the traffic is sent before the container is ready"))
+	}
+	if _, ok := c.Object["livenessProbe"]; !ok {
+		resp = append(resp, r.finding(RuleMissingProbes, SeverityInfo, c, "no liveness probe, the container is not restarted if it hangs"))
+	}
+	return resp
+}
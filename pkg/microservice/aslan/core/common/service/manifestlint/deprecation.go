@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestlint
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// DeprecatedAPIs returns the api versions deprecated or removed by kubernetes.
+func DeprecatedAPIs() []*DeprecatedAPI {
+	return deprecatedAPIs
+}
+
+// FindDeprecatedAPI returns the deprecation of the api version of the kind, nil if it is not deprecated.
+func FindDeprecatedAPI(apiVersion, kind string) *DeprecatedAPI {
+	for _, api := range deprecatedAPIs {
+		if api.APIVersion == apiVersion && sets.NewString(api.Kinds...).Has(kind) {
+			return api
+		}
+	}
+	return nil
+}
+
+func (api *DeprecatedAPI) suggestion() string {
+	if api.Replacement == "" {
+		return "there is no replacement"
+	}
+	return fmt.Sprintf("use %s instead", api.Replacement)
+}
+
+func (api *DeprecatedAPI) removedBy(v *version.Version) bool {
+	return v != nil && v.AtLeast(version.MustParseGeneric(api.RemovedIn))
+}
+
+func (api *DeprecatedAPI) deprecatedBy(v *version.Version) bool {
+	return v != nil && api.DeprecatedIn != "" && v.AtLeast(version.MustParseGeneric(api.DeprecatedIn))
+}
+
+// NextMinorVersion returns the minor version the cluster of the version is upgraded to, e.g. v1.25 for v1.24.3.
+func NextMinorVersion(kubeVersion string) (string, error) {
+	v, err := version.ParseGeneric(kubeVersion)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("v%d.%d", v.Major(), v.Minor()+1), nil
+}
+
+// Deprecation checks the api version of a resource against the current version of the cluster and the
+// version it is upgraded to, the api versions removed in the current version are errors, the ones removed
+// by the upgrade are warnings and the ones only deprecated by then are infos. Nil is returned if the api
+// version of the resource is not affected. If the current version is unknown, the api versions removed by
+// the target version are warnings, and if both are unknown, all the deprecated api versions are.
+func Deprecation(apiVersion, kind, name, namespace string, current, target *version.Version) *commonmodels.APIDeprecation {
+	api := FindDeprecatedAPI(apiVersion, kind)
+	if api == nil {
+		return nil
+	}
+	d := &commonmodels.APIDeprecation{
+		APIVersion:   apiVersion,
+		Kind:         kind,
+		Name:         name,
+		Namespace:    namespace,
+		DeprecatedIn: api.DeprecatedIn,
+		RemovedIn:    api.RemovedIn,
+		Replacement:  api.Replacement,
+	}
+	switch {
+	case api.removedBy(current):
+		d.Severity = SeverityError
+		d.Message = fmt.Sprintf("%s %s is removed in kubernetes %s, the cluster is %s, %s", apiVersion, kind, api.RemovedIn, current, api.suggestion())
+	case api.removedBy(target):
+		d.Severity = SeverityWarning
+		d.Message = fmt.Sprintf("%s %s is removed in kubernetes %s, upgrading the cluster to %s breaks it, %s", apiVersion, kind, api.RemovedIn, target, api.suggestion())
+	case api.deprecatedBy(target):
+		d.Severity = SeverityInfo
+		d.Message = fmt.Sprintf("%s %s is deprecated since kubernetes %s and removed in %s, %s", apiVersion, kind, api.DeprecatedIn, api.RemovedIn, api.suggestion())
+	case current == nil && target == nil:
+		d.Severity = SeverityWarning
+		d.Message = fmt.Sprintf("%s %s is removed in kubernetes %s, %s", apiVersion, kind, api.RemovedIn, api.suggestion())
+	default:
+		return nil
+	}
+	return d
+}
+
+// FindDeprecations checks the api versions of the resources in the manifests, the versions are ignored
+// if they can not be parsed. The manifests which can not be parsed are skipped.
+func FindDeprecations(manifests []string, currentVersion, targetVersion string) []*commonmodels.APIDeprecation {
+	current, _ := version.ParseGeneric(currentVersion)
+	target, _ := version.ParseGeneric(targetVersion)
+
+	resp := make([]*commonmodels.APIDeprecation, 0)
+	for _, manifest := range manifests {
+		object := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(manifest), &object); err != nil {
+			continue
+		}
+		res := newResource(object)
+		if res == nil {
+			continue
+		}
+		namespace := ""
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			namespace, _ = metadata["namespace"].(string)
+		}
+		if d := Deprecation(res.APIVersion, res.Kind, res.Name, namespace, current, target); d != nil {
+			resp = append(resp, d)
+		}
+	}
+	return resp
+}
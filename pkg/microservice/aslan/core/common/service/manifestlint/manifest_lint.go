@@ -0,0 +1,186 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestlint
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+)
+
+const (
+	RuleMissingProbes        = "missing-probes"
+	RuleLatestTag            = "latest-tag"
+	RuleMissingLimits        = "missing-resource-limits"
+	RuleDeprecatedAPIVersion = "deprecated-api-version"
+	// RuleInvalidManifest is reported for the manifests which can not be parsed, it can not be disabled.
+	RuleInvalidManifest = "invalid-manifest"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+var severityLevels = map[string]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+// Rule describes a check, Severity is the highest severity the check reports.
+type Rule struct {
+	Name        string `json:"name"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+var rules = []*Rule{
+	{Name: RuleMissingProbes, Severity: SeverityWarning, Description: "the long-running containers should have readiness and liveness probes"},
+	{Name: RuleLatestTag, Severity: SeverityWarning, Description: "the images should be pinned to a tag other than latest or to a digest"},
+	{Name: RuleMissingLimits, Severity: SeverityWarning, Description: "the containers should have cpu and memory limits"},
+	{Name: RuleDeprecatedAPIVersion, Severity: SeverityError, Description: "the resources should not use the api versions deprecated or removed in the target cluster version"},
+}
+
+// Options are the checks to run, the deprecated api versions are reported as warnings if the version of
+// the target cluster is unknown.
+type Options struct {
+	DisabledRules []string
+	KubeVersion   string
+}
+
+// Rules returns the checks which can be run.
+func Rules() []*Rule {
+	return rules
+}
+
+// Enabled returns the lint policy of the project if the deployments to the env are gated by it.
+func Enabled(projectName, envName string) (*template.ManifestLintPolicy, bool, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find project %s: %s", projectName, err)
+	}
+	policy := project.ManifestLint
+	if policy == nil || !policy.Enabled {
+		return nil, false, nil
+	}
+	if len(policy.ProtectedEnvs) > 0 && !sets.NewString(policy.ProtectedEnvs...).Has(envName) {
+		return nil, false, nil
+	}
+	return policy, true, nil
+}
+
+// Validate checks the lint policy of the project.
+func Validate(policy *template.ManifestLintPolicy) error {
+	known := sets.NewString()
+	for _, r := range rules {
+		known.Insert(r.Name)
+	}
+	for _, rule := range policy.DisabledRules {
+		if !known.Has(rule) {
+			return fmt.Errorf("unknown rule %s", rule)
+		}
+	}
+	if policy.FailOn == "" {
+		policy.FailOn = SeverityError
+	}
+	if policy.FailOn != SeverityError && policy.FailOn != SeverityWarning {
+		return fmt.Errorf("fail_on must be %s or %s", SeverityError, SeverityWarning)
+	}
+	return nil
+}
+
+// Blocking returns the findings at least as severe as failOn.
+func Blocking(findings []*commonmodels.ManifestLintFinding, failOn string) []*commonmodels.ManifestLintFinding {
+	level, ok := severityLevels[failOn]
+	if !ok {
+		level = severityLevels[SeverityError]
+	}
+	resp := make([]*commonmodels.ManifestLintFinding, 0)
+	for _, f := range findings {
+		if severityLevels[f.Severity] >= level {
+			resp = append(resp, f)
+		}
+	}
+	return resp
+}
+
+// FormatFindings summarizes the findings for the error of the job.
+func FormatFindings(findings []*commonmodels.ManifestLintFinding) string {
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		target := fmt.Sprintf("%s/%s", f.Kind, f.Name)
+		if f.Container != "" {
+			target = fmt.Sprintf("%s[%s]", target, f.Container)
+		}
+		lines = append(lines, fmt.Sprintf("[%s][%s] %s: %s", f.Severity, f.Rule, target, f.Message))
+	}
+	return fmt.Sprintf("%d manifest lint finding(s) found:\n%s", len(findings), strings.Join(lines, "\n"))
+}
+
+// Lint runs the checks on every resource of the manifests, the manifests which can not be parsed are
+// reported as findings so that the rest are still checked.
+func Lint(manifests []string, opt *Options) []*commonmodels.ManifestLintFinding {
+	if opt == nil {
+		opt = &Options{}
+	}
+	disabled := sets.NewString(opt.DisabledRules...)
+	var kubeVersion *version.Version
+	if opt.KubeVersion != "" {
+		kubeVersion, _ = version.ParseGeneric(opt.KubeVersion)
+	}
+
+	findings := make([]*commonmodels.ManifestLintFinding, 0)
+	for _, manifest := range manifests {
+		object := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(manifest), &object); err != nil {
+			findings = append(findings, &commonmodels.ManifestLintFinding{
+				Rule:     RuleInvalidManifest,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("failed to parse the manifest: %s", err),
+			})
+			continue
+		}
+		res := newResource(object)
+		if res == nil {
+			continue
+		}
+		if !disabled.Has(RuleDeprecatedAPIVersion) {
+			findings = append(findings, checkAPIVersion(res, kubeVersion)...)
+		}
+		for _, c := range res.containers() {
+			if !disabled.Has(RuleLatestTag) {
+				findings = append(findings, checkImageTag(res, c)...)
+			}
+			if !disabled.Has(RuleMissingLimits) {
+				findings = append(findings, checkLimits(res, c)...)
+			}
+			if !disabled.Has(RuleMissingProbes) && res.longRunning() {
+				findings = append(findings, checkProbes(res, c)...)
+			}
+		}
+	}
+	return findings
+}
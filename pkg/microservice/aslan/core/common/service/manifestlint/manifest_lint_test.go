@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestlint
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+)
+
+func TestManifestLint(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "manifest lint Suite")
+}
+
+const testDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+spec:
+  template:
+    spec:
+      containers:
+        - name: demo
+          image: registry:5000/demo
+        - name: sidecar
+          image: docker.io/sidecar:v1
+          readinessProbe:
+            httpGet:
+              path: /healthz
+              port: 8080
+          livenessProbe:
+            httpGet:
+              path: /healthz
+              port: 8080
+          resources:
+            limits:
+              cpu: 100m
+              memory: 128Mi
+`
+
+const testCronJob = `
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: clean
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: clean
+              image: docker.io/clean@sha256:0123456789abcdef
+              resources:
+                limits:
+                  cpu: 100m
+                  memory: 128Mi
+`
+
+func rulesOf(findings []*commonmodels.ManifestLintFinding) []string {
+	resp := make([]string, 0, len(findings))
+	for _, f := range findings {
+		resp = append(resp, f.Rule+"/"+f.Severity)
+	}
+	return resp
+}
+
+var _ = Describe("Testing manifest lint", func() {
+
+	It("checks the containers of the workloads", func() {
+		findings := Lint([]string{testDeployment}, nil)
+		Expect(rulesOf(findings)).To(ConsistOf(
+			RuleLatestTag+"/"+SeverityWarning,
+			RuleMissingLimits+"/"+SeverityWarning,
+			RuleMissingProbes+"/"+SeverityWarning,
+			RuleMissingProbes+"/"+SeverityInfo,
+		))
+		for _, f := range findings {
+			Expect(f.Kind).To(Equal("Deployment"))
+			Expect(f.Name).To(Equal("demo"))
+			Expect(f.Container).To(Equal("demo"))
+		}
+
+		findings = Lint([]string{testDeployment}, &Options{DisabledRules: []string{RuleMissingProbes, RuleMissingLimits}})
+		Expect(rulesOf(findings)).To(ConsistOf(RuleLatestTag + "/" + SeverityWarning))
+	})
+
+	It("checks the api versions against the cluster version", func() {
+		Expect(rulesOf(Lint([]string{testCronJob}, &Options{KubeVersion: "v1.20.4"}))).To(BeEmpty())
+		Expect(rulesOf(Lint([]string{testCronJob}, &Options{KubeVersion: "v1.23.1"}))).To(ConsistOf(RuleDeprecatedAPIVersion + "/" + SeverityWarning))
+		Expect(rulesOf(Lint([]string{testCronJob}, &Options{KubeVersion: "v1.25.0-eks"}))).To(ConsistOf(RuleDeprecatedAPIVersion + "/" + SeverityError))
+		Expect(rulesOf(Lint([]string{testCronJob}, nil))).To(ConsistOf(RuleDeprecatedAPIVersion + "/" + SeverityWarning))
+	})
+
+	It("reports the invalid manifests", func() {
+		findings := Lint([]string{"kind: [", "# empty\n"}, nil)
+		Expect(rulesOf(findings)).To(ConsistOf(RuleInvalidManifest + "/" + SeverityError))
+	})
+
+	It("validates the policy and selects the blocking findings", func() {
+		policy := &template.ManifestLintPolicy{}
+		Expect(Validate(policy)).To(Succeed())
+		Expect(policy.FailOn).To(Equal(SeverityError))
+		Expect(Validate(&template.ManifestLintPolicy{DisabledRules: []string{"unknown"}})).NotTo(Succeed())
+		Expect(Validate(&template.ManifestLintPolicy{FailOn: SeverityInfo})).NotTo(Succeed())
+
+		findings := Lint([]string{testDeployment}, nil)
+		Expect(Blocking(findings, SeverityError)).To(BeEmpty())
+		Expect(Blocking(findings, SeverityWarning)).To(HaveLen(3))
+	})
+
+	It("finds the api versions broken by the upgrade of the cluster", func() {
+		next, err := NextMinorVersion("v1.24.3-gke.100")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next).To(Equal("v1.25"))
+
+		deprecations := FindDeprecations([]string{testCronJob, testDeployment}, "v1.24.3", next)
+		Expect(deprecations).To(HaveLen(1))
+		Expect(deprecations[0].Kind).To(Equal("CronJob"))
+		Expect(deprecations[0].Severity).To(Equal(SeverityWarning))
+		Expect(deprecations[0].Replacement).To(Equal("batch/v1"))
+
+		Expect(FindDeprecations([]string{testCronJob}, "v1.25.0", "v1.26")[0].Severity).To(Equal(SeverityError))
+		Expect(FindDeprecations([]string{testCronJob}, "v1.20.0", "v1.21")[0].Severity).To(Equal(SeverityInfo))
+		Expect(FindDeprecations([]string{testCronJob}, "v1.19.0", "v1.20")).To(BeEmpty())
+	})
+})
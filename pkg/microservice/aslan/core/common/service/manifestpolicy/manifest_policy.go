@@ -0,0 +1,287 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestpolicy
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/tool/opa"
+)
+
+// The bundled rules, they are applied unless disabled by the project.
+const (
+	RuleMissingLimits       = "missing-limits"
+	RulePrivilegedContainer = "privileged-container"
+	RuleBannedRegistries    = "banned-registries"
+)
+
+// the packages are out of the roots of the authz bundle, so they can be uploaded by the policy API of opa.
+const (
+	packageRoot    = "zadig.manifest"
+	projectPackage = packageRoot + ".projects"
+	libPackage     = packageRoot + ".lib"
+)
+
+var (
+	//go:embed rego/lib.rego
+	libRego string
+	//go:embed rego/missing_limits.rego
+	missingLimitsRego string
+	//go:embed rego/privileged_container.rego
+	privilegedContainerRego string
+	//go:embed rego/banned_registries.rego
+	bannedRegistriesRego string
+)
+
+var (
+	packageRegx    = regexp.MustCompile(`(?m)^[ \t]*package[ \t]+\S+[ \t]*$`)
+	policyNameRegx = regexp.MustCompile(`^[a-z][a-z0-9-]{0,31}$`)
+)
+
+type module struct {
+	rule string
+	pkg  string
+	rego string
+}
+
+var bundledModules = []*module{
+	{rule: RuleMissingLimits, pkg: packageRoot + ".missing_limits", rego: missingLimitsRego},
+	{rule: RulePrivilegedContainer, pkg: packageRoot + ".privileged_container", rego: privilegedContainerRego},
+	{rule: RuleBannedRegistries, pkg: packageRoot + ".banned_registries", rego: bannedRegistriesRego},
+}
+
+type reviewInput struct {
+	Review     *review     `json:"review"`
+	Parameters *parameters `json:"parameters"`
+}
+
+type review struct {
+	Kind      *reviewKind            `json:"kind"`
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Object    map[string]interface{} `json:"object"`
+}
+
+type reviewKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+type parameters struct {
+	BannedRegistries []string `json:"banned_registries"`
+}
+
+type violationResult struct {
+	Msg string `json:"msg"`
+}
+
+// Enabled returns the manifest policy of the project if it is applied to the env.
+func Enabled(projectName, envName string) (*template.ManifestPolicy, bool, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find project %s: %s", projectName, err)
+	}
+	policy := project.ManifestPolicy
+	if policy == nil || !policy.Enabled {
+		return nil, false, nil
+	}
+	if len(policy.ProtectedEnvs) > 0 && !sets.NewString(policy.ProtectedEnvs...).Has(envName) {
+		return nil, false, nil
+	}
+	return policy, true, nil
+}
+
+// Validate checks the policy of the project, the rego policies are compiled by uploading them to opa.
+func Validate(projectName string, policy *template.ManifestPolicy) error {
+	bundled := sets.NewString()
+	for _, m := range bundledModules {
+		bundled.Insert(m.rule)
+	}
+	for _, rule := range policy.DisabledRules {
+		if !bundled.Has(rule) {
+			return fmt.Errorf("unknown rule %s", rule)
+		}
+	}
+	for _, registry := range policy.BannedRegistries {
+		if strings.TrimSpace(registry) == "" {
+			return fmt.Errorf("empty banned registry")
+		}
+	}
+
+	names := sets.NewString()
+	modules := []*module{}
+	for _, p := range policy.Policies {
+		if !policyNameRegx.MatchString(p.Name) {
+			return fmt.Errorf("invalid policy name %s, it should match %s", p.Name, policyNameRegx)
+		}
+		if names.Has(p.Name) || bundled.Has(p.Name) {
+			return fmt.Errorf("duplicated policy name %s", p.Name)
+		}
+		names.Insert(p.Name)
+		modules = append(modules, newProjectModule(projectName, p))
+	}
+	if len(modules) == 0 {
+		return nil
+	}
+
+	client := opa.NewClient(configbase.OPAServiceAddress())
+	if err := client.PutPolicy(moduleID(libPackage), libRego); err != nil {
+		return fmt.Errorf("failed to upload the rego library: %s", err)
+	}
+	for _, m := range modules {
+		if err := client.PutPolicy(moduleID(m.pkg), m.rego); err != nil {
+			return fmt.Errorf("invalid policy %s: %s", m.rule, err)
+		}
+	}
+	return nil
+}
+
+// RemovePolicies deletes the rego policies which are no longer used by the project from opa.
+func RemovePolicies(projectName string, policies []*template.RegoPolicy) {
+	client := opa.NewClient(configbase.OPAServiceAddress())
+	for _, p := range policies {
+		_ = client.DeletePolicy(moduleID(newProjectModule(projectName, p).pkg))
+	}
+}
+
+// Check evaluates every resource of the manifests against the policy, all violations found are returned.
+// The policies are uploaded before the evaluation, so they survive the restarts of opa.
+func Check(projectName string, policy *template.ManifestPolicy, manifests []string) ([]*commonmodels.ManifestPolicyViolation, error) {
+	disabled := sets.NewString(policy.DisabledRules...)
+	modules := []*module{}
+	for _, m := range bundledModules {
+		if !disabled.Has(m.rule) {
+			modules = append(modules, m)
+		}
+	}
+	for _, p := range policy.Policies {
+		modules = append(modules, newProjectModule(projectName, p))
+	}
+	if len(modules) == 0 {
+		return nil, nil
+	}
+
+	client := opa.NewClient(configbase.OPAServiceAddress())
+	if err := client.PutPolicy(moduleID(libPackage), libRego); err != nil {
+		return nil, fmt.Errorf("failed to upload the rego library: %s", err)
+	}
+	for _, m := range modules {
+		if err := client.PutPolicy(moduleID(m.pkg), m.rego); err != nil {
+			return nil, fmt.Errorf("failed to upload policy %s: %s", m.rule, err)
+		}
+	}
+
+	violations := []*commonmodels.ManifestPolicyViolation{}
+	for _, manifest := range manifests {
+		input, err := newReviewInput(manifest, policy)
+		if err != nil {
+			return nil, err
+		}
+		if input == nil {
+			continue
+		}
+		for _, m := range modules {
+			results := []*violationResult{}
+			if err := client.EvaluateData(m.pkg+".violation", input, &results); err != nil {
+				return nil, fmt.Errorf("failed to evaluate policy %s: %s", m.rule, err)
+			}
+			for _, r := range results {
+				violations = append(violations, &commonmodels.ManifestPolicyViolation{
+					Rule:    m.rule,
+					Kind:    input.Review.Kind.Kind,
+					Name:    input.Review.Name,
+					Message: r.Msg,
+				})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// FormatViolations summarizes the violations for the error of the job.
+func FormatViolations(violations []*commonmodels.ManifestPolicyViolation) string {
+	lines := make([]string, 0, len(violations))
+	for _, v := range violations {
+		lines = append(lines, fmt.Sprintf("[%s] %s/%s: %s", v.Rule, v.Kind, v.Name, v.Message))
+	}
+	return fmt.Sprintf("%d manifest policy violation(s) found:\n%s", len(violations), strings.Join(lines, "\n"))
+}
+
+func newReviewInput(manifest string, policy *template.ManifestPolicy) (*reviewInput, error) {
+	object := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(manifest), &object); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %s", err)
+	}
+	kind, _ := object["kind"].(string)
+	if kind == "" {
+		return nil, nil
+	}
+	input := &reviewInput{
+		Review:     &review{Kind: &reviewKind{Kind: kind}, Object: object},
+		Parameters: &parameters{BannedRegistries: policy.BannedRegistries},
+	}
+	if apiVersion, ok := object["apiVersion"].(string); ok {
+		input.Review.Kind.Version = apiVersion
+		if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+			input.Review.Kind.Group, input.Review.Kind.Version = apiVersion[:i], apiVersion[i+1:]
+		}
+	}
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		input.Review.Name, _ = metadata["name"].(string)
+		input.Review.Namespace, _ = metadata["namespace"].(string)
+	}
+	return input, nil
+}
+
+// newProjectModule moves the rego policy of the project to its own package.
+func newProjectModule(projectName string, policy *template.RegoPolicy) *module {
+	pkg := fmt.Sprintf("%s.%s.%s", projectPackage, regoIdentifier(projectName), regoIdentifier(policy.Name))
+	rego := policy.Rego
+	if packageRegx.MatchString(rego) {
+		replaced := false
+		rego = packageRegx.ReplaceAllStringFunc(rego, func(s string) string {
+			if replaced {
+				return s
+			}
+			replaced = true
+			return "package " + pkg
+		})
+	} else {
+		rego = fmt.Sprintf("package %s\n\n%s", pkg, rego)
+	}
+	return &module{rule: policy.Name, pkg: pkg, rego: rego}
+}
+
+// regoIdentifier converts the names to identifiers of rego, which can not start with a digit or contain "-".
+func regoIdentifier(name string) string {
+	return "x_" + strings.ReplaceAll(name, "-", "_")
+}
+
+func moduleID(pkg string) string {
+	return strings.ReplaceAll(pkg, ".", "_")
+}
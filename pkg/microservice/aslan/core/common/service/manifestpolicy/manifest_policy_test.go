@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestpolicy
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+)
+
+func TestManifestPolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "manifest policy Suite")
+}
+
+const testDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+  namespace: dev
+spec:
+  template:
+    spec:
+      containers:
+        - name: demo
+          image: docker.io/demo:v1
+`
+
+var _ = Describe("Testing manifest policy", func() {
+
+	It("moves project policies to their own packages", func() {
+		m := newProjectModule("demo-project", &template.RegoPolicy{Name: "no-latest", Rego: "package k8s.nolatest\n\nviolation[{\"msg\": \"x\"}] { true }\n"})
+		Expect(m.pkg).To(Equal("zadig.manifest.projects.x_demo_project.x_no_latest"))
+		Expect(m.rego).To(HavePrefix("package zadig.manifest.projects.x_demo_project.x_no_latest\n"))
+		Expect(m.rego).NotTo(ContainSubstring("k8s.nolatest"))
+
+		m = newProjectModule("demo", &template.RegoPolicy{Name: "rule", Rego: "violation[{\"msg\": \"x\"}] { true }"})
+		Expect(m.rego).To(HavePrefix("package zadig.manifest.projects.x_demo.x_rule\n"))
+	})
+
+	It("builds gatekeeper compatible inputs", func() {
+		input, err := newReviewInput(testDeployment, &template.ManifestPolicy{BannedRegistries: []string{"docker.io/"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(input.Review.Kind).To(Equal(&reviewKind{Group: "apps", Version: "v1", Kind: "Deployment"}))
+		Expect(input.Review.Name).To(Equal("demo"))
+		Expect(input.Review.Namespace).To(Equal("dev"))
+		Expect(input.Parameters.BannedRegistries).To(Equal([]string{"docker.io/"}))
+
+		input, err = newReviewInput("# empty\n", &template.ManifestPolicy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(input).To(BeNil())
+	})
+})
@@ -0,0 +1,10 @@
+package zadig.manifest.banned_registries
+
+import data.zadig.manifest.lib
+
+violation[{"msg": msg}] {
+	container := lib.containers[_]
+	registry := input.parameters.banned_registries[_]
+	startswith(container.image, registry)
+	msg := sprintf("image %v of container %v is from banned registry %v", [container.image, container.name, registry])
+}
@@ -0,0 +1,23 @@
+package zadig.manifest.lib
+
+# pod_spec is the pod template of the workload in input.review.object.
+pod_spec = input.review.object.spec {
+	input.review.object.kind == "Pod"
+}
+
+pod_spec = input.review.object.spec.template.spec {
+	input.review.object.kind != "Pod"
+	input.review.object.kind != "CronJob"
+}
+
+pod_spec = input.review.object.spec.jobTemplate.spec.template.spec {
+	input.review.object.kind == "CronJob"
+}
+
+containers[container] {
+	container := pod_spec.containers[_]
+}
+
+containers[container] {
+	container := pod_spec.initContainers[_]
+}
@@ -0,0 +1,15 @@
+package zadig.manifest.missing_limits
+
+import data.zadig.manifest.lib
+
+violation[{"msg": msg}] {
+	container := lib.containers[_]
+	not container.resources.limits.cpu
+	msg := sprintf("container %v has no cpu limit", [container.name])
+}
+
+violation[{"msg": msg}] {
+	container := lib.containers[_]
+	not container.resources.limits.memory
+	msg := sprintf("container %v has no memory limit", [container.name])
+}
@@ -0,0 +1,15 @@
+package zadig.manifest.privileged_container
+
+import data.zadig.manifest.lib
+
+violation[{"msg": msg}] {
+	container := lib.containers[_]
+	container.securityContext.privileged == true
+	msg := sprintf("container %v is privileged", [container.name])
+}
+
+violation[{"msg": msg}] {
+	container := lib.containers[_]
+	container.securityContext.capabilities.add[_] == "SYS_ADMIN"
+	msg := sprintf("container %v has capability SYS_ADMIN", [container.name])
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitlab"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+)
+
+type mergeGate struct {
+	codehostID int
+	namespace  string
+	repo       string
+	branch     string
+}
+
+// ProcessMergeGate makes the workflow a required check of the target branches of the triggers
+// with merge gate enabled, and stops requiring it on the branches which are no longer gated.
+// GitLab only supports the check per project, the project setting is kept once it is enabled
+// since it may be shared with other pipelines of the project.
+func ProcessMergeGate(updatedHooks, currentHooks []*models.WorkflowV4Hook, workflowName string, logger *zap.SugaredLogger) error {
+	updated, err := toMergeGates(updatedHooks)
+	if err != nil {
+		return err
+	}
+	current, _ := toMergeGates(currentHooks)
+
+	var errs *multierror.Error
+	for gate := range current {
+		if _, ok := updated[gate]; ok {
+			continue
+		}
+		if err := removeMergeGate(gate, workflowName); err != nil {
+			logger.Errorf("Failed to remove merge gate of workflow %s from %s/%s:%s, err: %s", workflowName, gate.namespace, gate.repo, gate.branch, err)
+			errs = multierror.Append(errs, err)
+		}
+	}
+	for gate := range updated {
+		if _, ok := current[gate]; ok {
+			continue
+		}
+		if err := addMergeGate(gate, workflowName); err != nil {
+			logger.Errorf("Failed to add merge gate of workflow %s to %s/%s:%s, err: %s", workflowName, gate.namespace, gate.repo, gate.branch, err)
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func toMergeGates(hooks []*models.WorkflowV4Hook) (map[mergeGate]struct{}, error) {
+	res := make(map[mergeGate]struct{})
+	for _, h := range hooks {
+		if !h.Enabled || !h.MergeGate || h.MainRepo == nil {
+			continue
+		}
+		if !hasHookEvent(h.MainRepo.Events, config.HookEventPr) {
+			return nil, fmt.Errorf("merge gate of trigger %s requires the pull request event", h.Name)
+		}
+		res[mergeGate{
+			codehostID: h.MainRepo.CodehostID,
+			namespace:  h.MainRepo.GetRepoNamespace(),
+			repo:       h.MainRepo.RepoName,
+			branch:     h.MainRepo.Branch,
+		}] = struct{}{}
+	}
+	return res, nil
+}
+
+func hasHookEvent(events []config.HookEventType, event config.HookEventType) bool {
+	for _, evt := range events {
+		if evt == event {
+			return true
+		}
+	}
+	return false
+}
+
+func addMergeGate(gate mergeGate, workflowName string) error {
+	ch, err := systemconfig.New().GetCodeHost(gate.codehostID)
+	if err != nil {
+		return err
+	}
+
+	switch ch.Type {
+	case setting.SourceFromGithub:
+		statusContext, err := githubCheckContext(gate.namespace, workflowName)
+		if err != nil {
+			return err
+		}
+		gc := github.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		return gc.AddRequiredStatusCheck(context.TODO(), gate.namespace, gate.repo, gate.branch, statusContext)
+	case setting.SourceFromGitlab:
+		gc, err := gitlab.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		if err != nil {
+			return err
+		}
+		return gc.EnablePipelineMergeCheck(gate.namespace, gate.repo)
+	default:
+		return fmt.Errorf("merge gate is not supported by codehost type %s", ch.Type)
+	}
+}
+
+func removeMergeGate(gate mergeGate, workflowName string) error {
+	ch, err := systemconfig.New().GetCodeHost(gate.codehostID)
+	if err != nil {
+		return err
+	}
+	if ch.Type != setting.SourceFromGithub {
+		return nil
+	}
+
+	statusContext, err := githubCheckContext(gate.namespace, workflowName)
+	if err != nil {
+		return err
+	}
+	gc := github.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+	return gc.RemoveRequiredStatusCheck(context.TODO(), gate.namespace, gate.repo, gate.branch, statusContext)
+}
+
+// githubCheckContext returns the name of the check published for the workflow, a check-run is
+// created if the GitHub App is installed, otherwise a commit status is created.
+func githubCheckContext(owner, workflowName string) (string, error) {
+	ghApp, err := github.GetGithubAppClientByOwner(owner)
+	if err != nil {
+		return "", err
+	}
+	if ghApp != nil {
+		return github.CheckRunName(workflowName), nil
+	}
+	return github.StatusContext(workflowName), nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	codehostrepo "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+)
+
+type indexedCollection interface {
+	GetCollectionName() string
+	Indexes() mongo.IndexView
+}
+
+// Index is an index required by the queries of a collection which is not created in its EnsureIndex.
+type Index struct {
+	Collection indexedCollection
+	Model      mongo.IndexModel
+}
+
+func requiredIndexes() []*Index {
+	return []*Index{
+		{
+			// tasks of a workflow in a status, e.g. the running tasks, ordered by time
+			Collection: commonrepo.NewworkflowTaskv4Coll(),
+			Model: mongo.IndexModel{
+				Keys: bson.D{
+					bson.E{Key: "workflow_name", Value: 1},
+					bson.E{Key: "status", Value: 1},
+					bson.E{Key: "create_time", Value: -1},
+				},
+			},
+		},
+		{
+			// codehosts are looked up by the address and the owner of the repos
+			Collection: codehostrepo.NewCodehostColl(),
+			Model: mongo.IndexModel{
+				Keys: bson.D{
+					bson.E{Key: "address", Value: 1},
+					bson.E{Key: "namespace", Value: 1},
+					bson.E{Key: "deleted_at", Value: 1},
+				},
+			},
+		},
+		{
+			Collection: codehostrepo.NewCodehostColl(),
+			Model: mongo.IndexModel{
+				Keys: bson.D{bson.E{Key: "id", Value: -1}},
+			},
+		},
+	}
+}
+
+func ensureIndexes(ctx context.Context) error {
+	for _, idx := range requiredIndexes() {
+		if _, err := idx.Collection.Indexes().CreateOne(ctx, idx.Model); err != nil {
+			return fmt.Errorf("failed to create index for %s, err: %s", idx.Collection.GetCollectionName(), err)
+		}
+	}
+	return nil
+}
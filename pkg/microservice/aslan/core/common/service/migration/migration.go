@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+
+	lockTTL          = 30 * time.Minute
+	lockPollInterval = 5 * time.Second
+)
+
+// Migration is a data migration which is applied once, it is retried at the next startup if it fails.
+type Migration struct {
+	// Version orders the migrations, it must be unique and never be changed once released.
+	Version int
+	Name    string
+	Migrate func(ctx context.Context, logger *zap.SugaredLogger) error
+}
+
+var migrations []*Migration
+
+// Register adds a migration, it should be called in init.
+func Register(m *Migration) {
+	for _, registered := range migrations {
+		if registered.Version == m.Version {
+			panic(fmt.Sprintf("migration version %d is registered twice", m.Version))
+		}
+	}
+	migrations = append(migrations, m)
+}
+
+// Run ensures the declared indexes and applies the pending migrations in order. The migrations are
+// run by one aslan instance at a time, the others wait for the lock until ctx is done.
+func Run(ctx context.Context, owner string, logger *zap.SugaredLogger) error {
+	if err := ensureIndexes(ctx); err != nil {
+		return err
+	}
+
+	lockColl := commonrepo.NewMigrationLockColl()
+	if err := waitForLock(ctx, lockColl, owner); err != nil {
+		return err
+	}
+	defer func() {
+		if err := lockColl.Release(context.Background(), owner); err != nil {
+			logger.Warnf("failed to release the migration lock, err: %s", err)
+		}
+	}()
+
+	applied, err := commonrepo.NewMigrationColl().List()
+	if err != nil {
+		return fmt.Errorf("failed to list migrations, err: %s", err)
+	}
+	for _, m := range pendingMigrations(migrations, applied) {
+		if _, err := lockColl.Acquire(ctx, owner, lockTTL); err != nil {
+			return fmt.Errorf("failed to extend the migration lock, err: %s", err)
+		}
+		if err := apply(ctx, m, owner, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitForLock(ctx context.Context, lockColl *commonrepo.MigrationLockColl, owner string) error {
+	for {
+		ok, err := lockColl.Acquire(ctx, owner, lockTTL)
+		if err != nil {
+			return fmt.Errorf("failed to acquire the migration lock, err: %s", err)
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for the migration lock")
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func apply(ctx context.Context, m *Migration, owner string, logger *zap.SugaredLogger) error {
+	logger.Infof("running migration %d: %s", m.Version, m.Name)
+	record := &models.Migration{
+		Version:    m.Version,
+		Name:       m.Name,
+		ExecutedBy: owner,
+		StartTime:  time.Now().Unix(),
+	}
+	err := m.Migrate(ctx, logger)
+	record.EndTime = time.Now().Unix()
+	record.Status = StatusSuccess
+	if err != nil {
+		record.Status = StatusFailed
+		record.Error = err.Error()
+	}
+	if upsertErr := commonrepo.NewMigrationColl().Upsert(record); upsertErr != nil {
+		logger.Errorf("failed to record migration %d, err: %s", m.Version, upsertErr)
+	}
+	if err != nil {
+		return fmt.Errorf("migration %d: %s failed, err: %s", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// pendingMigrations returns the migrations which are not applied successfully, ordered by version.
+func pendingMigrations(all []*Migration, applied []*models.Migration) []*Migration {
+	succeeded := make(map[int]bool, len(applied))
+	for _, m := range applied {
+		if m.Status == StatusSuccess {
+			succeeded[m.Version] = true
+		}
+	}
+	resp := make([]*Migration, 0)
+	for _, m := range all {
+		if !succeeded[m.Version] {
+			resp = append(resp, m)
+		}
+	}
+	sort.SliceStable(resp, func(i, j int) bool {
+		return resp[i].Version < resp[j].Version
+	})
+	return resp
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing pending migrations", func() {
+
+	It("returns the migrations not applied successfully in order", func() {
+		all := []*Migration{{Version: 3}, {Version: 1}, {Version: 2}, {Version: 4}}
+		applied := []*models.Migration{
+			{Version: 1, Status: StatusSuccess},
+			{Version: 3, Status: StatusFailed},
+		}
+
+		var versions []int
+		for _, m := range pendingMigrations(all, applied) {
+			versions = append(versions, m.Version)
+		}
+		Expect(versions).To(Equal([]int{2, 3, 4}))
+	})
+
+	It("panics on a duplicated version", func() {
+		Expect(func() { Register(&Migration{Version: 1, Name: "duplicated"}) }).To(Panic())
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	codehostrepo "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+func init() {
+	Register(&Migration{Version: 1, Name: "set the archived and deleted flags of workflow tasks", Migrate: setWorkflowTaskV4Flags})
+	Register(&Migration{Version: 2, Name: "seed the codehost id counter", Migrate: seedCodeHostCounter})
+	Register(&Migration{Version: 3, Name: "share the codehosts not bound to projects", Migrate: shareUnscopedCodeHosts})
+	Register(&Migration{Version: 4, Name: "enable the codehosts saved before they can be disabled", Migrate: enableUnmarkedCodeHosts})
+	Register(&Migration{Version: 5, Name: "set the revision of the codehosts saved before it is checked", Migrate: initCodeHostRevisions})
+}
+
+// setWorkflowTaskV4Flags sets the flags missing in the old tasks, the tasks are listed by
+// is_archived=false and is_deleted=false which does not match the missing fields.
+func setWorkflowTaskV4Flags(ctx context.Context, logger *zap.SugaredLogger) error {
+	coll := commonrepo.NewworkflowTaskv4Coll()
+	for _, field := range []string{"is_archived", "is_deleted"} {
+		res, err := coll.UpdateMany(ctx, bson.M{field: bson.M{"$exists": false}}, bson.M{"$set": bson.M{field: false}})
+		if err != nil {
+			return err
+		}
+		logger.Infof("set %s of %d workflow tasks", field, res.ModifiedCount)
+	}
+	return nil
+}
+
+// seedCodeHostCounter starts the id counter of the codehosts from the largest id, the ids were
+// allocated from the largest id before and the ones of the deleted codehosts are never reused.
+func seedCodeHostCounter(_ context.Context, logger *zap.SugaredLogger) error {
+	maxID, err := codehostrepo.NewCodehostColl().GetMaxID()
+	if err != nil {
+		return err
+	}
+	logger.Infof("seed the codehost id counter from %d", maxID)
+	return commonrepo.NewCounterColl().Seed(setting.CodeHostCounterName, int64(maxID))
+}
+
+// shareUnscopedCodeHosts keeps the codehosts not bound to any project available to all the projects,
+// only the shared ones are after the shared flag is added.
+func shareUnscopedCodeHosts(_ context.Context, logger *zap.SugaredLogger) error {
+	count, err := codehostrepo.NewCodehostColl().ShareUnscopedCodeHosts()
+	if err != nil {
+		return err
+	}
+	logger.Infof("share %d codehosts not bound to projects", count)
+	return nil
+}
+
+// enableUnmarkedCodeHosts keeps the existing codehosts usable after the enabled flag is added.
+func enableUnmarkedCodeHosts(_ context.Context, logger *zap.SugaredLogger) error {
+	count, err := codehostrepo.NewCodehostColl().EnableUnmarkedCodeHosts()
+	if err != nil {
+		return err
+	}
+	logger.Infof("enable %d codehosts saved before they can be disabled", count)
+	return nil
+}
+
+// initCodeHostRevisions starts the revisions of the existing codehosts from 1, the updates of the codehosts
+// at revision 0 could not be checked.
+func initCodeHostRevisions(_ context.Context, logger *zap.SugaredLogger) error {
+	count, err := codehostrepo.NewCodehostColl().InitCodeHostRevisions()
+	if err != nil {
+		return err
+	}
+	logger.Infof("set the revision of %d codehosts", count)
+	return nil
+}
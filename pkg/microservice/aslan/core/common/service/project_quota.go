@@ -0,0 +1,270 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+const (
+	QuotaStatusOK           = "ok"
+	QuotaStatusSoftExceeded = "soft_exceeded"
+	QuotaStatusHardExceeded = "hard_exceeded"
+
+	// projectStorageMeasureInterval is how often the artifacts of a project are measured, the cached size is used
+	// in between.
+	projectStorageMeasureInterval = time.Hour
+)
+
+// measuringProjects keeps the projects whose storage is being measured in the background.
+var measuringProjects sync.Map
+
+type QuotaUsage struct {
+	Used   int64  `json:"used"`
+	Soft   int64  `json:"soft"`
+	Hard   int64  `json:"hard"`
+	Status string `json:"status"`
+}
+
+type ProjectQuotaUsage struct {
+	ProjectName string `json:"project_name"`
+	// Month is the calendar month the build minutes are counted in, e.g. 2022-10.
+	Month             string                      `json:"month"`
+	BuildMinutes      *QuotaUsage                 `json:"build_minutes"`
+	StorageMB         *QuotaUsage                 `json:"storage_mb"`
+	Environments      *QuotaUsage                 `json:"environments"`
+	StorageMeasuredAt int64                       `json:"storage_measured_at"`
+	Overridden        bool                        `json:"overridden"`
+	Override          *commonmodels.QuotaOverride `json:"override,omitempty"`
+}
+
+// GetProjectQuotaUsage returns the consumption of the project against its quota, the finished workflow tasks
+// of the current month are counted as the build minutes. The storage is measured again if refreshStorage is
+// true, otherwise the latest measurement is used.
+func GetProjectQuotaUsage(projectName string, refreshStorage bool) (*ProjectQuotaUsage, error) {
+	quota, err := findProjectQuota(projectName)
+	if err != nil {
+		return nil, err
+	}
+	if refreshStorage {
+		if err := updateProjectStorage(quota); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	resp := &ProjectQuotaUsage{
+		ProjectName:       projectName,
+		Month:             now.Format("2006-01"),
+		StorageMeasuredAt: quota.StorageMeasuredAt,
+		Overridden:        quota.Overridden(),
+		Override:          quota.Override,
+	}
+	minutes, err := countBuildMinutes(projectName, now)
+	if err != nil {
+		return nil, err
+	}
+	resp.BuildMinutes = newQuotaUsage(minutes, quota.BuildMinutes)
+	resp.StorageMB = newQuotaUsage(quota.StorageBytes>>20, quota.StorageMB)
+	envs, err := commonrepo.NewProductColl().Count(projectName)
+	if err != nil {
+		return nil, err
+	}
+	resp.Environments = newQuotaUsage(int64(envs), quota.Environments)
+	return resp, nil
+}
+
+// CheckWorkflowTaskQuota rejects the new workflow tasks of the project if it has used up the build minutes of the
+// month or the storage, a soft limit only logs a warning.
+func CheckWorkflowTaskQuota(projectName string, log *zap.SugaredLogger) error {
+	quota, err := commonrepo.NewProjectQuotaColl().Find(projectName)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Errorf("failed to find the quota of project %s, err: %s", projectName, err)
+		}
+		return nil
+	}
+	if quota.StorageMeasuredAt < time.Now().Add(-projectStorageMeasureInterval).Unix() {
+		go refreshProjectStorage(quota, log)
+	}
+
+	if limited(quota.BuildMinutes) {
+		minutes, err := countBuildMinutes(projectName, time.Now())
+		if err != nil {
+			log.Errorf("failed to count the build minutes of project %s, err: %s", projectName, err)
+			return nil
+		}
+		if err := checkQuotaLimit(quota, "build minutes", minutes, 0, quota.BuildMinutes, log); err != nil {
+			return err
+		}
+	}
+	return checkQuotaLimit(quota, "storage MB", quota.StorageBytes>>20, 0, quota.StorageMB, log)
+}
+
+// CheckEnvironmentQuota rejects the creation of count environments of the project if they exceed the hard limit.
+func CheckEnvironmentQuota(projectName string, count int, log *zap.SugaredLogger) error {
+	quota, err := commonrepo.NewProjectQuotaColl().Find(projectName)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Errorf("failed to find the quota of project %s, err: %s", projectName, err)
+		}
+		return nil
+	}
+	if !limited(quota.Environments) {
+		return nil
+	}
+	envs, err := commonrepo.NewProductColl().Count(projectName)
+	if err != nil {
+		log.Errorf("failed to count the envs of project %s, err: %s", projectName, err)
+		return nil
+	}
+	return checkQuotaLimit(quota, "environments", int64(envs), int64(count), quota.Environments, log)
+}
+
+// checkQuotaLimit checks whether used+requested exceeds the limit, nothing is requested for the consumptions which
+// are only known afterwards, e.g. the build minutes, so they are rejected once the limit is reached.
+func checkQuotaLimit(quota *commonmodels.ProjectQuota, resource string, used, requested int64, limit *commonmodels.QuotaLimit, log *zap.SugaredLogger) error {
+	if !limited(limit) {
+		return nil
+	}
+	exceeded := func(max int64) bool {
+		if requested > 0 {
+			return used+requested > max
+		}
+		return used >= max
+	}
+
+	if limit.Hard > 0 && exceeded(limit.Hard) {
+		if quota.Overridden() {
+			log.Warnf("the hard limit %d of %s of project %s is exceeded but overridden by %s until %s: %s", limit.Hard, resource, quota.ProjectName,
+				quota.Override.CreatedBy, time.Unix(quota.Override.Until, 0).Format(time.RFC3339), quota.Override.Reason)
+			return nil
+		}
+		return e.ErrProjectQuotaExceeded.AddDesc(fmt.Sprintf("project %s has used %d of the %d %s", quota.ProjectName, used, limit.Hard, resource))
+	}
+	if limit.Soft > 0 && exceeded(limit.Soft) {
+		log.Warnf("project %s has used %d %s, exceeding the soft limit %d", quota.ProjectName, used, resource, limit.Soft)
+	}
+	return nil
+}
+
+func newQuotaUsage(used int64, limit *commonmodels.QuotaLimit) *QuotaUsage {
+	usage := &QuotaUsage{Used: used, Status: QuotaStatusOK}
+	if limit == nil {
+		return usage
+	}
+	usage.Soft, usage.Hard = limit.Soft, limit.Hard
+	switch {
+	case limit.Hard > 0 && used >= limit.Hard:
+		usage.Status = QuotaStatusHardExceeded
+	case limit.Soft > 0 && used >= limit.Soft:
+		usage.Status = QuotaStatusSoftExceeded
+	}
+	return usage
+}
+
+func limited(limit *commonmodels.QuotaLimit) bool {
+	return limit != nil && (limit.Soft > 0 || limit.Hard > 0)
+}
+
+// findProjectQuota returns an empty quota if the project is not limited.
+func findProjectQuota(projectName string) (*commonmodels.ProjectQuota, error) {
+	quota, err := commonrepo.NewProjectQuotaColl().Find(projectName)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &commonmodels.ProjectQuota{ProjectName: projectName}, nil
+	}
+	return quota, err
+}
+
+// countBuildMinutes sums up the running minutes of the finished workflow tasks of the project in the month.
+func countBuildMinutes(projectName string, now time.Time) (int64, error) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	usages, err := commonrepo.NewworkflowTaskv4Coll().StatUsageByProjects([]string{projectName}, monthStart.Unix(), 0)
+	if err != nil {
+		return 0, err
+	}
+	var seconds int64
+	for _, usage := range usages {
+		seconds += usage.Duration
+	}
+	return seconds / 60, nil
+}
+
+func refreshProjectStorage(quota *commonmodels.ProjectQuota, log *zap.SugaredLogger) {
+	if _, loaded := measuringProjects.LoadOrStore(quota.ProjectName, struct{}{}); loaded {
+		return
+	}
+	defer measuringProjects.Delete(quota.ProjectName)
+
+	if err := updateProjectStorage(quota); err != nil {
+		log.Errorf("failed to measure the storage of project %s, err: %s", quota.ProjectName, err)
+	}
+}
+
+// updateProjectStorage measures the storage of the project, it is saved if the project has a quota.
+func updateProjectStorage(quota *commonmodels.ProjectQuota) error {
+	size, err := measureProjectStorage(quota.ProjectName)
+	if err != nil {
+		return err
+	}
+	quota.StorageBytes, quota.StorageMeasuredAt = size, time.Now().Unix()
+	return commonrepo.NewProjectQuotaColl().UpdateStorage(quota.ProjectName, size)
+}
+
+// measureProjectStorage sums up the size of the artifacts of the workflows of the project in the default object
+// storage, they are kept under the names of the workflows.
+func measureProjectStorage(projectName string) (int64, error) {
+	store, err := s3.FindDefaultS3()
+	if err != nil {
+		return 0, err
+	}
+	forcedPathStyle := true
+	if store.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Insecure, forcedPathStyle)
+	if err != nil {
+		return 0, err
+	}
+
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, workflow := range workflows {
+		workflowSize, err := client.SumSize(store.Bucket, store.GetObjectPath(workflow.Name+"/"))
+		if err != nil {
+			return 0, err
+		}
+		size += workflowSize
+	}
+	return size, nil
+}
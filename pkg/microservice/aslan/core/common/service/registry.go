@@ -35,6 +35,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/crypto"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/util"
@@ -151,6 +152,21 @@ func ListRegistryNamespaces(encryptedKey string, getRealCredential bool, log *za
 	return resp, nil
 }
 
+// FindImageRegistry returns the integrated registry the image is pushed to, it is nil if the image is
+// from the other registries.
+func FindImageRegistry(image string, registries []*models.RegistryNamespace) *models.RegistryNamespace {
+	for _, reg := range registries {
+		prefix := util.TrimURLScheme(reg.RegAddr) + "/"
+		if reg.Namespace != "" {
+			prefix += reg.Namespace + "/"
+		}
+		if strings.HasPrefix(image, prefix) {
+			return reg
+		}
+	}
+	return nil
+}
+
 func EnsureDefaultRegistrySecret(namespace string, registryId string, kubeClient client.Client, log *zap.SugaredLogger) error {
 	var reg *models.RegistryNamespace
 	var err error
@@ -179,6 +195,9 @@ func EnsureDefaultRegistrySecret(namespace string, registryId string, kubeClient
 		log.Errorf("[%s] CreateDockerSecret error: %s", namespace, err)
 		return e.ErrUpdateSecret.AddDesc(e.CreateDefaultRegistryErrMsg)
 	}
+	if err := kube.AttachImagePullSecrets(namespace, kube.DefaultServiceAccount, []string{setting.DefaultImagePullSecret}, kubeClient); err != nil {
+		log.Warnf("[%s] failed to attach the registry secret to the default service account: %s", namespace, err)
+	}
 
 	return nil
 }
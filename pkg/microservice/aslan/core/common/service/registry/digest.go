@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// PinImageDigest resolves the tag of the image to the digest by the integrated registry the image belongs to,
+// the image is returned in the form of repo:tag@digest. Images already pinned are returned as they are.
+func PinImageDigest(image string, log *zap.SugaredLogger) (pinned string, imageDigest string, err error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid image %s: %s", image, err)
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		return image, digested.Digest().String(), nil
+	}
+	named = reference.TagNameOnly(named)
+	tag := named.(reference.Tagged).Tag()
+
+	registries, err := mongodb.NewRegistryNamespaceColl().FindAll(&mongodb.FindRegOps{})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list registries: %s", err)
+	}
+	reg, repoName := matchRegistry(reference.Domain(named), reference.Path(named), registries)
+	if reg == nil {
+		return "", "", fmt.Errorf("no registry is integrated for image %s", image)
+	}
+
+	var regService Service
+	if reg.AdvancedSetting != nil {
+		regService = NewV2Service(reg.RegProvider, reg.AdvancedSetting.TLSEnabled, reg.AdvancedSetting.TLSCert)
+	} else {
+		regService = NewV2Service(reg.RegProvider, true, "")
+	}
+	imageDigest, err = regService.GetImageDigest(GetRepoImageDetailOption{
+		Endpoint: Endpoint{
+			Addr:      reg.RegAddr,
+			Ak:        reg.AccessKey,
+			Sk:        reg.SecretKey,
+			Namespace: reg.Namespace,
+			Region:    reg.Region,
+		},
+		Image: repoName,
+		Tag:   tag,
+	}, log)
+	if err != nil {
+		return "", "", err
+	}
+
+	withDigest, err := reference.WithDigest(named, digest.Digest(imageDigest))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid digest %s of image %s: %s", imageDigest, image, err)
+	}
+	return reference.FamiliarString(withDigest), imageDigest, nil
+}
+
+// matchRegistry finds the registry with the longest namespace the image path belongs to, the path of the
+// image relative to the namespace is returned as well.
+func matchRegistry(domain, path string, registries []*commonmodels.RegistryNamespace) (*commonmodels.RegistryNamespace, string) {
+	var (
+		matched  *commonmodels.RegistryNamespace
+		repoName string
+	)
+	for _, reg := range registries {
+		host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(reg.RegAddr, "https://"), "http://"), "/")
+		if host != domain || reg.Namespace == "" || !strings.HasPrefix(path, reg.Namespace+"/") {
+			continue
+		}
+		if matched == nil || len(reg.Namespace) > len(matched.Namespace) {
+			matched, repoName = reg, strings.TrimPrefix(path, reg.Namespace+"/")
+		}
+	}
+	return matched, repoName
+}
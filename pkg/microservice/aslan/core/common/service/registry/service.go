@@ -35,6 +35,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/docker/distribution"
+	_ "github.com/docker/distribution/manifest/manifestlist"
+	_ "github.com/docker/distribution/manifest/ocischema"
 	"github.com/docker/distribution/manifest/schema2"
 	"github.com/docker/distribution/reference"
 	"github.com/docker/distribution/registry/client"
@@ -79,6 +81,11 @@ type GetRepoImageDetailOption struct {
 type Service interface {
 	ListRepoImages(option ListRepoImagesOption, log *zap.SugaredLogger) (*ReposResp, error)
 	GetImageInfo(option GetRepoImageDetailOption, log *zap.SugaredLogger) (*commonmodels.DeliveryImage, error)
+	// CheckCredential returns an error if the registry can not be accessed with the credential of the endpoint.
+	CheckCredential(ep Endpoint, log *zap.SugaredLogger) error
+	// GetImageDigest returns the digest the tag currently points to, which is the digest of the manifest
+	// list for multi-arch images.
+	GetImageDigest(option GetRepoImageDetailOption, log *zap.SugaredLogger) (string, error)
 }
 
 func NewV2Service(provider string, tlsEnabled bool, tlsCert string) Service {
@@ -226,13 +233,42 @@ func (c *authClient) listTags(repoName string) (tags []string, err error) {
 	return
 }
 
+// ping requests the api version check with the credential, no scope is requested so that it
+// passes as long as the credential is valid.
+func (c *authClient) ping() error {
+	creds := registry.NewStaticCredentialStore(&types.AuthConfig{
+		Username:      c.endpoint.Ak,
+		Password:      c.endpoint.Sk,
+		ServerAddress: c.endpoint.Addr,
+	})
+
+	tokenHandler := auth.NewTokenHandlerWithOptions(auth.TokenHandlerOptions{
+		Transport:   c.tr,
+		Credentials: creds,
+		ClientID:    registry.AuthClientID,
+	})
+	modifier := auth.NewAuthorizer(c.cm, tokenHandler, auth.NewBasicHandler(creds))
+	httpClient := &http.Client{Transport: transport.NewTransport(c.tr, modifier), Timeout: 30 * time.Second}
+
+	resp, err := httpClient.Get(strings.TrimSuffix(c.endpointURL.String(), "/") + "/v2/")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry %s responded with status %d", c.endpoint.Addr, resp.StatusCode)
+	}
+	return nil
+}
+
 type containerInfo struct {
-	Architecture  string        `json:"architecture"`
-	Created       string        `json:"created"`
-	Os            string        `json:"os"`
-	Digest        digest.Digest `json:"-"`
-	Size          int64         `json:"-"`
-	DockerVersion string        `json:"docker_version"`
+	Architecture  string                             `json:"architecture"`
+	Created       string                             `json:"created"`
+	Os            string                             `json:"os"`
+	Digest        digest.Digest                      `json:"-"`
+	Size          int64                              `json:"-"`
+	Layers        []*commonmodels.DeliveryImageLayer `json:"-"`
+	DockerVersion string                             `json:"docker_version"`
 }
 
 func (c *authClient) getImageInfo(repoName, tag string) (ci *containerInfo, err error) {
@@ -277,6 +313,7 @@ func (c *authClient) getImageInfo(repoName, tag string) (ci *containerInfo, err
 
 			for _, layer := range v2.Manifest.Layers {
 				ci.Size += layer.Size
+				ci.Layers = append(ci.Layers, &commonmodels.DeliveryImageLayer{Digest: layer.Digest.String(), Size: layer.Size})
 			}
 			return
 		}
@@ -308,9 +345,28 @@ func (s *v2RegistryService) GetImageInfo(option GetRepoImageDetailOption, log *z
 		ImageDigest:   ci.Digest.String(),
 		ImageSize:     ci.Size,
 		DockerVersion: ci.DockerVersion,
+		Layers:        ci.Layers,
 	}, nil
 }
 
+func (s *v2RegistryService) GetImageDigest(option GetRepoImageDetailOption, log *zap.SugaredLogger) (string, error) {
+	cli, err := s.createClient(option.Endpoint, log)
+	if err != nil {
+		return "", err
+	}
+
+	img := strings.Join([]string{option.Namespace, option.Image}, "/")
+	repo, err := cli.getRepository(img)
+	if err != nil {
+		return "", err
+	}
+	desc, err := repo.Tags(cli.ctx).Get(cli.ctx, option.Tag)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get digest of %s:%s", img, option.Tag)
+	}
+	return desc.Digest.String(), nil
+}
+
 type ReverseStringSlice []string
 
 // Len is the number of elements in the collection.
@@ -329,6 +385,14 @@ func (rss ReverseStringSlice) Swap(i, j int) {
 	rss[i], rss[j] = rss[j], rss[i]
 }
 
+func (s *v2RegistryService) CheckCredential(ep Endpoint, log *zap.SugaredLogger) error {
+	cli, err := s.createClient(ep, log)
+	if err != nil {
+		return err
+	}
+	return cli.ping()
+}
+
 func (s *v2RegistryService) ListRepoImages(option ListRepoImagesOption, log *zap.SugaredLogger) (resp *ReposResp, err error) {
 	cli, err := s.createClient(option.Endpoint, log)
 	if err != nil {
@@ -454,6 +518,11 @@ func (s *swrService) ListRepoImages(option ListRepoImagesOption, log *zap.Sugare
 
 }
 
+func (s *swrService) CheckCredential(ep Endpoint, log *zap.SugaredLogger) error {
+	_, err := s.createClient(ep).ListNamespaces(&model.ListNamespacesRequest{})
+	return err
+}
+
 func (s *swrService) GetImageInfo(option GetRepoImageDetailOption, log *zap.SugaredLogger) (di *commonmodels.DeliveryImage, err error) {
 	swrCli := s.createClient(option.Endpoint)
 
@@ -477,6 +546,10 @@ func (s *swrService) GetImageInfo(option GetRepoImageDetailOption, log *zap.Suga
 	return &commonmodels.DeliveryImage{}, nil
 }
 
+func (s *swrService) GetImageDigest(option GetRepoImageDetailOption, log *zap.SugaredLogger) (string, error) {
+	return getImageDigestFromInfo(s, option, log)
+}
+
 type ecrService struct {
 }
 
@@ -494,6 +567,15 @@ func (s *ecrService) getECRService(ep Endpoint, log *zap.SugaredLogger) (*ecr.EC
 	return ecr.New(sess), nil
 }
 
+func (s *ecrService) CheckCredential(ep Endpoint, log *zap.SugaredLogger) error {
+	svc, err := s.getECRService(ep, log)
+	if err != nil {
+		return err
+	}
+	_, err = svc.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{})
+	return err
+}
+
 func (s *ecrService) ListRepoImages(option ListRepoImagesOption, log *zap.SugaredLogger) (resp *ReposResp, err error) {
 	svc, err := s.getECRService(option.Endpoint, log)
 	if err != nil {
@@ -577,3 +659,18 @@ func (s *ecrService) GetImageInfo(option GetRepoImageDetailOption, log *zap.Suga
 	}
 	return &commonmodels.DeliveryImage{}, nil
 }
+
+func (s *ecrService) GetImageDigest(option GetRepoImageDetailOption, log *zap.SugaredLogger) (string, error) {
+	return getImageDigestFromInfo(s, option, log)
+}
+
+func getImageDigestFromInfo(s Service, option GetRepoImageDetailOption, log *zap.SugaredLogger) (string, error) {
+	di, err := s.GetImageInfo(option, log)
+	if err != nil {
+		return "", err
+	}
+	if di.ImageDigest == "" {
+		return "", fmt.Errorf("image %s:%s is not found", option.Image, option.Tag)
+	}
+	return di.ImageDigest, nil
+}
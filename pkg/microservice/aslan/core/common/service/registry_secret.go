@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+)
+
+// registrySecretRotateInterval is less than the 12 hours the tokens of aws ecr are valid for.
+const registrySecretRotateInterval = 6 * time.Hour
+
+// registrySecretSyncLock avoids syncing the secrets of all the envs more than once at a time.
+var registrySecretSyncLock sync.Mutex
+
+// SyncEnvRegistrySecrets creates or updates the image pull secrets of the integrated registries in the
+// namespace of the env, and attaches them to the default service account of the namespace.
+func SyncEnvRegistrySecrets(env *models.Product, log *zap.SugaredLogger) error {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return fmt.Errorf("failed to get kube client of cluster %s: %s", env.ClusterID, err)
+	}
+	if err := EnsureDefaultRegistrySecret(env.Namespace, env.RegistryID, kubeClient, log); err != nil {
+		return err
+	}
+
+	registries, err := ListRegistryNamespaces("", true, log)
+	if err != nil {
+		return err
+	}
+	secretNames := []string{setting.DefaultImagePullSecret}
+	for _, reg := range registries {
+		// the default secret is the one of the registry of the env.
+		if reg.IsDefault {
+			continue
+		}
+		secretName, err := kube.GenRegistrySecretName(reg)
+		if err != nil {
+			return err
+		}
+		if err := kube.CreateOrUpdateRegistrySecret(env.Namespace, reg, false, kubeClient); err != nil {
+			return fmt.Errorf("failed to update secret %s in namespace %s: %s", secretName, env.Namespace, err)
+		}
+		secretNames = append(secretNames, secretName)
+	}
+	return kube.AttachImagePullSecrets(env.Namespace, kube.DefaultServiceAccount, secretNames, kubeClient)
+}
+
+// SyncRegistrySecrets repairs the image pull secrets of all the envs, it is called when the registries
+// are changed so that the credentials in the namespaces are never stale.
+func SyncRegistrySecrets(log *zap.SugaredLogger) {
+	registrySecretSyncLock.Lock()
+	defer registrySecretSyncLock.Unlock()
+
+	envs, err := mongodb.NewProductColl().List(&mongodb.ProductListOptions{ExcludeStatus: []string{setting.ProductStatusDeleting}})
+	if err != nil {
+		log.Errorf("failed to list envs to sync the registry secrets, err: %s", err)
+		return
+	}
+	projects := make(map[string]bool)
+	for _, env := range envs {
+		hasNamespace, ok := projects[env.ProductName]
+		if !ok {
+			project, err := templaterepo.NewProductColl().Find(env.ProductName)
+			if err != nil {
+				log.Warnf("failed to find project %s, err: %s", env.ProductName, err)
+				continue
+			}
+			// the envs of the projects on cloud hosts have no namespace.
+			hasNamespace = project.ProductFeature == nil || project.ProductFeature.BasicFacility != setting.BasicFacilityCVM
+			projects[env.ProductName] = hasNamespace
+		}
+		if !hasNamespace {
+			continue
+		}
+		if err := SyncEnvRegistrySecrets(env, log); err != nil {
+			log.Warnf("failed to sync the registry secrets of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+}
+
+// StartRegistrySecretRotation rotates the image pull secrets periodically if any of the registries
+// issues short-lived credentials, e.g. aws ecr.
+func StartRegistrySecretRotation(ctx context.Context, log *zap.SugaredLogger) {
+	ticker := time.NewTicker(registrySecretRotateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			registries, err := mongodb.NewRegistryNamespaceColl().FindAll(&mongodb.FindRegOps{})
+			if err != nil {
+				log.Errorf("failed to list registries, err: %s", err)
+				continue
+			}
+			for _, reg := range registries {
+				if reg.RegProvider == config.RegistryTypeAWS {
+					SyncRegistrySecrets(log)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+const defaultAgentCommand = "zadig-runner-agent"
+
+type ec2Provisioner struct {
+	runner *commonmodels.Runner
+	client *ec2.EC2
+}
+
+func newEC2Provisioner(r *commonmodels.Runner, sess *session.Session) *ec2Provisioner {
+	return &ec2Provisioner{runner: r, client: ec2.New(sess)}
+}
+
+func (p *ec2Provisioner) Provision(ctx context.Context, job *commonmodels.RunnerJob) (string, error) {
+	cfg := p.runner.EC2
+	input := &ec2.RunInstancesInput{
+		ImageId:      aws.String(cfg.ImageID),
+		InstanceType: aws.String(cfg.InstanceType),
+		MinCount:     aws.Int64(1),
+		MaxCount:     aws.Int64(1),
+		UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(p.userData(job)))),
+		// the instance is gone once the agent powers it off, even if it is not released.
+		InstanceInitiatedShutdownBehavior: aws.String(ec2.ShutdownBehaviorTerminate),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags: []*ec2.Tag{
+				{Key: aws.String("Name"), Value: aws.String(instanceName(p.runner, job))},
+				{Key: aws.String("zadig-runner"), Value: aws.String(p.runner.Name)},
+			},
+		}},
+	}
+	if cfg.SubnetID != "" {
+		input.SubnetId = aws.String(cfg.SubnetID)
+	}
+	if len(cfg.SecurityGroupIDs) > 0 {
+		input.SecurityGroupIds = aws.StringSlice(cfg.SecurityGroupIDs)
+	}
+	if cfg.KeyName != "" {
+		input.KeyName = aws.String(cfg.KeyName)
+	}
+	if cfg.IAMInstanceProfile != "" {
+		input.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{Name: aws.String(cfg.IAMInstanceProfile)}
+	}
+
+	out, err := p.client.RunInstancesWithContext(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to run ec2 instance: %s", err)
+	}
+	if len(out.Instances) == 0 {
+		return "", fmt.Errorf("no ec2 instance is launched")
+	}
+	return aws.StringValue(out.Instances[0].InstanceId), nil
+}
+
+func (p *ec2Provisioner) Release(ctx context.Context, instanceID string) error {
+	_, err := p.client.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{instanceID})})
+	return err
+}
+
+// userData starts the agent when the instance boots, the instance is powered off after the agent exits.
+func (p *ec2Provisioner) userData(job *commonmodels.RunnerJob) string {
+	command := p.runner.EC2.AgentCommand
+	if command == "" {
+		command = defaultAgentCommand
+	}
+	lines := []string{"#!/bin/sh"}
+	for _, env := range agentEnvs(p.runner, job) {
+		lines = append(lines, fmt.Sprintf("export %s=%s", env[0], shellQuote(env[1])))
+	}
+	lines = append(lines, command, "shutdown -h now", "")
+	return strings.Join(lines, "\n")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecs"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+type ecsProvisioner struct {
+	runner *commonmodels.Runner
+	client *ecs.ECS
+}
+
+func newECSProvisioner(r *commonmodels.Runner, sess *session.Session) *ecsProvisioner {
+	return &ecsProvisioner{runner: r, client: ecs.New(sess)}
+}
+
+func (p *ecsProvisioner) Provision(ctx context.Context, job *commonmodels.RunnerJob) (string, error) {
+	cfg := p.runner.ECS
+	var envs []*ecs.KeyValuePair
+	for _, env := range agentEnvs(p.runner, job) {
+		envs = append(envs, &ecs.KeyValuePair{Name: aws.String(env[0]), Value: aws.String(env[1])})
+	}
+	input := &ecs.RunTaskInput{
+		Cluster:        aws.String(cfg.Cluster),
+		TaskDefinition: aws.String(cfg.TaskDefinition),
+		Count:          aws.Int64(1),
+		// the value is limited to 36 characters
+		StartedBy: aws.String("zadig-" + job.ID.Hex()),
+		Overrides: &ecs.TaskOverride{
+			ContainerOverrides: []*ecs.ContainerOverride{{
+				Name:        aws.String(cfg.ContainerName),
+				Environment: envs,
+			}},
+		},
+	}
+	if cfg.LaunchType != "" {
+		input.LaunchType = aws.String(cfg.LaunchType)
+	}
+	if len(cfg.Subnets) > 0 {
+		assignPublicIP := ecs.AssignPublicIpDisabled
+		if cfg.AssignPublicIP {
+			assignPublicIP = ecs.AssignPublicIpEnabled
+		}
+		input.NetworkConfiguration = &ecs.NetworkConfiguration{
+			AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+				Subnets:        aws.StringSlice(cfg.Subnets),
+				SecurityGroups: aws.StringSlice(cfg.SecurityGroups),
+				AssignPublicIp: aws.String(assignPublicIP),
+			},
+		}
+	}
+
+	out, err := p.client.RunTaskWithContext(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to run ecs task: %s", err)
+	}
+	if len(out.Failures) > 0 {
+		return "", fmt.Errorf("failed to run ecs task: %s", aws.StringValue(out.Failures[0].Reason))
+	}
+	if len(out.Tasks) == 0 {
+		return "", fmt.Errorf("no ecs task is started")
+	}
+	return aws.StringValue(out.Tasks[0].TaskArn), nil
+}
+
+func (p *ecsProvisioner) Release(ctx context.Context, instanceID string) error {
+	_, err := p.client.StopTaskWithContext(ctx, &ecs.StopTaskInput{
+		Cluster: aws.String(p.runner.ECS.Cluster),
+		Task:    aws.String(instanceID),
+		Reason:  aws.String("the zadig job is done"),
+	})
+	return err
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+const (
+	// AgentAPIPath is the path of the apis the agents talk to.
+	AgentAPIPath = "/api/aslan/runner/agent"
+	// LeaseDuration is how long a job is held by an agent without renewing the lease, the job fails
+	// once the lease expires.
+	LeaseDuration = time.Minute
+)
+
+// Provisioner launches an ephemeral machine running the agent for a job, the machine is released after
+// the job is done.
+type Provisioner interface {
+	// Provision returns the id of the launched instance.
+	Provision(ctx context.Context, job *commonmodels.RunnerJob) (string, error)
+	Release(ctx context.Context, instanceID string) error
+}
+
+// NewProvisioner returns nil for the static and macOS runners, their agents are started by the users.
+func NewProvisioner(r *commonmodels.Runner) (Provisioner, error) {
+	switch r.Type {
+	case config.RunnerTypeStatic, config.RunnerTypeMacOS, "":
+		return nil, nil
+	case config.RunnerTypeEC2:
+		if r.EC2 == nil {
+			return nil, fmt.Errorf("the ec2 config of runner %s is empty", r.Name)
+		}
+		sess, err := newAWSSession(&r.EC2.RunnerAWSCredential)
+		if err != nil {
+			return nil, err
+		}
+		return newEC2Provisioner(r, sess), nil
+	case config.RunnerTypeECS:
+		if r.ECS == nil {
+			return nil, fmt.Errorf("the ecs config of runner %s is empty", r.Name)
+		}
+		sess, err := newAWSSession(&r.ECS.RunnerAWSCredential)
+		if err != nil {
+			return nil, err
+		}
+		return newECSProvisioner(r, sess), nil
+	}
+	return nil, fmt.Errorf("unsupported runner type %s", r.Type)
+}
+
+func newAWSSession(cred *commonmodels.RunnerAWSCredential) (*session.Session, error) {
+	cfg := &aws.Config{Region: aws.String(cred.Region)}
+	// the credentials of the instance role of aslan are used if the keys are not set.
+	if cred.AccessKeyID != "" {
+		cfg.Credentials = credentials.NewStaticCredentials(cred.AccessKeyID, cred.SecretAccessKey, "")
+	}
+	return session.NewSession(cfg)
+}
+
+// agentEnvs are the environment variables the ephemeral agent of the job is configured by.
+func agentEnvs(r *commonmodels.Runner, job *commonmodels.RunnerJob) [][2]string {
+	return [][2]string{
+		{runner.ServerURLEnv, strings.TrimSuffix(configbase.SystemAddress(), "/") + AgentAPIPath},
+		{runner.RunnerNameEnv, r.Name},
+		{runner.RunnerTokenEnv, r.Token},
+		{runner.JobIDEnv, job.ID.Hex()},
+	}
+}
+
+func instanceName(r *commonmodels.Runner, job *commonmodels.RunnerJob) string {
+	return fmt.Sprintf("zadig-runner-%s-%s", r.Name, job.ID.Hex())
+}
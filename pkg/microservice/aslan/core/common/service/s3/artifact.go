@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"path"
+	"strings"
+)
+
+// UploadWorkflowJobArtifact saves the artifact uploaded by the agent running the job outside kubernetes
+// to the default storage, the object key is returned.
+func UploadWorkflowJobArtifact(workflowName, jobName string, taskID int64, name, src string) (string, error) {
+	storage, client, err := newWorkflowTaskStorage(workflowName, taskID, "artifacts")
+	if err != nil {
+		return "", err
+	}
+	// the name is relative to the artifacts dir of the job, it can not escape from it.
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	objectKey := storage.GetObjectPath(path.Join(strings.Replace(strings.ToLower(jobName), "_", "-", -1), name))
+	if err := client.Upload(storage.Bucket, src, objectKey); err != nil {
+		return "", err
+	}
+	return objectKey, nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/logstream"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+// max number of keys in a DeleteObjects request
+const deleteObjectsBatchSize = 1000
+
+// JobLog is the log of a workflow job in the default object storage. The log is uploaded as
+// chunks while the job is running and is saved as a single file when the job is done.
+type JobLog struct {
+	client      *s3tool.Client
+	bucket      string
+	objectKey   string
+	chunkPrefix string
+}
+
+func NewWorkflowJobLog(workflowName, jobName string, taskID int64) (*JobLog, error) {
+	storage, client, err := newWorkflowTaskStorage(workflowName, taskID, "log")
+	if err != nil {
+		return nil, err
+	}
+
+	objectKey := storage.GetObjectPath(strings.Replace(strings.ToLower(jobName), "_", "-", -1) + ".log")
+	return &JobLog{
+		client:      client,
+		bucket:      storage.Bucket,
+		objectKey:   objectKey,
+		chunkPrefix: objectKey + ".chunks",
+	}, nil
+}
+
+// newWorkflowTaskStorage returns the default storage whose subfolder is the folder of the workflow task.
+func newWorkflowTaskStorage(workflowName string, taskID int64, folder string) (*S3, *s3tool.Client, error) {
+	storage, err := FindDefaultS3()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find default s3 storage: %s", err)
+	}
+	if storage.Subfolder != "" {
+		storage.Subfolder = fmt.Sprintf("%s/%s/%d/%s", storage.Subfolder, strings.ToLower(workflowName), taskID, folder)
+	} else {
+		storage.Subfolder = fmt.Sprintf("%s/%d/%s", strings.ToLower(workflowName), taskID, folder)
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create s3 client: %s", err)
+	}
+	return storage, client, nil
+}
+
+// ChunkWriter returns the writer uploading the log of the running job.
+func (l *JobLog) ChunkWriter() *logstream.Writer {
+	return logstream.NewWriter(&logStore{client: l.client, bucket: l.bucket}, l.chunkPrefix, logstream.DefaultChunkSize)
+}
+
+// AppendChunk saves the data pushed by an agent running the job outside kubernetes at the offset, the
+// size of the log received is returned. The data is skipped if it has been received, e.g. the request
+// is retried, and an error is returned if the offset is beyond the end of the log.
+func (l *JobLog) AppendChunk(offset int64, data []byte) (size int64, appended bool, err error) {
+	store := &logStore{client: l.client, bucket: l.bucket}
+	chunks, err := logstream.Open(store, l.chunkPrefix)
+	if err != nil {
+		return 0, false, err
+	}
+	size = chunks.Size()
+	if offset > size {
+		return size, false, fmt.Errorf("the log is received until %d, the chunk at %d is not continuous", size, offset)
+	}
+	if offset < size || len(data) == 0 {
+		return size, false, nil
+	}
+	if err := logstream.PutChunk(store, l.chunkPrefix, offset, data); err != nil {
+		return size, false, err
+	}
+	return size + int64(len(data)), true, nil
+}
+
+// Upload saves the complete log and removes the chunks uploaded during the execution.
+func (l *JobLog) Upload(src string) error {
+	if err := l.client.Upload(l.bucket, src, l.objectKey); err != nil {
+		return err
+	}
+	return l.DeleteChunks()
+}
+
+// DeleteChunks removes the chunks uploaded during the execution.
+func (l *JobLog) DeleteChunks() error {
+	keys, err := l.client.ListAllFiles(l.bucket, l.chunkPrefix+"/")
+	if err != nil {
+		return err
+	}
+	for len(keys) > 0 {
+		n := deleteObjectsBatchSize
+		if n > len(keys) {
+			n = len(keys)
+		}
+		if err := l.client.DeleteObjects(l.bucket, keys[:n]); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// JobLogReader reads the saved log, or the chunks uploaded so far if the job is still running.
+type JobLogReader struct {
+	io.ReaderAt
+	Size int64
+	// Completed is true if the log of the finished job is read.
+	Completed bool
+
+	log *JobLog
+}
+
+func (l *JobLog) Open() (*JobLogReader, error) {
+	size, exists, err := l.client.GetObjectSize(l.bucket, l.objectKey)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return &JobLogReader{ReaderAt: &objectReaderAt{JobLog: l}, Size: size, Completed: true, log: l}, nil
+	}
+
+	chunks, err := logstream.Open(&logStore{client: l.client, bucket: l.bucket}, l.chunkPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &JobLogReader{ReaderAt: chunks, Size: chunks.Size(), log: l}, nil
+}
+
+// Stream returns the whole content, the saved log is streamed in a single request.
+func (r *JobLogReader) Stream() (io.ReadCloser, error) {
+	if !r.Completed {
+		return io.NopCloser(io.NewSectionReader(r.ReaderAt, 0, r.Size)), nil
+	}
+	obj, err := r.log.client.GetFile(r.log.bucket, r.log.objectKey, &s3tool.DownloadOption{RetryNum: 3})
+	if err != nil {
+		return nil, err
+	}
+	return obj.Body, nil
+}
+
+type objectReaderAt struct {
+	*JobLog
+}
+
+func (o *objectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	data, err := o.client.GetRange(o.bucket, o.objectKey, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// logStore saves the log chunks in a bucket.
+type logStore struct {
+	client *s3tool.Client
+	bucket string
+}
+
+func (s *logStore) Put(key string, body []byte) error {
+	return s.client.UploadBytes(s.bucket, key, body)
+}
+
+func (s *logStore) Get(key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetFile(s.bucket, key, &s3tool.DownloadOption{RetryNum: 3})
+	if err != nil {
+		return nil, err
+	}
+	return obj.Body, nil
+}
+
+func (s *logStore) List(prefix string) ([]string, error) {
+	return s.client.ListAllFiles(s.bucket, prefix)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scmnotify
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// AttachTriggerForWorkflowV4 reports the status of the running task to the pull request of a deduplicated
+// trigger, workflowArgs carries the hook payload and notification of the trigger.
+func (s *Service) AttachTriggerForWorkflowV4(task *models.WorkflowTask, workflowArgs *models.WorkflowV4, createGitCheck bool, log *zap.SugaredLogger) error {
+	if createGitCheck {
+		if err := s.CreateGitCheckForWorkflowV4(workflowArgs, task.TaskID, log); err != nil {
+			log.Warnf("Failed to create git check of attached trigger for custom workflow %s, taskID: %d the error is: %s", task.WorkflowName, task.TaskID, err)
+		}
+	}
+	attachedTask := attachedWorkflowTask(task, workflowArgs.HookPayload, workflowArgs.NotificationID)
+	if err := s.UpdateWebhookCommentForWorkflowV4(attachedTask, log); err != nil {
+		log.Warnf("Failed to update comment of attached trigger for custom workflow %s, taskID: %d the error is: %s", task.WorkflowName, task.TaskID, err)
+	}
+
+	return mongodb.NewAttachedTriggerColl().Create(&models.AttachedTrigger{
+		WorkflowName:   task.WorkflowName,
+		TaskID:         task.TaskID,
+		HookPayload:    workflowArgs.HookPayload,
+		NotificationID: workflowArgs.NotificationID,
+	})
+}
+
+// CompleteAttachedTriggersForWorkflowV4 reports the final status of the task to the attached triggers.
+func (s *Service) CompleteAttachedTriggersForWorkflowV4(task *models.WorkflowTask, log *zap.SugaredLogger) {
+	triggers, err := mongodb.NewAttachedTriggerColl().List(task.WorkflowName, task.TaskID)
+	if err != nil {
+		log.Warnf("Failed to list attached triggers of custom workflow %s, taskID: %d the error is: %s", task.WorkflowName, task.TaskID, err)
+		return
+	}
+	if len(triggers) == 0 {
+		return
+	}
+	for _, trigger := range triggers {
+		attachedTask := attachedWorkflowTask(task, trigger.HookPayload, trigger.NotificationID)
+		if err := s.UpdateWebhookCommentForWorkflowV4(attachedTask, log); err != nil {
+			log.Warnf("Failed to update comment of attached trigger for custom workflow %s, taskID: %d the error is: %s", task.WorkflowName, task.TaskID, err)
+		}
+		if err := s.CompleteGitCheckForWorkflowV4(attachedTask.WorkflowArgs, task.TaskID, task.Status, log); err != nil {
+			log.Warnf("Failed to complete git check of attached trigger for custom workflow %s, taskID: %d the error is: %s", task.WorkflowName, task.TaskID, err)
+		}
+	}
+	if err := mongodb.NewAttachedTriggerColl().Delete(task.WorkflowName, task.TaskID); err != nil {
+		log.Warnf("Failed to delete attached triggers of custom workflow %s, taskID: %d the error is: %s", task.WorkflowName, task.TaskID, err)
+	}
+}
+
+// attachedWorkflowTask returns a copy of the task which reports to the pull request of the trigger.
+func attachedWorkflowTask(task *models.WorkflowTask, payload *models.HookPayload, notificationID string) *models.WorkflowTask {
+	args := *task.WorkflowArgs
+	args.HookPayload = payload
+	args.NotificationID = notificationID
+	attachedTask := *task
+	attachedTask.WorkflowArgs = &args
+	return &attachedTask
+}
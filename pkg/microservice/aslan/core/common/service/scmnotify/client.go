@@ -32,6 +32,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitee"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
 	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -65,7 +66,7 @@ func (c *Client) Comment(notify *models.Notification) error {
 	}
 	if strings.ToLower(codeHostDetail.Type) == setting.SourceFromGitlab {
 		var note *gitlab.Note
-		cli, err := gitlabtool.NewClient(codeHostDetail.ID, codeHostDetail.Address, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		cli, err := gitlabtool.NewClient(codeHostDetail.ID, codeHostDetail.Address, codeHostDetail.AccessToken, codeHostDetail.ProxyAddr(config.ProxyHTTPSAddr()), codeHostDetail.UseProxy())
 		if err != nil {
 			c.logger.Errorf("create gitlab client failed err: %v", err)
 			return fmt.Errorf("create gitlab client failed err: %v", err)
@@ -94,72 +95,73 @@ func (c *Client) Comment(notify *models.Notification) error {
 			return fmt.Errorf("failed to comment gitlab due to %s/%d %v", notify.ProjectID, notify.PrID, err)
 		}
 	} else if strings.ToLower(codeHostDetail.Type) == gerrit.CodehostTypeGerrit {
-		cli := gerrit.NewClient(codeHostDetail.Address, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		cli := gerrit.NewClient(codeHostDetail.Address, codeHostDetail.AccessToken, codeHostDetail.ProxyAddr(config.ProxyHTTPSAddr()), codeHostDetail.UseProxy())
 		for _, task := range notify.Tasks {
 			// create task created comment
 			if !task.FirstCommented && task.Status == config.TaskStatusReady {
-				if e := cli.SetReview(
+				message := fmt.Sprintf(""+
+					"%s ⏱️ %s/v1/projects/detail/%s/pipelines/multi/%s/%d",
+					strings.ToUpper(string(task.Status)),
+					notify.BaseURI,
+					task.ProductName,
+					task.WorkflowName,
+					task.ID,
+				)
+				labels := gerritVotes(task.ProductName, notify.Label, task.Status)
+				if e := cli.SetReviewLabels(
 					notify.RepoName,
 					notify.PrID,
-					fmt.Sprintf(""+
-						"%s ⏱️ %s/v1/projects/detail/%s/pipelines/multi/%s/%d",
-						strings.ToUpper(string(task.Status)),
-						notify.BaseURI,
-						task.ProductName,
-						task.WorkflowName,
-						task.ID,
-					),
-					notify.Label,
-					"0",
+					message,
+					labels,
 					notify.Revision,
 				); e != nil {
 					c.logger.Warnf("failed to set review %v %v %v", task, notify, e)
 				}
+				c.setGerritSeriesReview(cli, notify, message, labels)
 
 				task.FirstCommented = true
 				continue
 			}
 
 			/* set review score*/
-			var emoji, score string
+			var emoji string
 			var skip bool
 			switch task.Status {
 			case config.TaskStatusPass:
 				emoji = "✅"
-				score = "+1"
 			case config.TaskStatusCancelled:
 				emoji = "✖️"
-				score = "0"
 			case config.TaskStatusTimeout, config.TaskStatusFailed:
 				emoji = "❌"
-				score = "-1"
 			default:
 				skip = true
 			}
 
 			if !skip {
-				if e := cli.SetReview(
+				message := fmt.Sprintf(""+
+					"%s %s %s/v1/projects/detail/%s/pipelines/multi/%s/%d",
+					strings.ToUpper(string(task.Status)),
+					emoji,
+					notify.BaseURI,
+					task.ProductName,
+					task.WorkflowName,
+					task.ID,
+				)
+				labels := gerritVotes(task.ProductName, notify.Label, task.Status)
+				if e := cli.SetReviewLabels(
 					notify.ProjectID,
 					notify.PrID,
-					fmt.Sprintf(""+
-						"%s %s %s/v1/projects/detail/%s/pipelines/multi/%s/%d",
-						strings.ToUpper(string(task.Status)),
-						emoji,
-						notify.BaseURI,
-						task.ProductName,
-						task.WorkflowName,
-						task.ID,
-					),
-					notify.Label,
-					score,
+					message,
+					labels,
 					notify.Revision,
 				); e != nil {
 					c.logger.Warnf("failed to set review %v %v %v", task, notify, e)
 				}
+				c.setGerritSeriesReview(cli, notify, message, labels)
 			}
 		}
 	} else if strings.ToLower(codeHostDetail.Type) == setting.SourceFromGitee {
-		cli := gitee.NewClient(codeHostDetail.ID, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		cli := gitee.NewClient(codeHostDetail.ID, codeHostDetail.AccessToken, codeHostDetail.ProxyAddr(config.ProxyHTTPSAddr()), codeHostDetail.UseProxy())
 		var pullRequestComments giteeClient.PullRequestComments
 		if notify.CommentID == "" {
 			// create comment
@@ -187,9 +189,45 @@ func (c *Client) Comment(notify *models.Notification) error {
 		if err != nil {
 			return fmt.Errorf("failed to comment gitee due to %s/%d %v", notify.ProjectID, notify.PrID, err)
 		}
+	} else if strings.ToLower(codeHostDetail.Type) == setting.SourceFromBitbucketServer {
+		cli := bitbucketserver.NewClient(codeHostDetail.Address, codeHostDetail.AccessToken, codeHostDetail.ProxyAddr(config.ProxyHTTPSAddr()), codeHostDetail.UseProxy())
+		for _, task := range notify.Tasks {
+			if err := cli.SetBuildStatus(notify.Revision, &bitbucketserver.BuildStatus{
+				State: bitbucketBuildState(task.Status),
+				// the status of the same workflow is overwritten when the pull request is built again
+				Key:  fmt.Sprintf("zadig-%s", task.WorkflowName),
+				Name: fmt.Sprintf("%s#%d", task.WorkflowName, task.ID),
+				URL: fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d",
+					notify.BaseURI, task.ProductName, task.WorkflowName, task.ID),
+				Description: string(task.Status),
+			}); err != nil {
+				c.logger.Warnf("failed to set build status %v %v %v", task, notify, err)
+			}
+		}
 	} else {
 		return fmt.Errorf("non gitlab source not supported to comment")
 	}
 
 	return nil
 }
+
+func bitbucketBuildState(status config.TaskStatus) string {
+	switch status {
+	case config.TaskStatusPass:
+		return bitbucketserver.BuildStateSuccessful
+	case config.TaskStatusFailed, config.TaskStatusTimeout, config.TaskStatusCancelled:
+		return bitbucketserver.BuildStateFailed
+	default:
+		return bitbucketserver.BuildStateInProgress
+	}
+}
+
+// setGerritSeriesReview reports the result to the other changes of the relation chain or topic
+// which are built together with the triggering change.
+func (c *Client) setGerritSeriesReview(cli *gerrit.Client, notify *models.Notification, message string, labels map[string]string) {
+	for _, change := range notify.SeriesChanges {
+		if e := cli.SetReviewLabels(change.Project, change.Number, message, labels, "current"); e != nil {
+			c.logger.Warnf("failed to set review of change %s~%d, err: %v", change.Project, change.Number, e)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scmnotify
+
+import (
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+)
+
+// gerritVotes returns the votes on the labels of the change for the status of the task by the voting policy of
+// the project, the label of the webhook is voted if the policy is not enabled.
+func gerritVotes(projectName, label string, status config.TaskStatus) map[string]string {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil || project.GerritVotingPolicy == nil || !project.GerritVotingPolicy.Enabled {
+		votes := make(map[string]string)
+		if label != "" {
+			votes[label] = defaultGerritScore(status)
+		}
+		return votes
+	}
+	return policyVotes(project.GerritVotingPolicy, status)
+}
+
+func policyVotes(policy *template.GerritVotingPolicy, status config.TaskStatus) map[string]string {
+	votes := make(map[string]string)
+	for _, vote := range policy.Votes {
+		var value string
+		switch status {
+		case config.TaskStatusReady:
+			if vote.Reset {
+				value = "0"
+			}
+		case config.TaskStatusPass:
+			value = vote.Passed
+		case config.TaskStatusFailed:
+			value = vote.Failed
+		case config.TaskStatusTimeout:
+			value = vote.Timeout
+		case config.TaskStatusCancelled:
+			value = vote.Cancelled
+		}
+		if value != "" {
+			votes[vote.Label] = value
+		}
+	}
+	return votes
+}
+
+func defaultGerritScore(status config.TaskStatus) string {
+	switch status {
+	case config.TaskStatusPass:
+		return "+1"
+	case config.TaskStatusTimeout, config.TaskStatusFailed:
+		return "-1"
+	default:
+		return "0"
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scmnotify
+
+import (
+	gogitlab "github.com/xanzy/go-gitlab"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitlab"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+)
+
+// updateGitlabStatusForWorkflowV4 sets the commit status of the merge request for the triggers
+// with merge gate enabled, so that gitlab blocks the merge until the workflow passes.
+// It returns false if the task is not triggered by such a gitlab trigger.
+func updateGitlabStatusForWorkflowV4(workflowArgs *models.WorkflowV4, taskID int64, state gogitlab.BuildStateValue, description string) (bool, error) {
+	hook := workflowArgs.HookPayload
+	if !hook.MergeGate {
+		return false, nil
+	}
+	ch, err := systemconfig.New().GetCodeHost(hook.CodehostID)
+	if err != nil {
+		return false, err
+	}
+	if ch.Type != setting.SourceFromGitlab {
+		return false, nil
+	}
+
+	gc, err := gitlab.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+	if err != nil {
+		return true, err
+	}
+	return true, gc.SetCommitStatus(hook.Owner, hook.Repo, hook.CommitID, &gogitlab.SetCommitStatusOptions{
+		State:       state,
+		Name:        gogitlab.String(github.StatusContext(workflowArgs.Name)),
+		TargetURL:   gogitlab.String(github.GetTaskLink(configbase.ExternalBaseURL(), workflowArgs.Project, workflowArgs.Name, config.WorkflowTypeV4, taskID)),
+		Description: gogitlab.String(description),
+	})
+}
+
+func getGitlabStatus(status config.Status) gogitlab.BuildStateValue {
+	switch status {
+	case config.StatusCreated, config.StatusRunning:
+		return gogitlab.Running
+	case config.StatusPassed:
+		return gogitlab.Success
+	case config.StatusSkipped, config.StatusCancelled:
+		return gogitlab.Canceled
+	default:
+		return gogitlab.Failed
+	}
+}
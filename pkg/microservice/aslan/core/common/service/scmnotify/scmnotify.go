@@ -23,6 +23,7 @@ import (
 	"os"
 	"strings"
 
+	gogitlab "github.com/xanzy/go-gitlab"
 	"go.uber.org/zap"
 
 	configbase "github.com/koderover/zadig/pkg/config"
@@ -35,6 +36,7 @@ import (
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/logannotation"
 	s3tool "github.com/koderover/zadig/pkg/tool/s3"
 	"github.com/koderover/zadig/pkg/util"
 )
@@ -57,18 +59,19 @@ func (s *Service) SendInitWebhookComment(
 	mainRepo *models.MainHookRepo, prID int, baseURI string, isPipeline, isTest, isScanning, isWorkflowV4 bool, logger *zap.SugaredLogger,
 ) (*models.Notification, error) {
 	notification := &models.Notification{
-		CodehostID:   mainRepo.CodehostID,
-		PrID:         prID,
-		ProjectID:    strings.TrimLeft(mainRepo.GetRepoNamespace()+"/"+mainRepo.RepoName, "/"),
-		BaseURI:      baseURI,
-		IsPipeline:   isPipeline,
-		IsTest:       isTest,
-		IsScanning:   isScanning,
-		IsWorkflowV4: isWorkflowV4,
-		Label:        mainRepo.GetLabelValue(),
-		Revision:     mainRepo.Revision,
-		RepoOwner:    mainRepo.RepoOwner,
-		RepoName:     mainRepo.RepoName,
+		CodehostID:    mainRepo.CodehostID,
+		PrID:          prID,
+		ProjectID:     strings.TrimLeft(mainRepo.GetRepoNamespace()+"/"+mainRepo.RepoName, "/"),
+		BaseURI:       baseURI,
+		IsPipeline:    isPipeline,
+		IsTest:        isTest,
+		IsScanning:    isScanning,
+		IsWorkflowV4:  isWorkflowV4,
+		Label:         mainRepo.GetLabelValue(),
+		Revision:      mainRepo.Revision,
+		RepoOwner:     mainRepo.RepoOwner,
+		RepoName:      mainRepo.RepoName,
+		SeriesChanges: mainRepo.SeriesChanges,
 	}
 
 	if err := s.Client.Comment(notification); err != nil {
@@ -502,6 +505,8 @@ func (s *Service) UpdateWebhookCommentForWorkflowV4(task *models.WorkflowTask, l
 				WorkflowName: task.WorkflowName,
 				ID:           task.TaskID,
 				Status:       status,
+				Summaries:    task.JobSummaries(),
+				Annotations:  commentAnnotations(task),
 			}
 
 			tasks = append(tasks, scmTask)
@@ -517,6 +522,8 @@ func (s *Service) UpdateWebhookCommentForWorkflowV4(task *models.WorkflowTask, l
 			WorkflowName: task.WorkflowName,
 			ID:           task.TaskID,
 			Status:       status,
+			Summaries:    task.JobSummaries(),
+			Annotations:  commentAnnotations(task),
 		})
 		shouldComment = true
 	}
@@ -538,6 +545,17 @@ func (s *Service) UpdateWebhookCommentForWorkflowV4(task *models.WorkflowTask, l
 	return nil
 }
 
+// maxCommentAnnotations is the most log annotations of a task in the comment, the others are in the task.
+const maxCommentAnnotations = 10
+
+func commentAnnotations(task *models.WorkflowTask) []*logannotation.Annotation {
+	annotations := task.LogAnnotations()
+	if len(annotations) > maxCommentAnnotations {
+		return annotations[:maxCommentAnnotations]
+	}
+	return annotations
+}
+
 func (s *Service) UpdatePipelineWebhookComment(task *task.Task, logger *zap.SugaredLogger) (err error) {
 	if task.TaskArgs == nil {
 		logger.Warnf("taskArgs of %s is nil", task.PipelineName)
@@ -653,6 +671,9 @@ func (s *Service) CreateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 	if hook == nil || !hook.IsPr {
 		return nil
 	}
+	if handled, err := updateGitlabStatusForWorkflowV4(workflowArgs, taskID, gogitlab.Pending, fmt.Sprintf("Workflow [%s] is queued.", workflowArgs.Name)); handled || err != nil {
+		return err
+	}
 
 	ghApp, err := github.GetGithubAppClientByOwner(hook.Owner)
 	if err != nil {
@@ -669,7 +690,7 @@ func (s *Service) CreateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 			Ref:    hook.Ref,
 			IsPr:   hook.IsPr,
 
-			AslanURL:    configbase.SystemAddress(),
+			AslanURL:    configbase.ExternalBaseURL(),
 			PipeName:    workflowArgs.Name,
 			ProductName: workflowArgs.Project,
 			PipeType:    config.WorkflowTypeV4,
@@ -689,7 +710,7 @@ func (s *Service) CreateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 		log.Errorf("Failed to get codeHost, err:%v", err)
 		return e.ErrGithubUpdateStatus.AddErr(err)
 	}
-	gc := github.NewClient(ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	gc := github.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 
 	return gc.UpdateCheckStatus(&github.StatusOptions{
 		Owner:       hook.Owner,
@@ -697,7 +718,7 @@ func (s *Service) CreateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 		Ref:         hook.Ref,
 		State:       github.StatePending,
 		Description: fmt.Sprintf("Workflow [%s] is queued.", workflowArgs.Name),
-		AslanURL:    configbase.SystemAddress(),
+		AslanURL:    configbase.ExternalBaseURL(),
 		PipeName:    workflowArgs.Name,
 		ProductName: workflowArgs.Project,
 		PipeType:    config.WorkflowTypeV4,
@@ -711,6 +732,9 @@ func (s *Service) UpdateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 	if hook == nil || !hook.IsPr {
 		return nil
 	}
+	if handled, err := updateGitlabStatusForWorkflowV4(workflowArgs, taskID, gogitlab.Running, fmt.Sprintf("Workflow [%s] is running.", workflowArgs.Name)); handled || err != nil {
+		return err
+	}
 
 	ghApp, err := github.GetGithubAppClientByOwner(hook.Owner)
 	if err != nil {
@@ -731,7 +755,7 @@ func (s *Service) UpdateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 			Ref:    hook.Ref,
 			IsPr:   hook.IsPr,
 
-			AslanURL:    configbase.SystemAddress(),
+			AslanURL:    configbase.ExternalBaseURL(),
 			PipeName:    workflowArgs.Name,
 			PipeType:    config.WorkflowTypeV4,
 			ProductName: workflowArgs.Project,
@@ -747,7 +771,7 @@ func (s *Service) UpdateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 		log.Errorf("Failed to get codeHost, err:%v", err)
 		return e.ErrGithubUpdateStatus.AddErr(err)
 	}
-	gc := github.NewClient(ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	gc := github.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 
 	return gc.UpdateCheckStatus(&github.StatusOptions{
 		Owner:       hook.Owner,
@@ -755,7 +779,7 @@ func (s *Service) UpdateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, t
 		Ref:         hook.Ref,
 		State:       github.StatePending,
 		Description: fmt.Sprintf("Workflow [%s] is running.", workflowArgs.Name),
-		AslanURL:    configbase.SystemAddress(),
+		AslanURL:    configbase.ExternalBaseURL(),
 		PipeName:    workflowArgs.Name,
 		PipeType:    config.WorkflowTypeV4,
 		ProductName: workflowArgs.Project,
@@ -769,6 +793,9 @@ func (s *Service) CompleteGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4,
 	if hook == nil || !hook.IsPr {
 		return nil
 	}
+	if handled, err := updateGitlabStatusForWorkflowV4(workflowArgs, taskID, getGitlabStatus(status), fmt.Sprintf("Workflow [%s] is %s.", workflowArgs.Name, getCheckStatus(status))); handled || err != nil {
+		return err
+	}
 
 	ghApp, err := github.GetGithubAppClientByOwner(hook.Owner)
 	if err != nil {
@@ -789,7 +816,7 @@ func (s *Service) CompleteGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4,
 			Ref:    hook.Ref,
 			IsPr:   hook.IsPr,
 
-			AslanURL:    configbase.SystemAddress(),
+			AslanURL:    configbase.ExternalBaseURL(),
 			PipeName:    workflowArgs.Name,
 			PipeType:    config.WorkflowTypeV4,
 			ProductName: workflowArgs.Project,
@@ -806,7 +833,7 @@ func (s *Service) CompleteGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4,
 		log.Errorf("Failed to get codeHost, err:%v", err)
 		return e.ErrGithubUpdateStatus.AddErr(err)
 	}
-	gc := github.NewClient(ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	gc := github.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 
 	return gc.UpdateCheckStatus(&github.StatusOptions{
 		Owner:       hook.Owner,
@@ -814,7 +841,7 @@ func (s *Service) CompleteGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4,
 		Ref:         hook.Ref,
 		State:       getGitHubStatusFromCIStatus(ciStatus),
 		Description: fmt.Sprintf("Workflow [%s] is %s.", workflowArgs.Name, ciStatus),
-		AslanURL:    configbase.SystemAddress(),
+		AslanURL:    configbase.ExternalBaseURL(),
 		PipeName:    workflowArgs.Name,
 		PipeType:    config.WorkflowTypeV4,
 		ProductName: workflowArgs.Project,
@@ -487,7 +487,7 @@ func DeleteServiceWebhookByName(serviceName, productName string, logger *zap.Sug
 func needProcessWebhook(source string) bool {
 	if source == setting.ServiceSourceTemplate || source == setting.SourceFromZadig || source == setting.SourceFromGerrit ||
 		source == "" || source == setting.SourceFromExternal || source == setting.SourceFromChartTemplate ||
-		source == setting.SourceFromChartRepo || source == setting.SourceFromCustomEdit {
+		source == setting.SourceFromChartRepo || source == setting.SourceFromCustomEdit || source == setting.SourceFromUmbrellaChart {
 		return false
 	}
 	return true
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+)
+
+// SortServicesByDependency puts the services into layers, a service is in a layer after all the
+// services it depends on, directly or through services which are not in the list.
+// An error is returned if there is a dependency cycle.
+func SortServicesByDependency(dependencies []*template.ServiceDependency, services []string) ([][]string, error) {
+	dependsOn := make(map[string]sets.String)
+	nodes := sets.NewString(services...)
+	for _, dependency := range dependencies {
+		if _, ok := dependsOn[dependency.ServiceName]; !ok {
+			dependsOn[dependency.ServiceName] = sets.NewString()
+		}
+		dependsOn[dependency.ServiceName].Insert(dependency.DependsOn...)
+		nodes.Insert(dependency.ServiceName)
+		nodes.Insert(dependency.DependsOn...)
+	}
+
+	// Kahn's algorithm, services with no pending dependencies form the next layer.
+	pending := make(map[string]int, nodes.Len())
+	dependents := make(map[string][]string)
+	for _, node := range nodes.List() {
+		pending[node] = dependsOn[node].Len()
+		for _, dependency := range dependsOn[node].List() {
+			dependents[dependency] = append(dependents[dependency], node)
+		}
+	}
+
+	var layers [][]string
+	var current []string
+	for _, node := range nodes.List() {
+		if pending[node] == 0 {
+			current = append(current, node)
+		}
+	}
+	sorted := 0
+	for len(current) > 0 {
+		layers = append(layers, current)
+		sorted += len(current)
+		var next []string
+		for _, node := range current {
+			for _, dependent := range dependents[node] {
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		sort.Strings(next)
+		current = next
+	}
+	if sorted < nodes.Len() {
+		var cycle []string
+		for _, node := range nodes.List() {
+			if pending[node] > 0 {
+				cycle = append(cycle, node)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle found among services: %s", strings.Join(cycle, ","))
+	}
+
+	wanted := sets.NewString(services...)
+	resp := make([][]string, 0, len(layers))
+	for _, layer := range layers {
+		var filtered []string
+		for _, node := range layer {
+			if wanted.Has(node) {
+				filtered = append(filtered, node)
+			}
+		}
+		if len(filtered) > 0 {
+			resp = append(resp, filtered)
+		}
+	}
+	return resp, nil
+}
+
+// GetServiceReadyCondition returns the condition the dependents of the service wait for.
+func GetServiceReadyCondition(dependencies []*template.ServiceDependency, serviceName string) *template.ServiceReadyCondition {
+	for _, dependency := range dependencies {
+		if dependency.ServiceName == serviceName && dependency.WaitFor != nil {
+			return dependency.WaitFor
+		}
+	}
+	return &template.ServiceReadyCondition{Type: config.ServiceReadyConditionReady}
+}
@@ -45,9 +45,33 @@ func GetDockerfileTemplateDetail(id string, logger *zap.SugaredLogger) (*Dockerf
 	resp.Name = dockerfileTemplate.Name
 	resp.Content = dockerfileTemplate.Content
 	resp.Variables = variables
+	resp.Revision = dockerfileTemplate.Revision
+	resp.TemplateMeta = TemplateMeta{
+		Language:    dockerfileTemplate.Language,
+		Framework:   dockerfileTemplate.Framework,
+		Description: dockerfileTemplate.Description,
+	}
 	return resp, nil
 }
 
+// GetDockerfileTemplateContent returns the content of the template at the revision pinned by a
+// build, the latest content is returned if the revision is 0 or was created before versioning.
+func GetDockerfileTemplateContent(id string, revision int64, logger *zap.SugaredLogger) (string, error) {
+	if revision > 0 {
+		version, err := commonrepo.NewDockerfileTemplateVersionColl().Find(id, revision)
+		if err == nil {
+			return version.Content, nil
+		}
+		logger.Warnf("Failed to find revision %d of dockerfile template %s, use the latest one, err: %s", revision, id, err)
+	}
+	dockerfileTemplate, err := commonrepo.NewDockerfileTemplateColl().GetById(id)
+	if err != nil {
+		logger.Errorf("Failed to get dockerfile template from id: %s, the error is: %s", id, err)
+		return "", err
+	}
+	return dockerfileTemplate.Content, nil
+}
+
 func getVariables(s string, logger *zap.SugaredLogger) ([]*commonmodels.ChartVariable, error) {
 	ret := make([]*commonmodels.ChartVariable, 0)
 	reader := strings.NewReader(s)
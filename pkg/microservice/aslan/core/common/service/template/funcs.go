@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const shortHashLength = 8
+
+var (
+	invalidNameChars       = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	repeatedDashes         = regexp.MustCompile(`-{2,}`)
+)
+
+// FuncMap returns the functions which can be used in the templates rendered by zadig, e.g. the service yamls
+// and the name templates, to make valid kubernetes names and labels of the templated values:
+//
+//	{{k8sName .Branch}}               feature/ABC_1 => feature-abc-1
+//	{{k8sSubdomain .Domain}}          the names joined by dots, e.g. the domains of the environments
+//	{{k8sLabelValue .CommitMessage}}  a value of at most 63 characters which can be used as a label value
+//	{{truncName 20 .Name}}            the name is cut to 20 characters ending with its hash if it is longer
+//	{{shortHash .Name}}               the first 8 hex characters of the sha256 of the name
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"k8sName":       K8sName,
+		"k8sSubdomain":  K8sSubdomain,
+		"k8sLabelValue": K8sLabelValue,
+		"truncName":     TruncName,
+		"shortHash":     ShortHash,
+	}
+}
+
+// K8sName converts s to a DNS-1123 label: the invalid characters are replaced by dashes and the name longer
+// than 63 characters is truncated by TruncName, so different long names stay different.
+func K8sName(s string) string {
+	name := dns1123(s)
+	if name == "" && s != "" {
+		return ShortHash(s)
+	}
+	return TruncName(validation.DNS1123LabelMaxLength, name)
+}
+
+// K8sSubdomain converts every part of s separated by dots to a DNS-1123 label, the empty parts are dropped.
+func K8sSubdomain(s string) string {
+	labels := make([]string, 0)
+	for _, part := range strings.Split(s, ".") {
+		if label := K8sName(part); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	subdomain := strings.Join(labels, ".")
+	if len(subdomain) > validation.DNS1123SubdomainMaxLength {
+		subdomain = strings.TrimRight(TruncName(validation.DNS1123SubdomainMaxLength, subdomain), ".")
+	}
+	return subdomain
+}
+
+// K8sLabelValue converts s to a valid label value, it begins and ends with an alphanumeric character and is at
+// most 63 characters.
+func K8sLabelValue(s string) string {
+	value := invalidLabelValueChars.ReplaceAllString(s, "-")
+	value = strings.Trim(value, "-_.")
+	if len(value) > validation.LabelValueMaxLength {
+		value = strings.Trim(TruncName(validation.LabelValueMaxLength, value), "-_.")
+	}
+	return value
+}
+
+// TruncName returns s if it is at most n characters, otherwise s is cut and ends with the short hash of the
+// whole s, so that the result is stable and the names sharing a long prefix do not collide.
+func TruncName(n int, s string) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= shortHashLength+1 {
+		return ShortHash(s)[:n]
+	}
+	prefix := strings.TrimRight(s[:n-shortHashLength-1], "-.")
+	return prefix + "-" + ShortHash(s)
+}
+
+// ShortHash returns the first 8 hex characters of the sha256 of s.
+func ShortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:shortHashLength]
+}
+
+func dns1123(s string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(s), "-")
+	name = repeatedDashes.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-")
+}
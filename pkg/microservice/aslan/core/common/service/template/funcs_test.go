@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestTemplate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "template Suite")
+}
+
+var _ = Describe("Testing template functions", func() {
+
+	It("makes valid names of the branches", func() {
+		Expect(K8sName("feature/ABC_1")).To(Equal("feature-abc-1"))
+		Expect(K8sName("--Release--2.0--")).To(Equal("release-2-0"))
+		Expect(K8sName("中文")).To(Equal(ShortHash("中文")))
+	})
+
+	It("keeps the long names valid and different", func() {
+		prefix := strings.Repeat("service-", 10)
+		a, b := K8sName(prefix+"a"), K8sName(prefix+"b")
+		Expect(a).NotTo(Equal(b))
+		Expect(validation.IsDNS1123Label(a)).To(BeEmpty())
+		Expect(validation.IsDNS1123Label(b)).To(BeEmpty())
+		Expect(K8sName(prefix + "a")).To(Equal(a))
+	})
+
+	It("makes valid subdomains and label values", func() {
+		Expect(K8sSubdomain("PR_12.Preview..example.com")).To(Equal("pr-12.preview.example.com"))
+		value := K8sLabelValue("fix: " + strings.Repeat("x", 100) + "!")
+		Expect(validation.IsValidLabelValue(value)).To(BeEmpty())
+	})
+
+	It("can be used in the templates", func() {
+		tmpl, err := template.New("name").Funcs(FuncMap()).Parse(`{{k8sName .Env}}-{{truncName 12 .Service}}`)
+		Expect(err).NotTo(HaveOccurred())
+		buf := &bytes.Buffer{}
+		Expect(tmpl.Execute(buf, map[string]string{"Env": "Dev_1", "Service": "short"})).To(Succeed())
+		Expect(buf.String()).To(Equal("dev-1-short"))
+	})
+})
@@ -21,7 +21,15 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
 )
 
+// TemplateMeta classifies the templates of the library.
+type TemplateMeta struct {
+	Language    string `json:"language"`
+	Framework   string `json:"framework"`
+	Description string `json:"description"`
+}
+
 type Chart struct {
+	TemplateMeta
 	Name       string                  `json:"name"`
 	CodehostID int                     `json:"codehostID"`
 	Owner      string                  `json:"owner"`
@@ -30,21 +38,27 @@ type Chart struct {
 	Branch     string                  `json:"branch"`
 	Path       string                  `json:"path"`
 	Variables  []*models.ChartVariable `json:"variables,omitempty"`
+	Revision   int64                   `json:"revision"`
 
 	Files []*fs.FileInfo `json:"files,omitempty"`
 }
 
 type DockerfileTemplate struct {
+	TemplateMeta
 	Name    string `json:"name"`
 	Content string `json:"content"`
 }
 
 type DockerfileListObject struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	TemplateMeta
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Revision int64  `json:"revision"`
 }
 
 type DockerfileDetail struct {
+	TemplateMeta
+	Revision  int64                   `json:"revision"`
 	ID        string                  `json:"id"`
 	Name      string                  `json:"name"`
 	Content   string                  `json:"content"`
@@ -52,8 +66,10 @@ type DockerfileDetail struct {
 }
 
 type BuildReference struct {
-	BuildName   string `json:"build_name"`
-	ProjectName string `json:"project_name"`
+	BuildName        string `json:"build_name"`
+	ProjectName      string `json:"project_name"`
+	TemplateRevision int64  `json:"template_revision,omitempty"`
+	Outdated         bool   `json:"outdated,omitempty"`
 }
 
 type YamlTemplate struct {
@@ -77,6 +93,16 @@ type YamlDetail struct {
 }
 
 type ServiceReference struct {
+	ProjectName      string `json:"project_name"`
+	ServiceName      string `json:"service_name"`
+	TemplateRevision int64  `json:"template_revision,omitempty"`
+	Outdated         bool   `json:"outdated,omitempty"`
+}
+
+// RerenderResult is the result of re-rendering a service or a build from the latest template,
+// Name is the service name or the build name.
+type RerenderResult struct {
 	ProjectName string `json:"project_name"`
-	ServiceName string `json:"service_name"`
+	Name        string `json:"name"`
+	Error       string `json:"error,omitempty"`
 }
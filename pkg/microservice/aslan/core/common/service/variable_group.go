@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// MergeVariableGroups returns the variables of the groups followed by the envs, so that the envs and the later
+// groups override the earlier ones when the keys are duplicated.
+func MergeVariableGroups(projectName string, groupNames []string, envs []*commonmodels.KeyVal) ([]*commonmodels.KeyVal, error) {
+	if len(groupNames) == 0 {
+		return envs, nil
+	}
+
+	groups, err := commonrepo.NewVariableGroupColl().ListByNames(projectName, groupNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list variable groups of project %s: %s", projectName, err)
+	}
+	if len(groups) != len(groupNames) {
+		found := make(map[string]bool, len(groups))
+		for _, group := range groups {
+			found[group.Name] = true
+		}
+		for _, name := range groupNames {
+			if !found[name] {
+				return nil, fmt.Errorf("variable group %s is not found in project %s", name, projectName)
+			}
+		}
+	}
+
+	resp := make([]*commonmodels.KeyVal, 0)
+	index := make(map[string]int)
+	add := func(kv *commonmodels.KeyVal) {
+		if i, ok := index[kv.Key]; ok {
+			resp[i] = kv
+			return
+		}
+		index[kv.Key] = len(resp)
+		resp = append(resp, kv)
+	}
+	for _, group := range groups {
+		for _, kv := range group.Variables {
+			add(&commonmodels.KeyVal{
+				Key:          kv.Key,
+				Value:        kv.Value,
+				Type:         commonmodels.StringType,
+				IsCredential: kv.IsCredential,
+			})
+		}
+	}
+	for _, kv := range envs {
+		add(kv)
+	}
+	return resp, nil
+}
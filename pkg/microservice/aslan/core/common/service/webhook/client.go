@@ -46,7 +46,7 @@ func NewClient() *client {
 type task struct {
 	ID                                                          int
 	owner, namespace, repo, address, token, ref, ak, sk, region string
-	from                                                        string
+	from, proxyAddr                                             string
 	add, enableProxy                                            bool
 	err                                                         error
 	doneCh                                                      chan struct{}
@@ -66,6 +66,8 @@ type TaskOption struct {
 	SK          string
 	Region      string
 	EnableProxy bool
+	// ProxyAddr is the proxy of the codehost or the system proxy if the codehost does not have one.
+	ProxyAddr string
 }
 
 func (c *client) AddWebHook(taskOption *TaskOption) error {
@@ -84,6 +86,7 @@ func (c *client) AddWebHook(taskOption *TaskOption) error {
 		from:        taskOption.From,
 		add:         true,
 		enableProxy: taskOption.EnableProxy,
+		proxyAddr:   taskOption.ProxyAddr,
 		ak:          taskOption.AK,
 		sk:          taskOption.SK,
 		region:      taskOption.Region,
@@ -121,6 +124,7 @@ func (c *client) RemoveWebHook(taskOption *TaskOption) error {
 		from:        taskOption.From,
 		add:         false,
 		enableProxy: taskOption.EnableProxy,
+		proxyAddr:   taskOption.ProxyAddr,
 		ak:          taskOption.AK,
 		sk:          taskOption.SK,
 		region:      taskOption.Region,
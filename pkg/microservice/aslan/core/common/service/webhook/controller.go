@@ -25,8 +25,9 @@ import (
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/util/wait"
 
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/azuredevops"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/bitbucketserver"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/codehub"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitee"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
@@ -157,18 +158,22 @@ func removeWebhook(t *task, logger *zap.Logger) {
 
 	switch t.from {
 	case setting.SourceFromGithub:
-		cl = github.NewClient(t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl = github.NewClient(t.token, t.proxyAddr, t.enableProxy)
 	case setting.SourceFromGitlab:
-		cl, err = gitlab.NewClient(t.ID, t.address, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl, err = gitlab.NewClient(t.ID, t.address, t.token, t.proxyAddr, t.enableProxy)
 		if err != nil {
 			t.err = err
 			t.doneCh <- struct{}{}
 			return
 		}
 	case setting.SourceFromCodeHub:
-		cl = codehub.NewClient(t.ak, t.sk, t.region, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl = codehub.NewClient(t.ak, t.sk, t.region, t.proxyAddr, t.enableProxy)
 	case setting.SourceFromGitee:
-		cl = gitee.NewClient(t.ID, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl = gitee.NewClient(t.ID, t.token, t.proxyAddr, t.enableProxy)
+	case setting.SourceFromBitbucketServer:
+		cl = bitbucketserver.NewClient(t.address, t.token, t.proxyAddr, t.enableProxy)
+	case setting.SourceFromAzureDevOps:
+		cl = azuredevops.NewClient(t.address, t.token, t.proxyAddr, t.enableProxy)
 	default:
 		t.err = fmt.Errorf("invaild source: %s", t.from)
 		t.doneCh <- struct{}{}
@@ -235,9 +240,9 @@ func addWebhook(t *task, logger *zap.Logger) {
 
 	switch t.from {
 	case setting.SourceFromGithub:
-		cl = github.NewClient(t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl = github.NewClient(t.token, t.proxyAddr, t.enableProxy)
 	case setting.SourceFromGitlab:
-		cl, err = gitlab.NewClient(t.ID, t.address, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl, err = gitlab.NewClient(t.ID, t.address, t.token, t.proxyAddr, t.enableProxy)
 		if err != nil {
 			t.err = err
 			t.doneCh <- struct{}{}
@@ -245,9 +250,13 @@ func addWebhook(t *task, logger *zap.Logger) {
 		}
 
 	case setting.SourceFromCodeHub:
-		cl = codehub.NewClient(t.ak, t.sk, t.region, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl = codehub.NewClient(t.ak, t.sk, t.region, t.proxyAddr, t.enableProxy)
 	case setting.SourceFromGitee:
-		cl = gitee.NewClient(t.ID, t.token, config.ProxyHTTPSAddr(), t.enableProxy)
+		cl = gitee.NewClient(t.ID, t.token, t.proxyAddr, t.enableProxy)
+	case setting.SourceFromBitbucketServer:
+		cl = bitbucketserver.NewClient(t.address, t.token, t.proxyAddr, t.enableProxy)
+	case setting.SourceFromAzureDevOps:
+		cl = azuredevops.NewClient(t.address, t.token, t.proxyAddr, t.enableProxy)
 	default:
 		t.err = fmt.Errorf("invaild source: %s", t.from)
 		t.doneCh <- struct{}{}
@@ -216,19 +216,20 @@ func ProcessWebhook(updatedHooks, currentHooks interface{}, name string, logger
 			}
 
 			switch ch.Type {
-			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromCodeHub, setting.SourceFromGitee:
+			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromCodeHub, setting.SourceFromGitee, setting.SourceFromBitbucketServer, setting.SourceFromAzureDevOps:
 				err = webhook.NewClient().RemoveWebHook(&webhook.TaskOption{
 					ID:          ch.ID,
 					Name:        wh.name,
 					Owner:       wh.owner,
 					Namespace:   wh.namespace,
 					Repo:        wh.repo,
-					Address:     ch.Address,
+					Address:     ch.RepoAddress(),
 					Token:       ch.AccessToken,
 					AK:          ch.AccessKey,
 					SK:          ch.SecretKey,
 					Region:      ch.Region,
-					EnableProxy: ch.EnableProxy,
+					EnableProxy: ch.UseProxy(),
+					ProxyAddr:   ch.ProxyAddr(config.ProxyHTTPSAddr()),
 					Ref:         name,
 					From:        ch.Type,
 				})
@@ -253,20 +254,22 @@ func ProcessWebhook(updatedHooks, currentHooks interface{}, name string, logger
 			}
 
 			switch ch.Type {
-			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromCodeHub, setting.SourceFromGitee:
+			case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromCodeHub, setting.SourceFromGitee, setting.SourceFromBitbucketServer, setting.SourceFromAzureDevOps:
 				err = webhook.NewClient().AddWebHook(&webhook.TaskOption{
-					ID:        ch.ID,
-					Name:      wh.name,
-					Owner:     wh.owner,
-					Namespace: wh.namespace,
-					Repo:      wh.repo,
-					Address:   ch.Address,
-					Token:     ch.AccessToken,
-					Ref:       name,
-					AK:        ch.AccessKey,
-					SK:        ch.SecretKey,
-					Region:    ch.Region,
-					From:      ch.Type,
+					ID:          ch.ID,
+					Name:        wh.name,
+					Owner:       wh.owner,
+					Namespace:   wh.namespace,
+					Repo:        wh.repo,
+					Address:     ch.RepoAddress(),
+					Token:       ch.AccessToken,
+					Ref:         name,
+					AK:          ch.AccessKey,
+					SK:          ch.SecretKey,
+					Region:      ch.Region,
+					EnableProxy: ch.UseProxy(),
+					ProxyAddr:   ch.ProxyAddr(config.ProxyHTTPSAddr()),
+					From:        ch.Type,
 				})
 				if err != nil {
 					logger.Errorf("Failed to add %s webhook %+v, err: %s", ch.Type, wh, err)
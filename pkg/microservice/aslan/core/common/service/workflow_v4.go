@@ -73,6 +73,9 @@ func DeleteWorkflowV4(name string, logger *zap.SugaredLogger) error {
 	if err != nil {
 		log.Errorf("Failed to process webhook, err: %s", err)
 	}
+	if err := ProcessMergeGate(nil, workflow.HookCtls, workflow.Name, logger); err != nil {
+		log.Errorf("Failed to process merge gate, err: %s", err)
+	}
 	if err := mongodb.NewWorkflowV4Coll().DeleteByID(workflow.ID.Hex()); err != nil {
 		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
 		return e.ErrDeleteWorkflow.AddErr(err)
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	webhookApprovalEvent           = "Approval"
+	webhookApprovalEventHeader     = "X-Zadig-Event"
+	webhookApprovalSignatureHeader = "X-Zadig-Signature-256"
+
+	defaultWebhookApprovalPollInterval = 30 * time.Second
+	minWebhookApprovalPollInterval     = 5 * time.Second
+	maxWebhookApprovalPollInterval     = 10 * time.Minute
+	webhookApprovalRequestTimeout      = 10 * time.Second
+)
+
+type webhookApprovalRequest struct {
+	EventName    string `json:"event_name"`
+	ProjectName  string `json:"project_name"`
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+	StageName    string `json:"stage_name"`
+	TaskCreator  string `json:"task_creator"`
+	Description  string `json:"description"`
+	// Labels are the labels of the task given when it is created.
+	Labels map[string]string `json:"labels,omitempty"`
+	// CustomFields are the values of the custom fields of the workflow and its project.
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+	// Timestamp is signed together with the other fields, the service may reject the stale requests.
+	Timestamp int64 `json:"timestamp"`
+}
+
+type webhookApprovalResponse struct {
+	Decision config.WebhookApprovalDecision `json:"decision"`
+	Message  string                         `json:"message"`
+	// PollInterval is in seconds, the pending approval is requested again after it.
+	PollInterval int `json:"poll_interval"`
+}
+
+// waitForWebhookApproval polls the approval service until it approves or denies the stage, the errors of the
+// service are recorded and retried since it may be restarted while the task is waiting.
+func waitForWebhookApproval(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func()) error {
+	webhook := stage.Approval.Webhook
+	if webhook == nil || webhook.URL == "" {
+		stage.Status = config.StatusFailed
+		return fmt.Errorf("the approval webhook of stage %s is not configured", stage.Name)
+	}
+	defer ack()
+
+	timeout := time.After(time.Duration(stage.Approval.Timeout) * time.Minute)
+	for {
+		resp, err := requestWebhookApproval(webhook, &webhookApprovalRequest{
+			EventName:    webhookApprovalEvent,
+			ProjectName:  workflowCtx.ProjectName,
+			WorkflowName: workflowCtx.WorkflowName,
+			TaskID:       workflowCtx.TaskID,
+			StageName:    stage.Name,
+			TaskCreator:  workflowCtx.TaskCreator,
+			Description:  stage.Approval.Description,
+			Labels:       workflowCtx.Labels,
+			CustomFields: workflowCtx.CustomFields,
+			Timestamp:    time.Now().Unix(),
+		})
+		if err != nil {
+			log.Warnf("failed to request the approval webhook of %s #%d stage %s: %s", workflowCtx.WorkflowName, workflowCtx.TaskID, stage.Name, err)
+			webhook.Message = fmt.Sprintf("failed to request the approval service: %s", err)
+		} else {
+			webhook.Decision = resp.Decision
+			webhook.Message = resp.Message
+			switch resp.Decision {
+			case config.WebhookApprovalApprove:
+				stage.Approval.RejectOrApprove = config.Approve
+				return nil
+			case config.WebhookApprovalDeny:
+				stage.Approval.RejectOrApprove = config.Reject
+				stage.Status = config.StatusReject
+				return fmt.Errorf("the approval service denied this task: %s", resp.Message)
+			case config.WebhookApprovalPending:
+			default:
+				webhook.Message = fmt.Sprintf("unknown decision %q of the approval service", resp.Decision)
+			}
+		}
+		ack()
+
+		select {
+		case <-ctx.Done():
+			stage.Status = config.StatusCancelled
+			return fmt.Errorf("workflow was canceled")
+		case <-timeout:
+			stage.Status = config.StatusCancelled
+			return fmt.Errorf("workflow timeout")
+		case <-time.After(webhookApprovalPollInterval(webhook, resp)):
+		}
+	}
+}
+
+func requestWebhookApproval(webhook *commonmodels.WebhookApproval, req *webhookApprovalRequest) (*webhookApprovalResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{webhookApprovalEventHeader: webhookApprovalEvent}
+	if webhook.Secret != "" {
+		headers[webhookApprovalSignatureHeader] = signWebhookApproval(webhook.Secret, body)
+	}
+
+	resp := &webhookApprovalResponse{}
+	_, err = httpclient.Post(webhook.URL,
+		httpclient.SetHeaders(headers),
+		httpclient.SetBody(body),
+		httpclient.SetResult(resp),
+		httpclient.SetRequestTimeout(webhookApprovalRequestTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// signWebhookApproval signs the body like the webhooks of github, so that the service can verify it by the
+// same code.
+func signWebhookApproval(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookApprovalPollInterval(webhook *commonmodels.WebhookApproval, resp *webhookApprovalResponse) time.Duration {
+	interval := defaultWebhookApprovalPollInterval
+	if webhook.PollInterval > 0 {
+		interval = time.Duration(webhook.PollInterval) * time.Second
+	}
+	if resp != nil && resp.PollInterval > 0 {
+		interval = time.Duration(resp.PollInterval) * time.Second
+	}
+	if interval < minWebhookApprovalPollInterval {
+		return minWebhookApprovalPollInterval
+	}
+	if interval > maxWebhookApprovalPollInterval {
+		return maxWebhookApprovalPollInterval
+	}
+	return interval
+}
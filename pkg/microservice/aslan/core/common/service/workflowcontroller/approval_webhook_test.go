@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+var _ = Describe("Testing webhook approval", func() {
+
+	It("signs the request and reads the decision", func() {
+		var signature, event string
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature = r.Header.Get(webhookApprovalSignatureHeader)
+			event = r.Header.Get(webhookApprovalEventHeader)
+			body, _ = io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"decision":"pending","message":"waiting for CAB","poll_interval":60}`))
+		}))
+		defer server.Close()
+
+		webhook := &commonmodels.WebhookApproval{URL: server.URL, Secret: "secret"}
+		resp, err := requestWebhookApproval(webhook, &webhookApprovalRequest{WorkflowName: "release", TaskID: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Decision).To(Equal(config.WebhookApprovalPending))
+		Expect(resp.Message).To(Equal("waiting for CAB"))
+		Expect(event).To(Equal(webhookApprovalEvent))
+		Expect(signature).To(Equal(signWebhookApproval("secret", body)))
+		Expect(webhookApprovalPollInterval(webhook, resp)).To(Equal(time.Minute))
+	})
+
+	It("fails on the errors of the service", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		_, err := requestWebhookApproval(&commonmodels.WebhookApproval{URL: server.URL}, &webhookApprovalRequest{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("bounds the poll interval", func() {
+		Expect(webhookApprovalPollInterval(&commonmodels.WebhookApproval{}, nil)).To(Equal(defaultWebhookApprovalPollInterval))
+		Expect(webhookApprovalPollInterval(&commonmodels.WebhookApproval{PollInterval: 1}, nil)).To(Equal(minWebhookApprovalPollInterval))
+		Expect(webhookApprovalPollInterval(&commonmodels.WebhookApproval{}, &webhookApprovalResponse{PollInterval: 3600})).To(Equal(maxWebhookApprovalPollInterval))
+	})
+})
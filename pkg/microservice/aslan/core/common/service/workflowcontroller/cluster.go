@@ -0,0 +1,215 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	heartbeatInterval = 5 * time.Second
+	// instances without a heartbeat in instanceTimeout are considered dead.
+	instanceTimeout = 30 * time.Second
+	leaseTTL        = 15 * time.Second
+)
+
+var (
+	instanceID        string
+	instanceStartTime int64
+	isLeader          int32
+
+	ringMutex sync.RWMutex
+	ring      = newHashRing(nil)
+)
+
+// initCluster registers the instance and starts the heartbeat loop. The waiting tasks are sharded
+// across the alive instances by workflow name, the leader cancels the tasks of the dead instances.
+func initCluster() {
+	instanceID = config.PodName()
+	if instanceID == "" {
+		instanceID, _ = os.Hostname()
+	}
+	instanceStartTime = time.Now().Unix()
+	syncCluster()
+	go func() {
+		for range time.Tick(heartbeatInterval) {
+			syncCluster()
+		}
+	}()
+}
+
+func syncCluster() {
+	logger := log.SugaredLogger()
+	instanceColl := commonrepo.NewWorkflowControllerInstanceColl()
+	if err := instanceColl.Heartbeat(instanceID, instanceStartTime); err != nil {
+		logger.Errorf("failed to send the heartbeat of workflow controller %s, err: %s", instanceID, err)
+		return
+	}
+
+	aliveAfter := time.Now().Add(-instanceTimeout).Unix()
+	instances, err := instanceColl.ListAlive(aliveAfter)
+	if err != nil {
+		logger.Errorf("failed to list workflow controller instances, err: %s", err)
+		return
+	}
+	names := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		names = append(names, instance.ID)
+	}
+	ringMutex.Lock()
+	ring = newHashRing(names)
+	ringMutex.Unlock()
+
+	leader, err := commonrepo.NewWorkflowControllerLeaseColl().Acquire(instanceID, leaseTTL)
+	if err != nil {
+		logger.Errorf("failed to acquire the workflow controller lease, err: %s", err)
+		leader = false
+	}
+	if leader {
+		if atomic.SwapInt32(&isLeader, 1) == 0 {
+			logger.Infof("workflow controller %s becomes the leader", instanceID)
+		}
+		reapDeadInstances(names, aliveAfter)
+	} else {
+		atomic.StoreInt32(&isLeader, 0)
+	}
+
+	cancelRemovedTasks()
+	adoptOrphanTasks()
+}
+
+// IsLeader returns true if the instance holds the workflow controller lease.
+func IsLeader() bool {
+	return atomic.LoadInt32(&isLeader) == 1
+}
+
+// ownsWorkflow returns true if the tasks of the workflow should be run by this instance.
+func ownsWorkflow(workflowName string) bool {
+	ringMutex.RLock()
+	defer ringMutex.RUnlock()
+	return ring.Get(workflowName) == instanceID
+}
+
+// reapDeadInstances releases the tasks run by the dead instances, they are resumed by the instances
+// owning the workflows.
+func reapDeadInstances(alive []string, aliveAfter int64) {
+	logger := log.SugaredLogger()
+	if err := commonrepo.NewWorkflowControllerInstanceColl().DeleteDead(aliveAfter); err != nil {
+		logger.Errorf("failed to delete dead workflow controller instances, err: %s", err)
+	}
+	aliveSet := make(map[string]bool, len(alive))
+	for _, instance := range alive {
+		aliveSet[instance] = true
+	}
+	for _, t := range RunningAndQueuedTasks() {
+		if t.Owner == "" || aliveSet[t.Owner] {
+			continue
+		}
+		logger.Infof("workflow controller %s is dead, release task %s:%d", t.Owner, t.WorkflowName, t.TaskID)
+		if _, err := commonrepo.NewWorkflowQueueColl().Adopt(t, ""); err != nil {
+			logger.Errorf("failed to release task %s:%d, err: %s", t.WorkflowName, t.TaskID, err)
+		}
+	}
+}
+
+// adoptOrphanTasks resumes the released tasks of the workflows owned by this instance.
+func adoptOrphanTasks() {
+	if jobcontroller.Detached() {
+		return
+	}
+	logger := log.SugaredLogger()
+	for _, t := range RunningAndQueuedTasks() {
+		if t.Owner != "" || !ownsWorkflow(t.WorkflowName) {
+			continue
+		}
+		resumeTask(t, logger)
+	}
+}
+
+// cancelRemovedTasks stops the local tasks whose queue items are removed, which happens when a task
+// is cancelled by a request handled by another instance.
+func cancelRemovedTasks() {
+	queues, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{Owner: instanceID})
+	if err != nil {
+		log.Errorf("failed to list the queue of workflow controller %s, err: %s", instanceID, err)
+		return
+	}
+	owned := make(map[string]bool, len(queues))
+	for _, q := range queues {
+		owned[fmt.Sprintf("%s-%d", q.WorkflowName, q.TaskID)] = true
+	}
+	cancelChannelMap.Range(func(key, value interface{}) bool {
+		if owned[key.(string)] {
+			return true
+		}
+		if f, ok := value.(context.CancelFunc); ok {
+			log.Infof("queue item of task %s is removed, cancel it", key)
+			f()
+		}
+		return true
+	})
+}
+
+// isStaleTask returns true if the incomplete task is left by this instance before it restarts, or
+// is not in the queue at all.
+func isStaleTask(task *commonmodels.WorkflowTask, queues map[string]*commonmodels.WorkflowQueue) bool {
+	q, ok := queues[fmt.Sprintf("%s-%d", task.WorkflowName, task.TaskID)]
+	if !ok {
+		return true
+	}
+	return q.Owner == "" || q.Owner == instanceID
+}
+
+// LeaveCluster removes the instance at shutdown, so that its workflows are taken over without
+// waiting for the heartbeat timeout.
+func LeaveCluster() {
+	if instanceID == "" {
+		return
+	}
+	// the running tasks are released so that they are resumed by the other instances, or by this
+	// instance after it restarts.
+	jobcontroller.Detach()
+	queues, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{Owner: instanceID})
+	if err != nil {
+		log.Warnf("failed to list the queue of workflow controller %s, err: %s", instanceID, err)
+	}
+	for _, q := range queues {
+		if !isActiveTask(q) {
+			continue
+		}
+		if _, err := commonrepo.NewWorkflowQueueColl().Adopt(q, ""); err != nil {
+			log.Warnf("failed to release task %s:%d, err: %s", q.WorkflowName, q.TaskID, err)
+		}
+	}
+	if err := commonrepo.NewWorkflowControllerInstanceColl().Delete(instanceID); err != nil {
+		log.Warnf("failed to remove workflow controller %s, err: %s", instanceID, err)
+	}
+	if err := commonrepo.NewWorkflowControllerLeaseColl().Release(instanceID); err != nil {
+		log.Warnf("failed to release the workflow controller lease, err: %s", err)
+	}
+}
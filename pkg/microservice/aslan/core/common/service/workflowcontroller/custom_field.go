@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+)
+
+// getCustomFields puts the values of the custom fields of the project and the workflow of the task together, the
+// keys of the custom fields are unique across the scopes.
+func getCustomFields(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) map[string]string {
+	fields := make(map[string]string)
+	project, err := templaterepo.NewProductColl().Find(task.ProjectName)
+	if err != nil {
+		logger.Warnf("failed to find project %s, err: %s", task.ProjectName, err)
+	} else {
+		for key, value := range project.CustomFields {
+			fields[key] = value
+		}
+	}
+	if task.WorkflowArgs != nil {
+		for key, value := range task.WorkflowArgs.CustomFields {
+			fields[key] = value
+		}
+	}
+	return fields
+}
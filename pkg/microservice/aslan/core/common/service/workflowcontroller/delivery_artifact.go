@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// recordArtifactDeployments adds the deployments of the task to the activities of the delivery artifacts of the
+// deployed images, e.g. the images built by the external CI systems.
+func recordArtifactDeployments(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	url := fmt.Sprintf("/v1/projects/detail/%s/pipelines/custom/%s/%d", task.ProjectName, task.WorkflowName, task.TaskID)
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.Status != config.StatusPassed {
+				continue
+			}
+			envName, images := getDeployedImages(job)
+			for _, image := range images {
+				artifact, err := commonrepo.NewDeliveryArtifactColl().Get(&commonrepo.DeliveryArtifactArgs{Image: image})
+				if err != nil {
+					if err != mongo.ErrNoDocuments {
+						logger.Errorf("failed to find the delivery artifact of image %s, err: %s", image, err)
+					}
+					continue
+				}
+				activity := &commonmodels.DeliveryActivity{
+					ArtifactID:  artifact.ID,
+					Type:        setting.DeployType,
+					URL:         url,
+					EnvName:     envName,
+					StartTime:   job.StartTime,
+					EndTime:     job.EndTime,
+					CreatedBy:   task.TaskCreator,
+					CreatedTime: time.Now().Unix(),
+				}
+				if err := commonrepo.NewDeliveryActivityColl().Insert(activity); err != nil {
+					logger.Errorf("failed to record the deployment of image %s, err: %s", image, err)
+				}
+			}
+		}
+	}
+}
+
+func getDeployedImages(job *commonmodels.JobTask) (string, []string) {
+	switch job.JobType {
+	case string(config.JobZadigDeploy):
+		spec := &commonmodels.JobTaskDeploySpec{}
+		if err := commonmodels.IToi(job.Spec, spec); err != nil || spec.Image == "" {
+			return "", nil
+		}
+		return spec.Env, []string{spec.Image}
+	case string(config.JobZadigHelmDeploy):
+		spec := &commonmodels.JobTaskHelmDeploySpec{}
+		if err := commonmodels.IToi(job.Spec, spec); err != nil {
+			return "", nil
+		}
+		images := make([]string, 0, len(spec.ImageAndModules))
+		for _, image := range spec.ImageAndModules {
+			images = append(images, image.Image)
+		}
+		return spec.Env, images
+	}
+	return "", nil
+}
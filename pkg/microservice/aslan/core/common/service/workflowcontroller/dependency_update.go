@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"strconv"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// linkDependencyUpdate records the task on the dependency update if the task is triggered by the
+// pull request opened for the update.
+func linkDependencyUpdate(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	if task.WorkflowArgs == nil || task.WorkflowArgs.HookPayload == nil || !task.WorkflowArgs.HookPayload.IsPr {
+		return
+	}
+	payload := task.WorkflowArgs.HookPayload
+	prNumber, err := strconv.Atoi(payload.MergeRequestID)
+	if err != nil {
+		return
+	}
+	update, err := commonrepo.NewDependencyUpdateColl().FindByPR(payload.CodehostID, payload.Owner, payload.Repo, prNumber)
+	if err != nil {
+		return
+	}
+
+	err = commonrepo.NewDependencyUpdateColl().UpsertTask(update.ID, &commonmodels.DependencyUpdateTask{
+		WorkflowName: task.WorkflowName,
+		TaskID:       task.TaskID,
+		Status:       task.Status,
+		EndTime:      task.EndTime,
+	})
+	if err != nil {
+		logger.Errorf("failed to link task %s#%d to dependency update %s, err: %s", task.WorkflowName, task.TaskID, update.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// recordEnvAutoUpdates puts the images a passed task built from the tracked branches on the change feeds of
+// the environments, they are deployed by the auto updater of the workflow service. The builds of the pull
+// requests are not tracked.
+func recordEnvAutoUpdates(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	if task.Status != config.StatusPassed || task.TaskCreator == setting.EnvAutoUpdateTaskCreator {
+		return
+	}
+	policies, err := commonrepo.NewEnvAutoUpdatePolicyColl().List(task.ProjectName, "")
+	if err != nil {
+		logger.Errorf("failed to list env auto update policies of project %s, err: %s", task.ProjectName, err)
+		return
+	}
+
+	for _, policy := range policies {
+		if policy.SourceWorkflow != "" && policy.SourceWorkflow != task.WorkflowName {
+			continue
+		}
+		images, commits := getBranchArtifacts(task, policy.Branch, sets.NewString(policy.Services...))
+		if len(images) == 0 {
+			continue
+		}
+		event := &commonmodels.EnvAutoUpdateEvent{
+			PolicyID:       policy.ID.Hex(),
+			ProjectName:    policy.ProjectName,
+			EnvName:        policy.EnvName,
+			Branch:         policy.Branch,
+			SourceWorkflow: task.WorkflowName,
+			SourceTaskID:   task.TaskID,
+			Commits:        commits,
+			Images:         images,
+			Status:         config.EnvAutoUpdatePending,
+		}
+		if policy.Paused {
+			event.Status = config.EnvAutoUpdateSkipped
+			event.Message = fmt.Sprintf("auto update is paused by %s", policy.PausedBy)
+		}
+		if err := commonrepo.NewEnvAutoUpdateEventColl().Create(event); err != nil {
+			logger.Errorf("failed to record task %s#%d for env %s, err: %s", task.WorkflowName, task.TaskID, policy.EnvName, err)
+		}
+	}
+}
+
+// getBranchArtifacts returns the images of the services built from the branch, all the services are returned
+// if the set is empty.
+func getBranchArtifacts(task *commonmodels.WorkflowTask, branch string, services sets.String) ([]*commonmodels.ServiceAndImage, []*commonmodels.ReleaseTrainCommit) {
+	images := make([]*commonmodels.ServiceAndImage, 0)
+	commits := make([]*commonmodels.ReleaseTrainCommit, 0)
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			artifact, jobCommits := getBuildJobArtifact(job)
+			if artifact == nil || (services.Len() > 0 && !services.Has(artifact.ServiceName)) {
+				continue
+			}
+			fromBranch := false
+			for _, commit := range jobCommits {
+				if commit.Branch == branch && commit.PR == 0 {
+					fromBranch = true
+				}
+			}
+			if !fromBranch {
+				continue
+			}
+			images = append(images, artifact)
+			commits = append(commits, jobCommits...)
+		}
+	}
+	return images, commits
+}
+
+// finishEnvAutoUpdates records the result of the task deploying the new builds on the change feeds.
+func finishEnvAutoUpdates(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	if task.TaskCreator != setting.EnvAutoUpdateTaskCreator {
+		return
+	}
+	status, message := config.EnvAutoUpdateDeployed, ""
+	if task.Status != config.StatusPassed {
+		status, message = config.EnvAutoUpdateFailed, fmt.Sprintf("the deploy task is %s", task.Status)
+	}
+	if err := commonrepo.NewEnvAutoUpdateEventColl().FinishDeployTask(task.WorkflowName, task.TaskID, status, message); err != nil {
+		logger.Errorf("failed to update env auto updates deployed by task %s#%d, err: %s", task.WorkflowName, task.TaskID, err)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/types"
+	stepspec "github.com/koderover/zadig/pkg/types/step"
+)
+
+var _ = Describe("Testing env auto update", func() {
+
+	buildJob := func(service, image string, repo *types.Repository) *commonmodels.JobTask {
+		return &commonmodels.JobTask{
+			JobType: string(config.JobZadigBuild),
+			Status:  config.StatusPassed,
+			Spec: &commonmodels.JobTaskBuildSpec{
+				Properties: commonmodels.JobProperties{Envs: []*commonmodels.KeyVal{
+					{Key: "SERVICE", Value: service},
+					{Key: "SERVICE_MODULE", Value: service},
+					{Key: "IMAGE", Value: image},
+				}},
+				Steps: []*commonmodels.StepTask{
+					{StepType: config.StepGit, Spec: &stepspec.StepGitSpec{Repos: []*types.Repository{repo}}},
+				},
+			},
+		}
+	}
+
+	task := &commonmodels.WorkflowTask{Stages: []*commonmodels.StageTask{{Jobs: []*commonmodels.JobTask{
+		buildJob("api", "api:main", &types.Repository{RepoName: "api", Branch: "main", CommitID: "a1"}),
+		buildJob("web", "web:main", &types.Repository{RepoName: "web", Branch: "main", CommitID: "b1"}),
+		buildJob("worker", "worker:dev", &types.Repository{RepoName: "worker", Branch: "dev", CommitID: "c1"}),
+		buildJob("job", "job:pr", &types.Repository{RepoName: "job", Branch: "main", PR: 12, CommitID: "d1"}),
+	}}}}
+
+	It("returns the images built from the branch only", func() {
+		images, commits := getBranchArtifacts(task, "main", sets.NewString())
+		Expect(images).To(HaveLen(2))
+		Expect(images[0].Image).To(Equal("api:main"))
+		Expect(images[1].Image).To(Equal("web:main"))
+		Expect(commits).To(HaveLen(2))
+	})
+
+	It("returns the images of the tracked services only", func() {
+		images, _ := getBranchArtifacts(task, "main", sets.NewString("web"))
+		Expect(images).To(HaveLen(1))
+		Expect(images[0].ServiceName).To(Equal("web"))
+	})
+})
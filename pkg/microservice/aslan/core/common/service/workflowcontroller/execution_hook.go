@@ -0,0 +1,251 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	executionHookEvent = "ExecutionHook"
+
+	defaultExecutionHookTimeout  = 10 * time.Second
+	defaultExecutionHookMaxDelay = 30 * time.Minute
+	defaultExecutionHookDelay    = 30 * time.Second
+	minExecutionHookDelay        = 5 * time.Second
+	maxExecutionHookDelay        = 10 * time.Minute
+)
+
+type executionHookRequest struct {
+	EventName    string                    `json:"event_name"`
+	HookName     string                    `json:"hook_name"`
+	Event        config.ExecutionHookEvent `json:"event"`
+	ProjectName  string                    `json:"project_name"`
+	WorkflowName string                    `json:"workflow_name"`
+	TaskID       int64                     `json:"task_id"`
+	TaskCreator  string                    `json:"task_creator"`
+	// Status, StartTime and EndTime are sent to the post-finish hooks only.
+	Status    config.Status `json:"status,omitempty"`
+	StartTime int64         `json:"start_time,omitempty"`
+	EndTime   int64         `json:"end_time,omitempty"`
+	// Labels are the labels of the task given when it is created.
+	Labels map[string]string `json:"labels,omitempty"`
+	// CustomFields are the values of the custom fields of the workflow and its project.
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+	// Timestamp is signed together with the other fields, the system may reject the stale requests.
+	Timestamp int64 `json:"timestamp"`
+}
+
+type executionHookResponse struct {
+	Decision config.ExecutionHookDecision `json:"decision"`
+	Message  string                       `json:"message"`
+	// Delay is in seconds, the pre-start hook is called again after it if the task is delayed.
+	Delay int `json:"delay"`
+}
+
+// runPreStartHooks calls the pre-start hooks of the workflow one by one before the stages run, the task
+// starts only if all of them let it proceed. The status the task ends with is returned if it does not start.
+// The hooks which have let the task proceed are not called again when the task is resumed.
+func runPreStartHooks(ctx context.Context, task *commonmodels.WorkflowTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func()) (config.Status, error) {
+	if task.WorkflowArgs == nil {
+		return "", nil
+	}
+	for _, hook := range task.WorkflowArgs.ExecutionHooks {
+		if hook.Event != config.ExecutionHookPreStart {
+			continue
+		}
+		result := findExecutionHookResult(task, hook)
+		if result != nil && result.Decision == config.ExecutionHookProceed {
+			continue
+		}
+		if result == nil {
+			result = &commonmodels.ExecutionHookResult{Name: hook.Name, Event: hook.Event}
+			task.ExecutionHookResults = append(task.ExecutionHookResults, result)
+		}
+
+		if status, err := waitForPreStartHook(ctx, hook, result, workflowCtx, ack); err != nil {
+			return status, err
+		}
+	}
+	return "", nil
+}
+
+func waitForPreStartHook(ctx context.Context, hook *commonmodels.ExecutionHook, result *commonmodels.ExecutionHookResult, workflowCtx *commonmodels.WorkflowTaskCtx, ack func()) (config.Status, error) {
+	defer ack()
+
+	maxDelay := defaultExecutionHookMaxDelay
+	if hook.MaxDelay > 0 {
+		maxDelay = time.Duration(hook.MaxDelay) * time.Minute
+	}
+	timeout := time.After(maxDelay)
+	for {
+		resp, err := callExecutionHook(hook, &executionHookRequest{
+			EventName:    executionHookEvent,
+			HookName:     hook.Name,
+			Event:        hook.Event,
+			ProjectName:  workflowCtx.ProjectName,
+			WorkflowName: workflowCtx.WorkflowName,
+			TaskID:       workflowCtx.TaskID,
+			TaskCreator:  workflowCtx.TaskCreator,
+			Labels:       workflowCtx.Labels,
+			CustomFields: workflowCtx.CustomFields,
+			Timestamp:    time.Now().Unix(),
+		})
+		result.CalledAt = time.Now().Unix()
+		if err != nil {
+			result.Error = err.Error()
+			if hook.FailOpen {
+				result.Decision = config.ExecutionHookProceed
+				return "", nil
+			}
+			return config.StatusFailed, fmt.Errorf("failed to call the pre-start hook %s: %s", hook.Name, err)
+		}
+		result.Error = ""
+		result.Decision = resp.Decision
+		result.Message = resp.Message
+
+		switch resp.Decision {
+		case "", config.ExecutionHookProceed:
+			result.Decision = config.ExecutionHookProceed
+			return "", nil
+		case config.ExecutionHookVeto:
+			return config.StatusFailed, fmt.Errorf("the pre-start hook %s vetoed this task: %s", hook.Name, resp.Message)
+		case config.ExecutionHookDelay:
+		default:
+			return config.StatusFailed, fmt.Errorf("unknown decision %q of the pre-start hook %s", resp.Decision, hook.Name)
+		}
+		ack()
+
+		select {
+		case <-ctx.Done():
+			return config.StatusCancelled, fmt.Errorf("workflow was canceled")
+		case <-timeout:
+			return config.StatusTimeout, fmt.Errorf("the pre-start hook %s delayed this task for more than %s", hook.Name, maxDelay)
+		case <-time.After(executionHookDelay(resp)):
+		}
+	}
+}
+
+// callPostFinishHooks tells the post-finish hooks of the workflow that the task has finished, the failures
+// are recorded in the task and not retried.
+func callPostFinishHooks(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	if task.WorkflowArgs == nil {
+		return
+	}
+	var customFields map[string]string
+	called := false
+	for _, hook := range task.WorkflowArgs.ExecutionHooks {
+		if hook.Event != config.ExecutionHookPostFinish {
+			continue
+		}
+		if !called {
+			customFields = getCustomFields(task, logger)
+			called = true
+		}
+
+		result := &commonmodels.ExecutionHookResult{Name: hook.Name, Event: hook.Event, CalledAt: time.Now().Unix()}
+		resp, err := callExecutionHook(hook, &executionHookRequest{
+			EventName:    executionHookEvent,
+			HookName:     hook.Name,
+			Event:        hook.Event,
+			ProjectName:  task.ProjectName,
+			WorkflowName: task.WorkflowName,
+			TaskID:       task.TaskID,
+			TaskCreator:  task.TaskCreator,
+			Status:       task.Status,
+			StartTime:    task.StartTime,
+			EndTime:      task.EndTime,
+			Labels:       task.Labels,
+			CustomFields: customFields,
+			Timestamp:    time.Now().Unix(),
+		})
+		if err != nil {
+			logger.Warnf("failed to call the post-finish hook %s of %s #%d: %s", hook.Name, task.WorkflowName, task.TaskID, err)
+			result.Error = err.Error()
+		} else {
+			result.Message = resp.Message
+		}
+		task.ExecutionHookResults = append(task.ExecutionHookResults, result)
+	}
+	if !called {
+		return
+	}
+
+	if err := commonrepo.NewworkflowTaskv4Coll().Update(task.ID.Hex(), task); err != nil {
+		logger.Errorf("failed to save the results of the post-finish hooks of %s #%d: %s", task.WorkflowName, task.TaskID, err)
+	}
+}
+
+func callExecutionHook(hook *commonmodels.ExecutionHook, req *executionHookRequest) (*executionHookResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{webhookApprovalEventHeader: executionHookEvent}
+	if hook.Secret != "" {
+		headers[webhookApprovalSignatureHeader] = signWebhookApproval(hook.Secret, body)
+	}
+	timeout := defaultExecutionHookTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+
+	resp := &executionHookResponse{}
+	_, err = httpclient.Post(hook.URL,
+		httpclient.SetHeaders(headers),
+		httpclient.SetBody(body),
+		httpclient.SetResult(resp),
+		httpclient.SetRequestTimeout(timeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func findExecutionHookResult(task *commonmodels.WorkflowTask, hook *commonmodels.ExecutionHook) *commonmodels.ExecutionHookResult {
+	for _, result := range task.ExecutionHookResults {
+		if result.Name == hook.Name && result.Event == hook.Event {
+			return result
+		}
+	}
+	return nil
+}
+
+func executionHookDelay(resp *executionHookResponse) time.Duration {
+	delay := defaultExecutionHookDelay
+	if resp.Delay > 0 {
+		delay = time.Duration(resp.Delay) * time.Second
+	}
+	if delay < minExecutionHookDelay {
+		return minExecutionHookDelay
+	}
+	if delay > maxExecutionHookDelay {
+		return maxExecutionHookDelay
+	}
+	return delay
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+var _ = Describe("Testing execution hooks", func() {
+	var calls int
+
+	newHookServer := func(decision config.ExecutionHookDecision) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			req := &executionHookRequest{}
+			Expect(json.NewDecoder(r.Body).Decode(req)).To(Succeed())
+			Expect(req.Event).To(Equal(config.ExecutionHookPreStart))
+			Expect(r.Header.Get(webhookApprovalEventHeader)).To(Equal(executionHookEvent))
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(&executionHookResponse{Decision: decision, Message: "checked"})
+		}))
+	}
+
+	newTask := func(hooks ...*commonmodels.ExecutionHook) *commonmodels.WorkflowTask {
+		return &commonmodels.WorkflowTask{WorkflowName: "release", TaskID: 1, WorkflowArgs: &commonmodels.WorkflowV4{ExecutionHooks: hooks}}
+	}
+
+	runHooks := func(task *commonmodels.WorkflowTask) (config.Status, error) {
+		return runPreStartHooks(context.Background(), task, &commonmodels.WorkflowTaskCtx{WorkflowName: task.WorkflowName, TaskID: task.TaskID}, func() {})
+	}
+
+	BeforeEach(func() {
+		calls = 0
+	})
+
+	It("starts the task the hooks let proceed and does not call them again", func() {
+		server := newHookServer(config.ExecutionHookProceed)
+		defer server.Close()
+
+		task := newTask(
+			&commonmodels.ExecutionHook{Name: "capacity", Event: config.ExecutionHookPreStart, URL: server.URL},
+			&commonmodels.ExecutionHook{Name: "recorder", Event: config.ExecutionHookPostFinish, URL: server.URL},
+		)
+		_, err := runHooks(task)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(1))
+		Expect(task.ExecutionHookResults).To(HaveLen(1))
+		Expect(task.ExecutionHookResults[0].Decision).To(Equal(config.ExecutionHookProceed))
+
+		_, err = runHooks(task)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(1))
+	})
+
+	It("fails the task vetoed by a hook", func() {
+		server := newHookServer(config.ExecutionHookVeto)
+		defer server.Close()
+
+		task := newTask(&commonmodels.ExecutionHook{Name: "compliance", Event: config.ExecutionHookPreStart, URL: server.URL})
+		status, err := runHooks(task)
+		Expect(err).To(HaveOccurred())
+		Expect(status).To(Equal(config.StatusFailed))
+		Expect(task.ExecutionHookResults[0].Message).To(Equal("checked"))
+	})
+
+	It("lets the task proceed if a fail-open hook can not be reached", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		_, err := runHooks(newTask(&commonmodels.ExecutionHook{Name: "capacity", Event: config.ExecutionHookPreStart, URL: server.URL}))
+		Expect(err).To(HaveOccurred())
+
+		task := newTask(&commonmodels.ExecutionHook{Name: "capacity", Event: config.ExecutionHookPreStart, URL: server.URL, FailOpen: true})
+		_, err = runHooks(task)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(task.ExecutionHookResults[0].Error).NotTo(BeEmpty())
+	})
+
+	It("bounds the delay", func() {
+		Expect(executionHookDelay(&executionHookResponse{})).To(Equal(defaultExecutionHookDelay))
+		Expect(executionHookDelay(&executionHookResponse{Delay: 1})).To(Equal(minExecutionHookDelay))
+		Expect(executionHookDelay(&executionHookResponse{Delay: 3600})).To(Equal(maxExecutionHookDelay))
+	})
+})
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"github.com/buraksezer/consistent"
+	"github.com/cespare/xxhash"
+)
+
+type member string
+
+func (m member) String() string {
+	return string(m)
+}
+
+type hasher struct{}
+
+func (h hasher) Sum64(data []byte) uint64 {
+	return xxhash.Sum64(data)
+}
+
+// hashRing assigns the workflows to the controller instances with consistent hashing, only the
+// workflows of an instance are moved to the others when it joins or leaves.
+type hashRing struct {
+	c *consistent.Consistent
+}
+
+func newHashRing(instances []string) *hashRing {
+	if len(instances) == 0 {
+		return &hashRing{}
+	}
+	members := make([]consistent.Member, 0, len(instances))
+	for _, instance := range instances {
+		members = append(members, member(instance))
+	}
+	cfg := consistent.Config{
+		PartitionCount:    271,
+		ReplicationFactor: 20,
+		Load:              1.25,
+		Hasher:            hasher{},
+	}
+	return &hashRing{c: consistent.New(members, cfg)}
+}
+
+// Get returns the instance of the key, an empty string is returned if there is no instance.
+func (r *hashRing) Get(key string) string {
+	if r.c == nil {
+		return ""
+	}
+	return r.c.LocateKey([]byte(key)).String()
+}
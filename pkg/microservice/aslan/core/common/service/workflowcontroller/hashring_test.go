@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkflowController(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "workflow controller Suite")
+}
+
+var _ = Describe("Testing hash ring", func() {
+
+	It("returns nothing without instances", func() {
+		Expect(newHashRing(nil).Get("workflow")).To(BeEmpty())
+	})
+
+	It("only moves the workflows of the removed instance", func() {
+		before := newHashRing([]string{"aslan-0", "aslan-1", "aslan-2"})
+		after := newHashRing([]string{"aslan-0", "aslan-2"})
+
+		counts := map[string]int{}
+		for i := 0; i < 3000; i++ {
+			key := fmt.Sprintf("workflow-%d", i)
+			owner := before.Get(key)
+			counts[owner]++
+			if owner != "aslan-1" {
+				Expect(after.Get(key)).To(Equal(owner))
+			} else {
+				Expect(after.Get(key)).NotTo(Equal("aslan-1"))
+			}
+		}
+		for _, instance := range []string{"aslan-0", "aslan-1", "aslan-2"} {
+			Expect(counts[instance]).To(BeNumerically(">", 500))
+		}
+	})
+})
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+)
+
+// notifyJobSummaries sends the summaries reported by the jobs of the ended task to the summary
+// notification of the workflow.
+func notifyJobSummaries(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	if task.WorkflowArgs == nil || task.WorkflowArgs.SummaryNotifyCtl == nil || !task.WorkflowArgs.SummaryNotifyCtl.Enabled {
+		return
+	}
+	summaries := task.JobSummaries()
+	if len(summaries) == 0 {
+		return
+	}
+
+	title := fmt.Sprintf("工作流 %s #%d 执行结果", task.WorkflowName, task.TaskID)
+	contents := []string{fmt.Sprintf("### %s\n\n项目：%s, 状态：%s", title, task.ProjectName, task.Status)}
+	for _, s := range summaries {
+		contents = append(contents, s.Markdown())
+	}
+	if err := instantmessage.NewWeChatClient().SendSystemMessage(task.WorkflowArgs.SummaryNotifyCtl, title, strings.Join(contents, "\n\n")); err != nil {
+		logger.Warnf("failed to send the summaries of task %s:%d, err: %s", task.WorkflowName, task.TaskID, err)
+	}
+}
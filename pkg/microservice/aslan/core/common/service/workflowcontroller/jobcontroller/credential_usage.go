@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// recordCredentialUsages records the codehost tokens, registry credentials and project secrets handed to the
+// job when it starts. A failure is only logged, the audit never blocks the job.
+func recordCredentialUsages(job *commonmodels.JobTask, spec *commonmodels.JobTaskBuildSpec, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) {
+	usages := getCredentialUsages(spec, workflowCtx.ProjectName, logger)
+	if len(usages) == 0 {
+		return
+	}
+
+	clusterID := spec.Properties.ClusterID
+	if spec.Properties.Runner != "" || len(spec.Properties.RunnerLabels) > 0 {
+		clusterID = ""
+	}
+	now := time.Now().Unix()
+	for _, usage := range usages {
+		usage.ProjectName = workflowCtx.ProjectName
+		usage.WorkflowName = workflowCtx.WorkflowName
+		usage.TaskID = workflowCtx.TaskID
+		usage.TaskCreator = workflowCtx.TaskCreator
+		usage.JobName = job.Name
+		usage.JobType = job.JobType
+		usage.ClusterID = clusterID
+		usage.Runner = spec.Properties.Runner
+		usage.UsedAt = now
+	}
+	if err := commonrepo.NewCredentialUsageColl().BulkCreate(usages); err != nil {
+		logger.Warnf("failed to record the credential usages of job %s: %s", job.Name, err)
+	}
+}
+
+// getCredentialUsages returns a usage for each credential of the job, the credentials used by several steps
+// are recorded once.
+func getCredentialUsages(spec *commonmodels.JobTaskBuildSpec, projectName string, logger *zap.SugaredLogger) []*commonmodels.CredentialUsage {
+	resp := make([]*commonmodels.CredentialUsage, 0)
+	seen := make(map[string]bool)
+	add := func(kind, id, name string) {
+		if id == "" || seen[kind+"/"+id] {
+			return
+		}
+		seen[kind+"/"+id] = true
+		resp = append(resp, &commonmodels.CredentialUsage{Kind: kind, CredentialID: id, CredentialName: name})
+	}
+
+	for _, stepTask := range spec.Steps {
+		switch stepTask.StepType {
+		case config.StepGit:
+			gitSpec := &step.StepGitSpec{}
+			if err := commonmodels.IToi(stepTask.Spec, gitSpec); err != nil {
+				logger.Warnf("failed to parse the spec of step %s: %s", stepTask.Name, err)
+				continue
+			}
+			for _, repo := range gitSpec.Repos {
+				if repo.CodehostID == 0 {
+					continue
+				}
+				add(config.CredentialKindCodehost, strconv.Itoa(repo.CodehostID), repo.Address)
+			}
+		case config.StepDockerBuild:
+			dockerBuildSpec := &step.StepDockerBuildSpec{}
+			if err := commonmodels.IToi(stepTask.Spec, dockerBuildSpec); err != nil {
+				logger.Warnf("failed to parse the spec of step %s: %s", stepTask.Name, err)
+				continue
+			}
+			if registry := dockerBuildSpec.DockerRegistry; registry != nil {
+				add(config.CredentialKindRegistry, registry.DockerRegistryID, registry.Host)
+			}
+		}
+	}
+	for _, registry := range spec.Properties.Registries {
+		if registry.ID.IsZero() {
+			continue
+		}
+		add(config.CredentialKindRegistry, registry.ID.Hex(), registry.RegAddr)
+	}
+
+	if len(spec.Properties.VariableGroups) == 0 {
+		return resp
+	}
+	groups, err := commonrepo.NewVariableGroupColl().ListByNames(projectName, spec.Properties.VariableGroups)
+	if err != nil {
+		logger.Warnf("failed to list the variable groups of project %s: %s", projectName, err)
+		return resp
+	}
+	for _, group := range groups {
+		keys := make([]string, 0)
+		for _, kv := range group.Variables {
+			if kv.IsCredential {
+				keys = append(keys, kv.Key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		resp = append(resp, &commonmodels.CredentialUsage{
+			Kind:           config.CredentialKindSecret,
+			CredentialID:   group.ID.Hex(),
+			CredentialName: group.Name,
+			Keys:           keys,
+		})
+	}
+	return resp
+}
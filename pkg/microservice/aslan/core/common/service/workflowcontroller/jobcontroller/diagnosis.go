@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/diagnosis"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// diagnoseJobPod diagnoses the failed job with its log, the events of the pod and the terminated containers.
+// The job is not failed by the errors of the diagnosis, they are only logged.
+func diagnoseJobPod(namespace string, pod *corev1.Pod, logFileName string, clientSet kubernetes.Interface) []*diagnosis.Diagnosis {
+	input := &diagnosis.Input{}
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.State.Terminated != nil {
+			input.TerminationReasons = append(input.TerminationReasons, status.State.Terminated.Reason)
+		}
+		if status.LastTerminationState.Terminated != nil {
+			input.TerminationReasons = append(input.TerminationReasons, status.LastTerminationState.Terminated.Reason)
+		}
+	}
+
+	events, err := clientSet.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.Set{"involvedObject.name": pod.Name}.String(),
+	})
+	if err != nil {
+		log.Warnf("failed to list the events of pod %s/%s: %s", namespace, pod.Name, err)
+	} else {
+		for _, event := range events.Items {
+			input.Events = append(input.Events, &diagnosis.Event{Reason: event.Reason, Message: event.Message})
+		}
+	}
+
+	file, err := os.Open(logFileName)
+	if err != nil {
+		log.Warnf("failed to open the log of pod %s/%s: %s", namespace, pod.Name, err)
+	} else {
+		defer file.Close()
+		input.Log = file
+	}
+
+	diagnoses, err := diagnosis.DefaultEngine().Diagnose(input)
+	if err != nil {
+		log.Warnf("failed to diagnose pod %s/%s: %s", namespace, pod.Name, err)
+	}
+	return diagnoses
+}
+
+// diagnoseJobError adds the diagnoses found in the error of the failed job, e.g. the error of helm returned
+// by a deploy job, the diagnoses found in the log of the job are kept.
+func diagnoseJobError(job *commonmodels.JobTask) {
+	if job.Error == "" || (job.Status != config.StatusFailed && job.Status != config.StatusTimeout) {
+		return
+	}
+	diagnoses, _ := diagnosis.DefaultEngine().Diagnose(&diagnosis.Input{Errors: []string{job.Error}})
+	for _, found := range diagnoses {
+		exists := false
+		for _, d := range job.Diagnoses {
+			if d.RuleID == found.RuleID {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			job.Diagnoses = append(job.Diagnoses, found)
+		}
+	}
+}
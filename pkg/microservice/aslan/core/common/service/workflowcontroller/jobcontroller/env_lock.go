@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+const (
+	// envLockTTL is long enough for the slowest deploy, the lock is released when the task is done and
+	// expires in case aslan exits without releasing it.
+	envLockTTL          = 6 * time.Hour
+	envLockPollInterval = 5 * time.Second
+)
+
+// deployEnv returns the environment changed by the job, an empty string is returned if the job
+// does not deploy to an environment.
+func deployEnv(job *commonmodels.JobTask) string {
+	switch job.JobType {
+	case string(config.JobZadigDeploy):
+		spec := &commonmodels.JobTaskDeploySpec{}
+		if err := commonmodels.IToi(job.Spec, spec); err != nil {
+			return ""
+		}
+		return spec.Env
+	case string(config.JobZadigHelmDeploy):
+		spec := &commonmodels.JobTaskHelmDeploySpec{}
+		if err := commonmodels.IToi(job.Spec, spec); err != nil {
+			return ""
+		}
+		return spec.Env
+	}
+	return ""
+}
+
+// acquireEnvLock waits until the environment deployed by the job is locked by the task, the jobs of
+// the same task share the lock. The job is waiting with the holder of the lock until then.
+func acquireEnvLock(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) error {
+	envName := deployEnv(job)
+	if envName == "" {
+		return nil
+	}
+	lock := &commonmodels.EnvLock{
+		ProjectName:  workflowCtx.ProjectName,
+		EnvName:      envName,
+		Owner:        commonmodels.WorkflowEnvLockOwner(workflowCtx.WorkflowName, workflowCtx.TaskID),
+		Kind:         commonmodels.EnvLockKindWorkflow,
+		WorkflowName: workflowCtx.WorkflowName,
+		TaskID:       workflowCtx.TaskID,
+		LockedBy:     workflowCtx.TaskCreator,
+	}
+
+	waiting := false
+	defer func() {
+		if waiting {
+			job.EnvLock = nil
+			ack()
+		}
+	}()
+	for {
+		ok, err := commonrepo.NewEnvLockColl().Acquire(lock, envLockTTL)
+		if err != nil {
+			logger.Errorf("failed to lock env %s/%s: %s", lock.ProjectName, envName, err)
+			job.Status = config.StatusFailed
+			job.Error = fmt.Sprintf("failed to lock env %s: %s", envName, err)
+			return err
+		}
+		if ok {
+			job.Status = config.StatusRunning
+			return nil
+		}
+
+		holder, err := commonrepo.NewEnvLockColl().Find(lock.ProjectName, envName)
+		if err != nil {
+			logger.Warnf("failed to find the lock of env %s/%s: %s", lock.ProjectName, envName, err)
+		}
+		if !waiting || holder != nil && (job.EnvLock == nil || job.EnvLock.Owner != holder.Owner) {
+			logger.Infof("job %s is waiting for the lock of env %s", job.Name, envName)
+			waiting = true
+			job.Status = config.StatusWaiting
+			job.EnvLock = holder
+			ack()
+		}
+
+		select {
+		case <-ctx.Done():
+			job.Status = config.StatusCancelled
+			return fmt.Errorf("workflow was canceled")
+		case <-time.After(envLockPollInterval):
+		}
+	}
+}
+
+// ReleaseEnvLocks releases the environments locked by the deploy jobs of the task.
+func ReleaseEnvLocks(workflowName string, taskID int64, logger *zap.SugaredLogger) {
+	if err := commonrepo.NewEnvLockColl().ReleaseByOwner(commonmodels.WorkflowEnvLockOwner(workflowName, taskID)); err != nil {
+		logger.Errorf("failed to release the env locks of %s-%d: %s", workflowName, taskID, err)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -38,7 +39,32 @@ type JobCtl interface {
 	Run(ctx context.Context)
 }
 
+// ResumableJobCtl is implemented by the jobs run in kubernetes jobs, the kubernetes job can be
+// reattached after aslan restarts instead of running the job again.
+type ResumableJobCtl interface {
+	Resume(ctx context.Context)
+}
+
+// detached is set when aslan shuts down, the running kubernetes jobs are kept so that they can be
+// reattached by the instance resuming the tasks.
+var detached int32
+
+// Detach stops cleaning up the kubernetes jobs, it is called before aslan shuts down.
+func Detach() {
+	atomic.StoreInt32(&detached, 1)
+}
+
+func Detached() bool {
+	return atomic.LoadInt32(&detached) == 1
+}
+
 func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	// the job is done before the task is resumed.
+	if jobDone(job.Status) {
+		return
+	}
+	// the job is running already if it is interrupted by a restart of aslan.
+	interrupted := job.Status == config.StatusRunning
 	// render global variables for every job.
 	workflowCtx.GlobalContextEach(func(k, v string) bool {
 		b, _ := json.Marshal(job)
@@ -46,16 +72,23 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 		json.Unmarshal([]byte(replacedString), &job)
 		return true
 	})
-	job.Status = config.StatusRunning
-	job.StartTime = time.Now().Unix()
-	ack()
+	if !interrupted {
+		job.Status = config.StatusRunning
+		job.StartTime = time.Now().Unix()
+		ack()
+	}
 
 	logger.Infof("start job: %s,status: %s", job.Name, job.Status)
 	defer func() {
+		diagnoseJobError(job)
 		job.EndTime = time.Now().Unix()
 		logger.Infof("finish job: %s,status: %s", job.Name, job.Status)
 		ack()
 	}()
+	// the deploy jobs of different tasks do not change the same environment at the same time.
+	if err := acquireEnvLock(ctx, job, workflowCtx, logger, ack); err != nil {
+		return
+	}
 	var jobCtl JobCtl
 	switch job.JobType {
 	case string(config.JobZadigDeploy):
@@ -66,13 +99,55 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 		jobCtl = NewCustomDeployJobCtl(job, workflowCtx, ack, logger)
 	case string(config.JobPlugin):
 		jobCtl = NewPluginsJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobJenkins):
+		jobCtl = NewJenkinsJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobZadigRollout):
+		jobCtl = NewRolloutJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobLicenseScan):
+		jobCtl = NewLicenseScanJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobSandboxCluster):
+		jobCtl = NewSandboxClusterJobCtl(job, workflowCtx, ack, logger)
 	default:
 		jobCtl = NewFreestyleJobCtl(job, workflowCtx, ack, logger)
 	}
 
+	if interrupted {
+		resumeJob(ctx, jobCtl, job, logger)
+		return
+	}
+	jobCtl.Run(ctx)
+}
+
+// resumeJob reattaches the kubernetes job or the runner job of the interrupted job, the job is run
+// again if it is not created yet or it can be run more than once.
+func resumeJob(ctx context.Context, jobCtl JobCtl, job *commonmodels.JobTask, logger *zap.SugaredLogger) {
+	logger.Infof("resume job: %s", job.Name)
+	if resumable, ok := jobCtl.(ResumableJobCtl); ok {
+		if job.K8sJobName != "" || job.RunnerJobID != "" {
+			resumable.Resume(ctx)
+			return
+		}
+		jobCtl.Run(ctx)
+		return
+	}
+	// the jenkins build may be triggered already, it is not triggered twice.
+	if job.JobType == string(config.JobJenkins) {
+		job.Status = config.StatusFailed
+		job.Error = "the job is interrupted by the restart of zadig"
+		return
+	}
+	// the deploy jobs only update the workloads to the target images, they can be run again.
 	jobCtl.Run(ctx)
 }
 
+func jobDone(status config.Status) bool {
+	switch status {
+	case config.StatusPassed, config.StatusSkipped, config.StatusFailed, config.StatusTimeout, config.StatusCancelled, config.StatusReject:
+		return true
+	}
+	return false
+}
+
 func RunJobs(ctx context.Context, jobs []*commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
 	jobPool := NewPool(ctx, jobs, workflowCtx, concurrency, logger, ack)
 	jobPool.Run()
@@ -89,6 +164,8 @@ type Pool struct {
 	ack         func()
 	ctx         context.Context
 	wg          sync.WaitGroup
+	// done is closed when the job with the name finishes.
+	done map[string]chan struct{}
 }
 
 // NewPool initializes a new pool with the given tasks and
@@ -102,6 +179,7 @@ func NewPool(ctx context.Context, jobs []*commonmodels.JobTask, workflowCtx *com
 		logger:      logger,
 		ack:         ack,
 		ctx:         ctx,
+		done:        make(map[string]chan struct{}, len(jobs)),
 	}
 }
 
@@ -112,7 +190,12 @@ func (p *Pool) Run() {
 		go p.work()
 	}
 
+	for _, job := range p.Jobs {
+		p.done[job.Name] = make(chan struct{})
+	}
+
 	p.wg.Add(len(p.Jobs))
+	// the jobs are sent in order, a job only waits for the jobs sent before it so the workers never deadlock.
 	for _, task := range p.Jobs {
 		p.jobsChan <- task
 	}
@@ -126,11 +209,46 @@ func (p *Pool) Run() {
 // The work loop for any single goroutine.
 func (p *Pool) work() {
 	for job := range p.jobsChan {
-		runJob(p.ctx, job, p.workflowCtx, p.logger, p.ack)
+		if p.waitDependencies(job) {
+			runJob(p.ctx, job, p.workflowCtx, p.logger, p.ack)
+		}
+		close(p.done[job.Name])
 		p.wg.Done()
 	}
 }
 
+// waitDependencies blocks until the jobs the job depends on finish, the job is skipped if any of
+// them does not pass.
+func (p *Pool) waitDependencies(job *commonmodels.JobTask) bool {
+	if jobDone(job.Status) {
+		return true
+	}
+	for _, name := range job.DependsOn {
+		done, ok := p.done[name]
+		if !ok {
+			continue
+		}
+		select {
+		case <-done:
+		case <-p.ctx.Done():
+			job.Status = config.StatusCancelled
+			p.ack()
+			return false
+		}
+		for _, dependency := range p.Jobs {
+			if dependency.Name != name || dependency.Status == config.StatusPassed {
+				continue
+			}
+			p.logger.Infof("skip job: %s, job %s it depends on is %s", job.Name, name, dependency.Status)
+			job.Status = config.StatusSkipped
+			job.Error = fmt.Sprintf("job %s it depends on is %s", name, dependency.Status)
+			p.ack()
+			return false
+		}
+	}
+	return true
+}
+
 func saveFile(src io.Reader, localFile string) error {
 	out, err := os.Create(localFile)
 	if err != nil {
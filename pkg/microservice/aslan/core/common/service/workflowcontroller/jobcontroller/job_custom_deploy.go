@@ -127,7 +127,7 @@ func (c *CustomDeployJobCtl) run(ctx context.Context) error {
 		}
 		for _, container := range statefulSet.Spec.Template.Spec.Containers {
 			if container.Name == c.jobTaskSpec.ContainerName {
-				err = updater.UpdateDeploymentImage(statefulSet.Namespace, statefulSet.Name, container.Name, c.jobTaskSpec.Image, c.kubeClient)
+				err = updater.UpdateStatefulSetImage(statefulSet.Namespace, statefulSet.Name, container.Name, c.jobTaskSpec.Image, c.kubeClient)
 				if err != nil {
 					err = errors.WithMessagef(
 						err,
@@ -148,6 +148,40 @@ func (c *CustomDeployJobCtl) run(ctx context.Context) error {
 				break
 			}
 		}
+	case setting.DaemonSet:
+		daemonSet, found, err := getter.GetDaemonSet(c.jobTaskSpec.Namespace, c.jobTaskSpec.WorkloadName, c.kubeClient)
+		if err == nil && !found {
+			err = fmt.Errorf("daemonset %s/%s not found", c.jobTaskSpec.Namespace, c.jobTaskSpec.WorkloadName)
+		}
+		if err != nil {
+			c.logger.Error(err)
+			c.job.Status = config.StatusFailed
+			c.job.Error = err.Error()
+			return err
+		}
+		for _, container := range daemonSet.Spec.Template.Spec.Containers {
+			if container.Name == c.jobTaskSpec.ContainerName {
+				err = updater.UpdateDaemonSetImage(daemonSet.Namespace, daemonSet.Name, container.Name, c.jobTaskSpec.Image, c.kubeClient)
+				if err != nil {
+					err = errors.WithMessagef(
+						err,
+						"failed to update container image in %s/daemonset/%s/%s",
+						daemonSet.Namespace, daemonSet.Name, container.Name)
+					c.logger.Error(err)
+					c.job.Status = config.StatusFailed
+					c.job.Error = err.Error()
+					return err
+				}
+				c.jobTaskSpec.ReplaceResources = append(c.jobTaskSpec.ReplaceResources, commonmodels.Resource{
+					Kind:      setting.DaemonSet,
+					Container: container.Name,
+					Origin:    container.Image,
+					Name:      daemonSet.Name,
+				})
+				replaced = true
+				break
+			}
+		}
 	default:
 		msg := fmt.Sprintf("workfload type: %s not supported", c.jobTaskSpec.WorkloadType)
 		c.logger.Error(msg)
@@ -217,6 +251,20 @@ func (c *CustomDeployJobCtl) wait(ctx context.Context) {
 				} else {
 					ready = wrapper.StatefulSet(st).Ready()
 				}
+			case setting.DaemonSet:
+				ds, found, e := getter.GetDaemonSet(c.jobTaskSpec.Namespace, c.jobTaskSpec.WorkloadName, c.kubeClient)
+				if e != nil || !found {
+					c.logger.Errorf(
+						"failed to check daemonSet ready status %s/%s/%s - %v",
+						c.jobTaskSpec.Namespace,
+						c.jobTaskSpec.WorkloadType,
+						c.jobTaskSpec.WorkloadName,
+						e,
+					)
+					ready = false
+				} else {
+					ready = wrapper.DaemonSet(ds).Ready()
+				}
 			default:
 				msg := fmt.Sprintf("workfload type: %s not supported", c.jobTaskSpec.WorkloadType)
 				c.logger.Error(msg)
@@ -31,6 +31,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
@@ -69,11 +70,80 @@ func (c *DeployJobCtl) Run(ctx context.Context) {
 	if err := c.run(ctx); err != nil {
 		return
 	}
-	if c.jobTaskSpec.SkipCheckRunStatus {
-		c.job.Status = config.StatusPassed
+	verification := c.jobTaskSpec.Verification
+	if verification == nil || !verification.Enabled {
+		if c.jobTaskSpec.SkipCheckRunStatus {
+			c.job.Status = config.StatusPassed
+			return
+		}
+		c.wait(ctx)
 		return
 	}
+
+	// the rollout is always waited for when the verification is enabled, a rollout failure is a verification failure.
 	c.wait(ctx)
+	var comparisonErr error
+	switch c.job.Status {
+	case config.StatusCancelled:
+		return
+	case config.StatusPassed:
+		results, err := runDeployVerification(ctx, verification, c.logger)
+		if err == nil && comparisonEnabled(verification) {
+			var comparisonResults []*commonmodels.VerificationResult
+			comparisonResults, comparisonErr = runDeployComparison(ctx, verification.Comparison, c.workflowCtx, c.jobTaskSpec.Env, c.jobTaskSpec.ServiceName, c.logger)
+			results = append(results, comparisonResults...)
+			err = comparisonErr
+		}
+		c.jobTaskSpec.VerificationResults = results
+		c.job.Spec = c.jobTaskSpec
+		if err == nil {
+			return
+		}
+		msg := fmt.Sprintf("post-deploy verification failed: %v", err)
+		c.logger.Error(msg)
+		c.job.Status = config.StatusFailed
+		c.job.Error = msg
+	}
+	c.rollback()
+	if comparisonErr != nil && ctx.Err() == nil {
+		alertDeployRegression(verification.Comparison, c.workflowCtx, c.jobTaskSpec.Env, c.jobTaskSpec.ServiceName, comparisonErr, c.jobTaskSpec.RolledBack, c.logger)
+	}
+}
+
+// rollback restores the images replaced by the job if the environment enables auto rollback.
+func (c *DeployJobCtl) rollback() {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:    c.workflowCtx.ProjectName,
+		EnvName: c.jobTaskSpec.Env,
+	})
+	if err != nil {
+		c.logger.Errorf("failed to find env %s for rollback: %v", c.jobTaskSpec.Env, err)
+		return
+	}
+	if !env.AutoRollback {
+		return
+	}
+
+	for _, resource := range c.jobTaskSpec.ReplaceResources {
+		switch resource.Kind {
+		case setting.Deployment:
+			err = updater.UpdateDeploymentImage(c.namespace, resource.Name, resource.Container, resource.Origin, c.kubeClient)
+		case setting.StatefulSet:
+			err = updater.UpdateStatefulSetImage(c.namespace, resource.Name, resource.Container, resource.Origin, c.kubeClient)
+		case setting.DaemonSet:
+			err = updater.UpdateDaemonSetImage(c.namespace, resource.Name, resource.Container, resource.Origin, c.kubeClient)
+		case setting.CronJob:
+			err = updater.UpdateCronJobImage(c.namespace, resource.Name, resource.Container, resource.Origin, c.kubeClient)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("failed to roll back %s/%s/%s to image %s: %v", c.namespace, resource.Kind, resource.Name, resource.Origin, err)
+			c.logger.Error(msg)
+			c.job.Error = fmt.Sprintf("%s\n%s", c.job.Error, msg)
+			return
+		}
+	}
+	c.jobTaskSpec.RolledBack = true
+	c.job.Spec = c.jobTaskSpec
 }
 
 func (c *DeployJobCtl) run(ctx context.Context) error {
@@ -118,6 +188,19 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 		c.restConfig = krkubeclient.RESTConfig()
 	}
 
+	if c.jobTaskSpec.PinImageDigest {
+		pinned, imageDigest, err := registry.PinImageDigest(c.jobTaskSpec.Image, c.logger)
+		if err != nil {
+			msg := fmt.Sprintf("failed to pin the digest of image %s: %v", c.jobTaskSpec.Image, err)
+			c.logger.Error(msg)
+			c.job.Status = config.StatusFailed
+			c.job.Error = msg
+			return errors.New(msg)
+		}
+		c.jobTaskSpec.Image, c.jobTaskSpec.ImageDigest = pinned, imageDigest
+		c.job.Spec = c.jobTaskSpec
+	}
+
 	// get servcie info
 	var (
 		serviceInfo *commonmodels.Service
@@ -147,6 +230,13 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 		}
 	}
 
+	if err = c.checkManifestPolicy(env, serviceInfo); err != nil {
+		return err
+	}
+	if err = c.previewDiff(ctx, env, serviceInfo); err != nil {
+		return err
+	}
+
 	if serviceInfo.WorkloadType == "" {
 		selector = labels.Set{setting.ProductLabel: c.workflowCtx.ProjectName, setting.ServiceLabel: c.jobTaskSpec.ServiceName}.AsSelector()
 
@@ -168,6 +258,21 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 			return err
 		}
 
+		var daemonSets []*appsv1.DaemonSet
+		daemonSets, err = getter.ListDaemonSets(env.Namespace, selector, c.kubeClient)
+		if err != nil {
+			c.logger.Error(err)
+			c.job.Status = config.StatusFailed
+			c.job.Error = err.Error()
+			return err
+		}
+
+		// the api version of cronJob may not be served by the cluster, which should not block the deployment of other workloads
+		cronJobs, errList := getter.ListCronJobs(env.Namespace, selector, c.kubeClient)
+		if errList != nil {
+			c.logger.Warnf("failed to list cronJobs in namespace %s: %v", env.Namespace, errList)
+		}
+
 	L:
 		for _, deploy := range deployments {
 			for _, container := range deploy.Spec.Template.Spec.Containers {
@@ -214,6 +319,52 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 				}
 			}
 		}
+	DaemonSetLoop:
+		for _, ds := range daemonSets {
+			for _, container := range ds.Spec.Template.Spec.Containers {
+				if container.Name == c.jobTaskSpec.ServiceModule {
+					err = updater.UpdateDaemonSetImage(ds.Namespace, ds.Name, c.jobTaskSpec.ServiceModule, c.jobTaskSpec.Image, c.kubeClient)
+					if err != nil {
+						msg := fmt.Sprintf("failed to update container image in %s/daemonsets/%s/%s: %v", env.Namespace, ds.Name, container.Name, err)
+						c.logger.Error(msg)
+						c.job.Status = config.StatusFailed
+						c.job.Error = msg
+						return errors.New(msg)
+					}
+					c.jobTaskSpec.ReplaceResources = append(c.jobTaskSpec.ReplaceResources, commonmodels.Resource{
+						Kind:      setting.DaemonSet,
+						Container: container.Name,
+						Origin:    container.Image,
+						Name:      ds.Name,
+					})
+					replaced = true
+					break DaemonSetLoop
+				}
+			}
+		}
+	CronJobLoop:
+		for _, cj := range cronJobs {
+			for _, container := range cj.Spec.JobTemplate.Spec.Template.Spec.Containers {
+				if container.Name == c.jobTaskSpec.ServiceModule {
+					err = updater.UpdateCronJobImage(cj.Namespace, cj.Name, c.jobTaskSpec.ServiceModule, c.jobTaskSpec.Image, c.kubeClient)
+					if err != nil {
+						msg := fmt.Sprintf("failed to update container image in %s/cronjobs/%s/%s: %v", env.Namespace, cj.Name, container.Name, err)
+						c.logger.Error(msg)
+						c.job.Status = config.StatusFailed
+						c.job.Error = msg
+						return errors.New(msg)
+					}
+					c.jobTaskSpec.ReplaceResources = append(c.jobTaskSpec.ReplaceResources, commonmodels.Resource{
+						Kind:      setting.CronJob,
+						Container: container.Name,
+						Origin:    container.Image,
+						Name:      cj.Name,
+					})
+					replaced = true
+					break CronJobLoop
+				}
+			}
+		}
 	} else {
 		switch serviceInfo.WorkloadType {
 		case setting.StatefulSet:
@@ -268,6 +419,35 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 					break
 				}
 			}
+		case setting.DaemonSet:
+			var daemonSet *appsv1.DaemonSet
+			daemonSet, _, err = getter.GetDaemonSet(env.Namespace, c.jobTaskSpec.ServiceName, c.kubeClient)
+			if err != nil {
+				return err
+			}
+			if daemonSet == nil {
+				break
+			}
+			for _, container := range daemonSet.Spec.Template.Spec.Containers {
+				if container.Name == c.jobTaskSpec.ServiceModule {
+					err = updater.UpdateDaemonSetImage(daemonSet.Namespace, daemonSet.Name, c.jobTaskSpec.ServiceModule, c.jobTaskSpec.Image, c.kubeClient)
+					if err != nil {
+						msg := fmt.Sprintf("failed to update container image in %s/daemonsets/%s/%s: %v", env.Namespace, daemonSet.Name, container.Name, err)
+						c.logger.Error(msg)
+						c.job.Status = config.StatusFailed
+						c.job.Error = msg
+						return errors.New(msg)
+					}
+					c.jobTaskSpec.ReplaceResources = append(c.jobTaskSpec.ReplaceResources, commonmodels.Resource{
+						Kind:      setting.DaemonSet,
+						Container: container.Name,
+						Origin:    container.Image,
+						Name:      daemonSet.Name,
+					})
+					replaced = true
+					break
+				}
+			}
 		}
 	}
 	if !replaced {
@@ -281,6 +461,153 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 	return nil
 }
 
+// previewDiff computes the changes of the workloads to be updated and the ones broken by upgrading the cluster,
+// and waits for the confirmation if the environment requires it. Failing to compute the changes does not block
+// the deployment otherwise.
+func (c *DeployJobCtl) previewDiff(ctx context.Context, env *commonmodels.Product, serviceInfo *commonmodels.Service) error {
+	approval := &commonmodels.DiffApproval{Required: env.RequireDiffApproval}
+	c.jobTaskSpec.DiffApproval = approval
+	c.job.Spec = c.jobTaskSpec
+
+	diffs, err := c.diffWorkloads(env.Namespace, serviceInfo)
+	if err != nil {
+		msg := fmt.Sprintf("failed to compute the changes of service %s: %v", c.jobTaskSpec.ServiceName, err)
+		c.logger.Error(msg)
+		if !approval.Required {
+			return nil
+		}
+		c.job.Status = config.StatusFailed
+		c.job.Error = msg
+		return errors.New(msg)
+	}
+	approval.Diffs = diffs
+	manifests := make([]string, 0, len(diffs))
+	for _, diff := range diffs {
+		manifests = append(manifests, diff.Latest)
+	}
+	approval.Deprecations = previewDeprecations(c.restConfig, manifests, c.logger)
+	if !approval.Required {
+		c.ack()
+		return nil
+	}
+	return waitForDiffApproval(ctx, c.job, c.workflowCtx, approval, c.ack)
+}
+
+// checkManifestPolicy evaluates the workloads with the image replaced against the manifest policy of the project,
+// and lints them if the project requires it.
+func (c *DeployJobCtl) checkManifestPolicy(env *commonmodels.Product, serviceInfo *commonmodels.Service) error {
+	render := renderOnce(func() ([]string, error) {
+		diffs, err := c.diffWorkloads(env.Namespace, serviceInfo)
+		if err != nil {
+			return nil, err
+		}
+		manifests := make([]string, 0, len(diffs))
+		for _, diff := range diffs {
+			manifests = append(manifests, diff.Latest)
+		}
+		return manifests, nil
+	})
+	violations, err := checkManifestPolicy(c.job, c.workflowCtx.ProjectName, env.EnvName, render, c.logger)
+	c.jobTaskSpec.PolicyViolations = violations
+	c.job.Spec = c.jobTaskSpec
+	if err != nil {
+		return err
+	}
+	findings, err := checkManifestLint(c.job, c.workflowCtx.ProjectName, env.EnvName, c.restConfig, render, c.logger)
+	c.jobTaskSpec.LintFindings = findings
+	c.job.Spec = c.jobTaskSpec
+	return err
+}
+
+// diffWorkloads finds the workloads in the same way as run does and diffs them with the image replaced.
+func (c *DeployJobCtl) diffWorkloads(namespace string, serviceInfo *commonmodels.Service) ([]*commonmodels.ResourceDiff, error) {
+	var (
+		deployments  []*appsv1.Deployment
+		statefulSets []*appsv1.StatefulSet
+		daemonSets   []*appsv1.DaemonSet
+		err          error
+	)
+	switch serviceInfo.WorkloadType {
+	case "":
+		selector := labels.Set{setting.ProductLabel: c.workflowCtx.ProjectName, setting.ServiceLabel: c.jobTaskSpec.ServiceName}.AsSelector()
+		if deployments, err = getter.ListDeployments(namespace, selector, c.kubeClient); err != nil {
+			return nil, err
+		}
+		if statefulSets, err = getter.ListStatefulSets(namespace, selector, c.kubeClient); err != nil {
+			return nil, err
+		}
+		if daemonSets, err = getter.ListDaemonSets(namespace, selector, c.kubeClient); err != nil {
+			return nil, err
+		}
+	case setting.Deployment:
+		deployment, found, err := getter.GetDeployment(namespace, c.jobTaskSpec.ServiceName, c.kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			deployments = append(deployments, deployment)
+		}
+	case setting.StatefulSet:
+		statefulSet, found, err := getter.GetStatefulSet(namespace, c.jobTaskSpec.ServiceName, c.kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			statefulSets = append(statefulSets, statefulSet)
+		}
+	case setting.DaemonSet:
+		daemonSet, found, err := getter.GetDaemonSet(namespace, c.jobTaskSpec.ServiceName, c.kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			daemonSets = append(daemonSets, daemonSet)
+		}
+	}
+
+	diffs := []*commonmodels.ResourceDiff{}
+	for _, deploy := range deployments {
+		latest := deploy.DeepCopy()
+		if !replaceContainerImage(latest.Spec.Template.Spec.Containers, c.jobTaskSpec.ServiceModule, c.jobTaskSpec.Image) {
+			continue
+		}
+		deploy.ManagedFields, latest.ManagedFields = nil, nil
+		diff, err := newResourceDiff(setting.Deployment, deploy.Name, deploy, latest)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+		break
+	}
+	for _, sts := range statefulSets {
+		latest := sts.DeepCopy()
+		if !replaceContainerImage(latest.Spec.Template.Spec.Containers, c.jobTaskSpec.ServiceModule, c.jobTaskSpec.Image) {
+			continue
+		}
+		sts.ManagedFields, latest.ManagedFields = nil, nil
+		diff, err := newResourceDiff(setting.StatefulSet, sts.Name, sts, latest)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+		break
+	}
+	for _, ds := range daemonSets {
+		latest := ds.DeepCopy()
+		if !replaceContainerImage(latest.Spec.Template.Spec.Containers, c.jobTaskSpec.ServiceModule, c.jobTaskSpec.Image) {
+			continue
+		}
+		ds.ManagedFields, latest.ManagedFields = nil, nil
+		diff, err := newResourceDiff(setting.DaemonSet, ds.Name, ds, latest)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+		break
+	}
+	return diffs, nil
+}
+
 func (c *DeployJobCtl) wait(ctx context.Context) {
 	timeout := time.After(time.Duration(c.timeout()) * time.Second)
 
@@ -371,6 +698,27 @@ func (c *DeployJobCtl) wait(ctx context.Context) {
 						ready = wrapper.StatefulSet(st).Ready()
 					}
 
+					if !ready {
+						break L
+					}
+				case setting.DaemonSet:
+					ds, found, e := getter.GetDaemonSet(c.namespace, resource.Name, c.kubeClient)
+					if e != nil {
+						err = e
+					}
+					if e != nil || !found {
+						c.logger.Errorf(
+							"failed to check daemonSet ready status %s/%s/%s - %v",
+							c.namespace,
+							resource.Kind,
+							resource.Name,
+							e,
+						)
+						ready = false
+					} else {
+						ready = wrapper.DaemonSet(ds).Ready()
+					}
+
 					if !ready {
 						break L
 					}
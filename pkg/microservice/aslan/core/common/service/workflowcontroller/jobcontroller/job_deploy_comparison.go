@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	labelconfig "github.com/koderover/zadig/pkg/microservice/aslan/core/label/config"
+	labeldb "github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+)
+
+func comparisonEnabled(verification *commonmodels.DeployVerification) bool {
+	return verification.Comparison != nil && verification.Comparison.Enabled
+}
+
+// runDeployComparison waits for the bake period, then measures the signals and compares them with the baseline of
+// the service in the env. An error is returned if any signal regresses or fails to be measured, otherwise the
+// signals become the baseline of the next deployment.
+func runDeployComparison(ctx context.Context, comparison *commonmodels.DeployComparison, workflowCtx *commonmodels.WorkflowTaskCtx, envName, serviceName string, logger *zap.SugaredLogger) ([]*commonmodels.VerificationResult, error) {
+	bakeStart := time.Now()
+	if comparison.BakeDuration > 0 {
+		logger.Infof("baking service %s in env %s for %d seconds before the comparison", serviceName, envName, comparison.BakeDuration)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(comparison.BakeDuration) * time.Second):
+		}
+	}
+
+	baselineColl := commonrepo.NewDeploymentBaselineColl()
+	baseline, err := baselineColl.Find(workflowCtx.ProjectName, envName, serviceName)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to find the baseline of service %s: %v", serviceName, err)
+		}
+		baseline = &commonmodels.DeploymentBaseline{}
+	}
+
+	// the signals not measured this time keep their baseline
+	signals := make(map[string]float64)
+	for name, value := range baseline.Signals {
+		signals[name] = value
+	}
+	results := make([]*commonmodels.VerificationResult, 0, len(comparison.Signals))
+	regressions := make([]string, 0)
+	for _, signal := range comparison.Signals {
+		result := &commonmodels.VerificationResult{Type: "comparison", Target: signal.Name, Passed: true}
+		results = append(results, result)
+
+		value, measured, err := measureSignal(signal, workflowCtx.ProjectName, envName, bakeStart)
+		if err != nil {
+			result.Passed = false
+			result.Message = err.Error()
+			return results, fmt.Errorf("failed to measure signal %s: %v", signal.Name, err)
+		}
+		if !measured {
+			result.Message = "no smoke test ended during the bake period"
+			continue
+		}
+		signals[signal.Name] = value
+
+		base, ok := baseline.Signals[signal.Name]
+		if !ok {
+			result.Message = fmt.Sprintf("%v, no baseline", value)
+			continue
+		}
+		result.Message = fmt.Sprintf("%v, baseline %v", value, base)
+		if signalRegressed(signal, base, value) {
+			result.Passed = false
+			result.Message = fmt.Sprintf("%s, tolerance %v", result.Message, signal.Tolerance)
+			regressions = append(regressions, signal.Name)
+		}
+	}
+	if len(regressions) > 0 {
+		return results, fmt.Errorf("signals %s regressed compared with the previous deployment", strings.Join(regressions, ", "))
+	}
+
+	err = baselineColl.Upsert(&commonmodels.DeploymentBaseline{
+		ProjectName:  workflowCtx.ProjectName,
+		EnvName:      envName,
+		ServiceName:  serviceName,
+		WorkflowName: workflowCtx.WorkflowName,
+		TaskID:       workflowCtx.TaskID,
+		Signals:      signals,
+	})
+	if err != nil {
+		logger.Errorf("failed to update the baseline of service %s in env %s: %v", serviceName, envName, err)
+	}
+	return results, nil
+}
+
+// measureSignal returns the current value of the signal, it is not measured if no smoke test of the env ended
+// since the bake started.
+func measureSignal(signal *commonmodels.ComparisonSignal, projectName, envName string, since time.Time) (float64, bool, error) {
+	switch signal.Type {
+	case config.ComparisonSignalErrorRate, config.ComparisonSignalLatency:
+		values, err := queryPrometheus(signal.Address, signal.Query)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(values) != 1 {
+			return 0, false, fmt.Errorf("the query returns %d values, expected 1", len(values))
+		}
+		return values[0], true, nil
+	case config.ComparisonSignalTestPassRate:
+		records, err := commonrepo.NewEnvSmokeTestRecordColl().ListEndedSince(projectName, envName, since.Unix())
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to list the smoke test records: %v", err)
+		}
+		if len(records) == 0 {
+			return 0, false, nil
+		}
+		passed := 0
+		for _, record := range records {
+			if record.Status == config.StatusPassed {
+				passed++
+			}
+		}
+		return float64(passed) * 100 / float64(len(records)), true, nil
+	default:
+		return 0, false, fmt.Errorf("unsupported signal type %s", signal.Type)
+	}
+}
+
+func signalRegressed(signal *commonmodels.ComparisonSignal, baseline, value float64) bool {
+	if signal.Type == config.ComparisonSignalTestPassRate {
+		return baseline-value > signal.Tolerance
+	}
+	return value-baseline > signal.MinDelta && value > baseline*(1+signal.Tolerance/100)
+}
+
+// alertDeployRegression sends the failed comparison to the notification routes, it is sent to the notify ctl of the
+// comparison if no route matches.
+func alertDeployRegression(comparison *commonmodels.DeployComparison, workflowCtx *commonmodels.WorkflowTaskCtx, envName, serviceName string, cause error, rolledBack bool, logger *zap.SugaredLogger) {
+	title := fmt.Sprintf("服务 %s 在环境 %s 的部署对比未通过", serviceName, envName)
+	rollback := "否"
+	if rolledBack {
+		rollback = "是"
+	}
+	url := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.ExternalBaseURL(), workflowCtx.ProjectName, workflowCtx.WorkflowName, workflowCtx.TaskID)
+	event := &instantmessage.NotificationEvent{
+		Type:        config.NotificationEventDeployRegression,
+		ProjectName: workflowCtx.ProjectName,
+		Envs:        []string{envName},
+		Severity:    config.NotificationSeverityCritical,
+		Actor:       workflowCtx.TaskCreator,
+		Resource: &labeldb.Resource{
+			Name:        workflowCtx.WorkflowName,
+			ProjectName: workflowCtx.ProjectName,
+			Type:        string(labelconfig.ResourceTypeWorkflow),
+		},
+		Title: title,
+		Content: fmt.Sprintf("### %s\n\n项目：%s, 工作流：%s #%d, 执行用户：%s\n\n原因：%s\n\n已回滚：%s\n\n[点击查看更多信息](%s)",
+			title, workflowCtx.ProjectName, workflowCtx.WorkflowName, workflowCtx.TaskID, workflowCtx.TaskCreator, cause, rollback, url),
+	}
+
+	client := instantmessage.NewWeChatClient()
+	routed, err := client.RouteNotification(event, logger)
+	if err != nil {
+		logger.Errorf("failed to route the regression of service %s in env %s, err: %s", serviceName, envName, err)
+	}
+	if routed || comparison.NotifyCtl == nil || !comparison.NotifyCtl.Enabled {
+		return
+	}
+	if err := client.SendSystemMessage(comparison.NotifyCtl, event.Title, event.Content); err != nil {
+		logger.Errorf("failed to send the regression of service %s in env %s, err: %s", serviceName, envName, err)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// diffApprovalTimeout is the same as the default timeout of the stage approval.
+const diffApprovalTimeout = 60 * time.Minute
+
+type diffConfirmation struct {
+	rejectOrApprove config.ApproveOrReject
+	userName        string
+	confirmTime     int64
+}
+
+type diffConfirmationMap struct {
+	sync.RWMutex
+	m map[string]*diffConfirmation
+}
+
+var globalDiffConfirmationMap = &diffConfirmationMap{m: make(map[string]*diffConfirmation)}
+
+func diffConfirmationKey(workflowName string, taskID int64, jobName string) string {
+	return fmt.Sprintf("%s-%d-%s", workflowName, taskID, jobName)
+}
+
+// ConfirmDeployDiff approves or rejects the changes of a deploy job waiting for the confirmation.
+func ConfirmDeployDiff(workflowName, jobName, userName string, taskID int64, approve bool) error {
+	globalDiffConfirmationMap.Lock()
+	defer globalDiffConfirmationMap.Unlock()
+
+	confirmation, ok := globalDiffConfirmationMap.m[diffConfirmationKey(workflowName, taskID, jobName)]
+	if !ok {
+		return fmt.Errorf("job %s of workflow %s task %d is not waiting for diff confirmation", jobName, workflowName, taskID)
+	}
+	if confirmation.rejectOrApprove != "" {
+		return fmt.Errorf("%s have %s already", confirmation.userName, confirmation.rejectOrApprove)
+	}
+	confirmation.rejectOrApprove = config.Reject
+	if approve {
+		confirmation.rejectOrApprove = config.Approve
+	}
+	confirmation.userName = userName
+	confirmation.confirmTime = time.Now().Unix()
+	return nil
+}
+
+func (m *diffConfirmationMap) get(key string) diffConfirmation {
+	m.RLock()
+	defer m.RUnlock()
+	if confirmation, ok := m.m[key]; ok {
+		return *confirmation
+	}
+	return diffConfirmation{}
+}
+
+func (m *diffConfirmationMap) set(key string) {
+	m.Lock()
+	defer m.Unlock()
+	m.m[key] = &diffConfirmation{}
+}
+
+func (m *diffConfirmationMap) delete(key string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.m, key)
+}
+
+// waitForDiffApproval blocks the job until the diffs are confirmed, the job status is updated if the
+// changes are rejected or not confirmed in time.
+func waitForDiffApproval(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, approval *commonmodels.DiffApproval, ack func()) error {
+	key := diffConfirmationKey(workflowCtx.WorkflowName, workflowCtx.TaskID, job.Name)
+	globalDiffConfirmationMap.set(key)
+	defer globalDiffConfirmationMap.delete(key)
+
+	job.Status = config.StatusWaiting
+	ack()
+	defer ack()
+
+	timeout := time.After(diffApprovalTimeout)
+	for {
+		select {
+		case <-ctx.Done():
+			job.Status = config.StatusCancelled
+			return fmt.Errorf("workflow was canceled")
+		case <-timeout:
+			job.Status = config.StatusTimeout
+			job.Error = "the changes are not confirmed in time"
+			return errors.New(job.Error)
+		case <-time.After(time.Second):
+			confirmation := globalDiffConfirmationMap.get(key)
+			if confirmation.rejectOrApprove == "" {
+				continue
+			}
+			approval.RejectOrApprove = confirmation.rejectOrApprove
+			approval.ConfirmedBy = confirmation.userName
+			approval.ConfirmTime = confirmation.confirmTime
+			if confirmation.rejectOrApprove == config.Reject {
+				job.Status = config.StatusReject
+				job.Error = fmt.Sprintf("%s rejected the changes", confirmation.userName)
+				return errors.New(job.Error)
+			}
+			job.Status = config.StatusRunning
+			return nil
+		}
+	}
+}
+
+// newResourceDiff marshals the resources to yaml and diffs them, a nil current means the resource is
+// going to be created.
+func newResourceDiff(kind, name string, current, latest interface{}) (*commonmodels.ResourceDiff, error) {
+	resp := &commonmodels.ResourceDiff{Kind: kind, Name: name}
+	if current != nil {
+		currentYaml, err := yaml.Marshal(current)
+		if err != nil {
+			return nil, err
+		}
+		resp.Current = string(currentYaml)
+	}
+	latestYaml, err := yaml.Marshal(latest)
+	if err != nil {
+		return nil, err
+	}
+	resp.Latest = string(latestYaml)
+
+	resp.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(resp.Current),
+		B:        difflib.SplitLines(resp.Latest),
+		FromFile: strings.ToLower(kind) + "/" + name + " (current)",
+		ToFile:   strings.ToLower(kind) + "/" + name + " (latest)",
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func replaceContainerImage(containers []corev1.Container, containerName, image string) bool {
+	for i := range containers {
+		if containers[i].Name == containerName {
+			containers[i].Image = image
+			return true
+		}
+	}
+	return false
+}
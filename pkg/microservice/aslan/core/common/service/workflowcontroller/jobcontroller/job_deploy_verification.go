@@ -0,0 +1,214 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	defaultVerificationTimeout = 300
+	probeInterval              = 5 * time.Second
+	probeTimeout               = 5 * time.Second
+	prometheusCheckInterval    = 15 * time.Second
+)
+
+// runDeployVerification runs the health probes until they all pass or the timeout is reached, then
+// evaluates the prometheus checks during the observe duration. An error is returned if any of them fails.
+func runDeployVerification(ctx context.Context, verification *commonmodels.DeployVerification, logger *zap.SugaredLogger) ([]*commonmodels.VerificationResult, error) {
+	timeout := verification.Timeout
+	if timeout <= 0 {
+		timeout = defaultVerificationTimeout
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+
+	results := make([]*commonmodels.VerificationResult, 0)
+	for _, probe := range verification.Probes {
+		result := &commonmodels.VerificationResult{Type: string(probe.Type), Target: probe.Address}
+		for {
+			err := runHealthProbe(ctx, probe)
+			if err == nil {
+				result.Passed = true
+				result.Message = ""
+				break
+			}
+			result.Message = err.Error()
+			if ctx.Err() != nil || time.Now().Add(probeInterval).After(deadline) {
+				break
+			}
+			logger.Infof("health probe %s %s is not passed yet: %s", probe.Type, probe.Address, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(probeInterval):
+			}
+		}
+		results = append(results, result)
+		if !result.Passed {
+			return results, fmt.Errorf("health probe %s %s failed: %s", probe.Type, probe.Address, result.Message)
+		}
+	}
+
+	if len(verification.PrometheusChecks) == 0 {
+		return results, nil
+	}
+	observeUntil := time.Now().Add(time.Duration(verification.ObserveDuration) * time.Second)
+	checkResults := make([]*commonmodels.VerificationResult, 0, len(verification.PrometheusChecks))
+	for _, check := range verification.PrometheusChecks {
+		checkResults = append(checkResults, &commonmodels.VerificationResult{Type: "prometheus", Target: check.Name, Passed: true})
+	}
+	results = append(results, checkResults...)
+	for {
+		for i, check := range verification.PrometheusChecks {
+			if err := runPrometheusCheck(check); err != nil {
+				checkResults[i].Passed = false
+				checkResults[i].Message = err.Error()
+				return results, fmt.Errorf("prometheus check %s failed: %s", check.Name, err)
+			}
+		}
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		if !time.Now().Add(prometheusCheckInterval).Before(observeUntil) {
+			return results, nil
+		}
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-time.After(prometheusCheckInterval):
+		}
+	}
+}
+
+func runHealthProbe(ctx context.Context, probe *commonmodels.HealthProbe) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	switch probe.Type {
+	case config.HealthProbeHTTP:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.Address, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if probe.ExpectedStatus != 0 && resp.StatusCode != probe.ExpectedStatus {
+			return fmt.Errorf("status code %d, expected %d", resp.StatusCode, probe.ExpectedStatus)
+		}
+		if probe.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+			return fmt.Errorf("status code %d", resp.StatusCode)
+		}
+		return nil
+	case config.HealthProbeGRPC:
+		conn, err := grpc.DialContext(ctx, probe.Address, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: probe.Service})
+		if err != nil {
+			return err
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("serving status %s", resp.Status)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported probe type %s", probe.Type)
+	}
+}
+
+type prometheusQueryResp struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string      `json:"resultType"`
+		Result     interface{} `json:"result"`
+	} `json:"data"`
+}
+
+// runPrometheusCheck evaluates the instant query, the check fails if any of the returned values exceeds the threshold.
+func runPrometheusCheck(check *commonmodels.PrometheusCheck) error {
+	values, err := queryPrometheus(check.Address, check.Query)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		if value > check.Threshold {
+			return fmt.Errorf("value %v exceeds the threshold %v", value, check.Threshold)
+		}
+	}
+	return nil
+}
+
+// queryPrometheus runs the instant query and returns the values of the scalar or vector result.
+func queryPrometheus(address, query string) ([]float64, error) {
+	resp := &prometheusQueryResp{}
+	_, err := httpclient.Get(strings.TrimSuffix(address, "/")+"/api/v1/query", httpclient.SetQueryParam("query", query), httpclient.SetResult(resp))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", resp.Error)
+	}
+
+	var samples []interface{}
+	switch resp.Data.ResultType {
+	case "scalar":
+		samples = append(samples, resp.Data.Result)
+	case "vector":
+		vector, _ := resp.Data.Result.([]interface{})
+		for _, sample := range vector {
+			if s, ok := sample.(map[string]interface{}); ok {
+				samples = append(samples, s["value"])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported result type %s", resp.Data.ResultType)
+	}
+
+	values := make([]float64, 0, len(samples))
+	for _, v := range samples {
+		// a sample value is a pair of timestamp and value in string
+		pair, ok := v.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("invalid sample value %v", v)
+		}
+		value, err := strconv.ParseFloat(fmt.Sprint(pair[1]), 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -25,6 +25,7 @@ import (
 
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	crClient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,9 +35,12 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/stepcontroller"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
 	"github.com/koderover/zadig/pkg/tool/dockerhost"
 	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	commontypes "github.com/koderover/zadig/pkg/types"
 )
 
 const (
@@ -55,6 +59,9 @@ type FreestyleJobCtl struct {
 	paths       *string
 	jobTaskSpec *commonmodels.JobTaskBuildSpec
 	ack         func()
+	secretScan  *logSecretScan
+	isolated    bool
+	security    commontypes.JobSecurityProfile
 }
 
 func NewFreestyleJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *FreestyleJobCtl {
@@ -78,10 +85,51 @@ func (c *FreestyleJobCtl) Run(ctx context.Context) {
 	if err := c.prepare(ctx); err != nil {
 		return
 	}
+	recordCredentialUsages(c.job, c.jobTaskSpec, c.workflowCtx, c.logger)
+	// the job runs on an agent outside kubernetes if a runner is chosen.
+	if c.jobTaskSpec.Properties.Runner != "" || len(c.jobTaskSpec.Properties.RunnerLabels) > 0 {
+		c.runOnRunner(ctx)
+		return
+	}
 	if err := c.run(ctx); err != nil {
 		return
 	}
+	c.watch(ctx)
+}
+
+// Resume reattaches the kubernetes job created before aslan restarts.
+func (c *FreestyleJobCtl) Resume(ctx context.Context) {
+	if err := c.prepare(ctx); err != nil {
+		return
+	}
+	if c.job.RunnerJobID != "" {
+		c.resumeOnRunner(ctx)
+		return
+	}
+	// the namespace of the job is saved when the job is created, it may be isolated.
+	namespace := c.jobTaskSpec.Properties.Namespace
+	if err := c.initKubeClients(); err != nil {
+		return
+	}
+	if namespace != "" {
+		c.jobTaskSpec.Properties.Namespace = namespace
+	}
+	c.jobName = c.job.K8sJobName
+	c.logger.Infof("reattach job %s", c.jobName)
+	c.watch(ctx)
+}
+
+func (c *FreestyleJobCtl) watch(ctx context.Context) {
+	jobLabel := &JobLabel{
+		WorkflowName: c.workflowCtx.WorkflowName,
+		TaskID:       c.workflowCtx.TaskID,
+		JobType:      string(c.job.JobType),
+		JobName:      c.job.Name,
+	}
+	c.secretScan = newLogSecretScan(c.workflowCtx.ProjectName, c.logger)
+	logStreamer := startJobLogStream(ctx, c.jobTaskSpec.Properties.Namespace, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient, c.clientset, c.secretScan.getScanner(), c.logger)
 	c.wait(ctx)
+	logStreamer.stop()
 	c.complete(ctx)
 }
 
@@ -108,9 +156,7 @@ func (c *FreestyleJobCtl) prepare(ctx context.Context) error {
 	return nil
 }
 
-func (c *FreestyleJobCtl) run(ctx context.Context) error {
-	// get kube client
-	hubServerAddr := config.HubServerAddress()
+func (c *FreestyleJobCtl) initKubeClients() error {
 	switch c.jobTaskSpec.Properties.ClusterID {
 	case setting.LocalClusterID:
 		c.jobTaskSpec.Properties.Namespace = zadigconfig.Namespace()
@@ -120,7 +166,7 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 	default:
 		c.jobTaskSpec.Properties.Namespace = setting.AttachedClusterNamespace
 
-		crClient, clientset, restConfig, err := GetK8sClients(hubServerAddr, c.jobTaskSpec.Properties.ClusterID)
+		crClient, clientset, restConfig, err := GetK8sClients(config.HubServerAddress(), c.jobTaskSpec.Properties.ClusterID)
 		if err != nil {
 			c.job.Status = config.StatusFailed
 			c.job.Error = err.Error()
@@ -131,6 +177,31 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 		c.clientset = clientset
 		c.restConfig = restConfig
 	}
+	return nil
+}
+
+func (c *FreestyleJobCtl) run(ctx context.Context) error {
+	// get kube client
+	if err := c.initKubeClients(); err != nil {
+		return err
+	}
+	zadigNamespace := c.jobTaskSpec.Properties.Namespace
+	isolated, err := isolateJob(&c.jobTaskSpec.Properties, c.workflowCtx.ProjectName, c.kubeclient)
+	if err != nil {
+		c.logger.Error(err)
+		c.job.Status = config.StatusFailed
+		c.job.Error = err.Error()
+		return err
+	}
+	c.isolated = isolated
+	c.security, err = getJobSecurityProfile(c.workflowCtx.ProjectName)
+	if err != nil {
+		c.logger.Error(err)
+		c.job.Status = config.StatusFailed
+		c.job.Error = err.Error()
+		return err
+	}
+	hubServerAddr := config.HubServerAddress()
 
 	// decide which docker host to use.
 	// TODO: do not use code in warpdrive moudule, should move to a public place
@@ -160,7 +231,9 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 
 	c.jobTaskSpec.Properties.DockerHost = dockerHost
 
-	jobCtxBytes, err := yaml.Marshal(BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger))
+	jobCtx := BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger)
+	jobCtx.DebugTimeout = jobDebugTimeout(c.workflowCtx)
+	jobCtxBytes, err := yaml.Marshal(jobCtx)
 	if err != nil {
 		msg := fmt.Sprintf("cannot Jobexcutor.Context data: %v", err)
 		c.logger.Error(msg)
@@ -182,6 +255,17 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 		return err
 	}
 
+	// the job config is written to the pod directly if an idle job of the warm pool is claimed, the warm
+	// jobs never run in the isolated namespaces and always run with the baseline profile.
+	if !c.isolated && c.security == commontypes.JobSecurityBaseline {
+		if warmJobName := claimWarmJob(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec, jobLabel, jobCtxBytes, c.kubeclient, c.clientset, c.restConfig, c.logger); warmJobName != "" {
+			c.jobName = warmJobName
+			c.logger.Infof("succeed to claim warm job %s", c.jobName)
+			c.checkpoint()
+			return nil
+		}
+	}
+
 	if err := createJobConfigMap(
 		c.jobTaskSpec.Properties.Namespace, c.jobName, jobLabel, string(jobCtxBytes), c.kubeclient); err != nil {
 		msg := fmt.Sprintf("createJobConfigMap error: %v", err)
@@ -194,12 +278,12 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 	c.logger.Infof("succeed to create cm for job %s", c.jobName)
 
 	// TODO: do not use default image
-	jobImage := getBaseImage(c.jobTaskSpec.Properties.BuildOS, c.jobTaskSpec.Properties.ImageFrom)
+	jobImage := GetBaseImage(c.jobTaskSpec.Properties.BuildOS, c.jobTaskSpec.Properties.ImageFrom)
 	// jobImage := "koderover.tencentcloudcr.com/test/job-excutor:guoyu-test2"
 	// jobImage := getReaperImage(config.ReaperImage(), c.job.Properties.BuildOS)
 
 	//Resource request default value is LOW
-	job, err := buildJob(c.job.JobType, jobImage, c.jobName, c.jobTaskSpec.Properties.ClusterID, c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ResourceRequest, c.jobTaskSpec.Properties.ResReqSpec, c.job, c.jobTaskSpec, c.workflowCtx, nil)
+	job, err := buildJob(c.job.JobType, jobImage, c.jobName, c.jobTaskSpec.Properties.ClusterID, zadigNamespace, c.jobTaskSpec.Properties.ResourceRequest, c.jobTaskSpec.Properties.ResReqSpec, c.job, c.jobTaskSpec, c.workflowCtx, nil)
 	if err != nil {
 		msg := fmt.Sprintf("create job context error: %v", err)
 		c.logger.Error(msg)
@@ -209,6 +293,13 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 	}
 
 	job.Namespace = c.jobTaskSpec.Properties.Namespace
+	if c.isolated {
+		isolateJobPod(&job.Spec.Template.Spec)
+	}
+	if c.security == commontypes.JobSecurityRestricted {
+		job.Spec.Template.Spec.Containers[0].Args = []string{restrictedJobBootingScript(c.jobTaskSpec.Properties.ClusterID, zadigNamespace)}
+	}
+	applyJobSecurityProfile(&job.Spec.Template.Spec, c.security, []string{c.workflowCtx.Workspace, "/tmp"})
 
 	if err := ensureDeleteJob(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient); err != nil {
 		msg := fmt.Sprintf("delete job error: %v", err)
@@ -235,9 +326,17 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 		return errors.New(msg)
 	}
 	c.logger.Infof("succeed to create job %s", c.jobName)
+	c.checkpoint()
 	return nil
 }
 
+// checkpoint saves the name of the kubernetes job so that it can be reattached after aslan restarts.
+func (c *FreestyleJobCtl) checkpoint() {
+	c.job.K8sJobName = c.jobName
+	c.job.Spec = c.jobTaskSpec
+	c.ack()
+}
+
 func (c *FreestyleJobCtl) wait(ctx context.Context) {
 	status := waitJobEndWithFile(ctx, int(c.jobTaskSpec.Properties.Timeout), c.jobTaskSpec.Properties.Namespace, c.jobName, true, c.kubeclient, c.clientset, c.restConfig, c.logger)
 	c.job.Status = status
@@ -251,9 +350,19 @@ func (c *FreestyleJobCtl) complete(ctx context.Context) {
 		JobName:      c.job.Name,
 	}
 
+	debugging := c.startDebugSession()
+
 	// 清理用户取消和超时的任务
 	defer func() {
 		go func() {
+			// the job is reattached after aslan restarts.
+			if Detached() {
+				return
+			}
+			// the pod is kept until the debug session expires, it is deleted earlier if the user ends the session.
+			if debugging {
+				time.Sleep(time.Until(time.Unix(c.job.Debug.ExpireTime, 0)))
+			}
 			if err := ensureDeleteJob(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient); err != nil {
 				c.logger.Error(err)
 			}
@@ -275,16 +384,59 @@ func (c *FreestyleJobCtl) complete(ctx context.Context) {
 		c.workflowCtx.GlobalContextSet(strings.Join([]string{"workflow", c.job.Name, output.Name}, "."), output.Value)
 	}
 
-	if err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient); err != nil {
+	analysis, err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient, c.secretScan.getScanner(), c.job.Status != config.StatusPassed)
+	if err != nil {
 		c.logger.Error(err)
 		c.job.Error = err.Error()
 		return
 	}
+	c.secretScan.report(c.job, analysis.Findings)
+	c.job.Diagnoses = analysis.Diagnoses
+	c.job.Annotations = linkLogAnnotations(analysis.Annotations, c.jobTaskSpec.Steps, c.workflowCtx.Workspace)
 	if err := stepcontroller.SummarizeSteps(ctx, c.workflowCtx, &c.jobTaskSpec.Properties.Paths, c.jobTaskSpec.Steps, c.logger); err != nil {
 		c.logger.Error(err)
 		c.job.Error = err.Error()
 		return
 	}
+	c.job.Summaries = stepcontroller.CollectSummaries(c.jobTaskSpec.Steps)
+}
+
+// startDebugSession keeps the pod of the failed job alive for debugging if it is asked by the run.
+func (c *FreestyleJobCtl) startDebugSession() bool {
+	timeout := jobDebugTimeout(c.workflowCtx)
+	if c.job.Status != config.StatusFailed || timeout <= 0 {
+		return false
+	}
+	pods, err := getter.ListPods(c.jobTaskSpec.Properties.Namespace, labels.Set{"job-name": c.jobName}.AsSelector(), c.kubeclient)
+	if err != nil {
+		c.logger.Errorf("failed to find the pod of job %s to debug: %v", c.jobName, err)
+		return false
+	}
+	for _, pod := range pods {
+		ipod := wrapper.Pod(pod)
+		// nothing is left to inspect if the executor has exited.
+		if ipod.Pending() || ipod.Finished() {
+			continue
+		}
+		c.job.Debug = &commonmodels.JobDebugSession{
+			ClusterID:     c.jobTaskSpec.Properties.ClusterID,
+			Namespace:     c.jobTaskSpec.Properties.Namespace,
+			PodName:       pod.Name,
+			ContainerName: ipod.ContainerNames()[0],
+			ExpireTime:    time.Now().Add(time.Duration(timeout) * time.Minute).Unix(),
+		}
+		c.logger.Infof("keep pod %s of job %s for debugging for %d minutes", pod.Name, c.jobName, timeout)
+		return true
+	}
+	return false
+}
+
+// jobDebugTimeout returns the minutes the pod of a failed job is kept alive for debugging.
+func jobDebugTimeout(workflowCtx *commonmodels.WorkflowTaskCtx) int64 {
+	if workflowCtx.DebugOnFailure == nil || !workflowCtx.DebugOnFailure.Enabled {
+		return 0
+	}
+	return workflowCtx.DebugOnFailure.Timeout
 }
 
 func BuildJobExcutorContext(jobTaskSpec *commonmodels.JobTaskBuildSpec, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) *JobContext {
@@ -36,6 +36,7 @@ import (
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/rest"
 	crClient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -45,8 +46,11 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/util"
 )
 
 const (
@@ -120,6 +124,21 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 		c.restConfig = krkubeclient.RESTConfig()
 	}
 
+	if c.jobTaskSpec.PinImageDigest {
+		for _, svcAndContainer := range c.jobTaskSpec.ImageAndModules {
+			pinned, imageDigest, err := registry.PinImageDigest(svcAndContainer.Image, c.logger)
+			if err != nil {
+				msg := fmt.Sprintf("failed to pin the digest of image %s: %v", svcAndContainer.Image, err)
+				c.logger.Error(msg)
+				c.job.Status = config.StatusFailed
+				c.job.Error = msg
+				return
+			}
+			svcAndContainer.Image, svcAndContainer.ImageDigest = pinned, imageDigest
+		}
+		c.job.Spec = c.jobTaskSpec
+	}
+
 	// all involved containers
 	containerNameSet := sets.NewString()
 	for _, svcAndContainer := range c.jobTaskSpec.ImageAndModules {
@@ -386,6 +405,8 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 	}
 
 	timeOut := c.timeout()
+	verification := c.jobTaskSpec.Verification
+	verificationEnabled := verification != nil && verification.Enabled
 	chartSpec := helmclient.ChartSpec{
 		ReleaseName: releaseName,
 		ChartName:   chartPath,
@@ -396,14 +417,23 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 		SkipCRDs:    false,
 		UpgradeCRDs: true,
 		Timeout:     time.Second * time.Duration(timeOut),
-		Wait:        !c.jobTaskSpec.SkipCheckRunStatus,
+		Wait:        !c.jobTaskSpec.SkipCheckRunStatus || verificationEnabled,
 		Replace:     true,
 		MaxHistory:  10,
 	}
+	render := c.renderRelease(ctx, helmClient, env, chartSpec)
+	if err = c.checkManifestPolicy(env, render); err != nil {
+		return
+	}
+	if err = c.previewDiff(ctx, helmClient, env, releaseName, replacedMergedValuesYaml, render); err != nil {
+		return
+	}
+
 	c.logger.Infof("start to upgrade helm chart, release name: %s, chart name: %s, version: %s", chartSpec.ReleaseName, chartSpec.ChartName, chartSpec.Version)
+	postRenderOpts := kube.NewHelmPostRenderOptions(env, releaseName, false, c.logger)
 	done := make(chan bool)
 	go func(chan bool) {
-		if _, err = helmClient.InstallOrUpgradeChart(ctx, &chartSpec, nil); err != nil {
+		if _, err = helmClient.InstallOrUpgradeChart(ctx, &chartSpec, postRenderOpts); err != nil {
 			err = errors.WithMessagef(
 				err,
 				"failed to upgrade helm chart %s/%s",
@@ -424,9 +454,36 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 		c.logger.Error(err)
 		c.job.Status = config.StatusFailed
 		c.job.Error = err.Error()
+		if verificationEnabled {
+			c.rollback(helmClient, env, &chartSpec)
+		}
 		return
 	}
 
+	if verificationEnabled {
+		results, err := runDeployVerification(ctx, verification, c.logger)
+		var comparisonErr error
+		if err == nil && comparisonEnabled(verification) {
+			var comparisonResults []*commonmodels.VerificationResult
+			comparisonResults, comparisonErr = runDeployComparison(ctx, verification.Comparison, c.workflowCtx, c.jobTaskSpec.Env, c.jobTaskSpec.ServiceName, c.logger)
+			results = append(results, comparisonResults...)
+			err = comparisonErr
+		}
+		c.jobTaskSpec.VerificationResults = results
+		c.job.Spec = c.jobTaskSpec
+		if err != nil {
+			msg := fmt.Sprintf("post-deploy verification failed: %v", err)
+			c.logger.Error(msg)
+			c.job.Status = config.StatusFailed
+			c.job.Error = msg
+			c.rollback(helmClient, env, &chartSpec)
+			if comparisonErr != nil && ctx.Err() == nil {
+				alertDeployRegression(verification.Comparison, c.workflowCtx, c.jobTaskSpec.Env, c.jobTaskSpec.ServiceName, comparisonErr, c.jobTaskSpec.RolledBack, c.logger)
+			}
+			return
+		}
+	}
+
 	//替换环境变量中的chartInfos
 	for _, chartInfo := range renderInfo.ChartInfos {
 		if chartInfo.ServiceName == c.jobTaskSpec.ServiceName {
@@ -454,6 +511,100 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 	c.job.Status = config.StatusPassed
 }
 
+// rollback rolls the release back to the previous revision if the environment enables auto rollback.
+func (c *HelmDeployJobCtl) rollback(helmClient helmclient.Client, env *commonmodels.Product, chartSpec *helmclient.ChartSpec) {
+	if !env.AutoRollback {
+		return
+	}
+	if err := helmClient.RollbackRelease(chartSpec); err != nil {
+		msg := fmt.Sprintf("failed to roll back release %s: %v", chartSpec.ReleaseName, err)
+		c.logger.Error(msg)
+		c.job.Error = fmt.Sprintf("%s\n%s", c.job.Error, msg)
+		return
+	}
+	c.jobTaskSpec.RolledBack = true
+	c.job.Spec = c.jobTaskSpec
+}
+
+// renderRelease renders the release by a dry run, including the post renderer of the env, the release is
+// rendered at most once.
+func (c *HelmDeployJobCtl) renderRelease(ctx context.Context, helmClient helmclient.Client, env *commonmodels.Product, chartSpec helmclient.ChartSpec) func() ([]string, error) {
+	return renderOnce(func() ([]string, error) {
+		chartSpec.DryRun = true
+		chartSpec.Wait = false
+		release, err := helmClient.InstallOrUpgradeChart(ctx, &chartSpec, kube.NewHelmPostRenderOptions(env, chartSpec.ReleaseName, true, c.logger))
+		if err != nil {
+			return nil, err
+		}
+		return util.SplitManifests(release.Manifest), nil
+	})
+}
+
+// checkManifestPolicy evaluates the rendered release against the manifest policy of the project, and lints it
+// if the project requires it.
+func (c *HelmDeployJobCtl) checkManifestPolicy(env *commonmodels.Product, render func() ([]string, error)) error {
+	violations, err := checkManifestPolicy(c.job, c.workflowCtx.ProjectName, env.EnvName, render, c.logger)
+	c.jobTaskSpec.PolicyViolations = violations
+	c.job.Spec = c.jobTaskSpec
+	if err != nil {
+		return err
+	}
+	findings, err := checkManifestLint(c.job, c.workflowCtx.ProjectName, env.EnvName, c.restConfig, render, c.logger)
+	c.jobTaskSpec.LintFindings = findings
+	c.job.Spec = c.jobTaskSpec
+	return err
+}
+
+// previewDiff computes the changes of the release values and the resources broken by upgrading the cluster,
+// and waits for the confirmation if the environment requires it. Failing to compute the changes does not
+// block the deployment otherwise.
+func (c *HelmDeployJobCtl) previewDiff(ctx context.Context, helmClient helmclient.Client, env *commonmodels.Product, releaseName, valuesYaml string, render func() ([]string, error)) error {
+	approval := &commonmodels.DiffApproval{Required: env.RequireDiffApproval}
+	c.jobTaskSpec.DiffApproval = approval
+	c.job.Spec = c.jobTaskSpec
+
+	diff, err := diffReleaseValues(helmClient, releaseName, valuesYaml)
+	if err != nil {
+		msg := fmt.Sprintf("failed to compute the changes of release %s: %v", releaseName, err)
+		c.logger.Error(msg)
+		if !approval.Required {
+			return nil
+		}
+		c.job.Status = config.StatusFailed
+		c.job.Error = msg
+		return errors.New(msg)
+	}
+	approval.Diffs = []*commonmodels.ResourceDiff{diff}
+	if manifests, err := render(); err == nil {
+		approval.Deprecations = previewDeprecations(c.restConfig, manifests, c.logger)
+	} else {
+		c.logger.Warnf("failed to render release %s for the deprecations: %v", releaseName, err)
+	}
+	if !approval.Required {
+		c.ack()
+		return nil
+	}
+	return waitForDiffApproval(ctx, c.job, c.workflowCtx, approval, c.ack)
+}
+
+// diffReleaseValues diffs the user supplied values of the deployed release with the values to be applied.
+func diffReleaseValues(helmClient helmclient.Client, releaseName, valuesYaml string) (*commonmodels.ResourceDiff, error) {
+	var current interface{}
+	currentValues, err := helmClient.GetReleaseValues(releaseName, false)
+	if err != nil && err != driver.ErrReleaseNotFound {
+		return nil, err
+	}
+	if err == nil {
+		current = currentValues
+	}
+
+	latest := map[string]interface{}{}
+	if err = yaml.Unmarshal([]byte(valuesYaml), &latest); err != nil {
+		return nil, err
+	}
+	return newResourceDiff("Values", releaseName, current, latest)
+}
+
 func (c *HelmDeployJobCtl) timeout() int {
 	if c.jobTaskSpec.Timeout == 0 {
 		c.jobTaskSpec.Timeout = setting.DeployTimeout
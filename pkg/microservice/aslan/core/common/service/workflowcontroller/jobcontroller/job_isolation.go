@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	crClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+)
+
+const (
+	// JobServiceAccount is the service account of the isolated job pods, it is bound to no role.
+	JobServiceAccount = "zadig-job"
+	jobNetworkPolicy  = "zadig-job-isolation"
+)
+
+// isolateJob moves the job to the isolated namespace of the project if the jobs of the cluster are isolated,
+// it returns whether the job is isolated.
+func isolateJob(properties *commonmodels.JobProperties, projectName string, kubeClient crClient.Client) (bool, error) {
+	cluster, err := commonrepo.NewK8SClusterColl().Get(properties.ClusterID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to find cluster %s: %s", properties.ClusterID, err)
+	}
+	isolation := cluster.JobIsolation
+	if isolation == nil || !isolation.Enabled {
+		return false, nil
+	}
+
+	namespace := isolation.JobNamespace(projectName)
+	if err := ensureJobNamespace(namespace, properties.Namespace, isolation, properties.Registries, kubeClient); err != nil {
+		return false, err
+	}
+	properties.Namespace = namespace
+	return true, nil
+}
+
+// ensureJobNamespace prepares the isolated namespace before the job is created in it: the service account
+// without permission, the image pull secrets of the job and the network policy which denies all the
+// ingress and only allows the egress to the dns, the zadig services and the addresses out of the cluster.
+// zadigNamespace is where the dind and the resource server of the cluster run.
+func ensureJobNamespace(namespace, zadigNamespace string, isolation *commonmodels.JobIsolation, registries []*commonmodels.RegistryNamespace, kubeClient crClient.Client) error {
+	if err := kube.CreateNamespace(namespace, nil, false, kubeClient); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %s", namespace, err)
+	}
+
+	for _, reg := range registries {
+		if err := kube.CreateOrUpdateRegistrySecret(namespace, reg, reg.IsDefault, kubeClient); err != nil {
+			return fmt.Errorf("failed to create the registry secret in namespace %s: %s", namespace, err)
+		}
+	}
+
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      JobServiceAccount,
+			Namespace: namespace,
+		},
+		AutomountServiceAccountToken: boolPtr(false),
+	}
+	if err := updater.UpdateOrCreateServiceAccount(sa, kubeClient); err != nil {
+		return fmt.Errorf("failed to create service account %s in namespace %s: %s", JobServiceAccount, namespace, err)
+	}
+
+	if err := updater.UpdateOrCreateNetworkPolicy(buildJobNetworkPolicy(namespace, zadigNamespace, isolation), kubeClient); err != nil {
+		return fmt.Errorf("failed to create network policy %s in namespace %s: %s", jobNetworkPolicy, namespace, err)
+	}
+	return nil
+}
+
+func buildJobNetworkPolicy(namespace, zadigNamespace string, isolation *commonmodels.JobIsolation) *networkingv1.NetworkPolicy {
+	udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+	dnsPort := intstr.FromInt(53)
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobNetworkPolicy,
+			Namespace: namespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			// all the pods in the namespace are jobs.
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{
+					To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Protocol: &udp, Port: &dnsPort},
+						{Protocol: &tcp, Port: &dnsPort},
+					},
+				},
+				{
+					To: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{corev1.LabelMetadataName: zadigNamespace},
+						},
+					}},
+				},
+				{
+					To: []networkingv1.NetworkPolicyPeer{{
+						IPBlock: &networkingv1.IPBlock{
+							CIDR:   "0.0.0.0/0",
+							Except: isolation.DeniedCIDRs,
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// isolateJobPod runs the pod with the service account of the isolated namespace, the token is not
+// mounted since the jobs never talk to the kubernetes api.
+func isolateJobPod(spec *corev1.PodSpec) {
+	spec.ServiceAccountName = JobServiceAccount
+	spec.AutomountServiceAccountToken = boolPtr(false)
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bndr/gojenkins"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+const jenkinsPollInterval = 5 * time.Second
+
+type JenkinsJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskJenkinsSpec
+	ack         func()
+}
+
+func NewJenkinsJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *JenkinsJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskJenkinsSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &JenkinsJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+func (c *JenkinsJobCtl) Run(ctx context.Context) {
+	if c.jobTaskSpec.Job == nil {
+		c.fail("jenkins job info not found")
+		return
+	}
+	// set default timeout, unit is minute.
+	if c.jobTaskSpec.Timeout <= 0 {
+		c.jobTaskSpec.Timeout = 60
+	}
+
+	integration, err := commonrepo.NewJenkinsIntegrationColl().Get(c.jobTaskSpec.ID)
+	if err != nil {
+		c.fail(fmt.Sprintf("failed to find jenkins integration %s: %v", c.jobTaskSpec.ID, err))
+		return
+	}
+	client, err := gojenkins.CreateJenkins(nil, integration.URL, integration.Username, integration.Password).Init(ctx)
+	if err != nil {
+		c.fail(fmt.Sprintf("failed to connect to jenkins %s: %v", integration.URL, err))
+		return
+	}
+
+	params := make(map[string]string, len(c.jobTaskSpec.Job.Parameters))
+	for _, param := range c.jobTaskSpec.Job.Parameters {
+		params[param.Name] = param.Value
+	}
+	queueID, err := client.BuildJob(ctx, c.jobTaskSpec.Job.JobName, params)
+	if err != nil {
+		c.fail(fmt.Sprintf("failed to trigger jenkins job %s: %v", c.jobTaskSpec.Job.JobName, err))
+		return
+	}
+	build, err := client.GetBuildFromQueueID(ctx, queueID)
+	if err != nil {
+		c.fail(fmt.Sprintf("failed to get jenkins build of job %s: %v", c.jobTaskSpec.Job.JobName, err))
+		return
+	}
+	c.jobTaskSpec.BuildNumber = build.GetBuildNumber()
+	c.jobTaskSpec.BuildURL = build.GetUrl()
+	c.ack()
+
+	c.wait(ctx, build)
+}
+
+func (c *JenkinsJobCtl) wait(ctx context.Context, build *gojenkins.Build) {
+	timeout := time.After(time.Duration(c.jobTaskSpec.Timeout) * time.Minute)
+	ticker := time.NewTicker(jenkinsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.job.Status = config.StatusCancelled
+			if _, err := build.Stop(context.Background()); err != nil {
+				c.logger.Errorf("failed to stop jenkins build %s: %v", c.jobTaskSpec.BuildURL, err)
+			}
+			return
+		case <-timeout:
+			c.job.Status = config.StatusTimeout
+			if _, err := build.Stop(context.Background()); err != nil {
+				c.logger.Errorf("failed to stop jenkins build %s: %v", c.jobTaskSpec.BuildURL, err)
+			}
+			return
+		case <-ticker.C:
+			if build.IsRunning(ctx) {
+				continue
+			}
+			c.complete(build)
+			return
+		}
+	}
+}
+
+func (c *JenkinsJobCtl) complete(build *gojenkins.Build) {
+	c.jobTaskSpec.Result = build.GetResult()
+	for _, artifact := range build.GetArtifacts() {
+		c.jobTaskSpec.Artifacts = append(c.jobTaskSpec.Artifacts, artifact.Path)
+	}
+
+	switch c.jobTaskSpec.Result {
+	case gojenkins.STATUS_SUCCESS:
+		c.job.Status = config.StatusPassed
+	case gojenkins.STATUS_ABORTED:
+		c.job.Status = config.StatusCancelled
+	default:
+		c.job.Status = config.StatusFailed
+		c.job.Error = fmt.Sprintf("jenkins build %s finished with result: %s", c.jobTaskSpec.BuildURL, c.jobTaskSpec.Result)
+	}
+}
+
+func (c *JenkinsJobCtl) fail(msg string) {
+	c.logger.Error(msg)
+	c.job.Status = config.StatusFailed
+	c.job.Error = msg
+}
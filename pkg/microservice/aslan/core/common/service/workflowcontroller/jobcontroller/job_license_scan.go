@@ -0,0 +1,292 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	crClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	zadigconfig "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/licensepolicy"
+	"github.com/koderover/zadig/pkg/setting"
+	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+const (
+	defaultLicenseScanner     = "anchore/syft:v0.68.1"
+	licenseScannerContainer   = "syft"
+	defaultLicenseScanTimeout = 30
+)
+
+type LicenseScanJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	kubeclient  crClient.Client
+	clientset   kubernetes.Interface
+	namespace   string
+	jobTaskSpec *commonmodels.JobTaskLicenseScanSpec
+	ack         func()
+}
+
+func NewLicenseScanJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *LicenseScanJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskLicenseScanSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &LicenseScanJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+// Run scans the images one by one, the job fails if the licenses of any image violate the license policy of
+// the project or any image can not be scanned.
+func (c *LicenseScanJobCtl) Run(ctx context.Context) {
+	if c.jobTaskSpec.ScannerImage == "" {
+		c.jobTaskSpec.ScannerImage = defaultLicenseScanner
+	}
+	if c.jobTaskSpec.Timeout <= 0 {
+		c.jobTaskSpec.Timeout = defaultLicenseScanTimeout
+	}
+	if c.jobTaskSpec.ClusterID == "" {
+		c.jobTaskSpec.ClusterID = setting.LocalClusterID
+	}
+	if err := c.initKubeClients(); err != nil {
+		c.fail(err.Error())
+		return
+	}
+	project, err := templaterepo.NewProductColl().Find(c.workflowCtx.ProjectName)
+	if err != nil {
+		c.fail(fmt.Sprintf("failed to find project %s: %v", c.workflowCtx.ProjectName, err))
+		return
+	}
+
+	c.jobTaskSpec.Results = make([]*commonmodels.LicenseScanResult, 0, len(c.jobTaskSpec.Images))
+	var violations, failures int
+	for _, image := range c.jobTaskSpec.Images {
+		result := &commonmodels.LicenseScanResult{ServiceName: image.ServiceName, ServiceModule: image.ServiceModule, Image: image.Image}
+		c.jobTaskSpec.Results = append(c.jobTaskSpec.Results, result)
+		c.ack()
+
+		sbom, err := c.scan(ctx, image.Image)
+		if ctx.Err() != nil {
+			c.job.Status = config.StatusCancelled
+			return
+		}
+		if err != nil {
+			c.logger.Errorf("failed to scan image %s, err: %s", image.Image, err)
+			result.Error = err.Error()
+			failures++
+			continue
+		}
+		packages, err := licensepolicy.ParseSyftJSON(sbom)
+		if err != nil {
+			result.Error = err.Error()
+			failures++
+			continue
+		}
+		result.Packages = len(packages)
+		result.Licenses = licensepolicy.Summarize(packages)
+		result.Violations, result.Waived = licensepolicy.Evaluate(project.LicensePolicy, packages, time.Now().Unix())
+		violations += len(result.Violations)
+	}
+
+	switch {
+	case failures > 0:
+		c.fail(fmt.Sprintf("%d of %d images can not be scanned", failures, len(c.jobTaskSpec.Images)))
+	case violations > 0:
+		c.fail(fmt.Sprintf("%d license violations are found", violations))
+	default:
+		c.job.Status = config.StatusPassed
+	}
+}
+
+func (c *LicenseScanJobCtl) initKubeClients() error {
+	if c.jobTaskSpec.ClusterID == setting.LocalClusterID {
+		c.namespace = zadigconfig.Namespace()
+		c.kubeclient = krkubeclient.Client()
+		c.clientset = krkubeclient.Clientset()
+		return nil
+	}
+
+	c.namespace = setting.AttachedClusterNamespace
+	kubeclient, clientset, _, err := GetK8sClients(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
+	if err != nil {
+		return fmt.Errorf("failed to get the clients of cluster %s: %v", c.jobTaskSpec.ClusterID, err)
+	}
+	c.kubeclient = kubeclient
+	c.clientset = clientset
+	return nil
+}
+
+// scan runs syft in a kubernetes job and returns the sbom it prints.
+func (c *LicenseScanJobCtl) scan(ctx context.Context, image string) ([]byte, error) {
+	jobLabel := &JobLabel{
+		WorkflowName: c.workflowCtx.WorkflowName,
+		TaskID:       c.workflowCtx.TaskID,
+		JobType:      string(c.job.JobType),
+		JobName:      c.job.Name,
+	}
+	if err := ensureDeleteJob(c.namespace, jobLabel, c.kubeclient); err != nil {
+		return nil, fmt.Errorf("failed to delete the former scan job: %v", err)
+	}
+	defer func() {
+		if err := ensureDeleteJob(c.namespace, jobLabel, c.kubeclient); err != nil {
+			c.logger.Error(err)
+		}
+	}()
+
+	jobName := getJobName(c.workflowCtx.WorkflowName, c.workflowCtx.TaskID)
+	job, err := c.buildScanJob(jobName, image, jobLabel)
+	if err != nil {
+		return nil, err
+	}
+	if err := updater.CreateJob(job, c.kubeclient); err != nil {
+		return nil, fmt.Errorf("failed to create the scan job: %v", err)
+	}
+	c.logger.Infof("scanning the licenses of image %s by job %s", image, jobName)
+
+	timeout := time.After(time.Duration(c.jobTaskSpec.Timeout) * time.Minute)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, fmt.Errorf("the scan is not finished in %d minutes", c.jobTaskSpec.Timeout)
+		case <-time.After(time.Second):
+		}
+		job, found, err := getter.GetJob(c.namespace, jobName, c.kubeclient)
+		if err != nil || !found {
+			continue
+		}
+		if job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+			continue
+		}
+
+		logs, err := c.getScanLogs(ctx, jobLabel)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status.Failed != 0 {
+			msg := strings.TrimSpace(string(logs))
+			if len(msg) > 500 {
+				msg = msg[len(msg)-500:]
+			}
+			return nil, fmt.Errorf("syft failed: %s", msg)
+		}
+		return logs, nil
+	}
+}
+
+func (c *LicenseScanJobCtl) getScanLogs(ctx context.Context, jobLabel *JobLabel) ([]byte, error) {
+	pods, err := getter.ListPods(c.namespace, labels.Set(getJobLabels(jobLabel)).AsSelector(), c.kubeclient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the pods of the scan job: %v", err)
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("the pod of the scan job is not found")
+	}
+	return c.clientset.CoreV1().Pods(c.namespace).GetLogs(pods[0].Name, &corev1.PodLogOptions{Container: licenseScannerContainer}).DoRaw(ctx)
+}
+
+func (c *LicenseScanJobCtl) buildScanJob(jobName, image string, jobLabel *JobLabel) (*batchv1.Job, error) {
+	imagePullSecrets, err := getImagePullSecrets(c.jobTaskSpec.Registries)
+	if err != nil {
+		return nil, err
+	}
+	ls := getJobLabels(jobLabel)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: c.namespace,
+			Labels:    ls,
+		},
+		Spec: batchv1.JobSpec{
+			Completions:  int32Ptr(1),
+			Parallelism:  int32Ptr(1),
+			BackoffLimit: int32Ptr(0),
+			// in case finished zombie job not cleaned up by zadig
+			TTLSecondsAfterFinished: int32Ptr(3600),
+			ActiveDeadlineSeconds:   int64Ptr(c.jobTaskSpec.Timeout*60 + 600),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: ls},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: imagePullSecrets,
+					Containers: []corev1.Container{
+						{
+							Name:      licenseScannerContainer,
+							Image:     c.jobTaskSpec.ScannerImage,
+							Args:      []string{"registry:" + image, "-o", "json", "-q"},
+							Env:       c.registryAuthEnvs(image),
+							Resources: getResourceRequirements(setting.MinRequest, setting.RequestSpec{}),
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// registryAuthEnvs passes the credential of the registry the image is pushed to to syft, which pulls the
+// image from the registry directly.
+func (c *LicenseScanJobCtl) registryAuthEnvs(image string) []corev1.EnvVar {
+	for _, reg := range c.jobTaskSpec.Registries {
+		host := util.TrimURLScheme(reg.RegAddr)
+		if host == "" || !strings.HasPrefix(image, host+"/") {
+			continue
+		}
+		envs := []corev1.EnvVar{
+			{Name: "SYFT_REGISTRY_AUTH_AUTHORITY", Value: host},
+			{Name: "SYFT_REGISTRY_AUTH_USERNAME", Value: reg.AccessKey},
+			{Name: "SYFT_REGISTRY_AUTH_PASSWORD", Value: reg.SecretKey},
+		}
+		if strings.HasPrefix(reg.RegAddr, "http://") {
+			envs = append(envs, corev1.EnvVar{Name: "SYFT_REGISTRY_INSECURE_USE_HTTP", Value: "true"})
+		}
+		return envs
+	}
+	return nil
+}
+
+func (c *LicenseScanJobCtl) fail(msg string) {
+	c.logger.Error(msg)
+	c.job.Status = config.StatusFailed
+	c.job.Error = msg
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	crClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/tool/kube/containerlog"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+)
+
+const (
+	// the uploaded chunks are readable after at most jobLogFlushInterval
+	jobLogFlushInterval = 5 * time.Second
+	jobLogPodInterval   = 2 * time.Second
+)
+
+// jobLogStreamer uploads the log of a running job in chunks, so that the log can be read
+// before the job is done.
+type jobLogStreamer struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startJobLogStream starts to stream the log of the job, the secrets are redacted from the streamed log if
+// scanner is not nil.
+func startJobLogStream(ctx context.Context, namespace, workflowName, jobName string, taskID int64, jobLabel *JobLabel, kubeClient crClient.Client, clientset kubernetes.Interface, scanner *secretscan.Scanner, logger *zap.SugaredLogger) *jobLogStreamer {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &jobLogStreamer{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(s.done)
+
+		jobLog, err := s3.NewWorkflowJobLog(workflowName, jobName, taskID)
+		if err != nil {
+			logger.Warnf("failed to stream the log of job %s: %s", jobName, err)
+			return
+		}
+		// the log is streamed from the beginning again when the job is reattached after aslan
+		// restarts, the chunks uploaded before are removed.
+		if err := jobLog.DeleteChunks(); err != nil {
+			logger.Warnf("failed to remove the log chunks of job %s: %s", jobName, err)
+		}
+		pod := waitJobPodStarted(ctx, namespace, jobLabel, kubeClient)
+		if pod == nil {
+			return
+		}
+		stream, err := containerlog.GetContainerLogStream(ctx, namespace, pod.Name, pod.Spec.Containers[0].Name, true, 0, clientset)
+		if err != nil {
+			logger.Warnf("failed to stream the log of job %s: %s", jobName, err)
+			return
+		}
+		defer stream.Close()
+
+		writer := jobLog.ChunkWriter()
+		copied := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(jobLogFlushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-copied:
+					return
+				case <-ticker.C:
+					if err := writer.Flush(); err != nil {
+						logger.Warnf("failed to upload the log of job %s: %s", jobName, err)
+						return
+					}
+				}
+			}
+		}()
+
+		if scanner != nil {
+			// the findings are collected from the complete log when the job is done.
+			_, _ = scanner.Redact(stream, writer)
+		} else {
+			_, _ = io.Copy(writer, stream)
+		}
+		close(copied)
+		if err := writer.Close(); err != nil {
+			logger.Warnf("failed to upload the log of job %s: %s", jobName, err)
+		}
+	}()
+	return s
+}
+
+// stop stops the streaming and waits for the written chunks to be uploaded, it must be called
+// before the complete log is saved.
+func (s *jobLogStreamer) stop() {
+	s.cancel()
+	<-s.done
+}
+
+// waitJobPodStarted returns the pod of the job once its container is started, nil is returned
+// if the context is done before that.
+func waitJobPodStarted(ctx context.Context, namespace string, jobLabel *JobLabel, kubeClient crClient.Client) *corev1.Pod {
+	selector := labels.Set(getJobLabels(jobLabel)).AsSelector()
+	ticker := time.NewTicker(jobLogPodInterval)
+	defer ticker.Stop()
+	for {
+		pods, err := getter.ListPods(namespace, selector, kubeClient)
+		if err == nil && len(pods) > 0 {
+			sort.SliceStable(pods, func(i, j int) bool {
+				return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+			})
+			if pods[0].Status.Phase != corev1.PodPending && pods[0].Status.Phase != corev1.PodUnknown && len(pods[0].Spec.Containers) > 0 {
+				return pods[0]
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
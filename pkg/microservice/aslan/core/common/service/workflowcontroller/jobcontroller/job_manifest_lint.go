@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/manifestlint"
+)
+
+// renderOnce lets the manifest policy and the manifest lint share the manifests rendered for the job.
+func renderOnce(render func() ([]string, error)) func() ([]string, error) {
+	var (
+		rendered  bool
+		manifests []string
+		err       error
+	)
+	return func() ([]string, error) {
+		if !rendered {
+			manifests, err = render()
+			rendered = true
+		}
+		return manifests, err
+	}
+}
+
+// checkManifestLint lints the manifests to be deployed against the version of the target cluster if the
+// project gates the deployments to the env, render is only called if it does. All the findings are kept
+// in the job, but the job fails only on the findings at least as severe as the policy requires.
+func checkManifestLint(job *commonmodels.JobTask, projectName, envName string, restConfig *rest.Config, render func() ([]string, error), logger *zap.SugaredLogger) ([]*commonmodels.ManifestLintFinding, error) {
+	fail := func(msg string) error {
+		logger.Error(msg)
+		job.Status = config.StatusFailed
+		job.Error = msg
+		return errors.New(msg)
+	}
+
+	policy, enabled, err := manifestlint.Enabled(projectName, envName)
+	if err != nil {
+		return nil, fail(fmt.Sprintf("failed to get the manifest lint policy: %v", err))
+	}
+	if !enabled {
+		return nil, nil
+	}
+	manifests, err := render()
+	if err != nil {
+		return nil, fail(fmt.Sprintf("failed to render the manifests for the manifest lint: %v", err))
+	}
+
+	opt := &manifestlint.Options{DisabledRules: policy.DisabledRules, KubeVersion: serverVersion(restConfig, logger)}
+	findings := manifestlint.Lint(manifests, opt)
+	if blocking := manifestlint.Blocking(findings, policy.FailOn); len(blocking) > 0 {
+		return findings, fail(manifestlint.FormatFindings(blocking))
+	}
+	return findings, nil
+}
+
+// serverVersion returns the version of the cluster, it is empty if the cluster can not be reached so that
+// the deprecated api versions are only reported as warnings.
+func serverVersion(restConfig *rest.Config, logger *zap.SugaredLogger) string {
+	if restConfig == nil {
+		return ""
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		logger.Warnf("failed to create discovery client: %v", err)
+		return ""
+	}
+	info, err := client.ServerVersion()
+	if err != nil {
+		logger.Warnf("failed to get the server version: %v", err)
+		return ""
+	}
+	return info.GitVersion
+}
+
+// previewDeprecations finds the resources using the api versions removed by upgrading the cluster to its next
+// minor version, nothing is found if the version of the cluster is unknown.
+func previewDeprecations(restConfig *rest.Config, manifests []string, logger *zap.SugaredLogger) []*commonmodels.APIDeprecation {
+	current := serverVersion(restConfig, logger)
+	if current == "" {
+		return nil
+	}
+	target, err := manifestlint.NextMinorVersion(current)
+	if err != nil {
+		logger.Warnf("failed to parse the server version %s: %v", current, err)
+		return nil
+	}
+	return manifestlint.FindDeprecations(manifests, current, target)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/manifestpolicy"
+)
+
+// checkManifestPolicy evaluates the manifests to be deployed against the manifest policy of the project,
+// render is only called if the policy is applied to the env. The job fails if any violation is found or
+// the policy can not be evaluated.
+func checkManifestPolicy(job *commonmodels.JobTask, projectName, envName string, render func() ([]string, error), logger *zap.SugaredLogger) ([]*commonmodels.ManifestPolicyViolation, error) {
+	fail := func(msg string) error {
+		logger.Error(msg)
+		job.Status = config.StatusFailed
+		job.Error = msg
+		return errors.New(msg)
+	}
+
+	policy, enabled, err := manifestpolicy.Enabled(projectName, envName)
+	if err != nil {
+		return nil, fail(fmt.Sprintf("failed to get the manifest policy: %v", err))
+	}
+	if !enabled {
+		return nil, nil
+	}
+	manifests, err := render()
+	if err != nil {
+		return nil, fail(fmt.Sprintf("failed to render the manifests for the manifest policy: %v", err))
+	}
+	violations, err := manifestpolicy.Check(projectName, policy, manifests)
+	if err != nil {
+		return nil, fail(fmt.Sprintf("failed to check the manifest policy: %v", err))
+	}
+	if len(violations) > 0 {
+		return violations, fail(manifestpolicy.FormatViolations(violations))
+	}
+	return nil, nil
+}
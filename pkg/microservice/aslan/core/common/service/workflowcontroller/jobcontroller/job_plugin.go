@@ -46,6 +46,7 @@ type PluginJobCtl struct {
 	restConfig  *rest.Config
 	jobTaskSpec *commonmodels.JobTaskPluginSpec
 	ack         func()
+	secretScan  *logSecretScan
 }
 
 func NewPluginsJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *PluginJobCtl {
@@ -83,13 +84,40 @@ func (c *PluginJobCtl) Run(ctx context.Context) {
 	if err := c.run(ctx); err != nil {
 		return
 	}
+	c.watch(ctx)
+}
+
+// Resume reattaches the kubernetes job created before aslan restarts.
+func (c *PluginJobCtl) Resume(ctx context.Context) {
+	c.prepare(ctx)
+	// the namespace of the job is saved when the job is created, it may be isolated.
+	namespace := c.jobTaskSpec.Properties.Namespace
+	if err := c.initKubeClients(); err != nil {
+		return
+	}
+	if namespace != "" {
+		c.jobTaskSpec.Properties.Namespace = namespace
+	}
+	c.jobName = c.job.K8sJobName
+	c.logger.Infof("reattach job %s", c.jobName)
+	c.watch(ctx)
+}
+
+func (c *PluginJobCtl) watch(ctx context.Context) {
+	jobLabel := &JobLabel{
+		WorkflowName: c.workflowCtx.WorkflowName,
+		TaskID:       c.workflowCtx.TaskID,
+		JobType:      string(c.job.JobType),
+		JobName:      c.job.Name,
+	}
+	c.secretScan = newLogSecretScan(c.workflowCtx.ProjectName, c.logger)
+	logStreamer := startJobLogStream(ctx, c.jobTaskSpec.Properties.Namespace, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient, c.clientset, c.secretScan.getScanner(), c.logger)
 	c.wait(ctx)
+	logStreamer.stop()
 	c.complete(ctx)
 }
 
-func (c *PluginJobCtl) run(ctx context.Context) error {
-	// get kube client
-	hubServerAddr := config.HubServerAddress()
+func (c *PluginJobCtl) initKubeClients() error {
 	switch c.jobTaskSpec.Properties.ClusterID {
 	case setting.LocalClusterID:
 		c.jobTaskSpec.Properties.Namespace = zadigconfig.Namespace()
@@ -99,7 +127,7 @@ func (c *PluginJobCtl) run(ctx context.Context) error {
 	default:
 		c.jobTaskSpec.Properties.Namespace = setting.AttachedClusterNamespace
 
-		crClient, clientset, restConfig, err := GetK8sClients(hubServerAddr, c.jobTaskSpec.Properties.ClusterID)
+		crClient, clientset, restConfig, err := GetK8sClients(config.HubServerAddress(), c.jobTaskSpec.Properties.ClusterID)
 		if err != nil {
 			c.job.Status = config.StatusFailed
 			c.job.Error = err.Error()
@@ -110,6 +138,28 @@ func (c *PluginJobCtl) run(ctx context.Context) error {
 		c.clientset = clientset
 		c.restConfig = restConfig
 	}
+	return nil
+}
+
+func (c *PluginJobCtl) run(ctx context.Context) error {
+	// get kube client
+	if err := c.initKubeClients(); err != nil {
+		return err
+	}
+	isolated, err := isolateJob(&c.jobTaskSpec.Properties, c.workflowCtx.ProjectName, c.kubeclient)
+	if err != nil {
+		c.logger.Error(err)
+		c.job.Status = config.StatusFailed
+		c.job.Error = err.Error()
+		return err
+	}
+	security, err := getJobSecurityProfile(c.workflowCtx.ProjectName)
+	if err != nil {
+		c.logger.Error(err)
+		c.job.Status = config.StatusFailed
+		c.job.Error = err.Error()
+		return err
+	}
 
 	jobLabel := &JobLabel{
 		WorkflowName: c.workflowCtx.WorkflowName,
@@ -127,6 +177,10 @@ func (c *PluginJobCtl) run(ctx context.Context) error {
 	}
 
 	job.Namespace = c.jobTaskSpec.Properties.Namespace
+	if isolated {
+		isolateJobPod(&job.Spec.Template.Spec)
+	}
+	applyJobSecurityProfile(&job.Spec.Template.Spec, security, []string{"/tmp"})
 
 	if err := ensureDeleteJob(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient); err != nil {
 		msg := fmt.Sprintf("delete job error: %v", err)
@@ -143,6 +197,10 @@ func (c *PluginJobCtl) run(ctx context.Context) error {
 		return errors.New(msg)
 	}
 	c.logger.Infof("succeed to create job %s", c.jobName)
+	// save the name of the kubernetes job so that it can be reattached after aslan restarts.
+	c.job.K8sJobName = c.jobName
+	c.job.Spec = c.jobTaskSpec
+	c.ack()
 	return nil
 }
 
@@ -163,6 +221,10 @@ func (c *PluginJobCtl) complete(ctx context.Context) {
 	// 清理用户取消和超时的任务
 	defer func() {
 		go func() {
+			// the job is reattached after aslan restarts.
+			if Detached() {
+				return
+			}
 			if err := ensureDeleteJob(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient); err != nil {
 				c.logger.Error(err)
 			}
@@ -181,9 +243,12 @@ func (c *PluginJobCtl) complete(ctx context.Context) {
 		c.workflowCtx.GlobalContextSet(strings.Join([]string{"workflow", c.job.Name, output.Name}, "."), output.Value)
 	}
 
-	if err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient); err != nil {
+	analysis, err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient, c.secretScan.getScanner(), c.job.Status != config.StatusPassed)
+	if err != nil {
 		c.logger.Error(err)
 		c.job.Error = err.Error()
 		return
 	}
+	c.secretScan.report(c.job, analysis.Findings)
+	c.job.Diagnoses = analysis.Diagnoses
 }
@@ -0,0 +1,285 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+const (
+	RolloutActionPause  = "pause"
+	RolloutActionResume = "resume"
+	RolloutActionAbort  = "abort"
+)
+
+type rolloutControl struct {
+	paused   bool
+	aborted  bool
+	userName string
+	cancel   context.CancelFunc
+}
+
+type rolloutControlMap struct {
+	sync.RWMutex
+	m map[string]*rolloutControl
+}
+
+var globalRolloutControlMap = &rolloutControlMap{m: make(map[string]*rolloutControl)}
+
+func rolloutControlKey(workflowName string, taskID int64, jobName string) string {
+	return fmt.Sprintf("%s-%d-%s", workflowName, taskID, jobName)
+}
+
+// ControlRollout pauses the running rollout job after the current wave, resumes the paused one or
+// aborts it. An aborted rollout stops the deployments of the current wave immediately.
+func ControlRollout(workflowName, jobName, userName string, taskID int64, action string) error {
+	globalRolloutControlMap.Lock()
+	defer globalRolloutControlMap.Unlock()
+
+	control, ok := globalRolloutControlMap.m[rolloutControlKey(workflowName, taskID, jobName)]
+	if !ok {
+		return fmt.Errorf("rollout job %s of workflow %s task %d is not running", jobName, workflowName, taskID)
+	}
+	if control.aborted {
+		return fmt.Errorf("%s have aborted the rollout already", control.userName)
+	}
+	switch action {
+	case RolloutActionPause:
+		control.paused = true
+	case RolloutActionResume:
+		control.paused = false
+	case RolloutActionAbort:
+		control.aborted = true
+		control.cancel()
+	default:
+		return fmt.Errorf("unknown rollout action: %s", action)
+	}
+	control.userName = userName
+	return nil
+}
+
+func (m *rolloutControlMap) get(key string) rolloutControl {
+	m.RLock()
+	defer m.RUnlock()
+	if control, ok := m.m[key]; ok {
+		return *control
+	}
+	return rolloutControl{}
+}
+
+func (m *rolloutControlMap) set(key string, paused bool, cancel context.CancelFunc) {
+	m.Lock()
+	defer m.Unlock()
+	m.m[key] = &rolloutControl{paused: paused, cancel: cancel}
+}
+
+func (m *rolloutControlMap) pause(key string) {
+	m.Lock()
+	defer m.Unlock()
+	if control, ok := m.m[key]; ok {
+		control.paused = true
+	}
+}
+
+func (m *rolloutControlMap) delete(key string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.m, key)
+}
+
+type RolloutJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskZadigRolloutSpec
+	ack         func()
+	// mu guards the deploys of the running wave, they are updated concurrently.
+	mu sync.Mutex
+}
+
+func NewRolloutJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *RolloutJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskZadigRolloutSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &RolloutJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+// Run deploys the images wave by wave, the waves passed before are skipped when the job is resumed.
+func (c *RolloutJobCtl) Run(ctx context.Context) {
+	key := rolloutControlKey(c.workflowCtx.WorkflowName, c.workflowCtx.TaskID, c.job.Name)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// a rollout paused before aslan restarts keeps waiting to be resumed.
+	globalRolloutControlMap.set(key, c.jobTaskSpec.Paused, cancel)
+	defer globalRolloutControlMap.delete(key)
+
+	for i, wave := range c.jobTaskSpec.Waves {
+		if wave.Status == config.StatusPassed {
+			continue
+		}
+		if err := c.waitToContinue(ctx, key); err != nil {
+			return
+		}
+		if err := c.runWave(ctx, wave); err != nil {
+			c.logger.Errorf("rollout job %s failed in wave %s: %v", c.job.Name, wave.Name, err)
+			c.abortOrFail(ctx, key, err)
+			return
+		}
+		if c.jobTaskSpec.PauseAfterWave && i < len(c.jobTaskSpec.Waves)-1 {
+			globalRolloutControlMap.pause(key)
+		}
+	}
+	c.job.Status = config.StatusPassed
+}
+
+// waitToContinue blocks the job while the rollout is paused.
+func (c *RolloutJobCtl) waitToContinue(ctx context.Context, key string) error {
+	if !globalRolloutControlMap.get(key).paused {
+		return nil
+	}
+	c.jobTaskSpec.Paused = true
+	c.job.Status = config.StatusWaiting
+	c.ack()
+	defer c.ack()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.jobTaskSpec.Paused = false
+			c.abortOrFail(ctx, key, errors.New("workflow was canceled"))
+			return ctx.Err()
+		case <-time.After(time.Second):
+			control := globalRolloutControlMap.get(key)
+			if control.paused {
+				continue
+			}
+			c.jobTaskSpec.Paused = false
+			c.jobTaskSpec.ControlledBy = control.userName
+			c.job.Status = config.StatusRunning
+			return nil
+		}
+	}
+}
+
+func (c *RolloutJobCtl) abortOrFail(ctx context.Context, key string, err error) {
+	control := globalRolloutControlMap.get(key)
+	switch {
+	case control.aborted:
+		c.jobTaskSpec.ControlledBy = control.userName
+		c.job.Status = config.StatusCancelled
+		c.job.Error = fmt.Sprintf("the rollout is aborted by %s", control.userName)
+	case ctx.Err() != nil:
+		c.job.Status = config.StatusCancelled
+	default:
+		c.job.Status = config.StatusFailed
+		c.job.Error = err.Error()
+	}
+}
+
+// runWave deploys the images to the envs of the wave in parallel and verifies the wave, the wave is
+// rolled back if any of the deployments or the verification fails.
+func (c *RolloutJobCtl) runWave(ctx context.Context, wave *commonmodels.RolloutWaveTask) error {
+	wave.Status = config.StatusRunning
+	wave.StartTime = time.Now().Unix()
+	wave.Error = ""
+	c.ack()
+	defer func() {
+		wave.EndTime = time.Now().Unix()
+		c.ack()
+	}()
+
+	ctls := make([]*DeployJobCtl, 0, len(wave.Deploys))
+	wg := sync.WaitGroup{}
+	for _, deploy := range wave.Deploys {
+		deploy.Status, deploy.Error, deploy.RolledBack = config.StatusRunning, "", false
+		ctl := NewDeployJobCtl(&commonmodels.JobTask{
+			Name:    fmt.Sprintf("%s-%s-%s-%s", c.job.Name, deploy.Env, deploy.ServiceName, deploy.ServiceModule),
+			JobType: string(config.JobZadigDeploy),
+			Spec: &commonmodels.JobTaskDeploySpec{
+				Env:           deploy.Env,
+				ServiceName:   deploy.ServiceName,
+				ServiceType:   setting.K8SDeployType,
+				ServiceModule: deploy.ServiceModule,
+				Image:         deploy.Image,
+				// the timeout of the rollout is in minutes.
+				Timeout: c.jobTaskSpec.Timeout * 60,
+			},
+		}, c.workflowCtx, c.ack, c.logger)
+		ctls = append(ctls, ctl)
+
+		wg.Add(1)
+		go func(deploy *commonmodels.RolloutDeployTask, ctl *DeployJobCtl) {
+			defer wg.Done()
+			ctl.Run(ctx)
+			c.mu.Lock()
+			deploy.Status, deploy.Error = ctl.job.Status, ctl.job.Error
+			c.mu.Unlock()
+			c.ack()
+		}(deploy, ctl)
+	}
+	wg.Wait()
+
+	var err error
+	for _, deploy := range wave.Deploys {
+		if deploy.Status != config.StatusPassed {
+			err = fmt.Errorf("failed to deploy %s/%s to env %s: %s", deploy.ServiceName, deploy.ServiceModule, deploy.Env, deploy.Error)
+			break
+		}
+	}
+	if err == nil && ctx.Err() != nil {
+		err = errors.New("workflow was canceled")
+	}
+	if err == nil && wave.Verification != nil && wave.Verification.Enabled {
+		wave.VerificationResults, err = runDeployVerification(ctx, wave.Verification, c.logger)
+		if err != nil {
+			err = fmt.Errorf("verification of wave %s failed: %v", wave.Name, err)
+		}
+	}
+	if err == nil {
+		wave.Status = config.StatusPassed
+		return nil
+	}
+
+	wave.Status = config.StatusFailed
+	wave.Error = err.Error()
+	for i, ctl := range ctls {
+		if len(ctl.jobTaskSpec.ReplaceResources) == 0 {
+			continue
+		}
+		ctl.rollback()
+		wave.Deploys[i].RolledBack = ctl.jobTaskSpec.RolledBack
+	}
+	return err
+}
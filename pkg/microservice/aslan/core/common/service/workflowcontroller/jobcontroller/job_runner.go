@@ -0,0 +1,292 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/runner"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/stepcontroller"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+const runnerJobPollInterval = 2 * time.Second
+
+// runOnRunner dispatches the job to an agent of the runner instead of creating a kubernetes job.
+func (c *FreestyleJobCtl) runOnRunner(ctx context.Context) {
+	runnerJob, err := c.dispatchRunnerJob(ctx)
+	if err != nil {
+		c.logger.Error(err)
+		c.job.Status = config.StatusFailed
+		c.job.Error = err.Error()
+		return
+	}
+	c.watchRunnerJob(ctx, runnerJob)
+}
+
+// resumeOnRunner reattaches the job dispatched to the runner before aslan restarts.
+func (c *FreestyleJobCtl) resumeOnRunner(ctx context.Context) {
+	runnerJob, err := commonrepo.NewRunnerJobColl().FindByID(c.job.RunnerJobID)
+	if err != nil {
+		msg := fmt.Sprintf("failed to find runner job %s: %s", c.job.RunnerJobID, err)
+		c.logger.Error(msg)
+		c.job.Status = config.StatusFailed
+		c.job.Error = msg
+		return
+	}
+	c.logger.Infof("reattach runner job %s", c.job.RunnerJobID)
+	c.watchRunnerJob(ctx, runnerJob)
+}
+
+func (c *FreestyleJobCtl) dispatchRunnerJob(ctx context.Context) (*commonmodels.RunnerJob, error) {
+	// the jobs targeting the runners by labels are leased by any agent of the matched runners, no instance
+	// is launched for them.
+	r := &commonmodels.Runner{}
+	var provisioner runner.Provisioner
+	if c.jobTaskSpec.Properties.Runner != "" {
+		var err error
+		r, err = commonrepo.NewRunnerColl().Find(c.jobTaskSpec.Properties.Runner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find runner %s: %s", c.jobTaskSpec.Properties.Runner, err)
+		}
+		provisioner, err = runner.NewProvisioner(r)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		count, err := commonrepo.NewRunnerColl().CountByLabels(c.jobTaskSpec.Properties.RunnerLabels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find runners by labels: %s", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("no runner has the labels %s", strings.Join(c.jobTaskSpec.Properties.RunnerLabels, ","))
+		}
+	}
+	jobCtxBytes, err := yaml.Marshal(BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger))
+	if err != nil {
+		return nil, fmt.Errorf("cannot Jobexcutor.Context data: %v", err)
+	}
+
+	runnerJobColl := commonrepo.NewRunnerJobColl()
+	runnerJob := &commonmodels.RunnerJob{
+		RunnerName:   r.Name,
+		ProjectName:  c.workflowCtx.ProjectName,
+		WorkflowName: c.workflowCtx.WorkflowName,
+		TaskID:       c.workflowCtx.TaskID,
+		JobName:      c.job.Name,
+		Context:      string(jobCtxBytes),
+		Timeout:      c.jobTaskSpec.Properties.Timeout,
+	}
+	if r.Name == "" {
+		runnerJob.Labels = c.jobTaskSpec.Properties.RunnerLabels
+	}
+	if err := runnerJobColl.Create(runnerJob); err != nil {
+		return nil, fmt.Errorf("failed to create runner job: %s", err)
+	}
+	c.job.RunnerJobID = runnerJob.ID.Hex()
+	c.ack()
+
+	if r.Name == "" {
+		c.logger.Infof("job %s is dispatched to the runners with labels %v", c.job.Name, runnerJob.Labels)
+		return runnerJob, nil
+	}
+	if provisioner == nil {
+		c.logger.Infof("job %s is dispatched to runner %s", c.job.Name, r.Name)
+		return runnerJob, nil
+	}
+	instanceID, err := provisioner.Provision(ctx, runnerJob)
+	if err != nil {
+		if stopErr := runnerJobColl.Stop(runnerJob.ID, config.StatusFailed, err.Error()); stopErr != nil {
+			c.logger.Errorf("failed to stop runner job %s: %s", runnerJob.ID.Hex(), stopErr)
+		}
+		return nil, err
+	}
+	runnerJob.InstanceID = instanceID
+	if err := runnerJobColl.UpdateInstanceID(runnerJob.ID, instanceID); err != nil {
+		c.logger.Errorf("failed to save instance %s of runner job %s: %s", instanceID, runnerJob.ID.Hex(), err)
+	}
+	c.logger.Infof("instance %s of runner %s is launched for job %s", instanceID, r.Name, c.job.Name)
+	return runnerJob, nil
+}
+
+func (c *FreestyleJobCtl) watchRunnerJob(ctx context.Context, runnerJob *commonmodels.RunnerJob) {
+	c.secretScan = newLogSecretScan(c.workflowCtx.ProjectName, c.logger)
+	status, errMsg := waitRunnerJobEnd(ctx, runnerJob, c.logger)
+	// the job is reattached by the instance resuming the tasks.
+	if Detached() {
+		return
+	}
+	c.job.Status = status
+	if errMsg != "" {
+		c.job.Error = errMsg
+	}
+	releaseRunnerJob(runnerJob, c.logger)
+	c.completeRunnerJob(ctx, runnerJob)
+}
+
+func (c *FreestyleJobCtl) completeRunnerJob(ctx context.Context, runnerJob *commonmodels.RunnerJob) {
+	result, err := commonrepo.NewRunnerJobColl().FindByID(runnerJob.ID.Hex())
+	if err != nil {
+		c.logger.Error(err)
+		c.job.Error = err.Error()
+		return
+	}
+	// write jobs output info to globalcontext so other job can use like this $(jobName.outputName)
+	for _, output := range result.Outputs {
+		c.workflowCtx.GlobalContextSet(strings.Join([]string{"workflow", c.job.Name, output.Name}, "."), output.Value)
+	}
+
+	if err := saveRunnerJobLog(c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID); err != nil {
+		c.logger.Error(err)
+		c.job.Error = err.Error()
+		return
+	}
+	c.secretScan.report(c.job, result.SecretFindings)
+	if err := stepcontroller.SummarizeSteps(ctx, c.workflowCtx, &c.jobTaskSpec.Properties.Paths, c.jobTaskSpec.Steps, c.logger); err != nil {
+		c.logger.Error(err)
+		c.job.Error = err.Error()
+		return
+	}
+	c.job.Summaries = stepcontroller.CollectSummaries(c.jobTaskSpec.Steps)
+}
+
+// waitRunnerJobEnd waits for the result reported by the agent, the job is stopped if it times out, is
+// cancelled, or the agent is lost.
+func waitRunnerJobEnd(ctx context.Context, runnerJob *commonmodels.RunnerJob, logger *zap.SugaredLogger) (config.Status, string) {
+	runnerJobColl := commonrepo.NewRunnerJobColl()
+	stop := func(status config.Status, msg string) (config.Status, string) {
+		if err := runnerJobColl.Stop(runnerJob.ID, status, msg); err != nil {
+			logger.Errorf("failed to stop runner job %s: %s", runnerJob.ID.Hex(), err)
+		}
+		return status, msg
+	}
+	// the timeout is counted from the dispatch so that it is kept after aslan restarts.
+	deadline := time.Unix(runnerJob.CreateTime, 0).Add(time.Duration(runnerJob.Timeout) * time.Minute)
+	ticker := time.NewTicker(runnerJobPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if Detached() {
+				return config.StatusCancelled, ""
+			}
+			return stop(config.StatusCancelled, "")
+		case <-ticker.C:
+		}
+
+		current, err := runnerJobColl.FindByID(runnerJob.ID.Hex())
+		if err != nil {
+			logger.Errorf("failed to get runner job %s: %s", runnerJob.ID.Hex(), err)
+			continue
+		}
+		switch current.Status {
+		case config.StatusPassed, config.StatusFailed, config.StatusCancelled, config.StatusTimeout:
+			return current.Status, current.Error
+		case config.StatusRunning:
+			if time.Now().Unix() > current.LeaseExpireTime {
+				return stop(config.StatusFailed, fmt.Sprintf("agent %s of runner %s is lost", current.Agent, current.RunnerName))
+			}
+		}
+		if time.Now().After(deadline) {
+			return stop(config.StatusTimeout, "")
+		}
+	}
+}
+
+// releaseRunnerJob releases the ephemeral instance launched for the job.
+func releaseRunnerJob(runnerJob *commonmodels.RunnerJob, logger *zap.SugaredLogger) {
+	if runnerJob.InstanceID == "" {
+		return
+	}
+	r, err := commonrepo.NewRunnerColl().Find(runnerJob.RunnerName)
+	if err != nil {
+		logger.Errorf("failed to find runner %s: %s", runnerJob.RunnerName, err)
+		return
+	}
+	provisioner, err := runner.NewProvisioner(r)
+	if err != nil || provisioner == nil {
+		logger.Errorf("failed to release instance %s of runner %s: %v", runnerJob.InstanceID, r.Name, err)
+		return
+	}
+	if err := provisioner.Release(context.Background(), runnerJob.InstanceID); err != nil {
+		logger.Errorf("failed to release instance %s of runner %s: %s", runnerJob.InstanceID, r.Name, err)
+	}
+}
+
+// saveRunnerJobLog saves the chunks pushed by the agent as the complete log, the chunks are redacted when
+// they are received.
+func saveRunnerJobLog(workflowName, jobName string, taskID int64) error {
+	jobLog, err := s3.NewWorkflowJobLog(workflowName, jobName, taskID)
+	if err != nil {
+		return fmt.Errorf("saveRunnerJobLog: %s", err)
+	}
+	reader, err := jobLog.Open()
+	if err != nil {
+		return fmt.Errorf("saveRunnerJobLog open log error: %v", err)
+	}
+	if reader.Completed {
+		return nil
+	}
+	stream, err := reader.Stream()
+	if err != nil {
+		return fmt.Errorf("saveRunnerJobLog open log error: %v", err)
+	}
+	defer stream.Close()
+
+	tempFileName, err := util.GenerateTmpFile()
+	if err != nil {
+		return fmt.Errorf("saveRunnerJobLog GenerateTmpFile error: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tempFileName)
+	}()
+	file, err := os.Create(tempFileName)
+	if err != nil {
+		return fmt.Errorf("saveRunnerJobLog create file error: %v", err)
+	}
+	_, err = io.Copy(file, stream)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("saveRunnerJobLog copy log error: %v", err)
+	}
+
+	if err = jobLog.Upload(tempFileName); err != nil {
+		return fmt.Errorf("saveRunnerJobLog s3 Upload error: %v", err)
+	}
+	return nil
+}
+
+// NewLogSecretScanner returns the scanner redacting the job logs of the project, nil is returned if the
+// logs are not redacted.
+func NewLogSecretScanner(projectName string, logger *zap.SugaredLogger) *secretscan.Scanner {
+	return newLogSecretScan(projectName, logger).getScanner()
+}
@@ -0,0 +1,641 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/repo"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	crClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/multicluster"
+	"github.com/koderover/zadig/pkg/tool/kube/serializer"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+const (
+	defaultSandboxClusterTimeout = 60
+	sandboxTeardownTimeout       = 10 * time.Minute
+	sandboxSuiteContainer        = "suite"
+	sandboxSuiteLogsLimit        = 4096
+
+	vclusterChartRepoName = "loft-sh"
+	vclusterChartRepoURL  = "https://charts.loft.sh"
+	// clusterAPIWorkerClass is the worker class defined by the ClusterClass of the Cluster API quick start.
+	clusterAPIWorkerClass = "default-worker"
+
+	sandboxPhaseProvisioning = "provisioning"
+	sandboxPhaseDeploying    = "deploying"
+	sandboxPhaseTesting      = "testing"
+	sandboxPhaseTearingDown  = "tearing_down"
+	sandboxPhaseTornDown     = "torn_down"
+)
+
+var clusterAPIClusterGVK = schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "Cluster"}
+
+type SandboxClusterJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskSandboxClusterSpec
+	ack         func()
+	// hostClient is the client of the host cluster of the vcluster or the Cluster API management cluster,
+	// kubeClient and clientset are the clients of the sandbox cluster.
+	hostClient crClient.Client
+	kubeClient crClient.Client
+	clientset  kubernetes.Interface
+	// ownNamespace is set if the vcluster is installed in the namespace created for it.
+	ownNamespace bool
+}
+
+func NewSandboxClusterJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *SandboxClusterJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskSandboxClusterSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &SandboxClusterJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+// Run provisions the sandbox cluster, deploys the services and runs the test suites in it, the sandbox is
+// torn down whatever the result is. The sandbox name is kept in the spec, so the same sandbox is reused if
+// the job is run again after aslan restarts.
+func (c *SandboxClusterJobCtl) Run(ctx context.Context) {
+	if c.jobTaskSpec.Timeout <= 0 {
+		c.jobTaskSpec.Timeout = defaultSandboxClusterTimeout
+	}
+	if c.jobTaskSpec.HostClusterID == "" {
+		c.jobTaskSpec.HostClusterID = setting.LocalClusterID
+	}
+	if c.jobTaskSpec.SandboxName == "" {
+		name := "sandbox-" + getJobName(c.workflowCtx.WorkflowName, c.workflowCtx.TaskID)
+		if len(name) > 52 {
+			name = strings.TrimRight(name[:52], "-")
+		}
+		c.jobTaskSpec.SandboxName = name
+	}
+	if c.jobTaskSpec.HostNamespace == "" {
+		if c.jobTaskSpec.Provider == config.SandboxProviderVCluster {
+			c.jobTaskSpec.HostNamespace = c.jobTaskSpec.SandboxName
+			c.ownNamespace = true
+		} else {
+			c.jobTaskSpec.HostNamespace = "default"
+		}
+	}
+	c.ack()
+
+	hostClient, _, _, err := GetK8sClients(config.HubServerAddress(), c.jobTaskSpec.HostClusterID)
+	if err != nil {
+		c.fail(fmt.Sprintf("failed to get the clients of cluster %s: %v", c.jobTaskSpec.HostClusterID, err))
+		return
+	}
+	c.hostClient = hostClient
+
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(c.jobTaskSpec.Timeout)*time.Minute)
+	defer cancel()
+	defer c.teardown()
+
+	err = c.run(runCtx)
+	switch {
+	case ctx.Err() != nil:
+		c.job.Status = config.StatusCancelled
+	case runCtx.Err() != nil:
+		c.job.Status = config.StatusTimeout
+		c.job.Error = fmt.Sprintf("the job is not finished in %d minutes", c.jobTaskSpec.Timeout)
+	case err != nil:
+		c.fail(err.Error())
+	default:
+		c.job.Status = config.StatusPassed
+	}
+}
+
+func (c *SandboxClusterJobCtl) run(ctx context.Context) error {
+	c.setPhase(sandboxPhaseProvisioning)
+	kubeConfig, err := c.provision(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to provision the sandbox cluster: %v", err)
+	}
+	if err := c.register(kubeConfig); err != nil {
+		return fmt.Errorf("failed to register the sandbox cluster: %v", err)
+	}
+
+	c.setPhase(sandboxPhaseDeploying)
+	if err := c.deploy(ctx); err != nil {
+		return fmt.Errorf("failed to deploy env %s to the sandbox cluster: %v", c.jobTaskSpec.Env, err)
+	}
+
+	c.setPhase(sandboxPhaseTesting)
+	return c.runSuites(ctx)
+}
+
+// provision creates the sandbox cluster and returns the kubeconfig of it.
+func (c *SandboxClusterJobCtl) provision(ctx context.Context) (string, error) {
+	switch c.jobTaskSpec.Provider {
+	case config.SandboxProviderVCluster:
+		return c.provisionVCluster(ctx)
+	case config.SandboxProviderClusterAPI:
+		return c.provisionClusterAPI(ctx)
+	default:
+		return "", fmt.Errorf("unknown provider: %s", c.jobTaskSpec.Provider)
+	}
+}
+
+func (c *SandboxClusterJobCtl) provisionVCluster(ctx context.Context) (string, error) {
+	name, namespace := c.jobTaskSpec.SandboxName, c.jobTaskSpec.HostNamespace
+	hClient, err := helmtool.NewClientFromNamespace(c.jobTaskSpec.HostClusterID, namespace)
+	if err != nil {
+		return "", err
+	}
+	if _, err := hClient.UpdateChartRepo(&repo.Entry{Name: vclusterChartRepoName, URL: vclusterChartRepoURL}); err != nil {
+		return "", fmt.Errorf("failed to update the chart repo of vcluster: %v", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_, err = hClient.InstallOrUpgradeChart(ctx, &helmclient.ChartSpec{
+		ReleaseName:     name,
+		ChartName:       vclusterChartRepoName + "/vcluster",
+		Namespace:       namespace,
+		Version:         c.jobTaskSpec.VClusterVersion,
+		ValuesYaml:      c.jobTaskSpec.VClusterValues,
+		CreateNamespace: true,
+		Wait:            true,
+		Timeout:         time.Until(deadline),
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to install vcluster: %v", err)
+	}
+
+	var kubeConfig string
+	err = waitForSandbox(ctx, func() (bool, error) {
+		secret, found, err := getter.GetSecret(namespace, "vc-"+name, c.hostClient)
+		if err != nil || !found || len(secret.Data["config"]) == 0 {
+			return false, err
+		}
+		server, insecure, err := c.vclusterServer(name, namespace)
+		if err != nil || server == "" {
+			return false, err
+		}
+		kubeConfig, err = rewriteKubeConfigServer(secret.Data["config"], server, insecure)
+		return err == nil, err
+	})
+	return kubeConfig, err
+}
+
+// vclusterServer returns the address of the vcluster reachable from zadig. The certificate of the vcluster is
+// not verified if it is exposed by a load balancer, since the address is not in the certificate.
+func (c *SandboxClusterJobCtl) vclusterServer(name, namespace string) (string, bool, error) {
+	svc, found, err := getter.GetService(namespace, name, c.hostClient)
+	if err != nil || !found {
+		return "", false, err
+	}
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			host := ingress.IP
+			if host == "" {
+				host = ingress.Hostname
+			}
+			if host != "" {
+				return fmt.Sprintf("https://%s:443", host), true, nil
+			}
+		}
+		return "", false, nil
+	}
+	if c.jobTaskSpec.HostClusterID != setting.LocalClusterID {
+		return "", false, fmt.Errorf("vcluster in cluster %s is not reachable from zadig, expose it by a LoadBalancer service in the values", c.jobTaskSpec.HostClusterID)
+	}
+	return fmt.Sprintf("https://%s.%s.svc:443", name, namespace), false, nil
+}
+
+func (c *SandboxClusterJobCtl) provisionClusterAPI(ctx context.Context) (string, error) {
+	name, namespace := c.jobTaskSpec.SandboxName, c.jobTaskSpec.HostNamespace
+	workers := int64(c.jobTaskSpec.WorkerReplicas)
+	if workers <= 0 {
+		workers = 1
+	}
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(clusterAPIClusterGVK)
+	cluster.SetName(name)
+	cluster.SetNamespace(namespace)
+	topology := map[string]interface{}{
+		"class":   c.jobTaskSpec.ClusterClass,
+		"version": c.jobTaskSpec.KubernetesVersion,
+		"controlPlane": map[string]interface{}{
+			"replicas": int64(1),
+		},
+		"workers": map[string]interface{}{
+			"machineDeployments": []interface{}{
+				map[string]interface{}{
+					"class":    clusterAPIWorkerClass,
+					"name":     "md-0",
+					"replicas": workers,
+				},
+			},
+		},
+	}
+	if err := unstructured.SetNestedField(cluster.Object, topology, "spec", "topology"); err != nil {
+		return "", err
+	}
+	if err := c.hostClient.Create(ctx, cluster); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create cluster %s: %v", name, err)
+	}
+
+	var kubeConfig string
+	err := waitForSandbox(ctx, func() (bool, error) {
+		current := &unstructured.Unstructured{}
+		current.SetGroupVersionKind(clusterAPIClusterGVK)
+		if err := c.hostClient.Get(ctx, crClient.ObjectKey{Namespace: namespace, Name: name}, current); err != nil {
+			return false, err
+		}
+		phase, _, _ := unstructured.NestedString(current.Object, "status", "phase")
+		if phase == "Failed" {
+			msg, _, _ := unstructured.NestedString(current.Object, "status", "failureMessage")
+			return false, fmt.Errorf("cluster %s failed: %s", name, msg)
+		}
+		ready, _, _ := unstructured.NestedBool(current.Object, "status", "controlPlaneReady")
+		if phase != "Provisioned" || !ready {
+			return false, nil
+		}
+		secret, found, err := getter.GetSecret(namespace, name+"-kubeconfig", c.hostClient)
+		if err != nil || !found || len(secret.Data["value"]) == 0 {
+			return false, err
+		}
+		kubeConfig = string(secret.Data["value"])
+		return true, nil
+	})
+	return kubeConfig, err
+}
+
+// register adds the sandbox cluster to zadig for the duration of the job, so that it is listed with the
+// other clusters and the later jobs of the task can access it.
+func (c *SandboxClusterJobCtl) register(kubeConfig string) error {
+	if c.jobTaskSpec.SandboxClusterID == "" {
+		cluster := &commonmodels.K8SCluster{
+			Name:        c.jobTaskSpec.SandboxName,
+			Tags:        []string{"sandbox"},
+			Description: fmt.Sprintf("sandbox of job %s in task %d of workflow %s", c.job.Name, c.workflowCtx.TaskID, c.workflowCtx.WorkflowName),
+			Status:      setting.Normal,
+			Type:        setting.KubeConfigClusterType,
+			KubeConfig:  kubeConfig,
+			CreatedAt:   time.Now().Unix(),
+			CreatedBy:   setting.SystemUser,
+		}
+		if err := commonrepo.NewK8SClusterColl().Create(cluster, ""); err != nil {
+			return err
+		}
+		c.jobTaskSpec.SandboxClusterID = cluster.ID.Hex()
+		c.ack()
+	}
+
+	kubeClient, err := multicluster.GetKubeClientFromKubeConfig(c.jobTaskSpec.SandboxClusterID, kubeConfig)
+	if err != nil {
+		return err
+	}
+	clientset, err := multicluster.GetKubeClientSetFromKubeConfig(c.jobTaskSpec.SandboxClusterID, kubeConfig)
+	if err != nil {
+		return err
+	}
+	c.kubeClient = kubeClient
+	c.clientset = clientset
+	return nil
+}
+
+// deploy applies the manifests to the namespace of the env in the sandbox and waits for the workloads.
+func (c *SandboxClusterJobCtl) deploy(ctx context.Context) error {
+	namespace := c.jobTaskSpec.Namespace
+	if err := kube.CreateNamespace(namespace, nil, false, c.kubeClient); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %v", namespace, err)
+	}
+	if err := c.ensureRegistrySecrets(ctx, namespace); err != nil {
+		return err
+	}
+
+	for _, manifest := range c.jobTaskSpec.Manifests {
+		for _, item := range util.SplitManifests(manifest.Yaml) {
+			u, err := serializer.NewDecoder().YamlToUnstructured([]byte(item))
+			if err != nil {
+				return fmt.Errorf("failed to decode the manifest of service %s: %v", manifest.ServiceName, err)
+			}
+			u.SetNamespace(namespace)
+			if err := updater.CreateOrPatchUnstructured(u, c.kubeClient); err != nil {
+				return fmt.Errorf("failed to apply %s %s of service %s: %v", u.GetKind(), u.GetName(), manifest.ServiceName, err)
+			}
+		}
+	}
+
+	return waitForSandbox(ctx, func() (bool, error) {
+		deployments, err := getter.ListDeployments(namespace, labels.Everything(), c.kubeClient)
+		if err != nil {
+			return false, err
+		}
+		for _, deployment := range deployments {
+			if deployment.Status.ObservedGeneration < deployment.Generation || !replicasReady(deployment.Spec.Replicas, deployment.Status.ReadyReplicas) {
+				return false, nil
+			}
+		}
+		statefulSets, err := getter.ListStatefulSets(namespace, labels.Everything(), c.kubeClient)
+		if err != nil {
+			return false, err
+		}
+		for _, sts := range statefulSets {
+			if sts.Status.ObservedGeneration < sts.Generation || !replicasReady(sts.Spec.Replicas, sts.Status.ReadyReplicas) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// ensureRegistrySecrets creates the registry secrets in the namespace and attaches them to the default
+// service account, which is created by kubernetes shortly after the namespace.
+func (c *SandboxClusterJobCtl) ensureRegistrySecrets(ctx context.Context, namespace string) error {
+	secretNames := make([]string, 0, len(c.jobTaskSpec.Registries))
+	for _, reg := range c.jobTaskSpec.Registries {
+		if err := kube.CreateOrUpdateRegistrySecret(namespace, reg, reg.IsDefault, c.kubeClient); err != nil {
+			return fmt.Errorf("failed to create the registry secret in namespace %s: %v", namespace, err)
+		}
+		secretName, err := kube.GenRegistrySecretName(reg)
+		if err != nil {
+			return err
+		}
+		secretNames = append(secretNames, secretName)
+	}
+	if len(secretNames) == 0 {
+		return nil
+	}
+	err := waitForSandbox(ctx, func() (bool, error) {
+		sa := &corev1.ServiceAccount{}
+		err := c.kubeClient.Get(ctx, crClient.ObjectKey{Namespace: namespace, Name: kube.DefaultServiceAccount}, sa)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return err
+	}
+	return kube.AttachImagePullSecrets(namespace, kube.DefaultServiceAccount, secretNames, c.kubeClient)
+}
+
+// runSuites runs all the suites one by one, the job fails if any suite fails.
+func (c *SandboxClusterJobCtl) runSuites(ctx context.Context) error {
+	c.jobTaskSpec.SuiteResults = make([]*commonmodels.SandboxSuiteResult, 0, len(c.jobTaskSpec.Suites))
+	var failures int
+	for i, suite := range c.jobTaskSpec.Suites {
+		result := &commonmodels.SandboxSuiteResult{Name: suite.Name, Status: config.StatusRunning, StartTime: time.Now().Unix()}
+		c.jobTaskSpec.SuiteResults = append(c.jobTaskSpec.SuiteResults, result)
+		c.ack()
+
+		logs, err := c.runSuite(ctx, fmt.Sprintf("zadig-suite-%d", i), suite)
+		result.EndTime = time.Now().Unix()
+		result.Logs = logs
+		if ctx.Err() != nil {
+			result.Status = config.StatusCancelled
+			return ctx.Err()
+		}
+		if err != nil {
+			c.logger.Errorf("suite %s failed: %s", suite.Name, err)
+			result.Status = config.StatusFailed
+			result.Error = err.Error()
+			failures++
+			continue
+		}
+		result.Status = config.StatusPassed
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d test suites failed", failures, len(c.jobTaskSpec.Suites))
+	}
+	return nil
+}
+
+// runSuite runs the suite in a kubernetes job of the sandbox and returns the tail of its logs.
+func (c *SandboxClusterJobCtl) runSuite(ctx context.Context, jobName string, suite *commonmodels.SandboxTestSuite) (string, error) {
+	namespace := c.jobTaskSpec.Namespace
+	if err := updater.DeleteJobAndWait(namespace, jobName, c.kubeClient); err != nil {
+		return "", fmt.Errorf("failed to delete the former suite job: %v", err)
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: namespace,
+			Labels:    map[string]string{setting.JobLabelNameKey: jobName},
+		},
+		Spec: batchv1.JobSpec{
+			Completions:  int32Ptr(1),
+			Parallelism:  int32Ptr(1),
+			BackoffLimit: int32Ptr(0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{setting.JobLabelNameKey: jobName}},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    sandboxSuiteContainer,
+							Image:   suite.Image,
+							Command: []string{"/bin/sh", "-c", suite.Script},
+							Env: []corev1.EnvVar{
+								{Name: "SANDBOX_NAMESPACE", Value: namespace},
+								{Name: "SANDBOX_ENV", Value: c.jobTaskSpec.Env},
+								{Name: "PROJECT", Value: c.workflowCtx.ProjectName},
+								{Name: "WORKFLOW", Value: c.workflowCtx.WorkflowName},
+								{Name: "TASK_ID", Value: fmt.Sprintf("%d", c.workflowCtx.TaskID)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := updater.CreateJob(job, c.kubeClient); err != nil {
+		return "", fmt.Errorf("failed to create the suite job: %v", err)
+	}
+
+	var failed bool
+	err := waitForSandbox(ctx, func() (bool, error) {
+		current, found, err := getter.GetJob(namespace, jobName, c.kubeClient)
+		if err != nil || !found {
+			return false, err
+		}
+		failed = current.Status.Failed > 0
+		return current.Status.Succeeded > 0 || failed, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	logs := c.getSuiteLogs(ctx, namespace, jobName)
+	if failed {
+		return logs, fmt.Errorf("suite %s failed", suite.Name)
+	}
+	return logs, nil
+}
+
+func (c *SandboxClusterJobCtl) getSuiteLogs(ctx context.Context, namespace, jobName string) string {
+	pods, err := getter.ListPods(namespace, labels.Set{setting.JobLabelNameKey: jobName}.AsSelector(), c.kubeClient)
+	if err != nil || len(pods) == 0 {
+		return ""
+	}
+	logs, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pods[0].Name, &corev1.PodLogOptions{Container: sandboxSuiteContainer, TailLines: int64Ptr(200)}).DoRaw(ctx)
+	if err != nil {
+		c.logger.Warnf("failed to get the logs of suite job %s: %s", jobName, err)
+		return ""
+	}
+	if len(logs) > sandboxSuiteLogsLimit {
+		logs = logs[len(logs)-sandboxSuiteLogsLimit:]
+	}
+	return string(logs)
+}
+
+// teardown removes the sandbox cluster from zadig and deletes it, it runs after the job is cancelled too.
+func (c *SandboxClusterJobCtl) teardown() {
+	if c.hostClient == nil {
+		return
+	}
+	c.setPhase(sandboxPhaseTearingDown)
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxTeardownTimeout)
+	defer cancel()
+
+	var errs []string
+	if c.jobTaskSpec.SandboxClusterID != "" {
+		if err := commonrepo.NewK8SClusterColl().Delete(c.jobTaskSpec.SandboxClusterID); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to unregister cluster %s: %v", c.jobTaskSpec.SandboxClusterID, err))
+		}
+	}
+
+	name, namespace := c.jobTaskSpec.SandboxName, c.jobTaskSpec.HostNamespace
+	switch c.jobTaskSpec.Provider {
+	case config.SandboxProviderVCluster:
+		hClient, err := helmtool.NewClientFromNamespace(c.jobTaskSpec.HostClusterID, namespace)
+		if err == nil {
+			err = hClient.UninstallRelease(&helmclient.ChartSpec{
+				ReleaseName: name,
+				Namespace:   namespace,
+				Wait:        true,
+				Timeout:     sandboxTeardownTimeout,
+			})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to uninstall vcluster %s: %v", name, err))
+		}
+		if c.ownNamespace {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+			if err := c.hostClient.Delete(ctx, ns); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Sprintf("failed to delete namespace %s: %v", namespace, err))
+			}
+		}
+	case config.SandboxProviderClusterAPI:
+		cluster := &unstructured.Unstructured{}
+		cluster.SetGroupVersionKind(clusterAPIClusterGVK)
+		cluster.SetName(name)
+		cluster.SetNamespace(namespace)
+		if err := c.hostClient.Delete(ctx, cluster); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Sprintf("failed to delete cluster %s: %v", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		msg := strings.Join(errs, "; ")
+		c.logger.Error(msg)
+		if c.job.Error == "" {
+			c.job.Error = msg
+		}
+		c.ack()
+		return
+	}
+	c.setPhase(sandboxPhaseTornDown)
+}
+
+func (c *SandboxClusterJobCtl) setPhase(phase string) {
+	c.jobTaskSpec.Phase = phase
+	c.ack()
+}
+
+func (c *SandboxClusterJobCtl) fail(msg string) {
+	c.logger.Error(msg)
+	c.job.Status = config.StatusFailed
+	c.job.Error = msg
+}
+
+// waitForSandbox polls the condition every 5 seconds until it is met, fails or the context is done.
+func waitForSandbox(ctx context.Context, condition func() (bool, error)) error {
+	for {
+		done, err := condition()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func replicasReady(desired *int32, ready int32) bool {
+	if desired == nil {
+		return ready >= 1
+	}
+	return ready >= *desired
+}
+
+// rewriteKubeConfigServer points the clusters of the kubeconfig to the server.
+func rewriteKubeConfigServer(kubeConfig []byte, server string, insecure bool) (string, error) {
+	cfg, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the kubeconfig: %v", err)
+	}
+	for _, cluster := range cfg.Clusters {
+		cluster.Server = server
+		if insecure {
+			cluster.CertificateAuthorityData = nil
+			cluster.InsecureSkipTLSVerify = true
+		}
+	}
+	resp, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(resp), nil
+}
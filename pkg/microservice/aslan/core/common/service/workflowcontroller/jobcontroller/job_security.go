@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	corev1 "k8s.io/api/core/v1"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commontypes "github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	// restrictedJobUser is the user the restricted jobs run as.
+	restrictedJobUser = 1000
+	// restrictedJobBinDir is where the job executor is downloaded to if the root filesystem is read-only.
+	restrictedJobBinDir = ZadigContextDir + "bin"
+	restrictedJobHome   = ZadigContextDir + "home"
+)
+
+// getJobSecurityProfile returns the profile the jobs of the project run with, the minimum profile of the
+// system is used if the project chooses a weaker one.
+func getJobSecurityProfile(projectName string) (commontypes.JobSecurityProfile, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to find project %s: %s", projectName, err)
+	}
+	var profile commontypes.JobSecurityProfile
+	if project.JobSecurityPolicy != nil {
+		profile = project.JobSecurityPolicy.Profile
+	}
+
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", fmt.Errorf("failed to get system settings: %s", err)
+	}
+	if err == nil && sysSetting.JobSecurity != nil {
+		return profile.Enforce(sysSetting.JobSecurity.MinimumProfile), nil
+	}
+	return profile.Normalize(), nil
+}
+
+// applyJobSecurityProfile renders the profile into the pod of the job. The writable dirs are mounted from
+// empty dirs if the root filesystem of the restricted job is read-only.
+func applyJobSecurityProfile(spec *corev1.PodSpec, profile commontypes.JobSecurityProfile, writableDirs []string) {
+	switch profile {
+	case commontypes.JobSecurityPrivileged:
+		for i := range spec.Containers {
+			spec.Containers[i].SecurityContext = &corev1.SecurityContext{Privileged: boolPtr(true)}
+		}
+	case commontypes.JobSecurityRestricted:
+		spec.SecurityContext = &corev1.PodSecurityContext{
+			RunAsNonRoot:   boolPtr(true),
+			RunAsUser:      int64Ptr(restrictedJobUser),
+			RunAsGroup:     int64Ptr(restrictedJobUser),
+			FSGroup:        int64Ptr(restrictedJobUser),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+		mounts := make([]corev1.VolumeMount, 0, len(writableDirs))
+		for i, dir := range writableDirs {
+			name := fmt.Sprintf("writable-%d", i)
+			spec.Volumes = append(spec.Volumes, corev1.Volume{
+				Name:         name,
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			})
+			mounts = append(mounts, corev1.VolumeMount{Name: name, MountPath: dir})
+		}
+		for i := range spec.Containers {
+			container := &spec.Containers[i]
+			container.SecurityContext = &corev1.SecurityContext{
+				AllowPrivilegeEscalation: boolPtr(false),
+				ReadOnlyRootFilesystem:   boolPtr(true),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			}
+			container.VolumeMounts = append(container.VolumeMounts, mounts...)
+			container.Env = append(container.Env, corev1.EnvVar{Name: "HOME", Value: restrictedJobHome})
+		}
+	}
+}
+
+// restrictedJobBootingScript runs the job executor out of the read-only root filesystem.
+func restrictedJobBootingScript(clusterID, currentNamespace string) string {
+	return fmt.Sprintf("mkdir -p %s %s && cd %s && %s && %s/reaper", restrictedJobBinDir, restrictedJobHome, restrictedJobHome,
+		downloadJobExecutorTo(clusterID, currentNamespace, restrictedJobBinDir), restrictedJobBinDir)
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package jobcontroller
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -29,7 +28,6 @@ import (
 	"strings"
 	"time"
 
-	s3tool "github.com/koderover/zadig/pkg/tool/s3"
 	"go.uber.org/zap"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -42,17 +40,20 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
-	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	"github.com/koderover/zadig/pkg/tool/diagnosis"
 	"github.com/koderover/zadig/pkg/tool/kube/containerlog"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/kube/podexec"
 	"github.com/koderover/zadig/pkg/tool/kube/updater"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/logannotation"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
 	commontypes "github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/job"
 	"github.com/koderover/zadig/pkg/util"
@@ -134,7 +135,8 @@ func createJobConfigMap(namespace, jobName string, jobLabel *JobLabel, jobCtx st
 	return updater.CreateConfigMap(cm, kubeClient)
 }
 
-func getBaseImage(buildOS, imageFrom string) string {
+// GetBaseImage returns the image the job runs in.
+func GetBaseImage(buildOS, imageFrom string) string {
 	// for built-in image, reaperImage and buildOs can generate a complete image
 	// reaperImage: koderover.tencentcloudcr.com/koderover-public/build-base:${BuildOS}-amd64
 	// buildOS: focal xenial bionic
@@ -266,18 +268,7 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 	// `
 	// 	tailLogCommand := fmt.Sprintf(tailLogCommandTemplate, ZadigLogFile, ZadigLifeCycleFile)
 
-	var (
-		jobExecutorBootingScript string
-		jobExecutorBinaryFile    = JobExecutorFile
-	)
-	// not local cluster
-	if clusterID != "" && clusterID != setting.LocalClusterID {
-		jobExecutorBinaryFile = strings.Replace(jobExecutorBinaryFile, ResourceServer, ResourceServer+".koderover-agent", -1)
-	} else {
-		jobExecutorBinaryFile = strings.Replace(jobExecutorBinaryFile, ResourceServer, ResourceServer+"."+currentNamespace, -1)
-	}
-
-	jobExecutorBootingScript = fmt.Sprintf("curl -m 10 --retry-delay 3 --retry 3 -sSL %s -o reaper && chmod +x reaper && mv reaper /usr/local/bin && /usr/local/bin/reaper", jobExecutorBinaryFile)
+	jobExecutorBootingScript := fmt.Sprintf("%s && /usr/local/bin/reaper", getJobExecutorDownloadScript(clusterID, currentNamespace))
 
 	labels := getJobLabels(&JobLabel{
 		WorkflowName: workflowCtx.WorkflowName,
@@ -303,7 +294,7 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 			// in case finished zombie job not cleaned up by zadig
 			TTLSecondsAfterFinished: int32Ptr(3600),
 			// in case zombie job never stop
-			ActiveDeadlineSeconds: int64Ptr(jobTaskSpec.Properties.Timeout*60 + 3600),
+			ActiveDeadlineSeconds: int64Ptr(jobTaskSpec.Properties.Timeout*60 + jobDebugTimeout(workflowCtx)*60 + 3600),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
@@ -398,6 +389,22 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 	return job, nil
 }
 
+// getJobExecutorDownloadScript returns the script which downloads the job executor to /usr/local/bin/reaper.
+func getJobExecutorDownloadScript(clusterID, currentNamespace string) string {
+	return downloadJobExecutorTo(clusterID, currentNamespace, "/usr/local/bin")
+}
+
+func downloadJobExecutorTo(clusterID, currentNamespace, binDir string) string {
+	jobExecutorBinaryFile := JobExecutorFile
+	// not local cluster
+	if clusterID != "" && clusterID != setting.LocalClusterID {
+		jobExecutorBinaryFile = strings.Replace(jobExecutorBinaryFile, ResourceServer, ResourceServer+".koderover-agent", -1)
+	} else {
+		jobExecutorBinaryFile = strings.Replace(jobExecutorBinaryFile, ResourceServer, ResourceServer+"."+currentNamespace, -1)
+	}
+	return fmt.Sprintf("curl -m 10 --retry-delay 3 --retry 3 -sSL %s -o reaper && chmod +x reaper && mv reaper %s", jobExecutorBinaryFile, binDir)
+}
+
 func getImagePullSecrets(registries []*commonmodels.RegistryNamespace) ([]corev1.LocalObjectReference, error) {
 	ImagePullSecrets := []corev1.LocalObjectReference{
 		{
@@ -703,7 +710,8 @@ func getJobOutput(namespace, containerName string, jobLabel *JobLabel, kubeClien
 			return resp, nil
 		}
 		for _, containerStatus := range pod.Status.ContainerStatuses {
-			if containerStatus.Name != ls[containerName] {
+			// the container of a warm job is not named after the job.
+			if containerStatus.Name != ls[containerName] && pod.Labels[setting.JobLabelWarmStateKey] != setting.JobWarmStateClaimed {
 				continue
 			}
 			if containerStatus.State.Terminated != nil && len(containerStatus.State.Terminated.Message) != 0 {
@@ -717,22 +725,30 @@ func getJobOutput(namespace, containerName string, jobLabel *JobLabel, kubeClien
 	return resp, nil
 }
 
-func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID int64, jobLabel *JobLabel, kubeClient crClient.Client) error {
+// containerLogAnalysis is what is found in the log of a job when it is saved.
+type containerLogAnalysis struct {
+	Findings    []*secretscan.Finding
+	Diagnoses   []*diagnosis.Diagnosis
+	Annotations []*logannotation.Annotation
+}
+
+// saveContainerLog uploads the log of the job, the secrets are redacted from the log if scanner is not nil.
+// The failure of the job is diagnosed and the errors in the log are annotated if failed is true.
+func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID int64, jobLabel *JobLabel, kubeClient crClient.Client, scanner *secretscan.Scanner, failed bool) (*containerLogAnalysis, error) {
 	selector := labels.Set(getJobLabels(jobLabel)).AsSelector()
 	pods, err := getter.ListPods(namespace, selector, kubeClient)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(pods) < 1 {
-		return fmt.Errorf("no pod found with selector: %s", selector)
+		return nil, fmt.Errorf("no pod found with selector: %s", selector)
 	}
 
 	if len(pods[0].Status.ContainerStatuses) < 1 {
-		return fmt.Errorf("no cotainer statuses : %s", selector)
+		return nil, fmt.Errorf("no cotainer statuses : %s", selector)
 	}
 
-	buf := new(bytes.Buffer)
 	// 默认取第一个build job的第一个pod的第一个container的日志
 	sort.SliceStable(pods, func(i, j int) bool {
 		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
@@ -741,53 +757,85 @@ func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID
 	clientSet, err := kubeclient.GetClientset(config.HubServerAddress(), clusterID)
 	if err != nil {
 		log.Errorf("saveContainerLog, get client set error: %s", err)
-		return err
+		return nil, err
+	}
+
+	jobLog, err := s3.NewWorkflowJobLog(workflowName, jobName, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("saveContainerLog: %s", err)
 	}
 
-	if err := containerlog.GetContainerLogs(namespace, pods[0].Name, pods[0].Spec.Containers[0].Name, false, int64(0), buf, clientSet); err != nil {
-		return fmt.Errorf("failed to get container logs: %s", err)
+	tempFileName, err := util.GenerateTmpFile()
+	if err != nil {
+		return nil, fmt.Errorf("saveContainerLog GenerateTmpFile error: %v", err)
 	}
+	defer func() {
+		_ = os.Remove(tempFileName)
+	}()
 
-	store, err := commonrepo.NewS3StorageColl().FindDefault()
+	// the log is written to the file directly so that a large log is not kept in memory
+	file, err := os.Create(tempFileName)
+	if err != nil {
+		return nil, fmt.Errorf("saveContainerLog create file error: %v", err)
+	}
+	err = containerlog.GetContainerLogs(namespace, pods[0].Name, pods[0].Spec.Containers[0].Name, false, int64(0), file, clientSet)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get default s3 storage: %s", err)
+		return nil, fmt.Errorf("failed to get container logs: %s", err)
 	}
 
-	if tempFileName, err := util.GenerateTmpFile(); err == nil {
+	analysis := &containerLogAnalysis{}
+	if scanner != nil {
+		var redactedFileName string
+		analysis.Findings, redactedFileName, err = redactLogFile(tempFileName, scanner)
+		if err != nil {
+			return nil, fmt.Errorf("saveContainerLog redact error: %v", err)
+		}
 		defer func() {
-			_ = os.Remove(tempFileName)
+			_ = os.Remove(redactedFileName)
 		}()
-		if err = saveFile(buf, tempFileName); err == nil {
+		tempFileName = redactedFileName
+	}
 
-			if store.Subfolder != "" {
-				store.Subfolder = fmt.Sprintf("%s/%s/%d/%s", store.Subfolder, strings.ToLower(workflowName), taskID, "log")
-			} else {
-				store.Subfolder = fmt.Sprintf("%s/%d/%s", strings.ToLower(workflowName), taskID, "log")
-			}
-			forcedPathStyle := true
-			if store.Provider == setting.ProviderSourceAli {
-				forcedPathStyle = false
-			}
-			s3client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Insecure, forcedPathStyle)
-			if err != nil {
-				return fmt.Errorf("saveContainerLog s3 create client error: %v", err)
-			}
-			fileName := strings.Replace(strings.ToLower(jobName), "_", "-", -1)
-			objectKey := GetObjectPath(store.Subfolder, fileName+".log")
-			if err = s3client.Upload(
-				store.Bucket,
-				tempFileName,
-				objectKey,
-			); err != nil {
-				return fmt.Errorf("saveContainerLog s3 Upload error: %v", err)
-			}
-		} else {
-			return fmt.Errorf("saveContainerLog saveFile error: %v", err)
-		}
-	} else {
-		return fmt.Errorf("saveContainerLog GenerateTmpFile error: %v", err)
+	if failed {
+		analysis.Diagnoses = diagnoseJobPod(namespace, pods[0], tempFileName, clientSet)
+		analysis.Annotations = annotateJobLog(tempFileName)
+	}
+
+	if err = jobLog.Upload(tempFileName); err != nil {
+		return nil, fmt.Errorf("saveContainerLog s3 Upload error: %v", err)
+	}
+	return analysis, nil
+}
+
+// redactLogFile writes the log with the secrets redacted to a new temp file.
+func redactLogFile(fileName string, scanner *secretscan.Scanner) ([]*secretscan.Finding, string, error) {
+	src, err := os.Open(fileName)
+	if err != nil {
+		return nil, "", err
+	}
+	defer src.Close()
+
+	redactedFileName, err := util.GenerateTmpFile()
+	if err != nil {
+		return nil, "", err
+	}
+	dst, err := os.Create(redactedFileName)
+	if err != nil {
+		_ = os.Remove(redactedFileName)
+		return nil, "", err
+	}
+	findings, err := scanner.Redact(src, dst)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(redactedFileName)
+		return nil, "", err
 	}
-	return nil
+	return findings, redactedFileName, nil
 }
 
 func GetObjectPath(subFolder, name string) string {
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/logannotation"
+	"github.com/koderover/zadig/pkg/types"
+	stepspec "github.com/koderover/zadig/pkg/types/step"
+)
+
+// annotateJobLog finds the errors referring to the source in the log of the failed job, the job is not failed
+// by the errors of the parsing, they are only logged.
+func annotateJobLog(logFileName string) []*logannotation.Annotation {
+	file, err := os.Open(logFileName)
+	if err != nil {
+		log.Warnf("failed to open the log %s: %s", logFileName, err)
+		return nil
+	}
+	defer file.Close()
+
+	annotations, err := logannotation.Parse(file)
+	if err != nil {
+		log.Warnf("failed to annotate the log %s: %s", logFileName, err)
+	}
+	return annotations
+}
+
+// linkLogAnnotations resolves the paths of the annotations to the repos checked out by the git steps of the
+// job, the resolved ones link to the files at the built commits.
+func linkLogAnnotations(annotations []*logannotation.Annotation, steps []*commonmodels.StepTask, workspace string) []*logannotation.Annotation {
+	if len(annotations) == 0 {
+		return annotations
+	}
+	var repos []*types.Repository
+	for _, step := range steps {
+		if step.StepType != config.StepGit {
+			continue
+		}
+		stepSpec := &stepspec.StepGitSpec{}
+		if err := commonmodels.IToi(step.Spec, stepSpec); err != nil {
+			continue
+		}
+		repos = append(repos, stepSpec.Repos...)
+	}
+
+	for _, annotation := range annotations {
+		repo, file := resolveAnnotationPath(annotation.Path, repos, workspace)
+		if repo == nil {
+			continue
+		}
+		annotation.Repo = repo.RepoName
+		annotation.File = file
+		annotation.URL = blobURL(repo, file, annotation.Line)
+	}
+	return annotations
+}
+
+// resolveAnnotationPath finds the repo of the path by the directory the repo is checked out to, the relative
+// paths which are not in any of the directories are regarded as the paths in the repo if there is only one,
+// since the builds usually run in it.
+func resolveAnnotationPath(file string, repos []*types.Repository, workspace string) (*types.Repository, string) {
+	if len(repos) == 0 {
+		return nil, ""
+	}
+	absolute := path.IsAbs(file)
+	if absolute {
+		if !strings.HasPrefix(file, workspace+"/") {
+			return nil, ""
+		}
+		file = strings.TrimPrefix(file, workspace+"/")
+	}
+	file = path.Clean(file)
+	if strings.HasPrefix(file, "../") {
+		return nil, ""
+	}
+
+	for _, repo := range repos {
+		dir := repo.RepoName
+		if repo.CheckoutPath != "" {
+			dir = path.Clean(repo.CheckoutPath)
+		}
+		if strings.HasPrefix(file, dir+"/") {
+			return repo, strings.TrimPrefix(file, dir+"/")
+		}
+	}
+	if !absolute && len(repos) == 1 {
+		return repos[0], file
+	}
+	return nil, ""
+}
+
+// blobURL links to the line of the file at the commit, it is empty if the code host is not supported.
+func blobURL(repo *types.Repository, file string, line int) string {
+	ref := repo.CommitID
+	if ref == "" {
+		ref = repo.Branch
+	}
+	if ref == "" || repo.Address == "" {
+		return ""
+	}
+	base := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(repo.Address, "/"), repo.GetRepoNamespace(), repo.RepoName)
+	switch repo.Source {
+	case setting.SourceFromGithub, setting.SourceFromGitee, setting.SourceFromGitea:
+		return fmt.Sprintf("%s/blob/%s/%s#L%d", base, ref, file, line)
+	case setting.SourceFromGitlab:
+		return fmt.Sprintf("%s/-/blob/%s/%s#L%d", base, ref, file, line)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+)
+
+// logSecretScan redacts the secrets from the job logs and collects the findings with the secret scan
+// policy of the project.
+type logSecretScan struct {
+	scanner *secretscan.Scanner
+	policy  *templatemodels.SecretScanPolicy
+}
+
+// newLogSecretScan returns nil if the secret scan of the project is disabled.
+func newLogSecretScan(projectName string, logger *zap.SugaredLogger) *logSecretScan {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		logger.Warnf("failed to find project %s: %s", projectName, err)
+		return nil
+	}
+	policy := project.SecretScanPolicy
+	if policy == nil || !policy.Enabled || (!policy.ScanLogs && !policy.ScanSource) {
+		return nil
+	}
+	rules := policy.Rules
+	if !policy.ScanLogs {
+		// the logs are not redacted, only the findings of the source scan are collected.
+		rules = &secretscan.Config{DisableDefaultRules: true}
+	}
+	scanner, err := secretscan.New(rules)
+	if err != nil {
+		logger.Warnf("invalid secret scan rules of project %s: %s", projectName, err)
+		return nil
+	}
+	return &logSecretScan{scanner: scanner, policy: policy}
+}
+
+func (s *logSecretScan) getScanner() *secretscan.Scanner {
+	if s == nil {
+		return nil
+	}
+	return s.scanner
+}
+
+// report raises the findings on the job, the job fails if the policy requires it.
+func (s *logSecretScan) report(job *commonmodels.JobTask, findings []*secretscan.Finding) {
+	if s == nil || len(findings) == 0 {
+		return
+	}
+	job.SecretFindings = findings
+	if s.policy.FailOnFinding && job.Status == config.StatusPassed {
+		job.Status = config.StatusFailed
+		job.Error = fmt.Sprintf("%d secrets are found by the secret scan", len(findings))
+	}
+}
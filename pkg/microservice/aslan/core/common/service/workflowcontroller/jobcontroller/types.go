@@ -35,6 +35,8 @@ type JobContext struct {
 	TaskID int64 `yaml:"task_id"`
 	// Paths 执行脚本Path
 	Paths string `yaml:"paths"`
+	// DebugTimeout 任务失败后保留容器用于调试的分钟数 [optional]
+	DebugTimeout int64 `yaml:"debug_timeout,omitempty"`
 
 	Steps   []*commonmodels.StepTask `yaml:"steps"`
 	Outputs []string                 `yaml:"outputs"`
@@ -0,0 +1,369 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	crClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	zadigconfig "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/podexec"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/tool/log"
+	commontypes "github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+const (
+	warmJobDir           = ZadigContextDir + "warm/"
+	warmJobBootedFile    = warmJobDir + "booted"
+	warmJobReadyFile     = warmJobDir + "ready"
+	warmJobEnvFile       = warmJobDir + "env"
+	warmJobConfigFile    = warmJobDir + "job-config.xml"
+	warmJobContainerName = "job"
+	// idle warm jobs are recreated after warmJobMaxIdle in case the base image is updated.
+	warmJobMaxIdle       = 24 * time.Hour
+	warmPoolSyncInterval = 10 * time.Second
+)
+
+var warmPoolNotify = make(chan struct{}, 1)
+
+// StartWarmPoolController keeps the idle jobs of the enabled warm pools, the pools are only
+// synced by the leader so that the jobs are not created by several instances.
+func StartWarmPoolController(isLeader func() bool) {
+	go func() {
+		ticker := time.NewTicker(warmPoolSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-warmPoolNotify:
+			}
+			if isLeader() {
+				syncWarmPools()
+			}
+		}
+	}()
+}
+
+// notifyWarmPool asks the controller to refill the pools after a warm job is claimed.
+func notifyWarmPool() {
+	select {
+	case warmPoolNotify <- struct{}{}:
+	default:
+	}
+}
+
+func syncWarmPools() {
+	logger := log.SugaredLogger()
+	pools, err := commonrepo.NewJobWarmPoolColl().ListEnabled()
+	if err != nil {
+		logger.Errorf("failed to list job warm pools, err: %s", err)
+		return
+	}
+	for _, pool := range pools {
+		if err := syncWarmPool(pool); err != nil {
+			logger.Errorf("failed to sync job warm pool %s, err: %s", pool.Name, err)
+		}
+	}
+}
+
+func syncWarmPool(pool *commonmodels.JobWarmPool) error {
+	namespace, kubeClient, err := getWarmPoolClient(pool.ClusterID)
+	if err != nil {
+		return err
+	}
+	jobs, err := getter.ListJobs(namespace, warmJobSelector(pool), kubeClient)
+	if err != nil {
+		return err
+	}
+
+	revision := warmPoolRevision(pool)
+	size := 0
+	for _, job := range jobs {
+		if job.Labels[setting.JobLabelWarmRevisionKey] != revision || job.Status.Failed > 0 || job.Status.Succeeded > 0 ||
+			time.Since(job.CreationTimestamp.Time) > warmJobMaxIdle {
+			if err := updater.DeleteJob(namespace, job.Name, kubeClient); err != nil {
+				log.Warnf("failed to delete warm job %s/%s, err: %s", namespace, job.Name, err)
+			}
+			continue
+		}
+		size++
+	}
+	for ; size < pool.Size; size++ {
+		if err := updater.CreateJob(buildWarmJob(pool, namespace), kubeClient); err != nil {
+			return fmt.Errorf("failed to create warm job: %s", err)
+		}
+	}
+	return nil
+}
+
+// DeleteWarmPoolJobs deletes the idle jobs of the pool, the claimed jobs are deleted when they are done.
+func DeleteWarmPoolJobs(pool *commonmodels.JobWarmPool) error {
+	namespace, kubeClient, err := getWarmPoolClient(pool.ClusterID)
+	if err != nil {
+		return err
+	}
+	return updater.DeleteJobsAndWait(namespace, warmJobSelector(pool), kubeClient)
+}
+
+func getWarmPoolClient(clusterID string) (string, crClient.Client, error) {
+	if clusterID == "" || clusterID == setting.LocalClusterID {
+		return zadigconfig.Namespace(), krkubeclient.Client(), nil
+	}
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), clusterID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get controller runtime client: %s", err)
+	}
+	return setting.AttachedClusterNamespace, kubeClient, nil
+}
+
+func warmPoolRevision(pool *commonmodels.JobWarmPool) string {
+	return strconv.FormatInt(pool.UpdateTime, 10)
+}
+
+func warmJobSelector(pool *commonmodels.JobWarmPool) labels.Selector {
+	return labels.Set{
+		setting.JobLabelWarmPoolKey:  pool.ID.Hex(),
+		setting.JobLabelWarmStateKey: setting.JobWarmStateIdle,
+	}.AsSelector()
+}
+
+// buildWarmJob builds a job which downloads the job executor and waits for the job config,
+// the executor is started once the job is claimed.
+func buildWarmJob(pool *commonmodels.JobWarmPool, namespace string) *batchv1.Job {
+	labels := map[string]string{
+		setting.JobLabelWarmPoolKey:     pool.ID.Hex(),
+		setting.JobLabelWarmStateKey:    setting.JobWarmStateIdle,
+		setting.JobLabelWarmRevisionKey: warmPoolRevision(pool),
+	}
+	jobExecutorBootingScript := fmt.Sprintf("%s && mkdir -p %s && touch %s && while [ ! -f %s ]; do sleep 1; done && . %s && /usr/local/bin/reaper",
+		getJobExecutorDownloadScript(pool.ClusterID, namespace), warmJobDir, warmJobBootedFile, warmJobReadyFile, warmJobEnvFile)
+	// the default image pull secret is always valid.
+	imagePullSecrets, _ := getImagePullSecrets(nil)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "zadig-warm-",
+			Namespace:    namespace,
+			Labels:       labels,
+		},
+		Spec: batchv1.JobSpec{
+			Completions:  int32Ptr(1),
+			Parallelism:  int32Ptr(1),
+			BackoffLimit: int32Ptr(0),
+			// in case finished zombie job not cleaned up by zadig
+			TTLSecondsAfterFinished: int32Ptr(3600),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: imagePullSecrets,
+					Containers: []corev1.Container{
+						{
+							ImagePullPolicy: corev1.PullAlways,
+							Name:            warmJobContainerName,
+							Image:           GetBaseImage(pool.BuildOS, pool.ImageFrom),
+							Command:         []string{"/bin/sh", "-c"},
+							Args:            []string{jobExecutorBootingScript},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "zadig-context",
+									MountPath: ZadigContextDir,
+								},
+							},
+							Resources: getResourceRequirements(pool.ResourceRequest, pool.ResReqSpec),
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									Exec: &corev1.ExecAction{
+										Command: []string{"test", "-f", warmJobBootedFile},
+									},
+								},
+								PeriodSeconds: 2,
+							},
+
+							TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+							TerminationMessagePath:   job.JobTerminationFile,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "zadig-context",
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// findWarmPool returns the enabled pool whose jobs can run the job, nil is returned if there is none.
+func findWarmPool(properties *commonmodels.JobProperties) *commonmodels.JobWarmPool {
+	// the cache volume can not be added to a running pod.
+	if properties.CacheEnable && properties.Cache.MediumType == commontypes.NFSMedium {
+		return nil
+	}
+	pools, err := commonrepo.NewJobWarmPoolColl().ListEnabled()
+	if err != nil {
+		log.Warnf("failed to list job warm pools, err: %s", err)
+		return nil
+	}
+	for _, pool := range pools {
+		clusterID := pool.ClusterID
+		if clusterID == "" {
+			clusterID = setting.LocalClusterID
+		}
+		if clusterID != properties.ClusterID || pool.BuildOS != properties.BuildOS || pool.ImageFrom != properties.ImageFrom ||
+			pool.ResourceRequest != properties.ResourceRequest {
+			continue
+		}
+		if properties.ResourceRequest == setting.DefineRequest && pool.ResReqSpec != properties.ResReqSpec {
+			continue
+		}
+		return pool
+	}
+	return nil
+}
+
+// claimWarmJob hands the job over to an idle job of a matching warm pool, the name of the claimed
+// job is returned. It is empty if no warm job can be used and the job should be created as usual.
+func claimWarmJob(namespace string, jobTaskSpec *commonmodels.JobTaskBuildSpec, jobLabel *JobLabel, jobCtx []byte, kubeClient crClient.Client, clientset kubernetes.Interface, restConfig *rest.Config, logger *zap.SugaredLogger) string {
+	pool := findWarmPool(&jobTaskSpec.Properties)
+	if pool == nil {
+		return ""
+	}
+	defer notifyWarmPool()
+
+	jobs, err := getter.ListJobs(namespace, warmJobSelector(pool), kubeClient)
+	if err != nil {
+		logger.Warnf("failed to list warm jobs of pool %s, err: %s", pool.Name, err)
+		return ""
+	}
+	if len(jobs) == 0 {
+		return ""
+	}
+	if err := ensureDeleteJob(namespace, jobLabel, kubeClient); err != nil {
+		logger.Warnf("failed to delete job, err: %s", err)
+		return ""
+	}
+
+	jobLabels := getJobLabels(jobLabel)
+	for _, warmJob := range jobs {
+		if warmJob.Labels[setting.JobLabelWarmRevisionKey] != warmPoolRevision(pool) {
+			continue
+		}
+		pod := getReadyWarmPod(namespace, warmJob.Name, kubeClient)
+		if pod == nil {
+			continue
+		}
+
+		warmJob.Labels[setting.JobLabelWarmStateKey] = setting.JobWarmStateClaimed
+		for k, v := range jobLabels {
+			warmJob.Labels[k] = v
+		}
+		// in case zombie job never stop
+		warmJob.Spec.ActiveDeadlineSeconds = int64Ptr(int64(time.Since(warmJob.CreationTimestamp.Time).Seconds()) + jobTaskSpec.Properties.Timeout*60 + 3600)
+		// the update fails with a conflict if the job is claimed by another task.
+		if err := updater.UpdateJob(warmJob, kubeClient); err != nil {
+			continue
+		}
+
+		if err := startWarmJob(namespace, pod.Name, warmJob.Labels, jobCtx, jobTaskSpec.Properties.DockerHost, kubeClient, clientset, restConfig); err != nil {
+			logger.Warnf("failed to start warm job %s, err: %s", warmJob.Name, err)
+			if err := updater.DeleteJobAndWait(namespace, warmJob.Name, kubeClient); err != nil {
+				logger.Errorf("failed to delete warm job %s, err: %s", warmJob.Name, err)
+			}
+			return ""
+		}
+		return warmJob.Name
+	}
+	return ""
+}
+
+func getReadyWarmPod(namespace, jobName string, kubeClient crClient.Client) *corev1.Pod {
+	pods, err := getter.ListPods(namespace, labels.Set{"job-name": jobName}.AsSelector(), kubeClient)
+	if err != nil {
+		return nil
+	}
+	for _, pod := range pods {
+		ipod := wrapper.Pod(pod)
+		if ipod.Phase() == setting.PodRunning && ipod.Ready() {
+			return pod
+		}
+	}
+	return nil
+}
+
+// startWarmJob labels the pod with the job labels so that its logs and outputs can be found,
+// then writes the job config to the pod and starts the job executor.
+func startWarmJob(namespace, podName string, podLabels map[string]string, jobCtx []byte, dockerHost string, kubeClient crClient.Client, clientset kubernetes.Interface, restConfig *rest.Config) error {
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": podLabels},
+	})
+	if err != nil {
+		return err
+	}
+	if err := updater.PatchPod(namespace, podName, patchBytes, kubeClient); err != nil {
+		return fmt.Errorf("failed to label pod: %s", err)
+	}
+
+	env := fmt.Sprintf("export JOB_CONFIG_FILE=%s\nexport DOCKER_HOST=%s\n", warmJobConfigFile, dockerHost)
+	for _, file := range []struct {
+		command string
+		content []byte
+	}{
+		{command: fmt.Sprintf("cat > %s", warmJobConfigFile), content: jobCtx},
+		{command: fmt.Sprintf("cat > %s && touch %s", warmJobEnvFile, warmJobReadyFile), content: []byte(env)},
+	} {
+		_, stderr, _, err := podexec.KubeExec(clientset, restConfig, podexec.ExecOptions{
+			Command:       []string{"/bin/sh", "-c", file.command},
+			Namespace:     namespace,
+			PodName:       podName,
+			ContainerName: warmJobContainerName,
+			Stdin:         bytes.NewReader(file.content),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write job config: %s, stderr: %s", err, stderr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const defaultMaintenanceMessage = "系统维护中，请稍后重试"
+
+// CheckMaintenance returns an error with the suggested retry interval if the platform is in
+// maintenance, no more tasks are accepted until the maintenance ends.
+func CheckMaintenance() error {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil || !inMaintenance(sysSetting) {
+		return nil
+	}
+	message := sysSetting.Maintenance.Message
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	return e.NewWithExtras(e.ErrServiceUnavailable, message, map[string]interface{}{
+		"retry_after": sysSetting.Maintenance.RetryAfter,
+	})
+}
+
+// InMaintenance returns true if the waiting tasks should not be started.
+func InMaintenance() bool {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	return err == nil && inMaintenance(sysSetting)
+}
+
+func inMaintenance(sysSetting *commonmodels.SystemSetting) bool {
+	return sysSetting != nil && sysSetting.Maintenance != nil && sysSetting.Maintenance.Enabled
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	labelconfig "github.com/koderover/zadig/pkg/microservice/aslan/core/label/config"
+	labeldb "github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+)
+
+// routeTaskNotification sends the ended task to the channels of the notification routes it matches, the envs of
+// the event are the ones the task deployed to.
+func routeTaskNotification(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	severity := instantmessage.TaskSeverity(task.Status)
+	if severity == "" {
+		return
+	}
+
+	title := fmt.Sprintf("工作流 %s #%d %s", task.WorkflowName, task.TaskID, task.Status)
+	url := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.ExternalBaseURL(), task.ProjectName, task.WorkflowName, task.TaskID)
+	event := &instantmessage.NotificationEvent{
+		Type:        config.NotificationEventWorkflow,
+		ProjectName: task.ProjectName,
+		Envs:        getDeployedEnvs(task),
+		Severity:    severity,
+		Actor:       task.TaskCreator,
+		Resource: &labeldb.Resource{
+			Name:        task.WorkflowName,
+			ProjectName: task.ProjectName,
+			Type:        string(labelconfig.ResourceTypeWorkflow),
+		},
+		Subject: fmt.Sprintf("%s/%s/%s", config.NotificationEventWorkflow, task.ProjectName, task.WorkflowName),
+		Title:   title,
+		Content: fmt.Sprintf("### %s\n\n项目：%s, 执行用户：%s, 状态：%s\n\n[点击查看更多信息](%s)", title, task.ProjectName, task.TaskCreator, task.Status, url),
+	}
+	if _, err := instantmessage.NewWeChatClient().RouteNotification(event, logger); err != nil {
+		logger.Errorf("failed to route the notification of task %s#%d, err: %s", task.WorkflowName, task.TaskID, err)
+	}
+}
+
+func getDeployedEnvs(task *commonmodels.WorkflowTask) []string {
+	envs := sets.NewString()
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.JobType != string(config.JobZadigDeploy) {
+				continue
+			}
+			spec := &commonmodels.JobTaskDeploySpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil || spec.Env == "" {
+				continue
+			}
+			envs.Insert(spec.Env)
+		}
+	}
+	return envs.List()
+}
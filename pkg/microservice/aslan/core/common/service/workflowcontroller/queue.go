@@ -23,9 +23,13 @@ import (
 	"fmt"
 	"time"
 
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
@@ -70,6 +74,9 @@ type CancelMessage struct {
 
 // CreateTask 接受create task请求, 保存task到数据库, 发送task到queue
 func CreateTask(t *commonmodels.WorkflowTask) error {
+	if err := CheckMaintenance(); err != nil {
+		return err
+	}
 	t.Status = config.StatusWaiting
 	if _, err := commonrepo.NewworkflowTaskv4Coll().Create(t); err != nil {
 		log.Errorf("create workflow task v4 error: %v", err)
@@ -79,6 +86,9 @@ func CreateTask(t *commonmodels.WorkflowTask) error {
 }
 
 func UpdateTask(t *commonmodels.WorkflowTask) error {
+	if err := CheckMaintenance(); err != nil {
+		return err
+	}
 	t.Status = config.StatusWaiting
 	if err := commonrepo.NewworkflowTaskv4Coll().Update(t.ID.Hex(), t); err != nil {
 		log.Errorf("create workflow task v4 error: %v", err)
@@ -92,7 +102,8 @@ func Push(t *commonmodels.WorkflowTask) error {
 		return errors.New("nil task")
 	}
 
-	if !t.MultiRun {
+	// the hotfix tasks are not blocked by the tasks of the same workflow.
+	if !t.MultiRun && !t.Hotfix {
 		opt := &commonrepo.ListWorfklowQueueOption{
 			WorkflowName: t.WorkflowName,
 		}
@@ -111,8 +122,10 @@ func Push(t *commonmodels.WorkflowTask) error {
 }
 
 func InitWorkflowController() {
+	initCluster()
 	InitQueue()
 	go WorfklowTaskSender()
+	jobcontroller.StartWarmPoolController(IsLeader)
 }
 
 func InitQueue() error {
@@ -126,7 +139,25 @@ func InitQueue() error {
 		return err
 	}
 
+	queues := make(map[string]*commonmodels.WorkflowQueue)
+	for _, q := range ListTasks() {
+		queues[fmt.Sprintf("%s-%d", q.WorkflowName, q.TaskID)] = q
+	}
+
 	for _, task := range tasks {
+		// the tasks run by the other instances are kept, they are resumed by the alive instances if
+		// the instances are dead.
+		if !isStaleTask(task, queues) {
+			continue
+		}
+		// the tasks left by this instance before it restarts, or released by the instances shut
+		// down, are resumed.
+		if q, ok := queues[fmt.Sprintf("%s-%d", task.WorkflowName, task.TaskID)]; ok && isActiveTask(q) {
+			if q.Owner == instanceID || ownsWorkflow(q.WorkflowName) {
+				resumeTask(q, log)
+			}
+			continue
+		}
 		// 如果 Queue 重新初始化, 取消所有 running tasks
 		if err := CancelWorkflowTask(setting.DefaultTaskRevoker, task.WorkflowName, task.TaskID, log); err != nil {
 			log.Errorf("[CancelRunningTask] error: %v", err)
@@ -141,11 +172,25 @@ func InitQueue() error {
 func WorfklowTaskSender() {
 	for {
 		time.Sleep(time.Second * 3)
+		// no more tasks are started when aslan shuts down.
+		if jobcontroller.Detached() {
+			continue
+		}
 
 		sysSetting, err := commonrepo.NewSystemSettingColl().Get()
 		if err != nil {
 			log.Errorf("get system stettings error: %v", err)
 		}
+		// the waiting tasks are kept in the queue until the maintenance ends.
+		if inMaintenance(sysSetting) {
+			continue
+		}
+		// the hotfix tasks are started regardless of the concurrency limits.
+		if t, err := NextHotfixTask(); err == nil {
+			log.Infof("start hotfix task %s:%d", t.WorkflowName, t.TaskID)
+			_ = updateQueueAndRunTask(t, int(sysSetting.BuildConcurrency))
+			continue
+		}
 		//c.checkAgents()
 		if !hasAgentAvaiable(int(sysSetting.WorkflowConcurrency)) {
 			continue
@@ -159,6 +204,9 @@ func WorfklowTaskSender() {
 				continue
 			}
 			for _, blockTask := range blockTasks {
+				if !ownsWorkflow(blockTask.WorkflowName) || !tenantHasCapacity(blockTask.ProjectName) {
+					continue
+				}
 				if hasAgentAvaiable(int(sysSetting.WorkflowConcurrency)) {
 					//判断相同的工作流是否正在运行
 					if ParallelRunningAndQueuedTasks(blockTask) {
@@ -206,7 +254,7 @@ func ListTasks() []*commonmodels.WorkflowQueue {
 	return queues
 }
 
-// NextWaitingTask 查询下一个等待的task
+// NextWaitingTask 查询下一个等待的task, only the tasks of the workflows owned by this instance are returned.
 func NextWaitingTask() (*commonmodels.WorkflowQueue, error) {
 	opt := &commonrepo.ListWorfklowQueueOption{
 		Status: config.StatusWaiting,
@@ -218,12 +266,55 @@ func NextWaitingTask() (*commonmodels.WorkflowQueue, error) {
 	}
 
 	for _, t := range tasks {
-		return t, nil
+		if ownsWorkflow(t.WorkflowName) && tenantHasCapacity(t.ProjectName) {
+			return t, nil
+		}
 	}
 
 	return nil, errors.New("no waiting task found")
 }
 
+// NextHotfixTask returns the earliest waiting hotfix task of the workflows owned by this instance.
+func NextHotfixTask() (*commonmodels.WorkflowQueue, error) {
+	opt := &commonrepo.ListWorfklowQueueOption{
+		Status: config.StatusWaiting,
+	}
+
+	tasks, err := commonrepo.NewWorkflowQueueColl().List(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tasks {
+		if t.Hotfix && ownsWorkflow(t.WorkflowName) {
+			return t, nil
+		}
+	}
+
+	return nil, errors.New("no waiting hotfix task found")
+}
+
+// tenantHasCapacity returns whether the tenant of the project can run one more task under its
+// workflow concurrency quota, tasks of the other tenants are not blocked by a busy tenant.
+func tenantHasCapacity(projectName string) bool {
+	if !config.TenantIsolationEnabled() {
+		return true
+	}
+	tenant, err := commonrepo.NewTenantColl().FindByProject(projectName)
+	if err != nil || tenant.Quota == nil || tenant.Quota.MaxWorkflowConcurrency <= 0 {
+		return true
+	}
+
+	projects := sets.NewString(tenant.Projects...)
+	running := 0
+	for _, t := range RunningAndQueuedTasks() {
+		if projects.Has(t.ProjectName) {
+			running++
+		}
+	}
+	return running < tenant.Quota.MaxWorkflowConcurrency
+}
+
 func BlockedTaskQueue() ([]*commonmodels.WorkflowQueue, error) {
 	opt := &commonrepo.ListWorfklowQueueOption{
 		Status: config.StatusBlocked,
@@ -259,8 +350,10 @@ func updateQueueAndRunTask(t *commonmodels.WorkflowQueue, jobConcurrency int) er
 		return fmt.Errorf("%s:%d get workflow task error: %v", t.WorkflowName, t.TaskID, err)
 	}
 	workflowTask.Status = config.StatusQueued
-	if success := UpdateQueue(workflowTask); !success {
-		logger.Errorf("%s:%d update t status error", t.WorkflowName, t.TaskID)
+	// the task may be claimed by another instance while the workflows are rebalanced.
+	claimed, err := commonrepo.NewWorkflowQueueColl().Claim(t, config.StatusQueued, instanceID)
+	if err != nil || !claimed {
+		logger.Errorf("%s:%d update t status error: %v", t.WorkflowName, t.TaskID, err)
 		return fmt.Errorf("%s:%d update t status error", t.WorkflowName, t.TaskID)
 	}
 	ctx := context.Background()
@@ -268,6 +361,31 @@ func updateQueueAndRunTask(t *commonmodels.WorkflowQueue, jobConcurrency int) er
 	return nil
 }
 
+// resumeTask runs the task again from the interrupted jobs, the running kubernetes jobs are reattached.
+func resumeTask(q *commonmodels.WorkflowQueue, logger *zap.SugaredLogger) {
+	workflowTask, err := commonrepo.NewworkflowTaskv4Coll().Find(q.WorkflowName, q.TaskID)
+	if err != nil {
+		logger.Errorf("%s:%d get workflow task error: %v", q.WorkflowName, q.TaskID, err)
+		return
+	}
+	if q.Owner != instanceID {
+		adopted, err := commonrepo.NewWorkflowQueueColl().Adopt(q, instanceID)
+		if err != nil || !adopted {
+			return
+		}
+	}
+	jobConcurrency := 1
+	if sysSetting, err := commonrepo.NewSystemSettingColl().Get(); err == nil {
+		jobConcurrency = int(sysSetting.BuildConcurrency)
+	}
+	logger.Infof("resume task %s:%d", q.WorkflowName, q.TaskID)
+	go NewWorkflowController(workflowTask, logger).Run(context.Background(), jobConcurrency)
+}
+
+func isActiveTask(q *commonmodels.WorkflowQueue) bool {
+	return q.Status == config.StatusRunning || q.Status == config.StatusQueued
+}
+
 func UpdateQueue(task *commonmodels.WorkflowTask) bool {
 	if err := commonrepo.NewWorkflowQueueColl().Update(ConvertTaskToQueue(task)); err != nil {
 		return false
@@ -286,6 +404,7 @@ func ConvertTaskToQueue(task *commonmodels.WorkflowTask) *commonmodels.WorkflowQ
 		TaskRevoker:  task.TaskRevoker,
 		CreateTime:   task.CreateTime,
 		MultiRun:     task.MultiRun,
+		Hotfix:       task.Hotfix,
 	}
 }
 
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	stepspec "github.com/koderover/zadig/pkg/types/step"
+)
+
+// boardReleaseTrains puts the images built by a passed task on the release trains of the workflow, only
+// the tasks triggered by the pushes to branches board the trains since they carry the merged changes.
+func boardReleaseTrains(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	if task.Status != config.StatusPassed || task.TaskCreator != setting.WebhookTaskCreator || task.WorkflowArgs == nil {
+		return
+	}
+	payload := task.WorkflowArgs.HookPayload
+	if payload == nil || payload.IsPr || payload.Tag != "" {
+		return
+	}
+	trains, err := commonrepo.NewReleaseTrainColl().ListBySourceWorkflow(task.ProjectName, task.WorkflowName)
+	if err != nil {
+		logger.Errorf("failed to list release trains of workflow %s, err: %s", task.WorkflowName, err)
+		return
+	}
+	if len(trains) == 0 {
+		return
+	}
+
+	artifacts, commits := getBuildArtifacts(task)
+	if len(artifacts) == 0 {
+		return
+	}
+	for _, train := range trains {
+		item := &commonmodels.ReleaseTrainItem{
+			TrainID:        train.ID.Hex(),
+			ProjectName:    task.ProjectName,
+			WorkflowName:   task.WorkflowName,
+			TaskID:         task.TaskID,
+			MergeRequestID: payload.MergeRequestID,
+			CommitMessage:  payload.CommitMessage,
+			CommitAuthor:   payload.CommitAuthor,
+			Commits:        commits,
+			Artifacts:      artifacts,
+			Status:         config.ReleaseTrainItemPending,
+		}
+		if err := commonrepo.NewReleaseTrainItemColl().Create(item); err != nil {
+			logger.Errorf("failed to put task %s#%d on release train %s, err: %s", task.WorkflowName, task.TaskID, train.Name, err)
+		}
+	}
+}
+
+// getBuildArtifacts returns the images and the commits of the build jobs of the task.
+func getBuildArtifacts(task *commonmodels.WorkflowTask) ([]*commonmodels.ServiceAndImage, []*commonmodels.ReleaseTrainCommit) {
+	artifacts := make([]*commonmodels.ServiceAndImage, 0)
+	commits := make([]*commonmodels.ReleaseTrainCommit, 0)
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			artifact, jobCommits := getBuildJobArtifact(job)
+			if artifact != nil {
+				artifacts = append(artifacts, artifact)
+			}
+			commits = append(commits, jobCommits...)
+		}
+	}
+	return artifacts, commits
+}
+
+// getBuildJobArtifact returns the image and the commits of a passed build job, the image is nil if the job
+// is not a passed build job or it builds no image.
+func getBuildJobArtifact(job *commonmodels.JobTask) (*commonmodels.ServiceAndImage, []*commonmodels.ReleaseTrainCommit) {
+	if job.JobType != string(config.JobZadigBuild) || job.Status != config.StatusPassed {
+		return nil, nil
+	}
+	spec := &commonmodels.JobTaskBuildSpec{}
+	if err := commonmodels.IToi(job.Spec, spec); err != nil {
+		return nil, nil
+	}
+	artifact := &commonmodels.ServiceAndImage{}
+	for _, env := range spec.Properties.Envs {
+		switch env.Key {
+		case "SERVICE":
+			artifact.ServiceName = env.Value
+		case "SERVICE_MODULE":
+			artifact.ServiceModule = env.Value
+		case "IMAGE":
+			artifact.Image = env.Value
+		}
+	}
+	commits := make([]*commonmodels.ReleaseTrainCommit, 0)
+	for _, step := range spec.Steps {
+		if step.StepType != config.StepGit {
+			continue
+		}
+		stepSpec := &stepspec.StepGitSpec{}
+		if err := commonmodels.IToi(step.Spec, stepSpec); err != nil {
+			continue
+		}
+		for _, repo := range stepSpec.Repos {
+			commits = append(commits, &commonmodels.ReleaseTrainCommit{
+				CodehostID:    repo.CodehostID,
+				RepoOwner:     repo.RepoOwner,
+				RepoName:      repo.RepoName,
+				Branch:        repo.Branch,
+				PR:            repo.PR,
+				CommitID:      repo.CommitID,
+				CommitMessage: repo.CommitMessage,
+				AuthorName:    repo.AuthorName,
+			})
+		}
+	}
+	if artifact.Image == "" {
+		return nil, commits
+	}
+	return artifact, commits
+}
@@ -49,8 +49,14 @@ type StageCtl interface {
 }
 
 func runStage(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
+	// the stage is done before the task is resumed.
+	if stage.Status == config.StatusPassed || stage.Status == config.StatusSkipped {
+		return
+	}
 	stage.Status = config.StatusRunning
-	stage.StartTime = time.Now().Unix()
+	if stage.StartTime == 0 {
+		stage.StartTime = time.Now().Unix()
+	}
 	ack()
 	logger.Infof("start stage: %s,status: %s", stage.Name, stage.Status)
 	if err := waitiForApprove(ctx, stage, workflowCtx, ack); err != nil {
@@ -100,6 +106,9 @@ func waitiForApprove(ctx context.Context, stage *commonmodels.StageTask, workflo
 	if stage.Approval.Timeout == 0 {
 		stage.Approval.Timeout = 60
 	}
+	if stage.Approval.Type == config.WebhookApproval {
+		return waitForWebhookApproval(ctx, stage, workflowCtx, ack)
+	}
 	approveKey := fmt.Sprintf("%s-%d-%s", workflowCtx.WorkflowName, workflowCtx.TaskID, stage.Name)
 	approveWithL := &approveWithLock{approval: stage.Approval}
 	globalApproveMap.setApproval(approveKey, approveWithL)
@@ -72,7 +72,7 @@ func instantiateStepCtl(step *commonmodels.StepTask, workflowCtx *commonmodels.W
 	var err error
 	switch step.StepType {
 	case config.StepGit:
-		stepCtl, err = NewGitCtl(step, logger)
+		stepCtl, err = NewGitCtl(step, workflowCtx, logger)
 	case config.StepShell:
 		stepCtl, err = NewShellCtl(step, logger)
 	case config.StepDockerBuild:
@@ -81,9 +81,29 @@ func instantiateStepCtl(step *commonmodels.StepTask, workflowCtx *commonmodels.W
 		stepCtl, err = NewToolInstallCtl(step, jobPath, logger)
 	case config.StepArchive:
 		stepCtl, err = NewArchiveCtl(step, logger)
+	case config.StepAndroidPublish:
+		stepCtl, err = NewAndroidPublishCtl(step, logger)
+	case config.StepDownload:
+		stepCtl, err = NewDownloadCtl(step, logger)
+	case config.StepSummary:
+		stepCtl, err = NewSummaryCtl(step, workflowCtx, logger)
 	default:
 		logger.Errorf("unknown step type: %s", step.StepType)
 		return stepCtl, fmt.Errorf("unknown step type: %s", step.StepType)
 	}
 	return stepCtl, err
 }
+
+// CollectSummaries returns the summaries reported by the summary steps after the steps are summarized.
+func CollectSummaries(steps []*commonmodels.StepTask) []*commonmodels.JobSummary {
+	var resp []*commonmodels.JobSummary
+	for _, step := range steps {
+		if step.StepType != config.StepSummary || step.Result == nil {
+			continue
+		}
+		if result, ok := step.Result.(*commonmodels.JobSummary); ok {
+			resp = append(resp, result)
+		}
+	}
+	return resp
+}
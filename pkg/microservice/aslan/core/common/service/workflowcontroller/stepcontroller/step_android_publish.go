@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type androidPublishCtl struct {
+	step        *commonmodels.StepTask
+	publishSpec *step.StepAndroidPublishSpec
+	log         *zap.SugaredLogger
+}
+
+func NewAndroidPublishCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (*androidPublishCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal android publish spec error: %v", err)
+	}
+	publishSpec := &step.StepAndroidPublishSpec{}
+	if err := yaml.Unmarshal(yamlString, &publishSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal android publish spec error: %v", err)
+	}
+	stepTask.Spec = publishSpec
+	return &androidPublishCtl{publishSpec: publishSpec, log: log, step: stepTask}, nil
+}
+
+func (s *androidPublishCtl) PreRun(ctx context.Context) error {
+	if len(s.publishSpec.Files) == 0 {
+		return fmt.Errorf("android publish step %s has no files to collect", s.step.Name)
+	}
+	return nil
+}
+
+func (s *androidPublishCtl) AfterRun(ctx context.Context) error {
+	return nil
+}
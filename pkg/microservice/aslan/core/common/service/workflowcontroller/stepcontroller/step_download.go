@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type downloadCtl struct {
+	step         *commonmodels.StepTask
+	downloadSpec *step.StepDownloadSpec
+	log          *zap.SugaredLogger
+}
+
+func NewDownloadCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (*downloadCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal download spec error: %v", err)
+	}
+	downloadSpec := &step.StepDownloadSpec{}
+	if err := yaml.Unmarshal(yamlString, &downloadSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal download spec error: %v", err)
+	}
+	stepTask.Spec = downloadSpec
+	return &downloadCtl{downloadSpec: downloadSpec, log: log, step: stepTask}, nil
+}
+
+func (s *downloadCtl) PreRun(ctx context.Context) error {
+	for _, artifact := range s.downloadSpec.Artifacts {
+		if err := artifact.Validate(); err != nil {
+			return fmt.Errorf("download step %s: %s", s.step.Name, err)
+		}
+		if artifact.Source != step.ArtifactSourceS3 || artifact.S3 != nil {
+			continue
+		}
+		if artifact.ObjectStorageID == "" {
+			modelS3, err := commonrepo.NewS3StorageColl().FindDefault()
+			if err != nil {
+				return err
+			}
+			artifact.S3 = modelS3toS3(modelS3)
+		} else {
+			modelS3, err := commonrepo.NewS3StorageColl().Find(artifact.ObjectStorageID)
+			if err != nil {
+				return err
+			}
+			artifact.S3 = modelS3toS3(modelS3)
+			artifact.S3.Subfolder = ""
+		}
+	}
+	s.step.Spec = s.downloadSpec
+	return nil
+}
+
+func (s *downloadCtl) AfterRun(ctx context.Context) error {
+	return nil
+}
@@ -25,18 +25,23 @@ import (
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitmirror"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+	"github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/step"
 )
 
 type gitCtl struct {
-	step    *commonmodels.StepTask
-	gitSpec *step.StepGitSpec
-	log     *zap.SugaredLogger
+	step        *commonmodels.StepTask
+	gitSpec     *step.StepGitSpec
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	log         *zap.SugaredLogger
 }
 
-func NewGitCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (*gitCtl, error) {
+func NewGitCtl(stepTask *commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, log *zap.SugaredLogger) (*gitCtl, error) {
 	yamlString, err := yaml.Marshal(stepTask.Spec)
 	if err != nil {
 		return nil, fmt.Errorf("marshal git spec error: %v", err)
@@ -49,7 +54,7 @@ func NewGitCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (*gitCtl
 		gitSpec.Proxy = &step.Proxy{}
 	}
 	stepTask.Spec = gitSpec
-	return &gitCtl{gitSpec: gitSpec, log: log, step: stepTask}, nil
+	return &gitCtl{gitSpec: gitSpec, workflowCtx: workflowCtx, log: log, step: stepTask}, nil
 }
 
 func (s *gitCtl) PreRun(ctx context.Context) error {
@@ -62,14 +67,29 @@ func (s *gitCtl) PreRun(ctx context.Context) error {
 		detail, err := systemconfig.New().GetCodeHost(cID)
 		if err != nil {
 			s.log.Error(err)
+			if systemconfig.IsCodeHostDisabled(err) {
+				return fmt.Errorf("repo %s/%s can not be cloned: %s", repo.GetRepoNamespace(), repo.RepoName, err)
+			}
 			return err
 		}
+		if !detail.AvailableToProject(s.workflowCtx.ProjectName) {
+			return fmt.Errorf("codehost %d is not available to project %s", cID, s.workflowCtx.ProjectName)
+		}
+		if !detail.RepoPolicy.RepoAllowed(repo.GetRepoNamespace(), repo.RepoName) {
+			return fmt.Errorf("repo %s/%s is not allowed by the policy of codehost %d", repo.GetRepoNamespace(), repo.RepoName, cID)
+		}
 		repo.Source = detail.Type
 		repo.OauthToken = detail.AccessToken
-		repo.Address = detail.Address
+		repo.Address = detail.RepoAddress()
 		repo.Username = detail.Username
 		repo.Password = detail.Password
 		repo.EnableProxy = detail.EnableProxy
+		if detail.Type == systemconfig.GerritProvider && detail.AuthType == types.SSHAuthType {
+			repo.AuthType = detail.AuthType
+			repo.SSHKey = detail.SSHKey
+			repo.SSHPort = detail.SSHPort
+		}
+		repo.MirrorURL = gitmirror.CloneURL(repo)
 	}
 	proxies, _ := mongodb.NewProxyColl().List(&mongodb.ProxyArgs{})
 	if len(proxies) != 0 {
@@ -82,6 +102,16 @@ func (s *gitCtl) PreRun(ctx context.Context) error {
 		s.gitSpec.Proxy.Type = proxies[0].Type
 		s.gitSpec.Proxy.Username = proxies[0].Username
 	}
+	project, err := templaterepo.NewProductColl().Find(s.workflowCtx.ProjectName)
+	if err != nil {
+		return fmt.Errorf("failed to find project %s: %v", s.workflowCtx.ProjectName, err)
+	}
+	if policy := project.SecretScanPolicy; policy != nil && policy.Enabled && policy.ScanSource {
+		s.gitSpec.SecretScan = policy.Rules
+		if s.gitSpec.SecretScan == nil {
+			s.gitSpec.SecretScan = &secretscan.Config{}
+		}
+	}
 	s.step.Spec = s.gitSpec
 	return nil
 }
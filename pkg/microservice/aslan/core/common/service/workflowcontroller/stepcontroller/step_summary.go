@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/tool/summary"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type summaryCtl struct {
+	step        *commonmodels.StepTask
+	summarySpec *step.StepSummarySpec
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	log         *zap.SugaredLogger
+}
+
+func NewSummaryCtl(stepTask *commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, log *zap.SugaredLogger) (*summaryCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal summary spec error: %v", err)
+	}
+	summarySpec := &step.StepSummarySpec{}
+	if err := yaml.Unmarshal(yamlString, &summarySpec); err != nil {
+		return nil, fmt.Errorf("unmarshal summary spec error: %v", err)
+	}
+	if summarySpec.Format == "" {
+		summarySpec.Format = summary.FormatMarkdown
+	}
+	stepTask.Spec = summarySpec
+	return &summaryCtl{summarySpec: summarySpec, workflowCtx: workflowCtx, log: log, step: stepTask}, nil
+}
+
+func (s *summaryCtl) PreRun(ctx context.Context) error {
+	if s.summarySpec.FilePath == "" {
+		return fmt.Errorf("summary step %s: the file path is empty", s.step.Name)
+	}
+	if s.summarySpec.S3 == nil {
+		var modelS3 *commonmodels.S3Storage
+		var err error
+		if s.summarySpec.ObjectStorageID == "" {
+			modelS3, err = commonrepo.NewS3StorageColl().FindDefault()
+		} else {
+			modelS3, err = commonrepo.NewS3StorageColl().Find(s.summarySpec.ObjectStorageID)
+		}
+		if err != nil {
+			return err
+		}
+		s.summarySpec.S3 = modelS3toS3(modelS3)
+		if s.summarySpec.ObjectStorageID != "" {
+			s.summarySpec.S3.Subfolder = ""
+		}
+	}
+	s.summarySpec.ObjectKey = path.Join(s.summarySpec.S3.Subfolder, s.workflowCtx.WorkflowName, strconv.FormatInt(s.workflowCtx.TaskID, 10), "summary", s.step.JobName, s.step.Name)
+	s.step.Spec = s.summarySpec
+	return nil
+}
+
+// AfterRun parses the uploaded summary file, the failures are recorded in the summary instead of failing the job.
+func (s *summaryCtl) AfterRun(ctx context.Context) error {
+	if s.summarySpec.S3 == nil || s.summarySpec.ObjectKey == "" {
+		return nil
+	}
+	content, exists, err := s.download()
+	if err == nil && !exists {
+		return nil
+	}
+
+	result := &commonmodels.JobSummary{JobName: s.step.JobName, StepName: s.step.Name}
+	if err == nil {
+		var parsed *summary.Summary
+		if parsed, err = summary.Parse(s.summarySpec.Format, content); err == nil {
+			result.Summary = *parsed
+		}
+	}
+	if err != nil {
+		s.log.Warnf("failed to get the summary of step %s: %s", s.step.Name, err)
+		result.Error = err.Error()
+	}
+	s.step.Result = result
+	return nil
+}
+
+func (s *summaryCtl) download() ([]byte, bool, error) {
+	forcedPathStyle := true
+	if s.summarySpec.S3.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(s.summarySpec.S3.Endpoint, s.summarySpec.S3.Ak, s.summarySpec.S3.Sk, s.summarySpec.S3.Insecure, forcedPathStyle)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create s3 client to download the summary, err: %s", err)
+	}
+	size, exists, err := client.GetObjectSize(s.summarySpec.S3.Bucket, s.summarySpec.ObjectKey)
+	if err != nil || !exists {
+		return nil, exists, err
+	}
+	content, err := client.GetRange(s.summarySpec.S3.Bucket, s.summarySpec.ObjectKey, 0, size)
+	return content, true, err
+}
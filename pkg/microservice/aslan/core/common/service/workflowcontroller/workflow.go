@@ -29,6 +29,7 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -79,6 +80,7 @@ func CancelWorkflowTask(userName, workflowName string, taskID int64, logger *zap
 	if err := scmnotify.NewService().CompleteGitCheckForWorkflowV4(t.WorkflowArgs, t.TaskID, t.Status, logger); err != nil {
 		log.Warnf("Failed to update github check status for custom workflow %s, taskID: %d the error is: %s", t.WorkflowName, t.TaskID, err)
 	}
+	scmnotify.NewService().CompleteAttachedTriggersForWorkflowV4(t, logger)
 
 	q := ConvertTaskToQueue(t)
 	if err := Remove(q); err != nil {
@@ -103,11 +105,18 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 		c.workflowTask.GlobalContext = make(map[string]string)
 	}
 	c.workflowTask.Status = config.StatusRunning
-	c.workflowTask.StartTime = time.Now().Unix()
+	// the start time is kept if the task is resumed after aslan restarts.
+	if c.workflowTask.StartTime == 0 {
+		c.workflowTask.StartTime = time.Now().Unix()
+	}
 	c.ack()
 	c.logger.Infof("start workflow: %s,status: %s", c.workflowTask.WorkflowName, c.workflowTask.Status)
 	defer func() {
 		c.workflowTask.EndTime = time.Now().Unix()
+		// the locks are kept for the instance resuming the task if aslan shuts down.
+		if !jobcontroller.Detached() {
+			jobcontroller.ReleaseEnvLocks(c.workflowTask.WorkflowName, c.workflowTask.TaskID, c.logger)
+		}
 		c.logger.Infof("finish workflow: %s,status: %s", c.workflowTask.WorkflowName, c.workflowTask.Status)
 		c.ack()
 	}()
@@ -121,6 +130,8 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 		WorkflowName:      c.workflowTask.WorkflowName,
 		ProjectName:       c.workflowTask.ProjectName,
 		TaskID:            c.workflowTask.TaskID,
+		TaskCreator:       c.workflowTask.TaskCreator,
+		Labels:            c.workflowTask.Labels,
 		Workspace:         "/workspace",
 		DistDir:           fmt.Sprintf("%s/%s/dist/%d", config.S3StoragePath(), c.workflowTask.WorkflowName, c.workflowTask.TaskID),
 		DockerMountDir:    fmt.Sprintf("/tmp/%s/docker/%d", uuid.NewV4(), time.Now().Unix()),
@@ -130,7 +141,17 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 		GlobalContextSet:  c.setGlobalContext,
 		GlobalContextEach: c.globalContextEach,
 	}
+	if c.workflowTask.WorkflowArgs != nil {
+		workflowCtx.DebugOnFailure = c.workflowTask.WorkflowArgs.DebugOnFailure
+	}
+	workflowCtx.CustomFields = getCustomFields(c.workflowTask, c.logger)
 
+	if status, err := runPreStartHooks(ctx, c.workflowTask, workflowCtx, c.ack); err != nil {
+		c.logger.Errorf("workflow %s #%d does not start: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
+		c.workflowTask.Status = status
+		c.workflowTask.Error = err.Error()
+		return
+	}
 	RunStages(ctx, c.workflowTask.Stages, workflowCtx, concurrency, c.logger, c.ack)
 	updateworkflowStatus(c.workflowTask)
 }
@@ -174,6 +195,11 @@ func updateworkflowStatus(workflow *commonmodels.WorkflowTask) {
 }
 
 func (c *workflowCtl) updateWorkflowTask() {
+	// the task is resumed by another instance after aslan shuts down, the state here is not saved
+	// any more in case it overwrites the resumed one.
+	if jobcontroller.Detached() {
+		return
+	}
 	taskInColl, err := commonrepo.NewworkflowTaskv4Coll().Find(c.workflowTask.WorkflowName, c.workflowTask.TaskID)
 	if err != nil {
 		c.logger.Errorf("find workflow task v4 %s failed,error: %v", c.workflowTask.WorkflowName, err)
@@ -222,6 +248,15 @@ func (c *workflowCtl) updateWorkflowTask() {
 		if err := scmnotify.NewService().CompleteGitCheckForWorkflowV4(c.workflowTask.WorkflowArgs, c.workflowTask.TaskID, c.workflowTask.Status, c.logger); err != nil {
 			log.Warnf("Failed to update github check status for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
 		}
+		scmnotify.NewService().CompleteAttachedTriggersForWorkflowV4(c.workflowTask, c.logger)
+		boardReleaseTrains(c.workflowTask, c.logger)
+		recordEnvAutoUpdates(c.workflowTask, c.logger)
+		finishEnvAutoUpdates(c.workflowTask, c.logger)
+		linkDependencyUpdate(c.workflowTask, c.logger)
+		recordArtifactDeployments(c.workflowTask, c.logger)
+		notifyJobSummaries(c.workflowTask, c.logger)
+		routeTaskNotification(c.workflowTask, c.logger)
+		callPostFinishHooks(c.workflowTask, c.logger)
 	}
 
 }
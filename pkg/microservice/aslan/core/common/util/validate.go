@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/types/step"
 )
 
 func CheckDefineResourceParam(req setting.Request, reqSpec setting.RequestSpec) error {
@@ -34,3 +35,13 @@ func CheckDefineResourceParam(req setting.Request, reqSpec setting.RequestSpec)
 	}
 	return nil
 }
+
+// CheckArtifactDownloads rejects the artifacts which can not be downloaded or verified.
+func CheckArtifactDownloads(downloads []*step.ArtifactDownload) error {
+	for _, download := range downloads {
+		if err := download.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	cronservice "github.com/koderover/zadig/pkg/microservice/aslan/core/cron/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 func CleanJobCronJob(c *gin.Context) {
@@ -60,20 +61,25 @@ func DisableCronjob(c *gin.Context) {
 }
 
 type cronjobResp struct {
-	ID           string                         `json:"_id,omitempty"`
-	Name         string                         `json:"name"`
-	Type         string                         `json:"type"`
-	Number       uint64                         `json:"number"`
-	Frequency    string                         `json:"frequency"`
-	Time         string                         `json:"time"`
-	Cron         string                         `json:"cron"`
-	ProductName  string                         `json:"product_name,omitempty"`
-	MaxFailure   int                            `json:"max_failures,omitempty"`
-	TaskArgs     *commonmodels.TaskArgs         `json:"task_args,omitempty"`
-	WorkflowArgs *commonmodels.WorkflowTaskArgs `json:"workflow_args,omitempty"`
-	TestArgs     *commonmodels.TestTaskArgs     `json:"test_args,omitempty"`
-	JobType      string                         `json:"job_type"`
-	Enabled      bool                           `json:"enabled"`
+	ID                 string                           `json:"_id,omitempty"`
+	Name               string                           `json:"name"`
+	Type               string                           `json:"type"`
+	Number             uint64                           `json:"number"`
+	Frequency          string                           `json:"frequency"`
+	Time               string                           `json:"time"`
+	Cron               string                           `json:"cron"`
+	ProductName        string                           `json:"product_name,omitempty"`
+	MaxFailure         int                              `json:"max_failures,omitempty"`
+	TaskArgs           *commonmodels.TaskArgs           `json:"task_args,omitempty"`
+	WorkflowArgs       *commonmodels.WorkflowTaskArgs   `json:"workflow_args,omitempty"`
+	TestArgs           *commonmodels.TestTaskArgs       `json:"test_args,omitempty"`
+	EnvDataRefreshArgs *commonmodels.EnvDataRefreshArgs `json:"env_data_refresh_args,omitempty"`
+	EnvSmokeTestArgs   *commonmodels.EnvSmokeTestArgs   `json:"env_smoke_test_args,omitempty"`
+	ReleaseTrainArgs   *commonmodels.ReleaseTrainArgs   `json:"release_train_args,omitempty"`
+	JobType            string                           `json:"job_type"`
+	Enabled            bool                             `json:"enabled"`
+	CatchUpPolicy      string                           `json:"catch_up_policy,omitempty"`
+	LastScheduledTime  int64                            `json:"last_scheduled_time,omitempty"`
 }
 
 func ListActiveCronjobFailsafe(c *gin.Context) {
@@ -84,20 +90,25 @@ func ListActiveCronjobFailsafe(c *gin.Context) {
 	cronjobList, err := cronservice.ListActiveCronjobFailsafe()
 	for _, cronjob := range cronjobList {
 		resp = append(resp, &cronjobResp{
-			ID:           cronjob.ID.Hex(),
-			Name:         cronjob.Name,
-			Type:         cronjob.Type,
-			Number:       cronjob.Number,
-			Frequency:    cronjob.Frequency,
-			Time:         cronjob.Time,
-			Cron:         cronjob.Cron,
-			ProductName:  cronjob.ProductName,
-			MaxFailure:   cronjob.MaxFailure,
-			TaskArgs:     cronjob.TaskArgs,
-			WorkflowArgs: cronjob.WorkflowArgs,
-			TestArgs:     cronjob.TestArgs,
-			JobType:      cronjob.JobType,
-			Enabled:      cronjob.Enabled,
+			ID:                 cronjob.ID.Hex(),
+			Name:               cronjob.Name,
+			Type:               cronjob.Type,
+			Number:             cronjob.Number,
+			Frequency:          cronjob.Frequency,
+			Time:               cronjob.Time,
+			Cron:               cronjob.Cron,
+			ProductName:        cronjob.ProductName,
+			MaxFailure:         cronjob.MaxFailure,
+			TaskArgs:           cronjob.TaskArgs,
+			WorkflowArgs:       cronjob.WorkflowArgs,
+			TestArgs:           cronjob.TestArgs,
+			EnvDataRefreshArgs: cronjob.EnvDataRefreshArgs,
+			EnvSmokeTestArgs:   cronjob.EnvSmokeTestArgs,
+			ReleaseTrainArgs:   cronjob.ReleaseTrainArgs,
+			JobType:            cronjob.JobType,
+			Enabled:            cronjob.Enabled,
+			CatchUpPolicy:      cronjob.CatchUpPolicy,
+			LastScheduledTime:  cronjob.LastScheduledTime,
 		})
 	}
 	ctx.Resp = resp
@@ -112,20 +123,25 @@ func ListActiveCronjob(c *gin.Context) {
 	cronjobList, err := cronservice.ListActiveCronjob()
 	for _, cronjob := range cronjobList {
 		resp = append(resp, &cronjobResp{
-			ID:           cronjob.ID.Hex(),
-			Name:         cronjob.Name,
-			Type:         cronjob.Type,
-			Number:       cronjob.Number,
-			Frequency:    cronjob.Frequency,
-			Time:         cronjob.Time,
-			Cron:         cronjob.Cron,
-			ProductName:  cronjob.ProductName,
-			MaxFailure:   cronjob.MaxFailure,
-			TaskArgs:     cronjob.TaskArgs,
-			WorkflowArgs: cronjob.WorkflowArgs,
-			TestArgs:     cronjob.TestArgs,
-			JobType:      cronjob.JobType,
-			Enabled:      cronjob.Enabled,
+			ID:                 cronjob.ID.Hex(),
+			Name:               cronjob.Name,
+			Type:               cronjob.Type,
+			Number:             cronjob.Number,
+			Frequency:          cronjob.Frequency,
+			Time:               cronjob.Time,
+			Cron:               cronjob.Cron,
+			ProductName:        cronjob.ProductName,
+			MaxFailure:         cronjob.MaxFailure,
+			TaskArgs:           cronjob.TaskArgs,
+			WorkflowArgs:       cronjob.WorkflowArgs,
+			TestArgs:           cronjob.TestArgs,
+			EnvDataRefreshArgs: cronjob.EnvDataRefreshArgs,
+			EnvSmokeTestArgs:   cronjob.EnvSmokeTestArgs,
+			ReleaseTrainArgs:   cronjob.ReleaseTrainArgs,
+			JobType:            cronjob.JobType,
+			Enabled:            cronjob.Enabled,
+			CatchUpPolicy:      cronjob.CatchUpPolicy,
+			LastScheduledTime:  cronjob.LastScheduledTime,
 		})
 	}
 	ctx.Resp = resp
@@ -143,22 +159,60 @@ func ListCronjob(c *gin.Context) {
 	cronjobList, err := cronservice.ListCronjob(name, pType)
 	for _, cronjob := range cronjobList {
 		resp = append(resp, &cronjobResp{
-			ID:           cronjob.ID.Hex(),
-			Name:         cronjob.Name,
-			Type:         cronjob.Type,
-			Number:       cronjob.Number,
-			Frequency:    cronjob.Frequency,
-			Time:         cronjob.Time,
-			Cron:         cronjob.Cron,
-			ProductName:  cronjob.ProductName,
-			MaxFailure:   cronjob.MaxFailure,
-			TaskArgs:     cronjob.TaskArgs,
-			WorkflowArgs: cronjob.WorkflowArgs,
-			TestArgs:     cronjob.TestArgs,
-			JobType:      cronjob.JobType,
-			Enabled:      cronjob.Enabled,
+			ID:                 cronjob.ID.Hex(),
+			Name:               cronjob.Name,
+			Type:               cronjob.Type,
+			Number:             cronjob.Number,
+			Frequency:          cronjob.Frequency,
+			Time:               cronjob.Time,
+			Cron:               cronjob.Cron,
+			ProductName:        cronjob.ProductName,
+			MaxFailure:         cronjob.MaxFailure,
+			TaskArgs:           cronjob.TaskArgs,
+			WorkflowArgs:       cronjob.WorkflowArgs,
+			TestArgs:           cronjob.TestArgs,
+			EnvDataRefreshArgs: cronjob.EnvDataRefreshArgs,
+			EnvSmokeTestArgs:   cronjob.EnvSmokeTestArgs,
+			ReleaseTrainArgs:   cronjob.ReleaseTrainArgs,
+			JobType:            cronjob.JobType,
+			Enabled:            cronjob.Enabled,
+			CatchUpPolicy:      cronjob.CatchUpPolicy,
+			LastScheduledTime:  cronjob.LastScheduledTime,
 		})
 	}
 	ctx.Resp = resp
 	ctx.Err = err
 }
+
+func AcquireCronjobRun(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(cronservice.AcquireCronjobRunReq)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = cronservice.AcquireCronjobRun(c.Param("id"), args, ctx.Logger)
+}
+
+func RecordMissedCronjobRuns(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(cronservice.MissedCronjobRunsReq)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = cronservice.RecordMissedCronjobRuns(c.Param("id"), args, ctx.Logger)
+}
+
+func ListCronjobRuns(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = cronservice.ListCronjobRuns(c.Param("id"), c.Query("missed") == "true", ctx.Logger)
+}
@@ -39,5 +39,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		cronjob.GET("/failsafe", ListActiveCronjobFailsafe)
 		cronjob.GET("", ListActiveCronjob)
 		cronjob.GET("/type/:type/name/:name", ListCronjob)
+		cronjob.POST("/:id/runs", AcquireCronjobRun)
+		cronjob.POST("/:id/missed", RecordMissedCronjobRuns)
+		cronjob.GET("/:id/runs", ListCronjobRuns)
 	}
 }
@@ -35,19 +35,23 @@ func DisableCronjob(name, ptype string, log *zap.SugaredLogger) error {
 	}
 	for _, job := range jobList {
 		err := commonrepo.NewCronjobColl().Update(&commonmodels.Cronjob{
-			ID:           job.ID,
-			Name:         job.Name,
-			Type:         job.Type,
-			Number:       job.Number,
-			Frequency:    job.Frequency,
-			Time:         job.Time,
-			Cron:         job.Cron,
-			MaxFailure:   job.MaxFailure,
-			TaskArgs:     job.TaskArgs,
-			WorkflowArgs: job.WorkflowArgs,
-			TestArgs:     job.TestArgs,
-			JobType:      job.JobType,
-			Enabled:      false,
+			ID:                 job.ID,
+			Name:               job.Name,
+			Type:               job.Type,
+			Number:             job.Number,
+			Frequency:          job.Frequency,
+			Time:               job.Time,
+			Cron:               job.Cron,
+			MaxFailure:         job.MaxFailure,
+			TaskArgs:           job.TaskArgs,
+			WorkflowArgs:       job.WorkflowArgs,
+			TestArgs:           job.TestArgs,
+			EnvDataRefreshArgs: job.EnvDataRefreshArgs,
+			EnvSmokeTestArgs:   job.EnvSmokeTestArgs,
+			ReleaseTrainArgs:   job.ReleaseTrainArgs,
+			JobType:            job.JobType,
+			CatchUpPolicy:      job.CatchUpPolicy,
+			Enabled:            false,
 		})
 		if err != nil {
 			log.Errorf("Failed to update document with ID: %s", job.ID.String())
@@ -90,6 +94,51 @@ func ListActiveCronjobFailsafe() ([]*commonmodels.Cronjob, error) {
 		ret = append(ret, jobList...)
 	}
 
+	refreshList, err := commonrepo.NewEnvDataRefreshColl().ListWithScheduleEnabled()
+	if err != nil {
+		return []*commonmodels.Cronjob{}, err
+	}
+	for _, refresh := range refreshList {
+		jobList, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+			ParentName: refresh.ID.Hex(),
+			ParentType: config.EnvDataRefreshCronjob,
+		})
+		if err != nil {
+			return []*commonmodels.Cronjob{}, err
+		}
+		ret = append(ret, jobList...)
+	}
+
+	smokeTestList, err := commonrepo.NewEnvSmokeTestColl().ListWithScheduleEnabled()
+	if err != nil {
+		return []*commonmodels.Cronjob{}, err
+	}
+	for _, smokeTest := range smokeTestList {
+		jobList, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+			ParentName: smokeTest.ID.Hex(),
+			ParentType: config.EnvSmokeTestCronjob,
+		})
+		if err != nil {
+			return []*commonmodels.Cronjob{}, err
+		}
+		ret = append(ret, jobList...)
+	}
+
+	trainList, err := commonrepo.NewReleaseTrainColl().ListWithScheduleEnabled()
+	if err != nil {
+		return []*commonmodels.Cronjob{}, err
+	}
+	for _, train := range trainList {
+		jobList, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+			ParentName: train.ID.Hex(),
+			ParentType: config.ReleaseTrainCronjob,
+		})
+		if err != nil {
+			return []*commonmodels.Cronjob{}, err
+		}
+		ret = append(ret, jobList...)
+	}
+
 	return ret, nil
 }
 
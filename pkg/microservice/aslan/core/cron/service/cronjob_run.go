@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	CronjobRunStatusTriggered = "triggered"
+	CronjobRunStatusSkipped   = "skipped"
+
+	cronjobRunListLimit = 100
+)
+
+type AcquireCronjobRunReq struct {
+	ScheduledTime int64 `json:"scheduled_time"`
+}
+
+type AcquireCronjobRunResp struct {
+	Acquired bool `json:"acquired"`
+}
+
+type MissedCronjobRunsReq struct {
+	ScheduledTimes []int64 `json:"scheduled_times"`
+	// Total is the number of the missed runs, only the latest ones are in ScheduledTimes.
+	Total int `json:"total"`
+}
+
+type MissedCronjobRunsResp struct {
+	// TriggerTimes are the scheduled times the cron service should run now.
+	TriggerTimes []int64 `json:"trigger_times"`
+}
+
+func cronjobRunIdempotencyKey(cronjobID string, scheduledTime int64) string {
+	return fmt.Sprintf("%s-%d", cronjobID, scheduledTime)
+}
+
+// AcquireCronjobRun is called by the cron service before a scheduled time is triggered, the run
+// is not acquired if the scheduled time has been triggered or caught up.
+func AcquireCronjobRun(id string, args *AcquireCronjobRunReq, log *zap.SugaredLogger) (*AcquireCronjobRunResp, error) {
+	job, err := commonrepo.NewCronjobColl().GetByID(id)
+	if err != nil {
+		log.Errorf("failed to find cronjob %s, err: %s", id, err)
+		return nil, e.ErrAcquireCronjobRun.AddErr(err)
+	}
+
+	acquired, err := createCronjobRun(job, args.ScheduledTime, false, CronjobRunStatusTriggered)
+	if err != nil {
+		log.Errorf("failed to create run of cronjob %s, err: %s", id, err)
+		return nil, e.ErrAcquireCronjobRun.AddErr(err)
+	}
+	return &AcquireCronjobRunResp{Acquired: acquired}, nil
+}
+
+// RecordMissedCronjobRuns records the runs missed while the cron service was down, and decides
+// which of them to run by the catch up policy of the cronjob.
+func RecordMissedCronjobRuns(id string, args *MissedCronjobRunsReq, log *zap.SugaredLogger) (*MissedCronjobRunsResp, error) {
+	job, err := commonrepo.NewCronjobColl().GetByID(id)
+	if err != nil {
+		log.Errorf("failed to find cronjob %s, err: %s", id, err)
+		return nil, e.ErrRecordMissedCronjobRuns.AddErr(err)
+	}
+
+	resp := &MissedCronjobRunsResp{TriggerTimes: []int64{}}
+	if len(args.ScheduledTimes) == 0 {
+		return resp, nil
+	}
+	sort.Slice(args.ScheduledTimes, func(i, j int) bool { return args.ScheduledTimes[i] < args.ScheduledTimes[j] })
+
+	latest := args.ScheduledTimes[len(args.ScheduledTimes)-1]
+	recorded := 0
+	for _, scheduledTime := range args.ScheduledTimes {
+		status := CronjobRunStatusSkipped
+		switch job.CatchUpPolicy {
+		case setting.CatchUpRunAllMissed:
+			status = CronjobRunStatusTriggered
+		case setting.CatchUpRunOnce:
+			if scheduledTime == latest {
+				status = CronjobRunStatusTriggered
+			}
+		}
+
+		created, err := createCronjobRun(job, scheduledTime, true, status)
+		if err != nil {
+			log.Errorf("failed to create missed run of cronjob %s, err: %s", id, err)
+			return nil, e.ErrRecordMissedCronjobRuns.AddErr(err)
+		}
+		if !created {
+			continue
+		}
+		recorded++
+		if status == CronjobRunStatusTriggered {
+			resp.TriggerTimes = append(resp.TriggerTimes, scheduledTime)
+		}
+	}
+	// the missed runs have been recorded by another replica of the cron service.
+	if recorded == 0 {
+		return resp, nil
+	}
+
+	total := args.Total
+	if total < len(args.ScheduledTimes) {
+		total = len(args.ScheduledTimes)
+	}
+	alertMissedCronjobRuns(job, total, len(resp.TriggerTimes), time.Unix(args.ScheduledTimes[0], 0), log)
+	return resp, nil
+}
+
+func ListCronjobRuns(id string, missedOnly bool, log *zap.SugaredLogger) ([]*commonmodels.CronjobRun, error) {
+	runs, err := commonrepo.NewCronjobRunColl().List(&commonrepo.ListCronjobRunOption{
+		CronjobID:  id,
+		MissedOnly: missedOnly,
+		Limit:      cronjobRunListLimit,
+	})
+	if err != nil {
+		log.Errorf("failed to list runs of cronjob %s, err: %s", id, err)
+		return nil, e.ErrListCronjobRuns.AddErr(err)
+	}
+	return runs, nil
+}
+
+func createCronjobRun(job *commonmodels.Cronjob, scheduledTime int64, missed bool, status string) (bool, error) {
+	run := &commonmodels.CronjobRun{
+		CronjobID:      job.ID.Hex(),
+		ParentName:     job.Name,
+		ParentType:     job.Type,
+		ProductName:    job.ProductName,
+		IdempotencyKey: cronjobRunIdempotencyKey(job.ID.Hex(), scheduledTime),
+		ScheduledTime:  scheduledTime,
+		Missed:         missed,
+		CatchUpPolicy:  job.CatchUpPolicy,
+		Status:         status,
+	}
+	created, err := commonrepo.NewCronjobRunColl().Create(run)
+	if err != nil || !created {
+		return created, err
+	}
+	return true, commonrepo.NewCronjobColl().UpdateLastScheduledTime(job.ID, scheduledTime)
+}
+
+// alertMissedCronjobRuns notifies the last updater of the workflow, testing, data refresh, smoke test or
+// release train the cronjob belongs to.
+func alertMissedCronjobRuns(job *commonmodels.Cronjob, missed, triggered int, since time.Time, log *zap.SugaredLogger) {
+	var receiver, name string
+	switch job.Type {
+	case config.WorkflowCronjob:
+		if workflow, err := commonrepo.NewWorkflowColl().Find(job.Name); err == nil {
+			receiver, name = workflow.UpdateBy, workflow.Name
+		}
+	case config.TestingCronjob:
+		if testing, err := commonrepo.NewTestingColl().Find(job.Name, job.ProductName); err == nil {
+			receiver, name = testing.UpdateBy, testing.Name
+		}
+	case config.EnvDataRefreshCronjob:
+		if refresh, err := commonrepo.NewEnvDataRefreshColl().FindByID(job.Name); err == nil {
+			receiver, name = refresh.UpdatedBy, fmt.Sprintf("%s/%s", refresh.EnvName, refresh.Name)
+		}
+	case config.EnvSmokeTestCronjob:
+		if smokeTest, err := commonrepo.NewEnvSmokeTestColl().FindByID(job.Name); err == nil {
+			receiver, name = smokeTest.UpdatedBy, fmt.Sprintf("%s/%s", smokeTest.EnvName, smokeTest.Name)
+		}
+	case config.ReleaseTrainCronjob:
+		if train, err := commonrepo.NewReleaseTrainColl().FindByID(job.Name); err == nil {
+			receiver, name = train.UpdatedBy, train.Name
+		}
+	}
+	if receiver == "" {
+		log.Warnf("cronjob %s missed %d runs since %s, no one to alert", job.ID.Hex(), missed, since.Format(time.RFC3339))
+		return
+	}
+
+	title := "定时任务错过执行"
+	content := fmt.Sprintf("%s, 项目：%s, 名称：%s, 自 %s 起错过 %d 次执行, 已补偿执行 %d 次",
+		title, job.ProductName, name, since.Format("2006-01-02 15:04:05"), missed, triggered)
+	commonservice.SendMessage(receiver, title, content, "", log)
+}
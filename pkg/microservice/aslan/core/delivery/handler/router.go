@@ -70,3 +70,12 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		deliverySecurity.POST("", CreateDeliverySecurity)
 	}
 }
+
+type OpenAPIRouter struct{}
+
+func (*OpenAPIRouter) Inject(router *gin.RouterGroup) {
+	artifact := router.Group("artifacts")
+	{
+		artifact.POST("", OpenAPIRegisterArtifact)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// OpenAPIRegisterArtifact registers the image as a delivery artifact with a build activity and the activities of
+// its test results, the id of the artifact is returned.
+func OpenAPIRegisterArtifact(username string, req *OpenAPIRegisterArtifactReq, logger *zap.SugaredLogger) (string, error) {
+	if req.ProjectName != "" {
+		if _, err := templaterepo.NewProductColl().Find(req.ProjectName); err != nil {
+			return "", e.ErrInvalidParam.AddDesc(fmt.Sprintf("project %s not found", req.ProjectName))
+		}
+	}
+	name, tag, _ := splitImage(req.Image)
+
+	build := &commonmodels.DeliveryActivity{
+		Type:      setting.BuildType,
+		URL:       req.BuildURL,
+		Commits:   req.Commits,
+		Issues:    req.Issues,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+	if req.CISystem != "" {
+		build.Content = fmt.Sprintf("built by %s", req.CISystem)
+	}
+	activities := []*commonmodels.DeliveryActivity{build}
+	for _, result := range req.TestResults {
+		activities = append(activities, &commonmodels.DeliveryActivity{
+			Type:      setting.TestType,
+			Content:   fmt.Sprintf("%s: %d/%d passed", result.Name, result.Passed, result.Total),
+			URL:       result.ReportURL,
+			StartTime: result.StartTime,
+			EndTime:   result.EndTime,
+		})
+	}
+
+	return InsertDeliveryArtifact(&DeliveryArtifactInfo{
+		DeliveryArtifact: &commonmodels.DeliveryArtifact{
+			Name:        name,
+			Type:        string(config.Image),
+			Source:      config.DeliveryArtifactSourceExternal,
+			Image:       req.Image,
+			ImageTag:    tag,
+			ImageDigest: req.ImageDigest,
+			ProjectName: req.ProjectName,
+			Metadata:    req.Metadata,
+			CreatedBy:   username,
+		},
+		DeliveryActivities: activities,
+	}, logger)
+}
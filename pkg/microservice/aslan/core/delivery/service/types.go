@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// OpenAPIRegisterArtifactReq is an image built by an external CI system, it is registered as a delivery artifact
+// so that its deployments are tracked as the ones of the images built by zadig.
+type OpenAPIRegisterArtifactReq struct {
+	Image       string `json:"image"`
+	ImageDigest string `json:"image_digest"`
+	// Optional fields below
+	ProjectName string                         `json:"project_name"`
+	CISystem    string                         `json:"ci_system"`
+	BuildURL    string                         `json:"build_url"`
+	StartTime   int64                          `json:"start_time"`
+	EndTime     int64                          `json:"end_time"`
+	Commits     []*commonmodels.ActivityCommit `json:"commits"`
+	Issues      []string                       `json:"issues"`
+	Metadata    map[string]string              `json:"metadata"`
+	TestResults []*OpenAPIArtifactTestResult   `json:"test_results"`
+}
+
+type OpenAPIArtifactTestResult struct {
+	Name      string `json:"name"`
+	Total     int    `json:"total"`
+	Passed    int    `json:"passed"`
+	ReportURL string `json:"report_url"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+}
+
+func (req OpenAPIRegisterArtifactReq) Validate() error {
+	if _, _, err := splitImage(req.Image); err != nil {
+		return err
+	}
+	if req.ImageDigest != "" && !strings.HasPrefix(req.ImageDigest, "sha256:") {
+		return errors.New("image_digest should start with sha256:")
+	}
+
+	for _, result := range req.TestResults {
+		if result.Name == "" {
+			return errors.New("name of the test results cannot be empty")
+		}
+		if result.Total < 0 || result.Passed < 0 || result.Passed > result.Total {
+			return fmt.Errorf("invalid case numbers of the test result %s", result.Name)
+		}
+	}
+	return nil
+}
+
+// splitImage returns the name and the tag of the image, e.g. nginx and 1.21 of registry.io/library/nginx:1.21.
+func splitImage(image string) (string, string, error) {
+	parts := strings.Split(image, "/")
+	nameAndTag := strings.Split(parts[len(parts)-1], ":")
+	if len(nameAndTag) != 2 || nameAndTag[0] == "" || nameAndTag[1] == "" {
+		return "", "", errors.New("image should be in the format of repository:tag")
+	}
+	return nameAndTag[0], nameAndTag[1], nil
+}
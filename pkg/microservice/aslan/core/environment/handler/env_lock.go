@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	"github.com/koderover/zadig/pkg/setting"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetEnvLock(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetEnvLock(projectName, envName, ctx.Logger)
+}
+
+func LockEnv(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+	args := new(service.LockEnvArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "新增", "环境-锁定", envName, "", ctx.Logger, envName)
+
+	ctx.Resp, ctx.Err = service.LockEnv(ctx.UserName, projectName, envName, args, ctx.Logger)
+}
+
+func UnlockEnv(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "删除", "环境-锁定", envName, "", ctx.Logger, envName)
+
+	ctx.Err = service.UnlockEnv(ctx.UserName, projectName, envName, ctx.Logger)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	"github.com/koderover/zadig/pkg/setting"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListEnvSmokeTests(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListEnvSmokeTests(c.Query("projectName"), c.Param("name"), ctx.Logger)
+}
+
+func CreateEnvSmokeTest(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.EnvSmokeTest)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+	args.EnvName = c.Param("name")
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, args.ProjectName, setting.OperationSceneEnv, "新增", "环境-冒烟测试", args.Name, "", ctx.Logger, args.EnvName)
+
+	ctx.Err = service.CreateEnvSmokeTest(ctx.UserName, args, ctx.Logger)
+}
+
+func UpdateEnvSmokeTest(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.EnvSmokeTest)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+	args.EnvName = c.Param("name")
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, args.ProjectName, setting.OperationSceneEnv, "更新", "环境-冒烟测试", args.Name, "", ctx.Logger, args.EnvName)
+
+	ctx.Err = service.UpdateEnvSmokeTest(ctx.UserName, c.Param("id"), args, ctx.Logger)
+}
+
+func DeleteEnvSmokeTest(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, c.Query("projectName"), setting.OperationSceneEnv, "删除", "环境-冒烟测试", c.Param("id"), "", ctx.Logger, c.Param("name"))
+
+	ctx.Err = service.DeleteEnvSmokeTest(c.Param("id"), c.Query("projectName"), c.Param("name"), ctx.Logger)
+}
+
+func RunEnvSmokeTest(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	// the request from the cron service carries the trigger in the body.
+	args := new(commonmodels.EnvSmokeTestArgs)
+	data, _ := c.GetRawData()
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, args); err != nil {
+			ctx.Err = e.ErrInvalidParam.AddErr(err)
+			return
+		}
+	}
+	triggeredBy := ctx.UserName
+	if args.TriggeredBy == setting.CronTaskCreator {
+		triggeredBy = setting.CronTaskCreator
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, triggeredBy, c.Query("projectName"), setting.OperationSceneEnv, "执行", "环境-冒烟测试", c.Param("id"), string(data), ctx.Logger, c.Param("name"))
+
+	ctx.Resp, ctx.Err = service.RunEnvSmokeTest(c.Param("id"), c.Query("projectName"), c.Param("name"), triggeredBy, ctx.Logger)
+}
+
+func ListEnvSmokeTestRecords(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListEnvSmokeTestRecords(c.Param("id"), c.Query("projectName"), c.Param("name"), ctx.Logger)
+}
@@ -39,8 +39,16 @@ import (
 	"github.com/koderover/zadig/pkg/shared/kube/resource"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/pagination"
 )
 
+var envSchema = &pagination.Schema{
+	Key:        "name",
+	Sortable:   []string{"updateTime", "status"},
+	Filterable: []string{"status", "cluster_id", "production", "source", "isPublic"},
+	Searchable: []string{"name", "clusterName"},
+}
+
 type DeleteProductServicesRequest struct {
 	ServiceNames []string `json:"service_names"`
 }
@@ -73,7 +81,12 @@ func ListProducts(c *gin.Context) {
 		return
 	}
 
-	ctx.Resp, ctx.Err = service.ListProducts(projectName, envNames, ctx.Logger)
+	envs, err := service.ListProducts(projectName, envNames, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Resp, _, ctx.Err = internalhandler.Paginate(c, envs, envSchema)
 }
 
 func UpdateMultiProducts(c *gin.Context) {
@@ -248,6 +261,38 @@ func CreateProduct(c *gin.Context) {
 	}
 }
 
+func DuplicateEnvironment(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	args := new(service.DuplicateEnvironmentArgs)
+	data, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	allowedClusters, found := internalhandler.GetResourcesInHeader(c)
+	if found && args.ClusterID != "" && !sets.NewString(allowedClusters...).Has(args.ClusterID) {
+		c.String(http.StatusForbidden, "permission denied for cluster %s", args.ClusterID)
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "复制", "环境", envName, string(data), ctx.Logger, envName)
+	ctx.Resp, ctx.Err = service.DuplicateEnvironment(projectName, envName, args, ctx.UserName, ctx.RequestID, ctx.Logger)
+}
+
 type UpdateProductParams struct {
 	ServiceNames []string `json:"service_names"`
 	commonmodels.Product
@@ -349,6 +394,106 @@ func UpdateProductRecycleDay(c *gin.Context) {
 	ctx.Err = service.UpdateProductRecycleDay(envName, projectName, recycleDay)
 }
 
+func UpdateProductDiffApproval(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+	required, err := strconv.ParseBool(c.Query("required"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("required必须是布尔值")
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "更新", "环境-部署变更确认", envName, "", ctx.Logger, envName)
+
+	ctx.Err = service.UpdateProductDiffApproval(envName, projectName, required)
+}
+
+func UpdateProductAutoRollback(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+	enabled, err := strconv.ParseBool(c.Query("enabled"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("enabled必须是布尔值")
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "更新", "环境-部署验证失败自动回滚", envName, "", ctx.Logger, envName)
+
+	ctx.Err = service.UpdateProductAutoRollback(envName, projectName, enabled)
+}
+
+func UpdateNamespaceMeta(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+	args := new(commonmodels.NamespaceMeta)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "更新", "环境-命名空间标签", envName, "", ctx.Logger, envName)
+
+	ctx.Err = service.UpdateNamespaceMeta(envName, projectName, args, ctx.Logger)
+}
+
+func UpdateClusterSharedServices(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+	args := make([]string, 0)
+	if err := c.ShouldBindJSON(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "更新", "环境-集群共享服务", envName, "", ctx.Logger, envName)
+
+	ctx.Err = service.UpdateClusterSharedServices(envName, projectName, args, ctx.Logger)
+}
+
+func SyncRegistrySecrets(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "同步", "环境-镜像仓库密钥", envName, "", ctx.Logger, envName)
+
+	ctx.Err = service.SyncRegistrySecrets(envName, projectName, ctx.Logger)
+}
+
 func EstimatedValues(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -777,3 +922,96 @@ func ListWorkloadsInEnv(c *gin.Context) {
 	ctx.Err = err
 	c.Writer.Header().Set("X-Total", strconv.Itoa(count))
 }
+
+type renderEnvServicesArgs struct {
+	ProjectName  string   `json:"projectName"  form:"projectName"`
+	ServiceNames []string `json:"serviceNames" form:"serviceNames"`
+	OnlyChanged  bool     `json:"onlyChanged"  form:"onlyChanged"`
+	Latest       bool     `json:"latest"       form:"latest"`
+}
+
+// RenderEnvServices renders the yaml of the given services, or only the changed ones, of a k8s yaml environment.
+func RenderEnvServices(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &renderEnvServicesArgs{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.RenderEnvServices(args.ProjectName, c.Param("name"), &service.RenderEnvServicesArgs{
+		ServiceNames: args.ServiceNames,
+		OnlyChanged:  args.OnlyChanged,
+		Latest:       args.Latest,
+	}, ctx.Logger)
+}
+
+type lintEnvServicesArgs struct {
+	ProjectName  string   `json:"projectName"  form:"projectName"`
+	ServiceNames []string `json:"serviceNames" form:"serviceNames"`
+}
+
+// LintEnvServices lints the deployed manifests of the services of an environment.
+func LintEnvServices(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &lintEnvServicesArgs{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.LintEnvServices(args.ProjectName, c.Param("name"), args.ServiceNames, ctx.Logger)
+}
+
+type deprecationReportArgs struct {
+	ProjectName   string `json:"projectName"   form:"projectName"`
+	TargetVersion string `json:"targetVersion" form:"targetVersion"`
+}
+
+// GetEnvDeprecationReport finds the resources of an environment broken by upgrading its cluster.
+func GetEnvDeprecationReport(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &deprecationReportArgs{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetEnvDeprecationReport(args.ProjectName, c.Param("name"), args.TargetVersion, ctx.Logger)
+}
+
+// GetProjectDeprecationReport finds the resources of all the environments of a project broken by upgrading their clusters.
+func GetProjectDeprecationReport(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &deprecationReportArgs{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetProjectDeprecationReport(args.ProjectName, args.TargetVersion, ctx.Logger)
+}
@@ -94,3 +94,46 @@ func UpdateDeploymentContainerImage(c *gin.Context) {
 
 	ctx.Err = service.UpdateContainerImage(ctx.RequestID, args, ctx.Logger)
 }
+
+func UpdateDaemonSetContainerImage(c *gin.Context) {
+	updateWorkloadContainerImage(c, setting.DaemonSet)
+}
+
+func UpdateCronJobContainerImage(c *gin.Context) {
+	updateWorkloadContainerImage(c, setting.CronJob)
+}
+
+func UpdateJobContainerImage(c *gin.Context) {
+	updateWorkloadContainerImage(c, setting.Job)
+}
+
+func updateWorkloadContainerImage(c *gin.Context, workloadType string) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.UpdateContainerImageArgs)
+
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("Update%sContainerImage c.GetRawData() err : %v", workloadType, err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("Update%sContainerImage json.Unmarshal err : %v", workloadType, err)
+	}
+
+	internalhandler.InsertDetailedOperationLog(
+		c, ctx.UserName, args.ProductName, setting.OperationSceneEnv,
+		"更新", "环境-服务镜像",
+		fmt.Sprintf("环境名称:%s,服务名称:%s,%s:%s", args.EnvName, args.ServiceName, workloadType, args.Name),
+		string(data), ctx.Logger, args.EnvName)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	args.Type = workloadType
+
+	ctx.Err = service.UpdateContainerImage(ctx.RequestID, args, ctx.Logger)
+}
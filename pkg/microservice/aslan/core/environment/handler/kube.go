@@ -44,7 +44,7 @@ func ListKubeEvents(c *gin.Context) {
 	name := c.Query("name")
 	rtype := c.Query("type")
 
-	if !(rtype == setting.Deployment || rtype == setting.StatefulSet) {
+	if !(rtype == setting.Deployment || rtype == setting.StatefulSet || rtype == setting.DaemonSet || rtype == setting.Job || rtype == setting.CronJob) {
 		ctx.Resp = make([]interface{}, 0)
 		return
 	}
@@ -88,6 +88,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	{
 		image.POST("/deployment/:envName", UpdateDeploymentContainerImage)
 		image.POST("/statefulset/:envName", UpdateStatefulSetContainerImage)
+		image.POST("/daemonset/:envName", UpdateDaemonSetContainerImage)
+		image.POST("/cronjob/:envName", UpdateCronJobContainerImage)
+		image.POST("/job/:envName", UpdateJobContainerImage)
 	}
 
 	// 查询环境创建时的服务和变量信息
@@ -120,6 +123,11 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		operations.GET("", GetOperationLogs)
 	}
 
+	deprecations := router.Group("deprecations")
+	{
+		deprecations.GET("", GetProjectDeprecationReport)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// 产品管理接口(环境)
 	// ---------------------------------------------------------------------------------------
@@ -131,10 +139,33 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.PUT("", UpdateMultiProducts)
 		environments.POST("", CreateProduct)
 		environments.GET("/:name", GetProduct)
+		environments.POST("/:name/duplicate", DuplicateEnvironment)
 		environments.PUT("/:name/envRecycle", UpdateProductRecycleDay)
+		environments.PUT("/:name/diffApproval", UpdateProductDiffApproval)
+		environments.PUT("/:name/autoRollback", UpdateProductAutoRollback)
+		environments.GET("/:name/lock", GetEnvLock)
+		environments.POST("/:name/lock", LockEnv)
+		environments.DELETE("/:name/lock", UnlockEnv)
+		environments.PUT("/:name/namespaceMeta", UpdateNamespaceMeta)
+		environments.PUT("/:name/clusterSharedServices", UpdateClusterSharedServices)
+		environments.POST("/:name/registrySecrets/sync", SyncRegistrySecrets)
+		environments.GET("/:name/dataRefresh", ListEnvDataRefreshes)
+		environments.POST("/:name/dataRefresh", CreateEnvDataRefresh)
+		environments.PUT("/:name/dataRefresh/:id", UpdateEnvDataRefresh)
+		environments.DELETE("/:name/dataRefresh/:id", DeleteEnvDataRefresh)
+		environments.POST("/:name/dataRefresh/:id/run", RunEnvDataRefresh)
+		environments.GET("/:name/dataRefresh/:id/records", ListEnvDataRefreshRecords)
+		environments.GET("/:name/smokeTests", ListEnvSmokeTests)
+		environments.POST("/:name/smokeTests", CreateEnvSmokeTest)
+		environments.PUT("/:name/smokeTests/:id", UpdateEnvSmokeTest)
+		environments.DELETE("/:name/smokeTests/:id", DeleteEnvSmokeTest)
+		environments.POST("/:name/smokeTests/:id/run", RunEnvSmokeTest)
+		environments.GET("/:name/smokeTests/:id/records", ListEnvSmokeTestRecords)
 		environments.POST("/:name/estimated-values", EstimatedValues)
 		environments.PUT("/:name/renderset", UpdateHelmProductRenderset)
 		environments.PUT("/:name/helm/default-values", UpdateHelmProductDefaultValues)
+		environments.PUT("/:name/helm/post-renderer", UpdateHelmPostRenderer)
+		environments.GET("/:name/helm/post-render/records", ListHelmPostRenderRecords)
 		environments.PUT("/:name/helm/charts", UpdateHelmProductCharts)
 		environments.PUT("/:name/syncVariables", SyncHelmProductRenderset)
 		environments.GET("/:name/helmChartVersions", GetHelmChartVersions)
@@ -143,6 +174,11 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.PUT("/:name/services", DeleteProductServices)
 		environments.GET("/:name/groups", ListGroups)
 		environments.GET("/:name/workloads", ListWorkloadsInEnv)
+		environments.GET("/:name/render/services", RenderEnvServices)
+		environments.GET("/:name/lint", LintEnvServices)
+		environments.GET("/:name/deprecations", GetEnvDeprecationReport)
+		environments.GET("/:name/dns", GetEnvDNSRecords)
+		environments.POST("/:name/dns/sync", SyncEnvDNSRecords)
 
 		environments.GET("/:name/helm/releases", ListReleases)
 		environments.GET("/:name/helm/values", GetChartValues)
@@ -155,6 +191,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.POST("/:name/services/:serviceName/restartNew", RestartNewService)
 		environments.POST("/:name/services/:serviceName/scale", ScaleService)
 		environments.POST("/:name/services/:serviceName/scaleNew", ScaleNewService)
+		environments.GET("/:name/services/:serviceName/copy/preview", PreviewServiceCopy)
+		environments.POST("/:name/services/:serviceName/copy", CopyService)
 		environments.GET("/:name/services/:serviceName/containers/:container", GetServiceContainer)
 
 		environments.GET("/:name/estimated-renderchart", GetEstimatedRenderCharts)
@@ -165,6 +203,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.GET("/:name/check/sharenv/:op/ready", CheckShareEnvReady)
 
 		environments.GET("/:name/services/:serviceName/pmexec", ConnectSshPmExec)
+		environments.GET("/:name/pods/:podName/logs", GetPodLogs)
+		environments.GET("/:name/pods/:podName/logs/tail", TailPodLogs)
+		environments.GET("/:name/services/:serviceName/logs/download", DownloadServiceLogs)
 
 		environments.POST("/:name/services/:serviceName/devmode/patch", PatchWorkload)
 		environments.POST("/:name/services/:serviceName/devmode/recover", RecoverWorkload)
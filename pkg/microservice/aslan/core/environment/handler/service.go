@@ -186,3 +186,44 @@ func GetServiceContainer(c *gin.Context) {
 
 	ctx.Err = service.GetServiceContainer(envName, projectName, serviceName, container, ctx.Logger)
 }
+
+func PreviewServiceCopy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+	args := new(service.CopyServiceArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.PreviewServiceCopy(projectName, envName, c.Param("serviceName"), args, ctx.Logger)
+}
+
+func CopyService(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	envName := c.Param("name")
+	projectName := c.Query("projectName")
+	if envName == "" || projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("envName or projectName不能为空")
+		return
+	}
+	args := new(service.CopyServiceArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	serviceName := c.Param("serviceName")
+
+	internalhandler.InsertDetailedOperationLog(c, ctx.UserName, projectName, setting.OperationSceneEnv, "复制", "环境-服务", fmt.Sprintf("环境名称:%s,服务名称:%s,来源环境:%s", envName, serviceName, args.SourceEnv), "", ctx.Logger, envName)
+
+	ctx.Err = service.CopyService(projectName, envName, serviceName, args, ctx.UserName, ctx.RequestID, ctx.Logger)
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/manifestlint"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	DeprecationSourceManifest = "manifest"
+	DeprecationSourceLive     = "live"
+)
+
+type DeprecationReport struct {
+	ProjectName string `json:"project_name"`
+	EnvName     string `json:"env_name"`
+	ClusterID   string `json:"cluster_id"`
+	// KubeVersion is the version of the cluster, it is empty if the cluster is unreachable.
+	KubeVersion   string            `json:"kube_version"`
+	TargetVersion string            `json:"target_version"`
+	Deprecations  []*EnvDeprecation `json:"deprecations"`
+	Error         string            `json:"error,omitempty"`
+}
+
+type EnvDeprecation struct {
+	// Source is manifest for the deployed manifests of the services and live for the resources in the cluster.
+	Source      string `json:"source"`
+	ServiceName string `json:"service_name,omitempty"`
+	*commonmodels.APIDeprecation
+}
+
+type ProjectDeprecationReport struct {
+	ProjectName  string               `json:"project_name"`
+	Environments []*DeprecationReport `json:"environments"`
+}
+
+// GetEnvDeprecationReport finds the resources of the environment which break when its cluster is upgraded
+// to the target version, it is the next minor version of the cluster by default.
+func GetEnvDeprecationReport(projectName, envName, targetVersion string, log *zap.SugaredLogger) (*DeprecationReport, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName})
+	if err != nil {
+		return nil, e.ErrGetDeprecationReport.AddErr(err)
+	}
+	return envDeprecationReport(env, targetVersion, log), nil
+}
+
+// GetProjectDeprecationReport reports the deprecations of all the environments of the project, failing to
+// scan an environment is reported in its report.
+func GetProjectDeprecationReport(projectName, targetVersion string, log *zap.SugaredLogger) (*ProjectDeprecationReport, error) {
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{Name: projectName})
+	if err != nil {
+		return nil, e.ErrGetDeprecationReport.AddErr(err)
+	}
+	resp := &ProjectDeprecationReport{ProjectName: projectName, Environments: make([]*DeprecationReport, 0, len(envs))}
+	for _, env := range envs {
+		resp.Environments = append(resp.Environments, envDeprecationReport(env, targetVersion, log))
+	}
+	sort.Slice(resp.Environments, func(i, j int) bool { return resp.Environments[i].EnvName < resp.Environments[j].EnvName })
+	return resp, nil
+}
+
+func envDeprecationReport(env *commonmodels.Product, targetVersion string, log *zap.SugaredLogger) *DeprecationReport {
+	report := &DeprecationReport{
+		ProjectName:   env.ProductName,
+		EnvName:       env.EnvName,
+		ClusterID:     env.ClusterID,
+		TargetVersion: targetVersion,
+		Deprecations:  make([]*EnvDeprecation, 0),
+	}
+	errs := make([]string, 0)
+
+	cls, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), env.ClusterID)
+	if err == nil {
+		if versionInfo, err := cls.Discovery().ServerVersion(); err == nil {
+			report.KubeVersion = versionInfo.GitVersion
+		} else {
+			errs = append(errs, "failed to get the version of the cluster: "+err.Error())
+		}
+	} else {
+		errs = append(errs, "failed to connect to the cluster: "+err.Error())
+	}
+	if report.TargetVersion == "" && report.KubeVersion != "" {
+		report.TargetVersion, _ = manifestlint.NextMinorVersion(report.KubeVersion)
+	}
+
+	services, err := envServiceManifests(env, nil, log)
+	if err != nil {
+		errs = append(errs, "failed to get the manifests of the services: "+err.Error())
+	}
+	for _, svc := range services {
+		if svc.Error != "" {
+			errs = append(errs, svc.ServiceName+": "+svc.Error)
+			continue
+		}
+		for _, d := range manifestlint.FindDeprecations(svc.Manifests, report.KubeVersion, report.TargetVersion) {
+			report.Deprecations = append(report.Deprecations, &EnvDeprecation{Source: DeprecationSourceManifest, ServiceName: svc.ServiceName, APIDeprecation: d})
+		}
+	}
+
+	if cls != nil {
+		live, err := liveDeprecations(env, cls.Discovery().ServerResourcesForGroupVersion, report.KubeVersion, report.TargetVersion)
+		if err != nil {
+			errs = append(errs, "failed to scan the resources in the cluster: "+err.Error())
+		}
+		report.Deprecations = append(report.Deprecations, live...)
+	}
+	report.Error = strings.Join(errs, "; ")
+	if report.Error != "" {
+		log.Warnf("deprecation report of %s/%s is incomplete: %s", env.ProductName, env.EnvName, report.Error)
+	}
+	return report
+}
+
+// liveDeprecations finds the resources in the namespace of the environment which are applied with the
+// deprecated api versions still served by the cluster. The api version a resource is applied with is
+// recorded in its managed fields or in the last applied configuration of kubectl.
+func liveDeprecations(env *commonmodels.Product, serverResources func(string) (*metav1.APIResourceList, error), currentVersion, targetVersion string) ([]*EnvDeprecation, error) {
+	current, _ := version.ParseGeneric(currentVersion)
+	target, _ := version.ParseGeneric(targetVersion)
+	dynamicClient, err := kubeclient.GetDynamicKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*EnvDeprecation, 0)
+	for _, api := range manifestlint.DeprecatedAPIs() {
+		resources, err := serverResources(api.APIVersion)
+		if err != nil {
+			// the api version is not served by the cluster any more.
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(api.APIVersion)
+		if err != nil {
+			continue
+		}
+		kinds := sets.NewString(api.Kinds...)
+		for _, r := range resources.APIResources {
+			if !kinds.Has(r.Kind) || !r.Namespaced || strings.Contains(r.Name, "/") {
+				continue
+			}
+			list, err := dynamicClient.Resource(gv.WithResource(r.Name)).Namespace(env.Namespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return resp, err
+			}
+			for _, item := range list.Items {
+				if !appliedWith(&item, api.APIVersion) {
+					continue
+				}
+				if d := manifestlint.Deprecation(api.APIVersion, r.Kind, item.GetName(), item.GetNamespace(), current, target); d != nil {
+					resp = append(resp, &EnvDeprecation{Source: DeprecationSourceLive, APIDeprecation: d})
+				}
+			}
+		}
+	}
+	return resp, nil
+}
+
+func appliedWith(obj *unstructured.Unstructured, apiVersion string) bool {
+	for _, field := range obj.GetManagedFields() {
+		if field.APIVersion == apiVersion {
+			return true
+		}
+	}
+	lastApplied := obj.GetAnnotations()["kubectl.kubernetes.io/last-applied-configuration"]
+	if lastApplied == "" {
+		return false
+	}
+	applied := &metav1.TypeMeta{}
+	if err := json.Unmarshal([]byte(lastApplied), applied); err != nil {
+		return false
+	}
+	return applied.APIVersion == apiVersion
+}
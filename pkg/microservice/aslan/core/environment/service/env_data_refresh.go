@@ -0,0 +1,408 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/nsq"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+)
+
+const (
+	defaultEnvDataRefreshTimeout = 60
+	envDataRefreshRecordLimit    = 50
+	envDataRefreshLabel          = "zadig-env-data-refresh"
+)
+
+func ListEnvDataRefreshes(projectName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvDataRefresh, error) {
+	refreshes, err := commonrepo.NewEnvDataRefreshColl().List(projectName, envName)
+	if err != nil {
+		log.Errorf("failed to list data refreshes of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrListEnvDataRefresh.AddErr(err)
+	}
+	for _, refresh := range refreshes {
+		schedules, err := listEnvDataRefreshSchedules(refresh)
+		if err != nil {
+			log.Errorf("failed to list schedules of data refresh %s, err: %s", refresh.Name, err)
+			return nil, e.ErrListEnvDataRefresh.AddErr(err)
+		}
+		refresh.Schedules = schedules
+		for _, kv := range refresh.Credentials {
+			kv.Value = setting.MaskValue
+		}
+	}
+	return refreshes, nil
+}
+
+func CreateEnvDataRefresh(userName string, args *commonmodels.EnvDataRefresh, log *zap.SugaredLogger) error {
+	if err := validateEnvDataRefresh(args); err != nil {
+		return e.ErrCreateEnvDataRefresh.AddErr(err)
+	}
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: args.ProjectName, EnvName: args.EnvName}); err != nil {
+		return e.ErrCreateEnvDataRefresh.AddDesc(fmt.Sprintf("env %s not found", args.EnvName))
+	}
+	if _, err := commonrepo.NewEnvDataRefreshColl().Find(args.ProjectName, args.EnvName, args.Name); err == nil {
+		return e.ErrCreateEnvDataRefresh.AddDesc(fmt.Sprintf("data refresh %s already exists", args.Name))
+	}
+
+	schedules := args.Schedules
+	args.ID = primitive.NilObjectID
+	args.CreatedBy = userName
+	args.UpdatedBy = userName
+	if err := commonrepo.NewEnvDataRefreshColl().Create(args); err != nil {
+		log.Errorf("failed to create data refresh %s, err: %s", args.Name, err)
+		return e.ErrCreateEnvDataRefresh.AddErr(err)
+	}
+	return handleEnvDataRefreshCronjob(args, schedules, log)
+}
+
+func UpdateEnvDataRefresh(userName, id string, args *commonmodels.EnvDataRefresh, log *zap.SugaredLogger) error {
+	refresh, err := findEnvDataRefresh(id, args.ProjectName, args.EnvName)
+	if err != nil {
+		return e.ErrUpdateEnvDataRefresh.AddErr(err)
+	}
+	if err := validateEnvDataRefresh(args); err != nil {
+		return e.ErrUpdateEnvDataRefresh.AddErr(err)
+	}
+
+	// the credentials are masked in the response, keep the saved value if it is not changed.
+	saved := make(map[string]string, len(refresh.Credentials))
+	for _, kv := range refresh.Credentials {
+		saved[kv.Key] = kv.Value
+	}
+	for _, kv := range args.Credentials {
+		if kv.Value == setting.MaskValue {
+			kv.Value = saved[kv.Key]
+		}
+	}
+
+	schedules := args.Schedules
+	args.ID = refresh.ID
+	args.Name = refresh.Name
+	args.CreatedBy = refresh.CreatedBy
+	args.CreateTime = refresh.CreateTime
+	args.UpdatedBy = userName
+	if err := commonrepo.NewEnvDataRefreshColl().Update(args); err != nil {
+		log.Errorf("failed to update data refresh %s, err: %s", refresh.Name, err)
+		return e.ErrUpdateEnvDataRefresh.AddErr(err)
+	}
+	return handleEnvDataRefreshCronjob(args, schedules, log)
+}
+
+func DeleteEnvDataRefresh(id, projectName, envName string, log *zap.SugaredLogger) error {
+	refresh, err := findEnvDataRefresh(id, projectName, envName)
+	if err != nil {
+		return e.ErrDeleteEnvDataRefresh.AddErr(err)
+	}
+
+	if err := workflowservice.DeleteCronjob(refresh.ID.Hex(), config.EnvDataRefreshCronjob); err != nil {
+		log.Errorf("failed to delete cronjobs of data refresh %s, err: %s", refresh.Name, err)
+		return e.ErrDeleteEnvDataRefresh.AddErr(err)
+	}
+	pl, _ := json.Marshal(&commonservice.CronjobPayload{
+		Name:        refresh.ID.Hex(),
+		ProductName: refresh.ProjectName,
+		JobType:     config.EnvDataRefreshCronjob,
+		Action:      setting.TypeDisableCronjob,
+	})
+	if err := nsq.Publish(setting.TopicCronjob, pl); err != nil {
+		log.Errorf("Failed to publish to nsq topic: %s, the error is: %v", setting.TopicCronjob, err)
+		return e.ErrDeleteEnvDataRefresh.AddErr(err)
+	}
+
+	if err := commonrepo.NewEnvDataRefreshColl().Delete(refresh.ID); err != nil {
+		log.Errorf("failed to delete data refresh %s, err: %s", refresh.Name, err)
+		return e.ErrDeleteEnvDataRefresh.AddErr(err)
+	}
+	if err := commonrepo.NewEnvDataRefreshRecordColl().DeleteByRefreshID(refresh.ID.Hex()); err != nil {
+		log.Warnf("failed to delete records of data refresh %s, err: %s", refresh.Name, err)
+	}
+	return nil
+}
+
+func ListEnvDataRefreshRecords(id, projectName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvDataRefreshRecord, error) {
+	refresh, err := findEnvDataRefresh(id, projectName, envName)
+	if err != nil {
+		return nil, e.ErrListEnvDataRefresh.AddErr(err)
+	}
+	records, err := commonrepo.NewEnvDataRefreshRecordColl().List(refresh.ID.Hex(), envDataRefreshRecordLimit)
+	if err != nil {
+		log.Errorf("failed to list records of data refresh %s, err: %s", refresh.Name, err)
+		return nil, e.ErrListEnvDataRefresh.AddErr(err)
+	}
+	return records, nil
+}
+
+// RunEnvDataRefresh starts the data refresh in the namespace of the env, the result is saved in the
+// record when the job finishes.
+func RunEnvDataRefresh(id, projectName, envName, triggeredBy string, log *zap.SugaredLogger) (*commonmodels.EnvDataRefreshRecord, error) {
+	refresh, err := findEnvDataRefresh(id, projectName, envName)
+	if err != nil {
+		return nil, e.ErrRunEnvDataRefresh.AddErr(err)
+	}
+	if _, err := commonrepo.NewEnvDataRefreshRecordColl().FindRunning(refresh.ID.Hex()); err == nil {
+		return nil, e.ErrRunEnvDataRefresh.AddDesc(fmt.Sprintf("data refresh %s is running", refresh.Name))
+	}
+	product, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName})
+	if err != nil {
+		return nil, e.ErrRunEnvDataRefresh.AddDesc(fmt.Sprintf("env %s not found", envName))
+	}
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), product.ClusterID)
+	if err != nil {
+		log.Errorf("failed to get kube client of cluster %s, err: %s", product.ClusterID, err)
+		return nil, e.ErrRunEnvDataRefresh.AddErr(err)
+	}
+
+	record := &commonmodels.EnvDataRefreshRecord{
+		RefreshID:   refresh.ID.Hex(),
+		ProjectName: projectName,
+		EnvName:     envName,
+		Status:      config.StatusRunning,
+		TriggeredBy: triggeredBy,
+		StartTime:   time.Now().Unix(),
+	}
+	if err := commonrepo.NewEnvDataRefreshRecordColl().Create(record); err != nil {
+		log.Errorf("failed to create record of data refresh %s, err: %s", refresh.Name, err)
+		return nil, e.ErrRunEnvDataRefresh.AddErr(err)
+	}
+
+	resp := *record
+	go func() {
+		status, err := runEnvDataRefreshJob(refresh, record, product, kubeClient)
+		record.Status = status
+		if err != nil {
+			log.Errorf("data refresh %s of env %s/%s finished with status %s, err: %s", refresh.Name, projectName, envName, record.Status, err)
+			record.Error = err.Error()
+		}
+		record.EndTime = time.Now().Unix()
+		if err := commonrepo.NewEnvDataRefreshRecordColl().Update(record); err != nil {
+			log.Errorf("failed to update record of data refresh %s, err: %s", refresh.Name, err)
+		}
+	}()
+	return &resp, nil
+}
+
+func runEnvDataRefreshJob(refresh *commonmodels.EnvDataRefresh, record *commonmodels.EnvDataRefreshRecord, product *commonmodels.Product, kubeClient client.Client) (config.Status, error) {
+	name := fmt.Sprintf("data-refresh-%s", record.ID.Hex())
+	labels := map[string]string{envDataRefreshLabel: refresh.ID.Hex()}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: product.Namespace, Labels: labels},
+		StringData: map[string]string{},
+	}
+	for _, kv := range refresh.Credentials {
+		secret.StringData[kv.Key] = kv.Value
+	}
+	if err := updater.UpdateOrCreateSecret(secret, kubeClient); err != nil {
+		return config.StatusFailed, fmt.Errorf("failed to create credential secret: %s", err)
+	}
+	defer func() {
+		_ = updater.DeleteJob(product.Namespace, name, kubeClient)
+		_ = updater.DeleteSecretWithName(product.Namespace, name, kubeClient)
+	}()
+
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: product.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "data-refresh",
+						Image:   refresh.Image,
+						Command: []string{"sh", "-c", buildEnvDataRefreshScript(refresh)},
+						Env: []corev1.EnvVar{
+							{Name: "SNAPSHOT_SOURCE", Value: refresh.Source},
+							{Name: "PROJECT_NAME", Value: product.ProductName},
+							{Name: "ENV_NAME", Value: product.EnvName},
+							{Name: "NAMESPACE", Value: product.Namespace},
+						},
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	if err := updater.CreateJob(job, kubeClient); err != nil {
+		return config.StatusFailed, fmt.Errorf("failed to create job: %s", err)
+	}
+
+	timeout := refresh.Timeout
+	if timeout <= 0 {
+		timeout = defaultEnvDataRefreshTimeout
+	}
+	status := config.StatusTimeout
+	err := wait.PollImmediate(5*time.Second, time.Duration(timeout)*time.Minute, func() (bool, error) {
+		job, found, err := getter.GetJob(product.Namespace, name, kubeClient)
+		if err != nil || !found {
+			return false, nil
+		}
+		if job.Status.Succeeded > 0 {
+			status = config.StatusPassed
+			return true, nil
+		}
+		if job.Status.Failed > 0 {
+			status = config.StatusFailed
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return status, fmt.Errorf("job did not finish in %d minutes", timeout)
+	}
+	if status == config.StatusFailed {
+		return status, fmt.Errorf("job %s failed, check the logs of its pod for details", name)
+	}
+	return status, nil
+}
+
+// buildEnvDataRefreshScript runs the mask hooks after the refresh script, the data is not usable
+// until all of them succeed.
+func buildEnvDataRefreshScript(refresh *commonmodels.EnvDataRefresh) string {
+	scripts := []string{"set -e", refresh.Script}
+	for _, hook := range refresh.MaskHooks {
+		scripts = append(scripts, fmt.Sprintf("echo \"running mask hook %s\"", hook.Name), hook.Script)
+	}
+	return strings.Join(scripts, "\n")
+}
+
+func handleEnvDataRefreshCronjob(refresh *commonmodels.EnvDataRefresh, schedules *commonmodels.ScheduleCtrl, log *zap.SugaredLogger) error {
+	if schedules == nil {
+		return nil
+	}
+	payload := &commonservice.CronjobPayload{
+		Name:        refresh.ID.Hex(),
+		ProductName: refresh.ProjectName,
+		JobType:     config.EnvDataRefreshCronjob,
+	}
+	if refresh.ScheduleEnabled {
+		for _, item := range schedules.Items {
+			item.EnvDataRefreshArgs = &commonmodels.EnvDataRefreshArgs{
+				ProjectName: refresh.ProjectName,
+				EnvName:     refresh.EnvName,
+				RefreshID:   refresh.ID.Hex(),
+			}
+		}
+		deleteList, err := workflowservice.UpdateCronjob(refresh.ID.Hex(), config.EnvDataRefreshCronjob, refresh.ProjectName, schedules, log)
+		if err != nil {
+			log.Errorf("Failed to update cronjob, the error is: %v", err)
+			return e.ErrUpsertCronjob.AddDesc(err.Error())
+		}
+		payload.Action = setting.TypeEnableCronjob
+		payload.DeleteList = deleteList
+		payload.JobList = schedules.Items
+	} else {
+		payload.Action = setting.TypeDisableCronjob
+	}
+
+	pl, _ := json.Marshal(payload)
+	if err := nsq.Publish(setting.TopicCronjob, pl); err != nil {
+		log.Errorf("Failed to publish to nsq topic: %s, the error is: %v", setting.TopicCronjob, err)
+		return e.ErrUpsertCronjob.AddDesc(err.Error())
+	}
+	return nil
+}
+
+func listEnvDataRefreshSchedules(refresh *commonmodels.EnvDataRefresh) (*commonmodels.ScheduleCtrl, error) {
+	jobs, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+		ParentName: refresh.ID.Hex(),
+		ParentType: config.EnvDataRefreshCronjob,
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*commonmodels.Schedule, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, &commonmodels.Schedule{
+			ID:                 job.ID,
+			Number:             job.Number,
+			Frequency:          job.Frequency,
+			Time:               job.Time,
+			MaxFailures:        job.MaxFailure,
+			EnvDataRefreshArgs: job.EnvDataRefreshArgs,
+			Type:               config.ScheduleType(job.JobType),
+			Cron:               job.Cron,
+			CatchUpPolicy:      job.CatchUpPolicy,
+			Enabled:            job.Enabled,
+		})
+	}
+	return &commonmodels.ScheduleCtrl{Enabled: refresh.ScheduleEnabled, Items: items}, nil
+}
+
+func findEnvDataRefresh(id, projectName, envName string) (*commonmodels.EnvDataRefresh, error) {
+	refresh, err := commonrepo.NewEnvDataRefreshColl().FindByID(id)
+	if err != nil || refresh.ProjectName != projectName || refresh.EnvName != envName {
+		return nil, fmt.Errorf("data refresh %s not found", id)
+	}
+	return refresh, nil
+}
+
+func validateEnvDataRefresh(args *commonmodels.EnvDataRefresh) error {
+	if args.Name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if args.Image == "" {
+		return fmt.Errorf("image is empty")
+	}
+	if strings.TrimSpace(args.Script) == "" {
+		return fmt.Errorf("script is empty")
+	}
+	for _, hook := range args.MaskHooks {
+		if strings.TrimSpace(hook.Script) == "" {
+			return fmt.Errorf("script of mask hook %s is empty", hook.Name)
+		}
+	}
+	for _, kv := range args.Credentials {
+		if kv.Key == "" {
+			return fmt.Errorf("credential key is empty")
+		}
+	}
+	if args.ScheduleEnabled && args.Schedules != nil {
+		for _, item := range args.Schedules.Items {
+			if err := item.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/dns"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+const (
+	envDNSSyncInterval = 5 * time.Minute
+	defaultDNSTTL      = 300
+)
+
+// dnsProviders creates the clients of the enabled dns providers once for a round of syncs.
+type dnsProviders struct {
+	configs []*commonmodels.DNSProvider
+	clients map[string]dns.Provider
+}
+
+func newDNSProviders() (*dnsProviders, error) {
+	configs, err := commonrepo.NewDNSProviderColl().List(true)
+	if err != nil {
+		return nil, err
+	}
+	return &dnsProviders{configs: configs, clients: map[string]dns.Provider{}}, nil
+}
+
+// match returns the provider whose domain is the longest suffix of the host, nil if the host is not managed.
+func (p *dnsProviders) match(host string) *commonmodels.DNSProvider {
+	var resp *commonmodels.DNSProvider
+	for _, c := range p.configs {
+		domain := strings.TrimSuffix(c.Domain, ".")
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if resp == nil || len(domain) > len(resp.Domain) {
+			resp = c
+		}
+	}
+	return resp
+}
+
+func (p *dnsProviders) find(id string) *commonmodels.DNSProvider {
+	for _, c := range p.configs {
+		if c.ID.Hex() == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (p *dnsProviders) client(ctx context.Context, c *commonmodels.DNSProvider) (dns.Provider, error) {
+	if client, ok := p.clients[c.ID.Hex()]; ok {
+		return client, nil
+	}
+	args := &dns.ProviderArgs{
+		Provider:    c.Provider,
+		Zone:        c.Zone,
+		Region:      c.Region,
+		Project:     c.Project,
+		AccessKeyID: c.AccessKeyID,
+	}
+	if c.Provider == dns.ProviderCloudDNS {
+		args.CredentialsJSON = c.Secret
+	} else {
+		args.AccessKeySecret = c.Secret
+	}
+	client, err := dns.NewProvider(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	p.clients[c.ID.Hex()] = client
+	return client, nil
+}
+
+// StartEnvDNSSync keeps the dns records of the ingress hosts of all the environments up to date, and removes
+// the records of the hosts and the environments which are gone.
+func StartEnvDNSSync(ctx context.Context, log *zap.SugaredLogger) {
+	ticker := time.NewTicker(envDNSSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			syncAllEnvDNS(ctx, log)
+		}
+	}
+}
+
+func syncAllEnvDNS(ctx context.Context, log *zap.SugaredLogger) {
+	providers, err := newDNSProviders()
+	if err != nil {
+		log.Errorf("failed to list dns providers, err: %s", err)
+		return
+	}
+	records, err := commonrepo.NewEnvDNSRecordColl().List("", "")
+	if err != nil {
+		log.Errorf("failed to list env dns records, err: %s", err)
+		return
+	}
+	if len(providers.configs) == 0 && len(records) == 0 {
+		return
+	}
+
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{})
+	if err != nil {
+		log.Errorf("failed to list envs, err: %s", err)
+		return
+	}
+	existing := map[string]bool{}
+	for _, env := range envs {
+		existing[env.ProductName+"/"+env.EnvName] = true
+		if env.Source == setting.SourceFromPM {
+			continue
+		}
+		if _, err := syncEnvDNS(ctx, env, providers, log); err != nil {
+			log.Warnf("failed to sync dns records of %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+
+	// the records of the deleted environments.
+	for _, record := range records {
+		if existing[record.ProjectName+"/"+record.EnvName] {
+			continue
+		}
+		if err := removeEnvDNSRecord(ctx, record, providers); err != nil {
+			log.Warnf("failed to remove dns record %s of deleted env %s/%s, err: %s", record.Host, record.ProjectName, record.EnvName, err)
+		}
+	}
+}
+
+func GetEnvDNSRecords(projectName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvDNSRecord, error) {
+	records, err := commonrepo.NewEnvDNSRecordColl().List(projectName, envName)
+	if err != nil {
+		log.Errorf("failed to list dns records of %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvDNSRecords.AddErr(err)
+	}
+	return records, nil
+}
+
+// SyncEnvDNSRecords syncs the dns records of the environment immediately instead of waiting for the next round.
+func SyncEnvDNSRecords(projectName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvDNSRecord, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName})
+	if err != nil {
+		return nil, e.ErrSyncEnvDNSRecords.AddErr(err)
+	}
+	if env.Source == setting.SourceFromPM {
+		return nil, e.ErrSyncEnvDNSRecords.AddDesc("the environment has no ingresses")
+	}
+	providers, err := newDNSProviders()
+	if err != nil {
+		return nil, e.ErrSyncEnvDNSRecords.AddErr(err)
+	}
+	records, err := syncEnvDNS(context.TODO(), env, providers, log)
+	if err != nil {
+		return nil, e.ErrSyncEnvDNSRecords.AddErr(err)
+	}
+	return records, nil
+}
+
+// envIngressTargets returns the addresses of the load balancers of the ingresses by their hosts.
+func envIngressTargets(env *commonmodels.Product) (map[string][]string, error) {
+	kubeCli, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	cliSet, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := cliSet.Discovery().ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	ingresses, err := getter.ListIngresses(env.Namespace, kubeCli, kubeclient.VersionLessThan122(version))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := map[string][]string{}
+	for _, ingress := range ingresses.Items {
+		addresses := []string{}
+		lbs, _, _ := unstructured.NestedSlice(ingress.Object, "status", "loadBalancer", "ingress")
+		for _, lb := range lbs {
+			lbMap, ok := lb.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if ip, _ := lbMap["ip"].(string); ip != "" {
+				addresses = append(addresses, ip)
+			} else if hostname, _ := lbMap["hostname"].(string); hostname != "" {
+				addresses = append(addresses, hostname)
+			}
+		}
+		rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+		for _, rule := range rules {
+			ruleMap, ok := rule.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			host, _ := ruleMap["host"].(string)
+			// the wildcard hosts are not supported by all the providers.
+			if host == "" || strings.HasPrefix(host, "*") {
+				continue
+			}
+			resp[strings.ToLower(host)] = append(resp[strings.ToLower(host)], addresses...)
+		}
+	}
+	return resp, nil
+}
+
+// recordValues returns the type and the values of the record of the host, the target of the provider
+// takes precedence over the addresses of the load balancers. A host name can only be the value of a CNAME
+// record, so the ip addresses are preferred if both are found.
+func recordValues(provider *commonmodels.DNSProvider, addresses []string) (string, []string) {
+	if provider.Target != "" {
+		return dns.RecordType(provider.Target), []string{provider.Target}
+	}
+	ips := map[string][]string{}
+	for _, address := range addresses {
+		recordType := dns.RecordType(address)
+		ips[recordType] = append(ips[recordType], address)
+	}
+	for _, recordType := range []string{dns.RecordTypeA, dns.RecordTypeAAAA} {
+		if values := ips[recordType]; len(values) > 0 {
+			sort.Strings(values)
+			return recordType, dedupe(values)
+		}
+	}
+	if values := ips[dns.RecordTypeCNAME]; len(values) > 0 {
+		sort.Strings(values)
+		return dns.RecordTypeCNAME, values[:1]
+	}
+	return "", nil
+}
+
+func dedupe(values []string) []string {
+	resp := make([]string, 0, len(values))
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			resp = append(resp, v)
+		}
+	}
+	return resp
+}
+
+func syncEnvDNS(ctx context.Context, env *commonmodels.Product, providers *dnsProviders, log *zap.SugaredLogger) ([]*commonmodels.EnvDNSRecord, error) {
+	current, err := commonrepo.NewEnvDNSRecordColl().List(env.ProductName, env.EnvName)
+	if err != nil {
+		return nil, err
+	}
+	currentMap := map[string]*commonmodels.EnvDNSRecord{}
+	for _, record := range current {
+		currentMap[record.Host] = record
+	}
+	// nothing to do if no host has been and can be managed.
+	if len(providers.configs) == 0 && len(current) == 0 {
+		return current, nil
+	}
+
+	targets, err := envIngressTargets(env)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*commonmodels.EnvDNSRecord, 0)
+	for host, addresses := range targets {
+		provider := providers.match(host)
+		if provider == nil {
+			continue
+		}
+		record := &commonmodels.EnvDNSRecord{ProjectName: env.ProductName, EnvName: env.EnvName, Host: host, ProviderID: provider.ID.Hex()}
+		if old, ok := currentMap[host]; ok {
+			delete(currentMap, host)
+			if old.ProviderID != provider.ID.Hex() {
+				// the host is moved to another provider.
+				if err := removeEnvDNSRecord(ctx, old, providers); err != nil {
+					log.Warnf("failed to remove dns record %s from the previous provider, err: %s", host, err)
+				}
+			} else {
+				record.Created, record.Type, record.Values = old.Created, old.Type, old.Values
+			}
+		}
+		upsertEnvDNSRecord(ctx, record, provider, addresses, providers)
+		if err := commonrepo.NewEnvDNSRecordColl().Upsert(record); err != nil {
+			return nil, err
+		}
+		resp = append(resp, record)
+	}
+
+	// the hosts removed from the ingresses.
+	for _, record := range currentMap {
+		if err := removeEnvDNSRecord(ctx, record, providers); err != nil {
+			log.Warnf("failed to remove dns record %s of %s/%s, err: %s", record.Host, record.ProjectName, record.EnvName, err)
+			resp = append(resp, record)
+		}
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].Host < resp[j].Host })
+	return resp, nil
+}
+
+// upsertEnvDNSRecord creates or updates the record of the host and its ownership TXT record. The records not
+// owned by the environment, i.e. created by hand, by other installations or for other environments, are never
+// changed, they are reported as conflicts instead.
+func upsertEnvDNSRecord(ctx context.Context, record *commonmodels.EnvDNSRecord, provider *commonmodels.DNSProvider, addresses []string, providers *dnsProviders) {
+	fail := func(status, format string, args ...interface{}) {
+		record.Status = status
+		record.Message = fmt.Sprintf(format, args...)
+	}
+
+	recordType, values := recordValues(provider, addresses)
+	if recordType == "" {
+		fail(commonmodels.EnvDNSRecordPending, "the load balancer of the ingress has no address yet")
+		return
+	}
+	client, err := providers.client(ctx, provider)
+	if err != nil {
+		fail(commonmodels.EnvDNSRecordFailed, "failed to create the client of dns provider %s: %s", provider.Name, err)
+		return
+	}
+
+	ownerValue := dns.OwnerValue(provider.OwnerID, record.ProjectName+"/"+record.EnvName)
+	ownerRecord, err := client.GetRecord(ctx, dns.OwnerRecordName(record.Host), dns.RecordTypeTXT)
+	if err != nil {
+		fail(commonmodels.EnvDNSRecordFailed, "failed to get the ownership record: %s", err)
+		return
+	}
+	if ownerRecord != nil && !dns.EqualValues(ownerRecord.Values, []string{ownerValue}) {
+		fail(commonmodels.EnvDNSRecordConflict, "the record is owned by %s", strings.Join(ownerRecord.Values, ","))
+		return
+	}
+	if ownerRecord == nil {
+		for _, t := range []string{dns.RecordTypeA, dns.RecordTypeAAAA, dns.RecordTypeCNAME} {
+			existing, err := client.GetRecord(ctx, record.Host, t)
+			if err != nil {
+				fail(commonmodels.EnvDNSRecordFailed, "failed to get the %s record: %s", t, err)
+				return
+			}
+			if existing != nil {
+				fail(commonmodels.EnvDNSRecordConflict, "the %s record exists and is not managed by zadig", t)
+				return
+			}
+		}
+	}
+
+	ttl := provider.TTL
+	if ttl <= 0 {
+		ttl = defaultDNSTTL
+	}
+	if ownerRecord == nil {
+		if err := client.UpsertRecord(ctx, &dns.Record{Name: dns.OwnerRecordName(record.Host), Type: dns.RecordTypeTXT, Values: []string{ownerValue}, TTL: ttl}); err != nil {
+			fail(commonmodels.EnvDNSRecordFailed, "failed to create the ownership record: %s", err)
+			return
+		}
+	}
+	record.Created = true
+	// the record of the previous type is replaced, e.g. when the load balancer gets an ip address.
+	if record.Type != "" && record.Type != recordType {
+		if err := client.DeleteRecord(ctx, record.Host, record.Type); err != nil {
+			fail(commonmodels.EnvDNSRecordFailed, "failed to delete the %s record: %s", record.Type, err)
+			return
+		}
+	}
+	record.Type, record.Values = recordType, values
+
+	existing, err := client.GetRecord(ctx, record.Host, recordType)
+	if err != nil {
+		fail(commonmodels.EnvDNSRecordFailed, "failed to get the %s record: %s", recordType, err)
+		return
+	}
+	if existing == nil || existing.TTL != ttl || !dns.EqualValues(existing.Values, values) {
+		if err := client.UpsertRecord(ctx, &dns.Record{Name: record.Host, Type: recordType, Values: values, TTL: ttl}); err != nil {
+			fail(commonmodels.EnvDNSRecordFailed, "failed to update the %s record: %s", recordType, err)
+			return
+		}
+	}
+	record.Status, record.Message = commonmodels.EnvDNSRecordSynced, ""
+}
+
+// removeEnvDNSRecord deletes the record and its ownership TXT record if they are created by zadig for the
+// environment, and forgets the record.
+func removeEnvDNSRecord(ctx context.Context, record *commonmodels.EnvDNSRecord, providers *dnsProviders) error {
+	provider := providers.find(record.ProviderID)
+	if record.Created && provider != nil {
+		client, err := providers.client(ctx, provider)
+		if err != nil {
+			return err
+		}
+		ownerValue := dns.OwnerValue(provider.OwnerID, record.ProjectName+"/"+record.EnvName)
+		ownerRecord, err := client.GetRecord(ctx, dns.OwnerRecordName(record.Host), dns.RecordTypeTXT)
+		if err != nil {
+			return err
+		}
+		if ownerRecord != nil && dns.EqualValues(ownerRecord.Values, []string{ownerValue}) {
+			if record.Type != "" {
+				if err := client.DeleteRecord(ctx, record.Host, record.Type); err != nil {
+					return err
+				}
+			}
+			if err := client.DeleteRecord(ctx, dns.OwnerRecordName(record.Host), dns.RecordTypeTXT); err != nil {
+				return err
+			}
+		}
+	}
+	return commonrepo.NewEnvDNSRecordColl().Delete(record.ProjectName, record.EnvName, record.Host)
+}
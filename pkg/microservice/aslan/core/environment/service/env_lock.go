@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	defaultEnvLockTTL = 60
+	maxEnvLockTTL     = 24 * 60
+)
+
+type LockEnvArgs struct {
+	Reason string `json:"reason"`
+	// TTL is the minutes the lock is held, the lock is released automatically after that.
+	TTL int64 `json:"ttl"`
+}
+
+// GetEnvLock returns the lock of the env, nil is returned if the env is not locked.
+func GetEnvLock(projectName, envName string, log *zap.SugaredLogger) (*commonmodels.EnvLock, error) {
+	lock, err := commonrepo.NewEnvLockColl().Find(projectName, envName)
+	if err != nil {
+		log.Errorf("failed to find the lock of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvLock.AddErr(err)
+	}
+	return lock, nil
+}
+
+// LockEnv locks the env manually so that the deploy jobs of the workflows wait until it is unlocked,
+// the lock is extended if it is held by the user already.
+func LockEnv(userName, projectName, envName string, args *LockEnvArgs, log *zap.SugaredLogger) (*commonmodels.EnvLock, error) {
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName}); err != nil {
+		return nil, e.ErrLockEnv.AddErr(err)
+	}
+	if args.TTL == 0 {
+		args.TTL = defaultEnvLockTTL
+	}
+	if args.TTL < 0 || args.TTL > maxEnvLockTTL {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("ttl must be between 1 and %d minutes", maxEnvLockTTL))
+	}
+
+	lock := &commonmodels.EnvLock{
+		ProjectName: projectName,
+		EnvName:     envName,
+		Owner:       commonmodels.ManualEnvLockOwner(userName),
+		Kind:        commonmodels.EnvLockKindManual,
+		LockedBy:    userName,
+		Reason:      args.Reason,
+	}
+	ok, err := commonrepo.NewEnvLockColl().Acquire(lock, time.Duration(args.TTL)*time.Minute)
+	if err != nil {
+		log.Errorf("failed to lock env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrLockEnv.AddErr(err)
+	}
+	if !ok {
+		return nil, e.ErrEnvLocked.AddDesc(describeEnvLock(projectName, envName))
+	}
+	return GetEnvLock(projectName, envName, log)
+}
+
+// UnlockEnv releases the manual lock of the env, the locks of the running workflow tasks are released
+// by the tasks themselves.
+func UnlockEnv(userName, projectName, envName string, log *zap.SugaredLogger) error {
+	lock, err := commonrepo.NewEnvLockColl().Find(projectName, envName)
+	if err != nil {
+		return e.ErrUnlockEnv.AddErr(err)
+	}
+	if lock == nil {
+		return nil
+	}
+	if lock.Kind == commonmodels.EnvLockKindWorkflow {
+		return e.ErrUnlockEnv.AddDesc(fmt.Sprintf("the env is locked by the running task %s#%d", lock.WorkflowName, lock.TaskID))
+	}
+	if err := commonrepo.NewEnvLockColl().Release(projectName, envName, lock.Owner); err != nil {
+		log.Errorf("failed to unlock env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUnlockEnv.AddErr(err)
+	}
+	log.Infof("env %s/%s locked by %s is unlocked by %s", projectName, envName, lock.LockedBy, userName)
+	return nil
+}
+
+func describeEnvLock(projectName, envName string) string {
+	lock, err := commonrepo.NewEnvLockColl().Find(projectName, envName)
+	if err != nil || lock == nil {
+		return "the env is locked by someone else"
+	}
+	since := time.Since(time.Unix(lock.LockTime, 0)).Round(time.Second)
+	if lock.Kind == commonmodels.EnvLockKindWorkflow {
+		return fmt.Sprintf("the env is locked by the task %s#%d of %s for %s", lock.WorkflowName, lock.TaskID, lock.LockedBy, since)
+	}
+	return fmt.Sprintf("the env is locked by %s for %s", lock.LockedBy, since)
+}
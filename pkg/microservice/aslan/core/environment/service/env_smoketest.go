@@ -0,0 +1,418 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/nsq"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	testingservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/service"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	defaultEnvSmokeTestTimeout = 60
+	envSmokeTestRecordLimit    = 50
+)
+
+// EnvSmokeTestHealth is the part of the environment health reported by the smoke tests, the smoke
+// tests which have never run are ignored.
+type EnvSmokeTestHealth struct {
+	Healthy   bool     `json:"healthy"`
+	Failed    []string `json:"failed"`
+	CheckedAt int64    `json:"checked_at"`
+}
+
+func ListEnvSmokeTests(projectName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvSmokeTest, error) {
+	smokeTests, err := commonrepo.NewEnvSmokeTestColl().List(projectName, envName)
+	if err != nil {
+		log.Errorf("failed to list smoke tests of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrListEnvSmokeTest.AddErr(err)
+	}
+	for _, smokeTest := range smokeTests {
+		schedules, err := listEnvSmokeTestSchedules(smokeTest)
+		if err != nil {
+			log.Errorf("failed to list schedules of smoke test %s, err: %s", smokeTest.Name, err)
+			return nil, e.ErrListEnvSmokeTest.AddErr(err)
+		}
+		smokeTest.Schedules = schedules
+	}
+	return smokeTests, nil
+}
+
+func CreateEnvSmokeTest(userName string, args *commonmodels.EnvSmokeTest, log *zap.SugaredLogger) error {
+	if err := validateEnvSmokeTest(args); err != nil {
+		return e.ErrCreateEnvSmokeTest.AddErr(err)
+	}
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: args.ProjectName, EnvName: args.EnvName}); err != nil {
+		return e.ErrCreateEnvSmokeTest.AddDesc(fmt.Sprintf("env %s not found", args.EnvName))
+	}
+	if _, err := commonrepo.NewEnvSmokeTestColl().Find(args.ProjectName, args.EnvName, args.Name); err == nil {
+		return e.ErrCreateEnvSmokeTest.AddDesc(fmt.Sprintf("smoke test %s already exists", args.Name))
+	}
+
+	schedules := args.Schedules
+	args.ID = primitive.NilObjectID
+	args.CreatedBy = userName
+	args.UpdatedBy = userName
+	args.LastStatus = ""
+	args.LastRunTime = 0
+	args.ConsecutiveFailures = 0
+	args.Alerting = false
+	if err := commonrepo.NewEnvSmokeTestColl().Create(args); err != nil {
+		log.Errorf("failed to create smoke test %s, err: %s", args.Name, err)
+		return e.ErrCreateEnvSmokeTest.AddErr(err)
+	}
+	return handleEnvSmokeTestCronjob(args, schedules, log)
+}
+
+func UpdateEnvSmokeTest(userName, id string, args *commonmodels.EnvSmokeTest, log *zap.SugaredLogger) error {
+	smokeTest, err := findEnvSmokeTest(id, args.ProjectName, args.EnvName)
+	if err != nil {
+		return e.ErrUpdateEnvSmokeTest.AddErr(err)
+	}
+	if err := validateEnvSmokeTest(args); err != nil {
+		return e.ErrUpdateEnvSmokeTest.AddErr(err)
+	}
+
+	schedules := args.Schedules
+	args.ID = smokeTest.ID
+	args.Name = smokeTest.Name
+	args.UpdatedBy = userName
+	if err := commonrepo.NewEnvSmokeTestColl().Update(args); err != nil {
+		log.Errorf("failed to update smoke test %s, err: %s", smokeTest.Name, err)
+		return e.ErrUpdateEnvSmokeTest.AddErr(err)
+	}
+	return handleEnvSmokeTestCronjob(args, schedules, log)
+}
+
+func DeleteEnvSmokeTest(id, projectName, envName string, log *zap.SugaredLogger) error {
+	smokeTest, err := findEnvSmokeTest(id, projectName, envName)
+	if err != nil {
+		return e.ErrDeleteEnvSmokeTest.AddErr(err)
+	}
+
+	if err := workflowservice.DeleteCronjob(smokeTest.ID.Hex(), config.EnvSmokeTestCronjob); err != nil {
+		log.Errorf("failed to delete cronjobs of smoke test %s, err: %s", smokeTest.Name, err)
+		return e.ErrDeleteEnvSmokeTest.AddErr(err)
+	}
+	pl, _ := json.Marshal(&commonservice.CronjobPayload{
+		Name:        smokeTest.ID.Hex(),
+		ProductName: smokeTest.ProjectName,
+		JobType:     config.EnvSmokeTestCronjob,
+		Action:      setting.TypeDisableCronjob,
+	})
+	if err := nsq.Publish(setting.TopicCronjob, pl); err != nil {
+		log.Errorf("Failed to publish to nsq topic: %s, the error is: %v", setting.TopicCronjob, err)
+		return e.ErrDeleteEnvSmokeTest.AddErr(err)
+	}
+
+	if err := commonrepo.NewEnvSmokeTestColl().Delete(smokeTest.ID); err != nil {
+		log.Errorf("failed to delete smoke test %s, err: %s", smokeTest.Name, err)
+		return e.ErrDeleteEnvSmokeTest.AddErr(err)
+	}
+	if err := commonrepo.NewEnvSmokeTestRecordColl().DeleteBySmokeTestID(smokeTest.ID.Hex()); err != nil {
+		log.Warnf("failed to delete records of smoke test %s, err: %s", smokeTest.Name, err)
+	}
+	return nil
+}
+
+func ListEnvSmokeTestRecords(id, projectName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvSmokeTestRecord, error) {
+	smokeTest, err := findEnvSmokeTest(id, projectName, envName)
+	if err != nil {
+		return nil, e.ErrListEnvSmokeTest.AddErr(err)
+	}
+	records, err := commonrepo.NewEnvSmokeTestRecordColl().List(smokeTest.ID.Hex(), envSmokeTestRecordLimit)
+	if err != nil {
+		log.Errorf("failed to list records of smoke test %s, err: %s", smokeTest.Name, err)
+		return nil, e.ErrListEnvSmokeTest.AddErr(err)
+	}
+	return records, nil
+}
+
+// RunEnvSmokeTest starts a task of the testing module against the env, the result is saved in the
+// record and the smoke test when the task finishes.
+func RunEnvSmokeTest(id, projectName, envName, triggeredBy string, log *zap.SugaredLogger) (*commonmodels.EnvSmokeTestRecord, error) {
+	smokeTest, err := findEnvSmokeTest(id, projectName, envName)
+	if err != nil {
+		return nil, e.ErrRunEnvSmokeTest.AddErr(err)
+	}
+	timeout := smokeTest.Timeout
+	if timeout <= 0 {
+		timeout = defaultEnvSmokeTestTimeout
+	}
+	if running, err := commonrepo.NewEnvSmokeTestRecordColl().FindRunning(smokeTest.ID.Hex()); err == nil {
+		// the run is lost if aslan restarts while waiting for the task.
+		if time.Now().Unix() < running.StartTime+timeout*60 {
+			return nil, e.ErrRunEnvSmokeTest.AddDesc(fmt.Sprintf("smoke test %s is running", smokeTest.Name))
+		}
+		running.Status = config.StatusTimeout
+		running.Error = fmt.Sprintf("task did not finish in %d minutes", timeout)
+		running.EndTime = time.Now().Unix()
+		if err := commonrepo.NewEnvSmokeTestRecordColl().Update(running); err != nil {
+			log.Errorf("failed to update record of smoke test %s, err: %s", smokeTest.Name, err)
+		}
+	}
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName}); err != nil {
+		return nil, e.ErrRunEnvSmokeTest.AddDesc(fmt.Sprintf("env %s not found", envName))
+	}
+
+	task, err := testingservice.CreateTestTask(&commonmodels.TestTaskArgs{
+		ProductName:     projectName,
+		TestName:        smokeTest.TestName,
+		TestTaskCreator: triggeredBy,
+		EnvName:         envName,
+	}, log)
+	if err != nil {
+		log.Errorf("failed to create test task of smoke test %s, err: %s", smokeTest.Name, err)
+		return nil, e.ErrRunEnvSmokeTest.AddErr(err)
+	}
+
+	record := &commonmodels.EnvSmokeTestRecord{
+		SmokeTestID:  smokeTest.ID.Hex(),
+		ProjectName:  projectName,
+		EnvName:      envName,
+		TestName:     smokeTest.TestName,
+		PipelineName: task.PipelineName,
+		TaskID:       task.TaskID,
+		Status:       config.StatusRunning,
+		TriggeredBy:  triggeredBy,
+		StartTime:    time.Now().Unix(),
+	}
+	if err := commonrepo.NewEnvSmokeTestRecordColl().Create(record); err != nil {
+		log.Errorf("failed to create record of smoke test %s, err: %s", smokeTest.Name, err)
+		return nil, e.ErrRunEnvSmokeTest.AddErr(err)
+	}
+
+	resp := *record
+	go func() {
+		status, err := waitEnvSmokeTestTask(record, timeout)
+		record.Status = status
+		if err != nil {
+			log.Errorf("smoke test %s of env %s/%s finished with status %s, err: %s", smokeTest.Name, projectName, envName, record.Status, err)
+			record.Error = err.Error()
+		}
+		record.EndTime = time.Now().Unix()
+		if err := commonrepo.NewEnvSmokeTestRecordColl().Update(record); err != nil {
+			log.Errorf("failed to update record of smoke test %s, err: %s", smokeTest.Name, err)
+		}
+		updateEnvSmokeTestResult(smokeTest.ID.Hex(), record, log)
+	}()
+	return &resp, nil
+}
+
+func waitEnvSmokeTestTask(record *commonmodels.EnvSmokeTestRecord, timeout int64) (config.Status, error) {
+	status := config.StatusTimeout
+	err := wait.PollImmediate(10*time.Second, time.Duration(timeout)*time.Minute, func() (bool, error) {
+		task, err := commonrepo.NewTaskColl().Find(record.TaskID, record.PipelineName, config.TestType)
+		if err != nil {
+			return false, nil
+		}
+		switch task.Status {
+		case config.StatusPassed, config.StatusFailed, config.StatusTimeout, config.StatusCancelled:
+			status = task.Status
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return status, fmt.Errorf("task %s#%d did not finish in %d minutes", record.PipelineName, record.TaskID, timeout)
+	}
+	if status != config.StatusPassed {
+		return status, fmt.Errorf("task %s#%d is %s", record.PipelineName, record.TaskID, status)
+	}
+	return status, nil
+}
+
+// updateEnvSmokeTestResult saves the result of the run and notifies the receivers of the alert rule
+// when the smoke test starts failing or recovers.
+func updateEnvSmokeTestResult(id string, record *commonmodels.EnvSmokeTestRecord, log *zap.SugaredLogger) {
+	// the smoke test may have been changed while the task was running.
+	smokeTest, err := commonrepo.NewEnvSmokeTestColl().FindByID(id)
+	if err != nil {
+		log.Warnf("failed to find smoke test %s, err: %s", id, err)
+		return
+	}
+	smokeTest.LastStatus = record.Status
+	smokeTest.LastRunTime = record.EndTime
+
+	rule := smokeTest.AlertRule
+	receivers := []string{smokeTest.UpdatedBy}
+	if rule != nil && len(rule.Receivers) > 0 {
+		receivers = rule.Receivers
+	}
+	if record.Status == config.StatusPassed {
+		if smokeTest.Alerting && rule != nil && rule.Enabled && rule.NotifyRecovery {
+			title := fmt.Sprintf("环境 %s 冒烟测试 %s 已恢复", smokeTest.EnvName, smokeTest.Name)
+			content := fmt.Sprintf("%s, 项目：%s, 测试：%s", title, smokeTest.ProjectName, smokeTest.TestName)
+			for _, receiver := range receivers {
+				commonservice.SendMessage(receiver, title, content, "", log)
+			}
+		}
+		smokeTest.ConsecutiveFailures = 0
+		smokeTest.Alerting = false
+	} else {
+		smokeTest.ConsecutiveFailures++
+		threshold := 1
+		if rule != nil && rule.FailureThreshold > 0 {
+			threshold = rule.FailureThreshold
+		}
+		if rule != nil && rule.Enabled && !smokeTest.Alerting && smokeTest.ConsecutiveFailures >= threshold {
+			title := fmt.Sprintf("环境 %s 冒烟测试 %s 失败", smokeTest.EnvName, smokeTest.Name)
+			content := fmt.Sprintf("%s, 项目：%s, 测试：%s, 已连续失败 %d 次, 最近一次任务：%s#%d, 原因：%s",
+				title, smokeTest.ProjectName, smokeTest.TestName, smokeTest.ConsecutiveFailures, record.PipelineName, record.TaskID, record.Error)
+			for _, receiver := range receivers {
+				commonservice.SendMessage(receiver, title, content, "", log)
+			}
+			smokeTest.Alerting = true
+		}
+	}
+	if err := commonrepo.NewEnvSmokeTestColl().UpdateResult(smokeTest); err != nil {
+		log.Errorf("failed to update result of smoke test %s, err: %s", smokeTest.Name, err)
+	}
+}
+
+// GetEnvSmokeTestHealth returns nil if no smoke test of the env has run.
+func GetEnvSmokeTestHealth(projectName, envName string) (*EnvSmokeTestHealth, error) {
+	smokeTests, err := commonrepo.NewEnvSmokeTestColl().List(projectName, envName)
+	if err != nil {
+		return nil, err
+	}
+	var resp *EnvSmokeTestHealth
+	for _, smokeTest := range smokeTests {
+		if smokeTest.LastStatus == "" {
+			continue
+		}
+		if resp == nil {
+			resp = &EnvSmokeTestHealth{Healthy: true, Failed: []string{}}
+		}
+		if smokeTest.LastStatus != config.StatusPassed {
+			resp.Healthy = false
+			resp.Failed = append(resp.Failed, smokeTest.Name)
+		}
+		if smokeTest.LastRunTime > resp.CheckedAt {
+			resp.CheckedAt = smokeTest.LastRunTime
+		}
+	}
+	return resp, nil
+}
+
+func handleEnvSmokeTestCronjob(smokeTest *commonmodels.EnvSmokeTest, schedules *commonmodels.ScheduleCtrl, log *zap.SugaredLogger) error {
+	if schedules == nil {
+		return nil
+	}
+	payload := &commonservice.CronjobPayload{
+		Name:        smokeTest.ID.Hex(),
+		ProductName: smokeTest.ProjectName,
+		JobType:     config.EnvSmokeTestCronjob,
+	}
+	if smokeTest.ScheduleEnabled {
+		for _, item := range schedules.Items {
+			item.EnvSmokeTestArgs = &commonmodels.EnvSmokeTestArgs{
+				ProjectName: smokeTest.ProjectName,
+				EnvName:     smokeTest.EnvName,
+				SmokeTestID: smokeTest.ID.Hex(),
+			}
+		}
+		deleteList, err := workflowservice.UpdateCronjob(smokeTest.ID.Hex(), config.EnvSmokeTestCronjob, smokeTest.ProjectName, schedules, log)
+		if err != nil {
+			log.Errorf("Failed to update cronjob, the error is: %v", err)
+			return e.ErrUpsertCronjob.AddDesc(err.Error())
+		}
+		payload.Action = setting.TypeEnableCronjob
+		payload.DeleteList = deleteList
+		payload.JobList = schedules.Items
+	} else {
+		payload.Action = setting.TypeDisableCronjob
+	}
+
+	pl, _ := json.Marshal(payload)
+	if err := nsq.Publish(setting.TopicCronjob, pl); err != nil {
+		log.Errorf("Failed to publish to nsq topic: %s, the error is: %v", setting.TopicCronjob, err)
+		return e.ErrUpsertCronjob.AddDesc(err.Error())
+	}
+	return nil
+}
+
+func listEnvSmokeTestSchedules(smokeTest *commonmodels.EnvSmokeTest) (*commonmodels.ScheduleCtrl, error) {
+	jobs, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+		ParentName: smokeTest.ID.Hex(),
+		ParentType: config.EnvSmokeTestCronjob,
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*commonmodels.Schedule, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, &commonmodels.Schedule{
+			ID:               job.ID,
+			Number:           job.Number,
+			Frequency:        job.Frequency,
+			Time:             job.Time,
+			MaxFailures:      job.MaxFailure,
+			EnvSmokeTestArgs: job.EnvSmokeTestArgs,
+			Type:             config.ScheduleType(job.JobType),
+			Cron:             job.Cron,
+			CatchUpPolicy:    job.CatchUpPolicy,
+			Enabled:          job.Enabled,
+		})
+	}
+	return &commonmodels.ScheduleCtrl{Enabled: smokeTest.ScheduleEnabled, Items: items}, nil
+}
+
+func findEnvSmokeTest(id, projectName, envName string) (*commonmodels.EnvSmokeTest, error) {
+	smokeTest, err := commonrepo.NewEnvSmokeTestColl().FindByID(id)
+	if err != nil || smokeTest.ProjectName != projectName || smokeTest.EnvName != envName {
+		return nil, fmt.Errorf("smoke test %s not found", id)
+	}
+	return smokeTest, nil
+}
+
+func validateEnvSmokeTest(args *commonmodels.EnvSmokeTest) error {
+	if args.Name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if args.TestName == "" {
+		return fmt.Errorf("test name is empty")
+	}
+	if _, err := commonrepo.NewTestingColl().Find(args.TestName, args.ProjectName); err != nil {
+		return fmt.Errorf("test %s not found", args.TestName)
+	}
+	if args.AlertRule != nil && args.AlertRule.FailureThreshold < 0 {
+		return fmt.Errorf("failure threshold of the alert rule can not be negative")
+	}
+	if args.ScheduleEnabled && args.Schedules != nil {
+		for _, item := range args.Schedules.Items {
+			if err := item.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/informers"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/informer"
+)
+
+// envStatusTTL bounds the age of a cached environment status. The workloads and pods are
+// watched by the informers, the other parts of the status, e.g. the ingresses and the
+// service templates, are refreshed within it.
+const envStatusTTL = time.Minute
+
+type envStatus struct {
+	generation uint64
+	updateTime int64
+	expireAt   time.Time
+	services   []*commonservice.ServiceResp
+}
+
+// envStatusCache keeps the aggregated service status of the environments, an entry is used
+// as long as nothing watched in the namespace and the environment itself has changed.
+var envStatusCache sync.Map
+
+func envStatusKey(productName, envName, serviceName string) string {
+	return fmt.Sprintf("%s/%s/%s", productName, envName, serviceName)
+}
+
+func getCachedEnvStatus(key string, generation uint64, product *commonmodels.Product) ([]*commonservice.ServiceResp, bool) {
+	value, ok := envStatusCache.Load(key)
+	if !ok {
+		return nil, false
+	}
+	status := value.(*envStatus)
+	if status.generation != generation || status.updateTime != product.UpdateTime || time.Now().After(status.expireAt) {
+		envStatusCache.Delete(key)
+		return nil, false
+	}
+	return status.services, true
+}
+
+// setCachedEnvStatus saves the status computed from the informer, generation must be read
+// before the computation so that the changes during it invalidate the entry.
+func setCachedEnvStatus(key string, generation uint64, product *commonmodels.Product, services []*commonservice.ServiceResp) {
+	envStatusCache.Store(key, &envStatus{
+		generation: generation,
+		updateTime: product.UpdateTime,
+		expireAt:   time.Now().Add(envStatusTTL),
+		services:   services,
+	})
+}
+
+// getEnvInformer returns the shared informer of the namespace and the server version of the
+// cluster, the cluster is only requested when the informer is not started yet.
+func getEnvInformer(clusterID, namespace string) (informers.SharedInformerFactory, *version.Info, error) {
+	if inf, versionInfo, ok := informer.Lookup(clusterID, namespace); ok {
+		return inf, versionInfo, nil
+	}
+
+	cls, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), clusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	inf, err := informer.NewInformer(clusterID, namespace, cls)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, versionInfo, ok := informer.Lookup(clusterID, namespace); ok {
+		return inf, versionInfo, nil
+	}
+	versionInfo, err := cls.Discovery().ServerVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+	return inf, versionInfo, nil
+}
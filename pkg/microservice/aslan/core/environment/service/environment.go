@@ -141,6 +141,13 @@ type ProductResp struct {
 	ShareEnvEnable  bool   `json:"share_env_enable"`
 	ShareEnvIsBase  bool   `json:"share_env_is_base"`
 	ShareEnvBaseEnv string `json:"share_env_base_env"`
+
+	RequireDiffApproval bool `json:"require_diff_approval"`
+	AutoRollback        bool `json:"auto_rollback"`
+
+	NamespaceMeta         *commonmodels.NamespaceMeta `json:"namespace_meta,omitempty"`
+	ClusterSharedServices []string                    `json:"cluster_shared_services,omitempty"`
+	SmokeTest             *EnvSmokeTestHealth         `json:"smoke_test,omitempty"`
 }
 
 type ProductParams struct {
@@ -199,6 +206,7 @@ type RawYamlResp struct {
 
 type ReleaseInstallParam struct {
 	ProductName  string
+	EnvName      string
 	Namespace    string
 	ReleaseName  string
 	MergedValues string
@@ -744,7 +752,7 @@ func UpdateProductRegistry(envName, productName, registryID string, log *zap.Sug
 	if err != nil {
 		return e.ErrUpdateEnv.AddErr(err)
 	}
-	err = ensureKubeEnv(exitedProd.Namespace, registryID, map[string]string{setting.ProductLabel: productName}, false, kubeClient, log)
+	err = ensureKubeEnv(exitedProd.Namespace, registryID, map[string]string{setting.ProductLabel: productName}, false, exitedProd.NamespaceMeta, kubeClient, log)
 
 	if err != nil {
 		log.Errorf("UpdateProductRegistry ensureKubeEnv by envName:%s,error: %v", envName, err)
@@ -805,7 +813,7 @@ func UpdateProductV2(envName, productName, user, requestID string, serviceNames
 	}
 
 	if project.ProductFeature != nil && project.ProductFeature.BasicFacility != setting.BasicFacilityCVM {
-		err = ensureKubeEnv(exitedProd.Namespace, exitedProd.RegistryID, map[string]string{setting.ProductLabel: project.ProductName}, exitedProd.ShareEnv.Enable, kubeClient, log)
+		err = ensureKubeEnv(exitedProd.Namespace, exitedProd.RegistryID, map[string]string{setting.ProductLabel: project.ProductName}, exitedProd.ShareEnv.Enable, exitedProd.NamespaceMeta, kubeClient, log)
 
 		if err != nil {
 			log.Errorf("[%s][P:%s] service.UpdateProductV2 create kubeEnv error: %v", envName, productName, err)
@@ -990,6 +998,9 @@ func prepareHelmProductCreation(templateProduct *templatemodels.Product, product
 }
 
 func CreateHelmProduct(productName, userName, requestID string, args []*CreateHelmProductArg, log *zap.SugaredLogger) error {
+	if err := commonservice.CheckEnvironmentQuota(productName, len(args), log); err != nil {
+		return err
+	}
 	templateProduct, err := templaterepo.NewProductColl().Find(productName)
 	if err != nil || templateProduct == nil {
 		if err != nil {
@@ -1177,6 +1188,9 @@ func CopyYamlProduct(user, requestID string, args *commonmodels.Product, log *za
 	if len(args.BaseEnvName) == 0 {
 		return e.ErrCreateEnv.AddDesc("base environment name can't be nil")
 	}
+	if err := commonservice.CheckEnvironmentQuota(args.ProductName, 1, log); err != nil {
+		return err
+	}
 	baseProject, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
 		Name:    args.ProductName,
 		EnvName: args.BaseEnvName,
@@ -1202,11 +1216,17 @@ func CopyYamlProduct(user, requestID string, args *commonmodels.Product, log *za
 // CreateProduct create a new product with its dependent stacks
 func CreateProduct(user, requestID string, args *commonmodels.Product, log *zap.SugaredLogger) (err error) {
 	log.Infof("[%s][P:%s] CreateProduct", args.EnvName, args.ProductName)
+	if err := commonservice.CheckEnvironmentQuota(args.ProductName, 1, log); err != nil {
+		return err
+	}
 	creator := getCreatorBySource(args.Source)
 	return creator.Create(user, requestID, args, log)
 }
 
 func CopyHelmProduct(productName, userName, requestID string, args []*CreateHelmProductArg, log *zap.SugaredLogger) error {
+	if err := commonservice.CheckEnvironmentQuota(productName, len(args), log); err != nil {
+		return err
+	}
 	errList := new(multierror.Error)
 	templateProduct, err := templaterepo.NewProductColl().Find(productName)
 	if err != nil || templateProduct == nil {
@@ -1325,6 +1345,75 @@ func UpdateProductRecycleDay(envName, productName string, recycleDay int) error
 	return commonrepo.NewProductColl().UpdateProductRecycleDay(envName, productName, recycleDay)
 }
 
+func UpdateProductDiffApproval(envName, productName string, required bool) error {
+	return commonrepo.NewProductColl().UpdateRequireDiffApproval(envName, productName, required)
+}
+
+func UpdateProductAutoRollback(envName, productName string, enabled bool) error {
+	return commonrepo.NewProductColl().UpdateAutoRollback(envName, productName, enabled)
+}
+
+// UpdateClusterSharedServices updates the shared services of the cluster which the env depends on.
+func UpdateClusterSharedServices(envName, productName string, names []string, log *zap.SugaredLogger) error {
+	product, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		return e.ErrUpdateEnv.AddErr(err)
+	}
+	if err := validateClusterSharedServices(product.ClusterID, names); err != nil {
+		return e.ErrUpdateEnv.AddDesc(err.Error())
+	}
+	if err := commonrepo.NewProductColl().UpdateClusterSharedServices(envName, productName, names); err != nil {
+		log.Errorf("failed to update shared services of env %s/%s, err: %s", productName, envName, err)
+		return e.ErrUpdateEnv.AddErr(err)
+	}
+	return nil
+}
+
+// SyncRegistrySecrets recreates the image pull secrets in the namespace of the env from the current
+// registry credentials.
+func SyncRegistrySecrets(envName, productName string, log *zap.SugaredLogger) error {
+	product, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		return e.ErrUpdateSecret.AddErr(err)
+	}
+	if err := commonservice.SyncEnvRegistrySecrets(product, log); err != nil {
+		log.Errorf("failed to sync the registry secrets of env %s/%s, err: %s", productName, envName, err)
+		return e.ErrUpdateSecret.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateNamespaceMeta updates the labels and annotations declared for the namespace of the env and applies them.
+func UpdateNamespaceMeta(envName, productName string, meta *commonmodels.NamespaceMeta, log *zap.SugaredLogger) error {
+	if err := kube.ValidateNamespaceMeta(meta); err != nil {
+		return e.ErrUpdateNamespaceMeta.AddErr(err)
+	}
+	product, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		return e.ErrUpdateNamespaceMeta.AddErr(err)
+	}
+	project, err := templaterepo.NewProductColl().Find(productName)
+	if err != nil {
+		return e.ErrUpdateNamespaceMeta.AddErr(err)
+	}
+	// envs of the projects on cloud hosts have no namespace.
+	if preCreateNSAndSecret(project.ProductFeature) {
+		kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), product.ClusterID)
+		if err != nil {
+			return e.ErrUpdateNamespaceMeta.AddErr(err)
+		}
+		if err := kube.EnsureNamespaceMeta(product.Namespace, meta, kubeClient); err != nil {
+			log.Errorf("failed to apply namespace meta of env %s/%s, err: %s", productName, envName, err)
+			return e.ErrUpdateNamespaceMeta.AddErr(err)
+		}
+	}
+	if err := commonrepo.NewProductColl().UpdateNamespaceMeta(envName, productName, meta); err != nil {
+		log.Errorf("failed to update namespace meta of env %s/%s, err: %s", productName, envName, err)
+		return e.ErrUpdateNamespaceMeta.AddErr(err)
+	}
+	return nil
+}
+
 func buildContainerMap(cs []*models.Container) map[string]*models.Container {
 	containerMap := make(map[string]*models.Container)
 	for _, c := range cs {
@@ -1644,7 +1733,7 @@ func UpdateHelmProductDefaultValues(productName, envName, userName, requestID st
 		log.Errorf("UpdateHelmProductRenderset GetKubeClient error, error msg:%s", err)
 		return err
 	}
-	return ensureKubeEnv(product.Namespace, product.RegistryID, map[string]string{setting.ProductLabel: product.ProductName}, false, kubeClient, log)
+	return ensureKubeEnv(product.Namespace, product.RegistryID, map[string]string{setting.ProductLabel: product.ProductName}, false, product.NamespaceMeta, kubeClient, log)
 }
 
 func UpdateHelmProductCharts(productName, envName, userName, requestID string, args *EnvRendersetArg, log *zap.SugaredLogger) error {
@@ -1874,7 +1963,7 @@ func UpdateHelmProductRenderset(productName, envName, userName, requestID string
 		log.Errorf("UpdateHelmProductRenderset GetKubeClient error, error msg:%s", err)
 		return err
 	}
-	return ensureKubeEnv(product.Namespace, product.RegistryID, map[string]string{setting.ProductLabel: product.ProductName}, false, kubeClient, log)
+	return ensureKubeEnv(product.Namespace, product.RegistryID, map[string]string{setting.ProductLabel: product.ProductName}, false, product.NamespaceMeta, kubeClient, log)
 }
 
 func UpdateHelmProductVariable(productName, envName, username, requestID string, updatedRcs []*templatemodels.RenderChart, renderset *commonmodels.RenderSet, log *zap.SugaredLogger) error {
@@ -2829,7 +2918,7 @@ func upsertService(isUpdate bool, env *commonmodels.Product,
 					continue
 				}
 			}
-		case setting.Deployment, setting.StatefulSet:
+		case setting.Deployment, setting.StatefulSet, setting.DaemonSet:
 			// compatibility flag, We add a match label in spec.selector field pre 1.10.
 			needSelectorLabel := false
 
@@ -2911,8 +3000,18 @@ func upsertService(isUpdate bool, env *commonmodels.Product,
 					errList = multierror.Append(errList, err)
 					continue
 				}
+			case *appsv1.DaemonSet:
+				// Inject imagePullSecrets if qn-registry-secret is not set
+				applySystemImagePullSecrets(&res.Spec.Template.Spec)
+
+				err = updater.CreateOrPatchDaemonSet(res, kubeClient)
+				if err != nil {
+					log.Errorf("Failed to create or update %s, manifest is\n%v\n, error: %v", u.GetKind(), res, err)
+					errList = multierror.Append(errList, err)
+					continue
+				}
 			default:
-				errList = multierror.Append(errList, fmt.Errorf("object is not a appsv1.Deployment or appsv1.StatefulSet"))
+				errList = multierror.Append(errList, fmt.Errorf("object is not a appsv1.Deployment, appsv1.StatefulSet or appsv1.DaemonSet"))
 				continue
 			}
 
@@ -3088,13 +3187,7 @@ func renderService(prod *commonmodels.Product, render *commonmodels.RenderSet, s
 		return nil, err
 	}
 
-	// 渲染配置集
-	parsedYaml := commonservice.RenderValueForString(svcTmpl.Yaml, render)
-	// 渲染系统变量键值
-	parsedYaml = kube.ParseSysKeys(prod.Namespace, prod.EnvName, prod.ProductName, service.ServiceName, parsedYaml)
-	// 替换服务模板容器镜像为用户指定镜像
-	parsedYaml = replaceContainerImages(parsedYaml, svcTmpl.Containers, service.Containers)
-
+	parsedYaml := renderServiceYaml(prod, render, svcTmpl, service.Containers)
 	return &parsedYaml, nil
 }
 
@@ -3118,7 +3211,7 @@ func replaceContainerImages(tmpl string, ori []*commonmodels.Container, replace
 
 func waitResourceRunning(
 	kubeClient client.Client, namespace string,
-	resources []*unstructured.Unstructured, timeoutSeconds int, log *zap.SugaredLogger,
+	resources []*unstructured.Unstructured, statusMappings []*commonmodels.ResourceStatusMapping, timeoutSeconds int, log *zap.SugaredLogger,
 ) error {
 	log.Infof("wait service group to run in %d seconds", timeoutSeconds)
 
@@ -3140,6 +3233,12 @@ func waitResourceRunning(
 				if err == nil && found {
 					ready = wrapper.StatefulSet(s).Ready()
 				}
+			case setting.DaemonSet:
+				var ds *appsv1.DaemonSet
+				ds, found, err = getter.GetDaemonSet(namespace, r.GetName(), kubeClient)
+				if err == nil && found {
+					ready = wrapper.DaemonSet(ds).Ready()
+				}
 			case setting.Job:
 				var j *batchv1.Job
 				j, found, err = getter.GetJob(namespace, r.GetName(), kubeClient)
@@ -3148,6 +3247,14 @@ func waitResourceRunning(
 				}
 			default:
 				ready = true
+				if mapping := kube.FindResourceStatusMapping(statusMappings, r); mapping != nil {
+					u := &unstructured.Unstructured{}
+					u.SetGroupVersionKind(r.GroupVersionKind())
+					found, err = getter.GetResourceInCache(namespace, r.GetName(), u, kubeClient)
+					if err == nil && found {
+						ready, err = kube.IsCustomResourceReady(u, mapping)
+					}
+				}
 			}
 
 			if err != nil {
@@ -3244,8 +3351,24 @@ func preCreateProduct(envName string, args *commonmodels.Product, kubeClient cli
 	}
 
 	args.Render = tmpRenderInfo
+	if err := kube.ValidateNamespaceMeta(args.NamespaceMeta); err != nil {
+		return e.ErrCreateEnv.AddDesc(err.Error())
+	}
+	if err := validateClusterSharedServices(args.ClusterID, args.ClusterSharedServices); err != nil {
+		return e.ErrCreateEnv.AddDesc(err.Error())
+	}
 	if preCreateNSAndSecret(productTmpl.ProductFeature) {
-		return ensureKubeEnv(args.Namespace, args.RegistryID, map[string]string{setting.ProductLabel: args.ProductName}, args.ShareEnv.Enable, kubeClient, log)
+		return ensureKubeEnv(args.Namespace, args.RegistryID, map[string]string{setting.ProductLabel: args.ProductName}, args.ShareEnv.Enable, args.NamespaceMeta, kubeClient, log)
+	}
+	return nil
+}
+
+// validateClusterSharedServices checks that the shared services referenced by the env exist in the cluster of the env.
+func validateClusterSharedServices(clusterID string, names []string) error {
+	for _, name := range names {
+		if _, err := commonrepo.NewClusterSharedServiceColl().Find(clusterID, name, 0); err != nil {
+			return fmt.Errorf("shared service %s not found in the cluster of the env", name)
+		}
 	}
 	return nil
 }
@@ -3294,13 +3417,19 @@ func applySystemImagePullSecrets(podSpec *corev1.PodSpec) {
 		})
 }
 
-func ensureKubeEnv(namespace, registryId string, customLabels map[string]string, enableShare bool, kubeClient client.Client, log *zap.SugaredLogger) error {
+func ensureKubeEnv(namespace, registryId string, customLabels map[string]string, enableShare bool, nsMeta *commonmodels.NamespaceMeta, kubeClient client.Client, log *zap.SugaredLogger) error {
 	err := kube.CreateNamespace(namespace, customLabels, enableShare, kubeClient)
 	if err != nil {
 		log.Errorf("[%s] get or create namespace error: %v", namespace, err)
 		return e.ErrCreateNamspace.AddDesc(err.Error())
 	}
 
+	// labels and annotations declared by the env are reconciled on every update to avoid drifting.
+	if err := kube.EnsureNamespaceMeta(namespace, nsMeta, kubeClient); err != nil {
+		log.Errorf("[%s] ensure namespace labels and annotations error: %v", namespace, err)
+		return e.ErrCreateNamspace.AddDesc(err.Error())
+	}
+
 	// 创建默认的镜像仓库secret
 	if err := commonservice.EnsureDefaultRegistrySecret(namespace, registryId, kubeClient, log); err != nil {
 		log.Errorf("[%s] get or create namespace error: %v", namespace, err)
@@ -3335,6 +3464,7 @@ func buildInstallParam(namespace, envName, defaultValues string, renderChart *te
 	}
 	ret := &ReleaseInstallParam{
 		ProductName:  serviceObj.ProductName,
+		EnvName:      envName,
 		Namespace:    namespace,
 		ReleaseName:  util.GeneReleaseName(serviceObj.GetReleaseNaming(), serviceObj.ProductName, namespace, envName, serviceObj.ServiceName),
 		MergedValues: mergedValues,
@@ -3394,8 +3524,17 @@ func installOrUpgradeHelmChartWithValues(param *ReleaseInstallParam, isRetry boo
 		return fmt.Errorf("failed to clone helm client: %s", err)
 	}
 
+	var opts *helmclient.GenericHelmOptions
+	if param.EnvName != "" {
+		env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: param.ProductName, EnvName: param.EnvName})
+		if err != nil {
+			return fmt.Errorf("failed to find env %s/%s: %s", param.ProductName, param.EnvName, err)
+		}
+		opts = kube.NewHelmPostRenderOptions(env, param.ReleaseName, param.DryRun, log.SugaredLogger())
+	}
+
 	var release *release.Release
-	release, err = helmClient.InstallOrUpgradeChart(ctx, chartSpec, nil)
+	release, err = helmClient.InstallOrUpgradeChart(ctx, chartSpec, opts)
 	if err != nil {
 		err = errors.WithMessagef(
 			err,
@@ -54,12 +54,21 @@ type CreateProductParam struct {
 	EnvType     string
 	log         *zap.SugaredLogger
 	RegistryID  string
+	// ClusterID is the cluster the environment is created in, the cluster of the project is used if it is empty.
+	ClusterID string
 }
 
 type AutoCreator struct {
 	Param *CreateProductParam
 }
 
+// NewAutoCreator returns the creator of the environments with all the services of the project and their
+// default values, e.g. the dev environment of a new project.
+func NewAutoCreator(param *CreateProductParam, log *zap.SugaredLogger) *AutoCreator {
+	param.log = log
+	return &AutoCreator{Param: param}
+}
+
 type IProductCreator interface {
 	Create(string, string, *models.Product, *zap.SugaredLogger) error
 }
@@ -98,6 +107,9 @@ func (autoCreator *AutoCreator) Create(envName string) (string, error) {
 	productObject.UpdateBy = autoCreator.Param.UserName
 	productObject.EnvName = envName
 	productObject.RegistryID = autoCreator.Param.RegistryID
+	if autoCreator.Param.ClusterID != "" {
+		productObject.ClusterID = autoCreator.Param.ClusterID
+	}
 	if autoCreator.Param.EnvType == setting.HelmDeployType {
 		productObject.Source = setting.SourceFromHelm
 	}
@@ -281,6 +293,13 @@ func (creator *ExternalProductCreator) Create(user, requestID string, args *mode
 		log.Errorf("[%s][%s] create add namesapce label error: %v", args.EnvName, args.ProductName, err)
 		return e.ErrCreateEnv.AddDesc(err.Error())
 	}
+	if err := kube.ValidateNamespaceMeta(args.NamespaceMeta); err != nil {
+		return e.ErrCreateEnv.AddDesc(err.Error())
+	}
+	if err := kube.EnsureNamespaceMeta(args.Namespace, args.NamespaceMeta, kubeClient); err != nil {
+		log.Errorf("[%s][%s] apply namespace labels and annotations error: %v", args.EnvName, args.ProductName, err)
+		return e.ErrCreateEnv.AddDesc(err.Error())
+	}
 	err = commonrepo.NewProductColl().Create(args)
 	if err != nil {
 		log.Errorf("[%s][%s] create product record error: %v", args.EnvName, args.ProductName, err)
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+const (
+	defaultEnvDuplicateName      = "{{.Name}}-copy"
+	defaultEnvDuplicateNamespace = "{{.Project}}-env-{{.Env}}"
+)
+
+type DuplicateEnvironmentArgs struct {
+	// EnvName renders the name of the new environment, {{.Name}}-copy is used if empty.
+	EnvName string `json:"env_name"`
+	// Namespace renders the namespace of the new environment, {{.Project}}-env-{{.Env}} is used if empty.
+	Namespace string `json:"namespace"`
+	// ClusterID is the cluster of the new environment, the cluster of the source is used if empty.
+	ClusterID string `json:"cluster_id"`
+}
+
+type DuplicateEnvironmentResp struct {
+	EnvName   string `json:"env_name"`
+	Namespace string `json:"namespace"`
+	ClusterID string `json:"cluster_id"`
+}
+
+// DuplicateEnvironment creates an environment with the services, revisions and variables of an
+// existing one, it can be put in another namespace or cluster.
+func DuplicateEnvironment(projectName, envName string, args *DuplicateEnvironmentArgs, userName, requestID string, log *zap.SugaredLogger) (*DuplicateEnvironmentResp, error) {
+	source, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName})
+	if err != nil {
+		log.Errorf("failed to find environment %s of project %s, err: %s", envName, projectName, err)
+		return nil, e.ErrDuplicateEnv.AddErr(err)
+	}
+	if source.Source == setting.SourceFromExternal || source.Source == setting.PMDeployType || source.IsExisted {
+		return nil, e.ErrDuplicateEnv.AddDesc(fmt.Sprintf("environment %s is not created by zadig and can not be duplicated", envName))
+	}
+
+	nameArgs := &commonservice.DuplicateNameArgs{Name: envName, Project: projectName, SourceProject: projectName}
+	newEnvName, err := commonservice.RenderDuplicateName(args.EnvName, defaultEnvDuplicateName, nameArgs)
+	if err != nil {
+		return nil, e.ErrDuplicateEnv.AddErr(err)
+	}
+	nameArgs.Env = newEnvName
+	namespace, err := commonservice.RenderDuplicateName(args.Namespace, defaultEnvDuplicateNamespace, nameArgs)
+	if err != nil {
+		return nil, e.ErrDuplicateEnv.AddErr(err)
+	}
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: newEnvName}); err == nil {
+		return nil, e.ErrDuplicateEnv.AddDesc(fmt.Sprintf("environment %s already exists", newEnvName))
+	}
+	clusterID := args.ClusterID
+	if clusterID == "" {
+		clusterID = source.ClusterID
+	}
+	if clusterID != source.ClusterID {
+		if _, err := commonrepo.NewK8SClusterColl().Get(clusterID); err != nil {
+			return nil, e.ErrDuplicateEnv.AddDesc(fmt.Sprintf("cluster %s not found", clusterID))
+		}
+	}
+
+	// the values and variables of the source environment are kept in its renderset.
+	renderSet := &commonmodels.RenderSet{}
+	if source.Render != nil {
+		renderSet, err = commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{
+			Name:        source.Render.Name,
+			EnvName:     envName,
+			ProductTmpl: projectName,
+			Revision:    source.Render.Revision,
+		})
+		if err != nil {
+			log.Errorf("failed to find renderset of environment %s, err: %s", envName, err)
+			return nil, e.ErrDuplicateEnv.AddErr(err)
+		}
+	}
+
+	resp := &DuplicateEnvironmentResp{EnvName: newEnvName, Namespace: namespace, ClusterID: clusterID}
+	if source.Source == setting.SourceFromHelm {
+		// the source environment is found by the base name when a helm environment is copied.
+		err = CopyHelmProduct(projectName, userName, requestID, []*CreateHelmProductArg{{
+			ProductName:   projectName,
+			EnvName:       newEnvName,
+			Namespace:     namespace,
+			ClusterID:     clusterID,
+			DefaultValues: renderSet.DefaultValues,
+			RegistryID:    source.RegistryID,
+			BaseEnvName:   envName,
+			BaseName:      envName,
+		}}, log)
+		if err != nil {
+			return nil, e.ErrDuplicateEnv.AddErr(err)
+		}
+		return resp, nil
+	}
+
+	newProduct := *source
+	util.Clear(&newProduct.ID)
+	newProduct.EnvName = newEnvName
+	newProduct.Namespace = namespace
+	newProduct.ClusterID = clusterID
+	newProduct.UpdateBy = userName
+	newProduct.BaseEnvName = envName
+	newProduct.ShareEnv = commonmodels.ProductShareEnv{}
+	newProduct.Vars = renderSet.KVs
+	newProduct.Render = &commonmodels.RenderInfo{ProductTmpl: projectName, Name: namespace}
+	if err := CopyYamlProduct(userName, requestID, &newProduct, log); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
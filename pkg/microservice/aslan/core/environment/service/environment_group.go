@@ -24,7 +24,6 @@ import (
 	"helm.sh/helm/v3/pkg/releaseutil"
 	"k8s.io/client-go/informers"
 
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
@@ -69,12 +68,9 @@ func ListGroups(serviceName, envName, productName string, perPage, page int, log
 	//将获取到的所有服务按照名称进行排序
 	sort.SliceStable(allServices, func(i, j int) bool { return allServices[i].ServiceName < allServices[j].ServiceName })
 
-	cls, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), productInfo.ClusterID)
-	if err != nil {
-		log.Errorf("[%s][%s] error: %v", envName, productName, err)
-		return resp, count, e.ErrListGroups.AddDesc(err.Error())
-	}
-	inf, err := informer.NewInformer(productInfo.ClusterID, productInfo.Namespace, cls)
+	// read before the status is computed, see setCachedEnvStatus
+	generation := informer.Generation(productInfo.ClusterID, productInfo.Namespace)
+	inf, _, err := getEnvInformer(productInfo.ClusterID, productInfo.Namespace)
 	if err != nil {
 		log.Errorf("[%s][%s] error: %v", envName, productName, err)
 		return resp, count, e.ErrListGroups.AddDesc(err.Error())
@@ -89,7 +85,12 @@ func ListGroups(serviceName, envName, productName string, perPage, page int, log
 	//针对获取环境状态的接口请求，这里不需要一次性获取所有的服务，先获取十条的数据，有异常的可以直接返回，不需要继续往下获取
 	if page == -1 && perPage == -1 {
 		// 获取环境的状态
+		key := envStatusKey(productName, envName, serviceName)
+		if cached, ok := getCachedEnvStatus(key, generation, productInfo); ok {
+			return cached, count, nil
+		}
 		resp = listGroupServiceStatus(allServices, envName, productName, inf, productInfo, log)
+		setCachedEnvStatus(key, generation, productInfo, resp)
 		return resp, count, nil
 	}
 
@@ -167,24 +168,12 @@ func GetIngressInfo(product *commonmodels.Product, service *commonmodels.Service
 		}
 		switch u.GetKind() {
 		case setting.Ingress:
-			clientset, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), product.ClusterID)
-			if err != nil {
-				log.Errorf("failed to init clientset, clusterID: %s", product.ClusterID)
-				return nil
-			}
-
-			inf, err := informer.NewInformer(product.ClusterID, product.Namespace, clientset)
+			inf, version, err := getEnvInformer(product.ClusterID, product.Namespace)
 			if err != nil {
-				log.Errorf("failed to create informer from clientset for clusterID: %s, the error is: %s", product.ClusterID, err)
+				log.Errorf("failed to create informer for clusterID: %s, the error is: %s", product.ClusterID, err)
 				return nil
 			}
 
-			version, err := clientset.Discovery().ServerVersion()
-			if err != nil {
-				log.Warnf("Failed to determine server version, error is: %s", err)
-				continue
-			}
-
 			if kubeclient.VersionLessThan122(version) {
 				// get the ingress info from kubernetes. For cluster version 1.22- we only search for
 				// extensions/v1beta1.
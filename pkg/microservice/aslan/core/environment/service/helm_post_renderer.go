@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const helmPostRenderRecordLimit = 50
+
+// UpdateHelmPostRenderer sets the post renderer applied to the manifests of all the helm releases of the env,
+// it takes effect in the next install or upgrade.
+func UpdateHelmPostRenderer(envName, projectName string, args *commonmodels.HelmPostRenderer, log *zap.SugaredLogger) error {
+	if err := kube.ValidateHelmPostRenderer(args); err != nil {
+		return e.ErrUpdateHelmPostRenderer.AddErr(err)
+	}
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName}); err != nil {
+		return e.ErrUpdateHelmPostRenderer.AddErr(err)
+	}
+	if err := commonrepo.NewProductColl().UpdateHelmPostRenderer(envName, projectName, args); err != nil {
+		log.Errorf("failed to update helm post renderer of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdateHelmPostRenderer.AddErr(err)
+	}
+	return nil
+}
+
+func ListHelmPostRenderRecords(projectName, envName, releaseName string, log *zap.SugaredLogger) ([]*commonmodels.HelmPostRenderRecord, error) {
+	records, err := commonrepo.NewHelmPostRenderRecordColl().List(projectName, envName, releaseName, helmPostRenderRecordLimit)
+	if err != nil {
+		log.Errorf("failed to list helm post render records of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrListHelmPostRenderRecords.AddErr(err)
+	}
+	return records, nil
+}
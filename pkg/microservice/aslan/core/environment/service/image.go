@@ -33,6 +33,7 @@ import (
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/kube/updater"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/util"
@@ -263,6 +264,21 @@ func UpdateContainerImage(requestID string, args *UpdateContainerImageArgs, log
 				log.Errorf("[%s] UpdateStatefulsetImageByName error: %s", namespace, err.Error())
 				return e.ErrUpdateConainterImage.AddDesc("更新 StatefulSet 容器镜像失败")
 			}
+		case setting.DaemonSet:
+			if err := updater.UpdateDaemonSetImage(namespace, args.Name, args.ContainerName, args.Image, kubeClient); err != nil {
+				log.Errorf("[%s] UpdateDaemonSetImage error: %s", namespace, err.Error())
+				return e.ErrUpdateConainterImage.AddDesc("更新 DaemonSet 容器镜像失败")
+			}
+		case setting.CronJob:
+			if err := updater.UpdateCronJobImage(namespace, args.Name, args.ContainerName, args.Image, kubeClient); err != nil {
+				log.Errorf("[%s] UpdateCronJobImage error: %s", namespace, err.Error())
+				return e.ErrUpdateConainterImage.AddDesc("更新 CronJob 容器镜像失败")
+			}
+		case setting.Job:
+			if err := updateJobImage(namespace, args.Name, args.ContainerName, args.Image, kubeClient); err != nil {
+				log.Errorf("[%s] updateJobImage error: %s", namespace, err.Error())
+				return e.ErrUpdateConainterImage.AddDesc("更新 Job 容器镜像失败")
+			}
 		default:
 			return e.ErrUpdateConainterImage.AddDesc(fmt.Sprintf("不支持的资源类型: %s", args.Type))
 		}
@@ -286,3 +302,25 @@ func UpdateContainerImage(requestID string, args *UpdateContainerImageArgs, log
 	}
 	return nil
 }
+
+// updateJobImage recreates the job with the new image since the pod template of a job is immutable.
+func updateJobImage(namespace, name, containerName, image string, kubeClient client.Client) error {
+	job, found, err := getter.GetJob(namespace, name, kubeClient)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("job %s/%s not found", namespace, name)
+	}
+	updated := false
+	for i := range job.Spec.Template.Spec.Containers {
+		if job.Spec.Template.Spec.Containers[i].Name == containerName {
+			job.Spec.Template.Spec.Containers[i].Image = image
+			updated = true
+		}
+	}
+	if !updated {
+		return fmt.Errorf("container %s not found in job %s/%s", containerName, namespace, name)
+	}
+	return updater.RecreateJob(job, kubeClient)
+}
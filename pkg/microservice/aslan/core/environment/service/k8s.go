@@ -32,9 +32,11 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	e "github.com/koderover/zadig/pkg/tool/errors"
@@ -60,6 +62,16 @@ func (k *K8sService) queryServiceStatus(namespace, envName, productName string,
 	return setting.PodSucceeded, setting.PodReady, []string{}
 }
 
+// queryCustomResourcesStatus checks the custom resources of the service against the status mappings of the service template.
+func (k *K8sService) queryCustomResourcesStatus(productInfo *commonmodels.Product, serviceTmpl *commonmodels.Service) (string, string) {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), productInfo.ClusterID)
+	if err != nil {
+		k.log.Errorf("failed to get kube client of cluster %s, err: %s", productInfo.ClusterID, err)
+		return setting.PodError, setting.PodNotReady
+	}
+	return kube.GetCustomResourcesStatus(productInfo.Namespace, productInfo.ProductName, serviceTmpl.ServiceName, serviceTmpl.ResourceStatusMappings, kubeClient)
+}
+
 func (k *K8sService) updateService(args *SvcOptArgs) error {
 	svc := &commonmodels.ProductService{
 		ServiceName: args.ServiceName,
@@ -186,6 +198,9 @@ func (k *K8sService) listGroupServices(allServices []*commonmodels.ProductServic
 			// 查询group下所有pods信息
 			if informer != nil {
 				gp.Status, gp.Ready, gp.Images = k.queryServiceStatus(productInfo.Namespace, envName, productName, serviceTmpl, informer)
+				if len(serviceTmpl.ResourceStatusMappings) > 0 && gp.Ready == setting.PodReady {
+					gp.Status, gp.Ready = k.queryCustomResourcesStatus(productInfo, serviceTmpl)
+				}
 				// 如果产品正在创建中，且service status为ERROR（POD还没创建出来），则判断为Pending，尚未开始创建
 				if productInfo.Status == setting.ProductStatusCreating && gp.Status == setting.PodError {
 					gp.Status = setting.PodPending
@@ -242,9 +257,25 @@ func (k *K8sService) createGroup(envName, productName, username string, group []
 		return fmt.Errorf("failed to new istio client: %s", err)
 	}
 
+	// the group waits for the services as the conditions declared in the service dependencies.
+	var dependencies []*template.ServiceDependency
+	if project, err := templaterepo.NewProductColl().Find(productName); err == nil {
+		dependencies = project.ServiceDependencies
+	}
+	timeout := 0
+	for _, svc := range group {
+		if condition := commonservice.GetServiceReadyCondition(dependencies, svc.ServiceName); condition.Timeout > timeout {
+			timeout = condition.Timeout
+		}
+	}
+	if timeout == 0 {
+		timeout = config.ServiceStartTimeout()
+	}
+
 	var wg sync.WaitGroup
 	var lock sync.Mutex
 	var resources []*unstructured.Unstructured
+	var statusMappings []*commonmodels.ResourceStatusMapping
 
 	for i := range group {
 		// 只有在service有Pod的时候，才需要等待pod running或者等待pod succeed
@@ -265,9 +296,17 @@ func (k *K8sService) createGroup(envName, productName, username string, group []
 				lock.Unlock()
 			}
 
+			if commonservice.GetServiceReadyCondition(dependencies, svc.ServiceName).Type == config.ServiceReadyConditionNone {
+				return
+			}
+			var mappings []*commonmodels.ResourceStatusMapping
+			if serviceTmpl, err := commonservice.GetServiceTemplate(svc.ServiceName, setting.K8SDeployType, svc.ProductName, "", svc.Revision, k.log); err == nil {
+				mappings = serviceTmpl.ResourceStatusMappings
+			}
 			//  concurrent array append
 			lock.Lock()
 			resources = append(resources, items...)
+			statusMappings = append(statusMappings, mappings...)
 			lock.Unlock()
 		}(group[i])
 	}
@@ -278,11 +317,11 @@ func (k *K8sService) createGroup(envName, productName, username string, group []
 		return err
 	}
 
-	if err := waitResourceRunning(kubeClient, prod.Namespace, resources, config.ServiceStartTimeout(), k.log); err != nil {
+	if err := waitResourceRunning(kubeClient, prod.Namespace, resources, statusMappings, timeout, k.log); err != nil {
 		k.log.Errorf(
 			"service group %s/%+v doesn't start in %d seconds: %v",
 			prod.Namespace,
-			updatableServiceNameList, config.ServiceStartTimeout(), err)
+			updatableServiceNameList, timeout, err)
 
 		err = e.ErrUpdateEnv.AddErr(
 			fmt.Errorf(e.StartPodTimeout+"\n %s", "["+strings.Join(updatableServiceNameList, "], [")+"]"))
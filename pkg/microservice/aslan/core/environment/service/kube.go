@@ -538,5 +538,15 @@ func ListCustomWorkload(clusterID, namespace string, log *zap.SugaredLogger) ([]
 			resp = append(resp, strings.Join([]string{setting.StatefulSet, statefulset.Name, container.Name}, "/"))
 		}
 	}
+	daemonSets, err := getter.ListDaemonSets(namespace, labels.Everything(), kubeClient)
+	if err != nil {
+		log.Errorf("ListDaemonSets err:%v", err)
+		return resp, err
+	}
+	for _, daemonSet := range daemonSets {
+		for _, container := range daemonSet.Spec.Template.Spec.Containers {
+			resp = append(resp, strings.Join([]string{setting.DaemonSet, daemonSet.Name, container.Name}, "/"))
+		}
+	}
 	return resp, nil
 }
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/manifestlint"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type EnvLintResp struct {
+	// KubeVersion is the version of the cluster of the environment, it is empty if it is unreachable.
+	KubeVersion string               `json:"kube_version"`
+	Services    []*ServiceLintResult `json:"services"`
+}
+
+type ServiceLintResult struct {
+	ServiceName string                              `json:"service_name"`
+	ReleaseName string                              `json:"release_name,omitempty"`
+	Findings    []*commonmodels.ManifestLintFinding `json:"findings"`
+	Error       string                              `json:"error,omitempty"`
+}
+
+// LintEnvServices lints the deployed manifests of the services of the environment against the version
+// of its cluster, all the services are linted if serviceNames is empty.
+func LintEnvServices(productName, envName string, serviceNames []string, log *zap.SugaredLogger) (*EnvLintResp, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		return nil, e.ErrLintManifests.AddErr(err)
+	}
+	project, err := templaterepo.NewProductColl().Find(productName)
+	if err != nil {
+		return nil, e.ErrLintManifests.AddErr(err)
+	}
+	opt := &manifestlint.Options{}
+	if project.ManifestLint != nil {
+		opt.DisabledRules = project.ManifestLint.DisabledRules
+	}
+	if cls, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), env.ClusterID); err == nil {
+		if versionInfo, err := cls.Discovery().ServerVersion(); err == nil {
+			opt.KubeVersion = versionInfo.GitVersion
+		} else {
+			log.Warnf("failed to get server version of cluster %s, err: %s", env.ClusterID, err)
+		}
+	}
+
+	services, err := envServiceManifests(env, serviceNames, log)
+	if err != nil {
+		return nil, e.ErrLintManifests.AddErr(err)
+	}
+	results := make([]*ServiceLintResult, 0, len(services))
+	for _, svc := range services {
+		result := &ServiceLintResult{ServiceName: svc.ServiceName, ReleaseName: svc.ReleaseName, Error: svc.Error}
+		if svc.Error == "" {
+			result.Findings = manifestlint.Lint(svc.Manifests, opt)
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ServiceName < results[j].ServiceName })
+	return &EnvLintResp{KubeVersion: opt.KubeVersion, Services: results}, nil
+}
+
+type serviceManifests struct {
+	ServiceName string
+	ReleaseName string
+	Manifests   []string
+	Error       string
+}
+
+// envServiceManifests returns the deployed manifests of the services of a k8s yaml or helm environment, all
+// the services are returned if serviceNames is empty. Failing to get the manifests of a service does not
+// fail the others.
+func envServiceManifests(env *commonmodels.Product, serviceNames []string, log *zap.SugaredLogger) ([]*serviceManifests, error) {
+	switch getProjectType(env.ProductName) {
+	case setting.K8SDeployType:
+		return k8sEnvServiceManifests(env, serviceNames, log)
+	case setting.HelmDeployType:
+		return helmEnvServiceManifests(env, serviceNames)
+	default:
+		return nil, fmt.Errorf("only k8s yaml and helm projects are supported")
+	}
+}
+
+func k8sEnvServiceManifests(env *commonmodels.Product, serviceNames []string, log *zap.SugaredLogger) ([]*serviceManifests, error) {
+	rendered, err := RenderEnvServices(env.ProductName, env.EnvName, &RenderEnvServicesArgs{ServiceNames: serviceNames}, log)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*serviceManifests, 0, len(rendered))
+	for _, svc := range rendered {
+		item := &serviceManifests{ServiceName: svc.ServiceName, Error: svc.Error}
+		if svc.Error == "" {
+			item.Manifests = util.SplitManifests(svc.Yaml)
+		}
+		resp = append(resp, item)
+	}
+	return resp, nil
+}
+
+func helmEnvServiceManifests(env *commonmodels.Product, serviceNames []string) ([]*serviceManifests, error) {
+	releaseNames, err := commonservice.GetServiceNameToReleaseNameMap(env)
+	if err != nil {
+		return nil, err
+	}
+	helmClient, err := helmtool.NewClientFromNamespace(env.ClusterID, env.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	names := sets.NewString(serviceNames...)
+	resp := make([]*serviceManifests, 0)
+	for serviceName := range env.GetServiceMap() {
+		if names.Len() > 0 && !names.Has(serviceName) {
+			continue
+		}
+		item := &serviceManifests{ServiceName: serviceName, ReleaseName: releaseNames[serviceName]}
+		resp = append(resp, item)
+		if item.ReleaseName == "" {
+			item.Error = "release name not found"
+			continue
+		}
+		release, err := helmClient.GetRelease(item.ReleaseName)
+		if err != nil {
+			item.Error = fmt.Sprintf("failed to get release %s: %s", item.ReleaseName, err)
+			continue
+		}
+		item.Manifests = util.SplitManifests(release.Manifest)
+	}
+	return resp, nil
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// maxPodLogBytes limits the logs returned by a single request, the bundle is used for more logs.
+const maxPodLogBytes = 10 * 1024 * 1024
+
+// podLogWriteTimeout closes the websocket if the client stops reading the logs.
+const podLogWriteTimeout = 10 * time.Second
+
+type PodLogArgs struct {
+	Container string `form:"container"`
+	// Previous returns the logs of the last terminated container, e.g. the one crashed.
+	Previous     bool  `form:"previous"`
+	TailLines    int64 `form:"tailLines"`
+	SinceSeconds int64 `form:"sinceSeconds"`
+	Timestamps   bool  `form:"timestamps"`
+}
+
+type ServiceLogBundleArgs struct {
+	// StartTime and EndTime are unix seconds, the logs of the last hour are collected if they are not set.
+	StartTime int64 `form:"startTime"`
+	EndTime   int64 `form:"endTime"`
+	// Previous also collects the logs of the last terminated containers.
+	Previous bool `form:"previous"`
+}
+
+type podLogContext struct {
+	env       *commonmodels.Product
+	clientset kubernetes.Interface
+}
+
+func newPodLogContext(envName, productName string) (*podLogContext, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find env %s of project %s: %s", envName, productName, err)
+	}
+	clientset, err := kube.GetClientset(env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube client of cluster %s: %s", env.ClusterID, err)
+	}
+	return &podLogContext{env: env, clientset: clientset}, nil
+}
+
+// logOptions checks the container is in the pod, the first container is used if it is not specified.
+func (c *podLogContext) logOptions(podName string, args *PodLogArgs, follow bool) (*corev1.PodLogOptions, error) {
+	pod, err := c.clientset.CoreV1().Pods(c.env.Namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pod %s in env %s: %s", podName, c.env.EnvName, err)
+	}
+	container := args.Container
+	if container == "" {
+		container = pod.Spec.Containers[0].Name
+	}
+	found := false
+	for _, ctr := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if ctr.Name == container {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("container %s is not found in pod %s", container, podName)
+	}
+
+	opts := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     follow,
+		Previous:   args.Previous,
+		Timestamps: args.Timestamps,
+	}
+	if args.TailLines > 0 {
+		opts.TailLines = &args.TailLines
+	}
+	if args.SinceSeconds > 0 {
+		opts.SinceSeconds = &args.SinceSeconds
+	}
+	if !follow {
+		limit := int64(maxPodLogBytes)
+		opts.LimitBytes = &limit
+	}
+	return opts, nil
+}
+
+// GetPodLogs returns the logs of a container in the env, the logs of the previous container can be
+// returned after the container crashes and restarts.
+func GetPodLogs(envName, productName, podName string, args *PodLogArgs, log *zap.SugaredLogger) (string, error) {
+	logCtx, err := newPodLogContext(envName, productName)
+	if err != nil {
+		return "", e.ErrGetPodLogs.AddErr(err)
+	}
+	opts, err := logCtx.logOptions(podName, args, false)
+	if err != nil {
+		return "", e.ErrGetPodLogs.AddErr(err)
+	}
+	content, err := logCtx.clientset.CoreV1().Pods(logCtx.env.Namespace).GetLogs(podName, opts).DoRaw(context.TODO())
+	if err != nil {
+		log.Errorf("failed to get logs of %s/%s, err: %s", podName, opts.Container, err)
+		return "", e.ErrGetPodLogs.AddErr(err)
+	}
+	return string(content), nil
+}
+
+// TailPodLogs upgrades the request to a websocket and sends the logs of the container line by line
+// until the container exits or the client disconnects.
+func TailPodLogs(c *gin.Context, envName, productName, podName string, args *PodLogArgs, log *zap.SugaredLogger) error {
+	logCtx, err := newPodLogContext(envName, productName)
+	if err != nil {
+		return e.ErrTailPodLogs.AddErr(err)
+	}
+	opts, err := logCtx.logOptions(podName, args, true)
+	if err != nil {
+		return e.ErrTailPodLogs.AddErr(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := logCtx.clientset.CoreV1().Pods(logCtx.env.Namespace).GetLogs(podName, opts).Stream(ctx)
+	if err != nil {
+		log.Errorf("failed to stream logs of %s/%s, err: %s", podName, opts.Container, err)
+		return e.ErrTailPodLogs.AddErr(err)
+	}
+	defer stream.Close()
+
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Errorf("ws upgrade err:%s", err)
+		return e.ErrTailPodLogs.AddErr(err)
+	}
+	defer ws.Close()
+
+	// the messages of the client are discarded, the read fails once the client disconnects.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			_ = ws.SetWriteDeadline(time.Now().Add(podLogWriteTimeout))
+			if werr := ws.WriteMessage(websocket.TextMessage, []byte(strings.TrimRight(line, "\n"))); werr != nil {
+				return nil
+			}
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Warnf("log stream of %s/%s is broken, err: %s", podName, opts.Container, err)
+			}
+			_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return nil
+		}
+	}
+}
+
+// DownloadServiceLogs writes a tar.gz of the logs of all the containers of the service in the time
+// range, the file of a container is named as <pod>/<container>.log.
+func DownloadServiceLogs(envName, productName, serviceName string, args *ServiceLogBundleArgs, out io.Writer, log *zap.SugaredLogger) error {
+	logCtx, err := newPodLogContext(envName, productName)
+	if err != nil {
+		return e.ErrDownloadServiceLogs.AddErr(err)
+	}
+	endTime := time.Now()
+	if args.EndTime > 0 {
+		endTime = time.Unix(args.EndTime, 0)
+	}
+	startTime := endTime.Add(-time.Hour)
+	if args.StartTime > 0 {
+		startTime = time.Unix(args.StartTime, 0)
+	}
+	if !startTime.Before(endTime) {
+		return e.ErrDownloadServiceLogs.AddDesc("start time should be before end time")
+	}
+
+	selector := labels.Set{setting.ProductLabel: productName, setting.ServiceLabel: serviceName}.AsSelector()
+	pods, err := logCtx.clientset.CoreV1().Pods(logCtx.env.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return e.ErrDownloadServiceLogs.AddErr(err)
+	}
+	if len(pods.Items) == 0 {
+		return e.ErrDownloadServiceLogs.AddDesc(fmt.Sprintf("no pods of service %s in env %s", serviceName, envName))
+	}
+
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+	for _, pod := range pods.Items {
+		restarted := map[string]bool{}
+		for _, status := range pod.Status.ContainerStatuses {
+			restarted[status.Name] = status.RestartCount > 0
+		}
+		for _, container := range pod.Spec.Containers {
+			previousLogs := []bool{false}
+			if args.Previous && restarted[container.Name] {
+				previousLogs = append(previousLogs, true)
+			}
+			for _, previous := range previousLogs {
+				name := fmt.Sprintf("%s/%s.log", pod.Name, container.Name)
+				if previous {
+					name = fmt.Sprintf("%s/%s.previous.log", pod.Name, container.Name)
+				}
+				content, err := logCtx.rangeLogs(pod.Name, container.Name, previous, startTime, endTime)
+				if err != nil {
+					// the logs of a container not started yet can not be fetched, it is recorded in the bundle.
+					log.Warnf("failed to get logs of %s/%s, err: %s", pod.Name, container.Name, err)
+					content = []byte(fmt.Sprintf("failed to get logs: %s\n", err))
+				}
+				if err := writeTarFile(tw, name, content); err != nil {
+					return e.ErrDownloadServiceLogs.AddErr(err)
+				}
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return e.ErrDownloadServiceLogs.AddErr(err)
+	}
+	if err := gw.Close(); err != nil {
+		return e.ErrDownloadServiceLogs.AddErr(err)
+	}
+	return nil
+}
+
+// rangeLogs gets the logs since the start time with timestamps, the lines after the end time are dropped.
+func (c *podLogContext) rangeLogs(podName, container string, previous bool, startTime, endTime time.Time) ([]byte, error) {
+	limit := int64(maxPodLogBytes)
+	since := metav1.NewTime(startTime)
+	content, err := c.clientset.CoreV1().Pods(c.env.Namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		Timestamps: true,
+		SinceTime:  &since,
+		LimitBytes: &limit,
+	}).DoRaw(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPodLogBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		timestamp, _, _ := strings.Cut(line, " ")
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil && t.After(endTime) {
+			break
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), scanner.Err()
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
@@ -217,6 +217,9 @@ func GetProduct(username, envName, productName string, log *zap.SugaredLogger) (
 		prod.RegistryID = reg.ID.Hex()
 	}
 	resp := buildProductResp(prod.EnvName, prod, log)
+	if resp.SmokeTest, err = GetEnvSmokeTestHealth(productName, envName); err != nil {
+		log.Warnf("failed to get smoke test health of env %s/%s, err: %s", productName, envName, err)
+	}
 	return resp, nil
 }
 
@@ -242,6 +245,11 @@ func buildProductResp(envName string, prod *commonmodels.Product, log *zap.Sugar
 		ShareEnvEnable:  prod.ShareEnv.Enable,
 		ShareEnvIsBase:  prod.ShareEnv.IsBase,
 		ShareEnvBaseEnv: prod.ShareEnv.BaseEnv,
+
+		RequireDiffApproval:   prod.RequireDiffApproval,
+		AutoRollback:          prod.AutoRollback,
+		NamespaceMeta:         prod.NamespaceMeta,
+		ClusterSharedServices: prod.ClusterSharedServices,
 	}
 
 	if prod.ClusterID != "" {
@@ -25,6 +25,9 @@ import (
 	"helm.sh/helm/v3/pkg/releaseutil"
 	versionedclient "istio.io/client-go/pkg/clientset/versioned"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -163,6 +166,12 @@ func RestartScale(args *RestartScaleArgs, _ *zap.SugaredLogger) error {
 		err = updater.RestartDeployment(prod.Namespace, args.Name, kubeClient)
 	case setting.StatefulSet:
 		err = updater.RestartStatefulSet(prod.Namespace, args.Name, kubeClient)
+	case setting.DaemonSet:
+		err = updater.RestartDaemonSet(prod.Namespace, args.Name, kubeClient)
+	case setting.Job:
+		err = rerunJob(prod.Namespace, args.Name, kubeClient)
+	case setting.CronJob:
+		err = triggerCronJob(prod.Namespace, args.Name, kubeClient)
 	}
 
 	if err != nil {
@@ -247,6 +256,31 @@ func GetService(envName, productName, serviceName string, workLoadType string, l
 					break
 				}
 			}
+		case setting.DaemonSet:
+			ds, found, err := getter.GetDaemonSet(namespace, serviceName, kubeClient)
+			if !found || err != nil {
+				return nil, e.ErrGetService.AddDesc(fmt.Sprintf("service %s not found", serviceName))
+			}
+			ret.Scales = append(ret.Scales, getDaemonSetWorkloadResource(ds, kubeClient, log))
+			podLabels := labels.Set(ds.Spec.Template.GetLabels())
+			for _, svc := range k8sServices {
+				if labels.SelectorFromValidatedSet(svc.Spec.Selector).Matches(podLabels) {
+					ret.Services = append(ret.Services, wrapper.Service(svc).Resource())
+					break
+				}
+			}
+		case setting.Job:
+			job, found, err := getter.GetJob(namespace, serviceName, kubeClient)
+			if !found || err != nil {
+				return nil, e.ErrGetService.AddDesc(fmt.Sprintf("service %s not found", serviceName))
+			}
+			ret.Scales = append(ret.Scales, getJobWorkloadResource(job, kubeClient, log))
+		case setting.CronJob:
+			cj, found, err := getter.GetCronJob(namespace, serviceName, kubeClient)
+			if !found || err != nil {
+				return nil, e.ErrGetService.AddDesc(fmt.Sprintf("service %s not found", serviceName))
+			}
+			ret.Scales = append(ret.Scales, getCronJobWorkloadResource(cj, kubeClient, log))
 		default:
 			return nil, e.ErrGetService.AddDesc(fmt.Sprintf("service %s not found", serviceName))
 		}
@@ -327,6 +361,33 @@ func GetService(envName, productName, serviceName string, workLoadType string, l
 
 				ret.Scales = append(ret.Scales, getStatefulSetWorkloadResource(sts, kubeClient, log))
 
+			case setting.DaemonSet:
+				ds, found, err := getter.GetDaemonSet(namespace, u.GetName(), kubeClient)
+				if err != nil || !found {
+					log.Warnf("failed to get daemonSet %s %s:%s %v", u.GetName(), service.ServiceName, namespace, err)
+					continue
+				}
+
+				ret.Scales = append(ret.Scales, getDaemonSetWorkloadResource(ds, kubeClient, log))
+
+			case setting.Job:
+				job, found, err := getter.GetJob(namespace, u.GetName(), kubeClient)
+				if err != nil || !found {
+					log.Warnf("failed to get job %s %s:%s %v", u.GetName(), service.ServiceName, namespace, err)
+					continue
+				}
+
+				ret.Scales = append(ret.Scales, getJobWorkloadResource(job, kubeClient, log))
+
+			case setting.CronJob:
+				cj, found, err := getter.GetCronJob(namespace, u.GetName(), kubeClient)
+				if err != nil || !found {
+					log.Warnf("failed to get cronJob %s %s:%s %v", u.GetName(), service.ServiceName, namespace, err)
+					continue
+				}
+
+				ret.Scales = append(ret.Scales, getCronJobWorkloadResource(cj, kubeClient, log))
+
 			case setting.Ingress:
 
 				version, err := clientset.Discovery().ServerVersion()
@@ -451,6 +512,15 @@ func RestartService(envName string, args *SvcOptArgs, log *zap.SugaredLogger) (e
 				}
 			}
 		}
+
+		if daemonSets, err := getter.ListDaemonSets(productObj.Namespace, selector, kubeClient); err == nil {
+			log.Infof("namespace:%s , selector:%s , len(daemonSets):%d", productObj.Namespace, selector, len(daemonSets))
+			for _, daemonSet := range daemonSets {
+				if err = updater.RestartDaemonSet(productObj.Namespace, daemonSet.Name, kubeClient); err != nil {
+					errList = multierror.Append(errList, err)
+				}
+			}
+		}
 	case setting.SourceFromHelm:
 		deploy, found, err := getter.GetDeployment(productObj.Namespace, args.ServiceName, kubeClient)
 		if err != nil {
@@ -467,6 +537,14 @@ func RestartService(envName string, args *SvcOptArgs, log *zap.SugaredLogger) (e
 		if found {
 			return updater.RestartStatefulSet(productObj.Namespace, sts.Name, kubeClient)
 		}
+
+		ds, found, err := getter.GetDaemonSet(productObj.Namespace, args.ServiceName, kubeClient)
+		if err != nil {
+			return fmt.Errorf("failed to find resource %s, type %s, err %s", args.ServiceName, setting.DaemonSet, err.Error())
+		}
+		if found {
+			return updater.RestartDaemonSet(productObj.Namespace, ds.Name, kubeClient)
+		}
 	default:
 		var serviceTmpl *commonmodels.Service
 		var newRender *commonmodels.RenderSet
@@ -596,3 +674,63 @@ func getStatefulSetWorkloadResource(sts *appsv1.StatefulSet, kubeClient client.C
 
 	return wrapper.StatefulSet(sts).WorkloadResource(pods)
 }
+
+func getDaemonSetWorkloadResource(ds *appsv1.DaemonSet, kubeClient client.Client, log *zap.SugaredLogger) *internalresource.Workload {
+	pods, err := getter.ListPods(ds.Namespace, labels.SelectorFromValidatedSet(ds.Spec.Selector.MatchLabels), kubeClient)
+	if err != nil {
+		log.Warnf("Failed to get pods, err: %s", err)
+	}
+
+	return wrapper.DaemonSet(ds).WorkloadResource(pods)
+}
+
+func getJobWorkloadResource(job *batchv1.Job, kubeClient client.Client, log *zap.SugaredLogger) *internalresource.Workload {
+	var pods []*corev1.Pod
+	if job.Spec.Selector != nil {
+		var err error
+		pods, err = getter.ListPods(job.Namespace, labels.SelectorFromValidatedSet(job.Spec.Selector.MatchLabels), kubeClient)
+		if err != nil {
+			log.Warnf("Failed to get pods, err: %s", err)
+		}
+	}
+
+	return wrapper.Job(job).WorkloadResource(pods)
+}
+
+// getCronJobWorkloadResource returns the cronJob with the pods of the jobs it created.
+func getCronJobWorkloadResource(cj *batchv1beta1.CronJob, kubeClient client.Client, log *zap.SugaredLogger) *internalresource.Workload {
+	var pods []*corev1.Pod
+	if podLabels := cj.Spec.JobTemplate.Spec.Template.Labels; len(podLabels) > 0 {
+		var err error
+		pods, err = getter.ListPods(cj.Namespace, labels.SelectorFromValidatedSet(podLabels), kubeClient)
+		if err != nil {
+			log.Warnf("Failed to get pods, err: %s", err)
+		}
+	}
+
+	return wrapper.CronJob(cj).WorkloadResource(pods)
+}
+
+// rerunJob runs the job again by recreating it, since a finished job can not be restarted.
+func rerunJob(namespace, name string, kubeClient client.Client) error {
+	job, found, err := getter.GetJob(namespace, name, kubeClient)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("job %s/%s not found", namespace, name)
+	}
+	return updater.RecreateJob(job, kubeClient)
+}
+
+// triggerCronJob creates a job from the cronJob immediately.
+func triggerCronJob(namespace, name string, kubeClient client.Client) error {
+	cj, found, err := getter.GetCronJob(namespace, name, kubeClient)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("cronJob %s/%s not found", namespace, name)
+	}
+	return updater.TriggerCronJob(cj, kubeClient)
+}
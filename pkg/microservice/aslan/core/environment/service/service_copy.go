@@ -0,0 +1,406 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+)
+
+const (
+	serviceCopyItemRevision = "revision"
+	serviceCopyItemImage    = "image"
+	serviceCopyItemVariable = "variable"
+	serviceCopyItemValues   = "values"
+	serviceCopyItemReplicas = "replicas"
+)
+
+type CopyServiceArgs struct {
+	SourceEnv string `json:"source_env" form:"sourceEnv"`
+	// Replicas copies the replicas of the workloads as well, only for the k8s yaml environments.
+	Replicas bool `json:"replicas"`
+}
+
+// ServiceCopyDiff is a change made to the target environment, Current is the value in the target
+// environment and Incoming is the value copied from the source environment.
+type ServiceCopyDiff struct {
+	Item     string `json:"item"`
+	Name     string `json:"name"`
+	Current  string `json:"current"`
+	Incoming string `json:"incoming"`
+}
+
+type ServiceCopyPreview struct {
+	ServiceName string             `json:"service_name"`
+	SourceEnv   string             `json:"source_env"`
+	TargetEnv   string             `json:"target_env"`
+	Diffs       []*ServiceCopyDiff `json:"diffs"`
+	// Current and Incoming are the rendered yaml of the k8s yaml service in the target environment
+	// before and after the copy.
+	Current  string `json:"current,omitempty"`
+	Incoming string `json:"incoming,omitempty"`
+}
+
+type serviceCopyContext struct {
+	source, target             *commonmodels.Product
+	sourceSvc, targetSvc       *commonmodels.ProductService
+	sourceRender, targetRender *commonmodels.RenderSet
+	sourceReplicas             map[string]int
+	targetReplicas             map[string]int
+	targetKubeClient           client.Client
+}
+
+// PreviewServiceCopy shows the changes made to the environment if the service is copied from the source environment.
+func PreviewServiceCopy(projectName, envName, serviceName string, args *CopyServiceArgs, log *zap.SugaredLogger) (*ServiceCopyPreview, error) {
+	ctx, err := newServiceCopyContext(projectName, envName, serviceName, args, log)
+	if err != nil {
+		return nil, e.ErrPreviewServiceCopy.AddErr(err)
+	}
+	resp := &ServiceCopyPreview{ServiceName: serviceName, SourceEnv: args.SourceEnv, TargetEnv: envName, Diffs: ctx.diffs()}
+
+	if ctx.target.Source != setting.SourceFromHelm {
+		current, err := renderService(ctx.target, ctx.targetRender, ctx.targetSvc)
+		if err != nil {
+			return nil, e.ErrPreviewServiceCopy.AddErr(err)
+		}
+		copied := *ctx.targetSvc
+		copied.Revision = ctx.sourceSvc.Revision
+		copied.Containers = ctx.sourceSvc.Containers
+		incoming, err := renderService(ctx.target, ctx.copiedRender(), &copied)
+		if err != nil {
+			return nil, e.ErrPreviewServiceCopy.AddErr(err)
+		}
+		resp.Current, resp.Incoming = *current, *incoming
+	}
+	return resp, nil
+}
+
+// CopyService copies the revision, images, variables or values and optionally the replicas of a service from
+// the source environment to the environment, other services of the environment are not touched.
+func CopyService(projectName, envName, serviceName string, args *CopyServiceArgs, userName, requestID string, log *zap.SugaredLogger) error {
+	ctx, err := newServiceCopyContext(projectName, envName, serviceName, args, log)
+	if err != nil {
+		return e.ErrCopyService.AddErr(err)
+	}
+	if len(ctx.diffs()) == 0 {
+		return nil
+	}
+	if ctx.target.Source == setting.SourceFromHelm {
+		return ctx.copyHelmService(userName, requestID, log)
+	}
+	return ctx.copyK8sService(userName, log)
+}
+
+func newServiceCopyContext(projectName, envName, serviceName string, args *CopyServiceArgs, log *zap.SugaredLogger) (*serviceCopyContext, error) {
+	if args.SourceEnv == "" || args.SourceEnv == envName {
+		return nil, fmt.Errorf("source env must be different from the target env")
+	}
+	ctx := &serviceCopyContext{}
+	var err error
+	if ctx.source, err = commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: args.SourceEnv}); err != nil {
+		return nil, fmt.Errorf("failed to find env %s: %s", args.SourceEnv, err)
+	}
+	if ctx.target, err = commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName}); err != nil {
+		return nil, fmt.Errorf("failed to find env %s: %s", envName, err)
+	}
+	for _, env := range []*commonmodels.Product{ctx.source, ctx.target} {
+		if env.Source == setting.SourceFromExternal || env.Source == setting.PMDeployType || env.IsExisted {
+			return nil, fmt.Errorf("env %s is not created by zadig", env.EnvName)
+		}
+	}
+
+	var ok bool
+	if ctx.sourceSvc, ok = ctx.source.GetServiceMap()[serviceName]; !ok {
+		return nil, fmt.Errorf("service %s not found in env %s", serviceName, args.SourceEnv)
+	}
+	if ctx.targetSvc, ok = ctx.target.GetServiceMap()[serviceName]; !ok {
+		return nil, fmt.Errorf("service %s not found in env %s", serviceName, envName)
+	}
+	if ctx.sourceSvc.Type != ctx.targetSvc.Type {
+		return nil, fmt.Errorf("service %s is deployed differently in the two envs", serviceName)
+	}
+
+	if ctx.sourceRender, err = getEnvRenderSet(ctx.source, log); err != nil {
+		return nil, err
+	}
+	if ctx.targetRender, err = getEnvRenderSet(ctx.target, log); err != nil {
+		return nil, err
+	}
+
+	if args.Replicas && ctx.target.Source != setting.SourceFromHelm {
+		if ctx.sourceReplicas, _, err = getServiceReplicas(ctx.source, serviceName); err != nil {
+			return nil, err
+		}
+		if ctx.targetReplicas, ctx.targetKubeClient, err = getServiceReplicas(ctx.target, serviceName); err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+func getEnvRenderSet(env *commonmodels.Product, log *zap.SugaredLogger) (*commonmodels.RenderSet, error) {
+	if env.Render == nil {
+		return &commonmodels.RenderSet{}, nil
+	}
+	renderSet, err := commonservice.GetRenderSet(env.Render.Name, env.Render.Revision, false, env.EnvName, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find renderset of env %s: %s", env.EnvName, err)
+	}
+	return renderSet, nil
+}
+
+// getServiceReplicas returns the replicas of the deployments and statefulsets of the service by name.
+func getServiceReplicas(env *commonmodels.Product, serviceName string) (map[string]int, client.Client, error) {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	selector := labels.Set{setting.ProductLabel: env.ProductName, setting.ServiceLabel: serviceName}.AsSelector()
+	replicas := map[string]int{}
+	deployments, err := getter.ListDeployments(env.Namespace, selector, kubeClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range deployments {
+		if d.Spec.Replicas != nil {
+			replicas[setting.Deployment+"/"+d.Name] = int(*d.Spec.Replicas)
+		}
+	}
+	statefulSets, err := getter.ListStatefulSets(env.Namespace, selector, kubeClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range statefulSets {
+		if s.Spec.Replicas != nil {
+			replicas[setting.StatefulSet+"/"+s.Name] = int(*s.Spec.Replicas)
+		}
+	}
+	return replicas, kubeClient, nil
+}
+
+func (ctx *serviceCopyContext) diffs() []*ServiceCopyDiff {
+	diffs := make([]*ServiceCopyDiff, 0)
+	add := func(item, name, current, incoming string) {
+		if current != incoming {
+			diffs = append(diffs, &ServiceCopyDiff{Item: item, Name: name, Current: current, Incoming: incoming})
+		}
+	}
+
+	add(serviceCopyItemRevision, ctx.targetSvc.ServiceName, strconv.FormatInt(ctx.targetSvc.Revision, 10), strconv.FormatInt(ctx.sourceSvc.Revision, 10))
+
+	targetImages := map[string]string{}
+	for _, container := range ctx.targetSvc.Containers {
+		targetImages[container.Name] = container.Image
+	}
+	for _, container := range ctx.sourceSvc.Containers {
+		add(serviceCopyItemImage, container.Name, targetImages[container.Name], container.Image)
+	}
+
+	if ctx.target.Source == setting.SourceFromHelm {
+		current, incoming := findRenderChart(ctx.targetRender, ctx.targetSvc.ServiceName), findRenderChart(ctx.sourceRender, ctx.sourceSvc.ServiceName)
+		add(serviceCopyItemValues, "override_yaml", renderChartOverrideYaml(current), renderChartOverrideYaml(incoming))
+		add(serviceCopyItemValues, "override_values", renderChartOverrideValues(current), renderChartOverrideValues(incoming))
+	} else {
+		targetKVs := map[string]string{}
+		for _, kv := range ctx.targetRender.KVs {
+			targetKVs[kv.Key] = kv.Value
+		}
+		for _, kv := range serviceKVs(ctx.sourceRender, ctx.sourceSvc.ServiceName) {
+			add(serviceCopyItemVariable, kv.Key, targetKVs[kv.Key], kv.Value)
+		}
+	}
+
+	names := make([]string, 0, len(ctx.sourceReplicas))
+	for name := range ctx.sourceReplicas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// the workloads not in the target environment are created by the copied yaml.
+		if current, ok := ctx.targetReplicas[name]; ok {
+			add(serviceCopyItemReplicas, name, strconv.Itoa(current), strconv.Itoa(ctx.sourceReplicas[name]))
+		}
+	}
+	return diffs
+}
+
+// copiedRender returns the renderset of the target environment with the variables of the service copied.
+func (ctx *serviceCopyContext) copiedRender() *commonmodels.RenderSet {
+	render := *ctx.targetRender
+	render.KVs = make([]*templatemodels.RenderKV, 0, len(ctx.targetRender.KVs))
+	incoming := map[string]string{}
+	for _, kv := range serviceKVs(ctx.sourceRender, ctx.sourceSvc.ServiceName) {
+		incoming[kv.Key] = kv.Value
+	}
+	for _, kv := range ctx.targetRender.KVs {
+		copied := *kv
+		if value, ok := incoming[kv.Key]; ok {
+			copied.Value = value
+			delete(incoming, kv.Key)
+		}
+		render.KVs = append(render.KVs, &copied)
+	}
+	for _, kv := range serviceKVs(ctx.sourceRender, ctx.sourceSvc.ServiceName) {
+		if _, ok := incoming[kv.Key]; ok {
+			copied := *kv
+			render.KVs = append(render.KVs, &copied)
+		}
+	}
+	return &render
+}
+
+func (ctx *serviceCopyContext) copyK8sService(userName string, log *zap.SugaredLogger) error {
+	if ctx.target.Render != nil {
+		render := ctx.copiedRender()
+		if err := commonservice.CreateRenderSetByMerge(&commonmodels.RenderSet{
+			Name:        ctx.target.Render.Name,
+			EnvName:     ctx.target.EnvName,
+			ProductTmpl: ctx.target.ProductName,
+			UpdateBy:    userName,
+			KVs:         render.KVs,
+		}, log); err != nil {
+			return e.ErrCopyService.AddErr(err)
+		}
+		latest, err := commonservice.GetRenderSet(ctx.target.Render.Name, 0, false, ctx.target.EnvName, log)
+		if err != nil {
+			return e.ErrCopyService.AddErr(err)
+		}
+		ctx.target.Render.Revision = latest.Revision
+		if err := commonrepo.NewProductColl().UpdateRender(ctx.target.EnvName, ctx.target.ProductName, ctx.target.Render); err != nil {
+			return e.ErrCopyService.AddErr(err)
+		}
+	}
+
+	err := UpdateService(&SvcOptArgs{
+		EnvName:     ctx.target.EnvName,
+		ProductName: ctx.target.ProductName,
+		ServiceName: ctx.targetSvc.ServiceName,
+		ServiceType: ctx.targetSvc.Type,
+		ServiceRev: &SvcRevision{
+			ServiceName:     ctx.targetSvc.ServiceName,
+			Type:            ctx.targetSvc.Type,
+			CurrentRevision: ctx.targetSvc.Revision,
+			NextRevision:    ctx.sourceSvc.Revision,
+			Updatable:       true,
+			Containers:      ctx.sourceSvc.Containers,
+		},
+		UpdateBy: userName,
+	}, log)
+	if err != nil {
+		return err
+	}
+
+	for name, replicas := range ctx.sourceReplicas {
+		current, ok := ctx.targetReplicas[name]
+		if !ok || current == replicas {
+			continue
+		}
+		kind, workloadName, _ := strings.Cut(name, "/")
+		switch kind {
+		case setting.Deployment:
+			err = updater.ScaleDeployment(ctx.target.Namespace, workloadName, replicas, ctx.targetKubeClient)
+		case setting.StatefulSet:
+			err = updater.ScaleStatefulSet(ctx.target.Namespace, workloadName, replicas, ctx.targetKubeClient)
+		}
+		if err != nil {
+			log.Errorf("failed to scale %s in env %s, err: %s", name, ctx.target.EnvName, err)
+			return e.ErrCopyService.AddErr(err)
+		}
+	}
+	return nil
+}
+
+func (ctx *serviceCopyContext) copyHelmService(userName, requestID string, log *zap.SugaredLogger) error {
+	serviceName := ctx.targetSvc.ServiceName
+	incoming := findRenderChart(ctx.sourceRender, serviceName)
+	if incoming == nil {
+		return e.ErrCopyService.AddDesc(fmt.Sprintf("values of service %s not found in env %s", serviceName, ctx.source.EnvName))
+	}
+
+	// the chart of the copied revision is installed with the values of the source environment.
+	ctx.targetSvc.Revision = ctx.sourceSvc.Revision
+	ctx.targetSvc.Containers = ctx.sourceSvc.Containers
+	if err := commonrepo.NewProductColl().Update(ctx.target); err != nil {
+		log.Errorf("failed to update services of env %s, err: %s", ctx.target.EnvName, err)
+		return e.ErrCopyService.AddErr(err)
+	}
+
+	chart := *incoming
+	chartInfos := make([]*templatemodels.RenderChart, 0, len(ctx.targetRender.ChartInfos))
+	for _, rc := range ctx.targetRender.ChartInfos {
+		if rc.ServiceName == serviceName {
+			rc = &chart
+		}
+		chartInfos = append(chartInfos, rc)
+	}
+	ctx.targetRender.ChartInfos = chartInfos
+	return UpdateHelmProductVariable(ctx.target.ProductName, ctx.target.EnvName, userName, requestID, []*templatemodels.RenderChart{&chart}, ctx.targetRender, log)
+}
+
+// serviceKVs returns the variables used by the service.
+func serviceKVs(render *commonmodels.RenderSet, serviceName string) []*templatemodels.RenderKV {
+	var kvs []*templatemodels.RenderKV
+	for _, kv := range render.KVs {
+		for _, svc := range kv.Services {
+			if svc == serviceName {
+				kvs = append(kvs, kv)
+				break
+			}
+		}
+	}
+	return kvs
+}
+
+func findRenderChart(render *commonmodels.RenderSet, serviceName string) *templatemodels.RenderChart {
+	for _, rc := range render.ChartInfos {
+		if rc.ServiceName == serviceName {
+			return rc
+		}
+	}
+	return nil
+}
+
+func renderChartOverrideYaml(rc *templatemodels.RenderChart) string {
+	if rc == nil || rc.OverrideYaml == nil {
+		return ""
+	}
+	return rc.OverrideYaml.YamlContent
+}
+
+func renderChartOverrideValues(rc *templatemodels.RenderChart) string {
+	if rc == nil {
+		return ""
+	}
+	return rc.OverrideValues
+}
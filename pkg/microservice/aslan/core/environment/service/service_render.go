@@ -0,0 +1,245 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/cache"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// the rendered yaml is keyed by the hash of everything it is rendered from, so it never goes
+// stale, the ttl only bounds the size of the cache.
+const renderCacheTTL = 24 * time.Hour
+
+type RenderEnvServicesArgs struct {
+	// ServiceNames are the services to render, all the services of the environment are rendered if it is empty.
+	ServiceNames []string
+	// OnlyChanged renders only the services whose template has a newer revision than the deployed one.
+	OnlyChanged bool
+	// Latest renders the latest revision of the templates instead of the deployed one.
+	Latest bool
+}
+
+type RenderedService struct {
+	ServiceName string `json:"service_name"`
+	Revision    int64  `json:"revision"`
+	// Changed is true if the template has a newer revision than the deployed one.
+	Changed bool   `json:"changed"`
+	Yaml    string `json:"yaml"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RenderEnvServices renders the yaml of some services of a k8s yaml environment, so that large
+// projects do not have to render all their services at once.
+func RenderEnvServices(productName, envName string, args *RenderEnvServicesArgs, log *zap.SugaredLogger) ([]*RenderedService, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
+	if err != nil {
+		return nil, e.ErrRenderEnvServices.AddErr(err)
+	}
+	if getProjectType(productName) != setting.K8SDeployType {
+		return nil, e.ErrRenderEnvServices.AddDesc("only k8s yaml projects are supported")
+	}
+
+	renderSet := &commonmodels.RenderSet{}
+	if env.Render != nil {
+		renderSet, err = commonservice.GetRenderSet(env.Render.Name, env.Render.Revision, false, envName, log)
+		if err != nil {
+			log.Errorf("failed to find renderset of %s/%s, err: %s", productName, envName, err)
+			return nil, e.ErrRenderEnvServices.AddErr(err)
+		}
+	}
+
+	serviceMap := env.GetServiceMap()
+	names := args.ServiceNames
+	if len(names) == 0 {
+		for name := range serviceMap {
+			names = append(names, name)
+		}
+	}
+	names = sets.NewString(names...).List()
+
+	envServices := make([]*commonmodels.ProductService, 0, len(names))
+	for _, name := range names {
+		if svc, ok := serviceMap[name]; ok && svc.Type == setting.K8SDeployType {
+			envServices = append(envServices, svc)
+		}
+	}
+	deployed, latest, err := listEnvServiceTemplates(productName, envServices)
+	if err != nil {
+		log.Errorf("failed to list service templates of %s/%s, err: %s", productName, envName, err)
+		return nil, e.ErrRenderEnvServices.AddErr(err)
+	}
+
+	resp := make([]*RenderedService, 0, len(names))
+	for _, name := range names {
+		svc, ok := serviceMap[name]
+		if !ok {
+			resp = append(resp, &RenderedService{ServiceName: name, Error: fmt.Sprintf("service %s is not in the environment", name)})
+			continue
+		}
+		if svc.Type != setting.K8SDeployType {
+			continue
+		}
+
+		latestTmpl := latest[name]
+		changed := latestTmpl != nil && latestTmpl.Revision != svc.Revision
+		if args.OnlyChanged && !changed {
+			continue
+		}
+		tmpl := deployed[name]
+		if args.Latest && latestTmpl != nil {
+			tmpl = latestTmpl
+		}
+		if tmpl == nil {
+			resp = append(resp, &RenderedService{ServiceName: name, Revision: svc.Revision, Changed: changed, Error: "service template not found"})
+			continue
+		}
+		resp = append(resp, &RenderedService{
+			ServiceName: name,
+			Revision:    tmpl.Revision,
+			Changed:     changed,
+			Yaml:        renderServiceYaml(env, renderSet, tmpl, svc.Containers),
+		})
+	}
+	return resp, nil
+}
+
+// listEnvServiceTemplates finds the deployed and the latest templates of the services with two queries.
+func listEnvServiceTemplates(productName string, services []*commonmodels.ProductService) (map[string]*commonmodels.Service, map[string]*commonmodels.Service, error) {
+	deployed := make(map[string]*commonmodels.Service)
+	latest := make(map[string]*commonmodels.Service)
+	if len(services) == 0 {
+		return deployed, latest, nil
+	}
+
+	revisions := make([]*commonrepo.ServiceRevision, 0, len(services))
+	infos := make([]*templatemodels.ServiceInfo, 0, len(services))
+	for _, svc := range services {
+		revisions = append(revisions, &commonrepo.ServiceRevision{ServiceName: svc.ServiceName, Revision: svc.Revision})
+		infos = append(infos, &templatemodels.ServiceInfo{Name: svc.ServiceName, Owner: svc.ProductName})
+	}
+
+	deployedTmpls, err := commonrepo.NewServiceColl().ListServicesWithSRevision(&commonrepo.SvcRevisionListOption{
+		ProductName:      productName,
+		ServiceRevisions: revisions,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tmpl := range deployedTmpls {
+		if tmpl.Type == setting.K8SDeployType {
+			deployed[tmpl.ServiceName] = tmpl
+		}
+	}
+
+	latestTmpls, err := commonrepo.NewServiceColl().ListMaxRevisionsForServices(infos, setting.K8SDeployType)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tmpl := range latestTmpls {
+		latest[tmpl.ServiceName] = tmpl
+	}
+	return deployed, latest, nil
+}
+
+// renderServiceYaml renders the template of a service for the environment, the result is cached
+// by the hash of the template, the variables and the images.
+func renderServiceYaml(prod *commonmodels.Product, render *commonmodels.RenderSet, svcTmpl *commonmodels.Service, containers []*commonmodels.Container) string {
+	key := renderCacheKey(prod, render, svcTmpl, containers)
+	var parsedYaml string
+	if cache.Get(key, &parsedYaml) {
+		return parsedYaml
+	}
+
+	// 渲染配置集
+	parsedYaml = commonservice.RenderValueForString(svcTmpl.Yaml, render)
+	// 渲染系统变量键值
+	parsedYaml = kube.ParseSysKeys(prod.Namespace, prod.EnvName, prod.ProductName, svcTmpl.ServiceName, parsedYaml)
+	// 替换服务模板容器镜像为用户指定镜像
+	parsedYaml = replaceContainerImages(parsedYaml, svcTmpl.Containers, containers)
+
+	cache.Set(key, parsedYaml, renderCacheTTL)
+	return parsedYaml
+}
+
+func renderCacheKey(prod *commonmodels.Product, render *commonmodels.RenderSet, svcTmpl *commonmodels.Service, containers []*commonmodels.Container) string {
+	if !cache.Enabled() {
+		return ""
+	}
+	kvs := make([]string, 0)
+	if render != nil {
+		for _, kv := range render.KVs {
+			if kv.State == "unused" {
+				continue
+			}
+			kvs = append(kvs, kv.Key+"="+kv.Value)
+		}
+	}
+	images := make([]string, 0, len(svcTmpl.Containers)+len(containers))
+	for _, container := range svcTmpl.Containers {
+		images = append(images, container.Name+"="+container.Image)
+	}
+	images = append(images, "")
+	for _, container := range containers {
+		images = append(images, container.Name+"="+container.Image)
+	}
+	return "render:" + cache.Hash(prod.Namespace, prod.EnvName, prod.ProductName, svcTmpl.ServiceName, svcTmpl.Yaml,
+		strings.Join(kvs, "\x00"), strings.Join(images, "\x00"))
+}
+
+// PreRenderService renders the latest template of a service in all the environments containing it
+// in the background, so that the following deployments and previews hit the cache.
+func PreRenderService(svcTmpl *commonmodels.Service, log *zap.SugaredLogger) {
+	if !cache.Enabled() || svcTmpl.Type != setting.K8SDeployType {
+		return
+	}
+	go func() {
+		envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{Name: svcTmpl.ProductName})
+		if err != nil {
+			log.Warnf("failed to list environments of %s to pre-render service %s, err: %s", svcTmpl.ProductName, svcTmpl.ServiceName, err)
+			return
+		}
+		for _, env := range envs {
+			svc, ok := env.GetServiceMap()[svcTmpl.ServiceName]
+			if !ok {
+				continue
+			}
+			renderSet := &commonmodels.RenderSet{}
+			if env.Render != nil {
+				renderSet, err = commonservice.GetRenderSet(env.Render.Name, env.Render.Revision, false, env.EnvName, log)
+				if err != nil {
+					log.Warnf("failed to find renderset of %s/%s to pre-render service %s, err: %s", env.ProductName, env.EnvName, svcTmpl.ServiceName, err)
+					continue
+				}
+			}
+			renderServiceYaml(env, renderSet, svcTmpl, svc.Containers)
+		}
+	}()
+}
@@ -22,4 +22,5 @@ const (
 	ResourceTypeWorkflow       ResourceType = "Workflow"
 	ResourceTypeCommonWorkflow ResourceType = "CommonWorkflow"
 	ResourceTypeEnvironment    ResourceType = "Environment"
+	ResourceTypeService        ResourceType = "Service"
 )
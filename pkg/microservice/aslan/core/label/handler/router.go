@@ -30,6 +30,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		labels.POST("/bulk-delete", DeleteLabels)
 		labels.POST("/resources-by-labels", ListResourcesByLabels)
 		labels.POST("/labels-by-resources", ListLabelsByResources)
+		labels.GET("/search", SearchResources)
 	}
 	labelBindings := router.Group("labelbindings")
 	{
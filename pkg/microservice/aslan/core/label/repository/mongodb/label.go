@@ -129,6 +129,23 @@ func (c *LabelColl) ListByProjectName(projectName string) ([]*models.Label, erro
 	return res, nil
 }
 
+func (c *LabelColl) ListByKey(key string) ([]*models.Label, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	var res []*models.Label
+	ctx := context.Background()
+	cursor, err := c.Collection.Find(ctx, bson.M{"key": key})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (c *LabelColl) Find(id string) (*models.Label, error) {
 	res := new(models.Label)
 	oid, err := primitive.ObjectIDFromHex(id)
@@ -46,17 +46,28 @@ func (c *LabelBindingColl) GetCollectionName() string {
 }
 
 func (c *LabelBindingColl) EnsureIndex(ctx context.Context) error {
-	mod := mongo.IndexModel{
-		Keys: bson.D{
-			bson.E{Key: "resource_name", Value: 1},
-			bson.E{Key: "project_name", Value: 1},
-			bson.E{Key: "label_id", Value: 1},
-			bson.E{Key: "resource_type", Value: 1},
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "resource_name", Value: 1},
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "label_id", Value: 1},
+				bson.E{Key: "resource_type", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		// used by the tag search which looks up bindings by labels first
+		{
+			Keys: bson.D{
+				bson.E{Key: "label_id", Value: 1},
+				bson.E{Key: "resource_type", Value: 1},
+				bson.E{Key: "project_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
 		},
-		Options: options.Index().SetUnique(true),
 	}
 
-	_, err := c.Indexes().CreateOne(ctx, mod)
+	_, err := c.Indexes().CreateMany(ctx, mod)
 	return err
 }
 
@@ -98,6 +109,7 @@ type LabelBindingCollFindOpt struct {
 	ResourceID   string
 	ResourcesIDs []string
 	ResourceType string
+	ProjectName  string
 }
 
 func (c *LabelBindingColl) FindByOpt(opt *LabelBindingCollFindOpt) (*models.LabelBinding, error) {
@@ -128,6 +140,9 @@ func (c *LabelBindingColl) ListByOpt(opt *LabelBindingCollFindOpt) ([]*models.La
 	if opt.ResourceType != "" {
 		query["resource_type"] = opt.ResourceType
 	}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
 	ctx := context.Background()
 
 	cursor, err := c.Collection.Find(ctx, query)
@@ -26,6 +26,7 @@ import (
 	commondb "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/label/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
@@ -118,6 +119,23 @@ func CreateLabelBindings(cr *CreateLabelBindingsArgs, userName string, logger *z
 				logger.Errorf("there're resources not exist")
 				return e.ErrForbidden.AddDesc("there're resources not exist")
 			}
+		case string(config.ResourceTypeService):
+			resourceSet := sets.NewString()
+			for _, vv := range v {
+				if resourceSet.Has(fmt.Sprintf("%s-%s", vv.Resource.Name, vv.Resource.ProjectName)) {
+					continue
+				}
+				resourceSet.Insert(fmt.Sprintf("%s-%s", vv.Resource.Name, vv.Resource.ProjectName))
+				_, err := commondb.NewServiceColl().Find(&commondb.ServiceFindOption{
+					ServiceName:   vv.Resource.Name,
+					ProductName:   vv.Resource.ProjectName,
+					ExcludeStatus: setting.ProductStatusDeleting,
+				})
+				if err != nil {
+					logger.Errorf("can not find service %s in project %s, err:%s", vv.Resource.Name, vv.Resource.ProjectName, err)
+					return e.ErrForbidden.AddDesc("there're resources not exist")
+				}
+			}
 		}
 	}
 
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// ParseSelector parses a tag selector like "team=payments,tier=backend", a resource matches the
+// selector only if it is bound to all of the labels.
+func ParseSelector(selector string) ([]mongodb.Label, error) {
+	var labels []mongodb.Label
+	keyValues := sets.NewString()
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		kv := strings.SplitN(term, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid selector term %q, expected key=value", term)
+		}
+		label := mongodb.Label{Key: strings.TrimSpace(kv[0]), Value: strings.TrimSpace(kv[1])}
+		if keyValues.Has(BuildLabelString(label.Key, label.Value)) {
+			continue
+		}
+		keyValues.Insert(BuildLabelString(label.Key, label.Value))
+		labels = append(labels, label)
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	return labels, nil
+}
+
+type SearchResourcesArgs struct {
+	Selector    string `form:"selector"`
+	Type        string `form:"type"`
+	ProjectName string `form:"projectName"`
+}
+
+type SearchResourcesResp struct {
+	Resources []mongodb.Resource `json:"resources"`
+}
+
+// SearchResources returns the resources bound to all labels of the selector, optionally narrowed down
+// to a resource type and a project.
+func SearchResources(args *SearchResourcesArgs, logger *zap.SugaredLogger) (*SearchResourcesResp, error) {
+	filters, err := ParseSelector(args.Selector)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	labels, err := mongodb.NewLabelColl().List(mongodb.ListLabelOpt{Labels: filters})
+	if err != nil {
+		logger.Errorf("find labels by selector %s err:%s", args.Selector, err)
+		return nil, err
+	}
+	res := make([]mongodb.Resource, 0)
+	if len(labels) < len(filters) {
+		return &SearchResourcesResp{Resources: res}, nil
+	}
+
+	labelIDs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		labelIDs = append(labelIDs, label.ID.Hex())
+	}
+	labelBindings, err := mongodb.NewLabelBindingColl().ListByOpt(&mongodb.LabelBindingCollFindOpt{
+		LabelIDs:     labelIDs,
+		ResourceType: args.Type,
+		ProjectName:  args.ProjectName,
+	})
+	if err != nil {
+		logger.Errorf("list labelBindings err:%s,ids:%v", err, labelIDs)
+		return nil, err
+	}
+
+	matched := make(map[string]int)
+	resources := make(map[string]mongodb.Resource)
+	for _, binding := range labelBindings {
+		resource := mongodb.Resource{
+			Name:        binding.ResourceName,
+			ProjectName: binding.ProjectName,
+			Type:        binding.ResourceType,
+		}
+		key := config.BuildResourceKey(resource.Type, resource.ProjectName, resource.Name)
+		matched[key]++
+		resources[key] = resource
+	}
+	keys := make([]string, 0, len(matched))
+	for key, count := range matched {
+		if count == len(filters) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		res = append(res, resources[key])
+	}
+
+	return &SearchResourcesResp{Resources: res}, nil
+}
+
+// MatchSelector reports whether the resource is bound to all labels of the selector.
+func MatchSelector(resource mongodb.Resource, selector string) (bool, error) {
+	filters, err := ParseSelector(selector)
+	if err != nil {
+		return false, err
+	}
+
+	labelBindings, err := mongodb.NewLabelBindingColl().ListByResources(mongodb.ListLabelBindingsByResources{Resources: []mongodb.Resource{resource}})
+	if err != nil {
+		return false, err
+	}
+	if len(labelBindings) == 0 {
+		return false, nil
+	}
+	labelIDs := sets.NewString()
+	for _, binding := range labelBindings {
+		labelIDs.Insert(binding.LabelID)
+	}
+	labels, err := mongodb.NewLabelColl().ListByIDs(labelIDs.List())
+	if err != nil {
+		return false, err
+	}
+	bound := sets.NewString()
+	for _, label := range labels {
+		bound.Insert(BuildLabelString(label.Key, label.Value))
+	}
+	for _, filter := range filters {
+		if !bound.Has(BuildLabelString(filter.Key, filter.Value)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+)
+
+func TestParseSelector(t *testing.T) {
+	assert := assert.New(t)
+
+	labels, err := ParseSelector("team=payments, tier = backend,team=payments")
+	assert.NoError(err)
+	assert.Equal([]mongodb.Label{
+		{Key: "team", Value: "payments"},
+		{Key: "tier", Value: "backend"},
+	}, labels)
+
+	_, err = ParseSelector("team")
+	assert.Error(err)
+
+	_, err = ParseSelector("team=")
+	assert.Error(err)
+
+	_, err = ParseSelector(" , ")
+	assert.Error(err)
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package handler
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
@@ -167,3 +169,84 @@ func GetScanningContainerLogs(c *gin.Context) {
 
 	ctx.Resp, ctx.Err = logservice.GetScanningContainerLogs(id, taskID, ctx.Logger)
 }
+
+func GetWorkflowV4JobLogRange(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid offset")
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(logservice.DefaultJobLogRangeLimit)), 10, 64)
+	if err != nil || limit <= 0 || limit > logservice.MaxJobLogRangeLimit {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("limit should be between 1 and %d", logservice.MaxJobLogRangeLimit))
+		return
+	}
+	ctx.Resp, ctx.Err = logservice.GetWorkflowV4JobLogRange(strings.ToLower(c.Param("workflowName")), c.Param("jobName"), taskID, offset, limit, ctx.Logger)
+}
+
+func GetWorkflowV4JobLogTail(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	lines, err := strconv.Atoi(c.DefaultQuery("lines", strconv.Itoa(logservice.DefaultJobLogTailLines)))
+	if err != nil || lines <= 0 {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid lines")
+		return
+	}
+	ctx.Resp, ctx.Err = logservice.GetWorkflowV4JobLogTail(strings.ToLower(c.Param("workflowName")), c.Param("jobName"), taskID, lines, ctx.Logger)
+}
+
+// DownloadWorkflowV4JobLog streams the whole job log, it is compressed on the fly if the
+// client accepts gzip.
+func DownloadWorkflowV4JobLog(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	workflowName, jobName := strings.ToLower(c.Param("workflowName")), c.Param("jobName")
+	reader, err := logservice.OpenWorkflowV4JobLog(workflowName, jobName, taskID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	stream, err := reader.Stream()
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	defer stream.Close()
+
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d-%s.log"`, workflowName, taskID, jobName))
+	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	var w io.Writer = c.Writer
+	if strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+		c.Writer.Header().Set("Content-Encoding", "gzip")
+		c.Writer.Header().Set("Vary", "Accept-Encoding")
+		gz := gzip.NewWriter(c.Writer)
+		defer gz.Close()
+		w = gz
+	} else {
+		c.Writer.Header().Set("Content-Length", strconv.FormatInt(reader.Size, 10))
+	}
+	c.Writer.WriteHeaderNow()
+	if _, err := io.Copy(w, stream); err != nil {
+		ctx.Logger.Warnf("failed to send the log of job %s: %s", jobName, err)
+	}
+}
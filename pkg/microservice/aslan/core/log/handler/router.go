@@ -36,6 +36,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		log.GET("/v3/workflow/:workflowName/tasks/:taskId", GetWorkflowBuildV3JobContainerLogs)
 		log.GET("/scanning/:id/task/:scan_id", GetScanningContainerLogs)
 		log.GET("/v4/workflow/:workflowName/tasks/:taskID/jobs/:jobName", GetWorkflowV4JobContainerLogs)
+		log.GET("/v4/workflow/:workflowName/tasks/:taskID/jobs/:jobName/range", GetWorkflowV4JobLogRange)
+		log.GET("/v4/workflow/:workflowName/tasks/:taskID/jobs/:jobName/tail", GetWorkflowV4JobLogTail)
+		log.GET("/v4/workflow/:workflowName/tasks/:taskID/jobs/:jobName/download", DownloadWorkflowV4JobLog)
 	}
 
 	sse := router.Group("sse")
@@ -31,6 +31,7 @@ import (
 	s3service "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/kube/containerlog"
+	"github.com/koderover/zadig/pkg/tool/logstream"
 	s3tool "github.com/koderover/zadig/pkg/tool/s3"
 	"github.com/koderover/zadig/pkg/util"
 )
@@ -172,3 +173,74 @@ func GetScanningContainerLogs(scanID string, taskID int64, log *zap.SugaredLogge
 
 	return buildLog, nil
 }
+
+const (
+	DefaultJobLogRangeLimit = 1 << 20
+	MaxJobLogRangeLimit     = 8 << 20
+	DefaultJobLogTailLines  = 1000
+	maxJobLogTailBytes      = 8 << 20
+)
+
+// JobLogRange is a part of the job log, the log of a running job can be followed by reading
+// from NextOffset until Completed is true.
+type JobLogRange struct {
+	Content    string `json:"content"`
+	Offset     int64  `json:"offset"`
+	NextOffset int64  `json:"next_offset"`
+	Size       int64  `json:"size"`
+	Completed  bool   `json:"completed"`
+}
+
+func OpenWorkflowV4JobLog(workflowName, jobName string, taskID int64, log *zap.SugaredLogger) (*s3service.JobLogReader, error) {
+	jobLog, err := s3service.NewWorkflowJobLog(workflowName, jobName, taskID)
+	if err != nil {
+		log.Errorf("failed to open the log of job %s, err: %s", jobName, err)
+		return nil, err
+	}
+	reader, err := jobLog.Open()
+	if err != nil {
+		log.Errorf("failed to open the log of job %s, err: %s", jobName, err)
+		return nil, err
+	}
+	return reader, nil
+}
+
+// GetWorkflowV4JobLogRange reads at most limit bytes of the job log from the offset.
+func GetWorkflowV4JobLogRange(workflowName, jobName string, taskID, offset, limit int64, log *zap.SugaredLogger) (*JobLogRange, error) {
+	reader, err := OpenWorkflowV4JobLog(workflowName, jobName, taskID, log)
+	if err != nil {
+		return nil, err
+	}
+	content, err := logstream.ReadRange(reader, reader.Size, offset, limit)
+	if err != nil {
+		log.Errorf("failed to read the log of job %s, err: %s", jobName, err)
+		return nil, err
+	}
+	return &JobLogRange{
+		Content:    string(content),
+		Offset:     offset,
+		NextOffset: offset + int64(len(content)),
+		Size:       reader.Size,
+		Completed:  reader.Completed,
+	}, nil
+}
+
+// GetWorkflowV4JobLogTail returns the last lines of the job log.
+func GetWorkflowV4JobLogTail(workflowName, jobName string, taskID int64, lines int, log *zap.SugaredLogger) (*JobLogRange, error) {
+	reader, err := OpenWorkflowV4JobLog(workflowName, jobName, taskID, log)
+	if err != nil {
+		return nil, err
+	}
+	content, offset, err := logstream.Tail(reader, reader.Size, lines, maxJobLogTailBytes)
+	if err != nil {
+		log.Errorf("failed to read the log of job %s, err: %s", jobName, err)
+		return nil, err
+	}
+	return &JobLogRange{
+		Content:    string(content),
+		Offset:     offset,
+		NextOffset: reader.Size,
+		Size:       reader.Size,
+		Completed:  reader.Completed,
+	}, nil
+}
@@ -219,6 +219,8 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 				fallthrough
 			case string(config.JobFreestyle):
 				fallthrough
+			case string(config.JobAndroidBuild):
+				fallthrough
 			case string(config.JobBuild):
 				jobSpec := &commonmodels.JobTaskBuildSpec{}
 				if err := commonmodels.IToi(job.Spec, jobSpec); err != nil {
@@ -226,6 +228,7 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 					return
 				}
 				options.ClusterID = jobSpec.Properties.ClusterID
+				options.Namespace = jobSpec.Properties.Namespace
 			case string(config.JobPlugin):
 				jobSpec := &commonmodels.JobTaskPluginSpec{}
 				if err := commonmodels.IToi(job.Spec, jobSpec); err != nil {
@@ -233,6 +236,7 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 					return
 				}
 				options.ClusterID = jobSpec.Properties.ClusterID
+				options.Namespace = jobSpec.Properties.Namespace
 			default:
 				log.Errorf("get real-time log error, unsupported job type %s", job.JobType)
 				return
@@ -240,6 +244,10 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 			if options.ClusterID == "" {
 				options.ClusterID = setting.LocalClusterID
 			}
+			// the namespace is saved once the job is created, it may be an isolated one.
+			if options.Namespace != "" {
+				break
+			}
 			switch options.ClusterID {
 			case setting.LocalClusterID:
 				options.Namespace = config.Namespace()
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "time"
+
+type SourceType string
+
+const (
+	SourceTypeGit SourceType = "git"
+	SourceTypeOCI SourceType = "oci"
+)
+
+type ItemKind string
+
+const (
+	ItemKindPlugin           ItemKind = "plugin"
+	ItemKindWorkflowTemplate ItemKind = "workflow_template"
+)
+
+const (
+	// CatalogFile is the index of a catalog, it is located in the root of the git repository
+	// or stored as a layer titled with the same name in the oci artifact.
+	CatalogFile = "catalog.yaml"
+	// CatalogSignatureFile holds the base64 encoded signature of the catalog file.
+	CatalogSignatureFile = "catalog.yaml.sig"
+
+	// SyncCheckInterval is how often the sources are checked for a scheduled sync.
+	SyncCheckInterval = time.Minute
+)
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListMarketplaceSources(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListMarketplaceSources(ctx.Logger)
+}
+
+func CreateMarketplaceSource(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.MarketplaceSource)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统设置-插件市场源", args.Name, "", ctx.Logger)
+	args.UpdateBy = ctx.UserName
+	ctx.Err = service.CreateMarketplaceSource(args, ctx.Logger)
+}
+
+func UpdateMarketplaceSource(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.MarketplaceSource)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统设置-插件市场源", c.Param("id"), "", ctx.Logger)
+	args.UpdateBy = ctx.UserName
+	ctx.Err = service.UpdateMarketplaceSource(c.Param("id"), args, ctx.Logger)
+}
+
+func DeleteMarketplaceSource(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统设置-插件市场源", c.Param("id"), "", ctx.Logger)
+	ctx.Err = service.DeleteMarketplaceSource(c.Param("id"), ctx.Logger)
+}
+
+func SyncMarketplaceSource(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "同步", "系统设置-插件市场源", c.Param("id"), "", ctx.Logger)
+	ctx.Err = service.SyncMarketplaceSource(c.Param("id"), ctx.Logger)
+}
+
+func ListMarketplaceItems(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListMarketplaceItems(&mongodb.ListItemsOption{
+		SourceID: c.Query("source_id"),
+		Kind:     config.ItemKind(c.Query("kind")),
+		Name:     c.Query("name"),
+	}, ctx.Logger)
+}
+
+func ListMarketplaceInstallations(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListMarketplaceInstallations(config.ItemKind(c.Query("kind")), ctx.Logger)
+}
+
+func InstallMarketplaceItem(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.InstallArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "安装", "系统设置-插件市场", fmt.Sprintf("%s %s@%s", args.Kind, args.Name, args.Version), "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.InstallMarketplaceItem(ctx.UserName, args, ctx.Logger)
+}
+
+func UpgradeMarketplaceInstallation(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.UpgradeArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "升级", "系统设置-插件市场", fmt.Sprintf("%s@%s", c.Param("id"), args.Version), "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.UpgradeMarketplaceInstallation(c.Param("id"), ctx.UserName, args, ctx.Logger)
+}
+
+func DeleteMarketplaceInstallation(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "卸载", "系统设置-插件市场", c.Param("id"), "", ctx.Logger)
+	ctx.Err = service.DeleteMarketplaceInstallation(c.Param("id"), ctx.Logger)
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type Router struct{}
+
+func (*Router) Inject(router *gin.RouterGroup) {
+	sources := router.Group("sources")
+	{
+		sources.GET("", ListMarketplaceSources)
+		sources.POST("", CreateMarketplaceSource)
+		sources.PUT("/:id", UpdateMarketplaceSource)
+		sources.DELETE("/:id", DeleteMarketplaceSource)
+		sources.POST("/:id/sync", SyncMarketplaceSource)
+	}
+
+	router.GET("/items", ListMarketplaceItems)
+
+	installations := router.Group("installations")
+	{
+		installations.GET("", ListMarketplaceInstallations)
+		installations.POST("", InstallMarketplaceItem)
+		installations.PUT("/:id", UpgradeMarketplaceInstallation)
+		installations.DELETE("/:id", DeleteMarketplaceInstallation)
+	}
+}
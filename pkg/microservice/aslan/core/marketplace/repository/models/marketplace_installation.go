@@ -0,0 +1,47 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+)
+
+// MarketplaceInstallation is a plugin or workflow template installed from a catalog, there is
+// at most one installation for the same kind and name. The content is copied on install so
+// that it keeps working if the version is removed from the catalog later.
+type MarketplaceInstallation struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"    json:"id,omitempty"`
+	SourceID string             `bson:"source_id"        json:"source_id"`
+	Kind     config.ItemKind    `bson:"kind"             json:"kind"`
+	Name     string             `bson:"name"             json:"name"`
+	Version  string             `bson:"version"          json:"version"`
+	// Pinned installations are not upgraded when a newer version is synced.
+	Pinned      bool   `bson:"pinned"           json:"pinned"`
+	Digest      string `bson:"digest"           json:"digest"`
+	Verified    bool   `bson:"verified"         json:"verified"`
+	Content     string `bson:"content"          json:"content"`
+	InstalledBy string `bson:"installed_by"     json:"installed_by"`
+	InstallTime int64  `bson:"install_time"     json:"install_time"`
+	UpdateBy    string `bson:"update_by"        json:"update_by"`
+	UpdateTime  int64  `bson:"update_time"      json:"update_time"`
+}
+
+func (MarketplaceInstallation) TableName() string {
+	return "marketplace_installation"
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+)
+
+// MarketplaceItem is one version of a plugin or workflow template in a synced catalog.
+type MarketplaceItem struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"    json:"id,omitempty"`
+	SourceID    string             `bson:"source_id"        json:"source_id"`
+	Kind        config.ItemKind    `bson:"kind"             json:"kind"`
+	Name        string             `bson:"name"             json:"name"`
+	Version     string             `bson:"version"          json:"version"`
+	Description string             `bson:"description"      json:"description"`
+	// Digest is the sha256 digest of the content, in the form of sha256:<hex>.
+	Digest string `bson:"digest"           json:"digest"`
+	// Verified is true if the item is covered by a verified catalog signature.
+	Verified bool   `bson:"verified"         json:"verified"`
+	Content  string `bson:"content"          json:"content"`
+	SyncTime int64  `bson:"sync_time"        json:"sync_time"`
+}
+
+func (MarketplaceItem) TableName() string {
+	return "marketplace_item"
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+)
+
+// MarketplaceSource is a remote catalog of plugins and workflow templates.
+type MarketplaceSource struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty"         json:"id,omitempty"`
+	Name string             `bson:"name"                  json:"name"`
+	Type config.SourceType  `bson:"type"                  json:"type"`
+	// git source, the repository is cloned with the code host credentials.
+	CodehostID    int    `bson:"codehost_id,omitempty"    json:"codehost_id,omitempty"`
+	RepoOwner     string `bson:"repo_owner,omitempty"     json:"repo_owner,omitempty"`
+	RepoNamespace string `bson:"repo_namespace,omitempty" json:"repo_namespace,omitempty"`
+	RepoName      string `bson:"repo_name,omitempty"      json:"repo_name,omitempty"`
+	Branch        string `bson:"branch,omitempty"         json:"branch,omitempty"`
+	// oci source, e.g. registry.example.com/zadig/catalog:v1.
+	Reference string `bson:"reference,omitempty"      json:"reference,omitempty"`
+	Username  string `bson:"username,omitempty"       json:"username,omitempty"`
+	Password  string `bson:"password,omitempty"       json:"password,omitempty"`
+	PlainHTTP bool   `bson:"plain_http"               json:"plain_http"`
+	// PublicKey is the PEM encoded public key of the catalog signer, unsigned catalogs are
+	// rejected if it is set.
+	PublicKey string `bson:"public_key"               json:"public_key"`
+	// unit is minute, 0 means the source is only synced manually.
+	SyncInterval int64 `bson:"sync_interval"            json:"sync_interval"`
+	// Revision is the digest of the catalog file of the last successful sync.
+	Revision     string `bson:"revision"                 json:"revision"`
+	LastSyncTime int64  `bson:"last_sync_time"           json:"last_sync_time"`
+	Error        string `bson:"error"                    json:"error"`
+	UpdateBy     string `bson:"update_by"                json:"update_by"`
+	UpdateTime   int64  `bson:"update_time"              json:"update_time"`
+}
+
+func (MarketplaceSource) TableName() string {
+	return "marketplace_source"
+}
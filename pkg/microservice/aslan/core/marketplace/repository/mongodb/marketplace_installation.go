@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	marketplaceconfig "github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type MarketplaceInstallationColl struct {
+	*mongo.Collection
+	coll string
+}
+
+func NewMarketplaceInstallationColl() *MarketplaceInstallationColl {
+	name := models.MarketplaceInstallation{}.TableName()
+	return &MarketplaceInstallationColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *MarketplaceInstallationColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *MarketplaceInstallationColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "kind", Value: 1},
+			bson.E{Key: "name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *MarketplaceInstallationColl) Create(installation *models.MarketplaceInstallation) error {
+	installation.InstallTime = time.Now().Unix()
+	installation.UpdateTime = installation.InstallTime
+	res, err := c.InsertOne(context.TODO(), installation)
+	if err != nil {
+		return err
+	}
+	installation.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *MarketplaceInstallationColl) Update(installation *models.MarketplaceInstallation) error {
+	installation.UpdateTime = time.Now().Unix()
+	_, err := c.ReplaceOne(context.TODO(), bson.M{"_id": installation.ID}, installation)
+	return err
+}
+
+func (c *MarketplaceInstallationColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
+
+func (c *MarketplaceInstallationColl) Get(id string) (*models.MarketplaceInstallation, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.MarketplaceInstallation{}
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(res)
+	return res, err
+}
+
+// List returns the installations filtered by the source id and kind, empty means no filter.
+func (c *MarketplaceInstallationColl) List(sourceID string, kind marketplaceconfig.ItemKind) ([]*models.MarketplaceInstallation, error) {
+	res := make([]*models.MarketplaceInstallation, 0)
+	query := bson.M{}
+	if sourceID != "" {
+		query["source_id"] = sourceID
+	}
+	if kind != "" {
+		query["kind"] = kind
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, err
+}
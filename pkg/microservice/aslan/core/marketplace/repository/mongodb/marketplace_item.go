@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	marketplaceconfig "github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type MarketplaceItemColl struct {
+	*mongo.Collection
+	coll string
+}
+
+type ListItemsOption struct {
+	SourceID string
+	Kind     marketplaceconfig.ItemKind
+	Name     string
+}
+
+func NewMarketplaceItemColl() *MarketplaceItemColl {
+	name := models.MarketplaceItem{}.TableName()
+	return &MarketplaceItemColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *MarketplaceItemColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *MarketplaceItemColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "source_id", Value: 1},
+			bson.E{Key: "kind", Value: 1},
+			bson.E{Key: "name", Value: 1},
+			bson.E{Key: "version", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Replace replaces all items of the source with the newly synced ones.
+func (c *MarketplaceItemColl) Replace(sourceID string, items []*models.MarketplaceItem) error {
+	if err := c.DeleteBySource(sourceID); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, item)
+	}
+	_, err := c.InsertMany(context.TODO(), docs)
+	return err
+}
+
+func (c *MarketplaceItemColl) DeleteBySource(sourceID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"source_id": sourceID})
+	return err
+}
+
+func (c *MarketplaceItemColl) Find(sourceID string, kind marketplaceconfig.ItemKind, name, version string) (*models.MarketplaceItem, error) {
+	res := &models.MarketplaceItem{}
+	query := bson.M{"source_id": sourceID, "kind": kind, "name": name, "version": version}
+	err := c.FindOne(context.TODO(), query).Decode(res)
+	return res, err
+}
+
+func (c *MarketplaceItemColl) List(opt *ListItemsOption) ([]*models.MarketplaceItem, error) {
+	res := make([]*models.MarketplaceItem, 0)
+	query := bson.M{}
+	if opt.SourceID != "" {
+		query["source_id"] = opt.SourceID
+	}
+	if opt.Kind != "" {
+		query["kind"] = opt.Kind
+	}
+	if opt.Name != "" {
+		query["name"] = opt.Name
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, err
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type MarketplaceSourceColl struct {
+	*mongo.Collection
+	coll string
+}
+
+func NewMarketplaceSourceColl() *MarketplaceSourceColl {
+	name := models.MarketplaceSource{}.TableName()
+	return &MarketplaceSourceColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *MarketplaceSourceColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *MarketplaceSourceColl) EnsureIndex(_ context.Context) error {
+	return nil
+}
+
+func (c *MarketplaceSourceColl) Create(source *models.MarketplaceSource) error {
+	source.UpdateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), source)
+	if err != nil {
+		return err
+	}
+	source.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *MarketplaceSourceColl) Update(id string, source *models.MarketplaceSource) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	source.ID = oid
+	source.UpdateTime = time.Now().Unix()
+	_, err = c.ReplaceOne(context.TODO(), bson.M{"_id": oid}, source)
+	return err
+}
+
+// UpdateSyncStatus only updates the fields changed by a sync, so that it won't overwrite the
+// changes made by users while syncing.
+func (c *MarketplaceSourceColl) UpdateSyncStatus(id primitive.ObjectID, revision, syncErr string) error {
+	change := bson.M{"last_sync_time": time.Now().Unix(), "error": syncErr}
+	if revision != "" {
+		change["revision"] = revision
+	}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": change})
+	return err
+}
+
+func (c *MarketplaceSourceColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
+
+func (c *MarketplaceSourceColl) Get(id string) (*models.MarketplaceSource, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.MarketplaceSource{}
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(res)
+	return res, err
+}
+
+func (c *MarketplaceSourceColl) List() ([]*models.MarketplaceSource, error) {
+	res := make([]*models.MarketplaceSource, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, err
+}
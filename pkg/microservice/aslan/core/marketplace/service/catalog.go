@@ -0,0 +1,287 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gopkg.in/yaml.v3"
+	orascontent "oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+
+	aslanconfig "github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/command"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+)
+
+const ociPullTimeout = 5 * time.Minute
+
+// catalog is the index of a marketplace source, for example:
+//
+//	plugins:
+//	  - name: sonar-scan
+//	    version: v1.0.0
+//	    description: scan the code with sonarqube
+//	    path: plugins/sonar-scan/v1.0.0/sonar-scan.yaml
+//	    digest: sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+//	workflow_templates:
+//	  - name: go-ci
+//	    version: v1.0.0
+//	    path: workflow-templates/go-ci/v1.0.0/go-ci.yaml
+//
+// The digests are required if the catalog is signed since the signature only covers the index.
+type catalog struct {
+	Plugins           []*catalogEntry `yaml:"plugins"`
+	WorkflowTemplates []*catalogEntry `yaml:"workflow_templates"`
+}
+
+type catalogEntry struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+	Path        string `yaml:"path"`
+	Digest      string `yaml:"digest"`
+}
+
+// readFile reads a file of the catalog by its path relative to the catalog root.
+type readFile func(name string) ([]byte, error)
+
+func fetchCatalog(source *models.MarketplaceSource) (readFile, error) {
+	switch source.Type {
+	case config.SourceTypeGit:
+		return fetchGitCatalog(source)
+	case config.SourceTypeOCI:
+		return fetchOCICatalog(source)
+	default:
+		return nil, fmt.Errorf("unsupported source type %s", source.Type)
+	}
+}
+
+func fetchGitCatalog(source *models.MarketplaceSource) (readFile, error) {
+	codehost, err := systemconfig.New().GetCodeHost(source.CodehostID)
+	if err != nil {
+		return nil, fmt.Errorf("get code host %d error: %v", source.CodehostID, err)
+	}
+	checkoutPath := path.Join(aslanconfig.S3StoragePath(), source.RepoName)
+	if err := os.RemoveAll(checkoutPath); err != nil {
+		return nil, fmt.Errorf("remove checkout path error: %v", err)
+	}
+	if err := command.RunGitCmds(codehost, source.RepoOwner, source.RepoNamespace, source.RepoName, source.Branch, "origin"); err != nil {
+		return nil, fmt.Errorf("run git cmds error: %v", err)
+	}
+	return func(name string) ([]byte, error) {
+		// the path is cleaned from the root so that it can not escape from the checkout path.
+		return os.ReadFile(path.Join(checkoutPath, path.Clean("/"+name)))
+	}, nil
+}
+
+// fetchOCICatalog pulls the artifact pushed by tools like `oras push <reference> catalog.yaml
+// catalog.yaml.sig plugins/...`, every file is a layer titled with its path.
+func fetchOCICatalog(source *models.MarketplaceSource) (readFile, error) {
+	registry, err := orascontent.NewRegistry(orascontent.RegistryOptions{
+		Username:  source.Username,
+		Password:  source.Password,
+		PlainHTTP: source.PlainHTTP,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create registry client error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ociPullTimeout)
+	defer cancel()
+	store := orascontent.NewMemory()
+	var layers []ocispec.Descriptor
+	if _, err := oras.Copy(ctx, registry, source.Reference, store, "", oras.WithLayerDescriptors(func(descs []ocispec.Descriptor) {
+		layers = descs
+	})); err != nil {
+		return nil, fmt.Errorf("pull %s error: %v", source.Reference, err)
+	}
+
+	files := make(map[string][]byte, len(layers))
+	for _, layer := range layers {
+		name := layer.Annotations[ocispec.AnnotationTitle]
+		if name == "" {
+			continue
+		}
+		if _, data, ok := store.Get(layer); ok {
+			files[path.Clean("/"+name)] = data
+		}
+	}
+	return func(name string) ([]byte, error) {
+		data, ok := files[path.Clean("/"+name)]
+		if !ok {
+			return nil, fmt.Errorf("file %s not found in %s", name, source.Reference)
+		}
+		return data, nil
+	}, nil
+}
+
+// loadCatalog reads and verifies the catalog, the digest of the catalog file is returned as
+// the revision.
+func loadCatalog(source *models.MarketplaceSource, read readFile) ([]*models.MarketplaceItem, string, error) {
+	data, err := read(config.CatalogFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("read %s error: %v", config.CatalogFile, err)
+	}
+	revision := contentDigest(data)
+
+	verified := false
+	if source.PublicKey != "" {
+		signature, err := read(config.CatalogSignatureFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("catalog is not signed: %v", err)
+		}
+		if err := verifySignature(source.PublicKey, data, signature); err != nil {
+			return nil, "", fmt.Errorf("verify catalog signature error: %v", err)
+		}
+		verified = true
+	}
+
+	index := &catalog{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, "", fmt.Errorf("unmarshal %s error: %v", config.CatalogFile, err)
+	}
+
+	items := make([]*models.MarketplaceItem, 0)
+	seen := make(map[string]bool)
+	now := time.Now().Unix()
+	for kind, entries := range map[config.ItemKind][]*catalogEntry{
+		config.ItemKindPlugin:           index.Plugins,
+		config.ItemKindWorkflowTemplate: index.WorkflowTemplates,
+	} {
+		for _, entry := range entries {
+			if entry.Name == "" || entry.Version == "" || entry.Path == "" {
+				return nil, "", fmt.Errorf("name, version and path of %s entries should not be empty", kind)
+			}
+			key := fmt.Sprintf("%s/%s@%s", kind, entry.Name, entry.Version)
+			if seen[key] {
+				return nil, "", fmt.Errorf("duplicated catalog entry %s", key)
+			}
+			seen[key] = true
+
+			content, err := read(entry.Path)
+			if err != nil {
+				return nil, "", fmt.Errorf("read %s error: %v", key, err)
+			}
+			digest := contentDigest(content)
+			if entry.Digest == "" && verified {
+				return nil, "", fmt.Errorf("digest of %s is required in a signed catalog", key)
+			}
+			if entry.Digest != "" && entry.Digest != digest {
+				return nil, "", fmt.Errorf("digest of %s mismatch, expect %s, got %s", key, entry.Digest, digest)
+			}
+			if err := validateContent(kind, entry, content); err != nil {
+				return nil, "", fmt.Errorf("invalid %s: %v", key, err)
+			}
+
+			items = append(items, &models.MarketplaceItem{
+				SourceID:    source.ID.Hex(),
+				Kind:        kind,
+				Name:        entry.Name,
+				Version:     entry.Version,
+				Description: entry.Description,
+				Digest:      digest,
+				Verified:    verified,
+				Content:     string(content),
+				SyncTime:    now,
+			})
+		}
+	}
+	return items, revision, nil
+}
+
+func validateContent(kind config.ItemKind, entry *catalogEntry, content []byte) error {
+	switch kind {
+	case config.ItemKindPlugin:
+		plugin := &commonmodels.PluginTemplate{}
+		if err := yaml.Unmarshal(content, plugin); err != nil {
+			return err
+		}
+		if plugin.Name != entry.Name {
+			return fmt.Errorf("plugin name %s does not match the catalog", plugin.Name)
+		}
+		if plugin.Image == "" {
+			return fmt.Errorf("plugin image should not be empty")
+		}
+	case config.ItemKindWorkflowTemplate:
+		workflow := &commonmodels.WorkflowV4{}
+		if err := yaml.Unmarshal(content, workflow); err != nil {
+			return err
+		}
+		if len(workflow.Stages) == 0 {
+			return fmt.Errorf("workflow template has no stages")
+		}
+	}
+	return nil
+}
+
+// verifySignature verifies the base64 encoded signature of the data, ed25519, rsa (PKCS #1 v1.5)
+// and ecdsa keys are supported, the latter two sign the sha256 digest of the data.
+func verifySignature(publicKey string, data, signature []byte) error {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return fmt.Errorf("failed to decode the PEM public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("decode signature error: %v", err)
+	}
+
+	hashed := sha256.Sum256(data)
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hashed[:], sig)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, hashed[:], sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
+
+func contentDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
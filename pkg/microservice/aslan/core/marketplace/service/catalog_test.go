@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+)
+
+const testPlugin = `
+name: sonar-scan
+version: v1.1.0
+image: koderover/sonar-scan:v1.1.0
+`
+
+const testWorkflowTemplate = `
+name: go-ci
+stages:
+  - name: test
+    jobs: []
+`
+
+func testCatalogFiles(pluginDigest string) map[string][]byte {
+	catalog := fmt.Sprintf(`
+plugins:
+  - name: sonar-scan
+    version: v1.1.0
+    path: plugins/sonar-scan.yaml
+    digest: %s
+workflow_templates:
+  - name: go-ci
+    version: v1.0.0
+    path: workflow-templates/go-ci.yaml
+    digest: %s
+`, pluginDigest, contentDigest([]byte(testWorkflowTemplate)))
+	return map[string][]byte{
+		config.CatalogFile:              []byte(catalog),
+		"plugins/sonar-scan.yaml":       []byte(testPlugin),
+		"workflow-templates/go-ci.yaml": []byte(testWorkflowTemplate),
+	}
+}
+
+func testReader(files map[string][]byte) readFile {
+	return func(name string) ([]byte, error) {
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		return data, nil
+	}
+}
+
+var _ = Describe("Testing load catalog", func() {
+	var (
+		publicKey  string
+		privateKey ed25519.PrivateKey
+	)
+
+	BeforeEach(func() {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		Expect(err).NotTo(HaveOccurred())
+		publicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		privateKey = priv
+	})
+
+	It("loads an unsigned catalog", func() {
+		files := testCatalogFiles(contentDigest([]byte(testPlugin)))
+		items, revision, err := loadCatalog(&models.MarketplaceSource{}, testReader(files))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revision).To(Equal(contentDigest(files[config.CatalogFile])))
+		Expect(items).To(HaveLen(2))
+		for _, item := range items {
+			Expect(item.Verified).To(BeFalse())
+		}
+	})
+
+	It("verifies the signature of a signed catalog", func() {
+		files := testCatalogFiles(contentDigest([]byte(testPlugin)))
+		signature := ed25519.Sign(privateKey, files[config.CatalogFile])
+		files[config.CatalogSignatureFile] = []byte(base64.StdEncoding.EncodeToString(signature))
+
+		items, _, err := loadCatalog(&models.MarketplaceSource{PublicKey: publicKey}, testReader(files))
+		Expect(err).NotTo(HaveOccurred())
+		for _, item := range items {
+			Expect(item.Verified).To(BeTrue())
+		}
+
+		files[config.CatalogFile] = append(files[config.CatalogFile], '\n')
+		_, _, err = loadCatalog(&models.MarketplaceSource{PublicKey: publicKey}, testReader(files))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects an unsigned catalog if the public key is set", func() {
+		files := testCatalogFiles(contentDigest([]byte(testPlugin)))
+		_, _, err := loadCatalog(&models.MarketplaceSource{PublicKey: publicKey}, testReader(files))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects content not matching the digest", func() {
+		files := testCatalogFiles(contentDigest([]byte("tampered")))
+		_, _, err := loadCatalog(&models.MarketplaceSource{}, testReader(files))
+		Expect(err).To(MatchError(ContainSubstring("digest of plugin/sonar-scan@v1.1.0 mismatch")))
+	})
+})
+
+var _ = Describe("Testing compare versions", func() {
+	It("picks the latest semantic version", func() {
+		items := []*models.MarketplaceItem{{Version: "v1.2.0"}, {Version: "v1.10.0"}, {Version: "v1.9.1"}}
+		Expect(latestItem(items).Version).To(Equal("v1.10.0"))
+		Expect(compareVersion("1.0.0", "v1.0.0")).To(Equal(0))
+	})
+})
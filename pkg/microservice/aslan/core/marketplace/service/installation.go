@@ -0,0 +1,200 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type InstallArgs struct {
+	SourceID string          `json:"source_id"`
+	Kind     config.ItemKind `json:"kind"`
+	Name     string          `json:"name"`
+	// Version is the version to be installed, the latest one is used if it is empty.
+	Version string `json:"version"`
+	Pinned  bool   `json:"pinned"`
+}
+
+type UpgradeArgs struct {
+	// Version is the target version, it can also be an older one, the latest one is used if it is empty.
+	Version string `json:"version"`
+	Pinned  bool   `json:"pinned"`
+}
+
+func ListMarketplaceItems(opt *mongodb.ListItemsOption, logger *zap.SugaredLogger) ([]*models.MarketplaceItem, error) {
+	items, err := mongodb.NewMarketplaceItemColl().List(opt)
+	if err != nil {
+		logger.Errorf("failed to list marketplace items, err: %s", err)
+		return nil, e.ErrListMarketplaceItems.AddErr(err)
+	}
+	return items, nil
+}
+
+func InstallMarketplaceItem(user string, args *InstallArgs, logger *zap.SugaredLogger) (*models.MarketplaceInstallation, error) {
+	if args.SourceID == "" || args.Name == "" {
+		return nil, e.ErrInvalidParam.AddDesc("source id and name are required")
+	}
+	item, err := findItem(args.SourceID, args.Kind, args.Name, args.Version)
+	if err != nil {
+		return nil, e.ErrInstallMarketplaceItem.AddErr(err)
+	}
+
+	installation := &models.MarketplaceInstallation{
+		SourceID:    args.SourceID,
+		Kind:        item.Kind,
+		Name:        item.Name,
+		Pinned:      args.Pinned,
+		InstalledBy: user,
+		UpdateBy:    user,
+	}
+	setInstalledItem(installation, item)
+	if err := mongodb.NewMarketplaceInstallationColl().Create(installation); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, e.ErrInstallMarketplaceItem.AddDesc(fmt.Sprintf("%s %s is already installed", item.Kind, item.Name))
+		}
+		logger.Errorf("failed to install %s %s, err: %s", item.Kind, item.Name, err)
+		return nil, e.ErrInstallMarketplaceItem.AddErr(err)
+	}
+	return installation, nil
+}
+
+func UpgradeMarketplaceInstallation(id, user string, args *UpgradeArgs, logger *zap.SugaredLogger) (*models.MarketplaceInstallation, error) {
+	installation, err := mongodb.NewMarketplaceInstallationColl().Get(id)
+	if err != nil {
+		return nil, e.ErrUpgradeMarketplaceInstallation.AddErr(err)
+	}
+	item, err := findItem(installation.SourceID, installation.Kind, installation.Name, args.Version)
+	if err != nil {
+		return nil, e.ErrUpgradeMarketplaceInstallation.AddErr(err)
+	}
+
+	setInstalledItem(installation, item)
+	installation.Pinned = args.Pinned
+	installation.UpdateBy = user
+	if err := mongodb.NewMarketplaceInstallationColl().Update(installation); err != nil {
+		logger.Errorf("failed to upgrade %s %s, err: %s", installation.Kind, installation.Name, err)
+		return nil, e.ErrUpgradeMarketplaceInstallation.AddErr(err)
+	}
+	return installation, nil
+}
+
+func DeleteMarketplaceInstallation(id string, logger *zap.SugaredLogger) error {
+	if err := mongodb.NewMarketplaceInstallationColl().Delete(id); err != nil {
+		logger.Errorf("failed to delete marketplace installation %s, err: %s", id, err)
+		return e.ErrDeleteMarketplaceInstallation.AddErr(err)
+	}
+	return nil
+}
+
+func ListMarketplaceInstallations(kind config.ItemKind, logger *zap.SugaredLogger) ([]*models.MarketplaceInstallation, error) {
+	installations, err := mongodb.NewMarketplaceInstallationColl().List("", kind)
+	if err != nil {
+		logger.Errorf("failed to list marketplace installations, err: %s", err)
+		return nil, e.ErrListMarketplaceInstallations.AddErr(err)
+	}
+	return installations, nil
+}
+
+// upgradeInstallations upgrades the installations of the source which are not pinned to the
+// latest synced version.
+func upgradeInstallations(sourceID string, items []*models.MarketplaceItem, logger *zap.SugaredLogger) {
+	installations, err := mongodb.NewMarketplaceInstallationColl().List(sourceID, "")
+	if err != nil {
+		logger.Errorf("failed to list installations of source %s, err: %s", sourceID, err)
+		return
+	}
+	for _, installation := range installations {
+		if installation.Pinned {
+			continue
+		}
+		candidates := make([]*models.MarketplaceItem, 0)
+		for _, item := range items {
+			if item.Kind == installation.Kind && item.Name == installation.Name {
+				candidates = append(candidates, item)
+			}
+		}
+		latest := latestItem(candidates)
+		if latest == nil || compareVersion(latest.Version, installation.Version) <= 0 {
+			continue
+		}
+		logger.Infof("upgrading %s %s from %s to %s", installation.Kind, installation.Name, installation.Version, latest.Version)
+		setInstalledItem(installation, latest)
+		if err := mongodb.NewMarketplaceInstallationColl().Update(installation); err != nil {
+			logger.Errorf("failed to upgrade %s %s, err: %s", installation.Kind, installation.Name, err)
+		}
+	}
+}
+
+func findItem(sourceID string, kind config.ItemKind, name, version string) (*models.MarketplaceItem, error) {
+	if kind != config.ItemKindPlugin && kind != config.ItemKindWorkflowTemplate {
+		return nil, fmt.Errorf("unsupported kind: %s", kind)
+	}
+	if version != "" {
+		item, err := mongodb.NewMarketplaceItemColl().Find(sourceID, kind, name, version)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s@%s not found in the catalog", kind, name, version)
+		}
+		return item, nil
+	}
+	items, err := mongodb.NewMarketplaceItemColl().List(&mongodb.ListItemsOption{SourceID: sourceID, Kind: kind, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	latest := latestItem(items)
+	if latest == nil {
+		return nil, fmt.Errorf("%s %s not found in the catalog", kind, name)
+	}
+	return latest, nil
+}
+
+func setInstalledItem(installation *models.MarketplaceInstallation, item *models.MarketplaceItem) {
+	installation.Version = item.Version
+	installation.Digest = item.Digest
+	installation.Verified = item.Verified
+	installation.Content = item.Content
+}
+
+func latestItem(items []*models.MarketplaceItem) *models.MarketplaceItem {
+	var latest *models.MarketplaceItem
+	for _, item := range items {
+		if latest == nil || compareVersion(item.Version, latest.Version) > 0 {
+			latest = item
+		}
+	}
+	return latest
+}
+
+// compareVersion compares versions as semantic versions, and falls back to comparing them as
+// strings if any of them is not a valid one.
+func compareVersion(a, b string) int {
+	va, errA := semver.ParseTolerant(a)
+	vb, errB := semver.ParseTolerant(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// syncing records the sources being synced, a source is synced by one goroutine at a time.
+var syncing sync.Map
+
+func validateSource(source *models.MarketplaceSource) error {
+	if source.Name == "" {
+		return fmt.Errorf("source name should not be empty")
+	}
+	switch source.Type {
+	case config.SourceTypeGit:
+		if source.CodehostID == 0 || source.RepoName == "" || source.Branch == "" {
+			return fmt.Errorf("code host, repository and branch are required for git source")
+		}
+	case config.SourceTypeOCI:
+		if source.Reference == "" {
+			return fmt.Errorf("artifact reference is required for oci source")
+		}
+	default:
+		return fmt.Errorf("unsupported source type: %s", source.Type)
+	}
+	if source.PublicKey != "" {
+		if block, _ := pem.Decode([]byte(source.PublicKey)); block == nil {
+			return fmt.Errorf("public key should be PEM encoded")
+		}
+	}
+	if source.SyncInterval < 0 {
+		return fmt.Errorf("sync interval should not be negative")
+	}
+	return nil
+}
+
+func CreateMarketplaceSource(source *models.MarketplaceSource, logger *zap.SugaredLogger) error {
+	if err := validateSource(source); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	source.Revision, source.LastSyncTime, source.Error = "", 0, ""
+	if err := mongodb.NewMarketplaceSourceColl().Create(source); err != nil {
+		logger.Errorf("failed to create marketplace source %s, err: %s", source.Name, err)
+		return e.ErrCreateMarketplaceSource.AddErr(err)
+	}
+	if err := syncSource(source, logger); err != nil {
+		return e.ErrSyncMarketplaceSource.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateMarketplaceSource keeps the stored password if it is not given, since it is never
+// returned by the list API.
+func UpdateMarketplaceSource(id string, source *models.MarketplaceSource, logger *zap.SugaredLogger) error {
+	origin, err := mongodb.NewMarketplaceSourceColl().Get(id)
+	if err != nil {
+		return e.ErrUpdateMarketplaceSource.AddErr(err)
+	}
+	if source.Password == "" {
+		source.Password = origin.Password
+	}
+	source.Revision, source.LastSyncTime, source.Error = origin.Revision, origin.LastSyncTime, origin.Error
+	if err := validateSource(source); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := mongodb.NewMarketplaceSourceColl().Update(id, source); err != nil {
+		logger.Errorf("failed to update marketplace source %s, err: %s", id, err)
+		return e.ErrUpdateMarketplaceSource.AddErr(err)
+	}
+	return nil
+}
+
+// DeleteMarketplaceSource removes the source and its catalog, the installations keep working
+// since they have their own copy of the content, but they can not be upgraded any more.
+func DeleteMarketplaceSource(id string, logger *zap.SugaredLogger) error {
+	if err := mongodb.NewMarketplaceSourceColl().Delete(id); err != nil {
+		logger.Errorf("failed to delete marketplace source %s, err: %s", id, err)
+		return e.ErrDeleteMarketplaceSource.AddErr(err)
+	}
+	if err := mongodb.NewMarketplaceItemColl().DeleteBySource(id); err != nil {
+		logger.Errorf("failed to delete catalog of marketplace source %s, err: %s", id, err)
+		return e.ErrDeleteMarketplaceSource.AddErr(err)
+	}
+	return nil
+}
+
+func ListMarketplaceSources(logger *zap.SugaredLogger) ([]*models.MarketplaceSource, error) {
+	sources, err := mongodb.NewMarketplaceSourceColl().List()
+	if err != nil {
+		logger.Errorf("failed to list marketplace sources, err: %s", err)
+		return nil, e.ErrListMarketplaceSources.AddErr(err)
+	}
+	for _, source := range sources {
+		source.Password = ""
+	}
+	return sources, nil
+}
+
+func SyncMarketplaceSource(id string, logger *zap.SugaredLogger) error {
+	source, err := mongodb.NewMarketplaceSourceColl().Get(id)
+	if err != nil {
+		return e.ErrSyncMarketplaceSource.AddErr(err)
+	}
+	if err := syncSource(source, logger); err != nil {
+		return e.ErrSyncMarketplaceSource.AddErr(err)
+	}
+	return nil
+}
+
+// syncSource replaces the catalog of the source with the remote one and upgrades the installations
+// which are not pinned, the result is saved in the sync status of the source.
+func syncSource(source *models.MarketplaceSource, logger *zap.SugaredLogger) (err error) {
+	id := source.ID.Hex()
+	if _, loaded := syncing.LoadOrStore(id, struct{}{}); loaded {
+		return fmt.Errorf("source %s is being synced", source.Name)
+	}
+	defer syncing.Delete(id)
+
+	revision := ""
+	defer func() {
+		syncErr := ""
+		if err != nil {
+			logger.Errorf("failed to sync marketplace source %s, err: %s", source.Name, err)
+			syncErr = err.Error()
+		}
+		if updateErr := mongodb.NewMarketplaceSourceColl().UpdateSyncStatus(source.ID, revision, syncErr); updateErr != nil {
+			logger.Errorf("failed to update sync status of marketplace source %s, err: %s", source.Name, updateErr)
+		}
+	}()
+
+	read, err := fetchCatalog(source)
+	if err != nil {
+		return err
+	}
+	items, rev, err := loadCatalog(source, read)
+	if err != nil {
+		return err
+	}
+	if err := mongodb.NewMarketplaceItemColl().Replace(id, items); err != nil {
+		return fmt.Errorf("save catalog error: %v", err)
+	}
+	revision = rev
+	upgradeInstallations(id, items, logger)
+	return nil
+}
+
+// StartMarketplaceSync syncs the sources with a sync interval periodically until the context is done.
+func StartMarketplaceSync(ctx context.Context, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(config.SyncCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sources, err := mongodb.NewMarketplaceSourceColl().List()
+			if err != nil {
+				logger.Errorf("failed to list marketplace sources, err: %s", err)
+				continue
+			}
+			now := time.Now().Unix()
+			for _, source := range sources {
+				if source.SyncInterval <= 0 || now-source.LastSyncTime < source.SyncInterval*60 {
+					continue
+				}
+				_ = syncSource(source, logger)
+			}
+		}
+	}
+}
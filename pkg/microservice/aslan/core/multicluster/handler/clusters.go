@@ -23,6 +23,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
+	tenantservice "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -38,7 +39,23 @@ func ListClusters(c *gin.Context) {
 		return
 	}
 
-	ctx.Resp, ctx.Err = service.ListClusters(clusters, c.Query("projectName"), ctx.Logger)
+	scope, err := tenantservice.GetScope(ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = e.ErrListTenant.AddErr(err)
+		return
+	}
+	resp, err := service.ListClusters(clusters, c.Query("projectName"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	visible := make([]*service.K8SCluster, 0, len(resp))
+	for _, cluster := range resp {
+		if scope.ClusterVisible(cluster.ID) {
+			visible = append(visible, cluster)
+		}
+	}
+	ctx.Resp = visible
 }
 
 func GetCluster(c *gin.Context) {
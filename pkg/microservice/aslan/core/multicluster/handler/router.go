@@ -40,6 +40,13 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		Cluster.DELETE("/:id", DeleteCluster)
 		Cluster.PUT("/:id/disconnect", DisconnectCluster)
 		Cluster.PUT("/:id/reconnect", ReconnectCluster)
+
+		Cluster.GET("/:id/sharedServices", ListClusterSharedServices)
+		Cluster.GET("/:id/sharedServices/:name", GetClusterSharedService)
+		Cluster.POST("/:id/sharedServices", CreateClusterSharedService)
+		Cluster.PUT("/:id/sharedServices/:name", UpgradeClusterSharedService)
+		Cluster.POST("/:id/sharedServices/:name/rollback", RollbackClusterSharedService)
+		Cluster.DELETE("/:id/sharedServices/:name", DeleteClusterSharedService)
 	}
 
 	bundles := router.Group("bundle-resources")
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListClusterSharedServices(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListClusterSharedServices(c.Param("id"), ctx.Logger)
+}
+
+func GetClusterSharedService(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.GetClusterSharedService(c.Param("id"), c.Param("name"), ctx.Logger)
+}
+
+func CreateClusterSharedService(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.ClusterSharedServiceArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = service.CreateClusterSharedService(c.Param("id"), args, ctx.UserName, ctx.Logger)
+}
+
+func UpgradeClusterSharedService(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.UpgradeClusterSharedServiceArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = service.UpgradeClusterSharedService(c.Param("id"), c.Param("name"), args, ctx.UserName, ctx.Logger)
+}
+
+func RollbackClusterSharedService(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	revision, err := strconv.ParseInt(c.Query("revision"), 10, 64)
+	if err != nil || revision <= 0 {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid revision")
+		return
+	}
+
+	ctx.Err = service.RollbackClusterSharedService(c.Param("id"), c.Param("name"), revision, ctx.UserName, ctx.Logger)
+}
+
+func DeleteClusterSharedService(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Err = service.DeleteClusterSharedService(c.Param("id"), c.Param("name"), ctx.Logger)
+}
@@ -58,20 +58,21 @@ import (
 var namePattern = regexp.MustCompile(`^[0-9a-zA-Z_.-]{1,32}$`)
 
 type K8SCluster struct {
-	ID                     string                   `json:"id,omitempty"`
-	Name                   string                   `json:"name"`
-	Description            string                   `json:"description"`
-	AdvancedConfig         *AdvancedConfig          `json:"advanced_config,omitempty"`
-	Status                 setting.K8SClusterStatus `json:"status"`
-	Production             bool                     `json:"production"`
-	CreatedAt              int64                    `json:"createdAt"`
-	CreatedBy              string                   `json:"createdBy"`
-	Provider               int8                     `json:"provider"`
-	Local                  bool                     `json:"local"`
-	Cache                  types.Cache              `json:"cache"`
-	LastConnectionTime     int64                    `json:"last_connection_time"`
-	UpdateHubagentErrorMsg string                   `json:"update_hubagent_error_msg"`
-	DindCfg                *commonmodels.DindCfg    `json:"dind_cfg"`
+	ID                     string                     `json:"id,omitempty"`
+	Name                   string                     `json:"name"`
+	Description            string                     `json:"description"`
+	AdvancedConfig         *AdvancedConfig            `json:"advanced_config,omitempty"`
+	Status                 setting.K8SClusterStatus   `json:"status"`
+	Production             bool                       `json:"production"`
+	CreatedAt              int64                      `json:"createdAt"`
+	CreatedBy              string                     `json:"createdBy"`
+	Provider               int8                       `json:"provider"`
+	Local                  bool                       `json:"local"`
+	Cache                  types.Cache                `json:"cache"`
+	LastConnectionTime     int64                      `json:"last_connection_time"`
+	UpdateHubagentErrorMsg string                     `json:"update_hubagent_error_msg"`
+	DindCfg                *commonmodels.DindCfg      `json:"dind_cfg"`
+	JobIsolation           *commonmodels.JobIsolation `json:"job_isolation,omitempty"`
 
 	// new field in 1.14, intended to enable kubeconfig for cluster management
 	Type       string `json:"type"` // either agent or kubeconfig supported
@@ -164,6 +165,7 @@ func ListClusters(ids []string, projectName string, logger *zap.SugaredLogger) (
 			LastConnectionTime:     c.LastConnectionTime,
 			UpdateHubagentErrorMsg: c.UpdateHubagentErrorMsg,
 			DindCfg:                c.DindCfg,
+			JobIsolation:           c.JobIsolation,
 			KubeConfig:             c.KubeConfig,
 			Type:                   c.Type,
 		}
@@ -256,6 +258,10 @@ func CreateCluster(args *K8SCluster, logger *zap.SugaredLogger) (*commonmodels.K
 		return nil, fmt.Errorf("failed to set dind args for cluster %s: %s", args.ID, err)
 	}
 
+	if err := validateJobIsolation(args); err != nil {
+		return nil, e.ErrCreateCluster.AddErr(err)
+	}
+
 	cluster := &commonmodels.K8SCluster{
 		Name:           args.Name,
 		Description:    args.Description,
@@ -267,6 +273,7 @@ func CreateCluster(args *K8SCluster, logger *zap.SugaredLogger) (*commonmodels.K
 		CreatedBy:      args.CreatedBy,
 		Cache:          args.Cache,
 		DindCfg:        args.DindCfg,
+		JobIsolation:   args.JobIsolation,
 		Type:           args.Type,
 		KubeConfig:     args.KubeConfig,
 	}
@@ -280,6 +287,10 @@ func UpdateCluster(id string, args *K8SCluster, logger *zap.SugaredLogger) (*com
 		return nil, fmt.Errorf("failed to new kube service: %s", err)
 	}
 
+	if err := validateJobIsolation(args); err != nil {
+		return nil, e.ErrUpdateCluster.AddErr(err)
+	}
+
 	advancedConfig := new(commonmodels.AdvancedConfig)
 	if args.AdvancedConfig != nil {
 		advancedConfig.Strategy = args.AdvancedConfig.Strategy
@@ -385,6 +396,7 @@ func UpdateCluster(id string, args *K8SCluster, logger *zap.SugaredLogger) (*com
 		Production:     args.Production,
 		Cache:          args.Cache,
 		DindCfg:        args.DindCfg,
+		JobIsolation:   args.JobIsolation,
 		Type:           args.Type,
 		KubeConfig:     args.KubeConfig,
 	}
@@ -410,6 +422,14 @@ func DeleteCluster(username, clusterID string, logger *zap.SugaredLogger) error
 		return e.ErrDeleteCluster.AddDesc("请删除在该集群创建的环境后，再尝试删除该集群")
 	}
 
+	sharedServices, err := commonrepo.NewClusterSharedServiceColl().ListLatest(clusterID)
+	if err != nil {
+		return e.ErrDeleteCluster.AddErr(err)
+	}
+	if len(sharedServices) > 0 {
+		return e.ErrDeleteCluster.AddDesc("请删除该集群的共享服务后，再尝试删除该集群")
+	}
+
 	s, _ := kube.NewService("")
 
 	if err = commonrepo.NewProjectClusterRelationColl().Delete(&commonrepo.ProjectClusterRelationOption{ClusterID: clusterID}); err != nil {
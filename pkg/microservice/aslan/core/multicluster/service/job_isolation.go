@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// validateJobIsolation makes sure the job namespaces of the cluster are not shared with zadig, and the pod
+// and service cidrs are given so that the job pods can not reach the services in the cluster.
+func validateJobIsolation(cluster *K8SCluster) error {
+	isolation := cluster.JobIsolation
+	if isolation == nil || !isolation.Enabled {
+		return nil
+	}
+
+	reserved := sets.NewString(config.Namespace(), setting.AttachedClusterNamespace, "default", "kube-system", "kube-public", "kube-node-lease")
+	validateNamespace := func(namespace string) error {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid job namespace %q: %s", namespace, strings.Join(errs, ", "))
+		}
+		if reserved.Has(namespace) {
+			return fmt.Errorf("namespace %s can not be used to isolate the jobs", namespace)
+		}
+		return nil
+	}
+	if err := validateNamespace(isolation.Namespace); err != nil {
+		return err
+	}
+	projects := sets.NewString()
+	for _, ns := range isolation.ProjectNamespaces {
+		if ns.Project == "" {
+			return fmt.Errorf("project of job namespace %s is required", ns.Namespace)
+		}
+		if projects.Has(ns.Project) {
+			return fmt.Errorf("project %s has more than one job namespace", ns.Project)
+		}
+		projects.Insert(ns.Project)
+		if err := validateNamespace(ns.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if len(isolation.DeniedCIDRs) == 0 {
+		return fmt.Errorf("the pod and service cidrs of the cluster are required to isolate the jobs")
+	}
+	for _, cidr := range isolation.DeniedCIDRs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid cidr %s: %s", cidr, err)
+		}
+		if ip.To4() == nil {
+			return fmt.Errorf("cidr %s is not an ipv4 cidr", cidr)
+		}
+	}
+
+	// the pvc of the cache is in the namespace of zadig and can not be mounted by the isolated jobs.
+	if cluster.Cache.MediumType == types.NFSMedium {
+		return fmt.Errorf("the jobs using the nfs cache can not be isolated, use the object storage instead")
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/util/fs"
+)
+
+const clusterSharedServiceTimeout = 10 * time.Minute
+
+type ClusterSharedServiceArgs struct {
+	Name          string `json:"name"`
+	Namespace     string `json:"namespace"`
+	ChartRepoName string `json:"chart_repo_name"`
+	ChartName     string `json:"chart_name"`
+	ChartVersion  string `json:"chart_version"`
+	ValuesYaml    string `json:"values_yaml"`
+}
+
+type UpgradeClusterSharedServiceArgs struct {
+	ChartVersion string `json:"chart_version"`
+	ValuesYaml   string `json:"values_yaml"`
+}
+
+type ClusterSharedServiceResp struct {
+	*commonmodels.ClusterSharedService
+	// Envs are the envs in the cluster which reference the shared service, formatted as project/env.
+	Envs      []string                             `json:"envs"`
+	Revisions []*commonmodels.ClusterSharedService `json:"revisions,omitempty"`
+}
+
+func ListClusterSharedServices(clusterID string, logger *zap.SugaredLogger) ([]*ClusterSharedServiceResp, error) {
+	services, err := commonrepo.NewClusterSharedServiceColl().ListLatest(clusterID)
+	if err != nil {
+		logger.Errorf("failed to list shared services of cluster %s, err: %s", clusterID, err)
+		return nil, e.ErrListClusterSharedService.AddErr(err)
+	}
+	refs, err := listSharedServiceRefs(clusterID)
+	if err != nil {
+		return nil, e.ErrListClusterSharedService.AddErr(err)
+	}
+
+	resp := make([]*ClusterSharedServiceResp, 0, len(services))
+	for _, svc := range services {
+		resp = append(resp, &ClusterSharedServiceResp{ClusterSharedService: svc, Envs: refs[svc.Name]})
+	}
+	return resp, nil
+}
+
+func GetClusterSharedService(clusterID, name string, logger *zap.SugaredLogger) (*ClusterSharedServiceResp, error) {
+	revisions, err := commonrepo.NewClusterSharedServiceColl().ListRevisions(clusterID, name)
+	if err != nil {
+		logger.Errorf("failed to list revisions of shared service %s, err: %s", name, err)
+		return nil, e.ErrGetClusterSharedService.AddErr(err)
+	}
+	if len(revisions) == 0 {
+		return nil, e.ErrGetClusterSharedService.AddDesc(fmt.Sprintf("shared service %s not found", name))
+	}
+	refs, err := listSharedServiceRefs(clusterID)
+	if err != nil {
+		return nil, e.ErrGetClusterSharedService.AddErr(err)
+	}
+
+	return &ClusterSharedServiceResp{ClusterSharedService: revisions[0], Envs: refs[name], Revisions: revisions}, nil
+}
+
+func CreateClusterSharedService(clusterID string, args *ClusterSharedServiceArgs, username string, logger *zap.SugaredLogger) error {
+	if !namePattern.MatchString(args.Name) || args.Namespace == "" || args.ChartRepoName == "" || args.ChartName == "" || args.ChartVersion == "" {
+		return e.ErrCreateClusterSharedService.AddDesc("name, namespace, chart repo, chart name and chart version are required")
+	}
+	if _, err := commonrepo.NewK8SClusterColl().Get(clusterID); err != nil {
+		return e.ErrCreateClusterSharedService.AddErr(err)
+	}
+	if _, err := commonrepo.NewClusterSharedServiceColl().Find(clusterID, args.Name, 0); err == nil {
+		return e.ErrCreateClusterSharedService.AddDesc(fmt.Sprintf("shared service %s already exists", args.Name))
+	}
+
+	svc := &commonmodels.ClusterSharedService{
+		ClusterID:     clusterID,
+		Name:          args.Name,
+		Namespace:     args.Namespace,
+		ChartRepoName: args.ChartRepoName,
+		ChartName:     args.ChartName,
+		ChartVersion:  args.ChartVersion,
+		ValuesYaml:    args.ValuesYaml,
+		Revision:      1,
+		UpdateBy:      username,
+	}
+	if err := createSharedServiceRevision(svc, logger); err != nil {
+		return e.ErrCreateClusterSharedService.AddErr(err)
+	}
+	return nil
+}
+
+// UpgradeClusterSharedService saves a new revision with the chart version and values and deploys it.
+func UpgradeClusterSharedService(clusterID, name string, args *UpgradeClusterSharedServiceArgs, username string, logger *zap.SugaredLogger) error {
+	latest, err := findUpgradableSharedService(clusterID, name)
+	if err != nil {
+		return err
+	}
+	if args.ChartVersion == "" {
+		return e.ErrUpgradeClusterSharedService.AddDesc("chart version is required")
+	}
+
+	svc := *latest
+	svc.ChartVersion, svc.ValuesYaml = args.ChartVersion, args.ValuesYaml
+	if err := upgradeSharedService(&svc, latest.Revision, username, logger); err != nil {
+		return e.ErrUpgradeClusterSharedService.AddErr(err)
+	}
+	return nil
+}
+
+// RollbackClusterSharedService saves the chart version and values of a former revision as a new revision and deploys it.
+func RollbackClusterSharedService(clusterID, name string, revision int64, username string, logger *zap.SugaredLogger) error {
+	latest, err := findUpgradableSharedService(clusterID, name)
+	if err != nil {
+		return err
+	}
+	target, err := commonrepo.NewClusterSharedServiceColl().Find(clusterID, name, revision)
+	if err != nil {
+		return e.ErrUpgradeClusterSharedService.AddDesc(fmt.Sprintf("revision %d of shared service %s not found", revision, name))
+	}
+
+	svc := *target
+	if err := upgradeSharedService(&svc, latest.Revision, username, logger); err != nil {
+		return e.ErrUpgradeClusterSharedService.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteClusterSharedService(clusterID, name string, logger *zap.SugaredLogger) error {
+	latest, err := commonrepo.NewClusterSharedServiceColl().Find(clusterID, name, 0)
+	if err != nil {
+		return e.ErrDeleteClusterSharedService.AddErr(err)
+	}
+	refs, err := listSharedServiceRefs(clusterID)
+	if err != nil {
+		return e.ErrDeleteClusterSharedService.AddErr(err)
+	}
+	if len(refs[name]) > 0 {
+		return e.ErrDeleteClusterSharedService.AddDesc(fmt.Sprintf("shared service %s is referenced by envs: %v", name, refs[name]))
+	}
+
+	hClient, err := helmtool.NewClientFromNamespace(clusterID, latest.Namespace)
+	if err != nil {
+		return e.ErrDeleteClusterSharedService.AddErr(err)
+	}
+	err = hClient.UninstallRelease(&helmclient.ChartSpec{
+		ReleaseName: latest.Name,
+		Namespace:   latest.Namespace,
+		Wait:        true,
+		Timeout:     clusterSharedServiceTimeout,
+	})
+	if err != nil {
+		logger.Errorf("failed to uninstall shared service %s, err: %s", name, err)
+		return e.ErrDeleteClusterSharedService.AddErr(err)
+	}
+
+	if err := commonrepo.NewClusterSharedServiceColl().Delete(clusterID, name); err != nil {
+		return e.ErrDeleteClusterSharedService.AddErr(err)
+	}
+	return nil
+}
+
+func findUpgradableSharedService(clusterID, name string) (*commonmodels.ClusterSharedService, error) {
+	latest, err := commonrepo.NewClusterSharedServiceColl().Find(clusterID, name, 0)
+	if err != nil {
+		return nil, e.ErrUpgradeClusterSharedService.AddErr(err)
+	}
+	if latest.Status == config.ClusterSharedServiceStatusDeploying {
+		return nil, e.ErrUpgradeClusterSharedService.AddDesc(fmt.Sprintf("shared service %s is being deployed", name))
+	}
+	return latest, nil
+}
+
+func upgradeSharedService(svc *commonmodels.ClusterSharedService, latestRevision int64, username string, logger *zap.SugaredLogger) error {
+	svc.ID = [12]byte{}
+	svc.Revision = latestRevision + 1
+	svc.UpdateBy = username
+	svc.Error = ""
+	return createSharedServiceRevision(svc, logger)
+}
+
+func createSharedServiceRevision(svc *commonmodels.ClusterSharedService, logger *zap.SugaredLogger) error {
+	svc.Status = config.ClusterSharedServiceStatusDeploying
+	if err := commonrepo.NewClusterSharedServiceColl().Create(svc); err != nil {
+		logger.Errorf("failed to create revision %d of shared service %s, err: %s", svc.Revision, svc.Name, err)
+		return err
+	}
+
+	go func() {
+		svc.Status = config.ClusterSharedServiceStatusRunning
+		if err := deploySharedService(svc); err != nil {
+			log.Errorf("failed to deploy revision %d of shared service %s in cluster %s, err: %s", svc.Revision, svc.Name, svc.ClusterID, err)
+			svc.Status, svc.Error = config.ClusterSharedServiceStatusFailed, err.Error()
+		}
+		if err := commonrepo.NewClusterSharedServiceColl().UpdateStatus(svc); err != nil {
+			log.Errorf("failed to update status of shared service %s, err: %s", svc.Name, err)
+		}
+	}()
+	return nil
+}
+
+func deploySharedService(svc *commonmodels.ClusterSharedService) error {
+	chartRepo, err := commonrepo.NewHelmRepoColl().Find(&commonrepo.HelmRepoFindOption{RepoName: svc.ChartRepoName})
+	if err != nil {
+		return fmt.Errorf("failed to find chart repo %s: %s", svc.ChartRepoName, err)
+	}
+
+	hClient, err := helmtool.NewClient()
+	if err != nil {
+		return err
+	}
+	localPath := filepath.Join(configbase.DataPath(), "cluster-shared-services", svc.ClusterID, fmt.Sprintf("%s-%d", svc.Name, svc.Revision))
+	_ = os.RemoveAll(localPath)
+	defer os.RemoveAll(localPath)
+	chartRef := fmt.Sprintf("%s/%s", chartRepo.RepoName, svc.ChartName)
+	if err := hClient.DownloadChart(commonservice.GeneHelmRepo(chartRepo), chartRef, svc.ChartVersion, localPath, true); err != nil {
+		return errors.Wrapf(err, "failed to download chart %s-%s", chartRef, svc.ChartVersion)
+	}
+
+	chartPath, err := fs.RelativeToCurrentPath(filepath.Join(localPath, svc.ChartName))
+	if err != nil {
+		return err
+	}
+	helmClient, err := helmtool.NewClientFromNamespace(svc.ClusterID, svc.Namespace)
+	if err != nil {
+		return err
+	}
+	chartSpec := &helmclient.ChartSpec{
+		ReleaseName:     svc.Name,
+		ChartName:       chartPath,
+		Namespace:       svc.Namespace,
+		Version:         svc.ChartVersion,
+		ValuesYaml:      svc.ValuesYaml,
+		CreateNamespace: true,
+		UpgradeCRDs:     true,
+		Wait:            true,
+		Timeout:         clusterSharedServiceTimeout,
+		MaxHistory:      10,
+	}
+	_, err = helmClient.InstallOrUpgradeChart(context.TODO(), chartSpec, nil)
+	return err
+}
+
+// listSharedServiceRefs returns the envs referencing each shared service of the cluster.
+func listSharedServiceRefs(clusterID string) (map[string][]string, error) {
+	products, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{ClusterID: clusterID})
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string][]string)
+	for _, product := range products {
+		for _, name := range product.ClusterSharedServices {
+			refs[name] = append(refs[name], fmt.Sprintf("%s/%s", product.ProductName, product.EnvName))
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func ListBlueprints(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = projectservice.ListBlueprints(ctx.Logger)
+}
+
+func GetBlueprint(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = projectservice.GetBlueprint(c.Param("name"), ctx.Logger)
+}
+
+func CreateBlueprint(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.Blueprint)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("CreateBlueprint c.GetRawData() err : %s", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("CreateBlueprint json.Unmarshal err : %s", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "项目蓝图", args.Name, string(data), ctx.Logger)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.CreatedBy = ctx.UserName
+	args.UpdatedBy = ctx.UserName
+
+	ctx.Err = projectservice.CreateBlueprint(args, ctx.Logger)
+}
+
+func UpdateBlueprint(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.Blueprint)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("UpdateBlueprint c.GetRawData() err : %s", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("UpdateBlueprint json.Unmarshal err : %s", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "项目蓝图", c.Param("name"), string(data), ctx.Logger)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.Name = c.Param("name")
+	args.UpdatedBy = ctx.UserName
+
+	ctx.Err = projectservice.UpdateBlueprint(args, ctx.Logger)
+}
+
+func DeleteBlueprint(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "项目蓝图", c.Param("name"), "", ctx.Logger)
+	ctx.Err = projectservice.DeleteBlueprint(c.Param("name"), ctx.Logger)
+}
+
+func InstantiateBlueprint(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(projectservice.InstantiateBlueprintArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" || args.Repo == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("project_name and repo are required")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "新增", "项目蓝图-创建项目", c.Param("name"), args.Repo, ctx.Logger)
+
+	ctx.Resp, ctx.Err = projectservice.InstantiateBlueprint(c.Param("name"), args, ctx.UserID, ctx.UserName, ctx.RequestID, ctx.Logger)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	tenantservice "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ExportProject(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Param("name"), "导出", "项目管理-项目", c.Param("name"), "", ctx.Logger)
+	ctx.Resp, ctx.Err = projectservice.ExportProject(c.Param("name"), ctx.Logger)
+	if ctx.Err == nil && c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", c.Param("name")))
+	}
+}
+
+func ImportProject(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(projectservice.ImportProjectArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	// the request body is not logged since it contains the credentials.
+	projectName := args.ProjectName
+	if projectName == "" && args.Bundle != nil {
+		projectName = args.Bundle.ProjectName
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "导入", "项目管理-项目", projectName, "", ctx.Logger)
+
+	tenant, err := tenantservice.GetTenantForNewProject(c.Query("tenant"), ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	resp, err := projectservice.ImportProject(args, ctx.UserName, ctx.Logger)
+	ctx.Resp, ctx.Err = resp, err
+	if err != nil || args.DryRun {
+		return
+	}
+	if resp.ProjectCreated() {
+		tenantservice.BindProject(tenant, resp.ProjectName, ctx.Logger)
+	}
+}
+
+func DuplicateProject(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(projectservice.DuplicateProjectArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "复制", "项目管理-项目", fmt.Sprintf("%s-->%s", c.Param("name"), args.ProjectName), "", ctx.Logger)
+
+	tenant, err := tenantservice.GetTenantForNewProject(c.Query("tenant"), ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	resp, err := projectservice.DuplicateProject(c.Param("name"), args, ctx.UserName, ctx.Logger)
+	ctx.Resp, ctx.Err = resp, err
+	if err != nil || args.DryRun {
+		return
+	}
+	if resp.ProjectCreated() {
+		tenantservice.BindProject(tenant, resp.ProjectName, ctx.Logger)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetLicensePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = projectservice.GetLicensePolicy(c.Param("name"), ctx.Logger)
+}
+
+func UpdateLicensePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(template.LicensePolicy)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	projectName := c.Param("name")
+	detail, _ := json.Marshal(args)
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "更新", "项目管理-许可证策略", projectName, string(detail), ctx.Logger)
+
+	ctx.Err = projectservice.UpdateLicensePolicy(projectName, args, ctx.UserName, ctx.Logger)
+}
+
+func CreateLicenseWaiver(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(template.LicenseWaiver)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	projectName := c.Param("name")
+	detail, _ := json.Marshal(args)
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "新增", "项目管理-许可证豁免", projectName, string(detail), ctx.Logger)
+
+	ctx.Resp, ctx.Err = projectservice.AddLicenseWaiver(projectName, args, ctx.UserName, ctx.Logger)
+}
+
+func DeleteLicenseWaiver(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Param("name")
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "删除", "项目管理-许可证豁免", c.Param("id"), "", ctx.Logger)
+
+	ctx.Err = projectservice.DeleteLicenseWaiver(projectName, c.Param("id"), ctx.UserName, ctx.Logger)
+}
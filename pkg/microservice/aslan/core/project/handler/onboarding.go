@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func AnalyzeRepository(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(projectservice.RepoAnalysisArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.Repo == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty repo")
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+
+	ctx.Resp, ctx.Err = projectservice.AnalyzeRepository(args, ctx.Logger)
+}
+
+func ApplyOnboarding(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(projectservice.ApplyOnboardingArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+	if args.ProjectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("empty projectName")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "新增", "项目管理-项目接入", args.Repo, "", ctx.Logger)
+
+	ctx.Resp, ctx.Err = projectservice.ApplyOnboarding(args, ctx.UserName, ctx.Logger)
+}
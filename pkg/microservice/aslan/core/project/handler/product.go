@@ -28,6 +28,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	tenantservice "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -89,7 +90,16 @@ func CreateProductTemplate(c *gin.Context) {
 		return
 	}
 	args.UpdateBy = ctx.UserName
-	ctx.Err = projectservice.CreateProductTemplate(args, ctx.Logger)
+
+	// the project is bound to the tenant of the creator, the project quota of the tenant is checked first.
+	tenant, err := tenantservice.GetTenantForNewProject(c.Query("tenant"), ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	if ctx.Err = projectservice.CreateProductTemplate(args, ctx.Logger); ctx.Err == nil {
+		tenantservice.BindProject(tenant, args.ProductName, ctx.Logger)
+	}
 }
 
 // UpdateProductTemplate ...
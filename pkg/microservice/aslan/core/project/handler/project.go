@@ -20,6 +20,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	tenantservice "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 )
@@ -47,12 +48,22 @@ func ListProjects(c *gin.Context) {
 		return
 	}
 
+	names, err := tenantservice.FilterProjectNames(args.Names, ctx.UserID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	if names != nil && len(names) == 0 {
+		ctx.Resp = []*projectservice.ProjectMinimalRepresentation{}
+		return
+	}
+
 	ctx.Resp, ctx.Err = projectservice.ListProjects(
 		&projectservice.ProjectListOptions{
 			IgnoreNoEnvs:     args.IgnoreNoEnvs,
 			IgnoreNoVersions: args.IgnoreNoVersions,
 			Verbosity:        projectservice.QueryVerbosity(args.Verbosity),
-			Names:            args.Names,
+			Names:            names,
 		},
 		ctx.Logger,
 	)
@@ -43,8 +43,38 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		product.PUT("/:name", UpdateProductTemplate)
 		product.PUT("/:name/:status", UpdateProductTmplStatus)
 		product.PATCH("/:name", UpdateServiceOrchestration)
+		product.GET("/:name/dependencies", GetServiceDependencyGraph)
+		product.PUT("/:name/dependencies", UpdateServiceDependencies)
+		product.GET("/:name/commit-policy", GetCommitPolicy)
+		product.PUT("/:name/commit-policy", UpdateCommitPolicy)
+		product.GET("/:name/gerrit-voting-policy", GetGerritVotingPolicy)
+		product.PUT("/:name/gerrit-voting-policy", UpdateGerritVotingPolicy)
+		product.GET("/:name/manifest-policy", GetManifestPolicy)
+		product.PUT("/:name/manifest-policy", UpdateManifestPolicy)
+		product.GET("/:name/manifest-lint", GetManifestLint)
+		product.PUT("/:name/manifest-lint", UpdateManifestLint)
+		product.GET("/:name/license-policy", GetLicensePolicy)
+		product.PUT("/:name/license-policy", UpdateLicensePolicy)
+		product.PUT("/:name/custom-fields", UpdateProjectCustomFields)
+		product.POST("/:name/license-policy/waivers", CreateLicenseWaiver)
+		product.DELETE("/:name/license-policy/waivers/:id", DeleteLicenseWaiver)
+		product.GET("/:name/secret-scan-policy", GetSecretScanPolicy)
+		product.PUT("/:name/secret-scan-policy", UpdateSecretScanPolicy)
+		product.GET("/:name/job-security-policy", GetJobSecurityPolicy)
+		product.PUT("/:name/job-security-policy", UpdateJobSecurityPolicy)
+		product.GET("/:name/variable-groups", ListVariableGroups)
+		product.POST("/:name/variable-groups", CreateVariableGroup)
+		product.GET("/:name/variable-groups/:group", GetVariableGroup)
+		product.PUT("/:name/variable-groups/:group", UpdateVariableGroup)
+		product.DELETE("/:name/variable-groups/:group", DeleteVariableGroup)
+		product.GET("/:name/variable-groups/:group/history", ListVariableGroupHistory)
+		product.GET("/:name/variable-groups/:group/references", ListVariableGroupReferences)
 		product.PUT("", UpdateProject)
 		product.DELETE("/:name", DeleteProductTemplate)
+		product.GET("/:name/bundle", ExportProject)
+		product.GET("/:name/config-as-of", GetProjectConfigAsOf)
+		product.POST("/import", ImportProject)
+		product.POST("/:name/duplicate", DuplicateProject)
 	}
 
 	openSource := router.Group("opensource")
@@ -58,6 +88,22 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		template.GET("/info", ListTemplatesHierachy)
 	}
 
+	onboarding := router.Group("onboarding")
+	{
+		onboarding.POST("/analysis", AnalyzeRepository)
+		onboarding.POST("/apply", ApplyOnboarding)
+	}
+
+	blueprint := router.Group("blueprints")
+	{
+		blueprint.GET("", ListBlueprints)
+		blueprint.POST("", CreateBlueprint)
+		blueprint.GET("/:name", GetBlueprint)
+		blueprint.PUT("/:name", UpdateBlueprint)
+		blueprint.DELETE("/:name", DeleteBlueprint)
+		blueprint.POST("/:name/instantiate", InstantiateBlueprint)
+	}
+
 	project := router.Group("projects")
 	{
 		project.GET("", ListProjects)
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListVariableGroups(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = projectservice.ListVariableGroups(c.Param("name"), ctx.Logger)
+}
+
+func GetVariableGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = projectservice.GetVariableGroup(c.Param("name"), c.Param("group"), ctx.Logger)
+}
+
+func CreateVariableGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(projectservice.VariableGroupArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	projectName := c.Param("name")
+	// the values are not logged since they may be credentials
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "新增", "项目管理-变量组", args.Name, "", ctx.Logger)
+
+	ctx.Err = projectservice.CreateVariableGroup(projectName, args, ctx.UserName, ctx.Logger)
+}
+
+func UpdateVariableGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(projectservice.VariableGroupArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	projectName := c.Param("name")
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "更新", "项目管理-变量组", c.Param("group"), "", ctx.Logger)
+
+	ctx.Err = projectservice.UpdateVariableGroup(projectName, c.Param("group"), args, ctx.UserName, ctx.Logger)
+}
+
+func DeleteVariableGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Param("name")
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "删除", "项目管理-变量组", c.Param("group"), "", ctx.Logger)
+
+	ctx.Err = projectservice.DeleteVariableGroup(projectName, c.Param("group"), ctx.Logger)
+}
+
+func ListVariableGroupHistory(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = projectservice.ListVariableGroupHistory(c.Param("name"), c.Param("group"), ctx.Logger)
+}
+
+func ListVariableGroupReferences(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = projectservice.ListVariableGroupReferences(c.Param("name"), c.Param("group"), ctx.Logger)
+}
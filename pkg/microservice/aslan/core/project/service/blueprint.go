@@ -0,0 +1,279 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitlab"
+	environmentservice "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	// the contents of the generated repository are copied asynchronously by the codehost.
+	blueprintRepoReadyTimeout  = 2 * time.Minute
+	blueprintRepoReadyInterval = 3 * time.Second
+)
+
+type InstantiateBlueprintArgs struct {
+	// ProjectName is the project the repository is onboarded to, it is created if it does not exist.
+	ProjectName string `json:"project_name"`
+	// Namespace is the owner or the group the repository is created under, it is the namespace of the
+	// template repository if it is empty.
+	Namespace string `json:"namespace"`
+	Repo      string `json:"repo"`
+	Private   bool   `json:"private"`
+}
+
+type InstantiateBlueprintResp struct {
+	Namespace      string               `json:"namespace"`
+	Repo           string               `json:"repo"`
+	Branch         string               `json:"branch"`
+	ProjectCreated bool                 `json:"project_created"`
+	Onboarding     *ApplyOnboardingResp `json:"onboarding"`
+	EnvName        string               `json:"env_name,omitempty"`
+	EnvStatus      string               `json:"env_status,omitempty"`
+}
+
+func ListBlueprints(log *zap.SugaredLogger) ([]*commonmodels.Blueprint, error) {
+	blueprints, err := commonrepo.NewBlueprintColl().List()
+	if err != nil {
+		log.Errorf("Failed to list blueprints, err: %s", err)
+		return nil, e.ErrListBlueprint.AddErr(err)
+	}
+	return blueprints, nil
+}
+
+func GetBlueprint(name string, log *zap.SugaredLogger) (*commonmodels.Blueprint, error) {
+	blueprint, err := commonrepo.NewBlueprintColl().Find(name)
+	if err != nil {
+		log.Errorf("Failed to find blueprint %s, err: %s", name, err)
+		return nil, e.ErrGetBlueprint.AddErr(err)
+	}
+	return blueprint, nil
+}
+
+func CreateBlueprint(blueprint *commonmodels.Blueprint, log *zap.SugaredLogger) error {
+	if err := validateBlueprint(blueprint); err != nil {
+		return e.ErrCreateBlueprint.AddErr(err)
+	}
+	if _, err := commonrepo.NewBlueprintColl().Find(blueprint.Name); err == nil {
+		return e.ErrCreateBlueprint.AddDesc(fmt.Sprintf("blueprint %s already exists", blueprint.Name))
+	}
+	if err := commonrepo.NewBlueprintColl().Create(blueprint); err != nil {
+		log.Errorf("Failed to create blueprint %s, err: %s", blueprint.Name, err)
+		return e.ErrCreateBlueprint.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateBlueprint(blueprint *commonmodels.Blueprint, log *zap.SugaredLogger) error {
+	if err := validateBlueprint(blueprint); err != nil {
+		return e.ErrUpdateBlueprint.AddErr(err)
+	}
+	if err := commonrepo.NewBlueprintColl().Update(blueprint); err != nil {
+		log.Errorf("Failed to update blueprint %s, err: %s", blueprint.Name, err)
+		return e.ErrUpdateBlueprint.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteBlueprint(name string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewBlueprintColl().Delete(name); err != nil {
+		log.Errorf("Failed to delete blueprint %s, err: %s", name, err)
+		return e.ErrDeleteBlueprint.AddErr(err)
+	}
+	return nil
+}
+
+func validateBlueprint(blueprint *commonmodels.Blueprint) error {
+	if blueprint.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if blueprint.TemplateNamespace == "" || blueprint.TemplateRepo == "" {
+		return fmt.Errorf("template repo is required")
+	}
+	ch, err := systemconfig.New().GetCodeHost(blueprint.CodehostID)
+	if err != nil {
+		return fmt.Errorf("failed to get codehost %d: %s", blueprint.CodehostID, err)
+	}
+	if ch.Type != setting.SourceFromGithub && ch.Type != setting.SourceFromGitlab {
+		return fmt.Errorf("repositories can not be generated from the templates of codehost type %s", ch.Type)
+	}
+	return nil
+}
+
+// InstantiateBlueprint generates a repository from the template of the blueprint, onboards it to the
+// project like ApplyOnboarding does and provisions the environment of the blueprint. The generated
+// repository is kept if a later step fails, the onboarding can be retried on it.
+func InstantiateBlueprint(name string, args *InstantiateBlueprintArgs, userID, username, requestID string, log *zap.SugaredLogger) (*InstantiateBlueprintResp, error) {
+	blueprint, err := commonrepo.NewBlueprintColl().Find(name)
+	if err != nil {
+		log.Errorf("Failed to find blueprint %s, err: %s", name, err)
+		return nil, e.ErrInstantiateBlueprint.AddDesc(fmt.Sprintf("blueprint %s not found", name))
+	}
+	ch, err := systemconfig.New().GetCodeHost(blueprint.CodehostID)
+	if err != nil {
+		log.Errorf("Failed to get codehost %d, err: %s", blueprint.CodehostID, err)
+		return nil, e.ErrInstantiateBlueprint.AddErr(err)
+	}
+	namespace := args.Namespace
+	if namespace == "" {
+		namespace = blueprint.TemplateNamespace
+	}
+
+	resp, err := generateRepository(ch, blueprint, namespace, args.Repo, args.Private)
+	if err != nil {
+		log.Errorf("Failed to generate repo %s/%s from blueprint %s, err: %s", namespace, args.Repo, name, err)
+		return nil, e.ErrInstantiateBlueprint.AddDesc(fmt.Sprintf("failed to generate the repository: %s", err))
+	}
+	if blueprint.Branch != "" {
+		resp.Branch = blueprint.Branch
+	}
+	if err := waitRepositoryReady(ch.ID, resp.Namespace, resp.Repo, resp.Branch); err != nil {
+		log.Errorf("Repo %s/%s is not ready, err: %s", resp.Namespace, resp.Repo, err)
+		return nil, e.ErrInstantiateBlueprint.AddDesc(fmt.Sprintf("repository %s/%s is not ready: %s", resp.Namespace, resp.Repo, err))
+	}
+
+	analysisArgs := RepoAnalysisArgs{
+		CodehostID:  ch.ID,
+		Owner:       resp.Namespace,
+		Namespace:   resp.Namespace,
+		Repo:        resp.Repo,
+		Branch:      resp.Branch,
+		ProjectName: args.ProjectName,
+		EnvName:     blueprint.EnvName,
+	}
+	analysis, err := AnalyzeRepository(&analysisArgs, log)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := templaterepo.NewProductColl().Find(args.ProjectName); errors.Is(err, mongo.ErrNoDocuments) {
+		var projectType config.ProjectType = config.ProjectTypeYaml
+		if analysis.DeployType == setting.HelmDeployType {
+			projectType = config.ProjectTypeHelm
+		}
+		if err := CreateProjectOpenAPI(userID, username, &OpenAPICreateProductReq{
+			ProjectName: args.ProjectName,
+			ProjectKey:  args.ProjectName,
+			Description: blueprint.Description,
+			ProjectType: projectType,
+		}, log); err != nil {
+			log.Errorf("Failed to create project %s, err: %s", args.ProjectName, err)
+			return nil, e.ErrInstantiateBlueprint.AddDesc(fmt.Sprintf("failed to create project %s: %s", args.ProjectName, err))
+		}
+		resp.ProjectCreated = true
+	} else if err != nil {
+		return nil, e.ErrInstantiateBlueprint.AddErr(err)
+	}
+
+	resp.Onboarding, err = ApplyOnboarding(&ApplyOnboardingArgs{
+		RepoAnalysisArgs: analysisArgs,
+		Services:         analysis.Services,
+		Builds:           analysis.Builds,
+		Workflow:         analysis.Workflow,
+	}, username, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// the environment is not provisioned without services, it can be created later.
+	if blueprint.EnvName == "" || len(resp.Onboarding.Services) == 0 {
+		return resp, nil
+	}
+	resp.EnvName = blueprint.EnvName
+	resp.EnvStatus, err = environmentservice.NewAutoCreator(&environmentservice.CreateProductParam{
+		UserName:    username,
+		RequestId:   requestID,
+		ProductName: args.ProjectName,
+		EnvType:     analysis.DeployType,
+		RegistryID:  blueprint.RegistryID,
+		ClusterID:   blueprint.ClusterID,
+	}, log).Create(blueprint.EnvName)
+	if err != nil {
+		resp.Onboarding.Failed = append(resp.Onboarding.Failed, &OnboardingFailure{Kind: "environment", Name: blueprint.EnvName, Error: err.Error()})
+	}
+	return resp, nil
+}
+
+// generateRepository creates the repository from the template by the codehost api, the branch of the
+// response is the default branch of the new repository.
+func generateRepository(ch *systemconfig.CodeHost, blueprint *commonmodels.Blueprint, namespace, repo string, private bool) (*InstantiateBlueprintResp, error) {
+	switch ch.Type {
+	case setting.SourceFromGithub:
+		gc := github.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		created, err := gc.CreateRepositoryFromTemplate(context.TODO(), blueprint.TemplateNamespace, blueprint.TemplateRepo, namespace, repo, private)
+		if err != nil {
+			return nil, err
+		}
+		return &InstantiateBlueprintResp{Namespace: created.GetOwner().GetLogin(), Repo: created.GetName(), Branch: created.GetDefaultBranch()}, nil
+	case setting.SourceFromGitlab:
+		gc, err := gitlab.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		if err != nil {
+			return nil, err
+		}
+		created, err := gc.CreateProjectFromTemplate(blueprint.TemplateNamespace, blueprint.TemplateRepo, namespace, repo, private)
+		if err != nil {
+			return nil, err
+		}
+		resp := &InstantiateBlueprintResp{Namespace: namespace, Repo: created.Path, Branch: created.DefaultBranch}
+		if created.Namespace != nil {
+			resp.Namespace = created.Namespace.FullPath
+		}
+		return resp, nil
+	default:
+		return nil, fmt.Errorf("repositories can not be generated from the templates of codehost type %s", ch.Type)
+	}
+}
+
+func waitRepositoryReady(codehostID int, namespace, repo, branch string) error {
+	getter, err := fs.GetTreeGetter(codehostID)
+	if err != nil {
+		return err
+	}
+	timeout := time.After(blueprintRepoReadyTimeout)
+	for {
+		nodes, err := getter.GetTree(namespace, repo, "", branch)
+		if err == nil && len(nodes) > 0 {
+			return nil
+		}
+		select {
+		case <-timeout:
+			if err == nil {
+				err = fmt.Errorf("repository is empty")
+			}
+			return err
+		case <-time.After(blueprintRepoReadyInterval):
+		}
+	}
+}
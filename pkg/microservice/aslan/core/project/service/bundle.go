@@ -0,0 +1,725 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const ProjectBundleVersion = "v1"
+
+// BundleObject is a resource of the project in its json form, the credentials are replaced by
+// placeholders.
+type BundleObject map[string]interface{}
+
+// ProjectBundle is a portable copy of a project which can be imported into another installation.
+type ProjectBundle struct {
+	Version         string             `json:"version"`
+	ExportTime      int64              `json:"export_time"`
+	ProjectName     string             `json:"project_name"`
+	Project         BundleObject       `json:"project"`
+	Services        []BundleObject     `json:"services"`
+	Builds          []BundleObject     `json:"builds"`
+	Workflows       []BundleObject     `json:"workflows"`
+	CustomWorkflows []BundleObject     `json:"custom_workflows"`
+	EnvTemplates    []BundleObject     `json:"env_templates"`
+	References      []*BundleReference `json:"references"`
+	Secrets         []*BundleSecret    `json:"secrets"`
+	// CustomFields are the definitions of the custom fields the project and its workflows have values of, the
+	// missing ones are created when the bundle is imported.
+	CustomFields []*commonmodels.CustomField `json:"custom_fields,omitempty"`
+}
+
+// BundleReference is an integration used by the project, it has to be mapped to the one of the
+// target installation when the bundle is imported.
+type BundleReference struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	// Name is the address of the codehost or the registry, or the name of the cluster.
+	Name string `json:"name"`
+}
+
+func (r *BundleReference) Key() string {
+	return r.Kind + ":" + r.ID
+}
+
+// BundleSecret is a credential removed from the bundle, the value has to be provided on import.
+type BundleSecret struct {
+	Placeholder string `json:"placeholder"`
+	Path        string `json:"path"`
+}
+
+type BundleConflictPolicy string
+
+const (
+	BundleConflictFail      BundleConflictPolicy = "fail"
+	BundleConflictSkip      BundleConflictPolicy = "skip"
+	BundleConflictOverwrite BundleConflictPolicy = "overwrite"
+)
+
+type ImportProjectArgs struct {
+	Bundle *ProjectBundle `json:"bundle"`
+	// ProjectName imports the bundle as another project, the name in the bundle is used if empty.
+	ProjectName string               `json:"project_name"`
+	Conflict    BundleConflictPolicy `json:"conflict"`
+	// References maps the key of a BundleReference to the id of the integration in this installation.
+	References map[string]string `json:"references"`
+	// Secrets maps the placeholders to the credentials.
+	Secrets map[string]string `json:"secrets"`
+	DryRun  bool              `json:"dry_run"`
+}
+
+type ImportItem struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+type ImportProjectResp struct {
+	ProjectName string        `json:"project_name"`
+	Items       []*ImportItem `json:"items"`
+	Warnings    []string      `json:"warnings"`
+}
+
+const (
+	bundleKindCodehost = "codehost"
+	bundleKindCluster  = "cluster"
+	bundleKindRegistry = "registry"
+
+	importActionCreate    = "create"
+	importActionOverwrite = "overwrite"
+	importActionSkip      = "skip"
+)
+
+// bundleReferenceKeys are the json keys whose values are ids of the integrations.
+var bundleReferenceKeys = map[string]string{
+	"codehost_id":        bundleKindCodehost,
+	"cluster_id":         bundleKindCluster,
+	"cluster_ids":        bundleKindCluster,
+	"deploy_cluster_id":  bundleKindCluster,
+	"registry_id":        bundleKindRegistry,
+	"docker_registry_id": bundleKindRegistry,
+}
+
+// bundleSecretKeys are the json keys whose values are credentials.
+var bundleSecretKeys = sets.NewString(
+	"password", "access_key", "secret_key", "access_token", "oauth_token", "token",
+	"private_key", "ssh_key", "ak", "sk",
+)
+
+func ExportProject(projectName string, log *zap.SugaredLogger) (*ProjectBundle, error) {
+	bundle, _, err := exportProject(projectName, log)
+	return bundle, err
+}
+
+// exportProject returns the bundle and the credentials removed from it keyed by the placeholders.
+func exportProject(projectName string, log *zap.SugaredLogger) (*ProjectBundle, map[string]string, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, nil, e.ErrExportProject.AddErr(err)
+	}
+	services, err := commonrepo.NewServiceColl().ListMaxRevisionsByProduct(projectName)
+	if err != nil {
+		log.Errorf("failed to list the services of project %s, err: %s", projectName, err)
+		return nil, nil, e.ErrExportProject.AddErr(err)
+	}
+	builds, err := commonrepo.NewBuildColl().List(&commonrepo.BuildListOption{ProductName: projectName})
+	if err != nil {
+		log.Errorf("failed to list the builds of project %s, err: %s", projectName, err)
+		return nil, nil, e.ErrExportProject.AddErr(err)
+	}
+	workflows, err := commonrepo.NewWorkflowColl().List(&commonrepo.ListWorkflowOption{Projects: []string{projectName}})
+	if err != nil {
+		log.Errorf("failed to list the workflows of project %s, err: %s", projectName, err)
+		return nil, nil, e.ErrExportProject.AddErr(err)
+	}
+	customWorkflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		log.Errorf("failed to list the custom workflows of project %s, err: %s", projectName, err)
+		return nil, nil, e.ErrExportProject.AddErr(err)
+	}
+
+	w := &bundleExporter{references: map[string]*BundleReference{}, values: map[string]string{}}
+	bundle := &ProjectBundle{
+		Version:     ProjectBundleVersion,
+		ExportTime:  time.Now().Unix(),
+		ProjectName: projectName,
+	}
+	if bundle.Project, err = w.export("project", project); err != nil {
+		return nil, nil, e.ErrExportProject.AddErr(err)
+	}
+	for _, service := range services {
+		obj, err := w.export("services."+service.ServiceName, service)
+		if err != nil {
+			return nil, nil, e.ErrExportProject.AddErr(err)
+		}
+		bundle.Services = append(bundle.Services, obj)
+	}
+	for _, build := range builds {
+		obj, err := w.export("builds."+build.Name, build)
+		if err != nil {
+			return nil, nil, e.ErrExportProject.AddErr(err)
+		}
+		bundle.Builds = append(bundle.Builds, obj)
+	}
+	for _, workflow := range workflows {
+		obj, err := w.export("workflows."+workflow.Name, workflow)
+		if err != nil {
+			return nil, nil, e.ErrExportProject.AddErr(err)
+		}
+		bundle.Workflows = append(bundle.Workflows, obj)
+	}
+	for _, workflow := range customWorkflows {
+		// the notifications are stored separately and are not exported.
+		workflow.NotificationID = ""
+		obj, err := w.export("custom_workflows."+workflow.Name, workflow)
+		if err != nil {
+			return nil, nil, e.ErrExportProject.AddErr(err)
+		}
+		bundle.CustomWorkflows = append(bundle.CustomWorkflows, obj)
+	}
+	renderSet, err := commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{Name: projectName, ProductTmpl: projectName})
+	if err == nil {
+		obj, err := w.export("env_templates."+renderSet.Name, renderSet)
+		if err != nil {
+			return nil, nil, e.ErrExportProject.AddErr(err)
+		}
+		bundle.EnvTemplates = append(bundle.EnvTemplates, obj)
+	} else {
+		log.Warnf("failed to find the default render set of project %s, err: %s", projectName, err)
+	}
+
+	bundle.Secrets = w.secrets
+	bundle.References = w.resolveReferences(log)
+	bundle.CustomFields = exportCustomFields(project, customWorkflows, log)
+	return bundle, w.values, nil
+}
+
+func ImportProject(args *ImportProjectArgs, userName string, log *zap.SugaredLogger) (*ImportProjectResp, error) {
+	bundle := args.Bundle
+	if bundle == nil || bundle.Project == nil {
+		return nil, e.ErrInvalidParam.AddDesc("empty project bundle")
+	}
+	if bundle.Version != ProjectBundleVersion {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("unsupported bundle version %s", bundle.Version))
+	}
+	if args.Conflict == "" {
+		args.Conflict = BundleConflictFail
+	}
+	if args.Conflict != BundleConflictFail && args.Conflict != BundleConflictSkip && args.Conflict != BundleConflictOverwrite {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid conflict policy %s", args.Conflict))
+	}
+	projectName := args.ProjectName
+	if projectName == "" {
+		projectName = bundle.ProjectName
+	}
+
+	imp := &bundleImporter{args: args, resp: &ImportProjectResp{ProjectName: projectName, Items: []*ImportItem{}, Warnings: []string{}}}
+	for _, ref := range bundle.References {
+		if _, ok := args.References[ref.Key()]; !ok {
+			imp.warn("%s %s (%s) is not mapped, the original id is kept", ref.Kind, ref.ID, ref.Name)
+		}
+	}
+	for _, secret := range bundle.Secrets {
+		if _, ok := args.Secrets[secret.Placeholder]; !ok {
+			imp.warn("the value of %s is not provided and is left empty", secret.Path)
+		}
+	}
+
+	plan, err := imp.plan(projectName, userName)
+	if err != nil {
+		log.Errorf("failed to import project %s, err: %s", projectName, err)
+		return nil, e.ErrImportProject.AddErr(err)
+	}
+	if args.DryRun {
+		return imp.resp, nil
+	}
+	if err := plan.apply(userName, log); err != nil {
+		log.Errorf("failed to import project %s, err: %s", projectName, err)
+		return imp.resp, e.ErrImportProject.AddErr(err)
+	}
+	return imp.resp, nil
+}
+
+// ProjectCreated returns whether the project is newly created by the import.
+func (r *ImportProjectResp) ProjectCreated() bool {
+	for _, item := range r.Items {
+		if item.Kind == "project" && item.Action == importActionCreate {
+			return true
+		}
+	}
+	return false
+}
+
+type bundleExporter struct {
+	references map[string]*BundleReference
+	secrets    []*BundleSecret
+	values     map[string]string
+}
+
+func (w *bundleExporter) export(path string, obj interface{}) (BundleObject, error) {
+	resp, err := toBundleObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(resp, "id")
+	w.walk(path, map[string]interface{}(resp))
+	return resp, nil
+}
+
+func (w *bundleExporter) walk(path string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// the values of the variables marked as credential are removed as well.
+		credential := v["is_credential"] == true
+		for key, item := range v {
+			itemPath := path + "." + key
+			if kind, ok := bundleReferenceKeys[key]; ok {
+				for _, id := range referenceIDs(item) {
+					w.references[kind+":"+id] = &BundleReference{Kind: kind, ID: id}
+				}
+				continue
+			}
+			if s, ok := item.(string); ok && s != "" && (bundleSecretKeys.Has(key) || (credential && key == "value")) {
+				placeholder := fmt.Sprintf("${secret:%d}", len(w.secrets)+1)
+				w.secrets = append(w.secrets, &BundleSecret{Placeholder: placeholder, Path: itemPath})
+				w.values[placeholder] = s
+				v[key] = placeholder
+				continue
+			}
+			w.walk(itemPath, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			w.walk(fmt.Sprintf("%s.%d", path, i), item)
+		}
+	}
+}
+
+func (w *bundleExporter) resolveReferences(log *zap.SugaredLogger) []*BundleReference {
+	resp := make([]*BundleReference, 0, len(w.references))
+	for _, ref := range w.references {
+		switch ref.Kind {
+		case bundleKindCodehost:
+			id, _ := strconv.Atoi(ref.ID)
+			if codeHost, err := systemconfig.New().GetCodeHost(id); err == nil {
+				ref.Name = codeHost.Address
+			} else {
+				log.Warnf("failed to find codehost %s, err: %s", ref.ID, err)
+			}
+		case bundleKindCluster:
+			if cluster, err := commonrepo.NewK8SClusterColl().Get(ref.ID); err == nil {
+				ref.Name = cluster.Name
+			} else {
+				log.Warnf("failed to find cluster %s, err: %s", ref.ID, err)
+			}
+		case bundleKindRegistry:
+			if registry, err := commonrepo.NewRegistryNamespaceColl().Find(&commonrepo.FindRegOps{ID: ref.ID}); err == nil {
+				ref.Name = registry.RegAddr + "/" + registry.Namespace
+			} else {
+				log.Warnf("failed to find registry %s, err: %s", ref.ID, err)
+			}
+		}
+		resp = append(resp, ref)
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].Key() < resp[j].Key() })
+	return resp
+}
+
+type bundleImporter struct {
+	args *ImportProjectArgs
+	resp *ImportProjectResp
+}
+
+// importPlan holds the decoded resources and whether the existing ones are overwritten.
+type importPlan struct {
+	projectName     string
+	project         *template.Product
+	projectExists   bool
+	services        []*commonmodels.Service
+	builds          []*commonmodels.Build
+	buildsExist     map[string]bool
+	workflows       []*commonmodels.Workflow
+	workflowsExist  map[string]bool
+	customWorkflows []*commonmodels.WorkflowV4
+	customExists    map[string]string
+	envTemplates    []*commonmodels.RenderSet
+	customFields    []*commonmodels.CustomField
+}
+
+// exportCustomFields returns the definitions of the custom fields having values in the project or the workflows.
+func exportCustomFields(project *template.Product, workflows []*commonmodels.WorkflowV4, log *zap.SugaredLogger) []*commonmodels.CustomField {
+	keys := sets.NewString()
+	for key := range project.CustomFields {
+		keys.Insert(key)
+	}
+	for _, workflow := range workflows {
+		for key := range workflow.CustomFields {
+			keys.Insert(key)
+		}
+	}
+
+	fields := make([]*commonmodels.CustomField, 0)
+	for _, key := range keys.List() {
+		field, err := commonrepo.NewCustomFieldColl().FindByKey(key)
+		if err != nil {
+			log.Warnf("failed to find custom field %s, err: %s", key, err)
+			continue
+		}
+		field.ID = primitive.NilObjectID
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func (imp *bundleImporter) warn(format string, a ...interface{}) {
+	imp.resp.Warnings = append(imp.resp.Warnings, fmt.Sprintf(format, a...))
+}
+
+// plan decodes the bundle and decides what to do with each resource, an error is returned if a
+// resource exists and the conflict policy is fail, or it belongs to another project.
+func (imp *bundleImporter) plan(projectName, userName string) (*importPlan, error) {
+	bundle := imp.args.Bundle
+	p := &importPlan{
+		projectName:    projectName,
+		project:        &template.Product{},
+		buildsExist:    map[string]bool{},
+		workflowsExist: map[string]bool{},
+		customExists:   map[string]string{},
+	}
+	var conflicts []string
+	// decide returns false if the resource is skipped.
+	decide := func(kind, name string, exists bool) bool {
+		action := importActionCreate
+		if exists {
+			switch imp.args.Conflict {
+			case BundleConflictSkip:
+				action = importActionSkip
+			case BundleConflictOverwrite:
+				action = importActionOverwrite
+			default:
+				conflicts = append(conflicts, fmt.Sprintf("%s %s", kind, name))
+			}
+		}
+		imp.resp.Items = append(imp.resp.Items, &ImportItem{Kind: kind, Name: name, Action: action})
+		return action != importActionSkip
+	}
+
+	for _, field := range bundle.CustomFields {
+		existing, err := commonrepo.NewCustomFieldColl().FindByKey(field.Key)
+		if err != nil {
+			imp.resp.Items = append(imp.resp.Items, &ImportItem{Kind: "custom_field", Name: field.Key, Action: importActionCreate})
+			p.customFields = append(p.customFields, field)
+			continue
+		}
+		if existing.Scope != field.Scope {
+			return nil, fmt.Errorf("custom field %s is a field of the %ss", field.Key, existing.Scope)
+		}
+	}
+
+	if err := imp.decode(bundle.Project, p.project); err != nil {
+		return nil, err
+	}
+	p.project.ProductName = projectName
+	if p.project.ProjectName == "" || bundle.ProjectName != projectName {
+		p.project.ProjectName = projectName
+	}
+	p.project.UpdateBy = userName
+	_, err := templaterepo.NewProductColl().Find(projectName)
+	p.projectExists = err == nil
+	if !decide("project", projectName, p.projectExists) {
+		p.project = nil
+	}
+
+	for _, obj := range bundle.Services {
+		service := &commonmodels.Service{}
+		if err := imp.decode(obj, service); err != nil {
+			return nil, err
+		}
+		service.ProductName = projectName
+		_, err := commonrepo.NewServiceColl().Find(&commonrepo.ServiceFindOption{ServiceName: service.ServiceName, ProductName: projectName, ExcludeStatus: setting.ProductStatusDeleting})
+		if decide("service", service.ServiceName, err == nil) {
+			p.services = append(p.services, service)
+		}
+	}
+
+	for _, obj := range bundle.Builds {
+		build := &commonmodels.Build{}
+		if err := imp.decode(obj, build); err != nil {
+			return nil, err
+		}
+		build.ProductName = projectName
+		// build names are unique in the installation.
+		existing, err := commonrepo.NewBuildColl().Find(&commonrepo.BuildFindOption{Name: build.Name})
+		if err == nil && existing.ProductName != projectName {
+			return nil, fmt.Errorf("build %s belongs to project %s", build.Name, existing.ProductName)
+		}
+		p.buildsExist[build.Name] = err == nil
+		if decide("build", build.Name, err == nil) {
+			p.builds = append(p.builds, build)
+		}
+	}
+
+	for _, obj := range bundle.Workflows {
+		workflow := &commonmodels.Workflow{}
+		if err := imp.decode(obj, workflow); err != nil {
+			return nil, err
+		}
+		workflow.ProductTmplName = projectName
+		existing, err := commonrepo.NewWorkflowColl().Find(workflow.Name)
+		if err == nil && existing.ProductTmplName != projectName {
+			return nil, fmt.Errorf("workflow %s belongs to project %s", workflow.Name, existing.ProductTmplName)
+		}
+		p.workflowsExist[workflow.Name] = err == nil
+		if decide("workflow", workflow.Name, err == nil) {
+			p.workflows = append(p.workflows, workflow)
+		}
+	}
+
+	for _, obj := range bundle.CustomWorkflows {
+		workflow := &commonmodels.WorkflowV4{}
+		if err := imp.decode(obj, workflow); err != nil {
+			return nil, err
+		}
+		workflow.Project = projectName
+		existing, err := commonrepo.NewWorkflowV4Coll().Find(workflow.Name)
+		if err == nil && existing.Project != projectName {
+			return nil, fmt.Errorf("custom workflow %s belongs to project %s", workflow.Name, existing.Project)
+		}
+		if err == nil {
+			p.customExists[workflow.Name] = existing.ID.Hex()
+		}
+		if decide("custom_workflow", workflow.Name, err == nil) {
+			p.customWorkflows = append(p.customWorkflows, workflow)
+		}
+	}
+
+	for _, obj := range bundle.EnvTemplates {
+		renderSet := &commonmodels.RenderSet{}
+		if err := imp.decode(obj, renderSet); err != nil {
+			return nil, err
+		}
+		renderSet.Name = projectName
+		renderSet.ProductTmpl = projectName
+		renderSet.IsDefault = true
+		_, err := commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{Name: projectName, ProductTmpl: projectName})
+		// the default render set is created with a new project.
+		if decide("env_template", renderSet.Name, err == nil && p.projectExists) {
+			p.envTemplates = append(p.envTemplates, renderSet)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%s already exist", strings.Join(conflicts, ", "))
+	}
+	return p, nil
+}
+
+// decode replaces the references and the placeholders of the bundle object and decodes it.
+func (imp *bundleImporter) decode(obj BundleObject, resp interface{}) error {
+	copied, err := toBundleObject(obj)
+	if err != nil {
+		return err
+	}
+	imp.walk(map[string]interface{}(copied))
+	data, err := json.Marshal(copied)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, resp)
+}
+
+func (imp *bundleImporter) walk(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if kind, ok := bundleReferenceKeys[key]; ok {
+				v[key] = imp.mapReference(kind, item)
+				continue
+			}
+			if s, ok := item.(string); ok && strings.HasPrefix(s, "${secret:") {
+				v[key] = imp.args.Secrets[s]
+				continue
+			}
+			imp.walk(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			imp.walk(item)
+		}
+	}
+}
+
+func (imp *bundleImporter) mapReference(kind string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if id, ok := imp.args.References[kind+":"+v]; ok && v != "" {
+			return id
+		}
+	case json.Number:
+		if id, ok := imp.args.References[kind+":"+v.String()]; ok {
+			return json.Number(id)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = imp.mapReference(kind, item)
+		}
+	}
+	return value
+}
+
+func (p *importPlan) apply(userName string, log *zap.SugaredLogger) error {
+	for _, field := range p.customFields {
+		field.CreatedBy = userName
+		field.UpdateBy = userName
+		if err := commonrepo.NewCustomFieldColl().Create(field); err != nil {
+			return fmt.Errorf("failed to create custom field %s: %s", field.Key, err)
+		}
+	}
+
+	if p.project != nil {
+		if p.projectExists {
+			if err := templaterepo.NewProductColl().Update(p.projectName, p.project); err != nil {
+				return fmt.Errorf("failed to update project %s: %s", p.projectName, err)
+			}
+			if err := templaterepo.NewProductColl().UpdateCustomFields(p.projectName, p.project.CustomFields, userName); err != nil {
+				return fmt.Errorf("failed to update the custom fields of project %s: %s", p.projectName, err)
+			}
+		} else if err := CreateProductTemplate(p.project, log); err != nil {
+			return fmt.Errorf("failed to create project %s: %s", p.projectName, err)
+		}
+	}
+
+	for _, service := range p.services {
+		rev, err := commonrepo.NewCounterColl().GetNextSeq(fmt.Sprintf(setting.ServiceTemplateCounterName, service.ServiceName, p.projectName))
+		if err != nil {
+			return fmt.Errorf("failed to get the revision of service %s: %s", service.ServiceName, err)
+		}
+		service.Revision = rev
+		service.CreateBy = userName
+		service.CreateTime = time.Now().Unix()
+		if err := commonrepo.NewServiceColl().Delete(service.ServiceName, service.Type, p.projectName, setting.ProductStatusDeleting, rev); err != nil {
+			log.Warnf("failed to delete stale service %s with revision %d, err: %s", service.ServiceName, rev, err)
+		}
+		if err := commonrepo.NewServiceColl().Create(service); err != nil {
+			return fmt.Errorf("failed to create service %s: %s", service.ServiceName, err)
+		}
+	}
+
+	for _, build := range p.builds {
+		build.UpdateBy = userName
+		var err error
+		if p.buildsExist[build.Name] {
+			err = commonrepo.NewBuildColl().Update(build)
+		} else {
+			err = commonrepo.NewBuildColl().Create(build)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save build %s: %s", build.Name, err)
+		}
+	}
+
+	for _, workflow := range p.workflows {
+		workflow.UpdateBy = userName
+		var err error
+		if p.workflowsExist[workflow.Name] {
+			err = commonrepo.NewWorkflowColl().Replace(workflow)
+		} else {
+			err = commonrepo.NewWorkflowColl().Create(workflow)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save workflow %s: %s", workflow.Name, err)
+		}
+	}
+
+	for _, workflow := range p.customWorkflows {
+		workflow.UpdatedBy = userName
+		workflow.UpdateTime = time.Now().Unix()
+		var err error
+		if id, ok := p.customExists[workflow.Name]; ok {
+			err = commonrepo.NewWorkflowV4Coll().Update(id, workflow)
+		} else {
+			workflow.CreatedBy = userName
+			workflow.CreateTime = time.Now().Unix()
+			_, err = commonrepo.NewWorkflowV4Coll().Create(workflow)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save custom workflow %s: %s", workflow.Name, err)
+		}
+	}
+
+	for _, renderSet := range p.envTemplates {
+		renderSet.UpdateBy = userName
+		if err := commonservice.CreateRenderSet(renderSet, log); err != nil {
+			return fmt.Errorf("failed to save the env template of project %s: %s", p.projectName, err)
+		}
+	}
+	return nil
+}
+
+func toBundleObject(obj interface{}) (BundleObject, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	// numbers are kept as they are, e.g. the int64 timestamps.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	resp := BundleObject{}
+	err = decoder.Decode(&resp)
+	return resp, err
+}
+
+func referenceIDs(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case json.Number:
+		if v.String() != "0" {
+			return []string{v.String()}
+		}
+	case []interface{}:
+		var resp []string
+		for _, item := range v {
+			resp = append(resp, referenceIDs(item)...)
+		}
+		return resp
+	}
+	return nil
+}
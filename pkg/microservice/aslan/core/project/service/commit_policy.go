@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetCommitPolicy(projectName string, log *zap.SugaredLogger) (*template.CommitPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetCommitPolicy.AddErr(err)
+	}
+	if project.CommitPolicy == nil {
+		return &template.CommitPolicy{}, nil
+	}
+	return project.CommitPolicy, nil
+}
+
+func UpdateCommitPolicy(projectName string, policy *template.CommitPolicy, updateBy string, log *zap.SugaredLogger) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return e.ErrUpdateCommitPolicy.AddErr(err)
+	}
+	for i, domain := range policy.AllowedAuthorDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain == "" {
+			return e.ErrUpdateCommitPolicy.AddErr(fmt.Errorf("empty author domain"))
+		}
+		policy.AllowedAuthorDomains[i] = domain
+	}
+	if policy.Enabled && !policy.RequireSigned && len(policy.AllowedAuthorDomains) == 0 {
+		return e.ErrUpdateCommitPolicy.AddErr(fmt.Errorf("either signed commits or allowed author domains should be required"))
+	}
+
+	if err := templaterepo.NewProductColl().UpdateCommitPolicy(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to update commit policy of project %s, err: %s", projectName, err)
+		return e.ErrUpdateCommitPolicy.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// ProjectConfigAsOf is the configuration of a project as of a time.
+type ProjectConfigAsOf struct {
+	ProjectName  string                     `json:"project_name"`
+	Timestamp    int64                      `json:"timestamp"`
+	Workflows    []*commonmodels.WorkflowV4 `json:"workflows"`
+	Services     []*commonmodels.Service    `json:"services"`
+	Environments []*EnvConfigAsOf           `json:"environments"`
+	// Notes lists the resources which have been changed since the time without a version saved before it, their
+	// current states are returned instead.
+	Notes []string `json:"notes"`
+}
+
+type EnvConfigAsOf struct {
+	Env       *commonmodels.Product   `json:"env"`
+	RenderSet *commonmodels.RenderSet `json:"render_set"`
+}
+
+// GetProjectConfigAsOf reconstructs the workflows, the services and the environments of the project as of the time
+// from their versions. The credentials in the workflows are masked.
+func GetProjectConfigAsOf(projectName string, timestamp int64, log *zap.SugaredLogger) (*ProjectConfigAsOf, error) {
+	if timestamp <= 0 {
+		return nil, e.ErrInvalidParam.AddDesc("timestamp is required")
+	}
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("project %s not found", projectName))
+		}
+		return nil, e.ErrGetProjectConfigAsOf.AddErr(err)
+	}
+
+	resp := &ProjectConfigAsOf{ProjectName: projectName, Timestamp: timestamp, Notes: make([]string, 0)}
+	var err error
+	if resp.Workflows, err = workflowsAsOf(projectName, timestamp, resp); err != nil {
+		log.Errorf("failed to get workflows of project %s as of %d, err: %s", projectName, timestamp, err)
+		return nil, e.ErrGetProjectConfigAsOf.AddErr(err)
+	}
+	if resp.Services, err = commonrepo.NewServiceColl().ListMaxRevisionsAsOf(projectName, timestamp); err != nil {
+		log.Errorf("failed to get services of project %s as of %d, err: %s", projectName, timestamp, err)
+		return nil, e.ErrGetProjectConfigAsOf.AddErr(err)
+	}
+	if resp.Services == nil {
+		resp.Services = make([]*commonmodels.Service, 0)
+	}
+	sort.Slice(resp.Services, func(i, j int) bool { return resp.Services[i].ServiceName < resp.Services[j].ServiceName })
+	if resp.Environments, err = envsAsOf(projectName, timestamp, resp); err != nil {
+		log.Errorf("failed to get environments of project %s as of %d, err: %s", projectName, timestamp, err)
+		return nil, e.ErrGetProjectConfigAsOf.AddErr(err)
+	}
+	return resp, nil
+}
+
+func workflowsAsOf(projectName string, timestamp int64, resp *ProjectConfigAsOf) ([]*commonmodels.WorkflowV4, error) {
+	versions, err := commonrepo.NewWorkflowV4VersionColl().ListAsOf(projectName, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	versioned := make(map[string]*commonmodels.WorkflowV4Version)
+	for _, version := range versions {
+		versioned[version.WorkflowName] = version
+	}
+	current, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows := make([]*commonmodels.WorkflowV4, 0)
+	for _, version := range versioned {
+		if !version.Deleted && version.Workflow != nil {
+			workflows = append(workflows, version.Workflow)
+		}
+	}
+	// the workflows created before the versions were saved have no version
+	for _, workflow := range current {
+		if _, ok := versioned[workflow.Name]; ok || workflow.CreateTime > timestamp {
+			continue
+		}
+		if workflow.UpdateTime > timestamp {
+			resp.Notes = append(resp.Notes, fmt.Sprintf("workflow %s has been updated since", workflow.Name))
+		}
+		workflows = append(workflows, workflow)
+	}
+	for _, workflow := range workflows {
+		maskWorkflowCredentials(workflow)
+	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].Name < workflows[j].Name })
+	return workflows, nil
+}
+
+func envsAsOf(projectName string, timestamp int64, resp *ProjectConfigAsOf) ([]*EnvConfigAsOf, error) {
+	versions, err := commonrepo.NewEnvVersionColl().ListAsOf(projectName, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	versioned := make(map[string]*commonmodels.EnvVersion)
+	for _, version := range versions {
+		versioned[version.EnvName] = version
+	}
+	current, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{Name: projectName})
+	if err != nil {
+		return nil, err
+	}
+
+	envs := make([]*EnvConfigAsOf, 0)
+	for _, version := range versioned {
+		if version.Deleted || version.Env == nil {
+			continue
+		}
+		env := &EnvConfigAsOf{Env: version.Env}
+		if version.Env.Render != nil {
+			renderSet, err := commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{
+				Name:     version.Env.Render.Name,
+				Revision: version.Env.Render.Revision,
+			})
+			if err == nil {
+				env.RenderSet = renderSet
+			} else if !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, err
+			}
+		}
+		envs = append(envs, env)
+	}
+	// the environments created before the versions were saved have no version
+	for _, product := range current {
+		if _, ok := versioned[product.EnvName]; ok || product.CreateTime > timestamp {
+			continue
+		}
+		if product.UpdateTime > timestamp {
+			resp.Notes = append(resp.Notes, fmt.Sprintf("environment %s has been updated since", product.EnvName))
+		}
+		env := &EnvConfigAsOf{Env: product}
+		if product.Render != nil {
+			renderSet, err := commonrepo.NewRenderSetColl().FindAsOf(product.Render.Name, timestamp)
+			if err == nil {
+				env.RenderSet = renderSet
+			} else if !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, err
+			}
+		}
+		envs = append(envs, env)
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Env.EnvName < envs[j].Env.EnvName })
+	return envs, nil
+}
+
+// maskWorkflowCredentials clears the values of the credential variables of the build and the freestyle jobs.
+func maskWorkflowCredentials(workflow *commonmodels.WorkflowV4) {
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			switch job.JobType {
+			case config.JobZadigBuild:
+				spec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					continue
+				}
+				for _, build := range spec.ServiceAndBuilds {
+					maskCredentialKeyVals(build.KeyVals)
+				}
+				job.Spec = spec
+			case config.JobFreestyle:
+				spec := &commonmodels.FreestyleJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					continue
+				}
+				if spec.Properties != nil {
+					maskCredentialKeyVals(spec.Properties.Envs)
+				}
+				job.Spec = spec
+			}
+		}
+	}
+}
+
+func maskCredentialKeyVals(kvs []*commonmodels.KeyVal) {
+	for _, kv := range kvs {
+		if kv.IsCredential {
+			kv.Value = ""
+		}
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// UpdateProjectCustomFields replaces the values of the custom fields of the project, the required fields should be
+// given once the values are updated.
+func UpdateProjectCustomFields(projectName string, fields map[string]string, updateBy string, log *zap.SugaredLogger) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return e.ErrUpdateCustomFieldValues.AddErr(err)
+	}
+	if err := commonservice.ValidateCustomFields(config.CustomFieldScopeProject, fields, true); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateCustomFields(projectName, fields, updateBy); err != nil {
+		log.Errorf("failed to update custom fields of project %s, err: %s", projectName, err)
+		return e.ErrUpdateCustomFieldValues.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+var gerritVoteRegexp = regexp.MustCompile(`^[+-]?[0-9]$`)
+
+func GetGerritVotingPolicy(projectName string, log *zap.SugaredLogger) (*template.GerritVotingPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetGerritVotingPolicy.AddErr(err)
+	}
+	if project.GerritVotingPolicy == nil {
+		return &template.GerritVotingPolicy{Votes: []*template.GerritLabelVote{}}, nil
+	}
+	return project.GerritVotingPolicy, nil
+}
+
+func UpdateGerritVotingPolicy(projectName string, policy *template.GerritVotingPolicy, updateBy string, log *zap.SugaredLogger) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return e.ErrUpdateGerritVotingPolicy.AddErr(err)
+	}
+	if err := validateGerritVotingPolicy(policy); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateGerritVotingPolicy(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to update gerrit voting policy of project %s, err: %s", projectName, err)
+		return e.ErrUpdateGerritVotingPolicy.AddErr(err)
+	}
+	return nil
+}
+
+func validateGerritVotingPolicy(policy *template.GerritVotingPolicy) error {
+	if policy.Enabled && len(policy.Votes) == 0 {
+		return fmt.Errorf("at least one label should be voted")
+	}
+	labels := sets.NewString()
+	for _, vote := range policy.Votes {
+		vote.Label = strings.TrimSpace(vote.Label)
+		if vote.Label == "" {
+			return fmt.Errorf("empty label")
+		}
+		if labels.Has(vote.Label) {
+			return fmt.Errorf("label %s is voted more than once", vote.Label)
+		}
+		labels.Insert(vote.Label)
+		for _, value := range []string{vote.Passed, vote.Failed, vote.Timeout, vote.Cancelled} {
+			if value != "" && !gerritVoteRegexp.MatchString(value) {
+				return fmt.Errorf("invalid value %s of label %s, it should be like +1 or -2", value, vote.Label)
+			}
+		}
+	}
+	return nil
+}
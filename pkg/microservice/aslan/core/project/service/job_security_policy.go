@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// JobSecurityPolicyResp shows the profile chosen by the project and the one its jobs run with.
+type JobSecurityPolicyResp struct {
+	*template.JobSecurityPolicy
+	MinimumProfile   types.JobSecurityProfile `json:"minimum_profile"`
+	EffectiveProfile types.JobSecurityProfile `json:"effective_profile"`
+}
+
+func GetJobSecurityPolicy(projectName string, log *zap.SugaredLogger) (*JobSecurityPolicyResp, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetJobSecurityPolicy.AddErr(err)
+	}
+	minimum, err := getMinimumJobSecurityProfile()
+	if err != nil {
+		log.Errorf("failed to get the minimum job security profile, err: %s", err)
+		return nil, e.ErrGetJobSecurityPolicy.AddErr(err)
+	}
+
+	policy := project.JobSecurityPolicy
+	if policy == nil {
+		policy = &template.JobSecurityPolicy{Profile: types.JobSecurityBaseline}
+	}
+	return &JobSecurityPolicyResp{
+		JobSecurityPolicy: policy,
+		MinimumProfile:    minimum,
+		EffectiveProfile:  policy.Profile.Enforce(minimum),
+	}, nil
+}
+
+// UpdateJobSecurityPolicy refuses the profiles weaker than the minimum one of the system.
+func UpdateJobSecurityPolicy(projectName string, policy *template.JobSecurityPolicy, updateBy string, log *zap.SugaredLogger) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return e.ErrUpdateJobSecurityPolicy.AddErr(err)
+	}
+	if !policy.Profile.Valid() {
+		return e.ErrUpdateJobSecurityPolicy.AddDesc(fmt.Sprintf("invalid job security profile: %s", policy.Profile))
+	}
+	policy.Profile = policy.Profile.Normalize()
+	minimum, err := getMinimumJobSecurityProfile()
+	if err != nil {
+		log.Errorf("failed to get the minimum job security profile, err: %s", err)
+		return e.ErrUpdateJobSecurityPolicy.AddErr(err)
+	}
+	if policy.Profile.Weaker(minimum) {
+		return e.ErrUpdateJobSecurityPolicy.AddDesc(fmt.Sprintf("job security profile %s is weaker than the minimum profile %s", policy.Profile, minimum))
+	}
+
+	if err := templaterepo.NewProductColl().UpdateJobSecurityPolicy(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to update job security policy of project %s, err: %s", projectName, err)
+		return e.ErrUpdateJobSecurityPolicy.AddErr(err)
+	}
+	return nil
+}
+
+func getMinimumJobSecurityProfile() (types.JobSecurityProfile, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		return "", err
+	}
+	if sysSetting.JobSecurity == nil {
+		return types.JobSecurityPrivileged, nil
+	}
+	return sysSetting.JobSecurity.MinimumProfile, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/licensepolicy"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetLicensePolicy(projectName string, log *zap.SugaredLogger) (*template.LicensePolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetLicensePolicy.AddErr(err)
+	}
+	if project.LicensePolicy == nil {
+		return &template.LicensePolicy{}, nil
+	}
+	return project.LicensePolicy, nil
+}
+
+// UpdateLicensePolicy updates the allowed and denied licenses, the waivers are kept since they are managed
+// by AddLicenseWaiver and DeleteLicenseWaiver.
+func UpdateLicensePolicy(projectName string, policy *template.LicensePolicy, updateBy string, log *zap.SugaredLogger) error {
+	current, err := GetLicensePolicy(projectName, log)
+	if err != nil {
+		return e.ErrUpdateLicensePolicy.AddErr(err)
+	}
+	policy.Waivers = current.Waivers
+	if err := licensepolicy.Validate(policy); err != nil {
+		return e.ErrUpdateLicensePolicy.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateLicensePolicy(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to update license policy of project %s, err: %s", projectName, err)
+		return e.ErrUpdateLicensePolicy.AddErr(err)
+	}
+	return nil
+}
+
+func AddLicenseWaiver(projectName string, waiver *template.LicenseWaiver, createBy string, log *zap.SugaredLogger) (*template.LicenseWaiver, error) {
+	policy, err := GetLicensePolicy(projectName, log)
+	if err != nil {
+		return nil, e.ErrCreateLicenseWaiver.AddErr(err)
+	}
+	waiver.ID = primitive.NewObjectID().Hex()
+	waiver.CreatedBy = createBy
+	waiver.CreateTime = time.Now().Unix()
+	policy.Waivers = append(policy.Waivers, waiver)
+	if err := licensepolicy.Validate(policy); err != nil {
+		return nil, e.ErrCreateLicenseWaiver.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateLicensePolicy(projectName, policy, createBy); err != nil {
+		log.Errorf("failed to add license waiver to project %s, err: %s", projectName, err)
+		return nil, e.ErrCreateLicenseWaiver.AddErr(err)
+	}
+	return waiver, nil
+}
+
+func DeleteLicenseWaiver(projectName, id, updateBy string, log *zap.SugaredLogger) error {
+	policy, err := GetLicensePolicy(projectName, log)
+	if err != nil {
+		return e.ErrDeleteLicenseWaiver.AddErr(err)
+	}
+	waivers := make([]*template.LicenseWaiver, 0, len(policy.Waivers))
+	for _, waiver := range policy.Waivers {
+		if waiver.ID != id {
+			waivers = append(waivers, waiver)
+		}
+	}
+	if len(waivers) == len(policy.Waivers) {
+		return e.ErrDeleteLicenseWaiver.AddErr(fmt.Errorf("waiver %s is not found", id))
+	}
+	policy.Waivers = waivers
+
+	if err := templaterepo.NewProductColl().UpdateLicensePolicy(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to delete license waiver %s of project %s, err: %s", id, projectName, err)
+		return e.ErrDeleteLicenseWaiver.AddErr(err)
+	}
+	return nil
+}
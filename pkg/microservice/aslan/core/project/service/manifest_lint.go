@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/manifestlint"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetManifestLint(projectName string, log *zap.SugaredLogger) (*template.ManifestLintPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetManifestLint.AddErr(err)
+	}
+	if project.ManifestLint == nil {
+		return &template.ManifestLintPolicy{FailOn: manifestlint.SeverityError}, nil
+	}
+	return project.ManifestLint, nil
+}
+
+func UpdateManifestLint(projectName string, policy *template.ManifestLintPolicy, updateBy string, log *zap.SugaredLogger) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return e.ErrUpdateManifestLint.AddErr(err)
+	}
+	if err := manifestlint.Validate(policy); err != nil {
+		return e.ErrUpdateManifestLint.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateManifestLint(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to update manifest lint policy of project %s, err: %s", projectName, err)
+		return e.ErrUpdateManifestLint.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/manifestpolicy"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetManifestPolicy(projectName string, log *zap.SugaredLogger) (*template.ManifestPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetManifestPolicy.AddErr(err)
+	}
+	if project.ManifestPolicy == nil {
+		return &template.ManifestPolicy{}, nil
+	}
+	return project.ManifestPolicy, nil
+}
+
+func UpdateManifestPolicy(projectName string, policy *template.ManifestPolicy, updateBy string, log *zap.SugaredLogger) error {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return e.ErrUpdateManifestPolicy.AddErr(err)
+	}
+	if err := manifestpolicy.Validate(projectName, policy); err != nil {
+		return e.ErrUpdateManifestPolicy.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateManifestPolicy(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to update manifest policy of project %s, err: %s", projectName, err)
+		return e.ErrUpdateManifestPolicy.AddErr(err)
+	}
+
+	if project.ManifestPolicy != nil {
+		names := sets.NewString()
+		for _, p := range policy.Policies {
+			names.Insert(p.Name)
+		}
+		removed := []*template.RegoPolicy{}
+		for _, p := range project.ManifestPolicy.Policies {
+			if !names.Has(p.Name) {
+				removed = append(removed, p)
+			}
+		}
+		manifestpolicy.RemovePolicies(projectName, removed)
+	}
+	return nil
+}
@@ -0,0 +1,593 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	buildservice "github.com/koderover/zadig/pkg/microservice/aslan/core/build/service"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	svcservice "github.com/koderover/zadig/pkg/microservice/aslan/core/service/service"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/kube/serializer"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	// the repository is scanned breadth first and stops at the limits, services nested deeper
+	// can still be added manually after onboarding.
+	onboardingMaxDepth = 3
+	onboardingMaxDirs  = 50
+
+	onboardingBuildStage  = "build"
+	onboardingDeployStage = "deploy"
+	onboardingBuildJob    = "build"
+	onboardingDeployJob   = "deploy"
+)
+
+type RepoAnalysisArgs struct {
+	CodehostID  int    `json:"codehost_id"`
+	Owner       string `json:"owner"`
+	Namespace   string `json:"namespace"`
+	Repo        string `json:"repo"`
+	Branch      string `json:"branch"`
+	ProjectName string `json:"project_name"`
+	// EnvName adds a deploy stage to the proposed workflow if it is set.
+	EnvName string `json:"env_name"`
+}
+
+func (args *RepoAnalysisArgs) getNamespace() string {
+	if args.Namespace != "" {
+		return args.Namespace
+	}
+	return args.Owner
+}
+
+// RepoAnalysis is the proposal generated from a repository, it can be edited and sent back to
+// ApplyOnboarding to create the services, builds and workflow.
+type RepoAnalysis struct {
+	Languages  []string                 `json:"languages"`
+	DeployType string                   `json:"deploy_type"`
+	Services   []*ProposedService       `json:"services"`
+	Builds     []*ProposedBuild         `json:"builds"`
+	Workflow   *commonmodels.WorkflowV4 `json:"workflow"`
+}
+
+type ProposedService struct {
+	// Name of a k8s service is the name of its directory, the name of a helm service is the
+	// name in Chart.yaml.
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Path    string   `json:"path"`
+	Modules []string `json:"modules"`
+}
+
+type ProposedBuild struct {
+	Name          string `json:"name"`
+	ServiceName   string `json:"service_name"`
+	ServiceModule string `json:"service_module"`
+	Language      string `json:"language"`
+	BuildTool     string `json:"build_tool"`
+	Path          string `json:"path"`
+	Dockerfile    string `json:"dockerfile"`
+	Scripts       string `json:"scripts"`
+}
+
+type ApplyOnboardingArgs struct {
+	RepoAnalysisArgs
+	Services []*ProposedService       `json:"services"`
+	Builds   []*ProposedBuild         `json:"builds"`
+	Workflow *commonmodels.WorkflowV4 `json:"workflow"`
+}
+
+type ApplyOnboardingResp struct {
+	Services []string             `json:"services"`
+	Builds   []string             `json:"builds"`
+	Workflow string               `json:"workflow"`
+	Failed   []*OnboardingFailure `json:"failed"`
+}
+
+type OnboardingFailure struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+type buildTool struct {
+	language string
+	tool     string
+	scripts  string
+}
+
+// buildTools are detected by the marker files in the directory of a service.
+var buildTools = map[string]*buildTool{
+	"go.mod":           {language: "go", tool: "go", scripts: "go build ./..."},
+	"package.json":     {language: "node", tool: "npm", scripts: "npm install\nnpm run build --if-present"},
+	"pom.xml":          {language: "java", tool: "maven", scripts: "mvn -B clean package -DskipTests"},
+	"build.gradle":     {language: "java", tool: "gradle", scripts: "gradle build -x test"},
+	"build.gradle.kts": {language: "kotlin", tool: "gradle", scripts: "gradle build -x test"},
+	"requirements.txt": {language: "python", tool: "pip", scripts: "pip install -r requirements.txt"},
+	"pyproject.toml":   {language: "python", tool: "pip", scripts: "pip install ."},
+	"setup.py":         {language: "python", tool: "pip", scripts: "pip install ."},
+	"Cargo.toml":       {language: "rust", tool: "cargo", scripts: "cargo build --release"},
+	"composer.json":    {language: "php", tool: "composer", scripts: "composer install"},
+	"Gemfile":          {language: "ruby", tool: "bundler", scripts: "bundle install"},
+}
+
+var onboardingWorkloadKinds = sets.NewString(setting.Deployment, setting.StatefulSet, setting.DaemonSet)
+
+type repoDir struct {
+	path         string
+	depth        int
+	tool         *buildTool
+	dockerfiles  []string
+	yamlFiles    []string
+	hasChartYaml bool
+}
+
+// AnalyzeRepository scans a repository for languages, Dockerfiles, helm charts and k8s manifests,
+// and proposes the services, builds and a starter workflow of the project.
+func AnalyzeRepository(args *RepoAnalysisArgs, log *zap.SugaredLogger) (*RepoAnalysis, error) {
+	getter, err := fs.GetTreeGetter(args.CodehostID)
+	if err != nil {
+		log.Errorf("Failed to get tree getter, err: %s", err)
+		return nil, e.ErrAnalyzeRepository.AddDesc(err.Error())
+	}
+
+	dirs, err := scanRepository(getter, args)
+	if err != nil {
+		log.Errorf("Failed to scan repo %s/%s, err: %s", args.getNamespace(), args.Repo, err)
+		return nil, e.ErrAnalyzeRepository.AddDesc(err.Error())
+	}
+
+	resp := &RepoAnalysis{
+		Languages: make([]string, 0),
+		Services:  make([]*ProposedService, 0),
+		Builds:    make([]*ProposedBuild, 0),
+	}
+	languages := sets.NewString()
+	helmServices := make([]*ProposedService, 0)
+	k8sServices := make([]*ProposedService, 0)
+	for _, dir := range dirs {
+		if dir.tool != nil {
+			languages.Insert(dir.tool.language)
+		}
+		if dir.hasChartYaml {
+			svc, err := proposeHelmService(getter, args, dir)
+			if err != nil {
+				log.Warnf("Failed to read chart under %s, err: %s", dir.path, err)
+				continue
+			}
+			helmServices = append(helmServices, svc)
+			continue
+		}
+		if svc := proposeK8sService(getter, args, dir, log); svc != nil {
+			k8sServices = append(k8sServices, svc)
+		}
+	}
+	resp.Languages = languages.List()
+
+	// a project can only hold services of one deploy type, helm charts win since the k8s
+	// manifests found beside them are usually rendered examples.
+	resp.DeployType, resp.Services = setting.K8SDeployType, k8sServices
+	if len(helmServices) > 0 {
+		resp.DeployType, resp.Services = setting.HelmDeployType, helmServices
+	}
+
+	resp.Builds = proposeBuilds(args, dirs, resp.Services)
+	if args.ProjectName != "" && len(resp.Builds) > 0 {
+		resp.Workflow = proposeWorkflow(args, resp.Builds)
+	}
+	return resp, nil
+}
+
+func scanRepository(getter fs.TreeGetter, args *RepoAnalysisArgs) ([]*repoDir, error) {
+	dirs := make([]*repoDir, 0)
+	queue := []*repoDir{{path: "", depth: 0}}
+	for len(queue) > 0 && len(dirs) < onboardingMaxDirs {
+		dir := queue[0]
+		queue = queue[1:]
+
+		nodes, err := getter.GetTree(args.getNamespace(), args.Repo, dir.path, args.Branch)
+		if err != nil {
+			// the root must be readable, sub directories failed to read are skipped.
+			if dir.path == "" {
+				return nil, err
+			}
+			continue
+		}
+		children := make([]*repoDir, 0)
+		for _, node := range nodes {
+			if node.IsDir {
+				if strings.HasPrefix(node.Name, ".") || node.Name == "node_modules" || node.Name == "vendor" {
+					continue
+				}
+				children = append(children, &repoDir{path: node.FullPath, depth: dir.depth + 1})
+				continue
+			}
+			inspectFile(dir, node)
+		}
+		dirs = append(dirs, dir)
+
+		// the templates of a chart are not scanned since they can not be parsed without values.
+		if dir.hasChartYaml || dir.depth >= onboardingMaxDepth {
+			continue
+		}
+		queue = append(queue, children...)
+	}
+	return dirs, nil
+}
+
+func inspectFile(dir *repoDir, node *git.TreeNode) {
+	name := node.Name
+	switch {
+	case name == setting.ChartYaml:
+		dir.hasChartYaml = true
+	case name == "Dockerfile" || strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(name, ".Dockerfile"):
+		dir.dockerfiles = append(dir.dockerfiles, node.FullPath)
+	case strings.HasSuffix(name, ".csproj"):
+		if dir.tool == nil {
+			dir.tool = &buildTool{language: "dotnet", tool: "dotnet", scripts: "dotnet build"}
+		}
+	case strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"):
+		dir.yamlFiles = append(dir.yamlFiles, node.FullPath)
+	default:
+		if tool, ok := buildTools[name]; ok && dir.tool == nil {
+			dir.tool = tool
+		}
+	}
+}
+
+func proposeHelmService(getter fs.TreeGetter, args *RepoAnalysisArgs, dir *repoDir) (*ProposedService, error) {
+	content, err := getter.GetFileContent(args.getNamespace(), args.Repo, path.Join(dir.path, setting.ChartYaml), args.Branch)
+	if err != nil {
+		return nil, err
+	}
+	chart := &svcservice.Chart{}
+	if err := yaml.Unmarshal(content, chart); err != nil {
+		return nil, err
+	}
+	name := chart.Name
+	if name == "" {
+		name = path.Base(dir.path)
+	}
+	// the containers of a chart are resolved from the values when the service is created,
+	// the chart name is the common convention.
+	return &ProposedService{Name: name, Type: setting.HelmDeployType, Path: dir.path, Modules: []string{name}}, nil
+}
+
+// proposeK8sService loads the directory as a service the same way the service loader does,
+// the root directory is skipped since the service would be named after it.
+func proposeK8sService(getter fs.TreeGetter, args *RepoAnalysisArgs, dir *repoDir, log *zap.SugaredLogger) *ProposedService {
+	if dir.path == "" || len(dir.yamlFiles) == 0 {
+		return nil
+	}
+	modules := sets.NewString()
+	for _, file := range dir.yamlFiles {
+		yamls, err := getter.GetYAMLContents(args.getNamespace(), args.Repo, file, args.Branch, false, true)
+		if err != nil {
+			log.Warnf("Failed to get yamls of %s, err: %s", file, err)
+			continue
+		}
+		for _, content := range yamls {
+			modules.Insert(workloadContainers(content)...)
+		}
+	}
+	if modules.Len() == 0 {
+		return nil
+	}
+	return &ProposedService{Name: path.Base(dir.path), Type: setting.K8SDeployType, Path: dir.path, Modules: modules.List()}
+}
+
+func workloadContainers(content string) []string {
+	u, err := serializer.NewDecoder().YamlToUnstructured([]byte(content))
+	if err != nil || !onboardingWorkloadKinds.Has(u.GetKind()) {
+		return nil
+	}
+	containers, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+	resp := make([]string, 0, len(containers))
+	for _, container := range containers {
+		if c, ok := container.(map[string]interface{}); ok {
+			if name, ok := c["name"].(string); ok && name != "" {
+				resp = append(resp, name)
+			}
+		}
+	}
+	return resp
+}
+
+// proposeBuilds creates a build for every Dockerfile, the service module of a build is the
+// module named after the directory of the Dockerfile, or the only module in the repository.
+func proposeBuilds(args *RepoAnalysisArgs, dirs []*repoDir, services []*ProposedService) []*ProposedBuild {
+	moduleServices := make(map[string]string)
+	for _, svc := range services {
+		for _, module := range svc.Modules {
+			moduleServices[module] = svc.Name
+		}
+	}
+	toolOf := make(map[string]*buildTool)
+	for _, dir := range dirs {
+		if dir.tool != nil {
+			toolOf[dir.path] = dir.tool
+		}
+	}
+
+	builds := make([]*ProposedBuild, 0)
+	names := sets.NewString()
+	for _, dir := range dirs {
+		for _, dockerfile := range dir.dockerfiles {
+			build := &ProposedBuild{
+				Path:       dir.path,
+				Dockerfile: dockerfile,
+			}
+			if tool := nearestBuildTool(toolOf, dir.path); tool != nil {
+				build.Language, build.BuildTool = tool.language, tool.tool
+				build.Scripts = fmt.Sprintf("#!/bin/bash\nset -e\n\ncd $WORKSPACE/%s\n%s\n", path.Join(args.Repo, dir.path), tool.scripts)
+			}
+
+			module := path.Base(dir.path)
+			if dir.path == "" {
+				module = args.Repo
+			}
+			if _, ok := moduleServices[module]; !ok && len(moduleServices) == 1 {
+				for m := range moduleServices {
+					module = m
+				}
+			}
+			if svc, ok := moduleServices[module]; ok {
+				build.ServiceName, build.ServiceModule = svc, module
+			}
+
+			name := fmt.Sprintf("%s-build", module)
+			for i := 1; names.Has(name); i++ {
+				name = fmt.Sprintf("%s-build-%d", module, i)
+			}
+			names.Insert(name)
+			build.Name = name
+			builds = append(builds, build)
+		}
+	}
+	return builds
+}
+
+// nearestBuildTool returns the build tool of the directory or of its closest parent.
+func nearestBuildTool(toolOf map[string]*buildTool, dir string) *buildTool {
+	for {
+		if tool, ok := toolOf[dir]; ok {
+			return tool
+		}
+		if dir == "" {
+			return nil
+		}
+		dir = path.Dir(dir)
+		if dir == "." || dir == "/" {
+			dir = ""
+		}
+	}
+}
+
+func proposeWorkflow(args *RepoAnalysisArgs, builds []*ProposedBuild) *commonmodels.WorkflowV4 {
+	buildSpec := &commonmodels.ZadigBuildJobSpec{ServiceAndBuilds: make([]*commonmodels.ServiceAndBuild, 0)}
+	for _, build := range builds {
+		if build.ServiceName == "" {
+			continue
+		}
+		buildSpec.ServiceAndBuilds = append(buildSpec.ServiceAndBuilds, &commonmodels.ServiceAndBuild{
+			ServiceName:   build.ServiceName,
+			ServiceModule: build.ServiceModule,
+			BuildName:     build.Name,
+		})
+	}
+	if len(buildSpec.ServiceAndBuilds) == 0 {
+		return nil
+	}
+
+	name := strings.ToLower(args.ProjectName) + "-workflow"
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	workflow := &commonmodels.WorkflowV4{
+		Name:    strings.Trim(name, "-"),
+		Project: args.ProjectName,
+		Stages: []*commonmodels.WorkflowStage{{
+			Name:     onboardingBuildStage,
+			Parallel: true,
+			Jobs:     []*commonmodels.Job{{Name: onboardingBuildJob, JobType: config.JobZadigBuild, Spec: buildSpec}},
+		}},
+	}
+	if args.EnvName != "" {
+		workflow.Stages = append(workflow.Stages, &commonmodels.WorkflowStage{
+			Name: onboardingDeployStage,
+			Jobs: []*commonmodels.Job{{
+				Name:    onboardingDeployJob,
+				JobType: config.JobZadigDeploy,
+				Spec: &commonmodels.ZadigDeployJobSpec{
+					Env:     args.EnvName,
+					Source:  config.SourceFromJob,
+					JobName: onboardingBuildJob,
+				},
+			}},
+		})
+	}
+	return workflow
+}
+
+// ApplyOnboarding creates the confirmed proposal in the project. Creation is best effort, the
+// failed items are reported and the workflow is skipped if any of its services or builds failed.
+func ApplyOnboarding(args *ApplyOnboardingArgs, username string, log *zap.SugaredLogger) (*ApplyOnboardingResp, error) {
+	project, err := templaterepo.NewProductColl().Find(args.ProjectName)
+	if err != nil {
+		log.Errorf("Failed to find project %s, err: %s", args.ProjectName, err)
+		return nil, e.ErrApplyOnboarding.AddDesc(fmt.Sprintf("project %s not found", args.ProjectName))
+	}
+	deployType := setting.K8SDeployType
+	if project.ProductFeature != nil && project.ProductFeature.DeployType == setting.HelmDeployType {
+		deployType = setting.HelmDeployType
+	}
+	for _, svc := range args.Services {
+		if svc.Type != deployType {
+			return nil, e.ErrApplyOnboarding.AddDesc(fmt.Sprintf("service %s of type %s can not be added to a %s project", svc.Name, svc.Type, deployType))
+		}
+	}
+	ch, err := systemconfig.New().GetCodeHost(args.CodehostID)
+	if err != nil {
+		log.Errorf("Failed to get codehost %d, err: %s", args.CodehostID, err)
+		return nil, e.ErrApplyOnboarding.AddErr(err)
+	}
+
+	resp := &ApplyOnboardingResp{
+		Services: make([]string, 0),
+		Builds:   make([]string, 0),
+		Failed:   make([]*OnboardingFailure, 0),
+	}
+	if deployType == setting.HelmDeployType {
+		applyHelmServices(args, username, resp, log)
+	} else {
+		applyK8sServices(args, username, resp, log)
+	}
+	applyBuilds(args, ch, username, resp, log)
+
+	if args.Workflow == nil {
+		return resp, nil
+	}
+	if len(resp.Failed) > 0 {
+		resp.Failed = append(resp.Failed, &OnboardingFailure{Kind: "workflow", Name: args.Workflow.Name, Error: "skipped since some services or builds failed to create"})
+		return resp, nil
+	}
+	args.Workflow.Project = args.ProjectName
+	if err := workflowservice.CreateWorkflowV4(username, args.Workflow, log); err != nil {
+		resp.Failed = append(resp.Failed, &OnboardingFailure{Kind: "workflow", Name: args.Workflow.Name, Error: err.Error()})
+		return resp, nil
+	}
+	resp.Workflow = args.Workflow.Name
+	return resp, nil
+}
+
+func applyK8sServices(args *ApplyOnboardingArgs, username string, resp *ApplyOnboardingResp, log *zap.SugaredLogger) {
+	for _, svc := range args.Services {
+		err := svcservice.LoadServiceFromCodeHost(username, args.CodehostID, args.Owner, args.getNamespace(), args.Repo, "", args.Branch, "", &svcservice.LoadServiceReq{
+			Type:        setting.K8SDeployType,
+			ProductName: args.ProjectName,
+			Visibility:  setting.PrivateVisibility,
+			LoadFromDir: true,
+			LoadPath:    svc.Path,
+		}, false, log)
+		if err != nil {
+			resp.Failed = append(resp.Failed, &OnboardingFailure{Kind: "service", Name: svc.Name, Error: err.Error()})
+			continue
+		}
+		resp.Services = append(resp.Services, path.Base(svc.Path))
+	}
+}
+
+func applyHelmServices(args *ApplyOnboardingArgs, username string, resp *ApplyOnboardingResp, log *zap.SugaredLogger) {
+	if len(args.Services) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(args.Services))
+	for _, svc := range args.Services {
+		paths = append(paths, svc.Path)
+	}
+	result, err := svcservice.CreateOrUpdateHelmService(args.ProjectName, &svcservice.HelmServiceCreationArgs{
+		HelmLoadSource: svcservice.HelmLoadSource{Source: svcservice.LoadFromRepo},
+		CreatedBy:      username,
+		CreateFrom: &svcservice.CreateFromRepo{
+			CodehostID: args.CodehostID,
+			Owner:      args.Owner,
+			Namespace:  args.Namespace,
+			Repo:       args.Repo,
+			Branch:     args.Branch,
+			Paths:      paths,
+		},
+	}, false, log)
+	if err != nil {
+		for _, svc := range args.Services {
+			resp.Failed = append(resp.Failed, &OnboardingFailure{Kind: "service", Name: svc.Name, Error: err.Error()})
+		}
+		return
+	}
+	sort.Strings(result.SuccessServices)
+	resp.Services = append(resp.Services, result.SuccessServices...)
+	for _, failed := range result.FailedServices {
+		resp.Failed = append(resp.Failed, &OnboardingFailure{Kind: "service", Name: failed.Path, Error: failed.Error})
+	}
+}
+
+func applyBuilds(args *ApplyOnboardingArgs, ch *systemconfig.CodeHost, username string, resp *ApplyOnboardingResp, log *zap.SugaredLogger) {
+	var imageID string
+	if images, err := commonrepo.NewBasicImageColl().List(&commonrepo.BasicImageOpt{Value: setting.UbuntuBionic}); err == nil && len(images) > 0 {
+		imageID = images[0].ID.Hex()
+	}
+
+	for _, proposed := range args.Builds {
+		build := &commonmodels.Build{
+			Name:        proposed.Name,
+			ProductName: args.ProjectName,
+			Timeout:     60,
+			Repos: []*types.Repository{{
+				Source:        ch.Type,
+				RepoOwner:     args.Owner,
+				RepoNamespace: args.getNamespace(),
+				RepoName:      args.Repo,
+				Branch:        args.Branch,
+				CodehostID:    args.CodehostID,
+			}},
+			PreBuild: &commonmodels.PreBuild{
+				ResReq:    setting.DefaultRequest,
+				BuildOS:   setting.UbuntuBionic,
+				ImageFrom: "koderover",
+				ImageID:   imageID,
+			},
+			Scripts: proposed.Scripts,
+		}
+		if proposed.ServiceName != "" {
+			build.Targets = []*commonmodels.ServiceModuleTarget{{
+				ProductName:   args.ProjectName,
+				ServiceName:   proposed.ServiceName,
+				ServiceModule: proposed.ServiceModule,
+			}}
+		}
+		if proposed.Dockerfile != "" {
+			build.PostBuild = &commonmodels.PostBuild{DockerBuild: &commonmodels.DockerBuild{
+				WorkDir:    path.Join(args.Repo, proposed.Path),
+				DockerFile: path.Join(args.Repo, proposed.Dockerfile),
+				Source:     setting.DockerfileSourceLocal,
+			}}
+		}
+		if err := buildservice.CreateBuild(username, build, log); err != nil {
+			resp.Failed = append(resp.Failed, &OnboardingFailure{Kind: "build", Name: proposed.Name, Error: err.Error()})
+			continue
+		}
+		resp.Builds = append(resp.Builds, proposed.Name)
+	}
+}
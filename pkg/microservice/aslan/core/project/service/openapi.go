@@ -101,6 +101,7 @@ func CreateProjectOpenAPI(userID, username string, args *OpenAPICreateProductReq
 		ClusterIDs:     clusterList,
 		ProductFeature: feature,
 		Public:         args.IsPublic,
+		CustomFields:   args.CustomFields,
 	}
 
 	return CreateProductTemplate(createArgs, logger)
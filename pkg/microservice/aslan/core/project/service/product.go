@@ -120,6 +120,9 @@ func CreateProductTemplate(args *template.Product, log *zap.SugaredLogger) (err
 	if err := ensureProductTmpl(args); err != nil {
 		return e.ErrCreateProduct.AddDesc(err.Error())
 	}
+	if err := commonservice.ValidateCustomFields(config.CustomFieldScopeProject, args.CustomFields, true); err != nil {
+		return e.ErrCreateProduct.AddDesc(err.Error())
+	}
 
 	err = commonrepo.NewProjectClusterRelationColl().Delete(&commonrepo.ProjectClusterRelationOption{ProjectName: args.ProductName})
 	if err != nil {
@@ -190,6 +193,9 @@ func UpdateServiceOrchestration(name string, services [][]string, updateBy strin
 	if validServices.Len() > 0 {
 		return fmt.Errorf("service: [%s] not found in params", strings.Join(validServices.List(), ","))
 	}
+	if err = validateOrchestrationDependencies(templateProductInfo.ServiceDependencies, services); err != nil {
+		return err
+	}
 
 	if err = templaterepo.NewProductColl().UpdateServiceOrchestration(name, services, updateBy); err != nil {
 		log.Errorf("UpdateChoreographyService error: %v", err)
@@ -433,6 +439,10 @@ func DeleteProductTemplate(userName, productName, requestID string, isDelete boo
 		log.Errorf("DeleteProductTemplate Delete productName %s ProjectClusterRelation err: %s", productName, err)
 	}
 
+	if err = commonrepo.NewTenantColl().RemoveProject(productName); err != nil {
+		log.Errorf("DeleteProductTemplate Delete productName %s from tenant err: %s", productName, err)
+	}
+
 	// Delete freestyle workflow
 	cl := configclient.New(configbase.AslanServiceAddress())
 	if enable, err := cl.CheckFeature(setting.ModernWorkflowType); err == nil && enable {
@@ -463,9 +473,13 @@ func DeleteProductTemplate(userName, productName, requestID string, isDelete boo
 	//删除workflow和历史task
 	go func() {
 		_ = commonrepo.NewBuildColl().Delete("", productName)
+		_ = commonrepo.NewVariableGroupColl().DeleteByProject(productName)
+		_ = commonrepo.NewVariableGroupHistoryColl().DeleteByProject(productName)
 		_ = commonrepo.NewServiceColl().Delete("", "", productName, "", 0)
 		_ = commonservice.DeleteDeliveryInfos(productName, log)
 		_ = DeleteProductsAsync(userName, productName, requestID, isDelete, log)
+		_ = commonrepo.NewWorkflowV4VersionColl().DeleteByProject(productName)
+		_ = commonrepo.NewEnvVersionColl().DeleteByProject(productName)
 
 		// delete service webhooks after services are deleted
 		for _, s := range services {
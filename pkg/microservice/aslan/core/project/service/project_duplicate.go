@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const defaultProjectDuplicateName = "{{.Name}}-{{.Project}}"
+
+type DuplicateProjectArgs struct {
+	// ProjectName is the key of the new project.
+	ProjectName string `json:"project_name"`
+	// ProjectTitle is the display name of the new project, the key is used if empty.
+	ProjectTitle string `json:"project_title"`
+	// NameTemplate renders the names of the builds and workflows since they are unique in the
+	// installation, {{.Name}}-{{.Project}} is used if empty.
+	NameTemplate string `json:"name_template"`
+	DryRun       bool   `json:"dry_run"`
+}
+
+// DuplicateProject copies the services, builds, workflows and the environment template of a
+// project to a new one, the build references of the services and workflows are rewritten to the
+// renamed builds. The environments are not copied.
+func DuplicateProject(projectName string, args *DuplicateProjectArgs, userName string, log *zap.SugaredLogger) (*ImportProjectResp, error) {
+	if args.ProjectName == "" || args.ProjectName == projectName {
+		return nil, e.ErrDuplicateProject.AddDesc("a new project key is required")
+	}
+	bundle, secrets, err := exportProject(projectName, log)
+	if err != nil {
+		return nil, err
+	}
+
+	nameArgs := &commonservice.DuplicateNameArgs{Project: args.ProjectName, SourceProject: projectName}
+	rename := func(objs []BundleObject) (map[string]string, error) {
+		renames := make(map[string]string, len(objs))
+		for _, obj := range objs {
+			name, _ := obj["name"].(string)
+			nameArgs.Name = name
+			newName, err := commonservice.RenderDuplicateName(args.NameTemplate, defaultProjectDuplicateName, nameArgs)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := renames[name]; ok {
+				return nil, fmt.Errorf("duplicated name %s", name)
+			}
+			renames[name] = newName
+			obj["name"] = newName
+		}
+		return renames, nil
+	}
+	buildRenames, err := rename(bundle.Builds)
+	if err != nil {
+		return nil, e.ErrDuplicateProject.AddErr(err)
+	}
+	if _, err := rename(bundle.Workflows); err != nil {
+		return nil, e.ErrDuplicateProject.AddErr(err)
+	}
+	if _, err := rename(bundle.CustomWorkflows); err != nil {
+		return nil, e.ErrDuplicateProject.AddErr(err)
+	}
+	for _, objs := range [][]BundleObject{bundle.Services, bundle.Builds, bundle.Workflows, bundle.CustomWorkflows} {
+		for _, obj := range objs {
+			renameBundleValues(map[string]interface{}(obj), "build_name", buildRenames)
+		}
+	}
+	if args.ProjectTitle != "" {
+		bundle.Project["project_name"] = args.ProjectTitle
+	}
+
+	// the project is duplicated in the same installation, the integrations are kept.
+	references := make(map[string]string, len(bundle.References))
+	for _, ref := range bundle.References {
+		references[ref.Key()] = ref.ID
+	}
+	resp, err := ImportProject(&ImportProjectArgs{
+		Bundle:      bundle,
+		ProjectName: args.ProjectName,
+		Conflict:    BundleConflictFail,
+		References:  references,
+		Secrets:     secrets,
+		DryRun:      args.DryRun,
+	}, userName, log)
+	if err != nil {
+		return resp, err
+	}
+	resp.Warnings = append(resp.Warnings, fmt.Sprintf("the environments of project %s are not duplicated", projectName))
+	return resp, nil
+}
+
+// renameBundleValues replaces the string values of the key in the bundle object.
+func renameBundleValues(value interface{}, key string, renames map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if s, ok := item.(string); ok && k == key {
+				if renamed, ok := renames[s]; ok {
+					v[k] = renamed
+				}
+				continue
+			}
+			renameBundleValues(item, key, renames)
+		}
+	case []interface{}:
+		for _, item := range v {
+			renameBundleValues(item, key, renames)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+)
+
+func GetSecretScanPolicy(projectName string, log *zap.SugaredLogger) (*template.SecretScanPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetSecretScanPolicy.AddErr(err)
+	}
+	if project.SecretScanPolicy == nil {
+		return &template.SecretScanPolicy{Rules: &secretscan.Config{}}, nil
+	}
+	return project.SecretScanPolicy, nil
+}
+
+func UpdateSecretScanPolicy(projectName string, policy *template.SecretScanPolicy, updateBy string, log *zap.SugaredLogger) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return e.ErrUpdateSecretScanPolicy.AddErr(err)
+	}
+	// the rules are compiled to make sure the jobs can use them
+	if _, err := secretscan.New(policy.Rules); err != nil {
+		return e.ErrUpdateSecretScanPolicy.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateSecretScanPolicy(projectName, policy, updateBy); err != nil {
+		log.Errorf("failed to update secret scan policy of project %s, err: %s", projectName, err)
+		return e.ErrUpdateSecretScanPolicy.AddErr(err)
+	}
+	return nil
+}
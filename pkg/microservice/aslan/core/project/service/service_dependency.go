@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type ServiceDependencyGraph struct {
+	Nodes []*ServiceDependencyNode `json:"nodes"`
+	// Edges point from a service to the service depending on it, which is the deploy order.
+	Edges []*ServiceDependencyEdge `json:"edges"`
+	// Layers are the deploy order of the services, they are also the service orchestration of the project.
+	Layers [][]string `json:"layers"`
+}
+
+type ServiceDependencyNode struct {
+	Name    string                          `json:"name"`
+	Layer   int                             `json:"layer"`
+	WaitFor *template.ServiceReadyCondition `json:"wait_for"`
+}
+
+type ServiceDependencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func GetServiceDependencyGraph(projectName string, log *zap.SugaredLogger) (*ServiceDependencyGraph, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrGetServiceDependency.AddErr(err)
+	}
+
+	var services []string
+	for _, group := range project.Services {
+		services = append(services, group...)
+	}
+	layers, err := commonservice.SortServicesByDependency(project.ServiceDependencies, services)
+	if err != nil {
+		return nil, e.ErrGetServiceDependency.AddErr(err)
+	}
+
+	resp := &ServiceDependencyGraph{
+		Nodes:  make([]*ServiceDependencyNode, 0, len(services)),
+		Edges:  make([]*ServiceDependencyEdge, 0),
+		Layers: layers,
+	}
+	validServices := sets.NewString(services...)
+	for i, layer := range layers {
+		for _, service := range layer {
+			resp.Nodes = append(resp.Nodes, &ServiceDependencyNode{
+				Name:    service,
+				Layer:   i,
+				WaitFor: commonservice.GetServiceReadyCondition(project.ServiceDependencies, service),
+			})
+		}
+	}
+	for _, dependency := range project.ServiceDependencies {
+		for _, dependsOn := range dependency.DependsOn {
+			// the dependencies of deleted services are ignored.
+			if !validServices.Has(dependency.ServiceName) || !validServices.Has(dependsOn) {
+				continue
+			}
+			resp.Edges = append(resp.Edges, &ServiceDependencyEdge{From: dependsOn, To: dependency.ServiceName})
+		}
+	}
+	return resp, nil
+}
+
+// UpdateServiceDependencies replaces the service dependencies of the project, the service orchestration
+// is regenerated from the dependencies so that the environments are deployed in the dependency order.
+func UpdateServiceDependencies(projectName string, dependencies []*template.ServiceDependency, updateBy string, log *zap.SugaredLogger) (*ServiceDependencyGraph, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", projectName, err)
+		return nil, e.ErrUpdateServiceDependency.AddErr(err)
+	}
+
+	var services []string
+	for _, group := range project.Services {
+		services = append(services, group...)
+	}
+	if err := validateServiceDependencies(dependencies, sets.NewString(services...)); err != nil {
+		return nil, e.ErrUpdateServiceDependency.AddErr(err)
+	}
+	layers, err := commonservice.SortServicesByDependency(dependencies, services)
+	if err != nil {
+		return nil, e.ErrUpdateServiceDependency.AddErr(err)
+	}
+
+	if err := templaterepo.NewProductColl().UpdateServiceDependencies(projectName, dependencies, layers, updateBy); err != nil {
+		log.Errorf("failed to update service dependencies of project %s, err: %s", projectName, err)
+		return nil, e.ErrUpdateServiceDependency.AddErr(err)
+	}
+	return GetServiceDependencyGraph(projectName, log)
+}
+
+func validateServiceDependencies(dependencies []*template.ServiceDependency, validServices sets.String) error {
+	declared := sets.NewString()
+	for _, dependency := range dependencies {
+		if !validServices.Has(dependency.ServiceName) {
+			return fmt.Errorf("service %s not found in project", dependency.ServiceName)
+		}
+		if declared.Has(dependency.ServiceName) {
+			return fmt.Errorf("duplicated dependencies of service %s", dependency.ServiceName)
+		}
+		declared.Insert(dependency.ServiceName)
+		for _, dependsOn := range dependency.DependsOn {
+			if dependsOn == dependency.ServiceName {
+				return fmt.Errorf("service %s can not depend on itself", dependsOn)
+			}
+			if !validServices.Has(dependsOn) {
+				return fmt.Errorf("service %s not found in project", dependsOn)
+			}
+		}
+		if dependency.WaitFor == nil {
+			continue
+		}
+		if dependency.WaitFor.Type != config.ServiceReadyConditionReady && dependency.WaitFor.Type != config.ServiceReadyConditionNone {
+			return fmt.Errorf("invalid wait condition %s of service %s", dependency.WaitFor.Type, dependency.ServiceName)
+		}
+		if dependency.WaitFor.Timeout < 0 {
+			return fmt.Errorf("invalid wait timeout of service %s", dependency.ServiceName)
+		}
+	}
+	return nil
+}
+
+// validateOrchestrationDependencies checks that every service is in a group after the services it depends on.
+func validateOrchestrationDependencies(dependencies []*template.ServiceDependency, services [][]string) error {
+	groupIndex := make(map[string]int)
+	for i, group := range services {
+		for _, service := range group {
+			groupIndex[service] = i
+		}
+	}
+	for _, dependency := range dependencies {
+		index, ok := groupIndex[dependency.ServiceName]
+		if !ok {
+			continue
+		}
+		for _, dependsOn := range dependency.DependsOn {
+			if dependsOnIndex, ok := groupIndex[dependsOn]; ok && dependsOnIndex >= index {
+				return fmt.Errorf("service %s depends on %s, it must be in a later group", dependency.ServiceName, dependsOn)
+			}
+		}
+	}
+	return nil
+}
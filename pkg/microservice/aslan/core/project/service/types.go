@@ -30,6 +30,8 @@ type OpenAPICreateProductReq struct {
 	IsPublic    bool               `json:"is_public"`
 	Description string             `json:"description"`
 	ProjectType config.ProjectType `json:"project_type"`
+	// CustomFields are the values of the custom fields of the projects, the required ones should be given.
+	CustomFields map[string]string `json:"custom_fields"`
 }
 
 func (req OpenAPICreateProductReq) Validate() error {
@@ -0,0 +1,302 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+var (
+	variableGroupNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	variableKeyRegex       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+type VariableGroupArgs struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Variables   []*commonmodels.KeyVal `json:"variables"`
+	// Revision is the revision the update is based on, the update is refused if the group is changed since then.
+	Revision int64 `json:"revision"`
+}
+
+type VariableGroupReferences struct {
+	Builds    []*VariableGroupBuildReference    `json:"builds"`
+	Workflows []*VariableGroupWorkflowReference `json:"workflows"`
+}
+
+type VariableGroupBuildReference struct {
+	BuildName string   `json:"build_name"`
+	Services  []string `json:"services"`
+}
+
+type VariableGroupWorkflowReference struct {
+	WorkflowName string `json:"workflow_name"`
+	JobName      string `json:"job_name"`
+}
+
+func ListVariableGroups(projectName string, log *zap.SugaredLogger) ([]*commonmodels.VariableGroup, error) {
+	groups, err := commonrepo.NewVariableGroupColl().List(projectName)
+	if err != nil {
+		log.Errorf("failed to list variable groups of project %s, err: %s", projectName, err)
+		return nil, e.ErrListVariableGroup.AddErr(err)
+	}
+	for _, group := range groups {
+		maskVariableGroup(group)
+	}
+	return groups, nil
+}
+
+func GetVariableGroup(projectName, name string, log *zap.SugaredLogger) (*commonmodels.VariableGroup, error) {
+	group, err := commonrepo.NewVariableGroupColl().Find(projectName, name)
+	if err != nil {
+		log.Errorf("failed to find variable group %s of project %s, err: %s", name, projectName, err)
+		return nil, e.ErrGetVariableGroup.AddErr(err)
+	}
+	maskVariableGroup(group)
+	return group, nil
+}
+
+func CreateVariableGroup(projectName string, args *VariableGroupArgs, createBy string, log *zap.SugaredLogger) error {
+	if !variableGroupNameRegex.MatchString(args.Name) {
+		return e.ErrCreateVariableGroup.AddDesc("name should consist of lower case letters, digits and '-'")
+	}
+	if err := validateVariables(args.Variables); err != nil {
+		return e.ErrCreateVariableGroup.AddErr(err)
+	}
+	if _, err := commonrepo.NewVariableGroupColl().Find(projectName, args.Name); err == nil {
+		return e.ErrCreateVariableGroup.AddDesc(fmt.Sprintf("variable group %s already exists", args.Name))
+	}
+
+	group := &commonmodels.VariableGroup{
+		ProjectName: projectName,
+		Name:        args.Name,
+		Description: args.Description,
+		Variables:   args.Variables,
+		Revision:    1,
+		CreateBy:    createBy,
+		UpdateBy:    createBy,
+	}
+	if err := commonrepo.NewVariableGroupColl().Create(group); err != nil {
+		log.Errorf("failed to create variable group %s of project %s, err: %s", args.Name, projectName, err)
+		return e.ErrCreateVariableGroup.AddErr(err)
+	}
+
+	recordVariableGroupHistory(group, diffVariables(nil, group.Variables), log)
+	return nil
+}
+
+// UpdateVariableGroup replaces the variables of the group, the masked values of the credentials are kept.
+func UpdateVariableGroup(projectName, name string, args *VariableGroupArgs, updateBy string, log *zap.SugaredLogger) error {
+	group, err := commonrepo.NewVariableGroupColl().Find(projectName, name)
+	if err != nil {
+		log.Errorf("failed to find variable group %s of project %s, err: %s", name, projectName, err)
+		return e.ErrUpdateVariableGroup.AddErr(err)
+	}
+	if args.Revision != 0 && args.Revision != group.Revision {
+		return e.ErrUpdateVariableGroup.AddDesc(fmt.Sprintf("variable group %s has been updated to revision %d, please refresh and retry", name, group.Revision))
+	}
+
+	existed := make(map[string]*commonmodels.KeyVal, len(group.Variables))
+	for _, kv := range group.Variables {
+		existed[kv.Key] = kv
+	}
+	for _, kv := range args.Variables {
+		if kv.Value != setting.MaskValue {
+			continue
+		}
+		old, ok := existed[kv.Key]
+		if !ok || !old.IsCredential {
+			return e.ErrUpdateVariableGroup.AddDesc(fmt.Sprintf("value of variable %s is required", kv.Key))
+		}
+		kv.Value = old.Value
+	}
+	if err := validateVariables(args.Variables); err != nil {
+		return e.ErrUpdateVariableGroup.AddErr(err)
+	}
+
+	changes := diffVariables(group.Variables, args.Variables)
+	group.Description = args.Description
+	group.Variables = args.Variables
+	group.UpdateBy = updateBy
+	if err := commonrepo.NewVariableGroupColl().Update(group); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return e.ErrUpdateVariableGroup.AddDesc(fmt.Sprintf("variable group %s has been updated concurrently, please refresh and retry", name))
+		}
+		log.Errorf("failed to update variable group %s of project %s, err: %s", name, projectName, err)
+		return e.ErrUpdateVariableGroup.AddErr(err)
+	}
+
+	if len(changes) > 0 {
+		recordVariableGroupHistory(group, changes, log)
+	}
+	return nil
+}
+
+// DeleteVariableGroup refuses to delete the group if it is still referenced by any build or workflow.
+func DeleteVariableGroup(projectName, name string, log *zap.SugaredLogger) error {
+	refs, err := ListVariableGroupReferences(projectName, name, log)
+	if err != nil {
+		return e.ErrDeleteVariableGroup.AddErr(err)
+	}
+	if len(refs.Builds) > 0 || len(refs.Workflows) > 0 {
+		return e.ErrDeleteVariableGroup.AddDesc(fmt.Sprintf("variable group %s is referenced by %d builds and %d workflow jobs", name, len(refs.Builds), len(refs.Workflows)))
+	}
+
+	if err := commonrepo.NewVariableGroupColl().Delete(projectName, name); err != nil {
+		log.Errorf("failed to delete variable group %s of project %s, err: %s", name, projectName, err)
+		return e.ErrDeleteVariableGroup.AddErr(err)
+	}
+	if err := commonrepo.NewVariableGroupHistoryColl().Delete(projectName, name); err != nil {
+		log.Warnf("failed to delete history of variable group %s of project %s, err: %s", name, projectName, err)
+	}
+	return nil
+}
+
+func ListVariableGroupHistory(projectName, name string, log *zap.SugaredLogger) ([]*commonmodels.VariableGroupHistory, error) {
+	history, err := commonrepo.NewVariableGroupHistoryColl().List(projectName, name)
+	if err != nil {
+		log.Errorf("failed to list history of variable group %s of project %s, err: %s", name, projectName, err)
+		return nil, e.ErrGetVariableGroup.AddErr(err)
+	}
+	return history, nil
+}
+
+// ListVariableGroupReferences returns the builds and the freestyle jobs of the custom workflows which reference
+// the group.
+func ListVariableGroupReferences(projectName, name string, log *zap.SugaredLogger) (*VariableGroupReferences, error) {
+	resp := &VariableGroupReferences{
+		Builds:    make([]*VariableGroupBuildReference, 0),
+		Workflows: make([]*VariableGroupWorkflowReference, 0),
+	}
+
+	builds, err := commonrepo.NewBuildColl().List(&commonrepo.BuildListOption{ProductName: projectName, VariableGroup: name})
+	if err != nil {
+		log.Errorf("failed to list builds of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetVariableGroup.AddErr(err)
+	}
+	for _, build := range builds {
+		ref := &VariableGroupBuildReference{BuildName: build.Name, Services: make([]string, 0)}
+		for _, target := range build.Targets {
+			ref.Services = append(ref.Services, fmt.Sprintf("%s/%s", target.ServiceName, target.ServiceModule))
+		}
+		resp.Builds = append(resp.Builds, ref)
+	}
+
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		log.Errorf("failed to list workflows of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetVariableGroup.AddErr(err)
+	}
+	for _, workflow := range workflows {
+		for _, stage := range workflow.Stages {
+			for _, job := range stage.Jobs {
+				if job.JobType != config.JobFreestyle {
+					continue
+				}
+				spec := &commonmodels.FreestyleJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil || spec.Properties == nil {
+					continue
+				}
+				for _, groupName := range spec.Properties.VariableGroups {
+					if groupName == name {
+						resp.Workflows = append(resp.Workflows, &VariableGroupWorkflowReference{
+							WorkflowName: workflow.Name,
+							JobName:      job.Name,
+						})
+						break
+					}
+				}
+			}
+		}
+	}
+	return resp, nil
+}
+
+func validateVariables(variables []*commonmodels.KeyVal) error {
+	keys := make(map[string]bool, len(variables))
+	for _, kv := range variables {
+		if !variableKeyRegex.MatchString(kv.Key) {
+			return fmt.Errorf("invalid variable key %q", kv.Key)
+		}
+		if keys[kv.Key] {
+			return fmt.Errorf("duplicated variable key %s", kv.Key)
+		}
+		keys[kv.Key] = true
+	}
+	return nil
+}
+
+// diffVariables returns the keys added, updated or removed, a key is updated if its value or credential flag
+// is changed.
+func diffVariables(oldVars, newVars []*commonmodels.KeyVal) []*commonmodels.VariableGroupChange {
+	oldMap := make(map[string]*commonmodels.KeyVal, len(oldVars))
+	for _, kv := range oldVars {
+		oldMap[kv.Key] = kv
+	}
+
+	changes := make([]*commonmodels.VariableGroupChange, 0)
+	newKeys := make(map[string]bool, len(newVars))
+	for _, kv := range newVars {
+		newKeys[kv.Key] = true
+		old, ok := oldMap[kv.Key]
+		switch {
+		case !ok:
+			changes = append(changes, &commonmodels.VariableGroupChange{Key: kv.Key, Action: config.VariableGroupChangeAdded})
+		case old.Value != kv.Value || old.IsCredential != kv.IsCredential:
+			changes = append(changes, &commonmodels.VariableGroupChange{Key: kv.Key, Action: config.VariableGroupChangeUpdated})
+		}
+	}
+	for _, kv := range oldVars {
+		if !newKeys[kv.Key] {
+			changes = append(changes, &commonmodels.VariableGroupChange{Key: kv.Key, Action: config.VariableGroupChangeRemoved})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func recordVariableGroupHistory(group *commonmodels.VariableGroup, changes []*commonmodels.VariableGroupChange, log *zap.SugaredLogger) {
+	err := commonrepo.NewVariableGroupHistoryColl().Create(&commonmodels.VariableGroupHistory{
+		ProjectName: group.ProjectName,
+		GroupName:   group.Name,
+		Revision:    group.Revision,
+		Changes:     changes,
+		UpdateBy:    group.UpdateBy,
+	})
+	if err != nil {
+		log.Warnf("failed to record history of variable group %s of project %s, err: %s", group.Name, group.ProjectName, err)
+	}
+}
+
+func maskVariableGroup(group *commonmodels.VariableGroup) {
+	for _, kv := range group.Variables {
+		if kv.IsCredential {
+			kv.Value = setting.MaskValue
+		}
+	}
+}
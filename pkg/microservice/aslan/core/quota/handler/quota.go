@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/quota/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func ListProjectQuotas(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListProjectQuotas(ctx.Logger)
+}
+
+func GetProjectQuota(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.GetProjectQuota(c.Param("name"), ctx.Logger)
+}
+
+// the changes of the quotas are recorded in the operation logs as the audit trail of the chargeback.
+func UpdateProjectQuota(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.ProjectQuota)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("UpdateProjectQuota c.GetRawData() err : %s", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("UpdateProjectQuota json.Unmarshal err : %s", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Param("name"), "更新", "系统配置-项目配额", fmt.Sprintf("project:%s", c.Param("name")), string(data), ctx.Logger)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid project quota args")
+		return
+	}
+	args.UpdateBy = ctx.UserName
+
+	ctx.Err = service.UpdateProjectQuota(c.Param("name"), args, ctx.Logger)
+}
+
+func DeleteProjectQuota(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Param("name"), "删除", "系统配置-项目配额", fmt.Sprintf("project:%s", c.Param("name")), "", ctx.Logger)
+	ctx.Err = service.DeleteProjectQuota(c.Param("name"), ctx.Logger)
+}
+
+type quotaUsageArgs struct {
+	Refresh bool `form:"refresh"`
+}
+
+func GetProjectQuotaUsage(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(quotaUsageArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetProjectQuotaUsage(c.Param("name"), args.Refresh, ctx.Logger)
+}
+
+func OverrideProjectQuota(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.OverrideArgs)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("OverrideProjectQuota c.GetRawData() err : %s", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("OverrideProjectQuota json.Unmarshal err : %s", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Param("name"), "新增", "系统配置-项目配额豁免", fmt.Sprintf("project:%s", c.Param("name")), string(data), ctx.Logger)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid override args")
+		return
+	}
+
+	ctx.Err = service.OverrideProjectQuota(c.Param("name"), args, ctx.UserName, ctx.Logger)
+}
+
+func RevokeProjectQuotaOverride(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Param("name"), "删除", "系统配置-项目配额豁免", fmt.Sprintf("project:%s", c.Param("name")), "", ctx.Logger)
+	ctx.Err = service.RevokeProjectQuotaOverride(c.Param("name"), ctx.Logger)
+}
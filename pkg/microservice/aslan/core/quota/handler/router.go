@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type Router struct{}
+
+func (*Router) Inject(router *gin.RouterGroup) {
+	quotas := router.Group("quotas")
+	{
+		quotas.GET("", ListProjectQuotas)
+		quotas.GET("/:name", GetProjectQuota)
+		quotas.PUT("/:name", UpdateProjectQuota)
+		quotas.DELETE("/:name", DeleteProjectQuota)
+		quotas.GET("/:name/usage", GetProjectQuotaUsage)
+		quotas.POST("/:name/override", OverrideProjectQuota)
+		quotas.DELETE("/:name/override", RevokeProjectQuotaOverride)
+	}
+}
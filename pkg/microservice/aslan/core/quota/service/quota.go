@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListProjectQuotas(log *zap.SugaredLogger) ([]*commonmodels.ProjectQuota, error) {
+	resp, err := commonrepo.NewProjectQuotaColl().List()
+	if err != nil {
+		log.Errorf("ProjectQuota.List error: %s", err)
+		return nil, e.ErrGetProjectQuota.AddErr(err)
+	}
+	return resp, nil
+}
+
+// GetProjectQuota returns an empty quota if the project is not limited.
+func GetProjectQuota(projectName string, log *zap.SugaredLogger) (*commonmodels.ProjectQuota, error) {
+	resp, err := commonrepo.NewProjectQuotaColl().Find(projectName)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &commonmodels.ProjectQuota{ProjectName: projectName}, nil
+	}
+	if err != nil {
+		log.Errorf("ProjectQuota.Find %s error: %s", projectName, err)
+		return nil, e.ErrGetProjectQuota.AddErr(err)
+	}
+	return resp, nil
+}
+
+func UpdateProjectQuota(projectName string, args *commonmodels.ProjectQuota, log *zap.SugaredLogger) error {
+	args.ProjectName = projectName
+	if err := validateProjectQuota(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("project %s is not found", projectName))
+	}
+	if err := commonrepo.NewProjectQuotaColl().Upsert(args); err != nil {
+		log.Errorf("ProjectQuota.Upsert %s error: %s", projectName, err)
+		return e.ErrUpdateProjectQuota.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteProjectQuota(projectName string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewProjectQuotaColl().Delete(projectName); err != nil {
+		log.Errorf("ProjectQuota.Delete %s error: %s", projectName, err)
+		return e.ErrDeleteProjectQuota.AddErr(err)
+	}
+	return nil
+}
+
+type OverrideArgs struct {
+	// Until is when the hard limits are enforced again, in seconds.
+	Until  int64  `json:"until"`
+	Reason string `json:"reason"`
+}
+
+// OverrideProjectQuota lifts the hard limits of the project until args.Until, the override replaces the
+// previous one.
+func OverrideProjectQuota(projectName string, args *OverrideArgs, user string, log *zap.SugaredLogger) error {
+	if args.Until <= time.Now().Unix() {
+		return e.ErrInvalidParam.AddDesc("the override must end in the future")
+	}
+	if args.Reason == "" {
+		return e.ErrInvalidParam.AddDesc("the reason of the override can not be empty")
+	}
+
+	override := &commonmodels.QuotaOverride{
+		Until:     args.Until,
+		Reason:    args.Reason,
+		CreatedBy: user,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := commonrepo.NewProjectQuotaColl().UpdateOverride(projectName, override); err != nil {
+		log.Errorf("ProjectQuota.UpdateOverride %s error: %s", projectName, err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return e.ErrOverrideProjectQuota.AddDesc(fmt.Sprintf("project %s has no quota", projectName))
+		}
+		return e.ErrOverrideProjectQuota.AddErr(err)
+	}
+	return nil
+}
+
+func RevokeProjectQuotaOverride(projectName string, log *zap.SugaredLogger) error {
+	err := commonrepo.NewProjectQuotaColl().UpdateOverride(projectName, nil)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Errorf("ProjectQuota.UpdateOverride %s error: %s", projectName, err)
+		return e.ErrOverrideProjectQuota.AddErr(err)
+	}
+	return nil
+}
+
+func GetProjectQuotaUsage(projectName string, refresh bool, log *zap.SugaredLogger) (*commonservice.ProjectQuotaUsage, error) {
+	resp, err := commonservice.GetProjectQuotaUsage(projectName, refresh)
+	if err != nil {
+		log.Errorf("failed to get the quota usage of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetProjectQuota.AddErr(err)
+	}
+	return resp, nil
+}
+
+func validateProjectQuota(args *commonmodels.ProjectQuota) error {
+	for name, limit := range map[string]*commonmodels.QuotaLimit{
+		"build minutes": args.BuildMinutes,
+		"storage":       args.StorageMB,
+		"environments":  args.Environments,
+	} {
+		if limit == nil {
+			continue
+		}
+		if limit.Soft < 0 || limit.Hard < 0 {
+			return fmt.Errorf("the limits of %s can not be negative", name)
+		}
+		if limit.Soft > 0 && limit.Hard > 0 && limit.Soft > limit.Hard {
+			return fmt.Errorf("the soft limit of %s can not be greater than the hard limit", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/runner/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+func LeaseRunnerJob(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(runner.LeaseArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.LeaseRunnerJob(c.Param("name"), c.GetHeader(runner.TokenHeader), args, ctx.Logger)
+}
+
+func RenewRunnerJobLease(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(runner.HeartbeatArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.RenewRunnerJobLease(c.Param("name"), c.GetHeader(runner.TokenHeader), c.Param("id"), args, ctx.Logger)
+}
+
+func PushRunnerJobLog(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid offset")
+		return
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, runner.MaxLogChunkSize+1))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if len(data) > runner.MaxLogChunkSize {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("log chunk exceeds %d bytes", runner.MaxLogChunkSize))
+		return
+	}
+	ctx.Resp, ctx.Err = service.PushRunnerJobLog(c.Param("name"), c.GetHeader(runner.TokenHeader), c.Param("id"), offset, data, ctx.Logger)
+}
+
+func CompleteRunnerJob(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(runner.CompleteArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Err = service.CompleteRunnerJob(c.Param("name"), c.GetHeader(runner.TokenHeader), c.Param("id"), args, ctx.Logger)
+}
+
+func UploadRunnerJobArtifact(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	name := c.Query("name")
+	if name == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("name is required")
+		return
+	}
+
+	tmp, err := ioutil.TempFile("", "runner-artifact-")
+	if err != nil {
+		ctx.Err = e.ErrUploadRunnerArtifact.AddErr(err)
+		return
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := io.Copy(tmp, c.Request.Body); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	key, err := service.UploadRunnerJobArtifact(c.Param("name"), c.GetHeader(runner.TokenHeader), c.Param("id"), name, tmp.Name(), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Resp = map[string]string{"key": key}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonrunner "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/runner"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+// authenticateAgent checks the token of the runner the agent belongs to.
+func authenticateAgent(runnerName, token string) (*commonmodels.Runner, error) {
+	r, err := commonrepo.NewRunnerColl().Find(runnerName)
+	if err != nil || token == "" || subtle.ConstantTimeCompare([]byte(r.Token), []byte(token)) != 1 {
+		return nil, e.ErrUnauthorized
+	}
+	return r, nil
+}
+
+// getAgentJob returns the job of the runner the agent is authenticated for.
+func getAgentJob(runnerName, token, jobID string) (*commonmodels.RunnerJob, error) {
+	if _, err := authenticateAgent(runnerName, token); err != nil {
+		return nil, err
+	}
+	job, err := commonrepo.NewRunnerJobColl().FindByID(jobID)
+	if err != nil || job.RunnerName != runnerName {
+		return nil, e.ErrNotFound.AddDesc(fmt.Sprintf("runner job %s is not found", jobID))
+	}
+	return job, nil
+}
+
+func leaseExpireTime() int64 {
+	return time.Now().Add(commonrunner.LeaseDuration).Unix()
+}
+
+// LeaseRunnerJob assigns a queued job to the agent, the job in the response is nil if there is none.
+func LeaseRunnerJob(runnerName, token string, args *runner.LeaseArgs, logger *zap.SugaredLogger) (*runner.LeaseResponse, error) {
+	r, err := authenticateAgent(runnerName, token)
+	if err != nil {
+		return nil, err
+	}
+	if args.Agent == "" {
+		return nil, e.ErrInvalidParam.AddDesc("agent is required")
+	}
+	if err := commonrepo.NewRunnerColl().UpdateLastSeenTime(runnerName, time.Now().Unix()); err != nil {
+		logger.Warnf("failed to update the last seen time of runner %s: %s", runnerName, err)
+	}
+
+	job, err := commonrepo.NewRunnerJobColl().Lease(r, args.JobID, args.Agent, leaseExpireTime())
+	if err == mongo.ErrNoDocuments {
+		return &runner.LeaseResponse{}, nil
+	}
+	if err != nil {
+		logger.Errorf("failed to lease job of runner %s, err: %s", runnerName, err)
+		return nil, e.ErrLeaseRunnerJob.AddErr(err)
+	}
+	logger.Infof("job %s of runner %s is leased by agent %s", job.ID.Hex(), runnerName, args.Agent)
+	lease := &runner.Lease{JobID: job.ID.Hex(), Context: job.Context, Timeout: job.Timeout}
+	if r.Type == config.RunnerTypeMacOS && r.MacOS != nil {
+		lease.Signing = r.MacOS.Signing
+	}
+	return &runner.LeaseResponse{Job: lease}, nil
+}
+
+// RenewRunnerJobLease extends the lease held by the agent, the agent stops the job once it is cancelled.
+func RenewRunnerJobLease(runnerName, token, jobID string, args *runner.HeartbeatArgs, logger *zap.SugaredLogger) (*runner.HeartbeatResponse, error) {
+	job, err := getAgentJob(runnerName, token, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := commonrepo.NewRunnerJobColl().Renew(job.ID, args.Agent, leaseExpireTime()); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// the job is stopped, or it is leased by another agent after this one was lost.
+			return &runner.HeartbeatResponse{Cancelled: true}, nil
+		}
+		logger.Errorf("failed to renew the lease of runner job %s, err: %s", jobID, err)
+		return nil, e.ErrUpdateRunnerJob.AddErr(err)
+	}
+	return &runner.HeartbeatResponse{}, nil
+}
+
+// PushRunnerJobLog saves the log chunk pushed by the agent, the secrets are redacted before it is saved.
+func PushRunnerJobLog(runnerName, token, jobID string, offset int64, data []byte, logger *zap.SugaredLogger) (*runner.LogResponse, error) {
+	job, err := getAgentJob(runnerName, token, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []*secretscan.Finding
+	if scanner := jobcontroller.NewLogSecretScanner(job.ProjectName, logger); scanner != nil {
+		redacted := new(bytes.Buffer)
+		findings, err = scanner.Redact(bytes.NewReader(data), redacted)
+		if err != nil {
+			return nil, e.ErrUpdateRunnerJob.AddErr(err)
+		}
+		data = redacted.Bytes()
+	}
+
+	jobLog, err := s3.NewWorkflowJobLog(job.WorkflowName, job.JobName, job.TaskID)
+	if err != nil {
+		return nil, e.ErrUpdateRunnerJob.AddErr(err)
+	}
+	size, appended, err := jobLog.AppendChunk(offset, data)
+	if err != nil {
+		logger.Errorf("failed to save the log of runner job %s, err: %s", jobID, err)
+		return nil, e.ErrUpdateRunnerJob.AddErr(err)
+	}
+	if appended && len(findings) > 0 {
+		for _, finding := range findings {
+			// the lines are counted in the chunk, the line in the whole log is unknown.
+			finding.Line = 0
+		}
+		if err := commonrepo.NewRunnerJobColl().AddSecretFindings(job.ID, findings); err != nil {
+			logger.Errorf("failed to save the secret findings of runner job %s, err: %s", jobID, err)
+		}
+	}
+	return &runner.LogResponse{Offset: size}, nil
+}
+
+// CompleteRunnerJob saves the result reported by the agent.
+func CompleteRunnerJob(runnerName, token, jobID string, args *runner.CompleteArgs, logger *zap.SugaredLogger) error {
+	job, err := getAgentJob(runnerName, token, jobID)
+	if err != nil {
+		return err
+	}
+	status := config.StatusFailed
+	if args.Status == runner.JobPassed {
+		status = config.StatusPassed
+	}
+	if err := commonrepo.NewRunnerJobColl().Complete(job.ID, args.Agent, status, args.Error, args.Outputs); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("runner job %s is not running on agent %s", jobID, args.Agent))
+		}
+		logger.Errorf("failed to complete runner job %s, err: %s", jobID, err)
+		return e.ErrUpdateRunnerJob.AddErr(err)
+	}
+	logger.Infof("runner job %s is done on agent %s: %s", jobID, args.Agent, status)
+	return nil
+}
+
+// UploadRunnerJobArtifact saves the artifact of the job to the default storage, the object key is returned.
+func UploadRunnerJobArtifact(runnerName, token, jobID, name, src string, logger *zap.SugaredLogger) (string, error) {
+	job, err := getAgentJob(runnerName, token, jobID)
+	if err != nil {
+		return "", err
+	}
+	if job.Status != config.StatusRunning {
+		return "", e.ErrInvalidParam.AddDesc(fmt.Sprintf("runner job %s is not running", jobID))
+	}
+	key, err := s3.UploadWorkflowJobArtifact(job.WorkflowName, job.JobName, job.TaskID, name, src)
+	if err != nil {
+		logger.Errorf("failed to upload artifact %s of runner job %s, err: %s", name, jobID, err)
+		return "", e.ErrUploadRunnerArtifact.AddErr(err)
+	}
+	return key, nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+// the name is used in the agent api path and the names of the ephemeral instances.
+var runnerNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+
+func validateRunner(r *commonmodels.Runner) error {
+	if !runnerNameRegex.MatchString(r.Name) {
+		return fmt.Errorf("invalid name %s, only lowercase letters, digits and dashes are allowed", r.Name)
+	}
+	switch r.Type {
+	case config.RunnerTypeStatic:
+	case config.RunnerTypeMacOS:
+		if r.MacOS != nil && r.MacOS.Signing != nil {
+			if err := validateIOSSigning(r.MacOS.Signing); err != nil {
+				return err
+			}
+		}
+	case config.RunnerTypeEC2:
+		if r.EC2 == nil || r.EC2.Region == "" || r.EC2.ImageID == "" || r.EC2.InstanceType == "" {
+			return fmt.Errorf("region, image id and instance type are required by the ec2 runner")
+		}
+	case config.RunnerTypeECS:
+		if r.ECS == nil || r.ECS.Region == "" || r.ECS.Cluster == "" || r.ECS.TaskDefinition == "" || r.ECS.ContainerName == "" {
+			return fmt.Errorf("region, cluster, task definition and container name are required by the ecs runner")
+		}
+	default:
+		return fmt.Errorf("unsupported runner type: %s", r.Type)
+	}
+	return nil
+}
+
+func validateIOSSigning(signing *runner.IOSSigning) error {
+	if _, err := base64.StdEncoding.DecodeString(signing.Certificate); err != nil {
+		return fmt.Errorf("the certificate is not base64 encoded: %s", err)
+	}
+	for i, profile := range signing.ProvisioningProfiles {
+		if _, err := base64.StdEncoding.DecodeString(profile); err != nil {
+			return fmt.Errorf("the provisioning profile %d is not base64 encoded: %s", i, err)
+		}
+	}
+	return nil
+}
+
+func newRunnerToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateRunner returns the runner with the token the agents are configured with.
+func CreateRunner(r *commonmodels.Runner, logger *zap.SugaredLogger) (*commonmodels.Runner, error) {
+	if err := validateRunner(r); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	token, err := newRunnerToken()
+	if err != nil {
+		return nil, e.ErrCreateRunner.AddErr(err)
+	}
+	r.Token = token
+	r.LastSeenTime = 0
+	if err := commonrepo.NewRunnerColl().Create(r); err != nil {
+		logger.Errorf("failed to create runner %s, err: %s", r.Name, err)
+		return nil, e.ErrCreateRunner.AddErr(err)
+	}
+	return r, nil
+}
+
+// UpdateRunner keeps the stored secret keys if they are not given, since they are never returned.
+func UpdateRunner(name string, r *commonmodels.Runner, logger *zap.SugaredLogger) error {
+	origin, err := commonrepo.NewRunnerColl().Find(name)
+	if err != nil {
+		return e.ErrUpdateRunner.AddErr(err)
+	}
+	r.Name = name
+	if r.EC2 != nil && r.EC2.SecretAccessKey == "" && origin.EC2 != nil {
+		r.EC2.SecretAccessKey = origin.EC2.SecretAccessKey
+	}
+	if r.ECS != nil && r.ECS.SecretAccessKey == "" && origin.ECS != nil {
+		r.ECS.SecretAccessKey = origin.ECS.SecretAccessKey
+	}
+	if r.MacOS != nil && r.MacOS.Signing != nil && origin.MacOS != nil && origin.MacOS.Signing != nil {
+		if r.MacOS.Signing.Certificate == "" {
+			r.MacOS.Signing.Certificate = origin.MacOS.Signing.Certificate
+			r.MacOS.Signing.CertificatePassword = origin.MacOS.Signing.CertificatePassword
+		}
+		if r.MacOS.Signing.ProvisioningProfiles == nil {
+			r.MacOS.Signing.ProvisioningProfiles = origin.MacOS.Signing.ProvisioningProfiles
+		}
+	}
+	if err := validateRunner(r); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := commonrepo.NewRunnerColl().Update(name, r); err != nil {
+		logger.Errorf("failed to update runner %s, err: %s", name, err)
+		return e.ErrUpdateRunner.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteRunner(name string, logger *zap.SugaredLogger) error {
+	if err := commonrepo.NewRunnerColl().Delete(name); err != nil {
+		logger.Errorf("failed to delete runner %s, err: %s", name, err)
+		return e.ErrDeleteRunner.AddErr(err)
+	}
+	return nil
+}
+
+func ListRunners(logger *zap.SugaredLogger) ([]*commonmodels.Runner, error) {
+	runners, err := commonrepo.NewRunnerColl().List()
+	if err != nil {
+		logger.Errorf("failed to list runners, err: %s", err)
+		return nil, e.ErrListRunners.AddErr(err)
+	}
+	for _, r := range runners {
+		r.Token = ""
+		hideSecretKeys(r)
+	}
+	return runners, nil
+}
+
+// GetRunner returns the token of the runner as well, the agents are started with it.
+func GetRunner(name string, logger *zap.SugaredLogger) (*commonmodels.Runner, error) {
+	r, err := commonrepo.NewRunnerColl().Find(name)
+	if err != nil {
+		logger.Errorf("failed to get runner %s, err: %s", name, err)
+		return nil, e.ErrGetRunner.AddErr(err)
+	}
+	hideSecretKeys(r)
+	return r, nil
+}
+
+func hideSecretKeys(r *commonmodels.Runner) {
+	if r.EC2 != nil {
+		r.EC2.SecretAccessKey = ""
+	}
+	if r.ECS != nil {
+		r.ECS.SecretAccessKey = ""
+	}
+	if r.MacOS != nil && r.MacOS.Signing != nil {
+		r.MacOS.Signing.Certificate = ""
+		r.MacOS.Signing.CertificatePassword = ""
+		r.MacOS.Signing.ProvisioningProfiles = nil
+	}
+}
@@ -29,28 +29,45 @@ import (
 	commonconfig "github.com/koderover/zadig/pkg/config"
 	configbase "github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	chatopsMongodb "github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/repository/mongodb"
 	modeMongodb "github.com/koderover/zadig/pkg/microservice/aslan/core/collaboration/repository/mongodb"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/devseed"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitmirror"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/migration"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/nsq"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/webhook"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	environmentservice "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
 	labelMongodb "github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+	marketplaceMongodb "github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/mongodb"
+	marketplaceservice "github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/service"
 	multiclusterservice "github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
 	policyservice "github.com/koderover/zadig/pkg/microservice/aslan/core/policy/service"
+	svcservice "github.com/koderover/zadig/pkg/microservice/aslan/core/service/service"
+	statservice "github.com/koderover/zadig/pkg/microservice/aslan/core/stat/service"
 	systemrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/mongodb"
 	systemservice "github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	webhookrelayMongodb "github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/mongodb"
+	webhookservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/webhook"
 	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	policydb "github.com/koderover/zadig/pkg/microservice/policy/core/repository/mongodb"
 	policybundle "github.com/koderover/zadig/pkg/microservice/policy/core/service/bundle"
+	codehostmongodb "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	codehostservice "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/service"
 	configmongodb "github.com/koderover/zadig/pkg/microservice/systemconfig/core/email/repository/mongodb"
 	configservice "github.com/koderover/zadig/pkg/microservice/systemconfig/core/features/service"
 	userCore "github.com/koderover/zadig/pkg/microservice/user/core"
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/cache"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	gormtool "github.com/koderover/zadig/pkg/tool/gorm"
+	"github.com/koderover/zadig/pkg/tool/healthz"
+	"github.com/koderover/zadig/pkg/tool/i18n"
 	"github.com/koderover/zadig/pkg/tool/log"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 	"github.com/koderover/zadig/pkg/tool/rsa"
@@ -94,6 +111,20 @@ func StartControllers(stopCh <-chan struct{}) {
 	wg.Wait()
 }
 
+// initKeyProvider sets the key provider of the stored secrets, it must be done before any secret is read.
+func initKeyProvider() {
+	err := crypto.InitKeyProvider(&crypto.KeyProviderArgs{
+		Provider:     config.EncryptionKeyProvider(),
+		KeyID:        config.EncryptionKeyID(),
+		Region:       config.EncryptionKMSRegion(),
+		VaultAddress: config.VaultAddress(),
+		VaultToken:   config.VaultToken(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to init key provider %s, err: %s", config.EncryptionKeyProvider(), err)
+	}
+}
+
 func initRsaKey() {
 	client, err := kubeclient.GetKubeClient(commonconfig.HubServerServiceAddress(), setting.LocalClusterID)
 	if err != nil {
@@ -129,10 +160,16 @@ func Start(ctx context.Context) {
 		Development: commonconfig.Mode() != setting.ReleaseMode,
 	})
 
+	initKeyProvider()
+	if err := codehostservice.InitSecretBackend(); err != nil {
+		log.Fatalf("Failed to init codehost secret backend, err: %s", err)
+	}
 	initDatabase()
+	cache.Init(configbase.RedisAddress(), configbase.RedisPassword(), configbase.RedisDB())
 
 	initService()
 	initDinD()
+	initI18n()
 
 	// old config service initialization, it didn't panic or stop if it fails, so I will just keep it that way.
 	InitializeConfigFeatureGates()
@@ -142,6 +179,12 @@ func Start(ctx context.Context) {
 	userCore.Start(context.TODO())
 
 	systemservice.SetProxyConfig()
+	configbase.RegisterExternalURLLoader(systemservice.LoadExternalURLSetting)
+
+	// reconcile the declarative system configuration if the bootstrap file is mounted.
+	systemservice.InitBootstrapConfig()
+	// seed the sample data and serve the mock code provider for the local development if it is enabled.
+	devseed.Init(ctx, log.SugaredLogger())
 
 	workflowservice.InitPipelineController()
 	// update offical plugins
@@ -157,16 +200,49 @@ func Start(ctx context.Context) {
 
 	go StartControllers(ctx.Done())
 
+	go webhookservice.StartWebhookWorkers(ctx, config.WebhookWorkers())
+
 	go multiclusterservice.ClusterApplyUpgradeAgent()
 
+	go marketplaceservice.StartMarketplaceSync(ctx, log.SugaredLogger())
+
+	codehostservice.RegisterTokenAlertHandler(systemservice.NotifyCodeHostTokenAlert)
+	go codehostservice.StartTokenRefresher(ctx, log.SugaredLogger())
+	go codehostservice.StartDeviceAuthPoller(ctx, log.SugaredLogger())
+
+	go codehostservice.StartHealthProber(ctx, log.SugaredLogger())
+
+	go commonservice.StartRegistrySecretRotation(ctx, log.SugaredLogger())
+
+	go environmentservice.StartEnvDNSSync(ctx, log.SugaredLogger())
+
+	go svcservice.StartDependencyUpdateChecker(ctx, workflowcontroller.IsLeader, log.SugaredLogger())
+
+	go gitmirror.StartSync(ctx, log.SugaredLogger())
+
+	go workflowservice.StartEnvAutoUpdater(ctx, log.SugaredLogger())
+
+	go statservice.StartHealthDigest(ctx, log.SugaredLogger())
+
 	initRsaKey()
 
 	// policy initialization process
 	policybundle.GenerateOPABundle()
 	policyservice.MigratePolicyData()
+
+	// the failures are logged with the hints, the startup goes on since the dependencies may become ready later
+	go healthz.SelfCheck(ctx, configbase.AslanServiceName(), systemservice.HealthChecks())
+}
+
+// initI18n sets the default language of the messages and looks up the languages chosen by the users.
+func initI18n() {
+	i18n.SetDefaultLanguage(configbase.DefaultLanguage())
+	i18n.RegisterUserLanguageResolver(systemservice.GetUserLanguage)
 }
 
 func Stop(ctx context.Context) {
+	workflowcontroller.LeaveCluster()
+	cache.Close()
 	mongotool.Close(ctx)
 	gormtool.Close()
 }
@@ -249,8 +325,36 @@ func initDatabase() {
 		commonrepo.NewDiffNoteColl(),
 		commonrepo.NewDindCleanColl(),
 		commonrepo.NewFavoriteColl(),
+		commonrepo.NewWorkflowPreferenceColl(),
+		commonrepo.NewUserSettingColl(),
+		commonrepo.NewWorkflowBadgeColl(),
+		commonrepo.NewEnvDataRefreshColl(),
+		commonrepo.NewEnvDataRefreshRecordColl(),
+		commonrepo.NewEnvSmokeTestColl(),
+		commonrepo.NewEnvSmokeTestRecordColl(),
+		commonrepo.NewReleaseTrainColl(),
+		commonrepo.NewReleaseTrainItemColl(),
+		commonrepo.NewEnvAutoUpdatePolicyColl(),
+		commonrepo.NewEnvAutoUpdateEventColl(),
+		commonrepo.NewDependencyUpdatePolicyColl(),
+		commonrepo.NewDependencyUpdateColl(),
+		commonrepo.NewAttachedTriggerColl(),
+		commonrepo.NewCronjobRunColl(),
+		commonrepo.NewCredentialHealthColl(),
+		commonrepo.NewCredentialUsageColl(),
+		commonrepo.NewCustomFieldColl(),
+		commonrepo.NewNotificationRouteColl(),
+		commonrepo.NewNotificationStreakColl(),
+		commonrepo.NewDeploymentBaselineColl(),
+		commonrepo.NewHealthDigestPolicyColl(),
+		commonrepo.NewHealthDigestColl(),
+		commonrepo.NewGitMirrorColl(),
 		commonrepo.NewGithubAppColl(),
 		commonrepo.NewHelmRepoColl(),
+		commonrepo.NewClusterSharedServiceColl(),
+		commonrepo.NewHelmPostRenderRecordColl(),
+		commonrepo.NewRunnerColl(),
+		commonrepo.NewRunnerJobColl(),
 		commonrepo.NewInstallColl(),
 		commonrepo.NewItReportColl(),
 		commonrepo.NewK8SClusterColl(),
@@ -279,8 +383,13 @@ func initDatabase() {
 		commonrepo.NewWorkLoadsStatColl(),
 		commonrepo.NewServicesInExternalEnvColl(),
 		commonrepo.NewExternalLinkColl(),
+		commonrepo.NewJobWarmPoolColl(),
+		commonrepo.NewTenantColl(),
+		commonrepo.NewProjectQuotaColl(),
+		commonrepo.NewBlueprintColl(),
 		commonrepo.NewChartColl(),
 		commonrepo.NewDockerfileTemplateColl(),
+		commonrepo.NewDockerfileTemplateVersionColl(),
 		commonrepo.NewProjectClusterRelationColl(),
 		commonrepo.NewEnvResourceColl(),
 		commonrepo.NewEnvSvcDependColl(),
@@ -290,16 +399,41 @@ func initDatabase() {
 		commonrepo.NewworkflowTaskv4Coll(),
 		commonrepo.NewWorkflowQueueColl(),
 		commonrepo.NewPluginRepoColl(),
+		commonrepo.NewVariableGroupColl(),
+		commonrepo.NewVariableGroupHistoryColl(),
+		commonrepo.NewWorkflowV4VersionColl(),
+		commonrepo.NewEnvVersionColl(),
+		commonrepo.NewEnvLockColl(),
+		commonrepo.NewDNSProviderColl(),
+		commonrepo.NewEnvDNSRecordColl(),
 
 		systemrepo.NewAnnouncementColl(),
+		systemrepo.NewAnnouncementAckColl(),
 		systemrepo.NewOperationLogColl(),
 		labelMongodb.NewLabelColl(),
 		labelMongodb.NewLabelBindingColl(),
 		modeMongodb.NewCollaborationModeColl(),
 		modeMongodb.NewCollaborationInstanceColl(),
+		chatopsMongodb.NewChatOpsAppColl(),
+		chatopsMongodb.NewChatOpsBindingColl(),
+		chatopsMongodb.NewChatOpsCommandColl(),
+		marketplaceMongodb.NewMarketplaceSourceColl(),
+		marketplaceMongodb.NewMarketplaceItemColl(),
+		marketplaceMongodb.NewMarketplaceInstallationColl(),
+		webhookrelayMongodb.NewWebhookRelayRuleColl(),
+		webhookrelayMongodb.NewWebhookRelayDeliveryColl(),
+		commonrepo.NewMigrationColl(),
+		commonrepo.NewMigrationLockColl(),
+		commonrepo.NewWorkflowControllerInstanceColl(),
+		commonrepo.NewWorkflowControllerLeaseColl(),
+		commonrepo.NewWebhookEventColl(),
 
 		// config related db index
 		configmongodb.NewEmailHostColl(),
+		codehostmongodb.NewCodeHostWebhookColl(),
+		codehostmongodb.NewCodeHostAuditColl(),
+		codehostmongodb.NewOAuthStateColl(),
+		codehostmongodb.NewDeviceAuthColl(),
 
 		// policy related db index
 		policydb.NewRoleColl(),
@@ -317,6 +451,10 @@ func initDatabase() {
 
 	wg.Wait()
 
+	if err := migration.Run(idxCtx, config.PodName(), log.SugaredLogger()); err != nil {
+		panic(fmt.Errorf("failed to run migrations, error: %s", err))
+	}
+
 	// 初始化数据
 	commonrepo.NewInstallColl().InitInstallData(systemservice.InitInstallMap())
 	commonrepo.NewBasicImageColl().InitBasicImageData(systemservice.InitbasicImageInfos())
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	svcservice "github.com/koderover/zadig/pkg/microservice/aslan/core/service/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetDependencyUpdatePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	ctx.Resp, ctx.Err = svcservice.GetDependencyUpdatePolicy(projectName, ctx.Logger)
+}
+
+func UpdateDependencyUpdatePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	args := new(commonmodels.DependencyUpdatePolicy)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = projectName
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "更新", "项目管理-依赖更新策略", projectName, "", ctx.Logger)
+
+	ctx.Err = svcservice.UpdateDependencyUpdatePolicy(ctx.UserName, args, ctx.Logger)
+}
+
+func ListDependencyUpdates(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	var status []config.DependencyUpdateStatus
+	if c.Query("status") != "" {
+		for _, s := range strings.Split(c.Query("status"), ",") {
+			status = append(status, config.DependencyUpdateStatus(s))
+		}
+	}
+	ctx.Resp, ctx.Err = svcservice.ListDependencyUpdates(projectName, c.Query("serviceName"), status, ctx.Logger)
+}
+
+func CheckDependencyUpdates(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	ctx.Resp, ctx.Err = svcservice.CheckDependencyUpdates(projectName, ctx.Logger)
+}
+
+func CreateDependencyUpdatePR(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "新建", "项目管理-依赖更新PR", c.Param("id"), "", ctx.Logger)
+
+	ctx.Resp, ctx.Err = svcservice.CreateDependencyUpdatePR(projectName, c.Param("id"), ctx.Logger)
+}
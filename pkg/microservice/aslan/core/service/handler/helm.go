@@ -134,6 +134,22 @@ func UpdateHelmService(c *gin.Context) {
 	ctx.Resp, ctx.Err = svcservice.CreateOrUpdateHelmService(projectName, args, true, ctx.Logger)
 }
 
+// ResyncUmbrellaChartServices reloads the services imported from umbrella charts after the charts change upstream
+func ResyncUmbrellaChartServices(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can't be nil")
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "更新", "项目管理-服务", "同步umbrella chart", "", ctx.Logger)
+
+	ctx.Resp, ctx.Err = svcservice.ResyncUmbrellaChartServices(projectName, ctx.UserName, ctx.RequestID, ctx.Logger)
+}
+
 func CreateOrUpdateBulkHelmServices(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -41,6 +41,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		helm.POST("/services", CreateOrUpdateHelmService)
 		helm.PUT("/services", UpdateHelmService)
 		helm.POST("/services/bulk", CreateOrUpdateBulkHelmServices)
+		helm.POST("/services/umbrella/resync", ResyncUmbrellaChartServices)
 		helm.PUT("/services/releaseNaming", HelmReleaseNaming)
 	}
 
@@ -52,8 +53,11 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		k8s.POST("", GetServiceTemplateProductName, CreateServiceTemplate)
 		k8s.PUT("", UpdateServiceTemplate)
 		k8s.PUT("/yaml/validator", YamlValidator)
+		k8s.PUT("/yaml/lint", LintYaml)
+		k8s.GET("/lint/rules", ListManifestLintRules)
 		k8s.PUT("/:name/yaml/view", YamlViewServiceTemplate)
 		k8s.DELETE("/:name/:type", DeleteServiceTemplate)
+		k8s.PUT("/:name/:type/statusMappings", UpdateResourceStatusMappings)
 		k8s.GET("/:name/:type/ports", ListServicePort)
 		k8s.GET("/:name/environments/deployable", GetDeployableEnvs)
 		k8s.GET("/kube/workloads", GetKubeWorkloads)
@@ -87,6 +91,15 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		pm.PUT("/:productName", UpdatePmServiceTemplate)
 	}
 
+	dependencyUpdates := router.Group("dependencyUpdates")
+	{
+		dependencyUpdates.GET("/policy", GetDependencyUpdatePolicy)
+		dependencyUpdates.PUT("/policy", UpdateDependencyUpdatePolicy)
+		dependencyUpdates.GET("", ListDependencyUpdates)
+		dependencyUpdates.POST("/check", CheckDependencyUpdates)
+		dependencyUpdates.POST("/:id/pr", CreateDependencyUpdatePR)
+	}
+
 	template := router.Group("template")
 	{
 		template.POST("/load", LoadServiceFromYamlTemplate)
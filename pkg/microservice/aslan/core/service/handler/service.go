@@ -34,13 +34,32 @@ import (
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/pagination"
 )
 
+var serviceSchema = &pagination.Schema{
+	Key:        "service_name",
+	Sortable:   []string{"type", "source"},
+	Filterable: []string{"type", "source", "product_name", "visibility", "codehost_id"},
+	Searchable: []string{"service_name", "repo_name"},
+}
+
 func ListServiceTemplate(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	ctx.Resp, ctx.Err = commonservice.ListServiceTemplate(c.Query("projectName"), ctx.Logger)
+	resp, err := commonservice.ListServiceTemplate(c.Query("projectName"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	data, page, err := internalhandler.Paginate(c, resp.Data, serviceSchema)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	resp.Data, resp.Total = data, int(page.Total)
+	ctx.Resp = resp
 }
 
 func ListWorkloadTemplate(c *gin.Context) {
@@ -155,6 +174,30 @@ func YamlValidator(c *gin.Context) {
 	ctx.Resp = resp
 }
 
+func ListManifestLintRules(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp = svcservice.ListManifestLintRules()
+}
+
+func LintYaml(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(svcservice.YamlLintReq)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.Yaml == "" && (args.ProjectName == "" || args.ServiceName == "") {
+		ctx.Err = e.ErrInvalidParam.AddDesc("yaml or project_name and service_name must be specified")
+		return
+	}
+
+	ctx.Resp, ctx.Err = svcservice.LintYaml(args, ctx.Logger)
+}
+
 func YamlViewServiceTemplate(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -204,6 +247,20 @@ func DeleteServiceTemplate(c *gin.Context) {
 	ctx.Err = svcservice.DeleteServiceTemplate(c.Param("name"), c.Param("type"), c.Query("projectName"), c.DefaultQuery("isEnvTemplate", "true"), c.DefaultQuery("visibility", "public"), ctx.Logger)
 }
 
+func UpdateResourceStatusMappings(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	var args []*commonmodels.ResourceStatusMapping
+	if err := c.ShouldBindJSON(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "更新", "项目管理-服务状态映射", c.Param("name"), "", ctx.Logger)
+
+	ctx.Err = svcservice.UpdateResourceStatusMappings(c.Param("name"), c.Param("type"), c.Query("projectName"), args, ctx.Logger)
+}
+
 func ListServicePort(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -0,0 +1,582 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+)
+
+const dependencyUpdateCheckInterval = 12 * time.Hour
+
+var openDependencyUpdateStatus = []config.DependencyUpdateStatus{
+	config.DependencyUpdateDetected,
+	config.DependencyUpdatePROpened,
+	config.DependencyUpdateFailed,
+}
+
+func GetDependencyUpdatePolicy(projectName string, log *zap.SugaredLogger) (*commonmodels.DependencyUpdatePolicy, error) {
+	policy, err := commonrepo.NewDependencyUpdatePolicyColl().Find(projectName)
+	if err == mongo.ErrNoDocuments {
+		return &commonmodels.DependencyUpdatePolicy{ProjectName: projectName, Ignored: make([]string, 0)}, nil
+	}
+	if err != nil {
+		log.Errorf("failed to find dependency update policy of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetDependencyUpdatePolicy.AddErr(err)
+	}
+	return policy, nil
+}
+
+func UpdateDependencyUpdatePolicy(username string, policy *commonmodels.DependencyUpdatePolicy, log *zap.SugaredLogger) error {
+	policy.UpdatedBy = username
+	if policy.Ignored == nil {
+		policy.Ignored = make([]string, 0)
+	}
+	if err := commonrepo.NewDependencyUpdatePolicyColl().Upsert(policy); err != nil {
+		log.Errorf("failed to update dependency update policy of project %s, err: %s", policy.ProjectName, err)
+		return e.ErrUpdateDependencyUpdatePolicy.AddErr(err)
+	}
+	return nil
+}
+
+func ListDependencyUpdates(projectName, serviceName string, status []config.DependencyUpdateStatus, log *zap.SugaredLogger) ([]*commonmodels.DependencyUpdate, error) {
+	updates, err := commonrepo.NewDependencyUpdateColl().List(&commonrepo.ListDependencyUpdateOption{
+		ProjectName: projectName,
+		ServiceName: serviceName,
+		Status:      status,
+	})
+	if err != nil {
+		log.Errorf("failed to list dependency updates of project %s, err: %s", projectName, err)
+		return nil, e.ErrListDependencyUpdate.AddErr(err)
+	}
+	return updates, nil
+}
+
+// CheckDependencyUpdates looks for the newer versions of the images used by the k8s services and the chart
+// dependencies of the helm services of the project. Only the services loaded from github or gitlab are checked,
+// since the bumps are sent as pull requests to the repos of the services.
+func CheckDependencyUpdates(projectName string, log *zap.SugaredLogger) ([]*commonmodels.DependencyUpdate, error) {
+	policy, err := GetDependencyUpdatePolicy(projectName, log)
+	if err != nil {
+		return nil, err
+	}
+	services, err := commonrepo.NewServiceColl().ListMaxRevisionsByProduct(projectName)
+	if err != nil {
+		log.Errorf("failed to list services of project %s, err: %s", projectName, err)
+		return nil, e.ErrCheckDependencyUpdate.AddErr(err)
+	}
+	registries, err := commonservice.ListRegistryNamespaces("", true, log)
+	if err != nil {
+		return nil, e.ErrCheckDependencyUpdate.AddErr(err)
+	}
+
+	ignored := sets.NewString(policy.Ignored...)
+	for _, svc := range services {
+		if svc.Source != setting.SourceFromGithub && svc.Source != setting.SourceFromGitlab {
+			continue
+		}
+		repo, err := newDependencyRepo(svc)
+		if err != nil {
+			log.Warnf("failed to access the repo of service %s, err: %s", svc.ServiceName, err)
+			continue
+		}
+
+		var found []*commonmodels.DependencyUpdate
+		switch svc.Type {
+		case setting.K8SDeployType:
+			found, err = findImageUpdates(svc, repo, registries, log)
+		case setting.HelmDeployType:
+			found, err = findChartUpdates(svc, repo)
+		default:
+			continue
+		}
+		if err != nil {
+			log.Warnf("failed to check the dependencies of service %s, err: %s", svc.ServiceName, err)
+			continue
+		}
+
+		for _, update := range found {
+			if ignored.Has(update.Name) {
+				continue
+			}
+			if err := saveDependencyUpdate(update, policy.AutoCreatePR, log); err != nil {
+				log.Errorf("failed to save dependency update of %s in service %s, err: %s", update.Name, svc.ServiceName, err)
+			}
+		}
+		resolveDependencyUpdates(svc, found, log)
+	}
+
+	if err := commonrepo.NewDependencyUpdatePolicyColl().UpdateLastCheckTime(projectName, time.Now().Unix()); err != nil {
+		log.Warnf("failed to update the check time of project %s, err: %s", projectName, err)
+	}
+	return ListDependencyUpdates(projectName, "", openDependencyUpdateStatus, log)
+}
+
+// CreateDependencyUpdatePR opens the pull request for the update, the bump is applied to the files of the
+// base branch at the moment.
+func CreateDependencyUpdatePR(projectName, id string, log *zap.SugaredLogger) (*commonmodels.DependencyUpdate, error) {
+	update, err := commonrepo.NewDependencyUpdateColl().FindByID(id)
+	if err != nil {
+		return nil, e.ErrCreateDependencyUpdatePR.AddErr(err)
+	}
+	if update.ProjectName != projectName {
+		return nil, e.ErrCreateDependencyUpdatePR.AddDesc(fmt.Sprintf("update %s is not found in project %s", id, projectName))
+	}
+	if update.Status == config.DependencyUpdatePROpened || update.Status == config.DependencyUpdateResolved {
+		return nil, e.ErrCreateDependencyUpdatePR.AddDesc(fmt.Sprintf("the update is %s", update.Status))
+	}
+
+	if err := openDependencyUpdatePR(update); err != nil {
+		log.Errorf("failed to open the pull request of dependency update %s, err: %s", id, err)
+		update.Status = config.DependencyUpdateFailed
+		update.Error = err.Error()
+		if err := commonrepo.NewDependencyUpdateColl().Update(update); err != nil {
+			log.Errorf("failed to update dependency update %s, err: %s", id, err)
+		}
+		return nil, e.ErrCreateDependencyUpdatePR.AddErr(err)
+	}
+
+	if err := commonrepo.NewDependencyUpdateColl().Update(update); err != nil {
+		return nil, e.ErrCreateDependencyUpdatePR.AddErr(err)
+	}
+	return update, nil
+}
+
+// StartDependencyUpdateChecker checks the dependencies of the projects with the checks enabled periodically.
+// The checks are only run by the leader, so that the updates are not recorded and the pull requests are not
+// opened by several instances.
+func StartDependencyUpdateChecker(ctx context.Context, isLeader func() bool, log *zap.SugaredLogger) {
+	ticker := time.NewTicker(dependencyUpdateCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !isLeader() {
+				continue
+			}
+			policies, err := commonrepo.NewDependencyUpdatePolicyColl().ListEnabled()
+			if err != nil {
+				log.Errorf("failed to list dependency update policies, err: %s", err)
+				continue
+			}
+			for _, policy := range policies {
+				if _, err := CheckDependencyUpdates(policy.ProjectName, log); err != nil {
+					log.Errorf("failed to check dependency updates of project %s, err: %s", policy.ProjectName, err)
+				}
+			}
+		}
+	}
+}
+
+// saveDependencyUpdate records the update unless it is already known, the update found before is refreshed
+// if a newer version is released in the meantime and its pull request is not opened yet.
+func saveDependencyUpdate(update *commonmodels.DependencyUpdate, autoCreatePR bool, log *zap.SugaredLogger) error {
+	existed, err := commonrepo.NewDependencyUpdateColl().List(&commonrepo.ListDependencyUpdateOption{
+		ProjectName: update.ProjectName,
+		ServiceName: update.ServiceName,
+		Status:      openDependencyUpdateStatus,
+	})
+	if err != nil {
+		return err
+	}
+
+	var current *commonmodels.DependencyUpdate
+	for _, u := range existed {
+		if u.Kind != update.Kind || u.Name != update.Name {
+			continue
+		}
+		if u.LatestVersion == update.LatestVersion {
+			return nil
+		}
+		if u.Status != config.DependencyUpdatePROpened {
+			current = u
+		}
+	}
+
+	if current == nil {
+		update.Status = config.DependencyUpdateDetected
+		if err := commonrepo.NewDependencyUpdateColl().Create(update); err != nil {
+			return err
+		}
+		current = update
+	} else {
+		current.CurrentVersion = update.CurrentVersion
+		current.LatestVersion = update.LatestVersion
+		current.Files = update.Files
+		current.Status = config.DependencyUpdateDetected
+		current.Error = ""
+	}
+
+	if autoCreatePR {
+		if err := openDependencyUpdatePR(current); err != nil {
+			log.Warnf("failed to open the pull request for %s in service %s, err: %s", current.Name, current.ServiceName, err)
+			current.Status = config.DependencyUpdateFailed
+			current.Error = err.Error()
+		}
+	}
+	return commonrepo.NewDependencyUpdateColl().Update(current)
+}
+
+// resolveDependencyUpdates closes the updates of the service which are not found any more, which means the
+// service uses the version already.
+func resolveDependencyUpdates(svc *commonmodels.Service, found []*commonmodels.DependencyUpdate, log *zap.SugaredLogger) {
+	existed, err := commonrepo.NewDependencyUpdateColl().List(&commonrepo.ListDependencyUpdateOption{
+		ProjectName: svc.ProductName,
+		ServiceName: svc.ServiceName,
+		Status:      openDependencyUpdateStatus,
+	})
+	if err != nil {
+		log.Errorf("failed to list dependency updates of service %s, err: %s", svc.ServiceName, err)
+		return
+	}
+
+	for _, u := range existed {
+		stillNeeded := false
+		for _, f := range found {
+			if f.Kind == u.Kind && f.Name == u.Name && f.CurrentVersion == u.CurrentVersion {
+				stillNeeded = true
+				break
+			}
+		}
+		if stillNeeded {
+			continue
+		}
+		u.Status = config.DependencyUpdateResolved
+		if err := commonrepo.NewDependencyUpdateColl().Update(u); err != nil {
+			log.Errorf("failed to resolve dependency update %s, err: %s", u.ID.Hex(), err)
+		}
+	}
+}
+
+func openDependencyUpdatePR(update *commonmodels.DependencyUpdate) error {
+	svc := &commonmodels.Service{
+		CodehostID:    update.CodehostID,
+		RepoNamespace: update.RepoOwner,
+		RepoName:      update.RepoName,
+		BranchName:    update.BaseBranch,
+	}
+	repo, err := newDependencyRepo(svc)
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string][]byte)
+	for _, file := range update.Files {
+		content, err := repo.getFile(file)
+		if err != nil {
+			return err
+		}
+		bumped, err := bumpDependency(update, content)
+		if err != nil {
+			return err
+		}
+		if string(bumped) != string(content) {
+			changed[file] = bumped
+		}
+	}
+	if len(changed) == 0 {
+		return fmt.Errorf("%s %s is not used in the files of branch %s", update.Name, update.CurrentVersion, update.BaseBranch)
+	}
+
+	branch := fmt.Sprintf("zadig/dependency/%s/%s-%s", update.ServiceName, path.Base(update.Name), update.LatestVersion)
+	title := fmt.Sprintf("Bump %s from %s to %s", update.Name, update.CurrentVersion, update.LatestVersion)
+	body := fmt.Sprintf("Bumps %s %s of service %s from %s to %s.\n\nThe update is found by the dependency checks of Zadig project %s.",
+		update.Kind, update.Name, update.ServiceName, update.CurrentVersion, update.LatestVersion, update.ProjectName)
+	number, url, err := repo.createPR(branch, title, body, changed)
+	if err != nil {
+		return err
+	}
+
+	update.Branch = branch
+	update.PRNumber = number
+	update.PRURL = url
+	update.Status = config.DependencyUpdatePROpened
+	update.Error = ""
+	return nil
+}
+
+func bumpDependency(update *commonmodels.DependencyUpdate, content []byte) ([]byte, error) {
+	switch update.Kind {
+	case config.DependencyKindImage:
+		return replaceImage(content, update.Name+":"+update.CurrentVersion, update.Name+":"+update.LatestVersion), nil
+	case config.DependencyKindChart:
+		return replaceChartDependencyVersion(content, update.Name, update.CurrentVersion, update.LatestVersion)
+	default:
+		return nil, fmt.Errorf("unknown dependency kind %s", update.Kind)
+	}
+}
+
+// findImageUpdates checks the images of the service pushed to the integrated registries, the images of the
+// other registries are skipped since no credential is available.
+func findImageUpdates(svc *commonmodels.Service, repo dependencyRepo, registries []*commonmodels.RegistryNamespace, log *zap.SugaredLogger) ([]*commonmodels.DependencyUpdate, error) {
+	files, err := repo.listFiles(svc.LoadPath, svc.LoadFromDir)
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string][]byte)
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".yaml") && !strings.HasSuffix(file, ".yml") {
+			continue
+		}
+		content, err := repo.getFile(file)
+		if err != nil {
+			return nil, err
+		}
+		contents[file] = content
+	}
+
+	resp := make([]*commonmodels.DependencyUpdate, 0)
+	checked := sets.NewString()
+	for _, container := range svc.Containers {
+		if checked.Has(container.Image) {
+			continue
+		}
+		checked.Insert(container.Image)
+
+		reg := commonservice.FindImageRegistry(container.Image, registries)
+		if reg == nil {
+			continue
+		}
+		name, tag := commonservice.ExtractImageName(container.Image), commonservice.ExtractImageTag(container.Image)
+		if name == "" || tag == "" {
+			continue
+		}
+		tags, err := listImageTags(reg, name, log)
+		if err != nil {
+			log.Warnf("failed to list the tags of image %s, err: %s", container.Image, err)
+			continue
+		}
+		latest := findLatestVersion(tag, tags)
+		if latest == "" {
+			continue
+		}
+
+		image := strings.TrimSuffix(container.Image, ":"+tag)
+		update := newDependencyUpdate(svc, config.DependencyKindImage, image, tag, latest)
+		for file, content := range contents {
+			if string(replaceImage(content, container.Image, image+":"+latest)) != string(content) {
+				update.Files = append(update.Files, file)
+			}
+		}
+		if len(update.Files) > 0 {
+			resp = append(resp, update)
+		}
+	}
+	return resp, nil
+}
+
+func listImageTags(reg *commonmodels.RegistryNamespace, name string, log *zap.SugaredLogger) ([]string, error) {
+	var regService registry.Service
+	if reg.AdvancedSetting != nil {
+		regService = registry.NewV2Service(reg.RegProvider, reg.AdvancedSetting.TLSEnabled, reg.AdvancedSetting.TLSCert)
+	} else {
+		regService = registry.NewV2Service(reg.RegProvider, true, "")
+	}
+	repos, err := regService.ListRepoImages(registry.ListRepoImagesOption{
+		Endpoint: registry.Endpoint{
+			Addr:      reg.RegAddr,
+			Ak:        reg.AccessKey,
+			Sk:        reg.SecretKey,
+			Namespace: reg.Namespace,
+			Region:    reg.Region,
+		},
+		Repos: []string{name},
+	}, log)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, 0)
+	for _, r := range repos.Repos {
+		tags = append(tags, r.Tags...)
+	}
+	return tags, nil
+}
+
+// replaceImage replaces the image reference in the content, the references with the image as a prefix
+// only, e.g. nginx:1.2.0 when replacing nginx:1.2, are left untouched.
+func replaceImage(content []byte, from, to string) []byte {
+	re := regexp.MustCompile(`(^|[\s"'=])` + regexp.QuoteMeta(from) + `([\s"',]|$)`)
+	return re.ReplaceAll(content, []byte("${1}"+to+"${2}"))
+}
+
+type chartDependency struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	Repository string `yaml:"repository"`
+}
+
+// findChartUpdates checks the dependencies declared in the Chart.yaml of the service, the dependencies with
+// a version range or from an oci or a local repository are skipped.
+func findChartUpdates(svc *commonmodels.Service, codeRepo dependencyRepo) ([]*commonmodels.DependencyUpdate, error) {
+	chartFile := path.Join(svc.LoadPath, "Chart.yaml")
+	content, err := codeRepo.getFile(chartFile)
+	if err != nil {
+		return nil, err
+	}
+	chart := &struct {
+		Dependencies []*chartDependency `yaml:"dependencies"`
+	}{}
+	if err := yaml.Unmarshal(content, chart); err != nil {
+		return nil, err
+	}
+
+	client, err := helmtool.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*commonmodels.DependencyUpdate, 0)
+	for _, dep := range chart.Dependencies {
+		if !strings.HasPrefix(dep.Repository, "http://") && !strings.HasPrefix(dep.Repository, "https://") {
+			continue
+		}
+		if _, err := semver.Parse(strings.TrimPrefix(dep.Version, "v")); err != nil {
+			continue
+		}
+
+		index, err := client.FetchIndexYaml(&repo.Entry{
+			Name: "dependency-" + strings.NewReplacer("://", "-", "/", "-", ":", "-").Replace(strings.TrimSuffix(dep.Repository, "/")),
+			URL:  dep.Repository,
+		})
+		if err != nil {
+			return nil, err
+		}
+		versions := make([]string, 0)
+		for _, v := range index.Entries[dep.Name] {
+			versions = append(versions, v.Version)
+		}
+		latest := findLatestVersion(dep.Version, versions)
+		if latest == "" {
+			continue
+		}
+		update := newDependencyUpdate(svc, config.DependencyKindChart, dep.Name, dep.Version, latest)
+		update.Files = []string{chartFile}
+		resp = append(resp, update)
+	}
+	return resp, nil
+}
+
+// replaceChartDependencyVersion edits the version line of the dependency only to keep the rest of the file
+// untouched.
+func replaceChartDependencyVersion(content []byte, name, from, to string) ([]byte, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(content, root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return content, nil
+	}
+
+	line := 0
+	doc := root.Content[0]
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "dependencies" {
+			continue
+		}
+		for _, dep := range doc.Content[i+1].Content {
+			var depName string
+			var version *yaml.Node
+			for j := 0; j+1 < len(dep.Content); j += 2 {
+				switch dep.Content[j].Value {
+				case "name":
+					depName = dep.Content[j+1].Value
+				case "version":
+					version = dep.Content[j+1]
+				}
+			}
+			if depName == name && version != nil && version.Value == from {
+				line = version.Line
+			}
+		}
+	}
+	if line == 0 {
+		return content, nil
+	}
+
+	lines := strings.Split(string(content), "\n")
+	lines[line-1] = strings.Replace(lines[line-1], from, to, 1)
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// findLatestVersion returns the highest version newer than the current one, a version counts only if it is
+// written the same way, e.g. with the same prefix and the same number of components, as the current one
+// and it is not a prerelease unless the current one is.
+func findLatestVersion(current string, versions []string) string {
+	cur, err := semver.ParseTolerant(current)
+	if err != nil {
+		return ""
+	}
+	prefixed := strings.HasPrefix(current, "v")
+	components := strings.Count(strings.SplitN(strings.TrimPrefix(current, "v"), "-", 2)[0], ".")
+
+	latest, best := "", cur
+	for _, v := range versions {
+		if strings.HasPrefix(v, "v") != prefixed || strings.Count(strings.SplitN(strings.TrimPrefix(v, "v"), "-", 2)[0], ".") != components {
+			continue
+		}
+		parsed, err := semver.ParseTolerant(v)
+		if err != nil {
+			continue
+		}
+		if len(parsed.Pre) > 0 && len(cur.Pre) == 0 {
+			continue
+		}
+		if parsed.GT(best) {
+			latest, best = v, parsed
+		}
+	}
+	return latest
+}
+
+func newDependencyUpdate(svc *commonmodels.Service, kind config.DependencyKind, name, current, latest string) *commonmodels.DependencyUpdate {
+	return &commonmodels.DependencyUpdate{
+		ProjectName:    svc.ProductName,
+		ServiceName:    svc.ServiceName,
+		Kind:           kind,
+		Name:           name,
+		CurrentVersion: current,
+		LatestVersion:  latest,
+		Files:          make([]string, 0),
+		CodehostID:     svc.CodehostID,
+		RepoOwner:      svc.GetRepoNamespace(),
+		RepoName:       svc.RepoName,
+		BaseBranch:     svc.BranchName,
+		WorkflowTasks:  make([]*commonmodels.DependencyUpdateTask, 0),
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v35/github"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	githubtool "github.com/koderover/zadig/pkg/tool/git/github"
+	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
+)
+
+// dependencyRepo is the repo a service is loaded from, the dependency updates are read from and sent to it.
+type dependencyRepo interface {
+	// listFiles returns the paths of the files under the path, or the path itself if it is a file.
+	listFiles(path string, isDir bool) ([]string, error)
+	getFile(path string) ([]byte, error)
+	// createPR commits the files to a new branch and opens a pull request from it to the base branch.
+	createPR(branch, title, body string, files map[string][]byte) (int, string, error)
+}
+
+func newDependencyRepo(svc *commonmodels.Service) (dependencyRepo, error) {
+	ch, err := systemconfig.New().GetCodeHost(svc.CodehostID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ch.Type {
+	case setting.SourceFromGithub:
+		return &githubDependencyRepo{
+			client: githubtool.NewClient(&githubtool.Config{AccessToken: ch.AccessToken, Proxy: ch.ProxyAddr(config.ProxyHTTPSAddr())}),
+			owner:  svc.GetRepoNamespace(),
+			repo:   svc.RepoName,
+			branch: svc.BranchName,
+		}, nil
+	case setting.SourceFromGitlab:
+		client, err := gitlabtool.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		if err != nil {
+			return nil, err
+		}
+		return &gitlabDependencyRepo{
+			client: client,
+			owner:  svc.GetRepoNamespace(),
+			repo:   svc.RepoName,
+			branch: svc.BranchName,
+		}, nil
+	default:
+		return nil, fmt.Errorf("codehost type %s is not supported", ch.Type)
+	}
+}
+
+type githubDependencyRepo struct {
+	client *githubtool.Client
+	owner  string
+	repo   string
+	branch string
+}
+
+func (r *githubDependencyRepo) listFiles(path string, isDir bool) ([]string, error) {
+	if !isDir {
+		return []string{path}, nil
+	}
+	_, dir, err := r.client.GetContents(context.TODO(), r.owner, r.repo, path, &github.RepositoryContentGetOptions{Ref: r.branch})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, fileOrDir := range dir {
+		switch fileOrDir.GetType() {
+		case "file":
+			files = append(files, fileOrDir.GetPath())
+		case "dir":
+			tree, err := r.client.GetTree(context.TODO(), r.owner, r.repo, fileOrDir.GetSHA(), true)
+			if err != nil {
+				return nil, err
+			}
+			if tree == nil {
+				continue
+			}
+			for _, ent := range tree.Entries {
+				if ent.GetType() == "blob" {
+					files = append(files, fmt.Sprintf("%s/%s", fileOrDir.GetPath(), ent.GetPath()))
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+func (r *githubDependencyRepo) getFile(path string) ([]byte, error) {
+	file, _, err := r.client.GetContents(context.TODO(), r.owner, r.repo, path, &github.RepositoryContentGetOptions{Ref: r.branch})
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+	content, err := file.GetContent()
+	return []byte(content), err
+}
+
+func (r *githubDependencyRepo) createPR(branch, title, body string, files map[string][]byte) (int, string, error) {
+	ctx := context.TODO()
+	if err := r.client.CreateBranch(ctx, r.owner, r.repo, r.branch, branch); err != nil {
+		return 0, "", err
+	}
+	for path, content := range files {
+		file, _, err := r.client.GetContents(ctx, r.owner, r.repo, path, &github.RepositoryContentGetOptions{Ref: branch})
+		if err != nil {
+			return 0, "", err
+		}
+		if err := r.client.UpdateFile(ctx, r.owner, r.repo, path, branch, file.GetSHA(), title, content); err != nil {
+			return 0, "", err
+		}
+	}
+
+	pr, err := r.client.CreatePullRequest(ctx, r.owner, r.repo, &github.NewPullRequest{
+		Title: github.String(title),
+		Head:  github.String(branch),
+		Base:  github.String(r.branch),
+		Body:  github.String(body),
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	return pr.GetNumber(), pr.GetHTMLURL(), nil
+}
+
+type gitlabDependencyRepo struct {
+	client *gitlabtool.Client
+	owner  string
+	repo   string
+	branch string
+}
+
+func (r *gitlabDependencyRepo) listFiles(path string, isDir bool) ([]string, error) {
+	if !isDir {
+		return []string{path}, nil
+	}
+	nodes, err := r.client.ListTree(r.owner, r.repo, path, r.branch, true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	for _, node := range nodes {
+		if node.Type == "blob" {
+			files = append(files, node.Path)
+		}
+	}
+	return files, nil
+}
+
+func (r *gitlabDependencyRepo) getFile(path string) ([]byte, error) {
+	return r.client.GetFileContent(r.owner, r.repo, path, r.branch)
+}
+
+func (r *gitlabDependencyRepo) createPR(branch, title, body string, files map[string][]byte) (int, string, error) {
+	if err := r.client.CreateBranch(r.owner, r.repo, r.branch, branch); err != nil {
+		return 0, "", err
+	}
+	for path, content := range files {
+		if err := r.client.UpdateFile(r.owner, r.repo, path, branch, title, content); err != nil {
+			return 0, "", err
+		}
+	}
+
+	mr, err := r.client.CreateMergeRequest(r.owner, r.repo, branch, r.branch, title, body)
+	if err != nil {
+		return 0, "", err
+	}
+	return mr.IID, mr.WebURL, nil
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
@@ -139,6 +140,40 @@ func GetKubeWorkloads(namespace, clusterID string, log *zap.SugaredLogger) (*Get
 		statefulsetNames = append(statefulsetNames, statefulset.Name)
 	}
 	workloadsMap["statefulset"] = statefulsetNames
+	daemonSets, err := getter.ListDaemonSets(namespace, nil, kubeClient)
+	if err != nil {
+		log.Errorf("GetKubeWorkloads ListDaemonSets error, error msg:%s", err)
+		return nil, err
+	}
+	var daemonSetNames []string
+	for _, daemonSet := range daemonSets {
+		daemonSetNames = append(daemonSetNames, daemonSet.Name)
+	}
+	workloadsMap["daemonset"] = daemonSetNames
+	jobs, err := getter.ListJobs(namespace, nil, kubeClient)
+	if err != nil {
+		log.Errorf("GetKubeWorkloads ListJobs error, error msg:%s", err)
+		return nil, err
+	}
+	var jobNames []string
+	for _, job := range jobs {
+		// the jobs created by cronJobs are loaded with their cronJobs
+		if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == setting.CronJob {
+			continue
+		}
+		jobNames = append(jobNames, job.Name)
+	}
+	workloadsMap["job"] = jobNames
+	cronJobs, err := getter.ListCronJobs(namespace, nil, kubeClient)
+	if err != nil {
+		// the api version of cronJob may not be served by the cluster
+		log.Warnf("GetKubeWorkloads ListCronJobs error, error msg:%s", err)
+	}
+	var cronJobNames []string
+	for _, cronJob := range cronJobs {
+		cronJobNames = append(cronJobNames, cronJob.Name)
+	}
+	workloadsMap["cronjob"] = cronJobNames
 	pvcs, err := getter.ListPvcs(namespace, nil, kubeClient)
 	if err != nil {
 		log.Errorf("GetKubeWorkloads ListPvcs error, error msg:%s", err)
@@ -254,6 +289,33 @@ func LoadKubeWorkloadsYaml(username string, params *LoadKubeWorkloadsYamlReq, fo
 					}
 					yamls = append(yamls, string(bs))
 				}
+			case "daemonset":
+				for _, workload := range workloads {
+					bs, _, err := getter.GetDaemonSetYamlFormat(params.Namespace, workload, kubeClient)
+					if len(bs) == 0 || err != nil {
+						log.Errorf("not found yaml %v", err)
+						return fmt.Errorf("get deploy/daemonset failed err:%s", err)
+					}
+					yamls = append(yamls, string(bs))
+				}
+			case "job":
+				for _, workload := range workloads {
+					bs, _, err := getter.GetJobYamlFormat(params.Namespace, workload, kubeClient)
+					if len(bs) == 0 || err != nil {
+						log.Errorf("not found yaml %v", err)
+						return fmt.Errorf("get deploy/job failed err:%s", err)
+					}
+					yamls = append(yamls, string(bs))
+				}
+			case "cronjob":
+				for _, workload := range workloads {
+					bs, _, err := getter.GetCronJobYamlFormat(params.Namespace, workload, kubeClient)
+					if len(bs) == 0 || err != nil {
+						log.Errorf("not found yaml %v", err)
+						return fmt.Errorf("get deploy/cronjob failed err:%s", err)
+					}
+					yamls = append(yamls, string(bs))
+				}
 			case "pvc":
 				for _, workload := range workloads {
 					bs, _, err := getter.GetPVCYamlFormat(params.Namespace, workload, kubeClient)
@@ -46,6 +46,7 @@ import (
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	fsservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	commomtemplate "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
@@ -98,6 +99,8 @@ type helmServiceCreationArgs struct {
 	RepoLink         string
 	Source           string
 	HelmTemplateName string
+	// TemplateRevision is the revision of the chart template the service is rendered from
+	TemplateRevision int64
 	ValuePaths       []string
 	ValuesYaml       string
 	Variables        []*Variable
@@ -546,6 +549,8 @@ func CreateOrUpdateHelmService(projectName string, args *HelmServiceCreationArgs
 		return CreateOrUpdateHelmServiceFromRepo(projectName, args, force, logger)
 	case LoadFromChartRepo:
 		return CreateOrUpdateHelmServiceFromChartRepo(projectName, args, force, logger)
+	case LoadFromUmbrellaChart:
+		return CreateOrUpdateHelmServicesFromUmbrellaChart(projectName, args, force, logger)
 	default:
 		return nil, fmt.Errorf("invalid source")
 	}
@@ -757,6 +762,7 @@ func createOrUpdateHelmServiceFromChartTemplate(templateArgs *CreateFromChartTem
 			RequestID:        args.RequestID,
 			Source:           setting.SourceFromChartTemplate,
 			HelmTemplateName: templateArgs.TemplateName,
+			TemplateRevision: templateChartInfo.TemplateData.Revision,
 			ValuesYaml:       templateArgs.ValuesYAML,
 			Variables:        templateArgs.Variables,
 			ValuesSource:     args.ValuesData,
@@ -1277,6 +1283,7 @@ func handleSingleService(projectName string, repoConfig *commonservice.RepoConfi
 			CodehostID:       repoConfig.CodehostID,
 			Source:           setting.SourceFromChartTemplate,
 			HelmTemplateName: templateChartData.TemplateName,
+			TemplateRevision: templateChartData.TemplateData.Revision,
 			ValuePaths:       []string{path},
 			ValuesYaml:       string(valuesYAML),
 			AutoSync:         args.AutoSync,
@@ -1396,10 +1403,11 @@ func geneCreationDetail(args *helmServiceCreationArgs) interface{} {
 			yamlData.SourceDetail = repoData
 		}
 		return &models.CreateFromChartTemplate{
-			YamlData:     yamlData,
-			TemplateName: args.HelmTemplateName,
-			ServiceName:  args.ServiceName,
-			Variables:    variables,
+			YamlData:         yamlData,
+			TemplateName:     args.HelmTemplateName,
+			ServiceName:      args.ServiceName,
+			Variables:        variables,
+			TemplateRevision: args.TemplateRevision,
 		}
 	case setting.SourceFromChartRepo:
 		return models.CreateFromChartRepo{
@@ -1421,7 +1429,7 @@ func renderVariablesToYaml(valuesYaml string, productName, serviceName string, v
 		valuesMap[variable.Key] = variable.Value
 	}
 
-	tmpl, err := template.New("values").Parse(valuesYaml)
+	tmpl, err := template.New("values").Funcs(commomtemplate.FuncMap()).Parse(valuesYaml)
 	if err != nil {
 		log.Errorf("failed to parse template, err %s valuesYaml %s", err, valuesYaml)
 		return "", errors.Wrapf(err, "failed to parse template, err %s", err)
@@ -0,0 +1,529 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/27149chen/afero"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	fsservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	yamlutil "github.com/koderover/zadig/pkg/util/yaml"
+)
+
+const (
+	umbrellaSubChartDir = "charts"
+	umbrellaGlobalKey   = "global"
+)
+
+type umbrellaChartDependency struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+}
+
+type umbrellaChartMeta struct {
+	Name         string                     `json:"name"`
+	Dependencies []*umbrellaChartDependency `json:"dependencies,omitempty"`
+}
+
+// umbrellaSubChart is a chart found in the umbrella chart, Files are keyed by their path relative to the chart root
+type umbrellaSubChart struct {
+	// ServiceName is the alias of the subchart in the umbrella chart, or the chart name if it has no alias
+	ServiceName string
+	Files       map[string][]byte
+}
+
+func CreateOrUpdateHelmServicesFromUmbrellaChart(projectName string, args *HelmServiceCreationArgs, force bool, logger *zap.SugaredLogger) (*BulkHelmServiceCreationResponse, error) {
+	umbrellaArgs, ok := args.CreateFrom.(*CreateFromUmbrellaChart)
+	if !ok {
+		return nil, e.ErrCreateTemplate.AddDesc("invalid argument")
+	}
+
+	response, services, err := importUmbrellaChart(projectName, umbrellaArgs, nil, args.CreatedBy, args.RequestID, force, logger)
+	if err != nil {
+		return nil, err
+	}
+	return response, service.AutoDeployHelmServiceToEnvs(args.CreatedBy, args.RequestID, projectName, services, logger)
+}
+
+// ResyncUmbrellaChartServices reloads all the services imported from umbrella charts in the project, services whose
+// subchart has been removed from the umbrella chart are reported as failed and are kept unchanged.
+func ResyncUmbrellaChartServices(projectName, userName, requestID string, logger *zap.SugaredLogger) (*BulkHelmServiceCreationResponse, error) {
+	services, err := commonrepo.NewServiceColl().ListMaxRevisionsByProduct(projectName)
+	if err != nil {
+		logger.Errorf("Failed to list services of project %s, err: %s", projectName, err)
+		return nil, e.ErrUpdateTemplate.AddErr(err)
+	}
+
+	umbrellas := make(map[string]*CreateFromUmbrellaChart)
+	subCharts := make(map[string][]string)
+	for _, svc := range services {
+		if svc.Source != setting.SourceFromUmbrellaChart {
+			continue
+		}
+		createFrom, err := getCreateFromUmbrellaChart(svc.CreateFrom)
+		if err != nil || createFrom.GitRepoConfig == nil {
+			logger.Warnf("Failed to get creation detail of service %s, err: %v", svc.ServiceName, err)
+			continue
+		}
+		umbrella := &CreateFromUmbrellaChart{
+			CodehostID: createFrom.GitRepoConfig.CodehostID,
+			Owner:      createFrom.GitRepoConfig.Owner,
+			Namespace:  createFrom.GitRepoConfig.Namespace,
+			Repo:       createFrom.GitRepoConfig.Repo,
+			Branch:     createFrom.GitRepoConfig.Branch,
+			Path:       createFrom.LoadPath,
+		}
+		key := fmt.Sprintf("%d/%s/%s/%s/%s", umbrella.CodehostID, umbrella.Namespace, umbrella.Repo, umbrella.Branch, umbrella.Path)
+		umbrellas[key] = umbrella
+		subCharts[key] = append(subCharts[key], svc.ServiceName)
+	}
+
+	response := &BulkHelmServiceCreationResponse{
+		SuccessServices: make([]string, 0),
+		FailedServices:  make([]*FailedService, 0),
+	}
+	updated := make([]*commonmodels.Service, 0)
+	for key, umbrella := range umbrellas {
+		resp, svcs, err := importUmbrellaChart(projectName, umbrella, subCharts[key], userName, requestID, true, logger)
+		if err != nil {
+			response.FailedServices = append(response.FailedServices, &FailedService{
+				Path:  umbrella.Path,
+				Error: err.Error(),
+			})
+			continue
+		}
+		response.SuccessServices = append(response.SuccessServices, resp.SuccessServices...)
+		response.FailedServices = append(response.FailedServices, resp.FailedServices...)
+		updated = append(updated, svcs...)
+	}
+
+	return response, service.AutoDeployHelmServiceToEnvs(userName, requestID, projectName, updated, logger)
+}
+
+func getCreateFromUmbrellaChart(createFrom interface{}) (*models.CreateFromUmbrellaChart, error) {
+	bs, err := json.Marshal(createFrom)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(models.CreateFromUmbrellaChart)
+	err = json.Unmarshal(bs, ret)
+	return ret, err
+}
+
+// importUmbrellaChart creates or updates a service for every subchart of the umbrella chart, if only is not empty,
+// only the listed services are imported.
+func importUmbrellaChart(projectName string, args *CreateFromUmbrellaChart, only []string, userName, requestID string, force bool, logger *zap.SugaredLogger) (*BulkHelmServiceCreationResponse, []*commonmodels.Service, error) {
+	filePath := strings.TrimLeft(args.Path, "/")
+	var chartTree afero.Fs
+	_, err := fsservice.DownloadFilesFromSource(
+		&fsservice.DownloadFromSourceArgs{CodehostID: args.CodehostID, Owner: args.Owner, Namespace: args.Namespace, Repo: args.Repo, Path: filePath, Branch: args.Branch},
+		func(tree afero.Fs) (string, error) {
+			chartTree = tree
+			return "", nil
+		})
+	if err != nil {
+		logger.Errorf("Failed to download files from source, err %s", err)
+		return nil, nil, e.ErrCreateTemplate.AddErr(err)
+	}
+
+	subCharts, err := findUmbrellaSubCharts(afero.NewIOFS(chartTree))
+	if err != nil {
+		logger.Errorf("Failed to find subcharts under path %s, err: %s", filePath, err)
+		return nil, nil, e.ErrCreateTemplate.AddErr(err)
+	}
+	if len(subCharts) == 0 {
+		return nil, nil, e.ErrCreateTemplate.AddDesc(fmt.Sprintf("no chart found under path %s", filePath))
+	}
+
+	response := &BulkHelmServiceCreationResponse{
+		SuccessServices: make([]string, 0),
+		FailedServices:  make([]*FailedService, 0),
+	}
+	found := make(map[string]*umbrellaSubChart, len(subCharts))
+	for _, subChart := range subCharts {
+		found[subChart.ServiceName] = subChart
+	}
+	if len(only) > 0 {
+		subCharts = make([]*umbrellaSubChart, 0, len(only))
+		for _, name := range only {
+			subChart, ok := found[name]
+			if !ok {
+				response.FailedServices = append(response.FailedServices, &FailedService{
+					Path:  filePath,
+					Error: fmt.Sprintf("subchart of service %s is removed from the umbrella chart", name),
+				})
+				continue
+			}
+			subCharts = append(subCharts, subChart)
+		}
+	}
+
+	helmRenderCharts := make([]*templatemodels.RenderChart, 0, len(subCharts))
+	services := make([]*commonmodels.Service, 0, len(subCharts))
+	for _, subChart := range subCharts {
+		svc, err := importUmbrellaSubChart(projectName, filePath, args, subChart, userName, requestID, force, logger)
+		if err != nil {
+			response.FailedServices = append(response.FailedServices, &FailedService{
+				Path:  path.Join(filePath, subChart.ServiceName),
+				Error: err.Error(),
+			})
+			continue
+		}
+		response.SuccessServices = append(response.SuccessServices, svc.ServiceName)
+		services = append(services, svc)
+		helmRenderCharts = append(helmRenderCharts, &templatemodels.RenderChart{
+			ServiceName:  svc.ServiceName,
+			ChartVersion: svc.HelmChart.Version,
+			ValuesYaml:   svc.HelmChart.ValuesYaml,
+		})
+	}
+
+	compareHelmVariable(helmRenderCharts, projectName, userName, logger)
+	return response, services, nil
+}
+
+func importUmbrellaSubChart(projectName, filePath string, args *CreateFromUmbrellaChart, subChart *umbrellaSubChart, userName, requestID string, force bool, logger *zap.SugaredLogger) (svc *commonmodels.Service, err error) {
+	serviceName := subChart.ServiceName
+	fsTree := afero.NewMemMapFs()
+	for name, content := range subChart.Files {
+		if err = afero.WriteFile(fsTree, filepath.Join(serviceName, name), content, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	rev, err := getNextServiceRevision(projectName, serviceName)
+	if err != nil {
+		logger.Errorf("Failed to get next revision for service %s, err: %s", serviceName, err)
+		return nil, e.ErrCreateTemplate.AddErr(err)
+	}
+
+	// clear files from both s3 and local when error occurred in next stages
+	defer func() {
+		if err != nil {
+			clearChartFiles(projectName, serviceName, rev, logger)
+		}
+	}()
+
+	if err = commonservice.SaveAndUploadService(projectName, serviceName, []string{fmt.Sprintf("%s-%d", serviceName, rev)}, afero.NewIOFS(fsTree)); err != nil {
+		logger.Errorf("Failed to save or upload files for service %s in project %s, error: %s", serviceName, projectName, err)
+		return nil, e.ErrCreateTemplate.AddErr(err)
+	}
+
+	if err = copyChartRevision(projectName, serviceName, rev); err != nil {
+		logger.Errorf("Failed to copy file %s, err: %s", serviceName, err)
+		return nil, errors.Wrapf(err, "Failed to copy chart info, service %s", serviceName)
+	}
+
+	svc, err = createOrUpdateHelmService(
+		afero.NewIOFS(fsTree),
+		&helmServiceCreationArgs{
+			ServiceRevision: rev,
+			MergedValues:    string(subChart.Files[setting.ValuesYaml]),
+			ServiceName:     serviceName,
+			FilePath:        filePath,
+			ProductName:     projectName,
+			CreateBy:        userName,
+			RequestID:       requestID,
+			CodehostID:      args.CodehostID,
+			Owner:           args.Owner,
+			Namespace:       args.Namespace,
+			Repo:            args.Repo,
+			Branch:          args.Branch,
+			Source:          setting.SourceFromUmbrellaChart,
+			CreationDetail: &models.CreateFromUmbrellaChart{
+				GitRepoConfig: &templatemodels.GitRepoConfig{
+					CodehostID: args.CodehostID,
+					Owner:      args.Owner,
+					Namespace:  args.Namespace,
+					Repo:       args.Repo,
+					Branch:     args.Branch,
+				},
+				LoadPath: filePath,
+				SubChart: subChart.ServiceName,
+			},
+		}, force,
+		logger,
+	)
+	if err != nil {
+		logger.Errorf("Failed to create service %s in project %s, error: %s", serviceName, projectName, err)
+		return nil, e.ErrCreateTemplate.AddErr(err)
+	}
+	return svc, nil
+}
+
+// findUmbrellaSubCharts returns the subcharts in charts/ if the root of the tree is a chart, the values of the umbrella
+// chart are split into the subcharts. Otherwise, every chart in the tree is returned as it is.
+func findUmbrellaSubCharts(tree fs.FS) ([]*umbrellaSubChart, error) {
+	entries, err := fs.ReadDir(tree, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil, fmt.Errorf("invalid chart tree")
+	}
+	root := entries[0].Name()
+
+	if _, err = fs.Stat(tree, path.Join(root, setting.ChartYaml)); err != nil {
+		return findChartsInTree(tree, root)
+	}
+
+	files, err := readChartFiles(tree, root)
+	if err != nil {
+		return nil, err
+	}
+	return splitUmbrellaChart(files)
+}
+
+func findChartsInTree(tree fs.FS, root string) ([]*umbrellaSubChart, error) {
+	ret := make([]*umbrellaSubChart, 0)
+	err := fs.WalkDir(tree, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if _, err := fs.Stat(tree, path.Join(p, setting.ChartYaml)); err != nil {
+			return nil
+		}
+
+		files, err := readChartFiles(tree, p)
+		if err != nil {
+			return err
+		}
+		meta, err := parseUmbrellaChartMeta(files)
+		if err != nil {
+			return errors.Wrapf(err, "invalid chart under %s", p)
+		}
+		ret = append(ret, &umbrellaSubChart{ServiceName: meta.Name, Files: files})
+		// the charts in the charts/ dir of a chart are part of it
+		return fs.SkipDir
+	})
+	return ret, err
+}
+
+// splitUmbrellaChart picks the subcharts declared as dependencies of the umbrella chart, the values under the name
+// of a subchart in the umbrella values and the global values are merged into the default values of the subchart.
+func splitUmbrellaChart(files map[string][]byte) ([]*umbrellaSubChart, error) {
+	meta, err := parseUmbrellaChartMeta(files)
+	if err != nil {
+		return nil, err
+	}
+	parentValues := make(map[string]interface{})
+	if err = yaml.Unmarshal(files[setting.ValuesYaml], &parentValues); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s of the umbrella chart", setting.ValuesYaml)
+	}
+
+	charts, err := collectSubCharts(files)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := meta.Dependencies
+	if len(dependencies) == 0 {
+		for _, name := range sortedChartNames(charts) {
+			dependencies = append(dependencies, &umbrellaChartDependency{Name: name})
+		}
+	}
+
+	ret := make([]*umbrellaSubChart, 0, len(dependencies))
+	for _, dep := range dependencies {
+		chartFiles, ok := charts[dep.Name]
+		if !ok {
+			return nil, fmt.Errorf("subchart %s is not found in %s/, run helm dependency update first", dep.Name, umbrellaSubChartDir)
+		}
+		key := dep.Name
+		if dep.Alias != "" {
+			key = dep.Alias
+		}
+
+		values, err := mergeSubChartValues(chartFiles[setting.ValuesYaml], parentValues, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to split values of subchart %s", key)
+		}
+		subFiles := make(map[string][]byte, len(chartFiles))
+		for name, content := range chartFiles {
+			subFiles[name] = content
+		}
+		subFiles[setting.ValuesYaml] = values
+		ret = append(ret, &umbrellaSubChart{ServiceName: key, Files: subFiles})
+	}
+	return ret, nil
+}
+
+func mergeSubChartValues(subValues []byte, parentValues map[string]interface{}, key string) ([]byte, error) {
+	layers := [][]byte{subValues}
+	if global, ok := parentValues[umbrellaGlobalKey]; ok {
+		bs, err := yaml.Marshal(map[string]interface{}{umbrellaGlobalKey: global})
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, bs)
+	}
+	if override, ok := parentValues[key]; ok {
+		bs, err := yaml.Marshal(override)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, bs)
+	}
+	return yamlutil.Merge(layers)
+}
+
+// collectSubCharts returns the files of the charts in the charts/ dir keyed by chart name, both unpacked charts
+// and packed .tgz charts are supported.
+func collectSubCharts(files map[string][]byte) (map[string]map[string][]byte, error) {
+	dirs := make(map[string]map[string][]byte)
+	packed := make([][]byte, 0)
+	for name, content := range files {
+		parts := strings.SplitN(name, "/", 3)
+		if len(parts) < 2 || parts[0] != umbrellaSubChartDir {
+			continue
+		}
+		if len(parts) == 2 {
+			if strings.HasSuffix(parts[1], ".tgz") {
+				packed = append(packed, content)
+			}
+			continue
+		}
+		if dirs[parts[1]] == nil {
+			dirs[parts[1]] = make(map[string][]byte)
+		}
+		dirs[parts[1]][parts[2]] = content
+	}
+
+	ret := make(map[string]map[string][]byte)
+	for _, chartFiles := range dirs {
+		if _, ok := chartFiles[setting.ChartYaml]; !ok {
+			continue
+		}
+		meta, err := parseUmbrellaChartMeta(chartFiles)
+		if err != nil {
+			return nil, err
+		}
+		ret[meta.Name] = chartFiles
+	}
+	for _, content := range packed {
+		chartFiles, err := extractChartArchive(content)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := parseUmbrellaChartMeta(chartFiles)
+		if err != nil {
+			return nil, err
+		}
+		ret[meta.Name] = chartFiles
+	}
+	return ret, nil
+}
+
+// extractChartArchive unpacks a chart packed by helm package, the top dir of the archive is stripped
+func extractChartArchive(content []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	ret := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		parts := strings.SplitN(path.Clean(hdr.Name), "/", 2)
+		if len(parts) != 2 || strings.HasPrefix(parts[1], "../") {
+			continue
+		}
+		bs, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		ret[parts[1]] = bs
+	}
+	return ret, nil
+}
+
+func readChartFiles(tree fs.FS, base string) (map[string][]byte, error) {
+	ret := make(map[string][]byte)
+	err := fs.WalkDir(tree, base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(tree, p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		ret[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	return ret, err
+}
+
+func parseUmbrellaChartMeta(files map[string][]byte) (*umbrellaChartMeta, error) {
+	content, ok := files[setting.ChartYaml]
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", setting.ChartYaml)
+	}
+	meta := new(umbrellaChartMeta)
+	if err := yaml.Unmarshal(content, meta); err != nil {
+		return nil, err
+	}
+	if meta.Name == "" {
+		return nil, fmt.Errorf("chart name is empty")
+	}
+	return meta, nil
+}
+
+func sortedChartNames(charts map[string]map[string][]byte) []string {
+	names := make([]string, 0, len(charts))
+	for name := range charts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -183,7 +183,7 @@ func preloadGerritService(detail *systemconfig.CodeHost, repoName, branchName, r
 func preloadCodehubService(detail *systemconfig.CodeHost, repoName, repoUUID, branchName, path string, isDir bool) ([]string, error) {
 	var ret []string
 
-	codeHubClient := codehub.NewCodeHubClient(detail.AccessKey, detail.SecretKey, detail.Region, config.ProxyHTTPSAddr(), detail.EnableProxy)
+	codeHubClient := codehub.NewCodeHubClient(detail.AccessKey, detail.SecretKey, detail.Region, detail.ProxyAddr(config.ProxyHTTPSAddr()), detail.UseProxy())
 	// 非文件夹情况下直接获取文件信息
 	if !isDir {
 		if !isYaml(path) {
@@ -311,7 +311,7 @@ func loadGerritService(username string, ch *systemconfig.CodeHost, repoOwner, re
 		}
 	}
 
-	gerritCli := gerrit.NewClient(ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	gerritCli := gerrit.NewClient(ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 	commit, err := gerritCli.GetCommitByBranch(repoName, branchName)
 	if err != nil {
 		log.Errorf("Failed to get latest commit info from repo: %s, the error is: %+v", repoName, err)
@@ -443,7 +443,7 @@ func loadServiceFromGerrit(tree []os.FileInfo, id int, username, branchName, loa
 
 // load codehub service
 func loadCodehubService(username string, ch *systemconfig.CodeHost, repoOwner, repoName, repoUUID, branchName string, args *LoadServiceReq, force bool, log *zap.SugaredLogger) error {
-	codeHubClient := codehub.NewCodeHubClient(ch.AccessKey, ch.SecretKey, ch.Region, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	codeHubClient := codehub.NewCodeHubClient(ch.AccessKey, ch.SecretKey, ch.Region, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 
 	if !args.LoadFromDir {
 		yamls, err := codeHubClient.GetYAMLContents(repoUUID, branchName, args.LoadPath, args.LoadFromDir, true)
@@ -581,7 +581,7 @@ func loadGiteeService(username string, ch *systemconfig.CodeHost, repoOwner, rep
 		}
 	}
 
-	giteeCli := gitee.NewClient(ch.ID, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	giteeCli := gitee.NewClient(ch.ID, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 	branch, err := giteeCli.GetSingleBranch(ch.AccessToken, repoOwner, repoName, branchName)
 	if err != nil {
 		log.Errorf("Failed to get latest commit info from repo: %s, the error is: %s", repoName, err)
@@ -852,7 +852,7 @@ func validateServiceUpdateGerrit(detail *systemconfig.CodeHost, serviceName, rep
 }
 
 func validateServiceUpdateCodehub(detail *systemconfig.CodeHost, serviceName, repoName, repoUUID, branchName, loadPath string, isDir bool) error {
-	codeHubClient := codehub.NewCodeHubClient(detail.AccessKey, detail.SecretKey, detail.Region, config.ProxyHTTPSAddr(), detail.EnableProxy)
+	codeHubClient := codehub.NewCodeHubClient(detail.AccessKey, detail.SecretKey, detail.Region, detail.ProxyAddr(config.ProxyHTTPSAddr()), detail.UseProxy())
 	// 非文件夹情况下直接获取文件信息
 	if !isDir {
 		if !isYaml(loadPath) {
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/manifestlint"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type YamlLintReq struct {
+	ProjectName string `json:"project_name"`
+	ServiceName string `json:"service_name"`
+	// Yaml is the yaml to lint, the yaml of the service template is linted if it is empty.
+	Yaml          string                     `json:"yaml"`
+	Variables     []*templatemodels.RenderKV `json:"variables"`
+	KubeVersion   string                     `json:"kube_version"`
+	DisabledRules []string                   `json:"disabled_rules"`
+}
+
+type YamlLintResp struct {
+	KubeVersion string                              `json:"kube_version"`
+	Findings    []*commonmodels.ManifestLintFinding `json:"findings"`
+}
+
+func ListManifestLintRules() []*manifestlint.Rule {
+	return manifestlint.Rules()
+}
+
+// LintYaml lints the yaml rendered with the variables, the rules disabled by the project are skipped
+// unless the rules to disable are given.
+func LintYaml(args *YamlLintReq, log *zap.SugaredLogger) (*YamlLintResp, error) {
+	content := args.Yaml
+	if content == "" {
+		svcTmpl, err := commonrepo.NewServiceColl().Find(&commonrepo.ServiceFindOption{
+			ServiceName:   args.ServiceName,
+			ProductName:   args.ProjectName,
+			ExcludeStatus: setting.ProductStatusDeleting,
+		})
+		if err != nil {
+			log.Errorf("failed to find service %s of project %s, err: %s", args.ServiceName, args.ProjectName, err)
+			return nil, e.ErrLintManifests.AddErr(err)
+		}
+		content = svcTmpl.Yaml
+	}
+
+	disabledRules := args.DisabledRules
+	if disabledRules == nil && args.ProjectName != "" {
+		project, err := templaterepo.NewProductColl().Find(args.ProjectName)
+		if err != nil {
+			log.Errorf("failed to find project %s, err: %s", args.ProjectName, err)
+			return nil, e.ErrLintManifests.AddErr(err)
+		}
+		if project.ManifestLint != nil {
+			disabledRules = project.ManifestLint.DisabledRules
+		}
+	}
+
+	content = commonservice.RenderValueForString(content, &commonmodels.RenderSet{KVs: args.Variables})
+	content = config.ServiceNameAlias.ReplaceAllLiteralString(content, args.ServiceName)
+	findings := manifestlint.Lint(util.SplitManifests(content), &manifestlint.Options{
+		DisabledRules: disabledRules,
+		KubeVersion:   args.KubeVersion,
+	})
+	return &YamlLintResp{KubeVersion: args.KubeVersion, Findings: findings}, nil
+}
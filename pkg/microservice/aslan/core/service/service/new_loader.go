@@ -240,9 +240,9 @@ type yamlLoader interface {
 func getLoader(ch *systemconfig.CodeHost) (yamlLoader, error) {
 	switch ch.Type {
 	case setting.SourceFromGithub:
-		return githubservice.NewClient(ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy), nil
+		return githubservice.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy()), nil
 	case setting.SourceFromGitlab:
-		return gitlabservice.NewClient(ch.ID, ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+		return gitlabservice.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 	default:
 		// should not have happened here
 		log.DPanicf("invalid source: %s", ch.Type)
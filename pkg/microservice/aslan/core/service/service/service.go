@@ -356,6 +356,12 @@ func CreateK8sWorkLoads(ctx context.Context, requestID, userName string, args *K
 				bs, _, err = getter.GetDeploymentYaml(args.Namespace, tempWorkload.Name, kubeClient)
 			case setting.StatefulSet:
 				bs, _, err = getter.GetStatefulSetYaml(args.Namespace, tempWorkload.Name, kubeClient)
+			case setting.DaemonSet:
+				bs, _, err = getter.GetDaemonSetYaml(args.Namespace, tempWorkload.Name, kubeClient)
+			case setting.Job:
+				bs, _, err = getter.GetJobYaml(args.Namespace, tempWorkload.Name, kubeClient)
+			case setting.CronJob:
+				bs, _, err = getter.GetCronJobYaml(args.Namespace, tempWorkload.Name, kubeClient)
 			}
 
 			if len(bs) == 0 || err != nil {
@@ -550,6 +556,12 @@ func UpdateWorkloads(ctx context.Context, requestID, username, productName, envN
 				bs, _, err = getter.GetDeploymentYaml(args.Namespace, v.Name, kubeClient)
 			case setting.StatefulSet:
 				bs, _, err = getter.GetStatefulSetYaml(args.Namespace, v.Name, kubeClient)
+			case setting.DaemonSet:
+				bs, _, err = getter.GetDaemonSetYaml(args.Namespace, v.Name, kubeClient)
+			case setting.Job:
+				bs, _, err = getter.GetJobYaml(args.Namespace, v.Name, kubeClient)
+			case setting.CronJob:
+				bs, _, err = getter.GetCronJobYaml(args.Namespace, v.Name, kubeClient)
 			}
 			if len(bs) == 0 || err != nil {
 				log.Errorf("UpdateK8sWorkLoads not found yaml %s", err)
@@ -760,6 +772,14 @@ func CreateServiceTemplate(userName string, args *commonmodels.Service, force bo
 		}
 	}
 
+	// the status mappings are kept when the yaml of the service is updated.
+	if notFoundErr == nil && args.ResourceStatusMappings == nil {
+		args.ResourceStatusMappings = serviceTmpl.ResourceStatusMappings
+	}
+	if err := kube.ValidateResourceStatusMappings(args.ResourceStatusMappings); err != nil {
+		return nil, e.ErrValidateTemplate.AddDesc(err.Error())
+	}
+
 	// 校验args
 	if err := ensureServiceTmpl(userName, args, log); err != nil {
 		log.Errorf("ensureServiceTmpl error: %+v", err)
@@ -792,6 +812,7 @@ func CreateServiceTemplate(userName string, args *commonmodels.Service, force bo
 		}
 	}
 	commonservice.ProcessServiceWebhook(args, serviceTmpl, args.ServiceName, log)
+	service.PreRenderService(args, log)
 
 	err = service.AutoDeployYamlServiceToEnvs(userName, "", args, log)
 	if err != nil {
@@ -870,6 +891,33 @@ func UpdateServiceVisibility(args *commonservice.ServiceTmplObject) error {
 	return commonrepo.NewServiceColl().Update(updateArgs)
 }
 
+// UpdateResourceStatusMappings updates the status mappings of the custom resources in the latest revision of the service.
+func UpdateResourceStatusMappings(serviceName, serviceType, productName string, mappings []*commonmodels.ResourceStatusMapping, log *zap.SugaredLogger) error {
+	if serviceType != setting.K8SDeployType {
+		return e.ErrInvalidParam.AddDesc("resource status mappings are only supported by k8s services")
+	}
+	if err := kube.ValidateResourceStatusMappings(mappings); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+
+	svc, err := commonrepo.NewServiceColl().Find(&commonrepo.ServiceFindOption{
+		ServiceName:   serviceName,
+		Type:          serviceType,
+		ProductName:   productName,
+		ExcludeStatus: setting.ProductStatusDeleting,
+	})
+	if err != nil {
+		log.Errorf("Failed to find service %s in project %s, err: %s", serviceName, productName, err)
+		return e.ErrUpdateService.AddErr(err)
+	}
+
+	if err := commonrepo.NewServiceColl().UpdateResourceStatusMappings(svc.ServiceName, svc.ProductName, svc.Revision, mappings); err != nil {
+		log.Errorf("Failed to update resource status mappings of service %s, err: %s", serviceName, err)
+		return e.ErrUpdateService.AddErr(err)
+	}
+	return nil
+}
+
 func UpdateServiceHealthCheckStatus(args *commonservice.ServiceTmplObject) error {
 	currentService, err := commonrepo.NewServiceColl().Find(&commonrepo.ServiceFindOption{
 		ProductName: args.ProductName,
@@ -1446,7 +1494,7 @@ func setCurrentContainerImages(args *commonmodels.Service) error {
 				return errors.New("nil ReourceKind")
 			}
 
-			if resKind.Kind == setting.Deployment || resKind.Kind == setting.StatefulSet || resKind.Kind == setting.Job {
+			if resKind.Kind == setting.Deployment || resKind.Kind == setting.StatefulSet || resKind.Kind == setting.DaemonSet || resKind.Kind == setting.Job {
 				containers, err := getContainers(yamlData)
 				if err != nil {
 					return fmt.Errorf("GetContainers error: %v", err)
@@ -1629,7 +1677,7 @@ func syncGerritLatestCommit(service *commonmodels.Service) (*systemconfig.CodeHo
 	}
 	ch, _ := systemconfig.New().GetCodeHost(service.GerritCodeHostID)
 
-	gerritCli := gerrit.NewClient(ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	gerritCli := gerrit.NewClient(ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 	commit, err := gerritCli.GetCommitByBranch(service.GerritRepoName, service.GerritBranchName)
 	if err != nil {
 		return nil, err
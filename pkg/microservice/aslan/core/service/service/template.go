@@ -146,7 +146,7 @@ func PreviewServiceFromYamlTemplate(req *LoadServiceFromYamlTemplateReq, logger
 
 func renderYamlFromTemplate(originYaml, productName, serviceName string, variables []*Variable, variableYamls ...string) (string, error) {
 
-	tmpl, err := gotemplate.New(serviceName).Parse(originYaml)
+	tmpl, err := gotemplate.New(serviceName).Funcs(commomtemplate.FuncMap()).Parse(originYaml)
 	if err != nil {
 		return originYaml, fmt.Errorf("failed to build template, err: %s", err)
 	}
@@ -264,6 +264,38 @@ func syncServicesFromChartTemplate(userName, templateName string, logger *zap.Su
 	return nil
 }
 
+// RerenderServicesFromChartTemplate re-renders the services from the latest revision of the chart
+// template even if auto sync is disabled, all the services using the template are re-rendered if
+// no service is specified.
+func RerenderServicesFromChartTemplate(templateName string, services []*commomtemplate.ServiceReference, logger *zap.SugaredLogger) ([]*commomtemplate.RerenderResult, error) {
+	chartTemplate, err := prepareChartTemplateData(templateName, logger)
+	if err != nil {
+		return nil, err
+	}
+	serviceList, err := commonrepo.NewServiceColl().ListMaxRevisionServicesByChartTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(services))
+	for _, svc := range services {
+		selected[svc.ProjectName+"/"+svc.ServiceName] = true
+	}
+
+	resp := make([]*commomtemplate.RerenderResult, 0)
+	for _, service := range serviceList {
+		if len(selected) > 0 && !selected[service.ProductName+"/"+service.ServiceName] {
+			continue
+		}
+		result := &commomtemplate.RerenderResult{ProjectName: service.ProductName, Name: service.ServiceName}
+		if err := reloadServiceFromChartTemplate(service, chartTemplate); err != nil {
+			logger.Errorf("failed to reload service %s/%s from chart template, err: %s", service.ProductName, service.ServiceName, err)
+			result.Error = err.Error()
+		}
+		resp = append(resp, result)
+	}
+	return resp, nil
+}
+
 func reloadServiceFromChartTemplate(service *commonmodels.Service, chartTemplate *ChartTemplateData) error {
 	variable, customYaml, err := buildChartTemplateVariables(service, chartTemplate.TemplateData)
 	if err != nil {
@@ -398,6 +430,7 @@ func buildChartTemplateVariables(service *commonmodels.Service, template *common
 			})
 		}
 		creation.Variables = vbs
+		creation.TemplateRevision = template.Revision
 		service.CreateFrom = creation
 	}
 	return variables, customYaml, nil
@@ -32,6 +32,7 @@ const (
 	LoadFromPublicRepo    LoadSource = "publicRepo"
 	LoadFromChartTemplate LoadSource = "chartTemplate"
 	LoadFromChartRepo     LoadSource = "chartRepo"
+	LoadFromUmbrellaChart LoadSource = "umbrellaChart"
 )
 
 type HelmLoadSource struct {
@@ -94,6 +95,17 @@ type CreateFromChartRepo struct {
 	ChartVersion  string `json:"chartVersion"`
 }
 
+// CreateFromUmbrellaChart creates a service for every subchart of the umbrella chart under Path, or for every
+// chart in the tree under Path if it is not a chart.
+type CreateFromUmbrellaChart struct {
+	CodehostID int    `json:"codehostID"`
+	Owner      string `json:"owner"`
+	Namespace  string `json:"namespace"`
+	Repo       string `json:"repo"`
+	Branch     string `json:"branch"`
+	Path       string `json:"path"`
+}
+
 func PublicRepoToPrivateRepoArgs(args *CreateFromPublicRepo) (*CreateFromRepo, error) {
 	if args.RepoLink == "" {
 		return nil, fmt.Errorf("empty link")
@@ -125,6 +137,8 @@ func (a *HelmServiceCreationArgs) UnmarshalJSON(data []byte) error {
 		a.CreateFrom = &CreateFromChartTemplate{}
 	case LoadFromChartRepo:
 		a.CreateFrom = &CreateFromChartRepo{}
+	case LoadFromUmbrellaChart:
+		a.CreateFrom = &CreateFromUmbrellaChart{}
 	}
 
 	type tmp HelmServiceCreationArgs
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/share/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type shareLinkArgs struct {
+	// ExpireHours is the lifetime of the link, it is valid for 24 hours by default.
+	ExpireHours int `json:"expire_hours"`
+}
+
+func CreateWorkflowTaskShareLink(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	args := new(shareLinkArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	projectName := c.Query("projectName")
+	workflowName := c.Param("workflowName")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "分享", "自定义工作流任务", fmt.Sprintf("%s#%d", workflowName, taskID), "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.CreateWorkflowTaskShareLink(configbase.ExternalBaseURLFromRequest(c.Request), projectName, workflowName, taskID, args.ExpireHours, ctx.UserName, ctx.Logger)
+}
+
+func CreateEnvironmentShareLink(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(shareLinkArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	projectName := c.Query("projectName")
+	envName := c.Param("name")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectName, "分享", "环境", envName, "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.CreateEnvironmentShareLink(configbase.ExternalBaseURLFromRequest(c.Request), projectName, envName, args.ExpireHours, ctx.UserName, ctx.Logger)
+}
+
+// GetSharedResource is called without logging in, the access is granted by the share link.
+func GetSharedResource(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.GetSharedResource(c.Param("token"), ctx.Logger)
+}
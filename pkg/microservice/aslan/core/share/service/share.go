@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	environmentservice "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type ShareKind string
+
+const (
+	ShareKindWorkflowTask ShareKind = "workflow_task"
+	ShareKindEnvironment  ShareKind = "environment"
+)
+
+const (
+	defaultShareLinkTTL = 24 * time.Hour
+	maxShareLinkTTL     = 30 * 24 * time.Hour
+)
+
+// ShareClaims is signed into the share link, the link grants the read access to a single task run or
+// environment until it expires, no matter whether the holder is a user of zadig.
+type ShareClaims struct {
+	Kind         ShareKind `json:"kind"`
+	ProjectName  string    `json:"project_name"`
+	WorkflowName string    `json:"workflow_name,omitempty"`
+	TaskID       int64     `json:"task_id,omitempty"`
+	EnvName      string    `json:"env_name,omitempty"`
+	jwt.StandardClaims
+}
+
+type ShareLink struct {
+	Token     string `json:"token"`
+	URL       string `json:"url"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type SharedResource struct {
+	Kind         ShareKind                     `json:"kind"`
+	ProjectName  string                        `json:"project_name"`
+	SharedBy     string                        `json:"shared_by"`
+	ExpiresAt    int64                         `json:"expires_at"`
+	WorkflowTask *workflow.WorkflowTaskPreview `json:"workflow_task,omitempty"`
+	Environment  *SharedEnvironment            `json:"environment,omitempty"`
+}
+
+type SharedEnvironment struct {
+	EnvName    string                       `json:"env_name"`
+	UpdateTime int64                        `json:"update_time"`
+	UpdateBy   string                       `json:"update_by"`
+	Services   []*commonservice.ServiceResp `json:"services"`
+}
+
+// CreateWorkflowTaskShareLink signs a link to the task run, the permission to view the workflow is
+// checked by the policy before.
+func CreateWorkflowTaskShareLink(baseURL, projectName, workflowName string, taskID int64, expireHours int, userName string, logger *zap.SugaredLogger) (*ShareLink, error) {
+	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	if err != nil {
+		logger.Errorf("failed to find task %s #%d, err: %s", workflowName, taskID, err)
+		return nil, e.ErrCreateShareLink.AddErr(err)
+	}
+	// the policy is evaluated with the project in the query, do not share the tasks of other projects.
+	if task.ProjectName != projectName {
+		return nil, e.ErrCreateShareLink.AddDesc(fmt.Sprintf("task %s #%d is not in project %s", workflowName, taskID, projectName))
+	}
+	return signShareLink(&ShareClaims{
+		Kind:         ShareKindWorkflowTask,
+		ProjectName:  projectName,
+		WorkflowName: workflowName,
+		TaskID:       taskID,
+	}, baseURL, expireHours, userName)
+}
+
+// CreateEnvironmentShareLink signs a link to the environment, the permission to view the environment is
+// checked by the policy before.
+func CreateEnvironmentShareLink(baseURL, projectName, envName string, expireHours int, userName string, logger *zap.SugaredLogger) (*ShareLink, error) {
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName}); err != nil {
+		logger.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrCreateShareLink.AddErr(err)
+	}
+	return signShareLink(&ShareClaims{
+		Kind:        ShareKindEnvironment,
+		ProjectName: projectName,
+		EnvName:     envName,
+	}, baseURL, expireHours, userName)
+}
+
+// GetSharedResource is called without logging in, the access is granted by the share link.
+func GetSharedResource(token string, logger *zap.SugaredLogger) (*SharedResource, error) {
+	claims, err := parseShareLink(token)
+	if err != nil {
+		return nil, e.ErrUnauthorized.AddDesc(fmt.Sprintf("invalid share link: %s", err))
+	}
+
+	resp := &SharedResource{
+		Kind:        claims.Kind,
+		ProjectName: claims.ProjectName,
+		SharedBy:    claims.Subject,
+		ExpiresAt:   claims.ExpiresAt,
+	}
+	switch claims.Kind {
+	case ShareKindWorkflowTask:
+		task, err := workflow.GetWorkflowTaskV4(claims.WorkflowName, claims.TaskID, logger)
+		if err != nil {
+			return nil, e.ErrGetSharedResource.AddErr(err)
+		}
+		// the parameters and the job specs may contain the credentials, the viewers follow the progress only.
+		task.Params = nil
+		for _, stage := range task.Stages {
+			for _, job := range stage.Jobs {
+				job.Spec = nil
+			}
+		}
+		resp.WorkflowTask = task
+	case ShareKindEnvironment:
+		env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: claims.ProjectName, EnvName: claims.EnvName})
+		if err != nil {
+			return nil, e.ErrGetSharedResource.AddErr(err)
+		}
+		services, _, err := environmentservice.ListGroups("", claims.EnvName, claims.ProjectName, 0, 0, logger)
+		if err != nil {
+			return nil, e.ErrGetSharedResource.AddErr(err)
+		}
+		for _, svc := range services {
+			svc.EnvConfigs = nil
+		}
+		resp.Environment = &SharedEnvironment{
+			EnvName:    env.EnvName,
+			UpdateTime: env.UpdateTime,
+			UpdateBy:   env.UpdateBy,
+			Services:   services,
+		}
+	default:
+		return nil, e.ErrUnauthorized.AddDesc(fmt.Sprintf("invalid share link: unknown kind %s", claims.Kind))
+	}
+	return resp, nil
+}
+
+// signShareLink signs the claims into a link under the base url the user is visiting.
+func signShareLink(claims *ShareClaims, baseURL string, expireHours int, userName string) (*ShareLink, error) {
+	ttl := time.Duration(expireHours) * time.Hour
+	if ttl <= 0 {
+		ttl = defaultShareLinkTTL
+	}
+	if ttl > maxShareLinkTTL {
+		return nil, e.ErrCreateShareLink.AddDesc(fmt.Sprintf("the share link can not be valid for more than %d hours", int(maxShareLinkTTL.Hours())))
+	}
+
+	now := time.Now()
+	claims.Subject = userName
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(shareLinkKey())
+	if err != nil {
+		return nil, e.ErrCreateShareLink.AddErr(err)
+	}
+	return &ShareLink{
+		Token:     token,
+		URL:       fmt.Sprintf("%s/v1/share/%s", baseURL, token),
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+func parseShareLink(token string) (*ShareClaims, error) {
+	claims := &ShareClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return shareLinkKey(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// shareLinkKey is derived from the secret key, so that the share links can not be used as the login
+// tokens, which are signed by the secret key itself.
+func shareLinkKey() []byte {
+	hash := sha256.Sum256([]byte("share-link:" + configbase.SecretKey()))
+	return hash[:]
+}
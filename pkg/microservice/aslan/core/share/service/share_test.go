@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+func TestShareLink(t *testing.T) {
+	viper.Set(setting.ENVSecretKey, "secret")
+	defer viper.Set(setting.ENVSecretKey, "")
+
+	link, err := signShareLink(&ShareClaims{Kind: ShareKindEnvironment, ProjectName: "demo", EnvName: "dev"}, "https://zadig.example.com/ci", 0, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://zadig.example.com/ci/v1/share/"+link.Token, link.URL)
+	assert.InDelta(t, time.Now().Add(defaultShareLinkTTL).Unix(), link.ExpiresAt, 5)
+
+	claims, err := parseShareLink(link.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, ShareKindEnvironment, claims.Kind)
+	assert.Equal(t, "dev", claims.EnvName)
+	assert.Equal(t, "admin", claims.Subject)
+
+	_, err = signShareLink(&ShareClaims{Kind: ShareKindEnvironment}, "https://zadig.example.com", int(maxShareLinkTTL.Hours())+1, "admin")
+	assert.Error(t, err)
+
+	// the login tokens are signed by the secret key itself.
+	loginToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"kind": "environment"}).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	_, err = parseShareLink(loginToken)
+	assert.Error(t, err)
+
+	expired := &ShareClaims{Kind: ShareKindEnvironment}
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	expiredToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, expired).SignedString(shareLinkKey())
+	assert.NoError(t, err)
+	_, err = parseShareLink(expiredToken)
+	assert.Error(t, err)
+}
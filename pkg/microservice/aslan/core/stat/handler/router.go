@@ -29,6 +29,12 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		dashboard.GET("/build", GetBuildStat)
 		dashboard.GET("/deploy", GetDeployStat)
 		dashboard.GET("/test", GetTestDashboard)
+		dashboard.GET("/label", GetWorkflowStatByLabel)
+		dashboard.GET("/customField", GetWorkflowStatByCustomField)
+		dashboard.GET("/health", GetProjectHealth)
+		dashboard.GET("/health/digests", ListHealthDigests)
+		dashboard.GET("/health/digestPolicy", GetHealthDigestPolicy)
+		dashboard.PUT("/health/digestPolicy", UpdateHealthDigestPolicy)
 	}
 
 	quality := router.Group("quality")
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// GetWorkflowStatByCustomField groups the tasks of the workflows by the values of the custom field, the workflows
+// are grouped by the values of their projects if it is a field of the projects. The workflows without a value are
+// not counted.
+func GetWorkflowStatByCustomField(key, projectName string, startDate, endDate int64, log *zap.SugaredLogger) ([]*LabelWorkflowStat, error) {
+	field, err := commonrepo.NewCustomFieldColl().FindByKey(key)
+	if err != nil {
+		log.Errorf("Failed to find custom field %s, err:%s", key, err)
+		return nil, e.ErrInvalidParam.AddDesc("custom field not found")
+	}
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		log.Errorf("Failed to list workflows, err:%s", err)
+		return nil, err
+	}
+
+	projectValues := make(map[string]string)
+	workflowsByValue := make(map[string]sets.String)
+	for _, workflow := range workflows {
+		var value string
+		switch field.Scope {
+		case config.CustomFieldScopeWorkflow:
+			value = workflow.CustomFields[key]
+		case config.CustomFieldScopeProject:
+			projectValue, ok := projectValues[workflow.Project]
+			if !ok {
+				project, err := templaterepo.NewProductColl().Find(workflow.Project)
+				if err != nil {
+					log.Warnf("Failed to find project %s, err:%s", workflow.Project, err)
+				} else {
+					projectValue = project.CustomFields[key]
+				}
+				projectValues[workflow.Project] = projectValue
+			}
+			value = projectValue
+		}
+		if value == "" {
+			continue
+		}
+		if _, ok := workflowsByValue[value]; !ok {
+			workflowsByValue[value] = sets.NewString()
+		}
+		workflowsByValue[value].Insert(workflow.Name)
+	}
+
+	return statWorkflowsByValue(projectName, workflowsByValue, startDate, endDate, log)
+}
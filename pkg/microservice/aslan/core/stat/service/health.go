@@ -0,0 +1,461 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	environmentservice "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	healthPeriod         = 7 * 24 * time.Hour
+	healthDigestInterval = time.Hour
+	// healthDeployDaysTarget is the number of days with deployments in a week scored as 100.
+	healthDeployDaysTarget = 5
+	// healthFindingPenalty is the score taken off by an open security finding.
+	healthFindingPenalty = 20
+	// healthCoverageMetric is the metric reported by the job summaries, e.g. {"name": "coverage", "value": "82.5", "unit": "%"}.
+	healthCoverageMetric = "coverage"
+)
+
+var healthWeights = map[config.HealthComponent]int{
+	config.HealthComponentBuildSuccess:     30,
+	config.HealthComponentDeployFrequency:  20,
+	config.HealthComponentTestCoverage:     20,
+	config.HealthComponentSecurityFindings: 15,
+	config.HealthComponentEnvDrift:         15,
+}
+
+var healthComponentNames = map[config.HealthComponent]string{
+	config.HealthComponentBuildSuccess:     "构建成功率",
+	config.HealthComponentDeployFrequency:  "部署频率",
+	config.HealthComponentTestCoverage:     "测试覆盖率",
+	config.HealthComponentSecurityFindings: "安全问题",
+	config.HealthComponentEnvDrift:         "环境漂移",
+}
+
+var deployJobTypes = sets.NewString(string(config.JobZadigDeploy), string(config.JobZadigHelmDeploy), string(config.JobCustomDeploy))
+
+// GetProjectHealth scores the project by the workflow tasks and the environments of the last week.
+func GetProjectHealth(projectName string, log *zap.SugaredLogger) (*commonmodels.ProjectHealth, error) {
+	health, err := computeProjectHealth(projectName, time.Now(), log)
+	if err != nil {
+		log.Errorf("failed to compute the health of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetProjectHealth.AddErr(err)
+	}
+	return health, nil
+}
+
+func computeProjectHealth(projectName string, now time.Time, log *zap.SugaredLogger) (*commonmodels.ProjectHealth, error) {
+	end := now.Unix()
+	start := now.Add(-healthPeriod).Unix()
+	// the tasks of the week before are compared with for the coverage trend
+	tasks, _, err := commonrepo.NewworkflowTaskv4Coll().List(&commonrepo.ListWorkflowTaskV4Option{
+		ProjectName: projectName,
+		CreateTime:  now.Add(-2 * healthPeriod).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow tasks: %s", err)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreateTime < tasks[j].CreateTime })
+
+	current := make([]*commonmodels.WorkflowTask, 0)
+	for _, task := range tasks {
+		if task.CreateTime >= start {
+			current = append(current, task)
+		}
+	}
+
+	components := []*commonmodels.HealthComponent{
+		scoreBuildSuccess(projectName, current),
+		scoreDeployFrequency(projectName, current, tasks),
+		scoreTestCoverage(tasks, start),
+		scoreSecurityFindings(tasks),
+	}
+	drift, err := scoreEnvDrift(projectName, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environment revisions: %s", err)
+	}
+	components = append(components, drift)
+
+	health := &commonmodels.ProjectHealth{
+		ProjectName: projectName,
+		StartTime:   start,
+		EndTime:     end,
+		Components:  components,
+	}
+	weights, sum := 0, 0
+	for _, component := range components {
+		component.Weight = healthWeights[component.Name]
+		if !component.Available {
+			continue
+		}
+		weights += component.Weight
+		sum += component.Weight * component.Score
+	}
+	if weights > 0 {
+		health.Score = int(math.Round(float64(sum) / float64(weights)))
+	}
+	return health, nil
+}
+
+func scoreBuildSuccess(projectName string, tasks []*commonmodels.WorkflowTask) *commonmodels.HealthComponent {
+	component := &commonmodels.HealthComponent{
+		Name: config.HealthComponentBuildSuccess,
+		URL:  fmt.Sprintf("%s/v1/projects/detail/%s/pipelines", configbase.ExternalBaseURL(), projectName),
+	}
+	total, passed := 0, 0
+	for _, task := range tasks {
+		for _, stage := range task.Stages {
+			for _, job := range stage.Jobs {
+				if job.JobType != string(config.JobZadigBuild) {
+					continue
+				}
+				switch job.Status {
+				case config.StatusPassed:
+					passed++
+					total++
+				case config.StatusFailed, config.StatusTimeout:
+					total++
+				}
+			}
+		}
+	}
+	if total == 0 {
+		component.Detail = "无构建"
+		return component
+	}
+	component.Available = true
+	component.Value = float64(passed) * 100 / float64(total)
+	component.Score = int(math.Round(component.Value))
+	component.Detail = fmt.Sprintf("%d/%d 次构建成功", passed, total)
+	return component
+}
+
+// scoreDeployFrequency scores by the days with successful deployments, the projects never deploying by the
+// workflows in the two weeks are not scored.
+func scoreDeployFrequency(projectName string, current, all []*commonmodels.WorkflowTask) *commonmodels.HealthComponent {
+	component := &commonmodels.HealthComponent{
+		Name: config.HealthComponentDeployFrequency,
+		URL:  fmt.Sprintf("%s/v1/projects/detail/%s/pipelines", configbase.ExternalBaseURL(), projectName),
+	}
+	for _, task := range all {
+		if hasDeployJob(task) {
+			component.Available = true
+			break
+		}
+	}
+	if !component.Available {
+		component.Detail = "无部署"
+		return component
+	}
+
+	deploys := 0
+	days := sets.NewString()
+	for _, task := range current {
+		for _, stage := range task.Stages {
+			for _, job := range stage.Jobs {
+				if deployJobTypes.Has(job.JobType) && job.Status == config.StatusPassed {
+					deploys++
+					days.Insert(time.Unix(job.EndTime, 0).Format("2006-01-02"))
+				}
+			}
+		}
+	}
+	component.Value = float64(deploys)
+	component.Score = int(math.Round(math.Min(float64(days.Len())/healthDeployDaysTarget, 1) * 100))
+	component.Detail = fmt.Sprintf("%d 天共 %d 次部署", days.Len(), deploys)
+	return component
+}
+
+func hasDeployJob(task *commonmodels.WorkflowTask) bool {
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if deployJobTypes.Has(job.JobType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scoreTestCoverage scores by the latest coverage reported by the job summaries, the drop from the week before
+// is taken off twice.
+func scoreTestCoverage(tasks []*commonmodels.WorkflowTask, start int64) *commonmodels.HealthComponent {
+	component := &commonmodels.HealthComponent{Name: config.HealthComponentTestCoverage}
+	var latest, previous *float64
+	for _, task := range tasks {
+		coverage, ok := taskCoverage(task)
+		if !ok {
+			continue
+		}
+		if task.CreateTime >= start {
+			latest = &coverage
+			component.URL = taskURL(task)
+		} else {
+			previous = &coverage
+		}
+	}
+	if latest == nil {
+		component.Detail = "无覆盖率数据"
+		return component
+	}
+
+	component.Available = true
+	component.Value = *latest
+	score := *latest
+	component.Detail = fmt.Sprintf("%.1f%%", *latest)
+	if previous != nil {
+		component.Detail = fmt.Sprintf("%.1f%%（上周 %.1f%%）", *latest, *previous)
+		if *previous > *latest {
+			score -= 2 * (*previous - *latest)
+		}
+	}
+	component.Score = int(math.Round(math.Max(math.Min(score, 100), 0)))
+	return component
+}
+
+func taskCoverage(task *commonmodels.WorkflowTask) (float64, bool) {
+	for _, summary := range task.JobSummaries() {
+		for _, metric := range summary.Metrics {
+			if !strings.EqualFold(metric.Name, healthCoverageMetric) {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(metric.Value), "%"), 64)
+			if err == nil {
+				return value, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// scoreSecurityFindings counts the secrets found by the latest task of every workflow, the findings of the
+// earlier tasks are regarded as fixed.
+func scoreSecurityFindings(tasks []*commonmodels.WorkflowTask) *commonmodels.HealthComponent {
+	component := &commonmodels.HealthComponent{Name: config.HealthComponentSecurityFindings}
+	latest := make(map[string]*commonmodels.WorkflowTask)
+	for _, task := range tasks {
+		latest[task.WorkflowName] = task
+	}
+	if len(latest) == 0 {
+		component.Detail = "无工作流任务"
+		return component
+	}
+
+	component.Available = true
+	findings, most := 0, 0
+	for _, task := range latest {
+		count := 0
+		for _, stage := range task.Stages {
+			for _, job := range stage.Jobs {
+				count += len(job.SecretFindings)
+			}
+		}
+		findings += count
+		if count > most {
+			most = count
+			component.URL = taskURL(task)
+		}
+	}
+	component.Value = float64(findings)
+	component.Score = int(math.Max(float64(100-healthFindingPenalty*findings), 0))
+	component.Detail = fmt.Sprintf("%d 个未修复问题", findings)
+	return component
+}
+
+// scoreEnvDrift scores by the environments not updated to the latest services of the project.
+func scoreEnvDrift(projectName string, log *zap.SugaredLogger) (*commonmodels.HealthComponent, error) {
+	component := &commonmodels.HealthComponent{
+		Name: config.HealthComponentEnvDrift,
+		URL:  fmt.Sprintf("%s/v1/projects/detail/%s/envs", configbase.ExternalBaseURL(), projectName),
+	}
+	revisions, err := environmentservice.ListProductsRevision(projectName, "", log)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		component.Detail = "无环境"
+		return component, nil
+	}
+
+	drifted := make([]string, 0)
+	for _, revision := range revisions {
+		if revision.Updatable {
+			drifted = append(drifted, revision.EnvName)
+		}
+	}
+	sort.Strings(drifted)
+	component.Available = true
+	component.Value = float64(len(drifted))
+	component.Score = int(math.Round(float64(len(revisions)-len(drifted)) * 100 / float64(len(revisions))))
+	component.Detail = fmt.Sprintf("%d/%d 个环境落后于服务配置", len(drifted), len(revisions))
+	if len(drifted) > 0 {
+		component.Detail += fmt.Sprintf("：%s", strings.Join(drifted, ", "))
+		component.URL = fmt.Sprintf("%s/v1/projects/detail/%s/envs/detail?envName=%s", configbase.ExternalBaseURL(), projectName, drifted[0])
+	}
+	return component, nil
+}
+
+func taskURL(task *commonmodels.WorkflowTask) string {
+	return fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.ExternalBaseURL(), task.ProjectName, task.WorkflowName, task.TaskID)
+}
+
+func GetHealthDigestPolicy(projectName string, log *zap.SugaredLogger) (*commonmodels.HealthDigestPolicy, error) {
+	policy, err := commonrepo.NewHealthDigestPolicyColl().Find(projectName)
+	if err == mongo.ErrNoDocuments {
+		return &commonmodels.HealthDigestPolicy{ProjectName: projectName, Weekday: int(time.Monday), Hour: 9}, nil
+	}
+	if err != nil {
+		log.Errorf("failed to find health digest policy of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetHealthDigestPolicy.AddErr(err)
+	}
+	return policy, nil
+}
+
+func UpdateHealthDigestPolicy(username string, policy *commonmodels.HealthDigestPolicy, log *zap.SugaredLogger) error {
+	if policy.Weekday < 0 || policy.Weekday > 6 {
+		return e.ErrUpdateHealthDigestPolicy.AddDesc("weekday must be between 0 and 6")
+	}
+	if policy.Hour < 0 || policy.Hour > 23 {
+		return e.ErrUpdateHealthDigestPolicy.AddDesc("hour must be between 0 and 23")
+	}
+	policy.UpdatedBy = username
+	if err := commonrepo.NewHealthDigestPolicyColl().Upsert(policy); err != nil {
+		log.Errorf("failed to update health digest policy of project %s, err: %s", policy.ProjectName, err)
+		return e.ErrUpdateHealthDigestPolicy.AddErr(err)
+	}
+	return nil
+}
+
+func ListHealthDigests(projectName string, log *zap.SugaredLogger) ([]*commonmodels.HealthDigest, error) {
+	digests, err := commonrepo.NewHealthDigestColl().List(projectName, 52)
+	if err != nil {
+		log.Errorf("failed to list health digests of project %s, err: %s", projectName, err)
+		return nil, e.ErrListHealthDigests.AddErr(err)
+	}
+	return digests, nil
+}
+
+// StartHealthDigest sends the weekly digests of the projects at the time configured by their policies.
+func StartHealthDigest(ctx context.Context, log *zap.SugaredLogger) {
+	ticker := time.NewTicker(healthDigestInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			policies, err := commonrepo.NewHealthDigestPolicyColl().ListEnabled()
+			if err != nil {
+				log.Errorf("failed to list health digest policies, err: %s", err)
+				continue
+			}
+			for _, policy := range policies {
+				if int(now.Weekday()) != policy.Weekday || now.Hour() != policy.Hour {
+					continue
+				}
+				if err := sendHealthDigest(policy, now, log); err != nil {
+					log.Errorf("failed to send health digest of project %s, err: %s", policy.ProjectName, err)
+				}
+			}
+		}
+	}
+}
+
+// sendHealthDigest records the digest of the week before sending it, so that it is sent once even if aslan
+// has several replicas. The record is removed if the digest fails to be sent and it is retried in the next hour.
+func sendHealthDigest(policy *commonmodels.HealthDigestPolicy, now time.Time, log *zap.SugaredLogger) error {
+	health, err := computeProjectHealth(policy.ProjectName, now, log)
+	if err != nil {
+		return err
+	}
+	var last *commonmodels.HealthDigest
+	if digests, err := commonrepo.NewHealthDigestColl().List(policy.ProjectName, 1); err == nil && len(digests) > 0 {
+		last = digests[0]
+	}
+
+	year, week := now.ISOWeek()
+	digest := &commonmodels.HealthDigest{
+		ProjectName: policy.ProjectName,
+		Week:        fmt.Sprintf("%d-W%02d", year, week),
+		Health:      health,
+	}
+	created, err := commonrepo.NewHealthDigestColl().Create(digest)
+	if err != nil || !created {
+		return err
+	}
+
+	title := fmt.Sprintf("项目 %s 健康周报：%d 分", policy.ProjectName, health.Score)
+	event := &instantmessage.NotificationEvent{
+		Type:        config.NotificationEventProjectDigest,
+		ProjectName: policy.ProjectName,
+		Severity:    config.NotificationSeverityInfo,
+		Title:       title,
+		Content:     healthDigestContent(title, health, last),
+	}
+	client := instantmessage.NewWeChatClient()
+	routed, err := client.RouteNotification(event, log)
+	if err == nil && !routed && policy.NotifyCtl != nil && policy.NotifyCtl.Enabled {
+		err = client.SendSystemMessage(policy.NotifyCtl, event.Title, event.Content)
+	}
+	if err != nil {
+		if delErr := commonrepo.NewHealthDigestColl().Delete(digest.ID); delErr != nil {
+			log.Errorf("failed to delete health digest of project %s, err: %s", policy.ProjectName, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func healthDigestContent(title string, health *commonmodels.ProjectHealth, last *commonmodels.HealthDigest) string {
+	content := fmt.Sprintf("### %s\n\n统计周期：%s ~ %s", title,
+		time.Unix(health.StartTime, 0).Format("2006-01-02"), time.Unix(health.EndTime, 0).Format("2006-01-02"))
+	if last != nil && last.Health != nil {
+		content += fmt.Sprintf("，上周 %d 分", last.Health.Score)
+	}
+	content += "\n\n"
+	for _, component := range health.Components {
+		score := "-"
+		if component.Available {
+			score = fmt.Sprintf("%d", component.Score)
+		}
+		line := fmt.Sprintf("- %s：%s 分，%s", healthComponentNames[component.Name], score, component.Detail)
+		if component.URL != "" {
+			line += fmt.Sprintf(" [查看](%s)", component.URL)
+		}
+		content += line + "\n"
+	}
+	return content
+}
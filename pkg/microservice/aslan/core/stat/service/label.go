@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	labelconfig "github.com/koderover/zadig/pkg/microservice/aslan/core/label/config"
+	labeldb "github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
+)
+
+type LabelWorkflowStat struct {
+	Value         string `json:"value"`
+	WorkflowCount int    `json:"workflow_count"`
+	TaskCount     int64  `json:"task_count"`
+	SuccessCount  int64  `json:"success_count"`
+	Duration      int64  `json:"duration"`
+}
+
+// GetWorkflowStatByLabel groups the tasks of the tagged workflows by the values of the label key,
+// e.g. key "team" sums up the tasks of every team.
+func GetWorkflowStatByLabel(key, projectName string, startDate, endDate int64, log *zap.SugaredLogger) ([]*LabelWorkflowStat, error) {
+	labels, err := labeldb.NewLabelColl().ListByKey(key)
+	if err != nil {
+		log.Errorf("Failed to list labels by key %s, err:%s", key, err)
+		return nil, err
+	}
+
+	workflowsByValue := make(map[string]sets.String)
+	for _, label := range labels {
+		bindings, err := labeldb.NewLabelBindingColl().ListByOpt(&labeldb.LabelBindingCollFindOpt{
+			LabelID:      label.ID.Hex(),
+			ResourceType: string(labelconfig.ResourceTypeCommonWorkflow),
+			ProjectName:  projectName,
+		})
+		if err != nil {
+			log.Errorf("Failed to list label bindings of %s:%s, err:%s", label.Key, label.Value, err)
+			return nil, err
+		}
+		if _, ok := workflowsByValue[label.Value]; !ok {
+			workflowsByValue[label.Value] = sets.NewString()
+		}
+		for _, binding := range bindings {
+			workflowsByValue[label.Value].Insert(binding.ResourceName)
+		}
+	}
+
+	return statWorkflowsByValue(projectName, workflowsByValue, startDate, endDate, log)
+}
+
+// statWorkflowsByValue sums up the tasks of the workflows grouped by a value, e.g. the value of a label.
+func statWorkflowsByValue(projectName string, workflowsByValue map[string]sets.String, startDate, endDate int64, log *zap.SugaredLogger) ([]*LabelWorkflowStat, error) {
+	resp := make([]*LabelWorkflowStat, 0)
+	allWorkflows := sets.NewString()
+	for _, workflows := range workflowsByValue {
+		allWorkflows.Insert(workflows.List()...)
+	}
+	stats, err := commonrepo.NewworkflowTaskv4Coll().StatByWorkflows(projectName, allWorkflows.List(), startDate, endDate)
+	if err != nil {
+		log.Errorf("Failed to stat workflow tasks, err:%s", err)
+		return nil, err
+	}
+	statMap := make(map[string]*commonrepo.WorkflowTaskStat)
+	for _, stat := range stats {
+		statMap[stat.WorkflowName] = stat
+	}
+
+	for value, workflows := range workflowsByValue {
+		labelStat := &LabelWorkflowStat{
+			Value:         value,
+			WorkflowCount: workflows.Len(),
+		}
+		for _, workflow := range workflows.List() {
+			if stat, ok := statMap[workflow]; ok {
+				labelStat.TaskCount += stat.TaskCount
+				labelStat.SuccessCount += stat.SuccessCount
+				labelStat.Duration += stat.Duration
+			}
+		}
+		resp = append(resp, labelStat)
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].Value < resp[j].Value })
+	return resp, nil
+}
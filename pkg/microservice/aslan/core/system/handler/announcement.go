@@ -17,6 +17,8 @@ limitations under the License.
 package handler
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 
 	systemmodel "github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/models"
@@ -73,3 +75,18 @@ func DeleteAnnouncement(c *gin.Context) {
 
 	ctx.Err = service.DeleteAnnouncement(ctx.UserName, ID, ctx.Logger)
 }
+
+func ListUserAnnouncements(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	unacknowledgedOnly, _ := strconv.ParseBool(c.Query("unacknowledged"))
+	ctx.Resp, ctx.Err = service.ListUserAnnouncements(ctx.UserID, unacknowledgedOnly, ctx.Logger)
+}
+
+func AcknowledgeAnnouncement(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Err = service.AcknowledgeAnnouncement(ctx.UserID, ctx.UserName, c.Param("id"), ctx.Logger)
+}
@@ -27,6 +27,7 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	tenantservice "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -36,7 +37,12 @@ func ListRegistries(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	ctx.Resp, ctx.Err = service.ListRegistries(ctx.Logger)
+	registries, err := service.ListRegistries(ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Resp, ctx.Err = filterRegistriesByTenant(registries, ctx)
 }
 
 func GetDefaultRegistryNamespace(c *gin.Context) {
@@ -98,7 +104,26 @@ func ListRegistryNamespaces(c *gin.Context) {
 		ctx.Err = e.ErrInvalidParam
 		return
 	}
-	ctx.Resp, ctx.Err = commonservice.ListRegistryNamespaces(encryptedKey, false, ctx.Logger)
+	registries, err := commonservice.ListRegistryNamespaces(encryptedKey, false, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Resp, ctx.Err = filterRegistriesByTenant(registries, ctx)
+}
+
+func filterRegistriesByTenant(registries []*commonmodels.RegistryNamespace, ctx *internalhandler.Context) ([]*commonmodels.RegistryNamespace, error) {
+	scope, err := tenantservice.GetScope(ctx.UserID, ctx.Logger)
+	if err != nil {
+		return nil, e.ErrListTenant.AddErr(err)
+	}
+	resp := make([]*commonmodels.RegistryNamespace, 0, len(registries))
+	for _, registry := range registries {
+		if scope.RegistryVisible(registry.ID.Hex()) {
+			resp = append(resp, registry)
+		}
+	}
+	return resp, nil
 }
 
 func CreateRegistryNamespace(c *gin.Context) {
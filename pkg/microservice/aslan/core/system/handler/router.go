@@ -130,6 +130,109 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		concurrency.POST("/workflow", UpdateWorkflowConcurrency)
 	}
 
+	// maintenance mode for platform upgrades
+	maintenance := router.Group("maintenance")
+	{
+		maintenance.GET("", GetMaintenance)
+		maintenance.PUT("", UpdateMaintenance)
+	}
+
+	// the minimum security profile of the job pods of all the projects
+	jobSecurity := router.Group("job-security")
+	{
+		jobSecurity.GET("", GetJobSecurity)
+		jobSecurity.PUT("", UpdateJobSecurity)
+	}
+
+	// declarative system configuration mounted into aslan
+	bootstrap := router.Group("bootstrap")
+	{
+		bootstrap.GET("", GetBootstrapResult)
+		bootstrap.POST("/reconcile", ReconcileBootstrapConfig)
+	}
+
+	// key provider of the stored secrets
+	encryption := router.Group("encryption")
+	{
+		encryption.GET("", GetEncryptionStatus)
+		encryption.POST("/rotate", RotateEncryptionKey)
+	}
+
+	// health and expiry of the codehost, registry and cluster credentials
+	credentialHealth := router.Group("credential/health")
+	{
+		credentialHealth.GET("", ListCredentialHealth)
+		credentialHealth.POST("/check", CheckCredentialHealth)
+	}
+
+	// the tasks which used the codehost tokens, registry credentials and project secrets
+	credentialUsage := router.Group("credential/usage")
+	{
+		credentialUsage.GET("", ListCredentialUsages)
+	}
+
+	// the workflows, environments, codehosts, registries and webhooks which are not used any more
+	staleResource := router.Group("stale-resources")
+	{
+		staleResource.GET("", ListStaleResources)
+		staleResource.POST("/archive", ArchiveStaleResources)
+	}
+
+	// the self-check of aslan and the reports of the other services, for troubleshooting the installation
+	router.GET("/diagnostics", GetDiagnostics)
+
+	// the resources referencing a codehost, they are checked before the codehost is deleted, and the
+	// IM channel the admins are alerted through when the token of a codehost expires
+	codehost := router.Group("codehost")
+	{
+		codehost.GET("/:id/references", ListCodeHostReferences)
+		codehost.GET("/notify", GetCodeHostNotify)
+		codehost.PUT("/notify", UpdateCodeHostNotify)
+	}
+
+	// the base urls zadig is accessed by through the reverse proxies, the links are generated with them
+	externalURL := router.Group("externalURL")
+	{
+		externalURL.GET("", GetExternalURL)
+		externalURL.PUT("", UpdateExternalURL)
+	}
+
+	// dns providers managing the records of the ingress hosts of the environments
+	dnsProvider := router.Group("dns/providers")
+	{
+		dnsProvider.GET("", ListDNSProviders)
+		dnsProvider.POST("", CreateDNSProvider)
+		dnsProvider.PUT("/:id", UpdateDNSProvider)
+		dnsProvider.DELETE("/:id", DeleteDNSProvider)
+	}
+
+	// personal settings of the current user, e.g. the language of the messages
+	userSetting := router.Group("user/setting")
+	{
+		userSetting.GET("", GetUserSetting)
+		userSetting.PUT("", UpdateUserSetting)
+		userSetting.PUT("/notification", UpdateNotificationPreference)
+	}
+
+	// organization-specific metadata fields of the projects and the workflows, they are listed by everyone to
+	// fill in the values
+	customField := router.Group("custom-fields")
+	{
+		customField.GET("", ListCustomFields)
+		customField.POST("", CreateCustomField)
+		customField.PUT("/:id", UpdateCustomField)
+		customField.DELETE("/:id", DeleteCustomField)
+	}
+
+	// routes of the notifications of the tasks to the channels by the projects, envs, severities and tags
+	notificationRoute := router.Group("notification/routes")
+	{
+		notificationRoute.GET("", ListNotificationRoutes)
+		notificationRoute.POST("", CreateNotificationRoute)
+		notificationRoute.PUT("/:id", UpdateNotificationRoute)
+		notificationRoute.DELETE("/:id", DeleteNotificationRoute)
+	}
+
 	// default login default login home page settings
 	login := router.Group("login")
 	{
@@ -203,6 +306,13 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		announcement.DELETE("/:id", DeleteAnnouncement)
 	}
 
+	// the announcements in effect for the current user, they are polled by the frontend.
+	announcements := router.Group("announcements")
+	{
+		announcements.GET("", ListUserAnnouncements)
+		announcements.POST("/:id/ack", AcknowledgeAnnouncement)
+	}
+
 	operation := router.Group("operation")
 	{
 		operation.GET("", GetOperationLogs)
@@ -221,6 +331,17 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		externalLink.DELETE("/:id", DeleteExternalLink)
 	}
 
+	// ---------------------------------------------------------------------------------------
+	// job warm pool
+	// ---------------------------------------------------------------------------------------
+	warmPool := router.Group("warmPool")
+	{
+		warmPool.GET("", ListJobWarmPools)
+		warmPool.POST("", CreateJobWarmPool)
+		warmPool.PUT("/:id", UpdateJobWarmPool)
+		warmPool.DELETE("/:id", DeleteJobWarmPool)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// external system API
 	// ---------------------------------------------------------------------------------------
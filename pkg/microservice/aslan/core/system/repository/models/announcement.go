@@ -24,6 +24,9 @@ type Announcement struct {
 	Content    *Content           `bson:"content"                 json:"content"`      // 消息内容
 	CreateTime int64              `bson:"create_time"             json:"create_time"`  // 消息创建时间
 	IsRead     bool               `bson:"is_read"                 json:"is_read"`      // 是否已读
+	// Projects are the audience of the announcement, it is shown to the members of the projects only,
+	// or to all the users if it is empty.
+	Projects []string `bson:"projects,omitempty" json:"projects,omitempty"`
 }
 
 type Content struct {
@@ -32,6 +35,8 @@ type Content struct {
 	Content   string `bson:"content"               json:"content"`    // 公告内容
 	StartTime int64  `bson:"start_time"            json:"start_time"` // 公告开始时间
 	EndTime   int64  `bson:"end_time"              json:"end_time"`   // 公告结束时间
+	// Banner announcements are shown on the top of every page until they are acknowledged, e.g. a maintenance window.
+	Banner bool `bson:"banner" json:"banner"`
 }
 
 func (Announcement) TableName() string {
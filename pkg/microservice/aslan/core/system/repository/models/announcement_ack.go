@@ -0,0 +1,33 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AnnouncementAck records that a user has acknowledged an announcement, it is not shown as unread to the
+// user any more.
+type AnnouncementAck struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	AnnouncementID string             `bson:"announcement_id" json:"announcement_id"`
+	UserID         string             `bson:"user_id"         json:"user_id"`
+	UserName       string             `bson:"user_name"       json:"user_name"`
+	AckTime        int64              `bson:"ack_time"        json:"ack_time"`
+}
+
+func (AnnouncementAck) TableName() string {
+	return "announcement_ack"
+}
@@ -58,6 +58,16 @@ func (c *AnnouncementColl) Create(args *models2.Announcement) error {
 	return err
 }
 
+func (c *AnnouncementColl) Find(id string) (*models2.Announcement, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models2.Announcement)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
 func (c *AnnouncementColl) Update(id string, args *models2.Announcement) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type AnnouncementAckColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewAnnouncementAckColl() *AnnouncementAckColl {
+	name := models.AnnouncementAck{}.TableName()
+	return &AnnouncementAckColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *AnnouncementAckColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *AnnouncementAckColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "user_id", Value: 1},
+				bson.E{Key: "announcement_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"announcement_id": 1},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+// Ack records the acknowledgement of the user, acknowledging an announcement again keeps the first time.
+func (c *AnnouncementAckColl) Ack(announcementID, userID, userName string) error {
+	query := bson.M{"announcement_id": announcementID, "user_id": userID}
+	change := bson.M{"$setOnInsert": bson.M{
+		"user_name": userName,
+		"ack_time":  time.Now().Unix(),
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// ListAcked returns the ids of the announcements acknowledged by the user among the given ones.
+func (c *AnnouncementAckColl) ListAcked(userID string, announcementIDs []string) (map[string]bool, error) {
+	resp := make(map[string]bool)
+	if len(announcementIDs) == 0 {
+		return resp, nil
+	}
+
+	var acks []*models.AnnouncementAck
+	query := bson.M{"user_id": userID, "announcement_id": bson.M{"$in": announcementIDs}}
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &acks); err != nil {
+		return nil, err
+	}
+	for _, ack := range acks {
+		resp[ack.AnnouncementID] = true
+	}
+	return resp, nil
+}
+
+func (c *AnnouncementAckColl) DeleteByAnnouncement(announcementID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"announcement_id": announcementID})
+	return err
+}
@@ -18,13 +18,37 @@ package service
 
 import (
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	systemmodel "github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/models"
 	systemrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/mongodb"
+	policyrepo "github.com/koderover/zadig/pkg/microservice/policy/core/repository/mongodb"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
+// UserAnnouncement is an announcement shown to the user, the acknowledged ones are not shown as unread.
+type UserAnnouncement struct {
+	*systemmodel.Announcement
+	Acknowledged bool `json:"acknowledged"`
+}
+
+func validateAnnouncement(args *systemmodel.Announcement) error {
+	if args.Content == nil {
+		return e.ErrInvalidParam.AddDesc("the content of the announcement is required")
+	}
+	if args.Content.EndTime <= args.Content.StartTime {
+		return e.ErrInvalidParam.AddDesc("the announcement must end after it starts")
+	}
+	return nil
+}
+
 func CreateAnnouncement(creater string, ctx *systemmodel.Announcement, log *zap.SugaredLogger) error {
+	if err := validateAnnouncement(ctx); err != nil {
+		return err
+	}
+	if ctx.Receiver == "" {
+		ctx.Receiver = "*"
+	}
 	err := systemrepo.NewAnnouncementColl().Create(ctx)
 	if err != nil {
 		log.Errorf("create announcement failed, creater: %s, error: %s", creater, err)
@@ -35,6 +59,9 @@ func CreateAnnouncement(creater string, ctx *systemmodel.Announcement, log *zap.
 }
 
 func UpdateAnnouncement(user string, notifyID string, ctx *systemmodel.Announcement, log *zap.SugaredLogger) error {
+	if err := validateAnnouncement(ctx); err != nil {
+		return err
+	}
 	err := systemrepo.NewAnnouncementColl().Update(notifyID, ctx)
 	if err != nil {
 		log.Errorf("create announcement failed, user: %s, error: %s", user, err)
@@ -69,6 +96,78 @@ func DeleteAnnouncement(user, id string, log *zap.SugaredLogger) error {
 	err := systemrepo.NewAnnouncementColl().DeleteAnnouncement(&systemrepo.AnnouncementDeleteArgs{ID: id})
 	if err != nil {
 		log.Errorf("Delete Announcement failed, user: %s, error: %s", user, err)
+		return err
+	}
+	if err := systemrepo.NewAnnouncementAckColl().DeleteByAnnouncement(id); err != nil {
+		log.Warnf("failed to delete the acknowledgements of announcement %s, error: %s", id, err)
+	}
+	return nil
+}
+
+// ListUserAnnouncements returns the announcements in effect for the user, the ones targeting the projects
+// are shown to the members of the projects only. The frontend polls it to show the banners and the popups.
+func ListUserAnnouncements(userID string, unacknowledgedOnly bool, log *zap.SugaredLogger) ([]*UserAnnouncement, error) {
+	announcements, err := systemrepo.NewAnnouncementColl().ListValidAnnouncements("*")
+	if err != nil {
+		log.Errorf("list announcement failed, user: %s, error: %s", userID, err)
+		return nil, e.ErrListUserAnnouncements.AddErr(err)
+	}
+	projects, allProjects, err := userProjects(userID)
+	if err != nil {
+		log.Errorf("failed to list the projects of user %s, error: %s", userID, err)
+		return nil, e.ErrListUserAnnouncements.AddErr(err)
+	}
+
+	visible := make([]*systemmodel.Announcement, 0, len(announcements))
+	ids := make([]string, 0, len(announcements))
+	for _, announcement := range announcements {
+		if len(announcement.Projects) > 0 && !allProjects && !projects.HasAny(announcement.Projects...) {
+			continue
+		}
+		visible = append(visible, announcement)
+		ids = append(ids, announcement.ID.Hex())
+	}
+	acked, err := systemrepo.NewAnnouncementAckColl().ListAcked(userID, ids)
+	if err != nil {
+		log.Errorf("failed to list the acknowledgements of user %s, error: %s", userID, err)
+		return nil, e.ErrListUserAnnouncements.AddErr(err)
+	}
+
+	resp := make([]*UserAnnouncement, 0, len(visible))
+	for _, announcement := range visible {
+		acknowledged := acked[announcement.ID.Hex()]
+		if unacknowledgedOnly && acknowledged {
+			continue
+		}
+		resp = append(resp, &UserAnnouncement{Announcement: announcement, Acknowledged: acknowledged})
+	}
+	return resp, nil
+}
+
+func AcknowledgeAnnouncement(userID, userName, id string, log *zap.SugaredLogger) error {
+	if _, err := systemrepo.NewAnnouncementColl().Find(id); err != nil {
+		return e.ErrAcknowledgeAnnouncement.AddErr(err)
+	}
+	if err := systemrepo.NewAnnouncementAckColl().Ack(id, userID, userName); err != nil {
+		log.Errorf("failed to acknowledge announcement %s, user: %s, error: %s", id, userName, err)
+		return e.ErrAcknowledgeAnnouncement.AddErr(err)
+	}
+	return nil
+}
+
+// userProjects returns the projects the user is bound to, including the public ones. allProjects is true if the
+// user is bound to all the projects, e.g. the system admins.
+func userProjects(userID string) (sets.String, bool, error) {
+	roleBindings, err := policyrepo.NewRoleBindingColl().ListRoleBindingsByUIDs([]string{userID, "*"})
+	if err != nil {
+		return nil, false, err
+	}
+	projects := sets.NewString()
+	for _, rb := range roleBindings {
+		if rb.Namespace == "*" {
+			return nil, true, nil
+		}
+		projects.Insert(rb.Namespace)
 	}
-	return err
+	return projects, false, nil
 }
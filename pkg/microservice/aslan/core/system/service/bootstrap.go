@@ -0,0 +1,354 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	multiclusterservice "github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
+	codehostmodels "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	codehostrepo "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	codehostservice "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/service"
+	connectorservice "github.com/koderover/zadig/pkg/microservice/systemconfig/core/connector/service"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/repository/orm"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	BootstrapKindCodeHost  = "codehost"
+	BootstrapKindRegistry  = "registry"
+	BootstrapKindCluster   = "cluster"
+	BootstrapKindStorage   = "storage"
+	BootstrapKindConnector = "connector"
+
+	BootstrapActionCreated = "created"
+	BootstrapActionUpdated = "updated"
+	BootstrapActionFailed  = "failed"
+)
+
+// BootstrapConfig is the declarative system configuration mounted into aslan. The specs use the same
+// fields as the corresponding APIs, and any value can be read from a key of a kubernetes secret
+// instead of being written in plain text, e.g.
+//
+//	access_token:
+//	  secretKeyRef:
+//	    name: codehost-secrets
+//	    key: github-token
+//
+// Items are matched with the existing ones by codehost alias, registry address and namespace, cluster
+// name, storage endpoint and bucket, and connector id. Items missing in the file are never deleted.
+type BootstrapConfig struct {
+	CodeHosts  []*codehostmodels.CodeHost        `json:"codehosts"`
+	Registries []*commonmodels.RegistryNamespace `json:"registries"`
+	Clusters   []*multiclusterservice.K8SCluster `json:"clusters"`
+	Storages   []*commonmodels.S3Storage         `json:"storages"`
+	Connectors []*connectorservice.Connector     `json:"connectors"`
+}
+
+type BootstrapResult struct {
+	File      string           `json:"file"`
+	StartTime int64            `json:"start_time"`
+	EndTime   int64            `json:"end_time"`
+	Items     []*BootstrapItem `json:"items"`
+}
+
+type BootstrapItem struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+type secretKeySelector struct {
+	Name      string `yaml:"name"`
+	Key       string `yaml:"key"`
+	Namespace string `yaml:"namespace"`
+}
+
+var (
+	bootstrapLock       sync.Mutex
+	bootstrapResultLock sync.RWMutex
+	bootstrapResult     *BootstrapResult
+)
+
+// InitBootstrapConfig reconciles the bootstrap file when aslan starts, nothing is done if the file is not mounted.
+func InitBootstrapConfig() {
+	file := config.BootstrapConfigFile()
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return
+	}
+
+	resp, err := ReconcileBootstrapConfig(log.SugaredLogger())
+	if err != nil {
+		log.Errorf("failed to reconcile system bootstrap file %s, err: %s", file, err)
+		return
+	}
+	for _, item := range resp.Items {
+		if item.Action == BootstrapActionFailed {
+			log.Errorf("failed to reconcile %s %s from the system bootstrap file, err: %s", item.Kind, item.Name, item.Error)
+		}
+	}
+}
+
+// GetBootstrapResult returns the result of the last reconciliation done by this aslan instance.
+func GetBootstrapResult() (*BootstrapResult, error) {
+	bootstrapResultLock.RLock()
+	defer bootstrapResultLock.RUnlock()
+
+	if bootstrapResult == nil {
+		return nil, e.ErrLoadSystemBootstrap.AddDesc("system bootstrap file has not been reconciled")
+	}
+	return bootstrapResult, nil
+}
+
+// ReconcileBootstrapConfig creates or updates the system configuration declared in the bootstrap file,
+// a failed item does not stop the others and is reported in the result.
+func ReconcileBootstrapConfig(logger *zap.SugaredLogger) (*BootstrapResult, error) {
+	bootstrapLock.Lock()
+	defer bootstrapLock.Unlock()
+
+	file := config.BootstrapConfigFile()
+	content, err := os.ReadFile(file)
+	if err != nil {
+		logger.Errorf("failed to read system bootstrap file %s, err: %s", file, err)
+		return nil, e.ErrLoadSystemBootstrap.AddErr(err)
+	}
+	bootstrapConfig, err := loadBootstrapConfig(content, krkubeclient.Client())
+	if err != nil {
+		logger.Errorf("failed to load system bootstrap file %s, err: %s", file, err)
+		return nil, e.ErrLoadSystemBootstrap.AddErr(err)
+	}
+
+	resp := &BootstrapResult{File: file, StartTime: time.Now().Unix(), Items: make([]*BootstrapItem, 0)}
+	addItem := func(kind, name, action string, err error) {
+		item := &BootstrapItem{Kind: kind, Name: name, Action: action}
+		if err != nil {
+			item.Action = BootstrapActionFailed
+			item.Error = err.Error()
+		}
+		resp.Items = append(resp.Items, item)
+	}
+
+	for _, codehost := range bootstrapConfig.CodeHosts {
+		action, err := reconcileBootstrapCodeHost(codehost, logger)
+		addItem(BootstrapKindCodeHost, codehost.Alias, action, err)
+	}
+	for _, registry := range bootstrapConfig.Registries {
+		action, err := reconcileBootstrapRegistry(registry, logger)
+		addItem(BootstrapKindRegistry, fmt.Sprintf("%s/%s", registry.RegAddr, registry.Namespace), action, err)
+	}
+	for _, cluster := range bootstrapConfig.Clusters {
+		action, err := reconcileBootstrapCluster(cluster, logger)
+		addItem(BootstrapKindCluster, cluster.Name, action, err)
+	}
+	for _, storage := range bootstrapConfig.Storages {
+		action, err := reconcileBootstrapStorage(storage, logger)
+		addItem(BootstrapKindStorage, fmt.Sprintf("%s/%s", storage.Endpoint, storage.Bucket), action, err)
+	}
+	for _, connector := range bootstrapConfig.Connectors {
+		action, err := reconcileBootstrapConnector(connector, logger)
+		addItem(BootstrapKindConnector, connector.ID, action, err)
+	}
+	resp.EndTime = time.Now().Unix()
+
+	bootstrapResultLock.Lock()
+	bootstrapResult = resp
+	bootstrapResultLock.Unlock()
+	return resp, nil
+}
+
+func loadBootstrapConfig(content []byte, kubeClient client.Client) (*BootstrapConfig, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(content, root); err != nil {
+		return nil, err
+	}
+	if err := resolveSecretKeyRefs(root, kubeClient); err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	if len(root.Content) > 0 {
+		if err := root.Content[0].Decode(&document); err != nil {
+			return nil, err
+		}
+	}
+	// decode through json so that the specs share the field names and the decoding of the APIs.
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	resp := &BootstrapConfig{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// resolveSecretKeyRefs replaces every secretKeyRef mapping in the tree with the value of the secret key.
+func resolveSecretKeyRefs(node *yaml.Node, kubeClient client.Client) error {
+	if node.Kind == yaml.MappingNode && len(node.Content) == 2 && node.Content[0].Value == "secretKeyRef" {
+		ref := &secretKeySelector{}
+		if err := node.Content[1].Decode(ref); err != nil {
+			return fmt.Errorf("invalid secretKeyRef at line %d: %s", node.Line, err)
+		}
+		value, err := getSecretKeyValue(ref, kubeClient)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secretKeyRef at line %d: %s", node.Line, err)
+		}
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: node.Line, Column: node.Column}
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := resolveSecretKeyRefs(child, kubeClient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getSecretKeyValue(ref *secretKeySelector, kubeClient client.Client) (string, error) {
+	if ref.Name == "" || ref.Key == "" {
+		return "", fmt.Errorf("name and key are required")
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = config.Namespace()
+	}
+
+	secret, found, err := getter.GetSecret(namespace, ref.Name, kubeClient)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("secret %s/%s not found", namespace, ref.Name)
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret %s/%s", ref.Key, namespace, ref.Name)
+	}
+	return string(value), nil
+}
+
+func reconcileBootstrapCodeHost(codehost *codehostmodels.CodeHost, logger *zap.SugaredLogger) (string, error) {
+	if codehost.Alias == "" {
+		return BootstrapActionFailed, fmt.Errorf("alias is required")
+	}
+	existed, err := codehostrepo.NewCodehostColl().GetCodeHostByAlias(codehost.Alias)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return BootstrapActionFailed, err
+	}
+	if existed == nil {
+		// the oauth authorization is not needed if the token is provided.
+		if codehost.AccessToken != "" {
+			codehost.IsReady = "2"
+		}
+		_, err := codehostservice.CreateCodeHost(codehost, setting.SystemUser, logger)
+		return BootstrapActionCreated, err
+	}
+
+	// the declared codehost overwrites the existing one.
+	codehost.ID, codehost.Revision = existed.ID, existed.Revision
+	updated, err := codehostservice.UpdateCodeHost(codehost, setting.SystemUser, logger)
+	if err != nil {
+		return BootstrapActionUpdated, err
+	}
+	if codehost.AccessToken != "" {
+		codehost.Revision = updated.Revision
+		_, err = codehostservice.UpdateCodeHostByToken(codehost, logger)
+	}
+	return BootstrapActionUpdated, err
+}
+
+func reconcileBootstrapRegistry(registry *commonmodels.RegistryNamespace, logger *zap.SugaredLogger) (string, error) {
+	if registry.RegAddr == "" {
+		return BootstrapActionFailed, fmt.Errorf("reg_addr is required")
+	}
+	registries, err := commonrepo.NewRegistryNamespaceColl().FindAll(&commonrepo.FindRegOps{RegAddr: registry.RegAddr, Namespace: registry.Namespace})
+	if err != nil {
+		return BootstrapActionFailed, err
+	}
+	if len(registries) == 0 {
+		return BootstrapActionCreated, CreateRegistryNamespace(setting.SystemUser, registry, logger)
+	}
+	return BootstrapActionUpdated, UpdateRegistryNamespace(setting.SystemUser, registries[0].ID.Hex(), registry, logger)
+}
+
+func reconcileBootstrapCluster(cluster *multiclusterservice.K8SCluster, logger *zap.SugaredLogger) (string, error) {
+	if cluster.Name == "" {
+		return BootstrapActionFailed, fmt.Errorf("name is required")
+	}
+	existed, err := commonrepo.NewK8SClusterColl().FindByName(cluster.Name)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return BootstrapActionFailed, err
+	}
+	cluster.CreatedBy = setting.SystemUser
+	if err == mongo.ErrNoDocuments {
+		cluster.CreatedAt = time.Now().Unix()
+		_, err := multiclusterservice.CreateCluster(cluster, logger)
+		return BootstrapActionCreated, err
+	}
+	_, err = multiclusterservice.UpdateCluster(existed.ID.Hex(), cluster, logger)
+	return BootstrapActionUpdated, err
+}
+
+func reconcileBootstrapStorage(storage *commonmodels.S3Storage, logger *zap.SugaredLogger) (string, error) {
+	if storage.Endpoint == "" || storage.Bucket == "" {
+		return BootstrapActionFailed, fmt.Errorf("endpoint and bucket are required")
+	}
+	storages, err := commonrepo.NewS3StorageColl().FindAll()
+	if err != nil {
+		return BootstrapActionFailed, err
+	}
+	for _, existed := range storages {
+		if existed.Endpoint == storage.Endpoint && existed.Bucket == storage.Bucket {
+			return BootstrapActionUpdated, UpdateS3Storage(setting.SystemUser, existed.ID.Hex(), storage, logger)
+		}
+	}
+	return BootstrapActionCreated, CreateS3Storage(setting.SystemUser, storage, logger)
+}
+
+func reconcileBootstrapConnector(connector *connectorservice.Connector, logger *zap.SugaredLogger) (string, error) {
+	if connector.ID == "" {
+		return BootstrapActionFailed, fmt.Errorf("id is required")
+	}
+	_, err := orm.NewConnectorColl().Get(connector.ID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return BootstrapActionFailed, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return BootstrapActionCreated, connectorservice.CreateConnector(connector, logger)
+	}
+	return BootstrapActionUpdated, connectorservice.UpdateConnector(connector, logger)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	codehostservice "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/service"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/i18n"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func GetCodeHostNotify(log *zap.SugaredLogger) (*commonmodels.NotifyCtl, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get system settings, err: %s", err)
+		return nil, e.ErrGetCodeHostNotify.AddErr(err)
+	}
+	if sysSetting.CodeHostNotify == nil {
+		return &commonmodels.NotifyCtl{}, nil
+	}
+	return sysSetting.CodeHostNotify, nil
+}
+
+func UpdateCodeHostNotify(args *commonmodels.NotifyCtl, log *zap.SugaredLogger) error {
+	if args.Enabled && instantmessage.WebHookURI(args) == "" {
+		return e.ErrUpdateCodeHostNotify.AddDesc(fmt.Sprintf("the webhook of type %s is required", args.WebHookType))
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateCodeHostNotifySetting(args); err != nil {
+		log.Errorf("failed to update the notification of codehosts, err: %s", err)
+		return e.ErrUpdateCodeHostNotify.AddErr(err)
+	}
+	return nil
+}
+
+// NotifyCodeHostTokenAlert is registered as the token alert handler of the codehosts. The admin is always
+// notified in the portal, and through the IM channel if it is configured.
+func NotifyCodeHostTokenAlert(alert *codehostservice.TokenAlert) {
+	logger := log.SugaredLogger()
+	lang := i18n.DefaultLanguage()
+
+	name := alert.Alias
+	if name == "" {
+		name = alert.Address
+	}
+	title := i18n.T(lang, "代码源凭证即将过期")
+	if alert.ExpiresAt == 0 {
+		title = i18n.T(lang, "代码源凭证刷新失败")
+	}
+	lines := []string{
+		fmt.Sprintf("#### %s", title),
+		fmt.Sprintf(i18n.Localize(lang, "**代码源**：%s (%s)"), name, alert.Type),
+		fmt.Sprintf(i18n.Localize(lang, "**原因**：%s"), alert.Reason),
+	}
+	if alert.ExpiresAt > 0 {
+		lines = append(lines, fmt.Sprintf(i18n.Localize(lang, "**过期时间**：%s"), time.Unix(alert.ExpiresAt, 0).Format("2006-01-02 15:04:05")))
+	}
+	lines = append(lines, fmt.Sprintf("[%s](%s)", i18n.T(lang, "重新授权"), alert.AuthURL))
+	content := strings.Join(lines, " \n")
+
+	commonservice.SendMessage(setting.PresetAccount, fmt.Sprintf("%s: %s", title, name), content, "", logger)
+
+	notify, err := GetCodeHostNotify(logger)
+	if err != nil {
+		return
+	}
+	if err := instantmessage.NewWeChatClient().SendSystemMessage(notify, title, content); err != nil {
+		logger.Errorf("failed to send the token alert of codehost %d, err: %s", alert.CodeHostID, err)
+	}
+}
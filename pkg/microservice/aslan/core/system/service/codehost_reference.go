@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+const (
+	CodeHostReferenceBuild      = "build"
+	CodeHostReferenceTesting    = "testing"
+	CodeHostReferenceScanning   = "scanning"
+	CodeHostReferenceService    = "service"
+	CodeHostReferenceWorkflowV4 = "workflow_v4"
+)
+
+// CodeHostReference is a resource which clones the repos of the codehost, the codehost can not be deleted
+// until it is not referenced any more.
+type CodeHostReference struct {
+	Type    string `json:"type"`
+	Project string `json:"project"`
+	Name    string `json:"name"`
+}
+
+func ListCodeHostReferences(codehostID int, log *zap.SugaredLogger) ([]*CodeHostReference, error) {
+	resp := make([]*CodeHostReference, 0)
+
+	builds, err := commonrepo.NewBuildColl().GetCodehostReference(codehostID)
+	if err != nil {
+		log.Errorf("failed to list the builds of codehost %d, err: %s", codehostID, err)
+		return nil, err
+	}
+	for _, build := range builds {
+		resp = append(resp, &CodeHostReference{Type: CodeHostReferenceBuild, Project: build.ProductName, Name: build.Name})
+	}
+
+	testings, err := commonrepo.NewTestingColl().GetCodehostReference(codehostID)
+	if err != nil {
+		log.Errorf("failed to list the testings of codehost %d, err: %s", codehostID, err)
+		return nil, err
+	}
+	for _, testing := range testings {
+		resp = append(resp, &CodeHostReference{Type: CodeHostReferenceTesting, Project: testing.ProductName, Name: testing.Name})
+	}
+
+	scannings, err := commonrepo.NewScanningColl().GetCodehostReference(codehostID)
+	if err != nil {
+		log.Errorf("failed to list the scannings of codehost %d, err: %s", codehostID, err)
+		return nil, err
+	}
+	for _, scanning := range scannings {
+		resp = append(resp, &CodeHostReference{Type: CodeHostReferenceScanning, Project: scanning.ProjectName, Name: scanning.Name})
+	}
+
+	services, err := commonrepo.NewServiceColl().GetCodehostReference(codehostID)
+	if err != nil {
+		log.Errorf("failed to list the services of codehost %d, err: %s", codehostID, err)
+		return nil, err
+	}
+	for _, service := range services {
+		resp = append(resp, &CodeHostReference{Type: CodeHostReferenceService, Project: service.ProductName, Name: service.ServiceName})
+	}
+
+	workflows, err := commonrepo.NewWorkflowV4Coll().GetCodehostReference(codehostID)
+	if err != nil {
+		log.Errorf("failed to list the workflows of codehost %d, err: %s", codehostID, err)
+		return nil, err
+	}
+	for _, workflow := range workflows {
+		resp = append(resp, &CodeHostReference{Type: CodeHostReferenceWorkflowV4, Project: workflow.Project, Name: workflow.Name})
+	}
+	return resp, nil
+}
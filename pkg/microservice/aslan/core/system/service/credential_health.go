@@ -0,0 +1,273 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client/open"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+func ListCredentialHealth(kind string, log *zap.SugaredLogger) ([]*commonmodels.CredentialHealth, error) {
+	resp, err := commonrepo.NewCredentialHealthColl().List(kind)
+	if err != nil {
+		log.Errorf("failed to list credential health, err: %s", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CheckCredentialHealth validates the credentials of all codehosts, registries and clusters and records
+// the results. Owners are notified when a credential turns unhealthy or is about to expire.
+func CheckCredentialHealth(log *zap.SugaredLogger) error {
+	startTime := time.Now().Unix()
+	previous, err := commonrepo.NewCredentialHealthColl().List("")
+	if err != nil {
+		log.Errorf("failed to list credential health, err: %s", err)
+		return err
+	}
+	prevMap := make(map[string]*commonmodels.CredentialHealth, len(previous))
+	for _, health := range previous {
+		prevMap[health.Kind+"/"+health.ResourceID] = health
+	}
+
+	var results []*commonmodels.CredentialHealth
+	codehosts, err := systemconfig.New().ListCodeHostsInternal()
+	if err != nil {
+		log.Errorf("failed to list codehosts, err: %s", err)
+		return err
+	}
+	for _, ch := range codehosts {
+		if ch.Type == systemconfig.OtherProvider {
+			continue
+		}
+		results = append(results, checkCodehostCredential(ch, log))
+	}
+
+	registries, err := commonrepo.NewRegistryNamespaceColl().FindAll(&commonrepo.FindRegOps{})
+	if err != nil {
+		log.Errorf("failed to list registries, err: %s", err)
+		return err
+	}
+	for _, reg := range registries {
+		results = append(results, checkRegistryCredential(reg, log))
+	}
+
+	clusters, err := commonrepo.NewK8SClusterColl().List(nil)
+	if err != nil {
+		log.Errorf("failed to list clusters, err: %s", err)
+		return err
+	}
+	for _, cluster := range clusters {
+		if cluster.Local {
+			continue
+		}
+		results = append(results, checkClusterCredential(cluster))
+	}
+
+	for _, health := range results {
+		health.CheckedAt = startTime
+		prev := prevMap[health.Kind+"/"+health.ResourceID]
+		if prev != nil {
+			health.NotifiedAt = prev.NotifiedAt
+		}
+		if title, content := credentialHealthMessage(health, prev); title != "" && health.Owner != "" {
+			commonservice.SendMessage(health.Owner, title, content, "", log)
+			health.NotifiedAt = startTime
+		}
+		if err := commonrepo.NewCredentialHealthColl().Upsert(health); err != nil {
+			log.Errorf("failed to save health of %s %s, err: %s", health.Kind, health.Name, err)
+		}
+	}
+	// records of the deleted integrations are not refreshed by this check
+	return commonrepo.NewCredentialHealthColl().DeleteStale(startTime)
+}
+
+// credentialHealthMessage returns the notification of the check result, title is empty if the owner
+// should not be notified.
+func credentialHealthMessage(health, prev *commonmodels.CredentialHealth) (string, string) {
+	if !health.Healthy {
+		if prev != nil && !prev.Healthy {
+			return "", ""
+		}
+		return fmt.Sprintf("%s %s 凭证不可用", health.Kind, health.Name), health.Message
+	}
+	if health.ExpiresAt == 0 {
+		return "", ""
+	}
+	notifyFrom := health.ExpiresAt - int64(config.CredentialExpiryNotifyDays*24*time.Hour/time.Second)
+	if time.Now().Unix() < notifyFrom || health.NotifiedAt >= notifyFrom {
+		return "", ""
+	}
+	return fmt.Sprintf("%s %s 凭证即将过期", health.Kind, health.Name),
+		fmt.Sprintf("凭证将于 %s 过期，请及时更新", time.Unix(health.ExpiresAt, 0).Format("2006-01-02 15:04:05"))
+}
+
+func checkCodehostCredential(ch *systemconfig.CodeHost, log *zap.SugaredLogger) *commonmodels.CredentialHealth {
+	health := &commonmodels.CredentialHealth{
+		Kind:       config.CredentialKindCodehost,
+		ResourceID: fmt.Sprintf("%d", ch.ID),
+		Name:       ch.Alias,
+		Owner:      setting.PresetAccount,
+		Healthy:    true,
+	}
+	if health.Name == "" {
+		health.Name = ch.Address
+	}
+	cli, err := open.OpenClient(ch, log)
+	if err == nil {
+		_, err = cli.ListNamespaces("")
+	}
+	if err != nil {
+		health.Healthy, health.Message = false, err.Error()
+		return health
+	}
+	health.ExpiresAt = codehostTokenExpiresAt(ch)
+	return health
+}
+
+// codehostTokenExpiresAt estimates the expiry of the token, 0 is returned if the codehost does not
+// expose it.
+func codehostTokenExpiresAt(ch *systemconfig.CodeHost) int64 {
+	switch ch.Type {
+	case systemconfig.GitHubProvider:
+		res, err := httpclient.Get("https://api.github.com/user", httpclient.SetHeader("Authorization", "token "+ch.AccessToken))
+		if err != nil {
+			return 0
+		}
+		// the header is only returned for fine-grained and expiring tokens, e.g. "2022-10-01 00:00:00 UTC"
+		expiration, err := time.Parse("2006-01-02 15:04:05 MST", res.Header().Get("GitHub-Authentication-Token-Expiration"))
+		if err != nil {
+			return 0
+		}
+		return expiration.Unix()
+	case systemconfig.GitLabProvider:
+		token := &struct {
+			ExpiresAt string `json:"expires_at"`
+		}{}
+		_, err := httpclient.Get(strings.TrimSuffix(ch.Address, "/")+"/api/v4/personal_access_tokens/self",
+			httpclient.SetHeader("Authorization", "Bearer "+ch.AccessToken), httpclient.SetResult(token))
+		if err != nil {
+			return 0
+		}
+		expiration, err := time.Parse("2006-01-02", token.ExpiresAt)
+		if err != nil {
+			return 0
+		}
+		return expiration.Unix()
+	}
+	return 0
+}
+
+func checkRegistryCredential(reg *commonmodels.RegistryNamespace, log *zap.SugaredLogger) *commonmodels.CredentialHealth {
+	health := &commonmodels.CredentialHealth{
+		Kind:       config.CredentialKindRegistry,
+		ResourceID: reg.ID.Hex(),
+		Name:       strings.TrimSuffix(reg.RegAddr+"/"+reg.Namespace, "/"),
+		Owner:      reg.UpdateBy,
+		Healthy:    true,
+	}
+	var regService registry.Service
+	if reg.AdvancedSetting != nil {
+		regService = registry.NewV2Service(reg.RegProvider, reg.AdvancedSetting.TLSEnabled, reg.AdvancedSetting.TLSCert)
+	} else {
+		regService = registry.NewV2Service(reg.RegProvider, true, "")
+	}
+	err := regService.CheckCredential(registry.Endpoint{
+		Addr:      reg.RegAddr,
+		Ak:        reg.AccessKey,
+		Sk:        reg.SecretKey,
+		Namespace: reg.Namespace,
+		Region:    reg.Region,
+	}, log)
+	if err != nil {
+		health.Healthy, health.Message = false, err.Error()
+	}
+	return health
+}
+
+func checkClusterCredential(cluster *commonmodels.K8SCluster) *commonmodels.CredentialHealth {
+	health := &commonmodels.CredentialHealth{
+		Kind:       config.CredentialKindCluster,
+		ResourceID: cluster.ID.Hex(),
+		Name:       cluster.Name,
+		Owner:      cluster.CreatedBy,
+		Healthy:    true,
+	}
+	if err := checkClusterReachable(cluster); err != nil {
+		health.Healthy, health.Message = false, err.Error()
+		return health
+	}
+	if cluster.Type == setting.KubeConfigClusterType {
+		health.ExpiresAt = kubeConfigExpiresAt(cluster.KubeConfig)
+	}
+	return health
+}
+
+func checkClusterReachable(cluster *commonmodels.K8SCluster) error {
+	if cluster.Type != setting.KubeConfigClusterType && cluster.Status != setting.Normal {
+		return fmt.Errorf("cluster agent is %s", cluster.Status)
+	}
+	cls, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), cluster.ID.Hex())
+	if err != nil {
+		return err
+	}
+	_, err = cls.Discovery().ServerVersion()
+	return err
+}
+
+// kubeConfigExpiresAt returns the expiry of the client certificate of the current context, 0 is
+// returned if the kubeconfig does not use a client certificate.
+func kubeConfigExpiresAt(kubeConfig string) int64 {
+	cfg, err := clientcmd.Load([]byte(kubeConfig))
+	if err != nil {
+		return 0
+	}
+	kubeCtx, ok := cfg.Contexts[cfg.CurrentContext]
+	if !ok {
+		return 0
+	}
+	authInfo, ok := cfg.AuthInfos[kubeCtx.AuthInfo]
+	if !ok || len(authInfo.ClientCertificateData) == 0 {
+		return 0
+	}
+	block, _ := pem.Decode(authInfo.ClientCertificateData)
+	if block == nil {
+		return 0
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return 0
+	}
+	return cert.NotAfter.Unix()
+}
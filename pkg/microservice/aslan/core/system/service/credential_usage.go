@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+type CredentialUsageArgs struct {
+	Kind         string `json:"kind"          form:"kind"`
+	CredentialID string `json:"credential_id" form:"credentialId"`
+	ProjectName  string `json:"project_name"  form:"projectName"`
+	StartTime    int64  `json:"start_time"    form:"startTime"`
+	EndTime      int64  `json:"end_time"      form:"endTime"`
+	Page         int64  `json:"page"          form:"page,default=1"`
+	PageSize     int64  `json:"page_size"     form:"pageSize,default=50"`
+}
+
+type CredentialUsageResp struct {
+	Usages []*commonmodels.CredentialUsage `json:"usages"`
+	Total  int64                           `json:"total"`
+}
+
+// ListCredentialUsages returns the history of the tasks which used a credential from the latest, so that the
+// tasks and clusters exposed to a leaked credential can be found.
+func ListCredentialUsages(args *CredentialUsageArgs, log *zap.SugaredLogger) (*CredentialUsageResp, error) {
+	switch args.Kind {
+	case config.CredentialKindCodehost, config.CredentialKindRegistry, config.CredentialKindSecret:
+	default:
+		return nil, fmt.Errorf("invalid credential kind: %s", args.Kind)
+	}
+	if args.CredentialID == "" {
+		return nil, fmt.Errorf("credential id is required")
+	}
+	if args.PageSize > 500 {
+		args.PageSize = 500
+	}
+
+	usages, total, err := commonrepo.NewCredentialUsageColl().List(&commonrepo.ListCredentialUsageOption{
+		Kind:         args.Kind,
+		CredentialID: args.CredentialID,
+		ProjectName:  args.ProjectName,
+		StartTime:    args.StartTime,
+		EndTime:      args.EndTime,
+		Page:         args.Page,
+		PageSize:     args.PageSize,
+	})
+	if err != nil {
+		log.Errorf("failed to list the usages of %s credential %s, err: %s", args.Kind, args.CredentialID, err)
+		return nil, err
+	}
+	return &CredentialUsageResp{Usages: usages, Total: total}, nil
+}
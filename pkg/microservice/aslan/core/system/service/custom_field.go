@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+var customFieldKeyRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// ListCustomFields lists the custom fields of the scope, the fields of all scopes are listed if it is empty.
+func ListCustomFields(scope config.CustomFieldScope, log *zap.SugaredLogger) ([]*commonmodels.CustomField, error) {
+	fields, err := commonrepo.NewCustomFieldColl().List(scope)
+	if err != nil {
+		log.Errorf("failed to list custom fields, err: %s", err)
+		return nil, e.ErrListCustomFields.AddErr(err)
+	}
+	return fields, nil
+}
+
+func CreateCustomField(args *commonmodels.CustomField, userName string, log *zap.SugaredLogger) error {
+	if err := validateCustomField(args); err != nil {
+		return e.ErrCreateCustomField.AddErr(err)
+	}
+	args.CreatedBy = userName
+	args.UpdateBy = userName
+	if err := commonrepo.NewCustomFieldColl().Create(args); err != nil {
+		log.Errorf("failed to create custom field %s, err: %s", args.Key, err)
+		return e.ErrCreateCustomField.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateCustomField updates the custom field, the key and the scope can not be changed since the projects or the
+// workflows have saved the values by them.
+func UpdateCustomField(id string, args *commonmodels.CustomField, userName string, log *zap.SugaredLogger) error {
+	current, err := commonrepo.NewCustomFieldColl().Find(id)
+	if err != nil {
+		return e.ErrUpdateCustomField.AddErr(err)
+	}
+	if args.Key != current.Key || args.Scope != current.Scope {
+		return e.ErrUpdateCustomField.AddDesc("key and scope of the custom field can not be changed")
+	}
+	if err := validateCustomField(args); err != nil {
+		return e.ErrUpdateCustomField.AddErr(err)
+	}
+	args.CreatedBy = current.CreatedBy
+	args.CreateTime = current.CreateTime
+	args.UpdateBy = userName
+	if err := commonrepo.NewCustomFieldColl().Update(id, args); err != nil {
+		log.Errorf("failed to update custom field %s, err: %s", id, err)
+		return e.ErrUpdateCustomField.AddErr(err)
+	}
+	return nil
+}
+
+// DeleteCustomField deletes the custom field together with its values of the projects or the workflows.
+func DeleteCustomField(id string, log *zap.SugaredLogger) error {
+	field, err := commonrepo.NewCustomFieldColl().Find(id)
+	if err != nil {
+		return e.ErrDeleteCustomField.AddErr(err)
+	}
+
+	switch field.Scope {
+	case config.CustomFieldScopeProject:
+		err = templaterepo.NewProductColl().UnsetCustomField(field.Key)
+	case config.CustomFieldScopeWorkflow:
+		err = commonrepo.NewWorkflowV4Coll().UnsetCustomField(field.Key)
+	}
+	if err != nil {
+		log.Errorf("failed to delete the values of custom field %s, err: %s", field.Key, err)
+		return e.ErrDeleteCustomField.AddErr(err)
+	}
+	if err := commonrepo.NewCustomFieldColl().Delete(id); err != nil {
+		log.Errorf("failed to delete custom field %s, err: %s", id, err)
+		return e.ErrDeleteCustomField.AddErr(err)
+	}
+	return nil
+}
+
+func validateCustomField(args *commonmodels.CustomField) error {
+	if !customFieldKeyRegexp.MatchString(args.Key) {
+		return fmt.Errorf("invalid key %s, it should match %s", args.Key, customFieldKeyRegexp.String())
+	}
+	if args.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch args.Scope {
+	case config.CustomFieldScopeProject, config.CustomFieldScopeWorkflow:
+	default:
+		return fmt.Errorf("unsupported scope %s", args.Scope)
+	}
+
+	switch args.Type {
+	case config.CustomFieldTypeString, config.CustomFieldTypeNumber, config.CustomFieldTypeBool:
+		args.Options = nil
+	case config.CustomFieldTypeEnum:
+		if len(args.Options) == 0 {
+			return fmt.Errorf("options of the enum field are required")
+		}
+		if sets.NewString(args.Options...).Len() != len(args.Options) {
+			return fmt.Errorf("duplicated options")
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", args.Type)
+	}
+	if args.Regex != "" {
+		if _, err := regexp.Compile(args.Regex); err != nil {
+			return fmt.Errorf("invalid regex %s: %s", args.Regex, err)
+		}
+		for _, option := range args.Options {
+			if err := commonservice.ValidateCustomFieldValue(args, option); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	gormtool "github.com/koderover/zadig/pkg/tool/gorm"
+	"github.com/koderover/zadig/pkg/tool/healthz"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+type Diagnostics struct {
+	Status   string            `json:"status"`
+	Services []*healthz.Report `json:"services"`
+}
+
+// HealthChecks returns the dependencies of aslan, the attached clusters are optional since one of
+// them being unreachable does not affect the others.
+func HealthChecks() []*healthz.Check {
+	checks := []*healthz.Check{
+		{
+			Name: "mongodb",
+			Hint: fmt.Sprintf("check that mongodb is running and %s is correct", setting.ENVMongoDBConnectionString),
+			Run:  mongotool.Ping,
+		},
+		{
+			Name: "mysql",
+			Hint: fmt.Sprintf("check that mysql is running and %s, %s and %s are correct", setting.ENVMysqlHost, setting.ENVMysqlUser, setting.ENVMysqlPassword),
+			Run:  pingMysql,
+		},
+		{
+			Name: "object-storage",
+			Hint: "check the endpoint, the access key and the bucket of the default object storage in the system settings, the endpoint must be reachable from aslan",
+			Run:  checkDefaultObjectStorage,
+		},
+		{
+			Name: "service-auth",
+			Hint: fmt.Sprintf("the services sign the internal calls with %s, make sure all of them are deployed with the same value", setting.ENVSecretKey),
+			Run:  checkServiceAuth,
+		},
+		{
+			Name: "local-cluster",
+			Hint: "the clusters are accessed through hub-server, check that hub-server is running and the service account of zadig is bound to the required roles",
+			Run:  checkLocalCluster,
+		},
+	}
+
+	clusters, err := commonrepo.NewK8SClusterColl().List(nil)
+	if err != nil {
+		// the failure is reported by the check of mongodb
+		return checks
+	}
+	for _, cluster := range clusters {
+		if cluster.Local {
+			continue
+		}
+		cluster := cluster
+		hint := "check the network between the cluster and zadig, reinstall the agent if the cluster has been disconnected for a long time"
+		if cluster.Type == setting.KubeConfigClusterType {
+			hint = "check that the api server in the kubeconfig is reachable from zadig and the credential has not expired"
+		}
+		checks = append(checks, &healthz.Check{
+			Name:     "cluster/" + cluster.Name,
+			Hint:     hint,
+			Optional: true,
+			Run: func(context.Context) error {
+				return checkClusterReachable(cluster)
+			},
+		})
+	}
+	return checks
+}
+
+func pingMysql(ctx context.Context) error {
+	db := gormtool.DB(configbase.MysqlUserDB())
+	if db == nil {
+		return fmt.Errorf("database %s is not opened", configbase.MysqlUserDB())
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func checkDefaultObjectStorage(context.Context) error {
+	storage, err := s3.FindDefaultS3()
+	if err != nil {
+		return err
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return err
+	}
+	return client.ValidateBucket(storage.Bucket)
+}
+
+// checkServiceAuth calls the grpc server with the token signed like the other services, only the
+// rejection of the token fails the check.
+func checkServiceAuth(ctx context.Context) error {
+	client, err := systemconfig.NewCodeHostGRPCClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.ListByProvider(ctx, "")
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unauthenticated {
+		return fmt.Errorf("the token of the services is rejected: %s", s.Message())
+	}
+	if err != nil && status.Code(err) == codes.Unavailable {
+		return fmt.Errorf("the grpc server is unavailable: %s", err)
+	}
+	return nil
+}
+
+func checkLocalCluster(context.Context) error {
+	cls, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), setting.LocalClusterID)
+	if err != nil {
+		return err
+	}
+	_, err = cls.Discovery().ServerVersion()
+	return err
+}
+
+// GetDiagnostics checks aslan and collects the reports of the other services.
+func GetDiagnostics(ctx context.Context, log *zap.SugaredLogger) *Diagnostics {
+	reports := []*healthz.Report{healthz.Run(ctx, configbase.AslanServiceName(), healthz.DefaultTimeout, HealthChecks())}
+	for _, svc := range []*setting.ServiceInfo{configbase.CronServiceInfo(), configbase.WarpDriveServiceInfo(), configbase.HubServerServiceInfo()} {
+		address := configbase.GetServiceAddress(svc.Name, svc.Port)
+		hint := fmt.Sprintf("check that the pod of %s is running with kubectl -n %s get pods", svc.Name, configbase.Namespace())
+		reports = append(reports, healthz.Fetch(ctx, svc.Name, address+"/healthz", hint))
+	}
+
+	diagnostics := &Diagnostics{Status: healthz.Merge(reports), Services: reports}
+	if diagnostics.Status != healthz.StatusOK {
+		log.Warnf("diagnostics of zadig is %s", diagnostics.Status)
+	}
+	return diagnostics
+}
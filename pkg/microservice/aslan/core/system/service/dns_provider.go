@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/dns"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const defaultDNSOwnerID = "default"
+
+// ListDNSProviders lists the dns providers without their secrets.
+func ListDNSProviders(log *zap.SugaredLogger) ([]*commonmodels.DNSProvider, error) {
+	providers, err := commonrepo.NewDNSProviderColl().List(false)
+	if err != nil {
+		log.Errorf("failed to list dns providers, err: %s", err)
+		return nil, e.ErrListDNSProviders.AddErr(err)
+	}
+	for _, provider := range providers {
+		provider.Secret = ""
+	}
+	return providers, nil
+}
+
+func CreateDNSProvider(args *commonmodels.DNSProvider, userName string, log *zap.SugaredLogger) error {
+	if err := validateDNSProvider(args); err != nil {
+		return e.ErrCreateDNSProvider.AddErr(err)
+	}
+	args.UpdateBy = userName
+	if err := commonrepo.NewDNSProviderColl().Create(args); err != nil {
+		log.Errorf("failed to create dns provider %s, err: %s", args.Name, err)
+		return e.ErrCreateDNSProvider.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateDNSProvider updates the dns provider, the secret is kept if it is empty.
+func UpdateDNSProvider(id string, args *commonmodels.DNSProvider, userName string, log *zap.SugaredLogger) error {
+	current, err := commonrepo.NewDNSProviderColl().Find(id)
+	if err != nil {
+		return e.ErrUpdateDNSProvider.AddErr(err)
+	}
+	if args.Secret == "" {
+		args.Secret = current.Secret
+	}
+	if err := validateDNSProvider(args); err != nil {
+		return e.ErrUpdateDNSProvider.AddErr(err)
+	}
+	args.UpdateBy = userName
+	if err := commonrepo.NewDNSProviderColl().Update(id, args); err != nil {
+		log.Errorf("failed to update dns provider %s, err: %s", id, err)
+		return e.ErrUpdateDNSProvider.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteDNSProvider(id string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewDNSProviderColl().Delete(id); err != nil {
+		log.Errorf("failed to delete dns provider %s, err: %s", id, err)
+		return e.ErrDeleteDNSProvider.AddErr(err)
+	}
+	return nil
+}
+
+func validateDNSProvider(args *commonmodels.DNSProvider) error {
+	args.Domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(args.Domain), "."))
+	if args.Name == "" || args.Domain == "" || args.Zone == "" {
+		return fmt.Errorf("name, domain and zone are required")
+	}
+	switch args.Provider {
+	case dns.ProviderRoute53:
+	case dns.ProviderCloudDNS:
+		if args.Project == "" {
+			return fmt.Errorf("project is required by cloud dns")
+		}
+	case dns.ProviderAlidns:
+		if args.AccessKeyID == "" || args.Secret == "" {
+			return fmt.Errorf("access key is required by alidns")
+		}
+		// the records of alidns are relative to the domain of the zone.
+		zone := strings.TrimSuffix(args.Zone, ".")
+		if args.Domain != zone && !strings.HasSuffix(args.Domain, "."+zone) {
+			return fmt.Errorf("domain %s is not in the zone %s", args.Domain, args.Zone)
+		}
+	default:
+		return fmt.Errorf("unsupported dns provider %s", args.Provider)
+	}
+	if args.TTL < 0 {
+		return fmt.Errorf("ttl can not be negative")
+	}
+	if args.OwnerID == "" {
+		args.OwnerID = defaultDNSOwnerID
+	}
+	if strings.Contains(args.OwnerID, ",") {
+		return fmt.Errorf("owner id can not contain commas")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type RotateEncryptionKeyArgs struct {
+	// Force re-encrypts all the secrets with a new data key, it is used after the master key is rotated
+	// in the key provider, otherwise only the secrets not encrypted by the current master key are changed.
+	Force bool `json:"force"`
+}
+
+// the secrets stored in aslan are encrypted by the key provider and re-encrypted by the rotations. The others
+// are encrypted by the static aes key since they are decrypted outside aslan which has no access to the key
+// provider, i.e. the tokens of the cluster agents by hubserver and the s3 urls by warpdrive and the build pods.
+var (
+	encryptionScope          = []string{"s3_storage.secret_key", "dns_provider.secret"}
+	staticKeyEncryptionScope = []string{"cluster.agent_token", "s3_storage.task_url"}
+)
+
+type EncryptionStatus struct {
+	Provider string `json:"provider"`
+	KeyID    string `json:"key_id"`
+	// Scope are the secrets encrypted by the key provider and covered by the rotations.
+	Scope []string `json:"scope"`
+	// StaticKeyScope are the secrets encrypted by the static aes key, they are not changed by the rotations.
+	StaticKeyScope []string     `json:"static_key_scope"`
+	Rotation       *KeyRotation `json:"rotation,omitempty"`
+}
+
+type KeyRotation struct {
+	Running     bool     `json:"running"`
+	Force       bool     `json:"force"`
+	CreatedBy   string   `json:"created_by"`
+	StartTime   int64    `json:"start_time"`
+	EndTime     int64    `json:"end_time"`
+	Total       int      `json:"total"`
+	ReEncrypted int      `json:"re_encrypted"`
+	Failed      int      `json:"failed"`
+	Errors      []string `json:"errors"`
+}
+
+var (
+	keyRotationLock sync.RWMutex
+	keyRotation     *KeyRotation
+)
+
+func GetEncryptionStatus() *EncryptionStatus {
+	provider, keyID := crypto.CurrentKeyProvider()
+	resp := &EncryptionStatus{
+		Provider:       provider,
+		KeyID:          keyID,
+		Scope:          encryptionScope,
+		StaticKeyScope: staticKeyEncryptionScope,
+	}
+
+	keyRotationLock.RLock()
+	defer keyRotationLock.RUnlock()
+	if keyRotation != nil {
+		rotation := *keyRotation
+		rotation.Errors = append([]string{}, keyRotation.Errors...)
+		resp.Rotation = &rotation
+	}
+	return resp
+}
+
+// RotateEncryptionKey re-encrypts the secrets in the encryption scope with the current key provider in the background.
+// The secrets can be read during the rotation since both the old and the new master keys are usable.
+func RotateEncryptionKey(args *RotateEncryptionKeyArgs, userName string, log *zap.SugaredLogger) (*EncryptionStatus, error) {
+	keyRotationLock.Lock()
+	if keyRotation != nil && keyRotation.Running {
+		keyRotationLock.Unlock()
+		return nil, e.ErrRotateEncryptionKey.AddDesc("a key rotation is running")
+	}
+	keyRotation = &KeyRotation{Running: true, Force: args.Force, CreatedBy: userName, StartTime: time.Now().Unix(), Errors: []string{}}
+	keyRotationLock.Unlock()
+
+	if args.Force {
+		crypto.RotateDataKey()
+	}
+	go func() {
+		reEncryptS3Storages(args.Force, log)
+		reEncryptDNSProviders(args.Force, log)
+
+		keyRotationLock.Lock()
+		keyRotation.Running = false
+		keyRotation.EndTime = time.Now().Unix()
+		keyRotationLock.Unlock()
+	}()
+
+	return GetEncryptionStatus(), nil
+}
+
+func reEncryptS3Storages(force bool, log *zap.SugaredLogger) {
+	storages, err := commonrepo.NewS3StorageColl().ListEncrypted()
+	if err != nil {
+		log.Errorf("failed to list s3 storages, err: %s", err)
+		recordKeyRotation(0, 0, fmt.Errorf("failed to list s3 storages: %s", err))
+		return
+	}
+
+	for _, storage := range storages {
+		encryptedSk, changed, err := crypto.ReEncryptSecret(storage.EncryptedSk, force)
+		if err == nil && changed {
+			err = commonrepo.NewS3StorageColl().UpdateEncryptedSk(storage.ID, storage.EncryptedSk, encryptedSk)
+		}
+		if err != nil {
+			log.Errorf("failed to re-encrypt the secret key of s3 storage %s, err: %s", storage.ID.Hex(), err)
+			recordKeyRotation(1, 0, fmt.Errorf("s3 storage %s: %s", storage.ID.Hex(), err))
+			continue
+		}
+		reEncrypted := 0
+		if changed {
+			reEncrypted = 1
+		}
+		recordKeyRotation(1, reEncrypted, nil)
+	}
+}
+
+func reEncryptDNSProviders(force bool, log *zap.SugaredLogger) {
+	providers, err := commonrepo.NewDNSProviderColl().ListEncrypted()
+	if err != nil {
+		log.Errorf("failed to list dns providers, err: %s", err)
+		recordKeyRotation(0, 0, fmt.Errorf("failed to list dns providers: %s", err))
+		return
+	}
+
+	for _, provider := range providers {
+		encryptedSecret, changed, err := crypto.ReEncryptSecret(provider.EncryptedSecret, force)
+		if err == nil && changed {
+			err = commonrepo.NewDNSProviderColl().UpdateEncryptedSecret(provider.ID, provider.EncryptedSecret, encryptedSecret)
+		}
+		if err != nil {
+			log.Errorf("failed to re-encrypt the secret of dns provider %s, err: %s", provider.ID.Hex(), err)
+			recordKeyRotation(1, 0, fmt.Errorf("dns provider %s: %s", provider.ID.Hex(), err))
+			continue
+		}
+		reEncrypted := 0
+		if changed {
+			reEncrypted = 1
+		}
+		recordKeyRotation(1, reEncrypted, nil)
+	}
+}
+
+func recordKeyRotation(total, reEncrypted int, err error) {
+	keyRotationLock.Lock()
+	defer keyRotationLock.Unlock()
+
+	keyRotation.Total += total
+	keyRotation.ReEncrypted += reEncrypted
+	if err != nil {
+		keyRotation.Failed++
+		keyRotation.Errors = append(keyRotation.Errors, err.Error())
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetExternalURL(log *zap.SugaredLogger) (*commonmodels.ExternalURLSetting, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get system settings, err: %s", err)
+		return nil, e.ErrGetExternalURL.AddErr(err)
+	}
+	if sysSetting.ExternalURL == nil {
+		return &commonmodels.ExternalURLSetting{BaseURLs: []string{}}, nil
+	}
+	return sysSetting.ExternalURL, nil
+}
+
+func UpdateExternalURL(args *commonmodels.ExternalURLSetting, log *zap.SugaredLogger) error {
+	baseURLs := make([]string, 0, len(args.BaseURLs))
+	for _, baseURL := range args.BaseURLs {
+		baseURL = strings.TrimSuffix(strings.TrimSpace(baseURL), "/")
+		if err := validateExternalBaseURL(baseURL); err != nil {
+			return e.ErrUpdateExternalURL.AddDesc(err.Error())
+		}
+		baseURLs = append(baseURLs, baseURL)
+	}
+	args.BaseURLs = baseURLs
+
+	if err := commonrepo.NewSystemSettingColl().UpdateExternalURLSetting(args); err != nil {
+		log.Errorf("failed to update the external url settings, err: %s", err)
+		return e.ErrUpdateExternalURL.AddErr(err)
+	}
+	configbase.RefreshExternalURL()
+	return nil
+}
+
+// LoadExternalURLSetting is registered as the loader of the external url setting, all the links
+// generated by zadig are based on it.
+func LoadExternalURLSetting() (*configbase.ExternalURLSetting, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		return nil, err
+	}
+	if sysSetting.ExternalURL == nil {
+		return &configbase.ExternalURLSetting{}, nil
+	}
+	return &configbase.ExternalURLSetting{
+		BaseURLs:              sysSetting.ExternalURL.BaseURLs,
+		TrustForwardedHeaders: sysSetting.ExternalURL.TrustForwardedHeaders,
+	}, nil
+}
+
+func validateExternalBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base url %s: %s", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("base url %s must be an absolute http or https url", baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("base url %s must not have a query or a fragment", baseURL)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+type JobSecurityArgs struct {
+	MinimumProfile types.JobSecurityProfile `json:"minimum_profile"`
+}
+
+func GetJobSecurity(log *zap.SugaredLogger) (*commonmodels.JobSecuritySetting, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get system settings, err: %s", err)
+		return nil, e.ErrGetJobSecurity.AddErr(err)
+	}
+	if sysSetting.JobSecurity == nil {
+		return &commonmodels.JobSecuritySetting{MinimumProfile: types.JobSecurityPrivileged}, nil
+	}
+	return sysSetting.JobSecurity, nil
+}
+
+// UpdateJobSecurity sets the minimum profile of the jobs, the projects choosing a weaker profile run their
+// jobs with the minimum one from now on.
+func UpdateJobSecurity(args *JobSecurityArgs, userName string, log *zap.SugaredLogger) (*commonmodels.JobSecuritySetting, error) {
+	if args.MinimumProfile == "" || !args.MinimumProfile.Valid() {
+		return nil, e.ErrUpdateJobSecurity.AddDesc(fmt.Sprintf("invalid job security profile: %s", args.MinimumProfile))
+	}
+	jobSecurity := &commonmodels.JobSecuritySetting{
+		MinimumProfile: args.MinimumProfile,
+		UpdatedBy:      userName,
+		UpdateTime:     time.Now().Unix(),
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateJobSecuritySetting(jobSecurity); err != nil {
+		log.Errorf("failed to update the job security setting, err: %s", err)
+		return nil, e.ErrUpdateJobSecurity.AddErr(err)
+	}
+	log.Infof("the minimum job security profile is set to %s by %s", jobSecurity.MinimumProfile, userName)
+	return jobSecurity, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListJobWarmPools(log *zap.SugaredLogger) ([]*commonmodels.JobWarmPool, error) {
+	resp, err := commonrepo.NewJobWarmPoolColl().List()
+	if err != nil {
+		log.Errorf("JobWarmPool.List error: %s", err)
+		return resp, e.ErrListJobWarmPool.AddErr(err)
+	}
+	return resp, nil
+}
+
+func CreateJobWarmPool(args *commonmodels.JobWarmPool, log *zap.SugaredLogger) error {
+	if err := validateJobWarmPool(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	err := commonrepo.NewJobWarmPoolColl().Create(args)
+	if err != nil {
+		log.Errorf("JobWarmPool.Create error: %s", err)
+		return e.ErrCreateJobWarmPool.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateJobWarmPool(id string, args *commonmodels.JobWarmPool, log *zap.SugaredLogger) error {
+	if err := validateJobWarmPool(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	pool, err := commonrepo.NewJobWarmPoolColl().Find(id)
+	if err != nil {
+		log.Errorf("JobWarmPool.Find %s error: %s", id, err)
+		return e.ErrUpdateJobWarmPool.AddErr(err)
+	}
+	err = commonrepo.NewJobWarmPoolColl().Update(id, args)
+	if err != nil {
+		log.Errorf("JobWarmPool.Update %s error: %s", id, err)
+		return e.ErrUpdateJobWarmPool.AddErr(err)
+	}
+	// the idle jobs are created again by the warm pool controller with the new profile.
+	if err := jobcontroller.DeleteWarmPoolJobs(pool); err != nil {
+		log.Warnf("failed to delete the jobs of warm pool %s, err: %s", pool.Name, err)
+	}
+	return nil
+}
+
+func DeleteJobWarmPool(id string, log *zap.SugaredLogger) error {
+	pool, err := commonrepo.NewJobWarmPoolColl().Find(id)
+	if err != nil {
+		log.Errorf("JobWarmPool.Find %s error: %s", id, err)
+		return e.ErrDeleteJobWarmPool.AddErr(err)
+	}
+	err = commonrepo.NewJobWarmPoolColl().Delete(id)
+	if err != nil {
+		log.Errorf("JobWarmPool.Delete %s error: %s", id, err)
+		return e.ErrDeleteJobWarmPool.AddErr(err)
+	}
+	if err := jobcontroller.DeleteWarmPoolJobs(pool); err != nil {
+		log.Warnf("failed to delete the jobs of warm pool %s, err: %s", pool.Name, err)
+	}
+	return nil
+}
+
+func validateJobWarmPool(args *commonmodels.JobWarmPool) error {
+	if args.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if args.BuildOS == "" {
+		return fmt.Errorf("build os is required")
+	}
+	if args.Size < 0 {
+		return fmt.Errorf("size can not be negative")
+	}
+	// align with the defaults of the job properties so that the pool matches the jobs.
+	if args.ClusterID == "" {
+		args.ClusterID = setting.LocalClusterID
+	}
+	if args.ResourceRequest == "" {
+		args.ResourceRequest = setting.MinRequest
+	}
+	if args.ResourceRequest != setting.DefineRequest {
+		args.ResReqSpec = setting.RequestSpec{}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const defaultMaintenanceRetryAfter = 300
+
+type MaintenanceArgs struct {
+	Enabled    bool   `json:"enabled"`
+	Checkpoint bool   `json:"checkpoint"`
+	Message    string `json:"message"`
+	RetryAfter int64  `json:"retry_after"`
+}
+
+// MaintenanceStatus shows the progress of the drain, it is safe to upgrade the platform if Drained is true.
+// In the checkpoint mode the running custom workflow tasks are released when aslan shuts down and resumed
+// after it restarts, so only the other tasks need to be drained.
+type MaintenanceStatus struct {
+	*commonmodels.MaintenanceSetting
+	Drained   bool               `json:"drained"`
+	Running   int                `json:"running"`
+	Resumable int                `json:"resumable"`
+	Waiting   int                `json:"waiting"`
+	Tasks     []*MaintenanceTask `json:"tasks"`
+}
+
+type MaintenanceTask struct {
+	Type         config.PipelineType `json:"type"`
+	ProjectName  string              `json:"project_name"`
+	WorkflowName string              `json:"workflow_name"`
+	TaskID       int64               `json:"task_id"`
+	Status       config.Status       `json:"status"`
+	Resumable    bool                `json:"resumable"`
+}
+
+func GetMaintenance(log *zap.SugaredLogger) (*MaintenanceStatus, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get system settings, err: %s", err)
+		return nil, e.ErrGetMaintenance.AddErr(err)
+	}
+	maintenance := sysSetting.Maintenance
+	if maintenance == nil {
+		maintenance = &commonmodels.MaintenanceSetting{}
+	}
+
+	resp := &MaintenanceStatus{MaintenanceSetting: maintenance, Tasks: []*MaintenanceTask{}}
+	for _, q := range workflowcontroller.ListTasks() {
+		switch q.Status {
+		case config.StatusRunning, config.StatusQueued:
+			resp.addTask(&MaintenanceTask{
+				Type:         config.WorkflowTypeV4,
+				ProjectName:  q.ProjectName,
+				WorkflowName: q.WorkflowName,
+				TaskID:       q.TaskID,
+				Status:       q.Status,
+				Resumable:    true,
+			})
+		case config.StatusWaiting, config.StatusBlocked:
+			resp.Waiting++
+		}
+	}
+
+	queues, err := commonrepo.NewQueueColl().List(&commonrepo.ListQueueOption{})
+	if err != nil {
+		log.Errorf("failed to list the pipeline queue, err: %s", err)
+		return nil, e.ErrGetMaintenance.AddErr(err)
+	}
+	for _, q := range queues {
+		switch q.Status {
+		case config.StatusRunning, config.StatusQueued:
+			resp.addTask(&MaintenanceTask{
+				Type:         q.Type,
+				ProjectName:  q.ProductName,
+				WorkflowName: q.PipelineName,
+				TaskID:       q.TaskID,
+				Status:       q.Status,
+			})
+		case config.StatusWaiting, config.StatusBlocked:
+			resp.Waiting++
+		}
+	}
+
+	resp.Drained = maintenance.Enabled && resp.Running == 0
+	if maintenance.Checkpoint {
+		resp.Drained = maintenance.Enabled && resp.Running == resp.Resumable
+	}
+	return resp, nil
+}
+
+func (s *MaintenanceStatus) addTask(task *MaintenanceTask) {
+	s.Tasks = append(s.Tasks, task)
+	s.Running++
+	if task.Resumable {
+		s.Resumable++
+	}
+}
+
+// UpdateMaintenance starts or ends the maintenance, the start time is kept if the maintenance is
+// started already.
+func UpdateMaintenance(args *MaintenanceArgs, userName string, log *zap.SugaredLogger) (*MaintenanceStatus, error) {
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get system settings, err: %s", err)
+		return nil, e.ErrUpdateMaintenance.AddErr(err)
+	}
+
+	maintenance := &commonmodels.MaintenanceSetting{}
+	if args.Enabled {
+		maintenance = &commonmodels.MaintenanceSetting{
+			Enabled:    true,
+			Checkpoint: args.Checkpoint,
+			Message:    args.Message,
+			RetryAfter: args.RetryAfter,
+			StartedBy:  userName,
+			StartTime:  time.Now().Unix(),
+		}
+		if maintenance.RetryAfter <= 0 {
+			maintenance.RetryAfter = defaultMaintenanceRetryAfter
+		}
+		if current := sysSetting.Maintenance; current != nil && current.Enabled {
+			maintenance.StartedBy, maintenance.StartTime = current.StartedBy, current.StartTime
+		}
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateMaintenanceSetting(maintenance); err != nil {
+		log.Errorf("failed to update the maintenance setting, err: %s", err)
+		return nil, e.ErrUpdateMaintenance.AddErr(err)
+	}
+	log.Infof("maintenance is updated by %s, enabled: %v, checkpoint: %v", userName, maintenance.Enabled, maintenance.Checkpoint)
+	return GetMaintenance(log)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	labelservice "github.com/koderover/zadig/pkg/microservice/aslan/core/label/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// ListNotificationRoutes lists the notification routes in the order they are evaluated.
+func ListNotificationRoutes(log *zap.SugaredLogger) ([]*commonmodels.NotificationRoute, error) {
+	routes, err := commonrepo.NewNotificationRouteColl().List(false)
+	if err != nil {
+		log.Errorf("failed to list notification routes, err: %s", err)
+		return nil, e.ErrListNotificationRoutes.AddErr(err)
+	}
+	return routes, nil
+}
+
+func CreateNotificationRoute(args *commonmodels.NotificationRoute, userName string, log *zap.SugaredLogger) error {
+	if err := validateNotificationRoute(args); err != nil {
+		return e.ErrCreateNotificationRoute.AddErr(err)
+	}
+	args.CreatedBy = userName
+	args.UpdateBy = userName
+	if err := commonrepo.NewNotificationRouteColl().Create(args); err != nil {
+		log.Errorf("failed to create notification route %s, err: %s", args.Name, err)
+		return e.ErrCreateNotificationRoute.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateNotificationRoute updates the notification route, the failures counted for its escalation are reset.
+func UpdateNotificationRoute(id string, args *commonmodels.NotificationRoute, userName string, log *zap.SugaredLogger) error {
+	current, err := commonrepo.NewNotificationRouteColl().Find(id)
+	if err != nil {
+		return e.ErrUpdateNotificationRoute.AddErr(err)
+	}
+	if err := validateNotificationRoute(args); err != nil {
+		return e.ErrUpdateNotificationRoute.AddErr(err)
+	}
+	args.CreatedBy = current.CreatedBy
+	args.CreateTime = current.CreateTime
+	args.UpdateBy = userName
+	if err := commonrepo.NewNotificationRouteColl().Update(id, args); err != nil {
+		log.Errorf("failed to update notification route %s, err: %s", id, err)
+		return e.ErrUpdateNotificationRoute.AddErr(err)
+	}
+	if err := commonrepo.NewNotificationStreakColl().DeleteByRoute(id); err != nil {
+		log.Warnf("failed to reset the failures of notification route %s, err: %s", id, err)
+	}
+	return nil
+}
+
+func DeleteNotificationRoute(id string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewNotificationRouteColl().Delete(id); err != nil {
+		log.Errorf("failed to delete notification route %s, err: %s", id, err)
+		return e.ErrDeleteNotificationRoute.AddErr(err)
+	}
+	if err := commonrepo.NewNotificationStreakColl().DeleteByRoute(id); err != nil {
+		log.Warnf("failed to delete the failures of notification route %s, err: %s", id, err)
+	}
+	return nil
+}
+
+func validateNotificationRoute(args *commonmodels.NotificationRoute) error {
+	if args.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := validateNotificationChannels(args.Channels); err != nil {
+		return err
+	}
+	if args.Match != nil {
+		for _, severity := range args.Match.Severities {
+			if !instantmessage.ValidSeverity(severity) {
+				return fmt.Errorf("unsupported severity %s", severity)
+			}
+		}
+		if args.Match.LabelSelector != "" {
+			if _, err := labelservice.ParseSelector(args.Match.LabelSelector); err != nil {
+				return err
+			}
+		}
+	}
+	if err := instantmessage.ValidateQuietHours(args.QuietHours); err != nil {
+		return err
+	}
+	if args.Escalation != nil {
+		if args.Escalation.AfterFailures < 0 {
+			return fmt.Errorf("failures of the escalation can not be negative")
+		}
+		if args.Escalation.AfterFailures > 0 && len(args.Escalation.Channels) == 0 {
+			return fmt.Errorf("channels of the escalation are required")
+		}
+		if err := validateNotificationChannels(args.Escalation.Channels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNotificationChannels(channels []*commonmodels.NotifyCtl) error {
+	for _, channel := range channels {
+		if channel == nil {
+			return fmt.Errorf("empty channel")
+		}
+		if channel.Enabled && instantmessage.WebHookURI(channel) == "" {
+			return fmt.Errorf("webhook of type %s is required", channel.WebHookType)
+		}
+	}
+	return nil
+}
@@ -100,6 +100,8 @@ func CreateRegistryNamespace(username string, args *commonmodels.RegistryNamespa
 		return fmt.Errorf("RegistryNamespace.Create error: %v", err)
 	}
 
+	// the image pull secrets in the env namespaces are repaired with the new credentials.
+	go commonservice.SyncRegistrySecrets(log)
 	return SyncDinDForRegistries()
 }
 
@@ -132,6 +134,8 @@ func UpdateRegistryNamespace(username, id string, args *commonmodels.RegistryNam
 		log.Errorf("RegistryNamespace.Update error: %v", err)
 		return fmt.Errorf("RegistryNamespace.Update error: %v", err)
 	}
+	// the image pull secrets in the env namespaces are repaired with the new credentials.
+	go commonservice.SyncRegistrySecrets(log)
 	return SyncDinDForRegistries()
 }
 
@@ -0,0 +1,346 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/webhook"
+	codehostservice "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/service"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+)
+
+const (
+	StaleResourceWorkflow    = "workflow"
+	StaleResourceEnvironment = "environment"
+	StaleResourceCodeHost    = "codehost"
+	StaleResourceRegistry    = "registry"
+	StaleResourceWebhook     = "webhook"
+
+	defaultWorkflowIdleDays = 90
+	defaultEnvIdleDays      = 30
+
+	// staleWebhookRef is removed from the orphaned webhooks which have no reference, so that they are deleted
+	// from the provider.
+	staleWebhookRef = "housekeeping"
+)
+
+type StaleResourceArgs struct {
+	// WorkflowIdleDays is the days a workflow is not run for, EnvIdleDays is the days an environment is not deployed.
+	WorkflowIdleDays int `json:"workflow_idle_days" form:"workflowIdleDays"`
+	EnvIdleDays      int `json:"env_idle_days"      form:"envIdleDays"`
+}
+
+// StaleResource is an entity which is not used for a long time, the archivable ones can be archived by
+// ArchiveStaleResources and the others are cleaned up as the suggestion says.
+type StaleResource struct {
+	Type         string `json:"type"`
+	Project      string `json:"project,omitempty"`
+	Name         string `json:"name"`
+	ID           string `json:"id,omitempty"`
+	Reason       string `json:"reason"`
+	LastActiveAt int64  `json:"last_active_at,omitempty"`
+	Archivable   bool   `json:"archivable"`
+	Suggestion   string `json:"suggestion,omitempty"`
+}
+
+type StaleResourceReport struct {
+	GeneratedAt int64            `json:"generated_at"`
+	Resources   []*StaleResource `json:"resources"`
+}
+
+// ListStaleResources analyzes the workflows, environments, codehosts, registries and webhooks to find the
+// ones which are not used any more.
+func ListStaleResources(args *StaleResourceArgs, log *zap.SugaredLogger) (*StaleResourceReport, error) {
+	if args.WorkflowIdleDays <= 0 {
+		args.WorkflowIdleDays = defaultWorkflowIdleDays
+	}
+	if args.EnvIdleDays <= 0 {
+		args.EnvIdleDays = defaultEnvIdleDays
+	}
+	now := time.Now()
+	report := &StaleResourceReport{GeneratedAt: now.Unix(), Resources: make([]*StaleResource, 0)}
+
+	analyzers := []func() ([]*StaleResource, error){
+		func() ([]*StaleResource, error) {
+			return staleWorkflows(now.AddDate(0, 0, -args.WorkflowIdleDays).Unix(), args.WorkflowIdleDays)
+		},
+		func() ([]*StaleResource, error) {
+			return staleEnvironments(now.AddDate(0, 0, -args.EnvIdleDays).Unix(), args.EnvIdleDays)
+		},
+		func() ([]*StaleResource, error) { return staleCodeHosts(log) },
+		staleRegistries,
+		staleWebhooks,
+	}
+	for _, analyze := range analyzers {
+		resources, err := analyze()
+		if err != nil {
+			log.Errorf("failed to analyze the stale resources, err: %s", err)
+			return nil, err
+		}
+		report.Resources = append(report.Resources, resources...)
+	}
+	return report, nil
+}
+
+func staleWorkflows(before int64, idleDays int) ([]*StaleResource, error) {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	lastRuns, err := commonrepo.NewworkflowTaskv4Coll().ListLastRuns()
+	if err != nil {
+		return nil, err
+	}
+	lastRunMap := make(map[string]int64, len(lastRuns))
+	for _, lastRun := range lastRuns {
+		lastRunMap[lastRun.WorkflowName] = lastRun.CreateTime
+	}
+
+	resp := make([]*StaleResource, 0)
+	for _, workflow := range workflows {
+		if workflow.Archived {
+			continue
+		}
+		lastActive, ok := lastRunMap[workflow.Name]
+		reason := fmt.Sprintf("not run in %d days", idleDays)
+		if !ok {
+			// the new workflows are not stale before they have a chance to run.
+			lastActive = workflow.UpdateTime
+			reason = fmt.Sprintf("never run and not updated in %d days", idleDays)
+		}
+		if lastActive >= before {
+			continue
+		}
+		resp = append(resp, &StaleResource{
+			Type:         StaleResourceWorkflow,
+			Project:      workflow.Project,
+			Name:         workflow.Name,
+			Reason:       reason,
+			LastActiveAt: lastActive,
+			Archivable:   true,
+		})
+	}
+	return resp, nil
+}
+
+func staleEnvironments(before int64, idleDays int) ([]*StaleResource, error) {
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{ExcludeStatus: []string{setting.ProductStatusDeleting}})
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*StaleResource, 0)
+	for _, env := range envs {
+		if env.UpdateTime >= before {
+			continue
+		}
+		resp = append(resp, &StaleResource{
+			Type:         StaleResourceEnvironment,
+			Project:      env.ProductName,
+			Name:         env.EnvName,
+			Reason:       fmt.Sprintf("not deployed in %d days", idleDays),
+			LastActiveAt: env.UpdateTime,
+			Suggestion:   "delete the environment or set its recycle day if it is no longer needed",
+		})
+	}
+	return resp, nil
+}
+
+func staleCodeHosts(log *zap.SugaredLogger) ([]*StaleResource, error) {
+	codehosts, err := systemconfig.New().ListCodeHostsInternal()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*StaleResource, 0)
+	for _, codehost := range codehosts {
+		references, err := ListCodeHostReferences(codehost.ID, log)
+		if err != nil {
+			return nil, err
+		}
+		if len(references) > 0 {
+			continue
+		}
+		resp = append(resp, &StaleResource{
+			Type:       StaleResourceCodeHost,
+			Name:       codeHostName(codehost),
+			ID:         strconv.Itoa(codehost.ID),
+			Reason:     "not referenced by any build, testing, scanning, service or workflow",
+			Archivable: true,
+			Suggestion: "the archived codehost is disabled and keeps its tokens",
+		})
+	}
+	return resp, nil
+}
+
+func staleRegistries() ([]*StaleResource, error) {
+	registries, err := commonrepo.NewRegistryNamespaceColl().FindAll(&commonrepo.FindRegOps{})
+	if err != nil {
+		return nil, err
+	}
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{ExcludeStatus: []string{setting.ProductStatusDeleting}})
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, env := range envs {
+		used[env.RegistryID] = true
+	}
+
+	resp := make([]*StaleResource, 0)
+	for _, registry := range registries {
+		if registry.IsDefault || used[registry.ID.Hex()] {
+			continue
+		}
+		resp = append(resp, &StaleResource{
+			Type:       StaleResourceRegistry,
+			Name:       registry.RegAddr + "/" + registry.Namespace,
+			ID:         registry.ID.Hex(),
+			Reason:     "not the default registry and not used by any environment",
+			Suggestion: "delete the registry if no build pushes images to it",
+		})
+	}
+	return resp, nil
+}
+
+func staleWebhooks() ([]*StaleResource, error) {
+	hooks, err := commonrepo.NewWebHookColl().List()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*StaleResource, 0)
+	for _, hook := range hooks {
+		if len(hook.References) > 0 {
+			continue
+		}
+		resp = append(resp, &StaleResource{
+			Type:       StaleResourceWebhook,
+			Name:       fmt.Sprintf("%s/%s/%s", hook.Address, hook.Owner, hook.Repo),
+			ID:         hook.ID.Hex(),
+			Reason:     "registered on the provider but not referenced by any workflow or service",
+			Archivable: true,
+			Suggestion: "the archived webhook is deleted from the provider",
+		})
+	}
+	return resp, nil
+}
+
+type ArchiveStaleResourcesArgs struct {
+	Resources []*StaleResource `json:"resources"`
+}
+
+type ArchiveStaleResourceResult struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ArchiveStaleResources archives the resources of the report one by one, the failures are returned in the
+// results instead of stopping the others.
+func ArchiveStaleResources(args *ArchiveStaleResourcesArgs, user string, log *zap.SugaredLogger) []*ArchiveStaleResourceResult {
+	results := make([]*ArchiveStaleResourceResult, 0, len(args.Resources))
+	for _, resource := range args.Resources {
+		result := &ArchiveStaleResourceResult{Type: resource.Type, Name: resource.Name, ID: resource.ID}
+		if err := archiveStaleResource(resource, user, log); err != nil {
+			log.Errorf("failed to archive %s %s, err: %s", resource.Type, resource.Name, err)
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func archiveStaleResource(resource *StaleResource, user string, log *zap.SugaredLogger) error {
+	switch resource.Type {
+	case StaleResourceWorkflow:
+		return commonrepo.NewWorkflowV4Coll().SetArchived(resource.Name, true, user)
+	case StaleResourceCodeHost:
+		id, err := strconv.Atoi(resource.ID)
+		if err != nil {
+			return fmt.Errorf("invalid codehost id %s", resource.ID)
+		}
+		// the codehost may be referenced since the report is generated.
+		references, err := ListCodeHostReferences(id, log)
+		if err != nil {
+			return err
+		}
+		if len(references) > 0 {
+			return fmt.Errorf("codehost %d is referenced by %d resources", id, len(references))
+		}
+		_, err = codehostservice.SetCodeHostEnabled(id, false, "archived as a stale codehost", user, log)
+		return err
+	case StaleResourceWebhook:
+		return archiveStaleWebhook(resource.ID)
+	}
+	return fmt.Errorf("%s can not be archived", resource.Type)
+}
+
+func archiveStaleWebhook(id string) error {
+	hooks, err := commonrepo.NewWebHookColl().List()
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if hook.ID.Hex() != id {
+			continue
+		}
+		if len(hook.References) > 0 {
+			return fmt.Errorf("webhook %s/%s is referenced by %d resources", hook.Owner, hook.Repo, len(hook.References))
+		}
+		codehosts, err := systemconfig.New().ListCodeHostsInternal()
+		if err != nil {
+			return err
+		}
+		for _, ch := range codehosts {
+			if ch.RepoAddress() != hook.Address {
+				continue
+			}
+			return webhook.NewClient().RemoveWebHook(&webhook.TaskOption{
+				ID:          ch.ID,
+				Name:        staleWebhookRef,
+				Owner:       hook.Owner,
+				Namespace:   hook.Owner,
+				Repo:        hook.Repo,
+				Address:     hook.Address,
+				Token:       ch.AccessToken,
+				AK:          ch.AccessKey,
+				SK:          ch.SecretKey,
+				Region:      ch.Region,
+				EnableProxy: ch.UseProxy(),
+				ProxyAddr:   ch.ProxyAddr(config.ProxyHTTPSAddr()),
+				Ref:         staleWebhookRef,
+				From:        ch.Type,
+			})
+		}
+		return fmt.Errorf("no enabled codehost of %s is found to delete the webhook", hook.Address)
+	}
+	return fmt.Errorf("webhook %s is not found", id)
+}
+
+func codeHostName(codehost *systemconfig.CodeHost) string {
+	if codehost.Alias != "" {
+		return codehost.Alias
+	}
+	return codehost.Address
+}
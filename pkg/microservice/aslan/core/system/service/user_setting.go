@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/i18n"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+type UserSettingArgs struct {
+	// Language is zh-CN or en, the default language of the system is used if it is empty.
+	Language string `json:"language"`
+}
+
+func GetUserSetting(userID string, logger *zap.SugaredLogger) (*commonmodels.UserSetting, error) {
+	setting, err := commonrepo.NewUserSettingColl().Find(userID)
+	if err == mongo.ErrNoDocuments {
+		return &commonmodels.UserSetting{UserID: userID}, nil
+	}
+	if err != nil {
+		logger.Errorf("failed to find settings of user %s, err: %s", userID, err)
+		return nil, e.ErrGetUserSetting.AddErr(err)
+	}
+	return setting, nil
+}
+
+func UpdateUserSetting(userID, userName string, args *UserSettingArgs, logger *zap.SugaredLogger) error {
+	lang := ""
+	if args.Language != "" {
+		if lang = i18n.Match(args.Language); lang == "" {
+			return e.ErrUpdateUserSetting.AddDesc(fmt.Sprintf("unsupported language %s, it should be %s or %s", args.Language, i18n.ZhCN, i18n.En))
+		}
+	}
+
+	err := commonrepo.NewUserSettingColl().Upsert(&commonmodels.UserSetting{
+		UserID:   userID,
+		UserName: userName,
+		Language: lang,
+	})
+	if err != nil {
+		logger.Errorf("failed to update settings of user %s, err: %s", userID, err)
+		return e.ErrUpdateUserSetting.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateNotificationPreference updates how the user is mentioned by the notification routes.
+func UpdateNotificationPreference(userID, userName string, args *commonmodels.NotificationPreference, logger *zap.SugaredLogger) error {
+	if args.MinSeverity != "" && !instantmessage.ValidSeverity(args.MinSeverity) {
+		return e.ErrUpdateNotificationPreference.AddDesc(fmt.Sprintf("unsupported severity %s", args.MinSeverity))
+	}
+	if err := instantmessage.ValidateQuietHours(args.QuietHours); err != nil {
+		return e.ErrUpdateNotificationPreference.AddErr(err)
+	}
+
+	if err := commonrepo.NewUserSettingColl().UpdateNotification(userID, userName, args); err != nil {
+		logger.Errorf("failed to update notification preference of user %s, err: %s", userID, err)
+		return e.ErrUpdateNotificationPreference.AddErr(err)
+	}
+	return nil
+}
+
+// GetUserLanguage returns the language chosen by the user, it is registered as the user language resolver of i18n.
+func GetUserLanguage(userName string) string {
+	setting, err := commonrepo.NewUserSettingColl().FindByUserName(userName)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Warnf("failed to find settings of user %s, err: %s", userName, err)
+		}
+		return ""
+	}
+	return setting.Language
+}
@@ -89,3 +89,21 @@ func RemoveBuildTemplate(c *gin.Context) {
 
 	ctx.Err = templateservice.RemoveBuildTemplate(c.Param("id"), ctx.Logger)
 }
+
+func ApplyBuildTemplate(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(templateservice.ApplyBuildTemplateArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	if !args.Preview {
+		bs, _ := json.Marshal(args)
+		internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "模板-构建-批量应用", c.Param("id"), string(bs), ctx.Logger)
+	}
+
+	ctx.Resp, ctx.Err = templateservice.ApplyBuildTemplate(c.Param("id"), ctx.UserName, args, ctx.Logger)
+}
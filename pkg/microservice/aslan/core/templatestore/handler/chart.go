@@ -23,6 +23,7 @@ import (
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
 	templateservice "github.com/koderover/zadig/pkg/microservice/aslan/core/templatestore/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	"github.com/koderover/zadig/pkg/tool/errors"
@@ -30,10 +31,15 @@ import (
 
 type addChartArgs struct {
 	*fs.DownloadFromSourceArgs
+	template.TemplateMeta
 
 	Name string `json:"name"`
 }
 
+type rerenderTemplateReferenceArgs struct {
+	Services []*template.ServiceReference `json:"services"`
+}
+
 func GetChartTemplate(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -60,7 +66,7 @@ func ListChartTemplates(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	ctx.Resp, ctx.Err = templateservice.ListChartTemplates(ctx.Logger)
+	ctx.Resp, ctx.Err = templateservice.ListChartTemplates(c.Query("language"), c.Query("framework"), ctx.Logger)
 }
 
 func AddChartTemplate(c *gin.Context) {
@@ -76,7 +82,7 @@ func AddChartTemplate(c *gin.Context) {
 	bs, _ := json.Marshal(args)
 	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新建", "模板库-Chart", args.Name, string(bs), ctx.Logger)
 
-	ctx.Err = templateservice.AddChartTemplate(args.Name, args.DownloadFromSourceArgs, ctx.Logger)
+	ctx.Err = templateservice.AddChartTemplate(args.Name, args.DownloadFromSourceArgs, &args.TemplateMeta, ctx.Logger)
 }
 
 func UpdateChartTemplate(c *gin.Context) {
@@ -92,7 +98,7 @@ func UpdateChartTemplate(c *gin.Context) {
 	bs, _ := json.Marshal(args)
 	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "模板库-Chart", args.Name, string(bs), ctx.Logger)
 
-	ctx.Err = templateservice.UpdateChartTemplate(c.Param("name"), args.DownloadFromSourceArgs, ctx.Logger)
+	ctx.Err = templateservice.UpdateChartTemplate(c.Param("name"), args.DownloadFromSourceArgs, &args.TemplateMeta, ctx.Logger)
 }
 
 func UpdateChartTemplateVariables(c *gin.Context) {
@@ -117,6 +123,26 @@ func SyncChartTemplateReference(c *gin.Context) {
 	ctx.Err = templateservice.SyncHelmTemplateReference(ctx.UserName, c.Param("name"), ctx.Logger)
 }
 
+func GetChartTemplateReference(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = templateservice.GetChartTemplateReference(c.Param("name"), ctx.Logger)
+}
+
+func RerenderChartTemplateReference(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &rerenderTemplateReferenceArgs{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = errors.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "重新渲染", "模板库-Chart", c.Param("name"), "", ctx.Logger)
+	ctx.Resp, ctx.Err = templateservice.RerenderChartTemplateReference(c.Param("name"), args.Services, ctx.Logger)
+}
+
 func RemoveChartTemplate(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
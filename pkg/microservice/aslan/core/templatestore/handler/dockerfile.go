@@ -22,6 +22,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
 	templateservice "github.com/koderover/zadig/pkg/microservice/aslan/core/templatestore/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
@@ -76,8 +77,10 @@ func UpdateDockerfileTemplate(c *gin.Context) {
 }
 
 type listDockerfileQuery struct {
-	PageSize int `json:"page_size" form:"page_size,default=100"`
-	PageNum  int `json:"page_num"  form:"page_num,default=1"`
+	PageSize  int    `json:"page_size" form:"page_size,default=100"`
+	PageNum   int    `json:"page_num"  form:"page_num,default=1"`
+	Language  string `json:"language"  form:"language"`
+	Framework string `json:"framework" form:"framework"`
 }
 
 type ListDockefileResp struct {
@@ -96,7 +99,7 @@ func ListDockerfileTemplate(c *gin.Context) {
 		return
 	}
 
-	dockerfileTemplateList, total, err := templateservice.ListDockerfileTemplate(args.PageNum, args.PageSize, ctx.Logger)
+	dockerfileTemplateList, total, err := templateservice.ListDockerfileTemplate(args.PageNum, args.PageSize, args.Language, args.Framework, ctx.Logger)
 	resp := ListDockefileResp{
 		DockerfileTemplates: dockerfileTemplateList,
 		Total:               total,
@@ -128,6 +131,46 @@ func GetDockerfileTemplateReference(c *gin.Context) {
 	ctx.Resp, ctx.Err = templateservice.GetDockerfileTemplateReference(c.Param("id"), ctx.Logger)
 }
 
+type rerenderDockerfileTemplateReferenceArgs struct {
+	Builds []*template.BuildReference `json:"builds"`
+}
+
+func RerenderDockerfileTemplateReference(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &rerenderDockerfileTemplateReferenceArgs{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = err
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "重新渲染", "模板库-Dockerfile", c.Param("id"), "", ctx.Logger)
+
+	ctx.Resp, ctx.Err = templateservice.RerenderDockerfileTemplateReference(c.Param("id"), args.Builds, ctx.Logger)
+}
+
+type renderDockerfileTemplateReq struct {
+	Variables []*commonmodels.ChartVariable `json:"variables"`
+}
+
+type renderDockerfileTemplateResp struct {
+	Content string `json:"content"`
+}
+
+func RenderDockerfileTemplate(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	req := &renderDockerfileTemplateReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		ctx.Err = err
+		return
+	}
+
+	content, err := templateservice.RenderDockerfileTemplate(c.Param("id"), req.Variables, ctx.Logger)
+	ctx.Resp, ctx.Err = &renderDockerfileTemplateResp{Content: content}, err
+}
+
 type validateDockerfileTemplateReq struct {
 	Content string `json:"content"`
 }
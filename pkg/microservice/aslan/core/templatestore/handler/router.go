@@ -35,6 +35,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		chart.POST("", AddChartTemplate)
 		chart.PUT("/:name", UpdateChartTemplate)
 		chart.POST("/:name/reference", SyncChartTemplateReference)
+		chart.GET("/:name/reference", GetChartTemplateReference)
+		chart.POST("/:name/rerender", RerenderChartTemplateReference)
 		chart.PUT("/:name/variables", UpdateChartTemplateVariables)
 		chart.DELETE("/:name", RemoveChartTemplate)
 	}
@@ -47,6 +49,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		dockerfile.GET("/:id", GetDockerfileTemplateDetail)
 		dockerfile.DELETE("/:id", DeleteDockerfileTemplate)
 		dockerfile.GET("/:id/reference", GetDockerfileTemplateReference)
+		dockerfile.POST("/:id/rerender", RerenderDockerfileTemplateReference)
+		dockerfile.POST("/:id/render", RenderDockerfileTemplate)
 		dockerfile.POST("/validation", ValidateDockerfileTemplate)
 	}
 
@@ -68,6 +72,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	{
 		build.POST("", AddBuildTemplate)
 		build.PUT("/:id", UpdateBuildTemplate)
+		build.POST("/:id/apply", ApplyBuildTemplate)
 		build.GET("", ListBuildTemplates)
 		build.GET("/:id", GetBuildTemplate)
 		build.DELETE("/:id", RemoveBuildTemplate)
@@ -18,15 +18,22 @@ package service
 
 import (
 	"fmt"
+	"reflect"
+	"time"
 
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/setting"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
 )
 
 type BuildTemplateBrief struct {
@@ -46,6 +53,9 @@ func AddBuildTemplate(userName string, build *commonmodels.BuildTemplate, logger
 	if err := commonutil.CheckDefineResourceParam(build.PreBuild.ResReq, build.PreBuild.ResReqSpec); err != nil {
 		return e.ErrCreateBuildModule.AddDesc(err.Error())
 	}
+	if err := commonutil.CheckArtifactDownloads(build.PreBuild.Downloads); err != nil {
+		return e.ErrCreateBuildModule.AddDesc(err.Error())
+	}
 	build.UpdateBy = userName
 	if err := commonrepo.NewBuildTemplateColl().Create(build); err != nil {
 		log.Errorf("[Build.Upsert] %s error: %s", build.Name, err)
@@ -111,5 +121,232 @@ func UpdateBuildTemplate(id string, buildTemplate *commonmodels.BuildTemplate, l
 	if err != nil {
 		return err
 	}
+	if buildTemplate.PreBuild != nil {
+		if err := commonutil.CheckArtifactDownloads(buildTemplate.PreBuild.Downloads); err != nil {
+			return e.ErrUpdateBuildModule.AddDesc(err.Error())
+		}
+	}
 	return commonrepo.NewBuildTemplateColl().Update(id, buildTemplate)
 }
+
+type ApplyBuildTemplateArgs struct {
+	// Template is the new content of the build template, the template is kept if it is nil.
+	Template *commonmodels.BuildTemplate `json:"template"`
+	// Builds are the builds to inherit the template in addition to the ones inheriting it already.
+	Builds []*BuildTemplateApplyTarget `json:"builds"`
+	// Preview returns the affected services and diffs without saving anything.
+	Preview bool `json:"preview"`
+}
+
+type BuildTemplateApplyTarget struct {
+	ProjectName string `json:"project_name"`
+	Name        string `json:"name"`
+}
+
+type ApplyBuildTemplateResp struct {
+	Applied  bool                        `json:"applied"`
+	Services []*BuildTemplateServiceDiff `json:"services"`
+}
+
+// BuildTemplateServiceDiff is the effective build of a service module before and after the template is applied.
+type BuildTemplateServiceDiff struct {
+	ProjectName   string   `json:"project_name"`
+	BuildName     string   `json:"build_name"`
+	ServiceName   string   `json:"service_name"`
+	ServiceModule string   `json:"service_module"`
+	Changed       []string `json:"changed"`
+	Current       string   `json:"current"`
+	Latest        string   `json:"latest"`
+}
+
+// buildSections are the sections of a build which can be inherited from the build template.
+type buildSections struct {
+	Timeout      int                     `json:"timeout"`
+	BuildOS      string                  `json:"build_os"`
+	ImageFrom    string                  `json:"image_from"`
+	ImageID      string                  `json:"image_id"`
+	Installs     []*commonmodels.Item    `json:"installs"`
+	Envs         []*commonmodels.KeyVal  `json:"envs"`
+	CacheEnable  bool                    `json:"cache_enable"`
+	CacheDirType types.CacheDirType      `json:"cache_dir_type"`
+	CacheUserDir string                  `json:"cache_user_dir"`
+	Scripts      string                  `json:"scripts"`
+	PostBuild    *commonmodels.PostBuild `json:"post_build"`
+}
+
+// ApplyBuildTemplate updates the build template and makes the given builds inherit it, the effective build of
+// every affected service module is returned so that the changes can be previewed before they are saved.
+func ApplyBuildTemplate(id, userName string, args *ApplyBuildTemplateArgs, logger *zap.SugaredLogger) (*ApplyBuildTemplateResp, error) {
+	buildTemplate, err := GetBuildTemplateByID(id)
+	if err != nil {
+		return nil, e.ErrApplyBuildTemplate.AddDesc(fmt.Sprintf("failed to find build template with id: %s, err: %s", id, err))
+	}
+	latestTemplate := buildTemplate
+	if args.Template != nil {
+		if args.Template.PreBuild == nil {
+			return nil, e.ErrApplyBuildTemplate.AddDesc("empty pre build of build template")
+		}
+		latestTemplate = args.Template
+	}
+
+	builds, err := commonrepo.NewBuildColl().List(&commonrepo.BuildListOption{TemplateID: id})
+	if err != nil {
+		return nil, e.ErrApplyBuildTemplate.AddErr(err)
+	}
+	inherited := sets.NewString()
+	for _, build := range builds {
+		inherited.Insert(build.ProductName + "/" + build.Name)
+	}
+	var convertedBuilds []*commonmodels.Build
+	for _, target := range args.Builds {
+		if inherited.Has(target.ProjectName + "/" + target.Name) {
+			continue
+		}
+		build, err := commonrepo.NewBuildColl().Find(&commonrepo.BuildFindOption{Name: target.Name, ProductName: target.ProjectName})
+		if err != nil {
+			return nil, e.ErrApplyBuildTemplate.AddDesc(fmt.Sprintf("failed to find build %s in project %s, err: %s", target.Name, target.ProjectName, err))
+		}
+		if build.JenkinsBuild != nil {
+			return nil, e.ErrApplyBuildTemplate.AddDesc(fmt.Sprintf("jenkins build %s can not inherit build template", build.Name))
+		}
+		builds = append(builds, build)
+		convertedBuilds = append(convertedBuilds, build)
+		inherited.Insert(target.ProjectName + "/" + target.Name)
+	}
+
+	resp := &ApplyBuildTemplateResp{Services: make([]*BuildTemplateServiceDiff, 0)}
+	latestBuilds := make(map[*commonmodels.Build]*commonmodels.Build, len(builds))
+	for _, build := range builds {
+		latestBuild := build
+		if build.TemplateID != id {
+			latestBuild = inheritBuildTemplate(build, id)
+		}
+		latestBuilds[build] = latestBuild
+
+		for _, target := range latestBuild.Targets {
+			current, err := effectiveBuildSections(build, target.ServiceName, target.ServiceModule, id, buildTemplate)
+			if err != nil {
+				return nil, e.ErrApplyBuildTemplate.AddErr(err)
+			}
+			latestCopy := *latestBuild
+			commonservice.FillBuildDetail(&latestCopy, latestTemplate, target.ServiceName, target.ServiceModule)
+			latest := newBuildSections(&latestCopy)
+
+			diff := &BuildTemplateServiceDiff{
+				ProjectName:   build.ProductName,
+				BuildName:     build.Name,
+				ServiceName:   target.ServiceName,
+				ServiceModule: target.ServiceModule,
+				Changed:       diffBuildSections(current, latest),
+			}
+			currentYaml, _ := yaml.Marshal(current)
+			latestYaml, _ := yaml.Marshal(latest)
+			diff.Current, diff.Latest = string(currentYaml), string(latestYaml)
+			resp.Services = append(resp.Services, diff)
+		}
+	}
+	if args.Preview {
+		return resp, nil
+	}
+
+	if args.Template != nil {
+		if err := commonutil.CheckDefineResourceParam(args.Template.PreBuild.ResReq, args.Template.PreBuild.ResReqSpec); err != nil {
+			return nil, e.ErrApplyBuildTemplate.AddDesc(err.Error())
+		}
+		args.Template.UpdateBy = userName
+		if err := UpdateBuildTemplate(id, args.Template, logger); err != nil {
+			return nil, e.ErrApplyBuildTemplate.AddErr(err)
+		}
+	}
+	for _, build := range convertedBuilds {
+		latestBuild := latestBuilds[build]
+		latestBuild.UpdateBy = userName
+		latestBuild.UpdateTime = time.Now().Unix()
+		if err := commonrepo.NewBuildColl().Update(latestBuild); err != nil {
+			logger.Errorf("failed to apply build template %s to build %s, err: %s", id, build.Name, err)
+			return nil, e.ErrApplyBuildTemplate.AddErr(err)
+		}
+	}
+	resp.Applied = true
+	return resp, nil
+}
+
+// inheritBuildTemplate converts the build to inherit the template, the repos and envs of the build are
+// moved to its service modules.
+func inheritBuildTemplate(build *commonmodels.Build, templateID string) *commonmodels.Build {
+	resp := *build
+	resp.TemplateID = templateID
+	resp.Targets = make([]*commonmodels.ServiceModuleTarget, 0, len(build.Targets))
+	for _, target := range build.Targets {
+		newTarget := *target
+		if build.TemplateID == "" {
+			newTarget.Repos = build.SafeRepos()
+			if build.PreBuild != nil {
+				newTarget.Envs = build.PreBuild.Envs
+			}
+		}
+		resp.Targets = append(resp.Targets, &newTarget)
+	}
+	return &resp
+}
+
+func effectiveBuildSections(build *commonmodels.Build, serviceName, serviceModule, templateID string, buildTemplate *commonmodels.BuildTemplate) (*buildSections, error) {
+	if build.TemplateID == "" {
+		return newBuildSections(build), nil
+	}
+	if build.TemplateID != templateID {
+		var err error
+		if buildTemplate, err = GetBuildTemplateByID(build.TemplateID); err != nil {
+			return nil, fmt.Errorf("failed to find build template with id: %s, err: %s", build.TemplateID, err)
+		}
+	}
+	buildCopy := *build
+	commonservice.FillBuildDetail(&buildCopy, buildTemplate, serviceName, serviceModule)
+	return newBuildSections(&buildCopy), nil
+}
+
+func newBuildSections(build *commonmodels.Build) *buildSections {
+	resp := &buildSections{
+		Timeout:      build.Timeout,
+		CacheEnable:  build.CacheEnable,
+		CacheDirType: build.CacheDirType,
+		CacheUserDir: build.CacheUserDir,
+		Scripts:      build.Scripts,
+		PostBuild:    build.PostBuild,
+	}
+	if build.PreBuild != nil {
+		resp.BuildOS = build.PreBuild.BuildOS
+		resp.ImageFrom = build.PreBuild.ImageFrom
+		resp.ImageID = build.PreBuild.ImageID
+		resp.Installs = build.PreBuild.Installs
+		for _, env := range build.PreBuild.Envs {
+			kv := *env
+			if kv.IsCredential {
+				kv.Value = setting.MaskValue
+			}
+			resp.Envs = append(resp.Envs, &kv)
+		}
+	}
+	return resp
+}
+
+func diffBuildSections(current, latest *buildSections) []string {
+	resp := make([]string, 0)
+	for _, section := range []struct {
+		name            string
+		current, latest interface{}
+	}{
+		{"timeout", current.Timeout, latest.Timeout},
+		{"image", []string{current.BuildOS, current.ImageFrom, current.ImageID}, []string{latest.BuildOS, latest.ImageFrom, latest.ImageID}},
+		{"installs", current.Installs, latest.Installs},
+		{"envs", current.Envs, latest.Envs},
+		{"cache", []interface{}{current.CacheEnable, current.CacheDirType, current.CacheUserDir}, []interface{}{latest.CacheEnable, latest.CacheDirType, latest.CacheUserDir}},
+		{"scripts", current.Scripts, latest.Scripts},
+		{"post_build", current.PostBuild, latest.PostBuild},
+	} {
+		if !reflect.DeepEqual(section.current, section.latest) {
+			resp = append(resp, section.name)
+		}
+	}
+	return resp
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package service
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
@@ -89,15 +90,17 @@ func GetChartTemplate(name string, logger *zap.SugaredLogger) (*template.Chart,
 	}
 
 	return &template.Chart{
-		Name:       name,
-		CodehostID: chart.CodeHostID,
-		Owner:      chart.Owner,
-		Repo:       chart.Repo,
-		Path:       chart.Path,
-		Branch:     chart.Branch,
-		Namespace:  chart.GetNamespace(),
-		Files:      fis,
-		Variables:  variables,
+		Name:         name,
+		CodehostID:   chart.CodeHostID,
+		Owner:        chart.Owner,
+		Repo:         chart.Repo,
+		Path:         chart.Path,
+		Branch:       chart.Branch,
+		Namespace:    chart.GetNamespace(),
+		Files:        fis,
+		Variables:    variables,
+		Revision:     chart.Revision,
+		TemplateMeta: chartTemplateMeta(chart),
 	}, nil
 }
 
@@ -129,7 +132,15 @@ func getChartTemplateDefaultVariables() []*commonmodels.ChartVariable {
 	return resp
 }
 
-func ListChartTemplates(logger *zap.SugaredLogger) (*ChartTemplateListResp, error) {
+func chartTemplateMeta(chart *commonmodels.Chart) template.TemplateMeta {
+	return template.TemplateMeta{
+		Language:    chart.Language,
+		Framework:   chart.Framework,
+		Description: chart.Description,
+	}
+}
+
+func ListChartTemplates(language, framework string, logger *zap.SugaredLogger) (*ChartTemplateListResp, error) {
 	cs, err := mongodb.NewChartColl().List()
 	if err != nil {
 		logger.Errorf("Failed to list chart templates, err: %s", err)
@@ -138,14 +149,19 @@ func ListChartTemplates(logger *zap.SugaredLogger) (*ChartTemplateListResp, erro
 
 	res := make([]*template.Chart, 0, len(cs))
 	for _, c := range cs {
+		if (language != "" && c.Language != language) || (framework != "" && c.Framework != framework) {
+			continue
+		}
 		res = append(res, &template.Chart{
-			Name:       c.Name,
-			CodehostID: c.CodeHostID,
-			Owner:      c.Owner,
-			Namespace:  c.GetNamespace(),
-			Repo:       c.Repo,
-			Path:       c.Path,
-			Branch:     c.Branch,
+			Name:         c.Name,
+			CodehostID:   c.CodeHostID,
+			Owner:        c.Owner,
+			Namespace:    c.GetNamespace(),
+			Repo:         c.Repo,
+			Path:         c.Path,
+			Branch:       c.Branch,
+			Revision:     c.Revision,
+			TemplateMeta: chartTemplateMeta(c),
 		})
 	}
 
@@ -201,7 +217,7 @@ func parseTemplateVariables(name, path, source string, logger *zap.SugaredLogger
 	return strSet.List(), nil
 }
 
-func AddChartTemplate(name string, args *fs.DownloadFromSourceArgs, logger *zap.SugaredLogger) error {
+func AddChartTemplate(name string, args *fs.DownloadFromSourceArgs, meta *template.TemplateMeta, logger *zap.SugaredLogger) error {
 	if mongodb.NewChartColl().Exist(name) {
 		return fmt.Errorf("a chart template with name %s is already existing", name)
 	}
@@ -249,10 +265,13 @@ func AddChartTemplate(name string, args *fs.DownloadFromSourceArgs, logger *zap.
 		Sha1:           sha1,
 		ChartVariables: variables,
 		Source:         ch.Type,
+		Language:       meta.Language,
+		Framework:      meta.Framework,
+		Description:    meta.Description,
 	})
 }
 
-func UpdateChartTemplate(name string, args *fs.DownloadFromSourceArgs, logger *zap.SugaredLogger) error {
+func UpdateChartTemplate(name string, args *fs.DownloadFromSourceArgs, meta *template.TemplateMeta, logger *zap.SugaredLogger) error {
 	chart, err := mongodb.NewChartColl().Get(name)
 	if err != nil {
 		logger.Errorf("Failed to get chart template %s, err: %s", name, err)
@@ -282,7 +301,7 @@ func UpdateChartTemplate(name string, args *fs.DownloadFromSourceArgs, logger *z
 
 	if chart.Sha1 == sha1 {
 		logger.Debug("Chart %s has no changes, skip updating.", name)
-		return nil
+		return mongodb.NewChartColl().UpdateMeta(name, meta.Language, meta.Framework, meta.Description)
 	}
 
 	variablesNames, err := parseTemplateVariables(name, args.Path, ch.Type, logger)
@@ -314,6 +333,9 @@ func UpdateChartTemplate(name string, args *fs.DownloadFromSourceArgs, logger *z
 		Sha1:           sha1,
 		ChartVariables: variables,
 		Source:         ch.Type,
+		Language:       meta.Language,
+		Framework:      meta.Framework,
+		Description:    meta.Description,
 	})
 
 	return err
@@ -323,6 +345,40 @@ func SyncHelmTemplateReference(userName, name string, logger *zap.SugaredLogger)
 	return service.SyncServiceFromTemplate(userName, setting.SourceFromChartTemplate, "", name, logger)
 }
 
+// GetChartTemplateReference lists the services rendered from the chart template, a service is
+// outdated if it is rendered from an earlier revision of the template.
+func GetChartTemplateReference(name string, logger *zap.SugaredLogger) ([]*template.ServiceReference, error) {
+	chart, err := mongodb.NewChartColl().Get(name)
+	if err != nil {
+		logger.Errorf("Failed to get chart template %s, err: %s", name, err)
+		return nil, err
+	}
+	services, err := commonrepo.NewServiceColl().ListMaxRevisionServicesByChartTemplate(name)
+	if err != nil {
+		logger.Errorf("Failed to list services of chart template %s, err: %s", name, err)
+		return nil, err
+	}
+
+	ret := make([]*template.ServiceReference, 0, len(services))
+	for _, svc := range services {
+		reference := &template.ServiceReference{
+			ProjectName: svc.ProductName,
+			ServiceName: svc.ServiceName,
+		}
+		creation := &commonmodels.CreateFromChartTemplate{}
+		if bs, err := json.Marshal(svc.CreateFrom); err == nil && json.Unmarshal(bs, creation) == nil {
+			reference.TemplateRevision = creation.TemplateRevision
+		}
+		reference.Outdated = reference.TemplateRevision < chart.Revision
+		ret = append(ret, reference)
+	}
+	return ret, nil
+}
+
+func RerenderChartTemplateReference(name string, services []*template.ServiceReference, logger *zap.SugaredLogger) ([]*template.RerenderResult, error) {
+	return service.RerenderServicesFromChartTemplate(name, services, logger)
+}
+
 func UpdateChartTemplateVariables(name string, args []*commonmodels.Variable, logger *zap.SugaredLogger) error {
 	chart, err := mongodb.NewChartColl().Get(name)
 	if err != nil {
@@ -359,6 +415,9 @@ func UpdateChartTemplateVariables(name string, args []*commonmodels.Variable, lo
 		CodeHostID:     chart.CodeHostID,
 		Sha1:           chart.Sha1,
 		ChartVariables: variables,
+		Language:       chart.Language,
+		Framework:      chart.Framework,
+		Description:    chart.Description,
 	})
 
 	if err != nil {
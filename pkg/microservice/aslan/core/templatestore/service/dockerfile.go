@@ -18,53 +18,110 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	dockerfileinstructions "github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
+	"github.com/koderover/zadig/pkg/setting"
 )
 
 func CreateDockerfileTemplate(template *template.DockerfileTemplate, logger *zap.SugaredLogger) error {
-	err := commonrepo.NewDockerfileTemplateColl().Create(&commonmodels.DockerfileTemplate{
-		Name:    template.Name,
-		Content: template.Content,
-	})
+	obj := &commonmodels.DockerfileTemplate{
+		ID:          primitive.NewObjectID(),
+		Name:        template.Name,
+		Content:     template.Content,
+		Language:    template.Language,
+		Framework:   template.Framework,
+		Description: template.Description,
+	}
+	rev, err := commonrepo.NewDockerfileTemplateColl().GetNextRevision(obj.ID.Hex())
 	if err != nil {
+		logger.Errorf("Failed to get revision of dockerfile template %s, err: %s", obj.Name, err)
+		return err
+	}
+	obj.Revision = rev
+	if err = commonrepo.NewDockerfileTemplateColl().Create(obj); err != nil {
 		logger.Errorf("create dockerfile template error: %s", err)
+		return err
 	}
-	return err
+	return saveDockerfileTemplateVersion(obj, logger)
 }
 
 func UpdateDockerfileTemplate(id string, template *template.DockerfileTemplate, logger *zap.SugaredLogger) error {
-	err := commonrepo.NewDockerfileTemplateColl().Update(
-		id,
-		&commonmodels.DockerfileTemplate{
-			Name:    template.Name,
-			Content: template.Content,
-		},
-	)
+	current, err := commonrepo.NewDockerfileTemplateColl().GetById(id)
 	if err != nil {
+		logger.Errorf("Failed to get dockerfile template from id: %s, the error is: %s", id, err)
+		return err
+	}
+	obj := &commonmodels.DockerfileTemplate{
+		Name:        template.Name,
+		Content:     template.Content,
+		Language:    template.Language,
+		Framework:   template.Framework,
+		Description: template.Description,
+		Revision:    current.Revision,
+	}
+	// only a change of the content creates a new revision.
+	contentChanged := current.Content != template.Content
+	if contentChanged {
+		if obj.Revision, err = commonrepo.NewDockerfileTemplateColl().GetNextRevision(id); err != nil {
+			logger.Errorf("Failed to get next revision of dockerfile template %s, err: %s", id, err)
+			return err
+		}
+	}
+	if err = commonrepo.NewDockerfileTemplateColl().Update(id, obj); err != nil {
 		logger.Errorf("update dockerfile template error: %s", err)
+		return err
+	}
+	if !contentChanged {
+		return nil
+	}
+	obj.ID = current.ID
+	return saveDockerfileTemplateVersion(obj, logger)
+}
+
+func saveDockerfileTemplateVersion(obj *commonmodels.DockerfileTemplate, logger *zap.SugaredLogger) error {
+	err := commonrepo.NewDockerfileTemplateVersionColl().Create(&commonmodels.DockerfileTemplateVersion{
+		TemplateID: obj.ID.Hex(),
+		Revision:   obj.Revision,
+		Content:    obj.Content,
+		CreateTime: time.Now().Unix(),
+	})
+	if err != nil {
+		logger.Errorf("Failed to save revision %d of dockerfile template %s, err: %s", obj.Revision, obj.Name, err)
 	}
 	return err
 }
 
-func ListDockerfileTemplate(pageNum, pageSize int, logger *zap.SugaredLogger) ([]*template.DockerfileListObject, int, error) {
+func ListDockerfileTemplate(pageNum, pageSize int, language, framework string, logger *zap.SugaredLogger) ([]*template.DockerfileListObject, int, error) {
 	resp := make([]*template.DockerfileListObject, 0)
-	templateList, total, err := commonrepo.NewDockerfileTemplateColl().List(pageNum, pageSize)
+	templateList, total, err := commonrepo.NewDockerfileTemplateColl().List(pageNum, pageSize, &commonrepo.DockerfileTemplateListOption{
+		Language:  language,
+		Framework: framework,
+	})
 	if err != nil {
 		logger.Errorf("list dockerfile template error: %s", err)
 		return resp, 0, err
 	}
 	for _, obj := range templateList {
 		resp = append(resp, &template.DockerfileListObject{
-			ID:   obj.ID.Hex(),
-			Name: obj.Name,
+			ID:       obj.ID.Hex(),
+			Name:     obj.Name,
+			Revision: obj.Revision,
+			TemplateMeta: template.TemplateMeta{
+				Language:    obj.Language,
+				Framework:   obj.Framework,
+				Description: obj.Description,
+			},
 		})
 	}
 	return resp, total, err
@@ -82,26 +139,98 @@ func DeleteDockerfileTemplate(id string, logger *zap.SugaredLogger) error {
 	err = commonrepo.NewDockerfileTemplateColl().DeleteByID(id)
 	if err != nil {
 		logger.Errorf("Failed to delete dockerfile template of id: %s, the error is: %s", id, err)
+		return err
 	}
-	return err
+	if err = commonrepo.NewDockerfileTemplateVersionColl().DeleteByTemplateID(id); err != nil {
+		logger.Warnf("Failed to delete revisions of dockerfile template %s, err: %s", id, err)
+	}
+	return nil
 }
 
 func GetDockerfileTemplateReference(id string, logger *zap.SugaredLogger) ([]*template.BuildReference, error) {
 	ret := make([]*template.BuildReference, 0)
+	dockerfileTemplate, err := commonrepo.NewDockerfileTemplateColl().GetById(id)
+	if err != nil {
+		logger.Errorf("Failed to get dockerfile template from id: %s, the error is: %s", id, err)
+		return ret, err
+	}
 	referenceList, err := commonrepo.NewBuildColl().GetDockerfileTemplateReference(id)
 	if err != nil {
 		logger.Errorf("Failed to get build reference for dockerfile template id: %s, the error is: %s", id, err)
 		return ret, err
 	}
 	for _, reference := range referenceList {
+		revision := reference.PostBuild.DockerBuild.TemplateRevision
 		ret = append(ret, &template.BuildReference{
-			BuildName:   reference.Name,
-			ProjectName: reference.ProductName,
+			BuildName:        reference.Name,
+			ProjectName:      reference.ProductName,
+			TemplateRevision: revision,
+			Outdated:         revision > 0 && revision < dockerfileTemplate.Revision,
 		})
 	}
 	return ret, nil
 }
 
+// RerenderDockerfileTemplateReference moves the builds to the latest revision of the template, all
+// the builds using the template are re-rendered if no build is specified.
+func RerenderDockerfileTemplateReference(id string, builds []*template.BuildReference, logger *zap.SugaredLogger) ([]*template.RerenderResult, error) {
+	dockerfileTemplate, err := commonrepo.NewDockerfileTemplateColl().GetById(id)
+	if err != nil {
+		logger.Errorf("Failed to get dockerfile template from id: %s, the error is: %s", id, err)
+		return nil, err
+	}
+	referenceList, err := commonrepo.NewBuildColl().GetDockerfileTemplateReference(id)
+	if err != nil {
+		logger.Errorf("Failed to get build reference for dockerfile template id: %s, the error is: %s", id, err)
+		return nil, err
+	}
+	selected := sets.NewString()
+	for _, build := range builds {
+		selected.Insert(build.ProjectName + "/" + build.BuildName)
+	}
+
+	resp := make([]*template.RerenderResult, 0)
+	for _, reference := range referenceList {
+		if selected.Len() > 0 && !selected.Has(reference.ProductName+"/"+reference.Name) {
+			continue
+		}
+		result := &template.RerenderResult{ProjectName: reference.ProductName, Name: reference.Name}
+		if err := commonrepo.NewBuildColl().UpdateDockerfileTemplateRevision(reference.Name, reference.ProductName, dockerfileTemplate.Revision); err != nil {
+			logger.Errorf("Failed to update template revision of build %s, err: %s", reference.Name, err)
+			result.Error = err.Error()
+		}
+		resp = append(resp, result)
+	}
+	return resp, nil
+}
+
+// RenderDockerfileTemplate generates a dockerfile from the template, the default values of the
+// ARG instructions are replaced by the given variables.
+func RenderDockerfileTemplate(id string, variables []*commonmodels.ChartVariable, logger *zap.SugaredLogger) (string, error) {
+	dockerfileTemplate, err := commonrepo.NewDockerfileTemplateColl().GetById(id)
+	if err != nil {
+		logger.Errorf("Failed to get dockerfile template from id: %s, the error is: %s", id, err)
+		return "", err
+	}
+	values := make(map[string]string, len(variables))
+	for _, v := range variables {
+		values[v.Key] = v.Value
+	}
+
+	lines := strings.Split(dockerfileTemplate.Content, "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.EqualFold(fields[0], setting.DockerfileCmdArg) {
+			continue
+		}
+		key := strings.SplitN(fields[1], "=", 2)[0]
+		if value, ok := values[key]; ok {
+			lines[i] = fmt.Sprintf("%s %s=%s", fields[0], key, value)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
 func ValidateDockerfileTemplate(template string, _ *zap.SugaredLogger) error {
 	// some dockerfile validation stuff
 	reader := strings.NewReader(template)
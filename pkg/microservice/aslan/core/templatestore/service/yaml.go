@@ -172,7 +172,7 @@ func ValidateVariable(content, variable string) error {
 		return fmt.Errorf("failed to unmarshal yaml: %s", err)
 	}
 
-	tmpl, err := gotemplate.New("").Parse(content)
+	tmpl, err := gotemplate.New("").Funcs(template.FuncMap()).Parse(content)
 	if err != nil {
 		return fmt.Errorf("failed to build template, err: %s", err)
 	}
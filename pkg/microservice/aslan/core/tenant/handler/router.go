@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type Router struct{}
+
+func (*Router) Inject(router *gin.RouterGroup) {
+	tenants := router.Group("tenants")
+	{
+		tenants.GET("", ListTenants)
+		tenants.POST("", CreateTenant)
+		tenants.PUT("/:key", UpdateTenant)
+		tenants.DELETE("/:key", DeleteTenant)
+		// the members and the usage are managed by the admins of the tenant.
+		tenants.PUT("/:key/members", UpdateTenantMembers)
+		tenants.GET("/:key/usage", GetTenantUsage)
+	}
+}
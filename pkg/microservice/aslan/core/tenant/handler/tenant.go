@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func ListTenants(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListTenants(ctx.UserID, ctx.Logger)
+}
+
+func CreateTenant(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.Tenant)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("CreateTenant c.GetRawData() err : %s", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("CreateTenant json.Unmarshal err : %s", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统配置-租户", fmt.Sprintf("key:%s name:%s", args.Key, args.Name), string(data), ctx.Logger)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid tenant args")
+		return
+	}
+	args.UpdateBy = ctx.UserName
+
+	ctx.Err = service.CreateTenant(args, ctx.Logger)
+}
+
+func UpdateTenant(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.Tenant)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("UpdateTenant c.GetRawData() err : %s", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("UpdateTenant json.Unmarshal err : %s", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统配置-租户", fmt.Sprintf("key:%s name:%s", c.Param("key"), args.Name), string(data), ctx.Logger)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid tenant args")
+		return
+	}
+	args.UpdateBy = ctx.UserName
+
+	ctx.Err = service.UpdateTenant(c.Param("key"), args, ctx.Logger)
+}
+
+func DeleteTenant(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统配置-租户", fmt.Sprintf("key:%s", c.Param("key")), "", ctx.Logger)
+	ctx.Err = service.DeleteTenant(c.Param("key"), ctx.Logger)
+}
+
+func UpdateTenantMembers(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.TenantMembers)
+	data, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("UpdateTenantMembers c.GetRawData() err : %s", err)
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		log.Errorf("UpdateTenantMembers json.Unmarshal err : %s", err)
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统配置-租户成员", fmt.Sprintf("key:%s", c.Param("key")), string(data), ctx.Logger)
+
+	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+
+	if err := c.ShouldBindJSON(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid tenant members args")
+		return
+	}
+
+	ctx.Err = service.UpdateTenantMembers(c.Param("key"), ctx.UserID, args, ctx.UserName, ctx.Logger)
+}
+
+type tenantUsageArgs struct {
+	StartTime int64 `form:"startTime"`
+	EndTime   int64 `form:"endTime"`
+}
+
+func GetTenantUsage(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(tenantUsageArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetTenantUsage(c.Param("key"), ctx.UserID, args.StartTime, args.EndTime, ctx.Logger)
+}
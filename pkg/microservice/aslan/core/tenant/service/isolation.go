@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	policyservice "github.com/koderover/zadig/pkg/microservice/policy/core/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// Scope holds the resources a user can not see because they are bound to tenants the user does
+// not belong to. A nil scope sees everything, which is the case when the isolation is disabled
+// or the user is a system admin.
+type Scope struct {
+	tenants    []*commonmodels.Tenant
+	projects   sets.String
+	clusters   sets.String
+	registries sets.String
+	codeHosts  sets.Int
+}
+
+func GetScope(uid string, log *zap.SugaredLogger) (*Scope, error) {
+	if !config.TenantIsolationEnabled() || uid == "" {
+		return nil, nil
+	}
+	isAdmin, err := policyservice.IsSystemAdmin(uid)
+	if err != nil {
+		log.Errorf("failed to check whether user %s is system admin, err: %s", uid, err)
+		return nil, err
+	}
+	if isAdmin {
+		return nil, nil
+	}
+
+	tenants, err := commonrepo.NewTenantColl().List()
+	if err != nil {
+		log.Errorf("Tenant.List error: %s", err)
+		return nil, err
+	}
+	scope := &Scope{
+		projects:   sets.NewString(),
+		clusters:   sets.NewString(),
+		registries: sets.NewString(),
+		codeHosts:  sets.NewInt(),
+	}
+	for _, tenant := range tenants {
+		if tenant.HasUser(uid) {
+			scope.tenants = append(scope.tenants, tenant)
+			continue
+		}
+		scope.projects.Insert(tenant.Projects...)
+		scope.clusters.Insert(tenant.Clusters...)
+		scope.registries.Insert(tenant.Registries...)
+		scope.codeHosts.Insert(tenant.CodeHosts...)
+	}
+	return scope, nil
+}
+
+func (s *Scope) ProjectVisible(projectName string) bool {
+	return s == nil || !s.projects.Has(projectName)
+}
+
+func (s *Scope) ClusterVisible(id string) bool {
+	return s == nil || !s.clusters.Has(id)
+}
+
+func (s *Scope) RegistryVisible(id string) bool {
+	return s == nil || !s.registries.Has(id)
+}
+
+func (s *Scope) CodeHostVisible(id int) bool {
+	return s == nil || !s.codeHosts.Has(id)
+}
+
+func (s *Scope) FilterProjects(projectNames []string) []string {
+	resp := make([]string, 0, len(projectNames))
+	for _, name := range projectNames {
+		if s.ProjectVisible(name) {
+			resp = append(resp, name)
+		}
+	}
+	return resp
+}
+
+// CheckProjectAccess rejects the requests to a project bound to a tenant the user does not belong to.
+func CheckProjectAccess(projectName, uid string, log *zap.SugaredLogger) error {
+	scope, err := GetScope(uid, log)
+	if err != nil {
+		return e.ErrTenantForbidden.AddErr(err)
+	}
+	if !scope.ProjectVisible(projectName) {
+		return e.ErrTenantForbidden.AddDesc(fmt.Sprintf("project %s belongs to another tenant", projectName))
+	}
+	return nil
+}
+
+// GetTenantForNewProject returns the tenant a project created by the user is bound to: the given
+// tenant, or the only tenant of the user if the key is empty. Nil is returned if the project is shared.
+func GetTenantForNewProject(key, uid string, log *zap.SugaredLogger) (*commonmodels.Tenant, error) {
+	scope, err := GetScope(uid, log)
+	if err != nil {
+		return nil, e.ErrCreateProduct.AddErr(err)
+	}
+
+	var tenant *commonmodels.Tenant
+	switch {
+	case key != "":
+		tenant, err = commonrepo.NewTenantColl().Find(key)
+		if err != nil {
+			log.Errorf("failed to find tenant %s, err: %s", key, err)
+			return nil, e.ErrCreateProduct.AddErr(err)
+		}
+		if scope != nil && !tenant.HasUser(uid) {
+			return nil, e.ErrTenantForbidden.AddDesc(fmt.Sprintf("user does not belong to tenant %s", key))
+		}
+	case scope != nil && len(scope.tenants) == 1:
+		tenant = scope.tenants[0]
+	case scope != nil && len(scope.tenants) > 1:
+		return nil, e.ErrInvalidParam.AddDesc("the tenant of the project must be specified")
+	default:
+		return nil, nil
+	}
+
+	if tenant.Quota != nil && tenant.Quota.MaxProjects > 0 && len(tenant.Projects) >= tenant.Quota.MaxProjects {
+		return nil, e.ErrTenantQuotaExceeded.AddDesc(fmt.Sprintf("tenant %s can have %d projects at most", tenant.Key, tenant.Quota.MaxProjects))
+	}
+	return tenant, nil
+}
+
+func BindProject(tenant *commonmodels.Tenant, projectName string, log *zap.SugaredLogger) {
+	if tenant == nil {
+		return
+	}
+	if err := commonrepo.NewTenantColl().AddProject(tenant.Key, projectName); err != nil {
+		log.Errorf("failed to bind project %s to tenant %s, err: %s", projectName, tenant.Key, err)
+	}
+}
+
+// FilterProjectNames returns the visible ones of the given projects, or of all projects if none is
+// given. Nil is returned if the user can see all projects.
+func FilterProjectNames(projectNames []string, uid string, log *zap.SugaredLogger) ([]string, error) {
+	scope, err := GetScope(uid, log)
+	if err != nil || scope == nil {
+		return projectNames, err
+	}
+	if len(projectNames) == 0 {
+		projectNames, err = templaterepo.NewProductColl().ListNames(nil)
+		if err != nil {
+			log.Errorf("failed to list project names, err: %s", err)
+			return nil, err
+		}
+	}
+	return scope.FilterProjects(projectNames), nil
+}
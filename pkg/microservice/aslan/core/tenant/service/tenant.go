@@ -0,0 +1,258 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	policyservice "github.com/koderover/zadig/pkg/microservice/policy/core/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+var tenantKeyRegx = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// ListTenants returns all tenants for system admins and the tenants the user belongs to for others.
+func ListTenants(uid string, log *zap.SugaredLogger) ([]*commonmodels.Tenant, error) {
+	isAdmin, err := policyservice.IsSystemAdmin(uid)
+	if err != nil {
+		log.Errorf("failed to check whether user %s is system admin, err: %s", uid, err)
+		return nil, e.ErrListTenant.AddErr(err)
+	}
+
+	var resp []*commonmodels.Tenant
+	if isAdmin {
+		resp, err = commonrepo.NewTenantColl().List()
+	} else {
+		resp, err = commonrepo.NewTenantColl().ListByUser(uid)
+	}
+	if err != nil {
+		log.Errorf("Tenant.List error: %s", err)
+		return nil, e.ErrListTenant.AddErr(err)
+	}
+	return resp, nil
+}
+
+func CreateTenant(args *commonmodels.Tenant, log *zap.SugaredLogger) error {
+	if err := validateTenant(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := checkTenantResources("", args); err != nil {
+		return e.ErrCreateTenant.AddErr(err)
+	}
+	if err := commonrepo.NewTenantColl().Create(args); err != nil {
+		log.Errorf("Tenant.Create error: %s", err)
+		return e.ErrCreateTenant.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateTenant(key string, args *commonmodels.Tenant, log *zap.SugaredLogger) error {
+	args.Key = key
+	if err := validateTenant(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := checkTenantResources(key, args); err != nil {
+		return e.ErrUpdateTenant.AddErr(err)
+	}
+	if err := commonrepo.NewTenantColl().Update(key, args); err != nil {
+		log.Errorf("Tenant.Update %s error: %s", key, err)
+		return e.ErrUpdateTenant.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteTenant(key string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewTenantColl().Delete(key); err != nil {
+		log.Errorf("Tenant.Delete %s error: %s", key, err)
+		return e.ErrDeleteTenant.AddErr(err)
+	}
+	return nil
+}
+
+type TenantMembers struct {
+	Admins  []string `json:"admins"`
+	Members []string `json:"members"`
+}
+
+// UpdateTenantMembers can be called by the system admins and the admins of the tenant.
+func UpdateTenantMembers(key, uid string, args *TenantMembers, updateBy string, log *zap.SugaredLogger) error {
+	tenant, err := getManagedTenant(key, uid)
+	if err != nil {
+		log.Errorf("failed to get tenant %s, err: %s", key, err)
+		return e.ErrUpdateTenant.AddErr(err)
+	}
+	if tenant == nil {
+		return e.ErrTenantForbidden.AddDesc(fmt.Sprintf("user is not an admin of tenant %s", key))
+	}
+	if err := commonrepo.NewTenantColl().UpdateMembers(key, uniqueStrings(args.Admins), uniqueStrings(args.Members), updateBy); err != nil {
+		log.Errorf("Tenant.UpdateMembers %s error: %s", key, err)
+		return e.ErrUpdateTenant.AddErr(err)
+	}
+	return nil
+}
+
+type TenantUsage struct {
+	Key          string                          `json:"key"`
+	Members      int                             `json:"members"`
+	Projects     int                             `json:"projects"`
+	Environments int                             `json:"environments"`
+	Clusters     int                             `json:"clusters"`
+	Registries   int                             `json:"registries"`
+	CodeHosts    int                             `json:"code_hosts"`
+	RunningTasks int                             `json:"running_tasks"`
+	TaskCount    int64                           `json:"task_count"`
+	TaskDuration int64                           `json:"task_duration"`
+	Quota        *commonmodels.TenantQuota       `json:"quota"`
+	Details      []*commonrepo.WorkflowTaskUsage `json:"details"`
+}
+
+// GetTenantUsage meters the resources bound to the tenant and the workflow tasks of its projects
+// created in [startTime, endTime), the duration unit is second.
+func GetTenantUsage(key, uid string, startTime, endTime int64, log *zap.SugaredLogger) (*TenantUsage, error) {
+	tenant, err := getManagedTenant(key, uid)
+	if err != nil {
+		log.Errorf("failed to get tenant %s, err: %s", key, err)
+		return nil, e.ErrGetTenantUsage.AddErr(err)
+	}
+	if tenant == nil {
+		return nil, e.ErrTenantForbidden.AddDesc(fmt.Sprintf("user is not an admin of tenant %s", key))
+	}
+
+	resp := &TenantUsage{
+		Key:        tenant.Key,
+		Members:    sets.NewString(append(tenant.Admins, tenant.Members...)...).Len(),
+		Projects:   len(tenant.Projects),
+		Clusters:   len(tenant.Clusters),
+		Registries: len(tenant.Registries),
+		CodeHosts:  len(tenant.CodeHosts),
+		Quota:      tenant.Quota,
+	}
+	for _, project := range tenant.Projects {
+		count, err := commonrepo.NewProductColl().Count(project)
+		if err != nil {
+			log.Errorf("failed to count the envs of project %s, err: %s", project, err)
+			return nil, e.ErrGetTenantUsage.AddErr(err)
+		}
+		resp.Environments += count
+	}
+
+	projects := sets.NewString(tenant.Projects...)
+	queues, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{})
+	if err != nil {
+		log.Errorf("WorkflowQueue.List error: %s", err)
+		return nil, e.ErrGetTenantUsage.AddErr(err)
+	}
+	for _, queue := range queues {
+		if projects.Has(queue.ProjectName) && isActiveQueue(queue) {
+			resp.RunningTasks++
+		}
+	}
+
+	resp.Details, err = commonrepo.NewworkflowTaskv4Coll().StatUsageByProjects(tenant.Projects, startTime, endTime)
+	if err != nil {
+		log.Errorf("failed to stat the workflow tasks of tenant %s, err: %s", key, err)
+		return nil, e.ErrGetTenantUsage.AddErr(err)
+	}
+	for _, detail := range resp.Details {
+		resp.TaskCount += detail.TaskCount
+		resp.TaskDuration += detail.Duration
+	}
+	return resp, nil
+}
+
+// getManagedTenant returns nil if the user is neither a system admin nor an admin of the tenant.
+func getManagedTenant(key, uid string) (*commonmodels.Tenant, error) {
+	tenant, err := commonrepo.NewTenantColl().Find(key)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.IsAdmin(uid) {
+		return tenant, nil
+	}
+	isAdmin, err := policyservice.IsSystemAdmin(uid)
+	if err != nil || !isAdmin {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func validateTenant(args *commonmodels.Tenant) error {
+	if !tenantKeyRegx.MatchString(args.Key) {
+		return fmt.Errorf("invalid tenant key %s, only lowercase letters, digits and '-' are allowed", args.Key)
+	}
+	if args.Name == "" {
+		return fmt.Errorf("tenant name can not be empty")
+	}
+	if args.Quota == nil {
+		args.Quota = &commonmodels.TenantQuota{}
+	}
+	if args.Quota.MaxProjects < 0 || args.Quota.MaxWorkflowConcurrency < 0 {
+		return fmt.Errorf("tenant quota can not be negative")
+	}
+	if args.Quota.MaxProjects > 0 && len(args.Projects) > args.Quota.MaxProjects {
+		return fmt.Errorf("%d projects exceed the project quota %d", len(args.Projects), args.Quota.MaxProjects)
+	}
+
+	args.Admins = uniqueStrings(args.Admins)
+	args.Members = uniqueStrings(args.Members)
+	args.Projects = uniqueStrings(args.Projects)
+	args.Clusters = uniqueStrings(args.Clusters)
+	args.Registries = uniqueStrings(args.Registries)
+	args.CodeHosts = sets.NewInt(args.CodeHosts...).List()
+	return nil
+}
+
+// checkTenantResources makes sure a resource is bound to one tenant at most.
+func checkTenantResources(key string, args *commonmodels.Tenant) error {
+	tenants, err := commonrepo.NewTenantColl().List()
+	if err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		if tenant.Key == key {
+			continue
+		}
+		if conflicts := sets.NewString(tenant.Projects...).Intersection(sets.NewString(args.Projects...)); conflicts.Len() > 0 {
+			return fmt.Errorf("projects %v are bound to tenant %s", conflicts.List(), tenant.Key)
+		}
+		if conflicts := sets.NewString(tenant.Clusters...).Intersection(sets.NewString(args.Clusters...)); conflicts.Len() > 0 {
+			return fmt.Errorf("clusters %v are bound to tenant %s", conflicts.List(), tenant.Key)
+		}
+		if conflicts := sets.NewString(tenant.Registries...).Intersection(sets.NewString(args.Registries...)); conflicts.Len() > 0 {
+			return fmt.Errorf("registries %v are bound to tenant %s", conflicts.List(), tenant.Key)
+		}
+		if conflicts := sets.NewInt(tenant.CodeHosts...).Intersection(sets.NewInt(args.CodeHosts...)); conflicts.Len() > 0 {
+			return fmt.Errorf("code hosts %v are bound to tenant %s", conflicts.List(), tenant.Key)
+		}
+	}
+	return nil
+}
+
+func isActiveQueue(queue *commonmodels.WorkflowQueue) bool {
+	return queue.Status == config.StatusRunning || queue.Status == config.StatusQueued
+}
+
+func uniqueStrings(list []string) []string {
+	return sets.NewString(list...).Delete("").List()
+}
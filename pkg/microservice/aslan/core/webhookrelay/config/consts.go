@@ -0,0 +1,31 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "time"
+
+const (
+	// RelayedHeader is set on the forwarded requests, a relayed request is never relayed again
+	// so that a misconfigured rule can not cause a forwarding loop.
+	RelayedHeader = "X-Zadig-Relayed-By"
+
+	// ForwardTimeout is the timeout of forwarding a webhook to a target, providers usually give up
+	// after about 10 seconds so all targets are forwarded in parallel.
+	ForwardTimeout = 8 * time.Second
+	// DeliveryRetention is how long the delivery records are kept.
+	DeliveryRetention = 7 * 24 * time.Hour
+)
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListWebhookRelayRules(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListWebhookRelayRules(ctx.Logger)
+}
+
+func CreateWebhookRelayRule(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.WebhookRelayRule)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统设置-Webhook转发", args.Name, "", ctx.Logger)
+	args.UpdateBy = ctx.UserName
+	ctx.Err = service.CreateWebhookRelayRule(args, ctx.Logger)
+}
+
+func UpdateWebhookRelayRule(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(models.WebhookRelayRule)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统设置-Webhook转发", c.Param("id"), "", ctx.Logger)
+	args.UpdateBy = ctx.UserName
+	ctx.Err = service.UpdateWebhookRelayRule(c.Param("id"), args, ctx.Logger)
+}
+
+func DeleteWebhookRelayRule(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统设置-Webhook转发", c.Param("id"), "", ctx.Logger)
+	ctx.Err = service.DeleteWebhookRelayRule(c.Param("id"), ctx.Logger)
+}
+
+func ListWebhookRelayDeliveries(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	pageNum, _ := strconv.ParseInt(c.DefaultQuery("page_num", "1"), 10, 64)
+	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "20"), 10, 64)
+	ctx.Resp, ctx.Err = service.ListWebhookRelayDeliveries(c.Query("rule_id"), pageNum, pageSize, ctx.Logger)
+}
+
+func RelayWebhook(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	payload, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.RelayWebhook(payload, c.Request.Header, c.Request.URL.RawQuery, ctx.Logger)
+}
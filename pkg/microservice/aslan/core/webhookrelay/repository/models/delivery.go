@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookRelayDelivery is the record of a webhook forwarded to a target.
+type WebhookRelayDelivery struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	RuleID     string             `bson:"rule_id"         json:"rule_id"`
+	RuleName   string             `bson:"rule_name"       json:"rule_name"`
+	Source     string             `bson:"source"          json:"source"`
+	Event      string             `bson:"event"           json:"event"`
+	Repo       string             `bson:"repo"            json:"repo"`
+	TargetURL  string             `bson:"target_url"      json:"target_url"`
+	StatusCode int                `bson:"status_code"     json:"status_code"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	// Duration is in milliseconds.
+	Duration   int64 `bson:"duration"        json:"duration"`
+	CreateTime int64 `bson:"create_time"     json:"create_time"`
+	// ExpireAt is used by the ttl index to clean up the old records.
+	ExpireAt time.Time `bson:"expire_at"       json:"-"`
+}
+
+func (WebhookRelayDelivery) TableName() string {
+	return "webhook_relay_delivery"
+}
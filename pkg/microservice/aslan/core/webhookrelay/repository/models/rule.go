@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookRelayRule routes the webhooks received by the relay to a zadig instance.
+type WebhookRelayRule struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	Name    string             `bson:"name"            json:"name"`
+	Enabled bool               `bson:"enabled"         json:"enabled"`
+	// Source is the code host type such as github and gitlab, the rule matches all sources if it is empty.
+	Source string `bson:"source"          json:"source"`
+	// Repos are glob patterns of the full repo name, e.g. koderover/*, the rule matches all repos if it is empty.
+	Repos []string `bson:"repos"           json:"repos"`
+	// Events are the event types given by the provider header, e.g. push or Merge Request Hook.
+	Events []string `bson:"events"          json:"events"`
+	// TargetURL is the webhook address of the target instance, e.g. https://zadig.internal/api/aslan/webhook.
+	TargetURL string `bson:"target_url"      json:"target_url"`
+	// Secret is the webhook secret of the target instance, the requests signed with it are accepted
+	// by the relay and the forwarded requests are signed with it.
+	Secret     string `bson:"secret,omitempty" json:"secret,omitempty"`
+	UpdateBy   string `bson:"update_by"       json:"update_by"`
+	UpdateTime int64  `bson:"update_time"     json:"update_time"`
+}
+
+func (WebhookRelayRule) TableName() string {
+	return "webhook_relay_rule"
+}
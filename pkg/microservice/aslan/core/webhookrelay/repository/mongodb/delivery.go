@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WebhookRelayDeliveryColl struct {
+	*mongo.Collection
+	coll string
+}
+
+func NewWebhookRelayDeliveryColl() *WebhookRelayDeliveryColl {
+	name := models.WebhookRelayDelivery{}.TableName()
+	return &WebhookRelayDeliveryColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WebhookRelayDeliveryColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WebhookRelayDeliveryColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "rule_id", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.M{"expire_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *WebhookRelayDeliveryColl) Create(delivery *models.WebhookRelayDelivery, retention time.Duration) error {
+	now := time.Now()
+	delivery.CreateTime = now.Unix()
+	delivery.ExpireAt = now.Add(retention)
+	_, err := c.InsertOne(context.TODO(), delivery)
+	return err
+}
+
+type ListDeliveryOption struct {
+	RuleID   string
+	PageNum  int64
+	PageSize int64
+}
+
+func (c *WebhookRelayDeliveryColl) List(opt *ListDeliveryOption) ([]*models.WebhookRelayDelivery, int64, error) {
+	query := bson.M{}
+	if opt.RuleID != "" {
+		query["rule_id"] = opt.RuleID
+	}
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+	findOption := options.Find().SetSort(bson.D{{Key: "create_time", Value: -1}})
+	if opt.PageNum > 0 && opt.PageSize > 0 {
+		findOption.SetSkip((opt.PageNum - 1) * opt.PageSize).SetLimit(opt.PageSize)
+	}
+	res := make([]*models.WebhookRelayDelivery, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, findOption)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, count, err
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WebhookRelayRuleColl struct {
+	*mongo.Collection
+	coll string
+}
+
+func NewWebhookRelayRuleColl() *WebhookRelayRuleColl {
+	name := models.WebhookRelayRule{}.TableName()
+	return &WebhookRelayRuleColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WebhookRelayRuleColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WebhookRelayRuleColl) EnsureIndex(_ context.Context) error {
+	return nil
+}
+
+func (c *WebhookRelayRuleColl) Create(rule *models.WebhookRelayRule) error {
+	rule.UpdateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), rule)
+	return err
+}
+
+func (c *WebhookRelayRuleColl) Update(id string, rule *models.WebhookRelayRule) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	rule.ID = oid
+	rule.UpdateTime = time.Now().Unix()
+	_, err = c.ReplaceOne(context.TODO(), bson.M{"_id": oid}, rule)
+	return err
+}
+
+func (c *WebhookRelayRuleColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
+
+func (c *WebhookRelayRuleColl) Get(id string) (*models.WebhookRelayRule, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.WebhookRelayRule{}
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(res)
+	return res, err
+}
+
+func (c *WebhookRelayRuleColl) List(onlyEnabled bool) ([]*models.WebhookRelayRule, error) {
+	query := bson.M{}
+	if onlyEnabled {
+		query["enabled"] = true
+	}
+	res := make([]*models.WebhookRelayRule, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &res)
+	return res, err
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	gitservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
+	"github.com/koderover/zadig/pkg/tool/codehub"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+)
+
+const (
+	githubSignatureHeader    = "X-Hub-Signature"
+	githubSignature256Header = "X-Hub-Signature-256"
+	gitlabTokenHeader        = "X-Gitlab-Token"
+	giteeTokenHeader         = "X-Gitee-Token"
+	codehubTokenHeader       = "X-Codehub-Token"
+	bitbucketSignatureHeader = "X-Hub-Signature"
+)
+
+// the headers which are set by the http client of the relay.
+var skippedHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Accept-Encoding":   true,
+	"Transfer-Encoding": true,
+}
+
+var relayClient = &http.Client{Timeout: config.ForwardTimeout}
+
+type RelayResult struct {
+	Source     string                         `json:"source"`
+	Event      string                         `json:"event"`
+	Repo       string                         `json:"repo"`
+	Deliveries []*models.WebhookRelayDelivery `json:"deliveries"`
+}
+
+// RelayWebhook validates a webhook of the code host and forwards it to the targets of the matched
+// rules. The request is accepted if it is signed with the secret of the relay or with the secret
+// of a matched rule, the latter is the case when the target instance creates the webhook itself.
+func RelayWebhook(payload []byte, header http.Header, rawQuery string, logger *zap.SugaredLogger) (*RelayResult, error) {
+	if header.Get(config.RelayedHeader) != "" {
+		return nil, e.ErrRelayWebhook.AddDesc("the webhook has already been relayed")
+	}
+	source, event := detectSource(header)
+	result := &RelayResult{
+		Source:     source,
+		Event:      event,
+		Repo:       extractRepo(payload),
+		Deliveries: make([]*models.WebhookRelayDelivery, 0),
+	}
+
+	rules, err := mongodb.NewWebhookRelayRuleColl().List(true)
+	if err != nil {
+		logger.Errorf("failed to list webhook relay rules, err: %s", err)
+		return nil, e.ErrRelayWebhook.AddErr(err)
+	}
+	matched := make([]*models.WebhookRelayRule, 0)
+	for _, rule := range rules {
+		if matchRule(rule, source, event, result.Repo) {
+			matched = append(matched, rule)
+		}
+	}
+
+	secrets := []string{gitservice.GetHookSecret()}
+	for _, rule := range matched {
+		if rule.Secret != "" {
+			secrets = append(secrets, rule.Secret)
+		}
+	}
+	if !verifySignature(source, header, payload, secrets) {
+		logger.Warnf("invalid signature of %s webhook for %s", source, result.Repo)
+		return nil, e.ErrRelayWebhook.AddDesc("invalid webhook signature")
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+	for _, rule := range matched {
+		wg.Add(1)
+		go func(rule *models.WebhookRelayRule) {
+			defer wg.Done()
+			delivery := forward(rule, source, payload, header, rawQuery)
+			delivery.Event, delivery.Repo = event, result.Repo
+			if err := mongodb.NewWebhookRelayDeliveryColl().Create(delivery, config.DeliveryRetention); err != nil {
+				logger.Errorf("failed to save delivery of rule %s, err: %s", rule.Name, err)
+			}
+			if delivery.Error != "" {
+				logger.Warnf("failed to relay %s webhook of %s to %s, err: %s", source, result.Repo, rule.TargetURL, delivery.Error)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.Deliveries = append(result.Deliveries, delivery)
+		}(rule)
+	}
+	wg.Wait()
+	return result, nil
+}
+
+func forward(rule *models.WebhookRelayRule, source string, payload []byte, header http.Header, rawQuery string) *models.WebhookRelayDelivery {
+	delivery := &models.WebhookRelayDelivery{
+		RuleID:    rule.ID.Hex(),
+		RuleName:  rule.Name,
+		Source:    source,
+		TargetURL: rule.TargetURL,
+	}
+	start := time.Now()
+	defer func() {
+		delivery.Duration = time.Since(start).Milliseconds()
+	}()
+
+	target, err := url.Parse(rule.TargetURL)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	// gerrit passes the workflow information in the query
+	if target.RawQuery == "" {
+		target.RawQuery = rawQuery
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ForwardTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	for key, values := range header {
+		if skippedHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if rule.Secret != "" {
+		sign(source, req.Header, payload, rule.Secret)
+	}
+	req.Header.Set(config.RelayedHeader, configbase.SystemAddress())
+
+	resp, err := relayClient.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		delivery.Error = fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return delivery
+}
+
+// detectSource detects the code host in the same way as the webhook handler.
+func detectSource(header http.Header) (string, string) {
+	req := &http.Request{Header: header}
+	if event := github.WebHookType(req); event != "" {
+		return setting.SourceFromGithub, event
+	}
+	if event := gitlab.HookEventType(req); event != "" {
+		return setting.SourceFromGitlab, string(event)
+	}
+	if event := codehub.HookEventType(req); event != "" {
+		return setting.SourceFromCodeHub, string(event)
+	}
+	if event := gitee.HookEventType(req); event != "" {
+		return setting.SourceFromGitee, string(event)
+	}
+	if event := bitbucketserver.HookEventType(req); event != "" {
+		return setting.SourceFromBitbucketServer, string(event)
+	}
+	if event := azuredevops.HookEventType(req); event != "" {
+		return setting.SourceFromAzureDevOps, string(event)
+	}
+	return setting.SourceFromGerrit, ""
+}
+
+// extractRepo returns the full name of the repo, e.g. koderover/zadig.
+func extractRepo(payload []byte) string {
+	event := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return ""
+	}
+	// bitbucket server
+	for _, keys := range [][]string{{"repository"}, {"pullRequest", "toRef", "repository"}} {
+		project, slug := lookupString(event, append(keys, "project", "key")), lookupString(event, append(keys, "slug"))
+		if project != "" && slug != "" {
+			return project + "/" + slug
+		}
+	}
+	// azure devops
+	project, name := lookupString(event, []string{"resource", "repository", "project", "name"}), lookupString(event, []string{"resource", "repository", "name"})
+	if project != "" && name != "" {
+		return project + "/" + name
+	}
+	for _, keys := range [][]string{
+		// github and gitee
+		{"repository", "full_name"},
+		// gitlab and codehub
+		{"project", "path_with_namespace"},
+		// gerrit
+		{"change", "project"},
+		{"refUpdate", "project"},
+		{"project", "name"},
+		{"project"},
+	} {
+		if repo := lookupString(event, keys); repo != "" {
+			return repo
+		}
+	}
+	return ""
+}
+
+func lookupString(value interface{}, keys []string) string {
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
+
+func matchRule(rule *models.WebhookRelayRule, source, event, repo string) bool {
+	if rule.Source != "" && rule.Source != source {
+		return false
+	}
+	if len(rule.Events) > 0 {
+		found := false
+		for _, e := range rule.Events {
+			if strings.EqualFold(e, event) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(rule.Repos) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Repos {
+		if ok, _ := path.Match(pattern, repo); ok && repo != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature checks the signature the same way as the webhook handler, gerrit webhooks are not signed.
+func verifySignature(source string, header http.Header, payload []byte, secrets []string) bool {
+	for _, secret := range secrets {
+		var token string
+		switch source {
+		case setting.SourceFromGithub:
+			sig := header.Get(githubSignature256Header)
+			if sig == "" {
+				sig = header.Get(githubSignatureHeader)
+			}
+			if github.ValidateSignature(sig, payload, []byte(secret)) == nil {
+				return true
+			}
+			continue
+		case setting.SourceFromGitlab:
+			token = header.Get(gitlabTokenHeader)
+		case setting.SourceFromGitee:
+			token = header.Get(giteeTokenHeader)
+		case setting.SourceFromCodeHub:
+			token = header.Get(codehubTokenHeader)
+		case setting.SourceFromBitbucketServer:
+			if bitbucketserver.ValidateSignature(&http.Request{Header: header}, payload, secret) == nil {
+				return true
+			}
+			continue
+		case setting.SourceFromAzureDevOps:
+			if azuredevops.ValidateSecret(&http.Request{Header: header}, secret) == nil {
+				return true
+			}
+			continue
+		default:
+			return true
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// sign replaces the signature of the webhook with the one made by the secret of the target.
+func sign(source string, header http.Header, payload []byte, secret string) {
+	switch source {
+	case setting.SourceFromGithub:
+		header.Set(githubSignatureHeader, "sha1="+hmacHex(sha1.New, payload, secret))
+		header.Set(githubSignature256Header, "sha256="+hmacHex(sha256.New, payload, secret))
+	case setting.SourceFromGitlab:
+		header.Set(gitlabTokenHeader, secret)
+	case setting.SourceFromGitee:
+		header.Set(giteeTokenHeader, secret)
+	case setting.SourceFromCodeHub:
+		header.Set(codehubTokenHeader, secret)
+	case setting.SourceFromBitbucketServer:
+		header.Set(bitbucketSignatureHeader, "sha256="+hmacHex(sha256.New, payload, secret))
+	case setting.SourceFromAzureDevOps:
+		req := &http.Request{Header: header}
+		req.SetBasicAuth(azuredevops.WebhookUser, secret)
+	}
+}
+
+func hmacHex(h func() hash.Hash, payload []byte, secret string) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("Testing webhook relay", func() {
+
+	It("extracts the repo of the providers", func() {
+		Expect(extractRepo([]byte(`{"repository": {"full_name": "koderover/zadig"}}`))).To(Equal("koderover/zadig"))
+		Expect(extractRepo([]byte(`{"project": {"path_with_namespace": "group/app"}}`))).To(Equal("group/app"))
+		Expect(extractRepo([]byte(`{"type": "patchset-created", "change": {"project": "app"}}`))).To(Equal("app"))
+		Expect(extractRepo([]byte(`not json`))).To(BeEmpty())
+	})
+
+	It("matches the rules by source, event and repo", func() {
+		rule := &models.WebhookRelayRule{
+			Source: setting.SourceFromGithub,
+			Repos:  []string{"koderover/*"},
+			Events: []string{"push"},
+		}
+		Expect(matchRule(rule, setting.SourceFromGithub, "push", "koderover/zadig")).To(BeTrue())
+		Expect(matchRule(rule, setting.SourceFromGithub, "pull_request", "koderover/zadig")).To(BeFalse())
+		Expect(matchRule(rule, setting.SourceFromGithub, "push", "other/zadig")).To(BeFalse())
+		Expect(matchRule(rule, setting.SourceFromGitlab, "push", "koderover/zadig")).To(BeFalse())
+		Expect(matchRule(&models.WebhookRelayRule{}, setting.SourceFromGerrit, "", "")).To(BeTrue())
+	})
+
+	It("accepts the signatures made with any of the secrets", func() {
+		payload := []byte(`{"ref": "refs/heads/main"}`)
+		header := http.Header{}
+		sign(setting.SourceFromGithub, header, payload, "target")
+		Expect(verifySignature(setting.SourceFromGithub, header, payload, []string{"relay", "target"})).To(BeTrue())
+		Expect(verifySignature(setting.SourceFromGithub, header, payload, []string{"relay"})).To(BeFalse())
+
+		header = http.Header{}
+		sign(setting.SourceFromGitlab, header, payload, "target")
+		Expect(verifySignature(setting.SourceFromGitlab, header, payload, []string{"target"})).To(BeTrue())
+		Expect(verifySignature(setting.SourceFromGitlab, header, payload, []string{"relay"})).To(BeFalse())
+	})
+})
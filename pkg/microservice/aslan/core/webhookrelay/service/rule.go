@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func validateRule(rule *models.WebhookRelayRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch rule.Source {
+	case "", setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromCodeHub, setting.SourceFromGerrit, setting.SourceFromBitbucketServer, setting.SourceFromAzureDevOps:
+	default:
+		return fmt.Errorf("unsupported source: %s", rule.Source)
+	}
+	target, err := url.Parse(rule.TargetURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("invalid target url: %s", rule.TargetURL)
+	}
+	for _, pattern := range rule.Repos {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid repo pattern %s: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+func CreateWebhookRelayRule(rule *models.WebhookRelayRule, logger *zap.SugaredLogger) error {
+	if err := validateRule(rule); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := mongodb.NewWebhookRelayRuleColl().Create(rule); err != nil {
+		logger.Errorf("failed to create webhook relay rule %s, err: %s", rule.Name, err)
+		return e.ErrCreateWebhookRelayRule.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateWebhookRelayRule keeps the stored secret if it is not given, since it is never returned by the list API.
+func UpdateWebhookRelayRule(id string, rule *models.WebhookRelayRule, logger *zap.SugaredLogger) error {
+	origin, err := mongodb.NewWebhookRelayRuleColl().Get(id)
+	if err != nil {
+		return e.ErrUpdateWebhookRelayRule.AddErr(err)
+	}
+	if rule.Secret == "" {
+		rule.Secret = origin.Secret
+	}
+	if err := validateRule(rule); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := mongodb.NewWebhookRelayRuleColl().Update(id, rule); err != nil {
+		logger.Errorf("failed to update webhook relay rule %s, err: %s", id, err)
+		return e.ErrUpdateWebhookRelayRule.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteWebhookRelayRule(id string, logger *zap.SugaredLogger) error {
+	if err := mongodb.NewWebhookRelayRuleColl().Delete(id); err != nil {
+		logger.Errorf("failed to delete webhook relay rule %s, err: %s", id, err)
+		return e.ErrDeleteWebhookRelayRule.AddErr(err)
+	}
+	return nil
+}
+
+func ListWebhookRelayRules(logger *zap.SugaredLogger) ([]*models.WebhookRelayRule, error) {
+	rules, err := mongodb.NewWebhookRelayRuleColl().List(false)
+	if err != nil {
+		logger.Errorf("failed to list webhook relay rules, err: %s", err)
+		return nil, e.ErrListWebhookRelayRules.AddErr(err)
+	}
+	for _, rule := range rules {
+		rule.Secret = ""
+	}
+	return rules, nil
+}
+
+type ListDeliveriesResp struct {
+	Deliveries []*models.WebhookRelayDelivery `json:"deliveries"`
+	Total      int64                          `json:"total"`
+}
+
+func ListWebhookRelayDeliveries(ruleID string, pageNum, pageSize int64, logger *zap.SugaredLogger) (*ListDeliveriesResp, error) {
+	deliveries, total, err := mongodb.NewWebhookRelayDeliveryColl().List(&mongodb.ListDeliveryOption{
+		RuleID:   ruleID,
+		PageNum:  pageNum,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		logger.Errorf("failed to list webhook relay deliveries, err: %s", err)
+		return nil, e.ErrListWebhookRelayDeliveries.AddErr(err)
+	}
+	return &ListDeliveriesResp{Deliveries: deliveries, Total: total}, nil
+}
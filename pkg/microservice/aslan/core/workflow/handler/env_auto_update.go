@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListEnvAutoUpdatePolicies(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.ListEnvAutoUpdatePolicies(c.Query("projectName"), c.Query("envName"), ctx.Logger)
+}
+
+func CreateEnvAutoUpdatePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.EnvAutoUpdatePolicy)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "新增", "环境-自动更新策略", args.EnvName, "", ctx.Logger)
+
+	ctx.Err = workflow.CreateEnvAutoUpdatePolicy(ctx.UserName, args, ctx.Logger)
+}
+
+func UpdateEnvAutoUpdatePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.EnvAutoUpdatePolicy)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "更新", "环境-自动更新策略", c.Param("id"), "", ctx.Logger)
+
+	ctx.Err = workflow.UpdateEnvAutoUpdatePolicy(ctx.UserName, c.Param("id"), args, ctx.Logger)
+}
+
+func DeleteEnvAutoUpdatePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "删除", "环境-自动更新策略", c.Param("id"), "", ctx.Logger)
+
+	ctx.Err = workflow.DeleteEnvAutoUpdatePolicy(c.Param("id"), c.Query("projectName"), ctx.Logger)
+}
+
+type pauseEnvAutoUpdateReq struct {
+	Reason string `json:"reason"`
+}
+
+func PauseEnvAutoUpdatePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(pauseEnvAutoUpdateReq)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "暂停", "环境-自动更新策略", c.Param("id"), args.Reason, ctx.Logger)
+
+	ctx.Err = workflow.PauseEnvAutoUpdatePolicy(ctx.UserName, c.Param("id"), c.Query("projectName"), args.Reason, ctx.Logger)
+}
+
+func ResumeEnvAutoUpdatePolicy(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "恢复", "环境-自动更新策略", c.Param("id"), "", ctx.Logger)
+
+	ctx.Err = workflow.ResumeEnvAutoUpdatePolicy(c.Param("id"), c.Query("projectName"), ctx.Logger)
+}
+
+func ListEnvAutoUpdateEvents(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.ListEnvAutoUpdateEvents(c.Query("projectName"), c.Query("envName"), c.Query("policyID"), ctx.Logger)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/setting"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListReleaseTrains(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.ListReleaseTrains(c.Query("projectName"), ctx.Logger)
+}
+
+func CreateReleaseTrain(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.ReleaseTrain)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "新增", "自定义工作流-发布列车", args.Name, "", ctx.Logger)
+
+	ctx.Err = workflow.CreateReleaseTrain(ctx.UserName, args, ctx.Logger)
+}
+
+func UpdateReleaseTrain(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.ReleaseTrain)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.ProjectName = c.Query("projectName")
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, args.ProjectName, "更新", "自定义工作流-发布列车", args.Name, "", ctx.Logger)
+
+	ctx.Err = workflow.UpdateReleaseTrain(ctx.UserName, c.Param("id"), args, ctx.Logger)
+}
+
+func DeleteReleaseTrain(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "删除", "自定义工作流-发布列车", c.Param("id"), "", ctx.Logger)
+
+	ctx.Err = workflow.DeleteReleaseTrain(c.Param("id"), c.Query("projectName"), ctx.Logger)
+}
+
+func ListReleaseTrainItems(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.ListReleaseTrainItems(c.Param("id"), c.Query("projectName"), c.Query("version"), ctx.Logger)
+}
+
+func DispatchReleaseTrain(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	// the request from the cron service carries the trigger in the body.
+	args := new(commonmodels.ReleaseTrainArgs)
+	data, _ := c.GetRawData()
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, args); err != nil {
+			ctx.Err = e.ErrInvalidParam.AddErr(err)
+			return
+		}
+	}
+	triggeredBy := ctx.UserName
+	if args.TriggeredBy == setting.CronTaskCreator {
+		triggeredBy = setting.CronTaskCreator
+	}
+
+	internalhandler.InsertOperationLog(c, triggeredBy, c.Query("projectName"), "发车", "自定义工作流-发布列车", c.Param("id"), string(data), ctx.Logger)
+
+	ctx.Resp, ctx.Err = workflow.DispatchReleaseTrain(c.Param("id"), c.Query("projectName"), triggeredBy, ctx.Logger)
+}
@@ -29,6 +29,18 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	webhook := router.Group("webhook")
 	{
 		webhook.POST("", ProcessWebHook)
+		webhook.POST("/simulate", SimulateWebhook)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// webhook 事件队列
+	// ---------------------------------------------------------------------------------------
+	webhookEvents := router.Group("webhook/events")
+	{
+		webhookEvents.GET("", ListWebhookEvents)
+		webhookEvents.GET("/:id", GetWebhookEvent)
+		webhookEvents.POST("/:id/retry", RetryWebhookEvent)
+		webhookEvents.DELETE("/:id", DeleteWebhookEvent)
 	}
 
 	build := router.Group("build")
@@ -171,10 +183,22 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		workflowV4.POST("", CreateWorkflowV4)
 		workflowV4.GET("", ListWorkflowV4)
 		workflowV4.POST("/lint", LintWorkflowV4)
+		workflowV4.GET("/schema", GetWorkflowV4Schema)
+		workflowV4.POST("/validate", ValidateWorkflowV4)
+		workflowV4.POST("/jenkinsfile/convert", ConvertJenkinsfile)
+		workflowV4.POST("/ci/convert", ConvertCIConfig)
 		workflowV4.GET("/name/:name", FindWorkflowV4)
 		workflowV4.PUT("/:name", UpdateWorkflowV4)
 		workflowV4.DELETE("/:name", DeleteWorkflowV4)
+		workflowV4.POST("/:name/duplicate", DuplicateWorkflowV4)
 		workflowV4.GET("/preset/:name", GetWorkflowV4Preset)
+		workflowV4.POST("/:name/params/:param/options", ListWorkflowV4ParamOptions)
+		workflowV4.GET("/preference/:name", GetWorkflowV4Preference)
+		workflowV4.PUT("/preference/:name", UpdateWorkflowV4Preference)
+		workflowV4.GET("/badge/:name", GetWorkflowV4Badge)
+		workflowV4.GET("/badge/:name/status", GetWorkflowV4PublicStatus)
+		workflowV4.GET("/badge/:name/token", GetWorkflowV4BadgeToken)
+		workflowV4.PUT("/badge/:name/token", ResetWorkflowV4BadgeToken)
 		workflowV4.GET("/webhook/preset", GetWebhookForWorkflowV4Preset)
 		workflowV4.GET("/webhook", ListWebhookForWorkflowV4Preset)
 		workflowV4.POST("/webhook/:workflowName", CreateWebhookForWorkflowV4)
@@ -189,10 +213,46 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	{
 		taskV4.POST("", CreateWorkflowTaskV4)
 		taskV4.GET("", ListWorkflowTaskV4)
+		taskV4.GET("/mine", ListMyRecentWorkflowTaskV4)
+		taskV4.GET("/search", SearchWorkflowTaskV4)
 		taskV4.GET("/workflow/:workflowName/task/:taskID", GetWorkflowTaskV4)
+		taskV4.GET("/workflow/:workflowName/compare", CompareWorkflowTaskV4)
 		taskV4.DELETE("/workflow/:workflowName/task/:taskID", CancelWorkflowTaskV4)
 		taskV4.GET("/clone/workflow/:workflowName/task/:taskID", CloneWorkflowTaskV4)
 		taskV4.POST("/approve", ApproveStage)
+		taskV4.POST("/diff/confirm", ConfirmDeployDiff)
+		taskV4.POST("/rollout/control", ControlRollout)
+		taskV4.GET("/workflow/:workflowName/task/:taskID/rollout/:jobName", GetRolloutProgress)
+		taskV4.GET("/workflow/:workflowName/task/:taskID/export/:jobName", ExportJobContext)
+		taskV4.GET("/workflow/:workflowName/task/:taskID/debug/:jobName", DebugWorkflowTaskV4Job)
+		taskV4.DELETE("/workflow/:workflowName/task/:taskID/debug/:jobName", StopWorkflowTaskV4JobDebug)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// 发布列车接口
+	// ---------------------------------------------------------------------------------------
+	releaseTrain := router.Group("v4/releasetrain")
+	{
+		releaseTrain.GET("", ListReleaseTrains)
+		releaseTrain.POST("", CreateReleaseTrain)
+		releaseTrain.PUT("/:id", UpdateReleaseTrain)
+		releaseTrain.DELETE("/:id", DeleteReleaseTrain)
+		releaseTrain.GET("/:id/items", ListReleaseTrainItems)
+		releaseTrain.POST("/:id/dispatch", DispatchReleaseTrain)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// 环境自动更新接口
+	// ---------------------------------------------------------------------------------------
+	envAutoUpdate := router.Group("v4/envautoupdate")
+	{
+		envAutoUpdate.GET("", ListEnvAutoUpdatePolicies)
+		envAutoUpdate.POST("", CreateEnvAutoUpdatePolicy)
+		envAutoUpdate.GET("/events", ListEnvAutoUpdateEvents)
+		envAutoUpdate.PUT("/:id", UpdateEnvAutoUpdatePolicy)
+		envAutoUpdate.DELETE("/:id", DeleteEnvAutoUpdatePolicy)
+		envAutoUpdate.POST("/:id/pause", PauseEnvAutoUpdatePolicy)
+		envAutoUpdate.POST("/:id/resume", ResumeEnvAutoUpdatePolicy)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -17,22 +17,18 @@ limitations under the License.
 package handler
 
 import (
-	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/go-github/v35/github"
-	"github.com/hashicorp/go-multierror"
-	"github.com/xanzy/go-gitlab"
-	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/webhook"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
-	"github.com/koderover/zadig/pkg/tool/codehub"
-	"github.com/koderover/zadig/pkg/tool/gitee"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 // @Router /workflow/webhook [POST]
 // @Summary Process webhook
+// @Description The webhook is queued and processed asynchronously, failed webhooks are retried with backoff.
 // @Accept  json
 // @Produce json
 // @Success 200 {object} map[string]string "map[string]string - {message: 'success information'}"
@@ -45,53 +41,51 @@ func ProcessWebHook(c *gin.Context) {
 		ctx.Err = err
 		return
 	}
-	if github.WebHookType(c.Request) != "" {
-		ctx.Err = processGithub(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else if gitlab.HookEventType(c.Request) != "" {
-		ctx.Err = webhook.ProcessGitlabHook(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else if codehub.HookEventType(c.Request) != "" {
-		ctx.Err = webhook.ProcessCodehubHook(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else if gitee.HookEventType(c.Request) != "" {
-		ctx.Err = webhook.ProcessGiteeHook(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else {
-		ctx.Err = webhook.ProcessGerritHook(payload, c.Request, ctx.RequestID, ctx.Logger)
-	}
+	ctx.Err = webhook.EnqueueWebhook(payload, c.Request, ctx.RequestID, ctx.Logger)
 }
 
-func processGithub(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
-	errs := &multierror.Error{}
+func ListWebhookEvents(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	// trigger classic pipeline
-	_, err := webhook.ProcessGithubHook(payload, req, requestID, log)
-	if err != nil {
-		log.Errorf("error happens to trigger classic pipeline %v", err)
-		errs = multierror.Append(errs, err)
-	}
+	pageNum, _ := strconv.ParseInt(c.DefaultQuery("page_num", "1"), 10, 64)
+	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "20"), 10, 64)
+	ctx.Resp, ctx.Err = webhook.ListWebhookEvents(c.Query("status"), c.Query("source"), pageNum, pageSize, ctx.Logger)
+}
 
-	// trigger workflow
-	err = webhook.ProcessGithubWebHook(payload, req, requestID, log)
+func GetWebhookEvent(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	if err != nil {
-		log.Errorf("error happens to trigger workflow %v", err)
-		errs = multierror.Append(errs, err)
-	}
-	//测试管理webhook
-	err = webhook.ProcessGithubWebHookForTest(payload, req, requestID, log)
-	if err != nil {
-		log.Errorf("error happens to trigger ProcessGithubWebHookForTest %v", err)
-		errs = multierror.Append(errs, err)
-	}
-	// webhooks for scanning task
-	err = webhook.ProcessGithubWebhookForScanning(payload, req, requestID, log)
-	if err != nil {
-		log.Errorf("error happens to trigger Scanning for github %v", err)
-		errs = multierror.Append(errs, err)
-	}
-	// webhooks for workflow v4
-	err = webhook.ProcessGithubWebHookForWorkflowV4(payload, req, requestID, log)
-	if err != nil {
-		log.Errorf("error happens to trigger workflowV4 for github %v", err)
-		errs = multierror.Append(errs, err)
+	ctx.Resp, ctx.Err = webhook.GetWebhookEvent(c.Param("id"), ctx.Logger)
+}
+
+func RetryWebhookEvent(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "重试", "系统设置-Webhook事件", c.Param("id"), "", ctx.Logger)
+	ctx.Err = webhook.RetryWebhookEvent(c.Param("id"), ctx.Logger)
+}
+
+func DeleteWebhookEvent(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统设置-Webhook事件", c.Param("id"), "", ctx.Logger)
+	ctx.Err = webhook.DeleteWebhookEvent(c.Param("id"), ctx.Logger)
+}
+
+// SimulateWebhook returns the custom workflow triggers which match the payload and the arguments
+// of the tasks, no task is created.
+func SimulateWebhook(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(webhook.SimulateWebhookArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
 	}
-	return errs.ErrorOrNil()
+	ctx.Resp, ctx.Err = webhook.SimulateWebhook(args, ctx.Logger)
 }
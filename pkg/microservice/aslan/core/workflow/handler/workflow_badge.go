@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetWorkflowV4BadgeToken(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowV4BadgeToken(configbase.ExternalBaseURLFromRequest(c.Request), c.Param("name"), ctx.Logger)
+}
+
+func ResetWorkflowV4BadgeToken(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "重置", "自定义工作流-徽章", c.Param("name"), "", ctx.Logger)
+	ctx.Resp, ctx.Err = workflow.ResetWorkflowV4BadgeToken(configbase.ExternalBaseURLFromRequest(c.Request), c.Param("name"), ctx.UserName, ctx.Logger)
+}
+
+// GetWorkflowV4PublicStatus is called without logging in, the access is granted by the badge token.
+func GetWorkflowV4PublicStatus(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowV4PublicStatus(c.Param("name"), c.Query("token"), c.Query("branch"), ctx.Logger)
+}
+
+// GetWorkflowV4Badge is called without logging in, the access is granted by the badge token.
+func GetWorkflowV4Badge(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	badge, err := workflow.GetWorkflowV4Badge(c.Param("name"), c.Query("token"), c.Query("branch"), ctx.Logger)
+	if err != nil {
+		c.JSON(e.ErrorMessage(err))
+		c.Abort()
+		return
+	}
+	// the status changes with every run, do not let the image proxies cache it.
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.Data(http.StatusOK, "image/svg+xml", badge)
+}
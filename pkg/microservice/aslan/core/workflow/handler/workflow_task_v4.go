@@ -19,13 +19,17 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	podexecservice "github.com/koderover/zadig/pkg/microservice/podexec/core/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -35,6 +39,23 @@ type listWorkflowTaskV4Query struct {
 	PageSize     int64  `json:"page_size"    form:"page_size,default=20"`
 	PageNum      int64  `json:"page_num"     form:"page_num,default=1"`
 	WorkflowName string `json:"workflow_name" form:"workflow_name"`
+	// Labels filters the tasks by their labels, the format is key1=value1,key2=value2.
+	Labels string `json:"labels"        form:"labels"`
+}
+
+type searchWorkflowTaskV4Query struct {
+	CommitID    string `form:"commit"`
+	Image       string `form:"image"`
+	ParamName   string `form:"paramName"`
+	ParamValue  string `form:"paramValue"`
+	TaskCreator string `form:"creator"`
+	PageNum     int    `form:"page_num,default=1"`
+	PageSize    int    `form:"page_size,default=20"`
+}
+
+type compareWorkflowTaskV4Query struct {
+	Base int64 `form:"base" binding:"required"`
+	Head int64 `form:"head" binding:"required"`
 }
 
 type listWorkflowTaskV4Resp struct {
@@ -50,6 +71,20 @@ type ApproveRequest struct {
 	Comment      string `json:"comment"`
 }
 
+type ConfirmDeployDiffRequest struct {
+	WorkflowName string `json:"workflow_name"`
+	JobName      string `json:"job_name"`
+	TaskID       int64  `json:"task_id"`
+	Approve      bool   `json:"approve"`
+}
+
+type ControlRolloutRequest struct {
+	WorkflowName string `json:"workflow_name"`
+	JobName      string `json:"job_name"`
+	TaskID       int64  `json:"task_id"`
+	Action       string `json:"action"`
+}
+
 func CreateWorkflowTaskV4(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -69,7 +104,19 @@ func CreateWorkflowTaskV4(c *gin.Context) {
 		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
 		return
 	}
-	ctx.Resp, ctx.Err = workflow.CreateWorkflowTaskV4(ctx.UserName, args, ctx.Logger)
+	if internalhandler.IsAPITokenRequest(c) {
+		if ctx.Err = workflow.CheckAPIParamPolicy(args, ctx.Logger); ctx.Err != nil {
+			return
+		}
+	}
+	resp, err := workflow.CreateWorkflowTaskV4(ctx.UserName, args, ctx.Logger)
+	ctx.Resp, ctx.Err = resp, err
+	if ctx.Err == nil {
+		workflow.RememberWorkflowV4Params(ctx.UserID, args, ctx.Logger)
+		if resp.Hotfix {
+			internalhandler.InsertOperationLog(c, ctx.UserName, args.Project, "紧急修复执行", "自定义工作流任务", fmt.Sprintf("%s#%d", args.Name, resp.TaskID), string(data), ctx.Logger)
+		}
+	}
 }
 
 func ListWorkflowTaskV4(c *gin.Context) {
@@ -81,7 +128,33 @@ func ListWorkflowTaskV4(c *gin.Context) {
 		return
 	}
 
-	taskList, total, err := workflow.ListWorkflowTaskV4(args.WorkflowName, args.PageNum, args.PageSize, ctx.Logger)
+	labels, err := parseTaskLabels(args.Labels)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	q, err := internalhandler.GetPaginationQuery(c)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	// cursor pagination is used if any of its parameters is given, page_num and page_size are kept for the old clients.
+	if !q.IsEmpty() {
+		taskList, page, err := workflow.ListWorkflowTaskV4ByQuery(args.WorkflowName, labels, q, ctx.Logger)
+		if err != nil {
+			ctx.Err = err
+			return
+		}
+		internalhandler.SetPageHeaders(c, page)
+		ctx.Resp = listWorkflowTaskV4Resp{
+			WorkflowList: taskList,
+			Total:        page.Total,
+		}
+		return
+	}
+
+	taskList, total, err := workflow.ListWorkflowTaskV4(args.WorkflowName, labels, args.PageNum, args.PageSize, ctx.Logger)
 	resp := listWorkflowTaskV4Resp{
 		WorkflowList: taskList,
 		Total:        total,
@@ -90,6 +163,46 @@ func ListWorkflowTaskV4(c *gin.Context) {
 	ctx.Err = err
 }
 
+// SearchWorkflowTaskV4 searches the task history across the projects, it is called by picket with the projects
+// visible to the caller.
+func SearchWorkflowTaskV4(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &searchWorkflowTaskV4Query{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = workflow.SearchWorkflowTaskV4(&workflow.SearchWorkflowTaskArgs{
+		Projects:    c.QueryArray("projects"),
+		CommitID:    args.CommitID,
+		Image:       args.Image,
+		ParamName:   args.ParamName,
+		ParamValue:  args.ParamValue,
+		TaskCreator: args.TaskCreator,
+		PageNum:     args.PageNum,
+		PageSize:    args.PageSize,
+	}, ctx.Logger)
+}
+
+// parseTaskLabels parses the labels in the format key1=value1,key2=value2.
+func parseTaskLabels(labels string) (map[string]string, error) {
+	if labels == "" {
+		return nil, nil
+	}
+	resp := make(map[string]string)
+	for _, pair := range strings.Split(labels, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid label %q, the format is key=value", pair)
+		}
+		resp[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return resp, nil
+}
+
 func GetWorkflowTaskV4(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -102,6 +215,19 @@ func GetWorkflowTaskV4(c *gin.Context) {
 	ctx.Resp, ctx.Err = workflow.GetWorkflowTaskV4(c.Param("workflowName"), taskID, ctx.Logger)
 }
 
+// CompareWorkflowTaskV4 compares the head task with the base task of the workflow.
+func CompareWorkflowTaskV4(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &compareWorkflowTaskV4Query{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = workflow.CompareWorkflowTasks(c.Param("workflowName"), args.Base, args.Head, ctx.Logger)
+}
+
 func CancelWorkflowTaskV4(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -148,3 +274,115 @@ func ApproveStage(c *gin.Context) {
 
 	ctx.Err = workflow.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, args.Comment, args.TaskID, args.Approve, ctx.Logger)
 }
+
+func ConfirmDeployDiff(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &ConfirmDeployDiffRequest{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = workflow.ConfirmDeployDiff(args.WorkflowName, args.JobName, ctx.UserName, args.TaskID, args.Approve, ctx.Logger)
+}
+
+func ControlRollout(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := &ControlRolloutRequest{}
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = workflow.ControlRollout(args.WorkflowName, args.JobName, ctx.UserName, args.TaskID, args.Action, ctx.Logger)
+}
+
+func GetRolloutProgress(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	ctx.Resp, ctx.Err = workflow.GetRolloutProgress(c.Param("workflowName"), c.Param("jobName"), taskID, ctx.Logger)
+}
+
+func ExportJobContext(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+	bundle, fileName, err := workflow.ExportJobContext(c.Param("workflowName"), c.Param("jobName"), taskID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Data(http.StatusOK, "application/gzip", bundle)
+}
+
+func DebugWorkflowTaskV4Job(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "调试", "自定义工作流任务", fmt.Sprintf("%s-%d-%s", c.Param("workflowName"), taskID, c.Param("jobName")), "", ctx.Logger)
+	session, err := workflow.GetWorkflowTaskV4JobDebugSession(c.Param("workflowName"), taskID, c.Param("jobName"))
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	kubeCli, cfg, err := podexecservice.NewKubeOutClusterClient(session.ClusterID)
+	if err != nil {
+		ctx.Err = e.ErrDebugJob.AddErr(err)
+		return
+	}
+	if ok, err := podexecservice.ValidatePod(kubeCli, session.Namespace, session.PodName, session.ContainerName); !ok {
+		ctx.Err = e.ErrDebugJob.AddDesc(fmt.Sprintf("the pod %s can not be accessed: %v", session.PodName, err))
+		return
+	}
+	pty, err := podexecservice.NewTerminalSession(c.Writer, c.Request, nil)
+	if err != nil {
+		ctx.Err = e.ErrDebugJob.AddErr(err)
+		return
+	}
+	defer func() {
+		_ = pty.Close()
+	}()
+
+	if err := podexecservice.ExecPod(kubeCli, cfg, []string{"/bin/sh"}, pty, session.Namespace, session.PodName, session.ContainerName); err != nil {
+		msg := fmt.Sprintf("exec to pod error: %v", err)
+		ctx.Logger.Error(msg)
+		_, _ = pty.Write([]byte(msg))
+		ctx.Err = e.ErrDebugJob.AddDesc(msg)
+	}
+}
+
+func StopWorkflowTaskV4JobDebug(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "结束调试", "自定义工作流任务", fmt.Sprintf("%s-%d-%s", c.Param("workflowName"), taskID, c.Param("jobName")), "", ctx.Logger)
+	ctx.Err = workflow.StopWorkflowTaskV4JobDebug(ctx.UserName, c.Param("workflowName"), taskID, c.Param("jobName"), ctx.Logger)
+}
@@ -19,6 +19,7 @@ package handler
 import (
 	"bytes"
 	"io/ioutil"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -29,8 +30,16 @@ import (
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/pagination"
 )
 
+var workflowSchema = &pagination.Schema{
+	Key:        "name",
+	Sortable:   []string{"updateTime", "createTime"},
+	Filterable: []string{"workflow_type", "projectName", "isFavorite"},
+	Searchable: []string{"name", "description"},
+}
+
 type listWorkflowV4Query struct {
 	PageSize int64  `json:"page_size"    form:"page_size,default=20"`
 	PageNum  int64  `json:"page_num"     form:"page_num,default=1"`
@@ -87,6 +96,22 @@ func LintWorkflowV4(c *gin.Context) {
 	ctx.Err = workflow.LintWorkflowV4(args, ctx.Logger)
 }
 
+func GetWorkflowV4Schema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", workflow.GetWorkflowV4Schema())
+}
+
+func ValidateWorkflowV4(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	data, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = workflow.ValidateWorkflowV4Yaml(data, ctx.Logger)
+}
+
 func ListWorkflowV4(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -115,12 +140,19 @@ func ListWorkflowV4(c *gin.Context) {
 		ignoreWorkflowV4 = true
 	}
 	workflowList, err := workflow.ListWorkflowV4(args.Project, ctx.UserID, names, workflowV4Names, ignoreWorkflow, ignoreWorkflowV4, ctx.Logger)
-	resp := listWorkflowV4Resp{
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	workflowList, page, err := internalhandler.Paginate(c, workflowList, workflowSchema)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	ctx.Resp = listWorkflowV4Resp{
 		WorkflowList: workflowList,
-		Total:        0,
+		Total:        page.Total,
 	}
-	ctx.Resp = resp
-	ctx.Err = err
 }
 
 func UpdateWorkflowV4(c *gin.Context) {
@@ -153,6 +185,19 @@ func DeleteWorkflowV4(c *gin.Context) {
 	ctx.Err = workflow.DeleteWorkflowV4(c.Param("name"), ctx.Logger)
 }
 
+func DuplicateWorkflowV4(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(workflow.DuplicateWorkflowV4Args)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, c.Query("projectName"), "复制", "自定义工作流", c.Param("name"), "", ctx.Logger)
+	ctx.Resp, ctx.Err = workflow.DuplicateWorkflowV4(c.Param("name"), args, ctx.UserName, ctx.Logger)
+}
+
 func FindWorkflowV4(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	resp, err := workflow.FindWorkflowV4(c.Query("encryptedKey"), c.Param("name"), ctx.Logger)
@@ -168,7 +213,13 @@ func GetWorkflowV4Preset(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	ctx.Resp, ctx.Err = workflow.GetWorkflowv4Preset(c.Query("encryptedKey"), c.Param("name"), ctx.Logger)
+	resp, err := workflow.GetWorkflowv4Preset(c.Query("encryptedKey"), c.Param("name"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	workflow.ApplyWorkflowV4Preference(ctx.UserID, resp, ctx.Logger)
+	ctx.Resp = resp
 }
 
 func GetWebhookForWorkflowV4Preset(c *gin.Context) {
@@ -215,3 +266,44 @@ func DeleteWebhookForWorkflowV4(c *gin.Context) {
 
 	ctx.Err = workflow.DeleteWebhookForWorkflowV4(c.Param("workflowName"), c.Param("triggerName"), ctx.Logger)
 }
+
+func ConvertJenkinsfile(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(workflow.ConvertWorkflowArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	ctx.Resp, ctx.Err = workflow.ConvertJenkinsfile(args, ctx.Logger)
+}
+
+func ConvertCIConfig(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(workflow.ConvertWorkflowArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	ctx.Resp, ctx.Err = workflow.ConvertCIConfig(args, ctx.Logger)
+}
+
+type listWorkflowV4ParamOptionsReq struct {
+	// Values are the params selected in the launch form
+	Values map[string]string `json:"values"`
+}
+
+func ListWorkflowV4ParamOptions(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(listWorkflowV4ParamOptionsReq)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = workflow.ListWorkflowV4ParamOptions(c.Param("name"), c.Param("param"), args.Values, ctx.Logger)
+}
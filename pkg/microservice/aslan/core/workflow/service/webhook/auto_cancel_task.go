@@ -20,11 +20,13 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/task"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/types"
 )
 
 func AutoCancelTask(autoCancelOpt *AutoCancelOpt, log *zap.SugaredLogger) error {
@@ -177,3 +179,56 @@ func AutoCancelWorkflowV4Task(autoCancelOpt *AutoCancelOpt, log *zap.SugaredLogg
 	}
 	return nil
 }
+
+// CancelInProgressWorkflowV4Tasks cancels the running tasks of the workflow which are triggered for the older
+// commits of the same branch or pull request, the tasks which have started deploying are kept.
+func CancelInProgressWorkflowV4Tasks(workflow *commonmodels.WorkflowV4, eventRepo *types.Repository, commitID string, log *zap.SugaredLogger) {
+	if commitID == "" || eventRepo.Tag != "" {
+		return
+	}
+	recordHookPayload(workflow, eventRepo, commitID)
+	payload := workflow.HookPayload
+
+	tasks, err := commonrepo.NewworkflowTaskv4Coll().FindTodoTasksByWorkflowName(workflow.Name)
+	if err != nil {
+		log.Errorf("find [InCompletedWorkflowV4Tasks] error: %v", err)
+		return
+	}
+	for _, task := range tasks {
+		if task.TaskCreator != setting.WebhookTaskCreator || task.WorkflowArgs == nil || task.WorkflowArgs.HookPayload == nil {
+			continue
+		}
+		hook := task.WorkflowArgs.HookPayload
+		if hook.CodehostID != payload.CodehostID || hook.Repo != payload.Repo || hook.IsPr != payload.IsPr || hook.CommitID == commitID {
+			continue
+		}
+		if payload.IsPr && hook.MergeRequestID != payload.MergeRequestID {
+			continue
+		}
+		if !payload.IsPr && hook.Branch != payload.Branch {
+			continue
+		}
+		if workflowV4TaskDeployStarted(task) {
+			log.Infof("task %d of workflow %s has started deploying, skip canceling it", task.TaskID, task.WorkflowName)
+			continue
+		}
+		log.Infof("cancel task %d of workflow %s superseded by commit %s", task.TaskID, task.WorkflowName, commitID)
+		if err = workflowcontroller.CancelWorkflowTask(task.TaskCreator, task.WorkflowName, task.TaskID, log); err != nil {
+			log.Errorf("CancelRunningWorkflowV4Task failed,task.TaskCreator:%s, task.WorkflowName:%s, task.TaskID:%d, error: %v", task.TaskCreator, task.WorkflowName, task.TaskID, err)
+		}
+	}
+}
+
+func workflowV4TaskDeployStarted(task *commonmodels.WorkflowTask) bool {
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			switch config.JobType(job.JobType) {
+			case config.JobZadigDeploy, config.JobZadigHelmDeploy, config.JobCustomDeploy:
+				if job.StartTime > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/config"
+	microserviceConfig "github.com/koderover/zadig/pkg/microservice/aslan/config"
+	gitservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+)
+
+func ProcessAzureDevOpsHook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	if err := azuredevops.ValidateSecret(req, gitservice.GetHookSecret()); err != nil {
+		return err
+	}
+
+	event, err := azuredevops.ParseHook(payload)
+	if err != nil {
+		return err
+	}
+
+	switch ev := event.(type) {
+	case *azuredevops.PushEvent:
+		if ev.Resource == nil || ev.Resource.Repository == nil || ev.Resource.Repository.Project == nil {
+			return fmt.Errorf("push event %s without repository is skipped", ev.ID)
+		}
+	case *azuredevops.PullRequestEvent:
+		pr := ev.Resource
+		if pr == nil || pr.Repository == nil || pr.Repository.Project == nil {
+			return fmt.Errorf("pull request event %s without repository is skipped", ev.ID)
+		}
+		if pr.Status != azuredevops.PullRequestStatusActive || pr.LastMergeSourceCommit == nil {
+			return fmt.Errorf("pull request event %s is skipped", ev.EventType)
+		}
+	}
+
+	return TriggerWorkflowV4ByAzureDevOpsEvent(event, config.ExternalBaseURL(), requestID, log)
+}
+
+func findChangedFilesOfAzureDevOpsEvent(project, repo string, update *azuredevops.RefUpdate, prID, codehostID int) ([]string, error) {
+	detail, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find codehost %d: %v", codehostID, err)
+	}
+
+	cli := azuredevops.NewClient(detail.RepoAddress(), detail.AccessToken, detail.ProxyAddr(microserviceConfig.ProxyHTTPSAddr()), detail.UseProxy())
+	if prID > 0 {
+		return cli.ListPullRequestChangedFiles(project, repo, prID)
+	}
+	return cli.ListChangedFiles(project, repo, update.OldObjectID, update.NewObjectID)
+}
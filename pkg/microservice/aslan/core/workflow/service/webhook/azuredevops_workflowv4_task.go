@@ -0,0 +1,323 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+type azureDevOpsEventMatcherForWorkflowV4 interface {
+	Match(*commonmodels.MainHookRepo) (bool, error)
+	GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository
+}
+
+// azureDevOpsRepoMatched matches the repo by the name of its project and its own name, the project is
+// the namespace of the hook repo.
+func azureDevOpsRepoMatched(hookRepo *commonmodels.MainHookRepo, repo *azuredevops.Repository) bool {
+	if hookRepo.Source != setting.SourceFromAzureDevOps || repo == nil || repo.Project == nil {
+		return false
+	}
+	return hookRepo.GetRepoNamespace() == repo.Project.Name && hookRepo.RepoName == repo.Name
+}
+
+func azureDevOpsBranchMatched(hookRepo *commonmodels.MainHookRepo, branch string) bool {
+	if !hookRepo.IsRegular {
+		return hookRepo.Branch == branch
+	}
+	// Do not use regexp.MustCompile to avoid panic
+	matched, err := regexp.MatchString(hookRepo.Branch, branch)
+	return err == nil && matched
+}
+
+type azureDevOpsPushEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *azuredevops.PushEvent
+	update   *azuredevops.RefUpdate
+}
+
+func (apem *azureDevOpsPushEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	push := apem.event.Resource
+	if !azureDevOpsRepoMatched(hookRepo, push.Repository) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventPush) {
+		return false, nil
+	}
+
+	branch, _ := apem.update.IsBranch()
+	if !azureDevOpsBranchMatched(hookRepo, branch) {
+		return false, nil
+	}
+	hookRepo.Branch = branch
+	if push.PushedBy != nil {
+		hookRepo.Committer = push.PushedBy.DisplayName
+	}
+
+	changedFiles, err := findChangedFilesOfAzureDevOpsEvent(push.Repository.Project.Name, push.Repository.Name, apem.update, 0, hookRepo.CodehostID)
+	if err != nil {
+		apem.log.Warnf("failed to get changes of push %d", push.PushID)
+		return false, err
+	}
+	hookRepo.ChangedFiles = changedFiles
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func (apem *azureDevOpsPushEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		RepoOwner:     hookRepo.RepoOwner,
+		Branch:        hookRepo.Branch,
+		CommitID:      apem.update.NewObjectID,
+		Source:        hookRepo.Source,
+	}
+}
+
+type azureDevOpsTagEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *azuredevops.PushEvent
+	update   *azuredevops.RefUpdate
+}
+
+func (atem *azureDevOpsTagEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	push := atem.event.Resource
+	if !azureDevOpsRepoMatched(hookRepo, push.Repository) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventTag) {
+		return false, nil
+	}
+
+	hookRepo.Tag, _ = atem.update.IsTag()
+	if !hookRepo.TagFilter.Match(hookRepo.Tag) {
+		return false, nil
+	}
+	if push.PushedBy != nil {
+		hookRepo.Committer = push.PushedBy.DisplayName
+	}
+	return true, nil
+}
+
+func (atem *azureDevOpsTagEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		Tag:           hookRepo.Tag,
+		Source:        hookRepo.Source,
+	}
+}
+
+type azureDevOpsPullRequestEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *azuredevops.PullRequestEvent
+}
+
+func (aprm *azureDevOpsPullRequestEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	pr := aprm.event.Resource
+	if !azureDevOpsRepoMatched(hookRepo, pr.Repository) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventPr) {
+		return false, nil
+	}
+
+	if !azureDevOpsBranchMatched(hookRepo, pr.TargetBranch()) {
+		return false, nil
+	}
+	hookRepo.Branch = pr.TargetBranch()
+	if pr.CreatedBy != nil {
+		hookRepo.Committer = pr.CreatedBy.DisplayName
+	}
+
+	changedFiles, err := findChangedFilesOfAzureDevOpsEvent(pr.Repository.Project.Name, pr.Repository.Name, nil, pr.PullRequestID, hookRepo.CodehostID)
+	if err != nil {
+		aprm.log.Warnf("failed to get changes of pull request %d", pr.PullRequestID)
+		return false, err
+	}
+	aprm.log.Debugf("succeed to get %d changes in pull request event", len(changedFiles))
+
+	hookRepo.ChangedFiles = changedFiles
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func (aprm *azureDevOpsPullRequestEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		PR:            aprm.event.Resource.PullRequestID,
+		Source:        hookRepo.Source,
+	}
+}
+
+// createAzureDevOpsEventMatchersForWorkflowV4 returns a matcher for each ref update of a push, one push
+// can update several branches and tags.
+func createAzureDevOpsEventMatchersForWorkflowV4(
+	event interface{}, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger,
+) []azureDevOpsEventMatcherForWorkflowV4 {
+	var matchers []azureDevOpsEventMatcherForWorkflowV4
+	switch evt := event.(type) {
+	case *azuredevops.PushEvent:
+		for _, update := range evt.Resource.RefUpdates {
+			if update.IsDeleted() {
+				continue
+			}
+			if _, ok := update.IsBranch(); ok {
+				matchers = append(matchers, &azureDevOpsPushEventMatcherForWorkflowV4{
+					workflow: workflow,
+					log:      log,
+					event:    evt,
+					update:   update,
+				})
+			} else if _, ok := update.IsTag(); ok {
+				matchers = append(matchers, &azureDevOpsTagEventMatcherForWorkflowV4{
+					workflow: workflow,
+					log:      log,
+					event:    evt,
+					update:   update,
+				})
+			}
+		}
+	case *azuredevops.PullRequestEvent:
+		matchers = append(matchers, &azureDevOpsPullRequestEventMatcherForWorkflowV4{
+			workflow: workflow,
+			log:      log,
+			event:    evt,
+		})
+	}
+
+	return matchers
+}
+
+func TriggerWorkflowV4ByAzureDevOpsEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
+	if err != nil {
+		errMsg := fmt.Sprintf("list workflow v4 error: %v", err)
+		log.Error(errMsg)
+		return fmt.Errorf(errMsg)
+	}
+
+	mErr := &multierror.Error{}
+	var hookPayload *commonmodels.HookPayload
+
+	for _, workflow := range workflows {
+		if workflow.HookCtls == nil {
+			continue
+		}
+		for _, item := range workflow.HookCtls {
+			if !item.Enabled {
+				continue
+			}
+			if !CodehostAvailableToProject(item.MainRepo.CodehostID, workflow.Project) {
+				continue
+			}
+			for _, matcher := range createAzureDevOpsEventMatchersForWorkflowV4(event, workflow, log) {
+				matches, err := matcher.Match(item.MainRepo)
+				if err != nil {
+					mErr = multierror.Append(mErr, err)
+				}
+				if !matches {
+					continue
+				}
+
+				log.Infof("event match hook %v of %s", item.MainRepo, workflow.Name)
+				eventRepo := matcher.GetHookRepo(item.MainRepo)
+				if ev, isPr := event.(*azuredevops.PullRequestEvent); isPr {
+					mergeRequestID := strconv.Itoa(ev.Resource.PullRequestID)
+					commitID := ev.Resource.LastMergeSourceCommit.CommitID
+					autoCancelOpt := &AutoCancelOpt{
+						MergeRequestID: mergeRequestID,
+						CommitID:       commitID,
+						TaskType:       config.WorkflowType,
+						MainRepo:       item.MainRepo,
+						AutoCancel:     item.AutoCancel,
+						WorkflowName:   workflow.Name,
+					}
+					err := AutoCancelWorkflowV4Task(autoCancelOpt, log)
+					if err != nil {
+						log.Errorf("failed to auto cancel workflowV4 task when receive event %v due to %v ", event, err)
+						mErr = multierror.Append(mErr, err)
+					}
+
+					hookPayload = &commonmodels.HookPayload{
+						Owner:          eventRepo.RepoOwner,
+						Repo:           eventRepo.RepoName,
+						Branch:         eventRepo.Branch,
+						IsPr:           true,
+						CodehostID:     item.MainRepo.CodehostID,
+						MergeRequestID: mergeRequestID,
+						CommitID:       commitID,
+					}
+				}
+				if err := job.MergeArgs(workflow, item.WorkflowArg); err != nil {
+					errMsg := fmt.Sprintf("merge workflow args error: %v", err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+					continue
+				}
+				if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
+					errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+					continue
+				}
+				workflow.HookPayload = hookPayload
+				if eventRepo.Tag != "" {
+					workflow.HookPayload = &commonmodels.HookPayload{CodehostID: eventRepo.CodehostID, Tag: eventRepo.Tag}
+				}
+				recordTriggerMetadata(workflow, item.MainRepo, event)
+				if DedupWorkflowV4Task(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), false, log) {
+					continue
+				}
+				if item.CancelInProgress {
+					CancelInProgressWorkflowV4Tasks(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), log)
+				}
+				if resp, err := workflowservice.CreateWorkflowTaskV4(setting.WebhookTaskCreator, workflow, log); err != nil {
+					errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				} else {
+					log.Infof("succeed to create task %v", resp)
+				}
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/config"
+	microserviceConfig "github.com/koderover/zadig/pkg/microservice/aslan/config"
+	gitservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
+)
+
+// the from hash of a ref change when the ref is created
+const bitbucketEmptyHash = "0000000000000000000000000000000000000000"
+
+func ProcessBitbucketServerHook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	eventType := bitbucketserver.HookEventType(req)
+	if eventType == bitbucketserver.EventTypeDiagnosticsPing {
+		return nil
+	}
+	if err := bitbucketserver.ValidateSignature(req, payload, gitservice.GetHookSecret()); err != nil {
+		return err
+	}
+
+	event, err := bitbucketserver.ParseHook(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	if ev, ok := event.(*bitbucketserver.PullRequestEvent); ok {
+		if ev.PullRequest == nil || ev.PullRequest.State != bitbucketserver.PullRequestStateOpen {
+			return fmt.Errorf("pull request event %s is skipped", eventType)
+		}
+	}
+
+	return TriggerWorkflowV4ByBitbucketServerEvent(event, config.ExternalBaseURL(), requestID, log)
+}
+
+func findChangedFilesOfBitbucketServerEvent(project, repo string, change *bitbucketserver.RefChange, prID, codehostID int) ([]string, error) {
+	detail, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find codehost %d: %v", codehostID, err)
+	}
+
+	cli := bitbucketserver.NewClient(detail.Address, detail.AccessToken, detail.ProxyAddr(microserviceConfig.ProxyHTTPSAddr()), detail.UseProxy())
+	if prID > 0 {
+		return cli.ListPullRequestChangedFiles(project, repo, prID)
+	}
+	since := change.FromHash
+	if since == bitbucketEmptyHash {
+		since = ""
+	}
+	return cli.ListChangedFiles(project, repo, since, change.ToHash)
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+type bitbucketServerEventMatcherForWorkflowV4 interface {
+	Match(*commonmodels.MainHookRepo) (bool, error)
+	GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository
+}
+
+func bitbucketServerRepoMatched(hookRepo *commonmodels.MainHookRepo, repo *bitbucketserver.Repository) bool {
+	if hookRepo.Source != setting.SourceFromBitbucketServer || repo == nil || repo.Project == nil {
+		return false
+	}
+	return hookRepo.GetRepoNamespace() == repo.Project.Key && hookRepo.RepoName == repo.Slug
+}
+
+func bitbucketServerBranchMatched(hookRepo *commonmodels.MainHookRepo, branch string) bool {
+	if !hookRepo.IsRegular {
+		return hookRepo.Branch == branch
+	}
+	// Do not use regexp.MustCompile to avoid panic
+	matched, err := regexp.MatchString(hookRepo.Branch, branch)
+	return err == nil && matched
+}
+
+type bitbucketServerPushEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *bitbucketserver.RefsChangedEvent
+	change   *bitbucketserver.RefChange
+}
+
+func (bpem *bitbucketServerPushEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	ev := bpem.event
+	if !bitbucketServerRepoMatched(hookRepo, ev.Repository) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventPush) {
+		return false, nil
+	}
+
+	branch := getBranchFromRef(bpem.change.RefID)
+	if !bitbucketServerBranchMatched(hookRepo, branch) {
+		return false, nil
+	}
+	hookRepo.Branch = branch
+	if ev.Actor != nil {
+		hookRepo.Committer = ev.Actor.Name
+	}
+
+	changedFiles, err := findChangedFilesOfBitbucketServerEvent(ev.Repository.Project.Key, ev.Repository.Slug, bpem.change, 0, hookRepo.CodehostID)
+	if err != nil {
+		bpem.log.Warnf("failed to get changes of event %v", ev)
+		return false, err
+	}
+	hookRepo.ChangedFiles = changedFiles
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func (bpem *bitbucketServerPushEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		RepoOwner:     hookRepo.RepoOwner,
+		Branch:        hookRepo.Branch,
+		CommitID:      bpem.change.ToHash,
+		Source:        hookRepo.Source,
+	}
+}
+
+type bitbucketServerTagEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *bitbucketserver.RefsChangedEvent
+	change   *bitbucketserver.RefChange
+}
+
+func (btem *bitbucketServerTagEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	ev := btem.event
+	if !bitbucketServerRepoMatched(hookRepo, ev.Repository) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventTag) {
+		return false, nil
+	}
+
+	hookRepo.Tag = getTagFromRef(btem.change.RefID)
+	if !hookRepo.TagFilter.Match(hookRepo.Tag) {
+		return false, nil
+	}
+	if ev.Actor != nil {
+		hookRepo.Committer = ev.Actor.Name
+	}
+	return true, nil
+}
+
+func (btem *bitbucketServerTagEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		Tag:           hookRepo.Tag,
+		Source:        hookRepo.Source,
+	}
+}
+
+type bitbucketServerPullRequestEventMatcherForWorkflowV4 struct {
+	log      *zap.SugaredLogger
+	workflow *commonmodels.WorkflowV4
+	event    *bitbucketserver.PullRequestEvent
+}
+
+func (bprm *bitbucketServerPullRequestEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	pr := bprm.event.PullRequest
+	if pr.ToRef == nil || pr.FromRef == nil || !bitbucketServerRepoMatched(hookRepo, pr.ToRef.Repository) {
+		return false, nil
+	}
+	if !EventConfigured(hookRepo, config.HookEventPr) {
+		return false, nil
+	}
+
+	if !bitbucketServerBranchMatched(hookRepo, pr.ToRef.DisplayID) {
+		return false, nil
+	}
+	hookRepo.Branch = pr.ToRef.DisplayID
+	if pr.Author != nil && pr.Author.User != nil {
+		hookRepo.Committer = pr.Author.User.Name
+	}
+
+	repo := pr.ToRef.Repository
+	changedFiles, err := findChangedFilesOfBitbucketServerEvent(repo.Project.Key, repo.Slug, nil, pr.ID, hookRepo.CodehostID)
+	if err != nil {
+		bprm.log.Warnf("failed to get changes of pull request %d", pr.ID)
+		return false, err
+	}
+	bprm.log.Debugf("succeed to get %d changes in pull request event", len(changedFiles))
+
+	hookRepo.ChangedFiles = changedFiles
+	return MatchChanges(hookRepo, changedFiles), nil
+}
+
+func (bprm *bitbucketServerPullRequestEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		PR:            bprm.event.PullRequest.ID,
+		Source:        hookRepo.Source,
+	}
+}
+
+// createBitbucketServerEventMatchersForWorkflowV4 returns a matcher for each ref change of a push, one push
+// can update several branches and tags.
+func createBitbucketServerEventMatchersForWorkflowV4(
+	event interface{}, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger,
+) []bitbucketServerEventMatcherForWorkflowV4 {
+	var matchers []bitbucketServerEventMatcherForWorkflowV4
+	switch evt := event.(type) {
+	case *bitbucketserver.RefsChangedEvent:
+		for _, change := range evt.Changes {
+			if change.Type == bitbucketserver.RefChangeTypeDelete || change.Ref == nil {
+				continue
+			}
+			switch change.Ref.Type {
+			case bitbucketserver.RefTypeBranch:
+				matchers = append(matchers, &bitbucketServerPushEventMatcherForWorkflowV4{
+					workflow: workflow,
+					log:      log,
+					event:    evt,
+					change:   change,
+				})
+			case bitbucketserver.RefTypeTag:
+				matchers = append(matchers, &bitbucketServerTagEventMatcherForWorkflowV4{
+					workflow: workflow,
+					log:      log,
+					event:    evt,
+					change:   change,
+				})
+			}
+		}
+	case *bitbucketserver.PullRequestEvent:
+		matchers = append(matchers, &bitbucketServerPullRequestEventMatcherForWorkflowV4{
+			workflow: workflow,
+			log:      log,
+			event:    evt,
+		})
+	}
+
+	return matchers
+}
+
+func TriggerWorkflowV4ByBitbucketServerEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
+	if err != nil {
+		errMsg := fmt.Sprintf("list workflow v4 error: %v", err)
+		log.Error(errMsg)
+		return fmt.Errorf(errMsg)
+	}
+
+	mErr := &multierror.Error{}
+	var hookPayload *commonmodels.HookPayload
+	var notification *commonmodels.Notification
+
+	for _, workflow := range workflows {
+		if workflow.HookCtls == nil {
+			continue
+		}
+		for _, item := range workflow.HookCtls {
+			if !item.Enabled {
+				continue
+			}
+			if !CodehostAvailableToProject(item.MainRepo.CodehostID, workflow.Project) {
+				continue
+			}
+			for _, matcher := range createBitbucketServerEventMatchersForWorkflowV4(event, workflow, log) {
+				matches, err := matcher.Match(item.MainRepo)
+				if err != nil {
+					mErr = multierror.Append(mErr, err)
+				}
+				if !matches {
+					continue
+				}
+
+				log.Infof("event match hook %v of %s", item.MainRepo, workflow.Name)
+				eventRepo := matcher.GetHookRepo(item.MainRepo)
+				if ev, isPr := event.(*bitbucketserver.PullRequestEvent); isPr {
+					mergeRequestID := strconv.Itoa(ev.PullRequest.ID)
+					commitID := ev.PullRequest.FromRef.LatestCommit
+					autoCancelOpt := &AutoCancelOpt{
+						MergeRequestID: mergeRequestID,
+						CommitID:       commitID,
+						TaskType:       config.WorkflowType,
+						MainRepo:       item.MainRepo,
+						AutoCancel:     item.AutoCancel,
+						WorkflowName:   workflow.Name,
+					}
+					err := AutoCancelWorkflowV4Task(autoCancelOpt, log)
+					if err != nil {
+						log.Errorf("failed to auto cancel workflowV4 task when receive event %v due to %v ", event, err)
+						mErr = multierror.Append(mErr, err)
+					}
+
+					if notification == nil {
+						// build statuses are reported against the head commit of the pull request
+						item.MainRepo.Revision = commitID
+						notification, err = scmnotify.NewService().SendInitWebhookComment(
+							item.MainRepo, ev.PullRequest.ID, baseURI, false, false, false, true, log,
+						)
+						if err != nil {
+							log.Errorf("failed to init webhook comment due to %s", err)
+							mErr = multierror.Append(mErr, err)
+						}
+					}
+
+					hookPayload = &commonmodels.HookPayload{
+						Owner:          eventRepo.RepoOwner,
+						Repo:           eventRepo.RepoName,
+						Branch:         eventRepo.Branch,
+						IsPr:           true,
+						CodehostID:     item.MainRepo.CodehostID,
+						MergeRequestID: mergeRequestID,
+						CommitID:       commitID,
+					}
+				}
+				if err := job.MergeArgs(workflow, item.WorkflowArg); err != nil {
+					errMsg := fmt.Sprintf("merge workflow args error: %v", err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+					continue
+				}
+				if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
+					errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+					continue
+				}
+				if notification != nil {
+					workflow.NotificationID = notification.ID.Hex()
+				}
+				workflow.HookPayload = hookPayload
+				if eventRepo.Tag != "" {
+					workflow.HookPayload = &commonmodels.HookPayload{CodehostID: eventRepo.CodehostID, Tag: eventRepo.Tag}
+				}
+				recordTriggerMetadata(workflow, item.MainRepo, event)
+				if DedupWorkflowV4Task(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), false, log) {
+					continue
+				}
+				if item.CancelInProgress {
+					CancelInProgressWorkflowV4Tasks(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), log)
+				}
+				if resp, err := workflowservice.CreateWorkflowTaskV4(setting.WebhookTaskCreator, workflow, log); err != nil {
+					errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				} else {
+					log.Infof("succeed to create task %v", resp)
+				}
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
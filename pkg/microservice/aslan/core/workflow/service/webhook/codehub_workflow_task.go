@@ -150,6 +150,9 @@ func TriggerWorkflowByCodehubEvent(event interface{}, baseURI, requestID string,
 			}
 
 			// 2. match webhook
+			if !CodehostAvailableToProject(item.MainRepo.CodehostID, workflow.ProductTmplName) {
+				continue
+			}
 			matcher := createCodehubEventMatcher(event, workflow, log)
 			if matcher == nil {
 				continue
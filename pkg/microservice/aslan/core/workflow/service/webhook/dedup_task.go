@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// DedupWorkflowV4Task returns true if the trigger is deduplicated to a running task of the workflow which
+// is triggered for the same commit, in which case no new task should be created.
+func DedupWorkflowV4Task(workflow *commonmodels.WorkflowV4, eventRepo *types.Repository, commitID string, createGitCheck bool, log *zap.SugaredLogger) bool {
+	if workflow.DedupStrategy == "" || workflow.DedupStrategy == config.DedupStrategyForceNew {
+		return false
+	}
+	// tag events are releases, they are never merged into the tasks of the branches
+	if commitID == "" || eventRepo.Tag != "" {
+		return false
+	}
+	recordHookPayload(workflow, eventRepo, commitID)
+
+	tasks, err := commonrepo.NewworkflowTaskv4Coll().FindTodoTasksByWorkflowName(workflow.Name)
+	if err != nil {
+		log.Errorf("find [InCompletedWorkflowV4Tasks] error: %v", err)
+		return false
+	}
+	for _, task := range tasks {
+		if task.TaskCreator != setting.WebhookTaskCreator || task.WorkflowArgs == nil || task.WorkflowArgs.HookPayload == nil {
+			continue
+		}
+		hook := task.WorkflowArgs.HookPayload
+		if hook.CodehostID != eventRepo.CodehostID || hook.Repo != eventRepo.RepoName || hook.CommitID != commitID {
+			continue
+		}
+		log.Infof("commit %s of %s has a running task %d of workflow %s, strategy: %s", commitID, eventRepo.RepoName, task.TaskID, workflow.Name, workflow.DedupStrategy)
+
+		payload := workflow.HookPayload
+		samePR := hook.IsPr && hook.MergeRequestID == payload.MergeRequestID
+		if workflow.DedupStrategy == config.DedupStrategyAttach && payload.IsPr && !samePR {
+			if err := scmnotify.NewService().AttachTriggerForWorkflowV4(task, workflow, createGitCheck, log); err != nil {
+				log.Errorf("failed to attach the trigger to task %d of workflow %s, err: %s", task.TaskID, workflow.Name, err)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// recordHookPayload records the repo and commit of the trigger in the hook payload so that the later
+// triggers can find the task.
+func recordHookPayload(workflow *commonmodels.WorkflowV4, eventRepo *types.Repository, commitID string) {
+	if workflow.HookPayload == nil {
+		workflow.HookPayload = &commonmodels.HookPayload{}
+	}
+	if workflow.HookPayload.Repo == "" {
+		workflow.HookPayload.Owner = eventRepo.RepoOwner
+		workflow.HookPayload.Repo = eventRepo.RepoName
+		workflow.HookPayload.Branch = eventRepo.Branch
+		workflow.HookPayload.CodehostID = eventRepo.CodehostID
+	}
+	if workflow.HookPayload.CommitID == "" {
+		workflow.HookPayload.CommitID = commitID
+	}
+}
+
+// eventCommitID returns the commit the event triggers the workflow for.
+func eventCommitID(event interface{}, eventRepo *types.Repository, payload *commonmodels.HookPayload) string {
+	if payload != nil && payload.CommitID != "" {
+		return payload.CommitID
+	}
+	switch ev := event.(type) {
+	case *gitlab.PushEvent:
+		return ev.After
+	case *gitee.PushEvent:
+		return ev.After
+	}
+	return eventRepo.CommitID
+}
@@ -52,6 +52,7 @@ import (
 const (
 	changeMergedEventType    = "change-merged"
 	patchsetCreatedEventType = "patchset-created"
+	commentAddedEventType    = "comment-added"
 )
 
 type gerritTypeEvent struct {
@@ -60,7 +61,7 @@ type gerritTypeEvent struct {
 }
 
 func ProcessGerritHook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
-	baseURI := systemConfig.SystemAddress()
+	baseURI := systemConfig.ExternalBaseURL()
 	gerritTypeEventObj := new(gerritTypeEvent)
 	if err := json.Unmarshal(payload, gerritTypeEventObj); err != nil {
 		log.Errorf("processGerritHook json.Unmarshal err : %v", err)
@@ -263,7 +264,7 @@ func syncGerritLatestCommit(service *commonmodels.Service) (*systemconfig.CodeHo
 		return nil, err
 	}
 
-	gerritCli := gerrit.NewClient(detail.Address, detail.AccessToken, config.ProxyHTTPSAddr(), detail.EnableProxy)
+	gerritCli := gerrit.NewClient(detail.Address, detail.AccessToken, detail.ProxyAddr(config.ProxyHTTPSAddr()), detail.UseProxy())
 	commit, err := gerritCli.GetCommitByBranch(service.GerritRepoName, service.GerritBranchName)
 	if err != nil {
 		return detail, err
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strconv"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+const (
+	defaultApprovalLabel = "Code-Review"
+	defaultApprovalValue = 2
+)
+
+type commentAddedEvent struct {
+	Author         UploaderInfo    `json:"author"`
+	Approvals      []*approvalInfo `json:"approvals"`
+	Comment        string          `json:"comment"`
+	PatchSet       PatchSetInfo    `json:"patchSet"`
+	Change         ChangeInfo      `json:"change"`
+	Project        ProjectInfo     `json:"project"`
+	RefName        string          `json:"refName"`
+	Type           string          `json:"type"`
+	EventCreatedOn int             `json:"eventCreatedOn"`
+}
+
+// approvalInfo is a vote of the comment, OldValue is only set if the vote is changed by the comment.
+type approvalInfo struct {
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	OldValue string `json:"oldValue"`
+}
+
+// approvalReached returns whether the comment raises the vote on the label of the approval to its value, the
+// comments keeping the vote, e.g. the ones posted with the results of the workflows, do not match.
+func (e *commentAddedEvent) approvalReached(approval *commonmodels.GerritApproval) bool {
+	label, value := defaultApprovalLabel, defaultApprovalValue
+	if approval != nil && approval.Label != "" {
+		label, value = approval.Label, approval.Value
+	}
+
+	for _, a := range e.Approvals {
+		if a.Type != label || a.OldValue == "" {
+			continue
+		}
+		newValue, err := strconv.Atoi(a.Value)
+		if err != nil {
+			continue
+		}
+		oldValue, err := strconv.Atoi(a.OldValue)
+		if err != nil {
+			continue
+		}
+		if newValue >= value && oldValue < value {
+			return true
+		}
+	}
+	return false
+}
+
+// patchsetEvent returns the patch set the comment is added to in the form of patchset-created.
+func (e *commentAddedEvent) patchsetEvent() *patchsetCreatedEvent {
+	return &patchsetCreatedEvent{
+		Uploader:       e.Author,
+		PatchSet:       e.PatchSet,
+		Change:         e.Change,
+		Project:        e.Project,
+		RefName:        e.RefName,
+		Type:           e.Type,
+		EventCreatedOn: e.EventCreatedOn,
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing gerrit approvals", func() {
+
+	Context("test approvalReached", func() {
+		It("should match the vote raised to Code-Review +2 by default", func() {
+			event := &commentAddedEvent{Approvals: []*approvalInfo{{Type: "Code-Review", Value: "2", OldValue: "1"}}}
+			Expect(event.approvalReached(nil)).To(BeTrue())
+		})
+
+		It("should not match the comments keeping the vote", func() {
+			event := &commentAddedEvent{Approvals: []*approvalInfo{{Type: "Code-Review", Value: "2"}}}
+			Expect(event.approvalReached(nil)).To(BeFalse())
+		})
+
+		It("should match the configured label", func() {
+			event := &commentAddedEvent{Approvals: []*approvalInfo{
+				{Type: "Code-Review", Value: "2", OldValue: "0"},
+				{Type: "QA-Verified", Value: "1", OldValue: "-1"},
+			}}
+			Expect(event.approvalReached(&commonmodels.GerritApproval{Label: "QA-Verified", Value: 1})).To(BeTrue())
+			Expect(event.approvalReached(&commonmodels.GerritApproval{Label: "QA-Verified", Value: 2})).To(BeFalse())
+		})
+	})
+})
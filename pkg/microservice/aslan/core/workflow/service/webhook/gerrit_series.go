@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/gerrit"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// gerritSeries is the relation chain or the topic of a change, the tip of each project is checked out
+// in the build, its ancestors in the relation chain are checked out together.
+type gerritSeries struct {
+	tips    map[string]*gerrit.SeriesChange
+	changes []*gerrit.SeriesChange
+}
+
+func resolveGerritSeries(detail *systemconfig.CodeHost, mode string, event *patchsetCreatedEvent) (*gerritSeries, error) {
+	cli := gerrit.NewClient(detail.Address, detail.AccessToken, detail.ProxyAddr(config.ProxyHTTPSAddr()), detail.UseProxy())
+	self := &gerrit.SeriesChange{
+		Project:        event.Change.Project,
+		Branch:         event.Change.Branch,
+		Number:         event.Change.Number,
+		RevisionNumber: event.PatchSet.Number,
+	}
+
+	if mode == config.GerritSeriesRelationChain {
+		chain, err := cli.ListRelationChain(event.Change.Project, event.Change.Number)
+		if err != nil {
+			return nil, err
+		}
+		return newGerritSeries(self, chain), nil
+	}
+
+	if mode != config.GerritSeriesTopic || event.Change.Topic == "" {
+		return newGerritSeries(self, nil), nil
+	}
+	topicChanges, err := cli.ListTopicChanges(event.Change.Topic)
+	if err != nil {
+		return nil, err
+	}
+	series := newGerritSeries(self, topicChanges)
+	inTopic := sets.NewInt()
+	for _, change := range topicChanges {
+		inTopic.Insert(change.Number)
+	}
+	// changes of the same project in a topic are usually stacked, build the one on the top.
+	for project, tip := range series.tips {
+		chain, err := cli.ListRelationChain(project, tip.Number)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range chain {
+			if inTopic.Has(change.Number) {
+				series.tips[project] = change
+				break
+			}
+		}
+	}
+	return series, nil
+}
+
+func newGerritSeries(self *gerrit.SeriesChange, changes []*gerrit.SeriesChange) *gerritSeries {
+	series := &gerritSeries{tips: map[string]*gerrit.SeriesChange{}}
+	for _, change := range changes {
+		if _, ok := series.tips[change.Project]; !ok {
+			series.tips[change.Project] = change
+		}
+		series.changes = append(series.changes, change)
+	}
+	if _, ok := series.tips[self.Project]; !ok {
+		series.tips[self.Project] = self
+		series.changes = append(series.changes, self)
+	}
+	// the patch set of the event is newer than the one returned by the relation chain of the triggering change
+	if tip := series.tips[self.Project]; tip.Number == self.Number {
+		series.tips[self.Project] = self
+	}
+	return series
+}
+
+// repos returns the repos of the projects other than the one of the hook repo.
+func (s *gerritSeries) repos(hookRepo *types.Repository) []*types.Repository {
+	resp := make([]*types.Repository, 0)
+	for project, tip := range s.tips {
+		if project == hookRepo.RepoName {
+			continue
+		}
+		resp = append(resp, &types.Repository{
+			CodehostID:    hookRepo.CodehostID,
+			RepoName:      project,
+			RepoOwner:     hookRepo.RepoOwner,
+			RepoNamespace: hookRepo.RepoNamespace,
+			Branch:        tip.Branch,
+			PR:            tip.Number,
+			Source:        hookRepo.Source,
+		})
+	}
+	return resp
+}
+
+// notifyChanges returns the changes which are reported to besides the triggering one.
+func (s *gerritSeries) notifyChanges(self int) []*commonmodels.GerritChange {
+	resp := make([]*commonmodels.GerritChange, 0)
+	for _, change := range s.changes {
+		if change.Number == self {
+			continue
+		}
+		resp = append(resp, &commonmodels.GerritChange{Project: change.Project, Number: change.Number})
+	}
+	return resp
+}
+
+// hasSeriesTask checks whether the series is already being built, gerrit sends an event for
+// every change of a relation chain when the chain is rebased.
+func hasSeriesTask(workflowName, mergeRequestID, commitID string) (bool, error) {
+	tasks, err := commonrepo.NewworkflowTaskv4Coll().FindTodoTasksByWorkflowName(workflowName)
+	if err != nil {
+		return false, err
+	}
+	for _, task := range tasks {
+		if task.TaskCreator != setting.WebhookTaskCreator || task.WorkflowArgs == nil || task.WorkflowArgs.HookPayload == nil {
+			continue
+		}
+		payload := task.WorkflowArgs.HookPayload
+		if payload.MergeRequestID == mergeRequestID && payload.CommitID == commitID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func mergeSeriesRepos(workflow *commonmodels.WorkflowV4, repos []*types.Repository) error {
+	for _, repo := range repos {
+		if err := job.MergeWebhookRepo(workflow, repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seriesCommitID(tip *gerrit.SeriesChange, commitID string) string {
+	if tip.RevisionNumber == 0 {
+		return commitID
+	}
+	return strconv.Itoa(tip.RevisionNumber)
+}
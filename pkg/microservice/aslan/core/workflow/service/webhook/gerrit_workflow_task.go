@@ -74,6 +74,7 @@ type ChangeInfo struct {
 	CommitMessage string    `json:"commitMessage"`
 	CreatedOn     int       `json:"createdOn"`
 	Status        string    `json:"status"`
+	Topic         string    `json:"topic"`
 }
 
 type UploaderInfo struct {
@@ -178,7 +179,7 @@ func (gruem *gerritChangeMergedEventMatcher) Match(hookRepo *commonmodels.MainHo
 		for _, eventName := range gruem.Item.MainRepo.Events {
 			existEventNames = append(existEventNames, string(eventName))
 		}
-		if sets.NewString(existEventNames...).Has(event.Type) {
+		if sets.NewString(existEventNames...).Has(event.Type) && HookRepoAllowed(hookRepo) {
 			hookRepo.Committer = event.Submitter.Username
 			return true, nil
 		}
@@ -223,7 +224,7 @@ func (gpcem *gerritPatchsetCreatedEventMatcher) Match(hookRepo *commonmodels.Mai
 		for _, eventName := range gpcem.Item.MainRepo.Events {
 			existEventNames = append(existEventNames, string(eventName))
 		}
-		if sets.NewString(existEventNames...).Has(event.Type) {
+		if sets.NewString(existEventNames...).Has(event.Type) && HookRepoAllowed(hookRepo) {
 			hookRepo.Committer = event.Uploader.Username
 			return true, nil
 		}
@@ -316,6 +317,9 @@ func TriggerWorkflowByGerritEvent(event *gerritTypeEvent, body []byte, uri, base
 				}
 				if detail.Type == gerrit.CodehostTypeGerrit {
 					log.Debugf("TriggerWorkflowByGerritEvent find gerrit hook in workflow %s", workflow.Name)
+					if !detail.AvailableToProject(workflow.ProductTmplName) {
+						continue
+					}
 					matcher := createGerritEventMatcher(event, body, item, workflow, log)
 					if matcher == nil {
 						continue
@@ -439,7 +443,7 @@ func checkLatestTaskStaus(pipelineName, mergeRequestID, commitID string, detail
 	}
 
 	// 比较本次patchset 和 上一个触发任务的patchset 的change file是否相同
-	cli := gerrit.NewClient(detail.Address, detail.AccessToken, config.ProxyHTTPSAddr(), detail.EnableProxy)
+	cli := gerrit.NewClient(detail.Address, detail.AccessToken, detail.ProxyAddr(config.ProxyHTTPSAddr()), detail.UseProxy())
 	isDiff, err := cli.CompareTwoPatchset(mergeRequestID, commitID, tasks[0].TriggerBy.CommitID)
 	if err != nil {
 		log.Errorf("CompareTwoPatchset failed, mergeRequestID:%s, patchsetID:%s, oldPatchsetID:%s, err:%v", mergeRequestID, commitID, tasks[0].TriggerBy.CommitID, err)
@@ -61,7 +61,7 @@ func (gruem *gerritChangeMergedEventMatcherForWorkflowV4) Match(hookRepo *common
 		for _, eventName := range gruem.Item.MainRepo.Events {
 			existEventNames = append(existEventNames, string(eventName))
 		}
-		if sets.NewString(existEventNames...).Has(event.Type) {
+		if sets.NewString(existEventNames...).Has(event.Type) && HookRepoAllowed(hookRepo) {
 			hookRepo.Committer = event.Submitter.Username
 			return true, nil
 		}
@@ -98,7 +98,7 @@ func (gpcem *gerritPatchsetCreatedEventMatcherForWorkflowV4) Match(hookRepo *com
 		for _, eventName := range gpcem.Item.MainRepo.Events {
 			existEventNames = append(existEventNames, string(eventName))
 		}
-		if sets.NewString(existEventNames...).Has(event.Type) {
+		if sets.NewString(existEventNames...).Has(event.Type) && HookRepoAllowed(hookRepo) {
 			hookRepo.Committer = event.Uploader.Username
 			return true, nil
 		}
@@ -118,6 +118,58 @@ func (gpcem *gerritPatchsetCreatedEventMatcherForWorkflowV4) GetHookRepo(hookRep
 	}
 }
 
+// gerritCommentAddedEventMatcherForWorkflowV4 matches the votes of the reviewers, e.g. the workflow runs the
+// checks required before submitting once the change is approved by Code-Review +2.
+type gerritCommentAddedEventMatcherForWorkflowV4 struct {
+	Log      *zap.SugaredLogger
+	Item     *commonmodels.WorkflowV4Hook
+	Workflow *commonmodels.WorkflowV4
+	Event    *commentAddedEvent
+}
+
+func (gcaem *gerritCommentAddedEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	event := gcaem.Event
+	if event == nil {
+		return false, fmt.Errorf("event doesn't match")
+	}
+
+	if event.Project.Name == gcaem.Item.MainRepo.RepoName && strings.Contains(event.RefName, gcaem.Item.MainRepo.Branch) {
+		existEventNames := make([]string, 0)
+		for _, eventName := range gcaem.Item.MainRepo.Events {
+			existEventNames = append(existEventNames, string(eventName))
+		}
+		if sets.NewString(existEventNames...).Has(event.Type) && event.approvalReached(hookRepo.GerritApproval) && HookRepoAllowed(hookRepo) {
+			hookRepo.Committer = event.Author.Username
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (gcaem *gerritCommentAddedEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		PR:            gcaem.Event.Change.Number,
+		Source:        hookRepo.Source,
+	}
+}
+
+// gerritChangeEvent returns the patch set the workflow is triggered on, nil is returned if the event is not on a
+// change.
+func gerritChangeEvent(matcher gerritEventMatcherForWorkflowV4) *patchsetCreatedEvent {
+	switch m := matcher.(type) {
+	case *gerritPatchsetCreatedEventMatcherForWorkflowV4:
+		return m.Event
+	case *gerritCommentAddedEventMatcherForWorkflowV4:
+		return m.Event.patchsetEvent()
+	}
+	return nil
+}
+
 func createGerritEventMatcherForWorkflowV4(event *gerritTypeEvent, body []byte, item *commonmodels.WorkflowV4Hook, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) gerritEventMatcherForWorkflowV4 {
 	switch event.Type {
 	case changeMergedEventType:
@@ -142,6 +194,17 @@ func createGerritEventMatcherForWorkflowV4(event *gerritTypeEvent, body []byte,
 			Log:      log,
 			Event:    &ev,
 		}
+	case commentAddedEventType:
+		var ev commentAddedEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			log.Errorf("createGerritEventMatcher json.Unmarshal err : %v", err)
+		}
+		return &gerritCommentAddedEventMatcherForWorkflowV4{
+			Workflow: workflow,
+			Item:     item,
+			Log:      log,
+			Event:    &ev,
+		}
 	}
 
 	return nil
@@ -188,6 +251,9 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 			if detail.Type != gerrit.CodehostTypeGerrit {
 				continue
 			}
+			if !detail.AvailableToProject(workflow.Project) {
+				continue
+			}
 			matcher := createGerritEventMatcherForWorkflowV4(event, body, item, workflow, log)
 			if matcher == nil {
 				continue
@@ -203,7 +269,9 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 			eventRepo := matcher.GetHookRepo(item.MainRepo)
 
 			var mergeRequestID, commitID string
-			if m, ok := matcher.(*gerritPatchsetCreatedEventMatcherForWorkflowV4); ok {
+			var seriesRepos []*types.Repository
+			var seriesChanges []*commonmodels.GerritChange
+			if ev := gerritChangeEvent(matcher); ev != nil {
 				if item.CheckPatchSetChange {
 					// for different patch sets under the same pr, if the updated contents of the two patch sets are exactly the same, and the task triggered by the previous patch set is executed successfully, the new patch set will no longer trigger the task.
 					if checkLatestTaskStaus(workflow.Name, mergeRequestID, commitID, detail, log) {
@@ -212,8 +280,28 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 					}
 				}
 
-				mergeRequestID = strconv.Itoa(m.Event.Change.Number)
-				commitID = strconv.Itoa(m.Event.PatchSet.Number)
+				mergeRequestID = strconv.Itoa(ev.Change.Number)
+				commitID = strconv.Itoa(ev.PatchSet.Number)
+				if item.MainRepo.GerritSeries != "" {
+					series, err := resolveGerritSeries(detail, item.MainRepo.GerritSeries, ev)
+					if err != nil {
+						// build the triggering change only
+						log.Warnf("failed to resolve gerrit %s of change %d, err: %s", item.MainRepo.GerritSeries, ev.Change.Number, err)
+					} else {
+						tip := series.tips[ev.Change.Project]
+						eventRepo.PR = tip.Number
+						mergeRequestID = strconv.Itoa(tip.Number)
+						commitID = seriesCommitID(tip, commitID)
+						seriesRepos = series.repos(eventRepo)
+						seriesChanges = series.notifyChanges(ev.Change.Number)
+					}
+					if exist, err := hasSeriesTask(workflow.Name, mergeRequestID, commitID); err != nil {
+						log.Errorf("failed to find tasks of workflow %s, err: %s", workflow.Name, err)
+					} else if exist {
+						log.Infof("gerrit series has already triggered task, workflowName:%s, mergeRequestID:%s, PatchSetID:%s", workflow.Name, mergeRequestID, commitID)
+						continue
+					}
+				}
 				autoCancelOpt := &AutoCancelOpt{
 					MergeRequestID: mergeRequestID,
 					CommitID:       commitID,
@@ -232,9 +320,10 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 					// gerrit has no repo owner
 					mainRepo := item.MainRepo
 					mainRepo.RepoOwner = ""
-					mainRepo.Revision = m.Event.PatchSet.Revision
+					mainRepo.Revision = ev.PatchSet.Revision
+					mainRepo.SeriesChanges = seriesChanges
 					notification, _ = scmnotify.NewService().SendInitWebhookComment(
-						mainRepo, m.Event.Change.Number, baseURI, false, false, false, true, log,
+						mainRepo, ev.Change.Number, baseURI, false, false, false, true, log,
 					)
 				}
 
@@ -260,6 +349,12 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 				errorList = multierror.Append(errorList, fmt.Errorf(errMsg))
 				continue
 			}
+			if err := mergeSeriesRepos(item.WorkflowArg, seriesRepos); err != nil {
+				errMsg := fmt.Sprintf("merge gerrit series repos to workflowargs error: %v", err)
+				log.Error(errMsg)
+				errorList = multierror.Append(errorList, fmt.Errorf(errMsg))
+				continue
+			}
 			if notification != nil {
 				workflow.NotificationID = notification.ID.Hex()
 			}
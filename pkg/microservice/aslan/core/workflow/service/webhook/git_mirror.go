@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitmirror"
+)
+
+// the fields locating the repo in the payloads of github, gitlab, gitee and bitbucket server.
+type gitMirrorHookPayload struct {
+	Repository struct {
+		FullName string `json:"full_name"`
+		Slug     string `json:"slug"`
+		Project  struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"repository"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+}
+
+// syncGitMirrors updates the mirrors of the repo in the background, the payload is not validated since
+// the worst case is an unnecessary sync.
+func syncGitMirrors(payload []byte, _ *http.Request, _ string, log *zap.SugaredLogger) error {
+	hook := &gitMirrorHookPayload{}
+	if err := json.Unmarshal(payload, hook); err != nil {
+		log.Debugf("skip the mirrors for the payload can not be parsed, err: %s", err)
+		return nil
+	}
+
+	switch {
+	case hook.Project.PathWithNamespace != "":
+		gitmirror.NotifyPush(hook.Project.PathWithNamespace)
+	case hook.Repository.FullName != "":
+		gitmirror.NotifyPush(hook.Repository.FullName)
+	case hook.Repository.Slug != "" && hook.Repository.Project.Key != "":
+		gitmirror.NotifyPush(hook.Repository.Project.Key + "/" + hook.Repository.Slug)
+	}
+	return nil
+}
@@ -61,7 +61,7 @@ func ProcessGiteeHook(payload []byte, req *http.Request, requestID string, log *
 		return err
 	}
 
-	baseURI := config.SystemAddress()
+	baseURI := config.ExternalBaseURL()
 	var errorList = &multierror.Error{}
 	var wg sync.WaitGroup
 
@@ -339,7 +339,7 @@ func syncGiteeLatestCommit(service *commonmodels.Service) (*systemconfig.CodeHos
 		return nil, err
 	}
 
-	giteeCli := gitee.NewClient(detail.ID, detail.AccessToken, microserviceConfig.ProxyHTTPSAddr(), detail.EnableProxy)
+	giteeCli := gitee.NewClient(detail.ID, detail.AccessToken, detail.ProxyAddr(microserviceConfig.ProxyHTTPSAddr()), detail.UseProxy())
 	commit, err := giteeCli.GetSingleBranch(detail.AccessToken, service.RepoOwner, service.RepoName, service.BranchName)
 	if err != nil {
 		return detail, err
@@ -222,6 +222,9 @@ func TriggerTestByGiteeEvent(event interface{}, baseURI, requestID string, log *
 				}
 
 				// 2. match webhook
+				if !CodehostAvailableToProject(item.MainRepo.CodehostID, testing.ProductName) {
+					continue
+				}
 				matcher := createGiteeEventMatcherForTesting(event, diffSrv, testing, log)
 				if matcher == nil {
 					continue
@@ -253,6 +253,9 @@ func TriggerWorkflowByGiteeEvent(event interface{}, baseURI, requestID string, l
 					continue
 				}
 
+				if !CodehostAvailableToProject(item.MainRepo.CodehostID, workflow.ProductTmplName) {
+					continue
+				}
 				matcher := createGiteeEventMatcher(event, diffSrv, workflow, log)
 				if matcher == nil {
 					continue
@@ -346,7 +349,7 @@ func findChangedFilesOfPullRequestEvent(event *gitee.PullRequestEvent, codehostI
 		return nil, fmt.Errorf("failed to find codehost %d: %v", codehostID, err)
 	}
 
-	giteeCli := gitee.NewClient(detail.ID, detail.AccessToken, config.ProxyHTTPSAddr(), detail.EnableProxy)
+	giteeCli := gitee.NewClient(detail.ID, detail.AccessToken, detail.ProxyAddr(config.ProxyHTTPSAddr()), detail.UseProxy())
 	commitComparison, err := giteeCli.GetReposOwnerRepoCompareBaseHead(detail.AccessToken, event.Project.Namespace, event.Project.Name, event.PullRequest.Base.Sha, event.PullRequest.Head.Sha)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get changes from gitee, err: %v", err)
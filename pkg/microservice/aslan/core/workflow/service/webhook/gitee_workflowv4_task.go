@@ -72,6 +72,7 @@ func (gpem *giteePushEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.Mai
 			changedFiles = append(changedFiles, commit.Removed...)
 			changedFiles = append(changedFiles, commit.Modified...)
 		}
+		hookRepo.ChangedFiles = changedFiles
 		return MatchChanges(hookRepo, changedFiles), nil
 	}
 
@@ -124,6 +125,7 @@ func (gmem *giteeMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.Ma
 			}
 			gmem.log.Debugf("succeed to get %d changes in merge event", len(changedFiles))
 
+			hookRepo.ChangedFiles = changedFiles
 			return MatchChanges(hookRepo, changedFiles), nil
 		}
 	}
@@ -167,6 +169,9 @@ func (gtem giteeTagEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainH
 			}
 		}
 		hookRepo.Tag = getTagFromRef(ev.Ref)
+		if !hookRepo.TagFilter.Match(hookRepo.Tag) {
+			return false, nil
+		}
 		hookRepo.Committer = ev.Sender.Name
 
 		return true, nil
@@ -238,6 +243,9 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 			if !item.Enabled {
 				continue
 			}
+			if !CodehostAvailableToProject(item.MainRepo.CodehostID, workflow.Project) {
+				continue
+			}
 			matcher := createGiteeEventMatcherForWorkflowV4(event, diffSrv, workflow, log)
 			if matcher == nil {
 				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
@@ -309,6 +317,16 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 				workflow.NotificationID = notification.ID.Hex()
 			}
 			workflow.HookPayload = hookPayload
+			if eventRepo.Tag != "" {
+				workflow.HookPayload = &commonmodels.HookPayload{CodehostID: eventRepo.CodehostID, Tag: eventRepo.Tag}
+			}
+			recordTriggerMetadata(workflow, item.MainRepo, event)
+			if DedupWorkflowV4Task(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), false, log) {
+				continue
+			}
+			if item.CancelInProgress {
+				CancelInProgressWorkflowV4Tasks(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), log)
+			}
 			if resp, err := workflowservice.CreateWorkflowTaskV4(setting.WebhookTaskCreator, workflow, log); err != nil {
 				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
 				log.Error(errMsg)
@@ -183,7 +183,7 @@ func prEventToPipelineTasks(event *github.PullRequestEvent, requestID string, lo
 		log.Errorf("GetCodeHostInfo failed, err: %v", err)
 		return nil, err
 	}
-	gc := githubtool.NewClient(&githubtool.Config{AccessToken: ch.AccessToken, Proxy: config.ProxyHTTPSAddr()})
+	gc := githubtool.NewClient(&githubtool.Config{AccessToken: ch.AccessToken, Proxy: ch.ProxyAddr(config.ProxyHTTPSAddr())})
 	commitFiles, _ := gc.ListFiles(context.Background(), owner, repo, prNum, &githubtool.ListOptions{PerPage: 100})
 
 	var files []string
@@ -51,6 +51,9 @@ func TriggerScanningByGithubEvent(event interface{}, requestID string, log *zap.
 		log.Infof("scanning.AdvancedSetting.Hookctl is: [%s], scanning.AdvancedSetting.HookCtl.Enabled is: [%s], scanningName is: [%s]", scanning.AdvancedSetting.HookCtl, scanning.AdvancedSetting.HookCtl.Enabled, scanning.ID)
 		if scanning.AdvancedSetting.HookCtl != nil && scanning.AdvancedSetting.HookCtl.Enabled {
 			for _, item := range scanning.AdvancedSetting.HookCtl.Items {
+				if !CodehostAvailableToProject(item.CodehostID, scanning.ProjectName) {
+					continue
+				}
 				matcher := createGithubEventMatcherForScanning(event, diffSrv, scanning, log)
 				if matcher == nil {
 					log.Infof("got a nil matcher for trigger: %s/%s, stopping...", item.RepoOwner, item.RepoName)
@@ -48,6 +48,9 @@ func TriggerTestByGithubEvent(event interface{}, requestID string, log *zap.Suga
 				if item.TestArgs == nil {
 					continue
 				}
+				if !CodehostAvailableToProject(item.MainRepo.CodehostID, testing.ProductName) {
+					continue
+				}
 				matcher := createGithubEventMatcherForTesting(event, diffSrv, testing, log)
 				if matcher == nil {
 					continue
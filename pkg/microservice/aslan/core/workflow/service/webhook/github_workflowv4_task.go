@@ -75,6 +75,7 @@ func (gpem *githubPushEventMatcheForWorkflowV4) Match(hookRepo *commonmodels.Mai
 		changedFiles = append(changedFiles, commit.Removed...)
 		changedFiles = append(changedFiles, commit.Modified...)
 	}
+	hookRepo.ChangedFiles = changedFiles
 	return MatchChanges(hookRepo, changedFiles), nil
 }
 
@@ -129,6 +130,7 @@ func (gmem *githubMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.M
 		}
 		gmem.log.Debugf("succeed to get %d changes in merge event", len(changedFiles))
 
+		hookRepo.ChangedFiles = changedFiles
 		return MatchChanges(hookRepo, changedFiles), nil
 	}
 
@@ -177,6 +179,9 @@ func (gtem githubTagEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.Main
 		}
 	}
 	hookRepo.Tag = getTagFromRef(*ev.Ref)
+	if !hookRepo.TagFilter.Match(hookRepo.Tag) {
+		return false, nil
+	}
 	if ev.Sender.Name != nil {
 		hookRepo.Committer = *ev.Sender.Name
 	}
@@ -246,6 +251,9 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 			if !item.Enabled {
 				continue
 			}
+			if !CodehostAvailableToProject(item.MainRepo.CodehostID, workflow.Project) {
+				continue
+			}
 			matcher := createGithubEventMatcherForWorkflowV4(event, diffSrv, workflow, log)
 			if matcher == nil {
 				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
@@ -306,6 +314,16 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 				continue
 			}
 			workflow.HookPayload = hookPayload
+			if eventRepo.Tag != "" {
+				workflow.HookPayload = &commonmodels.HookPayload{CodehostID: eventRepo.CodehostID, Tag: eventRepo.Tag}
+			}
+			recordTriggerMetadata(workflow, item.MainRepo, event)
+			if DedupWorkflowV4Task(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), workflow.HookPayload.IsPr, log) {
+				continue
+			}
+			if item.CancelInProgress {
+				CancelInProgressWorkflowV4Tasks(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), log)
+			}
 			if resp, err := workflowservice.CreateWorkflowTaskV4(setting.WebhookTaskCreator, workflow, log); err != nil {
 				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
 				log.Error(errMsg)
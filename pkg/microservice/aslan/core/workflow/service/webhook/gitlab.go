@@ -59,7 +59,7 @@ func ProcessGitlabHook(payload []byte, req *http.Request, requestID string, log
 		return err
 	}
 
-	baseURI := config.SystemAddress()
+	baseURI := config.ExternalBaseURL()
 	var pushEvent *gitlab.PushEvent
 	var mergeEvent *gitlab.MergeEvent
 	var tagEvent *gitlab.TagEvent
@@ -199,6 +199,9 @@ func TriggerPipelineByGitlabEvent(event interface{}, baseURI, requestID string,
 		if pipelineObject.Hook != nil && pipelineObject.Hook.Enabled {
 			log.Debugf("find %d hooks in pipeline %s", len(pipelineObject.Hook.GitHooks), pipelineObject.Name)
 			for _, item := range pipelineObject.Hook.GitHooks {
+				if !CodehostAvailableToProject(item.CodehostID, pipelineObject.ProductName) {
+					continue
+				}
 				matcher := pipelineCreateGitlabEventMatcher(event, diffSrv, pipelineObject, log)
 				if matcher == nil {
 					continue
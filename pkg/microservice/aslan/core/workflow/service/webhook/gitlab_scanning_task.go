@@ -49,6 +49,9 @@ func TriggerScanningByGitlabEvent(event interface{}, baseURI, requestID string,
 			log.Infof("find %d hooks in scanning %s", len(scanning.AdvancedSetting.HookCtl.Items), scanning.Name)
 			for _, item := range scanning.AdvancedSetting.HookCtl.Items {
 				// 2. match webhook
+				if !CodehostAvailableToProject(item.CodehostID, scanning.ProjectName) {
+					continue
+				}
 				matcher := createGitlabEventMatcherForScanning(event, diffSrv, scanning, log)
 				if matcher == nil {
 					continue
@@ -160,6 +160,9 @@ func TriggerTestByGitlabEvent(event interface{}, baseURI, requestID string, log
 				}
 
 				// 2. match webhook
+				if !CodehostAvailableToProject(item.MainRepo.CodehostID, testing.ProductName) {
+					continue
+				}
 				matcher := createGitlabEventMatcherForTesting(event, diffSrv, testing, log)
 				if matcher == nil {
 					continue
@@ -93,6 +93,7 @@ func (gmem *gitlabMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.M
 			gmem.yamlServiceChanged = serviceChangeds
 			return len(serviceChangeds) != 0, nil
 		}
+		hookRepo.ChangedFiles = changedFiles
 		return MatchChanges(hookRepo, changedFiles), nil
 	}
 	return false, nil
@@ -187,7 +188,7 @@ func (gpem *gitlabPushEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.Ma
 		return false, err
 	}
 
-	client, err := gitlabtool.NewClient(detail.ID, detail.Address, detail.AccessToken, config.ProxyHTTPSAddr(), detail.EnableProxy)
+	client, err := gitlabtool.NewClient(detail.ID, detail.Address, detail.AccessToken, detail.ProxyAddr(config.ProxyHTTPSAddr()), detail.UseProxy())
 	if err != nil {
 		gpem.log.Errorf("NewClient error: %s", err)
 		return false, err
@@ -208,6 +209,7 @@ func (gpem *gitlabPushEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.Ma
 		gpem.yamlServiceChanged = serviceChangeds
 		return len(serviceChangeds) != 0, nil
 	}
+	hookRepo.ChangedFiles = changedFiles
 	return MatchChanges(hookRepo, changedFiles), nil
 }
 
@@ -266,6 +268,9 @@ func (gtem gitlabTagEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.Main
 
 	hookRepo.Committer = ev.UserName
 	hookRepo.Tag = getTagFromRef(ev.Ref)
+	if !hookRepo.TagFilter.Match(hookRepo.Tag) {
+		return false, nil
+	}
 
 	return true, nil
 }
@@ -330,6 +335,9 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 					continue
 				}
 			}
+			if !CodehostAvailableToProject(item.MainRepo.CodehostID, workflow.Project) {
+				continue
+			}
 			matcher := createGitlabEventMatcherForWorkflowV4(event, diffSrv, workflow, log)
 			if matcher == nil {
 				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
@@ -373,6 +381,7 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 					MergeRequestID: mergeRequestID,
 					CommitID:       commitID,
 					CodehostID:     eventRepo.CodehostID,
+					MergeGate:      item.MergeGate,
 				}
 
 				if notification == nil {
@@ -397,11 +406,26 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 				workflow.NotificationID = notification.ID.Hex()
 			}
 			workflow.HookPayload = hookPayload
+			if eventRepo.Tag != "" {
+				workflow.HookPayload = &commonmodels.HookPayload{CodehostID: eventRepo.CodehostID, Tag: eventRepo.Tag}
+			}
+			recordTriggerMetadata(workflow, item.MainRepo, event)
+			if DedupWorkflowV4Task(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), item.MergeGate, log) {
+				continue
+			}
+			if item.CancelInProgress {
+				CancelInProgressWorkflowV4Tasks(workflow, eventRepo, eventCommitID(event, eventRepo, workflow.HookPayload), log)
+			}
 			if resp, err := workflowservice.CreateWorkflowTaskV4(setting.WebhookTaskCreator, workflow, log); err != nil {
 				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
 			} else {
+				if workflow.HookPayload != nil && workflow.HookPayload.MergeGate {
+					if err := scmnotify.NewService().CreateGitCheckForWorkflowV4(workflow, resp.TaskID, log); err != nil {
+						log.Warnf("Failed to create gitlab commit status for custom workflow %s, taskID: %d the error is: %s", workflow.Name, resp.TaskID, err)
+					}
+				}
 				log.Infof("succeed to create task %v", resp)
 			}
 		}
@@ -0,0 +1,322 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/hashicorp/go-multierror"
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
+	"github.com/koderover/zadig/pkg/tool/codehub"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	webhookEventMaxAttempts = 5
+	// an event is locked by a worker for webhookEventLockDuration, it is picked up by another
+	// worker if it is still not finished, e.g. the instance is restarted during processing.
+	webhookEventLockDuration = 10 * time.Minute
+	webhookEventRetention    = 7 * 24 * time.Hour
+	webhookRetryBaseDelay    = 10 * time.Second
+	webhookRetryMaxDelay     = 10 * time.Minute
+	webhookPollInterval      = time.Second
+)
+
+// headers carrying the webhook secrets, they are hidden when the events are inspected.
+var sensitiveWebhookHeaders = []string{"X-Gitlab-Token", "X-Gitee-Token", "X-Codehub-Token", "Authorization"}
+
+// webhookEventNotify wakes up a worker of this instance when an event is queued, the workers
+// of the other instances pick it up in the next poll.
+var webhookEventNotify = make(chan struct{}, 1)
+
+type webhookProcessor struct {
+	name    string
+	process func(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error
+}
+
+// webhookProcessors returns the processors of the webhook in the order they run.
+func webhookProcessors(source string) []webhookProcessor {
+	switch source {
+	case setting.SourceFromGithub:
+		return []webhookProcessor{
+			{name: "git_mirror", process: syncGitMirrors},
+			// trigger classic pipeline
+			{name: "pipeline", process: func(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+				_, err := ProcessGithubHook(payload, req, requestID, log)
+				return err
+			}},
+			{name: "workflow", process: ProcessGithubWebHook},
+			{name: "testing", process: ProcessGithubWebHookForTest},
+			{name: "scanning", process: ProcessGithubWebhookForScanning},
+			{name: "workflow_v4", process: ProcessGithubWebHookForWorkflowV4},
+		}
+	case setting.SourceFromGitlab:
+		return []webhookProcessor{{name: "git_mirror", process: syncGitMirrors}, {name: "gitlab", process: ProcessGitlabHook}}
+	case setting.SourceFromCodeHub:
+		return []webhookProcessor{{name: "codehub", process: ProcessCodehubHook}}
+	case setting.SourceFromGitee:
+		return []webhookProcessor{{name: "git_mirror", process: syncGitMirrors}, {name: "gitee", process: ProcessGiteeHook}}
+	case setting.SourceFromBitbucketServer:
+		return []webhookProcessor{{name: "git_mirror", process: syncGitMirrors}, {name: "bitbucket_server", process: ProcessBitbucketServerHook}}
+	case setting.SourceFromAzureDevOps:
+		return []webhookProcessor{{name: "azure_devops", process: ProcessAzureDevOpsHook}}
+	default:
+		return []webhookProcessor{{name: "gerrit", process: ProcessGerritHook}}
+	}
+}
+
+func detectWebhookSource(req *http.Request) (string, string) {
+	if event := github.WebHookType(req); event != "" {
+		return setting.SourceFromGithub, event
+	}
+	if event := gitlab.HookEventType(req); event != "" {
+		return setting.SourceFromGitlab, string(event)
+	}
+	if event := codehub.HookEventType(req); event != "" {
+		return setting.SourceFromCodeHub, string(event)
+	}
+	if event := gitee.HookEventType(req); event != "" {
+		return setting.SourceFromGitee, string(event)
+	}
+	if event := bitbucketserver.HookEventType(req); event != "" {
+		return setting.SourceFromBitbucketServer, string(event)
+	}
+	if event := azuredevops.HookEventType(req); event != "" {
+		return setting.SourceFromAzureDevOps, string(event)
+	}
+	return setting.SourceFromGerrit, ""
+}
+
+// EnqueueWebhook persists the webhook so that it is processed asynchronously by the workers,
+// the code host gets the response without waiting for the triggers.
+func EnqueueWebhook(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	source, eventType := detectWebhookSource(req)
+	event := &commonmodels.WebhookEvent{
+		RequestID:  requestID,
+		Source:     source,
+		Event:      eventType,
+		RequestURI: req.RequestURI,
+		Header:     req.Header.Clone(),
+		Payload:    string(payload),
+		Processed:  []string{},
+	}
+	if err := commonrepo.NewWebhookEventColl().Create(event); err != nil {
+		log.Errorf("failed to enqueue %s webhook, err: %s", source, err)
+		return e.ErrEnqueueWebhookEvent.AddErr(err)
+	}
+
+	select {
+	case webhookEventNotify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// StartWebhookWorkers starts the workers processing the queued webhooks and blocks until the
+// context is done.
+func StartWebhookWorkers(ctx context.Context, workers int) {
+	logger := log.SugaredLogger()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWebhookWorker(ctx, logger)
+		}()
+	}
+	wg.Wait()
+}
+
+func runWebhookWorker(ctx context.Context, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// drain the due events before waiting for the next poll
+		for ctx.Err() == nil && processNextWebhookEvent(logger) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-webhookEventNotify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNextWebhookEvent processes one queued event, false is returned if there is nothing
+// to process.
+func processNextWebhookEvent(logger *zap.SugaredLogger) bool {
+	coll := commonrepo.NewWebhookEventColl()
+	event, err := coll.Claim(webhookEventLockDuration)
+	if err != nil {
+		logger.Errorf("failed to claim webhook event, err: %s", err)
+		return false
+	}
+	if event == nil {
+		return false
+	}
+
+	logger = logger.With("reqID", event.RequestID)
+	err = handleWebhookEvent(event, logger)
+	if err == nil {
+		if err := coll.MarkSucceeded(event.ID, webhookEventRetention); err != nil {
+			logger.Errorf("failed to mark webhook event %s as succeeded, err: %s", event.ID.Hex(), err)
+		}
+		return true
+	}
+
+	dead := event.Attempts >= webhookEventMaxAttempts
+	if dead {
+		logger.Errorf("webhook event %s failed after %d attempts, err: %s", event.ID.Hex(), event.Attempts, err)
+	} else {
+		logger.Warnf("webhook event %s failed in attempt %d, err: %s", event.ID.Hex(), event.Attempts, err)
+	}
+	nextAttemptTime := time.Now().Add(webhookRetryDelay(event.Attempts)).Unix()
+	if err := coll.MarkFailed(event.ID, err.Error(), nextAttemptTime, dead); err != nil {
+		logger.Errorf("failed to mark webhook event %s as failed, err: %s", event.ID.Hex(), err)
+	}
+	return true
+}
+
+// handleWebhookEvent runs the processors of the event which have not finished in the previous
+// attempts.
+func handleWebhookEvent(event *commonmodels.WebhookEvent, logger *zap.SugaredLogger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during processing: %v", r)
+		}
+	}()
+
+	payload := []byte(event.Payload)
+	processed := sets.NewString(event.Processed...)
+	coll := commonrepo.NewWebhookEventColl()
+	errs := &multierror.Error{}
+	for _, processor := range webhookProcessors(event.Source) {
+		if processed.Has(processor.name) {
+			continue
+		}
+		req, err := http.NewRequest(http.MethodPost, "/api/workflow/webhook", bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header = event.Header
+		req.RequestURI = event.RequestURI
+
+		if err := processor.process(payload, req, event.RequestID, logger); err != nil {
+			logger.Errorf("error happens to process %s webhook by %s, err: %s", event.Source, processor.name, err)
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		if err := coll.MarkProcessed(event.ID, processor.name); err != nil {
+			logger.Warnf("failed to record processor %s of webhook event %s, err: %s", processor.name, event.ID.Hex(), err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// webhookRetryDelay is the exponential backoff after the given attempts.
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMaxDelay {
+			return webhookRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+type ListWebhookEventsResp struct {
+	Events []*commonmodels.WebhookEvent `json:"events"`
+	Total  int64                        `json:"total"`
+}
+
+func ListWebhookEvents(status, source string, pageNum, pageSize int64, logger *zap.SugaredLogger) (*ListWebhookEventsResp, error) {
+	events, total, err := commonrepo.NewWebhookEventColl().List(&commonrepo.ListWebhookEventOption{
+		Status:   status,
+		Source:   source,
+		PageNum:  pageNum,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		logger.Errorf("failed to list webhook events, err: %s", err)
+		return nil, e.ErrListWebhookEvents.AddErr(err)
+	}
+	for _, event := range events {
+		redactWebhookEvent(event)
+	}
+	return &ListWebhookEventsResp{Events: events, Total: total}, nil
+}
+
+func GetWebhookEvent(id string, logger *zap.SugaredLogger) (*commonmodels.WebhookEvent, error) {
+	event, err := commonrepo.NewWebhookEventColl().Find(id)
+	if err != nil {
+		logger.Errorf("failed to find webhook event %s, err: %s", id, err)
+		return nil, e.ErrGetWebhookEvent.AddErr(err)
+	}
+	redactWebhookEvent(event)
+	return event, nil
+}
+
+// RetryWebhookEvent puts a dead event back to the queue, the processors succeeded before are
+// not run again.
+func RetryWebhookEvent(id string, logger *zap.SugaredLogger) error {
+	if err := commonrepo.NewWebhookEventColl().Retry(id); err != nil {
+		logger.Errorf("failed to retry webhook event %s, err: %s", id, err)
+		return e.ErrRetryWebhookEvent.AddDesc("only dead events can be retried").AddErr(err)
+	}
+	select {
+	case webhookEventNotify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func DeleteWebhookEvent(id string, logger *zap.SugaredLogger) error {
+	if err := commonrepo.NewWebhookEventColl().Delete(id); err != nil {
+		logger.Errorf("failed to delete webhook event %s, err: %s", id, err)
+		return e.ErrDeleteWebhookEvent.AddErr(err)
+	}
+	return nil
+}
+
+func redactWebhookEvent(event *commonmodels.WebhookEvent) {
+	header := http.Header(event.Header)
+	for _, key := range sensitiveWebhookHeaders {
+		if header.Get(key) != "" {
+			header.Set(key, "******")
+		}
+	}
+}
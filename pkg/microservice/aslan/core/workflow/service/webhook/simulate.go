@@ -0,0 +1,377 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+type SimulateWebhookArgs struct {
+	// EventID is a received webhook event, it is simulated instead of the payload if set.
+	EventID string `json:"event_id"`
+	// Source is the code host of the payload: github, gitlab, gitee or gerrit.
+	Source string `json:"source"`
+	// Event is the event type the code host sends in the header, e.g. push for github or
+	// Push Hook for gitlab.
+	Event   string `json:"event"`
+	Payload string `json:"payload"`
+	// ChangedFiles are matched against the path rules of pull requests instead of the files
+	// fetched from the code host.
+	ChangedFiles []string `json:"changed_files"`
+	ProjectName  string   `json:"project_name"`
+	WorkflowName string   `json:"workflow_name"`
+}
+
+type SimulateWebhookResp struct {
+	Source string `json:"source"`
+	Event  string `json:"event"`
+	// Ignored is the reason why the event does not trigger any workflow, e.g. the action of
+	// the pull request is not handled.
+	Ignored  string              `json:"ignored,omitempty"`
+	Triggers []*SimulatedTrigger `json:"triggers"`
+}
+
+// SimulatedTrigger is a trigger of the repository of the event, or any trigger of the workflow
+// if a workflow is specified.
+type SimulatedTrigger struct {
+	WorkflowName string            `json:"workflow_name"`
+	ProjectName  string            `json:"project_name"`
+	TriggerName  string            `json:"trigger_name"`
+	Enabled      bool              `json:"enabled"`
+	Matched      bool              `json:"matched"`
+	Reason       string            `json:"reason,omitempty"`
+	Repo         *types.Repository `json:"repo,omitempty"`
+	// Params and Stages are the arguments of the task which would be created.
+	Params []*commonmodels.Param         `json:"params,omitempty"`
+	Stages []*commonmodels.WorkflowStage `json:"stages,omitempty"`
+}
+
+// simulatedEvent is the parsed payload, the fields besides the matcher are used to explain
+// why a trigger does not match.
+type simulatedEvent struct {
+	ignored     string
+	repoMatched func(hookRepo *commonmodels.MainHookRepo) bool
+	repoName    string
+	branch      string
+	hookEvent   config.HookEventType
+	newMatcher  func(workflow *commonmodels.WorkflowV4, item *commonmodels.WorkflowV4Hook) gitEventMatcherForWorkflowV4
+}
+
+// SimulateWebhook finds the custom workflow triggers matching a webhook payload and resolves
+// the task arguments without creating any task, so that the trigger rules can be checked
+// without pushing commits.
+func SimulateWebhook(args *SimulateWebhookArgs, logger *zap.SugaredLogger) (*SimulateWebhookResp, error) {
+	source, eventType, payload := args.Source, args.Event, []byte(args.Payload)
+	if args.EventID != "" {
+		event, err := commonrepo.NewWebhookEventColl().Find(args.EventID)
+		if err != nil {
+			logger.Errorf("failed to find webhook event %s, err: %s", args.EventID, err)
+			return nil, e.ErrGetWebhookEvent.AddErr(err)
+		}
+		source, eventType, payload = event.Source, event.Event, []byte(event.Payload)
+	}
+	if len(payload) == 0 {
+		return nil, e.ErrInvalidParam.AddDesc("payload can not be empty")
+	}
+
+	ev, err := parseSimulatedEvent(source, eventType, payload, args.ChangedFiles, logger)
+	if err != nil {
+		return nil, e.ErrSimulateWebhook.AddErr(err)
+	}
+	resp := &SimulateWebhookResp{Source: source, Event: eventType, Triggers: []*SimulatedTrigger{}}
+	if ev.ignored != "" {
+		resp.Ignored = ev.ignored
+		return resp, nil
+	}
+
+	var workflows []*commonmodels.WorkflowV4
+	if args.WorkflowName != "" {
+		workflow, err := commonrepo.NewWorkflowV4Coll().Find(args.WorkflowName)
+		if err != nil {
+			logger.Errorf("failed to find workflow %s, err: %s", args.WorkflowName, err)
+			return nil, e.ErrFindWorkflow.AddErr(err)
+		}
+		workflows = append(workflows, workflow)
+	} else {
+		workflows, _, err = commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: args.ProjectName}, 0, 0)
+		if err != nil {
+			logger.Errorf("failed to list workflows, err: %s", err)
+			return nil, e.ErrListWorkflow.AddErr(err)
+		}
+	}
+
+	for _, workflow := range workflows {
+		for _, item := range workflow.HookCtls {
+			if item.MainRepo == nil {
+				continue
+			}
+			// the triggers of the other repositories are only listed for the given workflow.
+			if args.WorkflowName == "" && !ev.repoMatched(item.MainRepo) {
+				continue
+			}
+			resp.Triggers = append(resp.Triggers, simulateTrigger(ev, workflow, item))
+		}
+	}
+	return resp, nil
+}
+
+func simulateTrigger(ev *simulatedEvent, workflow *commonmodels.WorkflowV4, item *commonmodels.WorkflowV4Hook) (trigger *SimulatedTrigger) {
+	trigger = &SimulatedTrigger{
+		WorkflowName: workflow.Name,
+		ProjectName:  workflow.Project,
+		TriggerName:  item.Name,
+		Enabled:      item.Enabled,
+	}
+	// the matchers dereference the fields of the payload, a malformed payload must not panic.
+	defer func() {
+		if r := recover(); r != nil {
+			trigger.Matched = false
+			trigger.Reason = fmt.Sprintf("failed to match the payload: %v", r)
+		}
+	}()
+
+	// the matchers update the rule with the event, the stored one is kept for the explanation.
+	rule := *item.MainRepo
+	hookRepo := *item.MainRepo
+	matcher := ev.newMatcher(workflow, item)
+	if matcher == nil {
+		trigger.Reason = "the event is not supported by custom workflows"
+		return trigger
+	}
+	matched, err := matcher.Match(&hookRepo)
+	if err != nil {
+		trigger.Reason = fmt.Sprintf("failed to match the event: %s", err)
+		return trigger
+	}
+	if !matched {
+		trigger.Reason = ev.explain(&rule)
+		return trigger
+	}
+
+	trigger.Matched = item.Enabled
+	if !item.Enabled {
+		trigger.Reason = "the trigger matches but is disabled"
+	}
+	trigger.Repo = matcher.GetHookRepo(&hookRepo)
+
+	args := &commonmodels.WorkflowV4{}
+	if err := commonmodels.IToi(workflow, args); err != nil {
+		trigger.Reason = fmt.Sprintf("failed to resolve the arguments: %s", err)
+		return trigger
+	}
+	if err := job.MergeArgs(args, item.WorkflowArg); err != nil {
+		trigger.Reason = fmt.Sprintf("failed to merge the arguments of the trigger: %s", err)
+		return trigger
+	}
+	if err := job.MergeWebhookRepo(args, trigger.Repo); err != nil {
+		trigger.Reason = fmt.Sprintf("failed to merge the repository of the event: %s", err)
+		return trigger
+	}
+	trigger.Params = args.Params
+	trigger.Stages = args.Stages
+	return trigger
+}
+
+// explain checks the rule in the order the matchers do, the path rules are the last ones.
+func (ev *simulatedEvent) explain(rule *commonmodels.MainHookRepo) string {
+	if !ev.repoMatched(rule) {
+		return fmt.Sprintf("the repository %s does not match", ev.repoName)
+	}
+	if ev.hookEvent != "" && !EventConfigured(rule, ev.hookEvent) {
+		return fmt.Sprintf("event %s is not enabled in the trigger", ev.hookEvent)
+	}
+	if ev.branch != "" {
+		matched := rule.Branch == ev.branch
+		if rule.IsRegular {
+			matched, _ = regexp.MatchString(rule.Branch, ev.branch)
+		}
+		if !matched {
+			return fmt.Sprintf("branch %s does not match %s", ev.branch, rule.Branch)
+		}
+	}
+	return "none of the changed files matches the path rules"
+}
+
+func parseSimulatedEvent(source, eventType string, payload []byte, changedFiles []string, log *zap.SugaredLogger) (ev *simulatedEvent, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid %s payload: %v", source, r)
+		}
+	}()
+
+	switch source {
+	case setting.SourceFromGithub:
+		return parseGithubSimulatedEvent(eventType, payload, changedFiles, log)
+	case setting.SourceFromGitlab:
+		return parseGitlabSimulatedEvent(eventType, payload, changedFiles, log)
+	case setting.SourceFromGitee:
+		return parseGiteeSimulatedEvent(eventType, payload, changedFiles, log)
+	case setting.SourceFromGerrit:
+		return parseGerritSimulatedEvent(payload, log)
+	default:
+		return nil, fmt.Errorf("unsupported source %s", source)
+	}
+}
+
+func namespaceMatcher(fullName string) func(hookRepo *commonmodels.MainHookRepo) bool {
+	return func(hookRepo *commonmodels.MainHookRepo) bool {
+		return checkRepoNamespaceMatch(hookRepo, fullName)
+	}
+}
+
+func parseGithubSimulatedEvent(eventType string, payload []byte, changedFiles []string, log *zap.SugaredLogger) (*simulatedEvent, error) {
+	if eventType == "integration_installation" || eventType == "installation" || eventType == "ping" {
+		return &simulatedEvent{ignored: fmt.Sprintf("%s events are ignored", eventType)}, nil
+	}
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		return nil, err
+	}
+	diffFunc := func(pullRequestEvent *github.PullRequestEvent, codehostID int) ([]string, error) {
+		if changedFiles != nil {
+			return changedFiles, nil
+		}
+		return findChangedFilesOfPullRequest(pullRequestEvent, codehostID)
+	}
+	ev := &simulatedEvent{}
+	switch et := event.(type) {
+	case *github.PushEvent:
+		ev.repoName, ev.branch, ev.hookEvent = et.GetRepo().GetFullName(), getBranchFromRef(et.GetRef()), config.HookEventPush
+	case *github.PullRequestEvent:
+		if et.GetAction() != "opened" && et.GetAction() != "synchronize" {
+			return &simulatedEvent{ignored: fmt.Sprintf("action %s of the pull request is ignored", et.GetAction())}, nil
+		}
+		base := et.GetPullRequest().GetBase()
+		ev.repoName, ev.branch, ev.hookEvent = base.GetRepo().GetFullName(), base.GetRef(), config.HookEventPr
+	case *github.CreateEvent:
+		ev.repoName, ev.branch, ev.hookEvent = et.GetRepo().GetFullName(), et.GetRepo().GetDefaultBranch(), config.HookEventTag
+	default:
+		return &simulatedEvent{ignored: fmt.Sprintf("%s events do not trigger custom workflows", eventType)}, nil
+	}
+	ev.repoMatched = namespaceMatcher(ev.repoName)
+	ev.newMatcher = func(workflow *commonmodels.WorkflowV4, item *commonmodels.WorkflowV4Hook) gitEventMatcherForWorkflowV4 {
+		return createGithubEventMatcherForWorkflowV4(event, diffFunc, workflow, log)
+	}
+	return ev, nil
+}
+
+func parseGitlabSimulatedEvent(eventType string, payload []byte, changedFiles []string, log *zap.SugaredLogger) (*simulatedEvent, error) {
+	event, err := gitlab.ParseHook(gitlab.EventType(eventType), payload)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := event.(*gitlab.PushSystemEvent); ok {
+		if event, err = gitlab.ParseWebhook(gitlab.EventTypePush, payload); err != nil {
+			return nil, err
+		}
+	}
+	diffFunc := func(mergeEvent *gitlab.MergeEvent, codehostID int) ([]string, error) {
+		if changedFiles != nil {
+			return changedFiles, nil
+		}
+		return findChangedFilesOfMergeRequest(mergeEvent, codehostID)
+	}
+	ev := &simulatedEvent{}
+	switch et := event.(type) {
+	case *gitlab.PushEvent:
+		ev.repoName, ev.branch, ev.hookEvent = et.Project.PathWithNamespace, getBranchFromRef(et.Ref), config.HookEventPush
+	case *gitlab.MergeEvent:
+		ev.repoName, ev.branch, ev.hookEvent = et.ObjectAttributes.Target.PathWithNamespace, et.ObjectAttributes.TargetBranch, config.HookEventPr
+	case *gitlab.TagEvent:
+		ev.repoName, ev.branch, ev.hookEvent = et.Project.PathWithNamespace, et.Project.DefaultBranch, config.HookEventTag
+	default:
+		return &simulatedEvent{ignored: fmt.Sprintf("%s events do not trigger custom workflows", eventType)}, nil
+	}
+	ev.repoMatched = namespaceMatcher(ev.repoName)
+	ev.newMatcher = func(workflow *commonmodels.WorkflowV4, item *commonmodels.WorkflowV4Hook) gitEventMatcherForWorkflowV4 {
+		return createGitlabEventMatcherForWorkflowV4(event, diffFunc, workflow, log)
+	}
+	return ev, nil
+}
+
+func parseGiteeSimulatedEvent(eventType string, payload []byte, changedFiles []string, log *zap.SugaredLogger) (*simulatedEvent, error) {
+	event, err := gitee.ParseHook(gitee.EventType(eventType), payload)
+	if err != nil {
+		return nil, err
+	}
+	diffFunc := func(pullRequestEvent *gitee.PullRequestEvent, codehostID int) ([]string, error) {
+		if changedFiles != nil {
+			return changedFiles, nil
+		}
+		return findChangedFilesOfPullRequestEvent(pullRequestEvent, codehostID)
+	}
+	ev := &simulatedEvent{}
+	switch et := event.(type) {
+	case *gitee.PushEvent:
+		ev.repoName, ev.branch, ev.hookEvent = et.Repository.FullName, getBranchFromRef(et.Ref), config.HookEventPush
+	case *gitee.PullRequestEvent:
+		if (et.Action != "open" && et.Action != "update") || et.ActionDesc == "target_branch_changed" {
+			return &simulatedEvent{ignored: fmt.Sprintf("action %s of the pull request is ignored", et.Action)}, nil
+		}
+		ev.repoName, ev.branch, ev.hookEvent = et.PullRequest.Base.Repo.FullName, et.PullRequest.Base.Ref, config.HookEventPr
+	case *gitee.TagPushEvent:
+		ev.repoName, ev.branch, ev.hookEvent = et.Repository.FullName, et.Repository.DefaultBranch, config.HookEventTag
+	default:
+		return &simulatedEvent{ignored: fmt.Sprintf("%s events do not trigger custom workflows", eventType)}, nil
+	}
+	// the gitee matchers compare the owner and the name of the repository.
+	ev.repoMatched = func(hookRepo *commonmodels.MainHookRepo) bool {
+		return hookRepo.RepoOwner+"/"+hookRepo.RepoName == ev.repoName
+	}
+	ev.newMatcher = func(workflow *commonmodels.WorkflowV4, item *commonmodels.WorkflowV4Hook) gitEventMatcherForWorkflowV4 {
+		return createGiteeEventMatcherForWorkflowV4(event, diffFunc, workflow, log)
+	}
+	return ev, nil
+}
+
+func parseGerritSimulatedEvent(payload []byte, log *zap.SugaredLogger) (*simulatedEvent, error) {
+	event := &struct {
+		gerritTypeEvent
+		Project ProjectInfo `json:"project"`
+	}{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+	if event.Type != changeMergedEventType && event.Type != patchsetCreatedEventType {
+		return &simulatedEvent{ignored: fmt.Sprintf("%s events do not trigger custom workflows", event.Type)}, nil
+	}
+	return &simulatedEvent{
+		repoName:  event.Project.Name,
+		hookEvent: config.HookEventType(event.Type),
+		repoMatched: func(hookRepo *commonmodels.MainHookRepo) bool {
+			return hookRepo.RepoName == event.Project.Name
+		},
+		newMatcher: func(workflow *commonmodels.WorkflowV4, item *commonmodels.WorkflowV4Hook) gitEventMatcherForWorkflowV4 {
+			return createGerritEventMatcherForWorkflowV4(&event.gerritTypeEvent, payload, item, workflow, log)
+		},
+	}, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/google/go-github/v35/github"
+	"github.com/xanzy/go-gitlab"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+)
+
+// recordTriggerMetadata records the author, message, labels and changed files of the event in the hook
+// payload, they are rendered as the workflow.trigger.* variables of the task.
+func recordTriggerMetadata(workflow *commonmodels.WorkflowV4, hookRepo *commonmodels.MainHookRepo, event interface{}) {
+	// the payload may be shared by the workflows triggered by the same event, copy it before writing.
+	payload := &commonmodels.HookPayload{}
+	if workflow.HookPayload != nil {
+		*payload = *workflow.HookPayload
+	}
+	payload.CommitAuthor = hookRepo.Committer
+	payload.CommitMessage, payload.Labels = eventMessageAndLabels(event)
+	payload.ChangedFiles = nil
+	if len(hookRepo.ChangedFiles) > 0 {
+		payload.ChangedFiles = sets.NewString(hookRepo.ChangedFiles...).Delete("").List()
+	}
+	workflow.HookPayload = payload
+}
+
+// eventMessageAndLabels returns the message of the head commit for push events, and the title and
+// labels of the pull request for pull request events.
+func eventMessageAndLabels(event interface{}) (string, []string) {
+	var labels []string
+	switch ev := event.(type) {
+	case *github.PushEvent:
+		if ev.HeadCommit != nil {
+			return ev.HeadCommit.GetMessage(), nil
+		}
+	case *github.PullRequestEvent:
+		for _, label := range ev.PullRequest.Labels {
+			labels = append(labels, label.GetName())
+		}
+		return ev.PullRequest.GetTitle(), labels
+	case *gitlab.PushEvent:
+		for _, commit := range ev.Commits {
+			if commit.ID == ev.After {
+				return commit.Message, nil
+			}
+		}
+	case *gitlab.MergeEvent:
+		for _, label := range ev.Labels {
+			labels = append(labels, label.Name)
+		}
+		return ev.ObjectAttributes.Title, labels
+	case *gitee.PushEvent:
+		for _, commit := range ev.Commits {
+			if commit.ID == ev.After {
+				return commit.Message, nil
+			}
+		}
+	case *gitee.PullRequestEvent:
+		if ev.PullRequest != nil {
+			return ev.PullRequest.Title, nil
+		}
+	case *bitbucketserver.PullRequestEvent:
+		if ev.PullRequest != nil {
+			return ev.PullRequest.Title, nil
+		}
+	case *azuredevops.PushEvent:
+		if ev.Resource == nil {
+			break
+		}
+		for _, commit := range ev.Resource.Commits {
+			for _, update := range ev.Resource.RefUpdates {
+				if commit.CommitID == update.NewObjectID {
+					return commit.Comment, nil
+				}
+			}
+		}
+	case *azuredevops.PullRequestEvent:
+		if ev.Resource != nil {
+			return ev.Resource.Title, nil
+		}
+	}
+	return "", nil
+}
@@ -278,7 +278,7 @@ func getCodehubClientByAddress(address string) (*codehub.Client, error) {
 		log.Error(err)
 		return nil, e.ErrCodehostListProjects.AddDesc("git client is nil")
 	}
-	client := codehub.NewClient(codehost.AccessKey, codehost.SecretKey, codehost.Region, config.ProxyHTTPSAddr(), codehost.EnableProxy)
+	client := codehub.NewClient(codehost.AccessKey, codehost.SecretKey, codehost.Region, codehost.ProxyAddr(config.ProxyHTTPSAddr()), codehost.UseProxy())
 
 	return client, nil
 }
@@ -289,7 +289,7 @@ func getGitlabClientByCodehostId(codehostId int) (*gitlabtool.Client, error) {
 		log.Error(err)
 		return nil, e.ErrCodehostListProjects.AddDesc(fmt.Sprintf("failed to get codehost:%d, err: %s", codehost, err))
 	}
-	client, err := gitlabtool.NewClient(codehost.ID, codehost.Address, codehost.AccessToken, config.ProxyHTTPSAddr(), codehost.EnableProxy)
+	client, err := gitlabtool.NewClient(codehost.ID, codehost.Address, codehost.AccessToken, codehost.ProxyAddr(config.ProxyHTTPSAddr()), codehost.UseProxy())
 	if err != nil {
 		log.Error(err)
 		return nil, e.ErrCodehostListProjects.AddDesc(err.Error())
@@ -308,7 +308,7 @@ func getGitlabClientByAddress(address string) (*gitlabtool.Client, error) {
 		log.Error(err)
 		return nil, e.ErrCodehostListProjects.AddDesc("git client is nil")
 	}
-	client, err := gitlabtool.NewClient(codehost.ID, codehost.Address, codehost.AccessToken, config.ProxyHTTPSAddr(), codehost.EnableProxy)
+	client, err := gitlabtool.NewClient(codehost.ID, codehost.Address, codehost.AccessToken, codehost.ProxyAddr(config.ProxyHTTPSAddr()), codehost.UseProxy())
 	if err != nil {
 		log.Error(err)
 		return nil, e.ErrCodehostListProjects.AddDesc(err.Error())
@@ -425,7 +425,7 @@ func syncContentFromGithub(args *commonmodels.Service, log *zap.SugaredLogger) e
 		return err
 	}
 
-	gc := githubtool.NewClient(&githubtool.Config{AccessToken: ch.AccessToken, Proxy: config.ProxyHTTPSAddr()})
+	gc := githubtool.NewClient(&githubtool.Config{AccessToken: ch.AccessToken, Proxy: ch.ProxyAddr(config.ProxyHTTPSAddr())})
 	fileContent, directoryContent, err := gc.GetContents(context.TODO(), owner, repo, path, &github.RepositoryContentGetOptions{Ref: branch})
 	if err != nil {
 		return err
@@ -494,12 +494,18 @@ func setCurrentContainerImages(args *commonmodels.Service) error {
 			}
 
 			switch u.GetKind() {
-			case setting.Deployment, setting.StatefulSet, setting.Job:
+			case setting.Deployment, setting.StatefulSet, setting.DaemonSet, setting.Job:
 				cs, err := getContainers(u)
 				if err != nil {
 					return fmt.Errorf("GetContainers error: %v", err)
 				}
 				srvContainers = append(srvContainers, cs...)
+			case setting.CronJob:
+				cs, err := getCronJobContainers(u)
+				if err != nil {
+					return fmt.Errorf("GetContainers error: %v", err)
+				}
+				srvContainers = append(srvContainers, cs...)
 			}
 		}
 	}
@@ -511,8 +517,16 @@ func setCurrentContainerImages(args *commonmodels.Service) error {
 
 // 从kube yaml中查找所有containers 镜像和名称
 func getContainers(u *unstructured.Unstructured) ([]*commonmodels.Container, error) {
+	return getContainersInPath(u, "spec", "template", "spec", "containers")
+}
+
+func getCronJobContainers(u *unstructured.Unstructured) ([]*commonmodels.Container, error) {
+	return getContainersInPath(u, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+}
+
+func getContainersInPath(u *unstructured.Unstructured, fields ...string) ([]*commonmodels.Container, error) {
 	var containers []*commonmodels.Container
-	cs, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+	cs, _, _ := unstructured.NestedSlice(u.Object, fields...)
 	for _, c := range cs {
 		val, ok := c.(map[string]interface{})
 		if !ok {
@@ -598,16 +612,42 @@ func ConvertScanningHookToMainHookRepo(hook *types.ScanningHook) *commonmodels.M
 	}
 }
 
+// EventConfigured reports whether the hook repo listens to the event, hooks of the repos excluded
+// by the repo policy of the codehost are never triggered.
 func EventConfigured(m *commonmodels.MainHookRepo, event config.HookEventType) bool {
 	for _, ev := range m.Events {
 		if ev == event {
-			return true
+			return HookRepoAllowed(m)
 		}
 	}
 
 	return false
 }
 
+// CodehostAvailableToProject reports whether the hooks of the project can be triggered by the events of the codehost,
+// a codehost bound to other projects is skipped so that its token is never used by the project.
+func CodehostAvailableToProject(codehostID int, projectName string) bool {
+	ch, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		log.Warnf("failed to get codehost %d: %s", codehostID, err)
+		return false
+	}
+	return ch.AvailableToProject(projectName)
+}
+
+func HookRepoAllowed(m *commonmodels.MainHookRepo) bool {
+	ch, err := systemconfig.New().GetCodeHost(m.CodehostID)
+	if err != nil {
+		log.Warnf("failed to get codehost %d of hook repo %s: %s", m.CodehostID, m.RepoName, err)
+		return false
+	}
+	if !ch.RepoPolicy.RepoAllowed(m.GetRepoNamespace(), m.RepoName) {
+		log.Warnf("hook repo %s/%s is not allowed by the policy of codehost %d", m.GetRepoNamespace(), m.RepoName, m.CodehostID)
+		return false
+	}
+	return true
+}
+
 func ServicesMatchChangesFiles(mf *MatchFoldersElem, files []string) []BuildServices {
 	resMactchSvr := []BuildServices{}
 	var wg sync.WaitGroup
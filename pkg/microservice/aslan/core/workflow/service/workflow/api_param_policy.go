@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func validateAPIParamPolicy(policy *commonmodels.APIParamPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	names := sets.NewString()
+	for _, rule := range policy.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("name of the api param rule is required")
+		}
+		if names.Has(rule.Name) {
+			return fmt.Errorf("duplicated api param rule: %s", rule.Name)
+		}
+		names.Insert(rule.Name)
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern of api param rule %s: %s", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// CheckAPIParamPolicy rejects the run triggered by API if it overrides a parameter which is not
+// allowed by the policy of the workflow, or with a value not matching the pattern of the rule.
+// The policy is always read from the saved workflow, the one in the args is ignored.
+func CheckAPIParamPolicy(args *commonmodels.WorkflowV4, log *zap.SugaredLogger) error {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(args.Name)
+	if err != nil {
+		log.Errorf("cannot find workflow %s, the error is: %v", args.Name, err)
+		return e.ErrFindWorkflow.AddDesc(err.Error())
+	}
+	policy := workflow.APIParamPolicy
+	if policy == nil || !policy.Enabled {
+		return nil
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if err := jobctl.SetPreset(job, workflow); err != nil {
+				log.Errorf("cannot get workflow %s preset, the error is: %v", workflow.Name, err)
+				return e.ErrFindWorkflow.AddDesc(err.Error())
+			}
+		}
+	}
+
+	defaults, err := workflowV4ParamValues(workflow)
+	if err != nil {
+		return e.ErrAPIParamPolicyDenied.AddErr(err)
+	}
+	values, err := workflowV4ParamValues(args)
+	if err != nil {
+		return e.ErrAPIParamPolicyDenied.AddErr(err)
+	}
+	rules := make(map[string]*commonmodels.APIParamRule, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		rules[rule.Name] = rule
+	}
+
+	reasons := []string{}
+	for _, name := range sets.StringKeySet(values).List() {
+		for _, value := range values[name].List() {
+			if defaults[name].Has(value) {
+				continue
+			}
+			rule, ok := rules[name]
+			if !ok {
+				reasons = append(reasons, fmt.Sprintf("parameter %s can not be overridden", name))
+				continue
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+			// the rules are validated when the workflow is saved
+			if matched, _ := regexp.MatchString("^(?:"+rule.Pattern+")$", value); !matched {
+				reasons = append(reasons, fmt.Sprintf("value %q of parameter %s does not match %s", value, name, rule.Pattern))
+			}
+		}
+	}
+	if len(reasons) > 0 {
+		return e.ErrAPIParamPolicyDenied.AddDesc(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// workflowV4ParamValues returns the values of the workflow params and of the variables of the build jobs,
+// a variable of a build job is named job_name.KEY and may have different values for the services.
+func workflowV4ParamValues(workflow *commonmodels.WorkflowV4) (map[string]sets.String, error) {
+	resp := map[string]sets.String{}
+	add := func(name, value string) {
+		if _, ok := resp[name]; !ok {
+			resp[name] = sets.NewString()
+		}
+		resp[name].Insert(strings.ReplaceAll(value, setting.FixedValueMark, ""))
+	}
+	for _, param := range workflow.Params {
+		add(param.Name, param.Value)
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.JobType != config.JobZadigBuild || job.Skipped {
+				continue
+			}
+			spec := &commonmodels.ZadigBuildJobSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return nil, err
+			}
+			for _, build := range spec.ServiceAndBuilds {
+				for _, kv := range build.KeyVals {
+					add(job.Name+"."+kv.Key, kv.Value)
+				}
+			}
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,860 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// ConvertCIConfig translates a GitHub Actions workflow or a GitLab CI config into a draft common workflow.
+// Jobs are grouped into stages by their dependencies, and matrix builds are expanded into parallel jobs.
+func ConvertCIConfig(args *ConvertWorkflowArgs, logger *zap.SugaredLogger) (*ConvertWorkflowResp, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(args.Content), root); err != nil {
+		logger.Errorf("failed to parse ci config, err: %s", err)
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, e.ErrInvalidParam.AddDesc("ci config should be a yaml mapping")
+	}
+
+	c := &ciConfigConverter{}
+	workflow := &commonmodels.WorkflowV4{
+		Name:    args.Name,
+		Project: args.Project,
+	}
+	var err error
+	switch args.Source {
+	case setting.SourceFromGithub:
+		workflow.Description = "converted from GitHub Actions"
+		err = c.convertGithubActions(workflow, root.Content[0])
+	case setting.SourceFromGitlab:
+		workflow.Description = "converted from GitLab CI"
+		err = c.convertGitlabCI(workflow, root.Content[0])
+	default:
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("unsupported ci config source: %s", args.Source))
+	}
+	if err != nil {
+		logger.Errorf("failed to convert %s ci config, err: %s", args.Source, err)
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	out, err := yaml.Marshal(workflow)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	return &ConvertWorkflowResp{Yaml: string(out), Warnings: c.warnings, Hooks: c.hooks}, nil
+}
+
+type ciConfigConverter struct {
+	warnings []string
+	hooks    []*commonmodels.WorkflowV4Hook
+}
+
+func (c *ciConfigConverter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+func (c *ciConfigConverter) addHook(name string, event config.HookEventType, branches, paths []string) {
+	for _, hook := range c.hooks {
+		if hook.Name == name {
+			return
+		}
+	}
+	repo := &commonmodels.MainHookRepo{
+		Events:       []config.HookEventType{event},
+		MatchFolders: paths,
+	}
+	switch {
+	case len(branches) == 1 && !strings.ContainsAny(branches[0], "*?[!"):
+		repo.Branch = branches[0]
+	case len(branches) > 0:
+		repo.IsRegular = true
+		repo.Branch = ciBranchRegex(branches)
+	}
+	c.hooks = append(c.hooks, &commonmodels.WorkflowV4Hook{
+		Name:     name,
+		Enabled:  true,
+		MainRepo: repo,
+	})
+}
+
+// ciJob is the intermediate form shared by all the CI config formats.
+type ciJob struct {
+	name      string
+	scripts   []string
+	envs      map[string]string
+	secrets   []string
+	timeout   int64
+	retry     int64
+	cachePath string
+	matrix    [][]*commonmodels.KeyVal
+}
+
+func (c *ciConfigConverter) toJobs(job *ciJob) []*commonmodels.Job {
+	resp := make([]*commonmodels.Job, 0, len(job.matrix))
+	for _, combination := range job.matrix {
+		name := job.name
+		envs := ciEnvs(job.envs)
+		for _, kv := range combination {
+			name += "-" + kv.Value
+			envs = append(envs, &commonmodels.KeyVal{Key: kv.Key, Value: kv.Value, Type: commonmodels.StringType})
+		}
+		for _, secret := range job.secrets {
+			envs = append(envs, &commonmodels.KeyVal{Key: secret, Type: commonmodels.StringType, IsCredential: true})
+		}
+
+		draft := newDraftFreestyleJob(name, strings.Join(job.scripts, "\n"), envs)
+		properties := draft.Spec.(*commonmodels.FreestyleJobSpec).Properties
+		if job.timeout > 0 {
+			properties.Timeout = job.timeout
+		}
+		properties.Retry = job.retry
+		if job.cachePath != "" {
+			properties.CacheEnable = true
+			properties.CacheDirType = types.UserDefinedCacheDir
+			properties.CacheUserDir = job.cachePath
+		}
+		resp = append(resp, draft)
+	}
+	return resp
+}
+
+type githubActionsWorkflow struct {
+	On   yaml.Node         `yaml:"on"`
+	Env  map[string]string `yaml:"env"`
+	Jobs yaml.Node         `yaml:"jobs"`
+}
+
+type githubActionsJob struct {
+	Name           string                 `yaml:"name"`
+	Needs          ciStringList           `yaml:"needs"`
+	RunsOn         yaml.Node              `yaml:"runs-on"`
+	Container      interface{}            `yaml:"container"`
+	Services       map[string]interface{} `yaml:"services"`
+	If             string                 `yaml:"if"`
+	Env            map[string]string      `yaml:"env"`
+	TimeoutMinutes int64                  `yaml:"timeout-minutes"`
+	Strategy       struct {
+		Matrix yaml.Node `yaml:"matrix"`
+	} `yaml:"strategy"`
+	Steps []*githubActionsStep `yaml:"steps"`
+}
+
+type githubActionsStep struct {
+	Name             string            `yaml:"name"`
+	Uses             string            `yaml:"uses"`
+	Run              string            `yaml:"run"`
+	If               string            `yaml:"if"`
+	WorkingDirectory string            `yaml:"working-directory"`
+	Env              map[string]string `yaml:"env"`
+	With             map[string]string `yaml:"with"`
+}
+
+type githubActionsEventFilter struct {
+	Branches       ciStringList `yaml:"branches"`
+	BranchesIgnore ciStringList `yaml:"branches-ignore"`
+	Tags           ciStringList `yaml:"tags"`
+	Paths          ciStringList `yaml:"paths"`
+	PathsIgnore    ciStringList `yaml:"paths-ignore"`
+}
+
+func (c *ciConfigConverter) convertGithubActions(workflow *commonmodels.WorkflowV4, root *yaml.Node) error {
+	spec := &githubActionsWorkflow{}
+	if err := root.Decode(spec); err != nil {
+		return err
+	}
+	c.convertGithubTriggers(&spec.On)
+
+	ids, nodes := yamlMapping(&spec.Jobs)
+	if len(ids) == 0 {
+		return fmt.Errorf("no job found in github actions workflow")
+	}
+	jobs := make(map[string]*githubActionsJob, len(ids))
+	for _, id := range ids {
+		job := &githubActionsJob{}
+		if err := nodes[id].Decode(job); err != nil {
+			return fmt.Errorf("failed to parse job %s: %s", id, err)
+		}
+		jobs[id] = job
+	}
+	levels, err := ciJobLevels(ids, func(id string) []string { return jobs[id].Needs })
+	if err != nil {
+		return err
+	}
+
+	for i, level := range levels {
+		stage := &commonmodels.WorkflowStage{Name: fmt.Sprintf("stage-%d", i+1)}
+		for _, id := range level {
+			stage.Jobs = append(stage.Jobs, c.toJobs(c.convertGithubJob(id, jobs[id], spec.Env))...)
+		}
+		stage.Parallel = len(stage.Jobs) > 1
+		workflow.Stages = append(workflow.Stages, stage)
+	}
+	return nil
+}
+
+func (c *ciConfigConverter) convertGithubTriggers(node *yaml.Node) {
+	var events []string
+	_, filters := yamlMapping(node)
+	switch node.Kind {
+	case yaml.ScalarNode:
+		events = []string{node.Value}
+	case yaml.SequenceNode:
+		if err := node.Decode(&events); err != nil {
+			c.warn("failed to parse triggers: %s", err)
+		}
+	case yaml.MappingNode:
+		events, _ = yamlMapping(node)
+	}
+
+	for _, event := range events {
+		filter := &githubActionsEventFilter{}
+		if v, ok := filters[event]; ok && v.Kind == yaml.MappingNode {
+			if err := v.Decode(filter); err != nil {
+				c.warn("failed to parse filters of trigger %s: %s", event, err)
+			}
+		}
+		if len(filter.BranchesIgnore) > 0 || len(filter.PathsIgnore) > 0 {
+			c.warn("ignore filters of trigger %s are not converted", event)
+		}
+		switch event {
+		case "push":
+			if len(filter.Branches) > 0 || len(filter.Tags) == 0 {
+				c.addHook("push", config.HookEventPush, filter.Branches, filter.Paths)
+			}
+			if len(filter.Tags) > 0 {
+				c.addHook("tag", config.HookEventTag, nil, filter.Paths)
+			}
+		case "pull_request", "pull_request_target":
+			c.addHook("pull-request", config.HookEventPr, filter.Branches, filter.Paths)
+		case "workflow_dispatch":
+			// workflows can always be run manually
+		case "schedule":
+			c.warn("schedule trigger is not converted, configure a timer for the workflow manually")
+		default:
+			c.warn("trigger %s is not supported", event)
+		}
+	}
+}
+
+var (
+	githubExpressionRegex = regexp.MustCompile(`\$\{\{\s*(.*?)\s*\}\}`)
+	githubContextRegex    = regexp.MustCompile(`^(matrix|env|secrets)\.([A-Za-z_][A-Za-z0-9_-]*)$`)
+)
+
+func (c *ciConfigConverter) convertGithubJob(id string, job *githubActionsJob, env map[string]string) *ciJob {
+	if job.If != "" {
+		c.warn("condition of job %s is not converted: %s", id, job.If)
+	}
+	if job.Container != nil {
+		c.warn("container of job %s is not converted, select the build image manually", id)
+	}
+	if len(job.Services) > 0 {
+		c.warn("services of job %s are not supported", id)
+	}
+	if runner := job.RunsOn.Value; strings.HasPrefix(runner, "windows") || strings.HasPrefix(runner, "macos") {
+		c.warn("runner %s of job %s is not supported, only linux build images are available", runner, id)
+	}
+
+	secrets := sets.NewString()
+	rewrite := func(s string) string {
+		return githubExpressionRegex.ReplaceAllStringFunc(s, func(expr string) string {
+			match := githubContextRegex.FindStringSubmatch(githubExpressionRegex.FindStringSubmatch(expr)[1])
+			if match == nil {
+				c.warn("expression %s in job %s is not converted", expr, id)
+				return expr
+			}
+			if match[1] == "secrets" {
+				secrets.Insert(ciEnvName(match[2]))
+			}
+			return "${" + ciEnvName(match[2]) + "}"
+		})
+	}
+
+	resp := &ciJob{
+		name:    id,
+		envs:    map[string]string{},
+		timeout: job.TimeoutMinutes,
+		matrix:  c.convertGithubMatrix(id, &job.Strategy.Matrix),
+	}
+	for _, m := range []map[string]string{env, job.Env} {
+		for k, v := range m {
+			resp.envs[k] = rewrite(v)
+		}
+	}
+	for i, step := range job.Steps {
+		label := step.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		if step.If != "" {
+			c.warn("condition of step %s in job %s is not converted: %s", label, id, step.If)
+		}
+		for k, v := range step.Env {
+			resp.envs[k] = rewrite(v)
+		}
+		switch {
+		case step.Run != "":
+			script := rewrite(strings.TrimSpace(step.Run))
+			if step.WorkingDirectory != "" {
+				script = fmt.Sprintf("pushd %s\n%s\npopd", step.WorkingDirectory, script)
+			}
+			resp.scripts = append(resp.scripts, script)
+		case strings.HasPrefix(step.Uses, "actions/checkout@"):
+			c.warn("source checkout in job %s should be configured as a git step", id)
+		case strings.HasPrefix(step.Uses, "actions/cache@"):
+			resp.cachePath = strings.TrimSpace(strings.Split(strings.TrimSpace(step.With["path"]), "\n")[0])
+		case strings.HasPrefix(step.Uses, "actions/setup-"):
+			c.warn("%s in job %s is not converted, select a build image with the tool installed", step.Uses, id)
+		case step.Uses != "":
+			c.warn("action %s in job %s is not supported", step.Uses, id)
+		}
+	}
+	if secrets.Len() > 0 {
+		resp.secrets = secrets.List()
+		c.warn("secrets %s of job %s should be filled in as credential variables", strings.Join(resp.secrets, ", "), id)
+	}
+	return resp
+}
+
+func (c *ciConfigConverter) convertGithubMatrix(id string, node *yaml.Node) [][]*commonmodels.KeyVal {
+	if node.Kind == yaml.ScalarNode && node.Value != "" {
+		c.warn("dynamic matrix of job %s is not converted", id)
+	}
+	var dims []ciMatrixDim
+	var excludes []map[string]string
+	keys, values := yamlMapping(node)
+	for _, key := range keys {
+		switch key {
+		case "include":
+			c.warn("matrix include of job %s is not converted", id)
+		case "exclude":
+			if err := values[key].Decode(&excludes); err != nil {
+				c.warn("matrix exclude of job %s is not converted: %s", id, err)
+			}
+		default:
+			var list []string
+			if err := values[key].Decode(&list); err != nil {
+				c.warn("matrix %s of job %s is not a list of values, ignored", key, id)
+				continue
+			}
+			dims = append(dims, ciMatrixDim{Key: ciEnvName(key), Values: list})
+		}
+	}
+	for _, exclude := range excludes {
+		for k, v := range exclude {
+			delete(exclude, k)
+			exclude[ciEnvName(k)] = v
+		}
+	}
+	return ciMatrixCombinations(dims, excludes)
+}
+
+var gitlabReservedKeys = sets.NewString("image", "services", "stages", "types", "before_script", "after_script", "variables", "cache", "include", "workflow", "default")
+
+type gitlabCIDefault struct {
+	Image        yaml.Node         `yaml:"image"`
+	BeforeScript ciStringList      `yaml:"before_script"`
+	AfterScript  ciStringList      `yaml:"after_script"`
+	Cache        yaml.Node         `yaml:"cache"`
+	Variables    gitlabCIVariables `yaml:"variables"`
+}
+
+type gitlabCIConfig struct {
+	gitlabCIDefault `yaml:",inline"`
+	Default         gitlabCIDefault `yaml:"default"`
+	Stages          []string        `yaml:"stages"`
+	Include         interface{}     `yaml:"include"`
+	Workflow        interface{}     `yaml:"workflow"`
+}
+
+type gitlabCIJob struct {
+	gitlabCIDefault `yaml:",inline"`
+	Stage           string        `yaml:"stage"`
+	Script          ciStringList  `yaml:"script"`
+	Services        []interface{} `yaml:"services"`
+	Parallel        yaml.Node     `yaml:"parallel"`
+	Retry           yaml.Node     `yaml:"retry"`
+	Only            yaml.Node     `yaml:"only"`
+	Except          interface{}   `yaml:"except"`
+	Rules           []interface{} `yaml:"rules"`
+	When            string        `yaml:"when"`
+	Needs           []interface{} `yaml:"needs"`
+	Extends         ciStringList  `yaml:"extends"`
+	Artifacts       interface{}   `yaml:"artifacts"`
+	Environment     interface{}   `yaml:"environment"`
+	Trigger         interface{}   `yaml:"trigger"`
+}
+
+// gitlabCITriggers collects the refs which the jobs are run on.
+type gitlabCITriggers struct {
+	push         bool
+	pushBranches []string
+	tag          bool
+	mergeRequest bool
+}
+
+func (c *ciConfigConverter) convertGitlabCI(workflow *commonmodels.WorkflowV4, root *yaml.Node) error {
+	spec := &gitlabCIConfig{}
+	if err := root.Decode(spec); err != nil {
+		return err
+	}
+	if spec.Include != nil {
+		c.warn("include is not supported, merge the included files manually")
+	}
+	if spec.Workflow != nil {
+		c.warn("workflow rules are not converted")
+	}
+
+	stages := []string{".pre", "build", "test", "deploy", ".post"}
+	if len(spec.Stages) > 0 {
+		stages = append(append([]string{".pre"}, spec.Stages...), ".post")
+	}
+	stageJobs := make(map[string][]*commonmodels.Job)
+	triggers := &gitlabCITriggers{}
+	names, nodes := yamlMapping(root)
+	for _, name := range names {
+		if gitlabReservedKeys.Has(name) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		job := &gitlabCIJob{}
+		if err := nodes[name].Decode(job); err != nil {
+			c.warn("job %s is ignored since it can not be parsed: %s", name, err)
+			continue
+		}
+		if job.Stage == "" {
+			job.Stage = "test"
+		}
+		if !sets.NewString(stages...).Has(job.Stage) {
+			c.warn("stage %s of job %s is not defined", job.Stage, name)
+			stages = append(stages, job.Stage)
+		}
+		c.collectGitlabTriggers(name, job, triggers)
+		stageJobs[job.Stage] = append(stageJobs[job.Stage], c.toJobs(c.convertGitlabJob(name, job, spec))...)
+	}
+
+	for _, name := range stages {
+		jobs := stageJobs[name]
+		if len(jobs) == 0 {
+			continue
+		}
+		workflow.Stages = append(workflow.Stages, &commonmodels.WorkflowStage{
+			Name:     name,
+			Parallel: len(jobs) > 1,
+			Jobs:     jobs,
+		})
+	}
+	if len(workflow.Stages) == 0 {
+		return fmt.Errorf("no job found in gitlab ci config")
+	}
+
+	switch {
+	case triggers.push:
+		c.addHook("push", config.HookEventPush, nil, nil)
+	case len(triggers.pushBranches) > 0:
+		c.addHook("push", config.HookEventPush, triggers.pushBranches, nil)
+	}
+	if triggers.tag {
+		c.addHook("tag", config.HookEventTag, nil, nil)
+	}
+	if triggers.mergeRequest {
+		c.addHook("merge-request", config.HookEventPr, nil, nil)
+	}
+	return nil
+}
+
+func (c *ciConfigConverter) convertGitlabJob(name string, job *gitlabCIJob, spec *gitlabCIConfig) *ciJob {
+	if len(job.Extends) > 0 {
+		c.warn("extends of job %s is not supported, merge the template manually", name)
+	}
+	if len(job.Rules) > 0 {
+		c.warn("rules of job %s are not converted", name)
+	}
+	if job.Except != nil {
+		c.warn("except of job %s is not converted", name)
+	}
+	switch job.When {
+	case "", "on_success", "always":
+	case "manual":
+		c.warn("manual job %s is not converted, add an approval to its stage instead", name)
+	default:
+		c.warn("when %s of job %s is not converted", job.When, name)
+	}
+	if len(job.Needs) > 0 {
+		c.warn("needs of job %s are not converted, jobs are run in the order of stages", name)
+	}
+	if job.Artifacts != nil {
+		c.warn("artifacts of job %s are not converted", name)
+	}
+	if job.Environment != nil {
+		c.warn("environment of job %s is not converted, use a deploy job instead", name)
+	}
+	if job.Trigger != nil {
+		c.warn("downstream pipeline trigger of job %s is not supported", name)
+	}
+	if len(job.Services) > 0 {
+		c.warn("services of job %s are not supported", name)
+	}
+	if image := firstYamlNode(&job.Image, &spec.Default.Image, &spec.Image); image != nil {
+		c.warn("image of job %s is not converted, select the build image manually", name)
+	}
+
+	resp := &ciJob{
+		name:   name,
+		envs:   map[string]string{},
+		matrix: c.convertGitlabMatrix(name, &job.Parallel),
+	}
+	for _, m := range []gitlabCIVariables{spec.Variables, spec.Default.Variables, job.Variables} {
+		for k, v := range m {
+			resp.envs[k] = v
+		}
+	}
+	resp.scripts = append(resp.scripts, firstStringList(job.BeforeScript, spec.Default.BeforeScript, spec.BeforeScript)...)
+	resp.scripts = append(resp.scripts, job.Script...)
+	resp.scripts = append(resp.scripts, firstStringList(job.AfterScript, spec.Default.AfterScript, spec.AfterScript)...)
+	if node := firstYamlNode(&job.Cache, &spec.Default.Cache, &spec.Cache); node != nil {
+		resp.cachePath = c.gitlabCachePath(name, node)
+	}
+
+	switch job.Retry.Kind {
+	case yaml.ScalarNode:
+		if err := job.Retry.Decode(&resp.retry); err != nil {
+			c.warn("retry of job %s is not converted: %s", name, err)
+		}
+	case yaml.MappingNode:
+		retry := &struct {
+			Max int64 `yaml:"max"`
+		}{}
+		if err := job.Retry.Decode(retry); err != nil {
+			c.warn("retry of job %s is not converted: %s", name, err)
+		}
+		resp.retry = retry.Max
+	}
+	return resp
+}
+
+func (c *ciConfigConverter) collectGitlabTriggers(name string, job *gitlabCIJob, triggers *gitlabCITriggers) {
+	if job.Only.Kind == 0 {
+		// jobs without rules are run on every push
+		if len(job.Rules) == 0 {
+			triggers.push = true
+		}
+		return
+	}
+
+	var refs ciStringList
+	switch job.Only.Kind {
+	case yaml.MappingNode:
+		only := &struct {
+			Refs ciStringList `yaml:"refs"`
+		}{}
+		if err := job.Only.Decode(only); err != nil {
+			c.warn("only of job %s is not converted: %s", name, err)
+		}
+		refs = only.Refs
+	default:
+		if err := job.Only.Decode(&refs); err != nil {
+			c.warn("only of job %s is not converted: %s", name, err)
+		}
+	}
+	for _, ref := range refs {
+		switch ref {
+		case "branches", "pushes":
+			triggers.push = true
+		case "tags":
+			triggers.tag = true
+		case "merge_requests":
+			triggers.mergeRequest = true
+		case "api", "web", "triggers", "pipelines":
+			// manual runs are always allowed
+		case "schedules":
+			c.warn("schedule trigger of job %s is not converted, configure a timer for the workflow manually", name)
+		default:
+			triggers.pushBranches = append(triggers.pushBranches, strings.Trim(ref, "/"))
+		}
+	}
+}
+
+func (c *ciConfigConverter) gitlabCachePath(name string, node *yaml.Node) string {
+	cache := &struct {
+		Paths []string `yaml:"paths"`
+	}{}
+	if node.Kind == yaml.SequenceNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if err := node.Decode(cache); err != nil {
+		c.warn("cache of job %s is not converted: %s", name, err)
+		return ""
+	}
+	if len(cache.Paths) > 1 {
+		c.warn("only the first cache path of job %s is converted", name)
+	}
+	if len(cache.Paths) == 0 {
+		return ""
+	}
+	return cache.Paths[0]
+}
+
+func (c *ciConfigConverter) convertGitlabMatrix(name string, node *yaml.Node) [][]*commonmodels.KeyVal {
+	_, values := yamlMapping(node)
+	matrix, ok := values["matrix"]
+	if !ok {
+		if node.Kind != 0 {
+			c.warn("parallel of job %s is not converted", name)
+		}
+		return [][]*commonmodels.KeyVal{{}}
+	}
+
+	resp := make([][]*commonmodels.KeyVal, 0)
+	for _, entry := range matrix.Content {
+		var dims []ciMatrixDim
+		keys, values := yamlMapping(entry)
+		for _, key := range keys {
+			var list ciStringList
+			if err := values[key].Decode(&list); err != nil {
+				c.warn("matrix %s of job %s is not a list of values, ignored", key, name)
+				continue
+			}
+			dims = append(dims, ciMatrixDim{Key: key, Values: list})
+		}
+		resp = append(resp, ciMatrixCombinations(dims, nil)...)
+	}
+	if len(resp) == 0 {
+		return [][]*commonmodels.KeyVal{{}}
+	}
+	return resp
+}
+
+// ciJobLevels groups the jobs by the length of their longest dependency chain,
+// jobs in the same group can be run in parallel.
+func ciJobLevels(ids []string, needs func(id string) []string) ([][]string, error) {
+	all := sets.NewString(ids...)
+	levels := make(map[string]int, len(ids))
+	visiting := sets.NewString()
+	var visit func(id string) (int, error)
+	visit = func(id string) (int, error) {
+		if level, ok := levels[id]; ok {
+			return level, nil
+		}
+		if visiting.Has(id) {
+			return 0, fmt.Errorf("circular dependency found on job %s", id)
+		}
+		visiting.Insert(id)
+		level := 0
+		for _, need := range needs(id) {
+			if !all.Has(need) {
+				return 0, fmt.Errorf("job %s needs undefined job %s", id, need)
+			}
+			l, err := visit(need)
+			if err != nil {
+				return 0, err
+			}
+			if l+1 > level {
+				level = l + 1
+			}
+		}
+		levels[id] = level
+		return level, nil
+	}
+
+	resp := make([][]string, 0)
+	for _, id := range ids {
+		level, err := visit(id)
+		if err != nil {
+			return nil, err
+		}
+		for len(resp) <= level {
+			resp = append(resp, nil)
+		}
+	}
+	for _, id := range ids {
+		resp[levels[id]] = append(resp[levels[id]], id)
+	}
+	return resp, nil
+}
+
+type ciMatrixDim struct {
+	Key    string
+	Values []string
+}
+
+// ciMatrixCombinations returns the cartesian product of the matrix, there is always
+// at least one combination so that a job without matrix is converted as is.
+func ciMatrixCombinations(dims []ciMatrixDim, excludes []map[string]string) [][]*commonmodels.KeyVal {
+	resp := [][]*commonmodels.KeyVal{{}}
+	for _, dim := range dims {
+		next := make([][]*commonmodels.KeyVal, 0, len(resp)*len(dim.Values))
+		for _, combination := range resp {
+			for _, value := range dim.Values {
+				item := append(append([]*commonmodels.KeyVal{}, combination...), &commonmodels.KeyVal{Key: dim.Key, Value: value})
+				next = append(next, item)
+			}
+		}
+		resp = next
+	}
+
+	filtered := make([][]*commonmodels.KeyVal, 0, len(resp))
+	for _, combination := range resp {
+		values := make(map[string]string, len(combination))
+		for _, kv := range combination {
+			values[kv.Key] = kv.Value
+		}
+		excluded := false
+		for _, exclude := range excludes {
+			matched := len(exclude) > 0
+			for k, v := range exclude {
+				if values[k] != v {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, combination)
+		}
+	}
+	return filtered
+}
+
+// ciStringList accepts both a single string and a (nested) list of strings.
+type ciStringList []string
+
+func (l *ciStringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*l = append(*l, value.Value)
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if err := l.UnmarshalYAML(item); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return l.UnmarshalYAML(value.Alias)
+	default:
+		return fmt.Errorf("line %d: expect a string or a list of strings", value.Line)
+	}
+	return nil
+}
+
+// gitlabCIVariables accepts both `KEY: value` and `KEY: {value: value, description: desc}`.
+type gitlabCIVariables map[string]string
+
+func (v *gitlabCIVariables) UnmarshalYAML(value *yaml.Node) error {
+	keys, values := yamlMapping(value)
+	*v = make(gitlabCIVariables, len(keys))
+	for _, key := range keys {
+		node := values[key]
+		if node.Kind == yaml.MappingNode {
+			variable := &struct {
+				Value string `yaml:"value"`
+			}{}
+			if err := node.Decode(variable); err != nil {
+				return err
+			}
+			(*v)[key] = variable.Value
+			continue
+		}
+		(*v)[key] = node.Value
+	}
+	return nil
+}
+
+// yamlMapping returns the keys of a mapping node in the order they are defined and the value of each key.
+func yamlMapping(node *yaml.Node) ([]string, map[string]*yaml.Node) {
+	keys := make([]string, 0)
+	values := make(map[string]*yaml.Node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return keys, values
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = node.Content[i+1]
+	}
+	return keys, values
+}
+
+func firstYamlNode(nodes ...*yaml.Node) *yaml.Node {
+	for _, node := range nodes {
+		if node.Kind != 0 {
+			return node
+		}
+	}
+	return nil
+}
+
+func firstStringList(lists ...ciStringList) ciStringList {
+	for _, list := range lists {
+		if list != nil {
+			return list
+		}
+	}
+	return nil
+}
+
+func ciEnvs(envs map[string]string) []*commonmodels.KeyVal {
+	keys := make([]string, 0, len(envs))
+	for k := range envs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	resp := make([]*commonmodels.KeyVal, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, &commonmodels.KeyVal{Key: k, Value: envs[k], Type: commonmodels.StringType})
+	}
+	return resp
+}
+
+var ciEnvNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func ciEnvName(name string) string {
+	return ciEnvNameInvalidChars.ReplaceAllString(name, "_")
+}
+
+// ciBranchRegex converts branch globs into a regular expression for the webhook.
+func ciBranchRegex(branches []string) string {
+	patterns := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		pattern := regexp.QuoteMeta(strings.TrimPrefix(branch, "!"))
+		pattern = strings.ReplaceAll(pattern, `\*\*`, ".*")
+		pattern = strings.ReplaceAll(pattern, `\*`, "[^/]*")
+		pattern = strings.ReplaceAll(pattern, `\?`, ".")
+		patterns = append(patterns, pattern)
+	}
+	return "^(" + strings.Join(patterns, "|") + ")$"
+}
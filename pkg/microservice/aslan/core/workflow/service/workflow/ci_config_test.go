@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	steptypes "github.com/koderover/zadig/pkg/types/step"
+)
+
+const testGithubActions = `
+on:
+  push:
+    branches: [main, "release/*"]
+  pull_request:
+env:
+  GO111MODULE: on
+jobs:
+  lint:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+      - run: make lint
+  test:
+    needs: lint
+    strategy:
+      matrix:
+        go: ["1.17", "1.18"]
+        os: [ubuntu-latest]
+        exclude:
+          - go: "1.17"
+    steps:
+      - uses: actions/cache@v3
+        with:
+          path: ~/go/pkg/mod
+      - run: go test ./... -token ${{ secrets.TOKEN }} -go ${{ matrix.go }}
+`
+
+const testGitlabCI = `
+stages: [build, test]
+variables:
+  IMAGE: demo
+.template: &template
+  before_script:
+    - echo prepare
+build:
+  <<: *template
+  stage: build
+  script: make build
+  cache:
+    paths: [.cache]
+test:
+  parallel:
+    matrix:
+      - DB: [mysql, postgres]
+  script:
+    - make test
+  only: [merge_requests, main]
+`
+
+func convertTestCIConfig(source, content string) (*ConvertWorkflowResp, *commonmodels.WorkflowV4) {
+	resp, err := ConvertCIConfig(&ConvertWorkflowArgs{Name: "demo", Project: "demo", Source: source, Content: content}, zap.NewNop().Sugar())
+	Expect(err).ShouldNot(HaveOccurred())
+	workflow := &commonmodels.WorkflowV4{}
+	Expect(yaml.Unmarshal([]byte(resp.Yaml), workflow)).Should(Succeed())
+	return resp, workflow
+}
+
+func testFreestyleSpec(job *commonmodels.Job) *commonmodels.FreestyleJobSpec {
+	spec := &commonmodels.FreestyleJobSpec{}
+	Expect(commonmodels.IToiYaml(job.Spec, spec)).Should(Succeed())
+	return spec
+}
+
+var _ = Describe("Testing ci config", func() {
+
+	Context("ConvertCIConfig", func() {
+		It("should convert github actions", func() {
+			resp, workflow := convertTestCIConfig(setting.SourceFromGithub, testGithubActions)
+			Expect(workflow.Stages).Should(HaveLen(2))
+			Expect(workflow.Stages[1].Jobs).Should(HaveLen(1))
+			Expect(workflow.Stages[1].Jobs[0].Name).Should(Equal("test-1-18-ubuntu-latest"))
+
+			spec := testFreestyleSpec(workflow.Stages[1].Jobs[0])
+			Expect(spec.Properties.CacheUserDir).Should(Equal("~/go/pkg/mod"))
+			step := &steptypes.StepShellSpec{}
+			Expect(commonmodels.IToiYaml(spec.Steps[0].Spec, step)).Should(Succeed())
+			Expect(step.Script).Should(Equal("go test ./... -token ${TOKEN} -go ${go}"))
+
+			Expect(resp.Hooks).Should(HaveLen(2))
+			Expect(resp.Hooks[0].MainRepo.IsRegular).Should(BeTrue())
+			Expect(resp.Hooks[0].MainRepo.Branch).Should(Equal(`^(main|release/[^/]*)$`))
+			Expect(resp.Hooks[1].MainRepo.Events).Should(Equal([]config.HookEventType{config.HookEventPr}))
+			Expect(resp.Warnings).Should(ContainElement("source checkout in job lint should be configured as a git step"))
+		})
+		It("should convert gitlab ci", func() {
+			resp, workflow := convertTestCIConfig(setting.SourceFromGitlab, testGitlabCI)
+			Expect(workflow.Stages).Should(HaveLen(2))
+			Expect(workflow.Stages[0].Name).Should(Equal("build"))
+			Expect(workflow.Stages[1].Parallel).Should(BeTrue())
+			Expect(workflow.Stages[1].Jobs).Should(HaveLen(2))
+
+			spec := testFreestyleSpec(workflow.Stages[0].Jobs[0])
+			Expect(spec.Properties.CacheEnable).Should(BeTrue())
+			step := &steptypes.StepShellSpec{}
+			Expect(commonmodels.IToiYaml(spec.Steps[0].Spec, step)).Should(Succeed())
+			Expect(step.Script).Should(Equal("echo prepare\nmake build"))
+
+			Expect(resp.Hooks).Should(HaveLen(2))
+			Expect(resp.Hooks[0].MainRepo.Branch).Should(BeEmpty())
+			Expect(resp.Hooks[1].MainRepo.Events).Should(Equal([]config.HookEventType{config.HookEventPr}))
+		})
+		It("should raise error for circular dependencies", func() {
+			_, err := ConvertCIConfig(&ConvertWorkflowArgs{Source: setting.SourceFromGithub, Content: "jobs:\n  a:\n    needs: b\n  b:\n    needs: a\n"}, zap.NewNop().Sugar())
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+})
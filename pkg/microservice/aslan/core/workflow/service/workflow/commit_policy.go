@@ -0,0 +1,192 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	git "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/types"
+	stepspec "github.com/koderover/zadig/pkg/types/step"
+)
+
+// commitVerification is what the codehost reports about a commit.
+type commitVerification struct {
+	Signed      bool
+	AuthorEmail string
+}
+
+// checkCommitPolicy verifies the commits of the build repos against the commit policy of the project
+// if the workflow deploys to a protected environment, all the violations are returned in the error.
+func checkCommitPolicy(workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) error {
+	project, err := templaterepo.NewProductColl().Find(workflow.Project)
+	if err != nil {
+		log.Errorf("failed to find project %s, err: %s", workflow.Project, err)
+		return e.ErrCreateTask.AddErr(err)
+	}
+	policy := project.CommitPolicy
+	if policy == nil || !policy.Enabled {
+		return nil
+	}
+
+	protected := false
+	var repos []*types.Repository
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.Skipped {
+				continue
+			}
+			switch job.JobType {
+			case config.JobZadigDeploy:
+				spec := &commonmodels.ZadigDeployJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return err
+				}
+				if len(policy.ProtectedEnvs) == 0 || sets.NewString(policy.ProtectedEnvs...).Has(spec.Env) {
+					protected = true
+				}
+			case config.JobZadigBuild:
+				spec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return err
+				}
+				for _, build := range spec.ServiceAndBuilds {
+					repos = append(repos, build.Repos...)
+				}
+			case config.JobFreestyle:
+				spec := &commonmodels.FreestyleJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return err
+				}
+				for _, s := range spec.Steps {
+					if s.StepType != config.StepGit {
+						continue
+					}
+					stepSpec := &stepspec.StepGitSpec{}
+					if err := commonmodels.IToi(s.Spec, stepSpec); err != nil {
+						return err
+					}
+					repos = append(repos, stepSpec.Repos...)
+				}
+			case config.JobAndroidBuild:
+				spec := &commonmodels.AndroidBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return err
+				}
+				repos = append(repos, spec.Repos...)
+			}
+		}
+	}
+	if !protected {
+		return nil
+	}
+
+	var reasons []string
+	checked := sets.NewString()
+	for _, repo := range repos {
+		key := fmt.Sprintf("%d/%s/%s@%s", repo.CodehostID, repo.GetRepoNamespace(), repo.RepoName, repo.CommitID)
+		if checked.Has(key) {
+			continue
+		}
+		checked.Insert(key)
+		if reason := checkRepoCommit(policy, repo, log); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s/%s: %s", repo.GetRepoNamespace(), repo.RepoName, reason))
+		}
+	}
+	if len(reasons) > 0 {
+		return e.ErrCommitPolicyDenied.AddDesc(strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// checkRepoCommit returns the reason why the commit of the repo violates the policy, it is empty if
+// the commit is allowed.
+func checkRepoCommit(policy *template.CommitPolicy, repo *types.Repository, log *zap.SugaredLogger) string {
+	if repo.CommitID == "" {
+		return "the commit to build can not be resolved"
+	}
+	ch, err := systemconfig.New().GetCodeHost(repo.CodehostID)
+	if err != nil {
+		log.Errorf("failed to get codehost %d, err: %s", repo.CodehostID, err)
+		return fmt.Sprintf("failed to get codehost: %s", err)
+	}
+	verification, err := getCommitVerification(ch, repo)
+	if err != nil {
+		log.Warnf("failed to get verification of commit %s, err: %s", repo.CommitID, err)
+		return fmt.Sprintf("failed to verify commit %s: %s", repo.CommitID, err)
+	}
+
+	var reasons []string
+	if policy.RequireSigned && !verification.Signed {
+		reasons = append(reasons, fmt.Sprintf("commit %s is not signed or the signature is not verified", repo.CommitID))
+	}
+	if len(policy.AllowedAuthorDomains) > 0 {
+		domain := ""
+		if i := strings.LastIndex(verification.AuthorEmail, "@"); i >= 0 {
+			domain = strings.ToLower(verification.AuthorEmail[i+1:])
+		}
+		if !sets.NewString(policy.AllowedAuthorDomains...).Has(domain) {
+			reasons = append(reasons, fmt.Sprintf("author %q of commit %s is not in the allowed domains %s", verification.AuthorEmail, repo.CommitID, strings.Join(policy.AllowedAuthorDomains, ",")))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+func getCommitVerification(ch *systemconfig.CodeHost, repo *types.Repository) (*commitVerification, error) {
+	switch ch.Type {
+	case systemconfig.GitHubProvider:
+		cli := git.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		commit, _, err := cli.Repositories.GetCommit(context.Background(), repo.RepoOwner, repo.RepoName, repo.CommitID)
+		if err != nil {
+			return nil, err
+		}
+		return &commitVerification{
+			Signed:      commit.GetCommit().GetVerification().GetVerified(),
+			AuthorEmail: commit.GetCommit().GetAuthor().GetEmail(),
+		}, nil
+	case systemconfig.GitLabProvider:
+		cli, err := gitlab.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		if err != nil {
+			return nil, err
+		}
+		commit, err := cli.GetSingleCommitOfProject(repo.GetRepoNamespace(), repo.RepoName, repo.CommitID)
+		if err != nil {
+			return nil, err
+		}
+		resp := &commitVerification{AuthorEmail: commit.AuthorEmail}
+		// the signature is not found for an unsigned commit
+		signature, err := cli.GetCommitSignature(repo.GetRepoNamespace(), repo.RepoName, repo.CommitID)
+		if err == nil && signature.VerificationStatus == "verified" {
+			resp.Signed = true
+		}
+		return resp, nil
+	default:
+		return nil, fmt.Errorf("commit verification is not supported by codehost type %s", ch.Type)
+	}
+}
@@ -83,22 +83,26 @@ func UpdateCronjob(parentName, parentType, productName string, schedule *commonm
 	for _, tasks := range schedule.Items {
 		// 非空ID：修改cronjob，保留这个cronjob 空ID: 直接新建条目
 		job := &commonmodels.Cronjob{
-			Name:         parentName,
-			Type:         parentType,
-			Number:       tasks.Number,
-			Frequency:    tasks.Frequency,
-			Time:         tasks.Time,
-			Cron:         tasks.Cron,
-			MaxFailure:   tasks.MaxFailures,
-			TaskArgs:     tasks.TaskArgs,
-			WorkflowArgs: tasks.WorkflowArgs,
-			TestArgs:     tasks.TestArgs,
-			JobType:      string(tasks.Type),
-			Enabled:      true,
+			Name:               parentName,
+			Type:               parentType,
+			Number:             tasks.Number,
+			Frequency:          tasks.Frequency,
+			Time:               tasks.Time,
+			Cron:               tasks.Cron,
+			MaxFailure:         tasks.MaxFailures,
+			TaskArgs:           tasks.TaskArgs,
+			WorkflowArgs:       tasks.WorkflowArgs,
+			TestArgs:           tasks.TestArgs,
+			EnvDataRefreshArgs: tasks.EnvDataRefreshArgs,
+			EnvSmokeTestArgs:   tasks.EnvSmokeTestArgs,
+			ReleaseTrainArgs:   tasks.ReleaseTrainArgs,
+			JobType:            string(tasks.Type),
+			CatchUpPolicy:      tasks.CatchUpPolicy,
+			Enabled:            true,
 		}
 		if !tasks.ID.IsZero() {
 			job.ID = tasks.ID
-			if parentType == config.TestingCronjob {
+			if parentType == config.TestingCronjob || parentType == config.EnvDataRefreshCronjob || parentType == config.EnvSmokeTestCronjob || parentType == config.ReleaseTrainCronjob {
 				job.ProductName = productName
 			}
 			err := commonrepo.NewCronjobColl().Update(job)
@@ -108,7 +112,7 @@ func UpdateCronjob(parentName, parentType, productName string, schedule *commonm
 			}
 			delete(idMap, tasks.ID.Hex())
 		} else {
-			if parentType == config.TestingCronjob {
+			if parentType == config.TestingCronjob || parentType == config.EnvDataRefreshCronjob || parentType == config.EnvSmokeTestCronjob || parentType == config.ReleaseTrainCronjob {
 				job.ProductName = productName
 			}
 			err := commonrepo.NewCronjobColl().Create(job)
@@ -131,11 +135,29 @@ func UpdateCronjob(parentName, parentType, productName string, schedule *commonm
 		log.Errorf("Failed to delete cronjobs: %v from mongodb, the error is: %v", deleteList, err)
 		return nil, err
 	}
+	if err := commonrepo.NewCronjobRunColl().DeleteByCronjobIDs(deleteList); err != nil {
+		log.Warnf("Failed to delete runs of cronjobs: %v, the error is: %v", deleteList, err)
+	}
 
 	return deleteList, nil
 }
 
 func DeleteCronjob(parentName, parentType string) error {
+	jobList, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+		ParentName: parentName,
+		ParentType: parentType,
+	})
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(jobList))
+	for _, job := range jobList {
+		ids = append(ids, job.ID.Hex())
+	}
+	if err := commonrepo.NewCronjobRunColl().DeleteByCronjobIDs(ids); err != nil {
+		return err
+	}
+
 	return commonrepo.NewCronjobColl().Delete(&commonrepo.CronjobDeleteOption{
 		ParentName: parentName,
 		ParentType: parentType,
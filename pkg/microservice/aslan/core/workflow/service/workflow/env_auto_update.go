@@ -0,0 +1,292 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	envAutoUpdateInterval   = 30 * time.Second
+	envAutoUpdateEventLimit = 100
+)
+
+func ListEnvAutoUpdatePolicies(projectName, envName string, log *zap.SugaredLogger) ([]*commonmodels.EnvAutoUpdatePolicy, error) {
+	policies, err := commonrepo.NewEnvAutoUpdatePolicyColl().List(projectName, envName)
+	if err != nil {
+		log.Errorf("failed to list env auto update policies of project %s, err: %s", projectName, err)
+		return nil, e.ErrListEnvAutoUpdatePolicies.AddErr(err)
+	}
+	return policies, nil
+}
+
+func CreateEnvAutoUpdatePolicy(userName string, args *commonmodels.EnvAutoUpdatePolicy, log *zap.SugaredLogger) error {
+	if err := validateEnvAutoUpdatePolicy(args); err != nil {
+		return e.ErrCreateEnvAutoUpdatePolicy.AddErr(err)
+	}
+
+	args.ID = primitive.NilObjectID
+	args.CreatedBy = userName
+	args.UpdatedBy = userName
+	args.Paused = false
+	args.PausedBy = ""
+	args.PauseReason = ""
+	args.PauseTime = 0
+	args.LastDeployTime = 0
+	if err := commonrepo.NewEnvAutoUpdatePolicyColl().Create(args); err != nil {
+		log.Errorf("failed to create auto update policy of env %s, err: %s", args.EnvName, err)
+		return e.ErrCreateEnvAutoUpdatePolicy.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateEnvAutoUpdatePolicy(userName, id string, args *commonmodels.EnvAutoUpdatePolicy, log *zap.SugaredLogger) error {
+	policy, err := findEnvAutoUpdatePolicy(id, args.ProjectName)
+	if err != nil {
+		return e.ErrUpdateEnvAutoUpdatePolicy.AddErr(err)
+	}
+	args.EnvName = policy.EnvName
+	if err := validateEnvAutoUpdatePolicy(args); err != nil {
+		return e.ErrUpdateEnvAutoUpdatePolicy.AddErr(err)
+	}
+
+	args.ID = policy.ID
+	args.UpdatedBy = userName
+	if err := commonrepo.NewEnvAutoUpdatePolicyColl().Update(args); err != nil {
+		log.Errorf("failed to update auto update policy %s, err: %s", id, err)
+		return e.ErrUpdateEnvAutoUpdatePolicy.AddErr(err)
+	}
+	return nil
+}
+
+// DeleteEnvAutoUpdatePolicy deletes the policy and its change feed, the deploy tasks it created are kept.
+func DeleteEnvAutoUpdatePolicy(id, projectName string, log *zap.SugaredLogger) error {
+	policy, err := findEnvAutoUpdatePolicy(id, projectName)
+	if err != nil {
+		return e.ErrDeleteEnvAutoUpdatePolicy.AddErr(err)
+	}
+	if err := commonrepo.NewEnvAutoUpdatePolicyColl().Delete(policy.ID); err != nil {
+		log.Errorf("failed to delete auto update policy %s, err: %s", id, err)
+		return e.ErrDeleteEnvAutoUpdatePolicy.AddErr(err)
+	}
+	if err := commonrepo.NewEnvAutoUpdateEventColl().DeleteByPolicyID(id); err != nil {
+		log.Warnf("failed to delete events of auto update policy %s, err: %s", id, err)
+	}
+	return nil
+}
+
+// PauseEnvAutoUpdatePolicy stops deploying the new builds to the environment, the builds waiting to be deployed
+// and the ones passed during the pause are skipped.
+func PauseEnvAutoUpdatePolicy(userName, id, projectName, reason string, log *zap.SugaredLogger) error {
+	policy, err := findEnvAutoUpdatePolicy(id, projectName)
+	if err != nil {
+		return e.ErrUpdateEnvAutoUpdatePolicy.AddErr(err)
+	}
+	if err := commonrepo.NewEnvAutoUpdatePolicyColl().UpdatePause(policy.ID, true, userName, reason); err != nil {
+		log.Errorf("failed to pause auto update policy %s, err: %s", id, err)
+		return e.ErrUpdateEnvAutoUpdatePolicy.AddErr(err)
+	}
+	if err := commonrepo.NewEnvAutoUpdateEventColl().SkipPending(id, fmt.Sprintf("auto update is paused by %s", userName)); err != nil {
+		log.Warnf("failed to skip pending events of auto update policy %s, err: %s", id, err)
+	}
+	return nil
+}
+
+// ResumeEnvAutoUpdatePolicy deploys the new builds to the environment again, the builds passed during the
+// pause are not deployed.
+func ResumeEnvAutoUpdatePolicy(id, projectName string, log *zap.SugaredLogger) error {
+	policy, err := findEnvAutoUpdatePolicy(id, projectName)
+	if err != nil {
+		return e.ErrUpdateEnvAutoUpdatePolicy.AddErr(err)
+	}
+	if err := commonrepo.NewEnvAutoUpdatePolicyColl().UpdatePause(policy.ID, false, "", ""); err != nil {
+		log.Errorf("failed to resume auto update policy %s, err: %s", id, err)
+		return e.ErrUpdateEnvAutoUpdatePolicy.AddErr(err)
+	}
+	return nil
+}
+
+// ListEnvAutoUpdateEvents returns the change feed of the environment, the latest events first.
+func ListEnvAutoUpdateEvents(projectName, envName, policyID string, log *zap.SugaredLogger) ([]*commonmodels.EnvAutoUpdateEvent, error) {
+	events, err := commonrepo.NewEnvAutoUpdateEventColl().List(&commonrepo.ListEnvAutoUpdateEventOption{
+		ProjectName: projectName,
+		EnvName:     envName,
+		PolicyID:    policyID,
+		Limit:       envAutoUpdateEventLimit,
+	})
+	if err != nil {
+		log.Errorf("failed to list env auto update events of project %s, err: %s", projectName, err)
+		return nil, e.ErrListEnvAutoUpdateEvents.AddErr(err)
+	}
+	return events, nil
+}
+
+// StartEnvAutoUpdater deploys the new builds recorded on the change feeds of the environments periodically,
+// the builds recorded for a policy since the last run are deployed together by one task.
+func StartEnvAutoUpdater(ctx context.Context, log *zap.SugaredLogger) {
+	ticker := time.NewTicker(envAutoUpdateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ids, err := commonrepo.NewEnvAutoUpdateEventColl().ListPendingPolicies()
+			if err != nil {
+				log.Errorf("failed to list pending env auto updates, err: %s", err)
+				continue
+			}
+			for _, id := range ids {
+				deployEnvAutoUpdates(id, log)
+			}
+		}
+	}
+}
+
+func deployEnvAutoUpdates(policyID string, log *zap.SugaredLogger) {
+	policy, err := commonrepo.NewEnvAutoUpdatePolicyColl().FindByID(policyID)
+	if err != nil {
+		log.Warnf("failed to find auto update policy %s, err: %s", policyID, err)
+		return
+	}
+	if policy.Paused {
+		if err := commonrepo.NewEnvAutoUpdateEventColl().SkipPending(policyID, fmt.Sprintf("auto update is paused by %s", policy.PausedBy)); err != nil {
+			log.Warnf("failed to skip pending events of auto update policy %s, err: %s", policyID, err)
+		}
+		return
+	}
+
+	// the events are claimed before the task is created, so that another instance can not deploy them again.
+	batch := primitive.NewObjectID().Hex()
+	if err := commonrepo.NewEnvAutoUpdateEventColl().Claim(policyID, batch); err != nil {
+		log.Errorf("failed to claim events of auto update policy %s, err: %s", policyID, err)
+		return
+	}
+	events, err := commonrepo.NewEnvAutoUpdateEventColl().List(&commonrepo.ListEnvAutoUpdateEventOption{Batch: batch})
+	if err != nil || len(events) == 0 {
+		return
+	}
+	fail := func(err error) {
+		log.Errorf("failed to update env %s of project %s automatically, err: %s", policy.EnvName, policy.ProjectName, err)
+		if err := commonrepo.NewEnvAutoUpdateEventColl().FinishBatch(batch, config.EnvAutoUpdateFailed, err.Error()); err != nil {
+			log.Errorf("failed to update events of auto update policy %s, err: %s", policyID, err)
+		}
+	}
+
+	workflow, err := getEnvAutoUpdateWorkflow(policy, mergeEnvAutoUpdateImages(events))
+	if err != nil {
+		fail(err)
+		return
+	}
+	task, err := CreateWorkflowTaskV4(setting.EnvAutoUpdateTaskCreator, workflow, log)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if err := commonrepo.NewEnvAutoUpdateEventColl().SetDeployTask(batch, workflow.Name, task.TaskID); err != nil {
+		log.Errorf("failed to record deploy task of auto update policy %s, err: %s", policyID, err)
+	}
+	if err := commonrepo.NewEnvAutoUpdatePolicyColl().UpdateLastDeploy(policy.ID, time.Now().Unix()); err != nil {
+		log.Errorf("failed to update last deploy of auto update policy %s, err: %s", policyID, err)
+	}
+}
+
+// mergeEnvAutoUpdateImages merges the images in the order they are built, the latest image of a service
+// module replaces the former ones.
+func mergeEnvAutoUpdateImages(events []*commonmodels.EnvAutoUpdateEvent) []*commonmodels.ServiceAndImage {
+	images := make([]*commonmodels.ServiceAndImage, 0)
+	indexes := make(map[string]int)
+	for _, event := range events {
+		for _, image := range event.Images {
+			key := fmt.Sprintf("%s/%s", image.ServiceName, image.ServiceModule)
+			if i, ok := indexes[key]; ok {
+				images[i] = image
+				continue
+			}
+			indexes[key] = len(images)
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// getEnvAutoUpdateWorkflow returns the deploy workflow whose deploy job deploys the images to the environment
+// of the policy at runtime.
+func getEnvAutoUpdateWorkflow(policy *commonmodels.EnvAutoUpdatePolicy, images []*commonmodels.ServiceAndImage) (*commonmodels.WorkflowV4, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(policy.DeployWorkflow)
+	if err != nil || workflow.Project != policy.ProjectName {
+		return nil, fmt.Errorf("deploy workflow %s not found", policy.DeployWorkflow)
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != policy.DeployJobName || job.JobType != config.JobZadigDeploy {
+				continue
+			}
+			spec := &commonmodels.ZadigDeployJobSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return nil, err
+			}
+			spec.Env = policy.EnvName
+			spec.Source = config.SourceRuntime
+			spec.JobName = ""
+			spec.ServiceAndImages = images
+			job.Spec = spec
+			return workflow, nil
+		}
+	}
+	return nil, fmt.Errorf("deploy job %s not found in workflow %s", policy.DeployJobName, policy.DeployWorkflow)
+}
+
+func findEnvAutoUpdatePolicy(id, projectName string) (*commonmodels.EnvAutoUpdatePolicy, error) {
+	policy, err := commonrepo.NewEnvAutoUpdatePolicyColl().FindByID(id)
+	if err != nil || policy.ProjectName != projectName {
+		return nil, fmt.Errorf("auto update policy %s not found", id)
+	}
+	return policy, nil
+}
+
+func validateEnvAutoUpdatePolicy(args *commonmodels.EnvAutoUpdatePolicy) error {
+	if args.EnvName == "" {
+		return fmt.Errorf("env is empty")
+	}
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: args.ProjectName, EnvName: args.EnvName}); err != nil {
+		return fmt.Errorf("env %s not found", args.EnvName)
+	}
+	if args.Branch == "" {
+		return fmt.Errorf("branch is empty")
+	}
+	if args.SourceWorkflow != "" {
+		if workflow, err := commonrepo.NewWorkflowV4Coll().Find(args.SourceWorkflow); err != nil || workflow.Project != args.ProjectName {
+			return fmt.Errorf("source workflow %s not found", args.SourceWorkflow)
+		}
+	}
+	if _, err := getEnvAutoUpdateWorkflow(args, nil); err != nil {
+		return err
+	}
+	return nil
+}
@@ -24,6 +24,10 @@ import (
 )
 
 func CreateFavoritePipeline(args *commonmodels.Favorite, log *zap.SugaredLogger) error {
+	// a workflow is favorited once by a user.
+	if _, err := commonrepo.NewFavoriteColl().Find(args.UserID, args.Name, args.Type); err == nil {
+		return nil
+	}
 	return commonrepo.NewFavoriteColl().Create(args)
 }
 
@@ -62,7 +62,7 @@ func createGitCheck(pt *task.Task, log *zap.SugaredLogger) error {
 			Ref:    hook.Ref,
 			IsPr:   hook.IsPr,
 
-			AslanURL:    configbase.SystemAddress(),
+			AslanURL:    configbase.ExternalBaseURL(),
 			PipeName:    pt.PipelineName,
 			ProductName: pt.ProductName,
 			PipeType:    pt.Type,
@@ -90,7 +90,7 @@ func createGitCheck(pt *task.Task, log *zap.SugaredLogger) error {
 		log.Errorf("Failed to get codeHost, err:%v", err)
 		return e.ErrGithubUpdateStatus.AddErr(err)
 	}
-	gc := github.NewClient(ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+	gc := github.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 
 	return gc.UpdateCheckStatus(&github.StatusOptions{
 		Owner:       hook.Owner,
@@ -98,7 +98,7 @@ func createGitCheck(pt *task.Task, log *zap.SugaredLogger) error {
 		Ref:         hook.Ref,
 		State:       github.StatePending,
 		Description: fmt.Sprintf("Workflow [%s] is queued.", pt.PipelineName),
-		AslanURL:    configbase.SystemAddress(),
+		AslanURL:    configbase.ExternalBaseURL(),
 		PipeName:    pt.PipelineName,
 		ProductName: pt.ProductName,
 		PipeType:    pt.Type,
@@ -51,8 +51,8 @@ func QueryByBranch(id int, owner, name, branch string, log *zap.SugaredLogger) (
 	if ch.Type == setting.SourceFromGitlab {
 		token, address := ch.AccessToken, ch.Address
 		var client *http.Client
-		if ch.EnableProxy {
-			proxyURL, err := url.Parse(config.ProxyHTTPSAddr())
+		if ch.UseProxy() {
+			proxyURL, err := url.Parse(ch.ProxyAddr(config.ProxyHTTPSAddr()))
 			if err != nil {
 				return nil, err
 			}
@@ -79,7 +79,7 @@ func QueryByBranch(id int, owner, name, branch string, log *zap.SugaredLogger) (
 			Message:    br.Commit.Message,
 		}, nil
 	} else if ch.Type == setting.SourceFromGerrit {
-		cli := gerrit.NewClient(ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+		cli := gerrit.NewClient(ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 		commit, err := cli.GetCommitByBranch(name, branch)
 		if err != nil {
 			return nil, err
@@ -124,7 +124,7 @@ func QueryByTag(id int, owner, name, tag string, log *zap.SugaredLogger) (*RepoC
 			Message:    br.Commit.Message,
 		}, nil
 	} else if ch.Type == setting.SourceFromGerrit {
-		cli := gerrit.NewClient(ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+		cli := gerrit.NewClient(ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 		commit, err := cli.GetCommitByTag(name, tag)
 		if err != nil {
 			return nil, err
@@ -165,7 +165,7 @@ func GetLatestPrCommit(codehostID, pr int, namespace, projectName string, log *z
 	}
 
 	if ch.Type == gerrit.CodehostTypeGerrit {
-		cli := gerrit.NewClient(ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+		cli := gerrit.NewClient(ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 		change, err := cli.GetCurrentVersionByChangeID(projectName, pr)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+)
+
+// resolveHotfixRun tells whether the run is a hotfix run. The designation is read from the saved workflow since
+// the workflow in the arguments is given by the caller, a reason is required to run another workflow as a hotfix.
+func resolveHotfixRun(saved, args *commonmodels.WorkflowV4) (bool, string, error) {
+	designated := saved != nil && saved.HotfixPolicy != nil && saved.HotfixPolicy.Enabled
+	if args.Hotfix == nil {
+		return designated, "", nil
+	}
+	reason := strings.TrimSpace(args.Hotfix.Reason)
+	if !designated && reason == "" {
+		return false, "", errors.New("a reason is required to run the workflow as a hotfix")
+	}
+	return true, reason, nil
+}
+
+// notifyHotfixRun notifies the receivers of the hotfix policy of the workflow that a hotfix run is created.
+func notifyHotfixRun(saved *commonmodels.WorkflowV4, task *commonmodels.WorkflowTask, log *zap.SugaredLogger) {
+	log.Infof("hotfix task %s:%d is created by %s, reason: %s", task.WorkflowName, task.TaskID, task.TaskCreator, task.HotfixReason)
+	if saved == nil || saved.HotfixPolicy == nil {
+		return
+	}
+
+	title := fmt.Sprintf("工作流 %s 以紧急修复方式执行", task.WorkflowName)
+	content := fmt.Sprintf("%s, 项目：%s, 任务：#%d, 执行人：%s", title, task.ProjectName, task.TaskID, task.TaskCreator)
+	if task.HotfixReason != "" {
+		content = fmt.Sprintf("%s, 原因：%s", content, task.HotfixReason)
+	}
+	for _, receiver := range saved.HotfixPolicy.Receivers {
+		commonservice.SendMessage(receiver, title, content, "", log)
+	}
+	if err := instantmessage.NewWeChatClient().SendSystemMessage(saved.HotfixPolicy.NotifyCtl, title, content); err != nil {
+		log.Warnf("failed to send the notification of hotfix task %s:%d, err: %s", task.WorkflowName, task.TaskID, err)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing hotfix", func() {
+
+	Context("resolveHotfixRun", func() {
+		designated := &commonmodels.WorkflowV4{HotfixPolicy: &commonmodels.HotfixPolicy{Enabled: true}}
+
+		It("should run the designated workflow as a hotfix", func() {
+			hotfix, reason, err := resolveHotfixRun(designated, &commonmodels.WorkflowV4{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hotfix).To(BeTrue())
+			Expect(reason).To(BeEmpty())
+		})
+		It("should ignore the policy given in the arguments", func() {
+			hotfix, _, err := resolveHotfixRun(&commonmodels.WorkflowV4{}, designated)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hotfix).To(BeFalse())
+		})
+		It("should require a reason to run another workflow as a hotfix", func() {
+			_, _, err := resolveHotfixRun(&commonmodels.WorkflowV4{}, &commonmodels.WorkflowV4{Hotfix: &commonmodels.HotfixRun{Reason: " "}})
+			Expect(err).To(HaveOccurred())
+
+			hotfix, reason, err := resolveHotfixRun(nil, &commonmodels.WorkflowV4{Hotfix: &commonmodels.HotfixRun{Reason: "payment outage"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hotfix).To(BeTrue())
+			Expect(reason).To(Equal("payment outage"))
+		})
+	})
+})
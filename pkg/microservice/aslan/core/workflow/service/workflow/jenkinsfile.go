@@ -0,0 +1,392 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// ConvertJenkinsfile translates a declarative Jenkinsfile into a draft common workflow.
+// Every stage becomes a workflow stage, and the shell steps of a stage are merged into a freestyle job.
+func ConvertJenkinsfile(args *ConvertWorkflowArgs, logger *zap.SugaredLogger) (*ConvertWorkflowResp, error) {
+	nodes, err := parseGroovyBlocks(args.Content)
+	if err != nil {
+		logger.Errorf("failed to parse jenkinsfile, err: %s", err)
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	pipeline := findGroovyBlock(nodes, "pipeline")
+	if pipeline == nil {
+		return nil, e.ErrInvalidParam.AddDesc("only declarative pipeline is supported, pipeline block not found")
+	}
+
+	c := &jenkinsfileConverter{}
+	workflow := c.convert(args.Project, args.Name, pipeline)
+	out, err := yaml.Marshal(workflow)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	return &ConvertWorkflowResp{Yaml: string(out), Warnings: c.warnings}, nil
+}
+
+type jenkinsfileConverter struct {
+	warnings []string
+}
+
+func (c *jenkinsfileConverter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+func (c *jenkinsfileConverter) convert(project, name string, pipeline *groovyNode) *commonmodels.WorkflowV4 {
+	workflow := &commonmodels.WorkflowV4{
+		Name:        name,
+		Project:     project,
+		Description: "converted from Jenkinsfile",
+	}
+	var envs []*commonmodels.KeyVal
+	for _, node := range pipeline.Children {
+		switch node.name() {
+		case "agent":
+			c.convertAgent(node)
+		case "environment":
+			envs = append(envs, c.convertEnvironment(node)...)
+		case "parameters":
+			workflow.Params = c.convertParameters(node)
+		case "stages":
+			for _, stage := range node.Children {
+				if stage.name() != "stage" {
+					c.warn("unsupported statement in stages: %s", stage.header())
+					continue
+				}
+				workflow.Stages = append(workflow.Stages, c.convertStage(stage, envs))
+			}
+		default:
+			c.warn("unsupported pipeline section: %s", node.name())
+		}
+	}
+	return workflow
+}
+
+func (c *jenkinsfileConverter) convertAgent(node *groovyNode) {
+	if node.Header == "" {
+		arg := strings.TrimSpace(strings.TrimPrefix(node.Text, "agent"))
+		if arg == "any" || arg == "none" {
+			return
+		}
+	}
+	c.warn("agent %s is not converted, select the build image of each job manually", strings.TrimSpace(strings.TrimPrefix(node.header(), "agent")))
+}
+
+var jenkinsEnvRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.+)$`)
+
+func (c *jenkinsfileConverter) convertEnvironment(node *groovyNode) []*commonmodels.KeyVal {
+	resp := make([]*commonmodels.KeyVal, 0)
+	for _, child := range node.Children {
+		match := jenkinsEnvRegex.FindStringSubmatch(child.Text)
+		if match == nil {
+			c.warn("unsupported environment statement: %s", child.header())
+			continue
+		}
+		value := strings.TrimSpace(match[2])
+		if strings.HasPrefix(value, "credentials(") {
+			c.warn("credential %s should be configured as a credential variable manually", match[1])
+			resp = append(resp, &commonmodels.KeyVal{Key: match[1], Type: commonmodels.StringType, IsCredential: true})
+			continue
+		}
+		resp = append(resp, &commonmodels.KeyVal{Key: match[1], Value: unquoteGroovy(value), Type: commonmodels.StringType})
+	}
+	return resp
+}
+
+var groovyNamedArgRegex = regexp.MustCompile(`(\w+)\s*:\s*('''[\s\S]*?'''|"""[\s\S]*?"""|'[^']*'|"[^"]*"|\[[^\]]*\]|[^,)]+)`)
+
+func (c *jenkinsfileConverter) convertParameters(node *groovyNode) []*commonmodels.Param {
+	resp := make([]*commonmodels.Param, 0)
+	for _, child := range node.Children {
+		fn := child.name()
+		args := map[string]string{}
+		for _, match := range groovyNamedArgRegex.FindAllStringSubmatch(child.Text, -1) {
+			args[match[1]] = strings.TrimSpace(match[2])
+		}
+		param := &commonmodels.Param{
+			Name:        unquoteGroovy(args["name"]),
+			Description: unquoteGroovy(args["description"]),
+			Default:     unquoteGroovy(args["defaultValue"]),
+			Value:       unquoteGroovy(args["defaultValue"]),
+		}
+		switch fn {
+		case "string", "password":
+			param.ParamsType = "string"
+			param.IsCredential = fn == "password"
+		case "text":
+			param.ParamsType = "text"
+		case "booleanParam":
+			param.ParamsType = "choice"
+			param.ChoiceOption = []string{"true", "false"}
+		case "choice":
+			param.ParamsType = "choice"
+			for _, choice := range strings.Split(strings.Trim(args["choices"], "[]"), ",") {
+				if choice = unquoteGroovy(strings.TrimSpace(choice)); choice != "" {
+					param.ChoiceOption = append(param.ChoiceOption, choice)
+				}
+			}
+			if len(param.ChoiceOption) > 0 {
+				param.Default = param.ChoiceOption[0]
+				param.Value = param.ChoiceOption[0]
+			}
+		default:
+			c.warn("unsupported parameter type: %s", fn)
+			continue
+		}
+		if param.Name == "" {
+			c.warn("parameter without name is ignored: %s", child.Text)
+			continue
+		}
+		resp = append(resp, param)
+	}
+	return resp
+}
+
+func (c *jenkinsfileConverter) convertStage(stage *groovyNode, envs []*commonmodels.KeyVal) *commonmodels.WorkflowStage {
+	stageName := unquoteGroovy(stageArg(stage.Header))
+	resp := &commonmodels.WorkflowStage{Name: stageName}
+	if parallel := findGroovyBlock(stage.Children, "parallel"); parallel != nil {
+		resp.Parallel = true
+		for _, sub := range parallel.Children {
+			if sub.name() != "stage" {
+				c.warn("unsupported statement in parallel block of stage %s: %s", stageName, sub.header())
+				continue
+			}
+			resp.Jobs = append(resp.Jobs, c.convertStageJob(sub, envs))
+		}
+		return resp
+	}
+	resp.Jobs = []*commonmodels.Job{c.convertStageJob(stage, envs)}
+	return resp
+}
+
+func (c *jenkinsfileConverter) convertStageJob(stage *groovyNode, envs []*commonmodels.KeyVal) *commonmodels.Job {
+	stageName := unquoteGroovy(stageArg(stage.Header))
+	jobEnvs := append([]*commonmodels.KeyVal{}, envs...)
+	var scripts []string
+	for _, node := range stage.Children {
+		switch node.name() {
+		case "steps":
+			scripts = append(scripts, c.convertSteps(stageName, node.Children)...)
+		case "environment":
+			jobEnvs = append(jobEnvs, c.convertEnvironment(node)...)
+		case "agent":
+			c.convertAgent(node)
+		default:
+			c.warn("unsupported section %s in stage %s", node.name(), stageName)
+		}
+	}
+	return newDraftFreestyleJob(stageName, strings.Join(scripts, "\n"), jobEnvs)
+}
+
+func (c *jenkinsfileConverter) convertSteps(stageName string, steps []*groovyNode) []string {
+	var scripts []string
+	for _, step := range steps {
+		switch step.name() {
+		case "sh", "bat":
+			script := strings.TrimSpace(strings.TrimPrefix(step.Text, step.name()))
+			script = strings.TrimSuffix(strings.TrimPrefix(script, "("), ")")
+			if match := groovyNamedArgRegex.FindStringSubmatch(script); match != nil && match[1] == "script" {
+				script = match[2]
+			}
+			scripts = append(scripts, unquoteGroovy(strings.TrimSpace(script)))
+		case "echo":
+			scripts = append(scripts, "echo "+strings.TrimSpace(strings.TrimPrefix(step.Text, "echo")))
+		case "dir":
+			scripts = append(scripts, fmt.Sprintf("pushd %s", unquoteGroovy(stageArg(step.Header))))
+			scripts = append(scripts, c.convertSteps(stageName, step.Children)...)
+			scripts = append(scripts, "popd")
+		case "checkout", "git":
+			c.warn("source checkout in stage %s should be configured as a git step", stageName)
+		default:
+			c.warn("unsupported step %s in stage %s", step.name(), stageName)
+		}
+	}
+	return scripts
+}
+
+// groovyNode is either a block (Header { Children }) or a single statement (Text).
+type groovyNode struct {
+	Header   string
+	Text     string
+	Children []*groovyNode
+}
+
+func (n *groovyNode) header() string {
+	if n.Header != "" {
+		return n.Header
+	}
+	return n.Text
+}
+
+func (n *groovyNode) name() string {
+	h := n.header()
+	for i, r := range h {
+		if r == '(' || r == ' ' || r == '\t' || r == '\'' || r == '"' {
+			return h[:i]
+		}
+	}
+	return h
+}
+
+func findGroovyBlock(nodes []*groovyNode, name string) *groovyNode {
+	for _, node := range nodes {
+		if node.Header != "" && node.name() == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// parseGroovyBlocks splits the source into a tree of blocks and statements, it understands
+// comments and string literals so that braces inside them are not treated as blocks.
+func parseGroovyBlocks(src string) ([]*groovyNode, error) {
+	p := &groovyParser{src: src}
+	nodes, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected '}' at offset %d", p.pos)
+	}
+	return nodes, nil
+}
+
+type groovyParser struct {
+	src string
+	pos int
+}
+
+func (p *groovyParser) parse(depth int) ([]*groovyNode, error) {
+	var nodes []*groovyNode
+	var current strings.Builder
+	// parentheses may span several lines, e.g. sh(script: '...', returnStdout: true)
+	parens := 0
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			nodes = append(nodes, &groovyNode{Text: text})
+		}
+		current.Reset()
+	}
+
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += end
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			p.pos += end + 4
+		case strings.HasPrefix(p.src[p.pos:], "'''") || strings.HasPrefix(p.src[p.pos:], `"""`):
+			quote := p.src[p.pos : p.pos+3]
+			end := strings.Index(p.src[p.pos+3:], quote)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			current.WriteString(p.src[p.pos : p.pos+end+6])
+			p.pos += end + 6
+		case ch == '\'' || ch == '"':
+			end := p.pos + 1
+			for end < len(p.src) && p.src[end] != ch {
+				if p.src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(p.src) {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			current.WriteString(p.src[p.pos : end+1])
+			p.pos = end + 1
+		case ch == '(':
+			parens++
+			current.WriteByte(ch)
+			p.pos++
+		case ch == ')':
+			parens--
+			current.WriteByte(ch)
+			p.pos++
+		case ch == '{':
+			p.pos++
+			header := strings.TrimSpace(current.String())
+			current.Reset()
+			children, err := p.parse(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, &groovyNode{Header: header, Children: children})
+		case ch == '}':
+			if depth == 0 {
+				flush()
+				return nodes, nil
+			}
+			flush()
+			p.pos++
+			return nodes, nil
+		case (ch == '\n' || ch == ';') && parens <= 0:
+			flush()
+			p.pos++
+		default:
+			current.WriteByte(ch)
+			p.pos++
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("unexpected end of file, missing '}'")
+	}
+	flush()
+	return nodes, nil
+}
+
+// stageArg returns the raw first argument of a call like stage('Build').
+func stageArg(header string) string {
+	start := strings.IndexByte(header, '(')
+	end := strings.LastIndexByte(header, ')')
+	if start < 0 || end <= start {
+		return ""
+	}
+	return strings.TrimSpace(header[start+1 : end])
+}
+
+func unquoteGroovy(s string) string {
+	for _, quote := range []string{"'''", `"""`, "'", `"`} {
+		if len(s) >= 2*len(quote) && strings.HasPrefix(s, quote) && strings.HasSuffix(s, quote) {
+			return strings.TrimSpace(s[len(quote) : len(s)-len(quote)])
+		}
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	steptypes "github.com/koderover/zadig/pkg/types/step"
+)
+
+const testJenkinsfile = `
+pipeline {
+    agent any
+    environment {
+        GO_VERSION = '1.17'
+    }
+    parameters {
+        string(name: 'BRANCH', defaultValue: 'main', description: 'branch to build')
+        choice(name: 'ENV', choices: ['dev', 'prod'], description: '')
+    }
+    stages {
+        stage('Build') {
+            steps {
+                // braces in comments { are ignored
+                sh 'make build'
+                sh """
+                    echo "}"
+                """
+            }
+        }
+        stage('Test') {
+            parallel {
+                stage('Unit Test') {
+                    steps { sh 'make test' }
+                }
+                stage('Lint') {
+                    steps { sh 'make lint' }
+                }
+            }
+        }
+    }
+    post {
+        always { echo 'done' }
+    }
+}
+`
+
+var _ = Describe("Testing jenkinsfile", func() {
+
+	Context("convertJenkinsfile", func() {
+		It("should convert stages, parameters and environments", func() {
+			nodes, err := parseGroovyBlocks(testJenkinsfile)
+			Expect(err).ShouldNot(HaveOccurred())
+			pipeline := findGroovyBlock(nodes, "pipeline")
+			Expect(pipeline).ShouldNot(BeNil())
+
+			c := &jenkinsfileConverter{}
+			workflow := c.convert("demo", "demo", pipeline)
+			Expect(workflow.Params).Should(HaveLen(2))
+			Expect(workflow.Params[1].ChoiceOption).Should(Equal([]string{"dev", "prod"}))
+			Expect(workflow.Stages).Should(HaveLen(2))
+			Expect(workflow.Stages[1].Parallel).Should(BeTrue())
+			Expect(workflow.Stages[1].Jobs[0].Name).Should(Equal("unit-test"))
+
+			spec := workflow.Stages[0].Jobs[0].Spec.(*commonmodels.FreestyleJobSpec)
+			Expect(spec.Properties.Envs[0].Value).Should(Equal("1.17"))
+			script := spec.Steps[0].Spec.(*steptypes.StepShellSpec).Script
+			Expect(script).Should(ContainSubstring("make build"))
+			Expect(script).Should(ContainSubstring(`echo "}"`))
+			Expect(c.warnings).Should(ContainElement("unsupported pipeline section: post"))
+		})
+		It("should raise error for unbalanced braces", func() {
+			_, err := parseGroovyBlocks("pipeline { stages {")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("draftJobName", func() {
+		It("should be converted to a valid job name", func() {
+			Expect(draftJobName("Build & Push")).Should(Equal("build-push"))
+			Expect(draftJobName("1st")).Should(Equal("job-1st"))
+		})
+	})
+})
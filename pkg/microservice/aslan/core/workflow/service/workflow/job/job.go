@@ -50,6 +50,16 @@ func InitJobCtl(job *commonmodels.Job, workflow *commonmodels.WorkflowV4) (JobCt
 		resp = &FreeStyleJob{job: job, workflow: workflow}
 	case config.JobCustomDeploy:
 		resp = &CustomDeployJob{job: job, workflow: workflow}
+	case config.JobJenkins:
+		resp = &JenkinsJob{job: job, workflow: workflow}
+	case config.JobZadigRollout:
+		resp = &RolloutJob{job: job, workflow: workflow}
+	case config.JobLicenseScan:
+		resp = &LicenseScanJob{job: job, workflow: workflow}
+	case config.JobAndroidBuild:
+		resp = &AndroidBuildJob{job: job, workflow: workflow}
+	case config.JobSandboxCluster:
+		resp = &SandboxClusterJob{job: job, workflow: workflow}
 	default:
 		return resp, fmt.Errorf("job type not found %s", job.JobType)
 	}
@@ -95,6 +105,12 @@ func MergeWebhookRepo(workflow *commonmodels.WorkflowV4, repo *types.Repository)
 					return err
 				}
 			}
+			if job.JobType == config.JobAndroidBuild {
+				jobCtl := &AndroidBuildJob{job: job, workflow: workflow}
+				if err := jobCtl.MergeWebhookRepo(repo); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	return nil
@@ -120,6 +136,14 @@ func GetRepos(workflow *commonmodels.WorkflowV4) ([]*types.Repository, error) {
 				}
 				resp = append(resp, freeStyleRepos...)
 			}
+			if job.JobType == config.JobAndroidBuild {
+				jobCtl := &AndroidBuildJob{job: job, workflow: workflow}
+				androidRepos, err := jobCtl.GetRepos()
+				if err != nil {
+					return resp, err
+				}
+				resp = append(resp, androidRepos...)
+			}
 		}
 	}
 	return resp, nil
@@ -227,14 +251,21 @@ func renderString(value, template string, inputs []*commonmodels.Param) string {
 	return value
 }
 
+// renderMultiLineString renders the inputs into a json string, the values are escaped so that
+// commit messages or any other values with quotes keep the json valid.
 func renderMultiLineString(value, template string, inputs []*commonmodels.Param) string {
 	for _, input := range inputs {
-		inputValue := strings.ReplaceAll(input.Value, "\n", "\\n")
+		inputValue := escapeJSONString(input.Value)
 		value = strings.ReplaceAll(value, fmt.Sprintf(template, input.Name), inputValue)
 	}
 	return value
 }
 
+func escapeJSONString(value string) string {
+	b, _ := json.Marshal(value)
+	return string(b[1 : len(b)-1])
+}
+
 func getWorkflowDefaultParams(workflow *commonmodels.WorkflowV4, taskID int64, creator string) []*commonmodels.Param {
 	resp := []*commonmodels.Param{}
 	resp = append(resp, &commonmodels.Param{Name: "project", Value: workflow.Project, ParamsType: "string", IsCredential: false})
@@ -242,6 +273,35 @@ func getWorkflowDefaultParams(workflow *commonmodels.WorkflowV4, taskID int64, c
 	resp = append(resp, &commonmodels.Param{Name: "workflow.task.id", Value: fmt.Sprintf("%d", taskID), ParamsType: "string", IsCredential: false})
 	resp = append(resp, &commonmodels.Param{Name: "workflow.task.creator", Value: creator, ParamsType: "string", IsCredential: false})
 	resp = append(resp, &commonmodels.Param{Name: "workflow.task.timestamp", Value: fmt.Sprintf("%d", time.Now().Unix()), ParamsType: "string", IsCredential: false})
+	// the tag and its semantic version components of the tag event which triggers the task
+	tag := ""
+	if workflow.HookPayload != nil {
+		tag = workflow.HookPayload.Tag
+	}
+	version := types.ParseTagVersion(tag)
+	if version == nil {
+		version = &types.TagVersion{}
+	}
+	resp = append(resp, &commonmodels.Param{Name: "workflow.tag", Value: tag, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.tag.version", Value: version.Version, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.tag.major", Value: version.Major, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.tag.minor", Value: version.Minor, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.tag.patch", Value: version.Patch, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.tag.prerelease", Value: version.Prerelease, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.tag.build", Value: version.Build, ParamsType: "string", IsCredential: false})
+	// the metadata of the commit or pull request which triggers the task, lists are joined by commas
+	payload := workflow.HookPayload
+	if payload == nil {
+		payload = &commonmodels.HookPayload{}
+	}
+	resp = append(resp, &commonmodels.Param{Name: "workflow.trigger.author", Value: payload.CommitAuthor, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.trigger.message", Value: payload.CommitMessage, ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.trigger.labels", Value: strings.Join(payload.Labels, ","), ParamsType: "string", IsCredential: false})
+	resp = append(resp, &commonmodels.Param{Name: "workflow.trigger.changed_files", Value: strings.Join(payload.ChangedFiles, ","), ParamsType: "string", IsCredential: false})
+	// the labels given when the task is created, e.g. {{.workflow.task.label.ticket}}
+	for key, value := range workflow.RunLabels {
+		resp = append(resp, &commonmodels.Param{Name: "workflow.task.label." + key, Value: value, ParamsType: "string", IsCredential: false})
+	}
 	for _, param := range workflow.Params {
 		paramsKey := strings.Join([]string{"workflow", "params", param.Name}, ".")
 		resp = append(resp, &commonmodels.Param{Name: paramsKey, Value: param.Value, ParamsType: "string", IsCredential: false})
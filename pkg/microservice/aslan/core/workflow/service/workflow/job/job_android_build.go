@@ -0,0 +1,290 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	// the apk and aab are collected into the dir with their metadata, and the dir is archived.
+	androidOutputDir    = ".zadig/android"
+	androidKeystoreFile = ".zadig/android-signing.keystore"
+)
+
+var (
+	defaultAndroidGradleTasks = []string{"assembleRelease", "bundleRelease"}
+	defaultAndroidOutputs     = []string{"app/build/outputs/apk/*/*.apk", "app/build/outputs/bundle/*/*.aab"}
+)
+
+type AndroidBuildJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.AndroidBuildJobSpec
+}
+
+func (j *AndroidBuildJob) Instantiate() error {
+	j.spec = &commonmodels.AndroidBuildJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.Properties == nil {
+		return fmt.Errorf("android build job %s has no properties", j.job.Name)
+	}
+	envs := j.spec.Properties.Envs
+	if j.spec.Signing != nil {
+		for _, key := range []string{j.spec.Signing.KeystoreEnv, j.spec.Signing.KeystorePasswordEnv, j.spec.Signing.KeyPasswordEnv} {
+			if err := checkCredentialEnv(envs, key); err != nil {
+				return fmt.Errorf("android build job %s signing: %s", j.job.Name, err)
+			}
+		}
+		if j.spec.Signing.KeyAlias == "" {
+			return fmt.Errorf("android build job %s signing: key alias is empty", j.job.Name)
+		}
+	}
+	if j.spec.Firebase != nil {
+		if j.spec.Firebase.AppID == "" {
+			return fmt.Errorf("android build job %s firebase: app id is empty", j.job.Name)
+		}
+		if err := checkCredentialEnv(envs, j.spec.Firebase.ServiceAccountEnv); err != nil {
+			return fmt.Errorf("android build job %s firebase: %s", j.job.Name, err)
+		}
+	}
+	if j.spec.GooglePlay != nil {
+		if j.spec.GooglePlay.PackageName == "" {
+			return fmt.Errorf("android build job %s google play: package name is empty", j.job.Name)
+		}
+		if err := checkCredentialEnv(envs, j.spec.GooglePlay.ServiceAccountEnv); err != nil {
+			return fmt.Errorf("android build job %s google play: %s", j.job.Name, err)
+		}
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *AndroidBuildJob) SetPreset() error {
+	j.spec = &commonmodels.AndroidBuildJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *AndroidBuildJob) GetRepos() ([]*types.Repository, error) {
+	j.spec = &commonmodels.AndroidBuildJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return []*types.Repository{}, err
+	}
+	return j.spec.Repos, nil
+}
+
+func (j *AndroidBuildJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.AndroidBuildJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.AndroidBuildJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		if argsSpec.Properties != nil {
+			j.spec.Properties.Envs = renderKeyVals(j.spec.Properties.Envs, argsSpec.Properties.Envs)
+		}
+		j.spec.Repos = mergeRepos(j.spec.Repos, argsSpec.Repos)
+		if argsSpec.VersionName != "" {
+			j.spec.VersionName = argsSpec.VersionName
+		}
+		if argsSpec.VersionCode != "" {
+			j.spec.VersionCode = argsSpec.VersionCode
+		}
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *AndroidBuildJob) MergeWebhookRepo(webhookRepo *types.Repository) error {
+	j.spec = &commonmodels.AndroidBuildJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.spec.Repos = mergeRepos(j.spec.Repos, []*types.Repository{webhookRepo})
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *AndroidBuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	logger := log.SugaredLogger()
+	resp := []*commonmodels.JobTask{}
+	j.spec = &commonmodels.AndroidBuildJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return resp, err
+	}
+	j.job.Spec = j.spec
+
+	defaultS3, err := commonrepo.NewS3StorageColl().FindDefault()
+	if err != nil {
+		return resp, err
+	}
+	jobTaskSpec := &commonmodels.JobTaskBuildSpec{
+		Properties: *j.spec.Properties,
+	}
+	jobTask := &commonmodels.JobTask{
+		Name:    jobNameFormat(j.job.Name),
+		JobType: string(config.JobAndroidBuild),
+		Spec:    jobTaskSpec,
+		Timeout: j.spec.Properties.Timeout,
+	}
+
+	versionCode := j.spec.VersionCode
+	if versionCode == "" {
+		versionCode = "$TASK_ID"
+	}
+	outputs := []string{}
+	for _, output := range defaultStrings(j.spec.Outputs, defaultAndroidOutputs) {
+		outputs = append(outputs, path.Join(j.spec.ProjectDir, output))
+	}
+	jobTaskSpec.Steps = []*commonmodels.StepTask{
+		{
+			Name:     j.job.Name + "-git",
+			JobName:  jobTask.Name,
+			StepType: config.StepGit,
+			Spec:     step.StepGitSpec{Repos: j.spec.Repos},
+		},
+		{
+			Name:     j.job.Name + "-gradle",
+			JobName:  jobTask.Name,
+			StepType: config.StepShell,
+			Spec:     &step.StepShellSpec{Scripts: j.gradleScripts(jobTaskSpec.Properties, versionCode)},
+		},
+		{
+			Name:     j.job.Name + "-publish",
+			JobName:  jobTask.Name,
+			StepType: config.StepAndroidPublish,
+			Spec: &step.StepAndroidPublishSpec{
+				Files:       outputs,
+				OutputDir:   androidOutputDir,
+				VersionName: j.spec.VersionName,
+				VersionCode: versionCode,
+				Firebase:    j.spec.Firebase,
+				GooglePlay:  j.spec.GooglePlay,
+			},
+		},
+		{
+			Name:     j.job.Name + "-archive",
+			JobName:  jobTask.Name,
+			StepType: config.StepArchive,
+			Spec: step.StepArchiveSpec{
+				// saved next to the artifacts uploaded by the runners.
+				UploadDetail: []*step.Upload{
+					{
+						FilePath:        androidOutputDir,
+						DestinationPath: path.Join(strings.ToLower(j.workflow.Name), fmt.Sprint(taskID), "artifacts", strings.Replace(strings.ToLower(jobTask.Name), "_", "-", -1)),
+					},
+				},
+				S3: modelS3toS3(defaultS3),
+			},
+		},
+	}
+
+	registries, err := commonservice.ListRegistryNamespaces("", true, logger)
+	if err != nil {
+		return resp, err
+	}
+	jobTaskSpec.Properties.Registries = registries
+	basicImage, err := commonrepo.NewBasicImageColl().Find(jobTaskSpec.Properties.ImageID)
+	if err != nil {
+		return resp, err
+	}
+	jobTaskSpec.Properties.BuildOS = basicImage.Value
+	jobTaskSpec.Properties.CustomEnvs = jobTaskSpec.Properties.Envs
+	jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs, getfreestyleJobVariables(jobTaskSpec.Steps, taskID, j.workflow.Project, j.workflow.Name)...)
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+// gradleScripts builds the app with the gradle wrapper, the version and the signing config are injected
+// by the properties which the android gradle plugin supports.
+func (j *AndroidBuildJob) gradleScripts(properties commonmodels.JobProperties, versionCode string) []string {
+	// keep the gradle caches in the cache dir so that they are reused by the next task.
+	gradleHome := "$WORKSPACE/.gradle"
+	if properties.CacheEnable && properties.CacheDirType == types.UserDefinedCacheDir && properties.CacheUserDir != "" {
+		gradleHome = path.Join(properties.CacheUserDir, ".gradle")
+	}
+	scripts := []string{
+		"set -e",
+		fmt.Sprintf(`export GRADLE_USER_HOME="%s"`, gradleHome),
+		fmt.Sprintf(`cd "$WORKSPACE/%s"`, j.spec.ProjectDir),
+		"chmod +x ./gradlew",
+	}
+
+	args := append([]string{"./gradlew", "--no-daemon"}, defaultStrings(j.spec.GradleTasks, defaultAndroidGradleTasks)...)
+	args = append(args, fmt.Sprintf(`-Pandroid.injected.version.code="%s"`, versionCode))
+	if j.spec.VersionName != "" {
+		args = append(args, fmt.Sprintf(`-Pandroid.injected.version.name="%s"`, j.spec.VersionName))
+	}
+	if signing := j.spec.Signing; signing != nil {
+		keystore := "$WORKSPACE/" + androidKeystoreFile
+		scripts = append(scripts,
+			`mkdir -p "$WORKSPACE/.zadig"`,
+			fmt.Sprintf(`trap 'rm -f "%s"' EXIT`, keystore),
+			fmt.Sprintf(`echo "$%s" | base64 -d > "%s"`, signing.KeystoreEnv, keystore),
+		)
+		args = append(args,
+			fmt.Sprintf(`-Pandroid.injected.signing.store.file="%s"`, keystore),
+			fmt.Sprintf(`-Pandroid.injected.signing.store.password="$%s"`, signing.KeystorePasswordEnv),
+			fmt.Sprintf(`-Pandroid.injected.signing.key.alias="%s"`, signing.KeyAlias),
+			fmt.Sprintf(`-Pandroid.injected.signing.key.password="$%s"`, signing.KeyPasswordEnv),
+		)
+	}
+	return append(scripts, strings.Join(args, " "))
+}
+
+// checkCredentialEnv makes sure the secrets are passed by the credential variables, which are encrypted
+// in the workflow and masked in the logs.
+func checkCredentialEnv(envs []*commonmodels.KeyVal, key string) error {
+	if key == "" {
+		return fmt.Errorf("variable is not specified")
+	}
+	for _, env := range envs {
+		if env.Key != key {
+			continue
+		}
+		if !env.IsCredential {
+			return fmt.Errorf("variable %s is not a credential", key)
+		}
+		return nil
+	}
+	return fmt.Errorf("variable %s not found", key)
+}
+
+func defaultStrings(values, defaults []string) []string {
+	if len(values) == 0 {
+		return defaults
+	}
+	return values
+}
@@ -207,6 +207,8 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			BuildOS:         basicImage.Value,
 			ImageFrom:       buildInfo.PreBuild.ImageFrom,
 			Registries:      registries,
+			Runner:          buildInfo.PreBuild.Runner,
+			RunnerLabels:    buildInfo.PreBuild.RunnerLabels,
 		}
 		clusterInfo, err := commonrepo.NewK8SClusterColl().Get(buildInfo.PreBuild.ClusterID)
 		if err != nil {
@@ -221,7 +223,11 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			jobTaskSpec.Properties.CacheDirType = buildInfo.CacheDirType
 			jobTaskSpec.Properties.CacheUserDir = buildInfo.CacheUserDir
 		}
-		jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.CustomEnvs, getBuildJobVariables(build, taskID, j.workflow.Project, j.workflow.Name, registry, logger)...)
+		envs, err := commonservice.MergeVariableGroups(buildInfo.ProductName, buildInfo.PreBuild.VariableGroups, jobTaskSpec.Properties.CustomEnvs)
+		if err != nil {
+			return resp, err
+		}
+		jobTaskSpec.Properties.Envs = append(envs, getBuildJobVariables(build, taskID, j.workflow.Project, j.workflow.Name, registry, logger)...)
 
 		if jobTaskSpec.Properties.CacheEnable && jobTaskSpec.Properties.Cache.MediumType == types.NFSMedium {
 			jobTaskSpec.Properties.CacheUserDir = renderEnv(jobTaskSpec.Properties.CacheUserDir, jobTaskSpec.Properties.Envs)
@@ -251,6 +257,16 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			Spec:     step.StepGitSpec{Repos: renderRepos(build.Repos, buildInfo.Repos)},
 		}
 		jobTaskSpec.Steps = append(jobTaskSpec.Steps, gitStep)
+		// init download step
+		if len(buildInfo.PreBuild.Downloads) > 0 {
+			downloadStep := &commonmodels.StepTask{
+				Name:     build.ServiceName + "-download",
+				JobName:  jobTask.Name,
+				StepType: config.StepDownload,
+				Spec:     step.StepDownloadSpec{Artifacts: buildInfo.PreBuild.Downloads},
+			}
+			jobTaskSpec.Steps = append(jobTaskSpec.Steps, downloadStep)
+		}
 
 		// init shell step
 		dockerLoginCmd := `docker login -u "$DOCKER_REGISTRY_AK" -p "$DOCKER_REGISTRY_SK" "$DOCKER_REGISTRY_HOST" &> /dev/null`
@@ -269,8 +285,8 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 		if buildInfo.PostBuild.DockerBuild != nil {
 			dockefileContent := ""
 			if buildInfo.PostBuild.DockerBuild.TemplateID != "" {
-				if dockerfileDetail, err := templ.GetDockerfileTemplateDetail(buildInfo.PostBuild.DockerBuild.TemplateID, logger); err == nil {
-					dockefileContent = dockerfileDetail.Content
+				if content, err := templ.GetDockerfileTemplateContent(buildInfo.PostBuild.DockerBuild.TemplateID, buildInfo.PostBuild.DockerBuild.TemplateRevision, logger); err == nil {
+					dockefileContent = content
 				}
 			}
 
@@ -414,7 +430,7 @@ func getBuildJobVariables(build *commonmodels.ServiceAndBuild, taskID int64, pro
 	ret = append(ret, &commonmodels.KeyVal{Key: "IMAGE", Value: build.Image, IsCredential: false})
 	ret = append(ret, &commonmodels.KeyVal{Key: "CI", Value: "true", IsCredential: false})
 	ret = append(ret, &commonmodels.KeyVal{Key: "ZADIG", Value: "true", IsCredential: false})
-	buildURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.SystemAddress(), project, workflowName, taskID)
+	buildURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.ExternalBaseURL(), project, workflowName, taskID)
 	ret = append(ret, &commonmodels.KeyVal{Key: "BUILD_URL", Value: buildURL, IsCredential: false})
 	ret = append(ret, &commonmodels.KeyVal{Key: "PKG_FILE", Value: build.Package, IsCredential: false})
 	return ret
@@ -447,29 +463,7 @@ func fillBuildDetail(moduleBuild *commonmodels.Build, serviceName, serviceModule
 		return fmt.Errorf("failed to find build template with id: %s, err: %s", moduleBuild.TemplateID, err)
 	}
 
-	moduleBuild.Timeout = buildTemplate.Timeout
-	moduleBuild.PreBuild = buildTemplate.PreBuild
-	moduleBuild.JenkinsBuild = buildTemplate.JenkinsBuild
-	moduleBuild.Scripts = buildTemplate.Scripts
-	moduleBuild.PostBuild = buildTemplate.PostBuild
-	moduleBuild.SSHs = buildTemplate.SSHs
-	moduleBuild.PMDeployScripts = buildTemplate.PMDeployScripts
-	moduleBuild.CacheEnable = buildTemplate.CacheEnable
-	moduleBuild.CacheDirType = buildTemplate.CacheDirType
-	moduleBuild.CacheUserDir = buildTemplate.CacheUserDir
-	moduleBuild.AdvancedSettingsModified = buildTemplate.AdvancedSettingsModified
-
-	// repos are configured by service modules
-	for _, serviceConfig := range moduleBuild.Targets {
-		if serviceConfig.ServiceName == serviceName && serviceConfig.ServiceModule == serviceModule {
-			moduleBuild.Repos = serviceConfig.Repos
-			if moduleBuild.PreBuild == nil {
-				moduleBuild.PreBuild = &commonmodels.PreBuild{}
-			}
-			moduleBuild.PreBuild.Envs = commonservice.MergeBuildEnvs(moduleBuild.PreBuild.Envs, serviceConfig.Envs)
-			break
-		}
-	}
+	commonservice.FillBuildDetail(moduleBuild, buildTemplate, serviceName, serviceModule)
 	return nil
 }
 
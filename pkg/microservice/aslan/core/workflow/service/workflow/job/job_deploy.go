@@ -18,11 +18,14 @@ package job
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/util"
 )
@@ -148,6 +151,7 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			}
 		}
 	}
+	serviceJobTasks := map[string][]*commonmodels.JobTask{}
 	if j.spec.DeployType == setting.K8SDeployType {
 		for _, deploy := range j.spec.ServiceAndImages {
 			if err := checkServiceExsistsInEnv(productServiceMap, deploy.ServiceName, j.spec.Env); err != nil {
@@ -161,6 +165,8 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				ServiceModule:      deploy.ServiceModule,
 				ClusterID:          product.ClusterID,
 				Image:              deploy.Image,
+				PinImageDigest:     j.spec.PinImageDigest,
+				Verification:       j.spec.Verification,
 			}
 			jobTask := &commonmodels.JobTask{
 				Name:    jobNameFormat(deploy.ServiceName + "-" + deploy.ServiceModule + "-" + j.job.Name),
@@ -168,6 +174,7 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				Spec:    jobTaskSpec,
 			}
 			resp = append(resp, jobTask)
+			serviceJobTasks[deploy.ServiceName] = append(serviceJobTasks[deploy.ServiceName], jobTask)
 		}
 	}
 	if j.spec.DeployType == setting.HelmDeployType {
@@ -198,6 +205,8 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				ServiceType:        setting.HelmDeployType,
 				ClusterID:          product.ClusterID,
 				ReleaseName:        releaseName,
+				PinImageDigest:     j.spec.PinImageDigest,
+				Verification:       j.spec.Verification,
 			}
 			for _, deploy := range deploys {
 				if err := checkServiceExsistsInEnv(productServiceMap, serviceName, j.spec.Env); err != nil {
@@ -214,6 +223,13 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				Spec:    jobTaskSpec,
 			}
 			resp = append(resp, jobTask)
+			serviceJobTasks[serviceName] = append(serviceJobTasks[serviceName], jobTask)
+		}
+	}
+
+	if len(project.ServiceDependencies) > 0 {
+		if resp, err = sortDeployJobTasks(project.ServiceDependencies, serviceJobTasks); err != nil {
+			return nil, err
 		}
 	}
 
@@ -221,6 +237,52 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 	return resp, nil
 }
 
+// sortDeployJobTasks orders the deploy tasks by the service dependencies, the tasks of a service depend on
+// the tasks of the services deployed before it so that the order is kept in parallel stages too.
+// The tasks of the services with dependents wait for the workloads to be ready unless the condition is none.
+func sortDeployJobTasks(dependencies []*template.ServiceDependency, serviceJobTasks map[string][]*commonmodels.JobTask) ([]*commonmodels.JobTask, error) {
+	services := make([]string, 0, len(serviceJobTasks))
+	for service := range serviceJobTasks {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	layers, err := commonservice.SortServicesByDependency(dependencies, services)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*commonmodels.JobTask, 0)
+	var previous []string
+	for i, layer := range layers {
+		var current []string
+		for _, service := range layer {
+			condition := commonservice.GetServiceReadyCondition(dependencies, service)
+			waitReady := i < len(layers)-1 && condition.Type != config.ServiceReadyConditionNone
+			for _, jobTask := range serviceJobTasks[service] {
+				jobTask.DependsOn = previous
+				if waitReady {
+					switch spec := jobTask.Spec.(type) {
+					case *commonmodels.JobTaskDeploySpec:
+						spec.SkipCheckRunStatus = false
+						if condition.Timeout > 0 {
+							spec.Timeout = condition.Timeout
+						}
+					case *commonmodels.JobTaskHelmDeploySpec:
+						spec.SkipCheckRunStatus = false
+						if condition.Timeout > 0 {
+							spec.Timeout = condition.Timeout
+						}
+					}
+				}
+				resp = append(resp, jobTask)
+				current = append(current, jobTask.Name)
+			}
+		}
+		previous = current
+	}
+	return resp, nil
+}
+
 func checkServiceExsistsInEnv(serviceMap map[string]*commonmodels.ProductService, serviceName, env string) error {
 	if _, ok := serviceMap[serviceName]; !ok {
 		return fmt.Errorf("service %s not exists in env %s", serviceName, env)
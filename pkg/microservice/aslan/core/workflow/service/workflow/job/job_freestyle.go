@@ -19,12 +19,15 @@ package job
 import (
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	configbase "github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/summary"
 	"github.com/koderover/zadig/pkg/types"
 	steptypes "github.com/koderover/zadig/pkg/types/step"
 )
@@ -66,6 +69,29 @@ func (j *FreeStyleJob) Instantiate() error {
 				return err
 			}
 			step.Spec = stepSpec
+		case config.StepDownload:
+			stepSpec := &steptypes.StepDownloadSpec{}
+			if err := commonmodels.IToiYaml(step.Spec, stepSpec); err != nil {
+				return err
+			}
+			for _, artifact := range stepSpec.Artifacts {
+				if err := artifact.Validate(); err != nil {
+					return fmt.Errorf("freestyle job step %s: %s", step.Name, err)
+				}
+			}
+			step.Spec = stepSpec
+		case config.StepSummary:
+			stepSpec := &steptypes.StepSummarySpec{}
+			if err := commonmodels.IToiYaml(step.Spec, stepSpec); err != nil {
+				return err
+			}
+			if stepSpec.FilePath == "" {
+				return fmt.Errorf("freestyle job step %s: the summary file path is empty", step.Name)
+			}
+			if stepSpec.Format != "" && !sets.NewString(summary.Formats()...).Has(stepSpec.Format) {
+				return fmt.Errorf("freestyle job step %s: summary format %s is not supported", step.Name, stepSpec.Format)
+			}
+			step.Spec = stepSpec
 		default:
 			return fmt.Errorf("freestyle job step type %s not supported", step.StepType)
 		}
@@ -174,7 +200,7 @@ func (j *FreeStyleJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 	j.job.Spec = j.spec
 	jobTaskSpec := &commonmodels.JobTaskBuildSpec{
 		Properties: *j.spec.Properties,
-		Steps:      stepsToStepTasks(j.spec.Steps),
+		Steps:      stepsToStepTasks(j.job.Name, j.spec.Steps),
 	}
 	jobTask := &commonmodels.JobTask{
 		Name:    j.job.Name,
@@ -194,16 +220,21 @@ func (j *FreeStyleJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 	jobTaskSpec.Properties.BuildOS = basicImage.Value
 	// save user defined variables.
 	jobTaskSpec.Properties.CustomEnvs = jobTaskSpec.Properties.Envs
-	jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs, getfreestyleJobVariables(jobTaskSpec.Steps, taskID, j.workflow.Project, j.workflow.Name)...)
+	envs, err := commonservice.MergeVariableGroups(j.workflow.Project, jobTaskSpec.Properties.VariableGroups, jobTaskSpec.Properties.Envs)
+	if err != nil {
+		return resp, err
+	}
+	jobTaskSpec.Properties.Envs = append(envs, getfreestyleJobVariables(jobTaskSpec.Steps, taskID, j.workflow.Project, j.workflow.Name)...)
 	return []*commonmodels.JobTask{jobTask}, nil
 }
 
-func stepsToStepTasks(step []*commonmodels.Step) []*commonmodels.StepTask {
+func stepsToStepTasks(jobName string, step []*commonmodels.Step) []*commonmodels.StepTask {
 	logger := log.SugaredLogger()
 	resp := []*commonmodels.StepTask{}
 	for _, step := range step {
 		stepTask := &commonmodels.StepTask{
 			Name:     step.Name,
+			JobName:  jobName,
 			StepType: step.StepType,
 			Spec:     step.Spec,
 		}
@@ -251,7 +282,7 @@ func getfreestyleJobVariables(steps []*commonmodels.StepTask, taskID int64, proj
 	ret = append(ret, getReposVariables(repos)...)
 
 	ret = append(ret, &commonmodels.KeyVal{Key: "TASK_ID", Value: fmt.Sprintf("%d", taskID), IsCredential: false})
-	buildURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.SystemAddress(), project, workflowName, taskID)
+	buildURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d", configbase.ExternalBaseURL(), project, workflowName, taskID)
 	ret = append(ret, &commonmodels.KeyVal{Key: "BUILD_URL", Value: buildURL, IsCredential: false})
 	return ret
 }
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+type JenkinsJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.JenkinsJobSpec
+}
+
+func (j *JenkinsJob) Instantiate() error {
+	j.spec = &commonmodels.JenkinsJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.ID == "" {
+		return fmt.Errorf("jenkins integration id of job %s should not be empty", j.job.Name)
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *JenkinsJob) SetPreset() error {
+	j.spec = &commonmodels.JenkinsJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *JenkinsJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.JenkinsJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.JenkinsJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		for _, jenkinsJob := range j.spec.Jobs {
+			for _, argsJob := range argsSpec.Jobs {
+				if jenkinsJob.JobName != argsJob.JobName {
+					continue
+				}
+				jenkinsJob.Parameters = mergeJenkinsParameters(jenkinsJob.Parameters, argsJob.Parameters)
+			}
+		}
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *JenkinsJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	resp := []*commonmodels.JobTask{}
+	j.spec = &commonmodels.JenkinsJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return resp, err
+	}
+	j.job.Spec = j.spec
+	for _, jenkinsJob := range j.spec.Jobs {
+		jobTask := &commonmodels.JobTask{
+			Name:    jobNameFormat(j.job.Name + "-" + jenkinsJob.JobName),
+			JobType: string(config.JobJenkins),
+			Spec: &commonmodels.JobTaskJenkinsSpec{
+				ID:      j.spec.ID,
+				Timeout: j.spec.Timeout,
+				Job:     jenkinsJob,
+			},
+			Timeout: j.spec.Timeout,
+		}
+		resp = append(resp, jobTask)
+	}
+	return resp, nil
+}
+
+// only the values of parameters defined in the workflow can be overridden by task args.
+func mergeJenkinsParameters(origin, input []*commonmodels.JenkinsJobParameter) []*commonmodels.JenkinsJobParameter {
+	inputMap := make(map[string]string, len(input))
+	for _, param := range input {
+		inputMap[param.Name] = param.Value
+	}
+	for _, param := range origin {
+		if value, ok := inputMap[param.Name]; ok {
+			param.Value = value
+		}
+	}
+	return origin
+}
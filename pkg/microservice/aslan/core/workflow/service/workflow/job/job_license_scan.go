@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+type LicenseScanJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.LicenseScanJobSpec
+}
+
+func (j *LicenseScanJob) Instantiate() error {
+	j.spec = &commonmodels.LicenseScanJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.Source == config.SourceFromJob && j.spec.JobName == "" {
+		return fmt.Errorf("license scan job %s should specify the build job to scan", j.job.Name)
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *LicenseScanJob) SetPreset() error {
+	j.spec = &commonmodels.LicenseScanJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *LicenseScanJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.LicenseScanJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.LicenseScanJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		if j.spec.Source == config.SourceRuntime {
+			j.spec.ServiceAndImages = argsSpec.ServiceAndImages
+		}
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *LicenseScanJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	logger := log.SugaredLogger()
+	resp := []*commonmodels.JobTask{}
+	j.spec = &commonmodels.LicenseScanJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return resp, err
+	}
+	j.job.Spec = j.spec
+
+	// scan the images built by the previous build job
+	if j.spec.Source == config.SourceFromJob {
+		j.spec.ServiceAndImages = []*commonmodels.ServiceAndImage{}
+		for _, stage := range j.workflow.Stages {
+			for _, job := range stage.Jobs {
+				if job.JobType != config.JobZadigBuild || job.Name != j.spec.JobName {
+					continue
+				}
+				buildSpec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, buildSpec); err != nil {
+					return resp, err
+				}
+				for _, build := range buildSpec.ServiceAndBuilds {
+					j.spec.ServiceAndImages = append(j.spec.ServiceAndImages, &commonmodels.ServiceAndImage{
+						ServiceName:   build.ServiceName,
+						ServiceModule: build.ServiceModule,
+						Image:         build.Image,
+					})
+				}
+			}
+		}
+	}
+	if len(j.spec.ServiceAndImages) == 0 {
+		return resp, fmt.Errorf("license scan job %s has no images to scan", j.job.Name)
+	}
+
+	registries, err := commonservice.ListRegistryNamespaces("", true, logger)
+	if err != nil {
+		return resp, err
+	}
+	resp = append(resp, &commonmodels.JobTask{
+		Name:    jobNameFormat(j.job.Name),
+		JobType: string(config.JobLicenseScan),
+		Spec: &commonmodels.JobTaskLicenseScanSpec{
+			Images:       j.spec.ServiceAndImages,
+			ScannerImage: j.spec.ScannerImage,
+			ClusterID:    j.spec.ClusterID,
+			Timeout:      j.spec.Timeout,
+			Registries:   registries,
+		},
+	})
+	return resp, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+type RolloutJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.ZadigRolloutJobSpec
+}
+
+func (j *RolloutJob) Instantiate() error {
+	j.spec = &commonmodels.ZadigRolloutJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if len(j.spec.Waves) == 0 {
+		return fmt.Errorf("rollout job %s should have at least one wave", j.job.Name)
+	}
+	envs := sets.NewString()
+	for _, wave := range j.spec.Waves {
+		if len(wave.Envs) == 0 {
+			return fmt.Errorf("wave %s of rollout job %s has no envs", wave.Name, j.job.Name)
+		}
+		for _, env := range wave.Envs {
+			if envs.Has(env) {
+				return fmt.Errorf("env %s is rolled out more than once in job %s", env, j.job.Name)
+			}
+			envs.Insert(env)
+		}
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *RolloutJob) SetPreset() error {
+	j.spec = &commonmodels.ZadigRolloutJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+// MergeArgs only takes the version, the waves are fixed by the workflow.
+func (j *RolloutJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.ZadigRolloutJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.ZadigRolloutJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		if argsSpec.Version != "" {
+			j.spec.Version = argsSpec.Version
+		}
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *RolloutJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	resp := []*commonmodels.JobTask{}
+	j.spec = &commonmodels.ZadigRolloutJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return resp, err
+	}
+	j.job.Spec = j.spec
+
+	images, err := getDeliveryVersionImages(j.workflow.Project, j.spec.Version)
+	if err != nil {
+		return resp, err
+	}
+	jobTaskSpec := &commonmodels.JobTaskZadigRolloutSpec{
+		Version:        j.spec.Version,
+		Images:         images,
+		PauseAfterWave: j.spec.PauseAfterWave,
+		Timeout:        j.spec.Timeout,
+	}
+	for _, wave := range j.spec.Waves {
+		waveTask := &commonmodels.RolloutWaveTask{Name: wave.Name, Envs: wave.Envs, Verification: wave.Verification, Status: config.StatusPrepare}
+		for _, envName := range wave.Envs {
+			env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: j.workflow.Project, EnvName: envName})
+			if err != nil {
+				return resp, fmt.Errorf("failed to find env %s of rollout job %s: %v", envName, j.job.Name, err)
+			}
+			if env.Source == setting.SourceFromHelm {
+				return resp, fmt.Errorf("helm env %s can not be rolled out by job %s", envName, j.job.Name)
+			}
+			for _, image := range images {
+				waveTask.Deploys = append(waveTask.Deploys, &commonmodels.RolloutDeployTask{
+					Env:           envName,
+					ServiceName:   image.ServiceName,
+					ServiceModule: image.ServiceModule,
+					Image:         image.Image,
+					Status:        config.StatusPrepare,
+				})
+			}
+		}
+		jobTaskSpec.Waves = append(jobTaskSpec.Waves, waveTask)
+	}
+
+	resp = append(resp, &commonmodels.JobTask{
+		Name:    jobNameFormat(j.job.Name),
+		JobType: string(config.JobZadigRollout),
+		Spec:    jobTaskSpec,
+	})
+	return resp, nil
+}
+
+// getDeliveryVersionImages returns the images of the services in a k8s yaml delivery version.
+func getDeliveryVersionImages(projectName, version string) ([]*commonmodels.ServiceAndImage, error) {
+	if version == "" {
+		return nil, fmt.Errorf("the delivery version to roll out is not specified")
+	}
+	deliveryVersion, err := commonrepo.NewDeliveryVersionColl().Get(&commonrepo.DeliveryVersionArgs{ProductName: projectName, Version: version})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find delivery version %s: %v", version, err)
+	}
+	if deliveryVersion.Type != setting.DeliveryVersionTypeK8SWorkflow {
+		return nil, fmt.Errorf("delivery version %s of type %s can not be rolled out", version, deliveryVersion.Type)
+	}
+	deploys, err := commonrepo.NewDeliveryDeployColl().Find(&commonrepo.DeliveryDeployArgs{ReleaseID: deliveryVersion.ID.Hex()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the images of delivery version %s: %v", version, err)
+	}
+
+	images := make([]*commonmodels.ServiceAndImage, 0, len(deploys))
+	for _, deploy := range deploys {
+		if deploy.Image == "" {
+			continue
+		}
+		images = append(images, &commonmodels.ServiceAndImage{ServiceName: deploy.ServiceName, ServiceModule: deploy.ContainerName, Image: deploy.Image})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("delivery version %s has no images", version)
+	}
+	return images, nil
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+type SandboxClusterJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.SandboxClusterJobSpec
+}
+
+func (j *SandboxClusterJob) Instantiate() error {
+	j.spec = &commonmodels.SandboxClusterJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if err := validateSandboxClusterJob(j.job.Name, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *SandboxClusterJob) SetPreset() error {
+	j.spec = &commonmodels.SandboxClusterJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *SandboxClusterJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.SandboxClusterJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.SandboxClusterJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		if j.spec.Source == config.SourceRuntime {
+			j.spec.ServiceAndImages = argsSpec.ServiceAndImages
+		}
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *SandboxClusterJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	logger := log.SugaredLogger()
+	resp := []*commonmodels.JobTask{}
+	j.spec = &commonmodels.SandboxClusterJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return resp, err
+	}
+	j.job.Spec = j.spec
+
+	project, err := templaterepo.NewProductColl().Find(j.workflow.Project)
+	if err != nil {
+		return resp, err
+	}
+	if project.ProductFeature != nil && project.ProductFeature.DeployType == setting.HelmDeployType {
+		return resp, fmt.Errorf("sandbox cluster job %s does not support the helm projects", j.job.Name)
+	}
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: j.workflow.Project, EnvName: j.spec.Env})
+	if err != nil {
+		return resp, fmt.Errorf("env %s not exists", j.spec.Env)
+	}
+
+	// deploy the images built by the previous build job
+	if j.spec.Source == config.SourceFromJob {
+		j.spec.ServiceAndImages = []*commonmodels.ServiceAndImage{}
+		for _, stage := range j.workflow.Stages {
+			for _, job := range stage.Jobs {
+				if job.JobType != config.JobZadigBuild || job.Name != j.spec.JobName {
+					continue
+				}
+				buildSpec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, buildSpec); err != nil {
+					return resp, err
+				}
+				for _, build := range buildSpec.ServiceAndBuilds {
+					j.spec.ServiceAndImages = append(j.spec.ServiceAndImages, &commonmodels.ServiceAndImage{
+						ServiceName:   build.ServiceName,
+						ServiceModule: build.ServiceModule,
+						Image:         build.Image,
+					})
+				}
+			}
+		}
+	}
+
+	manifests, err := renderSandboxManifests(env, j.spec.ServiceAndImages)
+	if err != nil {
+		return resp, err
+	}
+	registries, err := commonservice.ListRegistryNamespaces("", true, logger)
+	if err != nil {
+		return resp, err
+	}
+	resp = append(resp, &commonmodels.JobTask{
+		Name:    jobNameFormat(j.job.Name),
+		JobType: string(config.JobSandboxCluster),
+		Spec: &commonmodels.JobTaskSandboxClusterSpec{
+			Provider:          j.spec.Provider,
+			HostClusterID:     j.spec.HostClusterID,
+			HostNamespace:     j.spec.HostNamespace,
+			VClusterVersion:   j.spec.VClusterVersion,
+			VClusterValues:    j.spec.VClusterValues,
+			ClusterClass:      j.spec.ClusterClass,
+			KubernetesVersion: j.spec.KubernetesVersion,
+			WorkerReplicas:    j.spec.WorkerReplicas,
+			Env:               j.spec.Env,
+			Namespace:         env.Namespace,
+			Manifests:         manifests,
+			Suites:            j.spec.Suites,
+			Timeout:           j.spec.Timeout,
+			Registries:        registries,
+		},
+	})
+	return resp, nil
+}
+
+// renderSandboxManifests renders the services of the env, the images of the containers are replaced by the
+// images under test and the others are the images running in the env.
+func renderSandboxManifests(env *commonmodels.Product, images []*commonmodels.ServiceAndImage) ([]*commonmodels.SandboxManifest, error) {
+	logger := log.SugaredLogger()
+	imageMap := make(map[string]string, len(images))
+	for _, image := range images {
+		imageMap[image.ServiceName+"/"+image.ServiceModule] = image.Image
+	}
+
+	resp := make([]*commonmodels.SandboxManifest, 0)
+	for _, group := range env.Services {
+		for _, service := range group {
+			if service.Type != setting.K8SDeployType {
+				continue
+			}
+			containers := make([]*commonmodels.Container, 0, len(service.Containers))
+			for _, container := range service.Containers {
+				image := container.Image
+				if replaced, ok := imageMap[service.ServiceName+"/"+container.Name]; ok {
+					image = replaced
+				}
+				containers = append(containers, &commonmodels.Container{Name: container.Name, Image: image})
+			}
+			yaml, err := commonservice.GetServiceRenderYAML(env, containers, service.ServiceName, setting.K8SDeployType, logger)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render service %s of env %s: %s", service.ServiceName, env.EnvName, err)
+			}
+			resp = append(resp, &commonmodels.SandboxManifest{ServiceName: service.ServiceName, Yaml: yaml})
+		}
+	}
+	if len(resp) == 0 {
+		return nil, fmt.Errorf("env %s has no service to deploy", env.EnvName)
+	}
+	return resp, nil
+}
+
+func validateSandboxClusterJob(jobName string, spec *commonmodels.SandboxClusterJobSpec) error {
+	switch spec.Provider {
+	case config.SandboxProviderVCluster:
+	case config.SandboxProviderClusterAPI:
+		if spec.ClusterClass == "" || spec.KubernetesVersion == "" {
+			return fmt.Errorf("sandbox cluster job %s should specify the cluster class and the kubernetes version", jobName)
+		}
+	default:
+		return fmt.Errorf("sandbox cluster job %s has invalid provider: %s", jobName, spec.Provider)
+	}
+	if spec.Env == "" {
+		return fmt.Errorf("sandbox cluster job %s should specify the env to deploy", jobName)
+	}
+	if spec.Source == config.SourceFromJob && spec.JobName == "" {
+		return fmt.Errorf("sandbox cluster job %s should specify the build job of the images", jobName)
+	}
+	for _, suite := range spec.Suites {
+		if suite.Name == "" || suite.Image == "" || suite.Script == "" {
+			return fmt.Errorf("the test suites of sandbox cluster job %s should specify the name, image and script", jobName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	defaultJobDebugTimeout = 30
+	maxJobDebugTimeout     = 120
+)
+
+// setDebugOnFailure bounds the minutes the pods of the failed jobs of a run are kept alive.
+func setDebugOnFailure(debug *commonmodels.DebugOnFailure) {
+	if debug == nil || !debug.Enabled {
+		return
+	}
+	if debug.Timeout <= 0 {
+		debug.Timeout = defaultJobDebugTimeout
+	}
+	if debug.Timeout > maxJobDebugTimeout {
+		debug.Timeout = maxJobDebugTimeout
+	}
+}
+
+func getJobDebugSession(workflowName string, taskID int64, jobName string) (*commonmodels.WorkflowTask, *commonmodels.JobTask, error) {
+	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("find task %s-%d error: %v", workflowName, taskID, err)
+	}
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != jobName {
+				continue
+			}
+			if !job.Debug.Active() {
+				return nil, nil, fmt.Errorf("the pod of job %s is not kept for debugging or the debug session has ended", jobName)
+			}
+			return task, job, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("job %s not found in task %s-%d", jobName, workflowName, taskID)
+}
+
+// GetWorkflowTaskV4JobDebugSession returns the pod kept alive after the job fails.
+func GetWorkflowTaskV4JobDebugSession(workflowName string, taskID int64, jobName string) (*commonmodels.JobDebugSession, error) {
+	_, job, err := getJobDebugSession(workflowName, taskID, jobName)
+	if err != nil {
+		return nil, e.ErrDebugJob.AddErr(err)
+	}
+	return job.Debug, nil
+}
+
+// StopWorkflowTaskV4JobDebug deletes the pod kept for debugging before the debug session expires.
+func StopWorkflowTaskV4JobDebug(user, workflowName string, taskID int64, jobName string, logger *zap.SugaredLogger) error {
+	task, job, err := getJobDebugSession(workflowName, taskID, jobName)
+	if err != nil {
+		return e.ErrStopDebugJob.AddErr(err)
+	}
+	kubeCli, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), job.Debug.ClusterID)
+	if err != nil {
+		logger.Errorf("failed to get kube client of cluster %s: %v", job.Debug.ClusterID, err)
+		return e.ErrStopDebugJob.AddErr(err)
+	}
+	propagation := metav1.DeletePropagationBackground
+	err = kubeCli.BatchV1().Jobs(job.Debug.Namespace).Delete(context.TODO(), job.K8sJobName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Errorf("failed to delete job %s: %v", job.K8sJobName, err)
+		return e.ErrStopDebugJob.AddErr(err)
+	}
+
+	job.Debug.EndTime = time.Now().Unix()
+	job.Debug.EndedBy = user
+	if err := commonrepo.NewworkflowTaskv4Coll().Update(task.ID.Hex(), task); err != nil {
+		logger.Errorf("update task %s-%d error: %v", workflowName, taskID, err)
+		return e.ErrStopDebugJob.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing job debug", func() {
+
+	Context("setDebugOnFailure", func() {
+		It("should use the default timeout", func() {
+			debug := &commonmodels.DebugOnFailure{Enabled: true}
+			setDebugOnFailure(debug)
+			Expect(debug.Timeout).To(Equal(int64(defaultJobDebugTimeout)))
+		})
+		It("should not keep the pod longer than the max timeout", func() {
+			debug := &commonmodels.DebugOnFailure{Enabled: true, Timeout: 600}
+			setDebugOnFailure(debug)
+			Expect(debug.Timeout).To(Equal(int64(maxJobDebugTimeout)))
+		})
+	})
+})
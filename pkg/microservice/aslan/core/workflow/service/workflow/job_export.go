@@ -0,0 +1,264 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	exportWorkspace = "/workspace"
+	exportScriptDir = "/zadig/scripts"
+)
+
+type exportCompose struct {
+	Services map[string]*exportComposeService `yaml:"services"`
+}
+
+type exportComposeService struct {
+	Image      string   `yaml:"image"`
+	WorkingDir string   `yaml:"working_dir"`
+	EnvFile    []string `yaml:"env_file"`
+	Volumes    []string `yaml:"volumes"`
+	Command    []string `yaml:"command"`
+}
+
+// ExportJobContext exports a bundle to reproduce the job locally with docker compose: the resolved image,
+// the variables, the repos at the refs of the task and the scripts. The values of the credentials are
+// never exported, they are left empty in job.env for the developers to fill in.
+func ExportJobContext(workflowName, jobName string, taskID int64, logger *zap.SugaredLogger) ([]byte, string, error) {
+	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	if err != nil {
+		logger.Errorf("find workflowTaskV4 error: %s", err)
+		return nil, "", e.ErrExportJobContext.AddErr(err)
+	}
+	var jobTask *commonmodels.JobTask
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name == jobName {
+				jobTask = job
+			}
+		}
+	}
+	if jobTask == nil {
+		return nil, "", e.ErrExportJobContext.AddDesc(fmt.Sprintf("job %s is not found in task %d", jobName, taskID))
+	}
+	switch jobTask.JobType {
+	case string(config.JobZadigBuild), string(config.JobFreestyle), string(config.JobAndroidBuild), string(config.JobBuild):
+	default:
+		return nil, "", e.ErrExportJobContext.AddDesc(fmt.Sprintf("%s job can not be exported", jobTask.JobType))
+	}
+	spec := &commonmodels.JobTaskBuildSpec{}
+	if err := commonmodels.IToi(jobTask.Spec, spec); err != nil {
+		logger.Errorf("failed to decode job %s: %s", jobName, err)
+		return nil, "", e.ErrExportJobContext.AddErr(err)
+	}
+
+	files, err := buildJobExportFiles(spec, logger)
+	if err != nil {
+		return nil, "", e.ErrExportJobContext.AddErr(err)
+	}
+	bundle, err := tarGzFiles(files)
+	if err != nil {
+		return nil, "", e.ErrExportJobContext.AddErr(err)
+	}
+	return bundle, fmt.Sprintf("%s-%d-%s.tar.gz", workflowName, taskID, jobName), nil
+}
+
+func buildJobExportFiles(spec *commonmodels.JobTaskBuildSpec, logger *zap.SugaredLogger) (map[string]string, error) {
+	files := map[string]string{"job.env": exportEnvFile(spec.Properties.Envs)}
+
+	run := []string{
+		"#!/bin/bash",
+		"# runs the steps of the job in order, the steps not reproducible locally are skipped.",
+		"set -e",
+		"cd " + exportWorkspace,
+	}
+	for i, stepTask := range spec.Steps {
+		switch stepTask.StepType {
+		case config.StepGit:
+			stepSpec := &step.StepGitSpec{}
+			if err := commonmodels.IToiYaml(stepTask.Spec, stepSpec); err != nil {
+				return nil, err
+			}
+			script := fmt.Sprintf("%02d-%s.sh", i, stepTask.Name)
+			files["scripts/"+script] = exportCloneScript(stepSpec.Repos, logger)
+			run = append(run, fmt.Sprintf("bash %s/%s", exportScriptDir, script))
+		case config.StepShell:
+			stepSpec := &step.StepShellSpec{}
+			if err := commonmodels.IToiYaml(stepTask.Spec, stepSpec); err != nil {
+				return nil, err
+			}
+			content := stepSpec.Script
+			if content == "" {
+				content = strings.Join(stepSpec.Scripts, "\n")
+			}
+			script := fmt.Sprintf("%02d-%s.sh", i, stepTask.Name)
+			files["scripts/"+script] = "#!/bin/bash\nset -e\n" + content + "\n"
+			run = append(run, fmt.Sprintf("(cd %s && bash %s/%s)", exportWorkspace, exportScriptDir, script))
+		default:
+			run = append(run, fmt.Sprintf("# skip step %s of type %s", stepTask.Name, stepTask.StepType))
+		}
+	}
+	files["scripts/run.sh"] = strings.Join(run, "\n") + "\n"
+
+	compose := &exportCompose{Services: map[string]*exportComposeService{
+		"job": {
+			Image:      jobcontroller.GetBaseImage(spec.Properties.BuildOS, spec.Properties.ImageFrom),
+			WorkingDir: exportWorkspace,
+			EnvFile:    []string{"job.env"},
+			Volumes: []string{
+				"./workspace:" + exportWorkspace,
+				"./scripts:" + exportScriptDir + ":ro",
+				"/var/run/docker.sock:/var/run/docker.sock",
+			},
+			Command: []string{"bash", exportScriptDir + "/run.sh"},
+		},
+	}}
+	composeBytes, err := yaml.Marshal(compose)
+	if err != nil {
+		return nil, err
+	}
+	files["docker-compose.yaml"] = string(composeBytes)
+	return files, nil
+}
+
+func exportEnvFile(envs []*commonmodels.KeyVal) string {
+	lines := []string{"# the variables of the job, the credentials are left empty and should be filled in before running."}
+	keys := make([]string, 0, len(envs))
+	values := make(map[string]*commonmodels.KeyVal, len(envs))
+	for _, env := range envs {
+		if _, ok := values[env.Key]; !ok {
+			keys = append(keys, env.Key)
+		}
+		values[env.Key] = env
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env := values[key]
+		switch {
+		case key == "WORKSPACE":
+			lines = append(lines, "WORKSPACE="+exportWorkspace)
+		case env.IsCredential:
+			lines = append(lines, fmt.Sprintf("# %s is a credential", key), key+"=")
+		default:
+			// the env file does not support multiline values.
+			lines = append(lines, key+"="+strings.ReplaceAll(env.Value, "\n", "\\n"))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// exportCloneScript clones the repos at the refs of the task with the git credentials of the developer,
+// the tokens of the codehosts are never exported.
+func exportCloneScript(repos []*types.Repository, logger *zap.SugaredLogger) string {
+	lines := []string{"#!/bin/bash", "set -e"}
+	for _, repo := range repos {
+		address := repo.Address
+		if address == "" && repo.CodehostID > 0 {
+			codeHost, err := systemconfig.New().GetCodeHost(repo.CodehostID)
+			if err != nil {
+				logger.Warnf("failed to get codehost %d, err: %s", repo.CodehostID, err)
+			} else {
+				address = codeHost.Address
+			}
+		}
+		if address == "" {
+			lines = append(lines, fmt.Sprintf("# skip repo %s/%s, its address is unknown", repo.GetRepoNamespace(), repo.RepoName))
+			continue
+		}
+		url := fmt.Sprintf("%s/%s/%s.git", strings.TrimSuffix(address, "/"), repo.GetRepoNamespace(), repo.RepoName)
+		if strings.HasPrefix(address, "git@") {
+			url = fmt.Sprintf("%s:%s/%s.git", address, repo.GetRepoNamespace(), repo.RepoName)
+		}
+		dir := repo.RepoName
+		if repo.CheckoutPath != "" {
+			dir = repo.CheckoutPath
+		}
+		dir = fmt.Sprintf("%s/%s", exportWorkspace, dir)
+
+		lines = append(lines, fmt.Sprintf("[ -d %q ] || git clone %q %q", dir, url, dir))
+		switch {
+		case repo.PR > 0 && repo.Source == types.ProviderGithub:
+			lines = append(lines, fmt.Sprintf("git -C %q fetch origin pull/%d/head", dir, repo.PR))
+		case repo.PR > 0 && repo.Source == types.ProviderGitlab:
+			lines = append(lines, fmt.Sprintf("git -C %q fetch origin merge-requests/%d/head", dir, repo.PR))
+		}
+		switch {
+		case repo.CommitID != "":
+			lines = append(lines, fmt.Sprintf("git -C %q checkout %s", dir, repo.CommitID))
+		case repo.Tag != "":
+			lines = append(lines, fmt.Sprintf("git -C %q checkout tags/%s", dir, repo.Tag))
+		case repo.Branch != "":
+			lines = append(lines, fmt.Sprintf("git -C %q checkout %s", dir, repo.Branch))
+		}
+		if repo.SubModules {
+			lines = append(lines, fmt.Sprintf("git -C %q submodule update --init --recursive", dir))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func tarGzFiles(files map[string]string) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+	for _, name := range names {
+		mode := int64(0644)
+		if strings.HasSuffix(name, ".sh") {
+			mode = 0755
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(files[name])), ModTime: now}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing job export", func() {
+
+	Context("exportEnvFile", func() {
+		It("should leave the credentials empty", func() {
+			content := exportEnvFile([]*commonmodels.KeyVal{
+				{Key: "TOKEN", Value: "secret", IsCredential: true},
+				{Key: "VERSION", Value: "v1"},
+			})
+			Expect(content).ShouldNot(ContainSubstring("secret"))
+			Expect(content).Should(ContainSubstring("TOKEN=\n"))
+			Expect(content).Should(ContainSubstring("VERSION=v1\n"))
+		})
+		It("should point the workspace to the mounted directory", func() {
+			content := exportEnvFile([]*commonmodels.KeyVal{{Key: "WORKSPACE", Value: "/workspace/abc"}})
+			Expect(content).Should(ContainSubstring("WORKSPACE=" + exportWorkspace + "\n"))
+		})
+	})
+})
@@ -228,13 +228,13 @@ func getRawFileContent(codehostID int, repo, owner, branch, filePath string) ([]
 	}
 	switch ch.Type {
 	case setting.SourceFromGitlab:
-		cli, err := gitlab.NewClient(ch.ID, ch.Address, ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+		cli, err := gitlab.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed to get gitlab client")
 		}
 		return cli.GetRawFile(repo, owner, branch, filePath)
 	case setting.SourceFromGithub:
-		gitClient := git.NewClient(ch.AccessToken, config.ProxyHTTPSAddr(), ch.EnableProxy)
+		gitClient := git.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
 		return gitClient.GetFileContent(owner, repo, filePath, branch)
 	default:
 		return nil, fmt.Errorf("Failed to create client for codehostID: %d", codehostID)
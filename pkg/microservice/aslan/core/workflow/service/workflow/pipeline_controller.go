@@ -31,6 +31,7 @@ import (
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	nsqservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/nsq"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	"github.com/koderover/zadig/pkg/setting"
 	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
@@ -215,6 +216,9 @@ type CancelMessage struct {
 
 // CreateTask 接受create task请求, 保存task到数据库, 发送task到queue
 func CreateTask(t *task.Task) error {
+	if err := workflowcontroller.CheckMaintenance(); err != nil {
+		return err
+	}
 	if err := commonrepo.NewTaskColl().Create(t); err != nil {
 		log.Errorf("create PipelineTaskV2 error: %v", err)
 		return err
@@ -224,6 +228,9 @@ func CreateTask(t *task.Task) error {
 }
 
 func UpdateTask(t *task.Task) error {
+	if err := workflowcontroller.CheckMaintenance(); err != nil {
+		return err
+	}
 	if err := commonrepo.NewTaskColl().Update(t); err != nil {
 		log.Errorf("create PipelineTaskV2 error: %v", err)
 		return err
@@ -289,6 +296,10 @@ func InitQueue() error {
 func PipelineTaskSender() {
 	for {
 		time.Sleep(time.Second * 3)
+		// the waiting tasks are kept in the queue until the maintenance ends.
+		if workflowcontroller.InMaintenance() {
+			continue
+		}
 
 		//c.checkAgents()
 		if hasAgentAvaiable() {
@@ -191,7 +191,7 @@ func FindTasks(commitID string, log *zap.SugaredLogger) ([]*TaskV2Info, error) {
 						keys.Insert(key)
 						resp = append(resp, &TaskV2Info{
 							TaskID:     workflowTask.TaskID,
-							URL:        fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/multi/%s/%d", configbase.SystemAddress(), workflowTask.ProductName, workflowTask.PipelineName, workflowTask.TaskID),
+							URL:        fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/multi/%s/%d", configbase.ExternalBaseURL(), workflowTask.ProductName, workflowTask.PipelineName, workflowTask.TaskID),
 							Status:     string(workflowTask.Status),
 							CreateTime: workflowTask.CreateTime,
 							StartTime:  workflowTask.StartTime,
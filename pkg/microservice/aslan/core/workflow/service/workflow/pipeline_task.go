@@ -39,6 +39,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	e "github.com/koderover/zadig/pkg/tool/errors"
@@ -49,6 +50,10 @@ import (
 )
 
 func CreatePipelineTask(args *commonmodels.TaskArgs, log *zap.SugaredLogger) (*CreateTaskResp, error) {
+	if err := workflowcontroller.CheckMaintenance(); err != nil {
+		return nil, err
+	}
+
 	pipeline, err := commonrepo.NewPipelineColl().Find(&commonrepo.PipelineFindOption{Name: args.PipelineName})
 	if err != nil {
 		log.Errorf("PipelineV2.Find %s error: %v", args.PipelineName, err)
@@ -705,8 +710,11 @@ func TestArgsToTestSubtask(args *commonmodels.TestTaskArgs, pt *task.Task, log *
 				}
 			}
 		}
-		envs = append(envs, &commonmodels.KeyVal{Key: "TEST_URL", Value: GetLink(pt, configbase.SystemAddress(), config.TestType)})
+		envs = append(envs, &commonmodels.KeyVal{Key: "TEST_URL", Value: GetLink(pt, configbase.ExternalBaseURL(), config.TestType)})
 		envs = append(envs, &commonmodels.KeyVal{Key: "WORKSPACE", Value: "/workspace"})
+		if args.EnvName != "" {
+			envs = append(envs, &commonmodels.KeyVal{Key: "ENV_NAME", Value: args.EnvName})
+		}
 		testTask.JobCtx.EnvVars = envs
 		testTask.ImageID = testModule.PreTest.ImageID
 		testTask.BuildOS = testModule.PreTest.BuildOS
@@ -775,17 +783,18 @@ func GetTesting(name, productName string, log *zap.SugaredLogger) (*commonmodels
 
 		for _, v := range schedules {
 			scheduleList = append(scheduleList, &commonmodels.Schedule{
-				ID:           v.ID,
-				Number:       v.Number,
-				Frequency:    v.Frequency,
-				Time:         v.Time,
-				MaxFailures:  v.MaxFailure,
-				TaskArgs:     v.TaskArgs,
-				WorkflowArgs: v.WorkflowArgs,
-				TestArgs:     v.TestArgs,
-				Type:         config.ScheduleType(v.JobType),
-				Cron:         v.Cron,
-				Enabled:      v.Enabled,
+				ID:            v.ID,
+				Number:        v.Number,
+				Frequency:     v.Frequency,
+				Time:          v.Time,
+				MaxFailures:   v.MaxFailure,
+				TaskArgs:      v.TaskArgs,
+				WorkflowArgs:  v.WorkflowArgs,
+				TestArgs:      v.TestArgs,
+				Type:          config.ScheduleType(v.JobType),
+				Cron:          v.Cron,
+				CatchUpPolicy: v.CatchUpPolicy,
+				Enabled:       v.Enabled,
 			})
 		}
 		schedule := commonmodels.ScheduleCtrl{
@@ -41,6 +41,7 @@ import (
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/bitbucketserver"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/gitee"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
@@ -258,9 +259,14 @@ func FmtBuilds(builds []*types.Repository, log *zap.SugaredLogger) {
 			log.Error(err)
 			return
 		}
+		// the credentials are not filled in so that the repo can not be checked out
+		if !detail.RepoPolicy.RepoAllowed(repo.GetRepoNamespace(), repo.RepoName) {
+			log.Errorf("repo %s/%s is not allowed by the policy of codehost %d", repo.GetRepoNamespace(), repo.RepoName, cID)
+			continue
+		}
 		repo.Source = detail.Type
 		repo.OauthToken = detail.AccessToken
-		repo.Address = detail.Address
+		repo.Address = detail.RepoAddress()
 		repo.Username = detail.Username
 		repo.Password = detail.Password
 	}
@@ -339,7 +345,7 @@ func setBuildInfo(build *types.Repository, buildArgs []*types.Repository, log *z
 			build.AuthorName = commit.AuthorName
 		}
 	} else if codeHostInfo.Type == systemconfig.CodeHubProvider {
-		codeHubClient := codehub.NewClient(codeHostInfo.AccessKey, codeHostInfo.SecretKey, codeHostInfo.Region, config.ProxyHTTPSAddr(), codeHostInfo.EnableProxy)
+		codeHubClient := codehub.NewClient(codeHostInfo.AccessKey, codeHostInfo.SecretKey, codeHostInfo.Region, codeHostInfo.ProxyAddr(config.ProxyHTTPSAddr()), codeHostInfo.UseProxy())
 		if build.CommitID == "" && build.Branch != "" {
 			branchList, _ := codeHubClient.BranchList(build.RepoUUID)
 			for _, branchInfo := range branchList {
@@ -352,7 +358,7 @@ func setBuildInfo(build *types.Repository, buildArgs []*types.Repository, log *z
 			}
 		}
 	} else if codeHostInfo.Type == systemconfig.GiteeProvider {
-		gitCli := gitee.NewClient(codeHostInfo.ID, codeHostInfo.AccessToken, config.ProxyHTTPSAddr(), codeHostInfo.EnableProxy)
+		gitCli := gitee.NewClient(codeHostInfo.ID, codeHostInfo.AccessToken, codeHostInfo.ProxyAddr(config.ProxyHTTPSAddr()), codeHostInfo.UseProxy())
 		if build.CommitID == "" {
 			if build.Tag != "" && build.PR == 0 {
 				tags, err := gitCli.ListTags(context.Background(), codeHostInfo.AccessToken, build.RepoOwner, build.RepoName)
@@ -399,7 +405,7 @@ func setBuildInfo(build *types.Repository, buildArgs []*types.Repository, log *z
 			}
 		}
 	} else if codeHostInfo.Type == systemconfig.GitHubProvider {
-		gitCli := git.NewClient(codeHostInfo.AccessToken, config.ProxyHTTPSAddr(), codeHostInfo.EnableProxy)
+		gitCli := git.NewClient(codeHostInfo.AccessToken, codeHostInfo.ProxyAddr(config.ProxyHTTPSAddr()), codeHostInfo.UseProxy())
 		if build.CommitID == "" {
 			if build.Tag != "" && build.PR == 0 {
 				opt := &github.ListOptions{Page: 1, PerPage: 100}
@@ -449,6 +455,29 @@ func setBuildInfo(build *types.Repository, buildArgs []*types.Repository, log *z
 				return
 			}
 		}
+	} else if codeHostInfo.Type == systemconfig.BitbucketServerProvider {
+		if build.CommitID == "" {
+			cli := bitbucketserver.NewClient(codeHostInfo.Address, codeHostInfo.AccessToken, codeHostInfo.ProxyAddr(config.ProxyHTTPSAddr()), codeHostInfo.UseProxy())
+			commitID, err := getBitbucketServerLatestCommit(cli, build)
+			if err != nil {
+				log.Errorf("failed to get latest commit of bitbucket server repo %s/%s, err: %s", build.GetRepoNamespace(), build.RepoName, err)
+				return
+			}
+			if commitID == "" {
+				log.Warnf("bitbucket server setBuildInfo failed, use build %+v", build)
+				return
+			}
+			commit, err := cli.GetCommit(build.GetRepoNamespace(), build.RepoName, commitID)
+			if err != nil {
+				log.Errorf("failed to bitbucket server GetCommit %s err:%s", commitID, err)
+				return
+			}
+			build.CommitID = commit.ID
+			build.CommitMessage = commit.Message
+			if commit.Author != nil {
+				build.AuthorName = commit.Author.Name
+			}
+		}
 	} else if codeHostInfo.Type == systemconfig.OtherProvider {
 		build.SSHKey = codeHostInfo.SSHKey
 		build.PrivateAccessToken = codeHostInfo.PrivateAccessToken
@@ -462,6 +491,38 @@ func setBuildInfo(build *types.Repository, buildArgs []*types.Repository, log *z
 	}
 }
 
+func getBitbucketServerLatestCommit(cli *bitbucketserver.Client, build *types.Repository) (string, error) {
+	if build.PR > 0 {
+		pr, err := cli.GetPullRequest(build.GetRepoNamespace(), build.RepoName, build.PR)
+		if err != nil {
+			return "", err
+		}
+		return pr.FromRef.LatestCommit, nil
+	}
+	if build.Tag != "" {
+		tags, err := cli.ListTags(build.GetRepoNamespace(), build.RepoName, build.Tag)
+		if err != nil {
+			return "", err
+		}
+		for _, tag := range tags {
+			if tag.DisplayID == build.Tag {
+				return tag.LatestCommit, nil
+			}
+		}
+		return "", nil
+	}
+	branches, err := cli.ListBranches(build.GetRepoNamespace(), build.RepoName, build.Branch)
+	if err != nil {
+		return "", err
+	}
+	for _, branch := range branches {
+		if branch.DisplayID == build.Branch {
+			return branch.LatestCommit, nil
+		}
+	}
+	return "", nil
+}
+
 // 根据传入的build arg设置build参数
 func setBuildFromArg(build, buildArg *types.Repository) {
 	// 单pr编译
@@ -540,7 +601,7 @@ func prepareTaskEnvs(pt *task.Task, log *zap.SugaredLogger) []*commonmodels.KeyV
 	// 设置系统信息
 	envs = append(envs,
 		&commonmodels.KeyVal{Key: "SERVICE", Value: pt.ServiceName},
-		&commonmodels.KeyVal{Key: "BUILD_URL", Value: GetLink(pt, configbase.SystemAddress(), config.WorkflowType)},
+		&commonmodels.KeyVal{Key: "BUILD_URL", Value: GetLink(pt, configbase.ExternalBaseURL(), config.WorkflowType)},
 		&commonmodels.KeyVal{Key: "DIST_DIR", Value: fmt.Sprintf("%s/%s/dist/%d", pt.ConfigPayload.S3Storage.Path, pt.PipelineName, pt.TaskID)},
 		&commonmodels.KeyVal{Key: "IMAGE", Value: pt.TaskArgs.Deploy.Image},
 		&commonmodels.KeyVal{Key: "PKG_FILE", Value: pt.TaskArgs.Deploy.PackageFile},
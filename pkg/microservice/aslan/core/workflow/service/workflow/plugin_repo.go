@@ -30,6 +30,8 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/command"
+	marketplaceconfig "github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/config"
+	marketplacerepo "github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/repository/mongodb"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 )
@@ -188,5 +190,25 @@ func ListPluginTemplates(log *zap.SugaredLogger) ([]*commonmodels.PluginTemplate
 			resp = append(resp, template)
 		}
 	}
+
+	// plugins installed from the marketplace.
+	installations, err := marketplacerepo.NewMarketplaceInstallationColl().List("", marketplaceconfig.ItemKindPlugin)
+	if err != nil {
+		log.Errorf("list marketplace plugins error: %v", err)
+		return resp, e.ErrListPluginRepo.AddDesc(err.Error())
+	}
+	for _, installation := range installations {
+		template := &commonmodels.PluginTemplate{}
+		if err := yaml.Unmarshal([]byte(installation.Content), template); err != nil {
+			log.Errorf("unmarshal marketplace plugin %s error: %v", installation.Name, err)
+			continue
+		}
+		template.RepoURL = "marketplace"
+		template.Version = installation.Version
+		for _, input := range template.Inputs {
+			input.Value = input.Default
+		}
+		resp = append(resp, template)
+	}
 	return resp, nil
 }
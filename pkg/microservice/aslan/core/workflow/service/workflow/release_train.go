@@ -0,0 +1,419 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/nsq"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const releaseTrainItemLimit = 100
+
+type DispatchReleaseTrainResp struct {
+	Version      string `json:"version"`
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+	Items        int    `json:"items"`
+}
+
+func ListReleaseTrains(projectName string, log *zap.SugaredLogger) ([]*commonmodels.ReleaseTrain, error) {
+	trains, err := commonrepo.NewReleaseTrainColl().List(projectName)
+	if err != nil {
+		log.Errorf("failed to list release trains of project %s, err: %s", projectName, err)
+		return nil, e.ErrListReleaseTrain.AddErr(err)
+	}
+	for _, train := range trains {
+		schedules, err := listReleaseTrainSchedules(train)
+		if err != nil {
+			log.Errorf("failed to list schedules of release train %s, err: %s", train.Name, err)
+			return nil, e.ErrListReleaseTrain.AddErr(err)
+		}
+		train.Schedules = schedules
+	}
+	return trains, nil
+}
+
+func CreateReleaseTrain(userName string, args *commonmodels.ReleaseTrain, log *zap.SugaredLogger) error {
+	if err := validateReleaseTrain(args); err != nil {
+		return e.ErrCreateReleaseTrain.AddErr(err)
+	}
+	if _, err := commonrepo.NewReleaseTrainColl().Find(args.ProjectName, args.Name); err == nil {
+		return e.ErrCreateReleaseTrain.AddDesc(fmt.Sprintf("release train %s already exists", args.Name))
+	}
+
+	schedules := args.Schedules
+	args.ID = primitive.NilObjectID
+	args.CreatedBy = userName
+	args.UpdatedBy = userName
+	args.LastVersion = ""
+	args.LastDispatchTime = 0
+	if err := commonrepo.NewReleaseTrainColl().Create(args); err != nil {
+		log.Errorf("failed to create release train %s, err: %s", args.Name, err)
+		return e.ErrCreateReleaseTrain.AddErr(err)
+	}
+	return handleReleaseTrainCronjob(args, schedules, log)
+}
+
+func UpdateReleaseTrain(userName, id string, args *commonmodels.ReleaseTrain, log *zap.SugaredLogger) error {
+	train, err := findReleaseTrain(id, args.ProjectName)
+	if err != nil {
+		return e.ErrUpdateReleaseTrain.AddErr(err)
+	}
+	args.Name = train.Name
+	if err := validateReleaseTrain(args); err != nil {
+		return e.ErrUpdateReleaseTrain.AddErr(err)
+	}
+
+	schedules := args.Schedules
+	args.ID = train.ID
+	args.UpdatedBy = userName
+	if err := commonrepo.NewReleaseTrainColl().Update(args); err != nil {
+		log.Errorf("failed to update release train %s, err: %s", train.Name, err)
+		return e.ErrUpdateReleaseTrain.AddErr(err)
+	}
+	return handleReleaseTrainCronjob(args, schedules, log)
+}
+
+// DeleteReleaseTrain deletes the train and the items waiting on it, the delivery versions it created are kept.
+func DeleteReleaseTrain(id, projectName string, log *zap.SugaredLogger) error {
+	train, err := findReleaseTrain(id, projectName)
+	if err != nil {
+		return e.ErrDeleteReleaseTrain.AddErr(err)
+	}
+
+	if err := DeleteCronjob(train.ID.Hex(), config.ReleaseTrainCronjob); err != nil {
+		log.Errorf("failed to delete cronjobs of release train %s, err: %s", train.Name, err)
+		return e.ErrDeleteReleaseTrain.AddErr(err)
+	}
+	pl, _ := json.Marshal(&commonservice.CronjobPayload{
+		Name:        train.ID.Hex(),
+		ProductName: train.ProjectName,
+		JobType:     config.ReleaseTrainCronjob,
+		Action:      setting.TypeDisableCronjob,
+	})
+	if err := nsq.Publish(setting.TopicCronjob, pl); err != nil {
+		log.Errorf("Failed to publish to nsq topic: %s, the error is: %v", setting.TopicCronjob, err)
+		return e.ErrDeleteReleaseTrain.AddErr(err)
+	}
+
+	if err := commonrepo.NewReleaseTrainColl().Delete(train.ID); err != nil {
+		log.Errorf("failed to delete release train %s, err: %s", train.Name, err)
+		return e.ErrDeleteReleaseTrain.AddErr(err)
+	}
+	if err := commonrepo.NewReleaseTrainItemColl().DeleteByTrainID(train.ID.Hex()); err != nil {
+		log.Warnf("failed to delete items of release train %s, err: %s", train.Name, err)
+	}
+	return nil
+}
+
+// ListReleaseTrainItems returns the items waiting on the train, or the items shipped in the version.
+func ListReleaseTrainItems(id, projectName, version string, log *zap.SugaredLogger) ([]*commonmodels.ReleaseTrainItem, error) {
+	train, err := findReleaseTrain(id, projectName)
+	if err != nil {
+		return nil, e.ErrListReleaseTrain.AddErr(err)
+	}
+	opt := &commonrepo.ListReleaseTrainItemOption{TrainID: train.ID.Hex(), Status: config.ReleaseTrainItemPending, Limit: releaseTrainItemLimit}
+	if version != "" {
+		opt.Status = config.ReleaseTrainItemShipped
+		opt.Version = version
+	}
+	items, err := commonrepo.NewReleaseTrainItemColl().List(opt)
+	if err != nil {
+		log.Errorf("failed to list items of release train %s, err: %s", train.Name, err)
+		return nil, e.ErrListReleaseTrain.AddErr(err)
+	}
+	return items, nil
+}
+
+// DispatchReleaseTrain ships the pending items of the train together, the latest images of the services
+// are deployed by a task of the deploy workflow and recorded on a new delivery version with the merge
+// requests and commits of the items. A scheduled dispatch of an empty train does nothing.
+func DispatchReleaseTrain(id, projectName, triggeredBy string, log *zap.SugaredLogger) (*DispatchReleaseTrainResp, error) {
+	train, err := findReleaseTrain(id, projectName)
+	if err != nil {
+		return nil, e.ErrDispatchReleaseTrain.AddErr(err)
+	}
+	pending, err := commonrepo.NewReleaseTrainItemColl().List(&commonrepo.ListReleaseTrainItemOption{TrainID: train.ID.Hex(), Status: config.ReleaseTrainItemPending})
+	if err != nil {
+		log.Errorf("failed to list items of release train %s, err: %s", train.Name, err)
+		return nil, e.ErrDispatchReleaseTrain.AddErr(err)
+	}
+	if len(pending) == 0 {
+		if triggeredBy == setting.CronTaskCreator {
+			log.Infof("release train %s has no pending items, skip the scheduled dispatch", train.Name)
+			return nil, nil
+		}
+		return nil, e.ErrDispatchReleaseTrain.AddDesc(fmt.Sprintf("release train %s has no pending items", train.Name))
+	}
+
+	version := fmt.Sprintf("%s-%s", train.Name, time.Now().Format("20060102150405"))
+	if _, err := commonrepo.NewDeliveryVersionColl().Get(&commonrepo.DeliveryVersionArgs{ProductName: projectName, Version: version}); err == nil {
+		return nil, e.ErrDispatchReleaseTrain.AddDesc(fmt.Sprintf("delivery version %s already exists", version))
+	}
+	ids := make([]primitive.ObjectID, 0, len(pending))
+	for _, item := range pending {
+		ids = append(ids, item.ID)
+	}
+	// the items are shipped before the task is created, so that a concurrent dispatch can not ship them again.
+	if err := commonrepo.NewReleaseTrainItemColl().Ship(ids, version); err != nil {
+		log.Errorf("failed to ship items of release train %s, err: %s", train.Name, err)
+		return nil, e.ErrDispatchReleaseTrain.AddErr(err)
+	}
+	items, err := commonrepo.NewReleaseTrainItemColl().List(&commonrepo.ListReleaseTrainItemOption{TrainID: train.ID.Hex(), Version: version})
+	if err != nil || len(items) == 0 {
+		unshipReleaseTrainItems(train, version, log)
+		return nil, e.ErrDispatchReleaseTrain.AddDesc(fmt.Sprintf("release train %s has no pending items", train.Name))
+	}
+
+	content := getReleaseTrainContent(train, items)
+	workflow, err := getReleaseTrainWorkflow(train, content.Artifacts)
+	if err != nil {
+		unshipReleaseTrainItems(train, version, log)
+		return nil, e.ErrDispatchReleaseTrain.AddErr(err)
+	}
+	task, err := CreateWorkflowTaskV4(triggeredBy, workflow, log)
+	if err != nil {
+		log.Errorf("failed to create task of workflow %s for release train %s, err: %s", workflow.Name, train.Name, err)
+		unshipReleaseTrainItems(train, version, log)
+		return nil, e.ErrDispatchReleaseTrain.AddErr(err)
+	}
+
+	deliveryVersion := &commonmodels.DeliveryVersion{
+		Version:      version,
+		ProductName:  projectName,
+		WorkflowName: workflow.Name,
+		Type:         setting.DeliveryVersionTypeK8SWorkflow,
+		TaskID:       int(task.TaskID),
+		Desc:         fmt.Sprintf("release train %s ships %d changes", train.Name, len(items)),
+		Status:       setting.DeliveryVersionStatusSuccess,
+		CreatedBy:    triggeredBy,
+		CreatedAt:    time.Now().Unix(),
+		ReleaseTrain: content,
+	}
+	if err := commonservice.InsertDeliveryVersion(deliveryVersion, log); err != nil {
+		return nil, e.ErrDispatchReleaseTrain.AddErr(err)
+	}
+	for _, artifact := range content.Artifacts {
+		deploy := &commonmodels.DeliveryDeploy{
+			ReleaseID:     deliveryVersion.ID,
+			ServiceName:   artifact.ServiceName,
+			ContainerName: artifact.ServiceModule,
+			Image:         artifact.Image,
+			CreatedAt:     time.Now().Unix(),
+		}
+		if err := commonrepo.NewDeliveryDeployColl().Insert(deploy); err != nil {
+			log.Errorf("failed to insert image of service %s into delivery version %s, err: %s", artifact.ServiceName, version, err)
+			return nil, e.ErrDispatchReleaseTrain.AddErr(err)
+		}
+	}
+	if err := commonrepo.NewReleaseTrainColl().UpdateDispatch(train.ID, version, deliveryVersion.CreatedAt); err != nil {
+		log.Errorf("failed to update dispatch of release train %s, err: %s", train.Name, err)
+	}
+
+	return &DispatchReleaseTrainResp{
+		Version:      version,
+		WorkflowName: workflow.Name,
+		TaskID:       task.TaskID,
+		Items:        len(items),
+	}, nil
+}
+
+func unshipReleaseTrainItems(train *commonmodels.ReleaseTrain, version string, log *zap.SugaredLogger) {
+	if err := commonrepo.NewReleaseTrainItemColl().Unship(train.ID.Hex(), version); err != nil {
+		log.Errorf("failed to put items of version %s back on release train %s, err: %s", version, train.Name, err)
+	}
+}
+
+// getReleaseTrainContent merges the items in the order they boarded the train, the latest image of a
+// service module replaces the former ones.
+func getReleaseTrainContent(train *commonmodels.ReleaseTrain, items []*commonmodels.ReleaseTrainItem) *commonmodels.ReleaseTrainContent {
+	content := &commonmodels.ReleaseTrainContent{
+		TrainID:       train.ID.Hex(),
+		TrainName:     train.Name,
+		Tasks:         make([]*commonmodels.ReleaseTrainTask, 0, len(items)),
+		MergeRequests: make([]string, 0),
+		Commits:       make([]*commonmodels.ReleaseTrainCommit, 0),
+		Artifacts:     make([]*commonmodels.ServiceAndImage, 0),
+	}
+	mergeRequests := make(map[string]bool)
+	commits := make(map[string]bool)
+	artifacts := make(map[string]int)
+	addMergeRequest := func(id string) {
+		if id == "" || id == "0" || mergeRequests[id] {
+			return
+		}
+		mergeRequests[id] = true
+		content.MergeRequests = append(content.MergeRequests, id)
+	}
+
+	for _, item := range items {
+		content.Tasks = append(content.Tasks, &commonmodels.ReleaseTrainTask{WorkflowName: item.WorkflowName, TaskID: item.TaskID})
+		addMergeRequest(item.MergeRequestID)
+		for _, commit := range item.Commits {
+			addMergeRequest(strconv.Itoa(commit.PR))
+			key := fmt.Sprintf("%s/%s@%s", commit.RepoOwner, commit.RepoName, commit.CommitID)
+			if commit.CommitID == "" || commits[key] {
+				continue
+			}
+			commits[key] = true
+			content.Commits = append(content.Commits, commit)
+		}
+		for _, artifact := range item.Artifacts {
+			key := fmt.Sprintf("%s/%s", artifact.ServiceName, artifact.ServiceModule)
+			if i, ok := artifacts[key]; ok {
+				content.Artifacts[i] = artifact
+				continue
+			}
+			artifacts[key] = len(content.Artifacts)
+			content.Artifacts = append(content.Artifacts, artifact)
+		}
+	}
+	return content
+}
+
+// getReleaseTrainWorkflow returns the deploy workflow whose deploy job deploys the images at runtime.
+func getReleaseTrainWorkflow(train *commonmodels.ReleaseTrain, images []*commonmodels.ServiceAndImage) (*commonmodels.WorkflowV4, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(train.DeployWorkflow)
+	if err != nil || workflow.Project != train.ProjectName {
+		return nil, fmt.Errorf("deploy workflow %s not found", train.DeployWorkflow)
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != train.DeployJobName || job.JobType != config.JobZadigDeploy {
+				continue
+			}
+			spec := &commonmodels.ZadigDeployJobSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return nil, err
+			}
+			spec.Source = config.SourceRuntime
+			spec.JobName = ""
+			spec.ServiceAndImages = images
+			job.Spec = spec
+			return workflow, nil
+		}
+	}
+	return nil, fmt.Errorf("deploy job %s not found in workflow %s", train.DeployJobName, train.DeployWorkflow)
+}
+
+func handleReleaseTrainCronjob(train *commonmodels.ReleaseTrain, schedules *commonmodels.ScheduleCtrl, log *zap.SugaredLogger) error {
+	if schedules == nil {
+		return nil
+	}
+	payload := &commonservice.CronjobPayload{
+		Name:        train.ID.Hex(),
+		ProductName: train.ProjectName,
+		JobType:     config.ReleaseTrainCronjob,
+	}
+	if train.ScheduleEnabled {
+		for _, item := range schedules.Items {
+			item.ReleaseTrainArgs = &commonmodels.ReleaseTrainArgs{
+				ProjectName: train.ProjectName,
+				TrainID:     train.ID.Hex(),
+			}
+		}
+		deleteList, err := UpdateCronjob(train.ID.Hex(), config.ReleaseTrainCronjob, train.ProjectName, schedules, log)
+		if err != nil {
+			log.Errorf("Failed to update cronjob, the error is: %v", err)
+			return e.ErrUpsertCronjob.AddDesc(err.Error())
+		}
+		payload.Action = setting.TypeEnableCronjob
+		payload.DeleteList = deleteList
+		payload.JobList = schedules.Items
+	} else {
+		payload.Action = setting.TypeDisableCronjob
+	}
+
+	pl, _ := json.Marshal(payload)
+	if err := nsq.Publish(setting.TopicCronjob, pl); err != nil {
+		log.Errorf("Failed to publish to nsq topic: %s, the error is: %v", setting.TopicCronjob, err)
+		return e.ErrUpsertCronjob.AddDesc(err.Error())
+	}
+	return nil
+}
+
+func listReleaseTrainSchedules(train *commonmodels.ReleaseTrain) (*commonmodels.ScheduleCtrl, error) {
+	jobs, err := commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+		ParentName: train.ID.Hex(),
+		ParentType: config.ReleaseTrainCronjob,
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*commonmodels.Schedule, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, &commonmodels.Schedule{
+			ID:               job.ID,
+			Number:           job.Number,
+			Frequency:        job.Frequency,
+			Time:             job.Time,
+			MaxFailures:      job.MaxFailure,
+			ReleaseTrainArgs: job.ReleaseTrainArgs,
+			Type:             config.ScheduleType(job.JobType),
+			Cron:             job.Cron,
+			CatchUpPolicy:    job.CatchUpPolicy,
+			Enabled:          job.Enabled,
+		})
+	}
+	return &commonmodels.ScheduleCtrl{Enabled: train.ScheduleEnabled, Items: items}, nil
+}
+
+func findReleaseTrain(id, projectName string) (*commonmodels.ReleaseTrain, error) {
+	train, err := commonrepo.NewReleaseTrainColl().FindByID(id)
+	if err != nil || train.ProjectName != projectName {
+		return nil, fmt.Errorf("release train %s not found", id)
+	}
+	return train, nil
+}
+
+func validateReleaseTrain(args *commonmodels.ReleaseTrain) error {
+	if args.Name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if len(args.SourceWorkflows) == 0 {
+		return fmt.Errorf("source workflows are empty")
+	}
+	for _, name := range args.SourceWorkflows {
+		if workflow, err := commonrepo.NewWorkflowV4Coll().Find(name); err != nil || workflow.Project != args.ProjectName {
+			return fmt.Errorf("source workflow %s not found", name)
+		}
+	}
+	if _, err := getReleaseTrainWorkflow(args, nil); err != nil {
+		return err
+	}
+	if args.ScheduleEnabled && args.Schedules != nil {
+		for _, item := range args.Schedules.Items {
+			if err := item.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -266,17 +266,18 @@ func FindWorkflow(workflowName string, log *zap.SugaredLogger) (*commonmodels.Wo
 		scheduleList := []*commonmodels.Schedule{}
 		for _, v := range schedules {
 			scheduleList = append(scheduleList, &commonmodels.Schedule{
-				ID:           v.ID,
-				Number:       v.Number,
-				Frequency:    v.Frequency,
-				Time:         v.Time,
-				MaxFailures:  v.MaxFailure,
-				TaskArgs:     v.TaskArgs,
-				WorkflowArgs: v.WorkflowArgs,
-				TestArgs:     v.TestArgs,
-				Type:         config.ScheduleType(v.JobType),
-				Cron:         v.Cron,
-				Enabled:      v.Enabled,
+				ID:            v.ID,
+				Number:        v.Number,
+				Frequency:     v.Frequency,
+				Time:          v.Time,
+				MaxFailures:   v.MaxFailure,
+				TaskArgs:      v.TaskArgs,
+				WorkflowArgs:  v.WorkflowArgs,
+				TestArgs:      v.TestArgs,
+				Type:          config.ScheduleType(v.JobType),
+				Cron:          v.Cron,
+				CatchUpPolicy: v.CatchUpPolicy,
+				Enabled:       v.Enabled,
 			})
 		}
 		schedule := commonmodels.ScheduleCtrl{
@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// badgeTaskScanLimit is the number of the latest tasks searched for the run on a branch.
+const badgeTaskScanLimit = 50
+
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+  <title>{{.Label}}: {{.Message}}</title>
+  <linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+  <clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+    <rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>
+    <rect width="{{.Width}}" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="{{.LabelX}}" y="14">{{.Label}}</text>
+    <text x="{{.MessageX}}" y="14">{{.Message}}</text>
+  </g>
+</svg>
+`))
+
+type WorkflowV4Status struct {
+	WorkflowName string        `json:"workflow_name"`
+	Branch       string        `json:"branch,omitempty"`
+	TaskID       int64         `json:"task_id,omitempty"`
+	Status       config.Status `json:"status"`
+	CreateTime   int64         `json:"create_time,omitempty"`
+	StartTime    int64         `json:"start_time,omitempty"`
+	EndTime      int64         `json:"end_time,omitempty"`
+}
+
+func GetWorkflowV4BadgeToken(baseURL, workflowName string, logger *zap.SugaredLogger) (*commonmodels.WorkflowBadge, error) {
+	badge, err := commonrepo.NewWorkflowBadgeColl().Find(workflowName)
+	if err == mongo.ErrNoDocuments {
+		return &commonmodels.WorkflowBadge{WorkflowName: workflowName}, nil
+	}
+	if err != nil {
+		logger.Errorf("failed to find badge of workflow %s, err: %s", workflowName, err)
+		return nil, e.ErrGetWorkflowBadge.AddErr(err)
+	}
+	if badge.Token != "" {
+		query := url.Values{"token": []string{badge.Token}}.Encode()
+		badge.BadgeURL = fmt.Sprintf("%s/api/aslan/workflow/v4/badge/%s?%s", baseURL, url.PathEscape(workflowName), query)
+		badge.StatusURL = fmt.Sprintf("%s/api/aslan/workflow/v4/badge/%s/status?%s", baseURL, url.PathEscape(workflowName), query)
+	}
+	return badge, nil
+}
+
+// ResetWorkflowV4BadgeToken generates a new badge token of the workflow, the urls with the previous
+// token stop working.
+func ResetWorkflowV4BadgeToken(baseURL, workflowName, userName string, logger *zap.SugaredLogger) (*commonmodels.WorkflowBadge, error) {
+	if _, err := commonrepo.NewWorkflowV4Coll().Find(workflowName); err != nil {
+		return nil, e.ErrUpdateWorkflowBadge.AddErr(err)
+	}
+	badge := &commonmodels.WorkflowBadge{
+		WorkflowName: workflowName,
+		Token:        strings.ReplaceAll(util.UUID(), "-", ""),
+		UpdatedBy:    userName,
+	}
+	if err := commonrepo.NewWorkflowBadgeColl().Upsert(badge); err != nil {
+		logger.Errorf("failed to update badge of workflow %s, err: %s", workflowName, err)
+		return nil, e.ErrUpdateWorkflowBadge.AddErr(err)
+	}
+	return GetWorkflowV4BadgeToken(baseURL, workflowName, logger)
+}
+
+// GetWorkflowV4PublicStatus returns the status of the latest task of the workflow, only the tasks
+// running with the branch are counted if the branch is specified.
+func GetWorkflowV4PublicStatus(workflowName, token, branch string, logger *zap.SugaredLogger) (*WorkflowV4Status, error) {
+	badge, err := commonrepo.NewWorkflowBadgeColl().Find(workflowName)
+	if err != nil || badge.Token == "" || subtle.ConstantTimeCompare([]byte(badge.Token), []byte(token)) != 1 {
+		return nil, e.ErrUnauthorized.AddDesc("invalid badge token")
+	}
+
+	tasks, _, err := commonrepo.NewworkflowTaskv4Coll().List(&commonrepo.ListWorkflowTaskV4Option{
+		WorkflowName: workflowName,
+		Limit:        badgeTaskScanLimit,
+	})
+	if err != nil {
+		logger.Errorf("failed to list tasks of workflow %s, err: %s", workflowName, err)
+		return nil, e.ErrGetWorkflowBadge.AddErr(err)
+	}
+
+	resp := &WorkflowV4Status{WorkflowName: workflowName, Branch: branch}
+	for _, task := range tasks {
+		if !taskRunsWithBranch(task, branch) {
+			continue
+		}
+		resp.TaskID = task.TaskID
+		resp.Status = task.Status
+		resp.CreateTime = task.CreateTime
+		resp.StartTime = task.StartTime
+		resp.EndTime = task.EndTime
+		break
+	}
+	return resp, nil
+}
+
+// GetWorkflowV4Badge renders the status of the latest task of the workflow as a svg badge.
+func GetWorkflowV4Badge(workflowName, token, branch string, logger *zap.SugaredLogger) ([]byte, error) {
+	status, err := GetWorkflowV4PublicStatus(workflowName, token, branch, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	message, color := badgeMessage(status.Status)
+	labelWidth, messageWidth := badgeTextWidth(workflowName), badgeTextWidth(message)
+	buf := &bytes.Buffer{}
+	err = badgeTemplate.Execute(buf, map[string]interface{}{
+		"Label":        workflowName,
+		"Message":      message,
+		"Color":        color,
+		"Width":        labelWidth + messageWidth,
+		"LabelWidth":   labelWidth,
+		"MessageWidth": messageWidth,
+		"LabelX":       labelWidth / 2,
+		"MessageX":     labelWidth + messageWidth/2,
+	})
+	if err != nil {
+		return nil, e.ErrGetWorkflowBadge.AddErr(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func taskRunsWithBranch(task *commonmodels.WorkflowTask, branch string) bool {
+	if branch == "" {
+		return true
+	}
+	args := task.WorkflowArgs
+	if args == nil {
+		return false
+	}
+	if args.HookPayload != nil && args.HookPayload.Branch == branch {
+		return true
+	}
+	for _, stage := range args.Stages {
+		for _, job := range stage.Jobs {
+			if job.JobType != config.JobZadigBuild {
+				continue
+			}
+			spec := &commonmodels.ZadigBuildJobSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				continue
+			}
+			for _, build := range spec.ServiceAndBuilds {
+				for _, repo := range build.Repos {
+					if repo.Branch == branch {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func badgeMessage(status config.Status) (string, string) {
+	switch status {
+	case config.StatusPassed:
+		return "passing", "#4c1"
+	case config.StatusFailed, config.StatusTimeout, config.StatusReject:
+		return "failing", "#e05d44"
+	case config.StatusCancelled:
+		return "cancelled", "#9f9f9f"
+	case "":
+		return "unknown", "#9f9f9f"
+	default:
+		return "running", "#dfb317"
+	}
+}
+
+// badgeTextWidth estimates the width of the text in pixels with the default font, with the padding.
+func badgeTextWidth(text string) int {
+	return len(text)*7 + 10
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const defaultWorkflowDuplicateName = "{{.Name}}-copy"
+
+type DuplicateWorkflowV4Args struct {
+	// ProjectName is the project the workflow is duplicated to, the project of the workflow is used if empty.
+	ProjectName string `json:"project_name"`
+	// NameTemplate renders the name of the duplicate, {{.Name}}-copy is used if empty.
+	NameTemplate string `json:"name_template"`
+	// EnvMapping maps the environments of the deploy jobs to the ones of the target project,
+	// environments not in the mapping are kept.
+	EnvMapping map[string]string `json:"env_mapping"`
+	// BuildMapping maps the builds of the build jobs to the ones of the target project, when a
+	// workflow is duplicated to another project the build of the same service module is used
+	// for builds not in the mapping.
+	BuildMapping map[string]string `json:"build_mapping"`
+}
+
+// DuplicateWorkflowV4 creates a copy of the workflow in the same or another project, the builds
+// and the environments referenced by the jobs are rewritten to the ones of the target project.
+func DuplicateWorkflowV4(name string, args *DuplicateWorkflowV4Args, userName string, logger *zap.SugaredLogger) (*commonmodels.WorkflowV4, error) {
+	source, err := commonrepo.NewWorkflowV4Coll().Find(name)
+	if err != nil {
+		logger.Errorf("failed to find workflow %s, err: %s", name, err)
+		return nil, e.ErrFindWorkflow.AddErr(err)
+	}
+	projectName := args.ProjectName
+	if projectName == "" {
+		projectName = source.Project
+	}
+	if projectName != source.Project {
+		if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+			return nil, e.ErrDuplicateWorkflow.AddDesc(fmt.Sprintf("project %s not found", projectName))
+		}
+	}
+	newName, err := commonservice.RenderDuplicateName(args.NameTemplate, defaultWorkflowDuplicateName, &commonservice.DuplicateNameArgs{
+		Name:          source.Name,
+		Project:       projectName,
+		SourceProject: source.Project,
+	})
+	if err != nil {
+		return nil, e.ErrDuplicateWorkflow.AddErr(err)
+	}
+
+	workflow := &commonmodels.WorkflowV4{}
+	if err := commonmodels.IToi(source, workflow); err != nil {
+		return nil, e.ErrDuplicateWorkflow.AddErr(err)
+	}
+	workflow.ID = primitive.NilObjectID
+	workflow.Name = newName
+	workflow.Project = projectName
+	workflow.BaseName = ""
+	workflow.HookCtls = nil
+	workflow.HookPayload = nil
+	workflow.NotificationID = ""
+
+	rewriter := &workflowReferenceRewriter{sourceProject: source.Project, projectName: projectName, args: args}
+	if err := rewriter.rewrite(workflow); err != nil {
+		return nil, e.ErrDuplicateWorkflow.AddErr(err)
+	}
+	if len(rewriter.unresolved) > 0 {
+		return nil, e.ErrDuplicateWorkflow.AddDesc(strings.Join(rewriter.unresolved, "; "))
+	}
+	if err := CreateWorkflowV4(userName, workflow, logger); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// workflowReferenceRewriter replaces the builds and environments used by the jobs, the
+// references which can not be resolved in the target project are collected.
+type workflowReferenceRewriter struct {
+	sourceProject string
+	projectName   string
+	args          *DuplicateWorkflowV4Args
+	unresolved    []string
+}
+
+func (r *workflowReferenceRewriter) rewrite(workflow *commonmodels.WorkflowV4) error {
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			switch job.JobType {
+			case config.JobZadigBuild:
+				spec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return err
+				}
+				for _, build := range spec.ServiceAndBuilds {
+					build.BuildName = r.resolveBuild(job.Name, build)
+				}
+				job.Spec = spec
+			case config.JobZadigDeploy:
+				spec := &commonmodels.ZadigDeployJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return err
+				}
+				spec.Env = r.resolveEnv(job.Name, spec.Env)
+				job.Spec = spec
+			}
+		}
+	}
+	return nil
+}
+
+func (r *workflowReferenceRewriter) resolveBuild(jobName string, build *commonmodels.ServiceAndBuild) string {
+	if mapped, ok := r.args.BuildMapping[build.BuildName]; ok {
+		build.BuildName = mapped
+	} else if r.projectName != r.sourceProject {
+		builds, err := commonrepo.NewBuildColl().List(&commonrepo.BuildListOption{
+			ProductName: r.projectName,
+			ServiceName: build.ServiceName,
+			Targets:     []string{build.ServiceModule},
+		})
+		if err != nil || len(builds) == 0 {
+			r.unresolved = append(r.unresolved, fmt.Sprintf("job %s: no build of %s/%s in project %s", jobName, build.ServiceName, build.ServiceModule, r.projectName))
+			return build.BuildName
+		}
+		return builds[0].Name
+	}
+	if _, err := commonrepo.NewBuildColl().Find(&commonrepo.BuildFindOption{Name: build.BuildName, ProductName: r.projectName}); err != nil {
+		r.unresolved = append(r.unresolved, fmt.Sprintf("job %s: build %s not found in project %s", jobName, build.BuildName, r.projectName))
+	}
+	return build.BuildName
+}
+
+func (r *workflowReferenceRewriter) resolveEnv(jobName, envName string) string {
+	if mapped, ok := r.args.EnvMapping[envName]; ok {
+		envName = mapped
+	}
+	// the environment of a deploy job can be provided when the workflow runs.
+	if envName == "" || r.projectName == r.sourceProject {
+		return envName
+	}
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: r.projectName, EnvName: envName}); err != nil {
+		r.unresolved = append(r.unresolved, fmt.Sprintf("job %s: environment %s not found in project %s", jobName, envName, r.projectName))
+	}
+	return envName
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	steptypes "github.com/koderover/zadig/pkg/types/step"
+)
+
+type ConvertWorkflowArgs struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	// Source is the kind of the CI config to be imported, github or gitlab.
+	Source string `json:"source"`
+}
+
+type ConvertWorkflowResp struct {
+	// Yaml is the draft workflow definition, it can be submitted to the create workflow API after review.
+	Yaml string `json:"yaml"`
+	// Warnings lists the constructs which could not be converted automatically.
+	Warnings []string `json:"warnings"`
+	// Hooks are the draft webhook triggers, the repositories should be selected before creating them.
+	Hooks []*commonmodels.WorkflowV4Hook `json:"hooks,omitempty"`
+}
+
+// newDraftFreestyleJob builds a freestyle job with one shell step. The build image is left
+// empty on purpose since there is no reliable way to map an external runner to it.
+func newDraftFreestyleJob(name, script string, envs []*commonmodels.KeyVal) *commonmodels.Job {
+	return &commonmodels.Job{
+		Name:    draftJobName(name),
+		JobType: config.JobFreestyle,
+		Spec: &commonmodels.FreestyleJobSpec{
+			Properties: &commonmodels.JobProperties{
+				Timeout:         60,
+				ResourceRequest: setting.MinRequest,
+				ClusterID:       setting.LocalClusterID,
+				Envs:            envs,
+			},
+			Steps: []*commonmodels.Step{
+				{
+					Name:     "shell",
+					StepType: config.StepShell,
+					Spec:     &steptypes.StepShellSpec{Script: script},
+				},
+			},
+		},
+	}
+}
+
+var draftJobNameInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// draftJobName converts a free-form stage name into a name matching JobNameRegx.
+func draftJobName(name string) string {
+	name = draftJobNameInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-")
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		name = "job-" + name
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return strings.TrimRight(name, "-")
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	codeservice "github.com/koderover/zadig/pkg/microservice/aslan/core/code/service"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	branchOptionsPerPage  = 100
+	branchOptionsMaxPages = 10
+)
+
+type ParamOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// ListWorkflowV4ParamOptions lists the options of a param in the launch form of the workflow, values are the
+// params selected in the form which the options may depend on.
+func ListWorkflowV4ParamOptions(workflowName, paramName string, values map[string]string, logger *zap.SugaredLogger) ([]*ParamOption, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("failed to find workflow %s, err: %s", workflowName, err)
+		return nil, e.ErrListWorkflowParamOptions.AddErr(err)
+	}
+	resolved := paramValues(workflow.Params, values)
+	for _, param := range workflow.Params {
+		if param.Name != paramName {
+			continue
+		}
+		options, err := paramOptions(workflow.Project, param, resolved, logger)
+		if err != nil {
+			return nil, e.ErrListWorkflowParamOptions.AddErr(err)
+		}
+		return options, nil
+	}
+	return nil, e.ErrListWorkflowParamOptions.AddDesc(fmt.Sprintf("param %s is not found in workflow %s", paramName, workflowName))
+}
+
+// paramValues returns the values of the params, the defaults of the workflow are used for the params not selected.
+func paramValues(params []*commonmodels.Param, values map[string]string) map[string]string {
+	resp := make(map[string]string, len(params))
+	for _, param := range params {
+		resp[param.Name] = strings.ReplaceAll(param.Value, setting.FixedValueMark, "")
+		if value, ok := values[param.Name]; ok {
+			resp[param.Name] = strings.ReplaceAll(value, setting.FixedValueMark, "")
+		}
+	}
+	return resp
+}
+
+// paramOptions lists the options of a choice param, it is empty if the param it depends on is not selected yet.
+func paramOptions(project string, param *commonmodels.Param, values map[string]string, logger *zap.SugaredLogger) ([]*ParamOption, error) {
+	if param.Source == nil {
+		options := make([]*ParamOption, 0, len(param.ChoiceOption))
+		for _, option := range param.ChoiceOption {
+			options = append(options, &ParamOption{Value: option, Label: option})
+		}
+		return options, nil
+	}
+
+	source := param.Source
+	switch source.Type {
+	case commonmodels.ParamSourceBranches:
+		namespace, name := source.RepoNamespace, source.RepoName
+		if source.RepoParam != "" {
+			repo := values[source.RepoParam]
+			idx := strings.LastIndex(repo, "/")
+			if idx <= 0 {
+				return []*ParamOption{}, nil
+			}
+			namespace, name = repo[:idx], repo[idx+1:]
+		}
+		return branchOptions(source.CodehostID, namespace, name, logger)
+	case commonmodels.ParamSourceEnvironments:
+		clusterID := source.ClusterID
+		if source.ClusterParam != "" {
+			clusterID = values[source.ClusterParam]
+			if clusterID == "" {
+				return []*ParamOption{}, nil
+			}
+		}
+		envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{Name: project, ClusterID: clusterID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the environments of project %s: %s", project, err)
+		}
+		options := make([]*ParamOption, 0, len(envs))
+		for _, env := range envs {
+			options = append(options, &ParamOption{Value: env.EnvName, Label: env.EnvName})
+		}
+		return options, nil
+	case commonmodels.ParamSourceClusters:
+		relations, err := commonrepo.NewProjectClusterRelationColl().List(&commonrepo.ProjectClusterRelationOption{ProjectName: project})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the clusters of project %s: %s", project, err)
+		}
+		ids := make([]string, 0, len(relations))
+		for _, relation := range relations {
+			ids = append(ids, relation.ClusterID)
+		}
+		options := make([]*ParamOption, 0, len(ids))
+		if len(ids) == 0 {
+			return options, nil
+		}
+		clusters, err := commonrepo.NewK8SClusterColl().List(&commonrepo.ClusterListOpts{IDs: ids})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the clusters of project %s: %s", project, err)
+		}
+		for _, cluster := range clusters {
+			options = append(options, &ParamOption{Value: cluster.ID.Hex(), Label: cluster.Name})
+		}
+		return options, nil
+	}
+	return nil, fmt.Errorf("unknown source %s of param %s", source.Type, param.Name)
+}
+
+func branchOptions(codehostID int, namespace, name string, logger *zap.SugaredLogger) ([]*ParamOption, error) {
+	options := make([]*ParamOption, 0)
+	for page := 1; page <= branchOptionsMaxPages; page++ {
+		branches, err := codeservice.CodeHostListBranches(codehostID, name, namespace, "", page, branchOptionsPerPage, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the branches of %s/%s: %s", namespace, name, err)
+		}
+		for _, branch := range branches {
+			options = append(options, &ParamOption{Value: branch.Name, Label: branch.Name})
+		}
+		if len(branches) < branchOptionsPerPage {
+			break
+		}
+	}
+	return options, nil
+}
+
+// lintParamSources checks the sources of the params when the workflow is saved. A param can only depend on a
+// param which has fixed options, so that the launch form is filled in at most two steps without cycles.
+func lintParamSources(params []*commonmodels.Param) error {
+	sources := make(map[string]*commonmodels.ParamSource, len(params))
+	for _, param := range params {
+		sources[param.Name] = param.Source
+	}
+	for _, param := range params {
+		source := param.Source
+		if source == nil {
+			continue
+		}
+		if param.ParamsType != string(commonmodels.ChoiceType) {
+			return fmt.Errorf("param %s with a source must be of type %s", param.Name, commonmodels.ChoiceType)
+		}
+		switch source.Type {
+		case commonmodels.ParamSourceBranches:
+			if source.CodehostID == 0 {
+				return fmt.Errorf("the codehost of the branches of param %s is not set", param.Name)
+			}
+			if source.RepoParam == "" && (source.RepoNamespace == "" || source.RepoName == "") {
+				return fmt.Errorf("the repo of the branches of param %s is not set", param.Name)
+			}
+		case commonmodels.ParamSourceEnvironments, commonmodels.ParamSourceClusters:
+		default:
+			return fmt.Errorf("unknown source %s of param %s", source.Type, param.Name)
+		}
+
+		dependsOn := source.DependsOn()
+		if dependsOn == "" {
+			continue
+		}
+		dependency, ok := sources[dependsOn]
+		if !ok || dependsOn == param.Name {
+			return fmt.Errorf("param %s depends on an unknown param %s", param.Name, dependsOn)
+		}
+		if dependency != nil && dependency.DependsOn() != "" {
+			return fmt.Errorf("param %s depends on param %s which depends on another param", param.Name, dependsOn)
+		}
+	}
+	return nil
+}
+
+// lintWorkflowV4ParamValues checks the values of the choice params against their options before the task is
+// accepted, the definitions of the params are taken from the saved workflow if there is one.
+func lintWorkflowV4ParamValues(saved, workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
+	definitions := workflow.Params
+	if saved != nil {
+		definitions = saved.Params
+	}
+	input := make(map[string]string, len(workflow.Params))
+	for _, param := range workflow.Params {
+		input[param.Name] = param.Value
+	}
+	values := paramValues(definitions, input)
+
+	reasons := []string{}
+	for _, param := range definitions {
+		value := values[param.Name]
+		if param.ParamsType != string(commonmodels.ChoiceType) || value == "" {
+			continue
+		}
+		if param.Source == nil && len(param.ChoiceOption) == 0 {
+			continue
+		}
+		options, err := paramOptions(workflow.Project, param, values, logger)
+		if err != nil {
+			// the codehosts may be unavailable for a while, the runs are not blocked by them
+			logger.Warnf("failed to list the options of param %s, skip checking it, err: %s", param.Name, err)
+			continue
+		}
+		optionSet := sets.NewString()
+		for _, option := range options {
+			optionSet.Insert(option.Value)
+		}
+		if optionSet.Has(value) {
+			continue
+		}
+		if param.Source != nil && param.Source.DependsOn() != "" {
+			dependsOn := param.Source.DependsOn()
+			reasons = append(reasons, fmt.Sprintf("%q is not an option of param %s when %s is %q", value, param.Name, dependsOn, values[dependsOn]))
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%q is not an option of param %s", value, param.Name))
+	}
+	if len(reasons) > 0 {
+		return e.ErrInvalidWorkflowParams.AddDesc(strings.Join(reasons, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing workflow param sources", func() {
+
+	choice := string(commonmodels.ChoiceType)
+
+	Context("lintParamSources", func() {
+		It("should accept the params depending on the params with fixed options", func() {
+			Expect(lintParamSources([]*commonmodels.Param{
+				{Name: "repo", ParamsType: choice, ChoiceOption: []string{"koderover/zadig"}},
+				{Name: "branch", ParamsType: choice, Source: &commonmodels.ParamSource{
+					Type: commonmodels.ParamSourceBranches, CodehostID: 1, RepoParam: "repo",
+				}},
+				{Name: "cluster", ParamsType: choice, Source: &commonmodels.ParamSource{Type: commonmodels.ParamSourceClusters}},
+				{Name: "env", ParamsType: choice, Source: &commonmodels.ParamSource{
+					Type: commonmodels.ParamSourceEnvironments, ClusterParam: "cluster",
+				}},
+			})).Should(Succeed())
+		})
+
+		It("should reject the unknown dependencies and the chains", func() {
+			Expect(lintParamSources([]*commonmodels.Param{
+				{Name: "env", ParamsType: choice, Source: &commonmodels.ParamSource{
+					Type: commonmodels.ParamSourceEnvironments, ClusterParam: "cluster",
+				}},
+			})).ShouldNot(Succeed())
+
+			Expect(lintParamSources([]*commonmodels.Param{
+				{Name: "cluster", ParamsType: choice, ChoiceOption: []string{"c1"}},
+				{Name: "env", ParamsType: choice, Source: &commonmodels.ParamSource{
+					Type: commonmodels.ParamSourceEnvironments, ClusterParam: "cluster",
+				}},
+				{Name: "branch", ParamsType: choice, Source: &commonmodels.ParamSource{
+					Type: commonmodels.ParamSourceBranches, CodehostID: 1, RepoParam: "env",
+				}},
+			})).ShouldNot(Succeed())
+		})
+
+		It("should reject the sources of the params which are not choices", func() {
+			Expect(lintParamSources([]*commonmodels.Param{
+				{Name: "cluster", ParamsType: "string", Source: &commonmodels.ParamSource{Type: commonmodels.ParamSourceClusters}},
+			})).ShouldNot(Succeed())
+		})
+	})
+
+	Context("paramValues", func() {
+		It("should use the defaults for the params not selected", func() {
+			values := paramValues([]*commonmodels.Param{
+				{Name: "repo", Value: "koderover/zadig"},
+				{Name: "branch", Value: "main"},
+			}, map[string]string{"branch": "dev"})
+			Expect(values).Should(Equal(map[string]string{"repo": "koderover/zadig", "branch": "dev"}))
+		})
+	})
+})
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const defaultRecentTaskLimit = 20
+
+type RecentWorkflowTask struct {
+	WorkflowName string        `json:"workflow_name"`
+	ProjectName  string        `json:"project_name"`
+	TaskID       int64         `json:"task_id"`
+	Status       config.Status `json:"status"`
+	CreateTime   int64         `json:"create_time"`
+	StartTime    int64         `json:"start_time"`
+	EndTime      int64         `json:"end_time"`
+	IsFavorite   bool          `json:"is_favorite"`
+}
+
+// ListMyRecentWorkflowTaskV4 lists the latest tasks created by the user so that they can be run again quickly.
+func ListMyRecentWorkflowTaskV4(userName, userID, projectName string, limit int, logger *zap.SugaredLogger) ([]*RecentWorkflowTask, error) {
+	if limit <= 0 {
+		limit = defaultRecentTaskLimit
+	}
+	tasks, _, err := commonrepo.NewworkflowTaskv4Coll().List(&commonrepo.ListWorkflowTaskV4Option{
+		TaskCreator: userName,
+		ProjectName: projectName,
+		Limit:       limit,
+	})
+	if err != nil {
+		logger.Errorf("failed to list workflow tasks of user %s, err: %s", userName, err)
+		return nil, e.ErrListTasks.AddErr(err)
+	}
+	favorites, err := commonrepo.NewFavoriteColl().List(&commonrepo.FavoriteArgs{UserID: userID, Type: string(config.WorkflowTypeV4)})
+	if err != nil {
+		logger.Warnf("failed to list favorites, err: %s", err)
+	}
+	favoriteSet := sets.NewString()
+	for _, favorite := range favorites {
+		favoriteSet.Insert(favorite.Name)
+	}
+
+	resp := make([]*RecentWorkflowTask, 0, len(tasks))
+	for _, task := range tasks {
+		resp = append(resp, &RecentWorkflowTask{
+			WorkflowName: task.WorkflowName,
+			ProjectName:  task.ProjectName,
+			TaskID:       task.TaskID,
+			Status:       task.Status,
+			CreateTime:   task.CreateTime,
+			StartTime:    task.StartTime,
+			EndTime:      task.EndTime,
+			IsFavorite:   favoriteSet.Has(task.WorkflowName),
+		})
+	}
+	return resp, nil
+}
+
+func GetWorkflowV4Preference(userID, workflowName string, logger *zap.SugaredLogger) (*commonmodels.WorkflowPreference, error) {
+	preference, err := commonrepo.NewWorkflowPreferenceColl().Find(userID, workflowName)
+	if err == mongo.ErrNoDocuments {
+		return &commonmodels.WorkflowPreference{UserID: userID, WorkflowName: workflowName, Params: []*commonmodels.Param{}}, nil
+	}
+	if err != nil {
+		logger.Errorf("failed to find preference of workflow %s, err: %s", workflowName, err)
+		return nil, e.ErrGetWorkflowPreference.AddErr(err)
+	}
+	return preference, nil
+}
+
+func UpdateWorkflowV4Preference(userID, workflowName string, params []*commonmodels.Param, logger *zap.SugaredLogger) error {
+	if _, err := commonrepo.NewWorkflowV4Coll().Find(workflowName); err != nil {
+		return e.ErrUpdateWorkflowPreference.AddErr(err)
+	}
+	if err := saveWorkflowV4Preference(userID, workflowName, params); err != nil {
+		logger.Errorf("failed to update preference of workflow %s, err: %s", workflowName, err)
+		return e.ErrUpdateWorkflowPreference.AddErr(err)
+	}
+	return nil
+}
+
+// RememberWorkflowV4Params saves the parameters the user runs the workflow with as the defaults of the next run.
+func RememberWorkflowV4Params(userID string, workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) {
+	if userID == "" || len(workflow.Params) == 0 {
+		return
+	}
+	if err := saveWorkflowV4Preference(userID, workflow.Name, workflow.Params); err != nil {
+		logger.Warnf("failed to save preference of workflow %s, err: %s", workflow.Name, err)
+	}
+}
+
+// ApplyWorkflowV4Preference fills the parameters of the workflow with the values remembered for the user,
+// the values which are no longer valid for the parameters are ignored.
+func ApplyWorkflowV4Preference(userID string, workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) {
+	preference, err := commonrepo.NewWorkflowPreferenceColl().Find(userID, workflow.Name)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			logger.Warnf("failed to find preference of workflow %s, err: %s", workflow.Name, err)
+		}
+		return
+	}
+	values := make(map[string]*commonmodels.Param, len(preference.Params))
+	for _, param := range preference.Params {
+		values[param.Name] = param
+	}
+	for _, param := range workflow.Params {
+		value, ok := values[param.Name]
+		if !ok || param.IsCredential || value.ParamsType != param.ParamsType {
+			continue
+		}
+		// the options from a source are checked when the workflow is run
+		if param.ParamsType == string(commonmodels.ChoiceType) && param.Source == nil && !sets.NewString(param.ChoiceOption...).Has(value.Value) {
+			continue
+		}
+		param.Value = value.Value
+	}
+}
+
+// saveWorkflowV4Preference saves the values of the parameters, the credentials are never saved.
+func saveWorkflowV4Preference(userID, workflowName string, params []*commonmodels.Param) error {
+	preference := &commonmodels.WorkflowPreference{
+		UserID:       userID,
+		WorkflowName: workflowName,
+		Params:       make([]*commonmodels.Param, 0, len(params)),
+	}
+	for _, param := range params {
+		if param.IsCredential {
+			continue
+		}
+		preference.Params = append(preference.Params, &commonmodels.Param{
+			Name:       param.Name,
+			ParamsType: param.ParamsType,
+			Value:      param.Value,
+		})
+	}
+	return commonrepo.NewWorkflowPreferenceColl().Upsert(preference)
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+//go:embed workflow_v4_schema.json
+var workflowV4Schema []byte
+
+var yamlErrorLineRegx = regexp.MustCompile(`line (\d+)`)
+
+// ValidationIssue is a problem found in the workflow yaml, line and column are 1-based
+// and are 0 if the problem can not be located.
+type ValidationIssue struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type ValidateWorkflowResp struct {
+	Valid    bool               `json:"valid"`
+	Errors   []*ValidationIssue `json:"errors"`
+	Warnings []*ValidationIssue `json:"warnings"`
+}
+
+// GetWorkflowV4Schema returns the json schema of the workflow yaml, editors can use it for
+// completion and inline validation.
+func GetWorkflowV4Schema() []byte {
+	return workflowV4Schema
+}
+
+// ValidateWorkflowV4Yaml checks the workflow yaml against the schema and the rules applied
+// when the workflow is saved, all problems are returned instead of stopping at the first one.
+func ValidateWorkflowV4Yaml(content []byte, logger *zap.SugaredLogger) (*ValidateWorkflowResp, error) {
+	resp, workflow, root := validateWorkflowV4Yaml(content)
+	if workflow != nil && workflow.Project != "" && workflow.Project != setting.EnterpriseProject {
+		if _, err := templaterepo.NewProductColl().Find(workflow.Project); err != nil {
+			logger.Warnf("failed to find project %s, err: %s", workflow.Project, err)
+			resp.addError(root, "project", fmt.Sprintf("project %s not found", workflow.Project))
+		}
+	}
+	resp.Valid = len(resp.Errors) == 0
+	return resp, nil
+}
+
+func validateWorkflowV4Yaml(content []byte) (*ValidateWorkflowResp, *commonmodels.WorkflowV4, *yaml.Node) {
+	resp := &ValidateWorkflowResp{Errors: []*ValidationIssue{}, Warnings: []*ValidationIssue{}}
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(content, root); err != nil {
+		issue := &ValidationIssue{Message: err.Error()}
+		if match := yamlErrorLineRegx.FindStringSubmatch(err.Error()); len(match) == 2 {
+			issue.Line, _ = strconv.Atoi(match[1])
+		}
+		resp.Errors = append(resp.Errors, issue)
+		return resp, nil, nil
+	}
+	if len(root.Content) == 0 {
+		resp.Errors = append(resp.Errors, &ValidationIssue{Message: "workflow yaml is empty"})
+		return resp, nil, nil
+	}
+
+	var document interface{}
+	if err := root.Content[0].Decode(&document); err != nil {
+		resp.addError(root, "", err.Error())
+		return resp, nil, root
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(workflowV4Schema), gojsonschema.NewGoLoader(toJSONCompatible(document)))
+	if err != nil {
+		resp.addError(root, "", err.Error())
+		return resp, nil, root
+	}
+	for _, re := range result.Errors() {
+		// the errors of the sub schemas are reported on their own, skip the summaries.
+		if re.Type() == "number_all_of" || re.Type() == "condition_then" || re.Type() == "condition_else" {
+			continue
+		}
+		// properties unknown to the schema are ignored when the workflow is saved.
+		if re.Type() == "additional_property_not_allowed" {
+			path := joinSchemaPath(re.Field(), re.Details()["property"])
+			resp.addWarning(root, path, fmt.Sprintf("unknown property %s will be ignored", re.Details()["property"]))
+			continue
+		}
+		path := re.Field()
+		if re.Type() == "required" {
+			resp.addError(root, path, re.Description())
+			continue
+		}
+		resp.addError(root, path, fmt.Sprintf("%s: %s", path, re.Description()))
+	}
+	if len(resp.Errors) > 0 {
+		return resp, nil, root
+	}
+
+	workflow := &commonmodels.WorkflowV4{}
+	if err := root.Content[0].Decode(workflow); err != nil {
+		resp.addError(root, "", err.Error())
+		return resp, nil, root
+	}
+	resp.lintStages(root, workflow)
+	return resp, workflow, root
+}
+
+// lintStages applies the rules of LintWorkflowV4 which can not be expressed by the schema.
+func (r *ValidateWorkflowResp) lintStages(root *yaml.Node, workflow *commonmodels.WorkflowV4) {
+	stageNames := sets.NewString()
+	jobNames := sets.NewString()
+	// deploy job can only quote a build job which runs before it.
+	buildJobs := map[string]config.JobType{}
+	for i, stage := range workflow.Stages {
+		stagePath := fmt.Sprintf("stages.%d", i)
+		if stageNames.Has(stage.Name) {
+			r.addError(root, stagePath+".name", fmt.Sprintf("duplicated stage name: %s", stage.Name))
+		}
+		stageNames.Insert(stage.Name)
+		if len(stage.Jobs) == 0 {
+			r.addWarning(root, stagePath, fmt.Sprintf("stage %s has no jobs", stage.Name))
+		}
+		if stage.Approval != nil && stage.Approval.Enabled {
+			if stage.Approval.Type == config.WebhookApproval {
+				if stage.Approval.Webhook == nil || stage.Approval.Webhook.URL == "" {
+					r.addError(root, stagePath+".approval.webhook", fmt.Sprintf("approval of stage %s is delegated to a webhook without url", stage.Name))
+				}
+			} else if len(stage.Approval.ApproveUsers) == 0 {
+				r.addWarning(root, stagePath+".approval", fmt.Sprintf("approval of stage %s is enabled without approve users", stage.Name))
+			}
+		}
+
+		stageBuildJobs := map[string]config.JobType{}
+		for j, job := range stage.Jobs {
+			jobPath := fmt.Sprintf("%s.jobs.%d", stagePath, j)
+			if jobNames.Has(job.Name) {
+				r.addError(root, jobPath+".name", fmt.Sprintf("duplicated job name: %s", job.Name))
+			}
+			jobNames.Insert(job.Name)
+			if stage.Parallel {
+				stageBuildJobs[job.Name] = job.JobType
+			} else {
+				buildJobs[job.Name] = job.JobType
+			}
+
+			if job.JobType != config.JobZadigDeploy {
+				continue
+			}
+			spec := &commonmodels.ZadigDeployJobSpec{}
+			if err := commonmodels.IToiYaml(job.Spec, spec); err != nil {
+				r.addError(root, jobPath+".spec", err.Error())
+				continue
+			}
+			if spec.Source != config.SourceFromJob {
+				continue
+			}
+			if jobType, ok := buildJobs[spec.JobName]; !ok || jobType != config.JobZadigBuild {
+				r.addError(root, jobPath+".spec.job_name", fmt.Sprintf("can not quote job %s in job %s", spec.JobName, job.Name))
+			}
+		}
+		for name, jobType := range stageBuildJobs {
+			buildJobs[name] = jobType
+		}
+	}
+}
+
+func (r *ValidateWorkflowResp) addError(root *yaml.Node, path, message string) {
+	r.Errors = append(r.Errors, newValidationIssue(root, path, message))
+}
+
+func (r *ValidateWorkflowResp) addWarning(root *yaml.Node, path, message string) {
+	r.Warnings = append(r.Warnings, newValidationIssue(root, path, message))
+}
+
+func newValidationIssue(root *yaml.Node, path, message string) *ValidationIssue {
+	issue := &ValidationIssue{Path: path, Message: message}
+	if node := locateYamlNode(root, path); node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	return issue
+}
+
+// locateYamlNode finds the node of a dot separated path such as stages.0.jobs.1.name, for
+// a mapping entry the key node is returned. The deepest existing node is returned if the
+// path can not be fully resolved, e.g. for a missing required property.
+func locateYamlNode(root *yaml.Node, path string) *yaml.Node {
+	if root == nil || len(root.Content) == 0 {
+		return nil
+	}
+	node := root.Content[0]
+	located := node
+	if path == "" || path == "(root)" {
+		return located
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch node.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == part {
+					located, next = node.Content[i], node.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return located
+			}
+			node = next
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return located
+			}
+			node = node.Content[index]
+			located = node
+		default:
+			return located
+		}
+	}
+	return located
+}
+
+func joinSchemaPath(field string, property interface{}) string {
+	if field == "" || field == "(root)" {
+		return fmt.Sprint(property)
+	}
+	return fmt.Sprintf("%s.%v", field, property)
+}
+
+// toJSONCompatible converts the maps decoded from yaml so that they can be marshaled to json.
+func toJSONCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = toJSONCompatible(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		resp := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resp[fmt.Sprint(key)] = toJSONCompatible(item)
+		}
+		return resp
+	case []interface{}:
+		for i, item := range v {
+			v[i] = toJSONCompatible(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
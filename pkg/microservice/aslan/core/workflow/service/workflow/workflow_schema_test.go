@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const testValidWorkflow = `
+name: demo
+project: demo
+stages:
+  - name: build
+    parallel: true
+    jobs:
+      - name: build
+        type: zadig-build
+        spec:
+          service_and_builds:
+            - service_name: svc
+              service_module: svc
+              build_name: svc-build
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: zadig-deploy
+        spec:
+          env: dev
+          source: fromjob
+          job_name: build
+`
+
+const testInvalidWorkflow = `
+name: Demo
+project: demo
+color: red
+stages:
+  - name: build
+    jobs:
+      - name: build
+        type: zadig-build
+        spec:
+          service_and_builds:
+            - service_name: svc
+              service_module: svc
+  - name: build
+    jobs:
+      - name: unknown
+        type: shell
+        spec: {}
+`
+
+const testDuplicatedWorkflow = `
+name: demo
+project: demo
+stages:
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: zadig-deploy
+        spec:
+          env: dev
+          source: fromjob
+          job_name: build
+  - name: deploy
+    jobs:
+      - name: build
+        type: zadig-build
+        spec:
+          service_and_builds: []
+`
+
+var _ = Describe("Testing validate workflow yaml", func() {
+
+	It("accepts a valid workflow", func() {
+		resp, workflow, _ := validateWorkflowV4Yaml([]byte(testValidWorkflow))
+		Expect(resp.Errors).To(BeEmpty())
+		Expect(resp.Warnings).To(BeEmpty())
+		Expect(workflow.Name).To(Equal("demo"))
+	})
+
+	It("reports schema errors with lines", func() {
+		resp, workflow, _ := validateWorkflowV4Yaml([]byte(testInvalidWorkflow))
+		Expect(workflow).To(BeNil())
+
+		lines := map[string]int{}
+		for _, issue := range resp.Errors {
+			lines[issue.Path] = issue.Line
+		}
+		Expect(lines).To(HaveLen(3))
+		Expect(lines).To(HaveKeyWithValue("name", 2))
+		Expect(lines).To(HaveKeyWithValue("stages.0.jobs.0.spec.service_and_builds.0", 12))
+		Expect(lines).To(HaveKeyWithValue("stages.1.jobs.0.type", 17))
+
+		Expect(resp.Warnings).To(HaveLen(1))
+		Expect(resp.Warnings[0].Path).To(Equal("color"))
+		Expect(resp.Warnings[0].Line).To(Equal(4))
+	})
+
+	It("reports duplicated names and invalid job references", func() {
+		resp, _, _ := validateWorkflowV4Yaml([]byte(testDuplicatedWorkflow))
+
+		lines := map[string]int{}
+		for _, issue := range resp.Errors {
+			lines[issue.Path] = issue.Line
+		}
+		Expect(lines).To(HaveLen(2))
+		Expect(lines).To(HaveKeyWithValue("stages.0.jobs.0.spec.job_name", 12))
+		Expect(lines).To(HaveKeyWithValue("stages.1.name", 13))
+	})
+
+	It("reports yaml syntax errors with lines", func() {
+		resp, _, _ := validateWorkflowV4Yaml([]byte("name: demo\nstages:\n  - name: [\n"))
+		Expect(resp.Errors).To(HaveLen(1))
+		Expect(resp.Errors[0].Line).To(BeNumerically(">", 0))
+	})
+})
@@ -47,6 +47,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
 	templ "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	e "github.com/koderover/zadig/pkg/tool/errors"
@@ -519,6 +520,10 @@ func PresetWorkflowArgs(namespace, workflowName string, log *zap.SugaredLogger)
 }
 
 func CreateWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string, log *zap.SugaredLogger) (*CreateTaskResp, error) {
+	if err := workflowcontroller.CheckMaintenance(); err != nil {
+		return nil, err
+	}
+
 	if args == nil {
 		return nil, fmt.Errorf("args should not be nil")
 	}
@@ -1659,7 +1664,7 @@ func testArgsToSubtask(args *commonmodels.WorkflowTaskArgs, pt *taskmodels.Task,
 					}
 				}
 			}
-			envs = append(envs, &commonmodels.KeyVal{Key: "TEST_URL", Value: GetLink(pt, configbase.SystemAddress(), config.WorkflowType)})
+			envs = append(envs, &commonmodels.KeyVal{Key: "TEST_URL", Value: GetLink(pt, configbase.ExternalBaseURL(), config.WorkflowType)})
 			envs = append(envs, &commonmodels.KeyVal{Key: "SERVICES", Value: services})
 			envs = append(envs, &commonmodels.KeyVal{Key: "WORKSPACE", Value: "/workspace"})
 
@@ -1703,6 +1708,10 @@ func testArgsToSubtask(args *commonmodels.WorkflowTaskArgs, pt *taskmodels.Task,
 }
 
 func CreateArtifactWorkflowTask(args *commonmodels.WorkflowTaskArgs, taskCreator string, log *zap.SugaredLogger) (*CreateTaskResp, error) {
+	if err := workflowcontroller.CheckMaintenance(); err != nil {
+		return nil, err
+	}
+
 	if args == nil {
 		return nil, fmt.Errorf("args should not be nil")
 	}
@@ -2004,29 +2013,7 @@ func fillBuildDetail(moduleBuild *commonmodels.Build, serviceName, serviceModule
 		return fmt.Errorf("failed to find build template with id: %s, err: %s", moduleBuild.TemplateID, err)
 	}
 
-	moduleBuild.Timeout = buildTemplate.Timeout
-	moduleBuild.PreBuild = buildTemplate.PreBuild
-	moduleBuild.JenkinsBuild = buildTemplate.JenkinsBuild
-	moduleBuild.Scripts = buildTemplate.Scripts
-	moduleBuild.PostBuild = buildTemplate.PostBuild
-	moduleBuild.SSHs = buildTemplate.SSHs
-	moduleBuild.PMDeployScripts = buildTemplate.PMDeployScripts
-	moduleBuild.CacheEnable = buildTemplate.CacheEnable
-	moduleBuild.CacheDirType = buildTemplate.CacheDirType
-	moduleBuild.CacheUserDir = buildTemplate.CacheUserDir
-	moduleBuild.AdvancedSettingsModified = buildTemplate.AdvancedSettingsModified
-
-	// repos are configured by service modules
-	for _, serviceConfig := range moduleBuild.Targets {
-		if serviceConfig.ServiceName == serviceName && serviceConfig.ServiceModule == serviceModule {
-			moduleBuild.Repos = serviceConfig.Repos
-			if moduleBuild.PreBuild == nil {
-				moduleBuild.PreBuild = &commonmodels.PreBuild{}
-			}
-			moduleBuild.PreBuild.Envs = commonservice.MergeBuildEnvs(moduleBuild.PreBuild.Envs, serviceConfig.Envs)
-			break
-		}
-	}
+	commonservice.FillBuildDetail(moduleBuild, buildTemplate, serviceName, serviceModule)
 	return nil
 }
 
@@ -2252,8 +2239,8 @@ func BuildModuleToSubTasks(args *commonmodels.BuildModuleArgs, log *zap.SugaredL
 		if module.PostBuild != nil && module.PostBuild.DockerBuild != nil {
 			dockerTemplateContent := ""
 			if module.PostBuild.DockerBuild.TemplateID != "" {
-				if dockerfileDetail, err := templ.GetDockerfileTemplateDetail(module.PostBuild.DockerBuild.TemplateID, log); err == nil {
-					dockerTemplateContent = dockerfileDetail.Content
+				if content, err := templ.GetDockerfileTemplateContent(module.PostBuild.DockerBuild.TemplateID, module.PostBuild.DockerBuild.TemplateRevision, log); err == nil {
+					dockerTemplateContent = content
 				}
 			}
 			build.JobCtx.DockerBuildCtx = &taskmodels.DockerBuildCtx{
@@ -0,0 +1,476 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	git "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/types"
+	stepspec "github.com/koderover/zadig/pkg/types/step"
+)
+
+// lockfiles are the files pinning the dependencies of the common package managers.
+var lockfiles = sets.NewString(
+	"go.mod", "go.sum",
+	"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "npm-shrinkwrap.json",
+	"pom.xml", "build.gradle", "build.gradle.kts", "gradle.lockfile",
+	"requirements.txt", "Pipfile.lock", "poetry.lock",
+	"Gemfile.lock", "Cargo.lock", "composer.lock",
+)
+
+type WorkflowTaskComparison struct {
+	WorkflowName string                 `json:"workflow_name"`
+	Base         *ComparedTask          `json:"base"`
+	Head         *ComparedTask          `json:"head"`
+	Params       []*ParamDifference     `json:"params"`
+	Repos        []*RepoDifference      `json:"repos"`
+	Images       []*ImageDifference     `json:"images"`
+	Jobs         []*JobDifference       `json:"jobs"`
+	Tests        []*TestSuiteDifference `json:"tests"`
+}
+
+type ComparedTask struct {
+	TaskID      int64         `json:"task_id"`
+	Status      config.Status `json:"status"`
+	TaskCreator string        `json:"task_creator"`
+	CreateTime  int64         `json:"create_time"`
+}
+
+type ParamDifference struct {
+	Name string `json:"name"`
+	Base string `json:"base"`
+	Head string `json:"head"`
+}
+
+// RepoDifference is the commit range between the commits of a repo checked out by the two tasks, the
+// commits and the changed files are only resolved for github and gitlab.
+type RepoDifference struct {
+	CodehostID      int               `json:"codehost_id"`
+	RepoNamespace   string            `json:"repo_namespace"`
+	RepoName        string            `json:"repo_name"`
+	BaseBranch      string            `json:"base_branch"`
+	HeadBranch      string            `json:"head_branch"`
+	BaseCommit      string            `json:"base_commit"`
+	HeadCommit      string            `json:"head_commit"`
+	Commits         []*ComparedCommit `json:"commits"`
+	ChangedFiles    []string          `json:"changed_files"`
+	LockfileChanges []string          `json:"lockfile_changes"`
+	Error           string            `json:"error,omitempty"`
+}
+
+type ComparedCommit struct {
+	ID      string `json:"id"`
+	Author  string `json:"author"`
+	Message string `json:"message"`
+}
+
+type ImageDifference struct {
+	JobName       string                             `json:"job_name"`
+	BaseImage     string                             `json:"base_image"`
+	HeadImage     string                             `json:"head_image"`
+	BaseSize      int64                              `json:"base_size"`
+	HeadSize      int64                              `json:"head_size"`
+	SizeDelta     int64                              `json:"size_delta"`
+	AddedLayers   []*commonmodels.DeliveryImageLayer `json:"added_layers"`
+	RemovedLayers []*commonmodels.DeliveryImageLayer `json:"removed_layers"`
+	Error         string                             `json:"error,omitempty"`
+}
+
+type JobDifference struct {
+	JobName      string        `json:"job_name"`
+	JobType      string        `json:"job_type"`
+	BaseStatus   config.Status `json:"base_status"`
+	HeadStatus   config.Status `json:"head_status"`
+	BaseDuration int64         `json:"base_duration"`
+	HeadDuration int64         `json:"head_duration"`
+}
+
+// TestSuiteDifference compares the junit results reported by the same step of the two tasks.
+type TestSuiteDifference struct {
+	JobName      string   `json:"job_name"`
+	StepName     string   `json:"step_name"`
+	BaseTests    int      `json:"base_tests"`
+	HeadTests    int      `json:"head_tests"`
+	BaseFailures int      `json:"base_failures"`
+	HeadFailures int      `json:"head_failures"`
+	NewFailures  []string `json:"new_failures"`
+	Fixed        []string `json:"fixed"`
+}
+
+// CompareWorkflowTasks compares the head task with the base task of the workflow to find out what changed
+// between the two runs.
+func CompareWorkflowTasks(workflowName string, baseID, headID int64, logger *zap.SugaredLogger) (*WorkflowTaskComparison, error) {
+	base, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, baseID)
+	if err != nil {
+		logger.Errorf("failed to find task %d of workflow %s, err: %s", baseID, workflowName, err)
+		return nil, e.ErrGetTask.AddDesc(fmt.Sprintf("task %d not found", baseID))
+	}
+	head, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, headID)
+	if err != nil {
+		logger.Errorf("failed to find task %d of workflow %s, err: %s", headID, workflowName, err)
+		return nil, e.ErrGetTask.AddDesc(fmt.Sprintf("task %d not found", headID))
+	}
+
+	baseSnapshot, headSnapshot := newTaskSnapshot(base), newTaskSnapshot(head)
+	return &WorkflowTaskComparison{
+		WorkflowName: workflowName,
+		Base:         newComparedTask(base),
+		Head:         newComparedTask(head),
+		Params:       compareParams(base.Params, head.Params),
+		Repos:        compareRepos(baseSnapshot.repos, headSnapshot.repos, logger),
+		Images:       compareImages(baseSnapshot.images, headSnapshot.images, logger),
+		Jobs:         compareJobs(baseSnapshot.jobs, headSnapshot.jobs),
+		Tests:        compareTestSuites(baseSnapshot.tests, headSnapshot.tests),
+	}, nil
+}
+
+func newComparedTask(task *commonmodels.WorkflowTask) *ComparedTask {
+	return &ComparedTask{
+		TaskID:      task.TaskID,
+		Status:      task.Status,
+		TaskCreator: task.TaskCreator,
+		CreateTime:  task.CreateTime,
+	}
+}
+
+// taskSnapshot is what a task checked out, built and reported, keyed by the job names for the
+// images and the jobs.
+type taskSnapshot struct {
+	repos  map[string]*types.Repository
+	images map[string]string
+	jobs   map[string]*commonmodels.JobTask
+	tests  map[string]*commonmodels.TestSuite
+}
+
+func newTaskSnapshot(task *commonmodels.WorkflowTask) *taskSnapshot {
+	snapshot := &taskSnapshot{
+		repos:  make(map[string]*types.Repository),
+		images: make(map[string]string),
+		jobs:   make(map[string]*commonmodels.JobTask),
+		tests:  make(map[string]*commonmodels.TestSuite),
+	}
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			snapshot.jobs[job.Name] = job
+			if job.JobType != string(config.JobZadigBuild) && job.JobType != string(config.JobFreestyle) {
+				continue
+			}
+			spec := &commonmodels.JobTaskBuildSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				continue
+			}
+			for _, env := range spec.Properties.Envs {
+				if env.Key == "IMAGE" && env.Value != "" {
+					snapshot.images[job.Name] = env.Value
+				}
+			}
+			for _, step := range spec.Steps {
+				switch step.StepType {
+				case config.StepGit:
+					stepSpec := &stepspec.StepGitSpec{}
+					if err := commonmodels.IToi(step.Spec, stepSpec); err != nil {
+						continue
+					}
+					for _, repo := range stepSpec.Repos {
+						snapshot.repos[fmt.Sprintf("%d/%s/%s", repo.CodehostID, repo.GetRepoNamespace(), repo.RepoName)] = repo
+					}
+				case config.StepJunitReport:
+					suite := &commonmodels.TestSuite{}
+					if step.Result == nil || commonmodels.IToi(step.Result, suite) != nil {
+						continue
+					}
+					snapshot.tests[job.Name+"/"+step.Name] = suite
+				}
+			}
+		}
+	}
+	return snapshot
+}
+
+func compareParams(base, head []*commonmodels.Param) []*ParamDifference {
+	values := func(params []*commonmodels.Param) map[string]string {
+		resp := make(map[string]string)
+		for _, param := range params {
+			resp[param.Name] = param.Value
+			if param.IsCredential {
+				resp[param.Name] = setting.MaskValue
+			}
+		}
+		return resp
+	}
+	baseValues, headValues := values(base), values(head)
+
+	resp := make([]*ParamDifference, 0)
+	for _, name := range unionKeys(baseValues, headValues) {
+		if baseValues[name] != headValues[name] {
+			resp = append(resp, &ParamDifference{Name: name, Base: baseValues[name], Head: headValues[name]})
+		}
+	}
+	return resp
+}
+
+func compareRepos(base, head map[string]*types.Repository, logger *zap.SugaredLogger) []*RepoDifference {
+	resp := make([]*RepoDifference, 0)
+	for _, key := range unionKeys(base, head) {
+		baseRepo, headRepo := base[key], head[key]
+		repo := headRepo
+		if repo == nil {
+			repo = baseRepo
+		}
+		diff := &RepoDifference{
+			CodehostID:    repo.CodehostID,
+			RepoNamespace: repo.GetRepoNamespace(),
+			RepoName:      repo.RepoName,
+		}
+		if baseRepo != nil {
+			diff.BaseBranch, diff.BaseCommit = baseRepo.Branch, baseRepo.CommitID
+		}
+		if headRepo != nil {
+			diff.HeadBranch, diff.HeadCommit = headRepo.Branch, headRepo.CommitID
+		}
+		if diff.BaseCommit == diff.HeadCommit && diff.BaseBranch == diff.HeadBranch {
+			continue
+		}
+		if diff.BaseCommit != "" && diff.HeadCommit != "" && diff.BaseCommit != diff.HeadCommit {
+			if err := resolveCommitRange(repo, diff); err != nil {
+				logger.Warnf("failed to compare %s..%s of %s/%s, err: %s", diff.BaseCommit, diff.HeadCommit, diff.RepoNamespace, diff.RepoName, err)
+				diff.Error = err.Error()
+			}
+		}
+		resp = append(resp, diff)
+	}
+	return resp
+}
+
+// resolveCommitRange fills the commits and the changed files between the base commit and the head commit.
+func resolveCommitRange(repo *types.Repository, diff *RepoDifference) error {
+	ch, err := systemconfig.New().GetCodeHost(repo.CodehostID)
+	if err != nil {
+		return fmt.Errorf("failed to get codehost: %s", err)
+	}
+
+	switch ch.Type {
+	case systemconfig.GitHubProvider:
+		cli := git.NewClient(ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		comparison, _, err := cli.Repositories.CompareCommits(context.Background(), repo.RepoOwner, repo.RepoName, diff.BaseCommit, diff.HeadCommit)
+		if err != nil {
+			return err
+		}
+		for _, commit := range comparison.Commits {
+			diff.Commits = append(diff.Commits, &ComparedCommit{
+				ID:      commit.GetSHA(),
+				Author:  commit.GetCommit().GetAuthor().GetName(),
+				Message: commit.GetCommit().GetMessage(),
+			})
+		}
+		for _, file := range comparison.Files {
+			diff.ChangedFiles = append(diff.ChangedFiles, file.GetFilename())
+		}
+	case systemconfig.GitLabProvider:
+		cli, err := gitlab.NewClient(ch.ID, ch.Address, ch.AccessToken, ch.ProxyAddr(config.ProxyHTTPSAddr()), ch.UseProxy())
+		if err != nil {
+			return err
+		}
+		comparison, err := cli.CompareCommits(repo.GetRepoNamespace(), repo.RepoName, diff.BaseCommit, diff.HeadCommit)
+		if err != nil {
+			return err
+		}
+		for _, commit := range comparison.Commits {
+			diff.Commits = append(diff.Commits, &ComparedCommit{
+				ID:      commit.ID,
+				Author:  commit.AuthorName,
+				Message: commit.Message,
+			})
+		}
+		for _, d := range comparison.Diffs {
+			diff.ChangedFiles = append(diff.ChangedFiles, d.NewPath)
+		}
+	default:
+		return fmt.Errorf("commit comparison is not supported by codehost type %s", ch.Type)
+	}
+
+	for _, file := range diff.ChangedFiles {
+		if lockfiles.Has(path.Base(file)) {
+			diff.LockfileChanges = append(diff.LockfileChanges, file)
+		}
+	}
+	return nil
+}
+
+func compareImages(base, head map[string]string, logger *zap.SugaredLogger) []*ImageDifference {
+	resp := make([]*ImageDifference, 0)
+	var registries []*commonmodels.RegistryNamespace
+	for _, jobName := range unionKeys(base, head) {
+		if base[jobName] == head[jobName] {
+			continue
+		}
+		diff := &ImageDifference{JobName: jobName, BaseImage: base[jobName], HeadImage: head[jobName]}
+		resp = append(resp, diff)
+		if diff.BaseImage == "" || diff.HeadImage == "" {
+			continue
+		}
+
+		if registries == nil {
+			var err error
+			registries, err = commonservice.ListRegistryNamespaces("", true, logger)
+			if err != nil {
+				diff.Error = err.Error()
+				continue
+			}
+		}
+		baseInfo, err := getImageLayers(diff.BaseImage, registries, logger)
+		if err != nil {
+			diff.Error = err.Error()
+			continue
+		}
+		headInfo, err := getImageLayers(diff.HeadImage, registries, logger)
+		if err != nil {
+			diff.Error = err.Error()
+			continue
+		}
+		diff.BaseSize, diff.HeadSize = baseInfo.ImageSize, headInfo.ImageSize
+		diff.SizeDelta = headInfo.ImageSize - baseInfo.ImageSize
+		diff.AddedLayers = subtractLayers(headInfo.Layers, baseInfo.Layers)
+		diff.RemovedLayers = subtractLayers(baseInfo.Layers, headInfo.Layers)
+	}
+	return resp
+}
+
+func getImageLayers(image string, registries []*commonmodels.RegistryNamespace, logger *zap.SugaredLogger) (*commonmodels.DeliveryImage, error) {
+	reg := commonservice.FindImageRegistry(image, registries)
+	if reg == nil {
+		return nil, fmt.Errorf("image %s is not pushed to an integrated registry", image)
+	}
+
+	var regService registry.Service
+	if reg.AdvancedSetting != nil {
+		regService = registry.NewV2Service(reg.RegProvider, reg.AdvancedSetting.TLSEnabled, reg.AdvancedSetting.TLSCert)
+	} else {
+		regService = registry.NewV2Service(reg.RegProvider, true, "")
+	}
+	return regService.GetImageInfo(registry.GetRepoImageDetailOption{
+		Endpoint: registry.Endpoint{
+			Addr:      reg.RegAddr,
+			Ak:        reg.AccessKey,
+			Sk:        reg.SecretKey,
+			Namespace: reg.Namespace,
+			Region:    reg.Region,
+		},
+		Image: commonservice.ExtractImageName(image),
+		Tag:   commonservice.ExtractImageTag(image),
+	}, logger)
+}
+
+// subtractLayers returns the layers which are not in the others.
+func subtractLayers(layers, others []*commonmodels.DeliveryImageLayer) []*commonmodels.DeliveryImageLayer {
+	digests := sets.NewString()
+	for _, layer := range others {
+		digests.Insert(layer.Digest)
+	}
+	resp := make([]*commonmodels.DeliveryImageLayer, 0)
+	for _, layer := range layers {
+		if !digests.Has(layer.Digest) {
+			resp = append(resp, layer)
+		}
+	}
+	return resp
+}
+
+func compareJobs(base, head map[string]*commonmodels.JobTask) []*JobDifference {
+	duration := func(job *commonmodels.JobTask) int64 {
+		if job == nil || job.EndTime < job.StartTime {
+			return 0
+		}
+		return job.EndTime - job.StartTime
+	}
+
+	resp := make([]*JobDifference, 0)
+	for _, name := range unionKeys(base, head) {
+		diff := &JobDifference{JobName: name, BaseDuration: duration(base[name]), HeadDuration: duration(head[name])}
+		if job := base[name]; job != nil {
+			diff.JobType, diff.BaseStatus = job.JobType, job.Status
+		}
+		if job := head[name]; job != nil {
+			diff.JobType, diff.HeadStatus = job.JobType, job.Status
+		}
+		resp = append(resp, diff)
+	}
+	return resp
+}
+
+func compareTestSuites(base, head map[string]*commonmodels.TestSuite) []*TestSuiteDifference {
+	failed := func(suite *commonmodels.TestSuite) sets.String {
+		resp := sets.NewString()
+		if suite == nil {
+			return resp
+		}
+		for _, tc := range suite.TestCases {
+			if tc.Failure != nil || tc.Error != nil {
+				resp.Insert(tc.ClassName + "." + tc.Name)
+			}
+		}
+		return resp
+	}
+
+	resp := make([]*TestSuiteDifference, 0)
+	for _, key := range unionKeys(base, head) {
+		jobName, stepName := key, ""
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			jobName, stepName = key[:i], key[i+1:]
+		}
+		diff := &TestSuiteDifference{JobName: jobName, StepName: stepName}
+		if suite := base[key]; suite != nil {
+			diff.BaseTests, diff.BaseFailures = suite.Tests, suite.Failures+suite.Errors
+		}
+		if suite := head[key]; suite != nil {
+			diff.HeadTests, diff.HeadFailures = suite.Tests, suite.Failures+suite.Errors
+		}
+		baseFailed, headFailed := failed(base[key]), failed(head[key])
+		diff.NewFailures = headFailed.Difference(baseFailed).List()
+		diff.Fixed = baseFailed.Difference(headFailed).List()
+		resp = append(resp, diff)
+	}
+	return resp
+}
+
+// unionKeys returns the sorted keys of both maps.
+func unionKeys[T any](base, head map[string]T) []string {
+	keys := sets.NewString()
+	for key := range base {
+		keys.Insert(key)
+	}
+	for key := range head {
+		keys.Insert(key)
+	}
+	return keys.List()
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing workflow task comparison", func() {
+
+	Context("compareParams", func() {
+		It("should not report the changes of the credentials", func() {
+			diffs := compareParams([]*commonmodels.Param{
+				{Name: "version", Value: "v1"},
+				{Name: "env", Value: "dev"},
+				{Name: "token", Value: "a", IsCredential: true},
+			}, []*commonmodels.Param{
+				{Name: "version", Value: "v2"},
+				{Name: "env", Value: "dev"},
+				{Name: "token", Value: "b", IsCredential: true},
+			})
+			Expect(diffs).Should(HaveLen(1))
+			Expect(*diffs[0]).Should(Equal(ParamDifference{Name: "version", Base: "v1", Head: "v2"}))
+		})
+	})
+
+	Context("compareTestSuites", func() {
+		It("should find the new failures and the fixed cases", func() {
+			failure := &commonmodels.Failure{Message: "failed"}
+			diffs := compareTestSuites(map[string]*commonmodels.TestSuite{
+				"build/junit": {Tests: 2, Failures: 1, TestCases: []commonmodels.TestCase{
+					{ClassName: "a", Name: "t1", Failure: failure},
+					{ClassName: "a", Name: "t2"},
+				}},
+			}, map[string]*commonmodels.TestSuite{
+				"build/junit": {Tests: 2, Failures: 1, TestCases: []commonmodels.TestCase{
+					{ClassName: "a", Name: "t1"},
+					{ClassName: "a", Name: "t2", Failure: failure},
+				}},
+			})
+			Expect(diffs).Should(HaveLen(1))
+			Expect(diffs[0].JobName).Should(Equal("build"))
+			Expect(diffs[0].StepName).Should(Equal("junit"))
+			Expect(diffs[0].NewFailures).Should(Equal([]string{"a.t2"}))
+			Expect(diffs[0].Fixed).Should(Equal([]string{"a.t1"}))
+		})
+	})
+})
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	stepspec "github.com/koderover/zadig/pkg/types/step"
+)
+
+const maxSearchTaskPageSize = 100
+
+type SearchWorkflowTaskArgs struct {
+	Projects    []string
+	CommitID    string
+	Image       string
+	ParamName   string
+	ParamValue  string
+	TaskCreator string
+	PageNum     int
+	PageSize    int
+}
+
+type SearchWorkflowTaskResult struct {
+	ProjectName  string        `json:"project_name"`
+	WorkflowName string        `json:"workflow_name"`
+	TaskID       int64         `json:"task_id"`
+	Status       config.Status `json:"status"`
+	TaskCreator  string        `json:"task_creator"`
+	CreateTime   int64         `json:"create_time"`
+	StartTime    int64         `json:"start_time"`
+	EndTime      int64         `json:"end_time"`
+	Commits      []string      `json:"commits"`
+	Images       []string      `json:"images"`
+}
+
+type SearchWorkflowTaskResp struct {
+	Tasks []*SearchWorkflowTaskResult `json:"tasks"`
+	Total int64                       `json:"total"`
+}
+
+// SearchWorkflowTaskV4 searches the task history of the given projects by the commit, the image, the parameter
+// and the creator, so that it can be told which run produced an image or what a commit deployed.
+func SearchWorkflowTaskV4(args *SearchWorkflowTaskArgs, logger *zap.SugaredLogger) (*SearchWorkflowTaskResp, error) {
+	if args.CommitID == "" && args.Image == "" && args.ParamValue == "" && args.TaskCreator == "" {
+		return nil, e.ErrInvalidParam.AddDesc("at least one of commit, image, param value and creator is required")
+	}
+	if args.ParamName != "" && args.ParamValue == "" {
+		return nil, e.ErrInvalidParam.AddDesc("param value is required if param name is given")
+	}
+	if args.PageNum <= 0 {
+		args.PageNum = 1
+	}
+	if args.PageSize <= 0 || args.PageSize > maxSearchTaskPageSize {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("page size must be between 1 and %d", maxSearchTaskPageSize))
+	}
+
+	tasks, total, err := commonrepo.NewworkflowTaskv4Coll().Search(&commonrepo.SearchWorkflowTaskV4Option{
+		Projects:    args.Projects,
+		CommitID:    args.CommitID,
+		Image:       args.Image,
+		ParamName:   args.ParamName,
+		ParamValue:  args.ParamValue,
+		TaskCreator: args.TaskCreator,
+		Limit:       args.PageSize,
+		Skip:        (args.PageNum - 1) * args.PageSize,
+	})
+	if err != nil {
+		logger.Errorf("failed to search workflow tasks, err: %s", err)
+		return nil, e.ErrListTasks.AddErr(err)
+	}
+
+	resp := &SearchWorkflowTaskResp{Tasks: make([]*SearchWorkflowTaskResult, 0, len(tasks)), Total: total}
+	for _, task := range tasks {
+		commits, images := taskCommitsAndImages(task)
+		resp.Tasks = append(resp.Tasks, &SearchWorkflowTaskResult{
+			ProjectName:  task.ProjectName,
+			WorkflowName: task.WorkflowName,
+			TaskID:       task.TaskID,
+			Status:       task.Status,
+			TaskCreator:  task.TaskCreator,
+			CreateTime:   task.CreateTime,
+			StartTime:    task.StartTime,
+			EndTime:      task.EndTime,
+			Commits:      commits,
+			Images:       images,
+		})
+	}
+	return resp, nil
+}
+
+// taskCommitsAndImages collects the commits checked out and the images built or deployed by the task.
+func taskCommitsAndImages(task *commonmodels.WorkflowTask) ([]string, []string) {
+	commits, images := sets.NewString(), sets.NewString()
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			switch job.JobType {
+			case string(config.JobZadigBuild), string(config.JobFreestyle):
+				spec := &commonmodels.JobTaskBuildSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					continue
+				}
+				for _, env := range spec.Properties.Envs {
+					if env.Key == "IMAGE" && env.Value != "" {
+						images.Insert(env.Value)
+					}
+				}
+				for _, step := range spec.Steps {
+					if step.StepType != config.StepGit {
+						continue
+					}
+					stepSpec := &stepspec.StepGitSpec{}
+					if err := commonmodels.IToi(step.Spec, stepSpec); err != nil {
+						continue
+					}
+					for _, repo := range stepSpec.Repos {
+						if repo.CommitID != "" {
+							commits.Insert(repo.CommitID)
+						}
+					}
+				}
+			case string(config.JobZadigDeploy):
+				spec := &commonmodels.JobTaskDeploySpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					continue
+				}
+				if spec.Image != "" {
+					images.Insert(spec.Image)
+				}
+			case string(config.JobZadigHelmDeploy):
+				spec := &commonmodels.JobTaskHelmDeploySpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					continue
+				}
+				for _, module := range spec.ImageAndModules {
+					if module.Image != "" {
+						images.Insert(module.Image)
+					}
+				}
+			}
+		}
+	}
+	return commits.List(), images.List()
+}
@@ -29,6 +29,7 @@ import (
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/base"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	"github.com/koderover/zadig/pkg/setting"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 )
@@ -39,6 +40,10 @@ type TaskResp struct {
 }
 
 func CreateWorkflowTaskV3(args *commonmodels.WorkflowV3Args, username, reqID string, log *zap.SugaredLogger) (*TaskResp, error) {
+	if err := workflowcontroller.CheckMaintenance(); err != nil {
+		return nil, err
+	}
+
 	workflowV3, err := commonrepo.NewWorkflowV3Coll().GetByID(args.ID)
 	if err != nil {
 		log.Errorf("workflowV3.Find %s error: %s", args.Name, err)
@@ -18,27 +18,41 @@ package workflow
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
 	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
 	"github.com/koderover/zadig/pkg/setting"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/logannotation"
+	"github.com/koderover/zadig/pkg/tool/pagination"
 	"github.com/koderover/zadig/pkg/types"
 	stepspec "github.com/koderover/zadig/pkg/types/step"
 	"go.uber.org/zap"
 )
 
+const (
+	maxRunLabels           = 20
+	maxRunLabelValueLength = 256
+)
+
+// the keys of the run labels are used in the variables like {{.workflow.task.label.<key>}}.
+var runLabelKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_.-]{0,61}[a-zA-Z0-9])?$`)
+
 type CreateTaskV4Resp struct {
 	ProjectName  string `json:"project_name"`
 	WorkflowName string `json:"workflow_name"`
 	TaskID       int64  `json:"task_id"`
+	Hotfix       bool   `json:"hotfix,omitempty"`
 }
 
 type WorkflowTaskPreview struct {
@@ -55,6 +69,7 @@ type WorkflowTaskPreview struct {
 	ProjectName  string                `bson:"project_name"              json:"project_name"`
 	Error        string                `bson:"error,omitempty"           json:"error,omitempty"`
 	IsRestart    bool                  `bson:"is_restart"                json:"is_restart"`
+	Labels       map[string]string     `bson:"labels"                    json:"labels,omitempty"`
 }
 
 type StageTaskPreview struct {
@@ -75,6 +90,10 @@ type JobTaskPreview struct {
 	EndTime   int64         `bson:"end_time"       json:"end_time,omitempty"`
 	Error     string        `bson:"error"          json:"error"`
 	Spec      interface{}   `bson:"spec"           json:"spec"`
+	// Summaries are reported by the summary steps of the job.
+	Summaries []*commonmodels.JobSummary `bson:"summaries"      json:"summaries,omitempty"`
+	// Annotations link the errors in the log of the failed job to the source.
+	Annotations []*logannotation.Annotation `bson:"annotations" json:"annotations,omitempty"`
 }
 
 type ZadigBuildJobSpec struct {
@@ -126,6 +145,10 @@ func GetWorkflowv4Preset(encryptedKey, workflowName string, log *zap.SugaredLogg
 }
 
 func CreateWorkflowTaskV4(user string, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
+	if err := workflowcontroller.CheckMaintenance(); err != nil {
+		return nil, err
+	}
+
 	resp := &CreateTaskV4Resp{
 		ProjectName:  workflow.Project,
 		WorkflowName: workflow.Name,
@@ -133,6 +156,27 @@ func CreateWorkflowTaskV4(user string, workflow *commonmodels.WorkflowV4, log *z
 	if err := LintWorkflowV4(workflow, log); err != nil {
 		return resp, err
 	}
+	if err := lintRunLabels(workflow.RunLabels); err != nil {
+		return resp, e.ErrCreateTask.AddErr(err)
+	}
+	saved, err := commonrepo.NewWorkflowV4Coll().Find(workflow.Name)
+	if err != nil {
+		saved = nil
+	}
+	if saved != nil && saved.Archived {
+		return resp, e.ErrCreateTask.AddDesc(fmt.Sprintf("workflow %s is archived by %s", workflow.Name, saved.ArchivedBy))
+	}
+	keepExecutionHookSecrets(workflow.ExecutionHooks, saved)
+	if err := lintWorkflowV4ParamValues(saved, workflow, log); err != nil {
+		return resp, err
+	}
+	hotfix, hotfixReason, err := resolveHotfixRun(saved, workflow)
+	if err != nil {
+		return resp, e.ErrCreateTask.AddErr(err)
+	}
+	if err := commonservice.CheckWorkflowTaskQuota(workflow.Project, log); err != nil {
+		return resp, err
+	}
 	workflowTask := &commonmodels.WorkflowTask{}
 	// save workflow original workflow task args.
 	originTaskArgs := &commonmodels.WorkflowV4{}
@@ -166,6 +210,9 @@ func CreateWorkflowTaskV4(user string, workflow *commonmodels.WorkflowV4, log *z
 	workflowTask.Params = workflow.Params
 	workflowTask.KeyVals = workflow.KeyVals
 	workflowTask.MultiRun = workflow.MultiRun
+	workflowTask.Labels = workflow.RunLabels
+	workflowTask.Hotfix = hotfix
+	workflowTask.HotfixReason = hotfixReason
 
 	for _, stage := range workflow.Stages {
 		stageTask := &commonmodels.StageTask{
@@ -190,6 +237,12 @@ func CreateWorkflowTaskV4(user string, workflow *commonmodels.WorkflowV4, log *z
 					return resp, e.ErrCreateTask.AddDesc(err.Error())
 				}
 			}
+			if job.JobType == config.JobAndroidBuild {
+				if err := setAndroidBuildRepos(job, log); err != nil {
+					log.Errorf("android build job set build info error: %v", err)
+					return resp, e.ErrCreateTask.AddDesc(err.Error())
+				}
+			}
 
 			jobs, err := jobctl.ToJobs(job, workflow, nextTaskID)
 			if err != nil {
@@ -206,7 +259,12 @@ func CreateWorkflowTaskV4(user string, workflow *commonmodels.WorkflowV4, log *z
 	if err := workflowTaskLint(workflowTask, log); err != nil {
 		return resp, err
 	}
+	// the commits to build are resolved above, reject them before anything is deployed
+	if err := checkCommitPolicy(workflow, log); err != nil {
+		return resp, err
+	}
 
+	setDebugOnFailure(workflow.DebugOnFailure)
 	workflowTask.WorkflowArgs = workflow
 	workflowTask.Status = config.StatusCreated
 
@@ -214,6 +272,10 @@ func CreateWorkflowTaskV4(user string, workflow *commonmodels.WorkflowV4, log *z
 		log.Errorf("create workflow task error: %v", err)
 		return resp, e.ErrCreateTask.AddDesc(err.Error())
 	}
+	if hotfix {
+		resp.Hotfix = true
+		notifyHotfixRun(saved, workflowTask, log)
+	}
 	// Updating the comment in the git repository, this will not cause the function to return error if this function call fails
 	if err := scmnotify.NewService().UpdateWebhookCommentForWorkflowV4(workflowTask, log); err != nil {
 		log.Warnf("Failed to update comment for custom workflow %s, taskID: %d the error is: %s", workflowTask.WorkflowName, workflowTask.TaskID, err)
@@ -225,12 +287,28 @@ func CreateWorkflowTaskV4(user string, workflow *commonmodels.WorkflowV4, log *z
 	return resp, nil
 }
 
+func lintRunLabels(labels map[string]string) error {
+	if len(labels) > maxRunLabels {
+		return fmt.Errorf("at most %d labels are allowed for a task", maxRunLabels)
+	}
+	for key, value := range labels {
+		if !runLabelKeyRegex.MatchString(key) {
+			return fmt.Errorf("invalid label key %q, it must be at most 63 characters of letters, digits, '_', '.' and '-'", key)
+		}
+		if len(value) > maxRunLabelValueLength {
+			return fmt.Errorf("the value of label %s is longer than %d characters", key, maxRunLabelValueLength)
+		}
+	}
+	return nil
+}
+
 func CloneWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLogger) (*commonmodels.WorkflowV4, error) {
 	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
 	if err != nil {
 		logger.Errorf("find workflowTaskV4 error: %s", err)
 		return nil, e.ErrGetTask.AddErr(err)
 	}
+	maskExecutionHookSecrets(task.OriginWorkflowArgs)
 	return task.OriginWorkflowArgs, nil
 }
 
@@ -246,8 +324,12 @@ func UpdateWorkflowTaskV4(id string, workflowTask *commonmodels.WorkflowTask, lo
 	return nil
 }
 
-func ListWorkflowTaskV4(workflowName string, pageNum, pageSize int64, logger *zap.SugaredLogger) ([]*commonmodels.WorkflowTask, int64, error) {
-	resp, total, err := commonrepo.NewworkflowTaskv4Coll().List(&commonrepo.ListWorkflowTaskV4Option{WorkflowName: workflowName, Limit: int(pageSize), Skip: int((pageNum - 1) * pageSize)})
+// ListWorkflowTaskV4 lists the tasks of the workflow, only the tasks having all the labels are listed if labels is given.
+func ListWorkflowTaskV4(workflowName string, labels map[string]string, pageNum, pageSize int64, logger *zap.SugaredLogger) ([]*commonmodels.WorkflowTask, int64, error) {
+	if err := lintRunLabels(labels); err != nil {
+		return nil, 0, e.ErrInvalidParam.AddErr(err)
+	}
+	resp, total, err := commonrepo.NewworkflowTaskv4Coll().List(&commonrepo.ListWorkflowTaskV4Option{WorkflowName: workflowName, Labels: labels, Limit: int(pageSize), Skip: int((pageNum - 1) * pageSize)})
 	if err != nil {
 		logger.Errorf("list workflowTaskV4 error: %s", err)
 		return resp, total, err
@@ -255,6 +337,19 @@ func ListWorkflowTaskV4(workflowName string, pageNum, pageSize int64, logger *za
 	return resp, total, nil
 }
 
+// ListWorkflowTaskV4ByQuery lists the tasks with the cursor pagination.
+func ListWorkflowTaskV4ByQuery(workflowName string, labels map[string]string, q *pagination.Query, logger *zap.SugaredLogger) ([]*commonmodels.WorkflowTask, *pagination.Page, error) {
+	if err := lintRunLabels(labels); err != nil {
+		return nil, nil, e.ErrInvalidParam.AddErr(err)
+	}
+	resp, page, err := commonrepo.NewworkflowTaskv4Coll().ListByQuery(workflowName, labels, q)
+	if err != nil {
+		logger.Errorf("list workflowTaskV4 error: %s", err)
+		return nil, nil, e.ErrInvalidParam.AddErr(err)
+	}
+	return resp, page, nil
+}
+
 func CancelWorkflowTaskV4(userName, workflowName string, taskID int64, logger *zap.SugaredLogger) error {
 	if err := workflowcontroller.CancelWorkflowTask(userName, workflowName, taskID, logger); err != nil {
 		logger.Errorf("cancel workflowTaskV4 error: %s", err)
@@ -282,6 +377,7 @@ func GetWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLog
 		EndTime:      task.EndTime,
 		Error:        task.Error,
 		IsRestart:    task.IsRestart,
+		Labels:       task.Labels,
 	}
 	for _, stage := range task.Stages {
 		resp.Stages = append(resp.Stages, &StageTaskPreview{
@@ -310,16 +406,101 @@ func ApproveStage(workflowName, stageName, userName, userID, comment string, tas
 	return nil
 }
 
+func ConfirmDeployDiff(workflowName, jobName, userName string, taskID int64, approve bool, logger *zap.SugaredLogger) error {
+	if workflowName == "" || jobName == "" || taskID == 0 {
+		errMsg := fmt.Sprintf("can not find deploy job of workflow: %s, taskID: %d, job: %s", workflowName, taskID, jobName)
+		logger.Error(errMsg)
+		return e.ErrConfirmDeployDiff.AddDesc(errMsg)
+	}
+	if err := jobcontroller.ConfirmDeployDiff(workflowName, jobName, userName, taskID, approve); err != nil {
+		logger.Error(err)
+		return e.ErrConfirmDeployDiff.AddErr(err)
+	}
+	return nil
+}
+
+func ControlRollout(workflowName, jobName, userName string, taskID int64, action string, logger *zap.SugaredLogger) error {
+	if workflowName == "" || jobName == "" || taskID == 0 {
+		errMsg := fmt.Sprintf("can not find rollout job of workflow: %s, taskID: %d, job: %s", workflowName, taskID, jobName)
+		logger.Error(errMsg)
+		return e.ErrControlRollout.AddDesc(errMsg)
+	}
+	if err := jobcontroller.ControlRollout(workflowName, jobName, userName, taskID, action); err != nil {
+		logger.Error(err)
+		return e.ErrControlRollout.AddErr(err)
+	}
+	return nil
+}
+
+type RolloutProgress struct {
+	Version       string                          `json:"version"`
+	Status        config.Status                   `json:"status"`
+	CurrentWave   string                          `json:"current_wave"`
+	Paused        bool                            `json:"paused"`
+	ControlledBy  string                          `json:"controlled_by"`
+	TotalDeploys  int                             `json:"total_deploys"`
+	PassedDeploys int                             `json:"passed_deploys"`
+	FailedDeploys int                             `json:"failed_deploys"`
+	Waves         []*commonmodels.RolloutWaveTask `json:"waves"`
+}
+
+// GetRolloutProgress summarizes the deployments of a rollout job, the current wave is the first
+// wave not passed yet.
+func GetRolloutProgress(workflowName, jobName string, taskID int64, logger *zap.SugaredLogger) (*RolloutProgress, error) {
+	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	if err != nil {
+		logger.Errorf("find workflowTaskV4 error: %s", err)
+		return nil, e.ErrGetRolloutProgress.AddErr(err)
+	}
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != jobName || job.JobType != string(config.JobZadigRollout) {
+				continue
+			}
+			spec := &commonmodels.JobTaskZadigRolloutSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				logger.Errorf("failed to decode rollout job %s: %s", jobName, err)
+				return nil, e.ErrGetRolloutProgress.AddErr(err)
+			}
+			resp := &RolloutProgress{
+				Version:      spec.Version,
+				Status:       job.Status,
+				Paused:       spec.Paused,
+				ControlledBy: spec.ControlledBy,
+				Waves:        spec.Waves,
+			}
+			for _, wave := range spec.Waves {
+				if resp.CurrentWave == "" && wave.Status != config.StatusPassed {
+					resp.CurrentWave = wave.Name
+				}
+				for _, deploy := range wave.Deploys {
+					resp.TotalDeploys++
+					switch deploy.Status {
+					case config.StatusPassed:
+						resp.PassedDeploys++
+					case config.StatusFailed, config.StatusTimeout, config.StatusCancelled:
+						resp.FailedDeploys++
+					}
+				}
+			}
+			return resp, nil
+		}
+	}
+	return nil, e.ErrGetRolloutProgress.AddDesc(fmt.Sprintf("rollout job %s not found in task %d of workflow %s", jobName, taskID, workflowName))
+}
+
 func jobsToJobPreviews(jobs []*commonmodels.JobTask) []*JobTaskPreview {
 	resp := []*JobTaskPreview{}
 	for _, job := range jobs {
 		jobPreview := &JobTaskPreview{
-			Name:      job.Name,
-			Status:    job.Status,
-			StartTime: job.StartTime,
-			EndTime:   job.EndTime,
-			Error:     job.Error,
-			JobType:   job.JobType,
+			Name:        job.Name,
+			Status:      job.Status,
+			StartTime:   job.StartTime,
+			EndTime:     job.EndTime,
+			Error:       job.Error,
+			JobType:     job.JobType,
+			Summaries:   job.Summaries,
+			Annotations: job.Annotations,
 		}
 		switch job.JobType {
 		case string(config.FreestyleType):
@@ -345,6 +526,11 @@ func jobsToJobPreviews(jobs []*commonmodels.JobTask) []*JobTaskPreview {
 				}
 			}
 			spec.Envs = taskJobSpec.Properties.CustomEnvs
+			for _, env := range spec.Envs {
+				if env.IsCredential {
+					env.Value = setting.MaskValue
+				}
+			}
 			for _, step := range taskJobSpec.Steps {
 				if step.StepType == config.StepGit {
 					stepSpec := &stepspec.StepGitSpec{}
@@ -453,6 +639,18 @@ func setFreeStyleRepos(job *commonmodels.Job, logger *zap.SugaredLogger) error {
 	return nil
 }
 
+func setAndroidBuildRepos(job *commonmodels.Job, logger *zap.SugaredLogger) error {
+	spec := &commonmodels.AndroidBuildJobSpec{}
+	if err := commonmodels.IToi(job.Spec, spec); err != nil {
+		return err
+	}
+	if err := setManunalBuilds(spec.Repos, spec.Repos, logger); err != nil {
+		return err
+	}
+	job.Spec = spec
+	return nil
+}
+
 func workflowTaskLint(workflowTask *commonmodels.WorkflowTask, logger *zap.SugaredLogger) error {
 	if len(workflowTask.Stages) <= 0 {
 		errMsg := fmt.Sprintf("no stage found in workflow task: %s,taskID: %d", workflowTask.WorkflowName, workflowTask.TaskID)
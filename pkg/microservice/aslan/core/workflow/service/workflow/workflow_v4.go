@@ -18,11 +18,13 @@ package workflow
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
@@ -54,6 +56,9 @@ func CreateWorkflowV4(user string, workflow *commonmodels.WorkflowV4, logger *za
 	if err := LintWorkflowV4(workflow, logger); err != nil {
 		return err
 	}
+	if err := commonservice.ValidateCustomFields(config.CustomFieldScopeWorkflow, workflow.CustomFields, true); err != nil {
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
 
 	workflow.CreatedBy = user
 	workflow.UpdatedBy = user
@@ -73,6 +78,7 @@ func CreateWorkflowV4(user string, workflow *commonmodels.WorkflowV4, logger *za
 		logger.Errorf("Failed to create workflow v4, the error is: %s", err)
 		return e.ErrUpsertWorkflow.AddErr(err)
 	}
+	saveWorkflowV4Version(workflow.Project, workflow.Name, workflow, user, logger)
 	return nil
 }
 
@@ -85,11 +91,20 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 	if err := LintWorkflowV4(inputWorkflow, logger); err != nil {
 		return err
 	}
+	keepExecutionHookSecrets(inputWorkflow.ExecutionHooks, workflow)
+	// the custom fields are kept if they are not given, e.g. by the clients not aware of them.
+	if inputWorkflow.CustomFields == nil {
+		inputWorkflow.CustomFields = workflow.CustomFields
+	}
+	if err := commonservice.ValidateCustomFields(config.CustomFieldScopeWorkflow, inputWorkflow.CustomFields, false); err != nil {
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
 
 	inputWorkflow.UpdatedBy = user
 	inputWorkflow.UpdateTime = time.Now().Unix()
 	inputWorkflow.ID = workflow.ID
 	inputWorkflow.HookCtls = workflow.HookCtls
+	inputWorkflow.Archived, inputWorkflow.ArchivedBy, inputWorkflow.ArchivedAt = workflow.Archived, workflow.ArchivedBy, workflow.ArchivedAt
 
 	for _, stage := range inputWorkflow.Stages {
 		for _, job := range stage.Jobs {
@@ -107,9 +122,25 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 		logger.Errorf("update workflowV4 error: %s", err)
 		return e.ErrUpsertWorkflow.AddErr(err)
 	}
+	saveWorkflowV4Version(workflow.Project, name, inputWorkflow, user, logger)
 	return nil
 }
 
+// saveWorkflowV4Version saves the workflow so that it can be looked up as of a time, the workflow is saved as
+// deleted if it is nil.
+func saveWorkflowV4Version(projectName, name string, workflow *commonmodels.WorkflowV4, user string, logger *zap.SugaredLogger) {
+	version := &commonmodels.WorkflowV4Version{
+		ProjectName:  projectName,
+		WorkflowName: name,
+		Workflow:     workflow,
+		Deleted:      workflow == nil,
+		CreateBy:     user,
+	}
+	if err := commonrepo.NewWorkflowV4VersionColl().Create(version); err != nil {
+		logger.Warnf("Failed to save the version of workflow %s, err: %s", name, err)
+	}
+}
+
 func FindWorkflowV4(encryptedKey, name string, logger *zap.SugaredLogger) (*commonmodels.WorkflowV4, error) {
 	workflow, err := commonrepo.NewWorkflowV4Coll().Find(name)
 	if err != nil {
@@ -119,6 +150,7 @@ func FindWorkflowV4(encryptedKey, name string, logger *zap.SugaredLogger) (*comm
 	if err := ensureWorkflowV4Resp(encryptedKey, workflow, logger); err != nil {
 		return workflow, err
 	}
+	maskExecutionHookSecrets(workflow)
 	return workflow, err
 }
 
@@ -139,6 +171,13 @@ func DeleteWorkflowV4(name string, logger *zap.SugaredLogger) error {
 	if err := commonrepo.NewCounterColl().Delete("WorkflowTaskV4:" + name); err != nil {
 		log.Errorf("Counter.Delete error: %s", err)
 	}
+	if err := commonrepo.NewWorkflowPreferenceColl().DeleteByWorkflowName(name); err != nil {
+		log.Errorf("failed to delete preferences of workflow %s, err: %s", name, err)
+	}
+	if err := commonrepo.NewWorkflowBadgeColl().Delete(name); err != nil {
+		log.Errorf("failed to delete badge of workflow %s, err: %s", name, err)
+	}
+	saveWorkflowV4Version(workflow.Project, name, nil, "", logger)
 	return nil
 }
 
@@ -180,6 +219,14 @@ func ListWorkflowV4(projectName, userID string, names, v4Names []string, ignoreW
 	if err != nil {
 		return resp, err
 	}
+	favorites, err := commonrepo.NewFavoriteColl().List(&commonrepo.FavoriteArgs{UserID: userID, Type: string(config.WorkflowTypeV4)})
+	if err != nil {
+		logger.Warnf("Failed to list favorites, err: %s", err)
+	}
+	favoriteSet := sets.NewString()
+	for _, f := range favorites {
+		favoriteSet.Insert(f.Name)
+	}
 
 	for _, workflowModel := range workflowV4List {
 		stages := []string{}
@@ -205,6 +252,7 @@ func ListWorkflowV4(projectName, userID string, names, v4Names []string, ignoreW
 			BaseName:      workflowModel.BaseName,
 		}
 		getRecentTaskV4Info(workflow, tasks)
+		workflow.IsFavorite = favoriteSet.Has(workflowModel.Name)
 
 		resp = append(resp, workflow)
 	}
@@ -308,6 +356,18 @@ func ensureWorkflowV4Resp(encryptedKey string, workflow *commonmodels.WorkflowV4
 				}
 				job.Spec = spec
 			}
+			if job.JobType == config.JobAndroidBuild {
+				spec := &commonmodels.AndroidBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					logger.Errorf(err.Error())
+					return e.ErrFindWorkflow.AddErr(err)
+				}
+				if err := commonservice.EncryptKeyVals(encryptedKey, spec.Properties.Envs, logger); err != nil {
+					logger.Errorf(err.Error())
+					return e.ErrFindWorkflow.AddErr(err)
+				}
+				job.Spec = spec
+			}
 			if job.JobType == config.JobPlugin {
 				spec := &commonmodels.PluginJobSpec{}
 				if err := commonmodels.IToi(job.Spec, spec); err != nil {
@@ -342,6 +402,26 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 		return e.ErrUpsertWorkflow.AddErr(err)
 	}
 
+	switch workflow.DedupStrategy {
+	case "", config.DedupStrategyForceNew, config.DedupStrategyReuse, config.DedupStrategyAttach:
+	default:
+		err := fmt.Errorf("unknown dedup strategy %s", workflow.DedupStrategy)
+		logger.Errorf(err.Error())
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if err := validateAPIParamPolicy(workflow.APIParamPolicy); err != nil {
+		logger.Errorf(err.Error())
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if err := lintParamSources(workflow.Params); err != nil {
+		logger.Errorf(err.Error())
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if err := lintExecutionHooks(workflow.ExecutionHooks); err != nil {
+		logger.Errorf(err.Error())
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+
 	project := &template.Product{}
 	// for deploy center workflow, it doesn't belongs to any project, so we use a specical project name to distinguish it.
 	if workflow.Project != setting.EnterpriseProject {
@@ -375,6 +455,10 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 			logger.Errorf("duplicated stage name: %s", stage.Name)
 			return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("duplicated job name: %s", stage.Name))
 		}
+		if err := lintApproval(stage); err != nil {
+			logger.Error(err.Error())
+			return e.ErrUpsertWorkflow.AddErr(err)
+		}
 		stageBuildJobNameMap := make(map[string]string)
 		for _, job := range stage.Jobs {
 			if !stage.Parallel {
@@ -417,6 +501,78 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 	return nil
 }
 
+func lintApproval(stage *commonmodels.WorkflowStage) error {
+	if stage.Approval == nil || !stage.Approval.Enabled {
+		return nil
+	}
+	switch stage.Approval.Type {
+	case "", config.NativeApproval:
+	case config.WebhookApproval:
+		if stage.Approval.Webhook == nil || stage.Approval.Webhook.URL == "" {
+			return fmt.Errorf("approval of stage %s is delegated to a webhook without url", stage.Name)
+		}
+		if _, err := url.ParseRequestURI(stage.Approval.Webhook.URL); err != nil {
+			return fmt.Errorf("invalid approval webhook url of stage %s: %s", stage.Name, err)
+		}
+	default:
+		return fmt.Errorf("unknown approval type %s of stage %s", stage.Approval.Type, stage.Name)
+	}
+	return nil
+}
+
+func lintExecutionHooks(hooks []*commonmodels.ExecutionHook) error {
+	names := sets.NewString()
+	for _, hook := range hooks {
+		if hook.Name == "" {
+			return fmt.Errorf("name of the execution hooks should not be empty")
+		}
+		if names.Has(hook.Name) {
+			return fmt.Errorf("duplicated execution hook name: %s", hook.Name)
+		}
+		names.Insert(hook.Name)
+		switch hook.Event {
+		case config.ExecutionHookPreStart, config.ExecutionHookPostFinish:
+		default:
+			return fmt.Errorf("unknown event %s of execution hook %s", hook.Event, hook.Name)
+		}
+		if _, err := url.ParseRequestURI(hook.URL); err != nil {
+			return fmt.Errorf("invalid url of execution hook %s: %s", hook.Name, err)
+		}
+		if hook.Timeout < 0 || hook.MaxDelay < 0 {
+			return fmt.Errorf("timeout and max delay of execution hook %s should not be negative", hook.Name)
+		}
+	}
+	return nil
+}
+
+// maskExecutionHookSecrets hides the secrets of the execution hooks of the workflow returned to the users.
+func maskExecutionHookSecrets(workflow *commonmodels.WorkflowV4) {
+	if workflow == nil {
+		return
+	}
+	for _, hook := range workflow.ExecutionHooks {
+		if hook.Secret != "" {
+			hook.Secret = setting.MaskValue
+		}
+	}
+}
+
+// keepExecutionHookSecrets restores the secrets of the hooks given as the mask from the saved workflow,
+// i.e. the secrets not changed by the users are kept.
+func keepExecutionHookSecrets(hooks []*commonmodels.ExecutionHook, saved *commonmodels.WorkflowV4) {
+	secrets := make(map[string]string)
+	if saved != nil {
+		for _, hook := range saved.ExecutionHooks {
+			secrets[hook.Name] = hook.Secret
+		}
+	}
+	for _, hook := range hooks {
+		if hook.Secret == setting.MaskValue {
+			hook.Secret = secrets[hook.Name]
+		}
+	}
+}
+
 func CreateWebhookForWorkflowV4(workflowName string, input *commonmodels.WorkflowV4Hook, logger *zap.SugaredLogger) error {
 	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
 	if err != nil {
@@ -434,13 +590,24 @@ func CreateWebhookForWorkflowV4(workflowName string, input *commonmodels.Workflo
 		logger.Errorf(err.Error())
 		return e.ErrCreateWebhook.AddErr(err)
 	}
+	if input.MainRepo != nil {
+		if err := input.MainRepo.TagFilter.Validate(); err != nil {
+			return e.ErrCreateWebhook.AddErr(err)
+		}
+	}
 	err = commonservice.ProcessWebhook([]*models.WorkflowV4Hook{input}, nil, webhook.WorkflowV4Prefix+workflowName, logger)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to create webhook for workflow %s, the error is: %v", workflowName, err)
 		log.Error(errMsg)
 		return e.ErrCreateWebhook.AddDesc(errMsg)
 	}
-	workflow.HookCtls = append(workflow.HookCtls, input)
+	updatedHooks := append(workflow.HookCtls, input)
+	if err := commonservice.ProcessMergeGate(updatedHooks, workflow.HookCtls, workflowName, logger); err != nil {
+		errMsg := fmt.Sprintf("failed to set merge gate for workflow %s, the error is: %v", workflowName, err)
+		log.Error(errMsg)
+		return e.ErrCreateWebhook.AddDesc(errMsg)
+	}
+	workflow.HookCtls = updatedHooks
 	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
 		errMsg := fmt.Sprintf("failed to create webhook for workflow %s, the error is: %v", workflowName, err)
 		log.Error(errMsg)
@@ -474,12 +641,22 @@ func UpdateWebhookForWorkflowV4(workflowName string, input *commonmodels.Workflo
 		logger.Errorf(err.Error())
 		return e.ErrUpdateWebhook.AddErr(err)
 	}
+	if input.MainRepo != nil {
+		if err := input.MainRepo.TagFilter.Validate(); err != nil {
+			return e.ErrUpdateWebhook.AddErr(err)
+		}
+	}
 	err = commonservice.ProcessWebhook([]*models.WorkflowV4Hook{input}, []*models.WorkflowV4Hook{existHook}, webhook.WorkflowV4Prefix+workflowName, logger)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to update webhook for workflow %s, the error is: %v", workflowName, err)
 		log.Error(errMsg)
 		return e.ErrUpdateWebhook.AddDesc(errMsg)
 	}
+	if err := commonservice.ProcessMergeGate(updatedHooks, workflow.HookCtls, workflowName, logger); err != nil {
+		errMsg := fmt.Sprintf("failed to update merge gate for workflow %s, the error is: %v", workflowName, err)
+		log.Error(errMsg)
+		return e.ErrUpdateWebhook.AddDesc(errMsg)
+	}
 	workflow.HookCtls = updatedHooks
 	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
 		errMsg := fmt.Sprintf("failed to update webhook for workflow %s, the error is: %v", workflowName, err)
@@ -556,6 +733,11 @@ func DeleteWebhookForWorkflowV4(workflowName, triggerName string, logger *zap.Su
 		log.Error(errMsg)
 		return e.ErrDeleteWebhook.AddDesc(errMsg)
 	}
+	if err := commonservice.ProcessMergeGate(updatedHooks, workflow.HookCtls, workflowName, logger); err != nil {
+		errMsg := fmt.Sprintf("failed to remove merge gate for workflow %s, the error is: %v", workflowName, err)
+		log.Error(errMsg)
+		return e.ErrDeleteWebhook.AddDesc(errMsg)
+	}
 	workflow.HookCtls = updatedHooks
 	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
 		errMsg := fmt.Sprintf("failed to delete webhook for workflow %s, the error is: %v", workflowName, err)
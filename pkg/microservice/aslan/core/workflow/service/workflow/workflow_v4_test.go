@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("Testing execution hook secrets", func() {
+
+	saved := func() *commonmodels.WorkflowV4 {
+		return &commonmodels.WorkflowV4{ExecutionHooks: []*commonmodels.ExecutionHook{
+			{Name: "capacity", Secret: "s3cret"},
+			{Name: "audit"},
+		}}
+	}
+
+	It("should mask the secrets returned to the users", func() {
+		workflow := saved()
+		maskExecutionHookSecrets(workflow)
+		Expect(workflow.ExecutionHooks[0].Secret).To(Equal(setting.MaskValue))
+		Expect(workflow.ExecutionHooks[1].Secret).To(BeEmpty())
+	})
+	It("should keep the saved secrets sent back as the mask", func() {
+		hooks := []*commonmodels.ExecutionHook{
+			{Name: "capacity", Secret: setting.MaskValue},
+			{Name: "audit", Secret: "changed"},
+			{Name: "new", Secret: setting.MaskValue},
+		}
+		keepExecutionHookSecrets(hooks, saved())
+		Expect(hooks[0].Secret).To(Equal("s3cret"))
+		Expect(hooks[1].Secret).To(Equal("changed"))
+		Expect(hooks[2].Secret).To(BeEmpty())
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+type claims struct {
+	Name string `json:"name"`
+	UID  string `json:"uid"`
+	jwt.StandardClaims
+	// service is set if the token is signed by the service token key, i.e. the caller is one of the services.
+	service bool
+}
+
+type claimsKey struct{}
+
+// The REST API is authenticated by the gateway, the grpc server is exposed directly
+// so the token issued by the user service or signed by the other services is verified here.
+func authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(strings.ToLower(setting.AuthorizationHeader))
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "authorization token is required")
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer"))
+	if c, err := parseToken(token, config.ServiceTokenKey()); err == nil {
+		c.service = true
+		return context.WithValue(ctx, claimsKey{}, c), nil
+	}
+	c, err := parseToken(token, []byte(config.SecretKey()))
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %s", err)
+	}
+	return context.WithValue(ctx, claimsKey{}, c), nil
+}
+
+func parseToken(token string, key []byte) (*claims, error) {
+	c := &claims{}
+	_, err := jwt.ParseWithClaims(token, c, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key, nil
+	})
+	return c, err
+}
+
+func userFromContext(ctx context.Context) *claims {
+	if c, ok := ctx.Value(claimsKey{}).(*claims); ok {
+		return c
+	}
+	return &claims{Name: "system"}
+}
+
+// requireService rejects the callers other than the services, e.g. the users calling with their login tokens.
+func requireService(ctx context.Context) error {
+	if c, ok := ctx.Value(claimsKey{}).(*claims); ok && c.service {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "only the services are allowed to call this method")
+}
+
+func unaryAuthInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func streamAuthInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{strings.ToLower(setting.AuthorizationHeader): "Bearer " + string(t)}, nil
+}
+
+func (tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// DialOptions returns the options a client needs to talk to the grpc server with the given token,
+// the transport credentials should be provided by the caller.
+func DialOptions(token string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})),
+		grpc.WithPerRPCCredentials(tokenCredentials(token)),
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	policyservice "github.com/koderover/zadig/pkg/microservice/policy/core/service"
+)
+
+// the actions of the workflows checked by the policy service for the REST API.
+const (
+	verbGetWorkflow = "get_workflow"
+	verbRunWorkflow = "run_workflow"
+)
+
+// they are replaced in the tests.
+var (
+	projectOfWorkflow = func(workflowName string) (string, error) {
+		workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+		if err != nil {
+			return "", err
+		}
+		return workflow.Project, nil
+	}
+	workflowVerbAllowed = allowedByRoles
+)
+
+// authorizeWorkflow checks the caller is allowed to act on the workflow by the same role bindings as the
+// REST API, it returns the project of the workflow. The tokens not issued to a user are rejected.
+func authorizeWorkflow(ctx context.Context, workflowName, verb string) (string, error) {
+	user := userFromContext(ctx)
+	if user.UID == "" {
+		return "", status.Error(codes.PermissionDenied, "the token is not issued to a user")
+	}
+	projectName, err := projectOfWorkflow(workflowName)
+	if err != nil {
+		return "", status.Errorf(codes.NotFound, "failed to find workflow %s: %s", workflowName, err)
+	}
+	allowed, err := workflowVerbAllowed(user.UID, projectName, workflowName, verb, logger(ctx))
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to check the permission: %s", err)
+	}
+	if !allowed {
+		return "", status.Errorf(codes.PermissionDenied, "%s is not allowed to %s %s", user.Name, verb, workflowName)
+	}
+	return projectName, nil
+}
+
+func allowedByRoles(uid, projectName, workflowName, verb string, log *zap.SugaredLogger) (bool, error) {
+	isAdmin, err := policyservice.IsSystemAdmin(uid)
+	if err != nil || isAdmin {
+		return isAdmin, err
+	}
+	rules, err := policyservice.GetUserRulesByProject(uid, projectName, log)
+	if err != nil {
+		return false, err
+	}
+	if rules.IsProjectAdmin || sets.NewString(rules.ProjectVerbs...).Has(verb) {
+		return true, nil
+	}
+	return sets.NewString(rules.WorkflowVerbsMap[workflowName]...).Has(verb), nil
+}
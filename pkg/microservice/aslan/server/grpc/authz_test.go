@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent int
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) Send(interface{}) error {
+	s.sent++
+	return nil
+}
+
+type fakeTaskStatusStream struct{ *fakeStream }
+
+func (s fakeTaskStatusStream) Send(status *TaskStatus) error { return s.fakeStream.Send(status) }
+
+type fakeJobLogStream struct{ *fakeStream }
+
+func (s fakeJobLogStream) Send(log *JobLog) error { return s.fakeStream.Send(log) }
+
+var _ = Describe("the authorization of the workflow server", func() {
+	var (
+		server   *workflowServer
+		ctx      context.Context
+		checked  []string
+		allowed  map[string]bool
+		original = struct {
+			project func(string) (string, error)
+			allowed func(uid, projectName, workflowName, verb string, log *zap.SugaredLogger) (bool, error)
+		}{projectOfWorkflow, workflowVerbAllowed}
+	)
+
+	BeforeEach(func() {
+		server = &workflowServer{}
+		ctx = context.WithValue(context.Background(), claimsKey{}, &claims{Name: "alice", UID: "uid-alice"})
+		checked = nil
+		allowed = map[string]bool{}
+		projectOfWorkflow = func(string) (string, error) { return "payments", nil }
+		workflowVerbAllowed = func(uid, projectName, workflowName, verb string, _ *zap.SugaredLogger) (bool, error) {
+			checked = append(checked, uid+"/"+projectName+"/"+workflowName+"/"+verb)
+			return allowed[verb], nil
+		}
+	})
+
+	AfterEach(func() {
+		projectOfWorkflow = original.project
+		workflowVerbAllowed = original.allowed
+	})
+
+	expectDenied := func(err error) {
+		Expect(err).To(HaveOccurred())
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	}
+
+	It("denies triggering a task without the run permission", func() {
+		allowed[verbGetWorkflow] = true
+		_, err := server.TriggerTask(ctx, &TriggerTaskRequest{Workflow: &commonmodels.WorkflowV4{Name: "deploy", Project: "payments"}})
+		expectDenied(err)
+		Expect(checked).To(Equal([]string{"uid-alice/payments/deploy/run_workflow"}))
+	})
+
+	It("checks the project the workflow belongs to instead of the requested one", func() {
+		allowed[verbRunWorkflow] = true
+		_, err := server.TriggerTask(ctx, &TriggerTaskRequest{Workflow: &commonmodels.WorkflowV4{Name: "deploy", Project: "sandbox"}})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(checked).To(Equal([]string{"uid-alice/payments/deploy/run_workflow"}))
+	})
+
+	It("denies cancelling a task without the run permission", func() {
+		_, err := server.CancelTask(ctx, &TaskRequest{WorkflowName: "deploy", TaskID: 1})
+		expectDenied(err)
+	})
+
+	It("denies streaming the status and the logs without the view permission", func() {
+		stream := &fakeStream{ctx: ctx}
+		expectDenied(server.StreamTaskStatus(&TaskRequest{WorkflowName: "deploy", TaskID: 1}, fakeTaskStatusStream{stream}))
+		expectDenied(server.StreamJobLogs(&JobLogRequest{WorkflowName: "deploy", TaskID: 1, JobName: "build"}, fakeJobLogStream{stream}))
+		Expect(stream.sent).To(BeZero())
+		Expect(checked).To(Equal([]string{"uid-alice/payments/deploy/get_workflow", "uid-alice/payments/deploy/get_workflow"}))
+	})
+
+	It("denies the tokens not issued to a user", func() {
+		allowed[verbRunWorkflow] = true
+		ctx = context.WithValue(context.Background(), claimsKey{}, &claims{Name: "system"})
+		_, err := server.CancelTask(ctx, &TaskRequest{WorkflowName: "deploy", TaskID: 1})
+		expectDenied(err)
+		Expect(checked).To(BeEmpty())
+	})
+
+	It("returns the project of the workflow if it is allowed", func() {
+		allowed[verbRunWorkflow] = true
+		projectName, err := authorizeWorkflow(ctx, "deploy", verbRunWorkflow)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(projectName).To(Equal("payments"))
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+)
+
+// The contract of the codehost service, messages are encoded with the json codec. The typed client
+// is systemconfig.CodeHostGRPCClient, the messages of both sides are encoded to the same json.
+//
+//	service CodeHost {
+//	  rpc GetCodeHost(GetCodeHostRequest) returns (CodeHost);
+//	  rpc ListByProvider(ListByProviderRequest) returns (ListByProviderResponse);
+//	  rpc ResolveTokenForRepo(ResolveTokenRequest) returns (RepoToken);
+//	}
+const codeHostServiceName = "zadig.systemconfig.v1.CodeHost"
+
+type GetCodeHostRequest struct {
+	ID           int  `json:"id"`
+	IgnoreDelete bool `json:"ignore_delete"`
+	BypassCache  bool `json:"bypass_cache"`
+}
+
+type ListByProviderRequest struct {
+	Provider string `json:"provider"`
+}
+
+type ListByProviderResponse struct {
+	CodeHosts []*models.CodeHost `json:"codehosts"`
+}
+
+type ResolveTokenRequest struct {
+	Address string `json:"address"`
+	Owner   string `json:"owner"`
+	Source  string `json:"source"`
+}
+
+type CodeHostServer interface {
+	GetCodeHost(context.Context, *GetCodeHostRequest) (*models.CodeHost, error)
+	ListByProvider(context.Context, *ListByProviderRequest) (*ListByProviderResponse, error)
+	ResolveTokenForRepo(context.Context, *ResolveTokenRequest) (*systemconfig.RepoToken, error)
+}
+
+func RegisterCodeHostServer(s grpc.ServiceRegistrar, srv CodeHostServer) {
+	s.RegisterService(&codeHostServiceDesc, srv)
+}
+
+var codeHostServiceDesc = grpc.ServiceDesc{
+	ServiceName: codeHostServiceName,
+	HandlerType: (*CodeHostServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetCodeHost", Handler: getCodeHostHandler},
+		{MethodName: "ListByProvider", Handler: listByProviderHandler},
+		{MethodName: "ResolveTokenForRepo", Handler: resolveTokenForRepoHandler},
+	},
+}
+
+func getCodeHostHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCodeHostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeHostServer).GetCodeHost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + codeHostServiceName + "/GetCodeHost"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeHostServer).GetCodeHost(ctx, req.(*GetCodeHostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func listByProviderHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListByProviderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeHostServer).ListByProvider(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + codeHostServiceName + "/ListByProvider"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeHostServer).ListByProvider(ctx, req.(*ListByProviderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func resolveTokenForRepoHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeHostServer).ResolveTokenForRepo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + codeHostServiceName + "/ResolveTokenForRepo"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeHostServer).ResolveTokenForRepo(ctx, req.(*ResolveTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	codehostservice "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/service"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// codeHostServer serves the lookups of the codehosts by the other services, it shares the service
+// functions and the in-memory cache with the REST API. The codehosts are returned with the credentials,
+// so only the services are allowed to call it.
+type codeHostServer struct{}
+
+func (s *codeHostServer) GetCodeHost(ctx context.Context, req *GetCodeHostRequest) (*models.CodeHost, error) {
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+	codeHost, err := codehostservice.GetCodeHost(req.ID, req.IgnoreDelete, req.BypassCache, logger(ctx))
+	if err != nil {
+		return nil, codeHostStatusError(err)
+	}
+	return codeHost, nil
+}
+
+func (s *codeHostServer) ListByProvider(ctx context.Context, req *ListByProviderRequest) (*ListByProviderResponse, error) {
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+	if req.Provider == "" {
+		return nil, status.Error(codes.InvalidArgument, "provider is required")
+	}
+	codeHosts, err := codehostservice.ListInternal("", "", req.Provider, logger(ctx))
+	if err != nil {
+		return nil, codeHostStatusError(err)
+	}
+	return &ListByProviderResponse{CodeHosts: codeHosts}, nil
+}
+
+func (s *codeHostServer) ResolveTokenForRepo(ctx context.Context, req *ResolveTokenRequest) (*systemconfig.RepoToken, error) {
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+	token, err := codehostservice.ResolveTokenForRepo(req.Address, req.Owner, req.Source, logger(ctx))
+	if err != nil {
+		return nil, codeHostStatusError(err)
+	}
+	return token, nil
+}
+
+// codeHostStatusError responds the disabled codehost with FailedPrecondition, so that the clients
+// can tell it from the other errors like the REST clients do with the error code.
+func codeHostStatusError(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return status.Error(codes.NotFound, "codehost not found")
+	}
+	var httpErr *e.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code() == e.ErrCodeHostDisabled.Code() {
+		reason, _ := httpErr.Extra()["reason"].(string)
+		return status.Error(codes.FailedPrecondition, reason)
+	}
+	return toStatusError(err)
+}
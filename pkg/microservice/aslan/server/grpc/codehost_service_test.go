@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("the authentication of the codehost server", func() {
+	var server *codeHostServer
+
+	signed := func(key []byte, uid string) context.Context {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"name": "alice",
+			"uid":  uid,
+			"exp":  time.Now().Add(time.Minute).Unix(),
+		}).SignedString(key)
+		Expect(err).NotTo(HaveOccurred())
+		md := metadata.Pairs(strings.ToLower(setting.AuthorizationHeader), "Bearer "+token)
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+
+	BeforeEach(func() {
+		server = &codeHostServer{}
+		viper.Set(setting.ENVSecretKey, "secret")
+	})
+
+	AfterEach(func() {
+		viper.Set(setting.ENVSecretKey, "")
+	})
+
+	It("authenticates the tokens signed by the services as the service", func() {
+		ctx, err := authenticate(signed(config.ServiceTokenKey(), ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requireService(ctx)).To(Succeed())
+	})
+
+	It("denies the codehost credentials to the login tokens of the users", func() {
+		ctx, err := authenticate(signed([]byte(config.SecretKey()), "uid-alice"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userFromContext(ctx).UID).To(Equal("uid-alice"))
+
+		_, err = server.GetCodeHost(ctx, &GetCodeHostRequest{ID: 1})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = server.ListByProvider(ctx, &ListByProviderRequest{Provider: "gitlab"})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		_, err = server.ResolveTokenForRepo(ctx, &ResolveTokenRequest{Address: "https://gitlab.com", Owner: "koderover"})
+		Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+	})
+
+	It("rejects the tokens signed by other keys", func() {
+		_, err := authenticate(signed([]byte("other"), "uid-alice"))
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+	})
+})
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const address = ":25002"
+
+// Serve starts the grpc server which is used by the programmatic clients in addition to the REST API,
+// it is stopped gracefully when the context is done.
+func Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Errorf("Failed to listen on %s, error: %s", address, err)
+		return err
+	}
+
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(jsonCodec{}),
+		grpc.UnaryInterceptor(unaryAuthInterceptor),
+		grpc.StreamInterceptor(streamAuthInterceptor),
+	)
+	RegisterWorkflowServer(server, &workflowServer{})
+	RegisterCodeHostServer(server, &codeHostServer{})
+
+	go func() {
+		<-ctx.Done()
+
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			server.GracefulStop()
+		}()
+		// log streams may last long, they are closed forcibly after the timeout.
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			server.Stop()
+		}
+	}()
+
+	log.Infof("Grpc server started at %s", address)
+	return server.Serve(listener)
+}
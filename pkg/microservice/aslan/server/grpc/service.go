@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	logservice "github.com/koderover/zadig/pkg/microservice/aslan/core/log/service"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const taskStatusPollInterval = 2 * time.Second
+
+type workflowServer struct{}
+
+func (s *workflowServer) TriggerTask(ctx context.Context, req *TriggerTaskRequest) (*TriggerTaskResponse, error) {
+	if req.Workflow == nil {
+		return nil, status.Error(codes.InvalidArgument, "workflow is required")
+	}
+	projectName, err := authorizeWorkflow(ctx, req.Workflow.Name, verbRunWorkflow)
+	if err != nil {
+		return nil, err
+	}
+	if req.Workflow.Project == "" {
+		req.Workflow.Project = projectName
+	}
+	if req.Workflow.Project != projectName {
+		return nil, status.Errorf(codes.InvalidArgument, "workflow %s does not belong to project %s", req.Workflow.Name, req.Workflow.Project)
+	}
+	// the grpc server is only used by external automations
+	if err := workflowservice.CheckAPIParamPolicy(req.Workflow, logger(ctx)); err != nil {
+		return nil, toStatusError(err)
+	}
+	resp, err := workflowservice.CreateWorkflowTaskV4(userFromContext(ctx).Name, req.Workflow, logger(ctx))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &TriggerTaskResponse{
+		ProjectName:  resp.ProjectName,
+		WorkflowName: resp.WorkflowName,
+		TaskID:       resp.TaskID,
+	}, nil
+}
+
+func (s *workflowServer) CancelTask(ctx context.Context, req *TaskRequest) (*CancelTaskResponse, error) {
+	if _, err := authorizeWorkflow(ctx, req.WorkflowName, verbRunWorkflow); err != nil {
+		return nil, err
+	}
+	if err := workflowservice.CancelWorkflowTaskV4(userFromContext(ctx).Name, req.WorkflowName, req.TaskID, logger(ctx)); err != nil {
+		return nil, toStatusError(err)
+	}
+	return &CancelTaskResponse{}, nil
+}
+
+// StreamTaskStatus sends the status of the task whenever it changes, the stream is closed
+// after the task is completed.
+func (s *workflowServer) StreamTaskStatus(req *TaskRequest, stream TaskStatusServerStream) error {
+	if _, err := authorizeWorkflow(stream.Context(), req.WorkflowName, verbGetWorkflow); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(taskStatusPollInterval)
+	defer ticker.Stop()
+
+	var last *TaskStatus
+	for {
+		task, err := commonrepo.NewworkflowTaskv4Coll().Find(req.WorkflowName, req.TaskID)
+		if err != nil {
+			return status.Errorf(codes.NotFound, "failed to find task %d of workflow %s: %s", req.TaskID, req.WorkflowName, err)
+		}
+		current := taskToStatus(task)
+		if !reflect.DeepEqual(current, last) {
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+			last = current
+		}
+		if taskCompleted(task.Status) {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *workflowServer) StreamJobLogs(req *JobLogRequest, stream JobLogServerStream) error {
+	if _, err := authorizeWorkflow(stream.Context(), req.WorkflowName, verbGetWorkflow); err != nil {
+		return err
+	}
+	if req.TailLines <= 0 {
+		req.TailLines = 10
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	streamChan := make(chan interface{}, 10)
+	go func() {
+		defer close(streamChan)
+		logservice.WorkflowTaskV4ContainerLogStream(ctx, streamChan, &logservice.GetContainerOptions{
+			Namespace:    config.Namespace(),
+			PipelineName: req.WorkflowName,
+			SubTask:      req.JobName,
+			TaskID:       req.TaskID,
+			TailLines:    req.TailLines,
+		}, logger(ctx))
+	}()
+
+	for msg := range streamChan {
+		line, ok := msg.(string)
+		if !ok {
+			continue
+		}
+		if err := stream.Send(&JobLog{Content: line}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func taskToStatus(task *commonmodels.WorkflowTask) *TaskStatus {
+	resp := &TaskStatus{
+		WorkflowName: task.WorkflowName,
+		TaskID:       task.TaskID,
+		Status:       task.Status,
+		Error:        task.Error,
+		StartTime:    task.StartTime,
+		EndTime:      task.EndTime,
+	}
+	for _, stage := range task.Stages {
+		stageStatus := &StageStatus{Name: stage.Name, Status: stage.Status}
+		for _, job := range stage.Jobs {
+			stageStatus.Jobs = append(stageStatus.Jobs, &JobStatus{
+				Name:    job.Name,
+				JobType: job.JobType,
+				Status:  job.Status,
+				Error:   job.Error,
+			})
+		}
+		resp.Stages = append(resp.Stages, stageStatus)
+	}
+	return resp
+}
+
+func taskCompleted(s config.Status) bool {
+	switch s {
+	case config.StatusPassed, config.StatusFailed, config.StatusTimeout, config.StatusCancelled, config.StatusReject:
+		return true
+	}
+	return false
+}
+
+func toStatusError(err error) error {
+	var httpErr *e.HTTPError
+	if !errors.As(err, &httpErr) {
+		return status.Error(codes.Unknown, err.Error())
+	}
+	code := codes.Unknown
+	switch httpErr.Code() {
+	case 400:
+		code = codes.InvalidArgument
+	case 401:
+		code = codes.Unauthenticated
+	case 403:
+		code = codes.PermissionDenied
+	case 404:
+		code = codes.NotFound
+	case 500:
+		code = codes.Internal
+	}
+	return status.Error(code, httpErr.Error())
+}
+
+func logger(ctx context.Context) *zap.SugaredLogger {
+	return log.SugaredLogger().With("user", userFromContext(ctx).Name)
+}
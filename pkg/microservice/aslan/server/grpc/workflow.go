@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// The contract of the workflow service, messages are encoded with the json codec.
+//
+//	service Workflow {
+//	  rpc TriggerTask(TriggerTaskRequest) returns (TriggerTaskResponse);
+//	  rpc CancelTask(TaskRequest) returns (CancelTaskResponse);
+//	  rpc StreamTaskStatus(TaskRequest) returns (stream TaskStatus);
+//	  rpc StreamJobLogs(JobLogRequest) returns (stream JobLog);
+//	}
+const workflowServiceName = "zadig.aslan.v1.Workflow"
+
+type TriggerTaskRequest struct {
+	Workflow *commonmodels.WorkflowV4 `json:"workflow"`
+}
+
+type TriggerTaskResponse struct {
+	ProjectName  string `json:"project_name"`
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+}
+
+type TaskRequest struct {
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+}
+
+type CancelTaskResponse struct{}
+
+type TaskStatus struct {
+	WorkflowName string         `json:"workflow_name"`
+	TaskID       int64          `json:"task_id"`
+	Status       config.Status  `json:"status"`
+	Error        string         `json:"error,omitempty"`
+	StartTime    int64          `json:"start_time"`
+	EndTime      int64          `json:"end_time"`
+	Stages       []*StageStatus `json:"stages"`
+}
+
+type StageStatus struct {
+	Name   string        `json:"name"`
+	Status config.Status `json:"status"`
+	Jobs   []*JobStatus  `json:"jobs"`
+}
+
+type JobStatus struct {
+	Name    string        `json:"name"`
+	JobType string        `json:"job_type"`
+	Status  config.Status `json:"status"`
+	Error   string        `json:"error,omitempty"`
+}
+
+type JobLogRequest struct {
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+	JobName      string `json:"job_name"`
+	TailLines    int64  `json:"tail_lines"`
+}
+
+type JobLog struct {
+	Content string `json:"content"`
+}
+
+type WorkflowServer interface {
+	TriggerTask(context.Context, *TriggerTaskRequest) (*TriggerTaskResponse, error)
+	CancelTask(context.Context, *TaskRequest) (*CancelTaskResponse, error)
+	StreamTaskStatus(*TaskRequest, TaskStatusServerStream) error
+	StreamJobLogs(*JobLogRequest, JobLogServerStream) error
+}
+
+type TaskStatusServerStream interface {
+	Send(*TaskStatus) error
+	grpc.ServerStream
+}
+
+type JobLogServerStream interface {
+	Send(*JobLog) error
+	grpc.ServerStream
+}
+
+func RegisterWorkflowServer(s grpc.ServiceRegistrar, srv WorkflowServer) {
+	s.RegisterService(&workflowServiceDesc, srv)
+}
+
+var workflowServiceDesc = grpc.ServiceDesc{
+	ServiceName: workflowServiceName,
+	HandlerType: (*WorkflowServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "TriggerTask", Handler: triggerTaskHandler},
+		{MethodName: "CancelTask", Handler: cancelTaskHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "StreamTaskStatus", Handler: streamTaskStatusHandler, ServerStreams: true},
+		{StreamName: "StreamJobLogs", Handler: streamJobLogsHandler, ServerStreams: true},
+	},
+}
+
+func triggerTaskHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkflowServer).TriggerTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + workflowServiceName + "/TriggerTask"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkflowServer).TriggerTask(ctx, req.(*TriggerTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func cancelTaskHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkflowServer).CancelTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + workflowServiceName + "/CancelTask"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkflowServer).CancelTask(ctx, req.(*TaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func streamTaskStatusHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(TaskRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(WorkflowServer).StreamTaskStatus(in, &taskStatusServerStream{stream})
+}
+
+func streamJobLogsHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(JobLogRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(WorkflowServer).StreamJobLogs(in, &jobLogServerStream{stream})
+}
+
+type taskStatusServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *taskStatusServerStream) Send(m *TaskStatus) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+type jobLogServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *jobLogServerStream) Send(m *JobLog) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+// WorkflowClient is the typed client of the workflow service, the connection should be
+// created with DialOptions so that the json codec and the user token are used.
+type WorkflowClient interface {
+	TriggerTask(ctx context.Context, in *TriggerTaskRequest, opts ...grpc.CallOption) (*TriggerTaskResponse, error)
+	CancelTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*CancelTaskResponse, error)
+	StreamTaskStatus(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (TaskStatusClientStream, error)
+	StreamJobLogs(ctx context.Context, in *JobLogRequest, opts ...grpc.CallOption) (JobLogClientStream, error)
+}
+
+type TaskStatusClientStream interface {
+	Recv() (*TaskStatus, error)
+	grpc.ClientStream
+}
+
+type JobLogClientStream interface {
+	Recv() (*JobLog, error)
+	grpc.ClientStream
+}
+
+type workflowClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWorkflowClient(cc grpc.ClientConnInterface) WorkflowClient {
+	return &workflowClient{cc: cc}
+}
+
+func (c *workflowClient) TriggerTask(ctx context.Context, in *TriggerTaskRequest, opts ...grpc.CallOption) (*TriggerTaskResponse, error) {
+	out := new(TriggerTaskResponse)
+	if err := c.cc.Invoke(ctx, "/"+workflowServiceName+"/TriggerTask", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workflowClient) CancelTask(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*CancelTaskResponse, error) {
+	out := new(CancelTaskResponse)
+	if err := c.cc.Invoke(ctx, "/"+workflowServiceName+"/CancelTask", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workflowClient) StreamTaskStatus(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (TaskStatusClientStream, error) {
+	stream, err := c.newServerStream(ctx, 0, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &taskStatusClientStream{stream}, nil
+}
+
+func (c *workflowClient) StreamJobLogs(ctx context.Context, in *JobLogRequest, opts ...grpc.CallOption) (JobLogClientStream, error) {
+	stream, err := c.newServerStream(ctx, 1, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &jobLogClientStream{stream}, nil
+}
+
+func (c *workflowClient) newServerStream(ctx context.Context, index int, in interface{}, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	desc := &workflowServiceDesc.Streams[index]
+	stream, err := c.cc.NewStream(ctx, desc, "/"+workflowServiceName+"/"+desc.StreamName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+type taskStatusClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *taskStatusClientStream) Recv() (*TaskStatus, error) {
+	m := new(TaskStatus)
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type jobLogClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *jobLogClientStream) Recv() (*JobLog, error) {
+	m := new(JobLog)
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -23,6 +23,7 @@ import (
 
 	cachehandler "github.com/koderover/zadig/pkg/handler/cache"
 	buildhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/build/handler"
+	chatopshandler "github.com/koderover/zadig/pkg/microservice/aslan/core/chatops/handler"
 	codehosthandler "github.com/koderover/zadig/pkg/microservice/aslan/core/code/handler"
 	collaborationhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/collaboration/handler"
 	commonhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/common/handler"
@@ -31,12 +32,18 @@ import (
 	environmenthandler "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/handler"
 	labelhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/label/handler"
 	loghandler "github.com/koderover/zadig/pkg/microservice/aslan/core/log/handler"
+	marketplacehandler "github.com/koderover/zadig/pkg/microservice/aslan/core/marketplace/handler"
 	multiclusterhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/handler"
 	projecthandler "github.com/koderover/zadig/pkg/microservice/aslan/core/project/handler"
+	quotahandler "github.com/koderover/zadig/pkg/microservice/aslan/core/quota/handler"
+	runnerhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/runner/handler"
 	servicehandler "github.com/koderover/zadig/pkg/microservice/aslan/core/service/handler"
+	sharehandler "github.com/koderover/zadig/pkg/microservice/aslan/core/share/handler"
 	stathandler "github.com/koderover/zadig/pkg/microservice/aslan/core/stat/handler"
 	systemhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/system/handler"
 	templatehandler "github.com/koderover/zadig/pkg/microservice/aslan/core/templatestore/handler"
+	tenanthandler "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/handler"
+	webhookrelayhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/webhookrelay/handler"
 	workflowhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/handler"
 	testinghandler "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/handler"
 	evaluationhandler "github.com/koderover/zadig/pkg/microservice/picket/core/evaluation/handler"
@@ -49,6 +56,7 @@ import (
 	emailHandler "github.com/koderover/zadig/pkg/microservice/systemconfig/core/email/handler"
 	featuresHandler "github.com/koderover/zadig/pkg/microservice/systemconfig/core/features/handler"
 	jiraHandler "github.com/koderover/zadig/pkg/microservice/systemconfig/core/jira/handler"
+	metricsHandler "github.com/koderover/zadig/pkg/microservice/systemconfig/core/metrics/handler"
 	userHandler "github.com/koderover/zadig/pkg/microservice/user/core/handler"
 
 	// Note: have to load docs for swagger to work. See https://blog.csdn.net/weixin_43249914/article/details/103035711
@@ -74,7 +82,13 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 			c.Request.URL.Path = "/api/workflow/webhook"
 			s.HandleContext(c)
 		})
+		public.POST("/webhook/relay", func(c *gin.Context) {
+			c.Request.URL.Path = "/api/webhookrelay/relay"
+			s.HandleContext(c)
+		})
 		public.GET("/health", commonhandler.Health)
+		public.GET("/health/clients", commonhandler.ClientHealth)
+		public.GET("/healthz", systemhandler.Healthz)
 		public.POST("/callback", commonhandler.HandleCallback)
 	}
 
@@ -82,6 +96,7 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 		"/openapi/statistics": new(stathandler.OpenAPIRouter),
 		"/openapi/projects":   new(projecthandler.OpenAPIRouter),
 		"/openapi/system":     new(systemhandler.OpenAPIRouter),
+		"/openapi/delivery":   new(deliveryhandler.OpenAPIRouter),
 	} {
 		r.Inject(router.Group(name))
 	}
@@ -109,7 +124,14 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 		"/api/collaboration": new(collaborationhandler.Router),
 		"/api/label":         new(labelhandler.Router),
 		"/api/stat":          new(stathandler.Router),
+		"/api/chatops":       new(chatopshandler.Router),
+		"/api/marketplace":   new(marketplacehandler.Router),
+		"/api/webhookrelay":  new(webhookrelayhandler.Router),
+		"/api/tenant":        new(tenanthandler.Router),
 		"/api/cache":         cachehandler.NewRouter(),
+		"/api/runner":        new(runnerhandler.Router),
+		"/api/share":         new(sharehandler.Router),
+		"/api/quota":         new(quotahandler.Router),
 	} {
 		r.Inject(router.Group(name))
 	}
@@ -121,6 +143,7 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 		new(jiraHandler.Router),
 		new(configcodehostHandler.Router),
 		new(featuresHandler.Router),
+		new(metricsHandler.Router),
 	} {
 		r.Inject(router.Group("/api/v1"))
 	}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core"
+	"github.com/koderover/zadig/pkg/microservice/aslan/server/grpc"
 	"github.com/koderover/zadig/pkg/microservice/aslan/server/rest"
 	"github.com/koderover/zadig/pkg/tool/kube/client"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -63,6 +64,12 @@ func Serve(ctx context.Context) error {
 		}
 	}()
 
+	go func() {
+		if err := grpc.Serve(ctx); err != nil {
+			log.Errorf("Failed to start grpc server, error: %s", err)
+		}
+	}()
+
 	// pprof service, you can access it by {your_ip}:8888/debug/pprof
 	go func() {
 		err := http.ListenAndServe("0.0.0.0:8888", nil)
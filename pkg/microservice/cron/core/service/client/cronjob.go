@@ -30,6 +30,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
 	"github.com/koderover/zadig/pkg/tool/rsa"
 )
 
@@ -175,6 +176,19 @@ func (c *Client) TriggerSystemGc(log *zap.SugaredLogger) error {
 	return err
 }
 
+func (c *Client) TriggerCredentialHealthCheck(log *zap.SugaredLogger) error {
+	url := fmt.Sprintf("%s/system/credential/health/check", c.APIBase)
+	log.Info("Start credential health check..")
+
+	result, err := c.sendPostRequest(url, nil, log)
+	if err != nil {
+		log.Errorf("trigger credential health check error :%v", err)
+	} else {
+		log.Infof("trigger credential health check: %v", result)
+	}
+	return err
+}
+
 func (c *Client) sendRequest(url string) error {
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -245,3 +259,27 @@ func (c *Client) CreateWebHookUser(webHookUser *service.DomainWebHookUser, log *
 	}
 	return err
 }
+
+// AcquireCronjobRun returns false if the scheduled time of the cronjob has been triggered.
+func (c *Client) AcquireCronjobRun(id string, scheduledTime int64, log *zap.SugaredLogger) (bool, error) {
+	url := fmt.Sprintf("%s/cron/cronjob/%s/runs", c.APIBase, id)
+	resp := &service.AcquireCronjobRunResp{}
+	_, err := httpclient.Post(url, httpclient.SetBody(&service.AcquireCronjobRunReq{ScheduledTime: scheduledTime}), httpclient.SetResult(resp))
+	if err != nil {
+		log.Errorf("acquire run of cronjob %s error: %v", id, err)
+		return false, err
+	}
+	return resp.Acquired, nil
+}
+
+// RecordMissedCronjobRuns returns the missed scheduled times which should be run by the catch up policy.
+func (c *Client) RecordMissedCronjobRuns(id string, args *service.MissedCronjobRunsReq, log *zap.SugaredLogger) ([]int64, error) {
+	url := fmt.Sprintf("%s/cron/cronjob/%s/missed", c.APIBase, id)
+	resp := &service.MissedCronjobRunsResp{}
+	_, err := httpclient.Post(url, httpclient.SetBody(args), httpclient.SetResult(resp))
+	if err != nil {
+		log.Errorf("record missed runs of cronjob %s error: %v", id, err)
+		return nil, err
+	}
+	return resp.TriggerTimes, nil
+}
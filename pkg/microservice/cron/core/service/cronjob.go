@@ -26,20 +26,42 @@ type CronjobPayload struct {
 }
 
 type Cronjob struct {
-	ID           string            `json:"_id,omitempty"`
-	Name         string            `json:"name"`
-	Type         string            `json:"type"`
-	Number       uint64            `json:"number"`
-	Frequency    string            `json:"frequency"`
-	Time         string            `json:"time"`
-	Cron         string            `json:"cron"`
-	ProductName  string            `json:"product_name,omitempty"`
-	MaxFailure   int               `json:"max_failures,omitempty"`
-	TaskArgs     *TaskArgs         `json:"task_args,omitempty"`
-	WorkflowArgs *WorkflowTaskArgs `json:"workflow_args,omitempty"`
-	TestArgs     *TestTaskArgs     `json:"test_args,omitempty"`
-	JobType      string            `json:"job_type"`
-	Enabled      bool              `json:"enabled"`
+	ID                 string              `json:"_id,omitempty"`
+	Name               string              `json:"name"`
+	Type               string              `json:"type"`
+	Number             uint64              `json:"number"`
+	Frequency          string              `json:"frequency"`
+	Time               string              `json:"time"`
+	Cron               string              `json:"cron"`
+	ProductName        string              `json:"product_name,omitempty"`
+	MaxFailure         int                 `json:"max_failures,omitempty"`
+	TaskArgs           *TaskArgs           `json:"task_args,omitempty"`
+	WorkflowArgs       *WorkflowTaskArgs   `json:"workflow_args,omitempty"`
+	TestArgs           *TestTaskArgs       `json:"test_args,omitempty"`
+	EnvDataRefreshArgs *EnvDataRefreshArgs `json:"env_data_refresh_args,omitempty"`
+	EnvSmokeTestArgs   *EnvSmokeTestArgs   `json:"env_smoke_test_args,omitempty"`
+	ReleaseTrainArgs   *ReleaseTrainArgs   `json:"release_train_args,omitempty"`
+	JobType            string              `json:"job_type"`
+	Enabled            bool                `json:"enabled"`
+	CatchUpPolicy      string              `json:"catch_up_policy,omitempty"`
+	LastScheduledTime  int64               `json:"last_scheduled_time,omitempty"`
+}
+
+type AcquireCronjobRunReq struct {
+	ScheduledTime int64 `json:"scheduled_time"`
+}
+
+type AcquireCronjobRunResp struct {
+	Acquired bool `json:"acquired"`
+}
+
+type MissedCronjobRunsReq struct {
+	ScheduledTimes []int64 `json:"scheduled_times"`
+	Total          int     `json:"total"`
+}
+
+type MissedCronjobRunsResp struct {
+	TriggerTimes []int64 `json:"trigger_times"`
 }
 
 // param type: cronjob的执行内容类型
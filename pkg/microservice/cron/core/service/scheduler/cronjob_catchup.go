@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/rfyiamcool/cronlib"
+
+	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
+	"github.com/koderover/zadig/pkg/microservice/cron/core/service/client"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// MaxCatchUpRuns is the max number of the missed runs reported to aslan, only the latest ones are kept.
+const MaxCatchUpRuns = 20
+
+// guardedRun acquires the scheduled time from aslan before the trigger runs, so that a time which
+// has been caught up, or triggered by another replica, is not run twice.
+func guardedRun(cli *client.Client, jobID string, trigger func()) func() {
+	return func() {
+		scheduledTime := time.Now().Truncate(time.Minute).Unix()
+		acquired, err := cli.AcquireCronjobRun(jobID, scheduledTime, log.SugaredLogger())
+		if err != nil {
+			log.Warnf("failed to acquire run of cronjob %s, trigger it anyway, err: %s", jobID, err)
+		} else if !acquired {
+			log.Infof("scheduled time %d of cronjob %s has been triggered, skip it", scheduledTime, jobID)
+			return
+		}
+		trigger()
+	}
+}
+
+// catchUpCronjob reports the runs missed since the last scheduled time of the job, and runs the
+// ones aslan decides to catch up.
+func catchUpCronjob(cli *client.Client, job *service.Cronjob, cron string, trigger func()) {
+	// the job has never run since the run records are introduced.
+	if job.LastScheduledTime == 0 {
+		return
+	}
+	scheduledTimes, total, err := missedScheduledTimes(cron, time.Unix(job.LastScheduledTime, 0), time.Now())
+	if err != nil {
+		log.Errorf("failed to parse cron %s of job %s, err: %s", cron, job.ID, err)
+		return
+	}
+	if total == 0 {
+		return
+	}
+
+	log.Infof("cronjob %s missed %d runs, catch up policy: %s", job.ID, total, job.CatchUpPolicy)
+	triggerTimes, err := cli.RecordMissedCronjobRuns(job.ID, &service.MissedCronjobRunsReq{
+		ScheduledTimes: scheduledTimes,
+		Total:          total,
+	}, log.SugaredLogger())
+	if err != nil {
+		return
+	}
+	for _, scheduledTime := range triggerTimes {
+		log.Infof("catching up scheduled time %d of cronjob %s", scheduledTime, job.ID)
+		trigger()
+	}
+}
+
+// missedScheduledTimes returns the latest MaxCatchUpRuns scheduled times after last and before now,
+// and the number of all of them.
+func missedScheduledTimes(cron string, last, now time.Time) ([]int64, int, error) {
+	schedule, err := cronlib.Parse(cron)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var scheduledTimes []int64
+	total := 0
+	for next := schedule.Next(last); !next.IsZero() && next.Before(now.Truncate(time.Minute)); next = schedule.Next(next) {
+		total++
+		scheduledTimes = append(scheduledTimes, next.Unix())
+		if len(scheduledTimes) > MaxCatchUpRuns {
+			scheduledTimes = scheduledTimes[1:]
+		}
+	}
+	return scheduledTimes, total, nil
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
 	"strings"
 	"time"
@@ -184,6 +185,21 @@ func (h *CronjobHandler) updateCronjob(name, productName, jobType string, jobLis
 			if err != nil {
 				return err
 			}
+		case setting.EnvDataRefreshCronjob:
+			err := h.registerEnvDataRefreshJob(name, cron, job)
+			if err != nil {
+				return err
+			}
+		case setting.EnvSmokeTestCronjob:
+			err := h.registerEnvSmokeTestJob(name, cron, job)
+			if err != nil {
+				return err
+			}
+		case setting.ReleaseTrainCronjob:
+			err := h.registerReleaseTrainJob(name, cron, job)
+			if err != nil {
+				return err
+			}
 		default:
 			log.Errorf("unrecognized cron job type for job id: %s", job.ID)
 		}
@@ -250,11 +266,11 @@ func (h *CronjobHandler) registerWorkFlowJob(name, schedule string, job *service
 		args.Tests = job.WorkflowArgs.Tests
 		args.DistributeEnabled = job.WorkflowArgs.DistributeEnabled
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	scheduleJob, err := cronlib.NewJobModel(schedule, guardedRun(h.aslanCli, job.ID.Hex(), func() {
 		if err := h.aslanCli.ScheduleCall(path.Join("workflow/workflowtask", args.WorkflowName), args, log.SugaredLogger()); err != nil {
 			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
 		}
-	})
+	}))
 	if err != nil {
 		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
 		return err
@@ -275,11 +291,105 @@ func (h *CronjobHandler) registerTestJob(name, productName, schedule string, job
 		ProductName:     productName,
 		TestTaskCreator: setting.CronTaskCreator,
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	scheduleJob, err := cronlib.NewJobModel(schedule, guardedRun(h.aslanCli, job.ID.Hex(), func() {
 		if err := h.aslanCli.ScheduleCall("testing/testtask", args, log.SugaredLogger()); err != nil {
 			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
 		}
-	})
+	}))
+	if err != nil {
+		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
+		return err
+	}
+
+	log.Infof("registering jobID: %s with cron: %s", job.ID.Hex(), schedule)
+	err = h.Scheduler.UpdateJobModel(job.ID.Hex(), scheduleJob)
+	if err != nil {
+		log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
+		return err
+	}
+	return nil
+}
+
+func (h *CronjobHandler) registerEnvDataRefreshJob(name, schedule string, job *service.Schedule) error {
+	if job.EnvDataRefreshArgs == nil {
+		return fmt.Errorf("env data refresh args of job %s not found", job.ID.Hex())
+	}
+	args := &service.EnvDataRefreshArgs{
+		ProjectName: job.EnvDataRefreshArgs.ProjectName,
+		EnvName:     job.EnvDataRefreshArgs.EnvName,
+		RefreshID:   job.EnvDataRefreshArgs.RefreshID,
+		TriggeredBy: setting.CronTaskCreator,
+	}
+	scheduleJob, err := cronlib.NewJobModel(schedule, guardedRun(h.aslanCli, job.ID.Hex(), func() {
+		if err := h.aslanCli.ScheduleCall(envDataRefreshAPI(args), args, log.SugaredLogger()); err != nil {
+			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
+		}
+	}))
+	if err != nil {
+		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
+		return err
+	}
+
+	log.Infof("registering jobID: %s with cron: %s", job.ID.Hex(), schedule)
+	err = h.Scheduler.UpdateJobModel(job.ID.Hex(), scheduleJob)
+	if err != nil {
+		log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
+		return err
+	}
+	return nil
+}
+
+func envDataRefreshAPI(args *service.EnvDataRefreshArgs) string {
+	return fmt.Sprintf("environment/environments/%s/dataRefresh/%s/run?projectName=%s", args.EnvName, args.RefreshID, url.QueryEscape(args.ProjectName))
+}
+
+func (h *CronjobHandler) registerEnvSmokeTestJob(name, schedule string, job *service.Schedule) error {
+	if job.EnvSmokeTestArgs == nil {
+		return fmt.Errorf("env smoke test args of job %s not found", job.ID.Hex())
+	}
+	args := &service.EnvSmokeTestArgs{
+		ProjectName: job.EnvSmokeTestArgs.ProjectName,
+		EnvName:     job.EnvSmokeTestArgs.EnvName,
+		SmokeTestID: job.EnvSmokeTestArgs.SmokeTestID,
+		TriggeredBy: setting.CronTaskCreator,
+	}
+	scheduleJob, err := cronlib.NewJobModel(schedule, guardedRun(h.aslanCli, job.ID.Hex(), func() {
+		if err := h.aslanCli.ScheduleCall(envSmokeTestAPI(args), args, log.SugaredLogger()); err != nil {
+			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
+		}
+	}))
+	if err != nil {
+		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
+		return err
+	}
+
+	log.Infof("registering jobID: %s with cron: %s", job.ID.Hex(), schedule)
+	err = h.Scheduler.UpdateJobModel(job.ID.Hex(), scheduleJob)
+	if err != nil {
+		log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
+		return err
+	}
+	return nil
+}
+
+func envSmokeTestAPI(args *service.EnvSmokeTestArgs) string {
+	return fmt.Sprintf("environment/environments/%s/smokeTests/%s/run?projectName=%s", args.EnvName, args.SmokeTestID, url.QueryEscape(args.ProjectName))
+}
+
+func (h *CronjobHandler) registerReleaseTrainJob(name, schedule string, job *service.Schedule) error {
+	if job.ReleaseTrainArgs == nil {
+		return fmt.Errorf("release train args of job %s not found", job.ID.Hex())
+	}
+	args := &service.ReleaseTrainArgs{
+		ProjectName: job.ReleaseTrainArgs.ProjectName,
+		TrainID:     job.ReleaseTrainArgs.TrainID,
+		TriggeredBy: setting.CronTaskCreator,
+	}
+	scheduleJob, err := cronlib.NewJobModel(schedule, guardedRun(h.aslanCli, job.ID.Hex(), func() {
+		if err := h.aslanCli.ScheduleCall(releaseTrainAPI(args), args, log.SugaredLogger()); err != nil {
+			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
+		}
+	}))
 	if err != nil {
 		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
 		return err
@@ -294,6 +404,10 @@ func (h *CronjobHandler) registerTestJob(name, productName, schedule string, job
 	return nil
 }
 
+func releaseTrainAPI(args *service.ReleaseTrainArgs) string {
+	return fmt.Sprintf("workflow/v4/releasetrain/%s/dispatch?projectName=%s", args.TrainID, url.QueryEscape(args.ProjectName))
+}
+
 // FIXME
 // UNDER CURRENT SERVICE STRUCTURE, STOPPING CRONJOB SERVICE AND UPDATING DB RECORD
 // ARE NOT ATOMIC, THIS WILL CAUSE SERIOUS PROBLEM IF UPDATE FAILED
@@ -354,11 +468,12 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 		} else {
 			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
 		}
-		scheduleJob, err := cronlib.NewJobModel(cron, func() {
+		trigger := func() {
 			if err := client.ScheduleCall(path.Join("workflow/workflowtask", job.WorkflowArgs.WorkflowName), args, log.SugaredLogger()); err != nil {
 				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
 			}
-		})
+		}
+		scheduleJob, err := cronlib.NewJobModel(cron, guardedRun(client, job.ID, trigger))
 		if err != nil {
 			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
 			return err
@@ -369,6 +484,7 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
 			return err
 		}
+		go catchUpCronjob(client, job, cron, trigger)
 	case setting.TestingCronjob:
 		args := &service.TestTaskArgs{
 			TestName:        job.Name,
@@ -381,11 +497,110 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 		} else {
 			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
 		}
-		scheduleJob, err := cronlib.NewJobModel(cron, func() {
+		trigger := func() {
 			if err := client.ScheduleCall("testing/testtask", args, log.SugaredLogger()); err != nil {
 				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
 			}
-		})
+		}
+		scheduleJob, err := cronlib.NewJobModel(cron, guardedRun(client, job.ID, trigger))
+		if err != nil {
+			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
+			return err
+		}
+		log.Infof("registering jobID: %s with cron: %s", job.ID, cron)
+		err = scheduler.UpdateJobModel(job.ID, scheduleJob)
+		if err != nil {
+			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
+			return err
+		}
+		go catchUpCronjob(client, job, cron, trigger)
+	case setting.EnvDataRefreshCronjob:
+		if job.EnvDataRefreshArgs == nil {
+			return fmt.Errorf("env data refresh args of job %s not found", job.ID)
+		}
+		args := &service.EnvDataRefreshArgs{
+			ProjectName: job.EnvDataRefreshArgs.ProjectName,
+			EnvName:     job.EnvDataRefreshArgs.EnvName,
+			RefreshID:   job.EnvDataRefreshArgs.RefreshID,
+			TriggeredBy: setting.CronTaskCreator,
+		}
+		var cron string
+		if job.JobType == setting.CrontabCronjob {
+			cron = fmt.Sprintf("%s%s", "0 ", job.Cron)
+		} else {
+			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
+		}
+		trigger := func() {
+			if err := client.ScheduleCall(envDataRefreshAPI(args), args, log.SugaredLogger()); err != nil {
+				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
+			}
+		}
+		scheduleJob, err := cronlib.NewJobModel(cron, guardedRun(client, job.ID, trigger))
+		if err != nil {
+			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
+			return err
+		}
+		log.Infof("registering jobID: %s with cron: %s", job.ID, cron)
+		err = scheduler.UpdateJobModel(job.ID, scheduleJob)
+		if err != nil {
+			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
+			return err
+		}
+		go catchUpCronjob(client, job, cron, trigger)
+	case setting.EnvSmokeTestCronjob:
+		if job.EnvSmokeTestArgs == nil {
+			return fmt.Errorf("env smoke test args of job %s not found", job.ID)
+		}
+		args := &service.EnvSmokeTestArgs{
+			ProjectName: job.EnvSmokeTestArgs.ProjectName,
+			EnvName:     job.EnvSmokeTestArgs.EnvName,
+			SmokeTestID: job.EnvSmokeTestArgs.SmokeTestID,
+			TriggeredBy: setting.CronTaskCreator,
+		}
+		var cron string
+		if job.JobType == setting.CrontabCronjob {
+			cron = fmt.Sprintf("%s%s", "0 ", job.Cron)
+		} else {
+			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
+		}
+		trigger := func() {
+			if err := client.ScheduleCall(envSmokeTestAPI(args), args, log.SugaredLogger()); err != nil {
+				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
+			}
+		}
+		scheduleJob, err := cronlib.NewJobModel(cron, guardedRun(client, job.ID, trigger))
+		if err != nil {
+			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
+			return err
+		}
+		log.Infof("registering jobID: %s with cron: %s", job.ID, cron)
+		err = scheduler.UpdateJobModel(job.ID, scheduleJob)
+		if err != nil {
+			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
+			return err
+		}
+		go catchUpCronjob(client, job, cron, trigger)
+	case setting.ReleaseTrainCronjob:
+		if job.ReleaseTrainArgs == nil {
+			return fmt.Errorf("release train args of job %s not found", job.ID)
+		}
+		args := &service.ReleaseTrainArgs{
+			ProjectName: job.ReleaseTrainArgs.ProjectName,
+			TrainID:     job.ReleaseTrainArgs.TrainID,
+			TriggeredBy: setting.CronTaskCreator,
+		}
+		var cron string
+		if job.JobType == setting.CrontabCronjob {
+			cron = fmt.Sprintf("%s%s", "0 ", job.Cron)
+		} else {
+			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
+		}
+		trigger := func() {
+			if err := client.ScheduleCall(releaseTrainAPI(args), args, log.SugaredLogger()); err != nil {
+				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
+			}
+		}
+		scheduleJob, err := cronlib.NewJobModel(cron, guardedRun(client, job.ID, trigger))
 		if err != nil {
 			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
 			return err
@@ -396,6 +611,7 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
 			return err
 		}
+		go catchUpCronjob(client, job, cron, trigger)
 	default:
 		fmt.Printf("Not supported type of service: %s\n", job.Type)
 		return errors.New("not supported service type")
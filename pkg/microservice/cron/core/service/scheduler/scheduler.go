@@ -133,6 +133,9 @@ const (
 	InitHelmEnvSyncValuesScheduler = "InitHelmEnvSyncValuesScheduler"
 
 	EnvResourceSyncScheduler = "EnvResourceSyncScheduler"
+
+	// CredentialHealthScheduler periodically checks the codehost, registry and cluster credentials.
+	CredentialHealthScheduler = "CredentialHealthScheduler"
 )
 
 // NewCronClient ...
@@ -208,6 +211,8 @@ func (c *CronClient) Init() {
 	c.InitHelmEnvSyncValuesScheduler()
 	// sync env resources from git at regular intervals
 	c.InitEnvResourceSyncScheduler()
+	// check the credentials of the integrations every 6 hours
+	c.InitCredentialHealthScheduler()
 }
 
 func (c *CronClient) InitCleanJobScheduler() {
@@ -324,3 +329,11 @@ func (c *CronClient) InitEnvResourceSyncScheduler() {
 
 	c.Schedulers[EnvResourceSyncScheduler].Start()
 }
+
+func (c *CronClient) InitCredentialHealthScheduler() {
+	c.Schedulers[CredentialHealthScheduler] = gocron.NewScheduler()
+
+	c.Schedulers[CredentialHealthScheduler].Every(6).Hours().Do(c.AslanCli.TriggerCredentialHealthCheck, c.log)
+
+	c.Schedulers[CredentialHealthScheduler].Start()
+}
@@ -76,17 +76,21 @@ type PipelineSpec struct {
 }
 
 type Schedule struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"                 json:"id,omitempty"`
-	Number       uint64             `bson:"number"                        json:"number"`
-	Frequency    string             `bson:"frequency"                     json:"frequency"`
-	Time         string             `bson:"time"                          json:"time"`
-	MaxFailures  int                `bson:"max_failures,omitempty"        json:"max_failures,omitempty"`
-	TaskArgs     *TaskArgs          `bson:"task_args,omitempty"           json:"task_args,omitempty"`
-	WorkflowArgs *WorkflowTaskArgs  `bson:"workflow_args,omitempty"       json:"workflow_args,omitempty"`
-	TestArgs     *TestTaskArgs      `bson:"test_args,omitempty"           json:"test_args,omitempty"`
-	Type         ScheduleType       `bson:"type"                          json:"type"`
-	Cron         string             `bson:"cron"                          json:"cron"`
-	IsModified   bool               `bson:"-"                             json:"-"`
+	ID                 primitive.ObjectID  `bson:"_id,omitempty"                 json:"id,omitempty"`
+	Number             uint64              `bson:"number"                        json:"number"`
+	Frequency          string              `bson:"frequency"                     json:"frequency"`
+	Time               string              `bson:"time"                          json:"time"`
+	MaxFailures        int                 `bson:"max_failures,omitempty"        json:"max_failures,omitempty"`
+	TaskArgs           *TaskArgs           `bson:"task_args,omitempty"           json:"task_args,omitempty"`
+	WorkflowArgs       *WorkflowTaskArgs   `bson:"workflow_args,omitempty"       json:"workflow_args,omitempty"`
+	TestArgs           *TestTaskArgs       `bson:"test_args,omitempty"           json:"test_args,omitempty"`
+	EnvDataRefreshArgs *EnvDataRefreshArgs `bson:"env_data_refresh_args,omitempty" json:"env_data_refresh_args,omitempty"`
+	EnvSmokeTestArgs   *EnvSmokeTestArgs   `bson:"env_smoke_test_args,omitempty"   json:"env_smoke_test_args,omitempty"`
+	ReleaseTrainArgs   *ReleaseTrainArgs   `bson:"release_train_args,omitempty"    json:"release_train_args,omitempty"`
+	Type               ScheduleType        `bson:"type"                          json:"type"`
+	Cron               string              `bson:"cron"                          json:"cron"`
+	CatchUpPolicy      string              `bson:"catch_up_policy,omitempty"     json:"catch_up_policy,omitempty"`
+	IsModified         bool                `bson:"-"                             json:"-"`
 	// 自由编排工作流的开关是放在schedule里面的
 	Enabled bool `bson:"enabled"                       json:"enabled"`
 }
@@ -348,6 +352,29 @@ type VersionArgs struct {
 	Labels  []string `bson:"labels"  json:"labels"`
 }
 
+// EnvDataRefreshArgs identifies the environment data refresh run by a schedule.
+type EnvDataRefreshArgs struct {
+	ProjectName string `bson:"project_name"   json:"project_name"`
+	EnvName     string `bson:"env_name"       json:"env_name"`
+	RefreshID   string `bson:"refresh_id"     json:"refresh_id"`
+	TriggeredBy string `bson:"-"              json:"triggered_by,omitempty"`
+}
+
+// EnvSmokeTestArgs identifies the environment smoke test run by a schedule.
+type EnvSmokeTestArgs struct {
+	ProjectName string `bson:"project_name"   json:"project_name"`
+	EnvName     string `bson:"env_name"       json:"env_name"`
+	SmokeTestID string `bson:"smoke_test_id"  json:"smoke_test_id"`
+	TriggeredBy string `bson:"-"              json:"triggered_by,omitempty"`
+}
+
+// ReleaseTrainArgs identifies the release train dispatched by a schedule.
+type ReleaseTrainArgs struct {
+	ProjectName string `bson:"project_name"   json:"project_name"`
+	TrainID     string `bson:"train_id"       json:"train_id"`
+	TriggeredBy string `bson:"-"              json:"triggered_by,omitempty"`
+}
+
 type TestTaskArgs struct {
 	ProductName     string `bson:"product_name"            json:"product_name"`
 	TestName        string `bson:"test_name"               json:"test_name"`
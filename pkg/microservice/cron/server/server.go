@@ -18,12 +18,15 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
 	commonconfig "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/cron/config"
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service/scheduler"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/healthz"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -42,7 +45,10 @@ func Serve(ctx context.Context) error {
 	cronV3Client := scheduler.NewCronV3()
 	cronV3Client.Start()
 
+	go healthz.SelfCheck(ctx, commonconfig.CronServiceInfo().Name, healthChecks())
+
 	http.HandleFunc("/ping", ping)
+	http.HandleFunc("/healthz", healthz.Handler(commonconfig.CronServiceInfo().Name, healthChecks))
 	server := &http.Server{Addr: ":8091", Handler: nil}
 
 	stopChan := make(chan struct{})
@@ -73,3 +79,15 @@ func Serve(ctx context.Context) error {
 func ping(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write([]byte("success"))
 }
+
+// healthChecks returns the dependencies of cron, the cron jobs are triggered through aslan and
+// the queues of nsq.
+func healthChecks() []*healthz.Check {
+	checks := []*healthz.Check{{
+		Name: "aslan",
+		Hint: "check that the pod of aslan is running, the cron jobs are not triggered until aslan is reachable",
+		Run:  healthz.HTTPGet(commonconfig.AslanServiceAddress() + "/api/health"),
+	}}
+	hint := fmt.Sprintf("check that nsqlookupd is running and %s is correct", setting.ENVNsqLookupAddrs)
+	return append(checks, healthz.NSQLookupdChecks(config.NsqLookupAddrs(), hint)...)
+}
@@ -22,6 +22,8 @@ import (
 	"time"
 
 	"github.com/koderover/zadig/pkg/microservice/hubserver/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/healthz"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -38,3 +40,12 @@ func initDatabase() {
 		panic(fmt.Errorf("failed to connect to mongo, error: %s", err))
 	}
 }
+
+// HealthChecks returns the dependencies of hub-server, the clusters are registered in mongodb.
+func HealthChecks() []*healthz.Check {
+	return []*healthz.Check{{
+		Name: "mongodb",
+		Hint: fmt.Sprintf("check that mongodb is running and %s is correct", setting.ENVMongoDBConnectionString),
+		Run:  mongotool.Ping,
+	}}
+}
@@ -21,7 +21,10 @@ import (
 
 	"github.com/gorilla/mux"
 
+	commonconfig "github.com/koderover/zadig/pkg/config"
 	h "github.com/koderover/zadig/pkg/microservice/hubserver/core/handler"
+	"github.com/koderover/zadig/pkg/microservice/hubserver/core/service"
+	"github.com/koderover/zadig/pkg/tool/healthz"
 	"github.com/koderover/zadig/pkg/tool/remotedialer"
 )
 
@@ -44,6 +47,8 @@ func (s *engine) injectRouters(handler *remotedialer.Server) {
 
 	r.Handle("/connect", handler)
 
+	r.Handle("/healthz", healthz.Handler(commonconfig.HubServerServiceInfo().Name, service.HealthChecks))
+
 	r.HandleFunc("/disconnect/{id}", func(rw http.ResponseWriter, req *http.Request) {
 		h.Disconnect(handler, rw, req)
 	})
@@ -26,6 +26,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/hubserver/core/service"
 	"github.com/koderover/zadig/pkg/microservice/hubserver/server/rest"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/healthz"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/tool/remotedialer"
 )
@@ -40,6 +41,7 @@ func Serve(ctx context.Context) error {
 
 	log.Info("hub server start...")
 	service.Init()
+	go healthz.SelfCheck(ctx, commonconfig.HubServerServiceInfo().Name, service.HealthChecks())
 
 	handler := remotedialer.New(service.Authorize, remotedialer.DefaultErrorWriter)
 	engine := rest.NewEngine(handler)
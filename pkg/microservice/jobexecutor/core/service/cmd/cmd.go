@@ -25,4 +25,6 @@ type Command struct {
 	DisableTrace bool
 	// IgnoreError ingore command run error
 	IgnoreError bool
+	// Fallback runs if the command fails
+	Fallback *Command
 }
@@ -108,6 +108,17 @@ func Merge(branch string) *exec.Cmd {
 	)
 }
 
+// Diff returns command git --no-pager diff $FROM $TO
+func Diff(from, to string) *exec.Cmd {
+	return exec.Command(
+		"git",
+		"--no-pager",
+		"diff",
+		from,
+		to,
+	)
+}
+
 // UpdateSubmodules returns command: git submodule update --init --recursive
 func UpdateSubmodules() *exec.Cmd {
 	cmd := exec.Command(
@@ -73,6 +73,21 @@ func runStep(ctx context.Context, step *meta.Step, workspace, paths string, envs
 		if err != nil {
 			return err
 		}
+	case "android_publish":
+		stepInstance, err = NewAndroidPublishStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "download":
+		stepInstance, err = NewDownloadStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "summary":
+		stepInstance, err = NewSummaryStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
 	default:
 		err := fmt.Errorf("step type: %s does not match any known type", step.StepType)
 		log.Error(err)
@@ -174,5 +189,8 @@ func isDirEmpty(dir string) bool {
 func setCmdsWorkDir(dir string, cmds []*cmd.Command) {
 	for _, c := range cmds {
 		c.Cmd.Dir = dir
+		if c.Fallback != nil {
+			c.Fallback.Cmd.Dir = dir
+		}
 	}
 }
@@ -0,0 +1,389 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/androidpublisher/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	firebaseAppDistributionAPI   = "https://firebaseappdistribution.googleapis.com"
+	firebaseScope                = "https://www.googleapis.com/auth/cloud-platform"
+	firebaseOperationPollTimes   = 60
+	firebaseOperationPollBackoff = 5 * time.Second
+
+	defaultPlayTrack  = "internal"
+	defaultPlayStatus = "completed"
+)
+
+type AndroidPublishStep struct {
+	spec       *step.StepAndroidPublishSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewAndroidPublishStep(spec interface{}, workspace string, envs, secretEnvs []string) (*AndroidPublishStep, error) {
+	publishStep := &AndroidPublishStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return publishStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &publishStep.spec); err != nil {
+		return publishStep, fmt.Errorf("unmarshal spec %s to android publish spec failed", yamlBytes)
+	}
+	return publishStep, nil
+}
+
+func (s *AndroidPublishStep) Run(ctx context.Context) error {
+	start := time.Now()
+	defer func() {
+		log.Infof("Android publish ended. Duration: %.2f seconds", time.Since(start).Seconds())
+	}()
+
+	envmaps := make(map[string]string)
+	// the values of the secrets, e.g. the json keys, may contain "=".
+	for _, env := range append(s.envs, s.secretEnvs...) {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		envmaps[kv[0]] = kv[1]
+	}
+	expand := func(str string) string {
+		return os.Expand(str, func(key string) string { return envmaps[key] })
+	}
+
+	outputDir := filepath.Join(s.workspace, s.spec.OutputDir)
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create output dir %s: %s", outputDir, err)
+	}
+	metadata := &step.AndroidMetadata{
+		VersionName: expand(s.spec.VersionName),
+		VersionCode: expand(s.spec.VersionCode),
+	}
+	var apks, aabs []string
+	for _, pattern := range s.spec.Files {
+		matches, err := filepath.Glob(filepath.Join(s.workspace, expand(pattern)))
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %s", pattern, err)
+		}
+		for _, match := range matches {
+			ext := filepath.Ext(match)
+			if ext != ".apk" && ext != ".aab" {
+				continue
+			}
+			artifact, dst, err := collectAndroidArtifact(match, outputDir, metadata.Files)
+			if err != nil {
+				return err
+			}
+			log.Infof("Collected %s, size: %d, sha256: %s.", artifact.Name, artifact.Size, artifact.Sha256)
+			metadata.Files = append(metadata.Files, artifact)
+			if ext == ".apk" {
+				apks = append(apks, dst)
+			} else {
+				aabs = append(aabs, dst)
+			}
+		}
+	}
+	if len(metadata.Files) == 0 {
+		return fmt.Errorf("no apk or aab matches %s", strings.Join(s.spec.Files, ", "))
+	}
+	metadataBytes, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(outputDir, step.AndroidMetadataFile), metadataBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata: %s", err)
+	}
+
+	if s.spec.Firebase != nil {
+		// firebase app distribution installs the apk directly, the aab is only used if there is no apk.
+		file := ""
+		if len(apks) > 0 {
+			file = apks[0]
+		} else {
+			file = aabs[0]
+		}
+		if err := publishToFirebase(ctx, s.spec.Firebase, envmaps[s.spec.Firebase.ServiceAccountEnv], file, expand(s.spec.Firebase.ReleaseNotes)); err != nil {
+			return fmt.Errorf("failed to publish to firebase app distribution: %s", err)
+		}
+	}
+	if s.spec.GooglePlay != nil {
+		files := aabs
+		if len(files) == 0 {
+			files = apks
+		}
+		if err := publishToGooglePlay(ctx, s.spec.GooglePlay, envmaps[s.spec.GooglePlay.ServiceAccountEnv], files, metadata.VersionName, expand(s.spec.GooglePlay.ReleaseNotes)); err != nil {
+			return fmt.Errorf("failed to publish to google play: %s", err)
+		}
+	}
+	return nil
+}
+
+// collectAndroidArtifact copies the file into the output dir and computes its checksum, the name of the
+// parent dir, e.g. the build type or the flavor, is prefixed if the name is collected already.
+func collectAndroidArtifact(src, outputDir string, collected []*step.AndroidArtifact) (*step.AndroidArtifact, string, error) {
+	name := filepath.Base(src)
+	for _, artifact := range collected {
+		if artifact.Name == name {
+			name = filepath.Base(filepath.Dir(src)) + "-" + name
+			break
+		}
+	}
+	dst := filepath.Join(outputDir, name)
+
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, "", err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return nil, "", err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), in)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to collect %s: %s", src, err)
+	}
+	return &step.AndroidArtifact{Name: name, Size: size, Sha256: hex.EncodeToString(hash.Sum(nil))}, dst, nil
+}
+
+type firebaseOperation struct {
+	Name  string `json:"name"`
+	Done  bool   `json:"done"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Response *struct {
+		Result  string           `json:"result"`
+		Release *firebaseRelease `json:"release"`
+	} `json:"response"`
+}
+
+type firebaseRelease struct {
+	Name               string `json:"name"`
+	DisplayVersion     string `json:"displayVersion"`
+	BuildVersion       string `json:"buildVersion"`
+	FirebaseConsoleURI string `json:"firebaseConsoleUri"`
+}
+
+func publishToFirebase(ctx context.Context, conf *step.FirebasePublish, serviceAccount, file, releaseNotes string) error {
+	// the app id is in the format of 1:<project number>:android:<hash>.
+	parts := strings.Split(conf.AppID, ":")
+	if len(parts) != 4 {
+		return fmt.Errorf("invalid app id %s", conf.AppID)
+	}
+	app := fmt.Sprintf("projects/%s/apps/%s", parts[1], conf.AppID)
+
+	creds, err := google.CredentialsFromJSON(ctx, []byte(serviceAccount), firebaseScope)
+	if err != nil {
+		return fmt.Errorf("invalid service account: %s", err)
+	}
+	client := oauth2.NewClient(ctx, creds.TokenSource)
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/upload/v1/%s/releases:upload", firebaseAppDistributionAPI, app), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Goog-Upload-Protocol", "raw")
+	req.Header.Set("X-Goog-Upload-File-Name", filepath.Base(file))
+	log.Infof("Uploading %s to firebase app distribution.", filepath.Base(file))
+	operation := &firebaseOperation{}
+	if err := doFirebaseRequest(client, req, operation); err != nil {
+		return err
+	}
+
+	// the release is processed asynchronously after the upload.
+	for i := 0; !operation.Done; i++ {
+		if i >= firebaseOperationPollTimes {
+			return fmt.Errorf("timed out waiting for the release to be processed")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(firebaseOperationPollBackoff):
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", firebaseAppDistributionAPI, operation.Name), nil)
+		if err != nil {
+			return err
+		}
+		if err := doFirebaseRequest(client, req, operation); err != nil {
+			return err
+		}
+	}
+	if operation.Error != nil {
+		return fmt.Errorf("failed to process the release: %s", operation.Error.Message)
+	}
+	if operation.Response == nil || operation.Response.Release == nil {
+		return fmt.Errorf("no release is returned")
+	}
+	release := operation.Response.Release
+	log.Infof("Release %s (%s) is %s, console: %s.", release.DisplayVersion, release.BuildVersion, strings.ToLower(operation.Response.Result), release.FirebaseConsoleURI)
+
+	if releaseNotes != "" {
+		body, _ := json.Marshal(map[string]interface{}{"releaseNotes": map[string]string{"text": releaseNotes}})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPatch, fmt.Sprintf("%s/v1/%s?updateMask=release_notes.text", firebaseAppDistributionAPI, release.Name), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := doFirebaseRequest(client, req, nil); err != nil {
+			return fmt.Errorf("failed to update release notes: %s", err)
+		}
+	}
+	if len(conf.Groups) > 0 || len(conf.Testers) > 0 {
+		body, _ := json.Marshal(map[string][]string{"groupAliases": conf.Groups, "testerEmails": conf.Testers})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v1/%s:distribute", firebaseAppDistributionAPI, release.Name), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := doFirebaseRequest(client, req, nil); err != nil {
+			return fmt.Errorf("failed to distribute the release: %s", err)
+		}
+		log.Infof("Release is distributed to groups: %v, testers: %v.", conf.Groups, conf.Testers)
+	}
+	return nil
+}
+
+func doFirebaseRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s %s", req.Method, req.URL.Path, resp.Status, body)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+func publishToGooglePlay(ctx context.Context, conf *step.PlayPublish, serviceAccount string, files []string, versionName, releaseNotes string) error {
+	service, err := androidpublisher.NewService(ctx, option.WithCredentialsJSON([]byte(serviceAccount)), option.WithScopes(androidpublisher.AndroidpublisherScope))
+	if err != nil {
+		return fmt.Errorf("failed to create client: %s", err)
+	}
+	edit, err := service.Edits.Insert(conf.PackageName, &androidpublisher.AppEdit{}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to create edit: %s", err)
+	}
+
+	versionCodes := googleapi.Int64s{}
+	for _, file := range files {
+		versionCode, err := uploadToGooglePlay(ctx, service, conf.PackageName, edit.Id, file)
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %s", filepath.Base(file), err)
+		}
+		log.Infof("Uploaded %s to google play, version code: %d.", filepath.Base(file), versionCode)
+		versionCodes = append(versionCodes, versionCode)
+	}
+
+	track := conf.Track
+	if track == "" {
+		track = defaultPlayTrack
+	}
+	status := conf.Status
+	if status == "" {
+		status = defaultPlayStatus
+	}
+	release := &androidpublisher.TrackRelease{
+		Name:         versionName,
+		Status:       status,
+		VersionCodes: versionCodes,
+	}
+	if releaseNotes != "" {
+		release.ReleaseNotes = []*androidpublisher.LocalizedText{{Language: "en-US", Text: releaseNotes}}
+	}
+	if _, err := service.Edits.Tracks.Update(conf.PackageName, edit.Id, track, &androidpublisher.Track{
+		Track:    track,
+		Releases: []*androidpublisher.TrackRelease{release},
+	}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to update track %s: %s", track, err)
+	}
+	if _, err := service.Edits.Commit(conf.PackageName, edit.Id).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to commit edit: %s", err)
+	}
+	log.Infof("Release is published to the %s track of %s.", track, conf.PackageName)
+	return nil
+}
+
+func uploadToGooglePlay(ctx context.Context, service *androidpublisher.Service, packageName, editID, file string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	contentType := googleapi.ContentType("application/octet-stream")
+	if filepath.Ext(file) == ".aab" {
+		bundle, err := service.Edits.Bundles.Upload(packageName, editID).Media(f, contentType).Context(ctx).Do()
+		if err != nil {
+			return 0, err
+		}
+		return bundle.VersionCode, nil
+	}
+	apk, err := service.Edits.Apks.Upload(packageName, editID).Media(f, contentType).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+	return apk.VersionCode, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// artifactoryChecksumHeader is the sha256 of the artifact returned by artifactory, it is checked before
+// the artifact is downloaded.
+const artifactoryChecksumHeader = "X-Checksum-Sha256"
+
+type DownloadStep struct {
+	spec       *step.StepDownloadSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewDownloadStep(spec interface{}, workspace string, envs, secretEnvs []string) (*DownloadStep, error) {
+	downloadStep := &DownloadStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return downloadStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &downloadStep.spec); err != nil {
+		return downloadStep, fmt.Errorf("unmarshal spec %s to download spec failed", yamlBytes)
+	}
+	return downloadStep, nil
+}
+
+func (s *DownloadStep) Run(ctx context.Context) error {
+	start := time.Now()
+	defer func() {
+		log.Infof("Download ended. Duration: %.2f seconds", time.Since(start).Seconds())
+	}()
+
+	envmaps := make(map[string]string)
+	// the values of the secrets, e.g. the tokens, may contain "=".
+	for _, env := range append(s.envs, s.secretEnvs...) {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		envmaps[kv[0]] = kv[1]
+	}
+	expand := func(str string) string {
+		return os.Expand(str, func(key string) string { return envmaps[key] })
+	}
+
+	for _, artifact := range s.spec.Artifacts {
+		if err := s.download(ctx, artifact, expand); err != nil {
+			return fmt.Errorf("failed to download %s: %s", maskSecretEnvs(expand(artifact.Name()), s.secretEnvs), err)
+		}
+	}
+	return nil
+}
+
+func (s *DownloadStep) download(ctx context.Context, artifact *step.ArtifactDownload, expand func(string) string) error {
+	workspace := filepath.Clean(s.workspace)
+	dest := filepath.Join(workspace, expand(artifact.Destination))
+	if !strings.HasPrefix(dest, workspace+string(os.PathSeparator)) {
+		return fmt.Errorf("destination %s is out of the workspace", artifact.Destination)
+	}
+	expected := strings.ToLower(strings.TrimSpace(expand(artifact.Sha256)))
+	if err := step.ValidateSha256(expected); err != nil {
+		return err
+	}
+
+	log.Infof("Start downloading %s to %s.", maskSecretEnvs(expand(artifact.Name()), s.secretEnvs), artifact.Destination)
+	var body io.ReadCloser
+	var err error
+	switch artifact.Source {
+	case step.ArtifactSourceS3:
+		body, err = openS3Artifact(artifact, expand)
+	default:
+		body, err = openHTTPArtifact(ctx, artifact, expected, expand)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	// the artifact is saved to a temporary file first so that a corrupted one is never left at the destination.
+	tmp, err := ioutil.TempFile(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("sha256 mismatch, expected: %s, actual: %s", expected, actual)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return err
+	}
+	log.Infof("Downloaded %s, size: %d, sha256: %s.", artifact.Destination, size, expected)
+	return nil
+}
+
+func openHTTPArtifact(ctx context.Context, artifact *step.ArtifactDownload, expected string, expand func(string) string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, expand(artifact.URL), nil)
+	if err != nil {
+		return nil, err
+	}
+	if artifact.TokenEnv != "" {
+		req.Header.Set("Authorization", "Bearer "+expand("$"+artifact.TokenEnv))
+	} else if artifact.UsernameEnv != "" {
+		req.SetBasicAuth(expand("$"+artifact.UsernameEnv), expand("$"+artifact.PasswordEnv))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	if artifact.Source == step.ArtifactSourceArtifactory {
+		if checksum := strings.ToLower(resp.Header.Get(artifactoryChecksumHeader)); checksum != "" && checksum != expected {
+			resp.Body.Close()
+			return nil, fmt.Errorf("sha256 mismatch, expected: %s, artifactory: %s", expected, checksum)
+		}
+	}
+	return resp.Body, nil
+}
+
+func openS3Artifact(artifact *step.ArtifactDownload, expand func(string) string) (io.ReadCloser, error) {
+	if artifact.S3 == nil {
+		return nil, fmt.Errorf("object storage is not found")
+	}
+	forcedPathStyle := true
+	if artifact.S3.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(artifact.S3.Endpoint, artifact.S3.Ak, artifact.S3.Sk, artifact.S3.Insecure, forcedPathStyle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client to download file, err: %s", err)
+	}
+	key := strings.TrimLeft(path.Join(artifact.S3.Subfolder, expand(artifact.ObjectPath)), "/")
+	obj, err := client.GetFile(artifact.S3.Bucket, key, &s3.DownloadOption{RetryNum: 3})
+	if err != nil {
+		return nil, err
+	}
+	return obj.Body, nil
+}
@@ -18,12 +18,14 @@ package step
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -34,11 +36,17 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/jobexecutor/config"
 	c "github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service/cmd"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+	"github.com/koderover/zadig/pkg/tool/gerrit"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/secretscan"
 	"github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/step"
 )
 
+// gitMirrorRemote is the remote of the mirror kept by zadig
+const gitMirrorRemote = "zadig-mirror"
+
 type GitStep struct {
 	spec       *step.StepGitSpec
 	envs       []string
@@ -64,7 +72,53 @@ func (s *GitStep) Run(ctx context.Context) error {
 	defer func() {
 		log.Infof("Git clone ended. Duration: %.2f seconds.", time.Since(start).Seconds())
 	}()
-	return s.runGitCmds()
+	if err := s.runGitCmds(); err != nil {
+		return err
+	}
+	if s.spec.SecretScan != nil {
+		s.scanSecrets()
+	}
+	return nil
+}
+
+// scanSecrets scans the changes of the pull requests merged into the branches, the findings are printed to
+// the log and collected when the log is saved.
+func (s *GitStep) scanSecrets() {
+	scanner, err := secretscan.New(s.spec.SecretScan)
+	if err != nil {
+		log.Warnf("Failed to create the secret scanner: %s", err)
+		return
+	}
+	for _, repo := range s.spec.Repos {
+		if repo == nil || len(repo.RepoName) == 0 || repo.PR <= 0 || len(repo.Branch) == 0 {
+			continue
+		}
+		// ORIG_HEAD is the branch before the pull request is merged
+		cmd := c.Diff("ORIG_HEAD", "HEAD")
+		cmd.Dir = s.repoWorkDir(repo)
+		diff, err := cmd.Output()
+		if err != nil {
+			log.Warnf("Failed to get the changes of pull request %d of %s: %s", repo.PR, repo.RepoName, err)
+			continue
+		}
+		findings, err := scanner.ScanDiff(bytes.NewReader(diff))
+		if err != nil {
+			log.Warnf("Failed to scan the changes of pull request %d of %s: %s", repo.PR, repo.RepoName, err)
+			continue
+		}
+		for _, finding := range findings {
+			finding.File = path.Join(repo.RepoName, finding.File)
+			fmt.Println(secretscan.FormatFinding(finding))
+		}
+		log.Infof("%d secrets are found in the changes of pull request %d of %s.", len(findings), repo.PR, repo.RepoName)
+	}
+}
+
+func (s *GitStep) repoWorkDir(repo *types.Repository) string {
+	if len(repo.CheckoutPath) != 0 {
+		return filepath.Join(s.workspace, repo.CheckoutPath)
+	}
+	return filepath.Join(s.workspace, repo.RepoName)
 }
 
 func (s *GitStep) RunGitGc(folder string) error {
@@ -85,6 +139,13 @@ func (s *GitStep) runGitCmds() error {
 		noProxy := ""
 		proxyFlag := false
 		for _, repo := range s.spec.Repos {
+			// the mirror kept by zadig is always reached directly
+			if uri, err := url.Parse(repo.MirrorURL); err == nil && repo.MirrorURL != "" {
+				if noProxy != "" {
+					noProxy += ","
+				}
+				noProxy += uri.Host
+			}
 			if repo.EnableProxy {
 				if !proxyFlag {
 					envs = append(envs, fmt.Sprintf("http_proxy=%s", s.spec.Proxy.GetProxyURL()))
@@ -133,6 +194,7 @@ func (s *GitStep) runGitCmds() error {
 				repo.Password = password
 				tokens = append(tokens, repo.Password)
 			}
+			tokens = append(tokens, repo.SSHKey)
 		} else if repo.Source == types.ProviderCodehub {
 			tokens = append(tokens, repo.Password)
 		} else if repo.Source == types.ProviderOther {
@@ -140,6 +202,11 @@ func (s *GitStep) runGitCmds() error {
 			tokens = append(tokens, repo.SSHKey)
 		}
 		tokens = append(tokens, repo.OauthToken)
+		if u, err := url.Parse(repo.MirrorURL); err == nil && repo.MirrorURL != "" {
+			if password, ok := u.User.Password(); ok {
+				tokens = append(tokens, password)
+			}
+		}
 		cmds = append(cmds, s.buildGitCommands(repo, hostNames)...)
 	}
 	// write ssh key
@@ -150,42 +217,51 @@ func (s *GitStep) runGitCmds() error {
 	}
 
 	for _, c := range cmds {
-		cmdOutReader, err := c.Cmd.StdoutPipe()
+		err := runGitCmd(c, envs, tokens)
+		if err != nil && c.Fallback != nil {
+			fmt.Printf("failed to run the command, falling back: %s\n", maskSecret(tokens, err.Error()))
+			err = runGitCmd(c.Fallback, envs, tokens)
+		}
 		if err != nil {
+			if c.IgnoreError {
+				continue
+			}
 			return err
 		}
+	}
+	return nil
+}
 
-		outScanner := bufio.NewScanner(cmdOutReader)
-		go func() {
-			for outScanner.Scan() {
-				fmt.Printf("%s\n", maskSecret(tokens, outScanner.Text()))
-			}
-		}()
+func runGitCmd(command *c.Command, envs, tokens []string) error {
+	cmdOutReader, err := command.Cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
 
-		cmdErrReader, err := c.Cmd.StderrPipe()
-		if err != nil {
-			return err
+	outScanner := bufio.NewScanner(cmdOutReader)
+	go func() {
+		for outScanner.Scan() {
+			fmt.Printf("%s\n", maskSecret(tokens, outScanner.Text()))
 		}
+	}()
 
-		errScanner := bufio.NewScanner(cmdErrReader)
-		go func() {
-			for errScanner.Scan() {
-				fmt.Printf("%s\n", maskSecret(tokens, errScanner.Text()))
-			}
-		}()
+	cmdErrReader, err := command.Cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
 
-		c.Cmd.Env = envs
-		if !c.DisableTrace {
-			fmt.Printf("%s\n", strings.Join(c.Cmd.Args, " "))
-		}
-		if err := c.Cmd.Run(); err != nil {
-			if c.IgnoreError {
-				continue
-			}
-			return err
+	errScanner := bufio.NewScanner(cmdErrReader)
+	go func() {
+		for errScanner.Scan() {
+			fmt.Printf("%s\n", maskSecret(tokens, errScanner.Text()))
 		}
+	}()
+
+	command.Cmd.Env = envs
+	if !command.DisableTrace {
+		fmt.Printf("%s\n", strings.Join(command.Cmd.Args, " "))
 	}
-	return nil
+	return command.Cmd.Run()
 }
 
 func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String) []*c.Command {
@@ -196,10 +272,7 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 		return cmds
 	}
 
-	workDir := filepath.Join(s.workspace, repo.RepoName)
-	if len(repo.CheckoutPath) != 0 {
-		workDir = filepath.Join(s.workspace, repo.CheckoutPath)
-	}
+	workDir := s.repoWorkDir(repo)
 
 	if _, err := os.Stat(workDir); os.IsNotExist(err) {
 		os.MkdirAll(workDir, 0777)
@@ -233,6 +306,25 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 			Cmd:          c.RemoteAdd(repo.RemoteName, OAuthCloneURL(repo.Source, repo.OauthToken, host, owner, repo.RepoName, u.Scheme)),
 			DisableTrace: true,
 		})
+	} else if repo.Source == types.ProviderGerrit && repo.AuthType == types.SSHAuthType {
+		host := getHost(repo.Address)
+		if u, err := url.Parse(repo.Address); err == nil {
+			host = u.Hostname()
+		}
+		if !hostNames.Has(host) {
+			if err := writeSSHFile(repo.SSHKey, host); err != nil {
+				log.Errorf("failed to write ssh file %s: %s", repo.SSHKey, err)
+			}
+			hostNames.Insert(host)
+		}
+		cloneURL, err := gerrit.SSHCloneURL(repo.Address, repo.SSHPort, repo.Username, repo.RepoName)
+		if err != nil {
+			log.Errorf("failed to get the ssh clone url of %s: %s", repo.RepoName, err)
+		}
+		cmds = append(cmds, &c.Command{
+			Cmd:          c.RemoteAdd(repo.RemoteName, cloneURL),
+			DisableTrace: true,
+		})
 	} else if repo.Source == types.ProviderGerrit {
 		u, _ := url.Parse(repo.Address)
 		u.Path = fmt.Sprintf("/a/%s", repo.RepoName)
@@ -250,6 +342,34 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 			Cmd:          c.RemoteAdd(repo.RemoteName, fmt.Sprintf("%s://%s:%s@%s/%s/%s.git", u.Scheme, user, repo.Password, host, owner, repo.RepoName)),
 			DisableTrace: true,
 		})
+	} else if repo.Source == types.ProviderBitbucketServer {
+		u, _ := url.Parse(repo.Address)
+		host := strings.TrimSuffix(strings.Join([]string{u.Host, u.Path}, "/"), "/")
+		// bitbucket server accepts http access tokens with any user name
+		user := repo.Username
+		if user == "" {
+			user = "x-token-auth"
+		}
+		cmds = append(cmds, &c.Command{
+			Cmd:          c.RemoteAdd(repo.RemoteName, fmt.Sprintf("%s://%s:%s@%s/scm/%s/%s.git", u.Scheme, url.QueryEscape(user), repo.OauthToken, host, owner, repo.RepoName)),
+			DisableTrace: true,
+		})
+	} else if repo.Source == types.ProviderAzureDevOps {
+		// the address is the url of the organization, e.g. https://dev.azure.com/koderover
+		u, _ := url.Parse(strings.TrimSuffix(repo.Address, "/"))
+		organizationURL := u.String()
+		repoPath := fmt.Sprintf("/%s/_git/%s", url.PathEscape(owner), url.PathEscape(repo.RepoName))
+		if azuredevops.IsBearerToken(repo.OauthToken) {
+			// the entra access tokens are only accepted in the authorization header, it is scoped to the organization.
+			cmds = append(cmds,
+				&c.Command{Cmd: c.RemoteAdd(repo.RemoteName, organizationURL+repoPath)},
+				&c.Command{Cmd: c.SetConfig(fmt.Sprintf("http.%s/.extraheader", organizationURL), "AUTHORIZATION: bearer "+repo.OauthToken), DisableTrace: true},
+			)
+		} else {
+			// the personal access tokens are accepted as the password of any user name.
+			u.User = url.UserPassword("pat", repo.OauthToken)
+			cmds = append(cmds, &c.Command{Cmd: c.RemoteAdd(repo.RemoteName, u.String()+repoPath), DisableTrace: true})
+		}
 	} else if repo.Source == types.ProviderGitee {
 		cmds = append(cmds, &c.Command{Cmd: c.RemoteAdd(repo.RemoteName, HTTPSCloneURL(repo.Source, repo.OauthToken, repo.RepoOwner, repo.RepoName)), DisableTrace: true})
 	} else if repo.Source == types.ProviderOther {
@@ -292,7 +412,25 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 		return cmds
 	}
 
-	cmds = append(cmds, &c.Command{Cmd: c.Fetch(repo.RemoteName, ref)}, &c.Command{Cmd: c.CheckoutHead()})
+	// fetch from the mirror kept by zadig if there is one, and from the codehost if the mirror fails
+	fetchCmd := func(fetch func(remoteName, ref string) *exec.Cmd, ref string) *c.Command {
+		if repo.MirrorURL == "" {
+			return &c.Command{Cmd: fetch(repo.RemoteName, ref)}
+		}
+		return &c.Command{
+			Cmd:          fetch(gitMirrorRemote, ref),
+			DisableTrace: true,
+			Fallback:     &c.Command{Cmd: fetch(repo.RemoteName, ref)},
+		}
+	}
+	if repo.MirrorURL != "" {
+		cmds = append(cmds,
+			&c.Command{Cmd: c.RemoteRemove(gitMirrorRemote), DisableTrace: true, IgnoreError: true},
+			&c.Command{Cmd: c.RemoteAdd(gitMirrorRemote, repo.MirrorURL), DisableTrace: true},
+		)
+	}
+
+	cmds = append(cmds, fetchCmd(c.Fetch, ref), &c.Command{Cmd: c.CheckoutHead()})
 
 	// PR rebase branch 请求
 	if repo.PR > 0 && len(repo.Branch) > 0 {
@@ -300,9 +438,9 @@ func (s *GitStep) buildGitCommands(repo *types.Repository, hostNames sets.String
 		ref := fmt.Sprintf("%s:%s", repo.PRRef(), newBranch)
 		cmds = append(
 			cmds,
-			&c.Command{Cmd: c.DeepenedFetch(repo.RemoteName, repo.BranchRef())},
+			fetchCmd(c.DeepenedFetch, repo.BranchRef()),
 			&c.Command{Cmd: c.ResetMerge()},
-			&c.Command{Cmd: c.DeepenedFetch(repo.RemoteName, ref)},
+			fetchCmd(c.DeepenedFetch, ref),
 			&c.Command{Cmd: c.Merge(newBranch)},
 		)
 	}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/tool/summary"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type SummaryStep struct {
+	spec       *step.StepSummarySpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewSummaryStep(spec interface{}, workspace string, envs, secretEnvs []string) (*SummaryStep, error) {
+	summaryStep := &SummaryStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return summaryStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &summaryStep.spec); err != nil {
+		return summaryStep, fmt.Errorf("unmarshal spec %s to summary spec failed", yamlBytes)
+	}
+	return summaryStep, nil
+}
+
+// Run uploads the summary file, a missing summary file does not fail the job since the tools may
+// only write it in some cases.
+func (s *SummaryStep) Run(ctx context.Context) error {
+	if s.spec.FilePath == "" || s.spec.ObjectKey == "" || s.spec.S3 == nil {
+		return nil
+	}
+
+	envmaps := make(map[string]string)
+	for _, env := range append(s.envs, s.secretEnvs...) {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		envmaps[kv[0]] = kv[1]
+	}
+	filePath := replaceEnvWithValue(s.spec.FilePath, envmaps)
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(s.workspace, filePath)
+	}
+
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		log.Infof("Summary file %s is not found, skipped.", s.spec.FilePath)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read the summary file %s: %s", s.spec.FilePath, err)
+	}
+	if info.Size() > summary.MaxSize {
+		return fmt.Errorf("the summary file %s of %d bytes exceeds the limit of %d bytes", s.spec.FilePath, info.Size(), summary.MaxSize)
+	}
+
+	forcedPathStyle := true
+	if s.spec.S3.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(s.spec.S3.Endpoint, s.spec.S3.Ak, s.spec.S3.Sk, s.spec.S3.Insecure, forcedPathStyle)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client to upload the summary, err: %s", err)
+	}
+	if err := client.Upload(s.spec.S3.Bucket, filePath, s.spec.ObjectKey); err != nil {
+		return fmt.Errorf("failed to upload the summary file %s: %s", s.spec.FilePath, err)
+	}
+	log.Infof("Summary file %s is uploaded.", s.spec.FilePath)
+	return nil
+}
@@ -57,6 +57,7 @@ func Execute(ctx context.Context) error {
 
 	excutor := "job-executor"
 	var err error
+	var j *job.Job
 	defer func() {
 		// os.Remove(ZadigLifeCycleFile)
 		resultMsg := types.JobSuccess
@@ -71,10 +72,15 @@ func Execute(ctx context.Context) error {
 		}
 
 		fmt.Printf("====================== %s End. Duration: %.2f seconds ======================\n", excutor, time.Since(start).Seconds())
+		// keep the pod alive so that the workspace of the failed job can be inspected.
+		if err != nil && j != nil && j.Ctx.DebugTimeout > 0 {
+			fmt.Printf("The job failed, the pod is kept for debugging for %d minutes, open the debug terminal of the job to inspect the workspace %s.\n", j.Ctx.DebugTimeout, j.Ctx.Workspace)
+			time.Sleep(time.Duration(j.Ctx.DebugTimeout) * time.Minute)
+			return
+		}
 		time.Sleep(30 * time.Second)
 	}()
 
-	var j *job.Job
 	j, err = job.NewJob()
 	if err != nil {
 		return err
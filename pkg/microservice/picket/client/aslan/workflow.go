@@ -59,6 +59,17 @@ func (c *Client) ListTestWorkflows(testName string, header http.Header, qs url.V
 	return res.Body(), nil
 }
 
+func (c *Client) SearchWorkflowTasks(header http.Header, qs url.Values) ([]byte, error) {
+	url := "/workflow/v4/workflowtask/search"
+
+	res, err := c.Get(url, httpclient.SetHeadersFromHTTPHeader(header), httpclient.SetQueryParamsFromValues(qs))
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body(), nil
+}
+
 func (c *Client) CreateWorkflowTask(header http.Header, qs url.Values, body []byte, workflowName string) ([]byte, error) {
 	url := path.Join("/workflow/workflowtask", workflowName)
 
@@ -17,12 +17,19 @@ limitations under the License.
 package opa
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/koderover/zadig/pkg/tool/cache"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
+	opatool "github.com/koderover/zadig/pkg/tool/opa"
 )
 
+// decisionCacheTTL covers the delay before opa loads the new bundle after the cache is invalidated.
+const decisionCacheTTL = 30 * time.Second
+
 type InputGenerator func() (*Input, error)
 
 // Evaluate evaluates the query with the given input and return a json response which has a field called "result"
@@ -39,11 +46,23 @@ func (c *Client) Evaluate(query string, result interface{}, ig InputGenerator) e
 		Input: input,
 	}
 
+	// the input contains the token of the user, so the decisions are cached by the hash of it.
+	key := ""
+	if cache.Enabled() {
+		if data, err := json.Marshal(req); err == nil {
+			key = cache.NamespaceKey(opatool.DecisionCacheNamespace, cache.Hash(query, string(data)))
+		}
+	}
+	if cache.Get(key, result) {
+		return nil
+	}
+
 	queryURL := fmt.Sprintf("%s/%s", url, strings.ReplaceAll(query, ".", "/"))
 	_, err = c.Post(queryURL, httpclient.SetBody(req), httpclient.SetResult(result))
 	if err != nil {
 		return err
 	}
+	cache.Set(key, result, decisionCacheTTL)
 
 	return nil
 }
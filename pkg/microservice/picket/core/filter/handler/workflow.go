@@ -44,3 +44,10 @@ func ListWorkflowsV3(c *gin.Context) {
 
 	ctx.Resp, ctx.Err = service.ListWorkflowsV3(c.Request.Header, c.Request.URL.Query(), ctx.Logger)
 }
+
+func SearchWorkflowTasks(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.SearchWorkflowTasks(c.Request.Header, c.Request.URL.Query(), ctx.Logger)
+}
@@ -96,6 +96,29 @@ func ListTestWorkflows(testName string, header http.Header, qs url.Values, logge
 	return aslan.New().ListTestWorkflows(testName, header, qs)
 }
 
+// SearchWorkflowTasks searches the task history of the workflows in all the projects the caller can view.
+func SearchWorkflowTasks(header http.Header, qs url.Values, logger *zap.SugaredLogger) ([]byte, error) {
+	rules := []*rule{{
+		method:   "/api/aslan/workflow/workflow",
+		endpoint: "GET",
+	}}
+	names, err := getAllowedProjects(header, rules, config.AND, logger)
+	if err != nil {
+		logger.Errorf("Failed to get allowed project names, err: %s", err)
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	qs.Del("projects")
+	if !(len(names) == 1 && names[0] == "*") {
+		for _, name := range names {
+			qs.Add("projects", name)
+		}
+	}
+	return aslan.New().SearchWorkflowTasks(header, qs)
+}
+
 //getAllowedProjects
 //rulesLogicalOperator@ OR:satisfy any one of rules / AND:satisfy all rules
 func getAllowedProjects(headers http.Header, rules []*rule, rulesLogicalOperator config.RulesLogicalOperator, logger *zap.SugaredLogger) (projects []string, err error) {
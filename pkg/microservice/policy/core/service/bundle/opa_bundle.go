@@ -26,6 +26,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/policy/core/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/policy/core/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/policy/core/yamlconfig"
+	"github.com/koderover/zadig/pkg/tool/cache"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/tool/opa"
 )
@@ -520,6 +521,9 @@ func GenerateOPABundle() error {
 		log.Errorf("Failed to calculate bundle hash, err: %s", err)
 		return err
 	}
+	if revision != hash {
+		cache.Invalidate(opa.DecisionCacheNamespace)
+	}
 	revision = hash
 
 	return bundle.Save(config.DataPath())
@@ -133,6 +133,7 @@ func GetUserRulesByProject(uid string, projectName string, log *zap.SugaredLogge
 	}
 	environmentVerbMap := make(map[string][]string)
 	workflowVerbMap := make(map[string][]string)
+	serviceVerbMap := make(map[string][]string)
 	for labelKey, resources := range resp.Resources {
 		for _, resource := range resources {
 			resourceType := resource.Type
@@ -158,6 +159,15 @@ func GetUserRulesByProject(uid string, projectName string, log *zap.SugaredLogge
 						workflowVerbMap[resource.Name] = verbs
 					}
 				}
+				if resourceType == string(config.ResourceTypeService) {
+					if resourceVerbs, rOK := serviceVerbMap[resource.Name]; rOK {
+						verbSet := sets.NewString(resourceVerbs...)
+						verbSet.Insert(verbs...)
+						serviceVerbMap[resource.Name] = verbSet.List()
+					} else {
+						serviceVerbMap[resource.Name] = verbs
+					}
+				}
 			} else {
 				log.Warnf("labelVerbMap key:%s not exist", resource.Type+":"+labelKey)
 			}
@@ -169,6 +179,7 @@ func GetUserRulesByProject(uid string, projectName string, log *zap.SugaredLogge
 		IsProjectAdmin:      isProjectAdmin,
 		EnvironmentVerbsMap: environmentVerbMap,
 		WorkflowVerbsMap:    workflowVerbMap,
+		ServiceVerbsMap:     serviceVerbMap,
 	}, nil
 }
 
@@ -178,6 +189,7 @@ type GetUserRulesByProjectResp struct {
 	ProjectVerbs        []string            `json:"project_verbs"`
 	WorkflowVerbsMap    map[string][]string `json:"workflow_verbs_map"`
 	EnvironmentVerbsMap map[string][]string `json:"environment_verbs_map"`
+	ServiceVerbsMap     map[string][]string `json:"service_verbs_map"`
 }
 
 type GetUserRulesResp struct {
@@ -369,3 +381,36 @@ func GetResourcesPermission(uid string, projectName string, resourceType string,
 	}
 	return resourceRes, nil
 }
+
+// IsSystemAdmin returns whether the user is bound to the system admin role.
+func IsSystemAdmin(uid string) (bool, error) {
+	roleBindings, err := mongodb.NewRoleBindingColl().ListRoleBindingsByUIDs([]string{uid, "*"})
+	if err != nil {
+		return false, err
+	}
+	for _, rolebinding := range roleBindings {
+		if rolebinding.RoleRef.Name == string(setting.SystemAdmin) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListUserProjects returns the projects the user is bound to a role in, including the public ones,
+// isAdmin is true if the user is a system admin who has access to all the projects.
+func ListUserProjects(uid string) (projects []string, isAdmin bool, err error) {
+	roleBindings, err := mongodb.NewRoleBindingColl().ListRoleBindingsByUIDs([]string{uid, "*"})
+	if err != nil {
+		return nil, false, err
+	}
+	projectSet := sets.NewString()
+	for _, rolebinding := range roleBindings {
+		if rolebinding.RoleRef.Name == string(setting.SystemAdmin) {
+			return nil, true, nil
+		}
+		if rolebinding.Namespace != SystemScope {
+			projectSet.Insert(rolebinding.Namespace)
+		}
+	}
+	return projectSet.List(), false, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+
+	"github.com/spf13/viper"
+
+	_ "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+const (
+	jobExecutorEnv = "ZADIG_RUNNER_JOB_EXECUTOR"
+	workDirEnv     = "ZADIG_RUNNER_WORK_DIR"
+)
+
+func init() {
+	viper.SetDefault(jobExecutorEnv, "jobexecutor")
+	viper.SetDefault(workDirEnv, os.TempDir())
+}
+
+func ServerURL() string {
+	return viper.GetString(runner.ServerURLEnv)
+}
+
+func RunnerName() string {
+	return viper.GetString(runner.RunnerNameEnv)
+}
+
+func RunnerToken() string {
+	return viper.GetString(runner.RunnerTokenEnv)
+}
+
+// JobID is set if the agent is launched for a job, it exits after the job is done.
+func JobID() string {
+	return viper.GetString(runner.JobIDEnv)
+}
+
+// JobExecutor is the path of the job executor binary.
+func JobExecutor() string {
+	return viper.GetString(jobExecutorEnv)
+}
+
+func WorkDir() string {
+	return viper.GetString(workDirEnv)
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service/meta"
+	"github.com/koderover/zadig/pkg/microservice/runneragent/config"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/job"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+const (
+	pollInterval      = 5 * time.Second
+	heartbeatInterval = 10 * time.Second
+)
+
+type Agent struct {
+	client      *client
+	name        string
+	jobID       string
+	jobExecutor string
+	workDir     string
+}
+
+func New() (*Agent, error) {
+	if config.ServerURL() == "" || config.RunnerName() == "" || config.RunnerToken() == "" {
+		return nil, fmt.Errorf("%s, %s and %s are required", runner.ServerURLEnv, runner.RunnerNameEnv, runner.RunnerTokenEnv)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Agent{
+		client:      newClient(config.ServerURL(), config.RunnerName(), config.RunnerToken()),
+		name:        hostname,
+		jobID:       config.JobID(),
+		jobExecutor: config.JobExecutor(),
+		workDir:     config.WorkDir(),
+	}, nil
+}
+
+// Run leases the jobs of the runner and runs them one by one until the context is done,
+// the agent launched for a job returns after the job is done.
+func (a *Agent) Run(ctx context.Context) error {
+	log.Infof("agent %s of runner %s is started", a.name, config.RunnerName())
+	for {
+		lease, err := a.client.lease(&runner.LeaseArgs{Agent: a.name, JobID: a.jobID})
+		if err != nil {
+			log.Errorf("failed to lease job: %s", err)
+		} else if lease != nil {
+			a.runJob(ctx, lease)
+			if a.jobID != "" {
+				return nil
+			}
+			continue
+		} else if a.jobID != "" {
+			// the job is cancelled before the agent is up.
+			log.Infof("job %s is not queued, exit", a.jobID)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (a *Agent) runJob(ctx context.Context, lease *runner.Lease) {
+	log.Infof("job %s is leased", lease.JobID)
+	args := &runner.CompleteArgs{Agent: a.name, Status: runner.JobPassed}
+	if err := a.execute(ctx, lease, args); err != nil {
+		args.Status = runner.JobFailed
+		args.Error = err.Error()
+	}
+
+	// the result is kept by the server once it is reported, it is retried until the lease is lost.
+	for i := 0; i < 10; i++ {
+		err := a.client.complete(lease.JobID, args)
+		if err == nil {
+			log.Infof("job %s is done: %s", lease.JobID, args.Status)
+			return
+		}
+		log.Errorf("failed to complete job %s: %s", lease.JobID, err)
+		time.Sleep(heartbeatInterval)
+	}
+}
+
+func (a *Agent) execute(ctx context.Context, lease *runner.Lease, args *runner.CompleteArgs) error {
+	dir := filepath.Join(a.workDir, "zadig-runner", lease.JobID)
+	artifactsDir := filepath.Join(dir, "artifacts")
+	if err := os.MkdirAll(artifactsDir, os.ModePerm); err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	envs := []string{fmt.Sprintf("%s=%s", runner.ArtifactsDirEnv, artifactsDir)}
+	if lease.Signing != nil {
+		keychain, cleanup, err := installSigning(dir, lease.JobID, lease.Signing)
+		if err != nil {
+			return fmt.Errorf("failed to install the signing identity: %s", err)
+		}
+		defer cleanup()
+		if keychain != "" {
+			envs = append(envs, fmt.Sprintf("%s=%s", runner.KeychainEnv, keychain))
+		}
+	}
+	configFile, err := writeJobConfig(lease.Context, dir, envs)
+	if err != nil {
+		return err
+	}
+	// the outputs of the last job must not be reported for this one.
+	_ = os.Remove(job.JobTerminationFile)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if lease.Timeout > 0 {
+		jobCtx, cancel = context.WithTimeout(jobCtx, time.Duration(lease.Timeout)*time.Minute)
+		defer cancel()
+	}
+
+	logs := newLogStreamer(a.client, lease.JobID)
+	cmd := exec.CommandContext(jobCtx, a.jobExecutor)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", setting.JobConfigFile, configFile))
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+
+	heartbeatDone := make(chan struct{})
+	cancelled := false
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				res, err := a.client.heartbeat(lease.JobID, &runner.HeartbeatArgs{Agent: a.name})
+				if err != nil {
+					log.Errorf("failed to send heartbeat of job %s: %s", lease.JobID, err)
+					continue
+				}
+				if res.Cancelled {
+					log.Infof("job %s is cancelled", lease.JobID)
+					cancelled = true
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	logs.start()
+	runErr := cmd.Run()
+	cancel()
+	<-heartbeatDone
+	logs.stop()
+
+	if cancelled {
+		return fmt.Errorf("job is cancelled")
+	}
+	if runErr != nil {
+		if jobCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("job is timed out")
+		}
+		return runErr
+	}
+
+	outputs, err := readOutputs()
+	if err != nil {
+		return fmt.Errorf("failed to read the outputs: %s", err)
+	}
+	args.Outputs = outputs
+	return a.uploadArtifacts(lease.JobID, artifactsDir)
+}
+
+// writeJobConfig exports the envs of the agent to the job and saves the config for the job executor.
+func writeJobConfig(context, dir string, envs []string) (string, error) {
+	jobCtx := new(meta.JobContext)
+	if err := yaml.Unmarshal([]byte(context), jobCtx); err != nil {
+		return "", fmt.Errorf("failed to unmarshal job context: %s", err)
+	}
+	jobCtx.Envs = append(jobCtx.Envs, envs...)
+
+	data, err := yaml.Marshal(jobCtx)
+	if err != nil {
+		return "", err
+	}
+	configFile := filepath.Join(dir, "job.yaml")
+	return configFile, ioutil.WriteFile(configFile, data, 0600)
+}
+
+func readOutputs() ([]*job.JobOutput, error) {
+	data, err := ioutil.ReadFile(job.JobTerminationFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var outputs []*job.JobOutput
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+func (a *Agent) uploadArtifacts(jobID, artifactsDir string) error {
+	return filepath.Walk(artifactsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		name, err := filepath.Rel(artifactsDir, path)
+		if err != nil {
+			return err
+		}
+		if err := a.client.uploadArtifact(jobID, filepath.ToSlash(name), path); err != nil {
+			return fmt.Errorf("failed to upload artifact %s: %s", name, err)
+		}
+		log.Infof("artifact %s of job %s is uploaded", name, jobID)
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+type client struct {
+	*httpclient.Client
+
+	// upload is not retried since the body is read once, and it has no timeout for the large artifacts.
+	upload *httpclient.Client
+}
+
+func newClient(serverURL, runnerName, token string) *client {
+	c := httpclient.New(
+		httpclient.SetHostURL(fmt.Sprintf("%s/%s", serverURL, runnerName)),
+		httpclient.SetRetryCount(3),
+		httpclient.SetRetryWaitTime(time.Second),
+	)
+	c.SetHeader(runner.TokenHeader, token)
+
+	upload := httpclient.New(
+		httpclient.SetHostURL(fmt.Sprintf("%s/%s", serverURL, runnerName)),
+		httpclient.UnsetTimeout(),
+	)
+	upload.SetHeader(runner.TokenHeader, token)
+
+	return &client{Client: c, upload: upload}
+}
+
+func (c *client) lease(args *runner.LeaseArgs) (*runner.Lease, error) {
+	res := new(runner.LeaseResponse)
+	if _, err := c.Post("/lease", httpclient.SetBody(args), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res.Job, nil
+}
+
+func (c *client) heartbeat(jobID string, args *runner.HeartbeatArgs) (*runner.HeartbeatResponse, error) {
+	res := new(runner.HeartbeatResponse)
+	url := fmt.Sprintf("/jobs/%s/heartbeat", jobID)
+	if _, err := c.Post(url, httpclient.SetBody(args), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// pushLog returns the offset the next chunk is pushed at.
+func (c *client) pushLog(jobID string, offset int64, data []byte) (int64, error) {
+	res := new(runner.LogResponse)
+	url := fmt.Sprintf("/jobs/%s/log", jobID)
+	_, err := c.Post(url,
+		httpclient.SetQueryParam("offset", strconv.FormatInt(offset, 10)),
+		httpclient.SetHeader("Content-Type", "application/octet-stream"),
+		httpclient.SetBody(data),
+		httpclient.SetResult(res),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.Offset, nil
+}
+
+func (c *client) complete(jobID string, args *runner.CompleteArgs) error {
+	url := fmt.Sprintf("/jobs/%s/complete", jobID)
+	_, err := c.Post(url, httpclient.SetBody(args))
+	return err
+}
+
+func (c *client) uploadArtifact(jobID, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	url := fmt.Sprintf("/jobs/%s/artifacts", jobID)
+	_, err = c.upload.Put(url,
+		httpclient.SetQueryParam("name", name),
+		httpclient.SetHeader("Content-Type", "application/octet-stream"),
+		httpclient.SetBody(f),
+	)
+	return err
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+const logFlushInterval = 2 * time.Second
+
+// logStreamer buffers the output of the job and pushes it to the server periodically,
+// the chunks are cut at the line breaks so the secrets in a line are redacted by the server.
+type logStreamer struct {
+	client *client
+	jobID  string
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	offset int64
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func newLogStreamer(c *client, jobID string) *logStreamer {
+	return &logStreamer{
+		client: c,
+		jobID:  jobID,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+func (s *logStreamer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *logStreamer) start() {
+	go func() {
+		defer close(s.doneCh)
+		ticker := time.NewTicker(logFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopCh:
+				s.flush(true)
+				return
+			case <-ticker.C:
+				s.flush(false)
+			}
+		}
+	}()
+}
+
+// stop pushes the rest of the log and waits until it is done.
+func (s *logStreamer) stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+func (s *logStreamer) flush(final bool) {
+	for {
+		chunk := s.nextChunk(final)
+		if len(chunk) == 0 {
+			return
+		}
+		offset, err := s.client.pushLog(s.jobID, s.offset, chunk)
+		if err != nil {
+			log.Errorf("failed to push the log of job %s: %s", s.jobID, err)
+			if !final {
+				// it is pushed again at the next flush.
+				return
+			}
+			time.Sleep(logFlushInterval)
+			continue
+		}
+
+		// the offset is reported by the server since the redacted log differs in size.
+		s.offset = offset
+		s.mu.Lock()
+		s.buf.Next(len(chunk))
+		s.mu.Unlock()
+	}
+}
+
+func (s *logStreamer) nextChunk(final bool) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.buf.Bytes()
+	if len(data) > runner.MaxLogChunkSize {
+		data = data[:runner.MaxLogChunkSize]
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		}
+	} else if !final {
+		i := bytes.LastIndexByte(data, '\n')
+		data = data[:i+1]
+	}
+	return append([]byte(nil), data...)
+}
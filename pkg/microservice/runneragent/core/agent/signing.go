@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/runner"
+)
+
+// installSigning imports the signing identity into a temporary keychain which is added to the search list
+// of the user, and copies the provisioning profiles to where xcode finds them. The returned func removes
+// them after the job is done.
+func installSigning(dir, jobID string, signing *runner.IOSSigning) (string, func(), error) {
+	if runtime.GOOS != "darwin" {
+		return "", nil, fmt.Errorf("the signing identity is only supported on macOS")
+	}
+
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+
+	// the keychain is empty if there is only the provisioning profiles.
+	keychain := ""
+	if signing.Certificate != "" {
+		keychain = filepath.Join(dir, "zadig.keychain-db")
+		if err := installCertificate(dir, keychain, signing, &cleanups); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	profileDir := filepath.Join(home, "Library", "MobileDevice", "Provisioning Profiles")
+	if err := os.MkdirAll(profileDir, 0755); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	for i, profile := range signing.ProvisioningProfiles {
+		data, err := base64.StdEncoding.DecodeString(profile)
+		if err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to decode provisioning profile %d: %s", i, err)
+		}
+		path := filepath.Join(profileDir, fmt.Sprintf("zadig-%s-%d.mobileprovision", jobID, i))
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+		cleanups = append(cleanups, func() { _ = os.Remove(path) })
+	}
+	return keychain, cleanup, nil
+}
+
+func installCertificate(dir, keychain string, signing *runner.IOSSigning, cleanups *[]func()) error {
+	cert, err := base64.StdEncoding.DecodeString(signing.Certificate)
+	if err != nil {
+		return fmt.Errorf("failed to decode the certificate: %s", err)
+	}
+	certFile := filepath.Join(dir, "signing.p12")
+	if err := ioutil.WriteFile(certFile, cert, 0600); err != nil {
+		return err
+	}
+	defer os.Remove(certFile)
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	password := hex.EncodeToString(b)
+
+	if _, err := security("create-keychain", "-p", password, keychain); err != nil {
+		return err
+	}
+	*cleanups = append(*cleanups, func() {
+		if _, err := security("delete-keychain", keychain); err != nil {
+			log.Warnf("failed to delete keychain %s: %s", keychain, err)
+		}
+	})
+
+	steps := [][]string{
+		// the keychain is locked after 6 hours, the jobs are expected to be done before it.
+		{"set-keychain-settings", "-lut", "21600", keychain},
+		{"unlock-keychain", "-p", password, keychain},
+		{"import", certFile, "-k", keychain, "-P", signing.CertificatePassword, "-T", "/usr/bin/codesign", "-T", "/usr/bin/security"},
+		// codesign accesses the key without prompting for the password.
+		{"set-key-partition-list", "-S", "apple-tool:,apple:,codesign:", "-s", "-k", password, keychain},
+	}
+	for _, step := range steps {
+		if _, err := security(step...); err != nil {
+			return err
+		}
+	}
+
+	out, err := security("list-keychains", "-d", "user")
+	if err != nil {
+		return err
+	}
+	var keychains []string
+	for _, line := range strings.Split(out, "\n") {
+		if k := strings.Trim(strings.TrimSpace(line), `"`); k != "" {
+			keychains = append(keychains, k)
+		}
+	}
+	if _, err := security(append([]string{"list-keychains", "-d", "user", "-s", keychain}, keychains...)...); err != nil {
+		return err
+	}
+	*cleanups = append(*cleanups, func() {
+		if _, err := security(append([]string{"list-keychains", "-d", "user", "-s"}, keychains...)...); err != nil {
+			log.Warnf("failed to restore the keychain search list: %s", err)
+		}
+	})
+	return nil
+}
+
+// security runs the security command, the args are not printed since they contain the passwords.
+func security(args ...string) (string, error) {
+	out, err := exec.Command("security", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("security %s failed: %s, output: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
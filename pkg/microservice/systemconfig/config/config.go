@@ -17,9 +17,12 @@ limitations under the License.
 package config
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 
 	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
 )
 
 func MysqlDexDB() string {
@@ -37,3 +40,58 @@ func MongoURI() string {
 func MongoDatabase() string {
 	return configbase.MongoDatabase()
 }
+
+// CodeHostCacheSize is the max number of the codehosts cached in memory, default is 512 and 0 disables the cache.
+func CodeHostCacheSize() int {
+	if !viper.IsSet(setting.ENVCodeHostCacheSize) {
+		return 512
+	}
+	if size := viper.GetInt(setting.ENVCodeHostCacheSize); size > 0 {
+		return size
+	}
+	return 0
+}
+
+// CodeHostCacheTTL is how long a codehost is cached in memory, default is 30 seconds.
+func CodeHostCacheTTL() time.Duration {
+	if ttl := viper.GetInt(setting.ENVCodeHostCacheTTL); ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// CodeHostSecretBackend is where the credentials of the codehosts are stored, default is mongo.
+func CodeHostSecretBackend() string {
+	if backend := viper.GetString(setting.ENVCodeHostSecretBackend); backend != "" {
+		return backend
+	}
+	return "mongo"
+}
+
+// CodeHostSecretVaultMount is the mount of the kv secrets engine of vault, default is secret.
+func CodeHostSecretVaultMount() string {
+	if mount := viper.GetString(setting.ENVCodeHostSecretVaultMount); mount != "" {
+		return mount
+	}
+	return "secret"
+}
+
+func VaultAddress() string {
+	return viper.GetString(setting.ENVVaultAddress)
+}
+
+func VaultToken() string {
+	return viper.GetString(setting.ENVVaultToken)
+}
+
+func EncryptionKeyProvider() string {
+	return viper.GetString(setting.ENVEncryptionKeyProvider)
+}
+
+func EncryptionKeyID() string {
+	return viper.GetString(setting.ENVEncryptionKeyID)
+}
+
+func EncryptionKMSRegion() string {
+	return viper.GetString(setting.ENVEncryptionKMSRegion)
+}
@@ -16,7 +16,33 @@ limitations under the License.
 
 package config
 
+import "time"
+
 const (
 	ENVMysqlDexDB = "MYSQL_DEX_DB"
 	FeatureFlag   = "feature-gates"
 )
+
+const (
+	// TokenRefreshInterval is how often the oauth tokens of the codehosts are checked.
+	TokenRefreshInterval = time.Minute
+	// TokenRefreshAhead is how long before the expiry a token is refreshed.
+	TokenRefreshAhead = 10 * time.Minute
+	// TokenRefreshLease is how long a replica holds the refreshing of a token, it is taken over by the
+	// other replicas after it if the replica dies while refreshing.
+	TokenRefreshLease = time.Minute
+	// GitLabTokenLifetime is used as the lifetime of the gitlab tokens authorized before the expiry is recorded.
+	GitLabTokenLifetime = 2 * time.Hour
+	// TokenExpiryNotifyAhead is how long before the expiry the admins are notified of the tokens which can not
+	// be refreshed automatically.
+	TokenExpiryNotifyAhead = 3 * 24 * time.Hour
+	// CodeHostHealthCheckInterval is how often the codehosts are probed with their credentials.
+	CodeHostHealthCheckInterval = 10 * time.Minute
+	// OAuthStateTTL is how long the authorization of a codehost can be completed after it starts.
+	OAuthStateTTL = 10 * time.Minute
+	// DeviceAuthPollInterval is how often the pending device authorizations are checked, the token endpoint
+	// of each authorization is polled no faster than the interval required by the provider.
+	DeviceAuthPollInterval = 5 * time.Second
+	// DeviceAuthRetention is how long the result of a device authorization is kept after it expires.
+	DeviceAuthRetention = 24 * time.Hour
+)
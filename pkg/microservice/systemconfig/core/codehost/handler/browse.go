@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	codeservice "github.com/koderover/zadig/pkg/microservice/aslan/core/code/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// the browse apis list the namespaces, repos, branches and tags of a codehost for the other services, the
+// lists are cached for a short time. A project can only browse the codehosts available to it.
+
+type browseArgs struct {
+	ProjectName string `form:"projectName"`
+	Namespace   string `form:"namespace"`
+	Type        string `form:"type,default=group"`
+	Repo        string `form:"repo"`
+	Key         string `form:"key"`
+	Page        int    `form:"page,default=1"`
+	PerPage     int    `form:"per_page,default=100"`
+}
+
+func bindBrowseArgs(c *gin.Context, ctx *internalhandler.Context, requireRepo bool) (int, *browseArgs, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return 0, nil, false
+	}
+	args := &browseArgs{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return 0, nil, false
+	}
+	if requireRepo && (args.Namespace == "" || args.Repo == "") {
+		ctx.Err = e.ErrInvalidParam.AddDesc("namespace and repo are required")
+		return 0, nil, false
+	}
+	if err := codeservice.CheckCodehostAvailable(id, args.ProjectName, ctx.Logger); err != nil {
+		ctx.Err = e.ErrForbidden.AddErr(err)
+		return 0, nil, false
+	}
+	return id, args, true
+}
+
+func ListCodeHostNamespaces(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	id, args, ok := bindBrowseArgs(c, ctx, false)
+	if !ok {
+		return
+	}
+	ctx.Resp, ctx.Err = codeservice.CodeHostListNamespaces(id, args.Key, ctx.Logger)
+	if ctx.Err != nil {
+		ctx.Err = e.ErrCodehostListNamespaces.AddErr(ctx.Err)
+	}
+}
+
+func ListCodeHostRepos(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	id, args, ok := bindBrowseArgs(c, ctx, false)
+	if !ok {
+		return
+	}
+	if args.Namespace == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("namespace is required")
+		return
+	}
+	if args.Type != codeservice.UserKind && args.Type != codeservice.GroupKind && args.Type != codeservice.OrgKind {
+		ctx.Err = e.ErrInvalidParam.AddDesc("type must be user/group/org")
+		return
+	}
+	ctx.Resp, ctx.Err = codeservice.CodeHostListProjects(id, args.Namespace, args.Type, args.Page, args.PerPage, args.Key, ctx.Logger)
+	if ctx.Err != nil {
+		ctx.Err = e.ErrCodehostListProjects.AddErr(ctx.Err)
+	}
+}
+
+func ListCodeHostBranches(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	id, args, ok := bindBrowseArgs(c, ctx, true)
+	if !ok {
+		return
+	}
+	ctx.Resp, ctx.Err = codeservice.CodeHostListBranches(id, args.Repo, args.Namespace, args.Key, args.Page, args.PerPage, ctx.Logger)
+	if ctx.Err != nil {
+		ctx.Err = e.ErrCodehostListBranches.AddErr(ctx.Err)
+	}
+}
+
+func ListCodeHostTags(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	id, args, ok := bindBrowseArgs(c, ctx, true)
+	if !ok {
+		return
+	}
+	ctx.Resp, ctx.Err = codeservice.CodeHostListTags(id, args.Repo, args.Namespace, args.Key, args.Page, args.PerPage, ctx.Logger)
+	if ctx.Err != nil {
+		ctx.Err = e.ErrCodehostListTags.AddErr(ctx.Err)
+	}
+}
@@ -20,15 +20,26 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	configbase "github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/pagination"
 )
 
+var codeHostSchema = &pagination.Schema{
+	Key:        "id",
+	Sortable:   []string{"address", "type", "namespace", "alias", "updated_at"},
+	Filterable: []string{"type", "address", "namespace", "auth_type"},
+	Searchable: []string{"address", "namespace", "alias"},
+}
+
 func CreateCodeHost(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -37,7 +48,36 @@ func CreateCodeHost(c *gin.Context) {
 		ctx.Err = err
 		return
 	}
-	ctx.Resp, ctx.Err = service.CreateCodeHost(rep, ctx.Logger)
+	if !validateBeforeSave(c, ctx, rep) {
+		return
+	}
+	ctx.Resp, ctx.Err = service.CreateCodeHost(rep, ctx.UserName, ctx.Logger)
+}
+
+// validateBeforeSave checks the codehost if the validate query is true, the diagnosis is returned in
+// the extra of the error if it is invalid.
+func validateBeforeSave(c *gin.Context, ctx *internalhandler.Context, codehost *models.CodeHost) bool {
+	validate, _ := strconv.ParseBool(c.Query("validate"))
+	if !validate {
+		return true
+	}
+	diagnosis := service.ValidateCodeHost(codehost, ctx.Logger)
+	if diagnosis.Valid {
+		return true
+	}
+	ctx.Err = e.NewWithExtras(e.ErrValidateCodeHost, diagnosis.Message, map[string]interface{}{"diagnosis": diagnosis})
+	return false
+}
+
+func ValidateCodeHost(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	req := new(models.CodeHost)
+	if err := c.ShouldBindJSON(req); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp = service.ValidateCodeHost(req, ctx.Logger)
 }
 
 func ListCodeHost(c *gin.Context) {
@@ -48,7 +88,61 @@ func ListCodeHost(c *gin.Context) {
 		ctx.Err = e.ErrInvalidParam
 		return
 	}
-	ctx.Resp, ctx.Err = service.List(encryptedKey, c.Query("address"), c.Query("owner"), c.Query("source"), ctx.Logger)
+	args := &mongodb.ListArgs{
+		Address: c.Query("address"),
+		Owner:   c.Query("owner"),
+		Source:  c.Query("source"),
+	}
+	args.IncludeDeleted, _ = strconv.ParseBool(c.Query("include_deleted"))
+	if c.Query("enabled") != "" {
+		enabled, err := strconv.ParseBool(c.Query("enabled"))
+		if err != nil {
+			ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid enabled: %s", c.Query("enabled")))
+			return
+		}
+		args.Enabled = &enabled
+	}
+
+	// page and per_page are paged by the database, the other lists are paginated by cursor in memory.
+	if c.Query("page") == "" && c.Query("per_page") == "" {
+		codeHosts, _, err := service.List(encryptedKey, args, ctx.Logger)
+		if err != nil {
+			ctx.Err = err
+			return
+		}
+		ctx.Resp, _, ctx.Err = internalhandler.Paginate(c, codeHosts, codeHostSchema)
+		return
+	}
+
+	var err error
+	args.Page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || args.Page < 1 {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid page: %s", c.Query("page")))
+		return
+	}
+	args.PerPage, err = strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if err != nil || args.PerPage < 1 || args.PerPage > pagination.MaxLimit {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid per_page: %s", c.Query("per_page")))
+		return
+	}
+	args.Search = strings.TrimSpace(c.Query("search"))
+	switch sort := c.Query("sort"); sort {
+	case "":
+	case "updated_at", "-updated_at":
+		args.SortByUpdatedAt = true
+		args.SortDesc = strings.HasPrefix(sort, "-")
+	default:
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("codehosts can not be sorted by %s", sort))
+		return
+	}
+
+	codeHosts, total, err := service.List(encryptedKey, args, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	c.Writer.Header().Set("X-Total", strconv.FormatInt(total, 10))
+	ctx.Resp = codeHosts
 }
 
 func ListCodeHostInternal(c *gin.Context) {
@@ -57,6 +151,12 @@ func ListCodeHostInternal(c *gin.Context) {
 	ctx.Resp, ctx.Err = service.ListInternal(c.Query("address"), c.Query("owner"), c.Query("source"), ctx.Logger)
 }
 
+func ResolveTokenForRepo(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	ctx.Resp, ctx.Err = service.ResolveTokenForRepo(c.Query("address"), c.Query("owner"), c.Query("source"), ctx.Logger)
+}
+
 func DeleteCodeHost(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -66,7 +166,68 @@ func DeleteCodeHost(c *gin.Context) {
 		ctx.Err = err
 		return
 	}
-	ctx.Err = service.DeleteCodeHost(id, ctx.Logger)
+	ctx.Err = service.DeleteCodeHost(id, ctx.UserName, ctx.Logger)
+}
+
+func RestoreCodeHost(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.RestoreCodeHost(id, ctx.UserName, ctx.Logger)
+}
+
+type setCodeHostEnabledArgs struct {
+	Enabled bool `json:"enabled"`
+	// Reason is shown to the users of the disabled codehost, e.g. the provider is being upgraded.
+	Reason string `json:"reason"`
+}
+
+func SetCodeHostEnabled(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args := new(setCodeHostEnabledArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.SetCodeHostEnabled(id, args.Enabled, strings.TrimSpace(args.Reason), ctx.UserName, ctx.Logger)
+}
+
+func ListCodeHostAudits(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid page: %s", c.Query("page")))
+		return
+	}
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if err != nil || perPage < 1 || perPage > pagination.MaxLimit {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid per_page: %s", c.Query("per_page")))
+		return
+	}
+
+	audits, total, err := service.ListCodeHostAudits(id, page, perPage, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	c.Writer.Header().Set("X-Total", strconv.FormatInt(total, 10))
+	ctx.Resp = audits
 }
 
 func GetCodeHost(c *gin.Context) {
@@ -88,7 +249,17 @@ func GetCodeHost(c *gin.Context) {
 		}
 	}
 
-	ctx.Resp, ctx.Err = service.GetCodeHost(id, ignoreDelete, ctx.Logger)
+	// consumers which need the latest codehost, e.g. right after updating it, bypass the in-memory cache.
+	bypassCache := false
+	if len(c.Query("bypassCache")) > 0 {
+		bypassCache, err = strconv.ParseBool(c.Query("bypassCache"))
+		if err != nil {
+			ctx.Err = fmt.Errorf("failed to parse param bypassCache, err: %s", err)
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = service.GetCodeHost(id, ignoreDelete, bypassCache, ctx.Logger)
 }
 
 func AuthCodeHost(c *gin.Context) {
@@ -101,7 +272,7 @@ func AuthCodeHost(c *gin.Context) {
 		ctx.Err = err
 		return
 	}
-	url, err := service.AuthCodeHost(c.Query("redirect_url"), idInt, ctx.Logger)
+	url, err := service.AuthCodeHost(configbase.ExternalBaseURLFromRequest(c.Request), c.Query("redirect_url"), idInt, ctx.UserName, ctx.Logger)
 	if err != nil {
 		ctx.Err = err
 		ctx.Logger.Errorf("auth err,id:%d,err: %s", idInt, err)
@@ -110,6 +281,43 @@ func AuthCodeHost(c *gin.Context) {
 	c.Redirect(http.StatusFound, url)
 }
 
+func StartDeviceAuth(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.StartDeviceAuth(id, ctx.UserName, ctx.Logger)
+}
+
+func GetDeviceAuth(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.GetDeviceAuth(id, ctx.Logger)
+}
+
+func GetCodeHostHealth(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	refresh, _ := strconv.ParseBool(c.Query("refresh"))
+	ctx.Resp, ctx.Err = service.GetCodeHostHealth(id, refresh, ctx.Logger)
+}
+
 func Callback(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -139,5 +347,119 @@ func UpdateCodeHost(c *gin.Context) {
 		return
 	}
 	req.ID = id
-	ctx.Resp, ctx.Err = service.UpdateCodeHost(req, ctx.Logger)
+	if !validateBeforeSave(c, ctx, req) {
+		return
+	}
+	ctx.Resp, ctx.Err = service.UpdateCodeHost(req, ctx.UserName, ctx.Logger)
+}
+
+func RegisterCodeHostWebhook(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args := new(service.RegisterWebhookArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统设置-代码源Webhook", fmt.Sprintf("%s/%s", args.Owner, args.Repo), "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.RegisterCodeHostWebhook(id, args, ctx.UserName, ctx.Logger)
+}
+
+func ListCodeHostWebhooks(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	ctx.Resp, ctx.Err = service.ListCodeHostWebhooks(id, ctx.Logger)
+}
+
+func DeleteCodeHostWebhook(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统设置-代码源Webhook", c.Param("hookID"), "", ctx.Logger)
+	ctx.Err = service.DeleteCodeHostWebhook(id, c.Param("hookID"), ctx.Logger)
+}
+
+func ReconcileCodeHostWebhooks(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统设置-代码源Webhook", c.Param("id"), "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.ReconcileCodeHostWebhooks(id, ctx.Logger)
+}
+
+// passphraseHeader carries the passphrase encrypting the secrets of the exported codehosts, it is not a query
+// so that it is not recorded in the access logs.
+const passphraseHeader = "X-Passphrase"
+
+func ExportCodeHosts(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "yaml" {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("unknown format: %s", format))
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+	bundle, err := service.ExportCodeHosts(c.GetHeader(passphraseHeader), format, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "导出", "系统设置-代码源", "", "", ctx.Logger)
+	contentType := "application/json"
+	if format == "yaml" {
+		contentType = "application/x-yaml"
+	}
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="codehosts.%s"`, format))
+	c.Data(http.StatusOK, contentType, bundle)
+}
+
+func ImportCodeHosts(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	data, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	strategy := service.ImportStrategy(c.DefaultQuery("strategy", string(service.ImportStrategySkip)))
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "导入", "系统设置-代码源", string(strategy), "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.ImportCodeHosts(data, c.GetHeader(passphraseHeader), strategy, ctx.UserName, ctx.Logger)
+}
+
+func BulkSaveCodeHosts(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	var codeHosts []*models.CodeHost
+	if err := c.ShouldBindJSON(&codeHosts); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "批量新增/更新", "系统设置-代码源", fmt.Sprintf("%d", len(codeHosts)), "", ctx.Logger)
+	ctx.Resp, ctx.Err = service.BulkSaveCodeHosts(codeHosts, ctx.UserName, ctx.Logger)
 }
@@ -28,10 +28,29 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		codehost.GET("/callback", Callback)
 		codehost.GET("", ListCodeHost)
 		codehost.GET("/internal", ListCodeHostInternal)
+		codehost.GET("/internal/token", ResolveTokenForRepo)
+		codehost.GET("/export", ExportCodeHosts)
+		codehost.POST("/import", ImportCodeHosts)
+		codehost.POST("/bulk", BulkSaveCodeHosts)
 		codehost.DELETE("/:id", DeleteCodeHost)
+		codehost.POST("/:id/restore", RestoreCodeHost)
+		codehost.PUT("/:id/enabled", SetCodeHostEnabled)
+		codehost.GET("/:id/audit", ListCodeHostAudits)
 		codehost.POST("", CreateCodeHost)
+		codehost.POST("/validate", ValidateCodeHost)
 		codehost.PATCH("/:id", UpdateCodeHost)
 		codehost.GET("/:id", GetCodeHost)
 		codehost.GET("/:id/auth", AuthCodeHost)
+		codehost.POST("/:id/device-auth", StartDeviceAuth)
+		codehost.GET("/:id/device-auth", GetDeviceAuth)
+		codehost.GET("/:id/health", GetCodeHostHealth)
+		codehost.GET("/:id/namespaces", ListCodeHostNamespaces)
+		codehost.GET("/:id/repos", ListCodeHostRepos)
+		codehost.GET("/:id/branches", ListCodeHostBranches)
+		codehost.GET("/:id/tags", ListCodeHostTags)
+		codehost.GET("/:id/webhooks", ListCodeHostWebhooks)
+		codehost.POST("/:id/webhooks", RegisterCodeHostWebhook)
+		codehost.POST("/:id/webhooks/reconcile", ReconcileCodeHostWebhooks)
+		codehost.DELETE("/:id/webhooks/:hookID", DeleteCodeHostWebhook)
 	}
 }
@@ -0,0 +1,39 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"golang.org/x/oauth2"
+)
+
+const (
+	azureADAddress = "https://login.microsoftonline.com"
+	// azureDevOpsResource is the application id of azure devops in microsoft entra id.
+	azureDevOpsResource = "499b84ac-1321-427f-aa17-267ca6975798"
+)
+
+// NewAzureDevOps returns the provider of azure devops, the users are authorized by microsoft entra id
+// since the azure devops oauth applications are deprecated. The entra application should be available
+// to the organizations, its api permissions of azure devops are requested by the default scope, and
+// offline_access is requested for the refresh token since the access tokens expire in an hour.
+func NewAzureDevOps(callbackURL, clientID, clientSecret string, opts Options) Provider {
+	return New(callbackURL, clientID, clientSecret, []string{azureDevOpsResource + "/.default", "offline_access"}, oauth2.Endpoint{
+		AuthURL:   azureADAddress + "/organizations/oauth2/v2.0/authorize",
+		TokenURL:  azureADAddress + "/organizations/oauth2/v2.0/token",
+		AuthStyle: oauth2.AuthStyleInParams,
+	}, opts)
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"golang.org/x/oauth2"
+)
+
+const bitbucketAddress = "https://bitbucket.org"
+
+// NewBitbucket returns the provider of bitbucket cloud. Bitbucket cloud grants the scopes
+// configured in the oauth consumer, so no scope is requested by default.
+func NewBitbucket(callbackURL, clientID, clientSecret string, opts Options) Provider {
+	return New(callbackURL, clientID, clientSecret, nil, oauth2.Endpoint{
+		AuthURL:  bitbucketAddress + "/site/oauth2/authorize",
+		TokenURL: bitbucketAddress + "/site/oauth2/access_token",
+		// bitbucket cloud only accepts the client credentials in the basic auth header.
+		AuthStyle: oauth2.AuthStyleInHeader,
+	}, opts)
+}
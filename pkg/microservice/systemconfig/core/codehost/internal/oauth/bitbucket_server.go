@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+)
+
+// bitbucketServer is the provider of bitbucket data center/server, which is available since 7.20.
+// The authorization code is protected by PKCE.
+type bitbucketServer struct {
+	*OAuth
+}
+
+func NewBitbucketServer(callbackURL, clientID, clientSecret, address string, opts Options) Provider {
+	address = strings.TrimSuffix(address, "/")
+	return &bitbucketServer{
+		OAuth: New(callbackURL, clientID, clientSecret, []string{"PUBLIC_REPOS", "REPO_ADMIN"}, oauth2.Endpoint{
+			AuthURL:  address + "/rest/oauth2/latest/authorize",
+			TokenURL: address + "/rest/oauth2/latest/token",
+		}, opts),
+	}
+}
+
+func (b *bitbucketServer) LoginURL(state string) string {
+	challenge := sha256.Sum256([]byte(b.codeVerifier(state)))
+	params := append([]oauth2.AuthCodeOption{}, b.authParams...)
+	params = append(params,
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	return b.oauth2Config.AuthCodeURL(state, params...)
+}
+
+func (b *bitbucketServer) HandleCallback(r *http.Request, c *models.CodeHost) (*oauth2.Token, error) {
+	return b.exchange(r, c, oauth2.SetAuthURLParam("code_verifier", b.codeVerifier(r.URL.Query().Get("state"))))
+}
+
+// codeVerifier derives the PKCE verifier from the state, so that it does not need to be stored
+// between the login and the callback.
+func (b *bitbucketServer) codeVerifier(state string) string {
+	mac := hmac.New(sha256.New, []byte(b.oauth2Config.ClientSecret))
+	mac.Write([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// ErrAuthorizationPending is returned while the user has not completed the authorization.
+	ErrAuthorizationPending = errors.New("authorization_pending")
+	// ErrSlowDown is returned if the token endpoint is polled too often, the interval should be increased.
+	ErrSlowDown = errors.New("slow_down")
+)
+
+// DeviceProvider authorizes zadig by the device authorization grant (RFC 8628), it is used when the browser
+// can not be redirected back to zadig, e.g. zadig is behind a firewall.
+type DeviceProvider interface {
+	DeviceAuth(c *models.CodeHost) (*DeviceAuthorization, error)
+	PollDeviceToken(c *models.CodeHost, deviceCode string) (*oauth2.Token, error)
+}
+
+// DeviceAuthorization is the response of the device authorization endpoint, the user enters UserCode at
+// VerificationURI to authorize zadig.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+}
+
+type deviceTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// DeviceOAuth is the provider of the device authorization grant of github and gitlab.
+type DeviceOAuth struct {
+	clientID      string
+	clientSecret  string
+	scopes        []string
+	deviceAuthURL string
+	tokenURL      string
+}
+
+// NewDevice returns the device provider, scopes are the default ones of the provider which are replaced by
+// the scopes of the options.
+func NewDevice(clientID, clientSecret string, scopes []string, deviceAuthURL, tokenURL string, opts Options) *DeviceOAuth {
+	if len(opts.Scopes) > 0 {
+		scopes = opts.Scopes
+	}
+	return &DeviceOAuth{
+		clientID:      clientID,
+		clientSecret:  clientSecret,
+		scopes:        scopes,
+		deviceAuthURL: deviceAuthURL,
+		tokenURL:      tokenURL,
+	}
+}
+
+func (o *DeviceOAuth) DeviceAuth(c *models.CodeHost) (*DeviceAuthorization, error) {
+	form := url.Values{
+		"client_id": {o.clientID},
+		"scope":     {strings.Join(o.scopes, " ")},
+	}
+	resp := &struct {
+		DeviceAuthorization
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+	if err := postForm(c, o.deviceAuthURL, form, resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, &OAuth2Error{resp.Error, resp.ErrorDescription}
+	}
+	if resp.DeviceCode == "" || resp.UserCode == "" {
+		return nil, fmt.Errorf("no device code is returned by %s", o.deviceAuthURL)
+	}
+	return &resp.DeviceAuthorization, nil
+}
+
+// PollDeviceToken exchanges the device code for the token, ErrAuthorizationPending and ErrSlowDown are
+// returned if the token should be polled again later, the other errors are permanent.
+func (o *DeviceOAuth) PollDeviceToken(c *models.CodeHost, deviceCode string) (*oauth2.Token, error) {
+	form := url.Values{
+		"client_id":   {o.clientID},
+		"device_code": {deviceCode},
+		"grant_type":  {deviceCodeGrantType},
+	}
+	// gitlab requires the secret of the confidential applications.
+	if o.clientSecret != "" {
+		form.Set("client_secret", o.clientSecret)
+	}
+	resp := &deviceTokenResponse{}
+	if err := postForm(c, o.tokenURL, form, resp); err != nil {
+		return nil, err
+	}
+	switch resp.Error {
+	case "":
+	case ErrAuthorizationPending.Error():
+		return nil, ErrAuthorizationPending
+	case ErrSlowDown.Error():
+		return nil, ErrSlowDown
+	default:
+		return nil, &OAuth2Error{resp.Error, resp.ErrorDescription}
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("no access token is returned by %s", o.tokenURL)
+	}
+
+	token := &oauth2.Token{AccessToken: resp.AccessToken, TokenType: resp.TokenType, RefreshToken: resp.RefreshToken}
+	if resp.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// postForm posts the form and decodes the json response, the errors of the device flow are returned with
+// status 400 so the body is decoded regardless of the status.
+func postForm(c *models.CodeHost, endpoint string, form url.Values, result interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// github responds with a form unless json is accepted.
+	req.Header.Set("Accept", "application/json")
+
+	res, err := newHTTPClient(c).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("unexpected response of %s, status: %d, body: %s", endpoint, res.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+)
+
+func TestDeviceAuthorizationFlow(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse the form: %s", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login/device/code":
+			if r.Form.Get("client_id") != "id" || r.Form.Get("scope") != "repo user" {
+				t.Errorf("Unexpected device authorization request %v", r.Form)
+			}
+			fmt.Fprint(w, `{"device_code":"dc","user_code":"ABCD-1234","verification_uri":"https://github.com/login/device","expires_in":900,"interval":5}`)
+		case "/login/oauth/access_token":
+			if r.Form.Get("grant_type") != deviceCodeGrantType || r.Form.Get("device_code") != "dc" {
+				t.Errorf("Unexpected token request %v", r.Form)
+			}
+			polls++
+			switch polls {
+			case 1:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"authorization_pending"}`)
+			case 2:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"slow_down"}`)
+			case 3:
+				fmt.Fprint(w, `{"access_token":"token","token_type":"bearer","refresh_token":"refresh","expires_in":3600}`)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"expired_token","error_description":"the device code has expired"}`)
+			}
+		}
+	}))
+	defer server.Close()
+
+	c := &models.CodeHost{}
+	o := NewDevice("id", "", []string{"repo", "user"}, server.URL+"/login/device/code", server.URL+"/login/oauth/access_token", Options{})
+	auth, err := o.DeviceAuth(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if auth.UserCode != "ABCD-1234" || auth.Interval != 5 {
+		t.Errorf("Unexpected device authorization %+v", auth)
+	}
+
+	if _, err := o.PollDeviceToken(c, auth.DeviceCode); err != ErrAuthorizationPending {
+		t.Errorf("Expected ErrAuthorizationPending, got %v", err)
+	}
+	if _, err := o.PollDeviceToken(c, auth.DeviceCode); err != ErrSlowDown {
+		t.Errorf("Expected ErrSlowDown, got %v", err)
+	}
+	token, err := o.PollDeviceToken(c, auth.DeviceCode)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if token.AccessToken != "token" || token.RefreshToken != "refresh" || token.Expiry.IsZero() {
+		t.Errorf("Unexpected token %+v", token)
+	}
+	if _, err := o.PollDeviceToken(c, auth.DeviceCode); err == nil || err.Error() != "expired_token: the device code has expired" {
+		t.Errorf("Expected the expired token error, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/tool/gerrit"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// diagnoseGerrit logs in the ssh daemon if the codehost is authorized by the ssh key, the http password is checked
+// against /a/accounts/self if it is set, it is required by the api unless the ssh key is used.
+func diagnoseGerrit(c *models.CodeHost) *Diagnosis {
+	if c.Username == "" {
+		return &Diagnosis{Message: "the username of gerrit is empty", Suggestion: credentialSuggestion(c)}
+	}
+
+	if c.AuthType == types.SSHAuthType {
+		if c.SSHKey == "" {
+			return &Diagnosis{Message: "the ssh key of gerrit is empty", Suggestion: credentialSuggestion(c)}
+		}
+		version, err := gerrit.VerifySSHKey(c.Address, c.SSHPort, c.Username, c.SSHKey)
+		if err != nil {
+			return &Diagnosis{
+				Message:    fmt.Sprintf("failed to login gerrit over ssh: %s", err),
+				Suggestion: "check the ssh port of gerrit and whether the public key is added to the ssh keys of the account",
+			}
+		}
+		if c.Password == "" {
+			return &Diagnosis{Valid: true, Reachable: true, Authenticated: true, User: c.Username, Message: fmt.Sprintf("gerrit %s", version)}
+		}
+	} else if c.Password == "" {
+		return &Diagnosis{Message: "the http password of gerrit is empty", Suggestion: credentialSuggestion(c)}
+	}
+
+	client := newHTTPClient(c)
+	client.Timeout = validateTimeout
+	cli, err := gerrit.NewBasicAuthClient(c.Address, c.Username, c.Password, client)
+	if err != nil {
+		return &Diagnosis{Message: fmt.Sprintf("invalid address %s: %s", c.Address, err), Suggestion: "check the address of the codehost"}
+	}
+	account, status, err := cli.GetAccountSelf()
+	diagnosis := &Diagnosis{Reachable: status != 0, StatusCode: status}
+	switch {
+	case status == 0:
+		diagnosis.Message = fmt.Sprintf("failed to connect to %s: %s", c.Address, err)
+		diagnosis.Suggestion = "check the address of the codehost and whether a proxy is required"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		diagnosis.Message = fmt.Sprintf("the http password of %s is rejected by gerrit", c.Username)
+		diagnosis.Suggestion = credentialSuggestion(c)
+	case status == http.StatusNotFound:
+		diagnosis.Message = "the api /a/accounts/self is not found"
+		diagnosis.Suggestion = "check the address of gerrit, it should be the url of the gerrit web ui"
+	case err != nil:
+		diagnosis.Message = fmt.Sprintf("unexpected response from gerrit: %s", err)
+	default:
+		diagnosis.Valid, diagnosis.Authenticated = true, true
+		diagnosis.User = account.Username
+	}
+	return diagnosis
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// NewGitea returns the provider of gitea. Gitea grants the access to all the resources of the user to
+// the oauth applications, so no scope is requested by default. The access tokens expire in an hour by
+// default and are refreshed by the refresh token.
+func NewGitea(callbackURL, clientID, clientSecret, address string, opts Options) Provider {
+	address = strings.TrimSuffix(address, "/")
+	return New(callbackURL, clientID, clientSecret, nil, oauth2.Endpoint{
+		AuthURL:  address + "/login/oauth/authorize",
+		TokenURL: address + "/login/oauth/access_token",
+	}, opts)
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+const giteeAddress = "https://gitee.com"
+
+// NewGitee returns the provider of gitee, the address is the one of the private deployment of gitee,
+// gitee.com is used if it is empty. The access tokens of gitee expire in a day and are refreshed by
+// the refresh token.
+func NewGitee(callbackURL, clientID, clientSecret, address string, opts Options) Provider {
+	address = strings.TrimSuffix(address, "/")
+	if address == "" {
+		address = giteeAddress
+	}
+	return New(callbackURL, clientID, clientSecret, []string{"projects", "pull_requests", "hook", "groups"}, oauth2.Endpoint{
+		AuthURL:  address + "/oauth/authorize",
+		TokenURL: address + "/oauth/token",
+		// gitee only accepts the client credentials in the form.
+		AuthStyle: oauth2.AuthStyleInParams,
+	}, opts)
+}
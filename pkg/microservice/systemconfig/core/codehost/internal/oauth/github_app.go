@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/oauth2"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+)
+
+const (
+	gitHubAPIURL = "https://api.github.com"
+	// gitHubAppJWTLifetime is less than the 10 minutes allowed by github to tolerate clock drift.
+	gitHubAppJWTLifetime = 9 * time.Minute
+)
+
+// GitHubApp mints the short-lived installation tokens of a github app installation, the app authenticates
+// itself by a JWT signed with its private key.
+type GitHubApp struct {
+	appID          int64
+	installationID int64
+	privateKey     []byte
+	apiURL         string
+}
+
+func NewGitHubApp(c *models.CodeHost) (*GitHubApp, error) {
+	if c.GitHubAppID == 0 || c.GitHubInstallationID == 0 || c.GitHubAppPrivateKey == "" {
+		return nil, fmt.Errorf("app id, installation id and private key of the github app are required")
+	}
+	privateKey := []byte(c.GitHubAppPrivateKey)
+	// the private key can be either the downloaded pem file or its base64 encoding.
+	if !strings.HasPrefix(strings.TrimSpace(c.GitHubAppPrivateKey), "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(c.GitHubAppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key of the github app: %s", err)
+		}
+		privateKey = decoded
+	}
+
+	apiURL := gitHubAPIURL
+	// the api of github enterprise server is served under /api/v3.
+	if address := strings.TrimSuffix(c.Address, "/"); address != "" && !strings.Contains(address, "github.com") {
+		apiURL = address + "/api/v3"
+	}
+	return &GitHubApp{appID: c.GitHubAppID, installationID: c.GitHubInstallationID, privateKey: privateKey, apiURL: apiURL}, nil
+}
+
+// InstallationToken exchanges a JWT of the app for a new installation token, which expires in an hour.
+func (a *GitHubApp) InstallationToken(c *models.CodeHost) (*oauth2.Token, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(a.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of the github app: %s", err)
+	}
+	now := time.Now()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(gitHubAppJWTLifetime).Unix(),
+		Issuer:    strconv.FormatInt(a.appID, 10),
+	}).SignedString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/app/installations/%d/access_tokens", a.apiURL, a.installationID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+signed)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := newHTTPClient(c).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to create the installation token, status: %d, body: %s", resp.StatusCode, body)
+	}
+
+	token := &struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: token.Token, Expiry: token.ExpiresAt}, nil
+}
@@ -18,19 +18,60 @@ package oauth
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
-	"net/url"
+	"sort"
+	"time"
 
 	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/util"
 )
 
+// Provider authorizes zadig to access the code host by the oauth2 authorization code flow.
+type Provider interface {
+	LoginURL(state string) string
+	HandleCallback(r *http.Request, c *models.CodeHost) (*oauth2.Token, error)
+	Refresh(c *models.CodeHost) (*oauth2.Token, error)
+}
+
 type OAuth struct {
 	oauth2Config *oauth2.Config
+	authParams   []oauth2.AuthCodeOption
+}
+
+// reservedAuthParams are set by the authorization flow itself, they can not be overridden by the extra
+// auth params of the codehost.
+var reservedAuthParams = sets.NewString("client_id", "redirect_uri", "response_type", "scope", "state", "code_challenge", "code_challenge_method")
+
+// Options customizes the authorization request of a codehost, the default scopes of the provider are
+// requested if Scopes is empty. ExtraAuthParams are added to the login url, e.g. prompt=consent.
+type Options struct {
+	Scopes          []string
+	ExtraAuthParams map[string]string
+}
+
+func NewOptions(c *models.CodeHost) Options {
+	return Options{Scopes: c.Scopes, ExtraAuthParams: c.ExtraAuthParams}
+}
+
+// ValidateExtraAuthParams rejects the params which would break the authorization flow.
+func ValidateExtraAuthParams(params map[string]string) error {
+	for key := range params {
+		if key == "" {
+			return fmt.Errorf("the name of the extra auth param can not be empty")
+		}
+		if reservedAuthParams.Has(key) {
+			return fmt.Errorf("the extra auth param %s is reserved", key)
+		}
+	}
+	return nil
 }
 
 type OAuth2Error struct {
@@ -45,7 +86,21 @@ func (e *OAuth2Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Err, e.Description)
 }
 
-func New(callbackURL, clientID, clientSecret string, scopes []string, endpoint oauth2.Endpoint) *OAuth {
+// New returns the oauth2 provider, scopes are the default ones of the provider which are replaced by the
+// scopes of the options.
+func New(callbackURL, clientID, clientSecret string, scopes []string, endpoint oauth2.Endpoint, opts Options) *OAuth {
+	if len(opts.Scopes) > 0 {
+		scopes = opts.Scopes
+	}
+	keys := make([]string, 0, len(opts.ExtraAuthParams))
+	for key := range opts.ExtraAuthParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var authParams []oauth2.AuthCodeOption
+	for _, key := range keys {
+		authParams = append(authParams, oauth2.SetAuthURLParam(key, opts.ExtraAuthParams[key]))
+	}
 	return &OAuth{
 		oauth2Config: &oauth2.Config{
 			ClientID:     clientID,
@@ -54,37 +109,92 @@ func New(callbackURL, clientID, clientSecret string, scopes []string, endpoint o
 			RedirectURL:  callbackURL,
 			Scopes:       scopes,
 		},
+		authParams: authParams,
 	}
 }
 
 func (o *OAuth) LoginURL(state string) string {
-	return o.oauth2Config.AuthCodeURL(state)
+	return o.oauth2Config.AuthCodeURL(state, o.authParams...)
 }
 
 func (o *OAuth) HandleCallback(r *http.Request, c *models.CodeHost) (*oauth2.Token, error) {
+	return o.exchange(r, c)
+}
+
+func (o *OAuth) exchange(r *http.Request, c *models.CodeHost, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
 	q := r.URL.Query()
 	if errType := q.Get("error"); errType != "" {
 		return nil, &OAuth2Error{errType, q.Get("error_description")}
 	}
 
-	httpClient := http.DefaultClient
+	return o.oauth2Config.Exchange(newContext(c), q.Get("code"), opts...)
+}
+
+// Refresh gets a new token by the refresh token of the codehost.
+func (o *OAuth) Refresh(c *models.CodeHost) (*oauth2.Token, error) {
+	// the token is treated as expired so that it is always refreshed.
+	token := &oauth2.Token{RefreshToken: c.RefreshToken, Expiry: time.Now().Add(-time.Second)}
+	return o.oauth2Config.TokenSource(newContext(c), token).Token()
+}
+
+func newContext(c *models.CodeHost) context.Context {
+	return context.WithValue(context.Background(), oauth2.HTTPClient, newHTTPClient(c))
+}
+
+// NewHTTPClient returns the client the api of the codehost is called with, the proxy and the CA bundle of
+// the codehost are applied.
+func NewHTTPClient(c *models.CodeHost) *http.Client {
+	return newHTTPClient(c)
+}
+
+func newHTTPClient(c *models.CodeHost) *http.Client {
+	httpClient := &http.Client{}
+	transport := &http.Transport{TLSClientConfig: newTLSConfig(c)}
+	if transport.TLSClientConfig != nil {
+		httpClient.Transport = transport
+	}
+	if !c.EnableProxy {
+		return httpClient
+	}
+	// the proxy of the codehost takes precedence over the system proxy
+	if c.ProxyURL != "" {
+		transport.Proxy = util.ProxyFunc(c.ProxyURL, c.NoProxy)
+		httpClient.Transport = transport
+		return httpClient
+	}
+
 	// if set http proxy
 	proxies, err := commonrepo.NewProxyColl().List(&commonrepo.ProxyArgs{})
-	if err == nil && len(proxies) != 0 && proxies[0].EnableRepoProxy && c.EnableProxy {
+	if err == nil && len(proxies) != 0 && proxies[0].EnableRepoProxy {
 		log.Info("use proxy")
 		port := proxies[0].Port
 		ip := proxies[0].Address
 		proxyRawUrl := fmt.Sprintf("http://%s:%d", ip, port)
-		proxyUrl, err2 := url.Parse(proxyRawUrl)
-		if err2 == nil {
-			httpClient.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyUrl),
-			}
-		}
-
+		transport.Proxy = util.ProxyFunc(proxyRawUrl, c.NoProxy)
+		httpClient.Transport = transport
 	}
 
-	ctx := context.Background()
-	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
-	return o.oauth2Config.Exchange(ctx, q.Get("code"))
+	return httpClient
+}
+
+// newTLSConfig returns nil if the default tls config is used, the CA bundle of the codehost is trusted
+// together with the system ones.
+func newTLSConfig(c *models.CodeHost) *tls.Config {
+	if c.CACert == "" && !c.InsecureSkipVerify {
+		return nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CACert == "" {
+		return tlsConfig
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		log.Warnf("failed to load the system cert pool: %s", err)
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(c.CACert)) {
+		log.Warnf("no valid certificate is found in the ca cert of codehost %d", c.ID)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig
 }
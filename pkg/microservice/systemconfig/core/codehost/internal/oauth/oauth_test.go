@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"net/url"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestLoginURLScopesAndExtraAuthParams(t *testing.T) {
+	endpoint := oauth2.Endpoint{AuthURL: "https://example.com/oauth/authorize"}
+
+	o := New("https://zadig.example.com/callback", "id", "secret", []string{"api", "read_user"}, endpoint, Options{})
+	q := loginQuery(t, o.LoginURL("state"))
+	if got := q.Get("scope"); got != "api read_user" {
+		t.Errorf("Expected the default scopes, got %q", got)
+	}
+
+	o = New("https://zadig.example.com/callback", "id", "secret", []string{"api", "read_user"}, endpoint, Options{
+		Scopes:          []string{"read_api"},
+		ExtraAuthParams: map[string]string{"prompt": "consent"},
+	})
+	q = loginQuery(t, o.LoginURL("state"))
+	if got := q.Get("scope"); got != "read_api" {
+		t.Errorf("Expected the configured scopes, got %q", got)
+	}
+	if got := q.Get("prompt"); got != "consent" {
+		t.Errorf("Expected the extra auth param prompt=consent, got %q", got)
+	}
+}
+
+func TestValidateExtraAuthParams(t *testing.T) {
+	if err := ValidateExtraAuthParams(map[string]string{"prompt": "consent", "login_hint": "dev"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	for _, key := range []string{"", "state", "redirect_uri", "code_challenge"} {
+		if err := ValidateExtraAuthParams(map[string]string{key: "x"}); err == nil {
+			t.Errorf("Expected the extra auth param %q to be rejected", key)
+		}
+	}
+}
+
+func loginQuery(t *testing.T, loginURL string) url.Values {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		t.Fatalf("Failed to parse the login url %s: %s", loginURL, err)
+	}
+	return u.Query()
+}
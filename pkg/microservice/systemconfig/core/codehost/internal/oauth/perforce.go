@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/tool/perforce"
+)
+
+// diagnosePerforce runs `p4 login -s` against p4d, the address is P4PORT such as ssl:p4.example.com:1666 and the
+// ticket is kept in the private access token, the password is used if there is no ticket.
+func diagnosePerforce(c *models.CodeHost) *Diagnosis {
+	if c.Username == "" {
+		return &Diagnosis{Message: "the user of perforce is empty", Suggestion: credentialSuggestion(c)}
+	}
+	if c.PrivateAccessToken == "" && c.Password == "" {
+		return &Diagnosis{Message: "both the ticket and the password of perforce are empty", Suggestion: credentialSuggestion(c)}
+	}
+	if _, err := perforce.ParseAddress(c.Address); err != nil {
+		return &Diagnosis{Message: err.Error(), Suggestion: "check the address of perforce, it should be P4PORT, e.g. ssl:p4.example.com:1666"}
+	}
+
+	status, err := perforce.VerifyLogin(c.Address, c.Username, c.Password, c.PrivateAccessToken, validateTimeout)
+	if err != nil {
+		var connectErr *perforce.ConnectError
+		if errors.As(err, &connectErr) {
+			return &Diagnosis{
+				Message:    fmt.Sprintf("failed to connect to %s: %s", c.Address, err),
+				Suggestion: "check the address of perforce and whether ssl: is required by p4d",
+			}
+		}
+		return &Diagnosis{
+			Reachable:  true,
+			Message:    fmt.Sprintf("the credentials of %s are rejected by perforce: %s", c.Username, err),
+			Suggestion: credentialSuggestion(c),
+		}
+	}
+	return &Diagnosis{Valid: true, Reachable: true, Authenticated: true, User: c.Username, Message: status}
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/azuredevops"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const validateTimeout = 10 * time.Second
+
+// Diagnosis is the result of checking the address and the credentials of a codehost against the
+// provider api.
+type Diagnosis struct {
+	Valid bool `json:"valid"`
+	// Reachable is false if the address can not be connected, e.g. it is misspelled or a proxy is required.
+	Reachable bool `json:"reachable"`
+	// Authenticated is false if the provider rejects the credentials, it is not checked for the oauth
+	// applications which are authorized after the codehost is saved.
+	Authenticated bool   `json:"authenticated"`
+	User          string `json:"user,omitempty"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	Message       string `json:"message,omitempty"`
+	Suggestion    string `json:"suggestion,omitempty"`
+}
+
+type userRequest struct {
+	url       string
+	header    http.Header
+	userField string
+}
+
+// Diagnose calls the user api of the provider with the credentials of the codehost, e.g. /user of
+// github and gitlab, gerrit and perforce are checked by diagnoseGerrit and diagnosePerforce.
+func Diagnose(c *models.CodeHost) *Diagnosis {
+	if c.Type == setting.SourceFromGithub && c.AuthType == types.GitHubAppAuthType {
+		return diagnoseGitHubApp(c)
+	}
+	if c.Type == setting.SourceFromGerrit {
+		return diagnoseGerrit(c)
+	}
+	if c.Type == setting.SourceFromPerforce {
+		return diagnosePerforce(c)
+	}
+
+	req := newUserRequest(c)
+	if req == nil {
+		return diagnoseAddress(c)
+	}
+	httpReq, err := http.NewRequest(http.MethodGet, req.url, nil)
+	if err != nil {
+		return &Diagnosis{Message: fmt.Sprintf("invalid address %s: %s", c.Address, err), Suggestion: "check the address of the codehost"}
+	}
+	for key, values := range req.header {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+
+	client := newHTTPClient(c)
+	client.Timeout = validateTimeout
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return &Diagnosis{
+			Message:    fmt.Sprintf("failed to connect to %s: %s", c.Address, err),
+			Suggestion: "check the address of the codehost and whether a proxy is required",
+		}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	diagnosis := &Diagnosis{Reachable: true, StatusCode: resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		diagnosis.Message = fmt.Sprintf("the credentials are rejected by %s", c.Type)
+		diagnosis.Suggestion = credentialSuggestion(c)
+	case resp.StatusCode == http.StatusNotFound:
+		diagnosis.Message = fmt.Sprintf("the api %s is not found", req.url)
+		diagnosis.Suggestion = "check the address and the type of the codehost"
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		diagnosis.Message = fmt.Sprintf("unexpected response from %s, status: %d, body: %s", req.url, resp.StatusCode, truncate(string(body), 200))
+	default:
+		user := map[string]interface{}{}
+		if err := json.Unmarshal(body, &user); err != nil {
+			diagnosis.Message = fmt.Sprintf("the response of %s is not the user api of %s", req.url, c.Type)
+			diagnosis.Suggestion = "check the address and the type of the codehost"
+			return diagnosis
+		}
+		diagnosis.Valid, diagnosis.Authenticated = true, true
+		diagnosis.User = fmt.Sprint(lookupField(user, req.userField))
+	}
+	return diagnosis
+}
+
+// lookupField returns the field of the user, the fields of the nested objects are separated by dots.
+func lookupField(user map[string]interface{}, field string) interface{} {
+	keys := strings.Split(field, ".")
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := user[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		user = nested
+	}
+	return user[keys[len(keys)-1]]
+}
+
+func newUserRequest(c *models.CodeHost) *userRequest {
+	address := strings.TrimSuffix(c.Address, "/")
+	token := c.AccessToken
+	if c.AuthType == types.PrivateAccessTokenAuthType || token == "" {
+		token = c.PrivateAccessToken
+	}
+
+	switch c.Type {
+	case setting.SourceFromGithub:
+		if token == "" {
+			return nil
+		}
+		apiURL := gitHubAPIURL
+		if address != "" && !strings.Contains(address, "github.com") {
+			apiURL = address + "/api/v3"
+		}
+		return &userRequest{url: apiURL + "/user", header: http.Header{"Authorization": {"token " + token}}, userField: "login"}
+	case setting.SourceFromGitlab:
+		if c.PrivateAccessToken != "" && c.AccessToken == "" {
+			return &userRequest{url: address + "/api/v4/user", header: http.Header{"Private-Token": {c.PrivateAccessToken}}, userField: "username"}
+		}
+		if token == "" {
+			return nil
+		}
+		return &userRequest{url: address + "/api/v4/user", header: http.Header{"Authorization": {"Bearer " + token}}, userField: "username"}
+	case setting.SourceFromGitee:
+		if token == "" {
+			return nil
+		}
+		if address == "" {
+			address = giteeAddress
+		}
+		return &userRequest{url: address + "/api/v5/user?access_token=" + token, userField: "login"}
+	case setting.SourceFromBitbucket:
+		if token == "" {
+			return nil
+		}
+		return &userRequest{url: "https://api.bitbucket.org/2.0/user", header: http.Header{"Authorization": {"Bearer " + token}}, userField: "username"}
+	case setting.SourceFromGitea:
+		if token == "" {
+			return nil
+		}
+		return &userRequest{url: address + "/api/v1/user", header: http.Header{"Authorization": {"token " + token}}, userField: "login"}
+	case setting.SourceFromBitbucketServer:
+		if token == "" {
+			return nil
+		}
+		return &userRequest{url: address + "/plugins/servlet/applinks/whoami", header: http.Header{"Authorization": {"Bearer " + token}}, userField: "name"}
+	case setting.SourceFromAzureDevOps:
+		if token == "" {
+			return nil
+		}
+		// the connection data of the organization tells the authenticated user, the organization is checked as well.
+		req := &userRequest{url: fmt.Sprintf("%s/%s/_apis/connectionData", address, c.AzureOrganization), userField: "authenticatedUser.providerDisplayName"}
+		if azuredevops.IsBearerToken(token) {
+			req.header = http.Header{"Authorization": {"Bearer " + token}}
+		} else {
+			req.header = http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(":"+token))}}
+		}
+		return req
+	}
+	return nil
+}
+
+// diagnoseAddress only checks the address of the codehost if there are no credentials to check yet.
+func diagnoseAddress(c *models.CodeHost) *Diagnosis {
+	if c.Address == "" {
+		return &Diagnosis{Message: "the address of the codehost is empty", Suggestion: "check the address of the codehost"}
+	}
+	client := newHTTPClient(c)
+	client.Timeout = validateTimeout
+	resp, err := client.Get(c.Address)
+	if err != nil {
+		return &Diagnosis{
+			Message:    fmt.Sprintf("failed to connect to %s: %s", c.Address, err),
+			Suggestion: "check the address of the codehost and whether a proxy is required",
+		}
+	}
+	resp.Body.Close()
+	return &Diagnosis{
+		Valid:      true,
+		Reachable:  true,
+		StatusCode: resp.StatusCode,
+		Message:    "the address is reachable, the credentials are checked when the codehost is authorized",
+	}
+}
+
+func diagnoseGitHubApp(c *models.CodeHost) *Diagnosis {
+	app, err := NewGitHubApp(c)
+	if err != nil {
+		return &Diagnosis{Message: err.Error(), Suggestion: credentialSuggestion(c)}
+	}
+	if _, err := app.InstallationToken(c); err != nil {
+		return &Diagnosis{Message: err.Error(), Suggestion: credentialSuggestion(c)}
+	}
+	return &Diagnosis{Valid: true, Reachable: true, Authenticated: true}
+}
+
+func credentialSuggestion(c *models.CodeHost) string {
+	switch {
+	case c.Type == setting.SourceFromGerrit && c.AuthType == types.SSHAuthType:
+		return "check the username and the ssh key of the gerrit account"
+	case c.Type == setting.SourceFromGerrit:
+		return "check the username and the http password of the gerrit account, it is generated in the http credentials of the settings"
+	case c.Type == setting.SourceFromPerforce:
+		return "check the user and the ticket or the password of the perforce account, the ticket is printed by p4 login -p"
+	case c.AuthType == types.GitHubAppAuthType:
+		return "check the app id, the installation id and the private key of the github app"
+	case c.AuthType == types.PrivateAccessTokenAuthType || c.AccessToken == "":
+		return "check whether the access token is expired or revoked and has the required scopes"
+	}
+	return "authorize the codehost again, the oauth token may be expired or revoked"
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length] + "..."
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"github.com/koderover/zadig/pkg/tool/crypto"
+)
+
+// KMSBackend encrypts the secrets by the key provider of the stored secrets, e.g. aws kms, the encrypted
+// blob is the reference saved in the database.
+type KMSBackend struct{}
+
+func (KMSBackend) Name() string {
+	return BackendKMS
+}
+
+func (KMSBackend) Put(_ int, _, value string) (string, error) {
+	return crypto.EncryptSecret(value)
+}
+
+func (KMSBackend) Get(ref string) (string, error) {
+	return crypto.DecryptSecret(ref)
+}
+
+func (KMSBackend) IsReference(value string) bool {
+	return crypto.IsEncryptedSecret(value)
+}
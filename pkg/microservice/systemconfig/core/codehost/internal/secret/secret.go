@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+const (
+	BackendMongo = "mongo"
+	BackendVault = "vault"
+	BackendKMS   = "kms"
+)
+
+// Backend stores the credentials of the codehosts outside the codehost documents, only the references
+// returned by the backend are saved in the database.
+type Backend interface {
+	Name() string
+	// Put stores the secret of the field of the codehost and returns the reference of it.
+	Put(codeHostID int, field, value string) (string, error)
+	// Get returns the secret the reference points to.
+	Get(ref string) (string, error)
+	// IsReference reports whether the value saved in the database is a reference of the backend.
+	IsReference(value string) bool
+}
+
+// MongoBackend keeps the secrets in the codehost documents as they are, it is the default backend.
+type MongoBackend struct{}
+
+func (MongoBackend) Name() string {
+	return BackendMongo
+}
+
+func (MongoBackend) Put(_ int, _, value string) (string, error) {
+	return value, nil
+}
+
+func (MongoBackend) Get(ref string) (string, error) {
+	return ref, nil
+}
+
+func (MongoBackend) IsReference(_ string) bool {
+	return false
+}
+
+// Store is the backend new secrets are put into and the backends the saved references are resolved by.
+type Store struct {
+	current  Backend
+	backends []Backend
+}
+
+// NewStore puts the new secrets into current, the references of the other backends are still resolved,
+// so that the secrets saved before the backend is changed can be read.
+func NewStore(current Backend, others ...Backend) *Store {
+	return &Store{current: current, backends: append([]Backend{current}, others...)}
+}
+
+// Put stores the secret by the current backend. Empty values and the references of any backend, e.g. the
+// ones given by the users, are returned as they are.
+func (s *Store) Put(codeHostID int, field, value string) (string, error) {
+	if value == "" || s.IsReference(value) {
+		return value, nil
+	}
+	ref, err := s.current.Put(codeHostID, field, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to put %s of codehost %d into %s: %s", field, codeHostID, s.current.Name(), err)
+	}
+	return ref, nil
+}
+
+// Resolve returns the secret the value points to if it is a reference, or the value itself otherwise.
+func (s *Store) Resolve(value string) (string, error) {
+	for _, backend := range s.backends {
+		if !backend.IsReference(value) {
+			continue
+		}
+		secret, err := backend.Get(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to get secret from %s: %s", backend.Name(), err)
+		}
+		return secret, nil
+	}
+	return value, nil
+}
+
+func (s *Store) IsReference(value string) bool {
+	for _, backend := range s.backends {
+		if backend.IsReference(value) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreErr  error
+	defaultStoreOnce sync.Once
+)
+
+// DefaultStore returns the store of the backend configured by CODEHOST_SECRET_BACKEND. The kms references
+// are always resolved, the vault ones are resolved if the address of vault is configured.
+func DefaultStore() (*Store, error) {
+	defaultStoreOnce.Do(func() {
+		defaultStore, defaultStoreErr = newDefaultStore()
+	})
+	return defaultStore, defaultStoreErr
+}
+
+func newDefaultStore() (*Store, error) {
+	backends := map[string]Backend{
+		BackendMongo: MongoBackend{},
+		BackendKMS:   KMSBackend{},
+	}
+	if config.VaultAddress() != "" {
+		vault, err := NewVaultBackend(config.VaultAddress(), config.VaultToken(), config.CodeHostSecretVaultMount())
+		if err != nil {
+			return nil, err
+		}
+		backends[BackendVault] = vault
+	}
+
+	name := config.CodeHostSecretBackend()
+	current, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported codehost secret backend %s", name)
+	}
+	// the local key would be used silently without a key provider, which is not what kms is selected for.
+	if name == BackendKMS && config.EncryptionKeyProvider() == "" {
+		return nil, fmt.Errorf("codehost secret backend %s requires %s", name, setting.ENVEncryptionKeyProvider)
+	}
+	others := make([]Backend, 0, len(backends))
+	for n, backend := range backends {
+		if n != name {
+			others = append(others, backend)
+		}
+	}
+	return NewStore(current, others...), nil
+}
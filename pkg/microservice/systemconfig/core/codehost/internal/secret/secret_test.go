@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/koderover/zadig/pkg/setting"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+func TestVaultBackend(t *testing.T) {
+	secrets := map[string]map[string]interface{}{
+		"/v1/secret/data/teams/infra/gitlab": {"token": "glpat-team"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			req := &vaultKVData{}
+			_ = json.NewDecoder(r.Body).Decode(req)
+			secrets[r.URL.Path] = req.Data
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			data, ok := secrets[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(&vaultKVResp{Data: &vaultKVData{Data: data}})
+		}
+	}))
+	defer server.Close()
+
+	vault, err := NewVaultBackend(server.URL, "root", "")
+	if err != nil {
+		t.Fatalf("Failed to create vault backend: %s", err)
+	}
+	store := NewStore(vault, MongoBackend{})
+
+	ref, err := store.Put(12, "access_token", "glpat-zadig")
+	if err != nil {
+		t.Fatalf("Failed to put secret: %s", err)
+	}
+	if ref != "vault:secret/zadig/codehosts/12/access_token#value" {
+		t.Errorf("Unexpected reference %s", ref)
+	}
+	if got, err := store.Resolve(ref); err != nil || got != "glpat-zadig" {
+		t.Errorf("Expected the secret put, got %q, err: %v", got, err)
+	}
+
+	// the references given by the users are kept and resolved by their keys.
+	given := "vault:secret/teams/infra/gitlab#token"
+	if got, _ := store.Put(12, "access_token", given); got != given {
+		t.Errorf("Expected the given reference to be kept, got %s", got)
+	}
+	if got, err := store.Resolve(given); err != nil || got != "glpat-team" {
+		t.Errorf("Expected the secret of the given reference, got %q, err: %v", got, err)
+	}
+	if _, err := store.Resolve("vault:secret/teams/infra/gitlab#missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected the missing key to be reported, got %v", err)
+	}
+
+	// the plain values saved before the backend is changed are returned as they are.
+	if got, _ := store.Resolve("plain-token"); got != "plain-token" {
+		t.Errorf("Expected the plain value, got %s", got)
+	}
+	if got, _ := store.Put(12, "password", ""); got != "" {
+		t.Errorf("Expected the empty value not to be put, got %s", got)
+	}
+}
+
+func TestParseVaultReference(t *testing.T) {
+	mount, path, key, err := parseVaultReference("vault:kv/a/b")
+	if err != nil || mount != "kv" || path != "a/b" || key != "value" {
+		t.Errorf("Unexpected result %s %s %s %v", mount, path, key, err)
+	}
+	for _, ref := range []string{"vault:kv", "vault:/a#", "vault:kv/a#"} {
+		if _, _, _, err := parseVaultReference(ref); err == nil {
+			t.Errorf("Expected %s to be invalid", ref)
+		}
+	}
+}
+
+func TestNewDefaultStore_KMSWithoutKeyProvider(t *testing.T) {
+	viper.Set(setting.ENVCodeHostSecretBackend, BackendKMS)
+	defer viper.Set(setting.ENVCodeHostSecretBackend, "")
+
+	if _, err := newDefaultStore(); err == nil {
+		t.Errorf("Expected the kms backend without a key provider to be rejected")
+	}
+
+	viper.Set(setting.ENVEncryptionKeyProvider, "local")
+	defer viper.Set(setting.ENVEncryptionKeyProvider, "")
+	store, err := newDefaultStore()
+	if err != nil {
+		t.Fatalf("Failed to create the store: %s", err)
+	}
+	if store.current.Name() != BackendKMS {
+		t.Errorf("Expected the current backend to be %s, got %s", BackendKMS, store.current.Name())
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	vaultReferencePrefix = "vault:"
+	vaultValueKey        = "value"
+	vaultCodeHostPath    = "zadig/codehosts"
+)
+
+// VaultBackend stores the secrets in the kv secrets engine (version 2) of vault. The reference has the format
+// vault:<mount>/<path>#<key>, e.g. vault:secret/teams/infra/gitlab#token, so the users can also refer to the
+// secrets they maintain in vault. The secrets put by zadig are saved in <mount>/zadig/codehosts/<id>/<field>.
+type VaultBackend struct {
+	mount  string
+	token  string
+	client *httpclient.Client
+}
+
+type vaultKVData struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+type vaultKVResp struct {
+	Data *vaultKVData `json:"data"`
+}
+
+func NewVaultBackend(address, token, mount string) (*VaultBackend, error) {
+	if address == "" || token == "" {
+		return nil, fmt.Errorf("address and token of vault are required")
+	}
+	if mount == "" {
+		mount = "secret"
+	}
+	return &VaultBackend{mount: strings.Trim(mount, "/"), token: token, client: httpclient.New(httpclient.SetHostURL(address))}, nil
+}
+
+func (b *VaultBackend) Name() string {
+	return BackendVault
+}
+
+func (b *VaultBackend) Put(codeHostID int, field, value string) (string, error) {
+	path := fmt.Sprintf("%s/%d/%s", vaultCodeHostPath, codeHostID, field)
+	req := &vaultKVData{Data: map[string]interface{}{vaultValueKey: value}}
+	if _, err := b.client.Post(vaultKVURL(b.mount, path), httpclient.SetBody(req), httpclient.SetHeader("X-Vault-Token", b.token)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s/%s#%s", vaultReferencePrefix, b.mount, path, vaultValueKey), nil
+}
+
+func (b *VaultBackend) Get(ref string) (string, error) {
+	mount, path, key, err := parseVaultReference(ref)
+	if err != nil {
+		return "", err
+	}
+	resp := &vaultKVResp{}
+	if _, err := b.client.Get(vaultKVURL(mount, path), httpclient.SetResult(resp), httpclient.SetHeader("X-Vault-Token", b.token)); err != nil {
+		return "", err
+	}
+	if resp.Data == nil {
+		return "", fmt.Errorf("secret %s/%s not found in vault", mount, path)
+	}
+	value, ok := resp.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret %s/%s", key, mount, path)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s of secret %s/%s is not a string", key, mount, path)
+	}
+	return secret, nil
+}
+
+func (b *VaultBackend) IsReference(value string) bool {
+	return strings.HasPrefix(value, vaultReferencePrefix)
+}
+
+// parseVaultReference splits vault:<mount>/<path>#<key>, the key is value if it is omitted.
+func parseVaultReference(ref string) (mount, path, key string, err error) {
+	location := strings.TrimPrefix(ref, vaultReferencePrefix)
+	key = vaultValueKey
+	if i := strings.LastIndex(location, "#"); i >= 0 {
+		location, key = location[:i], location[i+1:]
+	}
+	parts := strings.SplitN(strings.Trim(location, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || key == "" {
+		return "", "", "", fmt.Errorf("invalid vault reference %s, the format is vault:<mount>/<path>#<key>", ref)
+	}
+	return parts[0], parts[1], key, nil
+}
+
+func vaultKVURL(mount, path string) string {
+	return fmt.Sprintf("/v1/%s/data/%s", mount, path)
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CodeHostAudit records who changed the codehost, the secrets are redacted in the changes.
+type CodeHostAudit struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	CodeHostID int                    `bson:"codehost_id"   json:"codehost_id"`
+	Action     string                 `bson:"action"        json:"action"`
+	Actor      string                 `bson:"actor"         json:"actor"`
+	Changes    []*CodeHostAuditChange `bson:"changes"       json:"changes"`
+	CreatedAt  int64                  `bson:"created_at"    json:"created_at"`
+}
+
+type CodeHostAuditChange struct {
+	Field string `bson:"field" json:"field"`
+	Old   string `bson:"old"   json:"old"`
+	New   string `bson:"new"   json:"new"`
+}
+
+func (CodeHostAudit) TableName() string {
+	return "codehost_audit"
+}
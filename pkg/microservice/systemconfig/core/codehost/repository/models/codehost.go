@@ -21,26 +21,83 @@ import (
 )
 
 type CodeHost struct {
-	ID                 int            `bson:"id"                              json:"id"`
-	Type               string         `bson:"type"                            json:"type"`
-	Address            string         `bson:"address"                         json:"address"`
-	IsReady            string         `bson:"is_ready"                        json:"is_ready"`
-	AccessToken        string         `bson:"access_token"                    json:"access_token"`
-	RefreshToken       string         `bson:"refresh_token"                   json:"refresh_token"`
-	Namespace          string         `bson:"namespace"                       json:"namespace"`
-	ApplicationId      string         `bson:"application_id"                  json:"application_id"`
-	Region             string         `bson:"region,omitempty"                json:"region,omitempty"`
-	Username           string         `bson:"username,omitempty"              json:"username,omitempty"`
-	Password           string         `bson:"password,omitempty"              json:"password,omitempty"`
-	ClientSecret       string         `bson:"client_secret"                   json:"client_secret"`
-	Alias              string         `bson:"alias,omitempty"                 json:"alias,omitempty"`
-	AuthType           types.AuthType `bson:"auth_type,omitempty"             json:"auth_type,omitempty"`
-	SSHKey             string         `bson:"ssh_key,omitempty"               json:"ssh_key,omitempty"`
-	PrivateAccessToken string         `bson:"private_access_token,omitempty"  json:"private_access_token,omitempty"`
-	CreatedAt          int64          `bson:"created_at"                      json:"created_at"`
-	UpdatedAt          int64          `bson:"updated_at"                      json:"updated_at"`
-	DeletedAt          int64          `bson:"deleted_at"                      json:"deleted_at"`
-	EnableProxy        bool           `bson:"enable_proxy"                    json:"enable_proxy"`
+	ID                 int               `bson:"id"                              json:"id"`
+	Type               string            `bson:"type"                            json:"type"`
+	Address            string            `bson:"address"                         json:"address"`
+	IsReady            string            `bson:"is_ready"                        json:"is_ready"`
+	AccessToken        string            `bson:"access_token"                    json:"access_token"`
+	RefreshToken       string            `bson:"refresh_token"                   json:"refresh_token"`
+	ExpiresAt          int64             `bson:"expires_at,omitempty"            json:"expires_at,omitempty"`
+	Namespace          string            `bson:"namespace"                       json:"namespace"`
+	ApplicationId      string            `bson:"application_id"                  json:"application_id"`
+	Region             string            `bson:"region,omitempty"                json:"region,omitempty"`
+	Username           string            `bson:"username,omitempty"              json:"username,omitempty"`
+	Password           string            `bson:"password,omitempty"              json:"password,omitempty"`
+	ClientSecret       string            `bson:"client_secret"                   json:"client_secret"`
+	Alias              string            `bson:"alias,omitempty"                 json:"alias,omitempty"`
+	AuthType           types.AuthType    `bson:"auth_type,omitempty"             json:"auth_type,omitempty"`
+	SSHKey             string            `bson:"ssh_key,omitempty"               json:"ssh_key,omitempty"`
+	PrivateAccessToken string            `bson:"private_access_token,omitempty"  json:"private_access_token,omitempty"`
+	CreatedAt          int64             `bson:"created_at"                      json:"created_at"`
+	UpdatedAt          int64             `bson:"updated_at"                      json:"updated_at"`
+	DeletedAt          int64             `bson:"deleted_at"                      json:"deleted_at"`
+	EnableProxy        bool              `bson:"enable_proxy"                    json:"enable_proxy"`
+	RepoPolicy         *types.RepoPolicy `bson:"repo_policy,omitempty"           json:"repo_policy,omitempty"`
+	Projects           []string          `bson:"projects,omitempty"              json:"projects,omitempty"`
+	// Shared codehosts are available to all the projects, Projects is ignored for them.
+	Shared bool `bson:"shared" json:"shared"`
+	// NotReadyReason is why the codehost is not ready, e.g. the token can not be refreshed.
+	NotReadyReason string `bson:"not_ready_reason,omitempty" json:"not_ready_reason,omitempty"`
+	// TokenAlertedAt is when the admins were notified that the token is about to expire.
+	TokenAlertedAt int64 `bson:"token_alerted_at,omitempty" json:"token_alerted_at,omitempty"`
+	// the github app fields are used when auth_type is GitHubApp, the access token is an installation token
+	// minted on demand.
+	GitHubAppID          int64  `bson:"github_app_id,omitempty"          json:"github_app_id,omitempty"`
+	GitHubAppPrivateKey  string `bson:"github_app_private_key,omitempty" json:"github_app_private_key,omitempty"`
+	GitHubInstallationID int64  `bson:"github_installation_id,omitempty" json:"github_installation_id,omitempty"`
+	// Health is the result of the latest probe against the provider api.
+	Health *CodeHostHealth `bson:"health,omitempty" json:"health,omitempty"`
+	// ProxyURL is the proxy the codehost is accessed by if EnableProxy is true, the system proxy is used if
+	// it is empty. The hosts in NoProxy are accessed directly, they are matched like NO_PROXY.
+	ProxyURL string   `bson:"proxy_url,omitempty" json:"proxy_url,omitempty"`
+	NoProxy  []string `bson:"no_proxy,omitempty"  json:"no_proxy,omitempty"`
+	// CACert is the PEM encoded CA bundle trusted besides the system ones, it is used by the codehosts
+	// whose certificates are issued by an internal CA.
+	CACert             string `bson:"ca_cert,omitempty"              json:"ca_cert,omitempty"`
+	InsecureSkipVerify bool   `bson:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+	// AzureOrganization is the azure devops organization the repos belong to, the codehost is limited to
+	// the repos of AzureProject if it is set.
+	AzureOrganization string `bson:"azure_organization,omitempty" json:"azure_organization,omitempty"`
+	AzureProject      string `bson:"azure_project,omitempty"      json:"azure_project,omitempty"`
+	// SSHPort is the port of the ssh daemon of gerrit, the repos are cloned over ssh with SSHKey if the auth type
+	// is SSH, gerrit.DefaultSSHPort is used if it is not set.
+	SSHPort int `bson:"ssh_port,omitempty" json:"ssh_port,omitempty"`
+	// the disabled codehosts keep their tokens but can not be used, e.g. during the upgrade of the provider.
+	// DisabledReason tells the users why it is disabled and when it is back.
+	Enabled        bool   `bson:"enabled"                   json:"enabled"`
+	DisabledReason string `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	DisabledBy     string `bson:"disabled_by,omitempty"     json:"disabled_by,omitempty"`
+	DisabledAt     int64  `bson:"disabled_at,omitempty"     json:"disabled_at,omitempty"`
+	// Scopes replace the default scopes the oauth application requests, ExtraAuthParams are added to the
+	// login url of the provider, e.g. prompt=consent.
+	Scopes          []string          `bson:"scopes,omitempty"            json:"scopes,omitempty"`
+	ExtraAuthParams map[string]string `bson:"extra_auth_params,omitempty" json:"extra_auth_params,omitempty"`
+	// Revision is increased by every update, the update with a stale revision is rejected so that the
+	// changes of others are not overwritten. It is required by the updates except the tokens saved internally.
+	Revision int64 `bson:"revision" json:"revision"`
+	// RefreshingUntil is when the claim of the replica refreshing the token expires.
+	RefreshingUntil int64 `bson:"refreshing_until,omitempty" json:"-"`
+}
+
+type CodeHostHealth struct {
+	Healthy       bool   `bson:"healthy"              json:"healthy"`
+	Reachable     bool   `bson:"reachable"            json:"reachable"`
+	Authenticated bool   `bson:"authenticated"        json:"authenticated"`
+	StatusCode    int    `bson:"status_code"          json:"status_code"`
+	Latency       int64  `bson:"latency"              json:"latency"`
+	Error         string `bson:"error,omitempty"      json:"error,omitempty"`
+	Suggestion    string `bson:"suggestion,omitempty" json:"suggestion,omitempty"`
+	LastCheckedAt int64  `bson:"last_checked_at"      json:"last_checked_at"`
 }
 
 func (CodeHost) TableName() string {
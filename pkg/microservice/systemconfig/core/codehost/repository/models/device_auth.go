@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DeviceAuthStatus string
+
+const (
+	DeviceAuthStatusPending   DeviceAuthStatus = "pending"
+	DeviceAuthStatusSucceeded DeviceAuthStatus = "succeeded"
+	DeviceAuthStatusFailed    DeviceAuthStatus = "failed"
+	DeviceAuthStatusExpired   DeviceAuthStatus = "expired"
+)
+
+// DeviceAuth tracks the device authorization of a codehost, the device code is exchanged for the token by
+// the poller after the user enters the user code at the verification uri.
+type DeviceAuth struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"       json:"id,omitempty"`
+	CodeHostID int                `bson:"codehost_id"         json:"codehost_id"`
+	// DeviceCode is encrypted, anyone holding it gets the token once the user authorizes.
+	DeviceCode              string           `bson:"device_code"                         json:"-"`
+	UserCode                string           `bson:"user_code"                           json:"user_code"`
+	VerificationURI         string           `bson:"verification_uri"                    json:"verification_uri"`
+	VerificationURIComplete string           `bson:"verification_uri_complete,omitempty" json:"verification_uri_complete,omitempty"`
+	Interval                int64            `bson:"interval"                            json:"interval"`
+	Status                  DeviceAuthStatus `bson:"status"                              json:"status"`
+	Reason                  string           `bson:"reason,omitempty"                    json:"reason,omitempty"`
+	User                    string           `bson:"user"                                json:"user"`
+	CreatedAt               int64            `bson:"created_at"                          json:"created_at"`
+	ExpiresAt               int64            `bson:"expires_at"                          json:"expires_at"`
+	NextPollAt              int64            `bson:"next_poll_at"                        json:"-"`
+	// CleanupAt is used by the ttl index to clean up the authorizations DeviceAuthRetention after they expire.
+	CleanupAt time.Time `bson:"cleanup_at" json:"-"`
+}
+
+func (DeviceAuth) TableName() string {
+	return "codehost_device_auth"
+}
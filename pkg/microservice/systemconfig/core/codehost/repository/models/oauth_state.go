@@ -0,0 +1,39 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+)
+
+// OAuthState is issued when the authorization of a codehost starts, its nonce is carried by the state
+// of the oauth callback and it is consumed by the first callback.
+type OAuthState struct {
+	Nonce       string `bson:"nonce"        json:"nonce"`
+	CodeHostID  int    `bson:"codehost_id"  json:"codehost_id"`
+	RedirectURL string `bson:"redirect_url" json:"redirect_url"`
+	// CallbackURL is the callback url sent to the provider, the token is exchanged with the same one.
+	CallbackURL string `bson:"callback_url" json:"callback_url"`
+	User        string `bson:"user"         json:"user"`
+	IssuedAt    int64  `bson:"issued_at"    json:"issued_at"`
+	// ExpireAt is used by the ttl index to clean up the states never consumed.
+	ExpireAt time.Time `bson:"expire_at" json:"expire_at"`
+}
+
+func (OAuthState) TableName() string {
+	return "codehost_oauth_state"
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CodeHostWebhook is a webhook registered on a repository of the codehost by zadig, it is recorded so that
+// it can be reconciled or removed together with the codehost.
+type CodeHostWebhook struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CodeHostID int                `bson:"codehost_id"   json:"codehost_id"`
+	Owner      string             `bson:"owner"         json:"owner"`
+	Repo       string             `bson:"repo"          json:"repo"`
+	// HookID is the id of the hook on the codehost, it is the name of the remote for gerrit.
+	HookID           string `bson:"hook_id"                      json:"hook_id"`
+	URL              string `bson:"url"                          json:"url"`
+	CreatedBy        string `bson:"created_by"                   json:"created_by"`
+	CreatedAt        int64  `bson:"created_at"                   json:"created_at"`
+	LastReconciledAt int64  `bson:"last_reconciled_at,omitempty" json:"last_reconciled_at,omitempty"`
+}
+
+func (CodeHostWebhook) TableName() string {
+	return "codehost_webhook"
+}
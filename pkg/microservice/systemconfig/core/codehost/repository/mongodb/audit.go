@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type CodeHostAuditColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewCodeHostAuditColl() *CodeHostAuditColl {
+	name := models.CodeHostAudit{}.TableName()
+	return &CodeHostAuditColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *CodeHostAuditColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *CodeHostAuditColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "codehost_id", Value: 1},
+			bson.E{Key: "created_at", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *CodeHostAuditColl) Create(audit *models.CodeHostAudit) error {
+	_, err := c.InsertOne(context.TODO(), audit)
+	return err
+}
+
+// List returns a page of the audits of the codehost from the latest one and the number of all its audits,
+// all the audits are returned if perPage is 0.
+func (c *CodeHostAuditColl) List(codeHostID, page, perPage int) ([]*models.CodeHostAudit, int64, error) {
+	audits := make([]*models.CodeHostAudit, 0)
+	query := bson.M{"codehost_id": codeHostID}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	if perPage > 0 {
+		if page < 1 {
+			page = 1
+		}
+		opts.SetSkip(int64((page - 1) * perPage)).SetLimit(int64(perPage))
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cursor.All(context.TODO(), &audits); err != nil {
+		return nil, 0, err
+	}
+	total, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return audits, total, nil
+}
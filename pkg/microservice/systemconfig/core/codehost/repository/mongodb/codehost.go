@@ -18,18 +18,39 @@ package mongodb
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/cache"
 	"github.com/koderover/zadig/pkg/tool/log"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+	"github.com/koderover/zadig/pkg/types"
 )
 
+// codehosts are looked up by id for every webhook and every repo operation, so they are cached.
+const codehostCacheTTL = 5 * time.Minute
+
+var (
+	// ErrRevisionConflict is returned if the codehost has been updated since the revision the caller read.
+	ErrRevisionConflict = errors.New("the codehost has been modified by others")
+	// ErrRevisionRequired is returned if the codehost is updated without the revision the caller read.
+	ErrRevisionRequired = errors.New("the revision of the codehost is required")
+)
+
+func codehostCacheKey(id int) string {
+	return fmt.Sprintf("codehost:%d", id)
+}
+
 type CodehostColl struct {
 	*mongo.Collection
 
@@ -40,6 +61,18 @@ type ListArgs struct {
 	Owner   string
 	Address string
 	Source  string
+	// Search is matched case insensitively against the address, owner and alias.
+	Search string
+	// Page starts from 1, all the codehosts are returned if PerPage is 0.
+	Page    int
+	PerPage int
+	// the codehosts are sorted by id if SortByUpdatedAt is false.
+	SortByUpdatedAt bool
+	SortDesc        bool
+	// the soft deleted codehosts are listed as well if IncludeDeleted is true, they can be restored.
+	IncludeDeleted bool
+	// only the enabled or the disabled codehosts are listed if Enabled is set.
+	Enabled *bool
 }
 
 func NewCodehostColl() *CodehostColl {
@@ -57,12 +90,13 @@ func (c *CodehostColl) EnsureIndex(ctx context.Context) error {
 }
 
 func (c *CodehostColl) AddCodeHost(iCodeHost *models.CodeHost) (*models.CodeHost, error) {
-
+	iCodeHost.Revision = 1
 	_, err := c.Collection.InsertOne(context.TODO(), iCodeHost)
 	if err != nil {
 		log.Error("repository AddCodeHost err : %v", err)
 		return nil, err
 	}
+	cache.Delete(codehostCacheKey(iCodeHost.ID))
 	return iCodeHost, nil
 }
 
@@ -72,11 +106,15 @@ func (c *CodehostColl) DeleteCodeHost() error {
 		"deleted_at": time.Now().Unix(),
 	}}
 
-	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
-	if err != nil {
+	deleted := new(models.CodeHost)
+	err := c.Collection.FindOneAndUpdate(context.TODO(), query, change).Decode(deleted)
+	if err != nil && err != mongo.ErrNoDocuments {
 		log.Error("repository DeleteCodeHostByID err : %v", err)
 		return err
 	}
+	if err == nil {
+		cache.Delete(codehostCacheKey(deleted.ID))
+	}
 	return nil
 }
 
@@ -90,12 +128,35 @@ func (c *CodehostColl) GetCodeHostByAlias(alias string) (*models.CodeHost, error
 }
 
 func (c *CodehostColl) GetCodeHostByID(ID int, ignoreDelete bool) (*models.CodeHost, error) {
-	codehost := new(models.CodeHost)
-	query := bson.M{"id": ID}
-	if !ignoreDelete {
-		query["deleted_at"] = 0
+	// the deleted codehosts are cached as well and filtered here.
+	codehost, err := cache.Load(codehostCacheKey(ID), codehostCacheTTL, func() (*models.CodeHost, error) {
+		return c.findCodeHostByID(ID)
+	})
+	if err != nil {
+		return nil, err
 	}
-	if err := c.Collection.FindOne(context.TODO(), query).Decode(codehost); err != nil {
+	if !ignoreDelete && codehost.DeletedAt != 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return codehost, nil
+}
+
+// GetLatestCodeHostByID reads the codehost from the collection instead of the cache, it is used when the
+// codehost must be the latest one, e.g. before the refresh token is spent.
+func (c *CodehostColl) GetLatestCodeHostByID(ID int, ignoreDelete bool) (*models.CodeHost, error) {
+	codehost, err := c.findCodeHostByID(ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ignoreDelete && codehost.DeletedAt != 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return codehost, nil
+}
+
+func (c *CodehostColl) findCodeHostByID(ID int) (*models.CodeHost, error) {
+	codehost := new(models.CodeHost)
+	if err := c.Collection.FindOne(context.TODO(), bson.M{"id": ID}).Decode(codehost); err != nil {
 		return nil, err
 	}
 	return codehost, nil
@@ -103,22 +164,28 @@ func (c *CodehostColl) GetCodeHostByID(ID int, ignoreDelete bool) (*models.CodeH
 
 func (c *CodehostColl) List(args *ListArgs) ([]*models.CodeHost, error) {
 	codeHosts := make([]*models.CodeHost, 0)
-	query := bson.M{"deleted_at": 0}
-
 	if args == nil {
 		args = &ListArgs{}
 	}
-	if args.Address != "" {
-		query["address"] = args.Address
+
+	order := 1
+	if args.SortDesc {
+		order = -1
 	}
-	if args.Owner != "" {
-		query["namespace"] = args.Owner
+	sortKey := "id"
+	if args.SortByUpdatedAt {
+		sortKey = "updated_at"
 	}
-	if args.Source != "" {
-		query["type"] = args.Source
+	opts := options.Find().SetSort(bson.D{{Key: sortKey, Value: order}, {Key: "id", Value: order}})
+	if args.PerPage > 0 {
+		page := args.Page
+		if page < 1 {
+			page = 1
+		}
+		opts.SetSkip(int64((page - 1) * args.PerPage)).SetLimit(int64(args.PerPage))
 	}
 
-	cursor, err := c.Collection.Find(context.TODO(), query)
+	cursor, err := c.Collection.Find(context.TODO(), listQuery(args), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +196,42 @@ func (c *CodehostColl) List(args *ListArgs) ([]*models.CodeHost, error) {
 	return codeHosts, nil
 }
 
+// Count returns the number of the codehosts matched by the args, the paging is ignored.
+func (c *CodehostColl) Count(args *ListArgs) (int64, error) {
+	if args == nil {
+		args = &ListArgs{}
+	}
+	return c.Collection.CountDocuments(context.TODO(), listQuery(args))
+}
+
+func listQuery(args *ListArgs) bson.M {
+	query := bson.M{}
+	if !args.IncludeDeleted {
+		query["deleted_at"] = 0
+	}
+	if args.Enabled != nil {
+		query["enabled"] = *args.Enabled
+	}
+	if args.Address != "" {
+		query["address"] = args.Address
+	}
+	if args.Owner != "" {
+		query["namespace"] = args.Owner
+	}
+	if args.Source != "" {
+		query["type"] = args.Source
+	}
+	if args.Search != "" {
+		search := bson.M{"$regex": regexp.QuoteMeta(args.Search), "$options": "i"}
+		query["$or"] = bson.A{
+			bson.M{"address": search},
+			bson.M{"namespace": search},
+			bson.M{"alias": search},
+		}
+	}
+	return query
+}
+
 func (c *CodehostColl) CodeHostList() ([]*models.CodeHost, error) {
 	codeHosts := make([]*models.CodeHost, 0)
 	cursor, err := c.Collection.Find(context.TODO(), bson.M{})
@@ -142,6 +245,138 @@ func (c *CodehostColl) CodeHostList() ([]*models.CodeHost, error) {
 	return codeHosts, nil
 }
 
+// GetMaxID returns the largest id of the codehosts including the deleted ones, 0 is returned if there is none.
+func (c *CodehostColl) GetMaxID() (int, error) {
+	codehost := new(models.CodeHost)
+	opts := options.FindOne().SetSort(bson.D{bson.E{Key: "id", Value: -1}})
+	err := c.Collection.FindOne(context.TODO(), bson.M{}, opts).Decode(codehost)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return codehost.ID, nil
+}
+
+// ShareUnscopedCodeHosts marks the codehosts saved before the shared flag existed as shared if they
+// are not bound to any project, they were available to all the projects.
+func (c *CodehostColl) ShareUnscopedCodeHosts() (int, error) {
+	query := bson.M{
+		"shared": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"projects": bson.M{"$exists": false}},
+			{"projects": bson.M{"$size": 0}},
+		},
+	}
+	var codeHosts []*models.CodeHost
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return 0, err
+	}
+	if err := cursor.All(context.TODO(), &codeHosts); err != nil {
+		return 0, err
+	}
+	if len(codeHosts) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int, 0, len(codeHosts))
+	for _, codeHost := range codeHosts {
+		ids = append(ids, codeHost.ID)
+	}
+	if _, err := c.Collection.UpdateMany(context.TODO(), bson.M{"id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"shared": true}}); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		cache.Delete(codehostCacheKey(id))
+	}
+	return len(ids), nil
+}
+
+// EnableUnmarkedCodeHosts enables the codehosts saved before the enabled flag existed, they were all usable.
+func (c *CodehostColl) EnableUnmarkedCodeHosts() (int, error) {
+	query := bson.M{"enabled": bson.M{"$exists": false}}
+	var codeHosts []*models.CodeHost
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return 0, err
+	}
+	if err := cursor.All(context.TODO(), &codeHosts); err != nil {
+		return 0, err
+	}
+	if len(codeHosts) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int, 0, len(codeHosts))
+	for _, codeHost := range codeHosts {
+		ids = append(ids, codeHost.ID)
+	}
+	if _, err := c.Collection.UpdateMany(context.TODO(), bson.M{"id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"enabled": true}}); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		cache.Delete(codehostCacheKey(id))
+	}
+	return len(ids), nil
+}
+
+// InitCodeHostRevisions sets the revision of the codehosts saved before the revision existed, the updates
+// with revision 0 are not checked so it starts from 1.
+func (c *CodehostColl) InitCodeHostRevisions() (int, error) {
+	query := bson.M{"revision": bson.M{"$exists": false}}
+	var codeHosts []*models.CodeHost
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return 0, err
+	}
+	if err := cursor.All(context.TODO(), &codeHosts); err != nil {
+		return 0, err
+	}
+	if len(codeHosts) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int, 0, len(codeHosts))
+	for _, codeHost := range codeHosts {
+		ids = append(ids, codeHost.ID)
+	}
+	if _, err := c.Collection.UpdateMany(context.TODO(), bson.M{"id": bson.M{"$in": ids}, "revision": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"revision": int64(1)}}); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		cache.Delete(codehostCacheKey(id))
+	}
+	return len(ids), nil
+}
+
+// NextID allocates the id of a new codehost from the counter, the counter starts from the largest id
+// of the existing codehosts so that their ids are kept.
+func (c *CodehostColl) NextID() (int, error) {
+	seq, err := commonrepo.NewCounterColl().GetNextSeqFrom(setting.CodeHostCounterName, func() (int64, error) {
+		maxID, err := c.GetMaxID()
+		return int64(maxID), err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(seq), nil
+}
+
+// NextIDs allocates the ids of n new codehosts in one atomic increase of the counter, the ids are
+// [first, first+n) so that concurrent allocations never overlap.
+func (c *CodehostColl) NextIDs(n int) (int, error) {
+	first, err := commonrepo.NewCounterColl().GetNextSeqsFrom(setting.CodeHostCounterName, int64(n), func() (int64, error) {
+		maxID, err := c.GetMaxID()
+		return int64(maxID), err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(first), nil
+}
+
 func (c *CodehostColl) DeleteCodeHostByID(ID int) error {
 	query := bson.M{"id": ID, "deleted_at": 0}
 	change := bson.M{"$set": bson.M{
@@ -152,6 +387,26 @@ func (c *CodehostColl) DeleteCodeHostByID(ID int) error {
 		log.Errorf("repository update fail,err:%s", err)
 		return err
 	}
+	cache.Delete(codehostCacheKey(ID))
+	return nil
+}
+
+// RestoreCodeHostByID clears the deleted mark of the soft deleted codehost.
+func (c *CodehostColl) RestoreCodeHostByID(ID int) error {
+	query := bson.M{"id": ID, "deleted_at": bson.M{"$ne": 0}}
+	change := bson.M{
+		"$set": bson.M{
+			"deleted_at": 0,
+			"updated_at": time.Now().Unix(),
+		},
+		"$inc": bson.M{"revision": 1},
+	}
+	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		log.Errorf("repository update fail,err:%s", err)
+		return err
+	}
+	cache.Delete(codehostCacheKey(ID))
 	return nil
 }
 
@@ -167,34 +422,194 @@ func (c *CodehostColl) UpdateCodeHost(host *models.CodeHost) (*models.CodeHost,
 		"username":       host.Username,
 		"password":       host.Password,
 		"enable_proxy":   host.EnableProxy,
+		"proxy_url":      host.ProxyURL,
+		"no_proxy":       host.NoProxy,
 		"alias":          host.Alias,
+		"repo_policy":    host.RepoPolicy,
+		"projects":       host.Projects,
+		"shared":         host.Shared,
 		"updated_at":     time.Now().Unix(),
 	}
+	modifyValue["ca_cert"] = host.CACert
+	modifyValue["insecure_skip_verify"] = host.InsecureSkipVerify
+	modifyValue["scopes"] = host.Scopes
+	modifyValue["extra_auth_params"] = host.ExtraAuthParams
 	if host.Type == setting.SourceFromGerrit {
+		modifyValue["auth_type"] = host.AuthType
+		modifyValue["ssh_key"] = host.SSHKey
+		modifyValue["ssh_port"] = host.SSHPort
+		modifyValue["access_token"] = host.AccessToken
+		modifyValue["is_ready"] = host.IsReady
+		modifyValue["not_ready_reason"] = host.NotReadyReason
+	} else if host.Type == setting.SourceFromPerforce {
+		modifyValue["private_access_token"] = host.PrivateAccessToken
+		modifyValue["access_token"] = host.AccessToken
+		modifyValue["is_ready"] = host.IsReady
+		modifyValue["not_ready_reason"] = host.NotReadyReason
+	} else if host.Type == setting.SourceFromBitbucketServer {
 		modifyValue["access_token"] = host.AccessToken
 	} else if host.Type == setting.SourceFromGitee || host.Type == setting.SourceFromGitlab {
+		modifyValue["auth_type"] = host.AuthType
 		modifyValue["access_token"] = host.AccessToken
 		modifyValue["refresh_token"] = host.RefreshToken
 		modifyValue["updated_at"] = host.UpdatedAt
+	} else if host.Type == setting.SourceFromBitbucket || host.Type == setting.SourceFromGitea {
+		modifyValue["auth_type"] = host.AuthType
+		modifyValue["access_token"] = host.AccessToken
+		modifyValue["refresh_token"] = host.RefreshToken
+		modifyValue["expires_at"] = host.ExpiresAt
+		modifyValue["updated_at"] = host.UpdatedAt
+	} else if host.Type == setting.SourceFromAzureDevOps {
+		modifyValue["auth_type"] = host.AuthType
+		modifyValue["azure_organization"] = host.AzureOrganization
+		modifyValue["azure_project"] = host.AzureProject
+		modifyValue["access_token"] = host.AccessToken
+		modifyValue["refresh_token"] = host.RefreshToken
+		modifyValue["expires_at"] = host.ExpiresAt
+		modifyValue["updated_at"] = host.UpdatedAt
+	} else if host.Type == setting.SourceFromGithub {
+		modifyValue["auth_type"] = host.AuthType
+		modifyValue["github_app_id"] = host.GitHubAppID
+		modifyValue["github_app_private_key"] = host.GitHubAppPrivateKey
+		modifyValue["github_installation_id"] = host.GitHubInstallationID
+		if host.AuthType == types.GitHubAppAuthType {
+			// the installation token is minted again with the new app settings.
+			modifyValue["access_token"] = ""
+			modifyValue["expires_at"] = 0
+			modifyValue["is_ready"] = "2"
+		}
 	} else if host.Type == setting.SourceFromOther {
 		modifyValue["auth_type"] = host.AuthType
 		modifyValue["ssh_key"] = host.SSHKey
 		modifyValue["private_access_token"] = host.PrivateAccessToken
 	}
+	if host.AuthType == types.PrivateAccessTokenAuthType && host.Type != setting.SourceFromOther && host.Type != setting.SourceFromBitbucketServer {
+		// the personal access token replaces the oauth tokens, it never expires.
+		modifyValue["auth_type"] = host.AuthType
+		modifyValue["private_access_token"] = host.PrivateAccessToken
+		modifyValue["access_token"] = host.AccessToken
+		modifyValue["refresh_token"] = ""
+		modifyValue["expires_at"] = 0
+		modifyValue["is_ready"] = "2"
+	}
 
-	change := bson.M{"$set": modifyValue}
-	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
-	return host, err
+	return host, c.updateWithRevision(host, query, modifyValue, true)
 }
 
+// UpdateCodeHostByToken saves the tokens of the codehost, the revision is checked if it is given.
 func (c *CodehostColl) UpdateCodeHostByToken(host *models.CodeHost) (*models.CodeHost, error) {
 	query := bson.M{"id": host.ID, "deleted_at": 0}
-	change := bson.M{"$set": bson.M{
-		"is_ready":      "2",
-		"access_token":  host.AccessToken,
-		"updated_at":    time.Now().Unix(),
-		"refresh_token": host.RefreshToken,
-	}}
+	modifyValue := bson.M{
+		"is_ready":         "2",
+		"access_token":     host.AccessToken,
+		"updated_at":       time.Now().Unix(),
+		"refresh_token":    host.RefreshToken,
+		"expires_at":       host.ExpiresAt,
+		"not_ready_reason": "",
+	}
+	return host, c.updateWithRevision(host, query, modifyValue, false)
+}
+
+// updateWithRevision applies the changes only if the codehost is still at the revision of the host, the revision
+// of the host is set to the increased one. The revision can be omitted only if it is not required, e.g. by the
+// tokens saved internally, mongo.ErrNoDocuments is returned if the codehost is not found then.
+func (c *CodehostColl) updateWithRevision(host *models.CodeHost, query, modifyValue bson.M, revisionRequired bool) error {
+	if host.Revision > 0 {
+		query["revision"] = host.Revision
+	} else if revisionRequired {
+		return ErrRevisionRequired
+	}
+	change := bson.M{"$set": modifyValue, "$inc": bson.M{"revision": 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"revision": 1})
+	updated := new(models.CodeHost)
+	err := c.Collection.FindOneAndUpdate(context.TODO(), query, change, opts).Decode(updated)
+	cache.Delete(codehostCacheKey(host.ID))
+	if err == mongo.ErrNoDocuments && host.Revision > 0 {
+		return ErrRevisionConflict
+	}
+	if err != nil {
+		return err
+	}
+	host.Revision = updated.Revision
+	return nil
+}
+
+// UpdateCodeHostEnabled enables or disables the codehost, the reason is kept only if it is disabled.
+func (c *CodehostColl) UpdateCodeHostEnabled(id int, enabled bool, reason, user string) error {
+	query := bson.M{"id": id, "deleted_at": 0}
+	modifyValue := bson.M{
+		"enabled":         enabled,
+		"disabled_reason": "",
+		"disabled_by":     "",
+		"disabled_at":     int64(0),
+		"updated_at":      time.Now().Unix(),
+	}
+	if !enabled {
+		modifyValue["disabled_reason"] = reason
+		modifyValue["disabled_by"] = user
+		modifyValue["disabled_at"] = time.Now().Unix()
+	}
+	res, err := c.Collection.UpdateOne(context.TODO(), query, bson.M{"$set": modifyValue, "$inc": bson.M{"revision": 1}})
+	cache.Delete(codehostCacheKey(id))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (c *CodehostColl) UpdateCodeHostHealth(id int, health *models.CodeHostHealth) error {
+	query := bson.M{"id": id, "deleted_at": 0}
+	change := bson.M{"$set": bson.M{"health": health}}
+	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
+	cache.Delete(codehostCacheKey(id))
+	return err
+}
+
+// ClaimTokenRefresh claims the refreshing of the token of the codehost for the lease and returns the codehost
+// read from the collection, so that the refresh token, which can only be used once, is spent by only one
+// replica. mongo.ErrNoDocuments is returned if the token is being refreshed by another replica.
+func (c *CodehostColl) ClaimTokenRefresh(id int, lease time.Duration) (*models.CodeHost, error) {
+	now := time.Now()
+	query := bson.M{"id": id, "deleted_at": 0, "refreshing_until": bson.M{"$not": bson.M{"$gt": now.Unix()}}}
+	change := bson.M{"$set": bson.M{"refreshing_until": now.Add(lease).Unix()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	codehost := new(models.CodeHost)
+	if err := c.Collection.FindOneAndUpdate(context.TODO(), query, change, opts).Decode(codehost); err != nil {
+		return nil, err
+	}
+	return codehost, nil
+}
+
+// ReleaseTokenRefresh releases the claim of ClaimTokenRefresh after the token is refreshed or failed to be.
+func (c *CodehostColl) ReleaseTokenRefresh(id int) error {
+	_, err := c.Collection.UpdateOne(context.TODO(), bson.M{"id": id}, bson.M{"$unset": bson.M{"refreshing_until": ""}})
+	return err
+}
+
+// UpdateCodeHostNotReady marks the codehost as not ready, e.g. when its token can not be refreshed.
+func (c *CodehostColl) UpdateCodeHostNotReady(id int, reason string) error {
+	query := bson.M{"id": id, "deleted_at": 0}
+	change := bson.M{
+		"$set": bson.M{
+			"is_ready":         "1",
+			"not_ready_reason": reason,
+			"updated_at":       time.Now().Unix(),
+		},
+		"$inc": bson.M{"revision": 1},
+	}
+	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
+	cache.Delete(codehostCacheKey(id))
+	return err
+}
+
+// UpdateCodeHostTokenAlertedAt records when the admins were notified of the expiry of the token.
+func (c *CodehostColl) UpdateCodeHostTokenAlertedAt(id int, alertedAt int64) error {
+	query := bson.M{"id": id, "deleted_at": 0}
+	change := bson.M{"$set": bson.M{"token_alerted_at": alertedAt}}
 	_, err := c.Collection.UpdateOne(context.TODO(), query, change)
-	return host, err
+	cache.Delete(codehostCacheKey(id))
+	return err
 }
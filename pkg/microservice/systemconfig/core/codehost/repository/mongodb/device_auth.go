@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type DeviceAuthColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewDeviceAuthColl() *DeviceAuthColl {
+	name := models.DeviceAuth{}.TableName()
+	return &DeviceAuthColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *DeviceAuthColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *DeviceAuthColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "codehost_id", Value: 1},
+				bson.E{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "status", Value: 1},
+				bson.E{Key: "next_poll_at", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.M{"cleanup_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *DeviceAuthColl) Create(auth *models.DeviceAuth) error {
+	res, err := c.InsertOne(context.TODO(), auth)
+	if err != nil {
+		return err
+	}
+	auth.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetLatest returns the latest device authorization of the codehost.
+func (c *DeviceAuthColl) GetLatest(codeHostID int) (*models.DeviceAuth, error) {
+	auth := new(models.DeviceAuth)
+	opts := options.FindOne().SetSort(bson.M{"created_at": -1})
+	err := c.FindOne(context.TODO(), bson.M{"codehost_id": codeHostID}, opts).Decode(auth)
+	return auth, err
+}
+
+// ClaimDue claims a pending authorization which is due to be polled, its next poll is postponed by the
+// interval so that it is polled by only one replica. mongo.ErrNoDocuments is returned if none is due.
+func (c *DeviceAuthColl) ClaimDue() (*models.DeviceAuth, error) {
+	now := time.Now().Unix()
+	auth := new(models.DeviceAuth)
+	query := bson.M{"status": models.DeviceAuthStatusPending, "next_poll_at": bson.M{"$lte": now}}
+	if err := c.FindOne(context.TODO(), query).Decode(auth); err != nil {
+		return nil, err
+	}
+
+	// the authorization is claimed by another replica if its next poll has changed.
+	query = bson.M{"_id": auth.ID, "status": models.DeviceAuthStatusPending, "next_poll_at": auth.NextPollAt}
+	change := bson.M{"$set": bson.M{"next_poll_at": now + auth.Interval}}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return auth, nil
+}
+
+// SlowDown increases the interval of the authorization as required by the provider.
+func (c *DeviceAuthColl) SlowDown(id primitive.ObjectID, interval int64) error {
+	change := bson.M{"$set": bson.M{
+		"interval":     interval,
+		"next_poll_at": time.Now().Unix() + interval,
+	}}
+	_, err := c.UpdateByID(context.TODO(), id, change)
+	return err
+}
+
+// Finish records the result of the authorization, only the pending ones are finished.
+func (c *DeviceAuthColl) Finish(id primitive.ObjectID, status models.DeviceAuthStatus, reason string) error {
+	query := bson.M{"_id": id, "status": models.DeviceAuthStatusPending}
+	change := bson.M{"$set": bson.M{"status": status, "reason": reason}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+// CancelPending fails the pending authorizations of the codehost, e.g. when a new one starts.
+func (c *DeviceAuthColl) CancelPending(codeHostID int, reason string) error {
+	query := bson.M{"codehost_id": codeHostID, "status": models.DeviceAuthStatusPending}
+	change := bson.M{"$set": bson.M{"status": models.DeviceAuthStatusFailed, "reason": reason}}
+	_, err := c.UpdateMany(context.TODO(), query, change)
+	return err
+}
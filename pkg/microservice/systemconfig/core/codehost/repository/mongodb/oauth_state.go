@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type OAuthStateColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewOAuthStateColl() *OAuthStateColl {
+	name := models.OAuthState{}.TableName()
+	return &OAuthStateColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *OAuthStateColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *OAuthStateColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"nonce": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"expire_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *OAuthStateColl) Create(state *models.OAuthState) error {
+	_, err := c.InsertOne(context.TODO(), state)
+	return err
+}
+
+// Consume deletes and returns the unexpired state of the nonce, so that a state is accepted only once.
+// mongo.ErrNoDocuments is returned if the state is expired, consumed or never issued.
+func (c *OAuthStateColl) Consume(nonce string) (*models.OAuthState, error) {
+	state := new(models.OAuthState)
+	query := bson.M{"nonce": nonce, "expire_at": bson.M{"$gt": time.Now()}}
+	if err := c.FindOneAndDelete(context.TODO(), query).Decode(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
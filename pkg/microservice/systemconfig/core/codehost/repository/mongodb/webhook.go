@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type CodeHostWebhookColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewCodeHostWebhookColl() *CodeHostWebhookColl {
+	name := models.CodeHostWebhook{}.TableName()
+	return &CodeHostWebhookColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *CodeHostWebhookColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *CodeHostWebhookColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "codehost_id", Value: 1},
+			bson.E{Key: "owner", Value: 1},
+			bson.E{Key: "repo", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *CodeHostWebhookColl) Create(hook *models.CodeHostWebhook) error {
+	res, err := c.InsertOne(context.TODO(), hook)
+	if err != nil {
+		return err
+	}
+	hook.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *CodeHostWebhookColl) Find(codeHostID int, owner, repo string) (*models.CodeHostWebhook, error) {
+	hook := new(models.CodeHostWebhook)
+	query := bson.M{"codehost_id": codeHostID, "owner": owner, "repo": repo}
+	if err := c.FindOne(context.TODO(), query).Decode(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (c *CodeHostWebhookColl) FindByID(codeHostID int, id string) (*models.CodeHostWebhook, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	hook := new(models.CodeHostWebhook)
+	if err := c.FindOne(context.TODO(), bson.M{"_id": oid, "codehost_id": codeHostID}).Decode(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (c *CodeHostWebhookColl) List(codeHostID int) ([]*models.CodeHostWebhook, error) {
+	hooks := make([]*models.CodeHostWebhook, 0)
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"codehost_id": codeHostID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// UpdateReconciled saves the id of the hook which may be created again when it is reconciled.
+func (c *CodeHostWebhookColl) UpdateReconciled(id primitive.ObjectID, hookID string) error {
+	change := bson.M{"$set": bson.M{"hook_id": hookID, "last_reconciled_at": time.Now().Unix()}}
+	_, err := c.UpdateByID(context.TODO(), id, change)
+	return err
+}
+
+func (c *CodeHostWebhookColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+const (
+	AuditActionCreate    = "create"
+	AuditActionUpdate    = "update"
+	AuditActionAuthorize = "authorize"
+	AuditActionDelete    = "delete"
+	AuditActionRestore   = "restore"
+	AuditActionEnable    = "enable"
+	AuditActionDisable   = "disable"
+
+	auditRedacted = "******"
+)
+
+var (
+	// the secrets are recorded as changed without their values.
+	auditSecretFields = sets.NewString("access_token", "refresh_token", "password", "client_secret", "ssh_key", "private_access_token", "github_app_private_key")
+	// the fields maintained by zadig instead of the users.
+	auditIgnoredFields = sets.NewString("id", "created_at", "updated_at", "deleted_at", "expires_at", "is_ready", "not_ready_reason", "health", "disabled_by", "disabled_at")
+)
+
+// recordCodeHostAudit saves who did the action and the redacted changes from old to new, old or new is nil
+// if the codehost is created or deleted. The action is not failed if the audit can not be saved.
+func recordCodeHostAudit(codeHostID int, action, actor string, old, new *models.CodeHost, logger *zap.SugaredLogger) {
+	if actor == "" {
+		actor = setting.SystemUser
+	}
+	audit := &models.CodeHostAudit{
+		CodeHostID: codeHostID,
+		Action:     action,
+		Actor:      actor,
+		Changes:    diffCodeHost(old, new),
+		CreatedAt:  time.Now().Unix(),
+	}
+	if err := mongodb.NewCodeHostAuditColl().Create(audit); err != nil {
+		logger.Errorf("failed to save the %s audit of codehost %d by %s, err: %s", action, codeHostID, actor, err)
+	}
+}
+
+func diffCodeHost(old, new *models.CodeHost) []*models.CodeHostAuditChange {
+	changes := make([]*models.CodeHostAuditChange, 0)
+	if old == nil && new == nil {
+		return changes
+	}
+	if old == nil {
+		old = &models.CodeHost{}
+	}
+	if new == nil {
+		new = &models.CodeHost{}
+	}
+
+	oldValue, newValue := reflect.ValueOf(*old), reflect.ValueOf(*new)
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := strings.Split(oldValue.Type().Field(i).Tag.Get("bson"), ",")[0]
+		if field == "" || field == "-" || auditIgnoredFields.Has(field) {
+			continue
+		}
+		before, after := auditValue(oldValue.Field(i)), auditValue(newValue.Field(i))
+		if before == after {
+			continue
+		}
+		if auditSecretFields.Has(field) {
+			before, after = redactAuditValue(before), redactAuditValue(after)
+		}
+		changes = append(changes, &models.CodeHostAuditChange{Field: field, Old: before, New: after})
+	}
+	return changes
+}
+
+func auditValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Struct:
+		if v.Kind() != reflect.Struct && v.IsNil() {
+			return ""
+		}
+		bs, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		return string(bs)
+	default:
+		if v.IsZero() {
+			return ""
+		}
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func redactAuditValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return auditRedacted
+}
+
+// ListCodeHostAudits returns a page of the audits of the codehost from the latest one, and the number of all
+// its audits.
+func ListCodeHostAudits(id, page, perPage int, logger *zap.SugaredLogger) ([]*models.CodeHostAudit, int64, error) {
+	audits, total, err := mongodb.NewCodeHostAuditColl().List(id, page, perPage)
+	if err != nil {
+		logger.Errorf("failed to list the audits of codehost %d, err: %s", id, err)
+		return nil, 0, err
+	}
+	return audits, total, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const maxBulkCodeHosts = 200
+
+type BulkCodeHostResult struct {
+	// Index is the position of the codehost in the request.
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+	Alias string `json:"alias,omitempty"`
+	// Action is one of created, updated and failed.
+	Action string `json:"action"`
+	ID     int    `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkSaveCodeHosts creates the codehosts without an id and updates the ones with an id. Each codehost is
+// validated the same as it is saved one by one, an invalid one does not stop the others. The ids of the new
+// codehosts are reserved in one block before any of them is saved, so that concurrent bulk requests never
+// collide; the id reserved for a codehost failed to be created is left unused.
+func BulkSaveCodeHosts(codeHosts []*models.CodeHost, user string, logger *zap.SugaredLogger) ([]*BulkCodeHostResult, error) {
+	if len(codeHosts) == 0 {
+		return nil, e.ErrBulkSaveCodeHost.AddDesc("no codehost is given")
+	}
+	if len(codeHosts) > maxBulkCodeHosts {
+		return nil, e.ErrBulkSaveCodeHost.AddDesc(fmt.Sprintf("at most %d codehosts are allowed in a batch", maxBulkCodeHosts))
+	}
+
+	creates := 0
+	for _, codeHost := range codeHosts {
+		if codeHost != nil && codeHost.ID == 0 {
+			creates++
+		}
+	}
+	nextID := 0
+	if creates > 0 {
+		first, err := mongodb.NewCodehostColl().NextIDs(creates)
+		if err != nil {
+			logger.Errorf("failed to allocate %d codehost ids, err: %s", creates, err)
+			return nil, e.ErrBulkSaveCodeHost.AddErr(err)
+		}
+		nextID = first
+	}
+
+	results := make([]*BulkCodeHostResult, 0, len(codeHosts))
+	for i, codeHost := range codeHosts {
+		result := &BulkCodeHostResult{Index: i}
+		results = append(results, result)
+		if codeHost == nil {
+			result.Action, result.Error = "failed", "the codehost is empty"
+			continue
+		}
+		result.Type, result.Alias = codeHost.Type, codeHost.Alias
+
+		var saved *models.CodeHost
+		var err error
+		if codeHost.ID == 0 {
+			result.Action = "created"
+			saved, err = createCodeHostWithID(codeHost, nextID, user, logger)
+			observeCodeHostOperation(metricOperationCreate, codeHost.Type, err)
+			nextID++
+		} else {
+			result.Action = "updated"
+			saved, err = UpdateCodeHost(codeHost, user, logger)
+		}
+		if err != nil {
+			logger.Warnf("failed to save codehost %d of the batch, err: %s", i, err)
+			result.Action, result.Error = "failed", err.Error()
+			continue
+		}
+		result.ID = saved.ID
+	}
+	return results, nil
+}
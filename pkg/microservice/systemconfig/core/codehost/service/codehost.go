@@ -17,18 +17,21 @@ limitations under the License.
 package service
 
 import (
-	"encoding/base64"
-	"encoding/json"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
 
 	"github.com/koderover/zadig/pkg/config"
+	systemconfigconfig "github.com/koderover/zadig/pkg/microservice/systemconfig/config"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/internal/oauth"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
@@ -36,17 +39,75 @@ import (
 	"github.com/koderover/zadig/pkg/shared/client/aslan"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	"github.com/koderover/zadig/pkg/tool/crypto"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/gerrit"
+	"github.com/koderover/zadig/pkg/types"
 )
 
 const callback = "/api/directory/codehosts/callback"
 
-func CreateCodeHost(codehost *models.CodeHost, _ *zap.SugaredLogger) (*models.CodeHost, error) {
+const azureDevOpsAddress = "https://dev.azure.com"
+
+func CreateCodeHost(codehost *models.CodeHost, user string, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	created, err := createCodeHost(codehost, user, logger)
+	observeCodeHostOperation(metricOperationCreate, codehost.Type, err)
+	return created, err
+}
+
+func createCodeHost(codehost *models.CodeHost, user string, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	return createCodeHostWithID(codehost, 0, user, logger)
+}
+
+// createCodeHostWithID creates the codehost with the id allocated in advance, a new id is allocated if id is 0.
+func createCodeHostWithID(codehost *models.CodeHost, id int, user string, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	if err := codehost.RepoPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	// the credentials may be given as references of the secret backend, they are verified by the values.
+	refs, err := resolveCodeHostSecrets(codehost)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	if err := validateProxy(codehost); err != nil {
+		return nil, err
+	}
+	if err := validateCACert(codehost); err != nil {
+		return nil, err
+	}
+	if err := oauth.ValidateExtraAuthParams(codehost.ExtraAuthParams); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	if err := normalizeAzureDevOps(codehost); err != nil {
+		return nil, err
+	}
+	normalizeScope(codehost)
 	if codehost.Type == setting.SourceFromCodeHub || codehost.Type == setting.SourceFromOther {
 		codehost.IsReady = "2"
 	}
-	if codehost.Type == setting.SourceFromGerrit {
+	// bitbucket server is authorized by the personal access token if no oauth application is configured
+	if codehost.Type == setting.SourceFromBitbucketServer && codehost.ApplicationId == "" {
 		codehost.IsReady = "2"
-		codehost.AccessToken = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", codehost.Username, codehost.Password)))
+	}
+	if codehost.Type == setting.SourceFromGithub && codehost.AuthType == types.GitHubAppAuthType {
+		// verify the app settings by minting the first installation token.
+		if err := mintInstallationToken(codehost); err != nil {
+			return nil, err
+		}
+	}
+	if usePrivateAccessToken(codehost) {
+		if err := verifyPrivateAccessToken(codehost); err != nil {
+			return nil, err
+		}
+	}
+	if codehost.Type == setting.SourceFromGerrit {
+		if err := verifyGerrit(codehost); err != nil {
+			return nil, err
+		}
+	}
+	if codehost.Type == setting.SourceFromPerforce {
+		if err := verifyPerforce(codehost); err != nil {
+			return nil, err
+		}
 	}
 
 	if codehost.Alias != "" {
@@ -57,13 +118,25 @@ func CreateCodeHost(codehost *models.CodeHost, _ *zap.SugaredLogger) (*models.Co
 
 	codehost.CreatedAt = time.Now().Unix()
 	codehost.UpdatedAt = time.Now().Unix()
+	codehost.Enabled = true
+	codehost.DisabledReason, codehost.DisabledBy, codehost.DisabledAt = "", "", 0
 
-	list, err := mongodb.NewCodehostColl().CodeHostList()
+	if id == 0 {
+		if id, err = mongodb.NewCodehostColl().NextID(); err != nil {
+			return nil, err
+		}
+	}
+	codehost.ID = id
+	stored, err := storeCodeHostSecrets(codehost, refs)
 	if err != nil {
 		return nil, err
 	}
-	codehost.ID = len(list) + 1
-	return mongodb.NewCodehostColl().AddCodeHost(codehost)
+	created, err := mongodb.NewCodehostColl().AddCodeHost(stored)
+	if err != nil {
+		return nil, err
+	}
+	recordCodeHostAudit(created.ID, AuditActionCreate, user, nil, created, logger)
+	return created, nil
 }
 
 func encypteCodeHost(encryptedKey string, codeHosts []*models.CodeHost, log *zap.SugaredLogger) ([]*models.CodeHost, error) {
@@ -96,6 +169,14 @@ func encypteCodeHost(encryptedKey string, codeHosts []*models.CodeHost, log *zap
 			}
 		}
 
+		if len(codeHost.GitHubAppPrivateKey) > 0 {
+			codeHost.GitHubAppPrivateKey, err = crypto.AesEncryptByKey(codeHost.GitHubAppPrivateKey, aesKey.PlainText)
+			if err != nil {
+				log.Errorf("ListCodeHost AesEncryptByKey error:%s", err)
+				return nil, err
+			}
+		}
+
 		if len(codeHost.PrivateAccessToken) > 0 {
 			codeHost.PrivateAccessToken, err = crypto.AesEncryptByKey(codeHost.PrivateAccessToken, aesKey.PlainText)
 			if err != nil {
@@ -109,40 +190,188 @@ func encypteCodeHost(encryptedKey string, codeHosts []*models.CodeHost, log *zap
 	return result, nil
 }
 
-func ListInternal(address, owner, source string, _ *zap.SugaredLogger) ([]*models.CodeHost, error) {
-	return mongodb.NewCodehostColl().List(&mongodb.ListArgs{
+func ListInternal(address, owner, source string, logger *zap.SugaredLogger) ([]*models.CodeHost, error) {
+	enabled := true
+	codeHosts, err := mongodb.NewCodehostColl().List(&mongodb.ListArgs{
 		Address: address,
 		Owner:   owner,
 		Source:  source,
+		Enabled: &enabled,
 	})
+	if err != nil {
+		return nil, err
+	}
+	for _, codeHost := range codeHosts {
+		if _, err := resolveCodeHostSecrets(codeHost); err != nil {
+			logger.Errorf("failed to resolve the credentials of codehost %d, err: %s", codeHost.ID, err)
+			return nil, err
+		}
+		if err := ensureInstallationToken(codeHost); err != nil {
+			logger.Warnf("failed to mint the installation token of codehost %d, err: %s", codeHost.ID, err)
+		}
+	}
+	return codeHosts, nil
 }
 
-func List(encryptedKey, address, owner, source string, log *zap.SugaredLogger) ([]*models.CodeHost, error) {
-	codeHosts, err := mongodb.NewCodehostColl().List(&mongodb.ListArgs{
-		Address: address,
-		Owner:   owner,
-		Source:  source,
-	})
+// ResolveTokenForRepo finds the only enabled codehost of the repo and returns its access token,
+// the repo is identified by the address of the codehost, the owner and the source type.
+func ResolveTokenForRepo(address, owner, source string, logger *zap.SugaredLogger) (*systemconfig.RepoToken, error) {
+	codeHosts, err := ListInternal(address, owner, source, logger)
 	if err != nil {
-		log.Errorf("ListCodeHost error:%s", err)
 		return nil, err
 	}
-	return encypteCodeHost(encryptedKey, codeHosts, log)
+	if len(codeHosts) == 0 {
+		return nil, mongo.ErrNoDocuments
+	} else if len(codeHosts) > 1 {
+		return nil, e.ErrInvalidParam.AddDesc("more than one codehosts found")
+	}
+	codeHost := codeHosts[0]
+	return &systemconfig.RepoToken{
+		CodeHostID: codeHost.ID,
+		Type:       codeHost.Type,
+		Address:    codeHost.Address,
+		Username:   codeHost.Username,
+		Token:      codeHost.AccessToken,
+		ExpiresAt:  codeHost.ExpiresAt,
+	}, nil
 }
 
-func DeleteCodeHost(id int, _ *zap.SugaredLogger) error {
-	return mongodb.NewCodehostColl().DeleteCodeHostByID(id)
+// List returns a page of the codehosts and the number of all the codehosts matched by the args.
+func List(encryptedKey string, args *mongodb.ListArgs, log *zap.SugaredLogger) ([]*models.CodeHost, int64, error) {
+	codeHosts, err := mongodb.NewCodehostColl().List(args)
+	if err != nil {
+		log.Errorf("ListCodeHost error:%s", err)
+		return nil, 0, err
+	}
+	total := int64(len(codeHosts))
+	if args.PerPage > 0 {
+		total, err = mongodb.NewCodehostColl().Count(args)
+		if err != nil {
+			log.Errorf("CountCodeHost error:%s", err)
+			return nil, 0, err
+		}
+	}
+	codeHosts, err = encypteCodeHost(encryptedKey, codeHosts, log)
+	return codeHosts, total, err
+}
+
+// DeleteCodeHost soft deletes the codehost if it is not referenced by the builds, testings, scannings,
+// services or workflows, the references are returned in the extra of the error otherwise.
+func DeleteCodeHost(id int, user string, logger *zap.SugaredLogger) error {
+	codehost, err := mongodb.NewCodehostColl().GetCodeHostByID(id, false)
+	if err != nil {
+		return e.ErrDeleteCodeHost.AddErr(err)
+	}
+	references, err := aslan.New(config.AslanServiceAddress()).ListCodeHostReferences(id)
+	if err != nil {
+		logger.Errorf("failed to list the references of codehost %d, err: %s", id, err)
+		return e.ErrDeleteCodeHost.AddErr(err)
+	}
+	if len(references) > 0 {
+		names := make([]string, 0, len(references))
+		for _, reference := range references {
+			names = append(names, fmt.Sprintf("%s %s/%s", reference.Type, reference.Project, reference.Name))
+		}
+		return e.NewWithExtras(e.ErrCodeHostInUse, fmt.Sprintf("codehost is referenced by %s", strings.Join(names, ", ")), map[string]interface{}{"references": references})
+	}
+
+	cleanupWebhooks(id, logger)
+	if err := mongodb.NewCodehostColl().DeleteCodeHostByID(id); err != nil {
+		return e.ErrDeleteCodeHost.AddErr(err)
+	}
+	invalidateCodeHostCache(id)
+	recordCodeHostAudit(id, AuditActionDelete, user, codehost, nil, logger)
+	return nil
 }
 
-func UpdateCodeHost(host *models.CodeHost, _ *zap.SugaredLogger) (*models.CodeHost, error) {
+// RestoreCodeHost brings back the soft deleted codehost, the webhooks removed by the deletion are not
+// registered again until they are reconciled.
+func RestoreCodeHost(id int, user string, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	codehost, err := mongodb.NewCodehostColl().GetCodeHostByID(id, true)
+	if err != nil {
+		return nil, e.ErrRestoreCodeHost.AddErr(err)
+	}
+	if codehost.DeletedAt == 0 {
+		return nil, e.ErrRestoreCodeHost.AddDesc("codehost is not deleted")
+	}
+	if codehost.Alias != "" {
+		if _, err := mongodb.NewCodehostColl().GetCodeHostByAlias(codehost.Alias); err == nil {
+			return nil, e.ErrRestoreCodeHost.AddDesc(fmt.Sprintf("alias %s is used by another codehost", codehost.Alias))
+		}
+	}
+	if err := mongodb.NewCodehostColl().RestoreCodeHostByID(id); err != nil {
+		return nil, e.ErrRestoreCodeHost.AddErr(err)
+	}
+	invalidateCodeHostCache(id)
+	recordCodeHostAudit(id, AuditActionRestore, user, nil, nil, logger)
+	return mongodb.NewCodehostColl().GetCodeHostByID(id, false)
+}
+
+func UpdateCodeHost(host *models.CodeHost, user string, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	updated, err := updateCodeHost(host, user, logger)
+	observeCodeHostOperation(metricOperationUpdate, host.Type, err)
+	return updated, err
+}
+
+func updateCodeHost(host *models.CodeHost, user string, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	// the codehost is updated only on the revision read by the caller, so that the changes of others, e.g. the
+	// tokens saved by the oauth callback, are never overwritten silently.
+	if host.Revision <= 0 {
+		return nil, codeHostRevisionRequiredError(host.ID, logger)
+	}
+	if err := host.RepoPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	refs, err := resolveCodeHostSecrets(host)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	if err := validateProxy(host); err != nil {
+		return nil, err
+	}
+	if err := validateCACert(host); err != nil {
+		return nil, err
+	}
+	if err := oauth.ValidateExtraAuthParams(host.ExtraAuthParams); err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	if err := normalizeAzureDevOps(host); err != nil {
+		return nil, err
+	}
+	normalizeScope(host)
 	if host.Type == setting.SourceFromGerrit {
-		host.AccessToken = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", host.Username, host.Password)))
+		if err := verifyGerrit(host); err != nil {
+			return nil, err
+		}
+	}
+	if host.Type == setting.SourceFromPerforce {
+		if err := verifyPerforce(host); err != nil {
+			return nil, err
+		}
+	}
+	if usePrivateAccessToken(host) {
+		if err := verifyPrivateAccessToken(host); err != nil {
+			return nil, err
+		}
 	}
 
 	var oldAlias string
 	oldCodeHost, err := mongodb.NewCodehostColl().GetCodeHostByID(host.ID, false)
 	if err == nil {
+		// fail before the secrets are stored, the update is checked by the revision again in case of a race.
+		if host.Revision != oldCodeHost.Revision {
+			return nil, codeHostConflictError(host.ID, logger)
+		}
 		oldAlias = oldCodeHost.Alias
+		// the unchanged credentials keep their references, e.g. the ones returned by GetCodeHost resolved.
+		resolved := *oldCodeHost
+		if oldRefs, err := resolveCodeHostSecrets(&resolved); err == nil {
+			for field, ref := range oldRefs {
+				if _, ok := refs[field]; !ok {
+					refs[field] = ref
+				}
+			}
+		}
 	}
 	if host.Alias != "" && host.Alias != oldAlias {
 		if _, err := mongodb.NewCodehostColl().GetCodeHostByAlias(host.Alias); err == nil {
@@ -150,62 +379,324 @@ func UpdateCodeHost(host *models.CodeHost, _ *zap.SugaredLogger) (*models.CodeHo
 		}
 	}
 
-	return mongodb.NewCodehostColl().UpdateCodeHost(host)
+	stored, err := storeCodeHostSecrets(host, refs)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := mongodb.NewCodehostColl().UpdateCodeHost(stored)
+	if err == mongodb.ErrRevisionConflict {
+		return nil, codeHostConflictError(host.ID, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+	invalidateCodeHostCache(host.ID)
+	// only part of the fields are updated, the saved codehost is compared instead of the arguments.
+	if saved, err := mongodb.NewCodehostColl().GetCodeHostByID(host.ID, false); err == nil {
+		recordCodeHostAudit(host.ID, AuditActionUpdate, user, oldCodeHost, saved, logger)
+	}
+	return updated, nil
+}
+
+// SetCodeHostEnabled enables or disables the codehost, the tokens of the disabled codehost are kept so that
+// it can be used again once it is enabled.
+func SetCodeHostEnabled(id int, enabled bool, reason, user string, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	old, err := mongodb.NewCodehostColl().GetCodeHostByID(id, false)
+	if err != nil {
+		return nil, e.ErrUpdateCodeHostEnabled.AddErr(err)
+	}
+	if err := mongodb.NewCodehostColl().UpdateCodeHostEnabled(id, enabled, reason, user); err != nil {
+		logger.Errorf("failed to set enabled of codehost %d to %t, err: %s", id, enabled, err)
+		return nil, e.ErrUpdateCodeHostEnabled.AddErr(err)
+	}
+	invalidateCodeHostCache(id)
+	saved, err := mongodb.NewCodehostColl().GetCodeHostByID(id, false)
+	if err != nil {
+		return nil, e.ErrUpdateCodeHostEnabled.AddErr(err)
+	}
+	action := AuditActionEnable
+	if !enabled {
+		action = AuditActionDisable
+	}
+	recordCodeHostAudit(id, action, user, old, saved, logger)
+	return saved, nil
+}
+
+func codeHostDisabledError(codeHost *models.CodeHost) error {
+	desc := fmt.Sprintf("codehost %d is disabled", codeHost.ID)
+	if codeHost.DisabledReason != "" {
+		desc = fmt.Sprintf("%s: %s", desc, codeHost.DisabledReason)
+	}
+	return e.NewWithExtras(e.ErrCodeHostDisabled, desc, map[string]interface{}{
+		"id":     codeHost.ID,
+		"reason": codeHost.DisabledReason,
+	})
+}
+
+// normalizeScope shares the codehost not bound to any project, it would be unavailable to all the projects otherwise.
+func normalizeScope(codehost *models.CodeHost) {
+	if len(codehost.Projects) == 0 {
+		codehost.Shared = true
+	}
+}
+
+// UpdateCodeHostByToken saves the tokens of the codehost, they are not saved if the codehost has been updated
+// since the revision of the host.
+func UpdateCodeHostByToken(host *models.CodeHost, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	defer invalidateCodeHostCache(host.ID)
+	saved, err := saveCodeHostToken(host)
+	if err == mongodb.ErrRevisionConflict {
+		return nil, codeHostConflictError(host.ID, logger)
+	}
+	return saved, err
+}
+
+// saveCodeHostToken saves the tokens of the codehost, the access token is put into the secret backend.
+func saveCodeHostToken(host *models.CodeHost) (*models.CodeHost, error) {
+	stored, err := storeCodeHostSecrets(host, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mongodb.NewCodehostColl().UpdateCodeHostByToken(stored); err != nil {
+		return nil, err
+	}
+	host.Revision = stored.Revision
+	return host, nil
+}
+
+// codeHostConflictError returns the latest codehost with the conflict, so that the client can merge its
+// changes into it and try again.
+func codeHostConflictError(id int, logger *zap.SugaredLogger) error {
+	return latestCodeHostError(id, "codehost %d has been modified by others, the latest revision is %d", logger)
+}
+
+// codeHostRevisionRequiredError returns the latest codehost like the conflict, so that the client omitting
+// the revision can apply its changes to the latest one and try again with its revision.
+func codeHostRevisionRequiredError(id int, logger *zap.SugaredLogger) error {
+	return latestCodeHostError(id, "the revision of codehost %d is required to update it, the latest revision is %d", logger)
+}
+
+func latestCodeHostError(id int, format string, logger *zap.SugaredLogger) error {
+	invalidateCodeHostCache(id)
+	current, err := GetCodeHost(id, false, true, logger)
+	if err != nil {
+		return err
+	}
+	return e.NewWithExtras(e.ErrConflict, fmt.Sprintf(format, id, current.Revision), map[string]interface{}{
+		"current": current,
+	})
+}
+
+// GetCodeHost reads the codehost from the in-memory cache unless bypassCache is true, the codehost read from
+// the database refreshes the cache. The codehost is read from the collection instead of the redis cache
+// if bypassCache is true, so that the consumers asking for the latest one never get a stale one.
+func GetCodeHost(id int, ignoreDelete, bypassCache bool, logger *zap.SugaredLogger) (*models.CodeHost, error) {
+	var (
+		codeHost *models.CodeHost
+		ok       bool
+	)
+	if !bypassCache {
+		codeHost, ok = getCodeHostCache().get(id)
+	}
+	if !ok {
+		// the deleted codehosts are cached as well and filtered below.
+		var err error
+		if bypassCache {
+			codeHost, err = mongodb.NewCodehostColl().GetLatestCodeHostByID(id, true)
+		} else {
+			codeHost, err = mongodb.NewCodehostColl().GetCodeHostByID(id, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// the references of the credentials are resolved once the codehost is read, the cache keeps the values.
+		if _, err := resolveCodeHostSecrets(codeHost); err != nil {
+			logger.Errorf("failed to resolve the credentials of codehost %d, err: %s", id, err)
+			return nil, err
+		}
+		getCodeHostCache().set(codeHost)
+	}
+	if !ignoreDelete && codeHost.DeletedAt != 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	// the disabled codehost is returned only if the raw one is asked, it can not be used otherwise.
+	if !ignoreDelete && !codeHost.Enabled {
+		return nil, codeHostDisabledError(codeHost)
+	}
+	if err := ensureInstallationToken(codeHost); err != nil {
+		logger.Warnf("failed to mint the installation token of codehost %d, err: %s", id, err)
+	}
+	return codeHost, nil
+}
+
+// installationTokenLocks makes sure only one installation token is minted for a codehost at a time.
+var installationTokenLocks sync.Map
+
+// ensureInstallationToken mints a new installation token for the codehost authorized by a github app
+// if the current one is about to expire, so that the consumers always get a valid access token.
+func ensureInstallationToken(codeHost *models.CodeHost) error {
+	if codeHost.Type != setting.SourceFromGithub || codeHost.AuthType != types.GitHubAppAuthType || codeHost.DeletedAt != 0 {
+		return nil
+	}
+	deadline := time.Now().Add(systemconfigconfig.TokenRefreshAhead).Unix()
+	if codeHost.AccessToken != "" && codeHost.ExpiresAt > deadline {
+		return nil
+	}
+
+	lockInterface, _ := installationTokenLocks.LoadOrStore(codeHost.ID, &sync.Mutex{})
+	lock := lockInterface.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// the token may have been minted while waiting for the lock.
+	if latest, err := mongodb.NewCodehostColl().GetCodeHostByID(codeHost.ID, false); err == nil && latest.AccessToken != "" && latest.ExpiresAt > deadline {
+		if _, err := resolveCodeHostSecrets(latest); err == nil {
+			codeHost.AccessToken, codeHost.ExpiresAt = latest.AccessToken, latest.ExpiresAt
+			return nil
+		}
+	}
+	if err := mintInstallationToken(codeHost); err != nil {
+		return err
+	}
+	_, err := saveCodeHostToken(codeHost)
+	invalidateCodeHostCache(codeHost.ID)
+	return err
+}
+
+func mintInstallationToken(codeHost *models.CodeHost) error {
+	app, err := oauth.NewGitHubApp(codeHost)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	token, err := app.InstallationToken(codeHost)
+	observeProviderAPI(codeHost.Type, providerAPIInstallationToken, start, err == nil)
+	if err != nil {
+		return err
+	}
+	codeHost.AccessToken = token.AccessToken
+	codeHost.ExpiresAt = token.Expiry.Unix()
+	codeHost.IsReady = "2"
+	return nil
 }
 
-func UpdateCodeHostByToken(host *models.CodeHost, _ *zap.SugaredLogger) (*models.CodeHost, error) {
-	return mongodb.NewCodehostColl().UpdateCodeHostByToken(host)
+// usePrivateAccessToken reports whether the codehost, which supports oauth, is authorized by a
+// personal access token instead.
+func usePrivateAccessToken(codeHost *models.CodeHost) bool {
+	if codeHost.AuthType != types.PrivateAccessTokenAuthType {
+		return false
+	}
+	switch codeHost.Type {
+	case setting.SourceFromGithub, setting.SourceFromGitlab, setting.SourceFromGitee, setting.SourceFromBitbucket, setting.SourceFromGitea, setting.SourceFromAzureDevOps:
+		return true
+	}
+	return false
 }
 
-func GetCodeHost(id int, ignoreDelete bool, _ *zap.SugaredLogger) (*models.CodeHost, error) {
-	return mongodb.NewCodehostColl().GetCodeHostByID(id, ignoreDelete)
+// verifyPrivateAccessToken checks the token against the provider api and uses it as the access token,
+// so that the codehost is ready without the oauth authorization.
+func verifyPrivateAccessToken(codeHost *models.CodeHost) error {
+	if codeHost.PrivateAccessToken == "" {
+		return fmt.Errorf("private access token is empty")
+	}
+	codeHost.AccessToken = ""
+	diagnosis := diagnose(codeHost)
+	if !diagnosis.Valid {
+		return fmt.Errorf("failed to verify the private access token: %s", diagnosis.Message)
+	}
+	codeHost.AccessToken = codeHost.PrivateAccessToken
+	codeHost.RefreshToken = ""
+	codeHost.ExpiresAt = 0
+	codeHost.IsReady = "2"
+	return nil
+}
+
+// verifyGerrit checks the http password or the ssh key of the gerrit account, the codehost is ready once it passes.
+func verifyGerrit(codehost *models.CodeHost) error {
+	diagnosis := diagnose(codehost)
+	if !diagnosis.Valid {
+		return fmt.Errorf("failed to verify the gerrit account: %s, %s", diagnosis.Message, diagnosis.Suggestion)
+	}
+	codehost.AccessToken = gerrit.BasicAuthToken(codehost.Username, codehost.Password)
+	codehost.IsReady = "2"
+	codehost.NotReadyReason = ""
+	return nil
+}
+
+// verifyPerforce logs in p4d with the ticket kept in the private access token or the password of the user, the
+// codehost is ready once it passes.
+func verifyPerforce(codehost *models.CodeHost) error {
+	diagnosis := diagnose(codehost)
+	if !diagnosis.Valid {
+		return fmt.Errorf("failed to verify the perforce account: %s, %s", diagnosis.Message, diagnosis.Suggestion)
+	}
+	codehost.AccessToken = codehost.PrivateAccessToken
+	if codehost.AccessToken == "" {
+		codehost.AccessToken = codehost.Password
+	}
+	codehost.IsReady = "2"
+	codehost.NotReadyReason = ""
+	return nil
+}
+
+// ValidateCodeHost checks the address and the credentials of the codehost against the provider api,
+// nothing is saved.
+func ValidateCodeHost(codehost *models.CodeHost, logger *zap.SugaredLogger) *oauth.Diagnosis {
+	diagnosis := diagnose(codehost)
+	if !diagnosis.Valid {
+		logger.Warnf("codehost %s %s is invalid: %s", codehost.Type, codehost.Address, diagnosis.Message)
+	}
+	return diagnosis
 }
 
 type state struct {
 	CodeHostID  int    `json:"code_host_id"`
 	RedirectURL string `json:"redirect_url"`
+	// CallbackURL is only persisted, it is not carried by the state sent to the provider.
+	CallbackURL string `json:"-"`
+	User        string `json:"user"`
+	// IssuedAt and TTL are in seconds, the state is rejected after it expires.
+	IssuedAt int64 `json:"issued_at"`
+	TTL      int64 `json:"ttl"`
+	// Nonce is persisted when the state is issued and consumed by the first callback carrying it.
+	Nonce string `json:"nonce"`
 }
 
-func AuthCodeHost(redirectURI string, codeHostID int, logger *zap.SugaredLogger) (string, error) {
-	codeHost, err := GetCodeHost(codeHostID, false, logger)
+// AuthCodeHost returns the login url of the provider, the provider redirects back to the callback under
+// the base url the user is visiting.
+func AuthCodeHost(baseURL, redirectURI string, codeHostID int, user string, logger *zap.SugaredLogger) (string, error) {
+	codeHost, err := GetCodeHost(codeHostID, false, true, logger)
 	if err != nil {
 		logger.Errorf("GetCodeHost:%d err:%s", codeHostID, err)
 		return "", err
 	}
-	redirectParsedURL, err := url.Parse(redirectURI)
-	if err != nil {
+	if usePrivateAccessToken(codeHost) || codeHost.AuthType == types.GitHubAppAuthType {
+		return "", fmt.Errorf("codehost %d is authorized by %s, oauth is not required", codeHostID, codeHost.AuthType)
+	}
+	if _, err := url.Parse(redirectURI); err != nil {
 		logger.Errorf("Parse redirectURI:%s err:%s", redirectURI, err)
 		return "", err
 	}
-	redirectParsedURL.Path = callback
-	oauth, err := newOAuth(codeHost.Type, redirectParsedURL.String(), codeHost.ApplicationId, codeHost.ClientSecret, codeHost.Address)
+	callbackURL := strings.TrimSuffix(baseURL, "/") + callback
+	oauth, err := newOAuth(codeHost, callbackURL)
 	if err != nil {
 		logger.Errorf("NewOAuth:%s err:%s", codeHost.Type, err)
 		return "", err
 	}
-	stateStruct := state{
-		CodeHostID:  codeHost.ID,
-		RedirectURL: redirectURI,
-	}
-	bs, err := json.Marshal(stateStruct)
+	stateStr, err := issueState(codeHost.ID, redirectURI, callbackURL, user)
 	if err != nil {
-		logger.Errorf("Marshal err:%s", err)
+		logger.Errorf("issueState err:%s", err)
 		return "", err
 	}
-	return oauth.LoginURL(base64.URLEncoding.EncodeToString(bs)), nil
+	return oauth.LoginURL(stateStr), nil
 }
 
 func HandleCallback(stateStr string, r *http.Request, logger *zap.SugaredLogger) (string, error) {
-	// TODO：validate the code
-	// https://www.jianshu.com/p/c7c8f51713b6
-	decryptedState, err := base64.URLEncoding.DecodeString(stateStr)
+	// the redirect url is not trusted until the state is verified, so the rejected states are not redirected.
+	sta, err := consumeState(stateStr)
 	if err != nil {
-		logger.Errorf("DecodeString err:%s", err)
-		return "", err
-	}
-	var sta state
-	if err := json.Unmarshal(decryptedState, &sta); err != nil {
-		logger.Errorf("Unmarshal err:%s", err)
+		logger.Errorf("consumeState err:%s", err)
+		observeOAuthCallback("", metricResultInvalidState)
 		return "", err
 	}
 	redirectParsedURL, err := url.Parse(sta.RedirectURL)
@@ -213,52 +704,75 @@ func HandleCallback(stateStr string, r *http.Request, logger *zap.SugaredLogger)
 		logger.Errorf("ParseURL:%s err:%s", sta.RedirectURL, err)
 		return "", err
 	}
-	codehost, err := GetCodeHost(sta.CodeHostID, false, logger)
+	codehost, err := GetCodeHost(sta.CodeHostID, false, true, logger)
 	if err != nil {
+		observeOAuthCallback("", metricResultFailure)
 		return handle(redirectParsedURL, err)
 	}
-	callbackURL := url.URL{
-		Scheme: redirectParsedURL.Scheme,
-		Host:   redirectParsedURL.Host,
-		Path:   callback,
+	// the states issued before the callback url is persisted are sent with the callback on the host of
+	// the redirect url.
+	callbackURL := sta.CallbackURL
+	if callbackURL == "" {
+		callbackURL = (&url.URL{Scheme: redirectParsedURL.Scheme, Host: redirectParsedURL.Host, Path: callback}).String()
 	}
-	o, err := newOAuth(codehost.Type, callbackURL.String(), codehost.ApplicationId, codehost.ClientSecret, codehost.Address)
+	o, err := newOAuth(codehost, callbackURL)
 	if err != nil {
 		logger.Errorf("newOAuth err:%s", err)
+		observeOAuthCallback(codehost.Type, metricResultFailure)
 		return handle(redirectParsedURL, err)
 	}
+	start := time.Now()
 	token, err := o.HandleCallback(r, codehost)
+	observeProviderAPI(codehost.Type, providerAPITokenExchange, start, err == nil)
 	if err != nil {
 		logger.Errorf("HandleCallback err:%s", err)
+		observeOAuthCallback(codehost.Type, metricResultFailure)
 		return handle(redirectParsedURL, err)
 	}
+	// the codehost may be shared with the cache, keep a copy to record the changes.
+	oldCodeHost := *codehost
 	codehost.AccessToken = token.AccessToken
 	codehost.RefreshToken = token.RefreshToken
+	if !token.Expiry.IsZero() {
+		codehost.ExpiresAt = token.Expiry.Unix()
+	}
 	if _, err := UpdateCodeHostByToken(codehost, logger); err != nil {
 		logger.Errorf("UpdateCodeHostByToken err:%s", err)
+		observeOAuthCallback(codehost.Type, metricResultFailure)
 		return handle(redirectParsedURL, err)
 	}
+	observeOAuthCallback(codehost.Type, metricResultSuccess)
+	recordCodeHostAudit(codehost.ID, AuditActionAuthorize, sta.User, &oldCodeHost, codehost, logger)
 	logger.Infof("success update codehost ready status")
 	return handle(redirectParsedURL, nil)
 }
 
-func newOAuth(provider, callbackURL, clientID, clientSecret, address string) (*oauth.OAuth, error) {
-	switch provider {
+// newOAuth returns the oauth provider of the codehost, the scopes and the extra auth params of the codehost
+// are applied to the login url.
+func newOAuth(c *models.CodeHost, callbackURL string) (oauth.Provider, error) {
+	clientID, clientSecret, address := c.ApplicationId, c.ClientSecret, c.Address
+	opts := oauth.NewOptions(c)
+	switch c.Type {
 	case systemconfig.GitHubProvider:
 		return oauth.New(callbackURL, clientID, clientSecret, []string{"repo", "user"}, oauth2.Endpoint{
 			AuthURL:  address + "/login/oauth/authorize",
 			TokenURL: address + "/login/oauth/access_token",
-		}), nil
+		}, opts), nil
 	case systemconfig.GitLabProvider:
 		return oauth.New(callbackURL, clientID, clientSecret, []string{"api", "read_user"}, oauth2.Endpoint{
 			AuthURL:  address + "/oauth/authorize",
 			TokenURL: address + "/oauth/token",
-		}), nil
+		}, opts), nil
 	case systemconfig.GiteeProvider:
-		return oauth.New(callbackURL, clientID, clientSecret, []string{"projects", "pull_requests", "hook", "groups"}, oauth2.Endpoint{
-			AuthURL:  address + "/oauth/authorize",
-			TokenURL: address + "/oauth/token",
-		}), nil
+		return oauth.NewGitee(callbackURL, clientID, clientSecret, address, opts), nil
+	case systemconfig.GiteaProvider:
+		return oauth.NewGitea(callbackURL, clientID, clientSecret, address, opts), nil
+	case systemconfig.BitbucketProvider:
+		return oauth.NewBitbucket(callbackURL, clientID, clientSecret, opts), nil
+	case systemconfig.BitbucketServerProvider:
+		return oauth.NewBitbucketServer(callbackURL, clientID, clientSecret, address, opts), nil
+	case systemconfig.AzureDevOpsProvider:
+		return oauth.NewAzureDevOps(callbackURL, clientID, clientSecret, opts), nil
 	}
 	return nil, errors.New("illegal provider")
 }
@@ -271,3 +785,49 @@ func handle(url *url.URL, err error) (string, error) {
 	}
 	return url.String(), nil
 }
+
+func validateProxy(c *models.CodeHost) error {
+	if c.ProxyURL == "" {
+		return nil
+	}
+	proxyURL, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy url %s: %v", c.ProxyURL, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("the scheme of proxy url %s should be http, https or socks5", c.ProxyURL)
+	}
+	if proxyURL.Host == "" {
+		return fmt.Errorf("the host of proxy url %s is empty", c.ProxyURL)
+	}
+	return nil
+}
+
+func validateCACert(c *models.CodeHost) error {
+	if c.CACert == "" {
+		return nil
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM([]byte(c.CACert)) {
+		return fmt.Errorf("no valid PEM encoded certificate is found in the ca cert")
+	}
+	return nil
+}
+
+// normalizeAzureDevOps requires the organization of the azure devops codehosts, the repos are under
+// https://dev.azure.com/{organization} by default.
+func normalizeAzureDevOps(c *models.CodeHost) error {
+	if c.Type != setting.SourceFromAzureDevOps {
+		return nil
+	}
+	c.AzureOrganization = strings.Trim(c.AzureOrganization, "/ ")
+	if c.AzureOrganization == "" {
+		return fmt.Errorf("the organization of azure devops is empty")
+	}
+	c.Address = strings.TrimSuffix(c.Address, "/")
+	if c.Address == "" {
+		c.Address = azureDevOpsAddress
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	systemconfigconfig "github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+)
+
+// codeHostCache keeps the recently used codehosts in memory, since GetCodeHost is called for nearly every
+// workflow trigger. The least recently used codehost is evicted when the cache is full. The cache is local
+// to the process, so the other replicas may read a stale codehost until the ttl expires, consumers which
+// need the latest codehost bypass the cache.
+type codeHostCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[int]*list.Element
+}
+
+type codeHostCacheEntry struct {
+	id       int
+	codeHost models.CodeHost
+	expireAt time.Time
+}
+
+var (
+	localCodeHostCache     *codeHostCache
+	localCodeHostCacheOnce sync.Once
+)
+
+func getCodeHostCache() *codeHostCache {
+	localCodeHostCacheOnce.Do(func() {
+		localCodeHostCache = newCodeHostCache(systemconfigconfig.CodeHostCacheSize(), systemconfigconfig.CodeHostCacheTTL())
+	})
+	return localCodeHostCache
+}
+
+func newCodeHostCache(size int, ttl time.Duration) *codeHostCache {
+	return &codeHostCache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[int]*list.Element),
+	}
+}
+
+// get returns a copy of the cached codehost, so the callers are free to modify it.
+func (c *codeHostCache) get(id int) (*models.CodeHost, bool) {
+	if c.size <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*codeHostCacheEntry)
+	if time.Now().After(entry.expireAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	codeHost := entry.codeHost
+	return &codeHost, true
+}
+
+func (c *codeHostCache) set(codeHost *models.CodeHost) {
+	if c.size <= 0 || codeHost == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &codeHostCacheEntry{id: codeHost.ID, codeHost: *codeHost, expireAt: time.Now().Add(c.ttl)}
+	if elem, ok := c.items[codeHost.ID]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[codeHost.ID] = c.ll.PushFront(entry)
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *codeHostCache) invalidate(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[id]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *codeHostCache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*codeHostCacheEntry).id)
+}
+
+// invalidateCodeHostCache should be called after the codehost is written.
+func invalidateCodeHostCache(id int) {
+	getCodeHostCache().invalidate(id)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+)
+
+func TestCodeHostCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCodeHostCache(2, time.Minute)
+	c.set(&models.CodeHost{ID: 1})
+	c.set(&models.CodeHost{ID: 2})
+	if _, ok := c.get(1); !ok {
+		t.Fatalf("Expected codehost 1 to be cached")
+	}
+	c.set(&models.CodeHost{ID: 3})
+
+	if _, ok := c.get(2); ok {
+		t.Errorf("Expected codehost 2 to be evicted")
+	}
+	for _, id := range []int{1, 3} {
+		if _, ok := c.get(id); !ok {
+			t.Errorf("Expected codehost %d to be cached", id)
+		}
+	}
+}
+
+func TestCodeHostCacheExpiresAndInvalidates(t *testing.T) {
+	c := newCodeHostCache(10, time.Millisecond)
+	c.set(&models.CodeHost{ID: 1})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.get(1); ok {
+		t.Errorf("Expected codehost 1 to be expired")
+	}
+
+	c = newCodeHostCache(10, time.Minute)
+	c.set(&models.CodeHost{ID: 1})
+	c.invalidate(1)
+	if _, ok := c.get(1); ok {
+		t.Errorf("Expected codehost 1 to be invalidated")
+	}
+}
+
+func TestCodeHostCacheReturnsCopies(t *testing.T) {
+	c := newCodeHostCache(10, time.Minute)
+	c.set(&models.CodeHost{ID: 1, AccessToken: "token"})
+
+	cached, _ := c.get(1)
+	cached.AccessToken = "changed"
+	if cached, _ = c.get(1); cached.AccessToken != "token" {
+		t.Errorf("Expected the cached access token to be <token> but got <%s>", cached.AccessToken)
+	}
+}
+
+func TestCodeHostCacheDisabled(t *testing.T) {
+	c := newCodeHostCache(0, time.Minute)
+	c.set(&models.CodeHost{ID: 1})
+	if _, ok := c.get(1); ok {
+		t.Errorf("Expected nothing to be cached when the size is 0")
+	}
+}
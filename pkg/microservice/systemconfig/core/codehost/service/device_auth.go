@@ -0,0 +1,226 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/internal/oauth"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	// the defaults of RFC 8628 if the provider does not return them.
+	defaultDeviceAuthInterval  = 5
+	defaultDeviceAuthExpiresIn = 900
+	// the interval is increased by 5 seconds when the provider asks to slow down.
+	deviceAuthSlowDownStep = 5
+)
+
+// newDeviceOAuth returns the device authorization provider of the codehost, only github and gitlab support it.
+func newDeviceOAuth(c *models.CodeHost) (oauth.DeviceProvider, error) {
+	address := strings.TrimSuffix(c.Address, "/")
+	opts := oauth.NewOptions(c)
+	switch c.Type {
+	case systemconfig.GitHubProvider:
+		return oauth.NewDevice(c.ApplicationId, c.ClientSecret, []string{"repo", "user"},
+			address+"/login/device/code", address+"/login/oauth/access_token", opts), nil
+	case systemconfig.GitLabProvider:
+		return oauth.NewDevice(c.ApplicationId, c.ClientSecret, []string{"api", "read_user"},
+			address+"/oauth/authorize_device", address+"/oauth/token", opts), nil
+	}
+	return nil, fmt.Errorf("device authorization is not supported by %s", c.Type)
+}
+
+// StartDeviceAuth starts the device authorization of the codehost for the installations which can not receive
+// the oauth callback. The user authorizes zadig by entering the user code at the verification uri, then the
+// token is stored by the device auth poller.
+func StartDeviceAuth(id int, user string, logger *zap.SugaredLogger) (*models.DeviceAuth, error) {
+	codeHost, err := GetCodeHost(id, false, true, logger)
+	if err != nil {
+		logger.Errorf("GetCodeHost:%d err:%s", id, err)
+		return nil, err
+	}
+	if usePrivateAccessToken(codeHost) || codeHost.AuthType == types.GitHubAppAuthType {
+		return nil, fmt.Errorf("codehost %d is authorized by %s, oauth is not required", id, codeHost.AuthType)
+	}
+	o, err := newDeviceOAuth(codeHost)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	authorization, err := o.DeviceAuth(codeHost)
+	observeProviderAPI(codeHost.Type, providerAPIDeviceAuth, start, err == nil)
+	if err != nil {
+		logger.Errorf("failed to start the device authorization of codehost %d, err: %s", id, err)
+		return nil, err
+	}
+	deviceCode, err := crypto.EncryptSecret(authorization.DeviceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	interval, expiresIn := authorization.Interval, authorization.ExpiresIn
+	if interval <= 0 {
+		interval = defaultDeviceAuthInterval
+	}
+	if expiresIn <= 0 {
+		expiresIn = defaultDeviceAuthExpiresIn
+	}
+	// the device codes issued before are abandoned, only the latest one is polled.
+	if err := mongodb.NewDeviceAuthColl().CancelPending(id, "a new device authorization is started"); err != nil {
+		logger.Warnf("failed to cancel the pending device authorizations of codehost %d, err: %s", id, err)
+	}
+	expiresAt := start.Add(time.Duration(expiresIn) * time.Second)
+	auth := &models.DeviceAuth{
+		CodeHostID:              id,
+		DeviceCode:              deviceCode,
+		UserCode:                authorization.UserCode,
+		VerificationURI:         authorization.VerificationURI,
+		VerificationURIComplete: authorization.VerificationURIComplete,
+		Interval:                interval,
+		Status:                  models.DeviceAuthStatusPending,
+		User:                    user,
+		CreatedAt:               start.Unix(),
+		ExpiresAt:               expiresAt.Unix(),
+		NextPollAt:              start.Unix() + interval,
+		CleanupAt:               expiresAt.Add(config.DeviceAuthRetention),
+	}
+	if err := mongodb.NewDeviceAuthColl().Create(auth); err != nil {
+		logger.Errorf("failed to save the device authorization of codehost %d, err: %s", id, err)
+		return nil, err
+	}
+	return auth, nil
+}
+
+// GetDeviceAuth returns the latest device authorization of the codehost, so that the portal can tell when
+// the authorization is completed.
+func GetDeviceAuth(id int, logger *zap.SugaredLogger) (*models.DeviceAuth, error) {
+	auth, err := mongodb.NewDeviceAuthColl().GetLatest(id)
+	if err != nil {
+		logger.Errorf("failed to find the device authorization of codehost %d, err: %s", id, err)
+		return nil, err
+	}
+	// it is not finished by the poller yet.
+	if auth.Status == models.DeviceAuthStatusPending && time.Now().Unix() >= auth.ExpiresAt {
+		auth.Status = models.DeviceAuthStatusExpired
+	}
+	return auth, nil
+}
+
+// StartDeviceAuthPoller exchanges the device codes of the pending device authorizations for the tokens.
+func StartDeviceAuthPoller(ctx context.Context, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(config.DeviceAuthPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				auth, err := mongodb.NewDeviceAuthColl().ClaimDue()
+				if err != nil {
+					if err != mongo.ErrNoDocuments {
+						logger.Errorf("failed to claim the device authorizations, err: %s", err)
+					}
+					break
+				}
+				pollDeviceAuth(auth, logger)
+			}
+		}
+	}
+}
+
+func pollDeviceAuth(auth *models.DeviceAuth, logger *zap.SugaredLogger) {
+	coll := mongodb.NewDeviceAuthColl()
+	finish := func(status models.DeviceAuthStatus, reason string) {
+		if err := coll.Finish(auth.ID, status, reason); err != nil {
+			logger.Errorf("failed to finish the device authorization of codehost %d, err: %s", auth.CodeHostID, err)
+		}
+	}
+
+	if time.Now().Unix() >= auth.ExpiresAt {
+		finish(models.DeviceAuthStatusExpired, "the device code has expired")
+		return
+	}
+	codehost, err := mongodb.NewCodehostColl().GetCodeHostByID(auth.CodeHostID, false)
+	if err != nil {
+		finish(models.DeviceAuthStatusFailed, fmt.Sprintf("failed to find the codehost: %s", err))
+		return
+	}
+	if _, err := resolveCodeHostSecrets(codehost); err != nil {
+		logger.Warnf("failed to resolve the credentials of codehost %d, err: %s", codehost.ID, err)
+		return
+	}
+	o, err := newDeviceOAuth(codehost)
+	if err != nil {
+		finish(models.DeviceAuthStatusFailed, err.Error())
+		return
+	}
+	deviceCode, err := crypto.DecryptSecret(auth.DeviceCode)
+	if err != nil {
+		finish(models.DeviceAuthStatusFailed, fmt.Sprintf("failed to decrypt the device code: %s", err))
+		return
+	}
+
+	start := time.Now()
+	token, err := o.PollDeviceToken(codehost, deviceCode)
+	switch err {
+	case nil:
+		observeProviderAPI(codehost.Type, providerAPITokenExchange, start, true)
+	case oauth.ErrAuthorizationPending:
+		return
+	case oauth.ErrSlowDown:
+		if err := coll.SlowDown(auth.ID, auth.Interval+deviceAuthSlowDownStep); err != nil {
+			logger.Warnf("failed to slow down the device authorization of codehost %d, err: %s", auth.CodeHostID, err)
+		}
+		return
+	default:
+		observeProviderAPI(codehost.Type, providerAPITokenExchange, start, false)
+		logger.Warnf("device authorization of codehost %d failed, err: %s", auth.CodeHostID, err)
+		finish(models.DeviceAuthStatusFailed, err.Error())
+		return
+	}
+
+	// the codehost may be shared with the cache, keep a copy to record the changes.
+	oldCodeHost := *codehost
+	codehost.AccessToken = token.AccessToken
+	codehost.RefreshToken = token.RefreshToken
+	if !token.Expiry.IsZero() {
+		codehost.ExpiresAt = token.Expiry.Unix()
+	}
+	if _, err := UpdateCodeHostByToken(codehost, logger); err != nil {
+		logger.Errorf("UpdateCodeHostByToken err:%s", err)
+		finish(models.DeviceAuthStatusFailed, fmt.Sprintf("failed to save the token: %s", err))
+		return
+	}
+	recordCodeHostAudit(codehost.ID, AuditActionAuthorize, auth.User, &oldCodeHost, codehost, logger)
+	finish(models.DeviceAuthStatusSucceeded, "")
+	logger.Infof("codehost %d is authorized by the device code", codehost.ID)
+}
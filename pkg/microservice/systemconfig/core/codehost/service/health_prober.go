@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// StartHealthProber probes the ready codehosts with their credentials periodically, so that the
+// broken integrations are found before the builds fail.
+func StartHealthProber(ctx context.Context, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(config.CodeHostHealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		probeCodeHosts(logger)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func probeCodeHosts(logger *zap.SugaredLogger) {
+	// the disabled codehosts are not accessed, e.g. during the maintenance of the provider.
+	enabled := true
+	codehosts, err := mongodb.NewCodehostColl().List(&mongodb.ListArgs{Enabled: &enabled})
+	if err != nil {
+		logger.Errorf("failed to list codehosts, err: %s", err)
+		return
+	}
+	for _, codehost := range codehosts {
+		// the codehosts of other type have no api to probe.
+		if codehost.IsReady != "2" || codehost.Type == setting.SourceFromOther {
+			continue
+		}
+		if _, err := resolveCodeHostSecrets(codehost); err != nil {
+			logger.Warnf("failed to resolve the credentials of codehost %d, err: %s", codehost.ID, err)
+			continue
+		}
+		if _, err := probeCodeHost(codehost); err != nil {
+			logger.Warnf("failed to save the health of codehost %d, err: %s", codehost.ID, err)
+		}
+	}
+}
+
+func probeCodeHost(codehost *models.CodeHost) (*models.CodeHostHealth, error) {
+	start := time.Now()
+	diagnosis := diagnose(codehost)
+	health := &models.CodeHostHealth{
+		Healthy:       diagnosis.Valid,
+		Reachable:     diagnosis.Reachable,
+		Authenticated: diagnosis.Authenticated,
+		StatusCode:    diagnosis.StatusCode,
+		Latency:       time.Since(start).Milliseconds(),
+		LastCheckedAt: start.Unix(),
+	}
+	if !diagnosis.Valid {
+		health.Error = diagnosis.Message
+		health.Suggestion = diagnosis.Suggestion
+	}
+	defer invalidateCodeHostCache(codehost.ID)
+	return health, mongodb.NewCodehostColl().UpdateCodeHostHealth(codehost.ID, health)
+}
+
+// GetCodeHostHealth returns the result of the latest probe, the codehost is probed at once if refresh
+// is true or it has never been probed.
+func GetCodeHostHealth(id int, refresh bool, logger *zap.SugaredLogger) (*models.CodeHostHealth, error) {
+	codehost, err := mongodb.NewCodehostColl().GetCodeHostByID(id, false)
+	if err != nil {
+		logger.Errorf("failed to find codehost %d, err: %s", id, err)
+		return nil, err
+	}
+	if codehost.Health != nil && !refresh {
+		return codehost.Health, nil
+	}
+	if _, err := resolveCodeHostSecrets(codehost); err != nil {
+		logger.Errorf("failed to resolve the credentials of codehost %d, err: %s", id, err)
+		return nil, err
+	}
+	health, err := probeCodeHost(codehost)
+	if err != nil {
+		logger.Warnf("failed to save the health of codehost %d, err: %s", id, err)
+	}
+	return health, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/internal/oauth"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+)
+
+const (
+	metricResultSuccess = "success"
+	metricResultFailure = "failure"
+	// metricResultInvalidState is the result of the oauth callbacks rejected before the codehost is known.
+	metricResultInvalidState = "invalid_state"
+
+	metricOperationCreate = "create"
+	metricOperationUpdate = "update"
+
+	providerAPIDiagnose          = "diagnose"
+	providerAPITokenExchange     = "token_exchange"
+	providerAPITokenRefresh      = "token_refresh"
+	providerAPIInstallationToken = "installation_token"
+	providerAPIWebhook           = "webhook"
+	providerAPIDeviceAuth        = "device_auth"
+)
+
+var (
+	codeHostOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "zadig",
+		Subsystem: "codehost",
+		Name:      "operations_total",
+		Help:      "The number of the codehost operations by the operation, the codehost type and the result.",
+	}, []string{"operation", "type", "result"})
+
+	oauthCallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "zadig",
+		Subsystem: "codehost",
+		Name:      "oauth_callbacks_total",
+		Help:      "The number of the oauth callbacks by the codehost type and the result.",
+	}, []string{"type", "result"})
+
+	tokenRefreshFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "zadig",
+		Subsystem: "codehost",
+		Name:      "token_refresh_failures_total",
+		Help:      "The number of the failed token refreshes by the codehost type, permanent is true if the codehost must be authorized again.",
+	}, []string{"type", "permanent"})
+
+	providerAPIDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "zadig",
+		Subsystem: "codehost",
+		Name:      "provider_api_duration_seconds",
+		Help:      "The latency of the provider api calls by the codehost type, the api and the result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type", "api", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(codeHostOperations, oauthCallbacks, tokenRefreshFailures, providerAPIDuration)
+}
+
+func metricResult(ok bool) string {
+	if ok {
+		return metricResultSuccess
+	}
+	return metricResultFailure
+}
+
+func observeCodeHostOperation(operation, codeHostType string, err error) {
+	codeHostOperations.WithLabelValues(operation, codeHostType, metricResult(err == nil)).Inc()
+}
+
+func observeOAuthCallback(codeHostType, result string) {
+	oauthCallbacks.WithLabelValues(codeHostType, result).Inc()
+}
+
+func observeTokenRefreshFailure(codeHostType string, permanent bool) {
+	p := "false"
+	if permanent {
+		p = "true"
+	}
+	tokenRefreshFailures.WithLabelValues(codeHostType, p).Inc()
+}
+
+func observeProviderAPI(codeHostType, api string, start time.Time, ok bool) {
+	providerAPIDuration.WithLabelValues(codeHostType, api, metricResult(ok)).Observe(time.Since(start).Seconds())
+}
+
+// diagnose checks the codehost against the provider api and records the latency.
+func diagnose(codehost *models.CodeHost) *oauth.Diagnosis {
+	start := time.Now()
+	diagnosis := oauth.Diagnose(codehost)
+	observeProviderAPI(codehost.Type, providerAPIDiagnose, start, diagnosis.Valid)
+	return diagnosis
+}
+
+// instrumentedWebhookManager records the latency of the webhook apis of the provider.
+type instrumentedWebhookManager struct {
+	webhookManager
+	codeHostType string
+}
+
+func (m *instrumentedWebhookManager) create(owner, repo string) (string, error) {
+	start := time.Now()
+	hookID, err := m.webhookManager.create(owner, repo)
+	observeProviderAPI(m.codeHostType, providerAPIWebhook, start, err == nil)
+	return hookID, err
+}
+
+func (m *instrumentedWebhookManager) exists(owner, repo, hookID string) (bool, error) {
+	start := time.Now()
+	exists, err := m.webhookManager.exists(owner, repo, hookID)
+	observeProviderAPI(m.codeHostType, providerAPIWebhook, start, err == nil)
+	return exists, err
+}
+
+func (m *instrumentedWebhookManager) delete(owner, repo, hookID string) error {
+	start := time.Now()
+	err := m.webhookManager.delete(owner, repo, hookID)
+	observeProviderAPI(m.codeHostType, providerAPIWebhook, start, err == nil)
+	return err
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	systemconfigconfig "github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+)
+
+// issueState persists a random nonce and returns the state carrying it, the state expires after
+// OAuthStateTTL.
+func issueState(codeHostID int, redirectURL, callbackURL, user string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	sta := &state{
+		CodeHostID:  codeHostID,
+		RedirectURL: redirectURL,
+		User:        user,
+		IssuedAt:    now.Unix(),
+		TTL:         int64(systemconfigconfig.OAuthStateTTL.Seconds()),
+		Nonce:       hex.EncodeToString(nonce),
+	}
+	if err := mongodb.NewOAuthStateColl().Create(&models.OAuthState{
+		Nonce:       sta.Nonce,
+		CodeHostID:  sta.CodeHostID,
+		RedirectURL: sta.RedirectURL,
+		CallbackURL: callbackURL,
+		User:        sta.User,
+		IssuedAt:    sta.IssuedAt,
+		ExpireAt:    now.Add(systemconfigconfig.OAuthStateTTL),
+	}); err != nil {
+		return "", err
+	}
+	bs, err := json.Marshal(sta)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(bs), nil
+}
+
+// consumeState rejects the expired, replayed or tampered states. The persisted state is returned, it is
+// deleted so that the same callback can not be accepted again.
+func consumeState(stateStr string) (*state, error) {
+	decoded, err := base64.URLEncoding.DecodeString(stateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth state: %s", err)
+	}
+	sta := &state{}
+	if err := json.Unmarshal(decoded, sta); err != nil {
+		return nil, fmt.Errorf("invalid oauth state: %s", err)
+	}
+	if sta.Nonce == "" {
+		return nil, fmt.Errorf("oauth state has no nonce")
+	}
+	if time.Now().Unix() > sta.IssuedAt+sta.TTL {
+		return nil, fmt.Errorf("oauth state is expired")
+	}
+
+	persisted, err := mongodb.NewOAuthStateColl().Consume(sta.Nonce)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("oauth state is expired or already used")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if persisted.CodeHostID != sta.CodeHostID || persisted.RedirectURL != sta.RedirectURL {
+		return nil, fmt.Errorf("oauth state does not match the issued one")
+	}
+	return &state{
+		CodeHostID:  persisted.CodeHostID,
+		RedirectURL: persisted.RedirectURL,
+		CallbackURL: persisted.CallbackURL,
+		User:        persisted.User,
+		IssuedAt:    persisted.IssuedAt,
+		TTL:         sta.TTL,
+		Nonce:       persisted.Nonce,
+	}, nil
+}
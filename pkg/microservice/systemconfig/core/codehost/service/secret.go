@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/internal/secret"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+)
+
+// InitSecretBackend sets the key provider the kms backend encrypts the credentials by and checks the configured
+// backend, it must be done before any codehost is read.
+func InitSecretBackend() error {
+	err := crypto.InitKeyProvider(&crypto.KeyProviderArgs{
+		Provider:     config.EncryptionKeyProvider(),
+		KeyID:        config.EncryptionKeyID(),
+		Region:       config.EncryptionKMSRegion(),
+		VaultAddress: config.VaultAddress(),
+		VaultToken:   config.VaultToken(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init key provider %s: %s", config.EncryptionKeyProvider(), err)
+	}
+	_, err = secret.DefaultStore()
+	return err
+}
+
+// secretRef is a reference saved in the codehost and the credential it points to.
+type secretRef struct {
+	ref   string
+	value string
+}
+
+// backendSecrets returns the credentials of the codehost kept by the secret backend, indexed by the fields.
+func backendSecrets(c *models.CodeHost) map[string]*string {
+	return map[string]*string{
+		"access_token":  &c.AccessToken,
+		"client_secret": &c.ClientSecret,
+		"password":      &c.Password,
+	}
+}
+
+// resolveCodeHostSecrets replaces the references in the codehost by the credentials they point to. The
+// references are returned indexed by the fields, so that they are saved again if the credentials are not changed.
+func resolveCodeHostSecrets(c *models.CodeHost) (map[string]*secretRef, error) {
+	store, err := secret.DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]*secretRef)
+	for field, value := range backendSecrets(c) {
+		if !store.IsReference(*value) {
+			continue
+		}
+		resolved, err := store.Resolve(*value)
+		if err != nil {
+			return nil, err
+		}
+		refs[field] = &secretRef{ref: *value, value: resolved}
+		*value = resolved
+	}
+	return refs, nil
+}
+
+// storeCodeHostSecrets returns a copy of the codehost to be saved, whose credentials are put into the secret
+// backend and replaced by the references. A credential equal to the one refs points to keeps the reference.
+func storeCodeHostSecrets(c *models.CodeHost, refs map[string]*secretRef) (*models.CodeHost, error) {
+	store, err := secret.DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	stored := *c
+	for field, value := range backendSecrets(&stored) {
+		if ref, ok := refs[field]; ok && ref.value == *value {
+			*value = ref.ref
+			continue
+		}
+		ref, err := store.Put(stored.ID, field, *value)
+		if err != nil {
+			return nil, err
+		}
+		*value = ref
+	}
+	return &stored, nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/config"
+	systemconfigconfig "github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// codeHostSettingsPath is the page of the codehosts in the portal, the users are redirected to it after
+// the authorization.
+const codeHostSettingsPath = "/v1/system/integration"
+
+// TokenAlert tells the admins that the token of a codehost is about to expire or can not be refreshed,
+// the codehost stops working until it is authorized again with AuthURL.
+type TokenAlert struct {
+	CodeHostID int    `json:"codehost_id"`
+	Type       string `json:"type"`
+	Alias      string `json:"alias"`
+	Address    string `json:"address"`
+	Reason     string `json:"reason"`
+	// ExpiresAt is when the token expires, it is 0 if the token has been rejected by the provider.
+	ExpiresAt int64  `json:"expires_at"`
+	AuthURL   string `json:"auth_url"`
+}
+
+var (
+	tokenAlertLock    sync.RWMutex
+	tokenAlertHandler func(alert *TokenAlert)
+)
+
+// RegisterTokenAlertHandler registers the function to deliver the token alerts, e.g. through the IM
+// integrations of aslan. The alerts are only logged if no handler is registered.
+func RegisterTokenAlertHandler(handler func(alert *TokenAlert)) {
+	tokenAlertLock.Lock()
+	defer tokenAlertLock.Unlock()
+
+	tokenAlertHandler = handler
+}
+
+func raiseTokenAlert(codehost *models.CodeHost, reason string, expiresAt int64, logger *zap.SugaredLogger) {
+	alert := &TokenAlert{
+		CodeHostID: codehost.ID,
+		Type:       codehost.Type,
+		Alias:      codehost.Alias,
+		Address:    codehost.Address,
+		Reason:     reason,
+		ExpiresAt:  expiresAt,
+		AuthURL:    reauthURL(codehost),
+	}
+	logger.Warnf("token alert of codehost %d: %s", codehost.ID, reason)
+
+	tokenAlertLock.RLock()
+	handler := tokenAlertHandler
+	tokenAlertLock.RUnlock()
+	if handler != nil {
+		handler(alert)
+	}
+}
+
+// reauthURL returns the link to authorize the codehost again. The state of the oauth flow expires in minutes,
+// so the link points to the endpoint of AuthCodeHost which issues the state when it is clicked. The codehosts
+// authorized without oauth are updated in the portal.
+func reauthURL(codehost *models.CodeHost) string {
+	address := strings.TrimSuffix(config.ExternalBaseURL(), "/")
+	if usePrivateAccessToken(codehost) || codehost.AuthType == types.GitHubAppAuthType {
+		return address + codeHostSettingsPath
+	}
+	return fmt.Sprintf("%s/api/directory/codehosts/%d/auth?redirect_url=%s", address, codehost.ID, url.QueryEscape(address+codeHostSettingsPath))
+}
+
+// checkTokenExpiry alerts once for the tokens which can not be refreshed and expire soon, e.g. the oauth
+// tokens without a refresh token. A new token expires later, so it is alerted again before its own expiry.
+func checkTokenExpiry(codehost *models.CodeHost, logger *zap.SugaredLogger) {
+	expiresAt := codehost.ExpiresAt
+	if expiresAt == 0 {
+		return
+	}
+	notifyFrom := expiresAt - int64(systemconfigconfig.TokenExpiryNotifyAhead.Seconds())
+	if time.Now().Unix() < notifyFrom || codehost.TokenAlertedAt >= notifyFrom {
+		return
+	}
+
+	reason := fmt.Sprintf("the access token expires at %s and can not be refreshed automatically, please authorize again",
+		time.Unix(expiresAt, 0).Format("2006-01-02 15:04:05"))
+	raiseTokenAlert(codehost, reason, expiresAt, logger)
+	if err := mongodb.NewCodehostColl().UpdateCodeHostTokenAlertedAt(codehost.ID, time.Now().Unix()); err != nil {
+		logger.Errorf("failed to save the token alert of codehost %d, err: %s", codehost.ID, err)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+func TestReauthURL(t *testing.T) {
+	viper.Set(setting.ENVSystemAddress, "https://zadig.example.com/")
+	defer viper.Set(setting.ENVSystemAddress, "")
+
+	oauth := &models.CodeHost{ID: 3, Type: setting.SourceFromGitlab}
+	expected := "https://zadig.example.com/api/directory/codehosts/3/auth?redirect_url=https%3A%2F%2Fzadig.example.com%2Fv1%2Fsystem%2Fintegration"
+	if got := reauthURL(oauth); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	token := &models.CodeHost{ID: 4, Type: setting.SourceFromGithub, AuthType: types.PrivateAccessTokenAuthType}
+	if got := reauthURL(token); got != "https://zadig.example.com/v1/system/integration" {
+		t.Errorf("Expected the portal page for the private token, got %s", got)
+	}
+}
+
+func TestRaiseTokenAlert(t *testing.T) {
+	var alerts []*TokenAlert
+	RegisterTokenAlertHandler(func(alert *TokenAlert) {
+		alerts = append(alerts, alert)
+	})
+	defer RegisterTokenAlertHandler(nil)
+
+	raiseTokenAlert(&models.CodeHost{ID: 5, Alias: "gitlab", Type: setting.SourceFromGitlab}, "refresh token is revoked", 0, zap.NewNop().Sugar())
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(alerts))
+	}
+	if alerts[0].CodeHostID != 5 || alerts[0].Reason != "refresh token is revoked" || alerts[0].AuthURL == "" {
+		t.Errorf("Unexpected alert %+v", alerts[0])
+	}
+}
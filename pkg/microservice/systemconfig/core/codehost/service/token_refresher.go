@@ -0,0 +1,168 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/config"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/git/gitlab"
+)
+
+// StartTokenRefresher refreshes the oauth tokens of the codehosts before they expire, a codehost is
+// marked as not ready and the admins are alerted if its token can not be refreshed any more.
+func StartTokenRefresher(ctx context.Context, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(config.TokenRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// the disabled codehosts are not accessed, e.g. during the maintenance of the provider.
+			enabled := true
+			codehosts, err := mongodb.NewCodehostColl().List(&mongodb.ListArgs{Enabled: &enabled})
+			if err != nil {
+				logger.Errorf("failed to list codehosts, err: %s", err)
+				continue
+			}
+			deadline := time.Now().Add(config.TokenRefreshAhead).Unix()
+			for _, codehost := range codehosts {
+				if codehost.IsReady != "2" {
+					continue
+				}
+				if codehost.RefreshToken == "" {
+					checkTokenExpiry(codehost, logger)
+					continue
+				}
+				if expiresAt := tokenExpiresAt(codehost); expiresAt == 0 || expiresAt > deadline {
+					continue
+				}
+				if err := refreshCodeHostToken(codehost.ID, logger); err != nil {
+					logger.Warnf("failed to refresh the token of codehost %d, err: %s", codehost.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// tokenExpiresAt returns when the token of the codehost expires, 0 is returned if it never expires.
+func tokenExpiresAt(codehost *models.CodeHost) int64 {
+	// the gitlab tokens refreshed on demand update updated_at only.
+	if codehost.ExpiresAt > codehost.UpdatedAt {
+		return codehost.ExpiresAt
+	}
+	if codehost.Type == systemconfig.GitLabProvider {
+		return codehost.UpdatedAt + int64(config.GitLabTokenLifetime.Seconds())
+	}
+	return codehost.ExpiresAt
+}
+
+func refreshCodeHostToken(id int, logger *zap.SugaredLogger) error {
+	// share the lock with the on demand refreshing of gitlab, the refresh token can only be used once.
+	lockInterface, _ := gitlab.CodeHostLockMap.LoadOrStore(id, &sync.RWMutex{})
+	lock := lockInterface.(*sync.RWMutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// the other replicas refresh the tokens as well, the claimed codehost is read from the collection
+	// instead of the cache so that the token refreshed while waiting for the lock is seen.
+	codehost, err := mongodb.NewCodehostColl().ClaimTokenRefresh(id, config.TokenRefreshLease)
+	if err == mongo.ErrNoDocuments {
+		logger.Debugf("the token of codehost %d is being refreshed by another replica", id)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := mongodb.NewCodehostColl().ReleaseTokenRefresh(id); err != nil {
+			logger.Warnf("failed to release the token refreshing of codehost %d, err: %s", id, err)
+		}
+	}()
+	if _, err := resolveCodeHostSecrets(codehost); err != nil {
+		return err
+	}
+	expiresAt := tokenExpiresAt(codehost)
+	if expiresAt > time.Now().Add(config.TokenRefreshAhead).Unix() {
+		return nil
+	}
+
+	o, err := newOAuth(codehost, "")
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	token, err := o.Refresh(codehost)
+	observeProviderAPI(codehost.Type, providerAPITokenRefresh, start, err == nil)
+	if err != nil {
+		// network errors are retried until the token expires, while a rejected refresh token never works again.
+		var retrieveErr *oauth2.RetrieveError
+		permanent := errors.As(err, &retrieveErr) || time.Now().Unix() >= expiresAt
+		if permanent && refreshedByOthers(codehost) {
+			// the refresh token was spent by the on demand refreshing of another replica, the codehost is healthy.
+			logger.Infof("the token of codehost %d has been refreshed by others", id)
+			return nil
+		}
+		observeTokenRefreshFailure(codehost.Type, permanent)
+		if permanent {
+			reason := fmt.Sprintf("failed to refresh the access token, please authorize again: %s", err)
+			if updateErr := mongodb.NewCodehostColl().UpdateCodeHostNotReady(id, reason); updateErr != nil {
+				logger.Errorf("failed to mark codehost %d as not ready, err: %s", id, updateErr)
+			}
+			invalidateCodeHostCache(id)
+			raiseTokenAlert(codehost, reason, 0, logger)
+		}
+		return err
+	}
+
+	codehost.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		codehost.RefreshToken = token.RefreshToken
+	}
+	codehost.ExpiresAt = 0
+	if !token.Expiry.IsZero() {
+		codehost.ExpiresAt = token.Expiry.Unix()
+	}
+	_, err = saveCodeHostToken(codehost)
+	invalidateCodeHostCache(id)
+	if err != nil {
+		return err
+	}
+	logger.Infof("the token of codehost %d is refreshed", id)
+	return nil
+}
+
+// refreshedByOthers reports whether the refresh token of the codehost has been replaced since it was read.
+func refreshedByOthers(codehost *models.CodeHost) bool {
+	latest, err := mongodb.NewCodehostColl().GetLatestCodeHostByID(codehost.ID, false)
+	if err != nil {
+		return false
+	}
+	return latest.RefreshToken != "" && latest.RefreshToken != codehost.RefreshToken
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/pbkdf2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	codeHostBundleVersion = 1
+	// bundleCheckText is encrypted in the bundle to tell whether the passphrase is correct on import.
+	bundleCheckText       = "zadig-codehost-bundle"
+	bundleKeyIterations   = 100000
+	importedAliasSuffix   = "-imported"
+	maxImportedAliasTries = 100
+)
+
+type ImportStrategy string
+
+const (
+	ImportStrategySkip      ImportStrategy = "skip"
+	ImportStrategyOverwrite ImportStrategy = "overwrite"
+	ImportStrategyRename    ImportStrategy = "rename"
+)
+
+// CodeHostBundle is the codehosts exported from a zadig installation. The secrets are encrypted by the key
+// derived from the passphrase if it is given on export, they are left out otherwise.
+type CodeHostBundle struct {
+	Version    int                `json:"version"`
+	ExportedAt int64              `json:"exported_at"`
+	Salt       string             `json:"salt,omitempty"`
+	Check      string             `json:"check,omitempty"`
+	CodeHosts  []*models.CodeHost `json:"codehosts"`
+}
+
+type ImportCodeHostResult struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Alias   string `json:"alias,omitempty"`
+	// Action is one of created, overwritten, renamed, skipped and failed.
+	Action string `json:"action"`
+	ID     int    `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ExportCodeHosts returns the bundle of all the codehosts not deleted, it is encoded as yaml if format is
+// yaml and as json otherwise.
+func ExportCodeHosts(passphrase, format string, logger *zap.SugaredLogger) ([]byte, error) {
+	codeHosts, err := mongodb.NewCodehostColl().List(&mongodb.ListArgs{})
+	if err != nil {
+		logger.Errorf("failed to list codehosts, err: %s", err)
+		return nil, e.ErrExportCodeHost.AddErr(err)
+	}
+
+	bundle := &CodeHostBundle{
+		Version:    codeHostBundleVersion,
+		ExportedAt: time.Now().Unix(),
+		CodeHosts:  make([]*models.CodeHost, 0, len(codeHosts)),
+	}
+	var cipher *crypto.Aes
+	if passphrase != "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, e.ErrExportCodeHost.AddErr(err)
+		}
+		bundle.Salt = hex.EncodeToString(salt)
+		if cipher, err = bundleCipher(passphrase, salt); err != nil {
+			return nil, e.ErrExportCodeHost.AddErr(err)
+		}
+		if bundle.Check, err = cipher.Encrypt(bundleCheckText); err != nil {
+			return nil, e.ErrExportCodeHost.AddErr(err)
+		}
+	}
+
+	for _, codeHost := range codeHosts {
+		// the state maintained by this installation is not exported.
+		codeHost.Health = nil
+		codeHost.NotReadyReason = ""
+		// the bundle carries the credentials instead of the references, which may not work in another installation.
+		if _, err := resolveCodeHostSecrets(codeHost); err != nil {
+			logger.Errorf("failed to resolve the credentials of codehost %d, err: %s", codeHost.ID, err)
+			return nil, e.ErrExportCodeHost.AddErr(err)
+		}
+		for _, secret := range codeHostSecrets(codeHost) {
+			if *secret == "" {
+				continue
+			}
+			if cipher == nil {
+				*secret = ""
+				continue
+			}
+			if *secret, err = cipher.Encrypt(*secret); err != nil {
+				return nil, e.ErrExportCodeHost.AddErr(err)
+			}
+		}
+		bundle.CodeHosts = append(bundle.CodeHosts, codeHost)
+	}
+
+	data, err := yaml.Marshal(bundle)
+	if err == nil && format != "yaml" {
+		data, err = yaml.YAMLToJSON(data)
+	}
+	if err != nil {
+		return nil, e.ErrExportCodeHost.AddErr(err)
+	}
+	return data, nil
+}
+
+// ImportCodeHosts saves the codehosts in the bundle encoded as yaml or json. A codehost conflicts with an
+// existing one having the same alias, or the same type, address and namespace, it is resolved by the strategy.
+// The codehosts are validated the same as they are created one by one, an invalid one does not stop the others.
+func ImportCodeHosts(data []byte, passphrase string, strategy ImportStrategy, user string, logger *zap.SugaredLogger) ([]*ImportCodeHostResult, error) {
+	switch strategy {
+	case ImportStrategySkip, ImportStrategyOverwrite, ImportStrategyRename:
+	default:
+		return nil, e.ErrImportCodeHost.AddDesc(fmt.Sprintf("unknown conflict strategy %s", strategy))
+	}
+
+	bundle := &CodeHostBundle{}
+	if err := yaml.Unmarshal(data, bundle); err != nil {
+		return nil, e.ErrImportCodeHost.AddDesc(fmt.Sprintf("invalid bundle: %s", err))
+	}
+	if bundle.Version != codeHostBundleVersion {
+		return nil, e.ErrImportCodeHost.AddDesc(fmt.Sprintf("unsupported bundle version %d", bundle.Version))
+	}
+	var cipher *crypto.Aes
+	if bundle.Salt != "" {
+		if passphrase == "" {
+			return nil, e.ErrImportCodeHost.AddDesc("the secrets of the bundle are encrypted, the passphrase is required")
+		}
+		salt, err := hex.DecodeString(bundle.Salt)
+		if err != nil {
+			return nil, e.ErrImportCodeHost.AddDesc(fmt.Sprintf("invalid salt: %s", err))
+		}
+		if cipher, err = bundleCipher(passphrase, salt); err != nil {
+			return nil, e.ErrImportCodeHost.AddErr(err)
+		}
+		if check, err := cipher.Decrypt(bundle.Check); err != nil || check != bundleCheckText {
+			return nil, e.ErrImportCodeHost.AddDesc("the passphrase is incorrect")
+		}
+	}
+
+	existing, err := mongodb.NewCodehostColl().List(&mongodb.ListArgs{})
+	if err != nil {
+		logger.Errorf("failed to list codehosts, err: %s", err)
+		return nil, e.ErrImportCodeHost.AddErr(err)
+	}
+
+	results := make([]*ImportCodeHostResult, 0, len(bundle.CodeHosts))
+	for _, codeHost := range bundle.CodeHosts {
+		result := &ImportCodeHostResult{Type: codeHost.Type, Address: codeHost.Address, Alias: codeHost.Alias}
+		results = append(results, result)
+
+		if err := prepareImportedCodeHost(codeHost, cipher); err != nil {
+			result.Action, result.Error = "failed", err.Error()
+			continue
+		}
+		saved, action, err := importCodeHost(codeHost, findConflictingCodeHost(codeHost, existing), strategy, user, logger)
+		if err != nil {
+			logger.Warnf("failed to import codehost %s %s, err: %s", codeHost.Type, codeHost.Address, err)
+			result.Action, result.Error = "failed", err.Error()
+			continue
+		}
+		result.Action = action
+		result.ID, result.Alias = saved.ID, saved.Alias
+		if action != "skipped" {
+			existing = append(existing, saved)
+		}
+	}
+	return results, nil
+}
+
+func importCodeHost(codeHost, conflict *models.CodeHost, strategy ImportStrategy, user string, logger *zap.SugaredLogger) (*models.CodeHost, string, error) {
+	if conflict == nil {
+		saved, err := CreateCodeHost(codeHost, user, logger)
+		return saved, "created", err
+	}
+
+	switch strategy {
+	case ImportStrategyOverwrite:
+		codeHost.ID = conflict.ID
+		codeHost.CreatedAt = conflict.CreatedAt
+		codeHost.Revision = conflict.Revision
+		saved, err := UpdateCodeHost(codeHost, user, logger)
+		return saved, "overwritten", err
+	case ImportStrategyRename:
+		alias, err := uniqueImportedAlias(codeHost)
+		if err != nil {
+			return nil, "", err
+		}
+		codeHost.Alias = alias
+		saved, err := CreateCodeHost(codeHost, user, logger)
+		return saved, "renamed", err
+	default:
+		return conflict, "skipped", nil
+	}
+}
+
+// prepareImportedCodeHost decrypts the secrets and clears the fields maintained by the installation.
+func prepareImportedCodeHost(codeHost *models.CodeHost, cipher *crypto.Aes) error {
+	if codeHost.Type == "" || codeHost.Address == "" {
+		return fmt.Errorf("the type and address of the codehost are required")
+	}
+	codeHost.ID = 0
+	codeHost.DeletedAt = 0
+	codeHost.Health = nil
+	codeHost.NotReadyReason = ""
+	// the revision of another installation means nothing here, the imported codehost overwrites the existing one.
+	codeHost.Revision = 0
+	for _, secret := range codeHostSecrets(codeHost) {
+		if *secret == "" {
+			continue
+		}
+		if cipher == nil {
+			return fmt.Errorf("the secrets of the codehost are not encrypted by a passphrase")
+		}
+		plain, err := cipher.Decrypt(*secret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the secrets: %s", err)
+		}
+		*secret = plain
+	}
+	return nil
+}
+
+func findConflictingCodeHost(codeHost *models.CodeHost, existing []*models.CodeHost) *models.CodeHost {
+	for _, ch := range existing {
+		if codeHost.Alias != "" && ch.Alias == codeHost.Alias {
+			return ch
+		}
+		if ch.Type == codeHost.Type && strings.TrimSuffix(ch.Address, "/") == strings.TrimSuffix(codeHost.Address, "/") &&
+			ch.Namespace == codeHost.Namespace && ch.AzureOrganization == codeHost.AzureOrganization {
+			return ch
+		}
+	}
+	return nil
+}
+
+// uniqueImportedAlias returns an alias not used by any codehost for the renamed codehost.
+func uniqueImportedAlias(codeHost *models.CodeHost) (string, error) {
+	base := codeHost.Alias
+	if base == "" {
+		base = codeHost.Type
+	}
+	base += importedAliasSuffix
+	for i := 1; i <= maxImportedAliasTries; i++ {
+		alias := base
+		if i > 1 {
+			alias = fmt.Sprintf("%s-%d", base, i)
+		}
+		if _, err := mongodb.NewCodehostColl().GetCodeHostByAlias(alias); err != nil {
+			return alias, nil
+		}
+	}
+	return "", fmt.Errorf("no alias is available for the renamed codehost %s", base)
+}
+
+// codeHostSecrets returns the pointers to the secret fields of the codehost, they are the ones redacted in the audits.
+func codeHostSecrets(codeHost *models.CodeHost) []*string {
+	secrets := make([]*string, 0, auditSecretFields.Len())
+	value := reflect.ValueOf(codeHost).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := strings.Split(value.Type().Field(i).Tag.Get("bson"), ",")[0]
+		if auditSecretFields.Has(field) && value.Field(i).Kind() == reflect.String {
+			secrets = append(secrets, value.Field(i).Addr().Interface().(*string))
+		}
+	}
+	return secrets
+}
+
+func bundleCipher(passphrase string, salt []byte) (*crypto.Aes, error) {
+	return crypto.NewAes(string(pbkdf2.Key([]byte(passphrase), salt, bundleKeyIterations, 32, sha256.New)))
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+
+	aslanconfig "github.com/koderover/zadig/pkg/microservice/aslan/config"
+	gitservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/internal/oauth"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/gerrit"
+	"github.com/koderover/zadig/pkg/tool/git"
+	"github.com/koderover/zadig/pkg/tool/git/github"
+	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// webhookManager manages the webhooks on the repositories of a codehost.
+type webhookManager interface {
+	create(owner, repo string) (string, error)
+	exists(owner, repo, hookID string) (bool, error)
+	delete(owner, repo, hookID string) error
+}
+
+func newWebhookManager(c *models.CodeHost) (webhookManager, error) {
+	var manager webhookManager
+	switch c.Type {
+	case setting.SourceFromGithub:
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, oauth.NewHTTPClient(c))
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.AccessToken})
+		manager = &githubWebhookManager{client: github.NewClient(&github.Config{HTTPClient: oauth2.NewClient(ctx, ts)})}
+	case setting.SourceFromGitlab:
+		cli, err := gitlab.NewOAuthClient(c.AccessToken, gitlab.WithBaseURL(c.Address), gitlab.WithHTTPClient(oauth.NewHTTPClient(c)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gitlab client, err: %s", err)
+		}
+		manager = &gitlabWebhookManager{client: &gitlabtool.Client{Client: cli}}
+	case setting.SourceFromGerrit:
+		manager = &gerritWebhookManager{client: gerrit.NewHTTPClient(c.Address, c.AccessToken), codeHostID: c.ID}
+	default:
+		return nil, fmt.Errorf("webhooks of codehost type %s are not supported", c.Type)
+	}
+	return &instrumentedWebhookManager{webhookManager: manager, codeHostType: c.Type}, nil
+}
+
+type githubWebhookManager struct {
+	client *github.Client
+}
+
+func (m *githubWebhookManager) create(owner, repo string) (string, error) {
+	hook, err := m.client.CreateHook(context.TODO(), owner, repo, &git.Hook{
+		URL:    aslanconfig.WebHookURL(),
+		Secret: gitservice.GetHookSecret(),
+		Events: []string{git.PushEvent, git.PullRequestEvent, git.BranchOrTagCreateEvent, git.CheckRunEvent},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(hook.GetID(), 10), nil
+}
+
+func (m *githubWebhookManager) exists(owner, repo, hookID string) (bool, error) {
+	id, err := strconv.ParseInt(hookID, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	_, resp, err := m.client.Repositories.GetHook(context.TODO(), owner, repo, id)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (m *githubWebhookManager) delete(owner, repo, hookID string) error {
+	id, err := strconv.ParseInt(hookID, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = m.client.DeleteHook(context.TODO(), owner, repo, id)
+	if httpclient.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+type gitlabWebhookManager struct {
+	client *gitlabtool.Client
+}
+
+func (m *gitlabWebhookManager) create(owner, repo string) (string, error) {
+	hook, err := m.client.AddProjectHook(owner, repo, &git.Hook{
+		URL:    aslanconfig.WebHookURL(),
+		Secret: gitservice.GetHookSecret(),
+		Events: []string{git.PushEvent, git.PullRequestEvent, git.BranchOrTagCreateEvent},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(hook.ID), nil
+}
+
+func (m *gitlabWebhookManager) exists(owner, repo, hookID string) (bool, error) {
+	id, err := strconv.Atoi(hookID)
+	if err != nil {
+		return false, err
+	}
+	_, resp, err := m.client.Projects.GetProjectHook(fmt.Sprintf("%s/%s", owner, repo), id)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (m *gitlabWebhookManager) delete(owner, repo, hookID string) error {
+	id, err := strconv.Atoi(hookID)
+	if err != nil {
+		return err
+	}
+	return m.client.DeleteProjectHook(owner, repo, id)
+}
+
+// gerritWebhookManager manages the remotes of the webhooks plugin, the remotes are named after the codehost
+// so they are not removed together with the ones of the workflows.
+type gerritWebhookManager struct {
+	client     *gerrit.HTTPClient
+	codeHostID int
+}
+
+func (m *gerritWebhookManager) remoteURL(repo, name string) string {
+	return fmt.Sprintf("/a/config/server/webhooks~projects/%s/remotes/%s", gerrit.Escape(repo), name)
+}
+
+func (m *gerritWebhookManager) create(_, repo string) (string, error) {
+	name := fmt.Sprintf("zadig-codehost-%d", m.codeHostID)
+	hook := &gerrit.Webhook{
+		URL:       aslanconfig.WebHookURL(),
+		MaxTries:  setting.MaxTries,
+		SslVerify: false,
+	}
+	if _, err := m.client.Put(m.remoteURL(repo, name), httpclient.SetBody(hook)); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (m *gerritWebhookManager) exists(_, repo, hookID string) (bool, error) {
+	_, err := m.client.Get(m.remoteURL(repo, hookID))
+	if httpclient.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (m *gerritWebhookManager) delete(_, repo, hookID string) error {
+	_, err := m.client.Delete(m.remoteURL(repo, hookID))
+	if httpclient.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+type RegisterWebhookArgs struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+}
+
+// RegisterCodeHostWebhook creates the webhook on the repository, the registered one is returned if it is
+// already registered.
+func RegisterCodeHostWebhook(id int, args *RegisterWebhookArgs, userName string, logger *zap.SugaredLogger) (*models.CodeHostWebhook, error) {
+	if args.Repo == "" {
+		return nil, e.ErrInvalidParam.AddDesc("repo is required")
+	}
+	codehost, err := GetCodeHost(id, false, false, logger)
+	if err != nil {
+		return nil, e.ErrRegisterCodeHostWebhook.AddErr(err)
+	}
+	if hook, err := mongodb.NewCodeHostWebhookColl().Find(id, args.Owner, args.Repo); err == nil {
+		return hook, nil
+	}
+	manager, err := newWebhookManager(codehost)
+	if err != nil {
+		return nil, e.ErrRegisterCodeHostWebhook.AddErr(err)
+	}
+
+	hookID, err := manager.create(args.Owner, args.Repo)
+	if err != nil {
+		logger.Errorf("failed to create webhook on %s/%s of codehost %d, err: %s", args.Owner, args.Repo, id, err)
+		return nil, e.ErrRegisterCodeHostWebhook.AddErr(err)
+	}
+	hook := &models.CodeHostWebhook{
+		CodeHostID: id,
+		Owner:      args.Owner,
+		Repo:       args.Repo,
+		HookID:     hookID,
+		URL:        aslanconfig.WebHookURL(),
+		CreatedBy:  userName,
+		CreatedAt:  time.Now().Unix(),
+	}
+	if err := mongodb.NewCodeHostWebhookColl().Create(hook); err != nil {
+		logger.Errorf("failed to save webhook %s of codehost %d, err: %s", hookID, id, err)
+		if err := manager.delete(args.Owner, args.Repo, hookID); err != nil {
+			logger.Warnf("failed to delete webhook %s of codehost %d, err: %s", hookID, id, err)
+		}
+		return nil, e.ErrRegisterCodeHostWebhook.AddErr(err)
+	}
+	return hook, nil
+}
+
+func ListCodeHostWebhooks(id int, logger *zap.SugaredLogger) ([]*models.CodeHostWebhook, error) {
+	hooks, err := mongodb.NewCodeHostWebhookColl().List(id)
+	if err != nil {
+		logger.Errorf("failed to list webhooks of codehost %d, err: %s", id, err)
+		return nil, e.ErrListCodeHostWebhooks.AddErr(err)
+	}
+	return hooks, nil
+}
+
+func DeleteCodeHostWebhook(id int, hookID string, logger *zap.SugaredLogger) error {
+	hook, err := mongodb.NewCodeHostWebhookColl().FindByID(id, hookID)
+	if err != nil {
+		return e.ErrDeleteCodeHostWebhook.AddErr(err)
+	}
+	codehost, err := GetCodeHost(id, false, false, logger)
+	if err != nil {
+		return e.ErrDeleteCodeHostWebhook.AddErr(err)
+	}
+	if err := deleteWebhook(codehost, hook); err != nil {
+		logger.Errorf("failed to delete webhook %s of codehost %d, err: %s", hook.HookID, id, err)
+		return e.ErrDeleteCodeHostWebhook.AddErr(err)
+	}
+	return nil
+}
+
+func deleteWebhook(codehost *models.CodeHost, hook *models.CodeHostWebhook) error {
+	manager, err := newWebhookManager(codehost)
+	if err != nil {
+		return err
+	}
+	if err := manager.delete(hook.Owner, hook.Repo, hook.HookID); err != nil {
+		return err
+	}
+	return mongodb.NewCodeHostWebhookColl().Delete(hook.ID)
+}
+
+type ReconcileWebhooksResult struct {
+	Recreated []*models.CodeHostWebhook `json:"recreated"`
+	Failed    map[string]string         `json:"failed"`
+}
+
+// ReconcileCodeHostWebhooks creates the registered webhooks again if they are removed on the codehost.
+func ReconcileCodeHostWebhooks(id int, logger *zap.SugaredLogger) (*ReconcileWebhooksResult, error) {
+	codehost, err := GetCodeHost(id, false, false, logger)
+	if err != nil {
+		return nil, e.ErrReconcileCodeHostWebhooks.AddErr(err)
+	}
+	hooks, err := mongodb.NewCodeHostWebhookColl().List(id)
+	if err != nil {
+		return nil, e.ErrReconcileCodeHostWebhooks.AddErr(err)
+	}
+	manager, err := newWebhookManager(codehost)
+	if err != nil {
+		return nil, e.ErrReconcileCodeHostWebhooks.AddErr(err)
+	}
+
+	res := &ReconcileWebhooksResult{Recreated: make([]*models.CodeHostWebhook, 0), Failed: map[string]string{}}
+	for _, hook := range hooks {
+		repo := fmt.Sprintf("%s/%s", hook.Owner, hook.Repo)
+		exists, err := manager.exists(hook.Owner, hook.Repo, hook.HookID)
+		if err != nil {
+			res.Failed[repo] = err.Error()
+			continue
+		}
+		if !exists {
+			hook.HookID, err = manager.create(hook.Owner, hook.Repo)
+			if err != nil {
+				res.Failed[repo] = err.Error()
+				continue
+			}
+			res.Recreated = append(res.Recreated, hook)
+		}
+		if err := mongodb.NewCodeHostWebhookColl().UpdateReconciled(hook.ID, hook.HookID); err != nil {
+			logger.Errorf("failed to save webhook %s of codehost %d, err: %s", hook.HookID, id, err)
+		}
+	}
+	return res, nil
+}
+
+// cleanupWebhooks removes the registered webhooks of the codehost which is being deleted, the failures
+// are only logged since the credentials may be revoked already.
+func cleanupWebhooks(id int, logger *zap.SugaredLogger) {
+	hooks, err := mongodb.NewCodeHostWebhookColl().List(id)
+	if err != nil || len(hooks) == 0 {
+		return
+	}
+	codehost, err := GetCodeHost(id, false, false, logger)
+	if err != nil {
+		return
+	}
+	for _, hook := range hooks {
+		if err := deleteWebhook(codehost, hook); err != nil {
+			logger.Warnf("failed to delete webhook %s on %s/%s of codehost %d, err: %s", hook.HookID, hook.Owner, hook.Repo, id, err)
+			_ = mongodb.NewCodeHostWebhookColl().Delete(hook.ID)
+		}
+	}
+}
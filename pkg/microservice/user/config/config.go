@@ -27,6 +27,7 @@ import (
 
 func init() {
 	viper.SetDefault(setting.ENVUserPort, "80")
+	viper.SetDefault(setting.ENVSCIMIdentityType, SystemIdentityType)
 }
 
 func IssuerURL() string {
@@ -56,3 +57,11 @@ func MysqlUserDB() string {
 func TokenExpiresAt() int {
 	return viper.GetInt(setting.ENVTokenExpiresAt)
 }
+
+func SCIMToken() string {
+	return viper.GetString(setting.ENVSCIMToken)
+}
+
+func SCIMIdentityType() string {
+	return viper.GetString(setting.ENVSCIMIdentityType)
+}
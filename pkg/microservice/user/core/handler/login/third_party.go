@@ -69,7 +69,7 @@ func Login(c *gin.Context) {
 		return
 	}
 	defaultLogin := ""
-	replaceURL := configbase.SystemAddress() + "/dex/auth"
+	replaceURL := configbase.ExternalBaseURLFromRequest(c.Request) + "/dex/auth"
 	if systemConfig.DefaultLogin != setting.DefaultLoginLocal {
 		defaultLogin = systemConfig.DefaultLogin
 		replaceURL = replaceURL + "/" + defaultLogin
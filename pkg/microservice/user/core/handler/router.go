@@ -20,6 +20,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/koderover/zadig/pkg/microservice/user/core/handler/login"
+	"github.com/koderover/zadig/pkg/microservice/user/core/handler/scim"
 	"github.com/koderover/zadig/pkg/microservice/user/core/handler/user"
 )
 
@@ -62,4 +63,26 @@ func (*Router) Inject(router *gin.RouterGroup) {
 
 		router.POST("reset", user.Reset)
 	}
+
+	// SCIM 2.0 provisioning, see RFC 7644
+	provisioning := router.Group("/scim/v2", scim.Auth())
+	{
+		provisioning.GET("/Users", scim.ListUsers)
+		provisioning.POST("/Users", scim.CreateUser)
+		provisioning.GET("/Users/:id", scim.GetUser)
+		provisioning.PUT("/Users/:id", scim.ReplaceUser)
+		provisioning.PATCH("/Users/:id", scim.PatchUser)
+		provisioning.DELETE("/Users/:id", scim.DeleteUser)
+
+		provisioning.GET("/Groups", scim.ListGroups)
+		provisioning.POST("/Groups", scim.CreateGroup)
+		provisioning.GET("/Groups/:id", scim.GetGroup)
+		provisioning.PUT("/Groups/:id", scim.ReplaceGroup)
+		provisioning.PATCH("/Groups/:id", scim.PatchGroup)
+		provisioning.DELETE("/Groups/:id", scim.DeleteGroup)
+
+		provisioning.GET("/ServiceProviderConfig", scim.GetServiceProviderConfig)
+		provisioning.GET("/ResourceTypes", scim.ListResourceTypes)
+		provisioning.GET("/Schemas", scim.ListSchemas)
+	}
 }
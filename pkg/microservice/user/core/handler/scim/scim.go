@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/user/config"
+	"github.com/koderover/zadig/pkg/microservice/user/core/service/scim"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+)
+
+const contentType = "application/scim+json"
+
+// Auth checks the bearer token sent by the identity provider, scim is disabled if no token is configured.
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config.SCIMToken()
+		if token == "" {
+			response(c, http.StatusNotFound, nil, &scim.Error{Schemas: []string{scim.SchemaError}, Status: "404", Detail: "scim is not enabled"})
+			c.Abort()
+			return
+		}
+		auth := c.GetHeader("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			response(c, http.StatusUnauthorized, nil, &scim.Error{Schemas: []string{scim.SchemaError}, Status: "401", Detail: "invalid token"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ListUsers(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.ListArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.ListUsers(args, ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func GetUser(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	resp, err := scim.GetUser(c.Param("id"), ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func CreateUser(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.User)
+	if err := c.ShouldBindJSON(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.CreateUser(args, ctx.Logger)
+	response(c, http.StatusCreated, resp, err)
+}
+
+func ReplaceUser(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.User)
+	if err := c.ShouldBindJSON(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.ReplaceUser(c.Param("id"), args, ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func PatchUser(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.PatchRequest)
+	if err := c.ShouldBindJSON(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.PatchUser(c.Param("id"), args, ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func DeleteUser(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	err := scim.DeleteUser(c.Param("id"), ctx.Logger)
+	response(c, http.StatusNoContent, nil, err)
+}
+
+func ListGroups(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.ListArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.ListGroups(args, ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func GetGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	resp, err := scim.GetGroup(c.Param("id"), ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func CreateGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.Group)
+	if err := c.ShouldBindJSON(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.CreateGroup(args, ctx.Logger)
+	response(c, http.StatusCreated, resp, err)
+}
+
+func ReplaceGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.Group)
+	if err := c.ShouldBindJSON(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.ReplaceGroup(c.Param("id"), args, ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func PatchGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	args := new(scim.PatchRequest)
+	if err := c.ShouldBindJSON(args); err != nil {
+		response(c, http.StatusBadRequest, nil, invalidRequest(err))
+		return
+	}
+	resp, err := scim.PatchGroup(c.Param("id"), args, ctx.Logger)
+	response(c, http.StatusOK, resp, err)
+}
+
+func DeleteGroup(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	err := scim.DeleteGroup(c.Param("id"), ctx.Logger)
+	response(c, http.StatusNoContent, nil, err)
+}
+
+func GetServiceProviderConfig(c *gin.Context) {
+	response(c, http.StatusOK, scim.GetServiceProviderConfig(), nil)
+}
+
+func ListResourceTypes(c *gin.Context) {
+	response(c, http.StatusOK, scim.ListResourceTypes(), nil)
+}
+
+func ListSchemas(c *gin.Context) {
+	response(c, http.StatusOK, scim.ListSchemas(), nil)
+}
+
+func invalidRequest(err error) *scim.Error {
+	return &scim.Error{Schemas: []string{scim.SchemaError}, Status: "400", ScimType: "invalidSyntax", Detail: err.Error()}
+}
+
+// response writes the body with the scim content type, the common json response is not used
+// since identity providers expect the resources or the scim errors as they are.
+func response(c *gin.Context, status int, resp interface{}, err error) {
+	if err != nil {
+		scimErr := &scim.Error{}
+		if !errors.As(err, &scimErr) {
+			scimErr = &scim.Error{Schemas: []string{scim.SchemaError}, Status: "500", Detail: err.Error()}
+		}
+		status, resp = scimErr.StatusCode(), scimErr
+	}
+	if status == http.StatusNoContent {
+		c.Status(status)
+		return
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		c.Data(http.StatusInternalServerError, contentType, []byte(`{"detail":"failed to encode response"}`))
+		return
+	}
+	c.Data(status, contentType, body)
+}
@@ -0,0 +1,30 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// SCIMUser holds the provisioning state of a user managed by an identity provider.
+type SCIMUser struct {
+	Model
+	UID        string `gorm:"primary_key" json:"uid"`
+	ExternalID string `json:"external_id"`
+	Active     bool   `json:"active"`
+}
+
+// TableName sets the insert table name for this struct type
+func (SCIMUser) TableName() string {
+	return "scim_user"
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orm
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+)
+
+// CreateSCIMUser add a scimUser record
+func CreateSCIMUser(scimUser *models.SCIMUser, db *gorm.DB) error {
+	return db.Create(scimUser).Error
+}
+
+// GetSCIMUser Get a scimUser based on uid, nil is returned if the user is not provisioned by scim
+func GetSCIMUser(uid string, db *gorm.DB) (*models.SCIMUser, error) {
+	var scimUser models.SCIMUser
+	err := db.Where("uid = ?", uid).First(&scimUser).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &scimUser, nil
+}
+
+// ListSCIMUsersByUIDs gets a list of scimUsers based on uids
+func ListSCIMUsersByUIDs(uids []string, db *gorm.DB) ([]models.SCIMUser, error) {
+	var scimUsers []models.SCIMUser
+	err := db.Find(&scimUsers, "uid in ?", uids).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return scimUsers, nil
+}
+
+// UpdateSCIMUser update all the fields of a scimUser, including the zero values
+func UpdateSCIMUser(scimUser *models.SCIMUser, db *gorm.DB) error {
+	return db.Model(&models.SCIMUser{}).Where("uid = ?", scimUser.UID).
+		Select("external_id", "active", "updated_at").Updates(scimUser).Error
+}
+
+// DeleteSCIMUserByUid Delete scimUser based on uid
+func DeleteSCIMUserByUid(uid string, db *gorm.DB) error {
+	var scimUser models.SCIMUser
+	return db.Where("uid = ?", uid).Delete(&scimUser).Error
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orm
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+)
+
+// CreateUserGroup add a userGroup record
+func CreateUserGroup(group *models.UserGroup, db *gorm.DB) error {
+	return db.Create(group).Error
+}
+
+// GetUserGroup Get a userGroup based on groupID
+func GetUserGroup(groupID string, db *gorm.DB) (*models.UserGroup, error) {
+	var group models.UserGroup
+	err := db.Where("group_id = ?", groupID).First(&group).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &group, nil
+}
+
+// ListUserGroups gets a list of userGroups, filtered by name if it is not empty
+func ListUserGroups(name string, db *gorm.DB) ([]models.UserGroup, error) {
+	var groups []models.UserGroup
+	query := db.Order("name ASC")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	err := query.Find(&groups).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// UpdateUserGroup update the name and externalID of a userGroup
+func UpdateUserGroup(group *models.UserGroup, db *gorm.DB) error {
+	return db.Model(&models.UserGroup{}).Where("group_id = ?", group.GroupID).
+		Select("name", "external_id", "updated_at").Updates(group).Error
+}
+
+// DeleteUserGroup Delete a userGroup based on groupID
+func DeleteUserGroup(groupID string, db *gorm.DB) error {
+	var group models.UserGroup
+	return db.Where("group_id = ?", groupID).Delete(&group).Error
+}
+
+// ListGroupBindings gets the bindings of the groups
+func ListGroupBindings(groupIDs []string, db *gorm.DB) ([]models.GroupBinding, error) {
+	var bindings []models.GroupBinding
+	err := db.Find(&bindings, "group_id in ?", groupIDs).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// ListGroupBindingsByUIDs gets the bindings of the users
+func ListGroupBindingsByUIDs(uids []string, db *gorm.DB) ([]models.GroupBinding, error) {
+	var bindings []models.GroupBinding
+	err := db.Find(&bindings, "uid in ?", uids).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// CreateGroupBindings add the members to a userGroup
+func CreateGroupBindings(bindings []*models.GroupBinding, db *gorm.DB) error {
+	if len(bindings) == 0 {
+		return nil
+	}
+	return db.Create(bindings).Error
+}
+
+// DeleteGroupBindings remove the members from a userGroup, all members are removed if uids is empty
+func DeleteGroupBindings(groupID string, uids []string, db *gorm.DB) error {
+	var binding models.GroupBinding
+	query := db.Where("group_id = ?", groupID)
+	if len(uids) > 0 {
+		query = query.Where("uid in ?", uids)
+	}
+	return query.Delete(&binding).Error
+}
+
+// DeleteGroupBindingsByUid remove a user from all userGroups
+func DeleteGroupBindingsByUid(uid string, db *gorm.DB) error {
+	var binding models.GroupBinding
+	return db.Where("uid = ?", uid).Delete(&binding).Error
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package login
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -78,6 +79,21 @@ func CheckSignature(ifLoggedIn bool, logger *zap.SugaredLogger) error {
 	return nil
 }
 
+// ErrUserDeactivated is returned for the users deactivated by the identity provider through scim.
+var ErrUserDeactivated = errors.New("user is deactivated")
+
+// CheckUserActive returns an error if the user is deactivated by the identity provider through scim.
+func CheckUserActive(uid string) error {
+	scimUser, err := orm.GetSCIMUser(uid, core.DB)
+	if err != nil {
+		return err
+	}
+	if scimUser != nil && !scimUser.Active {
+		return ErrUserDeactivated
+	}
+	return nil
+}
+
 func LocalLogin(args *LoginArgs, logger *zap.SugaredLogger) (*User, error) {
 	user, err := orm.GetUser(args.Account, config.SystemIdentityType, core.DB)
 	if err != nil {
@@ -87,6 +103,9 @@ func LocalLogin(args *LoginArgs, logger *zap.SugaredLogger) (*User, error) {
 	if user == nil {
 		return nil, fmt.Errorf("user not exist")
 	}
+	if err := CheckUserActive(user.UID); err != nil {
+		return nil, err
+	}
 	userLogin, err := orm.GetUserLogin(user.UID, args.Account, config.AccountLoginType, core.DB)
 	if err != nil {
 		logger.Errorf("LocalLogin get user:%s user login not exist, error msg:%s", args.Account, err.Error())
@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scim
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type bulk struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type filterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type ServiceProviderConfig struct {
+	Schemas               []string                `json:"schemas"`
+	Patch                 supported               `json:"patch"`
+	Bulk                  bulk                    `json:"bulk"`
+	Filter                filterSupported         `json:"filter"`
+	ChangePassword        supported               `json:"changePassword"`
+	Sort                  supported               `json:"sort"`
+	ETag                  supported               `json:"etag"`
+	AuthenticationSchemes []*authenticationScheme `json:"authenticationSchemes"`
+}
+
+type ResourceType struct {
+	Schemas  []string `json:"schemas"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Endpoint string   `json:"endpoint"`
+	Schema   string   `json:"schema"`
+}
+
+type Schema struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Attributes  []*Attribute `json:"attributes"`
+}
+
+type Attribute struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	MultiValued bool   `json:"multiValued"`
+	Required    bool   `json:"required"`
+	Mutability  string `json:"mutability"`
+	Returned    string `json:"returned"`
+	Uniqueness  string `json:"uniqueness"`
+}
+
+func GetServiceProviderConfig() *ServiceProviderConfig {
+	return &ServiceProviderConfig{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          supported{Supported: true},
+		Filter:         filterSupported{Supported: true, MaxResults: maxCount},
+		ChangePassword: supported{Supported: true},
+		AuthenticationSchemes: []*authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication with the token configured by SCIM_TOKEN",
+			Primary:     true,
+		}},
+	}
+}
+
+func ListResourceTypes() *ListResponse {
+	resourceTypes := []interface{}{
+		&ResourceType{Schemas: []string{SchemaResourceType}, ID: resourceUser, Name: resourceUser, Endpoint: "/Users", Schema: SchemaUser},
+		&ResourceType{Schemas: []string{SchemaResourceType}, ID: resourceGroup, Name: resourceGroup, Endpoint: "/Groups", Schema: SchemaGroup},
+	}
+	return newStaticList(resourceTypes)
+}
+
+func ListSchemas() *ListResponse {
+	schemas := []interface{}{
+		&Schema{
+			ID:          SchemaUser,
+			Name:        resourceUser,
+			Description: "User Account",
+			Attributes: []*Attribute{
+				newAttribute("userName", "string", false, true, "readWrite", "server"),
+				newAttribute("displayName", "string", false, false, "readWrite", "none"),
+				newAttribute("name", "complex", false, false, "readWrite", "none"),
+				newAttribute("emails", "complex", true, false, "readWrite", "none"),
+				newAttribute("phoneNumbers", "complex", true, false, "readWrite", "none"),
+				newAttribute("active", "boolean", false, false, "readWrite", "none"),
+				newAttribute("password", "string", false, false, "writeOnly", "none"),
+				newAttribute("groups", "complex", true, false, "readOnly", "none"),
+			},
+		},
+		&Schema{
+			ID:          SchemaGroup,
+			Name:        resourceGroup,
+			Description: "Group",
+			Attributes: []*Attribute{
+				newAttribute("displayName", "string", false, true, "readWrite", "server"),
+				newAttribute("members", "complex", true, false, "readWrite", "none"),
+			},
+		},
+	}
+	return newStaticList(schemas)
+}
+
+func newAttribute(name, attrType string, multiValued, required bool, mutability, uniqueness string) *Attribute {
+	returned := "default"
+	if mutability == "writeOnly" {
+		returned = "never"
+	}
+	return &Attribute{
+		Name:        name,
+		Type:        attrType,
+		MultiValued: multiValued,
+		Required:    required,
+		Mutability:  mutability,
+		Returned:    returned,
+		Uniqueness:  uniqueness,
+	}
+}
+
+func newStaticList(resources []interface{}) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
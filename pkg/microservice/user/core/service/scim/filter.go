@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scim
+
+import (
+	"regexp"
+	"strings"
+)
+
+// only the equality filters are supported, which are the ones used by the identity providers
+// to look up the existing resources, e.g. userName eq "alice".
+var filterRegx = regexp.MustCompile(`^\s*([A-Za-z][\w.]*)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+type filter struct {
+	// Attribute is in lower case since the attribute names are case insensitive.
+	Attribute string
+	Value     string
+}
+
+func parseFilter(expr string) (*filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	match := filterRegx.FindStringSubmatch(expr)
+	if len(match) != 3 {
+		return nil, newError(400, "invalidFilter", "unsupported filter: %s", expr)
+	}
+	return &filter{
+		Attribute: strings.ToLower(match[1]),
+		Value:     strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(match[2]),
+	}, nil
+}
+
+// parseValuePath parses paths like members[value eq "id"] or emails[type eq "work"].value, the
+// path without the filter and the filter are returned, the filter is nil for plain paths.
+func parseValuePath(path string) (string, *filter, error) {
+	start, end := strings.Index(path, "["), strings.LastIndex(path, "]")
+	if start < 0 || end < start {
+		return strings.ToLower(path), nil, nil
+	}
+	f, err := parseFilter(path[start+1 : end])
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.ToLower(path[:start] + path[end+1:]), f, nil
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/orm"
+)
+
+const resourceGroup = "Group"
+
+func ListGroups(args *ListArgs, logger *zap.SugaredLogger) (*ListResponse, error) {
+	args.normalize()
+	f, err := parseFilter(args.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ""
+	if f != nil && f.Attribute == "displayname" {
+		name = f.Value
+	}
+	groups, err := orm.ListUserGroups(name, core.DB)
+	if err != nil {
+		logger.Errorf("failed to list groups, err: %s", err)
+		return nil, errInternal(err)
+	}
+	matched := make([]models.UserGroup, 0, len(groups))
+	for _, g := range groups {
+		if f == nil || matchGroup(f, &g) {
+			matched = append(matched, g)
+		}
+	}
+
+	resp := &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: int64(len(matched)),
+		StartIndex:   args.StartIndex,
+		Resources:    []interface{}{},
+	}
+	if args.StartIndex > len(matched) {
+		return resp, nil
+	}
+	end := args.StartIndex - 1 + args.Count
+	if end > len(matched) {
+		end = len(matched)
+	}
+	page := matched[args.StartIndex-1 : end]
+	groupIDs := make([]string, 0, len(page))
+	for _, g := range page {
+		groupIDs = append(groupIDs, g.GroupID)
+	}
+	members, err := listGroupMemberMap(groupIDs)
+	if err != nil {
+		logger.Errorf("failed to list group members, err: %s", err)
+		return nil, errInternal(err)
+	}
+	for i := range page {
+		resp.Resources = append(resp.Resources, toSCIMGroup(&page[i], members[page[i].GroupID]))
+	}
+	resp.ItemsPerPage = len(resp.Resources)
+	return resp, nil
+}
+
+func matchGroup(f *filter, g *models.UserGroup) bool {
+	switch f.Attribute {
+	case "id":
+		return g.GroupID == f.Value
+	case "displayname":
+		return g.Name == f.Value
+	case "externalid":
+		return g.ExternalID == f.Value
+	default:
+		return false
+	}
+}
+
+func GetGroup(groupID string, logger *zap.SugaredLogger) (*Group, error) {
+	g, err := orm.GetUserGroup(groupID, core.DB)
+	if err != nil {
+		logger.Errorf("failed to get group %s, err: %s", groupID, err)
+		return nil, errInternal(err)
+	}
+	if g == nil {
+		return nil, errNotFound(resourceGroup, groupID)
+	}
+	members, err := listGroupMemberMap([]string{groupID})
+	if err != nil {
+		logger.Errorf("failed to list members of group %s, err: %s", groupID, err)
+		return nil, errInternal(err)
+	}
+	return toSCIMGroup(g, members[groupID]), nil
+}
+
+func CreateGroup(args *Group, logger *zap.SugaredLogger) (*Group, error) {
+	if args.DisplayName == "" {
+		return nil, errInvalidValue("displayName is required")
+	}
+	groupID, _ := uuid.NewUUID()
+	g := &models.UserGroup{
+		GroupID:    groupID.String(),
+		Name:       args.DisplayName,
+		ExternalID: args.ExternalID,
+	}
+	uids, err := memberUIDs(args.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	err = transaction(func(tx *gorm.DB) error {
+		if err := orm.CreateUserGroup(g, tx); err != nil {
+			return err
+		}
+		return orm.CreateGroupBindings(newGroupBindings(g.GroupID, uids), tx)
+	})
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, newError(http.StatusConflict, "uniqueness", "group %s already exists", args.DisplayName)
+		}
+		logger.Errorf("failed to create group %s, err: %s", args.DisplayName, err)
+		return nil, errInternal(err)
+	}
+	return GetGroup(g.GroupID, logger)
+}
+
+// ReplaceGroup replaces the name and the members of a group.
+func ReplaceGroup(groupID string, args *Group, logger *zap.SugaredLogger) (*Group, error) {
+	if _, err := GetGroup(groupID, logger); err != nil {
+		return nil, err
+	}
+	args.ID = groupID
+	return saveGroup(args, logger)
+}
+
+// PatchGroup applies the patch operations to a group, identity providers usually add or
+// remove members with it instead of replacing the whole group.
+func PatchGroup(groupID string, args *PatchRequest, logger *zap.SugaredLogger) (*Group, error) {
+	current, err := GetGroup(groupID, logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range args.Operations {
+		if err := applyGroupPatch(current, op); err != nil {
+			return nil, err
+		}
+	}
+	return saveGroup(current, logger)
+}
+
+func DeleteGroup(groupID string, logger *zap.SugaredLogger) error {
+	if _, err := GetGroup(groupID, logger); err != nil {
+		return err
+	}
+	err := transaction(func(tx *gorm.DB) error {
+		if err := orm.DeleteGroupBindings(groupID, nil, tx); err != nil {
+			return err
+		}
+		return orm.DeleteUserGroup(groupID, tx)
+	})
+	if err != nil {
+		logger.Errorf("failed to delete group %s, err: %s", groupID, err)
+		return errInternal(err)
+	}
+	return nil
+}
+
+func saveGroup(args *Group, logger *zap.SugaredLogger) (*Group, error) {
+	if args.DisplayName == "" {
+		return nil, errInvalidValue("displayName is required")
+	}
+	uids, err := memberUIDs(args.Members)
+	if err != nil {
+		return nil, err
+	}
+	g := &models.UserGroup{
+		GroupID:    args.ID,
+		Name:       args.DisplayName,
+		ExternalID: args.ExternalID,
+	}
+
+	err = transaction(func(tx *gorm.DB) error {
+		if err := orm.UpdateUserGroup(g, tx); err != nil {
+			return err
+		}
+		if err := orm.DeleteGroupBindings(g.GroupID, nil, tx); err != nil {
+			return err
+		}
+		return orm.CreateGroupBindings(newGroupBindings(g.GroupID, uids), tx)
+	})
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, newError(http.StatusConflict, "uniqueness", "group %s already exists", args.DisplayName)
+		}
+		logger.Errorf("failed to update group %s, err: %s", g.GroupID, err)
+		return nil, errInternal(err)
+	}
+	return GetGroup(g.GroupID, logger)
+}
+
+func applyGroupPatch(g *Group, op *PatchOperation) error {
+	attr, f, err := parseValuePath(op.Path)
+	if err != nil {
+		return err
+	}
+	attr = strings.TrimPrefix(attr, strings.ToLower(SchemaGroup)+":")
+
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		switch attr {
+		case "":
+			attributes := struct {
+				DisplayName *string   `json:"displayName"`
+				ExternalID  *string   `json:"externalId"`
+				Members     []*Member `json:"members"`
+			}{}
+			if err := json.Unmarshal(op.Value, &attributes); err != nil {
+				return errInvalidValue("invalid value of operation %s: %s", op.Op, err)
+			}
+			if attributes.DisplayName != nil {
+				g.DisplayName = *attributes.DisplayName
+			}
+			if attributes.ExternalID != nil {
+				g.ExternalID = *attributes.ExternalID
+			}
+			if attributes.Members != nil {
+				g.Members = mergeMembers(g.Members, attributes.Members, strings.EqualFold(op.Op, "replace"))
+			}
+		case "displayname", "externalid":
+			var s string
+			if err := json.Unmarshal(op.Value, &s); err != nil {
+				return errInvalidValue("invalid value of %s: %s", op.Path, err)
+			}
+			if attr == "displayname" {
+				g.DisplayName = s
+			} else {
+				g.ExternalID = s
+			}
+		case "members":
+			var members []*Member
+			if err := json.Unmarshal(op.Value, &members); err != nil {
+				return errInvalidValue("invalid value of %s: %s", op.Path, err)
+			}
+			g.Members = mergeMembers(g.Members, members, strings.EqualFold(op.Op, "replace"))
+		}
+	case "remove":
+		switch attr {
+		case "externalid":
+			g.ExternalID = ""
+		case "members":
+			// members[value eq "id"] removes a single member, the members given in the value are
+			// removed if there is no filter, and all of them are removed if there is neither.
+			var removed []*Member
+			if f != nil {
+				if f.Attribute != "value" {
+					return newError(http.StatusBadRequest, "invalidFilter", "unsupported filter of members: %s", op.Path)
+				}
+				removed = []*Member{{Value: f.Value}}
+			} else if len(op.Value) > 0 {
+				if err := json.Unmarshal(op.Value, &removed); err != nil {
+					return errInvalidValue("invalid value of %s: %s", op.Path, err)
+				}
+			} else {
+				g.Members = nil
+				return nil
+			}
+			g.Members = removeMembers(g.Members, removed)
+		default:
+			return newError(http.StatusBadRequest, "noTarget", "can not remove %s", op.Path)
+		}
+	default:
+		return errInvalidValue("unsupported operation: %s", op.Op)
+	}
+	return nil
+}
+
+func mergeMembers(current, added []*Member, replace bool) []*Member {
+	if replace {
+		current = nil
+	}
+	existed := map[string]bool{}
+	for _, m := range current {
+		existed[m.Value] = true
+	}
+	for _, m := range added {
+		if !existed[m.Value] {
+			existed[m.Value] = true
+			current = append(current, m)
+		}
+	}
+	return current
+}
+
+func removeMembers(current, removed []*Member) []*Member {
+	toRemove := map[string]bool{}
+	for _, m := range removed {
+		toRemove[m.Value] = true
+	}
+	resp := make([]*Member, 0, len(current))
+	for _, m := range current {
+		if !toRemove[m.Value] {
+			resp = append(resp, m)
+		}
+	}
+	return resp
+}
+
+// memberUIDs checks that all the members exist and returns their uids.
+func memberUIDs(members []*Member) ([]string, error) {
+	uids := make([]string, 0, len(members))
+	for _, m := range members {
+		uids = append(uids, m.Value)
+	}
+	if len(uids) == 0 {
+		return uids, nil
+	}
+	users, err := orm.ListUsersByUIDs(uids, core.DB)
+	if err != nil {
+		return nil, errInternal(err)
+	}
+	existed := map[string]bool{}
+	for _, u := range users {
+		existed[u.UID] = true
+	}
+	for _, uid := range uids {
+		if !existed[uid] {
+			return nil, errInvalidValue("member %s not found", uid)
+		}
+	}
+	return uids, nil
+}
+
+func newGroupBindings(groupID string, uids []string) []*models.GroupBinding {
+	bindings := make([]*models.GroupBinding, 0, len(uids))
+	for _, uid := range uids {
+		bindings = append(bindings, &models.GroupBinding{GroupID: groupID, UID: uid})
+	}
+	return bindings
+}
+
+func toSCIMGroup(g *models.UserGroup, members []*Member) *Group {
+	return &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          g.GroupID,
+		ExternalID:  g.ExternalID,
+		DisplayName: g.Name,
+		Members:     members,
+		Meta:        newMeta(resourceGroup, g.CreatedAt, g.UpdatedAt),
+	}
+}
+
+func listGroupMemberMap(groupIDs []string) (map[string][]*Member, error) {
+	resp := map[string][]*Member{}
+	bindings, err := orm.ListGroupBindings(groupIDs, core.DB)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		uids = append(uids, b.UID)
+	}
+	names := map[string]string{}
+	if len(uids) > 0 {
+		users, err := orm.ListUsersByUIDs(uids, core.DB)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			names[u.UID] = u.Name
+		}
+	}
+	for _, b := range bindings {
+		resp[b.GroupID] = append(resp[b.GroupID], &Member{Value: b.UID, Display: names[b.UID]})
+	}
+	return resp, nil
+}
+
+func listUserGroupMap(uids []string) (map[string][]*Member, error) {
+	resp := map[string][]*Member{}
+	bindings, err := orm.ListGroupBindingsByUIDs(uids, core.DB)
+	if err != nil {
+		return nil, err
+	}
+	if len(bindings) == 0 {
+		return resp, nil
+	}
+	groups, err := orm.ListUserGroups("", core.DB)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, g := range groups {
+		names[g.GroupID] = g.Name
+	}
+	for _, b := range bindings {
+		resp[b.UID] = append(resp[b.UID], &Member{Value: b.GroupID, Display: names[b.GroupID]})
+	}
+	return resp, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scim
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing scim filter", func() {
+
+	It("parses equality filters", func() {
+		f, err := parseFilter(`userName eq "al\"ice"`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Attribute).To(Equal("username"))
+		Expect(f.Value).To(Equal(`al"ice`))
+
+		f, err = parseFilter("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f).To(BeNil())
+
+		_, err = parseFilter(`userName sw "al"`)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("parses value paths", func() {
+		attr, f, err := parseValuePath(`members[value eq "uid-1"]`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attr).To(Equal("members"))
+		Expect(f.Value).To(Equal("uid-1"))
+	})
+})
+
+var _ = Describe("Testing scim patch", func() {
+
+	patch := func(body string) []*PatchOperation {
+		req := &PatchRequest{}
+		Expect(json.Unmarshal([]byte(body), req)).To(Succeed())
+		return req.Operations
+	}
+
+	It("applies user operations with string booleans and mixed case ops", func() {
+		active := true
+		u := &User{UserName: "alice", Active: &active}
+		for _, op := range patch(`{"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "alice@example.com"},
+			{"op": "add", "value": {"displayName": "Alice", "externalId": "ext-1"}}
+		]}`) {
+			Expect(applyUserPatch(u, op)).To(Succeed())
+		}
+		Expect(*u.Active).To(BeFalse())
+		Expect(primaryValue(u.Emails)).To(Equal("alice@example.com"))
+		Expect(u.DisplayName).To(Equal("Alice"))
+		Expect(u.ExternalID).To(Equal("ext-1"))
+	})
+
+	It("adds and removes group members", func() {
+		g := &Group{DisplayName: "dev", Members: []*Member{{Value: "uid-1"}}}
+		for _, op := range patch(`{"Operations": [
+			{"op": "add", "path": "members", "value": [{"value": "uid-1"}, {"value": "uid-2"}, {"value": "uid-3"}]},
+			{"op": "remove", "path": "members[value eq \"uid-1\"]"},
+			{"op": "replace", "path": "displayName", "value": "developers"}
+		]}`) {
+			Expect(applyGroupPatch(g, op)).To(Succeed())
+		}
+		Expect(g.DisplayName).To(Equal("developers"))
+		Expect(g.Members).To(HaveLen(2))
+		Expect(g.Members[0].Value).To(Equal("uid-2"))
+		Expect(g.Members[1].Value).To(Equal("uid-3"))
+	})
+})
@@ -0,0 +1,516 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/koderover/zadig/pkg/microservice/user/config"
+	"github.com/koderover/zadig/pkg/microservice/user/core"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/orm"
+	"github.com/koderover/zadig/pkg/microservice/user/core/service/user"
+)
+
+const resourceUser = "User"
+
+// ListUsers lists the users managed by the identity provider, which are the users of the scim identity type.
+func ListUsers(args *ListArgs, logger *zap.SugaredLogger) (*ListResponse, error) {
+	args.normalize()
+	f, err := parseFilter(args.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := orm.ListUsersByIdentityType(config.SCIMIdentityType(), core.DB)
+	if err != nil {
+		logger.Errorf("failed to list users, err: %s", err)
+		return nil, errInternal(err)
+	}
+	uids := make([]string, 0, len(users))
+	for _, u := range users {
+		uids = append(uids, u.UID)
+	}
+	scimUsers, err := listSCIMUserMap(uids)
+	if err != nil {
+		logger.Errorf("failed to list scim users, err: %s", err)
+		return nil, errInternal(err)
+	}
+
+	matched := make([]models.User, 0, len(users))
+	for _, u := range users {
+		if f == nil || matchUser(f, &u, scimUsers[u.UID]) {
+			matched = append(matched, u)
+		}
+	}
+
+	resp := &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: int64(len(matched)),
+		StartIndex:   args.StartIndex,
+		Resources:    []interface{}{},
+	}
+	if args.StartIndex > len(matched) {
+		return resp, nil
+	}
+	end := args.StartIndex - 1 + args.Count
+	if end > len(matched) {
+		end = len(matched)
+	}
+	page := matched[args.StartIndex-1 : end]
+	groups, err := listUserGroupMap(uids)
+	if err != nil {
+		logger.Errorf("failed to list groups of users, err: %s", err)
+		return nil, errInternal(err)
+	}
+	for i := range page {
+		resp.Resources = append(resp.Resources, toSCIMUser(&page[i], scimUsers[page[i].UID], groups[page[i].UID]))
+	}
+	resp.ItemsPerPage = len(resp.Resources)
+	return resp, nil
+}
+
+func matchUser(f *filter, u *models.User, scimUser *models.SCIMUser) bool {
+	switch f.Attribute {
+	case "id":
+		return u.UID == f.Value
+	case "username":
+		// user names are case insensitive in scim
+		return strings.EqualFold(u.Account, f.Value)
+	case "externalid":
+		return scimUser != nil && scimUser.ExternalID == f.Value
+	case "emails", "emails.value":
+		return strings.EqualFold(u.Email, f.Value)
+	case "displayname":
+		return u.Name == f.Value
+	default:
+		return false
+	}
+}
+
+func GetUser(uid string, logger *zap.SugaredLogger) (*User, error) {
+	u, err := orm.GetUserByUid(uid, core.DB)
+	if err != nil {
+		logger.Errorf("failed to get user %s, err: %s", uid, err)
+		return nil, errInternal(err)
+	}
+	if u == nil || u.IdentityType != config.SCIMIdentityType() {
+		return nil, errNotFound(resourceUser, uid)
+	}
+	scimUser, err := orm.GetSCIMUser(uid, core.DB)
+	if err != nil {
+		logger.Errorf("failed to get scim user %s, err: %s", uid, err)
+		return nil, errInternal(err)
+	}
+	groups, err := listUserGroupMap([]string{uid})
+	if err != nil {
+		logger.Errorf("failed to list groups of user %s, err: %s", uid, err)
+		return nil, errInternal(err)
+	}
+	return toSCIMUser(u, scimUser, groups[uid]), nil
+}
+
+func CreateUser(args *User, logger *zap.SugaredLogger) (*User, error) {
+	if args.UserName == "" {
+		return nil, errInvalidValue("userName is required")
+	}
+	existed, err := orm.GetUser(args.UserName, config.SCIMIdentityType(), core.DB)
+	if err != nil {
+		logger.Errorf("failed to get user %s, err: %s", args.UserName, err)
+		return nil, errInternal(err)
+	}
+	if existed != nil {
+		return nil, newError(http.StatusConflict, "uniqueness", "user %s already exists", args.UserName)
+	}
+
+	uid, _ := uuid.NewUUID()
+	u := &models.User{
+		UID:          uid.String(),
+		IdentityType: config.SCIMIdentityType(),
+		Account:      args.UserName,
+	}
+	applyUserAttributes(u, args)
+	password, err := hashPassword(args.Password)
+	if err != nil {
+		return nil, errInternal(err)
+	}
+	scimUser := &models.SCIMUser{
+		UID:        u.UID,
+		ExternalID: args.ExternalID,
+		Active:     args.Active == nil || *args.Active,
+	}
+
+	err = transaction(func(tx *gorm.DB) error {
+		if err := orm.CreateUser(u, tx); err != nil {
+			return err
+		}
+		userLogin := &models.UserLogin{
+			UID:       u.UID,
+			Password:  password,
+			LoginId:   u.Account,
+			LoginType: int(config.AccountLoginType),
+		}
+		if err := orm.CreateUserLogin(userLogin, tx); err != nil {
+			return err
+		}
+		return orm.CreateSCIMUser(scimUser, tx)
+	})
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, newError(http.StatusConflict, "uniqueness", "user %s already exists", args.UserName)
+		}
+		logger.Errorf("failed to create user %s, err: %s", args.UserName, err)
+		return nil, errInternal(err)
+	}
+	return toSCIMUser(u, scimUser, nil), nil
+}
+
+// ReplaceUser replaces the attributes of a user, the attributes which are not given are cleared.
+func ReplaceUser(uid string, args *User, logger *zap.SugaredLogger) (*User, error) {
+	current, err := GetUser(uid, logger)
+	if err != nil {
+		return nil, err
+	}
+	if args.UserName == "" {
+		args.UserName = current.UserName
+	}
+	if args.Active == nil {
+		args.Active = current.Active
+	}
+	args.ID = uid
+	return saveUser(args, logger)
+}
+
+// PatchUser applies the patch operations to a user, the operations are applied in order and
+// either all of them succeed or none of them is applied.
+func PatchUser(uid string, args *PatchRequest, logger *zap.SugaredLogger) (*User, error) {
+	current, err := GetUser(uid, logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range args.Operations {
+		if err := applyUserPatch(current, op); err != nil {
+			return nil, err
+		}
+	}
+	return saveUser(current, logger)
+}
+
+func DeleteUser(uid string, logger *zap.SugaredLogger) error {
+	if _, err := GetUser(uid, logger); err != nil {
+		return err
+	}
+	if err := user.DeleteUserByUID(uid, logger); err != nil {
+		logger.Errorf("failed to delete user %s, err: %s", uid, err)
+		return errInternal(err)
+	}
+	return nil
+}
+
+func saveUser(args *User, logger *zap.SugaredLogger) (*User, error) {
+	u, err := orm.GetUserByUid(args.ID, core.DB)
+	if err != nil {
+		return nil, errInternal(err)
+	}
+	if u == nil {
+		return nil, errNotFound(resourceUser, args.ID)
+	}
+	if args.UserName != u.Account {
+		existed, err := orm.GetUser(args.UserName, u.IdentityType, core.DB)
+		if err != nil {
+			return nil, errInternal(err)
+		}
+		if existed != nil {
+			return nil, newError(http.StatusConflict, "uniqueness", "user %s already exists", args.UserName)
+		}
+	}
+	accountChanged := args.UserName != u.Account
+	u.Account = args.UserName
+	u.Name, u.Email, u.Phone = "", "", ""
+	applyUserAttributes(u, args)
+
+	var password string
+	if args.Password != "" {
+		if password, err = hashPassword(args.Password); err != nil {
+			return nil, errInternal(err)
+		}
+	}
+	scimUser, err := orm.GetSCIMUser(u.UID, core.DB)
+	if err != nil {
+		return nil, errInternal(err)
+	}
+	// users created before scim is enabled have no provisioning state yet
+	created := scimUser == nil
+	if created {
+		scimUser = &models.SCIMUser{UID: u.UID}
+	}
+	scimUser.ExternalID = args.ExternalID
+	scimUser.Active = args.Active == nil || *args.Active
+
+	err = transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("uid = ?", u.UID).
+			Select("name", "email", "phone", "account").Updates(u).Error; err != nil {
+			return err
+		}
+		if accountChanged || password != "" {
+			if err := orm.UpdateUserLogin(u.UID, &models.UserLogin{LoginId: u.Account, Password: password}, tx); err != nil {
+				return err
+			}
+		}
+		if created {
+			return orm.CreateSCIMUser(scimUser, tx)
+		}
+		return orm.UpdateSCIMUser(scimUser, tx)
+	})
+	if err != nil {
+		logger.Errorf("failed to update user %s, err: %s", u.UID, err)
+		return nil, errInternal(err)
+	}
+
+	groups, err := listUserGroupMap([]string{u.UID})
+	if err != nil {
+		return nil, errInternal(err)
+	}
+	return toSCIMUser(u, scimUser, groups[u.UID]), nil
+}
+
+func applyUserPatch(u *User, op *PatchOperation) error {
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		if op.Path == "" {
+			// the value is an object of attributes when no path is given
+			attributes := map[string]json.RawMessage{}
+			if err := json.Unmarshal(op.Value, &attributes); err != nil {
+				return errInvalidValue("invalid value of operation %s: %s", op.Op, err)
+			}
+			for path, value := range attributes {
+				if err := setUserAttribute(u, path, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return setUserAttribute(u, op.Path, op.Value)
+	case "remove":
+		return setUserAttribute(u, op.Path, nil)
+	default:
+		return errInvalidValue("unsupported operation: %s", op.Op)
+	}
+}
+
+// setUserAttribute sets an attribute of the user, the attribute is cleared if value is nil.
+// Attributes which are not stored by zadig are ignored.
+func setUserAttribute(u *User, path string, value json.RawMessage) error {
+	attr, _, err := parseValuePath(path)
+	if err != nil {
+		return err
+	}
+	// sub attributes of a multi valued attribute, e.g. emails[type eq "work"].value
+	attr = strings.TrimSuffix(attr, ".value")
+	attr = strings.TrimPrefix(attr, strings.ToLower(SchemaUser)+":")
+
+	var s string
+	if value != nil && attr != "active" && attr != "emails" && attr != "phonenumbers" && attr != "name" {
+		if err := json.Unmarshal(value, &s); err != nil {
+			return errInvalidValue("invalid value of %s: %s", path, err)
+		}
+	}
+	switch attr {
+	case "active":
+		active := false
+		if value != nil {
+			if active, err = parseBool(value); err != nil {
+				return errInvalidValue("invalid value of %s: %s", path, err)
+			}
+		}
+		u.Active = &active
+	case "username":
+		if s == "" {
+			return newError(http.StatusBadRequest, "mutability", "userName can not be removed")
+		}
+		u.UserName = s
+	case "externalid":
+		u.ExternalID = s
+	case "displayname":
+		u.DisplayName = s
+	case "password":
+		u.Password = s
+	case "name":
+		u.Name = nil
+		if value != nil {
+			u.Name = &Name{}
+			if err := json.Unmarshal(value, u.Name); err != nil {
+				return errInvalidValue("invalid value of %s: %s", path, err)
+			}
+		}
+	case "name.formatted", "name.givenname", "name.familyname":
+		if u.Name == nil {
+			u.Name = &Name{}
+		}
+		switch attr {
+		case "name.formatted":
+			u.Name.Formatted = s
+		case "name.givenname":
+			u.Name.GivenName = s
+		default:
+			u.Name.FamilyName = s
+		}
+	case "emails":
+		values, err := parseMultiValued(value)
+		if err != nil {
+			return errInvalidValue("invalid value of %s: %s", path, err)
+		}
+		u.Emails = values
+	case "phonenumbers":
+		values, err := parseMultiValued(value)
+		if err != nil {
+			return errInvalidValue("invalid value of %s: %s", path, err)
+		}
+		u.PhoneNumbers = values
+	}
+	return nil
+}
+
+// parseMultiValued accepts a list of values, a single value or a plain string.
+func parseMultiValued(value json.RawMessage) ([]*MultiValued, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var values []*MultiValued
+	if err := json.Unmarshal(value, &values); err == nil {
+		return values, nil
+	}
+	single := &MultiValued{}
+	if err := json.Unmarshal(value, single); err == nil {
+		return []*MultiValued{single}, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return nil, err
+	}
+	return []*MultiValued{{Value: s, Primary: true}}, nil
+}
+
+func applyUserAttributes(u *models.User, args *User) {
+	u.Name = displayName(args)
+	u.Email = primaryValue(args.Emails)
+	u.Phone = primaryValue(args.PhoneNumbers)
+}
+
+func displayName(args *User) string {
+	if args.DisplayName != "" {
+		return args.DisplayName
+	}
+	if args.Name != nil {
+		if args.Name.Formatted != "" {
+			return args.Name.Formatted
+		}
+		if name := strings.TrimSpace(args.Name.GivenName + " " + args.Name.FamilyName); name != "" {
+			return name
+		}
+	}
+	return args.UserName
+}
+
+func primaryValue(values []*MultiValued) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// hashPassword hashes the password, a random one is used if the identity provider does not
+// provide it so that the user can not login with an empty password.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		password = uuid.NewString()
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func toSCIMUser(u *models.User, scimUser *models.SCIMUser, groups []*Member) *User {
+	active := scimUser == nil || scimUser.Active
+	resp := &User{
+		Schemas:     []string{SchemaUser},
+		ID:          u.UID,
+		UserName:    u.Account,
+		DisplayName: u.Name,
+		Name:        &Name{Formatted: u.Name},
+		Active:      &active,
+		Groups:      groups,
+		Meta:        newMeta(resourceUser, u.CreatedAt, u.UpdatedAt),
+	}
+	if scimUser != nil {
+		resp.ExternalID = scimUser.ExternalID
+	}
+	if u.Email != "" {
+		resp.Emails = []*MultiValued{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	if u.Phone != "" {
+		resp.PhoneNumbers = []*MultiValued{{Value: u.Phone, Type: "work", Primary: true}}
+	}
+	return resp
+}
+
+func listSCIMUserMap(uids []string) (map[string]*models.SCIMUser, error) {
+	resp := map[string]*models.SCIMUser{}
+	if len(uids) == 0 {
+		return resp, nil
+	}
+	scimUsers, err := orm.ListSCIMUsersByUIDs(uids, core.DB)
+	if err != nil {
+		return nil, err
+	}
+	for i := range scimUsers {
+		resp[scimUsers[i].UID] = &scimUsers[i]
+	}
+	return resp, nil
+}
+
+func transaction(fn func(tx *gorm.DB) error) (err error) {
+	tx := core.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/user/core/service/login"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+var _ = Describe("Testing ldap sync", func() {
+	users := []*SyncUserInfo{{Account: "alice"}, {Account: "bob"}, {Account: "carol"}}
+
+	It("skips the deactivated users and syncs the rest", func() {
+		synced := make([]string, 0)
+		err := syncLDAPUsers("ldap:389", users, func(info *SyncUserInfo, _ *zap.SugaredLogger) (*models.User, error) {
+			if info.Account == "bob" {
+				return nil, login.ErrUserDeactivated
+			}
+			synced = append(synced, info.Account)
+			return &models.User{Account: info.Account}, nil
+		}, log.NopSugaredLogger())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(synced).To(Equal([]string{"alice", "carol"}))
+	})
+
+	It("stops at the other errors", func() {
+		synced := make([]string, 0)
+		err := syncLDAPUsers("ldap:389", users, func(info *SyncUserInfo, _ *zap.SugaredLogger) (*models.User, error) {
+			if info.Account == "bob" {
+				return nil, errors.New("connection refused")
+			}
+			synced = append(synced, info.Account)
+			return &models.User{Account: info.Account}, nil
+		}, log.NopSugaredLogger())
+		Expect(err).To(HaveOccurred())
+		Expect(synced).To(Equal([]string{"alice"}))
+	})
+})
@@ -129,20 +129,34 @@ func SearchAndSyncUser(ldapId string, logger *zap.SugaredLogger) error {
 		logger.Errorf("ldap search host:%s error, error msg:%s", config.Host, err)
 		return err
 	}
+	users := make([]*SyncUserInfo, 0, len(sr.Entries))
 	for _, entry := range sr.Entries {
 		account := config.UserSearch.PreferredUsernameAttrAttr
 		name := account
 		if len(config.UserSearch.NameAttr) != 0 {
 			name = config.UserSearch.NameAttr
 		}
-		_, err := SyncUser(&SyncUserInfo{
+		users = append(users, &SyncUserInfo{
 			Account:      entry.GetAttributeValue(account),
 			Name:         entry.GetAttributeValue(name),
 			Email:        entry.GetAttributeValue(config.UserSearch.EmailAttr),
 			IdentityType: si.ID, // ldap may have not only one instance, so use id as identityType
-		}, logger)
+		})
+	}
+	return syncLDAPUsers(config.Host, users, SyncUser, logger)
+}
+
+// syncLDAPUsers skips the users deactivated through scim, they stay deactivated until the identity provider
+// activates them again.
+func syncLDAPUsers(host string, users []*SyncUserInfo, sync func(*SyncUserInfo, *zap.SugaredLogger) (*models.User, error), logger *zap.SugaredLogger) error {
+	for _, info := range users {
+		_, err := sync(info, logger)
+		if errors.Is(err, login.ErrUserDeactivated) {
+			logger.Infof("ldap host:%s skip the deactivated user %s", host, info.Account)
+			continue
+		}
 		if err != nil {
-			logger.Errorf("ldap host:%s sync user error, error msg:%s", config.Host, err)
+			logger.Errorf("ldap host:%s sync user error, error msg:%s", host, err)
 			return err
 		}
 	}
@@ -328,6 +342,18 @@ func DeleteUserByUID(uid string, logger *zap.SugaredLogger) error {
 		logger.Errorf("DeleteUserByUID DeleteUserLoginByUid:%s error, error msg:%s", uid, err.Error())
 		return err
 	}
+	err = orm.DeleteSCIMUserByUid(uid, tx)
+	if err != nil {
+		tx.Rollback()
+		logger.Errorf("DeleteUserByUID DeleteSCIMUserByUid:%s error, error msg:%s", uid, err.Error())
+		return err
+	}
+	err = orm.DeleteGroupBindingsByUid(uid, tx)
+	if err != nil {
+		tx.Rollback()
+		logger.Errorf("DeleteUserByUID DeleteGroupBindingsByUid:%s error, error msg:%s", uid, err.Error())
+		return err
+	}
 	return tx.Commit().Error
 }
 
@@ -367,7 +393,7 @@ func Retrieve(account string, logger *zap.SugaredLogger) (*RetrieveResp, error)
 	}
 	v := url.Values{}
 	v.Add("idtoken", token)
-	retrieveURL := configbase.SystemAddress() + "/signin?" + v.Encode()
+	retrieveURL := configbase.ExternalBaseURL() + "/signin?" + v.Encode()
 	body, err := mail.RenderEmailTemplate(retrieveURL, string(retrieveHemlTemplate))
 	if err != nil {
 		logger.Errorf("Retrieve renderEmailTemplate error, error msg:%s ", err)
@@ -544,6 +570,10 @@ func SyncUser(syncUserInfo *SyncUserInfo, logger *zap.SugaredLogger) (*models.Us
 			return nil, err
 		}
 	} else {
+		if err := login.CheckUserActive(user.UID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 		err = orm.UpdateUser(user.UID, &models.User{
 			Name:    syncUserInfo.Name,
 			Account: syncUserInfo.Account,
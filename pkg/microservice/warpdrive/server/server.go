@@ -23,8 +23,10 @@ import (
 	"time"
 
 	commonconfig "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/warpdrive/config"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/taskcontroller"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/healthz"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -44,7 +46,10 @@ func Serve(ctx context.Context) error {
 		return fmt.Errorf("failed to init controller: %s", err)
 	}
 
+	go healthz.SelfCheck(ctx, commonconfig.WarpDriveServiceName(), healthChecks())
+
 	http.HandleFunc("/ping", ping)
+	http.HandleFunc("/healthz", healthz.Handler(commonconfig.WarpDriveServiceName(), healthChecks))
 	server := &http.Server{Addr: ":25001", Handler: nil}
 
 	stopChan := make(chan struct{})
@@ -74,3 +79,15 @@ func Serve(ctx context.Context) error {
 func ping(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("success"))
 }
+
+// healthChecks returns the dependencies of warpdrive, the tasks are received from nsq and reported
+// to aslan.
+func healthChecks() []*healthz.Check {
+	checks := []*healthz.Check{{
+		Name: "aslan",
+		Hint: "check that the pod of aslan is running, the status of the tasks is not updated until aslan is reachable",
+		Run:  healthz.HTTPGet(commonconfig.AslanServiceAddress() + "/api/health"),
+	}}
+	hint := fmt.Sprintf("check that nsqlookupd is running and %s is correct", setting.ENVNsqLookupAddrs)
+	return append(checks, healthz.NSQLookupdChecks(config.NSQLookupAddrs(), hint)...)
+}
@@ -17,12 +17,14 @@ limitations under the License.
 package gin
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/koderover/zadig/pkg/setting"
 	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/i18n"
 )
 
 // Response handle response
@@ -40,7 +42,14 @@ func handleResponse(c *gin.Context) {
 	}
 
 	if v, ok := c.Get(setting.ResponseError); ok {
-		c.JSON(e.ErrorMessage(v.(error)))
+		code, message := e.LocalizedErrorMessage(v.(error), responseLanguage(c))
+		// tell the clients when to retry if the service is unavailable temporarily.
+		if extra, ok := message["extra"].(map[string]interface{}); ok {
+			if retryAfter, ok := extra["retry_after"]; ok {
+				c.Header("Retry-After", fmt.Sprint(retryAfter))
+			}
+		}
+		c.JSON(code, message)
 		return
 	}
 
@@ -51,6 +60,18 @@ func handleResponse(c *gin.Context) {
 	}
 }
 
+// responseLanguage returns the language chosen by the user, the one preferred by the client
+// or the default language in order.
+func responseLanguage(c *gin.Context) string {
+	if lang := c.GetString(setting.ResponseLanguage); lang != "" {
+		return lang
+	}
+	if lang := i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language")); lang != "" {
+		return lang
+	}
+	return i18n.DefaultLanguage()
+}
+
 func setResponse(resp interface{}, c *gin.Context) {
 	switch r := resp.(type) {
 	case string:
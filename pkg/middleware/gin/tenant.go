@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	tenantservice "github.com/koderover/zadig/pkg/microservice/aslan/core/tenant/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+)
+
+// TenantIsolation rejects the requests to the projects of other tenants when the tenant isolation
+// is enabled.
+func TenantIsolation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectName := c.Query("projectName")
+		if !config.TenantIsolationEnabled() || projectName == "" {
+			c.Next()
+			return
+		}
+
+		ctx := internalhandler.NewContext(c)
+		if err := tenantservice.CheckProjectAccess(projectName, ctx.UserID, ctx.Logger); err != nil {
+			ctx.Err = err
+			internalhandler.JSONResponse(c, ctx)
+			return
+		}
+		c.Next()
+	}
+}
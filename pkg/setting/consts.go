@@ -35,6 +35,14 @@ const (
 	ENVMysqlPassword           = "MYSQL_PASSWORD"
 	ENVMysqlHost               = "MYSQL_HOST"
 	ENVMysqlUserDb             = "MYSQL_USER_DB"
+	ENVRedisAddress            = "REDIS_ADDRESS"
+	ENVRedisPassword           = "REDIS_PASSWORD"
+	ENVRedisDB                 = "REDIS_DB"
+	ENVClientTimeout           = "CLIENT_TIMEOUT"
+	ENVClientRetryCount        = "CLIENT_RETRY_COUNT"
+	ENVClientBreakerThreshold  = "CLIENT_BREAKER_THRESHOLD"
+	ENVClientBreakerCoolDown   = "CLIENT_BREAKER_COOL_DOWN"
+	ENVDefaultLanguage         = "DEFAULT_LANGUAGE"
 
 	// Aslan
 	ENVPodName              = "BE_POD_NAME"
@@ -47,6 +55,18 @@ const (
 	ENVAslanRegAccessKey    = "DEFAULT_REGISTRY_AK"
 	ENVAslanRegSecretKey    = "DEFAULT_REGISTRY_SK"
 	ENVAslanRegNamespace    = "DEFAULT_REGISTRY_NAMESPACE"
+	ENVWebhookWorkers       = "WEBHOOK_WORKERS"
+	ENVBootstrapConfigFile  = "BOOTSTRAP_CONFIG_FILE"
+	ENVGitMirrorPath        = "GIT_MIRROR_PATH"
+	ENVDevSeedSnapshot      = "DEV_SEED_SNAPSHOT"
+	ENVDevMockProviderAddr  = "DEV_MOCK_PROVIDER_ADDR"
+
+	// key provider of the stored secrets
+	ENVEncryptionKeyProvider = "ENCRYPTION_KEY_PROVIDER"
+	ENVEncryptionKeyID       = "ENCRYPTION_KEY_ID"
+	ENVEncryptionKMSRegion   = "ENCRYPTION_KMS_REGION"
+	ENVVaultAddress          = "VAULT_ADDR"
+	ENVVaultToken            = "VAULT_TOKEN"
 
 	ENVGithubSSHKey    = "GITHUB_SSH_KEY"
 	ENVGithubKnownHost = "GITHUB_KNOWN_HOST"
@@ -63,6 +83,8 @@ const (
 
 	ENVOldEnvSupported = "OLD_ENV_SUPPORTED"
 
+	ENVTenantIsolation = "TENANT_ISOLATION"
+
 	ENVS3StorageAK       = "S3STORAGE_AK"
 	ENVS3StorageSK       = "S3STORAGE_SK"
 	ENVS3StorageEndpoint = "S3STORAGE_ENDPOINT"
@@ -118,10 +140,23 @@ const (
 	ENVScopes         = "SCOPES"
 	ENVTokenExpiresAt = "TOKEN_EXPIRES_AT"
 	ENVUserPort       = "USER_PORT"
+	// ENVSCIMToken is the bearer token of the SCIM provisioning API, the API is disabled if it is empty.
+	ENVSCIMToken = "SCIM_TOKEN"
+	// ENVSCIMIdentityType is the identity type of the provisioned users, it should be the id
+	// of the SSO connector so that the provisioned users are used when they log in.
+	ENVSCIMIdentityType = "SCIM_IDENTITY_TYPE"
 
 	// config
 	ENVMysqlDexDB = "MYSQL_DEX_DB"
 	FeatureFlag   = "feature-gates"
+	// ENVCodeHostCacheSize is the max number of the codehosts cached in memory, the cache is disabled if it is negative.
+	ENVCodeHostCacheSize = "CODEHOST_CACHE_SIZE"
+	// ENVCodeHostCacheTTL is how long a codehost is cached in memory, unit is second.
+	ENVCodeHostCacheTTL = "CODEHOST_CACHE_TTL"
+	// ENVCodeHostSecretBackend is where the credentials of the codehosts are stored, one of mongo, vault and kms.
+	ENVCodeHostSecretBackend = "CODEHOST_SECRET_BACKEND"
+	// ENVCodeHostSecretVaultMount is the mount of the kv secrets engine of vault the credentials are stored in.
+	ENVCodeHostSecretVaultMount = "CODEHOST_SECRET_VAULT_MOUNT"
 
 	// initconfig
 	ENVAdminEmail    = "ADMIN_EMAIL"
@@ -138,6 +173,7 @@ const (
 	Service               = "Service"
 	Deployment            = "Deployment"
 	StatefulSet           = "StatefulSet"
+	DaemonSet             = "DaemonSet"
 	Pod                   = "Pod"
 	ReplicaSet            = "ReplicaSet"
 	Job                   = "Job"
@@ -173,11 +209,18 @@ const (
 	ModifiedByAnnotation            = companyLabel + "/" + "last-modified-by"
 	EditorIDAnnotation              = companyLabel + "/" + "editor-id"
 	LastUpdateTimeAnnotation        = companyLabel + "/" + "last-update-time"
+	ManagedNamespaceMetaAnnotation  = companyLabel + "/" + "managed-namespace-meta"
 
 	JobLabelTaskKey  = "s-task"
 	JobLabelNameKey  = "s-name"
 	JobLabelSTypeKey = "s-type"
 
+	JobLabelWarmPoolKey     = "s-warm-pool"
+	JobLabelWarmStateKey    = "s-warm-state"
+	JobLabelWarmRevisionKey = "s-warm-revision"
+	JobWarmStateIdle        = "idle"
+	JobWarmStateClaimed     = "claimed"
+
 	LabelValueTrue = "true"
 
 	// Pod status
@@ -254,6 +297,16 @@ const (
 	SourceFromCodeHub = "codehub"
 	// SourceFromGitee Configure the source as gitee
 	SourceFromGitee = "gitee"
+	// SourceFromBitbucket The configuration source is bitbucket cloud
+	SourceFromBitbucket = "bitbucket"
+	// SourceFromBitbucketServer The configuration source is bitbucket data center/server
+	SourceFromBitbucketServer = "bitbucket_server"
+	// SourceFromGitea The configuration source is gitea
+	SourceFromGitea = "gitea"
+	// SourceFromAzureDevOps The configuration source is azure devops repos
+	SourceFromAzureDevOps = "azure_devops"
+	// SourceFromPerforce The configuration source is perforce helix core
+	SourceFromPerforce = "perforce"
 	// SourceFromGitee Configure the source as other
 	SourceFromOther = "other"
 	// SourceFromChartTemplate The configuration source is helmTemplate
@@ -262,6 +315,8 @@ const (
 	SourceFromPublicRepo = "publicRepo"
 	SourceFromChartRepo  = "chartRepo"
 	SourceFromCustomEdit = "customEdit"
+	// SourceFromUmbrellaChart The configuration source is a subchart of an umbrella chart in a git repo
+	SourceFromUmbrellaChart = "umbrellaChart"
 
 	// SourceFromGUI The configuration source is gui
 	SourceFromGUI = "gui"
@@ -425,6 +480,8 @@ const (
 	TemplatesDir = "templates"
 	// ServiceTemplateCounterName 服务模板counter name
 	ServiceTemplateCounterName = "service:%s&project:%s"
+	// CodeHostCounterName codehost id counter name
+	CodeHostCounterName = "codehost"
 	// GerritDefaultOwner
 	GerritDefaultOwner = "dafault"
 	// YamlFileSeperator ...
@@ -441,11 +498,17 @@ const (
 	ProxySocks5Addr = "PROXY_SOCKS_ADDR"
 )
 
+// ENVWebhookRelayURL is the address of the webhook relay, the webhooks created by an instance which is
+// not exposed to the internet point to the relay instead of the instance itself.
+const ENVWebhookRelayURL = "WEBHOOK_RELAY_URL"
+
 const (
 	// WebhookTaskCreator ...
 	WebhookTaskCreator = "webhook"
 	// CronTaskCreator ...
 	CronTaskCreator = "timer"
+	// EnvAutoUpdateTaskCreator creates the tasks deploying the new builds to the environments automatically
+	EnvAutoUpdateTaskCreator = "env_auto_update"
 	// DefaultTaskRevoker ...
 	DefaultTaskRevoker = "system" // default task revoker
 )
@@ -516,8 +579,16 @@ const (
 	FixedGapCronjob     = "gap"
 	CrontabCronjob      = "crontab"
 
-	WorkflowCronjob = "workflow"
-	TestingCronjob  = "test"
+	WorkflowCronjob       = "workflow"
+	TestingCronjob        = "test"
+	EnvDataRefreshCronjob = "env_data_refresh"
+	EnvSmokeTestCronjob   = "env_smoke_test"
+	ReleaseTrainCronjob   = "release_train"
+
+	// catch up policies of the runs missed while the cron service is down
+	CatchUpSkip         = "skip"
+	CatchUpRunOnce      = "run_once"
+	CatchUpRunAllMissed = "run_all_missed"
 
 	TopicProcess      = "task.process"
 	TopicCancel       = "task.cancel"
@@ -588,8 +659,9 @@ const DogFood = "/var/run/koderover-dog-food"
 const ProgressFile = "/var/log/job-progress"
 
 const (
-	ResponseError = "error"
-	ResponseData  = "response"
+	ResponseError    = "error"
+	ResponseData     = "response"
+	ResponseLanguage = "language"
 )
 
 const ChartTemplatesPath = "charts"
@@ -602,6 +674,7 @@ const (
 	ProjectAdmin    RoleType = "project-admin"
 	SystemAdmin     RoleType = "admin"
 	ReadProjectOnly RoleType = "read-project-only"
+	Viewer          RoleType = "viewer"
 )
 
 // ModernWorkflowType 自由编排工作流
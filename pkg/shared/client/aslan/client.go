@@ -32,7 +32,8 @@ type Client struct {
 
 func New(host string) *Client {
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api"),
+		httpclient.SetHostURL(host+"/api"),
+		httpclient.SetServiceClient("aslan"),
 	)
 
 	return &Client{
@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aslan
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type CodeHostReference struct {
+	Type    string `json:"type"`
+	Project string `json:"project"`
+	Name    string `json:"name"`
+}
+
+// ListCodeHostReferences returns the builds, testings, scannings, services and workflows which clone the
+// repos of the codehost.
+func (c *Client) ListCodeHostReferences(codehostID int) ([]*CodeHostReference, error) {
+	url := fmt.Sprintf("/system/codehost/%d/references", codehostID)
+	res := make([]*CodeHostReference, 0)
+	_, err := c.Get(url, httpclient.SetResult(&res))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -29,6 +29,7 @@ type Client struct {
 func New(host string) *Client {
 	c := httpclient.New(
 		httpclient.SetHostURL(host),
+		httpclient.SetServiceClient("aslanx"),
 	)
 
 	return &Client{
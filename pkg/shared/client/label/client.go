@@ -30,7 +30,8 @@ type Client struct {
 func New() *Client {
 	host := config.AslanServiceAddress()
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api"),
+		httpclient.SetHostURL(host+"/api"),
+		httpclient.SetServiceClient("aslan"),
 	)
 
 	return &Client{
@@ -31,7 +31,8 @@ func New() *Client {
 	host := config.VendorServiceAddress()
 
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api/plutus"),
+		httpclient.SetHostURL(host+"/api/plutus"),
+		httpclient.SetServiceClient("plutus-vendor"),
 	)
 
 	return &Client{
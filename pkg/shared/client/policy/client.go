@@ -31,7 +31,8 @@ func NewDefault() *Client {
 	host := config.AslanServiceAddress()
 
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api/v1"),
+		httpclient.SetHostURL(host+"/api/v1"),
+		httpclient.SetServiceClient("aslan"),
 	)
 
 	return &Client{
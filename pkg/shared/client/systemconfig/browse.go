@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systemconfig
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type CodeHostNamespace struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Kind        string `json:"kind"`
+	ProjectUUID string `json:"project_uuid,omitempty"`
+}
+
+type CodeHostRepo struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	DefaultBranch string `json:"defaultBranch"`
+	Namespace     string `json:"namespace"`
+	RepoUUID      string `json:"repo_uuid,omitempty"`
+	RepoID        string `json:"repo_id,omitempty"`
+}
+
+type CodeHostBranch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+	Merged    bool   `json:"merged"`
+}
+
+type CodeHostTag struct {
+	Name       string `json:"name"`
+	ZipballURL string `json:"zipball_url"`
+	TarballURL string `json:"tarball_url"`
+	Message    string `json:"message"`
+}
+
+// BrowseOption filters the lists of a codehost, the codehost must be available to ProjectName if it is set.
+type BrowseOption struct {
+	ProjectName string
+	Namespace   string
+	// NamespaceType is user, group or org, it is only used to list the repos.
+	NamespaceType string
+	Repo          string
+	Key           string
+	Page          int
+	PerPage       int
+}
+
+func (o *BrowseOption) queryParams() map[string]string {
+	params := map[string]string{
+		"projectName": o.ProjectName,
+		"namespace":   o.Namespace,
+		"repo":        o.Repo,
+		"key":         o.Key,
+	}
+	if o.NamespaceType != "" {
+		params["type"] = o.NamespaceType
+	}
+	if o.Page > 0 {
+		params["page"] = strconv.Itoa(o.Page)
+	}
+	if o.PerPage > 0 {
+		params["per_page"] = strconv.Itoa(o.PerPage)
+	}
+	return params
+}
+
+func (c *Client) ListCodeHostNamespaces(id int, opt *BrowseOption) ([]*CodeHostNamespace, error) {
+	url := fmt.Sprintf("/codehosts/%d/namespaces", id)
+
+	res := make([]*CodeHostNamespace, 0)
+	_, err := c.Get(url, httpclient.SetQueryParams(opt.queryParams()), httpclient.SetResult(&res))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListCodeHostRepos(id int, opt *BrowseOption) ([]*CodeHostRepo, error) {
+	url := fmt.Sprintf("/codehosts/%d/repos", id)
+
+	res := make([]*CodeHostRepo, 0)
+	_, err := c.Get(url, httpclient.SetQueryParams(opt.queryParams()), httpclient.SetResult(&res))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListCodeHostBranches(id int, opt *BrowseOption) ([]*CodeHostBranch, error) {
+	url := fmt.Sprintf("/codehosts/%d/branches", id)
+
+	res := make([]*CodeHostBranch, 0)
+	_, err := c.Get(url, httpclient.SetQueryParams(opt.queryParams()), httpclient.SetResult(&res))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) ListCodeHostTags(id int, opt *BrowseOption) ([]*CodeHostTag, error) {
+	url := fmt.Sprintf("/codehosts/%d/tags", id)
+
+	res := make([]*CodeHostTag, 0)
+	_, err := c.Get(url, httpclient.SetQueryParams(opt.queryParams()), httpclient.SetResult(&res))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
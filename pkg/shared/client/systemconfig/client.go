@@ -30,7 +30,8 @@ type Client struct {
 func New() *Client {
 	host := config.AslanServiceAddress()
 	c := httpclient.New(
-		httpclient.SetHostURL(host + "/api/v1"),
+		httpclient.SetHostURL(host+"/api/v1"),
+		httpclient.SetServiceClient("aslan"),
 	)
 
 	return &Client{
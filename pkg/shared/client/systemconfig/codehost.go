@@ -17,19 +17,29 @@ limitations under the License.
 package systemconfig
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
+	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/util"
 )
 
 const (
-	GitLabProvider  = "gitlab"
-	GitHubProvider  = "github"
-	GerritProvider  = "gerrit"
-	CodeHubProvider = "codehub"
-	GiteeProvider   = "gitee"
-	OtherProvider   = "other"
+	GitLabProvider          = "gitlab"
+	GitHubProvider          = "github"
+	GerritProvider          = "gerrit"
+	CodeHubProvider         = "codehub"
+	GiteeProvider           = "gitee"
+	BitbucketProvider       = "bitbucket"
+	BitbucketServerProvider = "bitbucket_server"
+	GiteaProvider           = "gitea"
+	AzureDevOpsProvider     = "azure_devops"
+	PerforceProvider        = "perforce"
+	OtherProvider           = "other"
 )
 
 type CodeHost struct {
@@ -38,6 +48,7 @@ type CodeHost struct {
 	Type         string `json:"type"`
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
 	Namespace    string `json:"namespace"`
 	Region       string `json:"region"`
 	// the field and tag not consistent because of db field
@@ -46,12 +57,120 @@ type CodeHost struct {
 	Username  string `json:"username"`
 	Password  string `json:"password"`
 	// the field determine whether the proxy is enabled
-	EnableProxy        bool           `json:"enable_proxy"`
-	UpdatedAt          int64          `json:"updated_at"`
-	Alias              string         `json:"alias,omitempty"`
-	AuthType           types.AuthType `json:"auth_type,omitempty"`
-	SSHKey             string         `json:"ssh_key,omitempty"`
-	PrivateAccessToken string         `json:"private_access_token,omitempty"`
+	EnableProxy        bool              `json:"enable_proxy"`
+	UpdatedAt          int64             `json:"updated_at"`
+	Alias              string            `json:"alias,omitempty"`
+	AuthType           types.AuthType    `json:"auth_type,omitempty"`
+	SSHKey             string            `json:"ssh_key,omitempty"`
+	PrivateAccessToken string            `json:"private_access_token,omitempty"`
+	RepoPolicy         *types.RepoPolicy `json:"repo_policy,omitempty"`
+	// Projects are the projects the codehost is bound to, they are ignored if the codehost is shared.
+	Projects []string `json:"projects,omitempty"`
+	// Shared codehosts are available to all the projects.
+	Shared bool `json:"shared"`
+	// the access token is a valid installation token if the codehost is authorized by a github app
+	GitHubAppID          int64 `json:"github_app_id,omitempty"`
+	GitHubInstallationID int64 `json:"github_installation_id,omitempty"`
+	// ProxyURL is the proxy of the codehost, the system proxy is used if it is empty.
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// NoProxy are the hosts accessed directly even if the proxy is enabled, e.g. gitlab.internal or .corp.com.
+	NoProxy []string `json:"no_proxy,omitempty"`
+	// the organization of azure devops the repos belong to, only the repos of AzureProject are listed if it is set.
+	AzureOrganization string `json:"azure_organization,omitempty"`
+	AzureProject      string `json:"azure_project,omitempty"`
+	// SSHPort is the port of the ssh daemon of gerrit, the repos are cloned over ssh if the auth type is SSH.
+	SSHPort int `json:"ssh_port,omitempty"`
+	// the disabled codehosts are returned only by GetRawCodeHost.
+	Enabled        bool   `json:"enabled"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+	// Revision is sent back by UpdateCodeHost, the update is rejected if the codehost has been modified since.
+	Revision int64 `json:"revision"`
+}
+
+// CodeHostDisabledError is returned if the codehost is disabled, e.g. during the upgrade of the provider, the
+// callers should surface it instead of failing later with a generic error, e.g. when the repos are cloned.
+type CodeHostDisabledError struct {
+	ID     int
+	Reason string
+}
+
+func (err *CodeHostDisabledError) Error() string {
+	if err.Reason == "" {
+		return fmt.Sprintf("codehost %d is disabled", err.ID)
+	}
+	return fmt.Sprintf("codehost %d is disabled: %s", err.ID, err.Reason)
+}
+
+func IsCodeHostDisabled(err error) bool {
+	var disabledErr *CodeHostDisabledError
+	return errors.As(err, &disabledErr)
+}
+
+// codeHostError converts the disabled error responded by systemconfig to a CodeHostDisabledError.
+func codeHostError(id int, err error) error {
+	var httpErr *httpclient.Error
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+	resp := struct {
+		Code  int `json:"code"`
+		Extra struct {
+			Reason string `json:"reason"`
+		} `json:"extra"`
+	}{}
+	if json.Unmarshal([]byte(httpErr.Detail), &resp) != nil || resp.Code != e.ErrCodeHostDisabled.Code() {
+		return err
+	}
+	return &CodeHostDisabledError{ID: id, Reason: resp.Extra.Reason}
+}
+
+// RepoAddress returns the address the repos of the codehost are under, which is the organization url
+// for azure devops, e.g. https://dev.azure.com/koderover.
+func (c *CodeHost) RepoAddress() string {
+	if c.Type == AzureDevOpsProvider && c.AzureOrganization != "" {
+		return strings.TrimSuffix(c.Address, "/") + "/" + c.AzureOrganization
+	}
+	return c.Address
+}
+
+// UseProxy reports whether the provider api of the codehost is accessed by proxy.
+func (c *CodeHost) UseProxy() bool {
+	return c.EnableProxy && !util.BypassProxy(c.Address, c.NoProxy)
+}
+
+// ProxyAddr returns the proxy the provider api of the codehost is accessed by, the proxy of the codehost
+// takes precedence over the system proxy.
+func (c *CodeHost) ProxyAddr(systemProxy string) string {
+	if !c.UseProxy() {
+		return ""
+	}
+	if c.ProxyURL != "" {
+		return c.ProxyURL
+	}
+	return systemProxy
+}
+
+// AvailableToProject reports whether the codehost can be used by the project.
+func (c *CodeHost) AvailableToProject(projectName string) bool {
+	if c.Shared {
+		return true
+	}
+	for _, project := range c.Projects {
+		if project == projectName {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableToAnyProject reports whether the codehost can be used by one of the projects.
+func (c *CodeHost) AvailableToAnyProject(projectNames []string) bool {
+	for _, projectName := range projectNames {
+		if c.AvailableToProject(projectName) {
+			return true
+		}
+	}
+	return c.Shared
 }
 
 type Option struct {
@@ -70,7 +189,21 @@ func (c *Client) GetCodeHost(id int) (*CodeHost, error) {
 	res := &CodeHost{}
 	_, err := c.Get(url, httpclient.SetResult(res))
 	if err != nil {
-		return nil, err
+		return nil, codeHostError(id, err)
+	}
+
+	return res, nil
+}
+
+// GetLatestCodeHost bypasses the in-memory cache of the codehosts, it should be used when the codehost is about
+// to be updated based on its current value, e.g. to refresh its token.
+func (c *Client) GetLatestCodeHost(id int) (*CodeHost, error) {
+	url := fmt.Sprintf("/codehosts/%d?bypassCache=true", id)
+
+	res := &CodeHost{}
+	_, err := c.Get(url, httpclient.SetResult(res))
+	if err != nil {
+		return nil, codeHostError(id, err)
 	}
 
 	return res, nil
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systemconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// the grpc server of aslan, which serves the codehost service of systemconfig as well.
+const aslanGRPCPort = 25002
+
+const codeHostServiceName = "zadig.systemconfig.v1.CodeHost"
+
+// RepoToken is the credential the repos of a codehost are accessed with.
+type RepoToken struct {
+	CodeHostID int    `json:"codehost_id"`
+	Type       string `json:"type"`
+	Address    string `json:"address"`
+	Username   string `json:"username,omitempty"`
+	Token      string `json:"token"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+}
+
+type getCodeHostRequest struct {
+	ID           int  `json:"id"`
+	IgnoreDelete bool `json:"ignore_delete"`
+	BypassCache  bool `json:"bypass_cache"`
+}
+
+type listByProviderRequest struct {
+	Provider string `json:"provider"`
+}
+
+type listByProviderResponse struct {
+	CodeHosts []*CodeHost `json:"codehosts"`
+}
+
+type resolveTokenRequest struct {
+	Address string `json:"address"`
+	Owner   string `json:"owner"`
+	Source  string `json:"source"`
+}
+
+// CodeHostGRPCClient looks up the codehosts over grpc, it is preferred over the REST client on
+// the hot paths, e.g. the processing of the webhooks.
+type CodeHostGRPCClient struct {
+	conn *grpc.ClientConn
+}
+
+// NewCodeHostGRPCClient connects to the grpc server of aslan, the connection is established lazily
+// and should be reused by the callers.
+func NewCodeHostGRPCClient() (*CodeHostGRPCClient, error) {
+	return DialCodeHostGRPCClient(fmt.Sprintf("%s:%d", config.AslanServiceName(), aslanGRPCPort))
+}
+
+func DialCodeHostGRPCClient(address string, opts ...grpc.DialOption) (*CodeHostGRPCClient, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})),
+		grpc.WithPerRPCCredentials(serviceCredentials{}),
+	}, opts...)
+	conn, err := grpc.Dial(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &CodeHostGRPCClient{conn: conn}, nil
+}
+
+func (c *CodeHostGRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// GetCodeHost returns the codehost like Client.GetCodeHost, a CodeHostDisabledError is returned if it is disabled.
+func (c *CodeHostGRPCClient) GetCodeHost(ctx context.Context, id int) (*CodeHost, error) {
+	res := &CodeHost{}
+	if err := c.invoke(ctx, "GetCodeHost", &getCodeHostRequest{ID: id}, res); err != nil {
+		return nil, grpcCodeHostError(id, err)
+	}
+	return res, nil
+}
+
+// GetLatestCodeHost bypasses the in-memory cache of the codehosts like Client.GetLatestCodeHost.
+func (c *CodeHostGRPCClient) GetLatestCodeHost(ctx context.Context, id int) (*CodeHost, error) {
+	res := &CodeHost{}
+	if err := c.invoke(ctx, "GetCodeHost", &getCodeHostRequest{ID: id, BypassCache: true}, res); err != nil {
+		return nil, grpcCodeHostError(id, err)
+	}
+	return res, nil
+}
+
+// ListByProvider returns the enabled codehosts of the provider, e.g. gitlab.
+func (c *CodeHostGRPCClient) ListByProvider(ctx context.Context, provider string) ([]*CodeHost, error) {
+	res := &listByProviderResponse{}
+	if err := c.invoke(ctx, "ListByProvider", &listByProviderRequest{Provider: provider}, res); err != nil {
+		return nil, err
+	}
+	return res.CodeHosts, nil
+}
+
+// ResolveTokenForRepo returns the access token of the only codehost the repo belongs to, the repo
+// is identified like GetCodeHostByAddressAndOwner.
+func (c *CodeHostGRPCClient) ResolveTokenForRepo(ctx context.Context, address, owner, source string) (*RepoToken, error) {
+	res := &RepoToken{}
+	if err := c.invoke(ctx, "ResolveTokenForRepo", &resolveTokenRequest{Address: address, Owner: owner, Source: source}, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *CodeHostGRPCClient) invoke(ctx context.Context, method string, in, out interface{}) error {
+	return c.conn.Invoke(ctx, "/"+codeHostServiceName+"/"+method, in, out)
+}
+
+// grpcCodeHostError converts the FailedPrecondition responded for the disabled codehost to a CodeHostDisabledError.
+func grpcCodeHostError(id int, err error) error {
+	if s, ok := status.FromError(err); ok && s.Code() == codes.FailedPrecondition {
+		return &CodeHostDisabledError{ID: id, Reason: s.Message()}
+	}
+	return err
+}
+
+// jsonCodec must be the same as the codec of the grpc server of aslan.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// serviceCredentials signs a short-lived token for every call with the service token key, the grpc server
+// serves the credentials of the codehosts only to the callers with such a token.
+type serviceCredentials struct{}
+
+func (serviceCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	claims := jwt.MapClaims{
+		"name": "system",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(config.ServiceTokenKey())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{strings.ToLower(setting.AuthorizationHeader): "Bearer " + token}, nil
+}
+
+func (serviceCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -31,6 +31,7 @@ import (
 	systemmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/models"
 	systemservice "github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/i18n"
 	"github.com/koderover/zadig/pkg/util/ginzap"
 )
 
@@ -104,6 +105,23 @@ func GetResourcesInHeader(c *gin.Context) ([]string, bool) {
 	return resources, true
 }
 
+// the API tokens of the users are permanent, the tokens of the login sessions expire in days.
+const apiTokenMinLifetime = 10 * 365 * 24 * time.Hour
+
+// IsAPITokenRequest reports whether the request is authenticated with the API token of the user
+// rather than a login session, i.e. it is sent by an external automation.
+func IsAPITokenRequest(c *gin.Context) bool {
+	token := c.GetHeader(setting.AuthorizationHeader)
+	if token == "" {
+		return false
+	}
+	claims, err := getUserFromJWT(token)
+	if err != nil {
+		return false
+	}
+	return time.Unix(claims.ExpiresAt, 0).After(time.Now().Add(apiTokenMinLifetime))
+}
+
 func getUserFromJWT(token string) (jwtClaims, error) {
 	cs := jwtClaims{}
 
@@ -128,6 +146,9 @@ func getUserFromJWT(token string) (jwtClaims, error) {
 func JSONResponse(c *gin.Context, ctx *Context) {
 	if ctx.Err != nil {
 		c.Set(setting.ResponseError, ctx.Err)
+		if lang := i18n.UserLanguage(ctx.UserName); lang != "" {
+			c.Set(setting.ResponseLanguage, lang)
+		}
 		c.Abort()
 		return
 	}
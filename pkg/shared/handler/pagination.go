@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/pagination"
+)
+
+// GetPaginationQuery parses the cursor, limit, sort, q and filter[field] parameters of the request.
+func GetPaginationQuery(c *gin.Context) (*pagination.Query, error) {
+	q, err := pagination.ParseQuery(c.Request.URL.Query())
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	return q, nil
+}
+
+// Paginate applies the pagination query of the request to the items in memory.
+func Paginate[T any](c *gin.Context, items []T, schema *pagination.Schema) ([]T, *pagination.Page, error) {
+	q, err := GetPaginationQuery(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, page, err := pagination.Apply(items, q, schema)
+	if err != nil {
+		return nil, nil, e.ErrInvalidParam.AddErr(err)
+	}
+	SetPageHeaders(c, page)
+	return items, page, nil
+}
+
+// SetPageHeaders returns the next cursor and the total count in the headers, so that the response
+// bodies are not changed for the clients which do not paginate.
+func SetPageHeaders(c *gin.Context, page *pagination.Page) {
+	if page == nil {
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header(pagination.HeaderNextCursor, page.NextCursor)
+	}
+	c.Header(pagination.HeaderTotalCount, strconv.FormatInt(page.Total, 10))
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/kube/resource"
+)
+
+// cronJob is the wrapper for batchv1beta1.CronJob type.
+type cronJob struct {
+	*batchv1beta1.CronJob
+}
+
+func CronJob(w *batchv1beta1.CronJob) *cronJob {
+	if w == nil {
+		return nil
+	}
+
+	return &cronJob{
+		CronJob: w,
+	}
+}
+
+// Unwrap returns the batchv1beta1.CronJob object.
+func (w *cronJob) Unwrap() *batchv1beta1.CronJob {
+	return w.CronJob
+}
+
+// Ready is always true for a cronJob since it has no long-running pods, the jobs created by it are
+// checked on their own.
+func (w *cronJob) Ready() bool {
+	return true
+}
+
+func (w *cronJob) WorkloadResource(pods []*corev1.Pod) *resource.Workload {
+	wl := &resource.Workload{
+		Name:     w.Name,
+		Type:     setting.CronJob,
+		Replicas: int32(len(w.Status.Active)),
+		Pods:     make([]*resource.Pod, 0, len(pods)),
+	}
+
+	for _, c := range w.Spec.JobTemplate.Spec.Template.Spec.Containers {
+		wl.Images = append(wl.Images, resource.ContainerImage{Name: c.Name, Image: c.Image})
+	}
+
+	for _, p := range pods {
+		wl.Pods = append(wl.Pods, Pod(p).Resource())
+	}
+
+	return wl
+}
+
+func (w *cronJob) ImageInfos() (images []string) {
+	for _, v := range w.Spec.JobTemplate.Spec.Template.Spec.Containers {
+		images = append(images, v.Image)
+	}
+	return
+}
+
+func (w *cronJob) GetContainers() []*resource.ContainerImage {
+	containers := make([]*resource.ContainerImage, 0, len(w.Spec.JobTemplate.Spec.Template.Spec.Containers))
+	for _, c := range w.Spec.JobTemplate.Spec.Template.Spec.Containers {
+		containers = append(containers, &resource.ContainerImage{Name: c.Name, Image: c.Image})
+	}
+	return containers
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/kube/resource"
+)
+
+// daemonSet is the wrapper for appsv1.DaemonSet type.
+type daemonSet struct {
+	*appsv1.DaemonSet
+}
+
+func DaemonSet(w *appsv1.DaemonSet) *daemonSet {
+	if w == nil {
+		return nil
+	}
+
+	return &daemonSet{
+		DaemonSet: w,
+	}
+}
+
+// Unwrap returns the appsv1.DaemonSet object.
+func (w *daemonSet) Unwrap() *appsv1.DaemonSet {
+	return w.DaemonSet
+}
+
+func (w *daemonSet) Ready() bool {
+	return w.Status.ObservedGeneration >= w.Generation &&
+		w.Status.UpdatedNumberScheduled == w.Status.DesiredNumberScheduled &&
+		w.Status.NumberAvailable == w.Status.DesiredNumberScheduled
+}
+
+func (w *daemonSet) WorkloadResource(pods []*corev1.Pod) *resource.Workload {
+	wl := &resource.Workload{
+		Name:     w.Name,
+		Type:     setting.DaemonSet,
+		Replicas: w.Status.DesiredNumberScheduled,
+		Pods:     make([]*resource.Pod, 0, len(pods)),
+	}
+
+	for _, c := range w.Spec.Template.Spec.Containers {
+		wl.Images = append(wl.Images, resource.ContainerImage{Name: c.Name, Image: c.Image})
+	}
+
+	for _, p := range pods {
+		wl.Pods = append(wl.Pods, Pod(p).Resource())
+	}
+
+	return wl
+}
+
+func (w *daemonSet) ImageInfos() (images []string) {
+	for _, v := range w.Spec.Template.Spec.Containers {
+		images = append(images, v.Image)
+	}
+	return
+}
+
+func (w *daemonSet) GetContainers() []*resource.ContainerImage {
+	containers := make([]*resource.ContainerImage, 0, len(w.Spec.Template.Spec.Containers))
+	for _, c := range w.Spec.Template.Spec.Containers {
+		containers = append(containers, &resource.ContainerImage{Name: c.Name, Image: c.Image})
+	}
+	return containers
+}
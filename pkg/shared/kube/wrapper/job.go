@@ -20,6 +20,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/kube/resource"
 	"github.com/koderover/zadig/pkg/util"
 )
@@ -66,3 +67,29 @@ func (w *job) Resource() *resource.Job {
 		Containers: append(w.Spec.Template.Spec.Containers, w.Spec.Template.Spec.InitContainers...),
 	}
 }
+
+func (w *job) WorkloadResource(pods []*corev1.Pod) *resource.Workload {
+	wl := &resource.Workload{
+		Name:     w.Name,
+		Type:     setting.Job,
+		Replicas: w.Status.Active,
+		Pods:     make([]*resource.Pod, 0, len(pods)),
+	}
+
+	for _, c := range w.Spec.Template.Spec.Containers {
+		wl.Images = append(wl.Images, resource.ContainerImage{Name: c.Name, Image: c.Image})
+	}
+
+	for _, p := range pods {
+		wl.Pods = append(wl.Pods, Pod(p).Resource())
+	}
+
+	return wl
+}
+
+func (w *job) ImageInfos() (images []string) {
+	for _, v := range w.Spec.Template.Spec.Containers {
+		images = append(images, v.Image)
+	}
+	return
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	apiVersion = "7.0"
+	// the max number of the changes azure devops returns in a page.
+	changesPageSize = 2000
+	// the sha of the old object of a ref update when the ref is created.
+	emptyObjectID = "0000000000000000000000000000000000000000"
+)
+
+// Client talks to the REST API of an azure devops organization, the repos are addressed by the name of
+// their project and their own name.
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns the client of the organization, e.g. https://dev.azure.com/koderover. The access
+// token is either a microsoft entra access token or a personal access token.
+func NewClient(organizationURL, accessToken, proxyAddr string, enableProxy bool) *Client {
+	opts := []httpclient.ClientFunc{httpclient.SetHostURL(strings.TrimSuffix(organizationURL, "/"))}
+	if IsBearerToken(accessToken) {
+		opts = append(opts, httpclient.SetAuthToken(accessToken))
+	} else {
+		// the personal access tokens are accepted as the password of any user name.
+		opts = append(opts, httpclient.SetBasicAuth("", accessToken))
+	}
+	if enableProxy {
+		opts = append(opts, httpclient.SetProxy(proxyAddr))
+	}
+	cli := httpclient.New(opts...)
+	cli.SetQueryParam("api-version", apiVersion)
+	return &Client{Client: cli}
+}
+
+// IsBearerToken reports whether the token is a microsoft entra access token, which is a JWT and sent as
+// a bearer token, while the personal access tokens are sent by the basic authentication.
+func IsBearerToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+type list struct {
+	Count int `json:"count"`
+}
+
+func repoURL(project, repo string) string {
+	return fmt.Sprintf("/%s/_apis/git/repositories/%s", url.PathEscape(project), url.PathEscape(repo))
+}
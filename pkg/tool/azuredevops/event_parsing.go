@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EventType represents an azure devops service hook event type.
+type EventType string
+
+// List of available event types.
+const (
+	EventTypePush               EventType = "git.push"
+	EventTypePullRequestCreated EventType = "git.pullrequest.created"
+	EventTypePullRequestUpdated EventType = "git.pullrequest.updated"
+)
+
+var subscribedEvents = []EventType{EventTypePush, EventTypePullRequestCreated, EventTypePullRequestUpdated}
+
+// eventTypeHeader is set by the subscriptions of zadig, azure devops does not send the event type in a
+// header by itself.
+const eventTypeHeader = "X-Azure-DevOps-Event"
+
+// HookEventType returns the event type for the given request.
+func HookEventType(r *http.Request) EventType {
+	event := r.Header.Get(eventTypeHeader)
+	if strings.HasPrefix(event, "git.") {
+		return EventType(event)
+	}
+	return ""
+}
+
+// ValidateSecret checks the password of the basic authentication if a secret is configured.
+func ValidateSecret(r *http.Request, secret string) error {
+	if secret == "" {
+		return nil
+	}
+	_, password, ok := r.BasicAuth()
+	if !ok {
+		return fmt.Errorf("missing basic authentication")
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(secret)) != 1 {
+		return fmt.Errorf("secret is illegal")
+	}
+	return nil
+}
+
+func ParseHook(payload []byte) (event interface{}, err error) {
+	header := &struct {
+		EventType EventType `json:"eventType"`
+	}{}
+	if err := json.Unmarshal(payload, header); err != nil {
+		return nil, err
+	}
+	switch header.EventType {
+	case EventTypePush:
+		event = &PushEvent{}
+	case EventTypePullRequestCreated, EventTypePullRequestUpdated:
+		event = &PullRequestEvent{}
+	default:
+		return nil, fmt.Errorf("unexpected event type: %s", header.EventType)
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+type RefUpdate struct {
+	Name        string `json:"name"`
+	OldObjectID string `json:"oldObjectId"`
+	NewObjectID string `json:"newObjectId"`
+}
+
+// IsBranch reports whether the branch is updated, the name of the branch is returned.
+func (u *RefUpdate) IsBranch() (string, bool) {
+	return strings.TrimPrefix(u.Name, refPrefixBranch), strings.HasPrefix(u.Name, refPrefixBranch)
+}
+
+// IsTag reports whether the tag is updated, the name of the tag is returned.
+func (u *RefUpdate) IsTag() (string, bool) {
+	return strings.TrimPrefix(u.Name, refPrefixTag), strings.HasPrefix(u.Name, refPrefixTag)
+}
+
+// IsDeleted reports whether the ref is deleted by the push.
+func (u *RefUpdate) IsDeleted() bool {
+	return u.NewObjectID == emptyObjectID
+}
+
+type Push struct {
+	PushID     int          `json:"pushId"`
+	PushedBy   *User        `json:"pushedBy"`
+	RefUpdates []*RefUpdate `json:"refUpdates"`
+	Commits    []*Commit    `json:"commits"`
+	Repository *Repository  `json:"repository"`
+}
+
+type PushEvent struct {
+	ID        string    `json:"id"`
+	EventType EventType `json:"eventType"`
+	Resource  *Push     `json:"resource"`
+}
+
+type PullRequestEvent struct {
+	ID        string       `json:"id"`
+	EventType EventType    `json:"eventType"`
+	Resource  *PullRequest `json:"resource"`
+}
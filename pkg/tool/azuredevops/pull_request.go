@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const PullRequestStatusActive = "active"
+
+type PullRequest struct {
+	PullRequestID         int         `json:"pullRequestId"`
+	Title                 string      `json:"title"`
+	Status                string      `json:"status"`
+	SourceRefName         string      `json:"sourceRefName"`
+	TargetRefName         string      `json:"targetRefName"`
+	CreationDate          time.Time   `json:"creationDate"`
+	CreatedBy             *User       `json:"createdBy"`
+	LastMergeSourceCommit *Commit     `json:"lastMergeSourceCommit"`
+	Repository            *Repository `json:"repository"`
+}
+
+// SourceBranch returns the name of the branch the changes are from.
+func (pr *PullRequest) SourceBranch() string {
+	return strings.TrimPrefix(pr.SourceRefName, refPrefixBranch)
+}
+
+// TargetBranch returns the name of the branch the changes are merged into.
+func (pr *PullRequest) TargetBranch() string {
+	return strings.TrimPrefix(pr.TargetRefName, refPrefixBranch)
+}
+
+// ListPullRequests returns the active pull requests, which are merged into the target branch if it is set.
+func (c *Client) ListPullRequests(project, repo, targetBranch string) ([]*PullRequest, error) {
+	params := map[string]string{"searchCriteria.status": PullRequestStatusActive, "$top": "100"}
+	if targetBranch != "" {
+		params["searchCriteria.targetRefName"] = refPrefixBranch + targetBranch
+	}
+	res := &struct {
+		list
+		Value []*PullRequest `json:"value"`
+	}{}
+	if _, err := c.Get(repoURL(project, repo)+"/pullrequests", httpclient.SetQueryParams(params), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res.Value, nil
+}
+
+func (c *Client) GetPullRequest(project, repo string, id int) (*PullRequest, error) {
+	res := &PullRequest{}
+	if _, err := c.Get(fmt.Sprintf("%s/pullrequests/%d", repoURL(project, repo), id), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListPullRequestChangedFiles returns the files changed by the latest iteration of the pull request,
+// an iteration is created every time the source branch is pushed.
+func (c *Client) ListPullRequestChangedFiles(project, repo string, id int) ([]string, error) {
+	prURL := fmt.Sprintf("%s/pullrequests/%d", repoURL(project, repo), id)
+	iterations := &struct {
+		list
+		Value []*struct {
+			ID int `json:"id"`
+		} `json:"value"`
+	}{}
+	if _, err := c.Get(prURL+"/iterations", httpclient.SetResult(iterations)); err != nil {
+		return nil, err
+	}
+	if len(iterations.Value) == 0 {
+		return []string{}, nil
+	}
+	latest := iterations.Value[len(iterations.Value)-1].ID
+
+	files := make([]string, 0)
+	for skip := 0; ; {
+		res := &struct {
+			ChangeEntries []*change `json:"changeEntries"`
+			NextSkip      int       `json:"nextSkip"`
+		}{}
+		params := map[string]string{"$top": strconv.Itoa(changesPageSize), "$skip": strconv.Itoa(skip)}
+		url := fmt.Sprintf("%s/iterations/%d/changes", prURL, latest)
+		if _, err := c.Get(url, httpclient.SetQueryParams(params), httpclient.SetResult(res)); err != nil {
+			return nil, err
+		}
+		files = append(files, changedFiles(res.ChangeEntries)...)
+		if res.NextSkip <= skip {
+			return files, nil
+		}
+		skip = res.NextSkip
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	refPrefixBranch = "refs/heads/"
+	refPrefixTag    = "refs/tags/"
+)
+
+type Project struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Repository struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	DefaultBranch string   `json:"defaultBranch"`
+	RemoteURL     string   `json:"remoteUrl"`
+	Project       *Project `json:"project"`
+}
+
+type Ref struct {
+	Name     string `json:"name"`
+	ObjectID string `json:"objectId"`
+	// PeeledObjectID is the commit an annotated tag points to.
+	PeeledObjectID string `json:"peeledObjectId"`
+}
+
+type User struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	UniqueName  string `json:"uniqueName"`
+}
+
+type GitUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type Commit struct {
+	CommitID string   `json:"commitId"`
+	Comment  string   `json:"comment"`
+	Author   *GitUser `json:"author"`
+}
+
+type item struct {
+	Path     string `json:"path"`
+	IsFolder bool   `json:"isFolder"`
+}
+
+type change struct {
+	Item         *item  `json:"item"`
+	ChangeType   string `json:"changeType"`
+	OriginalPath string `json:"originalPath"`
+}
+
+// ListProjects returns the projects of the organization whose name contains the keyword.
+func (c *Client) ListProjects(keyword string) ([]*Project, error) {
+	projects := make([]*Project, 0)
+	continuation := ""
+	for {
+		params := map[string]string{"$top": "500"}
+		if continuation != "" {
+			params["continuationToken"] = continuation
+		}
+		res := &struct {
+			list
+			Value []*Project `json:"value"`
+		}{}
+		resp, err := c.Get("/_apis/projects", httpclient.SetQueryParams(params), httpclient.SetResult(res))
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range res.Value {
+			if keyword == "" || strings.Contains(strings.ToLower(project.Name), strings.ToLower(keyword)) {
+				projects = append(projects, project)
+			}
+		}
+		continuation = resp.Header().Get("x-ms-continuationtoken")
+		if continuation == "" || len(res.Value) == 0 {
+			return projects, nil
+		}
+	}
+}
+
+// ListRepositories returns the repos of the project whose name contains the keyword.
+func (c *Client) ListRepositories(project, keyword string) ([]*Repository, error) {
+	res := &struct {
+		list
+		Value []*Repository `json:"value"`
+	}{}
+	if _, err := c.Get(fmt.Sprintf("/%s/_apis/git/repositories", url.PathEscape(project)), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	repos := make([]*Repository, 0, len(res.Value))
+	for _, repo := range res.Value {
+		if keyword == "" || strings.Contains(strings.ToLower(repo.Name), strings.ToLower(keyword)) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos, nil
+}
+
+func (c *Client) GetRepository(project, repo string) (*Repository, error) {
+	res := &Repository{}
+	if _, err := c.Get(repoURL(project, repo), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListBranches returns the branches whose name contains the keyword, the names are without refs/heads/.
+func (c *Client) ListBranches(project, repo, keyword string) ([]*Ref, error) {
+	return c.listRefs(project, repo, refPrefixBranch, keyword)
+}
+
+// ListTags returns the tags whose name contains the keyword, the names are without refs/tags/.
+func (c *Client) ListTags(project, repo, keyword string) ([]*Ref, error) {
+	return c.listRefs(project, repo, refPrefixTag, keyword)
+}
+
+func (c *Client) listRefs(project, repo, prefix, keyword string) ([]*Ref, error) {
+	params := map[string]string{"filter": strings.TrimPrefix(prefix, "refs/"), "peelTags": "true"}
+	if keyword != "" {
+		params["filterContains"] = keyword
+	}
+	res := &struct {
+		list
+		Value []*Ref `json:"value"`
+	}{}
+	if _, err := c.Get(repoURL(project, repo)+"/refs", httpclient.SetQueryParams(params), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	for _, ref := range res.Value {
+		ref.Name = strings.TrimPrefix(ref.Name, prefix)
+	}
+	return res.Value, nil
+}
+
+func (c *Client) GetCommit(project, repo, commitID string) (*Commit, error) {
+	res := &Commit{}
+	if _, err := c.Get(fmt.Sprintf("%s/commits/%s", repoURL(project, repo), commitID), httpclient.SetResult(res)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListChangedFiles returns the files changed between the two commits, the changes of the target commit
+// are returned if the base commit is empty, e.g. the branch is created by the push.
+func (c *Client) ListChangedFiles(project, repo, base, target string) ([]string, error) {
+	if base == "" || base == emptyObjectID {
+		return c.listCommitChangedFiles(project, repo, target)
+	}
+
+	files := make([]string, 0)
+	for skip := 0; ; {
+		res := &struct {
+			AllChangesIncluded bool      `json:"allChangesIncluded"`
+			Changes            []*change `json:"changes"`
+		}{}
+		params := map[string]string{
+			"baseVersion":       base,
+			"baseVersionType":   "commit",
+			"targetVersion":     target,
+			"targetVersionType": "commit",
+			"$top":              strconv.Itoa(changesPageSize),
+			"$skip":             strconv.Itoa(skip),
+		}
+		if _, err := c.Get(repoURL(project, repo)+"/diffs/commits", httpclient.SetQueryParams(params), httpclient.SetResult(res)); err != nil {
+			return nil, err
+		}
+		files = append(files, changedFiles(res.Changes)...)
+		if res.AllChangesIncluded || len(res.Changes) == 0 {
+			return files, nil
+		}
+		skip += len(res.Changes)
+	}
+}
+
+func (c *Client) listCommitChangedFiles(project, repo, commitID string) ([]string, error) {
+	files := make([]string, 0)
+	for skip := 0; ; {
+		res := &struct {
+			Changes []*change `json:"changes"`
+		}{}
+		params := map[string]string{"top": strconv.Itoa(changesPageSize), "skip": strconv.Itoa(skip)}
+		changesURL := fmt.Sprintf("%s/commits/%s/changes", repoURL(project, repo), commitID)
+		if _, err := c.Get(changesURL, httpclient.SetQueryParams(params), httpclient.SetResult(res)); err != nil {
+			return nil, err
+		}
+		files = append(files, changedFiles(res.Changes)...)
+		if len(res.Changes) < changesPageSize {
+			return files, nil
+		}
+		skip += len(res.Changes)
+	}
+}
+
+// changedFiles returns the paths of the changed files relative to the root of the repo, the old path
+// of a renamed file is also returned.
+func changedFiles(changes []*change) []string {
+	files := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		if ch.Item == nil || ch.Item.IsFolder {
+			continue
+		}
+		files = append(files, strings.TrimPrefix(ch.Item.Path, "/"))
+		if ch.OriginalPath != "" {
+			files = append(files, strings.TrimPrefix(ch.OriginalPath, "/"))
+		}
+	}
+	return files
+}
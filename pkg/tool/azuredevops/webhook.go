@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azuredevops
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// WebhookUser is the user name of the basic authentication the secret is sent by.
+const WebhookUser = "zadig"
+
+type subscription struct {
+	ID               string            `json:"id,omitempty"`
+	PublisherID      string            `json:"publisherId"`
+	EventType        string            `json:"eventType"`
+	ResourceVersion  string            `json:"resourceVersion"`
+	ConsumerID       string            `json:"consumerId"`
+	ConsumerActionID string            `json:"consumerActionId"`
+	PublisherInputs  map[string]string `json:"publisherInputs"`
+	ConsumerInputs   map[string]string `json:"consumerInputs"`
+}
+
+// CreateWebhook subscribes the push and pull request events of the repo by the web hooks service, one
+// subscription is created for each event and their ids are returned joined by commas. The secret is sent
+// as the password of the basic authentication.
+func (c *Client) CreateWebhook(project, repo, url, secret string) (string, error) {
+	repository, err := c.GetRepository(project, repo)
+	if err != nil {
+		return "", err
+	}
+	if repository.Project == nil {
+		return "", fmt.Errorf("the project of repo %s/%s is not found", project, repo)
+	}
+
+	ids := make([]string, 0, len(subscribedEvents))
+	for _, event := range subscribedEvents {
+		consumerInputs := map[string]string{
+			"url":         url,
+			"httpHeaders": fmt.Sprintf("%s:%s", eventTypeHeader, event),
+		}
+		if secret != "" {
+			consumerInputs["basicAuthUsername"] = WebhookUser
+			consumerInputs["basicAuthPassword"] = secret
+		}
+		created := &subscription{}
+		_, err := c.Post("/_apis/hooks/subscriptions", httpclient.SetBody(&subscription{
+			PublisherID:      "tfs",
+			EventType:        string(event),
+			ResourceVersion:  "1.0",
+			ConsumerID:       "webHooks",
+			ConsumerActionID: "httpRequest",
+			PublisherInputs:  map[string]string{"projectId": repository.Project.ID, "repository": repository.ID},
+			ConsumerInputs:   consumerInputs,
+		}), httpclient.SetResult(created))
+		if err != nil {
+			// do not leave the subscriptions of part of the events.
+			_ = c.DeleteWebhook(strings.Join(ids, ","))
+			return "", err
+		}
+		ids = append(ids, created.ID)
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// DeleteWebhook deletes the subscriptions created by CreateWebhook.
+func (c *Client) DeleteWebhook(hookID string) error {
+	for _, id := range strings.Split(hookID, ",") {
+		if id == "" {
+			continue
+		}
+		if _, err := c.Delete("/_apis/hooks/subscriptions/" + id); err != nil && !httpclient.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
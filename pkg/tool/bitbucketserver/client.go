@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucketserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const defaultPageLimit = 100
+
+// Client talks to the REST API of Bitbucket Data Center/Server with a personal access token,
+// the API is different from the one of Bitbucket Cloud.
+type Client struct {
+	*httpclient.Client
+}
+
+func NewClient(address, accessToken, proxyAddr string, enableProxy bool) *Client {
+	opts := []httpclient.ClientFunc{
+		httpclient.SetHostURL(strings.TrimSuffix(address, "/") + "/rest"),
+		httpclient.SetAuthToken(accessToken),
+	}
+	if enableProxy {
+		opts = append(opts, httpclient.SetProxy(proxyAddr))
+	}
+	return &Client{Client: httpclient.New(opts...)}
+}
+
+type page struct {
+	Size          int  `json:"size"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+// listAll fetches the pages of a paged API until the limit is reached, a limit less than 1 means no limit.
+func (c *Client) listAll(url string, params map[string]string, limit int, fetch func(body []byte) (*page, int, error)) error {
+	query := map[string]string{"limit": strconv.Itoa(defaultPageLimit)}
+	for k, v := range params {
+		query[k] = v
+	}
+
+	total := 0
+	for start := 0; ; {
+		query["start"] = strconv.Itoa(start)
+		res, err := c.Get(url, httpclient.SetQueryParams(query))
+		if err != nil {
+			return err
+		}
+		p, count, err := fetch(res.Body())
+		if err != nil {
+			return err
+		}
+		total += count
+		if p.IsLastPage || count == 0 || (limit > 0 && total >= limit) {
+			return nil
+		}
+		start = p.NextPageStart
+	}
+}
+
+func repoURL(project, repo string) string {
+	return fmt.Sprintf("/api/1.0/projects/%s/repos/%s", project, repo)
+}
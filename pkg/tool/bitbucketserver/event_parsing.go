@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucketserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EventType represents a bitbucket server event type.
+type EventType string
+
+// List of available event types.
+const (
+	EventTypeRefsChanged               EventType = "repo:refs_changed"
+	EventTypePullRequestOpened         EventType = "pr:opened"
+	EventTypePullRequestFromRefUpdated EventType = "pr:from_ref_updated"
+	EventTypeDiagnosticsPing           EventType = "diagnostics:ping"
+)
+
+const (
+	RefTypeBranch = "BRANCH"
+	RefTypeTag    = "TAG"
+
+	RefChangeTypeDelete = "DELETE"
+)
+
+const (
+	eventTypeHeader = "X-Event-Key"
+	signatureHeader = "X-Hub-Signature"
+)
+
+// HookEventType returns the event type for the given request, bitbucket cloud sends the same header,
+// but its events are named differently, e.g. repo:push and pullrequest:created.
+func HookEventType(r *http.Request) EventType {
+	event := r.Header.Get(eventTypeHeader)
+	if strings.HasPrefix(event, "pr:") || event == string(EventTypeRefsChanged) || event == string(EventTypeDiagnosticsPing) {
+		return EventType(event)
+	}
+	return ""
+}
+
+// ValidateSignature checks the signature of the payload if a secret is configured.
+func ValidateSignature(r *http.Request, payload []byte, secret string) error {
+	if secret == "" {
+		return nil
+	}
+	signature := strings.TrimPrefix(r.Header.Get(signatureHeader), "sha256=")
+	if signature == "" {
+		return fmt.Errorf("missing signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return fmt.Errorf("signature is illegal")
+	}
+	return nil
+}
+
+func ParseHook(eventType EventType, payload []byte) (event interface{}, err error) {
+	switch eventType {
+	case EventTypeRefsChanged:
+		event = &RefsChangedEvent{}
+	case EventTypePullRequestOpened, EventTypePullRequestFromRefUpdated:
+		event = &PullRequestEvent{}
+	default:
+		return nil, fmt.Errorf("unexpected event type: %s", eventType)
+	}
+
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+type EventRef struct {
+	ID        string `json:"id"`
+	DisplayID string `json:"displayId"`
+	Type      string `json:"type"`
+}
+
+type RefChange struct {
+	Ref      *EventRef `json:"ref"`
+	RefID    string    `json:"refId"`
+	FromHash string    `json:"fromHash"`
+	ToHash   string    `json:"toHash"`
+	Type     string    `json:"type"`
+}
+
+type RefsChangedEvent struct {
+	EventKey   string       `json:"eventKey"`
+	Date       string       `json:"date"`
+	Actor      *User        `json:"actor"`
+	Repository *Repository  `json:"repository"`
+	Changes    []*RefChange `json:"changes"`
+}
+
+type PullRequestEvent struct {
+	EventKey    string       `json:"eventKey"`
+	Date        string       `json:"date"`
+	Actor       *User        `json:"actor"`
+	PullRequest *PullRequest `json:"pullRequest"`
+}
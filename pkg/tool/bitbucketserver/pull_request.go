@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucketserver
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	PullRequestStateOpen   = "OPEN"
+	PullRequestStateMerged = "MERGED"
+)
+
+type Ref struct {
+	ID           string      `json:"id"`
+	DisplayID    string      `json:"displayId"`
+	LatestCommit string      `json:"latestCommit"`
+	Repository   *Repository `json:"repository"`
+}
+
+type Participant struct {
+	User *User `json:"user"`
+}
+
+type PullRequest struct {
+	ID          int          `json:"id"`
+	Version     int          `json:"version"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	State       string       `json:"state"`
+	CreatedDate int64        `json:"createdDate"`
+	UpdatedDate int64        `json:"updatedDate"`
+	FromRef     *Ref         `json:"fromRef"`
+	ToRef       *Ref         `json:"toRef"`
+	Author      *Participant `json:"author"`
+}
+
+type pathInfo struct {
+	ToString string `json:"toString"`
+}
+
+type change struct {
+	Path    *pathInfo `json:"path"`
+	SrcPath *pathInfo `json:"srcPath"`
+	Type    string    `json:"type"`
+}
+
+func (c *Client) ListPullRequests(project, repo, targetBranch string) ([]*PullRequest, error) {
+	params := map[string]string{"state": PullRequestStateOpen}
+	if targetBranch != "" {
+		params["at"] = "refs/heads/" + targetBranch
+		params["direction"] = "INCOMING"
+	}
+	prs := make([]*PullRequest, 0)
+	err := c.listAll(repoURL(project, repo)+"/pull-requests", params, defaultPageLimit, func(body []byte) (*page, int, error) {
+		res := &struct {
+			page
+			Values []*PullRequest `json:"values"`
+		}{}
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, 0, err
+		}
+		prs = append(prs, res.Values...)
+		return &res.page, len(res.Values), nil
+	})
+	return prs, err
+}
+
+func (c *Client) GetPullRequest(project, repo string, id int) (*PullRequest, error) {
+	pr := &PullRequest{}
+	_, err := c.Get(fmt.Sprintf("%s/pull-requests/%d", repoURL(project, repo), id), httpclient.SetResult(pr))
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// ListPullRequestChangedFiles returns the files changed by the pull request.
+func (c *Client) ListPullRequestChangedFiles(project, repo string, id int) ([]string, error) {
+	return c.listChangedFiles(fmt.Sprintf("%s/pull-requests/%d/changes", repoURL(project, repo), id), nil)
+}
+
+// ListChangedFiles returns the files changed between the two commits, the changes of the until
+// commit are returned if since is empty.
+func (c *Client) ListChangedFiles(project, repo, since, until string) ([]string, error) {
+	params := map[string]string{"until": until}
+	if since != "" {
+		params["since"] = since
+	}
+	return c.listChangedFiles(repoURL(project, repo)+"/changes", params)
+}
+
+// listChangedFiles returns the changed files, the old path of a moved file is also returned.
+func (c *Client) listChangedFiles(url string, params map[string]string) ([]string, error) {
+	files := make([]string, 0)
+	err := c.listAll(url, params, 0, func(body []byte) (*page, int, error) {
+		res := &struct {
+			page
+			Values []*change `json:"values"`
+		}{}
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, 0, err
+		}
+		for _, ch := range res.Values {
+			if ch.Path != nil {
+				files = append(files, ch.Path.ToString)
+			}
+			if ch.SrcPath != nil {
+				files = append(files, ch.SrcPath.ToString)
+			}
+		}
+		return &res.page, len(res.Values), nil
+	})
+	return files, err
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucketserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type Project struct {
+	ID   int    `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type Repository struct {
+	ID      int      `json:"id"`
+	Slug    string   `json:"slug"`
+	Name    string   `json:"name"`
+	Project *Project `json:"project"`
+}
+
+type Branch struct {
+	ID           string `json:"id"`
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit"`
+	IsDefault    bool   `json:"isDefault"`
+}
+
+type Tag struct {
+	ID           string `json:"id"`
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit"`
+}
+
+type User struct {
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+	DisplayName  string `json:"displayName"`
+	Slug         string `json:"slug"`
+}
+
+type Commit struct {
+	ID        string `json:"id"`
+	DisplayID string `json:"displayId"`
+	Message   string `json:"message"`
+	Author    *User  `json:"author"`
+}
+
+func (c *Client) ListProjects(keyword string, limit int) ([]*Project, error) {
+	params := map[string]string{}
+	if keyword != "" {
+		params["name"] = keyword
+	}
+	projects := make([]*Project, 0)
+	err := c.listAll("/api/1.0/projects", params, limit, func(body []byte) (*page, int, error) {
+		res := &struct {
+			page
+			Values []*Project `json:"values"`
+		}{}
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, 0, err
+		}
+		projects = append(projects, res.Values...)
+		return &res.page, len(res.Values), nil
+	})
+	return projects, err
+}
+
+// ListRepositories lists the repositories of the project, or the repositories the user can access
+// whose name contains the keyword if the project is empty.
+func (c *Client) ListRepositories(project, keyword string, limit int) ([]*Repository, error) {
+	url, params := "/api/1.0/repos", map[string]string{}
+	if project != "" {
+		url = fmt.Sprintf("/api/1.0/projects/%s/repos", project)
+	} else if keyword != "" {
+		params["name"] = keyword
+	}
+
+	repos := make([]*Repository, 0)
+	err := c.listAll(url, params, limit, func(body []byte) (*page, int, error) {
+		res := &struct {
+			page
+			Values []*Repository `json:"values"`
+		}{}
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, 0, err
+		}
+		repos = append(repos, res.Values...)
+		return &res.page, len(res.Values), nil
+	})
+	if err != nil || project == "" || keyword == "" {
+		return repos, err
+	}
+
+	resp := make([]*Repository, 0)
+	for _, repo := range repos {
+		if strings.Contains(strings.ToLower(repo.Name), strings.ToLower(keyword)) {
+			resp = append(resp, repo)
+		}
+	}
+	return resp, nil
+}
+
+func (c *Client) ListBranches(project, repo, keyword string) ([]*Branch, error) {
+	params := map[string]string{}
+	if keyword != "" {
+		params["filterText"] = keyword
+	}
+	branches := make([]*Branch, 0)
+	err := c.listAll(repoURL(project, repo)+"/branches", params, 0, func(body []byte) (*page, int, error) {
+		res := &struct {
+			page
+			Values []*Branch `json:"values"`
+		}{}
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, 0, err
+		}
+		branches = append(branches, res.Values...)
+		return &res.page, len(res.Values), nil
+	})
+	return branches, err
+}
+
+func (c *Client) ListTags(project, repo, keyword string) ([]*Tag, error) {
+	params := map[string]string{"orderBy": "MODIFICATION"}
+	if keyword != "" {
+		params["filterText"] = keyword
+	}
+	tags := make([]*Tag, 0)
+	err := c.listAll(repoURL(project, repo)+"/tags", params, defaultPageLimit, func(body []byte) (*page, int, error) {
+		res := &struct {
+			page
+			Values []*Tag `json:"values"`
+		}{}
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, 0, err
+		}
+		tags = append(tags, res.Values...)
+		return &res.page, len(res.Values), nil
+	})
+	return tags, err
+}
+
+// GetCommit returns the commit of the commit id or the latest commit of the ref, e.g. a branch name.
+func (c *Client) GetCommit(project, repo, commitID string) (*Commit, error) {
+	commit := &Commit{}
+	_, err := c.Get(fmt.Sprintf("%s/commits/%s", repoURL(project, repo), commitID), httpclient.SetResult(commit))
+	if err != nil {
+		return nil, err
+	}
+	return commit, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bitbucketserver
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+type Webhook struct {
+	ID            int               `json:"id,omitempty"`
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Active        bool              `json:"active"`
+	Events        []string          `json:"events"`
+	Configuration map[string]string `json:"configuration,omitempty"`
+}
+
+// CreateWebhook registers a webhook of the push and pull request events in the repository, the
+// payloads are signed by the secret.
+func (c *Client) CreateWebhook(project, repo, name, url, secret string) (string, error) {
+	hook := &Webhook{
+		Name:   name,
+		URL:    url,
+		Active: true,
+		Events: []string{string(EventTypeRefsChanged), string(EventTypePullRequestOpened), string(EventTypePullRequestFromRefUpdated)},
+	}
+	if secret != "" {
+		hook.Configuration = map[string]string{"secret": secret}
+	}
+	created := &Webhook{}
+	if _, err := c.Post(repoURL(project, repo)+"/webhooks", httpclient.SetBody(hook), httpclient.SetResult(created)); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(created.ID), nil
+}
+
+func (c *Client) DeleteWebhook(project, repo, hookID string) error {
+	_, err := c.Delete(fmt.Sprintf("%s/webhooks/%s", repoURL(project, repo), hookID))
+	return err
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	keyPrefix = "zadig:"
+	// the cache is skipped if redis does not respond in time, so a slow redis never slows down the reads.
+	opTimeout = 300 * time.Millisecond
+	// versionTTL keeps the version of a deleted key longer than any load may take.
+	versionTTL = time.Hour
+)
+
+// setIfUnchanged caches the loaded value only if the version of the key is still the one read before
+// loading, so that a value loaded before a write is not cached after the write deleted the key.
+var setIfUnchanged = redis.NewScript(`
+if (redis.call("GET", KEYS[2]) or "") ~= ARGV[1] then
+	return 0
+end
+if ARGV[3] == "0" then
+	redis.call("SET", KEYS[1], ARGV[2])
+else
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return 1
+`)
+
+var client *redis.Client
+
+// Init connects to the redis, the cache is disabled if addr is empty and all the reads go to the loaders.
+func Init(addr, password string, db int) {
+	if addr == "" {
+		return
+	}
+	client = redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+}
+
+func Close() error {
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
+
+func Enabled() bool {
+	return client != nil
+}
+
+// Get decodes the cached value of key into v, false is returned on a miss or any error.
+func Get(key string, v interface{}) bool {
+	if client == nil || key == "" {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	data, err := client.Get(ctx, keyPrefix+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Warnf("failed to get cache %s, err: %s", key, err)
+		}
+		return false
+	}
+	if err := decode(data, v); err != nil {
+		log.Warnf("failed to decode cache %s, err: %s", key, err)
+		return false
+	}
+	return true
+}
+
+func Set(key string, v interface{}, ttl time.Duration) {
+	if client == nil || key == "" {
+		return
+	}
+	data, err := encode(v)
+	if err != nil {
+		log.Warnf("failed to encode cache %s, err: %s", key, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := client.Set(ctx, keyPrefix+key, data, ttl).Err(); err != nil {
+		log.Warnf("failed to set cache %s, err: %s", key, err)
+	}
+}
+
+// Delete removes the keys, it should be called after the data is written. The versions of the keys are
+// bumped as well, so that the values being loaded by Load before the write are not cached.
+func Delete(keys ...string) {
+	if client == nil || len(keys) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, keyPrefix+key)
+			pipe.Incr(ctx, versionKey(key))
+			pipe.Expire(ctx, versionKey(key), versionTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warnf("failed to delete cache %v, err: %s", keys, err)
+	}
+}
+
+// Load returns the cached value of key, or calls load and caches its result. Errors of load are
+// returned as they are and never cached. The result is not cached if the key is deleted while loading.
+func Load[T any](key string, ttl time.Duration, load func() (T, error)) (T, error) {
+	var cached T
+	if Get(key, &cached) {
+		return cached, nil
+	}
+	version, ok := keyVersion(key)
+	resp, err := load()
+	if err != nil {
+		return resp, err
+	}
+	if ok {
+		setIfVersion(key, version, resp, ttl)
+	}
+	return resp, nil
+}
+
+// keyVersion returns the version of key, false is returned if it can not be read and the value should not be cached.
+func keyVersion(key string) (string, bool) {
+	if client == nil || key == "" {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	version, err := client.Get(ctx, versionKey(key)).Result()
+	if err != nil && err != redis.Nil {
+		log.Warnf("failed to get cache version of %s, err: %s", key, err)
+		return "", false
+	}
+	return version, true
+}
+
+func setIfVersion(key, version string, v interface{}, ttl time.Duration) {
+	data, err := encode(v)
+	if err != nil {
+		log.Warnf("failed to encode cache %s, err: %s", key, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	keys := []string{keyPrefix + key, versionKey(key)}
+	if err := setIfUnchanged.Run(ctx, client, keys, version, data, ttl.Milliseconds()).Err(); err != nil {
+		log.Warnf("failed to set cache %s, err: %s", key, err)
+	}
+}
+
+// NamespaceKey returns the key in a namespace, all the keys of a namespace are invalidated together
+// by Invalidate. It is used when the keys affected by a write are unknown, e.g. the keys of queries.
+// An empty key is returned if the cache is not available.
+func NamespaceKey(namespace, key string) string {
+	if client == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	generation, err := client.Get(ctx, generationKey(namespace)).Int64()
+	if err != nil && err != redis.Nil {
+		log.Warnf("failed to get cache generation of %s, err: %s", namespace, err)
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%s", namespace, generation, key)
+}
+
+// Invalidate invalidates all the keys of the namespace, the old keys expire with their ttl.
+func Invalidate(namespace string) {
+	if client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := client.Incr(ctx, generationKey(namespace)).Err(); err != nil {
+		log.Warnf("failed to invalidate cache %s, err: %s", namespace, err)
+	}
+}
+
+// Hash returns a short key of the parts, e.g. the arguments of a query.
+func Hash(parts ...interface{}) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%v\x00", part)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+func generationKey(namespace string) string {
+	return keyPrefix + "generation:" + namespace
+}
+
+func versionKey(key string) string {
+	return keyPrefix + "version:" + key
+}
+
+type wrapper struct {
+	V interface{} `bson:"v"`
+}
+
+// the values are encoded with bson, so that the documents read from mongo are cached without loss.
+func encode(v interface{}) ([]byte, error) {
+	return bson.Marshal(&wrapper{V: v})
+}
+
+func decode(data []byte, v interface{}) error {
+	value, err := bson.Raw(data).LookupErr("v")
+	if err != nil {
+		return err
+	}
+	return value.Unmarshal(v)
+}
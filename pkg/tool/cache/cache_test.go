@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testProject struct {
+	Name     string     `bson:"product_name"`
+	Services [][]string `bson:"services"`
+	Public   bool       `bson:"public"`
+}
+
+var _ = Describe("Testing cache", func() {
+
+	It("encodes and decodes the values without loss", func() {
+		project := &testProject{Name: "demo", Services: [][]string{{"a", "b"}, {"c"}}, Public: true}
+		data, err := encode(project)
+		Expect(err).NotTo(HaveOccurred())
+
+		decoded := &testProject{}
+		Expect(decode(data, decoded)).To(Succeed())
+		Expect(decoded).To(Equal(project))
+
+		data, err = encode([]string{"x", "y"})
+		Expect(err).NotTo(HaveOccurred())
+		var list []string
+		Expect(decode(data, &list)).To(Succeed())
+		Expect(list).To(Equal([]string{"x", "y"}))
+	})
+
+	It("calls the loader if the cache is disabled", func() {
+		Expect(Enabled()).To(BeFalse())
+		calls := 0
+		load := func() (*testProject, error) {
+			calls++
+			return &testProject{Name: "demo"}, nil
+		}
+		for i := 0; i < 2; i++ {
+			resp, err := Load("project:demo", 0, load)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Name).To(Equal("demo"))
+		}
+		Expect(calls).To(Equal(2))
+		Expect(NamespaceKey("registry", "all")).To(BeEmpty())
+	})
+
+	It("returns the errors of the loader", func() {
+		_, err := Load("project:missing", 0, func() (*testProject, error) {
+			return nil, errors.New("not found")
+		})
+		Expect(err).To(MatchError("not found"))
+	})
+})
@@ -0,0 +1,314 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	KeyProviderLocal        = "local"
+	KeyProviderAWSKMS       = "aws-kms"
+	KeyProviderGCPKMS       = "gcp-kms"
+	KeyProviderVaultTransit = "vault-transit"
+
+	envelopePrefix = "enc:v1:"
+	dataKeySize    = 32
+	// a data key is reused for a while so that the key provider is not called for every secret.
+	dataKeyTTL = time.Hour
+	// maxDataKeys bounds the unwrapped data keys kept in memory, an evicted one is unwrapped again if needed.
+	maxDataKeys = 256
+)
+
+// KeyProvider wraps the data keys used to encrypt the stored secrets with a master key which never
+// leaves the provider. The id of the master key is saved with each secret, so that the secrets can
+// still be decrypted after the master key is changed, until they are re-encrypted.
+type KeyProvider interface {
+	// Name identifies the provider in the encrypted secrets.
+	Name() string
+	// KeyID is the master key used to wrap new data keys.
+	KeyID() string
+	WrapDataKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+type KeyProviderArgs struct {
+	Provider string
+	KeyID    string
+	// Region of aws kms.
+	Region string
+	// Address and token of vault.
+	VaultAddress string
+	VaultToken   string
+}
+
+type dataKey struct {
+	plain   []byte
+	wrapped []byte
+	expire  time.Time
+}
+
+type keyManager struct {
+	sync.Mutex
+	args      *KeyProviderArgs
+	current   KeyProvider
+	providers map[string]KeyProvider
+	dataKey   *dataKey
+	// unwrapped data keys, indexed by the envelope header.
+	dataKeys map[string][]byte
+}
+
+var keys = &keyManager{
+	providers: map[string]KeyProvider{},
+	dataKeys:  map[string][]byte{},
+}
+
+// NewKeyProvider creates the key provider of args.Provider, the local key is used if it is empty.
+func NewKeyProvider(args *KeyProviderArgs) (KeyProvider, error) {
+	switch args.Provider {
+	case "", KeyProviderLocal:
+		return NewLocalKeyProvider(args.KeyID), nil
+	case KeyProviderAWSKMS:
+		return NewAWSKMSKeyProvider(args.Region, args.KeyID)
+	case KeyProviderGCPKMS:
+		return NewGCPKMSKeyProvider(context.Background(), args.KeyID)
+	case KeyProviderVaultTransit:
+		return NewVaultTransitKeyProvider(args.VaultAddress, args.VaultToken, args.KeyID)
+	default:
+		return nil, fmt.Errorf("unsupported key provider %s", args.Provider)
+	}
+}
+
+// InitKeyProvider sets the key provider used to encrypt the stored secrets. The other providers are
+// created with the same args when a secret encrypted by them is decrypted, e.g. during a key rotation.
+func InitKeyProvider(args *KeyProviderArgs) error {
+	provider, err := NewKeyProvider(args)
+	if err != nil {
+		return err
+	}
+
+	keys.Lock()
+	defer keys.Unlock()
+	keys.args = args
+	keys.current = provider
+	keys.providers[provider.Name()] = provider
+	keys.dataKey = nil
+	return nil
+}
+
+// CurrentKeyProvider returns the name and the master key id used to encrypt new secrets.
+func CurrentKeyProvider() (string, string) {
+	provider := keys.currentProvider()
+	return provider.Name(), provider.KeyID()
+}
+
+// EncryptSecret encrypts a secret to be stored with a data key wrapped by the key provider,
+// the result has the format enc:v1:<provider>:<base64 key id>:<base64 wrapped data key>:<hex cipher>.
+func EncryptSecret(src string) (string, error) {
+	provider := keys.currentProvider()
+	key, err := keys.currentDataKey(provider)
+	if err != nil {
+		return "", err
+	}
+	client, err := NewAes(string(key.plain))
+	if err != nil {
+		return "", err
+	}
+	dest, err := client.Encrypt(src)
+	if err != nil {
+		return "", err
+	}
+	return envelopeHeader(provider.Name(), provider.KeyID(), key.wrapped) + ":" + dest, nil
+}
+
+// DecryptSecret decrypts a secret encrypted by EncryptSecret, secrets encrypted by AesEncrypt before
+// the key providers are introduced are decrypted by the static aes key.
+func DecryptSecret(src string) (string, error) {
+	if !strings.HasPrefix(src, envelopePrefix) {
+		return AesDecrypt(src)
+	}
+
+	header, cipherText, name, keyID, wrapped, err := parseEnvelope(src)
+	if err != nil {
+		return "", err
+	}
+	key, err := keys.unwrapDataKey(header, name, keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	client, err := NewAes(string(key))
+	if err != nil {
+		return "", err
+	}
+	return client.Decrypt(cipherText)
+}
+
+// IsEncryptedSecret reports whether the secret is encrypted by EncryptSecret.
+func IsEncryptedSecret(src string) bool {
+	return strings.HasPrefix(src, envelopePrefix)
+}
+
+// RotateDataKey drops the data key in use, the secrets encrypted later use a new data key wrapped by
+// the latest version of the master key.
+func RotateDataKey() {
+	keys.Lock()
+	defer keys.Unlock()
+	keys.dataKey = nil
+}
+
+// ReEncryptSecret encrypts the secret again if it is not encrypted by the current master key, or always
+// if force is true, e.g. after the master key is rotated in the key provider. The second return value
+// reports whether the secret is changed.
+func ReEncryptSecret(src string, force bool) (string, bool, error) {
+	if !force && strings.HasPrefix(src, envelopePrefix) {
+		_, _, name, keyID, _, err := parseEnvelope(src)
+		if err != nil {
+			return "", false, err
+		}
+		current := keys.currentProvider()
+		if name == current.Name() && keyID == current.KeyID() {
+			return src, false, nil
+		}
+	}
+
+	plain, err := DecryptSecret(src)
+	if err != nil {
+		return "", false, err
+	}
+	dest, err := EncryptSecret(plain)
+	if err != nil {
+		return "", false, err
+	}
+	return dest, true, nil
+}
+
+func (m *keyManager) currentProvider() KeyProvider {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.current == nil {
+		m.current = NewLocalKeyProvider("")
+		m.providers[m.current.Name()] = m.current
+	}
+	return m.current
+}
+
+func (m *keyManager) currentDataKey(provider KeyProvider) (*dataKey, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.dataKey != nil && time.Now().Before(m.dataKey.expire) {
+		return m.dataKey, nil
+	}
+
+	plain := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, plain); err != nil {
+		return nil, err
+	}
+	wrapped, err := provider.WrapDataKey(context.Background(), plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key by %s: %s", provider.Name(), err)
+	}
+	m.dataKey = &dataKey{plain: plain, wrapped: wrapped, expire: time.Now().Add(dataKeyTTL)}
+	m.cacheDataKey(envelopeHeader(provider.Name(), provider.KeyID(), wrapped), plain)
+	return m.dataKey, nil
+}
+
+// unwrapDataKey calls the key provider without holding the lock, so that a slow provider does not block
+// the secrets whose data keys are cached.
+func (m *keyManager) unwrapDataKey(header, name, keyID string, wrapped []byte) ([]byte, error) {
+	provider, key, err := m.cachedDataKey(header, name, keyID)
+	if err != nil || key != nil {
+		return key, err
+	}
+
+	key, err = provider.UnwrapDataKey(context.Background(), keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key by %s: %s", name, err)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.cacheDataKey(header, key)
+	return key, nil
+}
+
+// cachedDataKey returns the unwrapped data key if it is cached, or the provider to unwrap it otherwise.
+func (m *keyManager) cachedDataKey(header, name, keyID string) (KeyProvider, []byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if key, ok := m.dataKeys[header]; ok {
+		return nil, key, nil
+	}
+
+	provider, ok := m.providers[name]
+	if !ok {
+		args := &KeyProviderArgs{Provider: name, KeyID: keyID}
+		if m.args != nil {
+			args.Region, args.VaultAddress, args.VaultToken = m.args.Region, m.args.VaultAddress, m.args.VaultToken
+		}
+		var err error
+		if provider, err = NewKeyProvider(args); err != nil {
+			return nil, nil, err
+		}
+		m.providers[name] = provider
+	}
+	return provider, nil, nil
+}
+
+// cacheDataKey must be called with the lock held.
+func (m *keyManager) cacheDataKey(header string, key []byte) {
+	if _, ok := m.dataKeys[header]; !ok && len(m.dataKeys) >= maxDataKeys {
+		for h := range m.dataKeys {
+			delete(m.dataKeys, h)
+			break
+		}
+	}
+	m.dataKeys[header] = key
+}
+
+func envelopeHeader(name, keyID string, wrapped []byte) string {
+	return envelopePrefix + name + ":" + base64.StdEncoding.EncodeToString([]byte(keyID)) + ":" + base64.StdEncoding.EncodeToString(wrapped)
+}
+
+func parseEnvelope(src string) (header, cipherText, name, keyID string, wrapped []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(src, envelopePrefix), ":")
+	if len(parts) != 4 {
+		return "", "", "", "", nil, fmt.Errorf("invalid encrypted secret")
+	}
+	id, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", "", "", nil, fmt.Errorf("invalid key id: %s", err)
+	}
+	wrapped, err = base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", "", "", "", nil, fmt.Errorf("invalid data key: %s", err)
+	}
+	if _, err := hex.DecodeString(parts[3]); err != nil {
+		return "", "", "", "", nil, fmt.Errorf("invalid cipher: %s", err)
+	}
+	return strings.TrimSuffix(src, ":"+parts[3]), parts[3], parts[0], string(id), wrapped, nil
+}
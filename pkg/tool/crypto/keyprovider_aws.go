@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// AWSKMSKeyProvider wraps the data keys by a symmetric key of aws kms, the credentials are loaded
+// by the default credential chain of the aws sdk.
+type AWSKMSKeyProvider struct {
+	keyID  string
+	client *kms.KMS
+}
+
+func NewAWSKMSKeyProvider(region, keyID string) (*AWSKMSKeyProvider, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("key id of aws kms is required")
+	}
+	config := &aws.Config{}
+	if region != "" {
+		config.Region = aws.String(region)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	return &AWSKMSKeyProvider{keyID: keyID, client: kms.New(sess)}, nil
+}
+
+func (p *AWSKMSKeyProvider) Name() string {
+	return KeyProviderAWSKMS
+}
+
+func (p *AWSKMSKeyProvider) KeyID() string {
+	return p.keyID
+}
+
+func (p *AWSKMSKeyProvider) WrapDataKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	resp, err := p.client.EncryptWithContext(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(p.keyID),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.CiphertextBlob, nil
+}
+
+func (p *AWSKMSKeyProvider) UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	resp, err := p.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(keyID),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
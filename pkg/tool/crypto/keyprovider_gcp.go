@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	cloudkms "google.golang.org/api/cloudkms/v1"
+)
+
+// GCPKMSKeyProvider wraps the data keys by a crypto key of gcp cloud kms, the key id is the resource
+// name of the crypto key: projects/*/locations/*/keyRings/*/cryptoKeys/*. The credentials are loaded
+// by the application default credentials.
+type GCPKMSKeyProvider struct {
+	keyID   string
+	service *cloudkms.Service
+}
+
+func NewGCPKMSKeyProvider(ctx context.Context, keyID string) (*GCPKMSKeyProvider, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("key id of gcp kms is required")
+	}
+	service, err := cloudkms.NewService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GCPKMSKeyProvider{keyID: keyID, service: service}, nil
+}
+
+func (p *GCPKMSKeyProvider) Name() string {
+	return KeyProviderGCPKMS
+}
+
+func (p *GCPKMSKeyProvider) KeyID() string {
+	return p.keyID
+}
+
+func (p *GCPKMSKeyProvider) WrapDataKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	resp, err := p.service.Projects.Locations.KeyRings.CryptoKeys.Encrypt(p.keyID, &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(dataKey),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Ciphertext)
+}
+
+func (p *GCPKMSKeyProvider) UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	resp, err := p.service.Projects.Locations.KeyRings.CryptoKeys.Decrypt(keyID, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(wrapped),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
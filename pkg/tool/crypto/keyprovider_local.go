@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	fsutil "github.com/koderover/zadig/pkg/util/fs"
+)
+
+const (
+	localKeyDir     = "etc/encryption"
+	defaultLocalKey = "aes"
+)
+
+// LocalKeyProvider wraps the data keys by the aes keys mounted in etc/encryption, the key id is the
+// file name of the key, so a new key can be mounted beside the old one for the rotation.
+type LocalKeyProvider struct {
+	keyID string
+	keys  sync.Map
+}
+
+func NewLocalKeyProvider(keyID string) *LocalKeyProvider {
+	if keyID == "" {
+		keyID = defaultLocalKey
+	}
+	return &LocalKeyProvider{keyID: keyID}
+}
+
+func (p *LocalKeyProvider) Name() string {
+	return KeyProviderLocal
+}
+
+func (p *LocalKeyProvider) KeyID() string {
+	return p.keyID
+}
+
+func (p *LocalKeyProvider) WrapDataKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	client, err := p.client(p.keyID)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := client.Encrypt(string(dataKey))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(wrapped), nil
+}
+
+func (p *LocalKeyProvider) UnwrapDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	client, err := p.client(keyID)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := client.Decrypt(string(wrapped))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(dataKey), nil
+}
+
+func (p *LocalKeyProvider) client(keyID string) (*Aes, error) {
+	if key, ok := p.keys.Load(keyID); ok {
+		return NewAes(key.(string))
+	}
+
+	var key string
+	if keyID == defaultLocalKey {
+		key = getAESKey()
+	} else {
+		if strings.Contains(keyID, "/") {
+			return nil, fmt.Errorf("invalid local key id %s", keyID)
+		}
+		keyByte, err := fs.ReadFile(fsutil.Root(), path.Join(localKeyDir, keyID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local key %s: %s", keyID, err)
+		}
+		key = strings.TrimSpace(string(keyByte))
+	}
+	p.keys.Store(keyID, key)
+	return NewAes(key)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKeyProvider struct {
+	keyID string
+	keys  map[string]*Aes
+	// unwrapped is called before a data key is unwrapped.
+	unwrapped func()
+}
+
+func (p *fakeKeyProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeKeyProvider) KeyID() string {
+	return p.keyID
+}
+
+func (p *fakeKeyProvider) WrapDataKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	wrapped, err := p.keys[p.keyID].Encrypt(string(dataKey))
+	return []byte(wrapped), err
+}
+
+func (p *fakeKeyProvider) UnwrapDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if p.unwrapped != nil {
+		p.unwrapped()
+	}
+	dataKey, err := p.keys[keyID].Decrypt(string(wrapped))
+	return []byte(dataKey), err
+}
+
+func TestEnvelope_Crypt(t *testing.T) {
+	ast := require.New(t)
+
+	oldKey, err := NewAes("0123456789abcdef")
+	ast.Nil(err)
+	newKey, err := NewAes("fedcba9876543210")
+	ast.Nil(err)
+	provider := &fakeKeyProvider{keyID: "old", keys: map[string]*Aes{"old": oldKey, "new": newKey}}
+	keys.current, keys.dataKey = provider, nil
+	keys.providers[provider.Name()] = provider
+
+	encrypted, err := EncryptSecret("hello")
+	ast.Nil(err)
+	decrypted, err := DecryptSecret(encrypted)
+	ast.Nil(err)
+	ast.Equal("hello", decrypted)
+
+	_, changed, err := ReEncryptSecret(encrypted, false)
+	ast.Nil(err)
+	ast.False(changed)
+
+	provider.keyID = "new"
+	RotateDataKey()
+	reEncrypted, changed, err := ReEncryptSecret(encrypted, false)
+	ast.Nil(err)
+	ast.True(changed)
+	_, _, _, keyID, _, err := parseEnvelope(reEncrypted)
+	ast.Nil(err)
+	ast.Equal("new", keyID)
+
+	keys.dataKeys = map[string][]byte{}
+	decrypted, err = DecryptSecret(reEncrypted)
+	ast.Nil(err)
+	ast.Equal("hello", decrypted)
+}
+
+func TestEnvelope_UnwrapDataKey(t *testing.T) {
+	ast := require.New(t)
+
+	key, err := NewAes("0123456789abcdef")
+	ast.Nil(err)
+	provider := &fakeKeyProvider{keyID: "k1", keys: map[string]*Aes{"k1": key}}
+	keys.current, keys.dataKey = provider, nil
+	keys.providers[provider.Name()] = provider
+
+	encrypted, err := EncryptSecret("hello")
+	ast.Nil(err)
+	keys.dataKeys = map[string][]byte{}
+
+	// the provider is called without the lock, otherwise the secrets encrypted in the meantime would block
+	provider.unwrapped = func() {
+		_, err := EncryptSecret("world")
+		ast.Nil(err)
+	}
+	decrypted, err := DecryptSecret(encrypted)
+	ast.Nil(err)
+	ast.Equal("hello", decrypted)
+	provider.unwrapped = nil
+
+	for i := 0; i < maxDataKeys*2; i++ {
+		RotateDataKey()
+		_, err := EncryptSecret("hello")
+		ast.Nil(err)
+	}
+	ast.Len(keys.dataKeys, maxDataKeys)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// VaultTransitKeyProvider wraps the data keys by a key of the vault transit secrets engine, the key id
+// is <mount>/<key name>, the mount is transit if it is omitted.
+type VaultTransitKeyProvider struct {
+	keyID  string
+	token  string
+	client *httpclient.Client
+}
+
+type vaultTransitData struct {
+	Plaintext  string `json:"plaintext,omitempty"`
+	Ciphertext string `json:"ciphertext,omitempty"`
+}
+
+type vaultTransitResp struct {
+	Data *vaultTransitData `json:"data"`
+}
+
+func NewVaultTransitKeyProvider(address, token, keyID string) (*VaultTransitKeyProvider, error) {
+	if address == "" || token == "" || keyID == "" {
+		return nil, fmt.Errorf("address, token and key id of vault are required")
+	}
+	return &VaultTransitKeyProvider{keyID: keyID, token: token, client: httpclient.New(httpclient.SetHostURL(address))}, nil
+}
+
+func (p *VaultTransitKeyProvider) Name() string {
+	return KeyProviderVaultTransit
+}
+
+func (p *VaultTransitKeyProvider) KeyID() string {
+	return p.keyID
+}
+
+func (p *VaultTransitKeyProvider) WrapDataKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	resp := &vaultTransitResp{}
+	req := &vaultTransitData{Plaintext: base64.StdEncoding.EncodeToString(dataKey)}
+	if _, err := p.client.Post(vaultTransitURL("encrypt", p.keyID), httpclient.SetBody(req), httpclient.SetResult(resp), httpclient.SetHeader("X-Vault-Token", p.token)); err != nil {
+		return nil, err
+	}
+	if resp.Data == nil || resp.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("empty ciphertext returned by vault")
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+func (p *VaultTransitKeyProvider) UnwrapDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	resp := &vaultTransitResp{}
+	req := &vaultTransitData{Ciphertext: string(wrapped)}
+	if _, err := p.client.Post(vaultTransitURL("decrypt", keyID), httpclient.SetBody(req), httpclient.SetResult(resp), httpclient.SetHeader("X-Vault-Token", p.token)); err != nil {
+		return nil, err
+	}
+	if resp.Data == nil {
+		return nil, fmt.Errorf("empty plaintext returned by vault")
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+func vaultTransitURL(action, keyID string) string {
+	mount, name := "transit", keyID
+	if i := strings.LastIndex(keyID, "/"); i >= 0 {
+		mount, name = keyID[:i], keyID[i+1:]
+	}
+	return fmt.Sprintf("/v1/%s/%s/%s", mount, action, name)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnosis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	SourceLog       = "log"
+	SourceEvent     = "event"
+	SourceContainer = "container"
+	SourceError     = "error"
+
+	maxEvidenceLength = 256
+)
+
+// Diagnosis is the probable cause of a failed job found by a rule.
+type Diagnosis struct {
+	RuleID      string `bson:"rule_id"     json:"rule_id"`
+	Cause       string `bson:"cause"       json:"cause"`
+	Remediation string `bson:"remediation" json:"remediation"`
+	Source      string `bson:"source"      json:"source"`
+	// Evidence is the log line, the event or the error matched by the rule.
+	Evidence string `bson:"evidence" json:"evidence"`
+}
+
+type Event struct {
+	Reason  string
+	Message string
+}
+
+// Input is what the job leaves behind, all the fields are optional.
+type Input struct {
+	Log    io.Reader
+	Events []*Event
+	// TerminationReasons are the reasons of the terminated containers, e.g. OOMKilled.
+	TerminationReasons []string
+	Errors             []string
+}
+
+type compiledRule struct {
+	*Rule
+	logRegexes   []*regexp.Regexp
+	eventRegexes []*regexp.Regexp
+}
+
+type Engine struct {
+	rules []*compiledRule
+}
+
+var defaultEngine = MustNew(DefaultRules)
+
+// DefaultEngine returns the engine with the default rules.
+func DefaultEngine() *Engine {
+	return defaultEngine
+}
+
+func New(rules []*Rule) (*Engine, error) {
+	e := &Engine{}
+	ids := map[string]bool{}
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("the id of a rule is empty")
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("rule %s is defined more than once", rule.ID)
+		}
+		ids[rule.ID] = true
+		compiled := &compiledRule{Rule: rule}
+		for _, pattern := range rule.LogPatterns {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid log pattern of rule %s: %v", rule.ID, err)
+			}
+			compiled.logRegexes = append(compiled.logRegexes, regex)
+		}
+		for _, pattern := range rule.EventPatterns {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid event pattern of rule %s: %v", rule.ID, err)
+			}
+			compiled.eventRegexes = append(compiled.eventRegexes, regex)
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+func MustNew(rules []*Rule) *Engine {
+	e, err := New(rules)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Diagnose returns at most one diagnosis for each rule in the order of the rules, the evidence of the
+// terminated containers and the events is preferred over the one of the log and the errors.
+func (e *Engine) Diagnose(input *Input) ([]*Diagnosis, error) {
+	found := make(map[string]*Diagnosis, len(e.rules))
+	add := func(rule *compiledRule, source, evidence string) {
+		if _, ok := found[rule.ID]; ok {
+			return
+		}
+		found[rule.ID] = &Diagnosis{
+			RuleID:      rule.ID,
+			Cause:       rule.Cause,
+			Remediation: rule.Remediation,
+			Source:      source,
+			Evidence:    truncate(evidence),
+		}
+	}
+
+	for _, reason := range input.TerminationReasons {
+		for _, rule := range e.rules {
+			if rule.matchReason(reason) {
+				add(rule, SourceContainer, reason)
+			}
+		}
+	}
+	for _, event := range input.Events {
+		for _, rule := range e.rules {
+			if rule.matchReason(event.Reason) || matchAny(rule.eventRegexes, event.Message) {
+				add(rule, SourceEvent, fmt.Sprintf("%s: %s", event.Reason, event.Message))
+			}
+		}
+	}
+
+	var readErr error
+	if input.Log != nil {
+		reader := bufio.NewReader(input.Log)
+		for len(found) < len(e.rules) {
+			line, err := reader.ReadString('\n')
+			if line = strings.TrimSpace(line); line != "" {
+				for _, rule := range e.rules {
+					if matchAny(rule.logRegexes, line) {
+						add(rule, SourceLog, line)
+					}
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				break
+			}
+		}
+	}
+
+	for _, msg := range input.Errors {
+		for _, rule := range e.rules {
+			if matchAny(rule.logRegexes, msg) {
+				add(rule, SourceError, msg)
+			}
+		}
+	}
+
+	var res []*Diagnosis
+	for _, rule := range e.rules {
+		if diagnosis, ok := found[rule.ID]; ok {
+			res = append(res, diagnosis)
+		}
+	}
+	return res, readErr
+}
+
+func (r *compiledRule) matchReason(reason string) bool {
+	for _, expected := range r.Reasons {
+		if reason != "" && reason == expected {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(regexes []*regexp.Regexp, s string) bool {
+	for _, regex := range regexes {
+		if regex.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= maxEvidenceLength {
+		return s
+	}
+	return s[:maxEvidenceLength] + "..."
+}
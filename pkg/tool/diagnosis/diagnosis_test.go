@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnosis
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Engine", func() {
+	It("rejects invalid rules", func() {
+		_, err := New([]*Rule{{ID: "bad", LogPatterns: []string{`(`}}})
+		Expect(err).To(HaveOccurred())
+		_, err = New([]*Rule{{ID: "dup"}, {ID: "dup"}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("diagnoses the failures in the order of the rules", func() {
+		log := strings.Join([]string{
+			"Step 2: npm install",
+			"npm ERR! code ETIMEDOUT",
+			"npm ERR! network request to https://registry.npmjs.org/react failed",
+			"FATAL ERROR: Reached heap limit Allocation failed - JavaScript heap out of memory",
+		}, "\n")
+
+		diagnoses, err := DefaultEngine().Diagnose(&Input{
+			Log:                strings.NewReader(log),
+			TerminationReasons: []string{"Error", "OOMKilled"},
+			Events: []*Event{
+				{Reason: "Scheduled", Message: "Successfully assigned default/build to node-1"},
+				{Reason: "Failed", Message: `Failed to pull image "registry.example.com/build:1": unauthorized: authentication required`},
+			},
+			Errors: []string{"Error: UPGRADE FAILED: another operation (install/upgrade/rollback) is in progress"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diagnoses).To(HaveLen(4))
+		Expect(diagnoses[0].RuleID).To(Equal("oom-killed"))
+		Expect(diagnoses[0].Source).To(Equal(SourceContainer))
+		Expect(diagnoses[0].Evidence).To(Equal("OOMKilled"))
+		Expect(diagnoses[1].RuleID).To(Equal("image-pull-auth-failure"))
+		Expect(diagnoses[1].Source).To(Equal(SourceEvent))
+		Expect(diagnoses[2]).To(Equal(&Diagnosis{
+			RuleID:      "npm-registry-timeout",
+			Cause:       diagnoses[2].Cause,
+			Remediation: diagnoses[2].Remediation,
+			Source:      SourceLog,
+			Evidence:    "npm ERR! code ETIMEDOUT",
+		}))
+		Expect(diagnoses[3].RuleID).To(Equal("helm-upgrade-conflict"))
+		Expect(diagnoses[3].Source).To(Equal(SourceError))
+	})
+
+	It("returns nothing for the unknown failures", func() {
+		diagnoses, err := DefaultEngine().Diagnose(&Input{Log: strings.NewReader("exit status 1\n"), Errors: []string{"exit status 1"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diagnoses).To(BeEmpty())
+	})
+})
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnosis
+
+// Rule is a known cause of the failed jobs, a rule matches if any of its patterns or reasons matches.
+type Rule struct {
+	ID          string `bson:"id"          json:"id"          yaml:"id"`
+	Cause       string `bson:"cause"       json:"cause"       yaml:"cause"`
+	Remediation string `bson:"remediation" json:"remediation" yaml:"remediation"`
+	// LogPatterns are the regexes matched with the lines of the job log and the error of the job.
+	LogPatterns []string `bson:"log_patterns,omitempty" json:"log_patterns,omitempty" yaml:"log_patterns,omitempty"`
+	// EventPatterns are the regexes matched with the messages of the kubernetes events of the job pod.
+	EventPatterns []string `bson:"event_patterns,omitempty" json:"event_patterns,omitempty" yaml:"event_patterns,omitempty"`
+	// Reasons are matched with the reasons of the kubernetes events and the terminated containers.
+	Reasons []string `bson:"reasons,omitempty" json:"reasons,omitempty" yaml:"reasons,omitempty"`
+}
+
+var DefaultRules = []*Rule{
+	{
+		ID:          "oom-killed",
+		Cause:       "The job container is killed because it runs out of memory.",
+		Remediation: "Raise the memory limit in the resource spec of the job, or reduce the memory used by the build, e.g. limit the parallelism or the heap size of the build tools.",
+		LogPatterns: []string{
+			`JavaScript heap out of memory`,
+			`java\.lang\.OutOfMemoryError`,
+			`(?i)\bout of memory\b`,
+		},
+		Reasons: []string{"OOMKilled"},
+	},
+	{
+		ID:          "image-pull-auth-failure",
+		Cause:       "The image can't be pulled because the registry rejects the credentials.",
+		Remediation: "Check the username and the password of the image registry in the system settings, and make sure the account has the permission to pull the repository.",
+		LogPatterns: []string{
+			`(?i)pull access denied`,
+			`(?i)unauthorized: authentication required`,
+			`(?i)no basic auth credentials`,
+		},
+		EventPatterns: []string{
+			`(?i)failed to pull image.*(unauthorized|authentication required|pull access denied|no basic auth credentials|403 forbidden)`,
+		},
+	},
+	{
+		ID:          "npm-registry-timeout",
+		Cause:       "npm can't reach the package registry in time.",
+		Remediation: "Check the network from the cluster to the npm registry, or use a registry mirror closer to the cluster with npm config set registry, and raise fetch-timeout and fetch-retries if the network is slow.",
+		LogPatterns: []string{
+			`npm ERR! code (ETIMEDOUT|ESOCKETTIMEDOUT|ECONNRESET|ECONNREFUSED|EAI_AGAIN)`,
+			`npm ERR! network`,
+		},
+	},
+	{
+		ID:          "helm-upgrade-conflict",
+		Cause:       "The helm release is locked by another operation or conflicts with the resources in the namespace.",
+		Remediation: "Wait for the other operation of the release to finish, or roll back the release stuck in the pending state with helm rollback. If a resource exists already, delete it or add the helm ownership labels and annotations of the release to it.",
+		LogPatterns: []string{
+			`another operation \(install/upgrade/rollback\) is in progress`,
+			`rendered manifests contain a resource that already exists`,
+		},
+	},
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	alidnsEndpoint = "https://alidns.aliyuncs.com/"
+	alidnsVersion  = "2015-01-09"
+)
+
+// AlidnsProvider manages the records of a domain of alibaba cloud dns, the records of alidns have a single
+// value, so a record set is kept as the records of the same name and type.
+type AlidnsProvider struct {
+	domain          string
+	accessKeyID     string
+	accessKeySecret string
+	client          *httpclient.Client
+}
+
+type alidnsRecord struct {
+	RecordID string `json:"RecordId"`
+	RR       string `json:"RR"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	TTL      int64  `json:"TTL"`
+}
+
+type alidnsRecordsResp struct {
+	TotalCount    int `json:"TotalCount"`
+	DomainRecords struct {
+		Record []*alidnsRecord `json:"Record"`
+	} `json:"DomainRecords"`
+}
+
+func NewAlidnsProvider(args *ProviderArgs) (*AlidnsProvider, error) {
+	if args.AccessKeyID == "" || args.AccessKeySecret == "" {
+		return nil, fmt.Errorf("access key of alidns is required")
+	}
+	return &AlidnsProvider{
+		domain:          strings.TrimSuffix(args.Zone, "."),
+		accessKeyID:     args.AccessKeyID,
+		accessKeySecret: args.AccessKeySecret,
+		client:          httpclient.New(),
+	}, nil
+}
+
+func (p *AlidnsProvider) Name() string {
+	return ProviderAlidns
+}
+
+func (p *AlidnsProvider) GetRecord(ctx context.Context, name, recordType string) (*Record, error) {
+	records, err := p.listRecords(ctx, name, recordType)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	record := &Record{Name: name, Type: recordType, TTL: records[0].TTL}
+	for _, r := range records {
+		record.Values = append(record.Values, r.Value)
+	}
+	return record, nil
+}
+
+func (p *AlidnsProvider) UpsertRecord(ctx context.Context, record *Record) error {
+	rr, err := p.rr(record.Name)
+	if err != nil {
+		return err
+	}
+	current, err := p.listRecords(ctx, record.Name, record.Type)
+	if err != nil {
+		return err
+	}
+
+	desired := map[string]bool{}
+	for _, value := range record.Values {
+		desired[value] = true
+	}
+	for _, r := range current {
+		if !desired[r.Value] {
+			if err := p.call(ctx, "DeleteDomainRecord", map[string]string{"RecordId": r.RecordID}, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		delete(desired, r.Value)
+		if r.TTL != record.TTL {
+			params := map[string]string{"RecordId": r.RecordID, "RR": rr, "Type": record.Type, "Value": r.Value, "TTL": strconv.FormatInt(record.TTL, 10)}
+			if err := p.call(ctx, "UpdateDomainRecord", params, nil); err != nil {
+				return err
+			}
+		}
+	}
+	for _, value := range record.Values {
+		if !desired[value] {
+			continue
+		}
+		params := map[string]string{"DomainName": p.domain, "RR": rr, "Type": record.Type, "Value": value, "TTL": strconv.FormatInt(record.TTL, 10)}
+		if err := p.call(ctx, "AddDomainRecord", params, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *AlidnsProvider) DeleteRecord(ctx context.Context, name, recordType string) error {
+	current, err := p.listRecords(ctx, name, recordType)
+	if err != nil {
+		return err
+	}
+	for _, r := range current {
+		if err := p.call(ctx, "DeleteDomainRecord", map[string]string{"RecordId": r.RecordID}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rr returns the host record of the name relative to the domain, @ is the domain itself.
+func (p *AlidnsProvider) rr(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == p.domain {
+		return "@", nil
+	}
+	if !strings.HasSuffix(name, "."+p.domain) {
+		return "", fmt.Errorf("%s is not in the domain %s", name, p.domain)
+	}
+	return strings.TrimSuffix(name, "."+p.domain), nil
+}
+
+func (p *AlidnsProvider) listRecords(ctx context.Context, name, recordType string) ([]*alidnsRecord, error) {
+	rr, err := p.rr(name)
+	if err != nil {
+		return nil, err
+	}
+	resp := &alidnsRecordsResp{}
+	params := map[string]string{"DomainName": p.domain, "SubDomain": strings.TrimSuffix(name, "."), "Type": recordType, "PageSize": "500"}
+	if err := p.call(ctx, "DescribeSubDomainRecords", params, resp); err != nil {
+		return nil, err
+	}
+	records := make([]*alidnsRecord, 0, len(resp.DomainRecords.Record))
+	for _, r := range resp.DomainRecords.Record {
+		// the sub domain of the domain itself is matched by every record without it.
+		if r.RR == rr && r.Type == recordType {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func (p *AlidnsProvider) call(ctx context.Context, action string, params map[string]string, result interface{}) error {
+	query := signAlidnsRequest(action, params, p.accessKeyID, p.accessKeySecret, time.Now())
+	rfs := []httpclient.RequestFunc{}
+	if result != nil {
+		rfs = append(rfs, httpclient.SetResult(result))
+	}
+	_, err := p.client.Get(alidnsEndpoint+"?"+query, rfs...)
+	if err != nil {
+		return fmt.Errorf("alidns %s: %s", action, err)
+	}
+	return nil
+}
+
+// signAlidnsRequest returns the query string of an rpc request signed by the signature version 1.0 of
+// alibaba cloud.
+func signAlidnsRequest(action string, params map[string]string, accessKeyID, accessKeySecret string, now time.Time) string {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	all := map[string]string{
+		"Action":           action,
+		"Format":           "JSON",
+		"Version":          alidnsVersion,
+		"AccessKeyId":      accessKeyID,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   hex.EncodeToString(nonce),
+		"Timestamp":        now.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	for k, v := range params {
+		all[k] = v
+	}
+	return alidnsSignedQuery(all, accessKeySecret)
+}
+
+func alidnsSignedQuery(params map[string]string, accessKeySecret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params[k]))
+	}
+	canonicalized := strings.Join(pairs, "&")
+
+	mac := hmac.New(sha1.New, []byte(accessKeySecret+"&"))
+	mac.Write([]byte("GET&" + percentEncode("/") + "&" + percentEncode(canonicalized)))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return canonicalized + "&Signature=" + percentEncode(signature)
+}
+
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	clouddns "google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// CloudDNSProvider manages the records of a managed zone of gcp cloud dns.
+type CloudDNSProvider struct {
+	project string
+	zone    string
+	service *clouddns.Service
+}
+
+func NewCloudDNSProvider(ctx context.Context, args *ProviderArgs) (*CloudDNSProvider, error) {
+	if args.Project == "" {
+		return nil, fmt.Errorf("project of cloud dns is required")
+	}
+	opts := []option.ClientOption{option.WithScopes(clouddns.NdevClouddnsReadwriteScope)}
+	if args.CredentialsJSON != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(args.CredentialsJSON)))
+	}
+	service, err := clouddns.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudDNSProvider{project: args.Project, zone: args.Zone, service: service}, nil
+}
+
+func (p *CloudDNSProvider) Name() string {
+	return ProviderCloudDNS
+}
+
+func (p *CloudDNSProvider) GetRecord(ctx context.Context, name, recordType string) (*Record, error) {
+	set, err := p.getRecordSet(ctx, name, recordType)
+	if err != nil || set == nil {
+		return nil, err
+	}
+	record := &Record{Name: name, Type: recordType, TTL: set.Ttl}
+	for _, value := range set.Rrdatas {
+		record.Values = append(record.Values, unquoteTXT(recordType, value))
+	}
+	return record, nil
+}
+
+func (p *CloudDNSProvider) UpsertRecord(ctx context.Context, record *Record) error {
+	set := &clouddns.ResourceRecordSet{Name: fqdn(record.Name), Type: record.Type, Ttl: record.TTL}
+	for _, value := range record.Values {
+		set.Rrdatas = append(set.Rrdatas, quoteTXT(record.Type, value))
+	}
+	current, err := p.getRecordSet(ctx, record.Name, record.Type)
+	if err != nil {
+		return err
+	}
+	// a change replaces the record set by deleting the current one and adding the new one atomically.
+	change := &clouddns.Change{Additions: []*clouddns.ResourceRecordSet{set}}
+	if current != nil {
+		change.Deletions = []*clouddns.ResourceRecordSet{current}
+	}
+	_, err = p.service.Changes.Create(p.project, p.zone, change).Context(ctx).Do()
+	return err
+}
+
+func (p *CloudDNSProvider) DeleteRecord(ctx context.Context, name, recordType string) error {
+	current, err := p.getRecordSet(ctx, name, recordType)
+	if err != nil || current == nil {
+		return err
+	}
+	_, err = p.service.Changes.Create(p.project, p.zone, &clouddns.Change{Deletions: []*clouddns.ResourceRecordSet{current}}).Context(ctx).Do()
+	return err
+}
+
+func (p *CloudDNSProvider) getRecordSet(ctx context.Context, name, recordType string) (*clouddns.ResourceRecordSet, error) {
+	resp, err := p.service.ResourceRecordSets.List(p.project, p.zone).Name(fqdn(name)).Type(recordType).Context(ctx).Do()
+	if err != nil {
+		if apiErr, ok := err.(*googleapi.Error); ok && apiErr.Code == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(resp.Rrsets) == 0 {
+		return nil, nil
+	}
+	return resp.Rrsets[0], nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+const (
+	ProviderRoute53  = "route53"
+	ProviderCloudDNS = "clouddns"
+	ProviderAlidns   = "alidns"
+
+	RecordTypeA     = "A"
+	RecordTypeAAAA  = "AAAA"
+	RecordTypeCNAME = "CNAME"
+	RecordTypeTXT   = "TXT"
+
+	// ownerPrefix is prepended to the name of a record to get the name of its ownership TXT record, the TXT
+	// record can not share the name with a CNAME record.
+	ownerPrefix = "_zadig-owner."
+	heritage    = "heritage=zadig"
+)
+
+// Record is a record set, Name is the fully qualified domain name without the trailing dot.
+type Record struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+	TTL    int64    `json:"ttl"`
+}
+
+// Provider manages the records of a zone of a dns service.
+type Provider interface {
+	Name() string
+	// GetRecord returns the record of the name and the type, nil if it does not exist.
+	GetRecord(ctx context.Context, name, recordType string) (*Record, error)
+	// UpsertRecord creates the record or replaces the values of the existing one.
+	UpsertRecord(ctx context.Context, record *Record) error
+	// DeleteRecord deletes the record of the name and the type, it is not an error if it does not exist.
+	DeleteRecord(ctx context.Context, name, recordType string) error
+}
+
+type ProviderArgs struct {
+	Provider string
+	// Zone is the hosted zone id of route53, the managed zone name of cloud dns, or the domain name of alidns.
+	Zone string
+	// Region of route53.
+	Region string
+	// Project of cloud dns.
+	Project string
+	// AccessKeyID and AccessKeySecret of route53 and alidns, the default credentials of route53 are used if
+	// they are empty.
+	AccessKeyID     string
+	AccessKeySecret string
+	// CredentialsJSON is the service account key of cloud dns, the application default credentials are used
+	// if it is empty.
+	CredentialsJSON string
+}
+
+func NewProvider(ctx context.Context, args *ProviderArgs) (Provider, error) {
+	if args.Zone == "" {
+		return nil, fmt.Errorf("zone is required")
+	}
+	switch args.Provider {
+	case ProviderRoute53:
+		return NewRoute53Provider(args)
+	case ProviderCloudDNS:
+		return NewCloudDNSProvider(ctx, args)
+	case ProviderAlidns:
+		return NewAlidnsProvider(args)
+	default:
+		return nil, fmt.Errorf("unsupported dns provider %s", args.Provider)
+	}
+}
+
+// RecordType returns A or AAAA for the ip addresses and CNAME for the host names.
+func RecordType(target string) string {
+	ip := net.ParseIP(target)
+	switch {
+	case ip == nil:
+		return RecordTypeCNAME
+	case ip.To4() != nil:
+		return RecordTypeA
+	default:
+		return RecordTypeAAAA
+	}
+}
+
+// OwnerRecordName returns the name of the ownership TXT record of the record.
+func OwnerRecordName(name string) string {
+	return ownerPrefix + name
+}
+
+// OwnerValue is the value of the ownership TXT record, so that the records created by other installations or
+// by hand are never changed.
+func OwnerValue(owner, resource string) string {
+	return fmt.Sprintf("%s,zadig/owner=%s,zadig/resource=%s", heritage, owner, resource)
+}
+
+// ParseOwnerValue returns the owner and the resource of the ownership TXT record, ok is false if the record
+// is not created by zadig.
+func ParseOwnerValue(value string) (owner, resource string, ok bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) == 0 || parts[0] != heritage {
+		return "", "", false
+	}
+	for _, part := range parts[1:] {
+		switch {
+		case strings.HasPrefix(part, "zadig/owner="):
+			owner = strings.TrimPrefix(part, "zadig/owner=")
+		case strings.HasPrefix(part, "zadig/resource="):
+			resource = strings.TrimPrefix(part, "zadig/resource=")
+		}
+	}
+	return owner, resource, true
+}
+
+// EqualValues returns true if the values of the records are the same regardless of the order.
+func EqualValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x, y := append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+func quoteTXT(recordType, value string) string {
+	if recordType != RecordTypeTXT || strings.HasPrefix(value, `"`) {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+func unquoteTXT(recordType, value string) string {
+	if recordType != RecordTypeTXT || len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return value
+	}
+	return strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+}
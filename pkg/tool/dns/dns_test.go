@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlidnsSignedQuery(t *testing.T) {
+	ast := require.New(t)
+
+	// the example of the signature document of alibaba cloud.
+	query := alidnsSignedQuery(map[string]string{
+		"AccessKeyId":      "testid",
+		"Action":           "DescribeRegions",
+		"Format":           "XML",
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+		"SignatureVersion": "1.0",
+		"Timestamp":        "2016-02-23T12:46:24Z",
+		"Version":          "2014-05-26",
+	}, "testsecret")
+	ast.Contains(query, "Timestamp=2016-02-23T12%3A46%3A24Z")
+	ast.Contains(query, "&Signature=OLeaidS1JvxuMvnyHOwuJ%2BuX5qY%3D")
+}
+
+func TestAlidnsRR(t *testing.T) {
+	ast := require.New(t)
+
+	p := &AlidnsProvider{domain: "example.com"}
+	rr, err := p.rr("app.dev.example.com")
+	ast.NoError(err)
+	ast.Equal("app.dev", rr)
+	rr, err = p.rr("example.com.")
+	ast.NoError(err)
+	ast.Equal("@", rr)
+	_, err = p.rr("app.example.org")
+	ast.Error(err)
+}
+
+func TestOwnerValue(t *testing.T) {
+	ast := require.New(t)
+
+	owner, resource, ok := ParseOwnerValue(OwnerValue("default", "demo/dev"))
+	ast.True(ok)
+	ast.Equal("default", owner)
+	ast.Equal("demo/dev", resource)
+	_, _, ok = ParseOwnerValue("heritage=external-dns,external-dns/owner=default")
+	ast.False(ok)
+
+	ast.Equal(`"heritage=zadig,zadig/owner=a"`, quoteTXT(RecordTypeTXT, "heritage=zadig,zadig/owner=a"))
+	ast.Equal("heritage=zadig,zadig/owner=a", unquoteTXT(RecordTypeTXT, `"heritage=zadig,zadig/owner=a"`))
+	ast.Equal("1.2.3.4", quoteTXT(RecordTypeA, "1.2.3.4"))
+}
+
+func TestRecordType(t *testing.T) {
+	ast := require.New(t)
+
+	ast.Equal(RecordTypeA, RecordType("10.0.0.1"))
+	ast.Equal(RecordTypeAAAA, RecordType("fd00::1"))
+	ast.Equal(RecordTypeCNAME, RecordType("a1b2.elb.us-east-1.amazonaws.com"))
+	ast.True(EqualValues([]string{"a", "b"}, []string{"b", "a"}))
+	ast.False(EqualValues([]string{"a"}, []string{"a", "b"}))
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Route53Provider manages the records of a hosted zone of aws route53.
+type Route53Provider struct {
+	zoneID string
+	client *route53.Route53
+}
+
+func NewRoute53Provider(args *ProviderArgs) (*Route53Provider, error) {
+	config := &aws.Config{}
+	if args.Region != "" {
+		config.Region = aws.String(args.Region)
+	}
+	if args.AccessKeyID != "" {
+		config.Credentials = credentials.NewStaticCredentials(args.AccessKeyID, args.AccessKeySecret, "")
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Route53Provider{zoneID: args.Zone, client: route53.New(sess)}, nil
+}
+
+func (p *Route53Provider) Name() string {
+	return ProviderRoute53
+}
+
+func (p *Route53Provider) GetRecord(ctx context.Context, name, recordType string) (*Record, error) {
+	resp, err := p.client.ListResourceRecordSetsWithContext(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(p.zoneID),
+		StartRecordName: aws.String(fqdn(name)),
+		StartRecordType: aws.String(recordType),
+		MaxItems:        aws.String("1"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, set := range resp.ResourceRecordSets {
+		// the record sets are listed from the name, the first one is another record if the name does not exist.
+		if !strings.EqualFold(aws.StringValue(set.Name), fqdn(name)) || aws.StringValue(set.Type) != recordType {
+			continue
+		}
+		record := &Record{Name: name, Type: recordType, TTL: aws.Int64Value(set.TTL)}
+		for _, rr := range set.ResourceRecords {
+			record.Values = append(record.Values, unquoteTXT(recordType, aws.StringValue(rr.Value)))
+		}
+		return record, nil
+	}
+	return nil, nil
+}
+
+func (p *Route53Provider) UpsertRecord(ctx context.Context, record *Record) error {
+	set := &route53.ResourceRecordSet{
+		Name: aws.String(fqdn(record.Name)),
+		Type: aws.String(record.Type),
+		TTL:  aws.Int64(record.TTL),
+	}
+	for _, value := range record.Values {
+		set.ResourceRecords = append(set.ResourceRecords, &route53.ResourceRecord{Value: aws.String(quoteTXT(record.Type, value))})
+	}
+	return p.change(ctx, route53.ChangeActionUpsert, set)
+}
+
+func (p *Route53Provider) DeleteRecord(ctx context.Context, name, recordType string) error {
+	// the record set to delete must match the existing one exactly.
+	record, err := p.GetRecord(ctx, name, recordType)
+	if err != nil || record == nil {
+		return err
+	}
+	set := &route53.ResourceRecordSet{
+		Name: aws.String(fqdn(name)),
+		Type: aws.String(recordType),
+		TTL:  aws.Int64(record.TTL),
+	}
+	for _, value := range record.Values {
+		set.ResourceRecords = append(set.ResourceRecords, &route53.ResourceRecord{Value: aws.String(quoteTXT(recordType, value))})
+	}
+	return p.change(ctx, route53.ChangeActionDelete, set)
+}
+
+func (p *Route53Provider) change(ctx context.Context, action string, set *route53.ResourceRecordSet) error {
+	_, err := p.client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(p.zoneID),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{{Action: aws.String(action), ResourceRecordSet: set}},
+		},
+	})
+	return err
+}
@@ -19,6 +19,8 @@ package errors
 import (
 	"fmt"
 	"regexp"
+
+	"github.com/koderover/zadig/pkg/tool/i18n"
 )
 
 // IHTTPError ...
@@ -129,7 +131,8 @@ func ErrorMessage(err error) (code int, message map[string]interface{}) {
 	v, ok := err.(*HTTPError)
 	if ok {
 		code = v.Code()
-		if v.Code()/1000 == 6 {
+		// the error codes of the APIs start from 6000, they are not valid http status codes.
+		if v.Code() >= 6000 {
 			code = ErrInvalidParam.Code()
 		}
 		return code, map[string]interface{}{
@@ -147,3 +150,12 @@ func ErrorMessage(err error) (code int, message map[string]interface{}) {
 		"description": err.Error(),
 	}
 }
+
+// LocalizedErrorMessage is the same as ErrorMessage but the message is translated into the given language.
+func LocalizedErrorMessage(err error, lang string) (code int, message map[string]interface{}) {
+	code, message = ErrorMessage(err)
+	if msg, ok := message["message"].(string); ok {
+		message["message"] = i18n.T(lang, msg)
+	}
+	return code, message
+}
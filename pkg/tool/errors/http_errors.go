@@ -29,8 +29,12 @@ var (
 	ErrForbidden = NewHTTPError(403, "Forbidden")
 	// ErrNotFound ...
 	ErrNotFound = NewHTTPError(404, "Request Not Found")
+	// ErrConflict ...
+	ErrConflict = NewHTTPError(409, "Conflict")
 	// ErrInternalError ...
 	ErrInternalError = NewHTTPError(500, "Internal Error")
+	// ErrServiceUnavailable ...
+	ErrServiceUnavailable = NewHTTPError(503, "Service Unavailable")
 
 	//-----------------------------------------------------------------------------------------------
 	// User APIs Range: 6000 - 6019
@@ -722,4 +726,489 @@ var (
 	ErrCreateWebhook = NewHTTPError(6882, "创建webhook失败")
 	ErrUpdateWebhook = NewHTTPError(6883, "更新webhook失败")
 	ErrDeleteWebhook = NewHTTPError(6884, "删除webhook失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// chatops releated Error Range: 6890 - 6899
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateChatOpsApp     = NewHTTPError(6890, "创建ChatOps应用失败")
+	ErrUpdateChatOpsApp     = NewHTTPError(6891, "更新ChatOps应用失败")
+	ErrDeleteChatOpsApp     = NewHTTPError(6892, "删除ChatOps应用失败")
+	ErrListChatOpsApps      = NewHTTPError(6893, "列出ChatOps应用失败")
+	ErrCreateChatOpsBinding = NewHTTPError(6894, "创建ChatOps用户绑定失败")
+	ErrDeleteChatOpsBinding = NewHTTPError(6895, "删除ChatOps用户绑定失败")
+	ErrListChatOpsBindings  = NewHTTPError(6896, "列出ChatOps用户绑定失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// marketplace releated Error Range: 6900 - 6909
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateMarketplaceSource        = NewHTTPError(6900, "创建插件市场源失败")
+	ErrUpdateMarketplaceSource        = NewHTTPError(6901, "更新插件市场源失败")
+	ErrDeleteMarketplaceSource        = NewHTTPError(6902, "删除插件市场源失败")
+	ErrListMarketplaceSources         = NewHTTPError(6903, "列出插件市场源失败")
+	ErrSyncMarketplaceSource          = NewHTTPError(6904, "同步插件市场源失败")
+	ErrListMarketplaceItems           = NewHTTPError(6905, "列出插件市场条目失败")
+	ErrInstallMarketplaceItem         = NewHTTPError(6906, "安装插件市场条目失败")
+	ErrUpgradeMarketplaceInstallation = NewHTTPError(6907, "升级插件市场条目失败")
+	ErrDeleteMarketplaceInstallation  = NewHTTPError(6908, "卸载插件市场条目失败")
+	ErrListMarketplaceInstallations   = NewHTTPError(6909, "列出已安装的插件市场条目失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// webhook relay releated Error Range: 6910 - 6919
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateWebhookRelayRule     = NewHTTPError(6910, "创建webhook转发规则失败")
+	ErrUpdateWebhookRelayRule     = NewHTTPError(6911, "更新webhook转发规则失败")
+	ErrDeleteWebhookRelayRule     = NewHTTPError(6912, "删除webhook转发规则失败")
+	ErrListWebhookRelayRules      = NewHTTPError(6913, "列出webhook转发规则失败")
+	ErrListWebhookRelayDeliveries = NewHTTPError(6914, "列出webhook转发记录失败")
+	ErrRelayWebhook               = NewHTTPError(6915, "转发webhook失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// webhook event queue releated Error Range: 6920 - 6929
+	//-----------------------------------------------------------------------------------------------
+	ErrEnqueueWebhookEvent = NewHTTPError(6920, "webhook入队失败")
+	ErrListWebhookEvents   = NewHTTPError(6921, "列出webhook事件失败")
+	ErrGetWebhookEvent     = NewHTTPError(6922, "获取webhook事件失败")
+	ErrRetryWebhookEvent   = NewHTTPError(6923, "重试webhook事件失败")
+	ErrDeleteWebhookEvent  = NewHTTPError(6924, "删除webhook事件失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// service render releated Error Range: 6930 - 6939
+	//-----------------------------------------------------------------------------------------------
+	ErrRenderEnvServices = NewHTTPError(6930, "渲染环境服务失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// job warm pool releated Error Range: 6940 - 6949
+	//-----------------------------------------------------------------------------------------------
+	ErrListJobWarmPool   = NewHTTPError(6940, "列出预热池失败")
+	ErrCreateJobWarmPool = NewHTTPError(6941, "创建预热池失败")
+	ErrUpdateJobWarmPool = NewHTTPError(6942, "更新预热池失败")
+	ErrDeleteJobWarmPool = NewHTTPError(6943, "删除预热池失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// tenant releated Error Range: 6950 - 6959
+	//-----------------------------------------------------------------------------------------------
+	ErrListTenant          = NewHTTPError(6950, "列出租户失败")
+	ErrCreateTenant        = NewHTTPError(6951, "创建租户失败")
+	ErrUpdateTenant        = NewHTTPError(6952, "更新租户失败")
+	ErrDeleteTenant        = NewHTTPError(6953, "删除租户失败")
+	ErrGetTenantUsage      = NewHTTPError(6954, "获取租户用量失败")
+	ErrTenantForbidden     = NewHTTPError(6955, "无权访问该租户的资源")
+	ErrTenantQuotaExceeded = NewHTTPError(6956, "超出租户配额")
+
+	//-----------------------------------------------------------------------------------------------
+	// project bundle releated Error Range: 6960 - 6969
+	//-----------------------------------------------------------------------------------------------
+	ErrExportProject = NewHTTPError(6960, "导出项目失败")
+	ErrImportProject = NewHTTPError(6961, "导入项目失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// project onboarding releated Error Range: 6970 - 6979
+	//-----------------------------------------------------------------------------------------------
+	ErrAnalyzeRepository = NewHTTPError(6970, "分析代码库失败")
+	ErrApplyOnboarding   = NewHTTPError(6971, "创建项目接入配置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// service dependency releated Error Range: 6980 - 6989
+	//-----------------------------------------------------------------------------------------------
+	ErrGetServiceDependency    = NewHTTPError(6980, "获取服务依赖失败")
+	ErrUpdateServiceDependency = NewHTTPError(6981, "更新服务依赖失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// build template releated Error Range: 6990 - 6999
+	//-----------------------------------------------------------------------------------------------
+	ErrApplyBuildTemplate = NewHTTPError(6990, "批量应用构建模板失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// workflow preference releated Error Range: 7000 - 7009
+	//-----------------------------------------------------------------------------------------------
+	ErrGetWorkflowPreference    = NewHTTPError(7000, "获取工作流偏好设置失败")
+	ErrUpdateWorkflowPreference = NewHTTPError(7001, "更新工作流偏好设置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// duplicate releated Error Range: 7010 - 7019
+	//-----------------------------------------------------------------------------------------------
+	ErrDuplicateWorkflow = NewHTTPError(7010, "复制工作流失败")
+	ErrDuplicateEnv      = NewHTTPError(7011, "复制环境失败")
+	ErrDuplicateProject  = NewHTTPError(7012, "复制项目失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// webhook simulation releated Error Range: 7020 - 7029
+	//-----------------------------------------------------------------------------------------------
+	ErrSimulateWebhook = NewHTTPError(7020, "模拟webhook触发失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// deploy diff releated Error Range: 7030 - 7039
+	//-----------------------------------------------------------------------------------------------
+	ErrConfirmDeployDiff = NewHTTPError(7030, "确认部署变更失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// maintenance releated Error Range: 7040 - 7049
+	//-----------------------------------------------------------------------------------------------
+	ErrGetMaintenance    = NewHTTPError(7040, "获取维护模式失败")
+	ErrUpdateMaintenance = NewHTTPError(7041, "更新维护模式失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// workflow badge releated Error Range: 7050 - 7059
+	//-----------------------------------------------------------------------------------------------
+	ErrGetWorkflowBadge    = NewHTTPError(7050, "获取工作流徽章失败")
+	ErrUpdateWorkflowBadge = NewHTTPError(7051, "更新工作流徽章失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// env data refresh releated Error Range: 7060 - 7069
+	//-----------------------------------------------------------------------------------------------
+	ErrListEnvDataRefresh   = NewHTTPError(7060, "获取环境数据刷新列表失败")
+	ErrCreateEnvDataRefresh = NewHTTPError(7061, "创建环境数据刷新失败")
+	ErrUpdateEnvDataRefresh = NewHTTPError(7062, "更新环境数据刷新失败")
+	ErrDeleteEnvDataRefresh = NewHTTPError(7063, "删除环境数据刷新失败")
+	ErrRunEnvDataRefresh    = NewHTTPError(7064, "执行环境数据刷新失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// cronjob run releated Error Range: 7070 - 7079
+	//-----------------------------------------------------------------------------------------------
+	ErrAcquireCronjobRun       = NewHTTPError(7070, "获取定时任务执行权失败")
+	ErrRecordMissedCronjobRuns = NewHTTPError(7071, "记录错过的定时任务失败")
+	ErrListCronjobRuns         = NewHTTPError(7072, "获取定时任务执行记录失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// system bootstrap releated Error Range: 7080 - 7089
+	//-----------------------------------------------------------------------------------------------
+	ErrLoadSystemBootstrap      = NewHTTPError(7080, "加载系统初始化配置失败")
+	ErrReconcileSystemBootstrap = NewHTTPError(7081, "同步系统初始化配置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// encryption key releated Error Range: 7090 - 7099
+	//-----------------------------------------------------------------------------------------------
+	ErrRotateEncryptionKey = NewHTTPError(7090, "轮换加密密钥失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// credential health releated Error Range: 7100 - 7109
+	//-----------------------------------------------------------------------------------------------
+	ErrListCredentialHealth  = NewHTTPError(7100, "获取凭证健康状态失败")
+	ErrCheckCredentialHealth = NewHTTPError(7101, "检查凭证健康状态失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// commit policy releated Error Range: 7110 - 7119
+	//-----------------------------------------------------------------------------------------------
+	ErrGetCommitPolicy    = NewHTTPError(7110, "获取提交策略失败")
+	ErrUpdateCommitPolicy = NewHTTPError(7111, "更新提交策略失败")
+	ErrCommitPolicyDenied = NewHTTPError(7112, "提交不满足项目的提交策略")
+
+	//-----------------------------------------------------------------------------------------------
+	// api param policy releated Error Range: 7120 - 7129
+	//-----------------------------------------------------------------------------------------------
+	ErrAPIParamPolicyDenied = NewHTTPError(7120, "参数不满足工作流的 API 触发策略")
+
+	//-----------------------------------------------------------------------------------------------
+	// helm post renderer releated Error Range: 7130 - 7139
+	//-----------------------------------------------------------------------------------------------
+	ErrUpdateHelmPostRenderer    = NewHTTPError(7130, "更新 Helm Post Renderer 失败")
+	ErrListHelmPostRenderRecords = NewHTTPError(7131, "获取 Helm Post Render 记录失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// manifest policy releated Error Range: 7140 - 7149
+	//-----------------------------------------------------------------------------------------------
+	ErrGetManifestPolicy    = NewHTTPError(7140, "获取部署策略失败")
+	ErrUpdateManifestPolicy = NewHTTPError(7141, "更新部署策略失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// namespace meta releated Error Range: 7150 - 7159
+	//-----------------------------------------------------------------------------------------------
+	ErrUpdateNamespaceMeta = NewHTTPError(7150, "更新环境命名空间标签失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// service copy releated Error Range: 7160 - 7169
+	//-----------------------------------------------------------------------------------------------
+	ErrPreviewServiceCopy = NewHTTPError(7160, "预览服务复制失败")
+	ErrCopyService        = NewHTTPError(7161, "复制服务到环境失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// cluster shared service releated Error Range: 7170 - 7179
+	//-----------------------------------------------------------------------------------------------
+	ErrListClusterSharedService    = NewHTTPError(7170, "获取集群共享服务列表失败")
+	ErrGetClusterSharedService     = NewHTTPError(7171, "获取集群共享服务失败")
+	ErrCreateClusterSharedService  = NewHTTPError(7172, "创建集群共享服务失败")
+	ErrUpgradeClusterSharedService = NewHTTPError(7173, "升级集群共享服务失败")
+	ErrDeleteClusterSharedService  = NewHTTPError(7174, "删除集群共享服务失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// rollout job releated Error Range: 7180 - 7189
+	//-----------------------------------------------------------------------------------------------
+	ErrControlRollout     = NewHTTPError(7180, "控制分批发布失败")
+	ErrGetRolloutProgress = NewHTTPError(7181, "获取分批发布进度失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// codehost validation releated Error Range: 7190 - 7199
+	//-----------------------------------------------------------------------------------------------
+	ErrValidateCodeHost = NewHTTPError(7190, "代码源配置校验失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// pod log releated Error Range: 7200 - 7209
+	//-----------------------------------------------------------------------------------------------
+	ErrGetPodLogs          = NewHTTPError(7200, "获取容器日志失败")
+	ErrTailPodLogs         = NewHTTPError(7201, "实时获取容器日志失败")
+	ErrDownloadServiceLogs = NewHTTPError(7202, "下载服务日志失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// env smoke test releated Error Range: 7210 - 7219
+	//-----------------------------------------------------------------------------------------------
+	ErrListEnvSmokeTest   = NewHTTPError(7210, "获取环境冒烟测试列表失败")
+	ErrCreateEnvSmokeTest = NewHTTPError(7211, "创建环境冒烟测试失败")
+	ErrUpdateEnvSmokeTest = NewHTTPError(7212, "更新环境冒烟测试失败")
+	ErrDeleteEnvSmokeTest = NewHTTPError(7213, "删除环境冒烟测试失败")
+	ErrRunEnvSmokeTest    = NewHTTPError(7214, "执行环境冒烟测试失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// release train releated Error Range: 7220 - 7229
+	//-----------------------------------------------------------------------------------------------
+	ErrListReleaseTrain     = NewHTTPError(7220, "获取发布列车列表失败")
+	ErrCreateReleaseTrain   = NewHTTPError(7221, "创建发布列车失败")
+	ErrUpdateReleaseTrain   = NewHTTPError(7222, "更新发布列车失败")
+	ErrDeleteReleaseTrain   = NewHTTPError(7223, "删除发布列车失败")
+	ErrDispatchReleaseTrain = NewHTTPError(7224, "发车失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// dependency update releated Error Range: 7230 - 7239
+	//-----------------------------------------------------------------------------------------------
+	ErrGetDependencyUpdatePolicy    = NewHTTPError(7230, "获取依赖更新策略失败")
+	ErrUpdateDependencyUpdatePolicy = NewHTTPError(7231, "更新依赖更新策略失败")
+	ErrListDependencyUpdate         = NewHTTPError(7232, "获取依赖更新列表失败")
+	ErrCheckDependencyUpdate        = NewHTTPError(7233, "检查依赖更新失败")
+	ErrCreateDependencyUpdatePR     = NewHTTPError(7234, "创建依赖更新 PR 失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// license policy releated Error Range: 7240 - 7249
+	//-----------------------------------------------------------------------------------------------
+	ErrGetLicensePolicy    = NewHTTPError(7240, "获取许可证策略失败")
+	ErrUpdateLicensePolicy = NewHTTPError(7241, "更新许可证策略失败")
+	ErrCreateLicenseWaiver = NewHTTPError(7242, "创建许可证豁免失败")
+	ErrDeleteLicenseWaiver = NewHTTPError(7243, "删除许可证豁免失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// secret scan releated Error Range: 7250 - 7259
+	//-----------------------------------------------------------------------------------------------
+	ErrGetSecretScanPolicy    = NewHTTPError(7250, "获取密钥扫描策略失败")
+	ErrUpdateSecretScanPolicy = NewHTTPError(7251, "更新密钥扫描策略失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// runner releated Error Range: 7260 - 7269
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateRunner         = NewHTTPError(7260, "创建构建节点失败")
+	ErrUpdateRunner         = NewHTTPError(7261, "更新构建节点失败")
+	ErrDeleteRunner         = NewHTTPError(7262, "删除构建节点失败")
+	ErrListRunners          = NewHTTPError(7263, "列出构建节点失败")
+	ErrGetRunner            = NewHTTPError(7264, "获取构建节点失败")
+	ErrLeaseRunnerJob       = NewHTTPError(7265, "领取构建节点任务失败")
+	ErrUpdateRunnerJob      = NewHTTPError(7266, "更新构建节点任务失败")
+	ErrUploadRunnerArtifact = NewHTTPError(7267, "上传构建节点产物失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// codehost webhook releated Error Range: 7270 - 7279
+	//-----------------------------------------------------------------------------------------------
+	ErrRegisterCodeHostWebhook   = NewHTTPError(7270, "注册代码源Webhook失败")
+	ErrListCodeHostWebhooks      = NewHTTPError(7271, "列出代码源Webhook失败")
+	ErrDeleteCodeHostWebhook     = NewHTTPError(7272, "删除代码源Webhook失败")
+	ErrReconcileCodeHostWebhooks = NewHTTPError(7273, "同步代码源Webhook失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// codehost deletion releated Error Range: 7280 - 7289
+	//-----------------------------------------------------------------------------------------------
+	ErrListCodeHostReferences = NewHTTPError(7280, "获取代码源引用失败")
+	ErrCodeHostInUse          = NewHTTPError(7281, "代码源正在被使用，无法删除")
+	ErrDeleteCodeHost         = NewHTTPError(7282, "删除代码源失败")
+	ErrRestoreCodeHost        = NewHTTPError(7283, "恢复代码源失败")
+	ErrCodeHostDisabled       = NewHTTPError(7284, "代码源已被禁用")
+	ErrUpdateCodeHostEnabled  = NewHTTPError(7285, "启用或禁用代码源失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// job security profile releated Error Range: 7290 - 7299
+	//-----------------------------------------------------------------------------------------------
+	ErrGetJobSecurity          = NewHTTPError(7290, "获取任务安全配置失败")
+	ErrUpdateJobSecurity       = NewHTTPError(7291, "更新任务安全配置失败")
+	ErrGetJobSecurityPolicy    = NewHTTPError(7292, "获取项目任务安全策略失败")
+	ErrUpdateJobSecurityPolicy = NewHTTPError(7293, "更新项目任务安全策略失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// codehost visibility releated Error Range: 7300 - 7309
+	//-----------------------------------------------------------------------------------------------
+	ErrListCodehost = NewHTTPError(7300, "列出代码源失败")
+	ErrGetCodehost  = NewHTTPError(7301, "获取代码源失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// job context export releated Error Range: 7310 - 7319
+	//-----------------------------------------------------------------------------------------------
+	ErrExportJobContext = NewHTTPError(7310, "导出任务执行环境失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// job debug releated Error Range: 7320 - 7329
+	//-----------------------------------------------------------------------------------------------
+	ErrDebugJob     = NewHTTPError(7320, "调试任务失败")
+	ErrStopDebugJob = NewHTTPError(7321, "结束任务调试失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// codehost transfer releated Error Range: 7330 - 7339
+	//-----------------------------------------------------------------------------------------------
+	ErrExportCodeHost   = NewHTTPError(7330, "导出代码源失败")
+	ErrImportCodeHost   = NewHTTPError(7331, "导入代码源失败")
+	ErrBulkSaveCodeHost = NewHTTPError(7332, "批量保存代码源失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// variable group releated Error Range: 7340 - 7349
+	//-----------------------------------------------------------------------------------------------
+	ErrListVariableGroup   = NewHTTPError(7340, "获取变量组列表失败")
+	ErrGetVariableGroup    = NewHTTPError(7341, "获取变量组失败")
+	ErrCreateVariableGroup = NewHTTPError(7342, "创建变量组失败")
+	ErrUpdateVariableGroup = NewHTTPError(7343, "更新变量组失败")
+	ErrDeleteVariableGroup = NewHTTPError(7344, "删除变量组失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// stale resource releated Error Range: 7350 - 7359
+	//-----------------------------------------------------------------------------------------------
+	ErrListStaleResources   = NewHTTPError(7350, "获取闲置资源报告失败")
+	ErrArchiveStaleResource = NewHTTPError(7351, "归档闲置资源失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// user setting releated Error Range: 7360 - 7369
+	//-----------------------------------------------------------------------------------------------
+	ErrGetUserSetting    = NewHTTPError(7360, "获取用户设置失败")
+	ErrUpdateUserSetting = NewHTTPError(7361, "更新用户设置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// codehost notify releated Error Range: 7370 - 7379
+	//-----------------------------------------------------------------------------------------------
+	ErrGetCodeHostNotify    = NewHTTPError(7370, "获取代码源通知配置失败")
+	ErrUpdateCodeHostNotify = NewHTTPError(7371, "更新代码源通知配置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// share link releated Error Range: 7380 - 7389
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateShareLink   = NewHTTPError(7380, "创建分享链接失败")
+	ErrGetSharedResource = NewHTTPError(7381, "获取分享内容失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// user announcement releated Error Range: 7390 - 7399
+	//-----------------------------------------------------------------------------------------------
+	ErrListUserAnnouncements   = NewHTTPError(7390, "获取用户公告失败")
+	ErrAcknowledgeAnnouncement = NewHTTPError(7391, "确认公告失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// project quota releated Error Range: 7400 - 7409
+	//-----------------------------------------------------------------------------------------------
+	ErrGetProjectQuota      = NewHTTPError(7400, "获取项目配额失败")
+	ErrUpdateProjectQuota   = NewHTTPError(7401, "更新项目配额失败")
+	ErrDeleteProjectQuota   = NewHTTPError(7402, "删除项目配额失败")
+	ErrOverrideProjectQuota = NewHTTPError(7403, "豁免项目配额失败")
+	ErrProjectQuotaExceeded = NewHTTPError(7404, "超出项目配额")
+
+	//-----------------------------------------------------------------------------------------------
+	// gerrit voting policy releated Error Range: 7410 - 7419
+	//-----------------------------------------------------------------------------------------------
+	ErrGetGerritVotingPolicy    = NewHTTPError(7410, "获取 Gerrit 投票策略失败")
+	ErrUpdateGerritVotingPolicy = NewHTTPError(7411, "更新 Gerrit 投票策略失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// blueprint releated Error Range: 7420 - 7429
+	//-----------------------------------------------------------------------------------------------
+	ErrListBlueprint        = NewHTTPError(7420, "获取项目蓝图列表失败")
+	ErrGetBlueprint         = NewHTTPError(7421, "获取项目蓝图失败")
+	ErrCreateBlueprint      = NewHTTPError(7422, "创建项目蓝图失败")
+	ErrUpdateBlueprint      = NewHTTPError(7423, "更新项目蓝图失败")
+	ErrDeleteBlueprint      = NewHTTPError(7424, "删除项目蓝图失败")
+	ErrInstantiateBlueprint = NewHTTPError(7425, "根据项目蓝图创建失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// project configuration as of a time releated Error Range: 7430 - 7439
+	//-----------------------------------------------------------------------------------------------
+	ErrGetProjectConfigAsOf = NewHTTPError(7430, "获取项目历史配置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// environment lock releated Error Range: 7440 - 7449
+	//-----------------------------------------------------------------------------------------------
+	ErrGetEnvLock = NewHTTPError(7440, "获取环境锁失败")
+	ErrLockEnv    = NewHTTPError(7441, "锁定环境失败")
+	ErrEnvLocked  = NewHTTPError(7442, "环境已被锁定")
+	ErrUnlockEnv  = NewHTTPError(7443, "解锁环境失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// external url setting releated Error Range: 7450 - 7459
+	//-----------------------------------------------------------------------------------------------
+	ErrGetExternalURL    = NewHTTPError(7450, "获取外部访问地址配置失败")
+	ErrUpdateExternalURL = NewHTTPError(7451, "更新外部访问地址配置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// manifest lint releated Error Range: 7460 - 7469
+	//-----------------------------------------------------------------------------------------------
+	ErrGetManifestLint    = NewHTTPError(7460, "获取部署检查配置失败")
+	ErrUpdateManifestLint = NewHTTPError(7461, "更新部署检查配置失败")
+	ErrLintManifests      = NewHTTPError(7462, "检查服务配置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// api deprecation report releated Error Range: 7470 - 7479
+	//-----------------------------------------------------------------------------------------------
+	ErrGetDeprecationReport = NewHTTPError(7470, "获取 API 弃用报告失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// dns provider releated Error Range: 7480 - 7489
+	//-----------------------------------------------------------------------------------------------
+	ErrListDNSProviders  = NewHTTPError(7480, "获取 DNS 服务列表失败")
+	ErrCreateDNSProvider = NewHTTPError(7481, "新增 DNS 服务失败")
+	ErrUpdateDNSProvider = NewHTTPError(7482, "更新 DNS 服务失败")
+	ErrDeleteDNSProvider = NewHTTPError(7483, "删除 DNS 服务失败")
+	ErrGetEnvDNSRecords  = NewHTTPError(7484, "获取环境域名解析记录失败")
+	ErrSyncEnvDNSRecords = NewHTTPError(7485, "同步环境域名解析记录失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// git mirror releated Error Range: 7490 - 7499
+	//-----------------------------------------------------------------------------------------------
+	ErrGetGitMirrorSetting    = NewHTTPError(7490, "获取代码镜像配置失败")
+	ErrUpdateGitMirrorSetting = NewHTTPError(7491, "更新代码镜像配置失败")
+	ErrListGitMirrors         = NewHTTPError(7492, "获取代码镜像列表失败")
+	ErrCreateGitMirror        = NewHTTPError(7493, "新增代码镜像失败")
+	ErrDeleteGitMirror        = NewHTTPError(7494, "删除代码镜像失败")
+	ErrSyncGitMirror          = NewHTTPError(7495, "同步代码镜像失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// workflow param options releated Error Range: 7500 - 7509
+	//-----------------------------------------------------------------------------------------------
+	ErrListWorkflowParamOptions = NewHTTPError(7500, "获取工作流参数选项失败")
+	ErrInvalidWorkflowParams    = NewHTTPError(7501, "工作流参数不合法")
+
+	//-----------------------------------------------------------------------------------------------
+	// env auto update releated Error Range: 7510 - 7519
+	//-----------------------------------------------------------------------------------------------
+	ErrListEnvAutoUpdatePolicies = NewHTTPError(7510, "获取环境自动更新策略失败")
+	ErrCreateEnvAutoUpdatePolicy = NewHTTPError(7511, "创建环境自动更新策略失败")
+	ErrUpdateEnvAutoUpdatePolicy = NewHTTPError(7512, "更新环境自动更新策略失败")
+	ErrDeleteEnvAutoUpdatePolicy = NewHTTPError(7513, "删除环境自动更新策略失败")
+	ErrListEnvAutoUpdateEvents   = NewHTTPError(7514, "获取环境自动更新记录失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// credential usage releated Error Range: 7520 - 7529
+	//-----------------------------------------------------------------------------------------------
+	ErrListCredentialUsages = NewHTTPError(7520, "获取凭证使用记录失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// notification route releated Error Range: 7530 - 7539
+	//-----------------------------------------------------------------------------------------------
+	ErrListNotificationRoutes       = NewHTTPError(7530, "获取通知路由失败")
+	ErrCreateNotificationRoute      = NewHTTPError(7531, "创建通知路由失败")
+	ErrUpdateNotificationRoute      = NewHTTPError(7532, "更新通知路由失败")
+	ErrDeleteNotificationRoute      = NewHTTPError(7533, "删除通知路由失败")
+	ErrUpdateNotificationPreference = NewHTTPError(7534, "更新通知偏好失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// custom field releated Error Range: 7540 - 7549
+	//-----------------------------------------------------------------------------------------------
+	ErrListCustomFields        = NewHTTPError(7540, "获取自定义字段失败")
+	ErrCreateCustomField       = NewHTTPError(7541, "创建自定义字段失败")
+	ErrUpdateCustomField       = NewHTTPError(7542, "更新自定义字段失败")
+	ErrDeleteCustomField       = NewHTTPError(7543, "删除自定义字段失败")
+	ErrUpdateCustomFieldValues = NewHTTPError(7544, "更新自定义字段的值失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// project health releated Error Range: 7550 - 7559
+	//-----------------------------------------------------------------------------------------------
+	ErrGetProjectHealth         = NewHTTPError(7550, "获取项目健康度失败")
+	ErrGetHealthDigestPolicy    = NewHTTPError(7551, "获取健康周报配置失败")
+	ErrUpdateHealthDigestPolicy = NewHTTPError(7552, "更新健康周报配置失败")
+	ErrListHealthDigests        = NewHTTPError(7553, "获取健康周报失败")
 )
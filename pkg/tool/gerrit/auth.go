@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gerrit
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	gerrit "github.com/andygrunwald/go-gerrit"
+
+	"github.com/koderover/zadig/pkg/tool/ssh"
+)
+
+// DefaultSSHPort is the port of the ssh daemon of gerrit if it is not changed by sshd.listenAddress
+const DefaultSSHPort = 29418
+
+// BasicAuthToken is the access token of a gerrit codehost, the api is authorized by the http password of the user.
+func BasicAuthToken(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+}
+
+// NewBasicAuthClient authorizes the requests by the http password of the user, a nil httpClient means the
+// default client.
+func NewBasicAuthClient(address, username, password string, httpClient *http.Client) (*Client, error) {
+	cli, err := gerrit.NewClient(strings.TrimSuffix(address, "/"), httpClient)
+	if err != nil {
+		return nil, err
+	}
+	cli.Authentication.SetBasicAuth(username, password)
+	return &Client{cli: cli}, nil
+}
+
+// GetAccountSelf returns the account the client is authorized as, the status code is 0 if gerrit can not be
+// connected.
+func (c *Client) GetAccountSelf() (*gerrit.AccountInfo, int, error) {
+	account, resp, err := c.cli.Accounts.GetAccount("self")
+	if resp == nil {
+		return nil, 0, err
+	}
+	return account, resp.StatusCode, err
+}
+
+// SSHAddress returns the host and the port of the ssh daemon of gerrit whose web url is address.
+func SSHAddress(address string, port int) (string, int, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", 0, err
+	}
+	host := u.Hostname()
+	if host == "" {
+		return "", 0, fmt.Errorf("invalid gerrit address %s", address)
+	}
+	if port <= 0 {
+		port = DefaultSSHPort
+	}
+	return host, port, nil
+}
+
+// SSHCloneURL returns the url to clone the project over ssh, e.g. ssh://user@gerrit.example.com:29418/project.
+func SSHCloneURL(address string, port int, username, project string) (string, error) {
+	host, port, err := SSHAddress(address, port)
+	if err != nil {
+		return "", err
+	}
+	u := &url.URL{
+		Scheme: "ssh",
+		User:   url.User(username),
+		Host:   net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:   "/" + Unescape(project),
+	}
+	return u.String(), nil
+}
+
+// VerifySSHKey logs in the ssh daemon of gerrit with the private key and returns the version of gerrit.
+func VerifySSHKey(address string, port int, username, privateKey string) (string, error) {
+	host, port, err := SSHAddress(address, port)
+	if err != nil {
+		return "", err
+	}
+	cli, err := ssh.NewSshCli([]byte(privateKey), username, host, int64(port))
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	session, err := cli.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	out, err := session.Output("gerrit version")
+	if err != nil {
+		return "", fmt.Errorf("failed to run gerrit version: %s", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gerrit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSHCloneURL(t *testing.T) {
+	testcases := []struct {
+		address string
+		port    int
+		project string
+		result  string
+	}{
+		{"https://gerrit.example.com", 0, "zadig", "ssh://admin@gerrit.example.com:29418/zadig"},
+		{"http://gerrit.example.com:8080/", 2222, "koderover%2Fzadig", "ssh://admin@gerrit.example.com:2222/koderover/zadig"},
+	}
+
+	for _, tc := range testcases {
+		result, err := SSHCloneURL(tc.address, tc.port, "admin", tc.project)
+		if err != nil {
+			t.Fatalf("Unexpected error for address <%s>: %s", tc.address, err)
+		}
+		if result != tc.result {
+			t.Errorf("Expected clone url for address <%s> to be <%s> but got <%s>", tc.address, tc.result, result)
+		}
+	}
+}
+
+func TestGetAccountSelf(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if r.URL.Path != "/a/accounts/self" || !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, ")]}'\n{\"_account_id\": 1000000, \"username\": \"admin\"}")
+	}))
+	defer server.Close()
+
+	cli, _ := NewBasicAuthClient(server.URL, "admin", "secret", nil)
+	account, status, err := cli.GetAccountSelf()
+	if err != nil || status != http.StatusOK || account.Username != "admin" {
+		t.Errorf("Expected account admin but got <%+v>, status: %d, err: %v", account, status, err)
+	}
+
+	cli, _ = NewBasicAuthClient(server.URL, "admin", "wrong", nil)
+	if _, status, err = cli.GetAccountSelf(); err == nil || status != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for the wrong password but got %d, err: %v", status, err)
+	}
+}
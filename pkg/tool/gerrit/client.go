@@ -207,13 +207,19 @@ func (c *Client) GetCurrentVersionByChangeID(name string, pr int) (*gerrit.Chang
 }
 
 func (c *Client) SetReview(projectName string, changeID int, m, label, score, revision string) error {
-	projectName = Unescape(projectName)
 	var labels map[string]string
 	if len(label) != 0 {
 		labels = map[string]string{
 			label: score,
 		}
 	}
+	return c.SetReviewLabels(projectName, changeID, m, labels, revision)
+}
+
+// SetReviewLabels posts the message and votes on the labels at once, e.g. {"Verified": "+1", "Code-Review": "0"}.
+// The labels the account is not permitted to vote on are ignored by gerrit.
+func (c *Client) SetReviewLabels(projectName string, changeID int, m string, labels map[string]string, revision string) error {
+	projectName = Unescape(projectName)
 	_, _, err := c.cli.Changes.SetReview(
 		fmt.Sprintf("%s~%d", url.QueryEscape(projectName), changeID),
 		revision,
@@ -227,6 +233,72 @@ func (c *Client) SetReview(projectName string, changeID int, m, label, score, re
 	return err
 }
 
+// SeriesChange is an open change of a relation chain or a topic.
+type SeriesChange struct {
+	Project        string
+	Branch         string
+	Number         int
+	RevisionNumber int
+}
+
+// ListRelationChain returns the open changes related to the given change, the descendants are
+// listed before the ancestors, so the first one is the tip of the chain.
+func (c *Client) ListRelationChain(project string, number int) ([]*SeriesChange, error) {
+	project = Unescape(project)
+	changeID := fmt.Sprintf("%s~%d", url.QueryEscape(project), number)
+	related, _, err := c.cli.Changes.GetRelatedChanges(changeID, "current")
+	if err != nil {
+		return nil, err
+	}
+	change, _, err := c.cli.Changes.GetChange(changeID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*SeriesChange, 0, len(related.Changes))
+	for _, rc := range related.Changes {
+		if rc.Status == "MERGED" || rc.Status == "ABANDONED" {
+			continue
+		}
+		resp = append(resp, &SeriesChange{
+			Project:        project,
+			Branch:         change.Branch,
+			Number:         rc.ChangeNumber,
+			RevisionNumber: rc.CurrentRevisionNumber,
+		})
+	}
+	// a change without relations has an empty relation chain
+	if len(resp) == 0 {
+		resp = append(resp, &SeriesChange{Project: project, Branch: change.Branch, Number: number})
+	}
+	return resp, nil
+}
+
+// ListTopicChanges returns the open changes of the topic in all projects.
+func (c *Client) ListTopicChanges(topic string) ([]*SeriesChange, error) {
+	changes, _, err := c.cli.Changes.QueryChanges(&gerrit.QueryChangeOptions{
+		QueryOptions: gerrit.QueryOptions{
+			Query: []string{fmt.Sprintf("topic:\"%s\" status:open", topic)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*SeriesChange, 0)
+	if changes == nil {
+		return resp, nil
+	}
+	for _, change := range *changes {
+		resp = append(resp, &SeriesChange{
+			Project: change.Project,
+			Branch:  change.Branch,
+			Number:  change.Number,
+		})
+	}
+	return resp, nil
+}
+
 // CompareTwoPatchset 如果两个Patchset更新的内容相同，返回true，不相同则返回false
 func (c *Client) CompareTwoPatchset(changeID, newPatchSetID, oldPatchSetID string) (bool, error) {
 	newPatchSetChangeFiles, _, err := c.cli.Changes.ListFiles(changeID, newPatchSetID, nil)
@@ -15,6 +15,7 @@ package github
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/go-github/v35/github"
 )
@@ -27,3 +28,21 @@ func (c *Client) GetTree(ctx context.Context, owner string, repo string, sha str
 
 	return nil, err
 }
+
+// CreateBranch creates the branch from the head of the base branch.
+func (c *Client) CreateBranch(ctx context.Context, owner, repo, base, branch string) error {
+	ref, err := wrap(c.Git.GetRef(ctx, owner, repo, "heads/"+base))
+	if err != nil {
+		return err
+	}
+	baseRef, ok := ref.(*github.Reference)
+	if !ok {
+		return fmt.Errorf("branch %s is not found", base)
+	}
+
+	_, err = wrap(c.Git.CreateRef(ctx, owner, repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: baseRef.Object.SHA},
+	}))
+	return err
+}
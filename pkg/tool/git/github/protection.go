@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/v35/github"
+)
+
+// AddRequiredStatusCheck makes the status check required before merging into the branch,
+// the branch is protected with the status check only if it is not protected yet.
+func (c *Client) AddRequiredStatusCheck(ctx context.Context, owner, repo, branch, statusContext string) error {
+	protection, res, err := c.Repositories.GetBranchProtection(ctx, owner, repo, branch)
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		_, err = wrap(c.Repositories.UpdateBranchProtection(ctx, owner, repo, branch, &github.ProtectionRequest{
+			RequiredStatusChecks: &github.RequiredStatusChecks{Contexts: []string{statusContext}},
+		}))
+		return err
+	}
+	if err = wrapError(res, err); err != nil {
+		return err
+	}
+
+	if protection.RequiredStatusChecks == nil {
+		// status checks can only be enabled by updating the whole protection, keep the other settings as they are.
+		preq := toProtectionRequest(protection)
+		preq.RequiredStatusChecks = &github.RequiredStatusChecks{Contexts: []string{statusContext}}
+		_, err = wrap(c.Repositories.UpdateBranchProtection(ctx, owner, repo, branch, preq))
+		return err
+	}
+
+	for _, sc := range protection.RequiredStatusChecks.Contexts {
+		if sc == statusContext {
+			return nil
+		}
+	}
+	_, err = wrap(c.Repositories.UpdateRequiredStatusChecks(ctx, owner, repo, branch, &github.RequiredStatusChecksRequest{
+		Contexts: append(protection.RequiredStatusChecks.Contexts, statusContext),
+	}))
+	return err
+}
+
+// RemoveRequiredStatusCheck stops requiring the status check before merging into the branch,
+// the protection of the branch itself is kept.
+func (c *Client) RemoveRequiredStatusCheck(ctx context.Context, owner, repo, branch, statusContext string) error {
+	checks, res, err := c.Repositories.GetRequiredStatusChecks(ctx, owner, repo, branch)
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err = wrapError(res, err); err != nil {
+		return err
+	}
+
+	var contexts []string
+	for _, sc := range checks.Contexts {
+		if sc != statusContext {
+			contexts = append(contexts, sc)
+		}
+	}
+	if len(contexts) == len(checks.Contexts) {
+		return nil
+	}
+	if len(contexts) == 0 {
+		return wrapError(c.Repositories.RemoveRequiredStatusChecks(ctx, owner, repo, branch))
+	}
+	_, err = wrap(c.Repositories.UpdateRequiredStatusChecks(ctx, owner, repo, branch, &github.RequiredStatusChecksRequest{
+		Contexts: contexts,
+	}))
+	return err
+}
+
+func toProtectionRequest(protection *github.Protection) *github.ProtectionRequest {
+	preq := &github.ProtectionRequest{}
+	if protection.EnforceAdmins != nil {
+		preq.EnforceAdmins = protection.EnforceAdmins.Enabled
+	}
+	if reviews := protection.RequiredPullRequestReviews; reviews != nil {
+		preq.RequiredPullRequestReviews = &github.PullRequestReviewsEnforcementRequest{
+			DismissStaleReviews:          reviews.DismissStaleReviews,
+			RequireCodeOwnerReviews:      reviews.RequireCodeOwnerReviews,
+			RequiredApprovingReviewCount: reviews.RequiredApprovingReviewCount,
+		}
+	}
+	if restrictions := protection.Restrictions; restrictions != nil {
+		preq.Restrictions = &github.BranchRestrictionsRequest{Users: []string{}, Teams: []string{}}
+		for _, u := range restrictions.Users {
+			preq.Restrictions.Users = append(preq.Restrictions.Users, u.GetLogin())
+		}
+		for _, t := range restrictions.Teams {
+			preq.Restrictions.Teams = append(preq.Restrictions.Teams, t.GetSlug())
+		}
+		for _, a := range restrictions.Apps {
+			preq.Restrictions.Apps = append(preq.Restrictions.Apps, a.GetSlug())
+		}
+	}
+	if protection.RequireLinearHistory != nil {
+		preq.RequireLinearHistory = github.Bool(protection.RequireLinearHistory.Enabled)
+	}
+	if protection.AllowForcePushes != nil {
+		preq.AllowForcePushes = github.Bool(protection.AllowForcePushes.Enabled)
+	}
+	if protection.AllowDeletions != nil {
+		preq.AllowDeletions = github.Bool(protection.AllowDeletions.Enabled)
+	}
+	return preq
+}
@@ -91,3 +91,12 @@ func (c *Client) ListFiles(ctx context.Context, owner string, repo string, numbe
 
 	return res, err
 }
+
+func (c *Client) CreatePullRequest(ctx context.Context, owner, repo string, pull *github.NewPullRequest) (*github.PullRequest, error) {
+	pr, err := wrap(c.PullRequests.Create(ctx, owner, repo, pull))
+	if p, ok := pr.(*github.PullRequest); ok {
+		return p, err
+	}
+
+	return nil, err
+}
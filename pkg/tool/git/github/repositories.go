@@ -375,3 +375,32 @@ func (c *Client) getTreeContents(ctx context.Context, owner, repo, branch, base,
 
 	return nil
 }
+
+// UpdateFile commits the content of the file to the branch, the sha is the blob sha of the file being replaced.
+func (c *Client) UpdateFile(ctx context.Context, owner, repo, path, branch, sha, message string, content []byte) error {
+	_, err := wrap(c.Repositories.UpdateFile(ctx, owner, repo, path, &github.RepositoryContentFileOptions{
+		Message: github.String(message),
+		Content: content,
+		SHA:     github.String(sha),
+		Branch:  github.String(branch),
+	}))
+	return err
+}
+
+// CreateRepositoryFromTemplate generates the repository owner/name from the template repository, the
+// repository is created for the authenticated user if owner is empty.
+func (c *Client) CreateRepositoryFromTemplate(ctx context.Context, templateOwner, templateRepo, owner, name string, private bool) (*github.Repository, error) {
+	req := &github.TemplateRepoRequest{Name: &name, Private: &private}
+	if owner != "" {
+		req.Owner = &owner
+	}
+	created, err := wrap(c.Repositories.CreateFromTemplate(ctx, templateOwner, templateRepo, req))
+	if err != nil {
+		return nil, err
+	}
+	res, ok := created.(*github.Repository)
+	if !ok {
+		return nil, fmt.Errorf("object is not a github Repository")
+	}
+	return res, nil
+}
@@ -44,3 +44,12 @@ func (c *Client) ListBranches(owner, repo, key string, opts *ListOptions) ([]*gi
 
 	return res, err
 }
+
+// CreateBranch creates the branch from the ref, which is a branch name or a commit sha.
+func (c *Client) CreateBranch(owner, repo, ref, branch string) error {
+	_, err := wrap(c.Branches.CreateBranch(generateProjectName(owner, repo), &gitlab.CreateBranchOptions{
+		Branch: gitlab.String(branch),
+		Ref:    gitlab.String(ref),
+	}))
+	return err
+}
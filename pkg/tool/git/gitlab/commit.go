@@ -53,3 +53,38 @@ func (c *Client) GetSingleCommitOfProject(owner, repo, commitSha string) (*gitla
 
 	return nil, err
 }
+
+func (c *Client) SetCommitStatus(owner, repo, commitSha string, opts *gitlab.SetCommitStatusOptions) error {
+	_, err := wrap(c.Commits.SetCommitStatus(generateProjectName(owner, repo), commitSha, opts))
+	return err
+}
+
+// GetCommitSignature returns the GPG or SSH signature of the commit.
+func (c *Client) GetCommitSignature(owner, repo, commitSha string) (*gitlab.GPGSignature, error) {
+	signature, err := wrap(c.Commits.GetGPGSiganature(generateProjectName(owner, repo), commitSha))
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := signature.(*gitlab.GPGSignature); ok {
+		return s, nil
+	}
+
+	return nil, err
+}
+
+// CompareCommits returns the commits and the diffs between the two commits of the project.
+func (c *Client) CompareCommits(owner, repo, from, to string) (*gitlab.Compare, error) {
+	opts := &gitlab.CompareOptions{
+		From: &from,
+		To:   &to,
+	}
+	compare, err := wrap(c.Repositories.Compare(generateProjectName(owner, repo), opts))
+	if err != nil {
+		return nil, err
+	}
+	if cp, ok := compare.(*gitlab.Compare); ok {
+		return cp, nil
+	}
+
+	return nil, err
+}
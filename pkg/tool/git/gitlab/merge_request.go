@@ -77,3 +77,17 @@ func (c *Client) ListChangedFiles(event *gitlab.MergeEvent) ([]string, error) {
 //	_, err := wrap(c.Discussions.CreateCommitDiscussion(generateProjectName(owner, repo), commitHash, args))
 //	return err
 //}
+
+func (c *Client) CreateMergeRequest(owner, repo, sourceBranch, targetBranch, title, description string) (*gitlab.MergeRequest, error) {
+	mr, err := wrap(c.MergeRequests.CreateMergeRequest(generateProjectName(owner, repo), &gitlab.CreateMergeRequestOptions{
+		Title:        gitlab.String(title),
+		Description:  gitlab.String(description),
+		SourceBranch: gitlab.String(sourceBranch),
+		TargetBranch: gitlab.String(targetBranch),
+	}))
+	if m, ok := mr.(*gitlab.MergeRequest); ok {
+		return m, err
+	}
+
+	return nil, err
+}
@@ -136,6 +136,16 @@ func (c *Client) ListProjectHooks(owner, repo string, opts *ListOptions) ([]*git
 	return res, err
 }
 
+// EnablePipelineMergeCheck blocks merging merge requests of the project until the pipeline
+// of the merge request, including the external commit statuses, succeeds.
+func (c *Client) EnablePipelineMergeCheck(owner, repo string) error {
+	opts := &gitlab.EditProjectOptions{
+		OnlyAllowMergeIfPipelineSucceeds: boolptr.True(),
+	}
+	_, err := wrap(c.Projects.EditProject(generateProjectName(owner, repo), opts))
+	return err
+}
+
 func (c *Client) GetProjectID(owner, repo string) (int, error) {
 	p, err := c.getProject(owner, repo)
 	if err != nil {
@@ -187,3 +197,34 @@ func addEventsToProjectHookEditOptions(events []string, opts *gitlab.EditProject
 
 	return opts
 }
+
+// CreateProjectFromTemplate forks the template project to namespace/name and removes the fork relationship,
+// so that the new project is independent of the template. The project is forked to the namespace of the
+// authenticated user if namespace is empty.
+func (c *Client) CreateProjectFromTemplate(templateOwner, templateRepo, namespace, name string, private bool) (*gitlab.Project, error) {
+	visibility := gitlab.InternalVisibility
+	if private {
+		visibility = gitlab.PrivateVisibility
+	}
+	opts := &gitlab.ForkProjectOptions{
+		Name:       gitlab.String(name),
+		Path:       gitlab.String(name),
+		Visibility: gitlab.Visibility(visibility),
+	}
+	if namespace != "" {
+		opts.NamespacePath = gitlab.String(namespace)
+	}
+	forked, err := wrap(c.Projects.ForkProject(generateProjectName(templateOwner, templateRepo), opts))
+	if err != nil {
+		return nil, err
+	}
+	res, ok := forked.(*gitlab.Project)
+	if !ok {
+		return nil, fmt.Errorf("object is not a gitlab Project")
+	}
+
+	if err := wrapError(c.Projects.DeleteProjectForkRelation(res.ID)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
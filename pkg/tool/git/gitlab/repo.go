@@ -196,3 +196,13 @@ func (c *Client) getTreeContents(owner, repo, branch, base, path string, isDir b
 
 	return nil
 }
+
+// UpdateFile commits the content of the file to the branch.
+func (c *Client) UpdateFile(owner, repo, path, branch, message string, content []byte) error {
+	_, err := wrap(c.RepositoryFiles.UpdateFile(generateProjectName(owner, repo), path, &gitlab.UpdateFileOptions{
+		Branch:        gitlab.String(branch),
+		Content:       gitlab.String(string(content)),
+		CommitMessage: gitlab.String(message),
+	}))
+	return err
+}
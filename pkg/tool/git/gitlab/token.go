@@ -50,13 +50,15 @@ func UpdateGitlabToken(id int, accessToken string) (string, error) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	ch, err := systemconfig.New().GetCodeHost(id)
+	// the refresh token can only be used once, so the cached codehost is never used.
+	ch, err := systemconfig.New().GetLatestCodeHost(id)
 
 	if err != nil {
 		return "", fmt.Errorf("get codehost info error: [%s]", err)
 	}
 
-	if time.Now().Unix()-ch.UpdatedAt <= TokenExpirationThreshold {
+	// personal access tokens have no refresh token and are used as they are.
+	if ch.RefreshToken == "" || time.Now().Unix()-ch.UpdatedAt <= TokenExpirationThreshold {
 		return ch.AccessToken, nil
 	}
 
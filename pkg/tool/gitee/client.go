@@ -53,7 +53,7 @@ func NewClient(id int, accessToken, proxyAddr string, enableProxy bool) *Client
 	}
 
 	if accessToken != "" {
-		ch, err := systemconfig.New().GetCodeHost(id)
+		ch, err := systemconfig.New().GetLatestCodeHost(id)
 		// The normal expiration time is 86400
 		if err == nil && (time.Now().Unix()-ch.UpdatedAt) >= 86000 {
 			token, err := RefreshAccessToken(ch.RefreshToken)
@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+
+	DefaultTimeout = 5 * time.Second
+)
+
+// Check is one dependency of a service, e.g. the database.
+type Check struct {
+	Name string
+	// Hint tells the administrator how to fix the failure.
+	Hint string
+	// The failure of an optional check, e.g. a cluster attached by the user, is reported as a
+	// warning and does not make the service unhealthy.
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+type Result struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+	// Duration is in milliseconds.
+	Duration int64 `json:"duration"`
+}
+
+type Report struct {
+	Service   string    `json:"service"`
+	Status    string    `json:"status"`
+	CheckedAt int64     `json:"checked_at"`
+	Checks    []*Result `json:"checks"`
+}
+
+// Run runs the checks concurrently, each of them is canceled after the timeout.
+func Run(ctx context.Context, service string, timeout time.Duration, checks []*Check) *Report {
+	report := &Report{
+		Service:   service,
+		Status:    StatusOK,
+		CheckedAt: time.Now().Unix(),
+		Checks:    make([]*Result, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *Check) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, timeout, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		report.Status = worse(report.Status, result.Status)
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, timeout time.Duration, check *Check) *Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errCh <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	result := &Result{
+		Name:     check.Name,
+		Status:   StatusOK,
+		Duration: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status, result.Message, result.Hint = StatusFailed, err.Error(), check.Hint
+		if check.Optional {
+			result.Status = StatusWarning
+		}
+	}
+	return result
+}
+
+func worse(a, b string) string {
+	rank := map[string]int{StatusOK: 0, StatusWarning: 1, StatusFailed: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// Merge returns the worst status of the reports.
+func Merge(reports []*Report) string {
+	status := StatusOK
+	for _, report := range reports {
+		status = worse(status, report.Status)
+	}
+	return status
+}
+
+// Handler serves the report of the checks, the status code is 503 if the service is unhealthy. The
+// checks are built for every request since some of them, e.g. the attached clusters, change at runtime.
+func Handler(service string, checks func() []*Check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context(), service, DefaultTimeout, checks())
+		code := http.StatusOK
+		if report.Status == StatusFailed {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	}
+}
+
+// SelfCheck runs the checks once when the service starts and logs the failures with the hints, it
+// does not block the startup since the dependencies may become ready later.
+func SelfCheck(ctx context.Context, service string, checks []*Check) *Report {
+	report := Run(ctx, service, DefaultTimeout, checks)
+	for _, result := range report.Checks {
+		switch result.Status {
+		case StatusFailed:
+			log.Errorf("self-check %s of %s failed: %s, hint: %s", result.Name, service, result.Message, result.Hint)
+		case StatusWarning:
+			log.Warnf("self-check %s of %s failed: %s, hint: %s", result.Name, service, result.Message, result.Hint)
+		}
+	}
+	if report.Status == StatusOK {
+		log.Infof("self-check of %s passed", service)
+	}
+	return report
+}
+
+// HTTPGet returns a check function which succeeds if the url responds with a 2xx status code.
+func HTTPGet(url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("GET %s responded with %s", url, resp.Status)
+		}
+		return nil
+	}
+}
+
+// NSQLookupdChecks returns the checks of the http addresses of nsqlookupd, the scheme is optional.
+func NSQLookupdChecks(addrs []string, hint string) []*Check {
+	var checks []*Check
+	for _, addr := range addrs {
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, "://") {
+			addr = "http://" + addr
+		}
+		checks = append(checks, &Check{
+			Name: "nsqlookupd/" + strings.TrimPrefix(strings.TrimPrefix(addr, "http://"), "https://"),
+			Hint: hint,
+			Run:  HTTPGet(addr + "/ping"),
+		})
+	}
+	return checks
+}
+
+// Fetch gets the report of a remote service, the returned report is failed if the service is not
+// reachable so that the caller can always merge it.
+func Fetch(ctx context.Context, service, url, hint string) *Report {
+	report := &Report{Service: service, Status: StatusFailed, CheckedAt: time.Now().Unix()}
+	ctx, cancel := context.WithTimeout(ctx, 2*DefaultTimeout)
+	defer cancel()
+
+	err := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		// 503 is responded with the report as well
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+			return fmt.Errorf("GET %s responded with %s", url, resp.Status)
+		}
+		return json.NewDecoder(resp.Body).Decode(report)
+	}()
+	if err != nil {
+		report.Status = StatusFailed
+		report.Checks = []*Result{{Name: "reachability", Status: StatusFailed, Message: err.Error(), Hint: hint}}
+	}
+	report.Service = service
+	return report
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func ok(context.Context) error { return nil }
+
+func fail(context.Context) error { return errors.New("connection refused") }
+
+var _ = Describe("Run", func() {
+	It("reports the worst status of the checks", func() {
+		report := Run(context.Background(), "aslan", time.Second, []*Check{
+			{Name: "mongodb", Run: ok},
+			{Name: "cluster/dev", Optional: true, Hint: "check the agent", Run: fail},
+		})
+		Expect(report.Status).To(Equal(StatusWarning))
+		Expect(report.Checks[0].Status).To(Equal(StatusOK))
+		Expect(report.Checks[1].Status).To(Equal(StatusWarning))
+		Expect(report.Checks[1].Hint).To(Equal("check the agent"))
+
+		report = Run(context.Background(), "aslan", time.Second, []*Check{
+			{Name: "mongodb", Hint: "check the mongodb uri", Run: fail},
+			{Name: "cluster/dev", Optional: true, Run: fail},
+		})
+		Expect(report.Status).To(Equal(StatusFailed))
+		Expect(report.Checks[0].Message).To(Equal("connection refused"))
+	})
+
+	It("fails the checks that time out or panic", func() {
+		report := Run(context.Background(), "aslan", 10*time.Millisecond, []*Check{
+			{Name: "slow", Run: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			}},
+			{Name: "panic", Run: func(context.Context) error { panic("nil map") }},
+		})
+		Expect(report.Checks[0].Status).To(Equal(StatusFailed))
+		Expect(report.Checks[0].Message).To(ContainSubstring("timed out"))
+		Expect(report.Checks[1].Status).To(Equal(StatusFailed))
+		Expect(report.Checks[1].Message).To(ContainSubstring("nil map"))
+	})
+})
+
+var _ = Describe("Handler", func() {
+	It("responds 503 if the service is unhealthy and the report can be fetched", func() {
+		server := httptest.NewServer(Handler("cron", func() []*Check {
+			return []*Check{{Name: "aslan", Run: fail}}
+		}))
+		defer server.Close()
+
+		resp, err := http.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+		report := Fetch(context.Background(), "cron", server.URL, "")
+		Expect(report.Status).To(Equal(StatusFailed))
+		Expect(report.Checks).To(HaveLen(1))
+		Expect(report.Checks[0].Name).To(Equal("aslan"))
+	})
+
+	It("fails the report of an unreachable service with the hint", func() {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		report := Fetch(context.Background(), "warpdrive", server.URL, "check the pod of warpdrive")
+		Expect(report.Status).To(Equal(StatusFailed))
+		Expect(report.Checks[0].Hint).To(Equal("check the pod of warpdrive"))
+		Expect(Merge([]*Report{{Status: StatusOK}, report})).To(Equal(StatusFailed))
+	})
+})
@@ -436,10 +436,13 @@ func (hClient *HelmClient) getChart(chartName string, chartPathOptions *action.C
 	return helmChart, chartPath, err
 }
 
-func (hClient *HelmClient) installChart(ctx context.Context, spec *hc.ChartSpec) (*release.Release, error) {
+func (hClient *HelmClient) installChart(ctx context.Context, spec *hc.ChartSpec, opts *hc.GenericHelmOptions) (*release.Release, error) {
 	c := hClient.HelmClient
 	install := action.NewInstall(c.ActionConfig)
 	mergeInstallOptions(spec, install)
+	if opts != nil {
+		install.PostRenderer = opts.PostRenderer
+	}
 
 	if install.Version == "" {
 		install.Version = ">0.0.0-0"
@@ -492,10 +495,13 @@ func (hClient *HelmClient) installChart(ctx context.Context, spec *hc.ChartSpec)
 	return rel, nil
 }
 
-func (hClient *HelmClient) upgradeChart(ctx context.Context, spec *hc.ChartSpec) (*release.Release, error) {
+func (hClient *HelmClient) upgradeChart(ctx context.Context, spec *hc.ChartSpec, opts *hc.GenericHelmOptions) (*release.Release, error) {
 	c := hClient.HelmClient
 	upgrade := action.NewUpgrade(c.ActionConfig)
 	mergeUpgradeOptions(spec, upgrade)
+	if opts != nil {
+		upgrade.PostRenderer = opts.PostRenderer
+	}
 
 	if upgrade.Version == "" {
 		upgrade.Version = ">0.0.0-0"
@@ -541,9 +547,9 @@ func (hClient *HelmClient) InstallOrUpgradeChart(ctx context.Context, spec *hc.C
 	}
 
 	if install {
-		return hClient.installChart(ctx, spec)
+		return hClient.installChart(ctx, spec, opts)
 	} else {
-		return hClient.upgradeChart(ctx, spec)
+		return hClient.upgradeChart(ctx, spec, opts)
 	}
 }
 
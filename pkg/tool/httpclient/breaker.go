@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	// BreakerClosed lets all requests pass.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects all requests until the cool down is over.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets one probe request pass, the breaker is closed if it succeeds.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// ErrCircuitOpen is returned without sending the request when the circuit breaker of the
+// target service is open.
+type ErrCircuitOpen struct {
+	Name  string
+	Until time.Time
+}
+
+func (e *ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit breaker of %s is open until %s", e.Name, e.Until.Format(time.RFC3339))
+}
+
+// CircuitBreaker is opened after a number of consecutive failures so that the callers
+// fail fast instead of waiting for an unhealthy service.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	coolDown  time.Duration
+
+	mu          sync.Mutex
+	state       BreakerState
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	lastError   string
+	lastFailure time.Time
+}
+
+// BreakerStatus is the health signal of a service seen by its callers.
+type BreakerStatus struct {
+	Name                string       `json:"name"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastFailureTime     int64        `json:"last_failure_time,omitempty"`
+}
+
+var breakers sync.Map
+
+// GetCircuitBreaker returns the breaker shared by all clients of the named service, threshold
+// and coolDown take effect when the breaker is created.
+func GetCircuitBreaker(name string, threshold int, coolDown time.Duration) *CircuitBreaker {
+	if b, ok := breakers.Load(name); ok {
+		return b.(*CircuitBreaker)
+	}
+	b, _ := breakers.LoadOrStore(name, &CircuitBreaker{
+		name:      name,
+		threshold: threshold,
+		coolDown:  coolDown,
+		state:     BreakerClosed,
+	})
+	return b.(*CircuitBreaker)
+}
+
+// ListBreakerStatus returns the status of all circuit breakers sorted by name.
+func ListBreakerStatus() []*BreakerStatus {
+	resp := make([]*BreakerStatus, 0)
+	breakers.Range(func(_, value interface{}) bool {
+		resp = append(resp, value.(*CircuitBreaker).Status())
+		return true
+	})
+	sort.Slice(resp, func(i, j int) bool { return resp[i].Name < resp[j].Name })
+	return resp
+}
+
+// Allow reports whether a request can be sent now.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		until := b.openedAt.Add(b.coolDown)
+		if time.Now().Before(until) {
+			return &ErrCircuitOpen{Name: b.name, Until: until}
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		// only one probe is in flight at a time
+		if b.probing {
+			return &ErrCircuitOpen{Name: b.name, Until: time.Now().Add(b.coolDown)}
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *CircuitBreaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	b.lastFailure = time.Now()
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.lastFailure
+	}
+}
+
+// Release ends a request whose result says nothing about the service.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if b.state == BreakerHalfOpen {
+		// let the next request probe again
+		b.state = BreakerOpen
+		b.openedAt = time.Now().Add(-b.coolDown)
+	}
+}
+
+func (b *CircuitBreaker) Status() *BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := &BreakerStatus{
+		Name:                b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if !b.lastFailure.IsZero() {
+		status.LastFailureTime = b.lastFailure.Unix()
+	}
+	return status
+}
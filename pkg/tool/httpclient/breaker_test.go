@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing circuit breaker", func() {
+	var b *CircuitBreaker
+
+	BeforeEach(func() {
+		b = &CircuitBreaker{name: "test", threshold: 2, coolDown: time.Hour, state: BreakerClosed}
+	})
+
+	It("opens after consecutive failures", func() {
+		b.Failure(errors.New("connection refused"))
+		Expect(b.Allow()).To(Succeed())
+		b.Success()
+		b.Failure(errors.New("connection refused"))
+		Expect(b.Allow()).To(Succeed())
+		b.Failure(errors.New("connection refused"))
+
+		err := b.Allow()
+		Expect(err).To(BeAssignableToTypeOf(&ErrCircuitOpen{}))
+		Expect(b.Status().State).To(Equal(BreakerOpen))
+		Expect(b.Status().LastError).To(Equal("connection refused"))
+	})
+
+	It("lets one probe pass after the cool down", func() {
+		b.Failure(nil)
+		b.Failure(nil)
+		b.openedAt = time.Now().Add(-2 * time.Hour)
+
+		Expect(b.Allow()).To(Succeed())
+		Expect(b.Status().State).To(Equal(BreakerHalfOpen))
+		Expect(b.Allow()).NotTo(Succeed())
+
+		b.Success()
+		Expect(b.Status().State).To(Equal(BreakerClosed))
+		Expect(b.Allow()).To(Succeed())
+	})
+
+	It("opens again if the probe fails", func() {
+		b.Failure(nil)
+		b.Failure(nil)
+		b.openedAt = time.Now().Add(-2 * time.Hour)
+
+		Expect(b.Allow()).To(Succeed())
+		b.Failure(nil)
+		Expect(b.Status().State).To(Equal(BreakerOpen))
+		Expect(b.Allow()).NotTo(Succeed())
+	})
+})
@@ -18,7 +18,10 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"net/http"
 	"os"
 	"time"
 
@@ -39,6 +42,8 @@ type Client struct {
 	Host        string   // Host is the fully qualified domain name of the system, or an IP Address. Port and protocol are required if necessary.
 	BaseURI     string   // BaseURI is the base uri for every request, starting with a slash, for example: /api/v1
 	IgnoreCodes sets.Int // IgnoreCodes ignores some code to be returned as an error.
+
+	breaker *CircuitBreaker
 }
 
 func Get(url string, rfs ...RequestFunc) (*resty.Response, error) {
@@ -149,7 +154,52 @@ func (c *Client) Request(method, url string, rfs ...RequestFunc) (*resty.Respons
 		rf(r)
 	}
 
-	return c.wrapError(r.Execute(method, url))
+	if timeout, ok := r.Context().Value(requestTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r.SetContext(ctx)
+	}
+
+	if c.breaker == nil {
+		return c.wrapError(r.Execute(method, url))
+	}
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	res, err := r.Execute(method, url)
+	switch {
+	case isUnavailable(res, err):
+		c.breaker.Failure(unavailableError(res, err))
+	case err != nil && errors.Is(err, context.Canceled):
+		// canceled by the caller, nothing is known about the service
+		c.breaker.Release()
+	default:
+		c.breaker.Success()
+	}
+	return c.wrapError(res, err)
+}
+
+// isUnavailable reports whether the request failed because the service can not be reached
+// or is overloaded, other errors are answered by a healthy service.
+func isUnavailable(res *resty.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	if res == nil {
+		return false
+	}
+	switch res.StatusCode() {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func unavailableError(res *resty.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return NewErrorFromRestyResponse(res)
 }
 
 func (c *Client) wrapError(res *resty.Response, err error) (*resty.Response, error) {
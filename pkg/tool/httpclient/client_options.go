@@ -18,7 +18,11 @@ package httpclient
 
 import (
 	"crypto/tls"
+	"net/http"
 	"time"
+
+	"github.com/go-resty/resty/v2"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 type ClientFunc func(*Client)
@@ -89,3 +93,41 @@ func SetTLSClientConfig(config *tls.Config) ClientFunc {
 		c.Client.SetTLSClientConfig(config)
 	}
 }
+
+// SetTimeout sets the timeout of every request, override it by SetRequestTimeout for a single call.
+func SetTimeout(timeout time.Duration) ClientFunc {
+	return func(c *Client) {
+		c.Client.SetTimeout(timeout)
+	}
+}
+
+var idempotentMethods = sets.NewString(http.MethodGet, http.MethodHead, http.MethodOptions)
+
+// SetRetry retries the GET, HEAD and OPTIONS requests which failed because the service can not
+// be reached or is overloaded. The wait time between the attempts grows exponentially from
+// waitTime to maxWaitTime with jitter.
+func SetRetry(count int, waitTime, maxWaitTime time.Duration) ClientFunc {
+	return func(c *Client) {
+		c.Client.SetRetryCount(count).
+			SetRetryWaitTime(waitTime).
+			SetRetryMaxWaitTime(maxWaitTime).
+			AddRetryCondition(func(res *resty.Response, err error) bool {
+				if res == nil || res.Request == nil || !idempotentMethods.Has(res.Request.Method) {
+					return false
+				}
+				if _, ok := err.(*ErrCircuitOpen); ok {
+					return false
+				}
+				return isUnavailable(res, err)
+			})
+	}
+}
+
+// SetCircuitBreaker makes the client fail fast after threshold consecutive requests failed
+// because the service is unavailable, a probe request is sent after coolDown. The breaker is
+// shared by all clients with the same name.
+func SetCircuitBreaker(name string, threshold int, coolDown time.Duration) ClientFunc {
+	return func(c *Client) {
+		c.breaker = GetCircuitBreaker(name, threshold, coolDown)
+	}
+}
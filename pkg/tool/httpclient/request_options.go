@@ -17,8 +17,10 @@ limitations under the License.
 package httpclient
 
 import (
+	"context"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -78,3 +80,12 @@ func ForceContentType(contentType string) RequestFunc {
 		r.ForceContentType(contentType)
 	}
 }
+
+type requestTimeoutKey struct{}
+
+// SetRequestTimeout sets the timeout of a single call, it is bounded by the timeout of the client.
+func SetRequestTimeout(timeout time.Duration) RequestFunc {
+	return func(r *resty.Request) {
+		r.SetContext(context.WithValue(r.Context(), requestTimeoutKey{}, timeout))
+	}
+}
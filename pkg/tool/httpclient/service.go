@@ -0,0 +1,39 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"time"
+
+	"github.com/koderover/zadig/pkg/config"
+)
+
+const (
+	serviceRetryWaitTime    = 200 * time.Millisecond
+	serviceRetryMaxWaitTime = 2 * time.Second
+)
+
+// SetServiceClient applies the timeout, retry and circuit breaker settings of the clients
+// calling the other zadig services, so that a slow or unavailable service fails its callers
+// fast instead of hanging them. name identifies the target service.
+func SetServiceClient(name string) ClientFunc {
+	return func(c *Client) {
+		SetTimeout(config.ClientTimeout())(c)
+		SetRetry(config.ClientRetryCount(), serviceRetryWaitTime, serviceRetryMaxWaitTime)(c)
+		SetCircuitBreaker(name, config.ClientBreakerThreshold(), config.ClientBreakerCoolDown())(c)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package i18n
+
+import (
+	_ "embed"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ZhCN is the language the messages in the source code are written in.
+	ZhCN = "zh-CN"
+	En   = "en"
+)
+
+//go:embed locales/en.yaml
+var enCatalog []byte
+
+var (
+	catalogs = map[string]*catalog{
+		En: mustLoadCatalog(enCatalog),
+	}
+
+	defaultLanguage = ZhCN
+
+	resolverLock sync.RWMutex
+	userResolver func(user string) string
+)
+
+// catalog holds the translations of the messages of one language.
+type catalog struct {
+	messages map[string]string
+	replacer *strings.Replacer
+}
+
+func mustLoadCatalog(b []byte) *catalog {
+	messages := map[string]string{}
+	if err := yaml.Unmarshal(b, &messages); err != nil {
+		panic(err)
+	}
+
+	// the longer phrases go first so that they are preferred to the phrases they contain.
+	sources := make([]string, 0, len(messages))
+	for source := range messages {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if len(sources[i]) != len(sources[j]) {
+			return len(sources[i]) > len(sources[j])
+		}
+		return sources[i] < sources[j]
+	})
+	pairs := make([]string, 0, 2*len(sources))
+	for _, source := range sources {
+		pairs = append(pairs, source, messages[source])
+	}
+
+	return &catalog{messages: messages, replacer: strings.NewReplacer(pairs...)}
+}
+
+// SetDefaultLanguage sets the language used when neither the user nor the request specifies one.
+func SetDefaultLanguage(lang string) {
+	if l := Match(lang); l != "" {
+		defaultLanguage = l
+	}
+}
+
+func DefaultLanguage() string {
+	return defaultLanguage
+}
+
+// Match returns the supported language of the given tag, or an empty string if it is not supported,
+// e.g. both "en-US" and "en" match En, "zh", "zh-Hans" and "zh_CN" match ZhCN.
+func Match(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	switch {
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return En
+	case tag == "zh" || tag == "zh-cn" || tag == "zh-sg" || strings.HasPrefix(tag, "zh-hans"):
+		return ZhCN
+	}
+	return ""
+}
+
+// ParseAcceptLanguage returns the supported language the client prefers most in the Accept-Language header,
+// or an empty string if none of them is supported.
+func ParseAcceptLanguage(header string) string {
+	lang, weight := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		l := Match(fields[0])
+		if l == "" {
+			continue
+		}
+		q := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(field, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > weight {
+			lang, weight = l, q
+		}
+	}
+	return lang
+}
+
+// RegisterUserLanguageResolver registers the function to look up the language chosen by the user.
+func RegisterUserLanguageResolver(resolver func(user string) string) {
+	resolverLock.Lock()
+	defer resolverLock.Unlock()
+
+	userResolver = resolver
+}
+
+// UserLanguage returns the language chosen by the user, or an empty string if the user didn't choose one.
+func UserLanguage(user string) string {
+	resolverLock.RLock()
+	resolver := userResolver
+	resolverLock.RUnlock()
+
+	if resolver == nil || user == "" {
+		return ""
+	}
+	return Match(resolver(user))
+}
+
+// UserLanguageOrDefault is the same as UserLanguage but falls back to the default language.
+func UserLanguageOrDefault(user string) string {
+	if lang := UserLanguage(user); lang != "" {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// T translates the message into the given language, the message is returned as is if it has no translation.
+func T(lang, msg string) string {
+	c, ok := catalogs[Match(lang)]
+	if !ok {
+		return msg
+	}
+	if translated, ok := c.messages[msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Localize translates all the known phrases in the text into the given language,
+// it is used for the templates which are composed of several phrases.
+func Localize(lang, text string) string {
+	c, ok := catalogs[Match(lang)]
+	if !ok {
+		return text
+	}
+	return c.replacer.Replace(text)
+}
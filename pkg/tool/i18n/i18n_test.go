@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	assert.Equal(t, En, Match("en-US"))
+	assert.Equal(t, En, Match("EN"))
+	assert.Equal(t, ZhCN, Match("zh"))
+	assert.Equal(t, ZhCN, Match("zh_CN"))
+	assert.Equal(t, ZhCN, Match("zh-Hans-CN"))
+	assert.Equal(t, "", Match("fr"))
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, En, ParseAcceptLanguage("fr-FR, en-US;q=0.8, zh-CN;q=0.5"))
+	assert.Equal(t, ZhCN, ParseAcceptLanguage("zh-CN,zh;q=0.9,en;q=0.8"))
+	assert.Equal(t, "", ParseAcceptLanguage("fr-FR"))
+	assert.Equal(t, "", ParseAcceptLanguage(""))
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Failed to create the workflow", T(En, "创建工作流失败"))
+	assert.Equal(t, "创建工作流失败", T(ZhCN, "创建工作流失败"))
+	assert.Equal(t, "no translation", T(En, "no translation"))
+}
+
+func TestLocalize(t *testing.T) {
+	assert.Equal(t, "**Executed by**: admin, Workflow succeeded", Localize(En, "**执行用户**：admin, 工作流执行成功"))
+	assert.Equal(t, "**执行用户**：admin", Localize(ZhCN, "**执行用户**：admin"))
+}
+
+func TestUserLanguage(t *testing.T) {
+	RegisterUserLanguageResolver(func(user string) string {
+		if user == "alice" {
+			return "en-US"
+		}
+		return ""
+	})
+	defer RegisterUserLanguageResolver(nil)
+
+	assert.Equal(t, En, UserLanguage("alice"))
+	assert.Equal(t, "", UserLanguage("bob"))
+	assert.Equal(t, ZhCN, UserLanguageOrDefault("bob"))
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func GetCronJob(ns, name string, cl client.Client) (*batchv1beta1.CronJob, bool, error) {
+	cj := &batchv1beta1.CronJob{}
+	found, err := GetResourceInCache(ns, name, cj, cl)
+	if err != nil || !found {
+		cj = nil
+	}
+
+	return cj, found, err
+}
+
+func ListCronJobs(ns string, selector labels.Selector, cl client.Client) ([]*batchv1beta1.CronJob, error) {
+	cjs := &batchv1beta1.CronJobList{}
+	err := ListResourceInCache(ns, selector, nil, cjs, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*batchv1beta1.CronJob
+	for i := range cjs.Items {
+		res = append(res, &cjs.Items[i])
+	}
+	return res, err
+}
+
+func GetCronJobYaml(ns string, name string, cl client.Client) ([]byte, bool, error) {
+	gvk := schema.GroupVersionKind{
+		Group:   "batch",
+		Kind:    "CronJob",
+		Version: "v1beta1",
+	}
+	return GetResourceYamlInCache(ns, name, gvk, cl)
+}
+
+func GetCronJobYamlFormat(ns string, name string, cl client.Client) ([]byte, bool, error) {
+	gvk := schema.GroupVersionKind{
+		Group:   "batch",
+		Kind:    "CronJob",
+		Version: "v1beta1",
+	}
+	return GetResourceYamlInCacheFormat(ns, name, gvk, cl)
+}
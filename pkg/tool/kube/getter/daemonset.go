@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func GetDaemonSet(ns, name string, cl client.Client) (*appsv1.DaemonSet, bool, error) {
+	ds := &appsv1.DaemonSet{}
+	found, err := GetResourceInCache(ns, name, ds, cl)
+	if err != nil || !found {
+		ds = nil
+	}
+
+	return ds, found, err
+}
+
+func ListDaemonSets(ns string, selector labels.Selector, cl client.Client) ([]*appsv1.DaemonSet, error) {
+	ds := &appsv1.DaemonSetList{}
+	err := ListResourceInCache(ns, selector, nil, ds, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*appsv1.DaemonSet
+	for i := range ds.Items {
+		res = append(res, &ds.Items[i])
+	}
+	return res, err
+}
+
+func ListDaemonSetsWithCache(selector labels.Selector, lister informers.SharedInformerFactory) ([]*appsv1.DaemonSet, error) {
+	if selector == nil {
+		selector = labels.NewSelector()
+	}
+	return lister.Apps().V1().DaemonSets().Lister().List(selector)
+}
+
+func GetDaemonSetYaml(ns string, name string, cl client.Client) ([]byte, bool, error) {
+	gvk := schema.GroupVersionKind{
+		Group:   "apps",
+		Kind:    "DaemonSet",
+		Version: "v1",
+	}
+	return GetResourceYamlInCache(ns, name, gvk, cl)
+}
+
+func GetDaemonSetYamlFormat(ns string, name string, cl client.Client) ([]byte, bool, error) {
+	gvk := schema.GroupVersionKind{
+		Group:   "apps",
+		Kind:    "DaemonSet",
+		Version: "v1",
+	}
+	return GetResourceYamlInCacheFormat(ns, name, gvk, cl)
+}
@@ -19,6 +19,8 @@ package getter
 import (
 	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -45,3 +47,28 @@ func GetJob(ns, name string, cl client.Client) (*batchv1.Job, bool, error) {
 
 	return g, found, err
 }
+
+func ListJobsWithCache(selector labels.Selector, lister informers.SharedInformerFactory) ([]*batchv1.Job, error) {
+	if selector == nil {
+		selector = labels.NewSelector()
+	}
+	return lister.Batch().V1().Jobs().Lister().List(selector)
+}
+
+func GetJobYaml(ns string, name string, cl client.Client) ([]byte, bool, error) {
+	gvk := schema.GroupVersionKind{
+		Group:   "batch",
+		Kind:    "Job",
+		Version: "v1",
+	}
+	return GetResourceYamlInCache(ns, name, gvk, cl)
+}
+
+func GetJobYamlFormat(ns string, name string, cl client.Client) ([]byte, bool, error) {
+	gvk := schema.GroupVersionKind{
+		Group:   "batch",
+		Kind:    "Job",
+		Version: "v1",
+	}
+	return GetResourceYamlInCacheFormat(ns, name, gvk, cl)
+}
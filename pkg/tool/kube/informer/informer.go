@@ -19,11 +19,15 @@ package informer
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/koderover/zadig/pkg/setting"
 )
@@ -31,6 +35,12 @@ import (
 var InformersMap sync.Map
 var StopChanMap sync.Map
 
+// versionMap keeps the server version of the cluster found when the informer is created.
+var versionMap sync.Map
+
+// generationMap keeps the change counters of the namespaces.
+var generationMap sync.Map
+
 // NewInformer initialize and start an informer for specific namespace in given cluster.
 // Currently the informer will NOT stop unless the service is down
 // If you want to watch a new resource, remember to register here
@@ -54,8 +64,17 @@ func NewInformer(clusterID, namespace string, cls *kubernetes.Clientset) (inform
 	// register the resources to be watched
 	informerFactory.Apps().V1().Deployments().Lister()
 	informerFactory.Apps().V1().StatefulSets().Lister()
+	informerFactory.Apps().V1().DaemonSets().Lister()
+	informerFactory.Batch().V1().Jobs().Lister()
 	informerFactory.Core().V1().Services().Lister()
 	informerFactory.Core().V1().Pods().Lister()
+	// the workloads and pods decide the status of the services, count their changes
+	handler := changeHandler(key)
+	informerFactory.Apps().V1().Deployments().Informer().AddEventHandler(handler)
+	informerFactory.Apps().V1().StatefulSets().Informer().AddEventHandler(handler)
+	informerFactory.Apps().V1().DaemonSets().Informer().AddEventHandler(handler)
+	informerFactory.Batch().V1().Jobs().Informer().AddEventHandler(handler)
+	informerFactory.Core().V1().Pods().Informer().AddEventHandler(handler)
 	versionInfo, err := cls.Discovery().ServerVersion()
 	if err != nil {
 		return nil, err
@@ -83,9 +102,60 @@ func NewInformer(clusterID, namespace string, cls *kubernetes.Clientset) (inform
 	}
 	InformersMap.Store(key, informerFactory)
 	StopChanMap.Store(key, stopchan)
+	versionMap.Store(key, versionInfo)
 	return informerFactory, nil
 }
 
+// Lookup returns the started informer of the namespace and the server version of the cluster,
+// unlike NewInformer no request is sent to the cluster.
+func Lookup(clusterID, namespace string) (informers.SharedInformerFactory, *version.Info, bool) {
+	if clusterID == "" {
+		clusterID = setting.LocalClusterID
+	}
+	key := generateInformerKey(clusterID, namespace)
+	informer, ok := InformersMap.Load(key)
+	if !ok {
+		return nil, nil, false
+	}
+	versionInfo, ok := versionMap.Load(key)
+	if !ok {
+		return nil, nil, false
+	}
+	return informer.(informers.SharedInformerFactory), versionInfo.(*version.Info), true
+}
+
+// Generation returns a counter of the changes of the deployments, statefulsets and pods in the
+// namespace, results computed from the informer stay valid until the counter changes.
+func Generation(clusterID, namespace string) uint64 {
+	if clusterID == "" {
+		clusterID = setting.LocalClusterID
+	}
+	return atomic.LoadUint64(generationCounter(generateInformerKey(clusterID, namespace)))
+}
+
+func generationCounter(key string) *uint64 {
+	counter, _ := generationMap.LoadOrStore(key, new(uint64))
+	return counter.(*uint64)
+}
+
+func changeHandler(key string) cache.ResourceEventHandler {
+	counter := generationCounter(key)
+	bump := func() { atomic.AddUint64(counter, 1) }
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) { bump() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// the periodic resyncs deliver the same objects, they are not changes
+			o, ok1 := oldObj.(metav1.Object)
+			n, ok2 := newObj.(metav1.Object)
+			if ok1 && ok2 && o.GetResourceVersion() == n.GetResourceVersion() {
+				return
+			}
+			bump()
+		},
+		DeleteFunc: func(interface{}) { bump() },
+	}
+}
+
 func DeleteInformer(clusterID, namespace string) {
 	key := generateInformerKey(clusterID, namespace)
 	// if informer exists
@@ -97,6 +167,8 @@ func DeleteInformer(clusterID, namespace string) {
 		}
 	}
 	InformersMap.Delete(key)
+	versionMap.Delete(key)
+	atomic.AddUint64(generationCounter(key), 1)
 }
 
 func generateInformerKey(clusterID, namespace string) string {
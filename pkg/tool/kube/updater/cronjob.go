@@ -18,7 +18,10 @@ package updater
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -46,3 +49,40 @@ func DeleteCronJobs(namespace string, selector labels.Selector, clientset *kuber
 func CreateOrPatchCronJob(cj *batchv1beta1.CronJob, cl client.Client) error {
 	return createOrPatchObject(cj, cl)
 }
+
+func PatchCronJob(ns, name string, patchBytes []byte, cl client.Client) error {
+	return patchObject(&batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      name,
+		},
+	}, patchBytes, cl)
+}
+
+func UpdateCronJobImage(ns, name, container, image string, cl client.Client) error {
+	patchBytes := []byte(fmt.Sprintf(`{"spec":{"jobTemplate":{"spec":{"template":{"spec":{"containers":[{"name":"%s","image":"%s"}]}}}}}}`, container, image))
+
+	return PatchCronJob(ns, name, patchBytes, cl)
+}
+
+// TriggerCronJob creates a job from the job template of the cronJob, it is the same as
+// `kubectl create job --from=cronjob/<name>`.
+func TriggerCronJob(cj *batchv1beta1.CronJob, cl client.Client) error {
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for k, v := range cj.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-manual-%d", cj.Name, time.Now().Unix()),
+			Namespace:   cj.Namespace,
+			Labels:      cj.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cj, batchv1beta1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cj.Spec.JobTemplate.Spec,
+	}
+	return createObject(job, cl)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package updater
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/tool/kube/util"
+)
+
+func PatchDaemonSet(ns, name string, patchBytes []byte, cl client.Client) error {
+	return patchObject(&appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      name,
+		},
+	}, patchBytes, cl)
+}
+
+func RestartDaemonSet(ns, name string, cl client.Client) error {
+	now := time.Now().Format(time.RFC3339Nano)
+	payload := bytes.NewBufferString("")
+	_ = restartPatchTemplate.Execute(payload, struct {
+		Time string
+	}{now})
+
+	if err := PatchDaemonSet(ns, name, payload.Bytes(), cl); err != nil {
+		return fmt.Errorf("failed to restart %s/ds/%s: %v", ns, name, err)
+	}
+
+	return nil
+}
+
+func DeleteDaemonSets(namespace string, selector labels.Selector, clientset *kubernetes.Clientset) error {
+	deletePolicy := metav1.DeletePropagationForeground
+	err := clientset.AppsV1().DaemonSets(namespace).DeleteCollection(
+		context.TODO(),
+		metav1.DeleteOptions{
+			PropagationPolicy: &deletePolicy,
+		},
+		metav1.ListOptions{
+			LabelSelector: selector.String(),
+		},
+	)
+
+	return util.IgnoreNotFoundError(err)
+}
+
+func UpdateDaemonSetImage(ns, name, container, image string, cl client.Client) error {
+	patchBytes := []byte(fmt.Sprintf(`{"spec":{"template":{"spec":{"containers":[{"name":"%s","image":"%s"}]}}}}`, container, image))
+
+	return PatchDaemonSet(ns, name, patchBytes, cl)
+}
+
+func CreateOrPatchDaemonSet(ds *appsv1.DaemonSet, cl client.Client) error {
+	return createOrPatchObject(ds, cl)
+}
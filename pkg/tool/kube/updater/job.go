@@ -48,6 +48,11 @@ func CreateJob(job *batchv1.Job, cl client.Client) error {
 	return createObject(job, cl)
 }
 
+// UpdateJob updates the job with its resourceVersion, a conflict error is returned if the job is changed by others.
+func UpdateJob(job *batchv1.Job, cl client.Client) error {
+	return updateObject(job, cl)
+}
+
 func DeleteJob(ns, name string, cl client.Client) error {
 	return deleteObjectWithDefaultOptions(&batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -74,3 +79,32 @@ func DeleteJobsAndWait(ns string, selector labels.Selector, cl client.Client) er
 	}
 	return deleteObjectsAndWait(ns, selector, &batchv1.Job{}, gvk, cl)
 }
+
+// RecreateJob deletes the job and creates it again since the pod template of a job is immutable,
+// the fields generated by the job controller are removed before it is created.
+func RecreateJob(job *batchv1.Job, cl client.Client) error {
+	newJob := job.DeepCopy()
+	newJob.ObjectMeta = metav1.ObjectMeta{
+		Name:        job.Name,
+		Namespace:   job.Namespace,
+		Labels:      job.Labels,
+		Annotations: job.Annotations,
+	}
+	newJob.Status = batchv1.JobStatus{}
+	if job.Spec.ManualSelector == nil || !*job.Spec.ManualSelector {
+		newJob.Spec.Selector = nil
+		podLabels := make(map[string]string)
+		for k, v := range job.Spec.Template.Labels {
+			if k == "controller-uid" || k == "job-name" {
+				continue
+			}
+			podLabels[k] = v
+		}
+		newJob.Spec.Template.Labels = podLabels
+	}
+
+	if err := DeleteJobAndWait(job.Namespace, job.Name, cl); err != nil {
+		return err
+	}
+	return CreateJob(newJob, cl)
+}
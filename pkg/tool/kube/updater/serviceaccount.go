@@ -46,3 +46,7 @@ func DeleteServiceAccounts(namespace string, selector labels.Selector, clientset
 func CreateServiceAccount(sa *corev1.ServiceAccount, cl client.Client) error {
 	return createObject(sa, cl)
 }
+
+func UpdateOrCreateServiceAccount(sa *corev1.ServiceAccount, cl client.Client) error {
+	return updateOrCreateObject(sa, cl)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logannotation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	// MaxAnnotations is the most annotations found in a log, the first errors are usually the causes.
+	MaxAnnotations = 50
+
+	maxMessageLength = 256
+)
+
+// Annotation is an error or a warning in the log of a job which refers to a location in the source.
+type Annotation struct {
+	// Path is the file as it is in the log, it may be absolute or relative to the working directory.
+	Path     string `bson:"path"             json:"path"`
+	Line     int    `bson:"line"             json:"line"`
+	Column   int    `bson:"column,omitempty" json:"column,omitempty"`
+	Severity string `bson:"severity"         json:"severity"`
+	Message  string `bson:"message"          json:"message"`
+	// Repo and File are the repository the path is resolved to and the path in it, URL links to the file
+	// at the built commit in the code host. They are empty if the path can not be resolved.
+	Repo string `bson:"repo,omitempty" json:"repo,omitempty"`
+	File string `bson:"file,omitempty" json:"file,omitempty"`
+	URL  string `bson:"url,omitempty"  json:"url,omitempty"`
+}
+
+// Markdown renders the annotation in a line of markdown, the location links to the source if it is resolved.
+func (a *Annotation) Markdown() string {
+	location := fmt.Sprintf("%s:%d", a.Path, a.Line)
+	if a.URL != "" {
+		location = fmt.Sprintf("[%s](%s)", location, a.URL)
+	} else {
+		location = "`" + location + "`"
+	}
+	return fmt.Sprintf("%s %s: %s", location, a.Severity, a.Message)
+}
+
+type pattern struct {
+	regex *regexp.Regexp
+	// severity is used if the line does not tell it.
+	severity string
+}
+
+// the groups of the patterns are path, line, column, severity and message, the column and the severity are optional.
+var patterns = []*pattern{
+	// gcc, clang, go, rustc short format, javac, eslint unix format, etc.: main.go:12:5: undefined: foo
+	{regex: regexp.MustCompile(`^(?:\S+\s+)?([^\s:()"'\[\]]+\.[A-Za-z0-9]+):(\d+):(?:(\d+):)?\s*(?:(fatal error|error|warning)(?:\[\w+\])?:\s*)?(.+)$`), severity: SeverityError},
+	// msbuild and tsc: src/app.ts(12,5): error TS2322: Type 'string' is not assignable
+	{regex: regexp.MustCompile(`^([^\s:()"'\[\]]+\.[A-Za-z0-9]+)\((\d+),(\d+)\):\s*(error|warning)\s*\w*:\s*(.+)$`), severity: SeverityError},
+	// maven: [ERROR] /workspace/app/src/main/java/App.java:[12,5] cannot find symbol
+	{regex: regexp.MustCompile(`^\[(ERROR|WARNING)\]\s+([^\s:()"'\[\]]+\.[A-Za-z0-9]+):\[(\d+),(\d+)\]\s*(.+)$`), severity: SeverityError},
+	// python: File "/workspace/app/main.py", line 12, in main
+	{regex: regexp.MustCompile(`^File "([^"]+\.py)", line (\d+)()()(?:, (in .+))?$`), severity: SeverityError},
+}
+
+var ansiColor = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// Parse finds the annotations in the log in their order, the duplicated ones are dropped.
+func Parse(log io.Reader) ([]*Annotation, error) {
+	var res []*Annotation
+	seen := map[string]bool{}
+	reader := bufio.NewReader(log)
+	for len(res) < MaxAnnotations {
+		line, err := reader.ReadString('\n')
+		if annotation := parseLine(line); annotation != nil {
+			key := fmt.Sprintf("%s:%d:%s", annotation.Path, annotation.Line, annotation.Message)
+			if !seen[key] {
+				seen[key] = true
+				res = append(res, annotation)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				return res, err
+			}
+			break
+		}
+	}
+	return res, nil
+}
+
+func parseLine(line string) *Annotation {
+	line = strings.TrimSpace(ansiColor.ReplaceAllString(line, ""))
+	if line == "" {
+		return nil
+	}
+	for i, p := range patterns {
+		groups := p.regex.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		// maven puts the severity before the location.
+		if i == 2 {
+			groups = []string{groups[0], groups[2], groups[3], groups[4], groups[1], groups[5]}
+		}
+		lineNumber, err := strconv.Atoi(groups[2])
+		if err != nil || lineNumber == 0 || isURL(line, groups[1]) {
+			continue
+		}
+		column, _ := strconv.Atoi(groups[3])
+		severity := p.severity
+		if strings.HasPrefix(strings.ToLower(groups[4]), SeverityWarning) {
+			severity = SeverityWarning
+		}
+		message := groups[5]
+		if message == "" {
+			message = "traceback"
+		}
+		return &Annotation{
+			Path:     groups[1],
+			Line:     lineNumber,
+			Column:   column,
+			Severity: severity,
+			Message:  truncate(message),
+		}
+	}
+	return nil
+}
+
+// isURL tells whether the path is a part of an url, e.g. http://example.com:8080/index.html.
+func isURL(line, path string) bool {
+	i := strings.Index(line, path)
+	return i >= 3 && line[i-3:i] == "://"
+}
+
+func truncate(s string) string {
+	if len(s) > maxMessageLength {
+		return s[:maxMessageLength] + "..."
+	}
+	return s
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logannotation
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+	It("finds the locations in the output of the compilers and the tests", func() {
+		log := strings.Join([]string{
+			"Step 3: go build ./...",
+			"# github.com/example/app",
+			"./main.go:12:5: undefined: foo",
+			"./main.go:12:5: undefined: foo",
+			"\x1b[31msrc/app.c:7:1: warning: implicit declaration of function 'bar'\x1b[0m",
+			"src/app.ts(3,9): error TS2322: Type 'string' is not assignable to type 'number'.",
+			"[ERROR] /workspace/app/src/main/java/App.java:[20,8] cannot find symbol",
+			`  File "/workspace/app/main.py", line 42, in main`,
+			"    app_test.go:33: expected 1, got 2",
+			"Get http://example.com:8080/index.html: dial tcp: i/o timeout",
+		}, "\n")
+
+		annotations, err := Parse(strings.NewReader(log))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(annotations).To(Equal([]*Annotation{
+			{Path: "./main.go", Line: 12, Column: 5, Severity: SeverityError, Message: "undefined: foo"},
+			{Path: "src/app.c", Line: 7, Column: 1, Severity: SeverityWarning, Message: "implicit declaration of function 'bar'"},
+			{Path: "src/app.ts", Line: 3, Column: 9, Severity: SeverityError, Message: "Type 'string' is not assignable to type 'number'."},
+			{Path: "/workspace/app/src/main/java/App.java", Line: 20, Column: 8, Severity: SeverityError, Message: "cannot find symbol"},
+			{Path: "/workspace/app/main.py", Line: 42, Severity: SeverityError, Message: "in main"},
+			{Path: "app_test.go", Line: 33, Severity: SeverityError, Message: "expected 1, got 2"},
+		}))
+	})
+
+	It("keeps the first annotations of a long log", func() {
+		var lines []string
+		for i := 1; i <= MaxAnnotations+10; i++ {
+			lines = append(lines, fmt.Sprintf("main.go:%d:1: error %d", i, i))
+		}
+		annotations, err := Parse(strings.NewReader(strings.Join(lines, "\n")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(annotations).To(HaveLen(MaxAnnotations))
+		Expect(annotations[0].Line).To(Equal(1))
+	})
+
+	It("renders the annotations in markdown", func() {
+		Expect((&Annotation{Path: "main.go", Line: 3, Severity: SeverityError, Message: "boom"}).Markdown()).
+			To(Equal("`main.go:3` error: boom"))
+		Expect((&Annotation{Path: "main.go", Line: 3, Severity: SeverityError, Message: "boom", URL: "https://github.com/a/b/blob/c/main.go#L3"}).Markdown()).
+			To(Equal("[main.go:3](https://github.com/a/b/blob/c/main.go#L3) error: boom"))
+	})
+})
@@ -0,0 +1,332 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logstream stores a log in the object storage as gzip compressed chunks, the chunks
+// are uploaded while the log is being written so that the log can be read by range before it
+// is complete, and nothing but a single chunk is kept in memory.
+package logstream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultChunkSize is the max size of the uncompressed content of a chunk.
+const DefaultChunkSize = 1 << 20
+
+// Store is the object storage the chunks are saved to.
+type Store interface {
+	Put(key string, body []byte) error
+	Get(key string) (io.ReadCloser, error)
+	// List returns the keys of all the objects with the given prefix.
+	List(prefix string) ([]string, error)
+}
+
+type chunk struct {
+	key    string
+	offset int64
+	size   int64
+}
+
+// the offset and the uncompressed size are kept in the key so that a log can be located by listing.
+func chunkKey(prefix string, offset, size int64) string {
+	return fmt.Sprintf("%s/%016d-%d.gz", prefix, offset, size)
+}
+
+func parseChunkKey(key string) (*chunk, bool) {
+	name := key[strings.LastIndex(key, "/")+1:]
+	if !strings.HasSuffix(name, ".gz") {
+		return nil, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(name, ".gz"), "-", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
+	offset, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	size, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || size <= 0 {
+		return nil, false
+	}
+	return &chunk{key: key, offset: offset, size: size}, true
+}
+
+// Writer uploads the written content as chunks under the prefix, a chunk is cut at the last
+// line break in it if there is one. It is safe to call Flush from another goroutine.
+type Writer struct {
+	store     Store
+	prefix    string
+	chunkSize int
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	offset int64
+	err    error
+}
+
+func NewWriter(store Store, prefix string, chunkSize int) *Writer {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &Writer{store: store, prefix: strings.TrimRight(prefix, "/"), chunkSize: chunkSize}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf.Write(p)
+	for w.buf.Len() >= w.chunkSize {
+		data := w.buf.Bytes()[:w.chunkSize]
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		}
+		if err := w.upload(data); err != nil {
+			return 0, err
+		}
+		w.buf.Next(len(data))
+	}
+	return len(p), nil
+}
+
+// Flush uploads the buffered content as a chunk, it is called periodically by the producer so
+// that the latest lines can be read.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	if err := w.upload(w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.buf.Reset()
+	return nil
+}
+
+func (w *Writer) Close() error {
+	return w.Flush()
+}
+
+// Size returns the size of the content uploaded.
+func (w *Writer) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.offset
+}
+
+func (w *Writer) upload(data []byte) error {
+	if err := PutChunk(w.store, w.prefix, w.offset, data); err != nil {
+		w.err = err
+		return err
+	}
+	w.offset += int64(len(data))
+	return nil
+}
+
+// PutChunk uploads the data as the chunk at the offset, it is used when the chunks are produced by a
+// remote writer which tracks the offset itself.
+func PutChunk(store Store, prefix string, offset int64, data []byte) error {
+	compressed := new(bytes.Buffer)
+	gz := gzip.NewWriter(compressed)
+	if _, err := gz.Write(data); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := store.Put(chunkKey(strings.TrimRight(prefix, "/"), offset, int64(len(data))), compressed.Bytes()); err != nil {
+		return fmt.Errorf("failed to upload log chunk at %d: %s", offset, err)
+	}
+	return nil
+}
+
+// Log is a log saved by the Writer, it implements io.ReaderAt.
+type Log struct {
+	store  Store
+	chunks []*chunk
+	size   int64
+
+	// the last decompressed chunk, the reads are usually sequential
+	cached     *chunk
+	cachedData []byte
+}
+
+// Open lists the chunks under the prefix, the log ends at the first missing chunk.
+func Open(store Store, prefix string) (*Log, error) {
+	keys, err := store.List(strings.TrimRight(prefix, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]*chunk, 0, len(keys))
+	for _, key := range keys {
+		if c, ok := parseChunkKey(key); ok {
+			chunks = append(chunks, c)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].offset < chunks[j].offset })
+
+	l := &Log{store: store}
+	for _, c := range chunks {
+		if c.offset != l.size {
+			break
+		}
+		l.chunks = append(l.chunks, c)
+		l.size += c.size
+	}
+	return l, nil
+}
+
+func (l *Log) Size() int64 {
+	return l.size
+}
+
+// Keys returns the keys of the chunks of the log.
+func (l *Log) Keys() []string {
+	keys := make([]string, 0, len(l.chunks))
+	for _, c := range l.chunks {
+		keys = append(keys, c.key)
+	}
+	return keys
+}
+
+func (l *Log) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	n := 0
+	i := sort.Search(len(l.chunks), func(i int) bool { return l.chunks[i].offset+l.chunks[i].size > off })
+	for ; i < len(l.chunks) && n < len(p); i++ {
+		data, err := l.read(l.chunks[i])
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[off+int64(n)-l.chunks[i].offset:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (l *Log) read(c *chunk) ([]byte, error) {
+	if l.cached == c {
+		return l.cachedData, nil
+	}
+	body, err := l.store.Get(c.key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != c.size {
+		return nil, fmt.Errorf("chunk %s is corrupted, expected %d bytes, got %d", c.key, c.size, len(data))
+	}
+	l.cached, l.cachedData = c, data
+	return data, nil
+}
+
+// ReadRange reads at most limit bytes from the offset of a log of the given size.
+func ReadRange(r io.ReaderAt, size, offset, limit int64) ([]byte, error) {
+	if offset >= size || limit <= 0 {
+		return []byte{}, nil
+	}
+	if offset+limit > size {
+		limit = size - offset
+	}
+	buf := make([]byte, limit)
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+const tailBlockSize = 32 << 10
+
+// Tail returns the last lines of a log of the given size and the offset they start at, at
+// most maxBytes are read.
+func Tail(r io.ReaderAt, size int64, lines int, maxBytes int64) ([]byte, int64, error) {
+	if size == 0 || lines <= 0 {
+		return []byte{}, size, nil
+	}
+	start := size
+	var data []byte
+	for start > 0 && size-start < maxBytes {
+		blockSize := int64(tailBlockSize)
+		if blockSize > start {
+			blockSize = start
+		}
+		if size-start+blockSize > maxBytes {
+			blockSize = maxBytes - (size - start)
+		}
+		block := make([]byte, blockSize)
+		if _, err := r.ReadAt(block, start-blockSize); err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		start -= blockSize
+		data = append(block, data...)
+
+		// the trailing line break does not start a new line
+		if bytes.Count(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) >= lines {
+			break
+		}
+	}
+
+	content := bytes.TrimSuffix(data, []byte("\n"))
+	for i := 0; i < lines; i++ {
+		idx := bytes.LastIndexByte(content, '\n')
+		if idx < 0 {
+			content = nil
+			break
+		}
+		content = content[:idx]
+	}
+	if content == nil {
+		// fewer lines than wanted, the first line may be truncated by maxBytes
+		if start > 0 {
+			if idx := bytes.IndexByte(data, '\n'); idx >= 0 && idx+1 < len(data) {
+				return data[idx+1:], start + int64(idx) + 1, nil
+			}
+		}
+		return data, start, nil
+	}
+	skip := int64(len(content) + 1)
+	return data[skip:], start + skip, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logstream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type memoryStore map[string][]byte
+
+func (s memoryStore) Put(key string, body []byte) error {
+	s[key] = append([]byte{}, body...)
+	return nil
+}
+
+func (s memoryStore) Get(key string) (io.ReadCloser, error) {
+	body, ok := s[key]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", key)
+	}
+	return io.NopCloser(bytes.NewReader(body)), nil
+}
+
+func (s memoryStore) List(prefix string) ([]string, error) {
+	keys := []string{}
+	for key := range s {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func testLines(n int) string {
+	b := &strings.Builder{}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(b, "line %03d\n", i)
+	}
+	return b.String()
+}
+
+var _ = Describe("Testing log stream", func() {
+	var store memoryStore
+	content := testLines(100)
+
+	BeforeEach(func() {
+		store = memoryStore{}
+		w := NewWriter(store, "job/log", 64)
+		// write in small pieces to cross the chunk boundaries
+		for i := 0; i < len(content); i += 7 {
+			end := i + 7
+			if end > len(content) {
+				end = len(content)
+			}
+			_, err := w.Write([]byte(content[i:end]))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(w.Close()).To(Succeed())
+		Expect(w.Size()).To(Equal(int64(len(content))))
+	})
+
+	It("cuts the chunks at line breaks", func() {
+		l, err := Open(store, "job/log")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(l.Keys())).To(BeNumerically(">", 1))
+		for _, key := range l.Keys() {
+			c, ok := parseChunkKey(key)
+			Expect(ok).To(BeTrue())
+			data, err := l.read(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data[len(data)-1]).To(Equal(byte('\n')))
+		}
+	})
+
+	It("reads the log by range", func() {
+		l, err := Open(store, "job/log")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Size()).To(Equal(int64(len(content))))
+
+		data, err := ReadRange(l, l.Size(), 0, l.Size())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+
+		data, err = ReadRange(l, l.Size(), 50, 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content[50:150]))
+
+		data, err = ReadRange(l, l.Size(), l.Size()-5, 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content[len(content)-5:]))
+
+		data, err = ReadRange(l, l.Size(), l.Size(), 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeEmpty())
+	})
+
+	It("stops at the first missing chunk", func() {
+		l, err := Open(store, "job/log")
+		Expect(err).NotTo(HaveOccurred())
+		keys := l.Keys()
+		delete(store, keys[1])
+
+		l, err = Open(store, "job/log")
+		Expect(err).NotTo(HaveOccurred())
+		c, _ := parseChunkKey(keys[0])
+		Expect(l.Size()).To(Equal(c.size))
+	})
+
+	It("tails the log", func() {
+		l, err := Open(store, "job/log")
+		Expect(err).NotTo(HaveOccurred())
+
+		data, offset, err := Tail(l, l.Size(), 3, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("line 097\nline 098\nline 099\n"))
+		Expect(offset).To(Equal(int64(len(content) - len(data))))
+
+		data, offset, err = Tail(l, l.Size(), 1000, 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+		Expect(offset).To(Equal(int64(0)))
+
+		// the partial line is dropped when the limit is hit
+		data, _, err = Tail(l, l.Size(), 1000, 25)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("line 098\nline 099\n"))
+	})
+})
@@ -30,6 +30,10 @@ import (
 
 const (
 	manifestPath = ".manifest"
+
+	// DecisionCacheNamespace is the cache namespace of the policy decisions, it is invalidated
+	// when the bundle changes.
+	DecisionCacheNamespace = "policy_decision"
 )
 
 type Manifest struct {
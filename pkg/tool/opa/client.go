@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// Client talks to the REST API of the opa server. Policies uploaded by the client must be out of the
+// roots of the bundles, otherwise they are rejected by opa.
+type Client struct {
+	*httpclient.Client
+}
+
+func NewClient(host string) *Client {
+	return &Client{
+		Client: httpclient.New(httpclient.SetHostURL(host)),
+	}
+}
+
+// PutPolicy creates or updates the policy module, the compile errors of the module are returned.
+func (c *Client) PutPolicy(id string, module string) error {
+	_, err := c.Put(fmt.Sprintf("v1/policies/%s", id), httpclient.SetHeader("Content-Type", "text/plain"), httpclient.SetBody(module))
+	return err
+}
+
+func (c *Client) DeletePolicy(id string) error {
+	_, err := c.Delete(fmt.Sprintf("v1/policies/%s", id))
+	if httpclient.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// EvaluateData evaluates the document of the dot separated path with the input, e.g. zadig.manifest.violation.
+func (c *Client) EvaluateData(path string, input interface{}, result interface{}) error {
+	req := struct {
+		Input interface{} `json:"input"`
+	}{
+		Input: input,
+	}
+	resp := struct {
+		Result interface{} `json:"result"`
+	}{
+		Result: result,
+	}
+	_, err := c.Post(fmt.Sprintf("v1/data/%s", strings.ReplaceAll(path, ".", "/")), httpclient.SetBody(req), httpclient.SetResult(&resp))
+	return err
+}
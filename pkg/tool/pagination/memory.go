@@ -0,0 +1,186 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pagination
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Apply paginates the items in memory, it is used by the APIs which assemble the list from multiple
+// sources or filter the list by permissions. The items are returned as they are if the query is empty.
+func Apply[T any](items []T, q *Query, s *Schema) ([]T, *Page, error) {
+	if q.IsEmpty() {
+		return items, &Page{Total: int64(len(items))}, nil
+	}
+	sortFields, err := q.validate(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matched := make([]T, 0, len(items))
+	for _, item := range items {
+		if matchFilters(item, q, s) {
+			matched = append(matched, item)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return compareItem(matched[i], itemValues(matched[j], sortFields), sortFields) < 0
+	})
+
+	page := &Page{Total: int64(len(matched))}
+	start := 0
+	if q.Cursor != "" {
+		last, err := decodeCursor(q.Cursor, sortFields)
+		if err != nil {
+			return nil, nil, err
+		}
+		start = sort.Search(len(matched), func(i int) bool {
+			return compareItem(matched[i], last, sortFields) > 0
+		})
+	}
+	matched = matched[start:]
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+		if page.NextCursor, err = encodeCursor(sortFields, itemValues(matched[len(matched)-1], sortFields)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return matched, page, nil
+}
+
+func matchFilters(item interface{}, q *Query, s *Schema) bool {
+	for field, values := range q.Filters {
+		value, _ := fieldValue(item, field)
+		if !contains(values, fmt.Sprint(value)) {
+			return false
+		}
+	}
+	if q.Search == "" {
+		return true
+	}
+	search := strings.ToLower(q.Search)
+	for _, field := range s.Searchable {
+		if value, ok := fieldValue(item, field); ok && strings.Contains(strings.ToLower(fmt.Sprint(value)), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func itemValues(item interface{}, sortFields []SortField) []interface{} {
+	values := make([]interface{}, 0, len(sortFields))
+	for _, f := range sortFields {
+		value, _ := fieldValue(item, f.Field)
+		values = append(values, value)
+	}
+	return values
+}
+
+// compareItem compares the item with the sort values in the order of the sort fields.
+func compareItem(item interface{}, values []interface{}, sortFields []SortField) int {
+	for i, f := range sortFields {
+		value, _ := fieldValue(item, f.Field)
+		c := compareValue(value, values[i])
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValue compares the values of a field, numbers are compared as float64 since the values
+// decoded from the cursor are float64.
+func compareValue(a, b interface{}) int {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// fieldValue returns the value of the field with the json name, the fields of the embedded
+// structs are included. Values of named string types are returned as strings.
+func fieldValue(item interface{}, name string) (interface{}, bool) {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			if value, ok := fieldValue(v.Field(i).Interface(), name); ok {
+				return value, true
+			}
+			continue
+		}
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		if jsonName != name {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.String {
+			return fv.String(), true
+		}
+		return fv.Interface(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pagination
+
+import (
+	"regexp"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoQuery is the mongo query of a page.
+type MongoQuery struct {
+	// Filter finds the items of the page, Count counts all the items matched by the query.
+	Filter  bson.M
+	Count   bson.M
+	Options *options.FindOptions
+
+	query      *Query
+	sortFields []SortField
+}
+
+// Mongo builds the keyset query of the page, the items should be found with Filter and Options
+// and passed to Page. The collection should have an index of the sort fields.
+func Mongo(query bson.M, q *Query, s *Schema) (*MongoQuery, error) {
+	if q == nil {
+		q = &Query{}
+	}
+	sortFields, err := q.validate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	and := []bson.M{query}
+	for field, values := range q.Filters {
+		in := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			// the type of the field is unknown, so the number and the bool forms are matched as well.
+			in = append(in, value)
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				in = append(in, n)
+			}
+			if b, err := strconv.ParseBool(value); err == nil {
+				in = append(in, b)
+			}
+		}
+		and = append(and, bson.M{s.bsonField(field): bson.M{"$in": in}})
+	}
+	if q.Search != "" {
+		or := make([]bson.M, 0, len(s.Searchable))
+		for _, field := range s.Searchable {
+			or = append(or, bson.M{s.bsonField(field): bson.M{"$regex": regexp.QuoteMeta(q.Search), "$options": "i"}})
+		}
+		and = append(and, bson.M{"$or": or})
+	}
+	resp := &MongoQuery{Count: combine(and), query: q, sortFields: sortFields}
+
+	if q.Cursor != "" {
+		last, err := decodeCursor(q.Cursor, sortFields)
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, keysetFilter(last, sortFields, s))
+	}
+	resp.Filter = combine(and)
+
+	sortDoc := bson.D{}
+	for _, f := range sortFields {
+		order := 1
+		if f.Desc {
+			order = -1
+		}
+		sortDoc = append(sortDoc, bson.E{Key: s.bsonField(f.Field), Value: order})
+	}
+	resp.Options = options.Find().SetSort(sortDoc)
+	if q.Limit > 0 {
+		// one more item is found to know whether there is a next page
+		resp.Options.SetLimit(int64(q.Limit + 1))
+	}
+	return resp, nil
+}
+
+// keysetFilter matches the items after the last item of the previous page:
+// (f1 > v1) or (f1 = v1 and f2 > v2) or ...
+func keysetFilter(last []interface{}, sortFields []SortField, s *Schema) bson.M {
+	or := make([]bson.M, 0, len(sortFields))
+	for i, f := range sortFields {
+		cond := bson.M{}
+		for j := 0; j < i; j++ {
+			cond[s.bsonField(sortFields[j].Field)] = last[j]
+		}
+		op := "$gt"
+		if f.Desc {
+			op = "$lt"
+		}
+		cond[s.bsonField(f.Field)] = bson.M{op: last[i]}
+		or = append(or, cond)
+	}
+	return bson.M{"$or": or}
+}
+
+func combine(and []bson.M) bson.M {
+	if len(and) == 1 {
+		return and[0]
+	}
+	return bson.M{"$and": and}
+}
+
+// MongoPage trims the items found by the mongo query to the page and creates the next cursor.
+func MongoPage[T any](items []T, mq *MongoQuery, total int64) ([]T, *Page, error) {
+	page := &Page{Total: total}
+	if mq.query.Limit > 0 && len(items) > mq.query.Limit {
+		items = items[:mq.query.Limit]
+		cursor, err := encodeCursor(mq.sortFields, itemValues(items[len(items)-1], mq.sortFields))
+		if err != nil {
+			return nil, nil, err
+		}
+		page.NextCursor = cursor
+	}
+	return items, page, nil
+}
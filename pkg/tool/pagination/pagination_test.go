@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pagination
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type testItem struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+var testSchema = &Schema{
+	Key:         "name",
+	Sortable:    []string{"update_time"},
+	Filterable:  []string{"type"},
+	Searchable:  []string{"name"},
+	DefaultSort: "-update_time",
+}
+
+func mustParse(raw string) *Query {
+	values, err := url.ParseQuery(raw)
+	Expect(err).NotTo(HaveOccurred())
+	q, err := ParseQuery(values)
+	Expect(err).NotTo(HaveOccurred())
+	return q
+}
+
+var _ = Describe("Testing pagination", func() {
+	items := []*testItem{
+		{Name: "a", Type: "k8s", UpdateTime: 3},
+		{Name: "b", Type: "helm", UpdateTime: 2},
+		{Name: "c", Type: "k8s", UpdateTime: 2},
+		{Name: "d", Type: "k8s", UpdateTime: 1},
+		{Name: "ab", Type: "helm", UpdateTime: 1},
+	}
+
+	It("returns the items as they are for an empty query", func() {
+		q := mustParse("")
+		Expect(q.IsEmpty()).To(BeTrue())
+		resp, page, err := Apply(items, q, testSchema)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp).To(Equal(items))
+		Expect(page.Total).To(BeEquivalentTo(5))
+	})
+
+	It("pages the items with the cursor", func() {
+		var names []string
+		cursor := ""
+		for i := 0; i < 5; i++ {
+			q := mustParse("limit=2&cursor=" + cursor)
+			resp, page, err := Apply(items, q, testSchema)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(page.Total).To(BeEquivalentTo(5))
+			for _, item := range resp {
+				names = append(names, item.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		Expect(names).To(Equal([]string{"a", "b", "c", "ab", "d"}))
+	})
+
+	It("filters and searches the items", func() {
+		resp, page, err := Apply(items, mustParse("filter[type]=helm&q=A&sort=name"), testSchema)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(page.Total).To(BeEquivalentTo(1))
+		Expect(resp).To(HaveLen(1))
+		Expect(resp[0].Name).To(Equal("ab"))
+	})
+
+	It("rejects the unknown fields and the cursor of another sort", func() {
+		_, _, err := Apply(items, mustParse("filter[name]=a"), testSchema)
+		Expect(err).To(HaveOccurred())
+		_, _, err = Apply(items, mustParse("sort=type"), testSchema)
+		Expect(err).To(HaveOccurred())
+
+		_, page, err := Apply(items, mustParse("limit=1"), testSchema)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = Apply(items, mustParse("limit=1&sort=name&cursor="+page.NextCursor), testSchema)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("builds the keyset mongo query", func() {
+		_, page, err := Apply(items, mustParse("limit=1"), testSchema)
+		Expect(err).NotTo(HaveOccurred())
+
+		mq, err := Mongo(bson.M{"deleted": false}, mustParse("limit=1&cursor="+page.NextCursor), testSchema)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*mq.Options.Limit).To(BeEquivalentTo(2))
+		Expect(mq.Options.Sort).To(Equal(bson.D{{Key: "update_time", Value: -1}, {Key: "name", Value: 1}}))
+		Expect(mq.Count).To(Equal(bson.M{"deleted": false}))
+		Expect(mq.Filter).To(Equal(bson.M{"$and": []bson.M{
+			{"deleted": false},
+			{"$or": []bson.M{
+				{"update_time": bson.M{"$lt": float64(3)}},
+				{"update_time": float64(3), "name": bson.M{"$gt": "a"}},
+			}},
+		}}))
+
+		found, page, err := MongoPage([]*testItem{items[1], items[2]}, mq, 5)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(HaveLen(1))
+		Expect(page.NextCursor).NotTo(BeEmpty())
+	})
+})
@@ -0,0 +1,223 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	ParamCursor = "cursor"
+	ParamLimit  = "limit"
+	ParamSort   = "sort"
+	ParamSearch = "q"
+	// filters are given as filter[field]=value, values separated by comma are matched with "or".
+	paramFilterPrefix = "filter["
+
+	// HeaderNextCursor and HeaderTotalCount are set in the responses of the list APIs, so that the
+	// response bodies stay the same for the clients which do not paginate.
+	HeaderNextCursor = "X-Next-Cursor"
+	HeaderTotalCount = "X-Total-Count"
+
+	MaxLimit = 500
+)
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Query is the pagination, filtering and sorting of a list API.
+type Query struct {
+	// Cursor is the next cursor returned with the previous page.
+	Cursor string
+	// Limit is the size of a page, all items are returned if it is 0.
+	Limit   int
+	Sort    []SortField
+	Filters map[string][]string
+	// Search is a case insensitive substring matched against the searchable fields.
+	Search string
+}
+
+// Page is the result of a paginated list.
+type Page struct {
+	// NextCursor is empty on the last page.
+	NextCursor string
+	Total      int64
+}
+
+// Schema describes the fields of a list API which can be used in the query, fields are referred
+// by their json names.
+type Schema struct {
+	// Key identifies an item, it is always the last sort field so that the order is stable.
+	Key        string
+	Sortable   []string
+	Filterable []string
+	Searchable []string
+	// DefaultSort is used if the query has no sort, e.g. -update_time.
+	DefaultSort string
+	// BsonFields maps the json names to the bson names if they are different.
+	BsonFields map[string]string
+}
+
+func (s *Schema) bsonField(field string) string {
+	if name, ok := s.BsonFields[field]; ok {
+		return name
+	}
+	return field
+}
+
+// ParseQuery parses the query parameters: cursor, limit, sort (e.g. -update_time,name), q and filter[field].
+func ParseQuery(values url.Values) (*Query, error) {
+	q := &Query{
+		Cursor:  values.Get(ParamCursor),
+		Search:  strings.TrimSpace(values.Get(ParamSearch)),
+		Filters: map[string][]string{},
+	}
+	if limit := values.Get(ParamLimit); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid limit: %s", limit)
+		}
+		if n > MaxLimit {
+			n = MaxLimit
+		}
+		q.Limit = n
+	}
+	sortFields, err := parseSort(values.Get(ParamSort))
+	if err != nil {
+		return nil, err
+	}
+	q.Sort = sortFields
+	for key, vals := range values {
+		if !strings.HasPrefix(key, paramFilterPrefix) || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		field := key[len(paramFilterPrefix) : len(key)-1]
+		for _, v := range vals {
+			q.Filters[field] = append(q.Filters[field], strings.Split(v, ",")...)
+		}
+	}
+	return q, nil
+}
+
+func parseSort(spec string) ([]SortField, error) {
+	var resp []SortField
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if field.Field == "" {
+			return nil, fmt.Errorf("invalid sort: %s", spec)
+		}
+		resp = append(resp, field)
+	}
+	return resp, nil
+}
+
+// IsEmpty returns true if the query does nothing, the list is returned as it is in that case.
+func (q *Query) IsEmpty() bool {
+	return q == nil || (q.Cursor == "" && q.Limit == 0 && len(q.Sort) == 0 && len(q.Filters) == 0 && q.Search == "")
+}
+
+// validate checks the fields against the schema and returns the sort fields ending with the key.
+func (q *Query) validate(s *Schema) ([]SortField, error) {
+	for field := range q.Filters {
+		if !contains(s.Filterable, field) {
+			return nil, fmt.Errorf("field %s can not be filtered", field)
+		}
+	}
+	if q.Search != "" && len(s.Searchable) == 0 {
+		return nil, fmt.Errorf("search is not supported")
+	}
+
+	sortFields := q.Sort
+	if len(sortFields) == 0 {
+		sortFields, _ = parseSort(s.DefaultSort)
+	}
+	resp := make([]SortField, 0, len(sortFields)+1)
+	hasKey := false
+	for _, f := range sortFields {
+		if f.Field != s.Key && !contains(s.Sortable, f.Field) {
+			return nil, fmt.Errorf("field %s can not be sorted", f.Field)
+		}
+		hasKey = hasKey || f.Field == s.Key
+		resp = append(resp, f)
+	}
+	if !hasKey {
+		resp = append(resp, SortField{Field: s.Key})
+	}
+	return resp, nil
+}
+
+type cursorToken struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+func sortSpec(sortFields []SortField) string {
+	parts := make([]string, 0, len(sortFields))
+	for _, f := range sortFields {
+		if f.Desc {
+			parts = append(parts, "-"+f.Field)
+		} else {
+			parts = append(parts, f.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(sortFields []SortField, values []interface{}) (string, error) {
+	data, err := json.Marshal(&cursorToken{Sort: sortSpec(sortFields), Values: values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort values of the last item of the previous page, the cursor must be
+// created with the same sorting.
+func decodeCursor(cursor string, sortFields []SortField) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	token := &cursorToken{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if token.Sort != sortSpec(sortFields) || len(token.Values) != len(sortFields) {
+		return nil, fmt.Errorf("cursor does not match the sort")
+	}
+	return token.Values, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perforce
+
+import (
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort is the port of p4d if P4PORT does not contain one.
+const DefaultPort = 1666
+
+const (
+	// clientProtocol is the protocol level of the client, the server talks in the lower one of it and its own.
+	clientProtocol = "82"
+	programName    = "zadig"
+)
+
+// severityFailed is the severity of the errors, it is the 4 highest bits of the codes of the messages.
+const severityFailed = 3
+
+var (
+	ticketRegexp = regexp.MustCompile(`^[0-9A-F]{32}$`)
+	varRegexp    = regexp.MustCompile(`%'?([^%']*)'?%`)
+)
+
+// Address is a P4PORT, e.g. ssl:p4.example.com:1666.
+type Address struct {
+	Host string
+	Port int
+	SSL  bool
+}
+
+// ParseAddress parses P4PORT in the form of [protocol:]host[:port], the protocol can be tcp, tcp4, tcp6, ssl,
+// ssl4 or ssl6.
+func ParseAddress(address string) (*Address, error) {
+	address = strings.TrimSpace(address)
+	result := &Address{Port: DefaultPort}
+	if i := strings.Index(address, ":"); i > 0 {
+		switch strings.ToLower(address[:i]) {
+		case "ssl", "ssl4", "ssl6", "ssl46", "ssl64":
+			result.SSL = true
+			address = address[i+1:]
+		case "tcp", "tcp4", "tcp6", "tcp46", "tcp64":
+			address = address[i+1:]
+		}
+	}
+
+	host := address
+	if i := strings.LastIndex(address, ":"); i >= 0 && !strings.HasSuffix(address, "]") {
+		port, err := strconv.Atoi(address[i+1:])
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port in perforce address %s", address)
+		}
+		host, result.Port = address[:i], port
+	}
+	result.Host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if result.Host == "" || strings.Contains(result.Host, "/") {
+		return nil, fmt.Errorf("invalid perforce address %s", address)
+	}
+	return result, nil
+}
+
+func (a *Address) String() string {
+	hostPort := net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+	if a.SSL {
+		return "ssl:" + hostPort
+	}
+	return hostPort
+}
+
+// ConnectError is returned if p4d can not be connected, the credentials are not checked then.
+type ConnectError struct {
+	Err error
+}
+
+func (e *ConnectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyLogin runs `p4 login -s` against the server with the ticket or the password of the user, the status of
+// the login is returned, e.g. "User admin ticket expires in 12 hours 0 minutes.".
+func VerifyLogin(address, user, password, ticket string, timeout time.Duration) (string, error) {
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+	conn, err := dial(addr, timeout)
+	if err != nil {
+		return "", &ConnectError{Err: err}
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	secret := ticket
+	if secret == "" && password != "" {
+		secret = hashPassword(password)
+	}
+	return runCommand(conn, user, secret, "user-login", "-s")
+}
+
+func dial(addr *Address, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	hostPort := net.JoinHostPort(addr.Host, strconv.Itoa(addr.Port))
+	if !addr.SSL {
+		return dialer.Dial("tcp", hostPort)
+	}
+	// p4d usually runs with a self-signed certificate, it is trusted by its fingerprint by the p4 clients.
+	return tls.DialWithDialer(dialer, "tcp", hostPort, &tls.Config{InsecureSkipVerify: true})
+}
+
+// hashPassword returns the md5 of the password, the challenges of the server are answered by it as if it is a
+// ticket. A ticket is used as is.
+func hashPassword(password string) string {
+	if ticketRegexp.MatchString(password) {
+		return password
+	}
+	sum := md5.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// runCommand sends the protocol and the command to the server and handles its callbacks until it releases the
+// client, the info messages are returned and the first error message is returned as the error.
+func runCommand(conn net.Conn, user, secret, function string, args ...string) (string, error) {
+	protocol := newMessage("protocol")
+	protocol.set("client", clientProtocol)
+	protocol.set("api", "99")
+	protocol.set("enableStreams", "")
+	protocol.set("enableGraph", "")
+	protocol.set("expandAndmaps", "")
+	if err := writeMessage(conn, protocol); err != nil {
+		return "", err
+	}
+
+	command := &message{}
+	command.set("prog", programName)
+	command.set("version", "1")
+	command.set("client", programName)
+	command.set("cwd", "/")
+	command.set("host", programName)
+	command.set("os", "UNIX")
+	command.set("user", user)
+	for _, arg := range args {
+		command.set("", arg)
+	}
+	command.set("func", function)
+	if err := writeMessage(conn, command); err != nil {
+		return "", err
+	}
+
+	var infos []string
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the response of perforce: %s", err)
+		}
+		switch msg.function() {
+		case "release", "release2":
+			return strings.Join(infos, "\n"), nil
+		case "flush1":
+			// the flow control of the server, the variables are echoed back.
+			reply := newMessage("flush2")
+			for _, v := range msg.vars {
+				if v.name != "func" {
+					reply.set(v.name, v.value)
+				}
+			}
+			if err := writeMessage(conn, reply); err != nil {
+				return "", err
+			}
+		case "client-Crypto":
+			if secret == "" {
+				return "", fmt.Errorf("perforce password (P4PASSWD) invalid or unset")
+			}
+			reply := newMessage(msg.get("confirm"))
+			reply.set("token", answerChallenge(msg.get("token"), secret))
+			if err := writeMessage(conn, reply); err != nil {
+				return "", err
+			}
+		case "client-Prompt":
+			return "", fmt.Errorf("perforce prompts for input: %s", msg.get("data"))
+		case "client-Message":
+			text, severity := formatMessage(msg)
+			if severity >= severityFailed {
+				return "", fmt.Errorf("%s", text)
+			}
+			if text != "" {
+				infos = append(infos, text)
+			}
+		case "client-OutputError":
+			return "", fmt.Errorf("%s", strings.TrimSpace(msg.get("data")))
+		case "client-OutputInfo", "client-OutputText":
+			infos = append(infos, strings.TrimSpace(msg.get("data")))
+		}
+	}
+}
+
+// answerChallenge returns the md5 of the token of the server and the ticket.
+func answerChallenge(token, secret string) string {
+	sum := md5.Sum([]byte(token + secret))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// formatMessage formats the first message of client-Message, its variables such as %user% in fmt0 are replaced
+// by the variables of the message.
+func formatMessage(m *message) (string, int) {
+	code, _ := strconv.ParseUint(m.get("code0"), 10, 32)
+	text := varRegexp.ReplaceAllStringFunc(m.get("fmt0"), func(s string) string {
+		return m.get(varRegexp.FindStringSubmatch(s)[1])
+	})
+	return strings.TrimSpace(text), int(code >> 28)
+}
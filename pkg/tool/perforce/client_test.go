@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perforce
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseAddress(t *testing.T) {
+	testcases := []struct {
+		address string
+		result  string
+	}{
+		{"p4.example.com", "p4.example.com:1666"},
+		{"p4.example.com:1777", "p4.example.com:1777"},
+		{"tcp:p4.example.com:1666", "p4.example.com:1666"},
+		{"ssl:p4.example.com:1666", "ssl:p4.example.com:1666"},
+		{"ssl6:[::1]:1666", "ssl:[::1]:1666"},
+	}
+	for _, tc := range testcases {
+		addr, err := ParseAddress(tc.address)
+		if err != nil {
+			t.Fatalf("Unexpected error for address <%s>: %s", tc.address, err)
+		}
+		if addr.String() != tc.result {
+			t.Errorf("Expected address <%s> to be parsed as <%s> but got <%s>", tc.address, tc.result, addr.String())
+		}
+	}
+
+	for _, address := range []string{"", "p4.example.com:port", "https://p4.example.com"} {
+		if _, err := ParseAddress(address); err == nil {
+			t.Errorf("Expected address <%s> to be rejected", address)
+		}
+	}
+}
+
+func TestVerifyLogin(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer ln.Close()
+	go serveLogin(ln, hashPassword("secret"))
+
+	status, err := VerifyLogin(ln.Addr().String(), "admin", "secret", "", time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if status != "User admin ticket expires in 12 hours." {
+		t.Errorf("Unexpected login status <%s>", status)
+	}
+
+	if _, err := VerifyLogin(ln.Addr().String(), "admin", "", "0123456789ABCDEF0123456789ABCDEF", time.Second); err == nil {
+		t.Errorf("Expected the wrong ticket to be rejected")
+	}
+}
+
+// serveLogin is a fake p4d which challenges the client and answers login -s.
+func serveLogin(ln net.Listener, ticket string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		func() {
+			defer conn.Close()
+			var command *message
+			for command == nil || command.function() == "protocol" {
+				if command, err = readMessage(conn); err != nil {
+					return
+				}
+			}
+			if command.function() != "user-login" || command.get("") != "-s" {
+				return
+			}
+
+			challenge := newMessage("client-Crypto")
+			challenge.set("token", "CHALLENGE")
+			challenge.set("confirm", "dm-Login")
+			_ = writeMessage(conn, challenge)
+			answer, err := readMessage(conn)
+			if err != nil || answer.function() != "dm-Login" {
+				return
+			}
+
+			reply := newMessage("client-Message")
+			if answer.get("token") == answerChallenge("CHALLENGE", ticket) {
+				reply.set("code0", strconv.Itoa(1<<28))
+				reply.set("fmt0", "User %user% ticket expires in %hours% hours.")
+				reply.set("user", command.get("user"))
+				reply.set("hours", "12")
+			} else {
+				reply.set("code0", strconv.Itoa(severityFailed<<28))
+				reply.set("fmt0", "Perforce password (P4PASSWD) invalid or unset.")
+			}
+			_ = writeMessage(conn, reply)
+			_ = writeMessage(conn, newMessage("release"))
+		}()
+	}
+}
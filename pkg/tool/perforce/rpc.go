@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package perforce
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxMessageLength protects the client from a peer which is not p4d, the messages of the commands used here
+// are tiny.
+const maxMessageLength = 16 << 20
+
+// variable is a named value of a message of the p4 rpc protocol, the arguments of a command are unnamed.
+type variable struct {
+	name  string
+	value string
+}
+
+// message is a frame of the p4 rpc protocol, the variable func tells the function to call on the peer.
+type message struct {
+	vars []variable
+}
+
+func newMessage(function string) *message {
+	m := &message{}
+	m.set("func", function)
+	return m
+}
+
+func (m *message) set(name, value string) {
+	m.vars = append(m.vars, variable{name: name, value: value})
+}
+
+func (m *message) get(name string) string {
+	for _, v := range m.vars {
+		if v.name == name {
+			return v.value
+		}
+	}
+	return ""
+}
+
+func (m *message) function() string {
+	return m.get("func")
+}
+
+// encode returns the frame of the message: a 5 bytes header of the checksum and the length of the payload,
+// followed by the variables, each of them is `name\0 length value\0` and the lengths are little endian.
+func (m *message) encode() []byte {
+	payload := &bytes.Buffer{}
+	for _, v := range m.vars {
+		payload.WriteString(v.name)
+		payload.WriteByte(0)
+		_ = binary.Write(payload, binary.LittleEndian, uint32(len(v.value)))
+		payload.WriteString(v.value)
+		payload.WriteByte(0)
+	}
+
+	frame := make([]byte, 5, 5+payload.Len())
+	binary.LittleEndian.PutUint32(frame[1:], uint32(payload.Len()))
+	frame[0] = frame[1] ^ frame[2] ^ frame[3] ^ frame[4]
+	return append(frame, payload.Bytes()...)
+}
+
+func writeMessage(w io.Writer, m *message) error {
+	_, err := w.Write(m.encode())
+	return err
+}
+
+func readMessage(r io.Reader) (*message, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != header[1]^header[2]^header[3]^header[4] {
+		return nil, fmt.Errorf("bad message header, the server may not be a perforce server")
+	}
+	length := binary.LittleEndian.Uint32(header[1:])
+	if length > maxMessageLength {
+		return nil, fmt.Errorf("message of %d bytes is too large", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	m := &message{}
+	for len(payload) > 0 {
+		end := bytes.IndexByte(payload, 0)
+		if end < 0 || len(payload) < end+5 {
+			return nil, fmt.Errorf("truncated variable in message")
+		}
+		name := string(payload[:end])
+		size := binary.LittleEndian.Uint32(payload[end+1 : end+5])
+		payload = payload[end+5:]
+		if uint32(len(payload)) < size+1 {
+			return nil, fmt.Errorf("truncated value of variable %s", name)
+		}
+		m.set(name, string(payload[:size]))
+		payload = payload[size+1:]
+	}
+	return m, nil
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package s3
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/fs"
 	"mime"
 	"os"
@@ -222,6 +224,86 @@ func (c *Client) Upload(bucketName, src string, objectKey string) error {
 	return err
 }
 
+// UploadBytes uploads the content to the bucket with the specified objectKey
+func (c *Client) UploadBytes(bucketName, objectKey string, body []byte) error {
+	_, err := c.PutObject(&s3.PutObjectInput{
+		Body:   bytes.NewReader(body),
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	return err
+}
+
+// GetObjectSize returns the size of the object, false is returned if the object does not exist.
+func (c *Client) GetObjectSize(bucketName, objectKey string) (int64, bool, error) {
+	output, err := c.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		// HeadObject has no body, so the error code is the http status text
+		if e, ok := err.(awserr.Error); ok && (e.Code() == "NotFound" || e.Code() == s3.ErrCodeNoSuchKey) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return aws.Int64Value(output.ContentLength), true, nil
+}
+
+// GetRange reads at most length bytes of the object from the offset.
+func (c *Client) GetRange(bucketName, objectKey string, offset, length int64) ([]byte, error) {
+	if length <= 0 {
+		return []byte{}, nil
+	}
+	output, err := c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
+
+// ListAllFiles lists the keys of all the files with the given prefix, unlike ListFiles it is
+// not limited to the first page of the results.
+func (c *Client) ListAllFiles(bucketName, prefix string) ([]string, error) {
+	ret := make([]string, 0)
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	err := c.ListObjectsPages(input, func(output *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, item := range output.Contents {
+			ret = append(ret, aws.StringValue(item.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// SumSize returns the total size of the files with the given prefix.
+func (c *Client) SumSize(bucketName, prefix string) (int64, error) {
+	var size int64
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	err := c.ListObjectsPages(input, func(output *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, item := range output.Contents {
+			size += aws.Int64Value(item.Size)
+		}
+		return true
+	})
+	return size, err
+}
+
 // Upload upload all files in a directory to a S3 path recursively
 func (c *Client) UploadDir(bucketName, srcdir string, s3dir string) error {
 	err := fs.WalkDir(os.DirFS(srcdir), ".", func(p string, d fs.DirEntry, e error) error {
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretscan
+
+// Rule detects a kind of secret, the rules are compatible with the ones of gitleaks.
+type Rule struct {
+	ID          string `bson:"id"          json:"id"          yaml:"id"`
+	Description string `bson:"description" json:"description" yaml:"description"`
+	Regex       string `bson:"regex"       json:"regex"       yaml:"regex"`
+	// SecretGroup is the group of the regex which is the secret, the whole match is the secret if it is 0.
+	SecretGroup int `bson:"secret_group" json:"secret_group" yaml:"secret_group"`
+	// Keywords are checked before the regex to speed up the scan, a line is matched only if it contains
+	// one of them case insensitively.
+	Keywords []string `bson:"keywords,omitempty" json:"keywords,omitempty" yaml:"keywords,omitempty"`
+}
+
+// PrivateKeyRuleID is the rule of the private keys, the lines between the begin and the end of a key are
+// all redacted.
+const PrivateKeyRuleID = "private-key"
+
+var DefaultRules = []*Rule{
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key id",
+		Regex:       `\b((?:A3T[A-Z0-9]|AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"akia", "asia", "abia", "acca", "a3t"},
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Regex:       `(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"secret"},
+	},
+	{
+		ID:          "github-token",
+		Description: "GitHub token",
+		Regex:       `\b(gh[pousr]_[A-Za-z0-9]{36,255})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_"},
+	},
+	{
+		ID:          "github-fine-grained-token",
+		Description: "GitHub fine-grained personal access token",
+		Regex:       `\b(github_pat_[A-Za-z0-9_]{82})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"github_pat_"},
+	},
+	{
+		ID:          "gitlab-token",
+		Description: "GitLab personal access token",
+		Regex:       `\b(glpat-[A-Za-z0-9_-]{20})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"glpat-"},
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack token",
+		Regex:       `\b(xox[baprs]-[A-Za-z0-9-]{10,48})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"xoxb", "xoxa", "xoxp", "xoxr", "xoxs"},
+	},
+	{
+		ID:          "google-api-key",
+		Description: "Google API key",
+		Regex:       `\b(AIza[0-9A-Za-z_-]{35})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"aiza"},
+	},
+	{
+		ID:          "stripe-key",
+		Description: "Stripe secret key",
+		Regex:       `\b((?:sk|rk)_(?:test|live)_[0-9A-Za-z]{24,99})\b`,
+		SecretGroup: 1,
+		Keywords:    []string{"sk_test", "sk_live", "rk_test", "rk_live"},
+	},
+	{
+		ID:          "jwt",
+		Description: "JSON web token",
+		Regex:       `\b(ey[A-Za-z0-9_-]{17,}\.ey[A-Za-z0-9_-]{17,}\.[A-Za-z0-9_-]{10,})`,
+		SecretGroup: 1,
+		Keywords:    []string{"ey"},
+	},
+	{
+		ID:          PrivateKeyRuleID,
+		Description: "Private key",
+		Regex:       `-----BEGIN[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----`,
+		Keywords:    []string{"private key"},
+	},
+	{
+		ID:          "generic-api-key",
+		Description: "Generic secret assigned to a key, token or password",
+		Regex:       `(?i)(?:api_?key|secret|token|passw(?:or)?d)["']?\s*[:=]\s*["']([A-Za-z0-9_\-+/=.@!#$%^&*]{12,})["']`,
+		SecretGroup: 1,
+		Keywords:    []string{"key", "secret", "token", "passw"},
+	},
+}
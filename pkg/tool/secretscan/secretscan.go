@@ -0,0 +1,279 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretscan
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	FindingSourceLog    = "log"
+	FindingSourceSource = "source"
+
+	// FindingLogPrefix marks the findings printed to the job log by the job executor, they are collected
+	// when the log is saved.
+	FindingLogPrefix = "[zadig-secret-scan] "
+
+	redacted = "[REDACTED]"
+)
+
+// Config is the rules used by a scanner, the default rules are used together with the custom ones unless
+// DisableDefaultRules is true.
+type Config struct {
+	DisableDefaultRules bool    `bson:"disable_default_rules" json:"disable_default_rules" yaml:"disable_default_rules"`
+	Rules               []*Rule `bson:"rules"                 json:"rules"                 yaml:"rules"`
+	// Allowlist are the regexes of the secrets which are not reported, e.g. the fake ones in the tests.
+	Allowlist []string `bson:"allowlist" json:"allowlist" yaml:"allowlist"`
+}
+
+type Finding struct {
+	RuleID      string `bson:"rule_id"        json:"rule_id"`
+	Description string `bson:"description"    json:"description"`
+	Source      string `bson:"source"         json:"source"`
+	File        string `bson:"file,omitempty" json:"file,omitempty"`
+	Line        int    `bson:"line"           json:"line"`
+	// Secret is masked, only the first characters are kept.
+	Secret string `bson:"secret" json:"secret"`
+}
+
+type compiledRule struct {
+	*Rule
+	regex    *regexp.Regexp
+	keywords []string
+}
+
+type Scanner struct {
+	rules      []*compiledRule
+	allowlist  []*regexp.Regexp
+	privateKey *compiledRule
+}
+
+func New(cfg *Config) (*Scanner, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	rules := cfg.Rules
+	if !cfg.DisableDefaultRules {
+		rules = append(append([]*Rule{}, DefaultRules...), cfg.Rules...)
+	}
+
+	s := &Scanner{}
+	ids := map[string]bool{}
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("the id of a rule is empty")
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("rule %s is defined more than once", rule.ID)
+		}
+		ids[rule.ID] = true
+		regex, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of rule %s: %v", rule.ID, err)
+		}
+		if rule.SecretGroup < 0 || rule.SecretGroup > regex.NumSubexp() {
+			return nil, fmt.Errorf("rule %s has no group %d", rule.ID, rule.SecretGroup)
+		}
+		compiled := &compiledRule{Rule: rule, regex: regex}
+		for _, keyword := range rule.Keywords {
+			compiled.keywords = append(compiled.keywords, strings.ToLower(keyword))
+		}
+		if rule.ID == PrivateKeyRuleID {
+			s.privateKey = compiled
+		}
+		s.rules = append(s.rules, compiled)
+	}
+	for _, allow := range cfg.Allowlist {
+		regex, err := regexp.Compile(allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist regex %s: %v", allow, err)
+		}
+		s.allowlist = append(s.allowlist, regex)
+	}
+	return s, nil
+}
+
+// Redact copies the log to w with the secrets redacted and returns the findings, the findings printed
+// by the job executor are collected as well.
+func (s *Scanner) Redact(r io.Reader, w io.Writer) ([]*Finding, error) {
+	var findings []*Finding
+	reader := bufio.NewReader(r)
+	inPrivateKey := false
+	for lineNum := 1; ; lineNum++ {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			if finding, ok := parseFinding(line); ok {
+				findings = append(findings, finding)
+			} else {
+				var lineFindings []*Finding
+				line, lineFindings, inPrivateKey = s.scanLine(line, inPrivateKey)
+				for _, finding := range lineFindings {
+					finding.Source = FindingSourceLog
+					finding.Line = lineNum
+				}
+				findings = append(findings, lineFindings...)
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return findings, err
+			}
+		}
+		if readErr == io.EOF {
+			return findings, nil
+		}
+		if readErr != nil {
+			return findings, readErr
+		}
+	}
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// ScanDiff scans the lines added by a unified diff, e.g. the output of git diff.
+func (s *Scanner) ScanDiff(r io.Reader) ([]*Finding, error) {
+	var findings []*Finding
+	var file string
+	var lineNum int
+	inPrivateKey := false
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			inPrivateKey = false
+		case strings.HasPrefix(line, "@@"):
+			if match := hunkHeader.FindStringSubmatch(line); match != nil {
+				lineNum, _ = strconv.Atoi(match[1])
+			}
+		case strings.HasPrefix(line, "+"):
+			var lineFindings []*Finding
+			_, lineFindings, inPrivateKey = s.scanLine(line[1:], inPrivateKey)
+			for _, finding := range lineFindings {
+				finding.Source = FindingSourceSource
+				finding.File = file
+				finding.Line = lineNum
+			}
+			findings = append(findings, lineFindings...)
+			lineNum++
+		case strings.HasPrefix(line, " "):
+			lineNum++
+		}
+		if readErr == io.EOF {
+			return findings, nil
+		}
+		if readErr != nil {
+			return findings, readErr
+		}
+	}
+}
+
+// scanLine returns the line with the secrets redacted, inPrivateKey is whether the line is in a private key.
+func (s *Scanner) scanLine(line string, inPrivateKey bool) (string, []*Finding, bool) {
+	if inPrivateKey {
+		if strings.Contains(line, "-----END") {
+			return line, nil, false
+		}
+		return redactAll(line), nil, true
+	}
+
+	var findings []*Finding
+	lower := strings.ToLower(line)
+	for _, rule := range s.rules {
+		if !rule.hasKeyword(lower) {
+			continue
+		}
+		matches := rule.regex.FindAllStringSubmatchIndex(line, -1)
+		// the secrets are replaced from the end so that the indexes are not changed
+		for i := len(matches) - 1; i >= 0; i-- {
+			start, end := matches[i][2*rule.SecretGroup], matches[i][2*rule.SecretGroup+1]
+			if start < 0 {
+				continue
+			}
+			secret := line[start:end]
+			if s.allowed(secret) {
+				continue
+			}
+			findings = append(findings, &Finding{RuleID: rule.ID, Description: rule.Description, Secret: mask(secret)})
+			line = line[:start] + redacted + line[end:]
+		}
+		if rule == s.privateKey && len(matches) > 0 && !strings.Contains(line, "-----END") {
+			inPrivateKey = true
+		}
+	}
+	return line, findings, inPrivateKey
+}
+
+func (r *compiledRule) hasKeyword(line string) bool {
+	if len(r.keywords) == 0 {
+		return true
+	}
+	for _, keyword := range r.keywords {
+		if strings.Contains(line, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) allowed(secret string) bool {
+	for _, allow := range s.allowlist {
+		if allow.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAll(line string) string {
+	content := strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(content) == "" {
+		return line
+	}
+	return redacted + line[len(content):]
+}
+
+func mask(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", 8)
+}
+
+// FormatFinding formats the finding as a line of the job log.
+func FormatFinding(finding *Finding) string {
+	data, _ := json.Marshal(finding)
+	return FindingLogPrefix + string(data)
+}
+
+func parseFinding(line string) (*Finding, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, FindingLogPrefix) {
+		return nil, false
+	}
+	finding := &Finding{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, FindingLogPrefix)), finding); err != nil {
+		return nil, false
+	}
+	return finding, true
+}
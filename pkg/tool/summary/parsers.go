@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// parseMarkdown takes the first level one or two heading as the title, the whole file is the text.
+func parseMarkdown(content []byte) (*Summary, error) {
+	s := &Summary{Text: string(content)}
+	for _, line := range strings.Split(s.Text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "# ") || strings.HasPrefix(line, "## ") {
+			s.Title = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			break
+		}
+	}
+	return s, nil
+}
+
+// parseJSON parses the summary in the schema of Summary, the unknown fields are rejected so that
+// the typos are not silently ignored.
+func parseJSON(content []byte) (*Summary, error) {
+	s := &Summary{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(s); err != nil {
+		return nil, fmt.Errorf("invalid json summary: %s", err)
+	}
+
+	switch s.Status {
+	case "", StatusSuccess, StatusWarning, StatusFailure, StatusInfo:
+	default:
+		return nil, fmt.Errorf("invalid status %q, it must be one of success, warning, failure and info", s.Status)
+	}
+	for _, m := range s.Metrics {
+		if m == nil || m.Name == "" {
+			return nil, fmt.Errorf("the name of a metric is empty")
+		}
+	}
+	for _, link := range s.Links {
+		if link == nil || link.Name == "" {
+			return nil, fmt.Errorf("the name of a link is empty")
+		}
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("link %s is not a http or https url", link.Name)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+
+	// MaxSize is the largest summary file accepted, the summaries are stored in the tasks.
+	MaxSize = 64 << 10
+)
+
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusWarning Status = "warning"
+	StatusFailure Status = "failure"
+	StatusInfo    Status = "info"
+)
+
+// Summary is the result summary a job reports in the summary file, it is rendered in the task details,
+// the comments of the pull requests and the notifications.
+type Summary struct {
+	Title  string `bson:"title"  json:"title"`
+	Status Status `bson:"status" json:"status"`
+	// Text is in markdown.
+	Text    string    `bson:"text"              json:"text"`
+	Metrics []*Metric `bson:"metrics,omitempty" json:"metrics,omitempty"`
+	Links   []*Link   `bson:"links,omitempty"   json:"links,omitempty"`
+}
+
+type Metric struct {
+	Name  string `bson:"name"           json:"name"`
+	Value string `bson:"value"          json:"value"`
+	Unit  string `bson:"unit,omitempty" json:"unit,omitempty"`
+}
+
+type Link struct {
+	Name string `bson:"name" json:"name"`
+	URL  string `bson:"url"  json:"url"`
+}
+
+// Parser parses the content of a summary file in a format.
+type Parser interface {
+	Parse(content []byte) (*Summary, error)
+}
+
+type ParserFunc func(content []byte) (*Summary, error)
+
+func (f ParserFunc) Parse(content []byte) (*Summary, error) {
+	return f(content)
+}
+
+var (
+	parsersMutex sync.RWMutex
+	parsers      = map[string]Parser{
+		FormatMarkdown: ParserFunc(parseMarkdown),
+		FormatJSON:     ParserFunc(parseJSON),
+	}
+)
+
+// RegisterParser registers the parser of the format, the parser registered earlier for the format is replaced.
+func RegisterParser(format string, parser Parser) {
+	parsersMutex.Lock()
+	defer parsersMutex.Unlock()
+	parsers[format] = parser
+}
+
+// Formats returns the formats which have a parser.
+func Formats() []string {
+	parsersMutex.RLock()
+	defer parsersMutex.RUnlock()
+	resp := make([]string, 0, len(parsers))
+	for format := range parsers {
+		resp = append(resp, format)
+	}
+	sort.Strings(resp)
+	return resp
+}
+
+// Parse parses the summary file with the parser of the format, the status of the summary defaults to info.
+func Parse(format string, content []byte) (*Summary, error) {
+	parsersMutex.RLock()
+	parser, ok := parsers[format]
+	parsersMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("summary format %q is not supported, the supported ones are %s", format, strings.Join(Formats(), ", "))
+	}
+	if len(content) > MaxSize {
+		return nil, fmt.Errorf("the summary of %d bytes exceeds the limit of %d bytes", len(content), MaxSize)
+	}
+
+	s, err := parser.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status == "" {
+		s.Status = StatusInfo
+	}
+	return s, nil
+}
+
+// Markdown renders the summary in markdown, the title is used if the summary does not have one.
+func (s *Summary) Markdown(title string) string {
+	if s.Title != "" {
+		title = s.Title
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "#### %s (%s)\n\n", title, s.Status)
+	if len(s.Metrics) > 0 {
+		b.WriteString("| | |\n|---|---|\n")
+		for _, m := range s.Metrics {
+			fmt.Fprintf(b, "| %s | %s |\n", m.Name, strings.TrimSpace(m.Value+" "+m.Unit))
+		}
+		b.WriteString("\n")
+	}
+	if text := strings.TrimSpace(s.Text); text != "" {
+		b.WriteString(text + "\n\n")
+	}
+	for _, link := range s.Links {
+		fmt.Fprintf(b, "- [%s](%s)\n", link.Name, link.URL)
+	}
+	return strings.TrimSpace(b.String())
+}
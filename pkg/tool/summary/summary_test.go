@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summary
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+	It("takes the first heading of the markdown as the title", func() {
+		s, err := Parse(FormatMarkdown, []byte("some notes\n## Coverage report\n\n- total: 81%\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Title).To(Equal("Coverage report"))
+		Expect(s.Status).To(Equal(StatusInfo))
+		Expect(s.Text).To(ContainSubstring("total: 81%"))
+	})
+
+	It("parses the json summary", func() {
+		s, err := Parse(FormatJSON, []byte(`{
+			"title": "Benchmark",
+			"status": "warning",
+			"text": "p99 regressed",
+			"metrics": [{"name": "p99", "value": "120", "unit": "ms"}],
+			"links": [{"name": "report", "url": "https://example.com/report"}]
+		}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Status).To(Equal(StatusWarning))
+		Expect(s.Metrics).To(HaveLen(1))
+
+		md := s.Markdown("bench")
+		Expect(md).To(HavePrefix("#### Benchmark (warning)"))
+		Expect(md).To(ContainSubstring("| p99 | 120 ms |"))
+		Expect(md).To(ContainSubstring("- [report](https://example.com/report)"))
+	})
+
+	It("rejects the invalid json summaries", func() {
+		for _, content := range []string{
+			`{"titel": "typo"}`,
+			`{"status": "ok"}`,
+			`{"metrics": [{"value": "1"}]}`,
+			`{"links": [{"name": "x", "url": "javascript:alert(1)"}]}`,
+		} {
+			_, err := Parse(FormatJSON, []byte(content))
+			Expect(err).To(HaveOccurred(), content)
+		}
+	})
+
+	It("rejects the unknown formats and the oversized files", func() {
+		_, err := Parse("xml", []byte("<a/>"))
+		Expect(err).To(HaveOccurred())
+		_, err = Parse(FormatMarkdown, []byte(strings.Repeat("a", MaxSize+1)))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("uses the registered parsers", func() {
+		RegisterParser("text", ParserFunc(func(content []byte) (*Summary, error) {
+			return &Summary{Text: string(content)}, nil
+		}))
+		Expect(Formats()).To(ContainElement("text"))
+		s, err := Parse("text", []byte("hello"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Markdown("custom")).To(Equal("#### custom (info)\n\nhello"))
+	})
+})
@@ -21,4 +21,6 @@ type AuthType string
 const (
 	SSHAuthType                AuthType = "SSH"
 	PrivateAccessTokenAuthType AuthType = "PrivateAccessToken"
+	// GitHubAppAuthType authenticates by the installation tokens of a github app.
+	GitHubAppAuthType AuthType = "GitHubApp"
 )
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// JobSecurityProfile decides the security context rendered into the pods of the jobs.
+type JobSecurityProfile string
+
+const (
+	// JobSecurityPrivileged runs the job in a privileged container so that docker can run in the job.
+	JobSecurityPrivileged JobSecurityProfile = "privileged"
+	// JobSecurityBaseline runs the job with the defaults of the image and the cluster.
+	JobSecurityBaseline JobSecurityProfile = "baseline"
+	// JobSecurityRestricted runs the job as a non-root user on a read-only root filesystem with the
+	// default seccomp profile and no capability.
+	JobSecurityRestricted JobSecurityProfile = "restricted"
+)
+
+var jobSecurityStrictness = map[JobSecurityProfile]int{
+	JobSecurityPrivileged: 0,
+	JobSecurityBaseline:   1,
+	JobSecurityRestricted: 2,
+}
+
+// Valid returns whether the profile is known, the empty profile is the baseline one.
+func (p JobSecurityProfile) Valid() bool {
+	if p == "" {
+		return true
+	}
+	_, ok := jobSecurityStrictness[p]
+	return ok
+}
+
+// Normalize returns the baseline profile for the empty one.
+func (p JobSecurityProfile) Normalize() JobSecurityProfile {
+	if p == "" {
+		return JobSecurityBaseline
+	}
+	return p
+}
+
+// Weaker returns whether the profile is less strict than the other one.
+func (p JobSecurityProfile) Weaker(other JobSecurityProfile) bool {
+	return jobSecurityStrictness[p.Normalize()] < jobSecurityStrictness[other.Normalize()]
+}
+
+// Enforce returns the minimum profile if the profile is weaker than it.
+func (p JobSecurityProfile) Enforce(minimum JobSecurityProfile) JobSecurityProfile {
+	if p.Weaker(minimum) {
+		return minimum.Normalize()
+	}
+	return p.Normalize()
+}
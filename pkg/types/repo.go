@@ -61,6 +61,11 @@ type Repository struct {
 	AuthType           AuthType `bson:"auth_type,omitempty"             json:"auth_type,omitempty"               yaml:"auth_type,omitempty"`
 	SSHKey             string   `bson:"ssh_key,omitempty"               json:"ssh_key,omitempty"                 yaml:"ssh_key,omitempty"`
 	PrivateAccessToken string   `bson:"private_access_token,omitempty"  json:"private_access_token,omitempty"    yaml:"private_access_token,omitempty"`
+	// SSHPort is the port of the ssh daemon of gerrit, it is decided on runtime like EnableProxy
+	SSHPort int `bson:"-" json:"ssh_port,omitempty" yaml:"ssh_port,omitempty"`
+	// MirrorURL is the mirror of the repo kept by zadig, the build fetches from the codehost if it is empty
+	// or the fetch from the mirror fails.
+	MirrorURL string `bson:"-" json:"mirror_url,omitempty" yaml:"mirror_url,omitempty"`
 }
 
 type BranchFilterInfo struct {
@@ -136,6 +141,12 @@ const (
 	// ProviderGitee
 	ProviderGitee = "gitee"
 
+	// ProviderBitbucketServer
+	ProviderBitbucketServer = "bitbucket_server"
+
+	// ProviderAzureDevOps
+	ProviderAzureDevOps = "azure_devops"
+
 	// ProviderOther
 	ProviderOther = "other"
 )
@@ -145,11 +156,17 @@ const (
 //
 // e.g. github returns refs/pull/1/head
 // e.g. gitlab returns merge-requests/1/head
+// e.g. bitbucket server returns refs/pull-requests/1/from
+// e.g. azure devops returns refs/pull/1/merge
 func (r *Repository) PRRef() string {
 	if strings.ToLower(r.Source) == ProviderGitlab || strings.ToLower(r.Source) == ProviderCodehub {
 		return fmt.Sprintf("merge-requests/%d/head", r.PR)
 	} else if strings.ToLower(r.Source) == ProviderGerrit {
 		return r.CheckoutRef
+	} else if strings.ToLower(r.Source) == ProviderBitbucketServer {
+		return fmt.Sprintf("refs/pull-requests/%d/from", r.PR)
+	} else if strings.ToLower(r.Source) == ProviderAzureDevOps {
+		return fmt.Sprintf("refs/pull/%d/merge", r.PR)
 	}
 	return fmt.Sprintf("refs/pull/%d/head", r.PR)
 }
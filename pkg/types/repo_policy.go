@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RepoPolicy restricts the repositories a codehost exposes to projects. The patterns are globs
+// matched against "namespace/repo", e.g. "koderover/*" or "*/zadig-*", deny patterns take precedence
+// and an empty allow list allows everything which is not denied.
+type RepoPolicy struct {
+	Allow []string `bson:"allow,omitempty" json:"allow,omitempty"`
+	Deny  []string `bson:"deny,omitempty"  json:"deny,omitempty"`
+}
+
+func (p *RepoPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, "/") {
+			return fmt.Errorf("invalid repo pattern %q, it should be like namespace/repo", pattern)
+		}
+	}
+	return nil
+}
+
+// RepoAllowed reports whether the repo can be used, a nil policy allows all repos.
+func (p *RepoPolicy) RepoAllowed(namespace, repo string) bool {
+	if p == nil {
+		return true
+	}
+	fullName := strings.Trim(namespace, "/") + "/" + repo
+	for _, pattern := range p.Deny {
+		if matched, _ := path.Match(pattern, fullName); matched {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range p.Allow {
+		if matched, _ := path.Match(pattern, fullName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// NamespaceAllowed reports whether any repo of the namespace may be allowed, it is used to filter
+// the namespaces before the repos are listed.
+func (p *RepoPolicy) NamespaceAllowed(namespace string) bool {
+	if p == nil {
+		return true
+	}
+	namespace = strings.Trim(namespace, "/")
+	for _, pattern := range p.Deny {
+		if matched, _ := path.Match(pattern, namespace+"/*"); matched && strings.HasSuffix(pattern, "/*") {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range p.Allow {
+		if matched, _ := path.Match(path.Dir(pattern), namespace); matched {
+			return true
+		}
+	}
+	return false
+}
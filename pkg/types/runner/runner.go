@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runner defines the protocol between aslan and the agents running the jobs outside kubernetes.
+package runner
+
+import (
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+const (
+	// TokenHeader carries the token of the runner the agent belongs to.
+	TokenHeader = "X-Zadig-Runner-Token"
+
+	// the environment variables the agent is configured by, the ephemeral agents launched for a job
+	// are given JobIDEnv and exit after the job is done.
+	ServerURLEnv   = "ZADIG_RUNNER_SERVER_URL"
+	RunnerNameEnv  = "ZADIG_RUNNER_NAME"
+	RunnerTokenEnv = "ZADIG_RUNNER_TOKEN"
+	JobIDEnv       = "ZADIG_RUNNER_JOB_ID"
+	// ArtifactsDirEnv is exported to the job, the files saved under it are uploaded when the job is done.
+	ArtifactsDirEnv = "ZADIG_ARTIFACTS_DIR"
+	// KeychainEnv is exported to the job on the macOS runners, it is the keychain the signing identity is
+	// imported into, e.g. for OTHER_CODE_SIGN_FLAGS="--keychain $ZADIG_KEYCHAIN".
+	KeychainEnv = "ZADIG_KEYCHAIN"
+
+	// MaxLogChunkSize is the max size of the log pushed in a request.
+	MaxLogChunkSize = 1 << 20
+)
+
+type JobStatus string
+
+const (
+	JobPassed JobStatus = "passed"
+	JobFailed JobStatus = "failed"
+)
+
+type LeaseArgs struct {
+	// Agent is the name of the agent, e.g. the hostname.
+	Agent string `json:"agent"`
+	// JobID is set by the ephemeral agents, only the job they are launched for is leased.
+	JobID string `json:"job_id,omitempty"`
+}
+
+type Lease struct {
+	JobID string `json:"job_id"`
+	// Context is the yaml config of the job executor.
+	Context string `json:"context"`
+	// Timeout is in minutes.
+	Timeout int64 `json:"timeout"`
+	// Signing is given to the agents of the macOS runners, it is installed before the job runs and removed
+	// after it is done.
+	Signing *IOSSigning `json:"signing,omitempty"`
+}
+
+type IOSSigning struct {
+	// Certificate is the base64 encoded p12 file of the signing identity.
+	Certificate         string `bson:"certificate"          json:"certificate"`
+	CertificatePassword string `bson:"certificate_password" json:"certificate_password"`
+	// ProvisioningProfiles are the base64 encoded provisioning profiles.
+	ProvisioningProfiles []string `bson:"provisioning_profiles" json:"provisioning_profiles"`
+}
+
+type LeaseResponse struct {
+	// Job is nil if there is no job to run.
+	Job *Lease `json:"job"`
+}
+
+type HeartbeatArgs struct {
+	Agent string `json:"agent"`
+}
+
+type HeartbeatResponse struct {
+	// Cancelled is true if the job is cancelled or timed out, the agent stops running it.
+	Cancelled bool `json:"cancelled"`
+}
+
+type LogResponse struct {
+	// Offset is the size of the log received, the next chunk is pushed at it.
+	Offset int64 `json:"offset"`
+}
+
+type CompleteArgs struct {
+	Agent   string           `json:"agent"`
+	Status  JobStatus        `json:"status"`
+	Error   string           `json:"error,omitempty"`
+	Outputs []*job.JobOutput `json:"outputs,omitempty"`
+}
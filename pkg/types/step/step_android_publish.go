@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+// StepAndroidPublishSpec collects the apk and aab built by gradle into OutputDir together with their version
+// metadata, and publishes them to firebase app distribution and/or google play if configured.
+type StepAndroidPublishSpec struct {
+	// Files are the globs of the apk and aab files, relative to the workspace.
+	Files       []string         `bson:"files"                              json:"files"                                   yaml:"files"`
+	OutputDir   string           `bson:"output_dir"                         json:"output_dir"                              yaml:"output_dir"`
+	VersionName string           `bson:"version_name"                       json:"version_name"                            yaml:"version_name"`
+	VersionCode string           `bson:"version_code"                       json:"version_code"                            yaml:"version_code"`
+	Firebase    *FirebasePublish `bson:"firebase,omitempty"                 json:"firebase,omitempty"                      yaml:"firebase,omitempty"`
+	GooglePlay  *PlayPublish     `bson:"google_play,omitempty"              json:"google_play,omitempty"                   yaml:"google_play,omitempty"`
+}
+
+// FirebasePublish distributes the apk, or the aab if there is no apk, to the testers of firebase app distribution.
+type FirebasePublish struct {
+	// AppID is the firebase app id, e.g. 1:1234567890:android:0a1b2c3d4e5f67890.
+	AppID string `bson:"app_id"                             json:"app_id"                                  yaml:"app_id"`
+	// ServiceAccountEnv is the credential variable of the json key of the service account which has the
+	// firebase app distribution admin role.
+	ServiceAccountEnv string   `bson:"service_account_env"                json:"service_account_env"                     yaml:"service_account_env"`
+	Groups            []string `bson:"groups"                             json:"groups"                                  yaml:"groups"`
+	Testers           []string `bson:"testers"                            json:"testers"                                 yaml:"testers"`
+	ReleaseNotes      string   `bson:"release_notes"                      json:"release_notes"                           yaml:"release_notes"`
+}
+
+// PlayPublish uploads the aab, or the apks if there is no aab, to a track of google play.
+type PlayPublish struct {
+	PackageName string `bson:"package_name"                       json:"package_name"                            yaml:"package_name"`
+	// ServiceAccountEnv is the credential variable of the json key of the service account which is granted
+	// the release permissions in play console.
+	ServiceAccountEnv string `bson:"service_account_env"                json:"service_account_env"                     yaml:"service_account_env"`
+	// Track is internal if it is empty.
+	Track string `bson:"track"                              json:"track"                                   yaml:"track"`
+	// Status of the release, completed or draft, completed is used if it is empty.
+	Status       string `bson:"status"                             json:"status"                                  yaml:"status"`
+	ReleaseNotes string `bson:"release_notes"                      json:"release_notes"                           yaml:"release_notes"`
+}
+
+// AndroidArtifact is an entry of the metadata of the android artifacts.
+type AndroidArtifact struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// AndroidMetadata is saved as AndroidMetadataFile in OutputDir next to the artifacts.
+type AndroidMetadata struct {
+	VersionName string             `json:"version_name"`
+	VersionCode string             `json:"version_code"`
+	Files       []*AndroidArtifact `json:"files"`
+}
+
+const AndroidMetadataFile = "metadata.json"
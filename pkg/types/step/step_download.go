@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type ArtifactSource string
+
+const (
+	ArtifactSourceHTTP        ArtifactSource = "http"
+	ArtifactSourceS3          ArtifactSource = "s3"
+	ArtifactSourceArtifactory ArtifactSource = "artifactory"
+)
+
+// StepDownloadSpec downloads the artifacts into the workspace, every artifact is verified by its sha256
+// and the step fails if any of them does not match.
+type StepDownloadSpec struct {
+	Artifacts []*ArtifactDownload `bson:"artifacts"                          json:"artifacts"                               yaml:"artifacts"`
+}
+
+// ArtifactDownload is an artifact downloaded by url from a http server or artifactory, or by path from
+// an object storage. The credentials are referenced by the names of the variables holding them, the
+// object storage credentials are filled in from ObjectStorageID before the job runs.
+type ArtifactDownload struct {
+	Source ArtifactSource `bson:"source"                             json:"source"                                  yaml:"source"`
+	URL    string         `bson:"url"                                json:"url"                                     yaml:"url"`
+	// ObjectStorageID and ObjectPath locate the artifact of the s3 source, the default object storage is
+	// used if ObjectStorageID is empty.
+	ObjectStorageID string `bson:"object_storage_id"                  json:"object_storage_id"                       yaml:"object_storage_id"`
+	ObjectPath      string `bson:"object_path"                        json:"object_path"                             yaml:"object_path"`
+	S3              *S3    `bson:"s3_storage,omitempty"               json:"s3_storage,omitempty"                    yaml:"s3_storage,omitempty"`
+	// UsernameEnv and PasswordEnv are the variables of the basic auth, TokenEnv is the variable of the
+	// bearer token, they are only used by the http and artifactory sources.
+	UsernameEnv string `bson:"username_env"                       json:"username_env"                            yaml:"username_env"`
+	PasswordEnv string `bson:"password_env"                       json:"password_env"                            yaml:"password_env"`
+	TokenEnv    string `bson:"token_env"                          json:"token_env"                               yaml:"token_env"`
+	// Sha256 is the expected hex digest of the artifact, it may reference variables.
+	Sha256 string `bson:"sha256"                             json:"sha256"                                  yaml:"sha256"`
+	// Destination is the file path relative to the workspace.
+	Destination string `bson:"destination"                        json:"destination"                             yaml:"destination"`
+}
+
+var sha256Regexp = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// Validate checks the artifact before the variables are rendered.
+func (a *ArtifactDownload) Validate() error {
+	switch a.Source {
+	case ArtifactSourceHTTP, ArtifactSourceArtifactory:
+		if a.URL == "" {
+			return fmt.Errorf("url of the %s artifact is empty", a.Source)
+		}
+	case ArtifactSourceS3:
+		if a.ObjectPath == "" {
+			return fmt.Errorf("object path of the s3 artifact is empty")
+		}
+	default:
+		return fmt.Errorf("unsupported artifact source: %s", a.Source)
+	}
+	if a.Destination == "" {
+		return fmt.Errorf("destination of artifact %s is empty", a.Name())
+	}
+	if a.Sha256 == "" {
+		return fmt.Errorf("sha256 of artifact %s is required", a.Name())
+	}
+	if !strings.Contains(a.Sha256, "$") {
+		return ValidateSha256(a.Sha256)
+	}
+	return nil
+}
+
+// Name is the url or the object path of the artifact.
+func (a *ArtifactDownload) Name() string {
+	if a.Source == ArtifactSourceS3 {
+		return a.ObjectPath
+	}
+	return a.URL
+}
+
+// ValidateSha256 checks the digest is a hex encoded sha256.
+func ValidateSha256(digest string) error {
+	if !sha256Regexp.MatchString(digest) {
+		return fmt.Errorf("invalid sha256: %s", digest)
+	}
+	return nil
+}
@@ -19,12 +19,15 @@ package step
 import (
 	"fmt"
 
+	"github.com/koderover/zadig/pkg/tool/secretscan"
 	"github.com/koderover/zadig/pkg/types"
 )
 
 type StepGitSpec struct {
 	Repos []*types.Repository `bson:"repos"          json:"repos"    yaml:"repos"`
 	Proxy *Proxy              `bson:"proxy"          json:"proxy"    yaml:"proxy"`
+	// SecretScan scans the changes of the merged pull requests for secrets if it is set.
+	SecretScan *secretscan.Config `bson:"secret_scan,omitempty" json:"secret_scan,omitempty" yaml:"secret_scan,omitempty"`
 }
 
 const (
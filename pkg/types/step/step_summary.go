@@ -0,0 +1,29 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+// StepSummarySpec uploads the summary file written by the job, the summary is parsed by aslan in the format
+// after the job ends.
+type StepSummarySpec struct {
+	// FilePath is relative to the workspace, the summary is skipped if the file does not exist.
+	FilePath        string `bson:"file_path"                          json:"file_path"                                 yaml:"file_path"`
+	Format          string `bson:"format"                             json:"format"                                    yaml:"format"`
+	ObjectStorageID string `bson:"object_storage_id"                  json:"object_storage_id"                         yaml:"object_storage_id"`
+	S3              *S3    `bson:"s3_storage"                         json:"s3_storage"                                yaml:"s3_storage"`
+	// ObjectKey is where the summary file is uploaded to, it is decided by aslan.
+	ObjectKey string `bson:"object_key"                              json:"object_key"                                yaml:"object_key"`
+}
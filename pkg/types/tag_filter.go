@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+const (
+	TagFilterSemver = "semver"
+	TagFilterRegexp = "regexp"
+)
+
+// TagFilter filters the tags which trigger a workflow. For semver type the expression is a range
+// such as ">=1.2.0 <2.0.0" matched against the tag with the "v" prefix trimmed, for regexp type it
+// is matched against the tag name, e.g. "^v\d+\.\d+\.\d+$".
+type TagFilter struct {
+	Type       string `bson:"type"        json:"type"`
+	Expression string `bson:"expression"  json:"expression"`
+	// StableOnly skips the pre-release versions such as v1.2.0-rc.1, it is only for semver type.
+	StableOnly bool `bson:"stable_only" json:"stable_only"`
+}
+
+func (f *TagFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	switch f.Type {
+	case TagFilterSemver:
+		if f.Expression == "" {
+			return nil
+		}
+		if _, err := semver.ParseRange(f.Expression); err != nil {
+			return fmt.Errorf("invalid semver range %q: %s", f.Expression, err)
+		}
+	case TagFilterRegexp:
+		if _, err := regexp.Compile(f.Expression); err != nil {
+			return fmt.Errorf("invalid tag regexp %q: %s", f.Expression, err)
+		}
+	default:
+		return fmt.Errorf("unknown tag filter type %q", f.Type)
+	}
+	return nil
+}
+
+// Match reports whether the tag passes the filter, a nil filter matches all tags.
+func (f *TagFilter) Match(tag string) bool {
+	if f == nil {
+		return true
+	}
+	switch f.Type {
+	case TagFilterSemver:
+		version := ParseTagVersion(tag)
+		if version == nil {
+			return false
+		}
+		v := semver.MustParse(version.Version)
+		if f.StableOnly && len(v.Pre) > 0 {
+			return false
+		}
+		if f.Expression == "" {
+			return true
+		}
+		versionRange, err := semver.ParseRange(f.Expression)
+		if err != nil {
+			return false
+		}
+		return versionRange(v)
+	case TagFilterRegexp:
+		// Do not use regexp.MustCompile to avoid panic
+		matched, _ := regexp.MatchString(f.Expression, tag)
+		return matched
+	}
+	return false
+}
+
+// TagVersion is the semantic version parsed from a tag such as v1.2.3-rc.1+build.5.
+type TagVersion struct {
+	Version    string
+	Major      string
+	Minor      string
+	Patch      string
+	Prerelease string
+	Build      string
+}
+
+// ParseTagVersion returns nil if the tag is not a semantic version.
+func ParseTagVersion(tag string) *TagVersion {
+	v, err := semver.Parse(strings.TrimPrefix(tag, "v"))
+	if err != nil {
+		return nil
+	}
+	pre := make([]string, 0, len(v.Pre))
+	for _, p := range v.Pre {
+		pre = append(pre, p.String())
+	}
+	return &TagVersion{
+		Version:    v.String(),
+		Major:      fmt.Sprintf("%d", v.Major),
+		Minor:      fmt.Sprintf("%d", v.Minor),
+		Patch:      fmt.Sprintf("%d", v.Patch),
+		Prerelease: strings.Join(pre, "."),
+		Build:      strings.Join(v.Build, "."),
+	}
+}
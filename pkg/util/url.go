@@ -18,9 +18,12 @@ package util
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
+	"golang.org/x/net/http/httpproxy"
+
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
 )
@@ -106,3 +109,29 @@ func ParseOwnerAndRepo(repoLink string, authType types.AuthType) (string, string
 
 	return ownerAndRepo[0], ownerAndRepo[1]
 }
+
+// ProxyFunc returns the proxy function of http.Transport which sends the requests by proxyURL, except the
+// ones to the hosts in noProxy, which are matched like the NO_PROXY environment variable.
+func ProxyFunc(proxyURL string, noProxy []string) func(*http.Request) (*url.URL, error) {
+	proxy := newProxyConfig(proxyURL, noProxy).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// BypassProxy reports whether the address is in noProxy and should be accessed directly.
+func BypassProxy(address string, noProxy []string) bool {
+	if len(noProxy) == 0 {
+		return false
+	}
+	uri, err := url.Parse(address)
+	if err != nil || uri.Host == "" {
+		return false
+	}
+	proxy, err := newProxyConfig("http://proxy", noProxy).ProxyFunc()(uri)
+	return err == nil && proxy == nil
+}
+
+func newProxyConfig(proxyURL string, noProxy []string) *httpproxy.Config {
+	return &httpproxy.Config{HTTPProxy: proxyURL, HTTPSProxy: proxyURL, NoProxy: strings.Join(noProxy, ",")}
+}